@@ -0,0 +1,365 @@
+// cleanup-orphaned-chunks
+//
+// Finds S3 session prefixes ({userID}/{provider}/{externalID}/) with no
+// matching row in the sessions table and deletes them. They are left behind
+// when a session delete removes the DB row but dies before its S3 cleanup.
+// Every canonical provider is scanned; other top-level entries under a user
+// (e.g. {userID}/exports/) are never touched.
+//
+// A prefix is only removed when its newest object is older than -min-age-days,
+// so a session whose delete (or first sync) is still in flight is left alone,
+// and the DB is checked again right before each delete.
+//
+// Usage:
+//   DATABASE_URL=... S3_ENDPOINT=... AWS_ACCESS_KEY_ID=... AWS_SECRET_ACCESS_KEY=... BUCKET_NAME=... go run ./cmd/cleanup-orphaned-chunks
+//
+// Flags:
+//   -dry-run        Print the orphaned prefixes without deleting them
+//   -min-age-days   Skip prefixes with an object newer than this many days (default: 7)
+//   -selftest       Check DB, schema and S3 access (with a sample read) and exit
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/lib/pq"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/selftest"
+)
+
+// existingQuery returns which of a user's external IDs still have a session
+// row under the provider (or one of its legacy aliases).
+const existingQuery = `
+	SELECT external_id FROM sessions
+	WHERE user_id = $1 AND session_type = ANY($2) AND external_id = ANY($3)`
+
+// orphan is one session prefix with no DB row.
+type orphan struct {
+	prefix  string
+	objects int
+	bytes   int64
+	newest  time.Time
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "Print the orphaned prefixes without deleting them")
+	minAgeDays := flag.Int("min-age-days", 7, "Skip prefixes with an object newer than this many days")
+	selfTest := selftest.Flag()
+	flag.Parse()
+
+	if *minAgeDays < 0 {
+		log.Fatalf("-min-age-days must not be negative")
+	}
+	minAge := time.Duration(*minAgeDays) * 24 * time.Hour
+
+	dbURL := requireEnv("DATABASE_URL")
+	s3Endpoint := requireEnv("S3_ENDPOINT")
+	accessKey := requireEnv("AWS_ACCESS_KEY_ID")
+	secretKey := requireEnv("AWS_SECRET_ACCESS_KEY")
+	bucketName := requireEnv("BUCKET_NAME")
+
+	useSSL := os.Getenv("S3_USE_SSL") != "false"
+
+	db, err := sql.Open("pgx", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	s3Client, err := minio.New(s3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create S3 client: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if *selfTest {
+		checks := []selftest.Check{
+			selftest.DatabaseReachable(db),
+			selftest.SchemaHasColumns(db, map[string][]string{
+				"sessions": {"user_id", "external_id", "session_type"},
+			}),
+			selftest.SampleRow(db, existingQuery, int64(0), pq.Array(models.AllowedProviders), pq.Array([]string{""})),
+			selftest.BucketExists(s3Client, bucketName),
+			selftest.SampleObject(s3Client, bucketName, ""),
+		}
+		if err := selftest.Run(ctx, os.Stdout, checks); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Selftest passed")
+		return
+	}
+
+	if err := db.Ping(); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+	log.Println("Connected to database")
+
+	cutoff := time.Now().Add(-minAge)
+	var scanned, found, tooRecent, deleted, deletedObjects, errors int
+	var orphanedBytes int64
+
+	userIDs, err := listUserIDs(ctx, s3Client, bucketName)
+	if err != nil {
+		log.Fatalf("Failed to list users in bucket: %v", err)
+	}
+
+	for _, userID := range userIDs {
+		for _, provider := range models.CanonicalProviders {
+			externalIDs, err := listSubPrefixes(ctx, s3Client, bucketName, userPrefix(userID)+provider+"/")
+			if err != nil {
+				log.Printf("Error listing user=%d provider=%s: %v", userID, provider, err)
+				errors++
+				continue
+			}
+			if len(externalIDs) == 0 {
+				continue
+			}
+			scanned += len(externalIDs)
+
+			missing, err := missingSessions(ctx, db, userID, provider, externalIDs)
+			if err != nil {
+				log.Printf("Error checking sessions of user=%d provider=%s: %v", userID, provider, err)
+				errors++
+				continue
+			}
+
+			for _, externalID := range missing {
+				o, err := describePrefix(ctx, s3Client, bucketName, sessionPrefix(userID, provider, externalID))
+				if err != nil {
+					log.Printf("Error listing %s: %v", o.prefix, err)
+					errors++
+					continue
+				}
+				if o.objects == 0 {
+					continue
+				}
+				if o.newest.After(cutoff) {
+					log.Printf("Skipping %s: newest object %s is within %d days", o.prefix, o.newest.Format(time.RFC3339), *minAgeDays)
+					tooRecent++
+					continue
+				}
+				found++
+				orphanedBytes += o.bytes
+
+				if *dryRun {
+					log.Printf("[DRY-RUN] Orphaned %s: %d objects, %d bytes, newest %s",
+						o.prefix, o.objects, o.bytes, o.newest.Format(time.RFC3339))
+					continue
+				}
+
+				// A session may have been created since the batch check.
+				stillMissing, err := missingSessions(ctx, db, userID, provider, []string{externalID})
+				if err != nil {
+					log.Printf("Error rechecking %s: %v", o.prefix, err)
+					errors++
+					continue
+				}
+				if len(stillMissing) == 0 {
+					log.Printf("Skipping %s: session now exists", o.prefix)
+					continue
+				}
+
+				n, err := removePrefix(ctx, s3Client, bucketName, o.prefix)
+				deletedObjects += n
+				if err != nil {
+					log.Printf("Error deleting %s: %v", o.prefix, err)
+					errors++
+					continue
+				}
+				deleted++
+				log.Printf("Deleted %s: %d objects, %d bytes", o.prefix, n, o.bytes)
+			}
+		}
+	}
+
+	log.Println("========================================")
+	log.Printf("Orphaned chunk cleanup complete:")
+	log.Printf("  Session prefixes scanned: %d", scanned)
+	log.Printf("  Orphaned (older than %d days): %d (%d bytes)", *minAgeDays, found, orphanedBytes)
+	log.Printf("  Skipped as too recent: %d", tooRecent)
+	if *dryRun {
+		log.Printf("  Would delete: %d", found)
+	} else {
+		log.Printf("  Deleted: %d prefixes, %d objects", deleted, deletedObjects)
+	}
+	log.Printf("  Errors: %d", errors)
+	if errors > 0 {
+		os.Exit(1)
+	}
+}
+
+func userPrefix(userID int64) string {
+	return strconv.FormatInt(userID, 10) + "/"
+}
+
+// sessionPrefix is the subtree DeleteAllSessionChunks cleans up for a session.
+func sessionPrefix(userID int64, provider, externalID string) string {
+	return userPrefix(userID) + provider + "/" + externalID + "/"
+}
+
+// listUserIDs returns the user IDs with a top-level prefix in the bucket.
+// Top-level entries that aren't a user ID are ignored.
+func listUserIDs(ctx context.Context, client *minio.Client, bucket string) ([]int64, error) {
+	names, err := listSubPrefixes(ctx, client, bucket, "")
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for _, name := range names {
+		if id, ok := parseUserID(name); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// parseUserID parses a top-level prefix name as a user ID.
+func parseUserID(name string) (int64, bool) {
+	id, err := strconv.ParseInt(name, 10, 64)
+	if err != nil || id <= 0 || strconv.FormatInt(id, 10) != name {
+		return 0, false
+	}
+	return id, true
+}
+
+// listSubPrefixes returns the names of the "directories" directly under
+// prefix (without the prefix or trailing slash).
+func listSubPrefixes(ctx context.Context, client *minio.Client, bucket, prefix string) ([]string, error) {
+	var names []string
+	for obj := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		if name, ok := subPrefixName(prefix, obj.Key); ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// subPrefixName extracts the directory name from a non-recursive listing
+// entry. Plain objects directly under prefix (no trailing slash) are skipped.
+func subPrefixName(prefix, key string) (string, bool) {
+	name, ok := strings.CutPrefix(key, prefix)
+	if !ok || !strings.HasSuffix(name, "/") {
+		return "", false
+	}
+	name = strings.TrimSuffix(name, "/")
+	if name == "" || strings.Contains(name, "/") {
+		return "", false
+	}
+	return name, true
+}
+
+// missingSessions returns the external IDs with no session row for the user
+// under provider.
+func missingSessions(ctx context.Context, db *sql.DB, userID int64, provider string, externalIDs []string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, existingQuery,
+		userID, pq.Array(models.ExpandWithAliases([]string{provider})), pq.Array(externalIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool, len(externalIDs))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		existing[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return missingIDs(externalIDs, existing), nil
+}
+
+// missingIDs returns the IDs not in existing, in their original order.
+func missingIDs(ids []string, existing map[string]bool) []string {
+	var missing []string
+	for _, id := range ids {
+		if !existing[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// describePrefix counts the objects under prefix and finds the newest one.
+func describePrefix(ctx context.Context, client *minio.Client, bucket, prefix string) (orphan, error) {
+	o := orphan{prefix: prefix}
+	for obj := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return o, obj.Err
+		}
+		o.objects++
+		o.bytes += obj.Size
+		if obj.LastModified.After(o.newest) {
+			o.newest = obj.LastModified
+		}
+	}
+	return o, nil
+}
+
+// removePrefix deletes every object under prefix with batched RemoveObjects
+// calls and returns how many were sent for removal, less the failures.
+func removePrefix(ctx context.Context, client *minio.Client, bucket, prefix string) (int, error) {
+	toRemove := make(chan minio.ObjectInfo)
+	type listed struct {
+		count int
+		err   error
+	}
+	done := make(chan listed, 1)
+	go func() {
+		defer close(toRemove)
+		var l listed
+		for obj := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if obj.Err != nil {
+				l.err = obj.Err
+				break
+			}
+			toRemove <- obj
+			l.count++
+		}
+		done <- l
+	}()
+
+	var firstErr error
+	failed := 0
+	for rerr := range client.RemoveObjects(ctx, bucket, toRemove, minio.RemoveObjectsOptions{}) {
+		failed++
+		if firstErr == nil {
+			firstErr = rerr.Err
+		}
+	}
+	l := <-done
+	if l.err != nil {
+		return l.count - failed, l.err
+	}
+	return l.count - failed, firstErr
+}
+
+func requireEnv(key string) string {
+	val := os.Getenv(key)
+	if val == "" {
+		log.Fatalf("%s is required", key)
+	}
+	return val
+}
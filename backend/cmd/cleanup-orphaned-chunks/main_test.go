@@ -0,0 +1,60 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSubPrefixName(t *testing.T) {
+	tests := []struct {
+		prefix, key string
+		want        string
+		ok          bool
+	}{
+		{"", "42/", "42", true},
+		{"42/claude-code/", "42/claude-code/abc-123/", "abc-123", true},
+		{"42/claude-code/", "42/claude-code/stray.jsonl", "", false}, // plain object, not a session
+		{"42/claude-code/", "42/codex/abc/", "", false},
+		{"42/claude-code/", "42/claude-code/a/b/", "", false},
+		{"42/claude-code/", "42/claude-code//", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := subPrefixName(tt.prefix, tt.key)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("subPrefixName(%q, %q) = %q, %v; want %q, %v", tt.prefix, tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseUserID(t *testing.T) {
+	for name, want := range map[string]int64{"1": 1, "9876543210": 9876543210} {
+		if got, ok := parseUserID(name); !ok || got != want {
+			t.Errorf("parseUserID(%q) = %d, %v; want %d", name, got, ok, want)
+		}
+	}
+	// Not user prefixes: other top-level entries, and forms that would
+	// format back to a different prefix.
+	for _, name := range []string{"exports", "0", "-3", "007", "+5", ""} {
+		if _, ok := parseUserID(name); ok {
+			t.Errorf("parseUserID(%q) accepted", name)
+		}
+	}
+}
+
+func TestMissingIDs(t *testing.T) {
+	got := missingIDs([]string{"a", "b", "c", "d"}, map[string]bool{"b": true, "d": true})
+	if want := []string{"a", "c"}; !slices.Equal(got, want) {
+		t.Errorf("missingIDs = %v, want %v", got, want)
+	}
+	if got := missingIDs([]string{"a"}, map[string]bool{"a": true}); got != nil {
+		t.Errorf("missingIDs with all present = %v, want nil", got)
+	}
+}
+
+func TestSessionPrefixMatchesStorageLayout(t *testing.T) {
+	// Must stay in sync with storage.sessionChunksPrefix /
+	// sessionGenerationsPrefix, which both sit under this prefix.
+	if got, want := sessionPrefix(42, "claude-code", "abc"), "42/claude-code/abc/"; got != want {
+		t.Errorf("sessionPrefix = %q, want %q", got, want)
+	}
+}
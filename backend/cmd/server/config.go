@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/auth"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/storage"
+	"github.com/ConfabulousDev/confab-web/internal/validation"
+)
+
+type Config struct {
+	Port         int
+	DatabaseURL  string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	S3Config     storage.S3Config
+	OAuthConfig  *auth.OAuthConfig
+	EmailConfig  EmailConfig
+
+	ShutdownConfig ShutdownConfig
+}
+
+type EmailConfig struct {
+	Enabled          bool
+	APIKey           string
+	FromAddress      string
+	FromName         string
+	RateLimitPerHour int
+	WorkerPoolSize   int // EMAIL_WORKER_POOL_SIZE: concurrent sends
+	QueueSize        int // EMAIL_QUEUE_SIZE: emails waiting for a worker
+}
+
+// ConfigError lists every missing or invalid environment variable ParseConfig
+// found, so an operator can fix them all in one restart instead of one per
+// restart.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// configProblems accumulates ParseConfig failures in the order found.
+type configProblems []string
+
+func (p *configProblems) missing(name, hint string) {
+	msg := name + " is required"
+	if hint != "" {
+		msg += " (" + hint + ")"
+	}
+	*p = append(*p, msg)
+}
+
+func (p *configProblems) invalid(name, reason string) {
+	*p = append(*p, name+" "+reason)
+}
+
+// ParseConfig builds the server configuration from environ (os.Getenv in
+// production; a map lookup in tests). Unset and empty values are treated the
+// same. Rather than stopping at the first problem it checks everything and
+// returns a *ConfigError listing each missing or invalid variable.
+func ParseConfig(environ func(key string) string) (Config, error) {
+	var problems configProblems
+
+	port := 8080
+	if p := environ("PORT"); p != "" {
+		parsed, err := strconv.Atoi(p)
+		if err != nil || parsed < 1 || parsed > 65535 {
+			problems.invalid("PORT", fmt.Sprintf("must be a port number between 1 and 65535, got %q", p))
+		} else {
+			port = parsed
+		}
+	}
+
+	// HTTP timeout configuration (defaults to 30s)
+	readTimeout := parseConfigDuration(environ, "HTTP_READ_TIMEOUT", 30*time.Second, &problems)
+	writeTimeout := parseConfigDuration(environ, "HTTP_WRITE_TIMEOUT", 30*time.Second, &problems)
+
+	// Graceful shutdown: each step gets its own timeout (defaults to 10s)
+	shutdownConfig := ShutdownConfig{
+		HTTPTimeout:        parseConfigDuration(environ, "HTTP_SHUTDOWN_TIMEOUT", 10*time.Second, &problems),
+		PrecomputerTimeout: parseConfigDuration(environ, "PRECOMPUTE_SHUTDOWN_TIMEOUT", 10*time.Second, &problems),
+		EmailTimeout:       parseConfigDuration(environ, "EMAIL_SHUTDOWN_TIMEOUT", 10*time.Second, &problems),
+	}
+
+	// Authentication configuration
+	// At least one auth method must be enabled: password, GitHub OAuth, or Google OAuth
+	var oauthConfig auth.OAuthConfig
+
+	// Password authentication (optional)
+	oauthConfig.PasswordEnabled = environ("AUTH_PASSWORD_ENABLED") == "true"
+	if oauthConfig.PasswordEnabled {
+		logger.Info("password authentication enabled")
+	}
+
+	// Email magic-link login (optional). Requires the email service; checked
+	// below once the email configuration is loaded.
+	oauthConfig.EmailLinkEnabled = environ("AUTH_EMAIL_LINK_ENABLED") == "true"
+	if oauthConfig.EmailLinkEnabled {
+		logger.Info("email magic-link authentication enabled")
+	}
+
+	// OAuth → existing-account email auto-linking. Default OFF (cm4f): a
+	// first-time OAuth login whose email matches an existing account is NOT
+	// auto-linked unless explicitly enabled, preventing account takeover when an
+	// attacker controls a matching IdP email.
+	oauthConfig.AutoLinkEmail = environ("OAUTH_AUTO_LINK_EMAIL") == "true"
+	if oauthConfig.AutoLinkEmail {
+		logger.Info("OAuth email auto-linking enabled (OAUTH_AUTO_LINK_EMAIL=true)")
+	}
+
+	// GitHub OAuth (optional)
+	githubClientID := environ("GITHUB_CLIENT_ID")
+	githubClientSecret := environ("GITHUB_CLIENT_SECRET")
+	githubRedirectURL := environ("GITHUB_REDIRECT_URL")
+	if githubClientID != "" && githubClientSecret != "" && githubRedirectURL != "" {
+		oauthConfig.GitHubEnabled = true
+		oauthConfig.GitHubClientID = githubClientID
+		oauthConfig.GitHubClientSecret = githubClientSecret
+		oauthConfig.GitHubRedirectURL = githubRedirectURL
+		checkConfigURL("GITHUB_REDIRECT_URL", githubRedirectURL, &problems)
+		logger.Info("GitHub OAuth enabled")
+	}
+
+	// Google OAuth (optional)
+	googleClientID := environ("GOOGLE_CLIENT_ID")
+	googleClientSecret := environ("GOOGLE_CLIENT_SECRET")
+	googleRedirectURL := environ("GOOGLE_REDIRECT_URL")
+	if googleClientID != "" && googleClientSecret != "" && googleRedirectURL != "" {
+		oauthConfig.GoogleEnabled = true
+		oauthConfig.GoogleClientID = googleClientID
+		oauthConfig.GoogleClientSecret = googleClientSecret
+		oauthConfig.GoogleRedirectURL = googleRedirectURL
+		checkConfigURL("GOOGLE_REDIRECT_URL", googleRedirectURL, &problems)
+		logger.Info("Google OAuth enabled")
+	}
+
+	// Generic OIDC (optional) — works with Okta, Auth0, Azure AD, Keycloak, etc.
+	oidcIssuerURL := environ("OIDC_ISSUER_URL")
+	oidcClientID := environ("OIDC_CLIENT_ID")
+	oidcClientSecret := environ("OIDC_CLIENT_SECRET")
+	oidcRedirectURL := environ("OIDC_REDIRECT_URL")
+	if oidcIssuerURL != "" && oidcClientID != "" && oidcClientSecret != "" && oidcRedirectURL != "" {
+		oauthConfig.OIDCEnabled = true
+		oauthConfig.OIDCIssuerURL = oidcIssuerURL
+		oauthConfig.OIDCClientID = oidcClientID
+		oauthConfig.OIDCClientSecret = oidcClientSecret
+		oauthConfig.OIDCRedirectURL = oidcRedirectURL
+		oauthConfig.OIDCDisplayName = environ("OIDC_DISPLAY_NAME")
+		if oauthConfig.OIDCDisplayName == "" {
+			oauthConfig.OIDCDisplayName = "SSO"
+		}
+		checkConfigURL("OIDC_ISSUER_URL", oidcIssuerURL, &problems)
+		checkConfigURL("OIDC_REDIRECT_URL", oidcRedirectURL, &problems)
+		logger.Info("OIDC enabled (discovery deferred)", "issuer", oidcIssuerURL, "display_name", oauthConfig.OIDCDisplayName)
+	}
+
+	// Parse allowed email domains (optional, for on-prem deployments)
+	if allowedDomainsEnv := environ("ALLOWED_EMAIL_DOMAINS"); allowedDomainsEnv != "" {
+		var domains []string
+		for _, d := range strings.Split(allowedDomainsEnv, ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if d != "" {
+				domains = append(domains, d)
+			}
+		}
+		if err := validation.ValidateDomainList(domains); err != nil {
+			problems.invalid("ALLOWED_EMAIL_DOMAINS", err.Error())
+		} else {
+			oauthConfig.AllowedEmailDomains = domains
+			logger.Info("email domain restrictions configured", "allowed_domains", domains)
+		}
+	}
+
+	// Require at least one authentication method
+	if !oauthConfig.PasswordEnabled && !oauthConfig.EmailLinkEnabled && !oauthConfig.GitHubEnabled && !oauthConfig.GoogleEnabled && !oauthConfig.OIDCEnabled {
+		problems = append(problems, "no authentication method configured: set AUTH_PASSWORD_ENABLED=true, or set AUTH_EMAIL_LINK_ENABLED=true, or configure GitHub OAuth (GITHUB_CLIENT_ID, GITHUB_CLIENT_SECRET, GITHUB_REDIRECT_URL), or configure Google OAuth, or configure OIDC (OIDC_ISSUER_URL, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL)")
+	}
+
+	// Validate required security configuration
+	csrfSecretKey := environ("CSRF_SECRET_KEY")
+	switch {
+	case csrfSecretKey == "":
+		problems.missing("CSRF_SECRET_KEY", "must be at least 32 characters")
+	case len(csrfSecretKey) < 32:
+		problems.invalid("CSRF_SECRET_KEY", "must be at least 32 characters")
+	}
+	// Refuse to run with a public default secret outside local eval. The shipped
+	// docker-compose.yml / .env.example defaults are fine for `docker compose up`
+	// (INSECURE_DEV_MODE=true), but become a serious hole the moment the instance
+	// is exposed. See security_guard.go.
+	if reason := insecureDefaultReason(environ("INSECURE_DEV_MODE") == "true", environ("FRONTEND_URL"), csrfSecretKey, environ("ADMIN_BOOTSTRAP_PASSWORD")); reason != "" {
+		problems = append(problems, "refusing to start: insecure default in production mode: "+reason+
+			" (set a unique CSRF_SECRET_KEY (openssl rand -base64 32) and ADMIN_BOOTSTRAP_PASSWORD, or set INSECURE_DEV_MODE=true for local evaluation only)")
+	}
+	oauthConfig.CSRFSecretKey = csrfSecretKey
+
+	// CF-483: optional demo identity. When set, the named user is the
+	// per-user read-only "demo identity"; anonymous web visitors are
+	// auto-impersonated as them. Unset = zero behavior change.
+	if demoEmail := strings.TrimSpace(environ("DEMO_IDENTITY_EMAIL")); demoEmail != "" {
+		demoEmail = validation.NormalizeEmail(demoEmail)
+		if !validation.IsValidEmail(demoEmail) {
+			problems.invalid("DEMO_IDENTITY_EMAIL", "must be a valid email address")
+		} else {
+			oauthConfig.DemoIdentityEmail = demoEmail
+			logger.Info("demo mode enabled", "demo_identity_email", demoEmail)
+		}
+	}
+
+	// Validate required database configuration
+	databaseURL := environ("DATABASE_URL")
+	if databaseURL == "" {
+		problems.missing("DATABASE_URL", "")
+	}
+
+	// Validate required frontend configuration
+	if frontendURL := environ("FRONTEND_URL"); frontendURL == "" {
+		problems.missing("FRONTEND_URL", "")
+	} else {
+		checkConfigURL("FRONTEND_URL", frontendURL, &problems)
+	}
+	allowedOrigins := environ("ALLOWED_ORIGINS")
+	if allowedOrigins == "" {
+		problems.missing("ALLOWED_ORIGINS", "comma-separated list of allowed origins")
+	}
+	// CORS spec forbids wildcard origin with AllowCredentials=true (which we use
+	// for cookie-based sessions). Browsers should refuse this combination, but
+	// some lenient clients honor it — fail loudly at startup so an operator
+	// can't silently expose authenticated endpoints to every origin.
+	for _, o := range strings.Split(allowedOrigins, ",") {
+		if strings.TrimSpace(o) == "*" {
+			problems.invalid("ALLOWED_ORIGINS", "must not contain wildcard '*': AllowCredentials=true requires an explicit origin list")
+			break
+		}
+	}
+
+	// Email configuration (optional)
+	resendAPIKey := environ("RESEND_API_KEY")
+	emailFromAddress := environ("EMAIL_FROM_ADDRESS")
+	emailFromName := environ("EMAIL_FROM_NAME")
+	if emailFromName == "" {
+		emailFromName = "Confab"
+	}
+
+	emailRateLimitPerHour := 100 // Default: 100 emails per hour per user
+	if rateLimit := environ("EMAIL_RATE_LIMIT_PER_HOUR"); rateLimit != "" {
+		parsed, err := strconv.Atoi(rateLimit)
+		if err != nil || parsed < 0 {
+			problems.invalid("EMAIL_RATE_LIMIT_PER_HOUR", fmt.Sprintf("must be a non-negative integer, got %q", rateLimit))
+		} else {
+			emailRateLimitPerHour = parsed
+		}
+	}
+
+	emailWorkerPoolSize := 3
+	if v := environ("EMAIL_WORKER_POOL_SIZE"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 {
+			problems.invalid("EMAIL_WORKER_POOL_SIZE", fmt.Sprintf("must be a positive integer, got %q", v))
+		} else {
+			emailWorkerPoolSize = parsed
+		}
+	}
+
+	emailQueueSize := 100
+	if v := environ("EMAIL_QUEUE_SIZE"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 {
+			problems.invalid("EMAIL_QUEUE_SIZE", fmt.Sprintf("must be a positive integer, got %q", v))
+		} else {
+			emailQueueSize = parsed
+		}
+	}
+
+	// Email is enabled only if both API key and from address are set
+	emailEnabled := resendAPIKey != "" && emailFromAddress != ""
+	if oauthConfig.EmailLinkEnabled && !emailEnabled {
+		problems.invalid("AUTH_EMAIL_LINK_ENABLED", "requires RESEND_API_KEY and EMAIL_FROM_ADDRESS")
+	}
+
+	s3Config, s3Problems := parseS3Config(environ)
+	problems = append(problems, s3Problems...)
+
+	if len(problems) > 0 {
+		return Config{}, &ConfigError{Problems: problems}
+	}
+	return Config{
+		Port:         port,
+		DatabaseURL:  databaseURL,
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+		S3Config:     s3Config,
+		OAuthConfig:  &oauthConfig,
+		EmailConfig: EmailConfig{
+			Enabled:          emailEnabled,
+			APIKey:           resendAPIKey,
+			FromAddress:      emailFromAddress,
+			FromName:         emailFromName,
+			RateLimitPerHour: emailRateLimitPerHour,
+			WorkerPoolSize:   emailWorkerPoolSize,
+			QueueSize:        emailQueueSize,
+		},
+		ShutdownConfig: shutdownConfig,
+	}, nil
+}
+
+// parseS3Config reads the S3 settings shared by the server and the worker,
+// returning one problem per missing variable.
+func parseS3Config(environ func(key string) string) (storage.S3Config, configProblems) {
+	var problems configProblems
+	required := func(name string) string {
+		v := environ(name)
+		if v == "" {
+			problems.missing(name, "")
+		}
+		return v
+	}
+	cfg := storage.S3Config{
+		Endpoint:        required("S3_ENDPOINT"),
+		AccessKeyID:     required("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: required("AWS_SECRET_ACCESS_KEY"),
+		BucketName:      required("BUCKET_NAME"),
+		UseSSL:          environ("S3_USE_SSL") != "false",
+
+		ReadFailoverEndpoint: environ("S3_READ_FAILOVER_ENDPOINT"),
+		ReadFailoverBucket:   environ("S3_READ_FAILOVER_BUCKET"),
+	}
+	return cfg, problems
+}
+
+// parseConfigDuration reads a Go duration, returning def when unset. A value
+// that does not parse, or is not positive, is recorded as a problem.
+func parseConfigDuration(environ func(key string) string, name string, def time.Duration, problems *configProblems) time.Duration {
+	raw := environ(name)
+	if raw == "" {
+		return def
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		problems.invalid(name, fmt.Sprintf("must be a positive Go duration (e.g. 30s), got %q", raw))
+		return def
+	}
+	return d
+}
+
+// checkConfigURL records a problem unless raw is an absolute http(s) URL.
+func checkConfigURL(name, raw string, problems *configProblems) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems.invalid(name, fmt.Sprintf("must be an absolute http(s) URL, got %q", raw))
+	}
+}
@@ -7,18 +7,17 @@ import (
 	"net/http/pprof"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 
 	"github.com/ConfabulousDev/confab-web/internal/admin"
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
 	"github.com/ConfabulousDev/confab-web/internal/api"
 	"github.com/ConfabulousDev/confab-web/internal/auth"
 	"github.com/ConfabulousDev/confab-web/internal/db"
 	"github.com/ConfabulousDev/confab-web/internal/email"
 	"github.com/ConfabulousDev/confab-web/internal/logger"
 	"github.com/ConfabulousDev/confab-web/internal/storage"
-	"github.com/ConfabulousDev/confab-web/internal/validation"
 	"github.com/honeycombio/otel-config-go/otelconfig"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
@@ -31,6 +30,10 @@ var (
 	buildTime string
 )
 
+// modelPricingRefreshInterval is how often the API server reloads the
+// model_pricing table. The worker reloads it every cycle instead.
+const modelPricingRefreshInterval = 5 * time.Minute
+
 // logFatal is a test seam over logger.Fatal so fatal branches are reachable
 // without os.Exit(1). Production keeps the real Fatal.
 var logFatal = logger.Fatal
@@ -59,7 +62,10 @@ func main() {
 	}
 
 	// Load configuration from environment
-	config := loadConfig()
+	config, err := ParseConfig(os.Getenv)
+	if err != nil {
+		logFatal("invalid configuration", "error", err)
+	}
 
 	if os.Getenv("INSECURE_DEV_MODE") == "true" {
 		logger.Warn("INSECURE_DEV_MODE=true: session/CSRF cookies will not require HTTPS and HSTS is disabled — do NOT use in production")
@@ -86,6 +92,24 @@ func main() {
 	}
 	defer database.Close()
 
+	// Optional read replica for analytics reads and the session list, detail
+	// and search. A replica that can't be reached at startup is skipped; those
+	// reads use the primary. Sync writes and the worker always use the primary.
+	if replicaURL := os.Getenv("DATABASE_REPLICA_URL"); replicaURL != "" {
+		if replica, err := db.ConnectReplica(replicaURL); err != nil {
+			logger.Warn("read replica unavailable, analytics reads will use the primary", "error", err)
+		} else {
+			maxLag := db.LoadReplicaMaxLag()
+			database.AttachReplica(replica, maxLag)
+			go database.MonitorReplica(context.Background())
+			logger.Info("read replica configured", "max_lag_ms", maxLag.Milliseconds())
+		}
+	}
+
+	// Keep the model_pricing table loaded for on-demand card computation, so
+	// price changes made through another instance reach this one.
+	go analytics.WatchModelPricing(context.Background(), database.Conn(), modelPricingRefreshInterval)
+
 	// Enable share-all-sessions mode for on-prem deployments
 	if os.Getenv("SHARE_ALL_SESSIONS_TO_AUTHENTICATED") == "true" {
 		database.ShareAllSessions = true
@@ -128,6 +152,7 @@ func main() {
 
 	// Initialize email service (optional)
 	var emailService *email.RateLimitedService
+	var emailPool *email.PooledService
 	if config.EmailConfig.Enabled {
 		resendService := email.NewResendService(
 			config.EmailConfig.APIKey,
@@ -135,8 +160,12 @@ func main() {
 			config.EmailConfig.FromName,
 			os.Getenv("FRONTEND_URL"),
 		)
-		emailService = email.NewRateLimitedService(resendService, config.EmailConfig.RateLimitPerHour)
-		logger.Info("email service configured", "provider", "resend", "rate_limit_per_hour", config.EmailConfig.RateLimitPerHour)
+		emailPool = email.NewPooledService(resendService, config.EmailConfig.WorkerPoolSize, config.EmailConfig.QueueSize)
+		emailService = email.NewRateLimitedService(emailPool, config.EmailConfig.RateLimitPerHour)
+		logger.Info("email service configured", "provider", "resend",
+			"rate_limit_per_hour", config.EmailConfig.RateLimitPerHour,
+			"worker_pool_size", config.EmailConfig.WorkerPoolSize,
+			"queue_size", config.EmailConfig.QueueSize)
 	} else {
 		logger.Info("email service disabled (RESEND_API_KEY or EMAIL_FROM_ADDRESS not set)")
 	}
@@ -151,7 +180,9 @@ func main() {
 
 	// Wrap router with OpenTelemetry HTTP instrumentation
 	// This automatically traces all incoming HTTP requests
-	handler := otelhttp.NewHandler(router, "confabulous-backend-prod")
+	// The in-flight count is reported if shutdown has to abandon requests.
+	var inFlight inFlightCounter
+	handler := otelhttp.NewHandler(inFlight.wrap(router), "confabulous-backend-prod")
 
 	// HTTP server configuration
 	httpServer := &http.Server{
@@ -175,225 +206,24 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	logger.Info("shutting down server")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	if err := httpServer.Shutdown(ctx); err != nil {
-		logFatal("server forced to shutdown", "error", err)
-	}
-
-	logger.Info("server stopped")
-}
-
-type Config struct {
-	Port         int
-	DatabaseURL  string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	S3Config     storage.S3Config
-	OAuthConfig  *auth.OAuthConfig
-	EmailConfig  EmailConfig
-}
-
-type EmailConfig struct {
-	Enabled          bool
-	APIKey           string
-	FromAddress      string
-	FromName         string
-	RateLimitPerHour int
-}
-
-func loadConfig() Config {
-	port := 8080
-	if p := os.Getenv("PORT"); p != "" {
-		fmt.Sscanf(p, "%d", &port)
-	}
-
-	// HTTP timeout configuration (defaults to 30s)
-	readTimeout := 30 * time.Second
-	if rt := os.Getenv("HTTP_READ_TIMEOUT"); rt != "" {
-		if parsed, err := time.ParseDuration(rt); err == nil {
-			readTimeout = parsed
-		}
-	}
-
-	writeTimeout := 30 * time.Second
-	if wt := os.Getenv("HTTP_WRITE_TIMEOUT"); wt != "" {
-		if parsed, err := time.ParseDuration(wt); err == nil {
-			writeTimeout = parsed
-		}
-	}
-
-	// Authentication configuration
-	// At least one auth method must be enabled: password, GitHub OAuth, or Google OAuth
-	var oauthConfig auth.OAuthConfig
-
-	// Password authentication (optional)
-	oauthConfig.PasswordEnabled = os.Getenv("AUTH_PASSWORD_ENABLED") == "true"
-	if oauthConfig.PasswordEnabled {
-		logger.Info("password authentication enabled")
-	}
-
-	// OAuth → existing-account email auto-linking. Default OFF (cm4f): a
-	// first-time OAuth login whose email matches an existing account is NOT
-	// auto-linked unless explicitly enabled, preventing account takeover when an
-	// attacker controls a matching IdP email.
-	oauthConfig.AutoLinkEmail = os.Getenv("OAUTH_AUTO_LINK_EMAIL") == "true"
-	if oauthConfig.AutoLinkEmail {
-		logger.Info("OAuth email auto-linking enabled (OAUTH_AUTO_LINK_EMAIL=true)")
-	}
-
-	// GitHub OAuth (optional)
-	githubClientID := os.Getenv("GITHUB_CLIENT_ID")
-	githubClientSecret := os.Getenv("GITHUB_CLIENT_SECRET")
-	githubRedirectURL := os.Getenv("GITHUB_REDIRECT_URL")
-	if githubClientID != "" && githubClientSecret != "" && githubRedirectURL != "" {
-		oauthConfig.GitHubEnabled = true
-		oauthConfig.GitHubClientID = githubClientID
-		oauthConfig.GitHubClientSecret = githubClientSecret
-		oauthConfig.GitHubRedirectURL = githubRedirectURL
-		logger.Info("GitHub OAuth enabled")
-	}
-
-	// Google OAuth (optional)
-	googleClientID := os.Getenv("GOOGLE_CLIENT_ID")
-	googleClientSecret := os.Getenv("GOOGLE_CLIENT_SECRET")
-	googleRedirectURL := os.Getenv("GOOGLE_REDIRECT_URL")
-	if googleClientID != "" && googleClientSecret != "" && googleRedirectURL != "" {
-		oauthConfig.GoogleEnabled = true
-		oauthConfig.GoogleClientID = googleClientID
-		oauthConfig.GoogleClientSecret = googleClientSecret
-		oauthConfig.GoogleRedirectURL = googleRedirectURL
-		logger.Info("Google OAuth enabled")
-	}
+	logger.Info("shutting down server",
+		"http_timeout", config.ShutdownConfig.HTTPTimeout,
+		"precompute_timeout", config.ShutdownConfig.PrecomputerTimeout,
+		"email_timeout", config.ShutdownConfig.EmailTimeout)
 
-	// Generic OIDC (optional) — works with Okta, Auth0, Azure AD, Keycloak, etc.
-	oidcIssuerURL := os.Getenv("OIDC_ISSUER_URL")
-	oidcClientID := os.Getenv("OIDC_CLIENT_ID")
-	oidcClientSecret := os.Getenv("OIDC_CLIENT_SECRET")
-	oidcRedirectURL := os.Getenv("OIDC_REDIRECT_URL")
-	if oidcIssuerURL != "" && oidcClientID != "" && oidcClientSecret != "" && oidcRedirectURL != "" {
-		oauthConfig.OIDCEnabled = true
-		oauthConfig.OIDCIssuerURL = oidcIssuerURL
-		oauthConfig.OIDCClientID = oidcClientID
-		oauthConfig.OIDCClientSecret = oidcClientSecret
-		oauthConfig.OIDCRedirectURL = oidcRedirectURL
-		oauthConfig.OIDCDisplayName = os.Getenv("OIDC_DISPLAY_NAME")
-		if oauthConfig.OIDCDisplayName == "" {
-			oauthConfig.OIDCDisplayName = "SSO"
-		}
-		logger.Info("OIDC enabled (discovery deferred)", "issuer", oidcIssuerURL, "display_name", oauthConfig.OIDCDisplayName)
+	// Stop accepting requests first. Once handlers have returned nothing else
+	// is counted or queued, so the background jobs they left (API key usage;
+	// the API server runs no precompute itself) and the email queue can drain.
+	steps := []shutdownStep{
+		{name: "http", timeout: config.ShutdownConfig.HTTPTimeout, stop: httpServer.Shutdown, pending: inFlight.count},
+		{name: "background jobs", timeout: config.ShutdownConfig.PrecomputerTimeout, stop: server.Close},
 	}
-
-	// Parse allowed email domains (optional, for on-prem deployments)
-	if allowedDomainsEnv := os.Getenv("ALLOWED_EMAIL_DOMAINS"); allowedDomainsEnv != "" {
-		var domains []string
-		for _, d := range strings.Split(allowedDomainsEnv, ",") {
-			d = strings.ToLower(strings.TrimSpace(d))
-			if d != "" {
-				domains = append(domains, d)
-			}
-		}
-		if err := validation.ValidateDomainList(domains); err != nil {
-			logFatal("invalid ALLOWED_EMAIL_DOMAINS", "error", err)
-		}
-		oauthConfig.AllowedEmailDomains = domains
-		logger.Info("email domain restrictions configured", "allowed_domains", domains)
+	if emailPool != nil {
+		steps = append(steps, shutdownStep{name: "email", timeout: config.ShutdownConfig.EmailTimeout, stop: emailPool.Close, pending: emailPool.QueueDepth})
 	}
+	runShutdown(steps)
 
-	// Require at least one authentication method
-	if !oauthConfig.PasswordEnabled && !oauthConfig.GitHubEnabled && !oauthConfig.GoogleEnabled && !oauthConfig.OIDCEnabled {
-		logFatal("no authentication method configured",
-			"hint", "set AUTH_PASSWORD_ENABLED=true, or configure GitHub OAuth (GITHUB_CLIENT_ID, GITHUB_CLIENT_SECRET, GITHUB_REDIRECT_URL), or configure Google OAuth, or configure OIDC (OIDC_ISSUER_URL, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL)")
-	}
-
-	// Validate required security configuration
-	csrfSecretKey := os.Getenv("CSRF_SECRET_KEY")
-	if csrfSecretKey == "" {
-		logFatal("missing required env var", "var", "CSRF_SECRET_KEY", "hint", "must be at least 32 characters")
-	}
-	if len(csrfSecretKey) < 32 {
-		logFatal("invalid env var", "var", "CSRF_SECRET_KEY", "error", "must be at least 32 characters")
-	}
-	// Refuse to run with a public default secret outside local eval. The shipped
-	// docker-compose.yml / .env.example defaults are fine for `docker compose up`
-	// (INSECURE_DEV_MODE=true), but become a serious hole the moment the instance
-	// is exposed. See security_guard.go.
-	if reason := insecureDefaultReason(os.Getenv("INSECURE_DEV_MODE") == "true", os.Getenv("FRONTEND_URL"), csrfSecretKey, os.Getenv("ADMIN_BOOTSTRAP_PASSWORD")); reason != "" {
-		logFatal("refusing to start: insecure default in production mode",
-			"error", reason,
-			"hint", "set a unique CSRF_SECRET_KEY (openssl rand -base64 32) and ADMIN_BOOTSTRAP_PASSWORD, or set INSECURE_DEV_MODE=true for local evaluation only")
-	}
-	oauthConfig.CSRFSecretKey = csrfSecretKey
-
-	// CF-483: optional demo identity. When set, the named user is the
-	// per-user read-only "demo identity"; anonymous web visitors are
-	// auto-impersonated as them. Unset = zero behavior change.
-	if demoEmail := strings.TrimSpace(os.Getenv("DEMO_IDENTITY_EMAIL")); demoEmail != "" {
-		demoEmail = validation.NormalizeEmail(demoEmail)
-		if !validation.IsValidEmail(demoEmail) {
-			logFatal("invalid env var", "var", "DEMO_IDENTITY_EMAIL", "error", "must be a valid email address")
-		}
-		oauthConfig.DemoIdentityEmail = demoEmail
-		logger.Info("demo mode enabled", "demo_identity_email", demoEmail)
-	}
-
-	// Validate required database configuration
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		logFatal("missing required env var", "var", "DATABASE_URL")
-	}
-
-	// Validate required frontend configuration
-	if os.Getenv("FRONTEND_URL") == "" {
-		logFatal("missing required env var", "var", "FRONTEND_URL")
-	}
-	allowedOrigins := os.Getenv("ALLOWED_ORIGINS")
-	if allowedOrigins == "" {
-		logFatal("missing required env var", "var", "ALLOWED_ORIGINS", "hint", "comma-separated list of allowed origins")
-	}
-	// CORS spec forbids wildcard origin with AllowCredentials=true (which we use
-	// for cookie-based sessions). Browsers should refuse this combination, but
-	// some lenient clients honor it — fail loudly at startup so an operator
-	// can't silently expose authenticated endpoints to every origin.
-	for _, o := range strings.Split(allowedOrigins, ",") {
-		if strings.TrimSpace(o) == "*" {
-			logFatal("invalid env var", "var", "ALLOWED_ORIGINS", "error", "wildcard '*' is not allowed: AllowCredentials=true requires an explicit origin list")
-		}
-	}
-
-	// Email configuration (optional)
-	resendAPIKey := os.Getenv("RESEND_API_KEY")
-	emailFromAddress := os.Getenv("EMAIL_FROM_ADDRESS")
-	emailFromName := os.Getenv("EMAIL_FROM_NAME")
-	if emailFromName == "" {
-		emailFromName = "Confab"
-	}
-
-	emailRateLimitPerHour := 100 // Default: 100 emails per hour per user
-	if rateLimit := os.Getenv("EMAIL_RATE_LIMIT_PER_HOUR"); rateLimit != "" {
-		fmt.Sscanf(rateLimit, "%d", &emailRateLimitPerHour)
-	}
-
-	// Email is enabled only if both API key and from address are set
-	emailEnabled := resendAPIKey != "" && emailFromAddress != ""
-
-	return Config{
-		Port:         port,
-		DatabaseURL:  databaseURL,
-		ReadTimeout:  readTimeout,
-		WriteTimeout: writeTimeout,
-		S3Config:     loadS3Config(),
-		OAuthConfig:  &oauthConfig,
-		EmailConfig: EmailConfig{
-			Enabled:          emailEnabled,
-			APIKey:           resendAPIKey,
-			FromAddress:      emailFromAddress,
-			FromName:         emailFromName,
-			RateLimitPerHour: emailRateLimitPerHour,
-		},
-	}
+	logger.Info("server stopped")
 }
 
 // buildPprofMux constructs the pprof debug mux. Split out from startPprofServer
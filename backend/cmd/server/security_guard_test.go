@@ -1,6 +1,7 @@
 package main
 
 import (
+	"os"
 	"strings"
 	"testing"
 )
@@ -52,42 +53,41 @@ func TestInsecureDefaultReason(t *testing.T) {
 	}
 }
 
-// loadConfig must fatal when production-intent meets a known default CSRF key.
-func TestLoadConfig_RefusesDefaultCSRFInProduction(t *testing.T) {
+// ParseConfig must fail when production-intent meets a known default CSRF key.
+func TestParseConfig_RefusesDefaultCSRFInProduction(t *testing.T) {
 	clearServerEnv(t)
 	setRequiredServerEnv(t)
 	t.Setenv("CSRF_SECRET_KEY", "local-dev-csrf-secret-change-me-32chars")
 	// INSECURE_DEV_MODE cleared by clearServerEnv => production intent.
 
-	got := withFatalRecover(t, func() { loadConfig() })
-	if got == nil {
-		t.Fatal("expected loadConfig to fatal on default CSRF key in production mode")
+	_, err := ParseConfig(os.Getenv)
+	if err == nil {
+		t.Fatal("expected ParseConfig to fail on default CSRF key in production mode")
 	}
-	if !strings.Contains(strings.ToLower(got.msg), "refusing to start") {
-		t.Errorf("unexpected fatal message: %q", got.msg)
+	if !strings.Contains(strings.ToLower(err.Error()), "refusing to start") {
+		t.Errorf("unexpected error: %q", err)
 	}
 }
 
 // The same default CSRF key is allowed in local eval (INSECURE_DEV_MODE=true).
-func TestLoadConfig_AllowsDefaultCSRFInDevMode(t *testing.T) {
+func TestParseConfig_AllowsDefaultCSRFInDevMode(t *testing.T) {
 	clearServerEnv(t)
 	setRequiredServerEnv(t)
 	t.Setenv("CSRF_SECRET_KEY", "local-dev-csrf-secret-change-me-32chars")
 	t.Setenv("INSECURE_DEV_MODE", "true")
 
-	if got := withFatalRecover(t, func() { loadConfig() }); got != nil {
-		t.Fatalf("expected no fatal in dev mode, got %q", got.msg)
+	if _, err := ParseConfig(os.Getenv); err != nil {
+		t.Fatalf("expected no error in dev mode, got %q", err)
 	}
 }
 
-// loadConfig must fatal when production-intent meets the default admin password.
-func TestLoadConfig_RefusesDefaultAdminPasswordInProduction(t *testing.T) {
+// ParseConfig must fail when production-intent meets the default admin password.
+func TestParseConfig_RefusesDefaultAdminPasswordInProduction(t *testing.T) {
 	clearServerEnv(t)
 	setRequiredServerEnv(t) // sets a unique CSRF key
 	t.Setenv("ADMIN_BOOTSTRAP_PASSWORD", "localdevpassword")
 
-	got := withFatalRecover(t, func() { loadConfig() })
-	if got == nil {
-		t.Fatal("expected loadConfig to fatal on default admin password in production mode")
+	if _, err := ParseConfig(os.Getenv); err == nil {
+		t.Fatal("expected ParseConfig to fail on default admin password in production mode")
 	}
 }
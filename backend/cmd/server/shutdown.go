@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// ShutdownConfig bounds each step of the API server's graceful shutdown. Each
+// step gets its own deadline, so a slow step can't eat the next one's time.
+type ShutdownConfig struct {
+	HTTPTimeout        time.Duration // HTTP_SHUTDOWN_TIMEOUT: in-flight requests finish
+	PrecomputerTimeout time.Duration // PRECOMPUTE_SHUTDOWN_TIMEOUT: background jobs requests left behind finish
+	EmailTimeout       time.Duration // EMAIL_SHUTDOWN_TIMEOUT: queued emails are sent
+}
+
+// shutdownStep is one service to stop. pending, when set, reports how much
+// work the service still holds; it is logged as abandoned if stop times out.
+type shutdownStep struct {
+	name    string
+	timeout time.Duration
+	stop    func(ctx context.Context) error
+	pending func() int
+}
+
+// runShutdown stops the steps one after another, each on a fresh context
+// with its own timeout. A step that fails or times out is logged and the
+// rest still run: an HTTP server that could not drain must not keep queued
+// emails from going out.
+func runShutdown(steps []shutdownStep) {
+	for _, step := range steps {
+		if step.stop == nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), step.timeout)
+		err := step.stop(ctx)
+		cancel()
+		if err == nil {
+			continue
+		}
+		attrs := []any{"step", step.name, "timeout", step.timeout, "error", err}
+		if step.pending != nil {
+			attrs = append(attrs, "abandoned", step.pending())
+		}
+		logger.Warn("shutdown step did not finish", attrs...)
+	}
+}
+
+// inFlightCounter counts requests being served, so a timed-out HTTP shutdown
+// can report how many it abandoned.
+type inFlightCounter struct {
+	n atomic.Int64
+}
+
+// wrap returns next, counted.
+func (c *inFlightCounter) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.n.Add(1)
+		defer c.n.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// count is the number of requests being served now.
+func (c *inFlightCounter) count() int {
+	return int(c.n.Load())
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeService records when it was stopped and, when blocking, holds its
+// stop until the context it was given ends.
+type fakeService struct {
+	name     string
+	blocking bool
+	order    *[]string
+	deadline time.Duration // time left on the stop context when called
+}
+
+func (f *fakeService) stop(ctx context.Context) error {
+	*f.order = append(*f.order, f.name)
+	if d, ok := ctx.Deadline(); ok {
+		f.deadline = time.Until(d)
+	}
+	if !f.blocking {
+		return nil
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRunShutdown_StopsInOrderWithSeparateTimeouts(t *testing.T) {
+	var order []string
+	httpSvc := &fakeService{name: "http", order: &order}
+	precompute := &fakeService{name: "precompute", order: &order}
+	emailSvc := &fakeService{name: "email", order: &order}
+
+	runShutdown([]shutdownStep{
+		{name: "http", timeout: time.Second, stop: httpSvc.stop},
+		{name: "precompute", timeout: 2 * time.Second, stop: precompute.stop},
+		{name: "email", timeout: 3 * time.Second, stop: emailSvc.stop},
+	})
+
+	if want := []string{"http", "precompute", "email"}; !slices.Equal(order, want) {
+		t.Fatalf("stop order = %v, want %v", order, want)
+	}
+	for _, c := range []struct {
+		svc     *fakeService
+		timeout time.Duration
+	}{{httpSvc, time.Second}, {precompute, 2 * time.Second}, {emailSvc, 3 * time.Second}} {
+		if c.svc.deadline <= c.timeout-100*time.Millisecond || c.svc.deadline > c.timeout {
+			t.Errorf("%s stop context deadline in %s, want about %s", c.svc.name, c.svc.deadline, c.timeout)
+		}
+	}
+}
+
+// A step that times out must not take time from, or stop, the ones after it.
+func TestRunShutdown_TimedOutStepDoesNotBlockLaterSteps(t *testing.T) {
+	var order []string
+	httpSvc := &fakeService{name: "http", blocking: true, order: &order}
+	emailSvc := &fakeService{name: "email", order: &order}
+	pendingCalls := 0
+
+	start := time.Now()
+	runShutdown([]shutdownStep{
+		{name: "http", timeout: 20 * time.Millisecond, stop: httpSvc.stop, pending: func() int {
+			pendingCalls++
+			return 3
+		}},
+		{name: "precompute", timeout: time.Second}, // nothing to stop
+		{name: "email", timeout: time.Second, stop: emailSvc.stop},
+	})
+
+	if want := []string{"http", "email"}; !slices.Equal(order, want) {
+		t.Fatalf("stop order = %v, want %v", order, want)
+	}
+	if pendingCalls != 1 {
+		t.Errorf("pending called %d times, want once for the timed-out step", pendingCalls)
+	}
+	if emailSvc.deadline < 900*time.Millisecond {
+		t.Errorf("email stop context deadline in %s, want its own full second", emailSvc.deadline)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("shutdown took %s, want about the http timeout", elapsed)
+	}
+}
+
+func TestRunShutdown_FailedStepSkipsPendingOnSuccess(t *testing.T) {
+	called := false
+	runShutdown([]shutdownStep{
+		{name: "ok", timeout: time.Second, stop: func(context.Context) error { return nil }, pending: func() int {
+			called = true
+			return 0
+		}},
+		{name: "failed", timeout: time.Second, stop: func(context.Context) error { return errors.New("boom") }},
+	})
+	if called {
+		t.Error("pending should only be asked for a step that did not finish")
+	}
+}
+
+func TestInFlightCounter_CountsRequestsBeingServed(t *testing.T) {
+	var c inFlightCounter
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	h := c.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		close(done)
+	}()
+	<-entered
+	if got := c.count(); got != 1 {
+		t.Errorf("count while serving = %d, want 1", got)
+	}
+	close(release)
+	<-done
+	if got := c.count(); got != 0 {
+		t.Errorf("count after serving = %d, want 0", got)
+	}
+}
@@ -45,24 +45,42 @@ func withFatalRecover(t *testing.T, fn func()) *fatalCalled {
 // blanks each one so tests start from a known clean slate.
 var serverEnvKeys = []string{
 	"PORT", "HTTP_READ_TIMEOUT", "HTTP_WRITE_TIMEOUT",
-	"AUTH_PASSWORD_ENABLED",
+	"HTTP_SHUTDOWN_TIMEOUT", "PRECOMPUTE_SHUTDOWN_TIMEOUT", "EMAIL_SHUTDOWN_TIMEOUT",
+	"AUTH_PASSWORD_ENABLED", "AUTH_EMAIL_LINK_ENABLED",
 	"GITHUB_CLIENT_ID", "GITHUB_CLIENT_SECRET", "GITHUB_REDIRECT_URL",
 	"GOOGLE_CLIENT_ID", "GOOGLE_CLIENT_SECRET", "GOOGLE_REDIRECT_URL",
 	"OIDC_ISSUER_URL", "OIDC_CLIENT_ID", "OIDC_CLIENT_SECRET",
 	"OIDC_REDIRECT_URL", "OIDC_DISPLAY_NAME",
 	"OAUTH_AUTO_LINK_EMAIL", "DEMO_IDENTITY_EMAIL", "SUPER_ADMIN_EMAILS",
 	"ALLOWED_EMAIL_DOMAINS", "CSRF_SECRET_KEY", "DATABASE_URL",
+	"DATABASE_REPLICA_URL", "DB_REPLICA_MAX_LAG_MS", "DB_SLOW_QUERY_LOG_THRESHOLD_MS",
 	"FRONTEND_URL", "ALLOWED_ORIGINS", "INSECURE_DEV_MODE",
 	"ADMIN_BOOTSTRAP_EMAIL", "ADMIN_BOOTSTRAP_PASSWORD",
 	"RESEND_API_KEY", "EMAIL_FROM_ADDRESS", "EMAIL_FROM_NAME",
-	"EMAIL_RATE_LIMIT_PER_HOUR",
+	"EMAIL_RATE_LIMIT_PER_HOUR", "EMAIL_WORKER_POOL_SIZE", "EMAIL_QUEUE_SIZE",
 	"ENABLE_PPROF", "SHARE_ALL_SESSIONS_TO_AUTHENTICATED",
 	"ENABLE_SHARE_CREATION", "ENABLE_SAAS_FOOTER", "ENABLE_SAAS_TERMLY",
 	"WORKER_POLL_INTERVAL", "WORKER_MAX_SESSIONS",
-	"WORKER_MAX_SEARCH_INDEX_SESSIONS", "WORKER_DRY_RUN",
-	"WORKER_SHARE_RETENTION",
+	"PRECOMPUTE_BASE_INTERVAL", "PRECOMPUTE_MIN_INTERVAL", "PRECOMPUTE_MAX_INTERVAL",
+	"PRECOMPUTE_MIN_BATCH_SIZE", "PRECOMPUTE_MAX_BATCH_SIZE",
+	"WORKER_MAX_SEARCH_INDEX_SESSIONS", "WORKER_DRY_RUN", "PRECOMPUTE_DORMANT_BATCH_SIZE",
+	"WORKER_SHARE_RETENTION", "WORKER_RECAP_CONCURRENCY", "WORKER_RECAP_MAX_PER_USER",
+	"WORKER_PRUNE_BATCH_SIZE", "WORKER_PRUNE_MAX_ROWS",
+	"WORKER_RETENTION_ADMIN_CARD_INVALIDATIONS", "WORKER_RETENTION_WEB_SESSIONS",
+	"WORKER_RETENTION_DEVICE_CODES", "WORKER_RETENTION_API_KEY_SESSION_VELOCITY",
+	"WORKER_RETENTION_CHUNK_UPLOAD_EVENTS", "WORKER_RETENTION_WEBHOOK_DELIVERIES",
+	"WORKER_RETENTION_MAGIC_LINK_REDEMPTIONS", "WORKER_RETENTION_SHARE_ACCESS_LOG",
+	"WORKER_RETENTION_SESSION_STATE_LOG", "WORKER_RETENTION_IDEMPOTENCY_KEYS",
+	"WORKER_RETENTION_API_KEY_USAGE",
+	"WORKER_CHUNK_RECONCILE_AFTER", "WORKER_CHUNK_RECONCILE_BATCH",
+	"WORKER_TRANSCRIPT_RETENTION", "WORKER_TRANSCRIPT_ARCHIVE_BATCH",
+	"WORKER_STORED_BYTES_BACKFILL_BATCH", "WORKER_DATA_EXPORT_BATCH", "WORKER_DATA_EXPORT_TTL",
+	"WORKER_SESSION_IDLE_AFTER", "WORKER_SESSION_ENDED_AFTER", "WORKER_SESSION_SWEEP_BATCH",
+	"WORKER_NOTIFICATION_FLUSH_BATCH",
+	"WORKER_SHUTDOWN_TIMEOUT",
+	"WEBHOOK_POLL_INTERVAL", "WEBHOOK_MAX_ATTEMPTS", "WEBHOOK_ALLOW_PRIVATE_TARGETS",
 	"S3_ENDPOINT", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY",
-	"BUCKET_NAME", "S3_USE_SSL",
+	"BUCKET_NAME", "S3_USE_SSL", "S3_READ_FAILOVER_ENDPOINT", "S3_READ_FAILOVER_BUCKET",
 	"SMART_RECAP_ENABLED", "ANTHROPIC_API_KEY", "SMART_RECAP_MODEL",
 	"SMART_RECAP_QUOTA_LIMIT", "SMART_RECAP_MAX_OUTPUT_TOKENS",
 	"SMART_RECAP_MAX_TRANSCRIPT_TOKENS",
@@ -71,7 +89,7 @@ var serverEnvKeys = []string{
 	"WORKER_REGULAR_MIN_SESSION_AGE",
 	"WORKER_RECAP_THRESHOLD_PCT", "WORKER_RECAP_BASE_MIN_LINES",
 	"WORKER_RECAP_BASE_MIN_TIME", "WORKER_RECAP_MIN_INITIAL_LINES",
-	"WORKER_RECAP_MIN_SESSION_AGE",
+	"WORKER_RECAP_MIN_SESSION_AGE", "WORKER_RECAP_MIN_INTERVAL",
 }
 
 // clearServerEnv sets every server-related env var to "" via t.Setenv (which
@@ -84,8 +102,8 @@ func clearServerEnv(t *testing.T) {
 	}
 }
 
-// setRequiredServerEnv populates the minimum env for loadConfig() to succeed.
-// loadConfig calls loadS3Config internally, so S3 vars are seeded too.
+// setRequiredServerEnv populates the minimum env for ParseConfig(os.Getenv) to
+// succeed. ParseConfig checks the S3 vars too, so they are seeded as well.
 func setRequiredServerEnv(t *testing.T) {
 	t.Helper()
 	t.Setenv("CSRF_SECRET_KEY", "this-is-a-32-character-long-secret-key")
@@ -101,19 +119,24 @@ func setRequiredServerEnv(t *testing.T) {
 
 // fakePrecomputer satisfies precomputerAPI with per-test function-field overrides.
 // Unset Fn fields return (zero, nil). The recorded *Calls slices let tests assert
-// what the Worker invoked, in order. Worker.Run is single-goroutine and tests run
-// serially, so no synchronization is needed.
+// what the Worker invoked, in order. Tests run serially and, with the default
+// RecapConcurrency of 1, smart recap calls are serialized through the dispatch
+// channel, so no synchronization is needed.
 type fakePrecomputer struct {
 	findStaleFn       func(context.Context, int) ([]analytics.StaleSession, error)
 	findSmartRecapFn  func(context.Context, int) ([]analytics.StaleSession, error)
 	findSearchIndexFn func(context.Context, int) ([]analytics.StaleSession, error)
+	findDormantFn     func(context.Context, int) ([]analytics.StaleSession, error)
 	precomputeRegFn   func(context.Context, analytics.StaleSession) error
 	precomputeRecapFn func(context.Context, analytics.StaleSession) error
 	buildSearchIdxFn  func(context.Context, analytics.StaleSession) error
+	refreshPricingFn  func(context.Context) error
 
 	findStaleCalls       int
 	findSmartRecapCalls  int
 	findSearchIndexCalls int
+	findDormantCalls     int
+	refreshPricingCalls  int
 
 	regularCalls   []analytics.StaleSession
 	recapCalls     []analytics.StaleSession
@@ -144,6 +167,14 @@ func (f *fakePrecomputer) FindStaleSearchIndexSessions(ctx context.Context, limi
 	return nil, nil
 }
 
+func (f *fakePrecomputer) FindDormantStaleSessions(ctx context.Context, limit int) ([]analytics.StaleSession, error) {
+	f.findDormantCalls++
+	if f.findDormantFn != nil {
+		return f.findDormantFn(ctx, limit)
+	}
+	return nil, nil
+}
+
 func (f *fakePrecomputer) PrecomputeRegularCards(ctx context.Context, session analytics.StaleSession) error {
 	f.regularCalls = append(f.regularCalls, session)
 	if f.precomputeRegFn != nil {
@@ -167,3 +198,11 @@ func (f *fakePrecomputer) BuildSearchIndexOnly(ctx context.Context, session anal
 	}
 	return nil
 }
+
+func (f *fakePrecomputer) RefreshModelPricing(ctx context.Context) error {
+	f.refreshPricingCalls++
+	if f.refreshPricingFn != nil {
+		return f.refreshPricingFn(ctx)
+	}
+	return nil
+}
@@ -2,33 +2,91 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/api"
 	"github.com/ConfabulousDev/confab-web/internal/db"
 	"github.com/ConfabulousDev/confab-web/internal/db/access"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbdataexport"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbnotify"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbretention"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbwebhook"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	dbuser "github.com/ConfabulousDev/confab-web/internal/db/user"
+	"github.com/ConfabulousDev/confab-web/internal/email"
 	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/notify"
 	"github.com/ConfabulousDev/confab-web/internal/pricingsource"
 	"github.com/ConfabulousDev/confab-web/internal/storage"
+	"github.com/ConfabulousDev/confab-web/internal/webhooks"
 	"github.com/honeycombio/otel-config-go/otelconfig"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var workerTracer = otel.Tracer("confab/worker")
 
 // WorkerConfig holds configuration for the analytics precompute worker.
 type WorkerConfig struct {
-	PollInterval           time.Duration
-	MaxSessions            int           // Maximum sessions to query per cycle (regular cards + smart recap)
+	PollInterval           time.Duration // Base wait between cycles (default 30m)
+	MinPollInterval        time.Duration // Wait after a cycle that found a backlog (default PollInterval)
+	MaxPollInterval        time.Duration // Longest wait after an idle cycle (default PollInterval)
+	MaxSessions            int           // Sessions queried per bucket in the first cycle (regular cards + smart recap)
+	MinBatchSize           int           // Smallest adaptive batch (default MaxSessions)
+	MaxBatchSize           int           // Largest adaptive batch (default MaxSessions)
 	MaxSearchIndexSessions int           // Maximum search index sessions per cycle (defaults to MaxSessions if 0)
+	DormantBatchSize       int           // Dormant sessions precomputed when the other buckets are empty (default 5); 0 disables
 	DryRun                 bool          // If true, log what would be done without actually precomputing
 	ShareRetention         time.Duration // Expired shares older than this are physically deleted each cycle
+	RecapConcurrency       int           // Smart recap generations run in parallel per cycle (default 1)
+	RecapMaxPerUser        int           // Smart recap generations in flight per user (default 1)
+
+	RetentionPolicies []dbretention.Policy // Tables pruned each cycle; a zero Retention skips the table
+	PruneBatchSize    int                  // Rows deleted per statement when pruning (default 1000)
+	PruneMaxRows      int                  // Rows deleted per table per cycle (default 10000)
+
+	TranscriptRetention    time.Duration // Raw chunks of sessions idle longer than this are deleted; 0 disables (default)
+	TranscriptArchiveBatch int           // Sessions archived per cycle (default 100)
+
+	StoredBytesBackfillBatch int // Sessions whose legacy stored_bytes are backfilled per cycle (default 100); 0 skips the step
+
+	ChunkReconcileAfter time.Duration // Chunk uploads pending this long are settled (default 5m)
+	ChunkReconcileBatch int           // Pending chunk uploads settled per cycle (default 500); 0 skips the step
+
+	DataExportBatch int           // Requested data exports built per cycle (default 2); 0 skips the step
+	DataExportTTL   time.Duration // How long a built export stays downloadable (default 72h, at most 7 days)
+
+	SessionIdleAfter  time.Duration // Active sessions unsynced this long go idle (default 30m); 0 disables
+	SessionEndedAfter time.Duration // Active/idle sessions unsynced this long end (default 24h); 0 disables
+	SessionSweepBatch int           // Sessions moved per state per cycle (default 500); 0 skips the sweep
+
+	NotificationFlushBatch int // Notifications held back by quiet hours sent per cycle (default 200); 0 skips the step
+
+	ShutdownTimeout time.Duration // How long in-flight sessions may finish after shutdown (default 10s); 0 aborts them
+}
+
+// dataExportMailer is the email the worker sends when a data export is built.
+// *email.ResendService satisfies it; nil disables the email (the export is
+// still listed by GET /api/v1/me/export).
+type dataExportMailer interface {
+	SendDataExportReady(ctx context.Context, params email.DataExportReadyParams) error
+}
+
+// notificationFlusher sends the notifications whose quiet hours have ended.
+// *notify.Dispatcher satisfies it.
+type notificationFlusher interface {
+	FlushDue(ctx context.Context, limit int) (int, error)
 }
 
 // precomputerAPI is the narrow surface Worker calls on the precomputer.
@@ -38,9 +96,11 @@ type precomputerAPI interface {
 	FindStaleSessions(ctx context.Context, limit int) ([]analytics.StaleSession, error)
 	FindStaleSmartRecapSessions(ctx context.Context, limit int) ([]analytics.StaleSession, error)
 	FindStaleSearchIndexSessions(ctx context.Context, limit int) ([]analytics.StaleSession, error)
+	FindDormantStaleSessions(ctx context.Context, limit int) ([]analytics.StaleSession, error)
 	PrecomputeRegularCards(ctx context.Context, session analytics.StaleSession) error
 	PrecomputeSmartRecapOnly(ctx context.Context, session analytics.StaleSession) error
 	BuildSearchIndexOnly(ctx context.Context, session analytics.StaleSession) error
+	RefreshModelPricing(ctx context.Context) error
 }
 
 // Worker is the background analytics precompute worker.
@@ -50,6 +110,17 @@ type Worker struct {
 	precomputer   precomputerAPI
 	config        WorkerConfig
 	pricingSource *pricingsource.Source // refreshes the active price table each cycle
+	mailer        dataExportMailer      // nil when email is not configured
+	notifier      notificationFlusher   // nil skips the notification flush
+
+	// recapInFlight counts smart recap generations currently running per user.
+	// Only the dispatch loop in processSmartRecapSessions reads or writes it
+	// (workers report completion over a channel), so it needs no lock.
+	recapInFlight map[int64]int
+
+	// batchSize is the limit passed to the regular and smart recap lookups.
+	// Run adjusts it after every cycle; zero means MaxSessions.
+	batchSize int
 }
 
 // runWorker is the entry point for the background worker process.
@@ -68,11 +139,35 @@ func runWorker() {
 	workerConfig := loadWorkerConfig()
 	logger.Info("worker configuration loaded",
 		"poll_interval", workerConfig.PollInterval,
+		"min_poll_interval", workerConfig.MinPollInterval,
+		"max_poll_interval", workerConfig.MaxPollInterval,
 		"max_sessions", workerConfig.MaxSessions,
+		"min_batch_size", workerConfig.MinBatchSize,
+		"max_batch_size", workerConfig.MaxBatchSize,
 		"max_search_index_sessions", workerConfig.MaxSearchIndexSessions,
+		"dormant_batch_size", workerConfig.DormantBatchSize,
 		"dry_run", workerConfig.DryRun,
 		"share_retention", workerConfig.ShareRetention,
+		"recap_concurrency", workerConfig.RecapConcurrency,
+		"recap_max_per_user", workerConfig.RecapMaxPerUser,
+		"prune_batch_size", workerConfig.PruneBatchSize,
+		"prune_max_rows", workerConfig.PruneMaxRows,
+		"transcript_retention", workerConfig.TranscriptRetention,
+		"transcript_archive_batch", workerConfig.TranscriptArchiveBatch,
+		"stored_bytes_backfill_batch", workerConfig.StoredBytesBackfillBatch,
+		"chunk_reconcile_after", workerConfig.ChunkReconcileAfter,
+		"chunk_reconcile_batch", workerConfig.ChunkReconcileBatch,
+		"data_export_batch", workerConfig.DataExportBatch,
+		"data_export_ttl", workerConfig.DataExportTTL,
+		"session_idle_after", workerConfig.SessionIdleAfter,
+		"session_ended_after", workerConfig.SessionEndedAfter,
+		"session_sweep_batch", workerConfig.SessionSweepBatch,
+		"notification_flush_batch", workerConfig.NotificationFlushBatch,
+		"shutdown_timeout", workerConfig.ShutdownTimeout,
 	)
+	for _, p := range workerConfig.RetentionPolicies {
+		logger.Info("retention policy", "table", p.Table, "retention", p.Retention)
+	}
 
 	if workerConfig.DryRun {
 		logger.Info("DRY-RUN MODE ENABLED - no sessions will be precomputed")
@@ -106,6 +201,7 @@ func runWorker() {
 		"enabled", precomputeConfig.SmartRecapEnabled,
 		"model", precomputeConfig.SmartRecapModel,
 		"quota", precomputeConfig.SmartRecapQuota,
+		"warm_active_window", precomputeConfig.WarmActiveWindow,
 	)
 
 	// Create analytics store and precomputer
@@ -121,6 +217,13 @@ func runWorker() {
 		config:        workerConfig,
 		pricingSource: pricingsource.NewFromEnv(os.Getenv("ENABLE_SAAS_FOOTER") == "true"),
 	}
+	mailer := loadWorkerMailer()
+	if mailer != nil {
+		worker.mailer = mailer
+	} else {
+		logger.Info("data export and notification emails disabled (RESEND_API_KEY or EMAIL_FROM_ADDRESS not set)")
+	}
+	worker.notifier = newWorkerNotifier(database, mailer)
 
 	// Setup graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -131,38 +234,134 @@ func runWorker() {
 
 	go func() {
 		<-quit
-		logger.Info("shutdown signal received, stopping worker")
+		logger.Info("shutdown signal received, stopping worker",
+			"shutdown_timeout", workerConfig.ShutdownTimeout)
 		cancel()
 	}()
 
+	// Webhook deliveries run on their own loop: events should go out within
+	// seconds, not at the precompute cadence. Skipped in dry-run.
+	if !workerConfig.DryRun {
+		dispatcher := webhooks.NewDispatcher(&dbwebhook.Store{DB: database}, loadWebhookConfig())
+		go dispatcher.Run(ctx)
+	}
+
 	// Run the worker
 	worker.Run(ctx)
 	logger.Info("worker stopped")
 }
 
-// Run executes the main worker loop.
+// Run executes the main worker loop. The first cycle runs immediately; each
+// later one waits an interval chosen by adaptivePollInterval from how many
+// stale sessions the previous cycle found, and queries a batch sized by
+// adaptBatchSize.
 func (w *Worker) Run(ctx context.Context) {
-	ticker := time.NewTicker(w.config.PollInterval)
-	defer ticker.Stop()
-
-	// Run immediately on startup
-	w.runOnce(ctx)
+	base, lo, hi := w.config.PollInterval, w.config.MinPollInterval, w.config.MaxPollInterval
+	// Unset bounds (a config not built by loadWorkerConfig) mean a fixed schedule.
+	if lo <= 0 {
+		lo = base
+	}
+	if hi <= 0 {
+		hi = base
+	}
 
+	interval := base
 	for {
+		next := base
+		start := time.Now()
+		if cycle, ok := w.runOnce(ctx); ok {
+			next = adaptivePollInterval(cycle.stale, w.config.RecapConcurrency, base, lo, hi)
+			w.adaptBatchSize(cycle, time.Since(start))
+		}
+		if next != interval {
+			logger.Info("poll interval changed", "from", interval, "to", next)
+			interval = next
+		}
+
+		timer := time.NewTimer(interval)
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			return
-		case <-ticker.C:
-			w.runOnce(ctx)
+		case <-timer.C:
 		}
 	}
 }
 
+// adaptivePollInterval picks the wait before the next cycle. A backlog of
+// more than two sessions per concurrency slot means the worker is behind, so
+// it polls again after minInterval. An idle cycle doubles baseInterval, up to
+// maxInterval. Anything in between keeps baseInterval. The result always lies
+// within [minInterval, maxInterval].
+func adaptivePollInterval(staleSessions, maxConcurrency int, baseInterval, minInterval, maxInterval time.Duration) time.Duration {
+	next := baseInterval
+	switch {
+	case staleSessions > max(maxConcurrency, 1)*2:
+		next = minInterval
+	case staleSessions == 0:
+		next = 2 * baseInterval
+	}
+	return min(max(next, minInterval), maxInterval)
+}
+
+// cycleResult summarizes what a precompute cycle found.
+type cycleResult struct {
+	stale   int // stale sessions across the buckets, dormant excluded
+	largest int // most sessions returned by one batch-sized lookup
+}
+
+// currentBatchSize is the limit for the regular and smart recap lookups.
+func (w *Worker) currentBatchSize() int {
+	if w.batchSize > 0 {
+		return w.batchSize
+	}
+	return w.config.MaxSessions
+}
+
+// adaptBatchSize sets the batch for the next cycle from the one just run,
+// within [MinBatchSize, MaxBatchSize]. Unset bounds mean a fixed batch.
+func (w *Worker) adaptBatchSize(cycle cycleResult, elapsed time.Duration) {
+	current := w.currentBatchSize()
+	lo, hi := w.config.MinBatchSize, w.config.MaxBatchSize
+	if lo <= 0 {
+		lo = w.config.MaxSessions
+	}
+	if hi <= 0 {
+		hi = w.config.MaxSessions
+	}
+	next := adaptiveBatchSize(cycle.largest, current, elapsed, w.config.PollInterval, lo, hi)
+	if next != current {
+		logger.Info("batch size changed", "from", current, "to", next)
+	}
+	w.batchSize = next
+}
+
+// adaptiveBatchSize picks the next batch size. A cycle that took longer than
+// baseInterval was too much work, so the batch halves. Otherwise a lookup
+// that came back full means the backlog is at least a batch deep, so the
+// batch doubles; an idle cycle halves it. Anything in between keeps it. The
+// result always lies within [minBatch, maxBatch].
+func adaptiveBatchSize(found, current int, elapsed, baseInterval time.Duration, minBatch, maxBatch int) int {
+	next := current
+	switch {
+	case baseInterval > 0 && elapsed > baseInterval:
+		next = current / 2
+	case found >= current:
+		next = current * 2
+	case found == 0:
+		next = current / 2
+	}
+	return min(max(next, minBatch), maxBatch)
+}
+
 // runOnce executes a single precomputation cycle.
 // It processes two independent buckets:
 // 1. Sessions with stale regular cards (computes regular cards only)
 // 2. Sessions with stale smart recap but fresh regular cards (computes smart recap only)
-func (w *Worker) runOnce(ctx context.Context) {
+//
+// It returns what the buckets held (dormant sessions excluded), and ok=false
+// when a bucket lookup failed.
+func (w *Worker) runOnce(ctx context.Context) (cycle cycleResult, ok bool) {
 	ctx, span := workerTracer.Start(ctx, "worker.run_once")
 	defer span.End()
 
@@ -172,6 +371,12 @@ func (w *Worker) runOnce(ctx context.Context) {
 	// timeout) so newly computed cards cost out at the freshest prices without a
 	// backend redeploy. Always returns a valid table (embedded floor at worst).
 	analytics.SetActivePricing(w.pricingSource.Effective(ctx))
+	// model_pricing rows take precedence over that table for the families
+	// they list. On failure the previously loaded rows stay in effect.
+	if err := w.precomputer.RefreshModelPricing(ctx); err != nil {
+		logger.Warn("failed to refresh model pricing", "error", err)
+		span.RecordError(err)
+	}
 
 	// Housekeeping: physically delete shares that have been expired longer than
 	// the retention window. Runs every tick (before the stale-session buckets,
@@ -191,22 +396,71 @@ func (w *Worker) runOnce(ctx context.Context) {
 		}
 	}
 
+	// Housekeeping: prune rows past each table's retention window. Same rules
+	// as share cleanup above: every tick, skipped in dry-run, best-effort.
+	if !w.config.DryRun {
+		w.pruneRetention(ctx, span)
+	}
+
+	// Housekeeping: settle chunk uploads whose sync-state update never
+	// landed. Same rules again; the first cycle runs at startup, so uploads
+	// orphaned by a crash are picked up as soon as the worker restarts.
+	if !w.config.DryRun && w.config.ChunkReconcileBatch > 0 {
+		w.reconcileChunkUploads(ctx, span)
+	}
+
+	// Housekeeping: move sessions that stopped syncing to idle, then ended.
+	// Same rules again; runs before archival so the log reads in lifecycle
+	// order.
+	if !w.config.DryRun && w.config.SessionSweepBatch > 0 {
+		w.sweepSessionStates(ctx, span)
+	}
+
+	// Housekeeping: delete the raw chunks of sessions idle past the transcript
+	// retention window, keeping their cards and search index. Same rules again;
+	// off unless WORKER_TRANSCRIPT_RETENTION is set.
+	if !w.config.DryRun && w.config.TranscriptRetention > 0 {
+		w.archiveTranscripts(ctx, span)
+	}
+
+	// Housekeeping: fill in stored_bytes for files synced before storage
+	// accounting. Same rules again; finds nothing once the backlog is gone.
+	if !w.config.DryRun && w.config.StoredBytesBackfillBatch > 0 {
+		w.backfillStoredBytes(ctx, span)
+	}
+
+	// Housekeeping: build requested data exports and delete expired ones.
+	// Same rules again.
+	if !w.config.DryRun && w.config.DataExportBatch > 0 {
+		w.processDataExports(ctx, span)
+	}
+
+	// Housekeeping: send notifications held back by quiet hours whose window
+	// has ended. Same rules again; the poll interval bounds how late they go
+	// out.
+	if !w.config.DryRun && w.config.NotificationFlushBatch > 0 && w.notifier != nil {
+		w.flushNotifications(ctx, span)
+	}
+
+	batchSize := w.currentBatchSize()
+	span.SetAttributes(attribute.Int("sessions.batch_size", batchSize))
+
 	// Bucket 1: Find sessions with stale regular cards
-	regularSessions, err := w.precomputer.FindStaleSessions(ctx, w.config.MaxSessions)
+	regularSessions, err := w.precomputer.FindStaleSessions(ctx, batchSize)
 	if err != nil {
 		logger.Error("failed to find stale sessions", "error", err)
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return
+		return cycleResult{}, false
 	}
 
 	// Bucket 2: Find sessions with only stale smart recap (regular cards up-to-date)
-	smartRecapSessions, err := w.precomputer.FindStaleSmartRecapSessions(ctx, w.config.MaxSessions)
+	smartRecapSessions, err := w.precomputer.FindStaleSmartRecapSessions(ctx, batchSize)
 	if err != nil {
 		logger.Error("failed to find stale smart recap sessions", "error", err)
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return
+		return cycleResult{}, false
 	}
 
 	// Bucket 3: Find sessions with stale search index (regular cards up-to-date)
@@ -215,10 +469,11 @@ func (w *Worker) runOnce(ctx context.Context) {
 		logger.Error("failed to find stale search index sessions", "error", err)
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return
+		return cycleResult{}, false
 	}
 
 	totalFound := len(regularSessions) + len(smartRecapSessions) + len(searchIndexSessions)
+	cycle = cycleResult{stale: totalFound, largest: max(len(regularSessions), len(smartRecapSessions))}
 	if totalFound == 0 {
 		logger.Info("no stale sessions found")
 		span.SetAttributes(
@@ -226,7 +481,12 @@ func (w *Worker) runOnce(ctx context.Context) {
 			attribute.Int("sessions.smart_recap.found", 0),
 			attribute.Int("sessions.search_index.found", 0),
 		)
-		return
+		// Bucket 4: only with nothing else to do, catch up dormant sessions
+		// whose cards stopped short of the thresholds above.
+		if w.config.DormantBatchSize > 0 {
+			w.processDormantSessions(ctx, span)
+		}
+		return cycleResult{}, true
 	}
 
 	logger.Info("found stale sessions",
@@ -277,7 +537,7 @@ func (w *Worker) runOnce(ctx context.Context) {
 			attribute.Int("sessions.smart_recap.would_process", len(smartRecapSessions)),
 			attribute.Int("sessions.search_index.would_process", len(searchIndexSessions)),
 		)
-		return
+		return cycle, true
 	}
 
 	// Process Bucket 1: Sessions with stale regular cards
@@ -305,6 +565,365 @@ func (w *Worker) runOnce(ctx context.Context) {
 		attribute.Int("sessions.search_index.processed", searchIndexProcessed),
 		attribute.Int("sessions.search_index.errors", searchIndexErrors),
 	)
+	return cycle, true
+}
+
+// pruneRetention deletes rows older than each policy's retention window, in
+// batches of PruneBatchSize and at most PruneMaxRows per table, and records
+// every run in retention_prune_runs. A table that still has a backlog after
+// hitting the cap continues on the next tick. Safe to run on every worker
+// instance at once: concurrent prunes skip each other's locked rows.
+func (w *Worker) pruneRetention(ctx context.Context, span trace.Span) {
+	store := &dbretention.Store{DB: w.db}
+	for _, p := range w.config.RetentionPolicies {
+		if p.Retention <= 0 {
+			continue
+		}
+		deleted, err := store.Prune(ctx, p, w.config.PruneBatchSize, w.config.PruneMaxRows)
+		if err != nil {
+			logger.Error("failed to prune table", "table", p.Table, "deleted", deleted, "error", err)
+			span.RecordError(err)
+			continue
+		}
+		if err := store.RecordRun(ctx, p, deleted); err != nil {
+			logger.Error("failed to record prune run", "table", p.Table, "error", err)
+			span.RecordError(err)
+		}
+		if deleted > 0 {
+			logger.Info("pruned rows past retention",
+				"table", p.Table,
+				"count", deleted,
+				"retention", p.Retention,
+				"capped", deleted >= int64(w.config.PruneMaxRows),
+			)
+		}
+		span.SetAttributes(attribute.Int64("retention."+p.Table+".deleted", deleted))
+	}
+}
+
+// reconcileChunkUploads settles up to ChunkReconcileBatch chunk upload
+// events still pending ChunkReconcileAfter after their upload started: the
+// chunk handler records one before each S3 upload and confirms it after the
+// sync-state update, so a pending one may be an object S3 holds but
+// sync_files does not. See dbsession.ReconcileChunkUpload for the outcomes.
+// An event that fails stays pending and is retried on a later tick.
+func (w *Worker) reconcileChunkUploads(ctx context.Context, span trace.Span) {
+	sessionStore := &dbsession.Store{DB: w.db}
+	ids, err := sessionStore.ListStaleChunkUploads(ctx, w.config.ChunkReconcileAfter, w.config.ChunkReconcileBatch)
+	if err != nil {
+		logger.Error("failed to list pending chunk uploads", "error", err)
+		span.RecordError(err)
+		return
+	}
+
+	settled := make(map[string]int)
+	var failed int
+	for _, id := range ids {
+		resolution, err := sessionStore.ReconcileChunkUpload(ctx, id, w.store)
+		if err != nil {
+			logger.Error("failed to reconcile chunk upload", "upload_event_id", id, "error", err)
+			span.RecordError(err)
+			failed++
+			continue
+		}
+		if resolution != "" {
+			settled[resolution]++
+		}
+	}
+
+	if len(settled) > 0 || failed > 0 {
+		logger.Info("reconciled pending chunk uploads",
+			"replayed", settled[dbsession.ChunkUploadReplayed],
+			"superseded", settled[dbsession.ChunkUploadSuperseded],
+			"discarded", settled[dbsession.ChunkUploadDiscarded],
+			"missing", settled[dbsession.ChunkUploadMissing],
+			"failed", failed,
+		)
+	}
+	span.SetAttributes(
+		attribute.Int("chunk_uploads.replayed", settled[dbsession.ChunkUploadReplayed]),
+		attribute.Int("chunk_uploads.superseded", settled[dbsession.ChunkUploadSuperseded]),
+		attribute.Int("chunk_uploads.discarded", settled[dbsession.ChunkUploadDiscarded]),
+		attribute.Int("chunk_uploads.missing", settled[dbsession.ChunkUploadMissing]),
+		attribute.Int("chunk_uploads.reconcile_failed", failed),
+	)
+}
+
+// archiveTranscripts finds up to TranscriptArchiveBatch sessions whose last
+// sync is older than TranscriptRetention (and whose cards and search index
+// cover the whole transcript), then claims each one and deletes its S3
+// chunks. The claim marks the session archived first, so raw reads and
+// uploads answer 410 before the chunks disappear, and it holds the sync
+// files' upload locks until the delete is done, so no chunk lands in between;
+// a session with an upload in flight is left for a later tick. A session
+// whose delete fails is released and retried on a later tick.
+func (w *Worker) archiveTranscripts(ctx context.Context, span trace.Span) {
+	sessionStore := &dbsession.Store{DB: w.db}
+	candidates, err := sessionStore.FindTranscriptArchiveCandidates(ctx, w.config.TranscriptRetention, w.config.TranscriptArchiveBatch)
+	if err != nil {
+		logger.Error("failed to find transcript archive candidates", "error", err)
+		span.RecordError(err)
+		return
+	}
+
+	var archived, failed int
+	for _, sessionID := range candidates {
+		a, release, claimed, err := sessionStore.ClaimTranscriptArchive(ctx, sessionID, w.config.TranscriptRetention)
+		if err != nil {
+			logger.Error("failed to claim transcript archive", "session_id", sessionID, "error", err)
+			span.RecordError(err)
+			failed++
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if err := w.store.DeleteAllSessionChunks(ctx, a.UserID, a.Provider, a.ExternalID); err != nil {
+			logger.Error("failed to delete archived transcript chunks",
+				"session_id", a.SessionID,
+				"error", err,
+			)
+			span.RecordError(err)
+			failed++
+			if err := sessionStore.ReleaseTranscriptArchive(context.WithoutCancel(ctx), a.SessionID); err != nil {
+				logger.Error("failed to release transcript archive", "session_id", a.SessionID, "error", err)
+			}
+			release()
+			continue
+		}
+		release()
+		archived++
+		if _, err := sessionStore.TransitionState(ctx, a.SessionID, dbsession.StateArchived, dbsession.StateReasonTranscriptRetention); err != nil {
+			logger.Error("failed to mark session archived", "session_id", a.SessionID, "error", err)
+			span.RecordError(err)
+		}
+	}
+
+	if archived > 0 || failed > 0 {
+		logger.Info("archived session transcripts",
+			"count", archived,
+			"failed", failed,
+			"retention", w.config.TranscriptRetention,
+		)
+	}
+	span.SetAttributes(
+		attribute.Int("transcripts.archived", archived),
+		attribute.Int("transcripts.archive_failed", failed),
+	)
+}
+
+// sweepSessionStates ends sessions unsynced for SessionEndedAfter and marks
+// those unsynced for SessionIdleAfter idle, up to SessionSweepBatch of each.
+// A session that syncs again goes back to active on its next chunk upload.
+func (w *Worker) sweepSessionStates(ctx context.Context, span trace.Span) {
+	idled, ended, err := (&dbsession.Store{DB: w.db}).SweepIdleSessions(ctx,
+		w.config.SessionIdleAfter, w.config.SessionEndedAfter, w.config.SessionSweepBatch)
+	if err != nil {
+		logger.Error("failed to sweep session states", "error", err)
+		span.RecordError(err)
+	}
+	if idled > 0 || ended > 0 {
+		logger.Info("swept session states", "idle", idled, "ended", ended)
+	}
+	span.SetAttributes(
+		attribute.Int("sessions.state.idled", idled),
+		attribute.Int("sessions.state.ended", ended),
+	)
+}
+
+// backfillStoredBytes sizes the chunks of up to StoredBytesBackfillBatch
+// sessions whose sync files predate storage accounting and records the totals
+// in stored_bytes. A session whose listing fails keeps NULL and is retried on
+// a later tick.
+func (w *Worker) backfillStoredBytes(ctx context.Context, span trace.Span) {
+	sessionStore := &dbsession.Store{DB: w.db}
+	backfills, err := sessionStore.ListStoredBytesBackfills(ctx, w.config.StoredBytesBackfillBatch)
+	if err != nil {
+		logger.Error("failed to list stored bytes backfills", "error", err)
+		span.RecordError(err)
+		return
+	}
+
+	var filled, failed int
+	for _, b := range backfills {
+		usage, err := w.store.SessionStoredBytes(ctx, b.UserID, b.Provider, b.ExternalID)
+		if err == nil {
+			generations := make(map[dbsession.StoredBytesGeneration]int64, len(usage.Generations))
+			for g, size := range usage.Generations {
+				generations[dbsession.StoredBytesGeneration{FileName: g.FileName, Generation: g.Generation}] = size
+			}
+			err = sessionStore.BackfillStoredBytes(ctx, b, usage.Files, generations)
+		}
+		if err != nil {
+			logger.Error("failed to backfill stored bytes", "session_id", b.SessionID, "error", err)
+			span.RecordError(err)
+			failed++
+			continue
+		}
+		filled++
+	}
+
+	if filled > 0 || failed > 0 {
+		logger.Info("backfilled session stored bytes", "count", filled, "failed", failed)
+	}
+	span.SetAttributes(
+		attribute.Int("stored_bytes.backfilled", filled),
+		attribute.Int("stored_bytes.backfill_failed", failed),
+	)
+}
+
+// dataExportStaleAfter is how long an export may sit in processing before
+// another cycle assumes its worker died and builds it again.
+const dataExportStaleAfter = time.Hour
+
+// processDataExports builds up to DataExportBatch requested data exports,
+// then deletes the archives of exports past their expiry. Each archive is
+// written to a temp file (so its size is known for the upload), stored in S3,
+// marked ready and announced by email with a presigned link. A failed build
+// is marked failed, which frees the user to request another.
+func (w *Worker) processDataExports(ctx context.Context, span trace.Span) {
+	exportStore := &dbdataexport.Store{DB: w.db}
+
+	var built, failed int
+	for range w.config.DataExportBatch {
+		export, err := exportStore.Claim(ctx, dataExportStaleAfter)
+		if err != nil {
+			logger.Error("failed to claim data export", "error", err)
+			span.RecordError(err)
+			break
+		}
+		if export == nil {
+			break
+		}
+		if err := w.buildDataExport(ctx, exportStore, export); err != nil {
+			logger.Error("failed to build data export",
+				"export_id", export.ID,
+				"user_id", export.UserID,
+				"error", err,
+			)
+			span.RecordError(err)
+			failed++
+			if err := exportStore.MarkFailed(context.WithoutCancel(ctx), export.ID, err.Error()); err != nil {
+				logger.Error("failed to mark data export failed", "export_id", export.ID, "error", err)
+			}
+			continue
+		}
+		built++
+	}
+
+	expired, err := exportStore.ListExpired(ctx, max(w.config.DataExportBatch, 100))
+	if err != nil {
+		logger.Error("failed to list expired data exports", "error", err)
+		span.RecordError(err)
+	}
+	var deleted int
+	for _, e := range expired {
+		if e.ObjectKey != nil {
+			if err := w.store.Delete(ctx, *e.ObjectKey); err != nil {
+				logger.Error("failed to delete expired data export", "export_id", e.ID, "error", err)
+				span.RecordError(err)
+				continue
+			}
+		}
+		if err := exportStore.MarkExpired(ctx, e.ID); err != nil {
+			logger.Error("failed to mark data export expired", "export_id", e.ID, "error", err)
+			span.RecordError(err)
+			continue
+		}
+		deleted++
+	}
+
+	if built > 0 || failed > 0 || deleted > 0 {
+		logger.Info("processed data exports", "built", built, "failed", failed, "expired", deleted)
+	}
+	span.SetAttributes(
+		attribute.Int("data_exports.built", built),
+		attribute.Int("data_exports.failed", failed),
+		attribute.Int("data_exports.expired", deleted),
+	)
+}
+
+// flushNotifications sends up to NotificationFlushBatch notifications whose
+// user's quiet hours have ended, oldest window first. Failed sends stay
+// queued for a later cycle (notify.Dispatcher.FlushDue).
+func (w *Worker) flushNotifications(ctx context.Context, span trace.Span) {
+	sent, err := w.notifier.FlushDue(ctx, w.config.NotificationFlushBatch)
+	if err != nil {
+		logger.Error("failed to flush queued notifications", "error", err)
+		span.RecordError(err)
+		return
+	}
+	if sent > 0 {
+		logger.Info("sent queued notifications", "sent", sent)
+	}
+	span.SetAttributes(attribute.Int("notifications.sent", sent))
+}
+
+// buildDataExport writes, uploads and announces one claimed export. A failure
+// to send the email is logged but doesn't fail the export, which the user can
+// still fetch from GET /api/v1/me/export.
+func (w *Worker) buildDataExport(ctx context.Context, exportStore *dbdataexport.Store, export *dbdataexport.Export) error {
+	f, err := os.CreateTemp("", "confab-export-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	sessionCount, err := api.WriteUserDataExport(ctx, w.db, w.store, export.UserID, f)
+	if err != nil {
+		return err
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to size archive: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind archive: %w", err)
+	}
+	key, err := w.store.UploadDataExport(ctx, export.UserID, export.ID, f, size)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(w.config.DataExportTTL)
+	if err := exportStore.MarkReady(ctx, export.ID, key, size, sessionCount, expiresAt); err != nil {
+		return err
+	}
+	logger.Info("built data export",
+		"export_id", export.ID,
+		"user_id", export.UserID,
+		"sessions", sessionCount,
+		"size_bytes", size,
+	)
+
+	if w.mailer == nil {
+		return nil
+	}
+	user, err := (&dbuser.Store{DB: w.db}).GetUserByID(ctx, export.UserID)
+	if err == nil {
+		var url string
+		url, err = w.store.PresignDataExport(ctx, key, api.DataExportFilename(export), w.config.DataExportTTL)
+		if err == nil {
+			err = w.mailer.SendDataExportReady(ctx, email.DataExportReadyParams{
+				ToEmail:      user.Email,
+				DownloadURL:  url,
+				ExpiresAt:    expiresAt,
+				SessionCount: sessionCount,
+				SizeBytes:    size,
+			})
+		}
+	}
+	if err != nil {
+		logger.Error("failed to send data export email", "export_id", export.ID, "error", err)
+	}
+	return nil
+}
+
+// retentionEnvVar is the env var that overrides a table's retention window,
+// e.g. WORKER_RETENTION_WEB_SESSIONS.
+func retentionEnvVar(table string) string {
+	return "WORKER_RETENTION_" + strings.ToUpper(table)
 }
 
 // processRegularSessions processes sessions with stale regular cards.
@@ -312,9 +931,56 @@ func (w *Worker) processRegularSessions(ctx context.Context, sessions []analytic
 	return w.processSessions(ctx, sessions, "session", w.precomputer.PrecomputeRegularCards, 500*time.Millisecond)
 }
 
+// processDormantSessions finds up to DormantBatchSize sessions idle past
+// analytics.DormantSessionAge with any stale card and computes their regular
+// cards. Called only when buckets 1-3 came back empty, so it never delays an
+// active session. A find error is logged and the cycle ends normally.
+func (w *Worker) processDormantSessions(ctx context.Context, span trace.Span) {
+	sessions, err := w.precomputer.FindDormantStaleSessions(ctx, w.config.DormantBatchSize)
+	if err != nil {
+		logger.Error("failed to find dormant stale sessions", "error", err)
+		span.RecordError(err)
+		return
+	}
+	span.SetAttributes(attribute.Int("sessions.dormant.found", len(sessions)))
+	if len(sessions) == 0 {
+		return
+	}
+
+	if w.config.DryRun {
+		for _, session := range sessions {
+			logger.Info("[DRY-RUN] would precompute dormant session (regular cards)",
+				"session_id", session.SessionID,
+				"user_id", session.UserID,
+				"external_id", session.ExternalID,
+				"total_lines", session.TotalLines,
+			)
+		}
+		span.SetAttributes(attribute.Int("sessions.dormant.would_process", len(sessions)))
+		return
+	}
+
+	processed, errors := w.processSessions(ctx, sessions, "dormant session", w.precomputer.PrecomputeRegularCards, 500*time.Millisecond)
+	logger.Info("dormant precomputation complete",
+		"found", len(sessions),
+		"processed", processed,
+		"errors", errors,
+	)
+	span.SetAttributes(
+		attribute.Int("sessions.dormant.processed", processed),
+		attribute.Int("sessions.dormant.errors", errors),
+	)
+}
+
 // processSmartRecapSessions processes sessions with only stale smart recap.
+// Up to RecapConcurrency generations run at once, but never more than
+// RecapMaxPerUser for one user: sessions are dispatched in discovery order
+// (already interleaved across users by FindStaleSmartRecapSessions), skipping
+// any whose user is at the cap until one of that user's generations finishes.
+// This keeps one user's backlog from monopolizing the LLM rate limit.
 func (w *Worker) processSmartRecapSessions(ctx context.Context, sessions []analytics.StaleSession) (processed, errors int) {
-	return w.processSessions(ctx, sessions, "smart recap", w.precomputer.PrecomputeSmartRecapOnly, 500*time.Millisecond)
+	return w.processSessionsPerUser(ctx, sessions, "smart recap", w.precomputer.PrecomputeSmartRecapOnly, 500*time.Millisecond,
+		max(w.config.RecapConcurrency, 1), max(w.config.RecapMaxPerUser, 1))
 }
 
 // processSearchIndexSessions processes sessions with stale search index.
@@ -322,8 +988,124 @@ func (w *Worker) processSearchIndexSessions(ctx context.Context, sessions []anal
 	return w.processSessions(ctx, sessions, "search index", w.precomputer.BuildSearchIndexOnly, 50*time.Millisecond)
 }
 
+// processSessionsPerUser processes sessions with up to concurrency in flight and
+// at most perUser in flight for any one user, tracked in w.recapInFlight.
+// Dispatches are paced like processSessions. On shutdown it stops dispatching
+// and waits for running sessions, which get ShutdownTimeout to finish.
+func (w *Worker) processSessionsPerUser(
+	ctx context.Context,
+	sessions []analytics.StaleSession,
+	label string,
+	process func(context.Context, analytics.StaleSession) error,
+	pacing time.Duration,
+	concurrency, perUser int,
+) (processed, errors int) {
+	if w.recapInFlight == nil {
+		w.recapInFlight = make(map[int64]int)
+	}
+
+	type result struct {
+		session analytics.StaleSession
+		err     error
+	}
+	// Sessions already dispatched may finish after shutdown; ctx still
+	// stops new dispatches.
+	drainCtx, stopDrain := withDrain(ctx, w.config.ShutdownTimeout)
+	defer stopDrain()
+
+	done := make(chan result)
+	pending := append([]analytics.StaleSession(nil), sessions...)
+	running, dispatched := 0, 0
+	collect := func(r result) {
+		running--
+		w.recapInFlight[r.session.UserID]--
+		if logPrecomputeResult(label, r.session, r.err) {
+			processed++
+		} else {
+			errors++
+		}
+	}
+
+	for len(pending) > 0 || running > 0 {
+		// Dispatch the earliest pending session whose user is under the cap.
+		next := -1
+		if running < concurrency && ctx.Err() == nil {
+			for i, session := range pending {
+				if w.recapInFlight[session.UserID] < perUser {
+					next = i
+					break
+				}
+			}
+		}
+
+		if next >= 0 && dispatched > 0 && pacing > 0 {
+			select {
+			case r := <-done:
+				// A completion arrived during pacing; account for it and
+				// re-evaluate which session is next.
+				collect(r)
+				continue
+			case <-ctx.Done():
+				continue
+			case <-time.After(pacing):
+			}
+		}
+
+		if next >= 0 {
+			session := pending[next]
+			pending = append(pending[:next], pending[next+1:]...)
+			w.recapInFlight[session.UserID]++
+			running++
+			dispatched++
+			go func() {
+				done <- result{session: session, err: process(drainCtx, session)}
+			}()
+			continue
+		}
+
+		if running == 0 {
+			// Nothing in flight and nothing dispatchable: shutting down.
+			logger.Info("stopping processing due to shutdown")
+			return
+		}
+		collect(<-done)
+	}
+	return
+}
+
+// logPrecomputeResult logs the outcome of one precompute call and reports
+// whether it succeeded. Quota-exceeded is logged as a skip but still counts as
+// an error.
+func logPrecomputeResult(label string, session analytics.StaleSession, err error) bool {
+	if err != nil {
+		if err == analytics.ErrQuotaExceeded {
+			logger.Warn("skipped precompute "+label+": quota exceeded",
+				"session_id", session.SessionID,
+				"user_id", session.UserID,
+			)
+		} else {
+			logger.Error("failed to precompute "+label,
+				"session_id", session.SessionID,
+				"user_id", session.UserID,
+				"external_id", session.ExternalID,
+				"total_lines", session.TotalLines,
+				"error", err,
+			)
+		}
+		return false
+	}
+	logger.Info("precomputed "+label,
+		"session_id", session.SessionID,
+		"user_id", session.UserID,
+		"external_id", session.ExternalID,
+		"total_lines", session.TotalLines,
+	)
+	return true
+}
+
 // processSessions is a generic loop that processes a list of stale sessions with pacing.
 // The label parameter is used for log messages (e.g., "session" or "smart recap").
+// On shutdown the session in flight gets ShutdownTimeout to finish.
 func (w *Worker) processSessions(
 	ctx context.Context,
 	sessions []analytics.StaleSession,
@@ -331,6 +1113,11 @@ func (w *Worker) processSessions(
 	process func(context.Context, analytics.StaleSession) error,
 	pacing time.Duration,
 ) (processed, errors int) {
+	// The session in flight may finish after shutdown; ctx still stops the
+	// loop before the next one.
+	drainCtx, stopDrain := withDrain(ctx, w.config.ShutdownTimeout)
+	defer stopDrain()
+
 	for i, session := range sessions {
 		select {
 		case <-ctx.Done():
@@ -339,31 +1126,10 @@ func (w *Worker) processSessions(
 		default:
 		}
 
-		err := process(ctx, session)
-		if err != nil {
-			if err == analytics.ErrQuotaExceeded {
-				logger.Warn("skipped precompute "+label+": quota exceeded",
-					"session_id", session.SessionID,
-					"user_id", session.UserID,
-				)
-			} else {
-				logger.Error("failed to precompute "+label,
-					"session_id", session.SessionID,
-					"user_id", session.UserID,
-					"external_id", session.ExternalID,
-					"total_lines", session.TotalLines,
-					"error", err,
-				)
-			}
-			errors++
-		} else {
-			logger.Info("precomputed "+label,
-				"session_id", session.SessionID,
-				"user_id", session.UserID,
-				"external_id", session.ExternalID,
-				"total_lines", session.TotalLines,
-			)
+		if logPrecomputeResult(label, session, process(drainCtx, session)) {
 			processed++
+		} else {
+			errors++
 		}
 
 		// Brief delay between sessions for steady pacing (skip after last)
@@ -378,6 +1144,27 @@ func (w *Worker) processSessions(
 	return
 }
 
+// withDrain returns a context for work already in flight. It outlives ctx by
+// up to grace, so a shutdown lets the current session finish (and release its
+// smart recap claim) instead of aborting it; past grace it is cancelled too.
+// A grace of 0 cancels it along with ctx.
+func withDrain(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	drainCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel()
+		case <-drainCtx.Done():
+		}
+	})
+	return drainCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
 // loadWorkerConfig loads worker configuration from environment variables.
 func loadWorkerConfig() WorkerConfig {
 	config := WorkerConfig{
@@ -391,6 +1178,28 @@ func loadWorkerConfig() WorkerConfig {
 		}
 	}
 
+	// Adaptive polling: PRECOMPUTE_BASE_INTERVAL takes precedence over
+	// WORKER_POLL_INTERVAL. The min and max default to the base, which keeps
+	// a fixed schedule; a min above the base or a max below it is pulled back
+	// to the base. Garbage and non-positive values are ignored.
+	if v := os.Getenv("PRECOMPUTE_BASE_INTERVAL"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil && parsed > 0 {
+			config.PollInterval = parsed
+		}
+	}
+	config.MinPollInterval = config.PollInterval
+	if v := os.Getenv("PRECOMPUTE_MIN_INTERVAL"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil && parsed > 0 {
+			config.MinPollInterval = min(parsed, config.PollInterval)
+		}
+	}
+	config.MaxPollInterval = config.PollInterval
+	if v := os.Getenv("PRECOMPUTE_MAX_INTERVAL"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil && parsed > 0 {
+			config.MaxPollInterval = max(parsed, config.PollInterval)
+		}
+	}
+
 	// WORKER_SHARE_RETENTION: optional, defaults to 30 days. time.ParseDuration
 	// only accepts h/m/s units (not "30d"), so express overrides in hours
 	// (e.g. "720h" = 30 days).
@@ -411,6 +1220,19 @@ func loadWorkerConfig() WorkerConfig {
 	}
 	config.MaxSessions = parsed
 
+	// Adaptive batch: WORKER_MAX_SESSIONS is the first cycle's batch, and
+	// the min and max default to it, which keeps a fixed batch. Same rules as
+	// the adaptive intervals: a min above it or a max below it is pulled back
+	// to it, and garbage and non-positive values are ignored.
+	config.MinBatchSize = config.MaxSessions
+	if n, err := strconv.Atoi(os.Getenv("PRECOMPUTE_MIN_BATCH_SIZE")); err == nil && n > 0 {
+		config.MinBatchSize = min(n, config.MaxSessions)
+	}
+	config.MaxBatchSize = config.MaxSessions
+	if n, err := strconv.Atoi(os.Getenv("PRECOMPUTE_MAX_BATCH_SIZE")); err == nil && n > 0 {
+		config.MaxBatchSize = max(n, config.MaxSessions)
+	}
+
 	// MaxSearchIndexSessions: optional, defaults to 200 (search indexing is cheap)
 	config.MaxSearchIndexSessions = 200
 	if maxSearch := os.Getenv("WORKER_MAX_SEARCH_INDEX_SESSIONS"); maxSearch != "" {
@@ -419,6 +1241,130 @@ func loadWorkerConfig() WorkerConfig {
 		}
 	}
 
+	// Dormant batch: optional, defaults to 5; "0" disables the bucket
+	config.DormantBatchSize = 5
+	if n, err := strconv.Atoi(os.Getenv("PRECOMPUTE_DORMANT_BATCH_SIZE")); err == nil && n >= 0 {
+		config.DormantBatchSize = n
+	}
+
+	// Smart recap concurrency: optional, defaults to 1 (sequential)
+	config.RecapConcurrency = 1
+	if n, err := strconv.Atoi(os.Getenv("WORKER_RECAP_CONCURRENCY")); err == nil && n > 0 {
+		config.RecapConcurrency = n
+	}
+
+	// Per-user smart recap cap: optional, defaults to 1 so one user's backlog
+	// can't occupy more than one slot while others are waiting
+	config.RecapMaxPerUser = 1
+	if n, err := strconv.Atoi(os.Getenv("WORKER_RECAP_MAX_PER_USER")); err == nil && n > 0 {
+		config.RecapMaxPerUser = n
+	}
+
+	// Retention pruning: WORKER_RETENTION_<TABLE> overrides a table's default
+	// window (same h/m/s format as WORKER_SHARE_RETENTION); "0" disables
+	// pruning for that table. Garbage and negative values keep the default.
+	config.RetentionPolicies = slices.Clone(dbretention.Defaults)
+	for i := range config.RetentionPolicies {
+		p := &config.RetentionPolicies[i]
+		if v := os.Getenv(retentionEnvVar(p.Table)); v != "" {
+			if parsed, err := time.ParseDuration(v); err == nil && parsed >= 0 {
+				p.Retention = parsed
+			}
+		}
+	}
+	config.PruneBatchSize = 1000
+	if n, err := strconv.Atoi(os.Getenv("WORKER_PRUNE_BATCH_SIZE")); err == nil && n > 0 {
+		config.PruneBatchSize = n
+	}
+	config.PruneMaxRows = 10000
+	if n, err := strconv.Atoi(os.Getenv("WORKER_PRUNE_MAX_ROWS")); err == nil && n > 0 {
+		config.PruneMaxRows = n
+	}
+
+	// Transcript retention: off by default. Same h/m/s format; garbage and
+	// non-positive values leave it off.
+	if v := os.Getenv("WORKER_TRANSCRIPT_RETENTION"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil && parsed > 0 {
+			config.TranscriptRetention = parsed
+		}
+	}
+	config.TranscriptArchiveBatch = 100
+	if n, err := strconv.Atoi(os.Getenv("WORKER_TRANSCRIPT_ARCHIVE_BATCH")); err == nil && n > 0 {
+		config.TranscriptArchiveBatch = n
+	}
+	config.StoredBytesBackfillBatch = 100
+	if n, err := strconv.Atoi(os.Getenv("WORKER_STORED_BYTES_BACKFILL_BATCH")); err == nil && n > 0 {
+		config.StoredBytesBackfillBatch = n
+	}
+
+	// Chunk upload reconciliation: WORKER_CHUNK_RECONCILE_AFTER is an h/m/s
+	// duration (garbage and non-positive values keep the default; it must
+	// comfortably exceed an upload's storage and database timeouts);
+	// WORKER_CHUNK_RECONCILE_BATCH caps events settled per cycle ("0"
+	// disables the step).
+	config.ChunkReconcileAfter = 5 * time.Minute
+	if v := os.Getenv("WORKER_CHUNK_RECONCILE_AFTER"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil && parsed > 0 {
+			config.ChunkReconcileAfter = parsed
+		}
+	}
+	config.ChunkReconcileBatch = 500
+	if n, err := strconv.Atoi(os.Getenv("WORKER_CHUNK_RECONCILE_BATCH")); err == nil && n >= 0 {
+		config.ChunkReconcileBatch = n
+	}
+
+	// Data exports: WORKER_DATA_EXPORT_BATCH archives built per cycle ("0"
+	// disables the step); WORKER_DATA_EXPORT_TTL is how long each stays
+	// downloadable, capped at the 7-day presigned URL limit.
+	config.DataExportBatch = 2
+	if n, err := strconv.Atoi(os.Getenv("WORKER_DATA_EXPORT_BATCH")); err == nil && n >= 0 {
+		config.DataExportBatch = n
+	}
+	config.DataExportTTL = 72 * time.Hour
+	if v := os.Getenv("WORKER_DATA_EXPORT_TTL"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil && parsed > 0 {
+			config.DataExportTTL = min(parsed, storage.MaxPresignExpiry)
+		}
+	}
+
+	// Session state sweep: WORKER_SESSION_IDLE_AFTER and
+	// WORKER_SESSION_ENDED_AFTER are h/m/s durations ("0" disables that
+	// transition; garbage keeps the default); WORKER_SESSION_SWEEP_BATCH caps
+	// the sessions moved per state per cycle ("0" disables the sweep).
+	config.SessionIdleAfter = 30 * time.Minute
+	if v := os.Getenv("WORKER_SESSION_IDLE_AFTER"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil && parsed >= 0 {
+			config.SessionIdleAfter = parsed
+		}
+	}
+	config.SessionEndedAfter = 24 * time.Hour
+	if v := os.Getenv("WORKER_SESSION_ENDED_AFTER"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil && parsed >= 0 {
+			config.SessionEndedAfter = parsed
+		}
+	}
+	config.SessionSweepBatch = 500
+	if n, err := strconv.Atoi(os.Getenv("WORKER_SESSION_SWEEP_BATCH")); err == nil && n >= 0 {
+		config.SessionSweepBatch = n
+	}
+
+	// Notification flush: WORKER_NOTIFICATION_FLUSH_BATCH notifications held
+	// back by quiet hours sent per cycle ("0" disables the step).
+	config.NotificationFlushBatch = 200
+	if n, err := strconv.Atoi(os.Getenv("WORKER_NOTIFICATION_FLUSH_BATCH")); err == nil && n >= 0 {
+		config.NotificationFlushBatch = n
+	}
+
+	// Shutdown drain: optional, defaults to 10s (the server's shutdown
+	// timeout); "0" aborts in-flight sessions at once. Garbage and negative
+	// values keep the default.
+	config.ShutdownTimeout = 10 * time.Second
+	if v := os.Getenv("WORKER_SHUTDOWN_TIMEOUT"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil && parsed >= 0 {
+			config.ShutdownTimeout = parsed
+		}
+	}
+
 	// Dry-run mode: log what would be done without actually precomputing
 	if dryRun := os.Getenv("WORKER_DRY_RUN"); dryRun == "true" || dryRun == "1" {
 		config.DryRun = true
@@ -427,35 +1373,64 @@ func loadWorkerConfig() WorkerConfig {
 	return config
 }
 
-// loadS3Config loads S3 configuration from environment variables.
-func loadS3Config() storage.S3Config {
-	s3Endpoint := os.Getenv("S3_ENDPOINT")
-	if s3Endpoint == "" {
-		logFatal("missing required env var", "var", "S3_ENDPOINT")
+// loadWebhookConfig loads the webhook dispatcher configuration. Garbage and
+// non-positive values keep the dispatcher's defaults.
+func loadWebhookConfig() webhooks.Config {
+	var config webhooks.Config
+	if v := os.Getenv("WEBHOOK_POLL_INTERVAL"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil && parsed > 0 {
+			config.PollInterval = parsed
+		}
 	}
-
-	awsAccessKeyID := os.Getenv("AWS_ACCESS_KEY_ID")
-	if awsAccessKeyID == "" {
-		logFatal("missing required env var", "var", "AWS_ACCESS_KEY_ID")
+	if n, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS")); err == nil && n > 0 {
+		config.MaxAttempts = n
 	}
+	config.AllowPrivateTargets = os.Getenv("WEBHOOK_ALLOW_PRIVATE_TARGETS") == "true"
+	return config
+}
 
-	awsSecretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
-	if awsSecretAccessKey == "" {
-		logFatal("missing required env var", "var", "AWS_SECRET_ACCESS_KEY")
+// newWorkerNotifier builds the dispatcher the worker flushes queued
+// notifications through: the same senders as the server's, except that email
+// goes out on mailer without the server's per-user hourly limit, which lives
+// in the server's memory. A nil mailer leaves email out, so queued emails are
+// dropped.
+func newWorkerNotifier(database *db.DB, mailer *email.ResendService) *notify.Dispatcher {
+	users := &dbuser.Store{DB: database}
+	senders := map[string]notify.Sender{
+		notify.ChannelWebhook: notify.NewWebhookSender(&dbwebhook.Store{DB: database}),
 	}
+	if mailer != nil {
+		senders[notify.ChannelEmail] = notify.NewEmailSender(users,
+			func(ctx context.Context, _ int64, params email.NotificationParams) error {
+				return mailer.SendNotification(ctx, params)
+			})
+	}
+	return notify.NewDispatcher(users, &dbnotify.Store{DB: database}, senders)
+}
 
-	bucketName := os.Getenv("BUCKET_NAME")
-	if bucketName == "" {
-		logFatal("missing required env var", "var", "BUCKET_NAME")
+// loadWorkerMailer builds the worker's mailer from the same env vars as the
+// server's email service, or returns nil when email is not configured.
+func loadWorkerMailer() *email.ResendService {
+	apiKey := os.Getenv("RESEND_API_KEY")
+	fromAddress := os.Getenv("EMAIL_FROM_ADDRESS")
+	if apiKey == "" || fromAddress == "" {
+		return nil
+	}
+	fromName := os.Getenv("EMAIL_FROM_NAME")
+	if fromName == "" {
+		fromName = "Confab"
 	}
+	return email.NewResendService(apiKey, fromAddress, fromName, os.Getenv("FRONTEND_URL"))
+}
 
-	return storage.S3Config{
-		Endpoint:        s3Endpoint,
-		AccessKeyID:     awsAccessKeyID,
-		SecretAccessKey: awsSecretAccessKey,
-		BucketName:      bucketName,
-		UseSSL:          os.Getenv("S3_USE_SSL") != "false",
+// loadS3Config loads S3 configuration from environment variables for the
+// worker, which does not go through ParseConfig.
+func loadS3Config() storage.S3Config {
+	cfg, problems := parseS3Config(os.Getenv)
+	if len(problems) > 0 {
+		logFatal("missing required env var", "error", strings.Join(problems, "; "))
 	}
+	return cfg
 }
 
 // loadPrecomputeConfig loads smart recap configuration from environment variables.
@@ -502,6 +1477,20 @@ func loadPrecomputeConfig() analytics.PrecomputeConfig {
 		analytics.DefaultSmartRecapThresholds(),
 	)
 
+	// Minimum interval between smart recaps of a growing session: optional
+	if interval := os.Getenv("WORKER_RECAP_MIN_INTERVAL"); interval != "" {
+		if dur, err := time.ParseDuration(interval); err == nil && dur > 0 {
+			config.SmartRecapMinInterval = dur
+		}
+	}
+
+	// Cache warming for recently active users: optional, off by default
+	if window := os.Getenv("WORKER_WARM_ACTIVE_WINDOW"); window != "" {
+		if dur, err := time.ParseDuration(window); err == nil && dur > 0 {
+			config.WarmActiveWindow = dur
+		}
+	}
+
 	// Disable if required config is missing (quota=0 means unlimited, not disabled)
 	if config.AnthropicAPIKey == "" || config.SmartRecapModel == "" {
 		config.SmartRecapEnabled = false
@@ -511,49 +1500,7 @@ func loadPrecomputeConfig() analytics.PrecomputeConfig {
 }
 
 // loadStalenessThresholds loads staleness thresholds from environment variables with a prefix.
-// For example, with prefix "WORKER_REGULAR", it reads:
-// - WORKER_REGULAR_THRESHOLD_PCT (e.g., "0.20")
-// - WORKER_REGULAR_BASE_MIN_LINES (e.g., "5")
-// - WORKER_REGULAR_BASE_MIN_TIME (e.g., "3m")
-// - WORKER_REGULAR_MIN_INITIAL_LINES (e.g., "10")
-// - WORKER_REGULAR_MIN_SESSION_AGE (e.g., "10m")
+// See analytics.LoadStalenessThresholds; the API server reads the same variables.
 func loadStalenessThresholds(prefix string, defaults analytics.StalenessThresholds) analytics.StalenessThresholds {
-	th := defaults
-
-	// Parse threshold percentage (e.g., "0.20" for 20%)
-	if pctStr := os.Getenv(prefix + "_THRESHOLD_PCT"); pctStr != "" {
-		if pct, err := strconv.ParseFloat(pctStr, 64); err == nil && pct >= 0 && pct <= 1 {
-			th.ThresholdPct = pct
-		}
-	}
-
-	// Parse base minimum lines
-	if linesStr := os.Getenv(prefix + "_BASE_MIN_LINES"); linesStr != "" {
-		if lines, err := strconv.ParseInt(linesStr, 10, 64); err == nil && lines >= 0 {
-			th.BaseMinLines = lines
-		}
-	}
-
-	// Parse base minimum time (duration string like "3m", "15m")
-	if timeStr := os.Getenv(prefix + "_BASE_MIN_TIME"); timeStr != "" {
-		if dur, err := time.ParseDuration(timeStr); err == nil && dur >= 0 {
-			th.BaseMinTime = dur
-		}
-	}
-
-	// Parse minimum initial lines
-	if linesStr := os.Getenv(prefix + "_MIN_INITIAL_LINES"); linesStr != "" {
-		if lines, err := strconv.ParseInt(linesStr, 10, 64); err == nil && lines >= 0 {
-			th.MinInitialLines = lines
-		}
-	}
-
-	// Parse minimum session age (duration string like "10m")
-	if ageStr := os.Getenv(prefix + "_MIN_SESSION_AGE"); ageStr != "" {
-		if dur, err := time.ParseDuration(ageStr); err == nil && dur >= 0 {
-			th.MinSessionAge = dur
-		}
-	}
-
-	return th
+	return analytics.LoadStalenessThresholds(prefix, defaults)
 }
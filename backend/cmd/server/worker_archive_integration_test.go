@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/api/apitest"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+const archiveTranscript = `{"type":"user","message":{"role":"user","content":"hello"},"uuid":"u1","timestamp":"2025-01-01T00:00:00Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"id":"msg_1","type":"message","model":"claude-sonnet-4-20241022","role":"assistant","content":[{"type":"text","text":"Hello!"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}},"uuid":"a1","timestamp":"2025-01-01T00:00:01Z","parentUuid":"u1","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+`
+
+// TestArchiveTranscripts_Integration runs the worker's transcript retention
+// step end to end: the session's chunks are deleted, raw reads answer 410,
+// and the cards computed before archival are still served.
+func TestArchiveTranscripts_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "archive@example.com", "Archive User")
+	token := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "archive-me")
+	testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "archive-me", "transcript.jsonl", 1, 2, []byte(archiveTranscript))
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 2)
+
+	ts := apitest.NewServer(t, env, apitest.Options{})
+	client := testutil.NewTestClient(t, ts).WithSession(token)
+
+	getAnalytics := func() analytics.AnalyticsResponse {
+		t.Helper()
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/analytics", sessionID))
+		if err != nil {
+			t.Fatalf("analytics request: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result analytics.AnalyticsResponse
+		testutil.ParseJSON(t, resp, &result)
+		return result
+	}
+
+	// Compute and cache the cards, then index the session and let it go idle.
+	if before := getAnalytics(); before.Tokens.Input != 100 || before.TranscriptArchived {
+		t.Fatalf("before archival: input tokens %d, archived %v", before.Tokens.Input, before.TranscriptArchived)
+	}
+	testutil.CreateTestSearchIndex(t, env, sessionID, "hello", 2)
+	if _, err := env.DB.Exec(ctx, `UPDATE sessions SET last_sync_at = $2 WHERE id = $1`,
+		sessionID, time.Now().UTC().Add(-100*24*time.Hour)); err != nil {
+		t.Fatalf("age session: %v", err)
+	}
+
+	w := &Worker{
+		db:    env.DB,
+		store: env.Storage,
+		config: WorkerConfig{
+			TranscriptRetention:    30 * 24 * time.Hour,
+			TranscriptArchiveBatch: 10,
+		},
+	}
+	w.archiveTranscripts(ctx, trace.SpanFromContext(ctx))
+
+	chunks, err := env.Storage.ListChunks(ctx, user.ID, models.ProviderClaudeCode, "archive-me", "transcript.jsonl")
+	if err != nil {
+		t.Fatalf("ListChunks: %v", err)
+	}
+	if len(chunks) != 0 {
+		t.Errorf("chunks after archival = %v, want none", chunks)
+	}
+
+	// Cards are served from the database, unchanged.
+	after := getAnalytics()
+	if !after.TranscriptArchived {
+		t.Error("analytics should report transcript_archived")
+	}
+	if after.Tokens.Input != 100 || after.Cards["session"] == nil {
+		t.Errorf("archived cards: input tokens %d, session card %v", after.Tokens.Input, after.Cards["session"])
+	}
+
+	// The raw file is gone for good.
+	resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/sync/file?file_name=transcript.jsonl", sessionID))
+	if err != nil {
+		t.Fatalf("file read: %v", err)
+	}
+	defer resp.Body.Close()
+	testutil.RequireStatus(t, resp, http.StatusGone)
+	var errBody httputil.ErrorResponse
+	testutil.ParseJSON(t, resp, &errBody)
+	if errBody.Code != httputil.CodeTranscriptArchived {
+		t.Errorf("error code = %q, want %q", errBody.Code, httputil.CodeTranscriptArchived)
+	}
+
+	// The session itself is still there, marked archived.
+	resp, err = client.Get(fmt.Sprintf("/api/v1/sessions/%s", sessionID))
+	if err != nil {
+		t.Fatalf("session request: %v", err)
+	}
+	defer resp.Body.Close()
+	testutil.RequireStatus(t, resp, http.StatusOK)
+	var detail db.SessionDetail
+	testutil.ParseJSON(t, resp, &detail)
+	if detail.TranscriptArchivedAt == nil {
+		t.Error("session detail should carry transcript_archived_at")
+	}
+	if detail.State != dbsession.StateArchived {
+		t.Errorf("session state = %q, want %q", detail.State, dbsession.StateArchived)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbdataexport"
+	"github.com/ConfabulousDev/confab-web/internal/email"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+type fakeExportMailer struct {
+	sent []email.DataExportReadyParams
+}
+
+func (m *fakeExportMailer) SendDataExportReady(_ context.Context, params email.DataExportReadyParams) error {
+	m.sent = append(m.sent, params)
+	return nil
+}
+
+// TestProcessDataExports_Integration builds the export of a small account
+// (two sessions, one with an agent file and a cached card) and checks the
+// archive in S3, the ready row and the email, then expires it.
+func TestProcessDataExports_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "export@example.com", "Export User")
+	other := testutil.CreateTestUser(t, env, "other@example.com", "Other User")
+
+	first := testutil.CreateTestSession(t, env, user.ID, "export-first")
+	transcript := []byte("{\"type\":\"user\",\"n\":1}\n{\"type\":\"assistant\",\"n\":2}\n")
+	agent := []byte("{\"agent\":true}\n")
+	testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "export-first", "transcript.jsonl", 1, 2, transcript)
+	testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "export-first", "agent-abc.jsonl", 1, 1, agent)
+	testutil.CreateTestSyncFile(t, env, first, "transcript.jsonl", "transcript", 2)
+	testutil.CreateTestSyncFile(t, env, first, "agent-abc.jsonl", "agent", 1)
+	testutil.SeedTokensV2Card(t, env, first, analytics.TokensV2Data{TotalCostUSD: "1.25", TotalInput: 10, TotalOutput: 20})
+
+	second := testutil.CreateTestSession(t, env, user.ID, "export-second")
+	testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "export-second", "transcript.jsonl", 1, 1, []byte("{\"n\":1}\n"))
+	testutil.CreateTestSyncFile(t, env, second, "transcript.jsonl", "transcript", 1)
+
+	// Another user's session must not leak into the archive.
+	testutil.CreateTestSession(t, env, other.ID, "export-other")
+
+	exportStore := &dbdataexport.Store{DB: env.DB}
+	requested, err := exportStore.Request(ctx, user.ID, 24*time.Hour)
+	if err != nil || !requested.Created {
+		t.Fatalf("Request = %+v, %v; want a created export", requested, err)
+	}
+
+	mailer := &fakeExportMailer{}
+	w := &Worker{
+		db:     env.DB,
+		store:  env.Storage,
+		mailer: mailer,
+		config: WorkerConfig{DataExportBatch: 2, DataExportTTL: time.Hour},
+	}
+	w.processDataExports(ctx, trace.SpanFromContext(ctx))
+
+	export, err := exportStore.Latest(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if export.Status != dbdataexport.StatusReady || export.ObjectKey == nil {
+		t.Fatalf("export = %+v, want ready with an object key", export)
+	}
+	if export.SessionCount == nil || *export.SessionCount != 2 {
+		t.Errorf("session_count = %v, want 2", export.SessionCount)
+	}
+
+	obj, err := env.Storage.Download(ctx, *export.ObjectKey)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if export.SizeBytes == nil || *export.SizeBytes != int64(len(obj)) {
+		t.Errorf("size_bytes = %v, want %d", export.SizeBytes, len(obj))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(obj), int64(len(obj)))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	entries := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		entries[f.Name] = data
+	}
+
+	var account struct {
+		User         models.User `json:"user"`
+		SessionCount int         `json:"session_count"`
+	}
+	if err := json.Unmarshal(entries["account.json"], &account); err != nil {
+		t.Fatalf("account.json: %v", err)
+	}
+	if account.User.Email != "export@example.com" || account.SessionCount != 2 {
+		t.Errorf("account.json = %+v, want export@example.com with 2 sessions", account)
+	}
+
+	dirs := map[string]string{}
+	for name := range entries {
+		for _, id := range []string{first, second} {
+			if strings.HasPrefix(name, "sessions/") && strings.Contains(name, "-"+id[:8]+"/") {
+				dirs[id] = name[:strings.Index(name, id[:8])+9]
+			}
+		}
+	}
+	if len(dirs) != 2 {
+		t.Fatalf("session dirs = %v, entries = %v; want one per session", dirs, slices.Collect(maps.Keys(entries)))
+	}
+	if got := entries[dirs[first]+"transcript.jsonl"]; !bytes.Equal(got, transcript) {
+		t.Errorf("first transcript = %q, want %q", got, transcript)
+	}
+	if got := entries[dirs[first]+"agents/agent-abc.jsonl"]; !bytes.Equal(got, agent) {
+		t.Errorf("first agent = %q, want %q", got, agent)
+	}
+	if !bytes.Contains(entries[dirs[first]+"analytics.json"], []byte(`"tokens"`)) {
+		t.Errorf("first analytics.json = %s, want the tokens card", entries[dirs[first]+"analytics.json"])
+	}
+	if _, ok := entries[dirs[second]+"analytics.json"]; ok {
+		t.Error("second session has analytics.json without any cards")
+	}
+	for _, id := range []string{first, second} {
+		if !bytes.Contains(entries[dirs[id]+"metadata.json"], []byte(id)) {
+			t.Errorf("metadata.json of %s doesn't name the session", id)
+		}
+	}
+
+	if len(mailer.sent) != 1 {
+		t.Fatalf("emails sent = %d, want 1", len(mailer.sent))
+	}
+	if sent := mailer.sent[0]; sent.ToEmail != "export@example.com" || sent.DownloadURL == "" || sent.SessionCount != 2 {
+		t.Errorf("email = %+v, want a link for export@example.com covering 2 sessions", sent)
+	}
+
+	// Past its expiry the archive is deleted and the export marked expired.
+	if _, err := env.DB.Exec(ctx, `UPDATE user_data_exports SET expires_at = NOW() - INTERVAL '1 minute' WHERE id = $1`,
+		export.ID); err != nil {
+		t.Fatalf("backdate expiry: %v", err)
+	}
+	w.processDataExports(ctx, trace.SpanFromContext(ctx))
+
+	expired, err := exportStore.Latest(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if expired.Status != dbdataexport.StatusExpired || expired.ObjectKey != nil {
+		t.Errorf("after expiry = %+v, want expired without an object key", expired)
+	}
+	if _, err := env.Storage.Download(ctx, *export.ObjectKey); err == nil {
+		t.Error("expired archive still downloadable")
+	}
+	if len(mailer.sent) != 1 {
+		t.Errorf("emails sent = %d after expiry, want still 1", len(mailer.sent))
+	}
+}
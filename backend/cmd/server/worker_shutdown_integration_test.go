@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// stallingRecapPrecomputer generates smart recaps through a real
+// SmartRecapGenerator, so the recap claim is taken and released as in
+// production, against an LLM endpoint that never answers.
+type stallingRecapPrecomputer struct {
+	*analytics.Precomputer
+	generator *analytics.SmartRecapGenerator
+}
+
+func (p *stallingRecapPrecomputer) PrecomputeSmartRecapOnly(ctx context.Context, session analytics.StaleSession) error {
+	return p.generator.Generate(ctx, analytics.GenerateInput{
+		SessionID:  session.SessionID,
+		UserID:     session.UserID,
+		LineCount:  session.TotalLines,
+		Transcript: "<transcript/>",
+	}, 60, false).Error
+}
+
+// TestWorkerShutdown_ReleasesSmartRecapClaim_Integration cancels the worker
+// context while a smart recap is generating: the in-flight session gets the
+// shutdown timeout, then aborts and clears its claim, the next session is not
+// started, and both are selected again by the next cycle.
+func TestWorkerShutdown_ReleasesSmartRecapClaim_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "shutdown@example.com", "Shutdown User")
+	for _, externalID := range []string{"shutdown-a", "shutdown-b"} {
+		sessionID := testutil.CreateTestSession(t, env, user.ID, externalID)
+		testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, externalID, "transcript.jsonl", 1, 2, []byte(archiveTranscript))
+		testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 2)
+	}
+
+	analyticsStore := analytics.NewStore(env.DB.Conn())
+	precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, analytics.PrecomputeConfig{
+		SmartRecapEnabled:      true,
+		AnthropicAPIKey:        "test-key",
+		SmartRecapModel:        "test-model",
+		SmartRecapQuota:        100,
+		LockTimeoutSeconds:     60,
+		RegularCardsThresholds: analytics.DefaultRegularCardsThresholds(),
+		SmartRecapThresholds:   analytics.DefaultSmartRecapThresholds(),
+	})
+
+	// Regular cards first: smart recaps wait for them.
+	stale, err := precomputer.FindStaleSessions(env.Ctx, 10)
+	if err != nil {
+		t.Fatalf("FindStaleSessions: %v", err)
+	}
+	for _, s := range stale {
+		if err := precomputer.PrecomputeRegularCards(env.Ctx, s); err != nil {
+			t.Fatalf("PrecomputeRegularCards(%s): %v", s.SessionID, err)
+		}
+	}
+	recaps, err := precomputer.FindStaleSmartRecapSessions(env.Ctx, 10)
+	if err != nil {
+		t.Fatalf("FindStaleSmartRecapSessions: %v", err)
+	}
+	if len(recaps) != 2 {
+		t.Fatalf("stale smart recap sessions = %d, want 2", len(recaps))
+	}
+
+	var requests atomic.Int32
+	reached := make(chan struct{}, 1)
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		reached <- struct{}{}
+		<-r.Context().Done()
+	}))
+	t.Cleanup(llm.Close)
+
+	w := &Worker{
+		db: env.DB,
+		precomputer: &stallingRecapPrecomputer{
+			Precomputer: precomputer,
+			generator: analytics.NewSmartRecapGenerator(analyticsStore, env.DB, analytics.SmartRecapGeneratorConfig{
+				APIKey:            "test-key",
+				Model:             "test-model",
+				GenerationTimeout: time.Minute,
+				BaseURL:           llm.URL,
+			}),
+		},
+		config: WorkerConfig{ShutdownTimeout: 200 * time.Millisecond},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	type counts struct{ processed, errors int }
+	done := make(chan counts, 1)
+	go func() {
+		processed, errs := w.processSmartRecapSessions(ctx, recaps)
+		done <- counts{processed, errs}
+	}()
+
+	// Mid-batch: the first recap holds its claim while the LLM call hangs.
+	select {
+	case <-reached:
+	case <-time.After(10 * time.Second):
+		t.Fatal("smart recap generation never reached the LLM")
+	}
+	claimed := func(sessionID string) bool {
+		t.Helper()
+		var held bool
+		if err := env.DB.QueryRow(env.Ctx,
+			`SELECT computing_started_at IS NOT NULL FROM session_card_smart_recap WHERE session_id = $1`,
+			sessionID).Scan(&held); err != nil {
+			t.Fatalf("failed to read recap claim: %v", err)
+		}
+		return held
+	}
+	if !claimed(recaps[0].SessionID) {
+		t.Fatal("recap claim not held during generation")
+	}
+
+	cancel()
+	select {
+	case got := <-done:
+		if got.processed != 0 || got.errors != 1 {
+			t.Errorf("processed=%d errors=%d, want 0/1", got.processed, got.errors)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not stop within the shutdown timeout")
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("LLM requests = %d, want 1 (no new session after shutdown)", n)
+	}
+	if claimed(recaps[0].SessionID) {
+		t.Error("recap claim still held after shutdown")
+	}
+
+	again, err := precomputer.FindStaleSmartRecapSessions(env.Ctx, 10)
+	if err != nil {
+		t.Fatalf("FindStaleSmartRecapSessions: %v", err)
+	}
+	if len(again) != 2 {
+		t.Errorf("stale smart recap sessions after shutdown = %d, want 2", len(again))
+	}
+	acquired, err := analyticsStore.AcquireSmartRecapLock(env.Ctx, recaps[0].SessionID, 60)
+	if err != nil || !acquired {
+		t.Errorf("AcquireSmartRecapLock after shutdown = %v, %v; want true", acquired, err)
+	}
+}
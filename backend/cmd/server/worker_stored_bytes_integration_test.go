@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestBackfillStoredBytes_Integration runs the worker's storage accounting
+// backfill over files synced before stored_bytes existed: live files and
+// archived generations get their object sizes, and files with no objects
+// get 0.
+func TestBackfillStoredBytes_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "backfill@example.com", "Backfill User")
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "backfill-me")
+	live1 := []byte("{\"n\":1}\n{\"n\":2}\n")
+	live2 := []byte("{\"n\":3}\n")
+	testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "backfill-me", "transcript.jsonl", 1, 2, live1)
+	testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "backfill-me", "transcript.jsonl", 3, 3, live2)
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 3)
+	testutil.CreateTestSyncFile(t, env, sessionID, "agent-empty.jsonl", "agent", 0)
+
+	// An archived generation 0 of another file, its chunks moved aside.
+	archived := []byte("{\"old\":true}\n")
+	testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "backfill-me", "scratch.jsonl", 1, 1, archived)
+	if _, err := env.Storage.ArchiveChunkGeneration(ctx, user.ID, models.ProviderClaudeCode, "backfill-me", "scratch.jsonl", 0); err != nil {
+		t.Fatalf("ArchiveChunkGeneration: %v", err)
+	}
+	if _, err := env.DB.Exec(ctx, `
+		INSERT INTO sync_file_generations (session_id, file_name, generation, file_type, line_count, chunk_count, reason, local_line_count)
+		VALUES ($1, 'scratch.jsonl', 0, 'agent', 1, 1, 'manual', 0)`, sessionID); err != nil {
+		t.Fatalf("insert generation: %v", err)
+	}
+
+	if _, err := env.DB.Exec(ctx, `UPDATE sync_files SET stored_bytes = NULL WHERE session_id = $1`, sessionID); err != nil {
+		t.Fatalf("clear sync file stored bytes: %v", err)
+	}
+	if _, err := env.DB.Exec(ctx, `UPDATE sync_file_generations SET stored_bytes = NULL WHERE session_id = $1`, sessionID); err != nil {
+		t.Fatalf("clear generation stored bytes: %v", err)
+	}
+
+	sessionStore := &dbsession.Store{DB: env.DB}
+	before, err := sessionStore.GetUserStorageUsage(ctx, user.ID, 10)
+	if err != nil {
+		t.Fatalf("GetUserStorageUsage: %v", err)
+	}
+	if before.TotalBytes != 0 || before.PendingFileCount != 3 {
+		t.Errorf("before backfill = %+v, want 0 bytes with 3 pending files", before)
+	}
+
+	w := &Worker{
+		db:     env.DB,
+		store:  env.Storage,
+		config: WorkerConfig{StoredBytesBackfillBatch: 10},
+	}
+	w.backfillStoredBytes(ctx, trace.SpanFromContext(ctx))
+
+	after, err := sessionStore.GetUserStorageUsage(ctx, user.ID, 10)
+	if err != nil {
+		t.Fatalf("GetUserStorageUsage: %v", err)
+	}
+	want := int64(len(live1) + len(live2) + len(archived))
+	if after.TotalBytes != want || after.PendingFileCount != 0 {
+		t.Errorf("after backfill = %+v, want %d bytes with nothing pending", after, want)
+	}
+
+	var emptyBytes *int64
+	if err := env.DB.QueryRow(ctx, `SELECT stored_bytes FROM sync_files WHERE session_id = $1 AND file_name = 'agent-empty.jsonl'`,
+		sessionID).Scan(&emptyBytes); err != nil {
+		t.Fatalf("query empty file: %v", err)
+	}
+	if emptyBytes == nil || *emptyBytes != 0 {
+		t.Errorf("file without chunks stored_bytes = %v, want 0", emptyBytes)
+	}
+
+	pending, err := sessionStore.ListStoredBytesBackfills(ctx, 10)
+	if err != nil {
+		t.Fatalf("ListStoredBytesBackfills: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("backfills left = %+v, want none", pending)
+	}
+}
@@ -3,13 +3,20 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbretention"
 	"github.com/ConfabulousDev/confab-web/internal/pricingsource"
+	"github.com/ConfabulousDev/confab-web/internal/storage"
+	"github.com/ConfabulousDev/confab-web/internal/webhooks"
 )
 
 // ---------- loadWorkerConfig ----------
@@ -32,6 +39,9 @@ func TestLoadWorkerConfig_DefaultsWhenOnlyRequiredEnvSet(t *testing.T) {
 	if cfg.DryRun {
 		t.Error("DryRun: want false")
 	}
+	if cfg.RecapConcurrency != 1 || cfg.RecapMaxPerUser != 1 {
+		t.Errorf("RecapConcurrency/RecapMaxPerUser: want 1/1, got %d/%d", cfg.RecapConcurrency, cfg.RecapMaxPerUser)
+	}
 }
 
 func TestLoadWorkerConfig_ParsesCustomPollInterval(t *testing.T) {
@@ -73,6 +83,134 @@ func TestLoadWorkerConfig_KeepsDefaultPollIntervalWhenZeroOrNegative(t *testing.
 	}
 }
 
+func TestLoadWorkerConfig_AdaptivePollIntervals(t *testing.T) {
+	tests := []struct {
+		name               string
+		poll, base, lo, hi string
+		wantBase, wantLo   time.Duration
+		wantHi             time.Duration
+	}{
+		{name: "defaults are a fixed schedule", wantBase: 30 * time.Minute, wantLo: 30 * time.Minute, wantHi: 30 * time.Minute},
+		{name: "min and max follow WORKER_POLL_INTERVAL", poll: "10m", wantBase: 10 * time.Minute, wantLo: 10 * time.Minute, wantHi: 10 * time.Minute},
+		{name: "all three set", base: "5m", lo: "30s", hi: "1h", wantBase: 5 * time.Minute, wantLo: 30 * time.Second, wantHi: time.Hour},
+		{name: "base overrides WORKER_POLL_INTERVAL", poll: "10m", base: "5m", wantBase: 5 * time.Minute, wantLo: 5 * time.Minute, wantHi: 5 * time.Minute},
+		{name: "min above base and max below base are pulled to base", base: "5m", lo: "10m", hi: "1m", wantBase: 5 * time.Minute, wantLo: 5 * time.Minute, wantHi: 5 * time.Minute},
+		{name: "garbage and non-positive values are ignored", base: "soon", lo: "0s", hi: "-1h", wantBase: 30 * time.Minute, wantLo: 30 * time.Minute, wantHi: 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearServerEnv(t)
+			t.Setenv("WORKER_MAX_SESSIONS", "50")
+			for key, val := range map[string]string{
+				"WORKER_POLL_INTERVAL":     tt.poll,
+				"PRECOMPUTE_BASE_INTERVAL": tt.base,
+				"PRECOMPUTE_MIN_INTERVAL":  tt.lo,
+				"PRECOMPUTE_MAX_INTERVAL":  tt.hi,
+			} {
+				if val != "" {
+					t.Setenv(key, val)
+				}
+			}
+			cfg := loadWorkerConfig()
+			if cfg.PollInterval != tt.wantBase || cfg.MinPollInterval != tt.wantLo || cfg.MaxPollInterval != tt.wantHi {
+				t.Errorf("base/min/max = %s/%s/%s, want %s/%s/%s",
+					cfg.PollInterval, cfg.MinPollInterval, cfg.MaxPollInterval, tt.wantBase, tt.wantLo, tt.wantHi)
+			}
+		})
+	}
+}
+
+func TestAdaptivePollInterval(t *testing.T) {
+	const (
+		base = 10 * time.Minute
+		lo   = time.Minute
+		hi   = time.Hour
+	)
+	tests := []struct {
+		name         string
+		stale, conc  int
+		base, lo, hi time.Duration
+		want         time.Duration
+	}{
+		{name: "idle doubles the base", stale: 0, conc: 4, base: base, lo: lo, hi: hi, want: 20 * time.Minute},
+		{name: "idle is capped at max", stale: 0, conc: 4, base: base, lo: lo, hi: 15 * time.Minute, want: 15 * time.Minute},
+		{name: "one stale session keeps the base", stale: 1, conc: 4, base: base, lo: lo, hi: hi, want: base},
+		{name: "exactly twice concurrency keeps the base", stale: 8, conc: 4, base: base, lo: lo, hi: hi, want: base},
+		{name: "more than twice concurrency drops to min", stale: 9, conc: 4, base: base, lo: lo, hi: hi, want: lo},
+		{name: "zero concurrency counts as one", stale: 3, conc: 0, base: base, lo: lo, hi: hi, want: lo},
+		{name: "zero concurrency at the threshold keeps the base", stale: 2, conc: 0, base: base, lo: lo, hi: hi, want: base},
+		{name: "fixed schedule never moves (idle)", stale: 0, conc: 1, base: base, lo: base, hi: base, want: base},
+		{name: "fixed schedule never moves (backlog)", stale: 500, conc: 1, base: base, lo: base, hi: base, want: base},
+		{name: "base below min is raised to min", stale: 1, conc: 4, base: 30 * time.Second, lo: lo, hi: hi, want: lo},
+		{name: "base above max is lowered to max", stale: 1, conc: 4, base: 2 * time.Hour, lo: lo, hi: hi, want: hi},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := adaptivePollInterval(tt.stale, tt.conc, tt.base, tt.lo, tt.hi); got != tt.want {
+				t.Errorf("adaptivePollInterval(%d, %d, %s, %s, %s) = %s, want %s",
+					tt.stale, tt.conc, tt.base, tt.lo, tt.hi, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadWorkerConfig_AdaptiveBatchSizes(t *testing.T) {
+	tests := []struct {
+		name           string
+		lo, hi         string
+		wantLo, wantHi int
+	}{
+		{name: "defaults are a fixed batch", wantLo: 50, wantHi: 50},
+		{name: "both set", lo: "10", hi: "500", wantLo: 10, wantHi: 500},
+		{name: "min above and max below WORKER_MAX_SESSIONS are pulled to it", lo: "80", hi: "20", wantLo: 50, wantHi: 50},
+		{name: "garbage and non-positive values are ignored", lo: "many", hi: "0", wantLo: 50, wantHi: 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearServerEnv(t)
+			t.Setenv("WORKER_MAX_SESSIONS", "50")
+			if tt.lo != "" {
+				t.Setenv("PRECOMPUTE_MIN_BATCH_SIZE", tt.lo)
+			}
+			if tt.hi != "" {
+				t.Setenv("PRECOMPUTE_MAX_BATCH_SIZE", tt.hi)
+			}
+			cfg := loadWorkerConfig()
+			if cfg.MinBatchSize != tt.wantLo || cfg.MaxBatchSize != tt.wantHi {
+				t.Errorf("min/max batch = %d/%d, want %d/%d", cfg.MinBatchSize, cfg.MaxBatchSize, tt.wantLo, tt.wantHi)
+			}
+		})
+	}
+}
+
+func TestAdaptiveBatchSize(t *testing.T) {
+	const base = 10 * time.Minute
+	tests := []struct {
+		name           string
+		found, current int
+		elapsed        time.Duration
+		lo, hi         int
+		want           int
+	}{
+		{name: "full batch doubles", found: 20, current: 20, elapsed: time.Minute, lo: 5, hi: 100, want: 40},
+		{name: "full batch is capped at max", found: 80, current: 80, elapsed: time.Minute, lo: 5, hi: 100, want: 100},
+		{name: "partial batch keeps the size", found: 7, current: 20, elapsed: time.Minute, lo: 5, hi: 100, want: 20},
+		{name: "idle halves", found: 0, current: 20, elapsed: time.Second, lo: 5, hi: 100, want: 10},
+		{name: "idle is floored at min", found: 0, current: 8, elapsed: time.Second, lo: 5, hi: 100, want: 5},
+		{name: "slow cycle halves even when full", found: 20, current: 20, elapsed: 11 * time.Minute, lo: 5, hi: 100, want: 10},
+		{name: "cycle of exactly the base interval is not slow", found: 20, current: 20, elapsed: base, lo: 5, hi: 100, want: 40},
+		{name: "fixed batch never moves", found: 20, current: 20, elapsed: time.Minute, lo: 20, hi: 20, want: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := adaptiveBatchSize(tt.found, tt.current, tt.elapsed, base, tt.lo, tt.hi); got != tt.want {
+				t.Errorf("adaptiveBatchSize(%d, %d, %s, %s, %d, %d) = %d, want %d",
+					tt.found, tt.current, tt.elapsed, base, tt.lo, tt.hi, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestLoadWorkerConfig_ParsesCustomMaxSearchIndexSessions(t *testing.T) {
 	clearServerEnv(t)
 	t.Setenv("WORKER_MAX_SESSIONS", "50")
@@ -85,6 +223,34 @@ func TestLoadWorkerConfig_ParsesCustomMaxSearchIndexSessions(t *testing.T) {
 	}
 }
 
+func TestLoadWorkerConfig_ParsesRecapConcurrencyAndPerUserCap(t *testing.T) {
+	clearServerEnv(t)
+	t.Setenv("WORKER_MAX_SESSIONS", "50")
+	t.Setenv("WORKER_RECAP_CONCURRENCY", "4")
+	t.Setenv("WORKER_RECAP_MAX_PER_USER", "2")
+
+	cfg := loadWorkerConfig()
+
+	if cfg.RecapConcurrency != 4 || cfg.RecapMaxPerUser != 2 {
+		t.Errorf("RecapConcurrency/RecapMaxPerUser: want 4/2, got %d/%d", cfg.RecapConcurrency, cfg.RecapMaxPerUser)
+	}
+}
+
+func TestLoadWorkerConfig_KeepsDefaultRecapLimitsWhenGarbageOrNonpositive(t *testing.T) {
+	for _, v := range []string{"abc", "0", "-3"} {
+		t.Run(v, func(t *testing.T) {
+			clearServerEnv(t)
+			t.Setenv("WORKER_MAX_SESSIONS", "50")
+			t.Setenv("WORKER_RECAP_CONCURRENCY", v)
+			t.Setenv("WORKER_RECAP_MAX_PER_USER", v)
+			cfg := loadWorkerConfig()
+			if cfg.RecapConcurrency != 1 || cfg.RecapMaxPerUser != 1 {
+				t.Errorf("for %q: want 1/1 defaults, got %d/%d", v, cfg.RecapConcurrency, cfg.RecapMaxPerUser)
+			}
+		})
+	}
+}
+
 func TestLoadWorkerConfig_KeepsDefaultMaxSearchIndexSessionsWhenGarbageOrNonpositive(t *testing.T) {
 	cases := []string{"abc", "0", "-3"}
 	for _, v := range cases {
@@ -165,6 +331,189 @@ func TestLoadWorkerConfig_KeepsDefaultShareRetentionWhenGarbageOrNonpositive(t *
 	}
 }
 
+func TestLoadWorkerConfig_DefaultsRetentionPolicies(t *testing.T) {
+	clearServerEnv(t)
+	t.Setenv("WORKER_MAX_SESSIONS", "50")
+
+	cfg := loadWorkerConfig()
+
+	if !reflect.DeepEqual(cfg.RetentionPolicies, dbretention.Defaults) {
+		t.Errorf("RetentionPolicies: want defaults, got %+v", cfg.RetentionPolicies)
+	}
+	if cfg.PruneBatchSize != 1000 || cfg.PruneMaxRows != 10000 {
+		t.Errorf("PruneBatchSize/PruneMaxRows: want 1000/10000, got %d/%d", cfg.PruneBatchSize, cfg.PruneMaxRows)
+	}
+}
+
+func TestLoadWorkerConfig_ParsesRetentionOverrides(t *testing.T) {
+	clearServerEnv(t)
+	t.Setenv("WORKER_MAX_SESSIONS", "50")
+	t.Setenv("WORKER_RETENTION_WEB_SESSIONS", "48h")
+	t.Setenv("WORKER_RETENTION_DEVICE_CODES", "0")
+	t.Setenv("WORKER_RETENTION_ADMIN_CARD_INVALIDATIONS", "30d") // garbage: keeps default
+	t.Setenv("WORKER_PRUNE_BATCH_SIZE", "250")
+	t.Setenv("WORKER_PRUNE_MAX_ROWS", "-1") // non-positive: keeps default
+
+	cfg := loadWorkerConfig()
+
+	got := map[string]time.Duration{}
+	for _, p := range cfg.RetentionPolicies {
+		got[p.Table] = p.Retention
+	}
+	if got["web_sessions"] != 48*time.Hour {
+		t.Errorf("web_sessions retention: want 48h, got %s", got["web_sessions"])
+	}
+	if got["device_codes"] != 0 {
+		t.Errorf("device_codes retention: want 0 (disabled), got %s", got["device_codes"])
+	}
+	if got["admin_card_invalidations"] != 365*24*time.Hour {
+		t.Errorf("admin_card_invalidations retention: want default 8760h, got %s", got["admin_card_invalidations"])
+	}
+	if cfg.PruneBatchSize != 250 || cfg.PruneMaxRows != 10000 {
+		t.Errorf("PruneBatchSize/PruneMaxRows: want 250/10000, got %d/%d", cfg.PruneBatchSize, cfg.PruneMaxRows)
+	}
+	if dbretention.Defaults[1].Retention != 30*24*time.Hour {
+		t.Error("overrides must not mutate dbretention.Defaults")
+	}
+}
+
+func TestLoadWorkerConfig_TranscriptRetention(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention string
+		batch     string
+		want      time.Duration
+		wantBatch int
+	}{
+		{"unset is off", "", "", 0, 100},
+		{"parses hours", "2160h", "25", 2160 * time.Hour, 25},
+		{"garbage stays off", "90d", "lots", 0, 100},
+		{"zero stays off", "0", "0", 0, 100},
+		{"negative stays off", "-1h", "-5", 0, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearServerEnv(t)
+			t.Setenv("WORKER_MAX_SESSIONS", "50")
+			if tt.retention != "" {
+				t.Setenv("WORKER_TRANSCRIPT_RETENTION", tt.retention)
+			}
+			if tt.batch != "" {
+				t.Setenv("WORKER_TRANSCRIPT_ARCHIVE_BATCH", tt.batch)
+			}
+
+			cfg := loadWorkerConfig()
+			if cfg.TranscriptRetention != tt.want || cfg.TranscriptArchiveBatch != tt.wantBatch {
+				t.Errorf("TranscriptRetention/TranscriptArchiveBatch: want %s/%d, got %s/%d",
+					tt.want, tt.wantBatch, cfg.TranscriptRetention, cfg.TranscriptArchiveBatch)
+			}
+		})
+	}
+}
+
+func TestLoadWorkerConfig_StoredBytesBackfillBatch(t *testing.T) {
+	tests := []struct {
+		val  string
+		want int
+	}{
+		{"", 100},
+		{"25", 25},
+		{"lots", 100},
+		{"0", 100},
+		{"-5", 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.val, func(t *testing.T) {
+			clearServerEnv(t)
+			t.Setenv("WORKER_MAX_SESSIONS", "50")
+			if tt.val != "" {
+				t.Setenv("WORKER_STORED_BYTES_BACKFILL_BATCH", tt.val)
+			}
+			if got := loadWorkerConfig().StoredBytesBackfillBatch; got != tt.want {
+				t.Errorf("StoredBytesBackfillBatch for %q: want %d, got %d", tt.val, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadWorkerConfig_DormantBatchSize(t *testing.T) {
+	tests := []struct {
+		val  string
+		want int
+	}{
+		{"", 5},
+		{"20", 20},
+		{"0", 0},
+		{"lots", 5},
+		{"-1", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.val, func(t *testing.T) {
+			clearServerEnv(t)
+			t.Setenv("WORKER_MAX_SESSIONS", "50")
+			if tt.val != "" {
+				t.Setenv("PRECOMPUTE_DORMANT_BATCH_SIZE", tt.val)
+			}
+			if got := loadWorkerConfig().DormantBatchSize; got != tt.want {
+				t.Errorf("DormantBatchSize for %q: want %d, got %d", tt.val, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadWorkerConfig_ChunkReconcile(t *testing.T) {
+	tests := []struct {
+		after, batch string
+		wantAfter    time.Duration
+		wantBatch    int
+	}{
+		{"", "", 5 * time.Minute, 500},
+		{"15m", "50", 15 * time.Minute, 50},
+		{"soon", "lots", 5 * time.Minute, 500},
+		{"0", "0", 5 * time.Minute, 0},
+		{"-1m", "-5", 5 * time.Minute, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.after+"/"+tt.batch, func(t *testing.T) {
+			clearServerEnv(t)
+			t.Setenv("WORKER_MAX_SESSIONS", "50")
+			if tt.after != "" {
+				t.Setenv("WORKER_CHUNK_RECONCILE_AFTER", tt.after)
+			}
+			if tt.batch != "" {
+				t.Setenv("WORKER_CHUNK_RECONCILE_BATCH", tt.batch)
+			}
+			config := loadWorkerConfig()
+			if config.ChunkReconcileAfter != tt.wantAfter || config.ChunkReconcileBatch != tt.wantBatch {
+				t.Errorf("got after=%s batch=%d, want after=%s batch=%d",
+					config.ChunkReconcileAfter, config.ChunkReconcileBatch, tt.wantAfter, tt.wantBatch)
+			}
+		})
+	}
+}
+
+func TestLoadWebhookConfig(t *testing.T) {
+	clearServerEnv(t)
+	if got := loadWebhookConfig(); got != (webhooks.Config{}) {
+		t.Errorf("unset: got %+v, want zero config (dispatcher defaults)", got)
+	}
+
+	t.Setenv("WEBHOOK_POLL_INTERVAL", "30s")
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "3")
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "true")
+	want := webhooks.Config{PollInterval: 30 * time.Second, MaxAttempts: 3, AllowPrivateTargets: true}
+	if got := loadWebhookConfig(); got != want {
+		t.Errorf("set: got %+v, want %+v", got, want)
+	}
+
+	t.Setenv("WEBHOOK_POLL_INTERVAL", "-1s")
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "lots")
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "yes")
+	if got := loadWebhookConfig(); got != (webhooks.Config{}) {
+		t.Errorf("garbage: got %+v, want zero config", got)
+	}
+}
+
 func TestLoadWorkerConfig_FatalsWhenMaxSessionsMissing(t *testing.T) {
 	clearServerEnv(t)
 
@@ -271,6 +620,25 @@ func TestLoadS3Config_KeepsSSLEnabledForAnyOtherString(t *testing.T) {
 	}
 }
 
+func TestLoadS3Config_ReadFailover(t *testing.T) {
+	clearServerEnv(t)
+	t.Setenv("S3_ENDPOINT", "s3.example.com")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIA")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("BUCKET_NAME", "bucket")
+
+	if cfg := loadS3Config(); cfg.ReadFailoverEndpoint != "" || cfg.ReadFailoverBucket != "" {
+		t.Errorf("read failover default: want off, got %q / %q", cfg.ReadFailoverEndpoint, cfg.ReadFailoverBucket)
+	}
+
+	t.Setenv("S3_READ_FAILOVER_ENDPOINT", "s3.us-west-2.example.com")
+	t.Setenv("S3_READ_FAILOVER_BUCKET", "bucket-replica")
+	cfg := loadS3Config()
+	if cfg.ReadFailoverEndpoint != "s3.us-west-2.example.com" || cfg.ReadFailoverBucket != "bucket-replica" {
+		t.Errorf("read failover: got %q / %q", cfg.ReadFailoverEndpoint, cfg.ReadFailoverBucket)
+	}
+}
+
 func TestLoadS3Config_FatalsWhenS3EndpointMissing(t *testing.T) {
 	clearServerEnv(t)
 	t.Setenv("AWS_ACCESS_KEY_ID", "AKIA")
@@ -422,6 +790,26 @@ func TestLoadPrecomputeConfig_ParsesMaxOutputTokensAndTranscriptTokens(t *testin
 	}
 }
 
+func TestLoadPrecomputeConfig_ParsesRecapMinInterval(t *testing.T) {
+	for _, tc := range []struct {
+		raw  string
+		want time.Duration
+	}{
+		{"", 0},
+		{"2h", 2 * time.Hour},
+		{"0", 0},
+		{"-5m", 0},
+		{"soon", 0},
+	} {
+		clearServerEnv(t)
+		t.Setenv("WORKER_RECAP_MIN_INTERVAL", tc.raw)
+
+		if got := loadPrecomputeConfig().SmartRecapMinInterval; got != tc.want {
+			t.Errorf("WORKER_RECAP_MIN_INTERVAL=%q: SmartRecapMinInterval = %v, want %v", tc.raw, got, tc.want)
+		}
+	}
+}
+
 func TestLoadPrecomputeConfig_LoadsRegularAndSmartRecapThresholdsWithCorrectPrefixes(t *testing.T) {
 	clearServerEnv(t)
 	t.Setenv("WORKER_REGULAR_BASE_MIN_LINES", "42")
@@ -705,6 +1093,101 @@ func TestWorkerProcessSmartRecapSessions_CallsPrecomputeSmartRecapOnly(t *testin
 	}
 }
 
+func userSess(id string, userID int64) analytics.StaleSession {
+	s := sess(id)
+	s.UserID = userID
+	return s
+}
+
+// TestWorkerProcessSmartRecapSessions_CapsInFlightPerUser gives one user a
+// large backlog ahead of another user's sessions. With two slots and a per-user
+// cap of 1, the backlog user never holds both slots, so the second user's
+// sessions start before the backlog drains.
+func TestWorkerProcessSmartRecapSessions_CapsInFlightPerUser(t *testing.T) {
+	w := newTestWorker(&fakePrecomputer{}, WorkerConfig{MaxSessions: 10, RecapConcurrency: 2, RecapMaxPerUser: 1})
+	sessions := []analytics.StaleSession{
+		userSess("a1", 1), userSess("a2", 1), userSess("a3", 1), userSess("a4", 1),
+		userSess("b1", 2), userSess("b2", 2),
+	}
+
+	var mu sync.Mutex
+	inFlight := map[int64]int{}
+	maxInFlight := map[int64]int{}
+	var started []string
+	processed, errs := w.processSessionsPerUser(context.Background(), sessions, "smart recap",
+		func(_ context.Context, s analytics.StaleSession) error {
+			mu.Lock()
+			inFlight[s.UserID]++
+			maxInFlight[s.UserID] = max(maxInFlight[s.UserID], inFlight[s.UserID])
+			started = append(started, s.SessionID)
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			inFlight[s.UserID]--
+			mu.Unlock()
+			return nil
+		}, 0, w.config.RecapConcurrency, w.config.RecapMaxPerUser)
+
+	if processed != 6 || errs != 0 {
+		t.Fatalf("counts: processed=%d errors=%d, want 6/0", processed, errs)
+	}
+	if maxInFlight[1] != 1 || maxInFlight[2] != 1 {
+		t.Errorf("max in flight per user = %v, want 1 for each", maxInFlight)
+	}
+	// a1 and b1 are dispatched together; their start order is up to the scheduler.
+	if first := map[string]bool{started[0]: true, started[1]: true}; !first["a1"] || !first["b1"] {
+		t.Errorf("first two dispatches = %v, want a1 and b1", started[:2])
+	}
+	for userID, n := range w.recapInFlight {
+		if n != 0 {
+			t.Errorf("recapInFlight[%d] = %d after processing, want 0", userID, n)
+		}
+	}
+}
+
+// TestWorkerProcessSmartRecapSessions_PreservesInterleavedOrder asserts the
+// sequential default dispatches in discovery order, so the per-user interleave
+// from FindStaleSmartRecapSessions carries through to processing.
+func TestWorkerProcessSmartRecapSessions_PreservesInterleavedOrder(t *testing.T) {
+	fp := &fakePrecomputer{}
+	w := newTestWorker(fp, WorkerConfig{MaxSessions: 10})
+	sessions := []analytics.StaleSession{
+		userSess("a1", 1), userSess("b1", 2), userSess("a2", 1), userSess("b2", 2),
+	}
+
+	processed, _ := w.processSmartRecapSessions(context.Background(), sessions)
+
+	if processed != 4 {
+		t.Fatalf("processed: want 4, got %d", processed)
+	}
+	var got []string
+	for _, s := range fp.recapCalls {
+		got = append(got, s.SessionID)
+	}
+	if strings.Join(got, ",") != "a1,b1,a2,b2" {
+		t.Errorf("processing order = %v, want [a1 b1 a2 b2]", got)
+	}
+}
+
+func TestWorkerProcessSmartRecapSessions_ContextCancelStopsDispatch(t *testing.T) {
+	w := newTestWorker(&fakePrecomputer{}, WorkerConfig{MaxSessions: 10})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls int32
+	processed, _ := w.processSessionsPerUser(ctx, []analytics.StaleSession{sess("a"), sess("b"), sess("c")}, "smart recap",
+		func(context.Context, analytics.StaleSession) error {
+			atomic.AddInt32(&calls, 1)
+			cancel()
+			return nil
+		}, 0, 1, 1)
+
+	if atomic.LoadInt32(&calls) != 1 || processed != 1 {
+		t.Errorf("calls=%d processed=%d after cancel; want 1/1", calls, processed)
+	}
+}
+
 func TestWorkerProcessSearchIndexSessions_CallsBuildSearchIndexOnly(t *testing.T) {
 	fp := &fakePrecomputer{}
 	w := newTestWorker(fp, WorkerConfig{MaxSessions: 10})
@@ -725,7 +1208,9 @@ func TestWorkerProcessSearchIndexSessions_CallsBuildSearchIndexOnly(t *testing.T
 func TestWorkerRunOnce_NoStaleSessionsReturnsEarly(t *testing.T) {
 	fp := &fakePrecomputer{} // all Find* default to empty
 	w := newTestWorker(fp, WorkerConfig{MaxSessions: 10, MaxSearchIndexSessions: 10})
-	w.runOnce(context.Background())
+	if cycle, ok := w.runOnce(context.Background()); cycle.stale != 0 || !ok {
+		t.Errorf("runOnce = (%d stale, %v), want (0, true)", cycle.stale, ok)
+	}
 
 	if fp.findStaleCalls != 1 || fp.findSmartRecapCalls != 1 || fp.findSearchIndexCalls != 1 {
 		t.Errorf("Find* calls: stale=%d recap=%d search=%d; want 1/1/1",
@@ -736,6 +1221,56 @@ func TestWorkerRunOnce_NoStaleSessionsReturnsEarly(t *testing.T) {
 	}
 }
 
+type fakeNotificationFlusher struct {
+	limits []int
+}
+
+func (f *fakeNotificationFlusher) FlushDue(_ context.Context, limit int) (int, error) {
+	f.limits = append(f.limits, limit)
+	return 0, nil
+}
+
+func TestWorkerRunOnce_FlushesQueuedNotifications(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		cfg    WorkerConfig
+		wantOn bool
+	}{
+		{"enabled", WorkerConfig{MaxSessions: 10, NotificationFlushBatch: 25}, true},
+		{"batch 0 disables", WorkerConfig{MaxSessions: 10}, false},
+		{"dry run skips", WorkerConfig{MaxSessions: 10, NotificationFlushBatch: 25, DryRun: true}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			flusher := &fakeNotificationFlusher{}
+			w := newTestWorker(&fakePrecomputer{}, tt.cfg)
+			w.notifier = flusher
+			w.runOnce(context.Background())
+			if got := len(flusher.limits) == 1 && flusher.limits[0] == 25; got != tt.wantOn {
+				t.Errorf("FlushDue calls = %v, want flushed=%v with limit 25", flusher.limits, tt.wantOn)
+			}
+		})
+	}
+}
+
+func TestWorkerRunOnce_ModelPricingRefreshFailureDoesNotAbort(t *testing.T) {
+	fp := &fakePrecomputer{
+		refreshPricingFn: func(context.Context) error { return errors.New("db down") },
+		findStaleFn: func(context.Context, int) ([]analytics.StaleSession, error) {
+			return []analytics.StaleSession{sess("a")}, nil
+		},
+	}
+	w := newTestWorker(fp, WorkerConfig{MaxSessions: 10, MaxSearchIndexSessions: 10})
+	if _, ok := w.runOnce(context.Background()); !ok {
+		t.Error("runOnce reported a failed cycle after a model pricing refresh error")
+	}
+	if fp.refreshPricingCalls != 1 {
+		t.Errorf("RefreshModelPricing calls = %d, want 1", fp.refreshPricingCalls)
+	}
+	if len(fp.regularCalls) != 1 {
+		t.Errorf("PrecomputeRegularCards calls = %d, want 1", len(fp.regularCalls))
+	}
+}
+
 func TestWorkerRunOnce_DryRunModeLogsButDoesNotProcess(t *testing.T) {
 	fp := &fakePrecomputer{
 		findStaleFn: func(context.Context, int) ([]analytics.StaleSession, error) {
@@ -749,7 +1284,9 @@ func TestWorkerRunOnce_DryRunModeLogsButDoesNotProcess(t *testing.T) {
 		},
 	}
 	w := newTestWorker(fp, WorkerConfig{MaxSessions: 10, MaxSearchIndexSessions: 10, DryRun: true})
-	w.runOnce(context.Background())
+	if cycle, ok := w.runOnce(context.Background()); cycle.stale != 3 || !ok {
+		t.Errorf("runOnce = (%d stale, %v), want (3, true)", cycle.stale, ok)
+	}
 
 	if len(fp.regularCalls) != 0 || len(fp.recapCalls) != 0 || len(fp.searchIdxCalls) != 0 {
 		t.Errorf("dry-run must skip processing; regular=%d recap=%d search=%d",
@@ -770,7 +1307,9 @@ func TestWorkerRunOnce_ProcessesAllThreeBuckets(t *testing.T) {
 		},
 	}
 	w := newTestWorker(fp, WorkerConfig{MaxSessions: 10, MaxSearchIndexSessions: 10})
-	w.runOnce(context.Background())
+	if cycle, ok := w.runOnce(context.Background()); cycle.stale != 6 || !ok {
+		t.Errorf("runOnce = (%d stale, %v), want (6, true)", cycle.stale, ok)
+	}
 
 	if len(fp.regularCalls) != 2 {
 		t.Errorf("regular bucket: want 2 calls, got %d", len(fp.regularCalls))
@@ -783,6 +1322,97 @@ func TestWorkerRunOnce_ProcessesAllThreeBuckets(t *testing.T) {
 	}
 }
 
+func TestWorkerRunOnce_GrowingBacklogRaisesBatchToMax(t *testing.T) {
+	// Every lookup fills the batch it asked for: the backlog outgrows it.
+	var limits []int
+	fp := &fakePrecomputer{
+		findStaleFn: func(_ context.Context, limit int) ([]analytics.StaleSession, error) {
+			limits = append(limits, limit)
+			found := make([]analytics.StaleSession, limit)
+			for i := range found {
+				found[i] = sess(fmt.Sprintf("s%d", i))
+			}
+			return found, nil
+		},
+	}
+	w := newTestWorker(fp, WorkerConfig{
+		PollInterval: time.Hour, MaxSessions: 10, MinBatchSize: 10, MaxBatchSize: 50,
+		MaxSearchIndexSessions: 10, DryRun: true,
+	})
+	for range 5 {
+		cycle, ok := w.runOnce(context.Background())
+		if !ok {
+			t.Fatal("runOnce failed")
+		}
+		w.adaptBatchSize(cycle, time.Millisecond)
+	}
+
+	if want := []int{10, 20, 40, 50, 50}; !slices.Equal(limits, want) {
+		t.Errorf("requested batches = %v, want %v", limits, want)
+	}
+}
+
+func TestWorkerRunOnce_ProcessesDormantSessionsWhenQueuesEmpty(t *testing.T) {
+	var gotLimit int
+	fp := &fakePrecomputer{
+		findDormantFn: func(_ context.Context, limit int) ([]analytics.StaleSession, error) {
+			gotLimit = limit
+			return []analytics.StaleSession{sess("d1"), sess("d2")}, nil
+		},
+	}
+	w := newTestWorker(fp, WorkerConfig{MaxSessions: 10, MaxSearchIndexSessions: 10, DormantBatchSize: 5})
+	w.runOnce(context.Background())
+
+	if fp.findDormantCalls != 1 || gotLimit != 5 {
+		t.Errorf("FindDormantStaleSessions: calls=%d limit=%d; want 1 call with limit 5", fp.findDormantCalls, gotLimit)
+	}
+	if len(fp.regularCalls) != 2 || fp.regularCalls[0].SessionID != "d1" {
+		t.Errorf("PrecomputeRegularCards calls: %+v; want d1, d2", fp.regularCalls)
+	}
+}
+
+func TestWorkerRunOnce_SkipsDormantSessionsWhenAnyQueueHasWork(t *testing.T) {
+	for name, fp := range map[string]*fakePrecomputer{
+		"regular": {findStaleFn: func(context.Context, int) ([]analytics.StaleSession, error) {
+			return []analytics.StaleSession{sess("r1")}, nil
+		}},
+		"smart recap": {findSmartRecapFn: func(context.Context, int) ([]analytics.StaleSession, error) {
+			return []analytics.StaleSession{sess("sr1")}, nil
+		}},
+		"search index": {findSearchIndexFn: func(context.Context, int) ([]analytics.StaleSession, error) {
+			return []analytics.StaleSession{sess("si1")}, nil
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := newTestWorker(fp, WorkerConfig{MaxSessions: 10, MaxSearchIndexSessions: 10, DormantBatchSize: 5})
+			w.runOnce(context.Background())
+			if fp.findDormantCalls != 0 {
+				t.Errorf("FindDormantStaleSessions called %d times with a non-empty %s queue", fp.findDormantCalls, name)
+			}
+		})
+	}
+}
+
+func TestWorkerRunOnce_DormantBatchDisabledOrDryRun(t *testing.T) {
+	fp := &fakePrecomputer{
+		findDormantFn: func(context.Context, int) ([]analytics.StaleSession, error) {
+			return []analytics.StaleSession{sess("d1")}, nil
+		},
+	}
+	newTestWorker(fp, WorkerConfig{MaxSessions: 10, MaxSearchIndexSessions: 10}).runOnce(context.Background())
+	if fp.findDormantCalls != 0 {
+		t.Errorf("DormantBatchSize 0: FindDormantStaleSessions called %d times", fp.findDormantCalls)
+	}
+
+	newTestWorker(fp, WorkerConfig{MaxSessions: 10, MaxSearchIndexSessions: 10, DormantBatchSize: 5, DryRun: true}).runOnce(context.Background())
+	if fp.findDormantCalls != 1 {
+		t.Errorf("dry-run: FindDormantStaleSessions calls=%d, want 1", fp.findDormantCalls)
+	}
+	if len(fp.regularCalls) != 0 {
+		t.Error("dry-run must not precompute dormant sessions")
+	}
+}
+
 func TestWorkerRunOnce_FindStaleSessionsErrorSkipsRemainingBuckets(t *testing.T) {
 	// Pins current behavior: an error in bucket 1's Find* aborts the whole cycle.
 	fp := &fakePrecomputer{
@@ -791,7 +1421,9 @@ func TestWorkerRunOnce_FindStaleSessionsErrorSkipsRemainingBuckets(t *testing.T)
 		},
 	}
 	w := newTestWorker(fp, WorkerConfig{MaxSessions: 10, MaxSearchIndexSessions: 10})
-	w.runOnce(context.Background())
+	if _, ok := w.runOnce(context.Background()); ok {
+		t.Error("runOnce reported ok after a failed lookup")
+	}
 
 	if fp.findSmartRecapCalls != 0 {
 		t.Errorf("FindStaleSmartRecapSessions should not be called when bucket1 fails; calls=%d", fp.findSmartRecapCalls)
@@ -906,3 +1538,223 @@ func TestWorkerRun_TickerFiresSubsequentCycles(t *testing.T) {
 		t.Errorf("cycles: want >= 2 with 10ms ticker over 80ms, got %d", got)
 	}
 }
+
+func TestWorkerRun_BacklogPollsAtMinInterval(t *testing.T) {
+	var cycles int32
+	fp := &fakePrecomputer{
+		findStaleFn: func(context.Context, int) ([]analytics.StaleSession, error) {
+			atomic.AddInt32(&cycles, 1)
+			return []analytics.StaleSession{sess("a"), sess("b"), sess("c")}, nil
+		},
+	}
+	w := newTestWorker(fp, WorkerConfig{
+		MaxSessions: 10, MaxSearchIndexSessions: 10, RecapConcurrency: 1, DryRun: true,
+		PollInterval:    time.Hour, // never reached: 3 stale > 2 × concurrency
+		MinPollInterval: 10 * time.Millisecond,
+		MaxPollInterval: time.Hour,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 80*time.Millisecond)
+	defer cancel()
+	w.Run(ctx)
+
+	if got := atomic.LoadInt32(&cycles); got < 2 {
+		t.Errorf("cycles: want >= 2 at the 10ms min interval over 80ms, got %d", got)
+	}
+}
+
+func TestLoadWorkerConfig_DataExport(t *testing.T) {
+	tests := []struct {
+		batch, ttl string
+		wantBatch  int
+		wantTTL    time.Duration
+	}{
+		{"", "", 2, 72 * time.Hour},
+		{"5", "24h", 5, 24 * time.Hour},
+		{"0", "", 0, 72 * time.Hour},
+		{"-1", "soon", 2, 72 * time.Hour},
+		{"", "720h", 2, storage.MaxPresignExpiry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.batch+"/"+tt.ttl, func(t *testing.T) {
+			clearServerEnv(t)
+			t.Setenv("WORKER_MAX_SESSIONS", "50")
+			if tt.batch != "" {
+				t.Setenv("WORKER_DATA_EXPORT_BATCH", tt.batch)
+			}
+			if tt.ttl != "" {
+				t.Setenv("WORKER_DATA_EXPORT_TTL", tt.ttl)
+			}
+			cfg := loadWorkerConfig()
+			if cfg.DataExportBatch != tt.wantBatch || cfg.DataExportTTL != tt.wantTTL {
+				t.Errorf("DataExportBatch/DataExportTTL: want %d/%s, got %d/%s",
+					tt.wantBatch, tt.wantTTL, cfg.DataExportBatch, cfg.DataExportTTL)
+			}
+		})
+	}
+}
+
+func TestLoadWorkerConfig_SessionSweep(t *testing.T) {
+	tests := []struct {
+		idle, ended, batch string
+		wantIdle, wantEnd  time.Duration
+		wantBatch          int
+	}{
+		{"", "", "", 30 * time.Minute, 24 * time.Hour, 500},
+		{"1h", "72h", "50", time.Hour, 72 * time.Hour, 50},
+		{"0", "0s", "0", 0, 0, 0},
+		{"soon", "-1h", "-1", 30 * time.Minute, 24 * time.Hour, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.idle+"/"+tt.ended+"/"+tt.batch, func(t *testing.T) {
+			clearServerEnv(t)
+			t.Setenv("WORKER_MAX_SESSIONS", "50")
+			if tt.idle != "" {
+				t.Setenv("WORKER_SESSION_IDLE_AFTER", tt.idle)
+			}
+			if tt.ended != "" {
+				t.Setenv("WORKER_SESSION_ENDED_AFTER", tt.ended)
+			}
+			if tt.batch != "" {
+				t.Setenv("WORKER_SESSION_SWEEP_BATCH", tt.batch)
+			}
+			cfg := loadWorkerConfig()
+			if cfg.SessionIdleAfter != tt.wantIdle || cfg.SessionEndedAfter != tt.wantEnd || cfg.SessionSweepBatch != tt.wantBatch {
+				t.Errorf("SessionIdleAfter/SessionEndedAfter/SessionSweepBatch: want %s/%s/%d, got %s/%s/%d",
+					tt.wantIdle, tt.wantEnd, tt.wantBatch,
+					cfg.SessionIdleAfter, cfg.SessionEndedAfter, cfg.SessionSweepBatch)
+			}
+		})
+	}
+}
+
+func TestLoadWorkerConfig_NotificationFlushBatch(t *testing.T) {
+	for value, want := range map[string]int{"": 200, "50": 50, "0": 0, "-1": 200, "lots": 200} {
+		t.Run(value, func(t *testing.T) {
+			clearServerEnv(t)
+			t.Setenv("WORKER_MAX_SESSIONS", "50")
+			if value != "" {
+				t.Setenv("WORKER_NOTIFICATION_FLUSH_BATCH", value)
+			}
+			if got := loadWorkerConfig().NotificationFlushBatch; got != want {
+				t.Errorf("NotificationFlushBatch = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadWorkerConfig_ShutdownTimeout(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 10 * time.Second},
+		{"30s", 30 * time.Second},
+		{"0", 0},
+		{"soon", 10 * time.Second},
+		{"-5s", 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			clearServerEnv(t)
+			t.Setenv("WORKER_MAX_SESSIONS", "50")
+			if tt.value != "" {
+				t.Setenv("WORKER_SHUTDOWN_TIMEOUT", tt.value)
+			}
+			if got := loadWorkerConfig().ShutdownTimeout; got != tt.want {
+				t.Errorf("ShutdownTimeout: want %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// ---------- Shutdown drain ----------
+
+func TestWithDrain_OutlivesParentUntilGrace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	drainCtx, stop := withDrain(ctx, 50*time.Millisecond)
+	defer stop()
+
+	cancel()
+	select {
+	case <-drainCtx.Done():
+		t.Fatal("drain ctx cancelled with its parent; want it to outlive it")
+	case <-time.After(10 * time.Millisecond):
+	}
+	select {
+	case <-drainCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("drain ctx still live after the grace period")
+	}
+}
+
+func TestWithDrain_StopCancels(t *testing.T) {
+	drainCtx, stop := withDrain(context.Background(), time.Hour)
+	stop()
+	if drainCtx.Err() == nil {
+		t.Error("drain ctx live after stop")
+	}
+}
+
+func TestWorkerProcessSessions_ShutdownLetsInFlightSessionFinish(t *testing.T) {
+	w := newTestWorker(&fakePrecomputer{}, WorkerConfig{MaxSessions: 10, ShutdownTimeout: time.Second})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls int32
+	processed, errs := w.processSessions(ctx, []analytics.StaleSession{sess("a"), sess("b")}, "test",
+		func(pctx context.Context, _ analytics.StaleSession) error {
+			atomic.AddInt32(&calls, 1)
+			cancel()
+			// Still usable: the session finishes rather than aborting.
+			time.Sleep(20 * time.Millisecond)
+			return pctx.Err()
+		}, 0)
+
+	if calls != 1 || processed != 1 || errs != 0 {
+		t.Errorf("calls=%d processed=%d errors=%d, want 1/1/0", calls, processed, errs)
+	}
+}
+
+func TestWorkerProcessSessions_ShutdownAbortsInFlightSessionAfterGrace(t *testing.T) {
+	w := newTestWorker(&fakePrecomputer{}, WorkerConfig{MaxSessions: 10, ShutdownTimeout: 20 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	start := time.Now()
+	processed, errs := w.processSessions(ctx, []analytics.StaleSession{sess("a")}, "test",
+		func(pctx context.Context, _ analytics.StaleSession) error {
+			cancel()
+			<-pctx.Done()
+			return pctx.Err()
+		}, 0)
+
+	if processed != 0 || errs != 1 {
+		t.Errorf("processed=%d errors=%d, want 0/1", processed, errs)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("in-flight session ran %s past a 20ms grace", elapsed)
+	}
+}
+
+func TestWorkerProcessSmartRecapSessions_ShutdownLetsRunningSessionsFinish(t *testing.T) {
+	w := newTestWorker(&fakePrecomputer{}, WorkerConfig{MaxSessions: 10, ShutdownTimeout: time.Second})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls int32
+	started := make(chan struct{}, 2)
+	processed, errs := w.processSessionsPerUser(ctx,
+		[]analytics.StaleSession{sess("a"), {SessionID: "b", UserID: 2}, sess("c")}, "smart recap",
+		func(pctx context.Context, _ analytics.StaleSession) error {
+			atomic.AddInt32(&calls, 1)
+			started <- struct{}{}
+			if len(started) == 2 {
+				cancel()
+			}
+			time.Sleep(20 * time.Millisecond)
+			return pctx.Err()
+		}, 0, 2, 1)
+
+	// a and b were running when the shutdown came; both finish, c never starts.
+	if calls != 2 || processed != 2 || errs != 0 {
+		t.Errorf("calls=%d processed=%d errors=%d, want 2/2/0", calls, processed, errs)
+	}
+}
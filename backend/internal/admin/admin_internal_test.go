@@ -1,8 +1,10 @@
 package admin
 
 import (
+	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 )
 
 // resetSuperAdminCache clears the package-level cache so a test exercises the
@@ -125,3 +127,41 @@ func TestWouldOrphanLastAdmin(t *testing.T) {
 		t.Error("sole admin who remains effective must NOT be blocked")
 	}
 }
+
+func TestParseLeaderboardRange(t *testing.T) {
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		query    string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{"defaults to the last 30 days", "", now.Add(-30 * 24 * time.Hour), now, false},
+		{"explicit window", "from=2026-03-01T00:00:00Z&to=2026-03-08T00:00:00Z",
+			time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), false},
+		{"from defaults relative to to", "to=2026-03-08T00:00:00Z",
+			time.Date(2026, 2, 6, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), false},
+		{"missing timezone", "from=2026-03-01T00:00:00", time.Time{}, time.Time{}, true},
+		{"to before from", "from=2026-03-08T00:00:00Z&to=2026-03-01T00:00:00Z", time.Time{}, time.Time{}, true},
+		{"range too long", "from=2024-01-01T00:00:00Z&to=2026-03-01T00:00:00Z", time.Time{}, time.Time{}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/analytics/leaderboard?"+c.query, nil)
+			got, err := parseLeaderboardRange(r, now)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.From.Equal(c.wantFrom) || !got.To.Equal(c.wantTo) {
+				t.Errorf("got [%v, %v), want [%v, %v)", got.From, got.To, c.wantFrom, c.wantTo)
+			}
+		})
+	}
+}
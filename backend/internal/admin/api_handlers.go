@@ -50,6 +50,9 @@ type AdminUserJSON struct {
 	// env super-admins (5k4v).
 	IsAdmin      bool `json:"is_admin"`
 	IsSuperAdmin bool `json:"is_super_admin"`
+	// RateLimitTier is the users.rate_limit_tier column, set with
+	// PUT /api/v1/admin/users/{id}/rate-limit-tier.
+	RateLimitTier string `json:"rate_limit_tier"`
 }
 
 // AdminTotals are system-wide aggregate stats
@@ -165,6 +168,7 @@ func (h *Handlers) HandleListUsersAPI(w http.ResponseWriter, r *http.Request) {
 			CreatedAt:       user.CreatedAt.Format(time.RFC3339),
 			IsAdmin:         user.IsAdmin,
 			IsSuperAdmin:    IsSuperAdmin(user.Email),
+			RateLimitTier:   string(user.RateLimitTier),
 		})
 	}
 
@@ -388,6 +392,75 @@ func (h *Handlers) setUserAdminAPI(w http.ResponseWriter, r *http.Request, isAdm
 	})
 }
 
+// SetRateLimitTierRequest is the request body for
+// PUT /api/v1/admin/users/{id}/rate-limit-tier.
+type SetRateLimitTierRequest struct {
+	Tier models.RateLimitTier `json:"tier"`
+}
+
+// RateLimitTierChangeResponse is returned by the set-rate-limit-tier endpoint.
+type RateLimitTierChangeResponse struct {
+	ID            int64                `json:"id"`
+	RateLimitTier models.RateLimitTier `json:"rate_limit_tier"`
+}
+
+// HandleSetRateLimitTierAPI moves a user to another rate limit tier. The
+// per-user limiters on the sync and external API routes apply the new tier's
+// limits from the user's next request.
+func (h *Handlers) HandleSetRateLimitTierAPI(w http.ResponseWriter, r *http.Request) {
+	log := logger.Ctx(r.Context())
+
+	userID, err := parseUserID(r)
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req SetRateLimitTierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !req.Tier.Valid() {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid rate limit tier")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	userStore := &dbuser.Store{DB: h.DB}
+
+	targetUser, err := userStore.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			httputil.RespondError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		log.Error("Failed to load target user", "error", err, "user_id", userID)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to load user")
+		return
+	}
+
+	if err := userStore.SetUserRateLimitTier(ctx, userID, req.Tier); err != nil {
+		log.Error("Failed to set user rate limit tier", "error", err, "user_id", userID, "tier", req.Tier)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to update rate limit tier")
+		return
+	}
+
+	AuditLogFromRequest(r, h.DB, ActionUserSetTier, map[string]interface{}{
+		"target_user_id":    userID,
+		"target_user_email": targetUser.Email,
+		"previous_tier":     targetUser.RateLimitTier,
+		"tier":              req.Tier,
+	})
+
+	httputil.RespondJSON(w, http.StatusOK, RateLimitTierChangeResponse{
+		ID:            userID,
+		RateLimitTier: req.Tier,
+	})
+}
+
 // guardLastAdmin blocks (409 Conflict) an action that would leave the system
 // with zero effective admins (g0bq, audit E5). Effective admins are active users
 // who can reach the panel via the union gate (is_admin OR SUPER_ADMIN_EMAILS).
@@ -1591,3 +1591,120 @@ func TestAdminList_AdminFlags(t *testing.T) {
 		t.Errorf("plain: is_admin=%v is_super_admin=%v, want false/false", got.IsAdmin, got.IsSuperAdmin)
 	}
 }
+
+// ===================================================================
+// PUT /api/v1/admin/users/{id}/rate-limit-tier
+// ===================================================================
+
+func TestAdminSetRateLimitTier(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	env := testutil.SetupTestEnvironment(t)
+
+	listTier := func(t *testing.T, client *testutil.TestClient, email string) string {
+		t.Helper()
+		resp, err := client.Get("/api/v1/admin/users")
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var body struct {
+			Users []struct {
+				Email         string `json:"email"`
+				RateLimitTier string `json:"rate_limit_tier"`
+			} `json:"users"`
+		}
+		testutil.ParseJSON(t, resp, &body)
+		for _, u := range body.Users {
+			if u.Email == email {
+				return u.RateLimitTier
+			}
+		}
+		t.Fatalf("%s not in admin user list", email)
+		return ""
+	}
+
+	t.Run("users start standard; admin moves them to another tier", func(t *testing.T) {
+		env.CleanDB(t)
+		testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "super@example.com")
+		super := testutil.CreateTestUser(t, env, "super@example.com", "Super")
+		target := testutil.CreateTestUser(t, env, "target@example.com", "Target")
+
+		ts := setupTestServer(t, env)
+		client := adminClient(t, env, ts, super.ID)
+
+		if got := listTier(t, client, "target@example.com"); got != "standard" {
+			t.Fatalf("initial tier = %q, want standard", got)
+		}
+
+		resp, err := client.Request(http.MethodPut, fmt.Sprintf("/api/v1/admin/users/%d/rate-limit-tier", target.ID), map[string]string{"tier": "trusted"})
+		if err != nil {
+			t.Fatalf("set tier: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var body struct {
+			ID            int64  `json:"id"`
+			RateLimitTier string `json:"rate_limit_tier"`
+		}
+		testutil.ParseJSON(t, resp, &body)
+		if body.ID != target.ID || body.RateLimitTier != "trusted" {
+			t.Errorf("response = %+v, want id %d tier trusted", body, target.ID)
+		}
+
+		if got := listTier(t, client, "target@example.com"); got != "trusted" {
+			t.Errorf("tier after set = %q, want trusted", got)
+		}
+	})
+
+	t.Run("unknown tier is rejected", func(t *testing.T) {
+		env.CleanDB(t)
+		testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "super@example.com")
+		super := testutil.CreateTestUser(t, env, "super@example.com", "Super")
+		target := testutil.CreateTestUser(t, env, "target@example.com", "Target")
+
+		ts := setupTestServer(t, env)
+		client := adminClient(t, env, ts, super.ID)
+
+		for _, tier := range []string{"", "platinum", "Trusted"} {
+			resp, err := client.Request(http.MethodPut, fmt.Sprintf("/api/v1/admin/users/%d/rate-limit-tier", target.ID), map[string]string{"tier": tier})
+			if err != nil {
+				t.Fatalf("set tier: %v", err)
+			}
+			testutil.RequireStatus(t, resp, http.StatusBadRequest)
+		}
+		if got := listTier(t, client, "target@example.com"); got != "standard" {
+			t.Errorf("tier after rejected sets = %q, want standard", got)
+		}
+	})
+
+	t.Run("unknown user is 404", func(t *testing.T) {
+		env.CleanDB(t)
+		testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "super@example.com")
+		super := testutil.CreateTestUser(t, env, "super@example.com", "Super")
+
+		ts := setupTestServer(t, env)
+		client := adminClient(t, env, ts, super.ID)
+
+		resp, err := client.Request(http.MethodPut, "/api/v1/admin/users/999999/rate-limit-tier", map[string]string{"tier": "internal"})
+		if err != nil {
+			t.Fatalf("set tier: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusNotFound)
+	})
+
+	t.Run("non-admin caller cannot set a tier", func(t *testing.T) {
+		env.CleanDB(t)
+		testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "super@example.com")
+		plain := testutil.CreateTestUser(t, env, "plain@example.com", "Plain")
+
+		ts := setupTestServer(t, env)
+		client := adminClient(t, env, ts, plain.ID)
+
+		resp, err := client.Request(http.MethodPut, fmt.Sprintf("/api/v1/admin/users/%d/rate-limit-tier", plain.ID), map[string]string{"tier": "internal"})
+		if err != nil {
+			t.Fatalf("set tier: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusForbidden)
+	})
+}
@@ -18,14 +18,25 @@ const (
 	ActionUserDeactivate    AdminAction = "user.deactivate"
 	ActionUserActivate      AdminAction = "user.activate"
 	ActionUserDelete        AdminAction = "user.delete"
+	ActionUserMerge         AdminAction = "user.merge"
 	ActionUserGrantAdmin    AdminAction = "user.grant_admin"
 	ActionUserRevokeAdmin   AdminAction = "user.revoke_admin"
+	ActionUserSetTier       AdminAction = "user.set_rate_limit_tier"
 	ActionSystemShareCreate AdminAction = "system_share.create"
 
 	ActionSettingUpdate           AdminAction = "setting.update"
 	ActionSettingReset            AdminAction = "setting.reset"
 	ActionSmartRecapRegenerateAll AdminAction = "smart_recap.regenerate_all"
 	ActionCardInvalidate          AdminAction = "cards.invalidate"
+	ActionFeatureFlagCreate       AdminAction = "feature_flag.create"
+	ActionFeatureFlagUpdate       AdminAction = "feature_flag.update"
+	ActionUsageImport             AdminAction = "reconciliation.import"
+	ActionCorrectionFactorSet     AdminAction = "reconciliation.correction_factor.set"
+	ActionCorrectionFactorDelete  AdminAction = "reconciliation.correction_factor.delete"
+	ActionModelPricingSet         AdminAction = "model_pricing.set"
+	ActionOrgCreate               AdminAction = "org.create"
+	ActionOrgMemberAdd            AdminAction = "org.member_add"
+	ActionOrgMemberRemove         AdminAction = "org.member_remove"
 )
 
 // AuditLog logs an admin action with full context for security audit trail.
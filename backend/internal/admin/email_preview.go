@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ConfabulousDev/confab-web/internal/email"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// EmailPreviewResponse is the response for GET /api/v1/admin/email-preview.
+type EmailPreviewResponse struct {
+	Kind    string `json:"kind"`
+	Locale  string `json:"locale"`
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// HandleEmailPreview renders an email kind (?kind=invite) with sample data so
+// admins can check template changes without sending anything. ?locale picks
+// the templates (default en); locale is the one actually used.
+func (h *Handlers) HandleEmailPreview(w http.ResponseWriter, r *http.Request) {
+	kind, ok := email.ParseKind(r.URL.Query().Get("kind"))
+	if !ok {
+		names := make([]string, len(email.Kinds))
+		for i, k := range email.Kinds {
+			names[i] = string(k)
+		}
+		httputil.RespondError(w, http.StatusBadRequest, "kind must be one of: "+strings.Join(names, ", "))
+		return
+	}
+
+	rendered, err := email.Preview(kind, r.URL.Query().Get("locale"))
+	if err != nil {
+		logger.Ctx(r.Context()).Error("Failed to render email preview", "error", err, "kind", kind)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to render email preview")
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, EmailPreviewResponse{
+		Kind:    string(kind),
+		Locale:  rendered.Locale,
+		Subject: rendered.Subject,
+		HTML:    rendered.HTML,
+		Text:    rendered.Text,
+	})
+}
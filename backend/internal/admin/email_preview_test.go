@@ -0,0 +1,68 @@
+package admin_test
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/admin"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestEmailPreviewAPI(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "user@test.com", "User")
+	adminUser := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+	testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+	ts := setupTestServer(t, env)
+
+	t.Run("non-admin gets 403", func(t *testing.T) {
+		resp, err := adminClient(t, env, ts, user.ID).Get("/api/v1/admin/email-preview?kind=invite")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("renders the invite with sample data", func(t *testing.T) {
+		resp, err := adminClient(t, env, ts, adminUser.ID).Get("/api/v1/admin/email-preview?kind=invite&locale=en-GB")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var body admin.EmailPreviewResponse
+		testutil.ParseJSON(t, resp, &body)
+
+		if body.Kind != "invite" || body.Locale != "en" {
+			t.Errorf("kind, locale = %q, %q; want invite, en", body.Kind, body.Locale)
+		}
+		if !strings.Contains(body.Subject, "shared a Claude Code session transcript") {
+			t.Errorf("subject = %q", body.Subject)
+		}
+		if !strings.HasPrefix(body.HTML, "<!DOCTYPE html>") || !strings.Contains(body.Text, "Unsubscribe:") {
+			t.Errorf("unexpected bodies:\n%s\n%s", body.HTML, body.Text)
+		}
+	})
+
+	t.Run("unknown kind is a 400", func(t *testing.T) {
+		resp, err := adminClient(t, env, ts, adminUser.ID).Get("/api/v1/admin/email-preview?kind=digest")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbfeatureflags"
+	"github.com/ConfabulousDev/confab-web/internal/features"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// maxFlagUserIDs bounds a flag's explicit allowlist; past this, use enabled_pct.
+const maxFlagUserIDs = 1000
+
+// flagNamePattern keeps flag names short, lowercase identifiers (e.g. "workflows_card_v2").
+var flagNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,64}$`)
+
+// FeatureFlagJSON is a feature flag as returned by the admin API.
+type FeatureFlagJSON struct {
+	FlagName       string  `json:"flag_name"`
+	EnabledUserIDs []int64 `json:"enabled_user_ids"`
+	EnabledPct     int     `json:"enabled_pct"`
+	CreatedAt      string  `json:"created_at"`
+	UpdatedAt      string  `json:"updated_at"`
+}
+
+// FeatureFlagsListResponse is returned by GET /api/v1/admin/feature-flags.
+type FeatureFlagsListResponse struct {
+	Flags []FeatureFlagJSON `json:"flags"`
+}
+
+// CreateFeatureFlagRequest is the body of POST /api/v1/admin/feature-flags.
+type CreateFeatureFlagRequest struct {
+	FlagName       string  `json:"flag_name"`
+	EnabledUserIDs []int64 `json:"enabled_user_ids"`
+	EnabledPct     int     `json:"enabled_pct"`
+}
+
+// UpdateFeatureFlagRequest is the body of PATCH /api/v1/admin/feature-flags/{name}.
+// Omitted fields are left unchanged.
+type UpdateFeatureFlagRequest struct {
+	EnabledUserIDs *[]int64 `json:"enabled_user_ids,omitempty"`
+	EnabledPct     *int     `json:"enabled_pct,omitempty"`
+}
+
+func featureFlagJSON(f *dbfeatureflags.Flag) FeatureFlagJSON {
+	return FeatureFlagJSON{
+		FlagName:       f.Name,
+		EnabledUserIDs: f.EnabledUserIDs,
+		EnabledPct:     f.EnabledPct,
+		CreatedAt:      f.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      f.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// validateFlagRollout checks the allowlist and percentage shared by create and update.
+func validateFlagRollout(userIDs []int64, pct int) error {
+	if pct < 0 || pct > 100 {
+		return errors.New("enabled_pct must be between 0 and 100")
+	}
+	if len(userIDs) > maxFlagUserIDs {
+		return errors.New("enabled_user_ids exceeds maximum of 1000 users")
+	}
+	for _, id := range userIDs {
+		if id <= 0 {
+			return errors.New("enabled_user_ids must contain positive user IDs")
+		}
+	}
+	return nil
+}
+
+// HandleListFeatureFlags returns every feature flag, ordered by name.
+func (h *Handlers) HandleListFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	flags, err := h.featureFlagsStore.List(ctx)
+	if err != nil {
+		logger.Ctx(r.Context()).Error("Failed to list feature flags", "error", err)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to list feature flags")
+		return
+	}
+
+	out := make([]FeatureFlagJSON, 0, len(flags))
+	for i := range flags {
+		out = append(out, featureFlagJSON(&flags[i]))
+	}
+	httputil.RespondJSON(w, http.StatusOK, FeatureFlagsListResponse{Flags: out})
+}
+
+// HandleCreateFeatureFlag creates a flag. Returns 409 if the name is taken.
+func (h *Handlers) HandleCreateFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	var req CreateFeatureFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !flagNamePattern.MatchString(req.FlagName) {
+		httputil.RespondError(w, http.StatusBadRequest, "flag_name must be 1-64 characters of a-z, 0-9, or _")
+		return
+	}
+	if err := validateFlagRollout(req.EnabledUserIDs, req.EnabledPct); err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	flag, err := h.featureFlagsStore.Create(ctx, req.FlagName, req.EnabledUserIDs, req.EnabledPct)
+	if err != nil {
+		if errors.Is(err, db.ErrFeatureFlagExists) {
+			httputil.RespondError(w, http.StatusConflict, "Feature flag already exists")
+			return
+		}
+		logger.Ctx(r.Context()).Error("Failed to create feature flag", "error", err, "flag_name", req.FlagName)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to create feature flag")
+		return
+	}
+	features.Invalidate(flag.Name)
+
+	AuditLogFromRequest(r, h.DB, ActionFeatureFlagCreate, map[string]interface{}{
+		"flag_name":        flag.Name,
+		"enabled_user_ids": flag.EnabledUserIDs,
+		"enabled_pct":      flag.EnabledPct,
+	})
+
+	httputil.RespondJSON(w, http.StatusCreated, featureFlagJSON(flag))
+}
+
+// HandleUpdateFeatureFlag changes a flag's allowlist and/or rollout percentage.
+// The change is visible to this process immediately and to the precompute
+// worker within the features cache TTL (5 minutes).
+func (h *Handlers) HandleUpdateFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var req UpdateFeatureFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.EnabledUserIDs == nil && req.EnabledPct == nil {
+		httputil.RespondError(w, http.StatusBadRequest, "At least one of enabled_user_ids or enabled_pct is required")
+		return
+	}
+	var userIDs []int64
+	if req.EnabledUserIDs != nil {
+		userIDs = *req.EnabledUserIDs
+	}
+	pct := 0
+	if req.EnabledPct != nil {
+		pct = *req.EnabledPct
+	}
+	if err := validateFlagRollout(userIDs, pct); err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	flag, err := h.featureFlagsStore.Update(ctx, name, dbfeatureflags.Update{
+		EnabledUserIDs: req.EnabledUserIDs,
+		EnabledPct:     req.EnabledPct,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrFeatureFlagNotFound) {
+			httputil.RespondError(w, http.StatusNotFound, "Feature flag not found")
+			return
+		}
+		logger.Ctx(r.Context()).Error("Failed to update feature flag", "error", err, "flag_name", name)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to update feature flag")
+		return
+	}
+	features.Invalidate(flag.Name)
+
+	AuditLogFromRequest(r, h.DB, ActionFeatureFlagUpdate, map[string]interface{}{
+		"flag_name":        flag.Name,
+		"enabled_user_ids": flag.EnabledUserIDs,
+		"enabled_pct":      flag.EnabledPct,
+	})
+
+	httputil.RespondJSON(w, http.StatusOK, featureFlagJSON(flag))
+}
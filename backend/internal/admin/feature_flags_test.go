@@ -0,0 +1,173 @@
+package admin_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/admin"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestFeatureFlagsAPI_AuthEnforcement(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "user@test.com", "User")
+	testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+	ts := setupTestServer(t, env)
+
+	t.Run("unauthenticated gets 401", func(t *testing.T) {
+		resp, err := testutil.NewTestClient(t, ts).Get("/api/v1/admin/feature-flags")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("non-admin gets 403", func(t *testing.T) {
+		client := adminClient(t, env, ts, user.ID)
+		resp, err := client.Post("/api/v1/admin/feature-flags", admin.CreateFeatureFlagRequest{FlagName: "x"})
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestFeatureFlagsAPI_CreateListUpdate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	if _, err := env.DB.Exec(env.Ctx, `TRUNCATE TABLE feature_flags`); err != nil {
+		t.Fatalf("truncate feature_flags: %v", err)
+	}
+
+	adminUser := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+	testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+	ts := setupTestServer(t, env)
+	client := adminClient(t, env, ts, adminUser.ID)
+
+	resp, err := client.Post("/api/v1/admin/feature-flags", admin.CreateFeatureFlagRequest{
+		FlagName:       "workflows_v2",
+		EnabledUserIDs: []int64{adminUser.ID},
+		EnabledPct:     10,
+	})
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	testutil.RequireStatus(t, resp, http.StatusCreated)
+	var created admin.FeatureFlagJSON
+	testutil.ParseJSON(t, resp, &created)
+	if created.FlagName != "workflows_v2" || created.EnabledPct != 10 || len(created.EnabledUserIDs) != 1 {
+		t.Errorf("created = %+v", created)
+	}
+
+	t.Run("duplicate name gets 409", func(t *testing.T) {
+		resp, err := client.Post("/api/v1/admin/feature-flags", admin.CreateFeatureFlagRequest{FlagName: "workflows_v2"})
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusConflict {
+			t.Errorf("expected 409, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("patch changes only the given fields", func(t *testing.T) {
+		pct := 100
+		resp, err := client.Patch("/api/v1/admin/feature-flags/workflows_v2", admin.UpdateFeatureFlagRequest{EnabledPct: &pct})
+		if err != nil {
+			t.Fatalf("patch: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var updated admin.FeatureFlagJSON
+		testutil.ParseJSON(t, resp, &updated)
+		if updated.EnabledPct != 100 || len(updated.EnabledUserIDs) != 1 {
+			t.Errorf("updated = %+v, want pct 100 with allowlist kept", updated)
+		}
+	})
+
+	t.Run("patch unknown flag gets 404", func(t *testing.T) {
+		pct := 1
+		resp, err := client.Patch("/api/v1/admin/feature-flags/nope", admin.UpdateFeatureFlagRequest{EnabledPct: &pct})
+		if err != nil {
+			t.Fatalf("patch: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("list returns the flag", func(t *testing.T) {
+		resp, err := client.Get("/api/v1/admin/feature-flags")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var body admin.FeatureFlagsListResponse
+		testutil.ParseJSON(t, resp, &body)
+		if len(body.Flags) != 1 || body.Flags[0].FlagName != "workflows_v2" || body.Flags[0].EnabledPct != 100 {
+			t.Errorf("flags = %+v", body.Flags)
+		}
+	})
+}
+
+func TestFeatureFlagsAPI_ValidationErrors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	adminUser := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+	testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+	ts := setupTestServer(t, env)
+	client := adminClient(t, env, ts, adminUser.ID)
+
+	badPct := 101
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+	}{
+		{"empty name", http.MethodPost, "/api/v1/admin/feature-flags", admin.CreateFeatureFlagRequest{}},
+		{"uppercase name", http.MethodPost, "/api/v1/admin/feature-flags", admin.CreateFeatureFlagRequest{FlagName: "NewCard"}},
+		{"negative pct", http.MethodPost, "/api/v1/admin/feature-flags", admin.CreateFeatureFlagRequest{FlagName: "f", EnabledPct: -1}},
+		{"non-positive user id", http.MethodPost, "/api/v1/admin/feature-flags", admin.CreateFeatureFlagRequest{FlagName: "f", EnabledUserIDs: []int64{0}}},
+		{"patch pct over 100", http.MethodPatch, "/api/v1/admin/feature-flags/f", admin.UpdateFeatureFlagRequest{EnabledPct: &badPct}},
+		{"patch with no fields", http.MethodPatch, "/api/v1/admin/feature-flags/f", admin.UpdateFeatureFlagRequest{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.Request(tc.method, tc.path, tc.body)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
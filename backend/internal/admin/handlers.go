@@ -7,6 +7,11 @@ import (
 	"github.com/ConfabulousDev/confab-web/internal/db"
 	"github.com/ConfabulousDev/confab-web/internal/db/dbadmincardinvalidations"
 	"github.com/ConfabulousDev/confab-web/internal/db/dbadminsettings"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbfeatureflags"
+	"github.com/ConfabulousDev/confab-web/internal/db/dborg"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbreconciliation"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbretention"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbvelocity"
 	"github.com/ConfabulousDev/confab-web/internal/storage"
 )
 
@@ -25,6 +30,11 @@ type Handlers struct {
 	settingsStore          *dbadminsettings.Store
 	analyticsStore         *analytics.Store
 	cardInvalidationsStore *dbadmincardinvalidations.Store
+	featureFlagsStore      *dbfeatureflags.Store
+	orgStore               *dborg.Store
+	reconciliationStore    *dbreconciliation.Store
+	retentionStore         *dbretention.Store
+	velocityStore          *dbvelocity.Store
 }
 
 // NewHandlers creates admin handlers with dependencies
@@ -38,5 +48,10 @@ func NewHandlers(database *db.DB, store *storage.S3Storage, frontendURL string,
 		settingsStore:          &dbadminsettings.Store{DB: database},
 		analyticsStore:         analytics.NewStore(database.Conn()),
 		cardInvalidationsStore: &dbadmincardinvalidations.Store{DB: database},
+		featureFlagsStore:      &dbfeatureflags.Store{DB: database},
+		orgStore:               &dborg.Store{DB: database},
+		reconciliationStore:    &dbreconciliation.Store{DB: database},
+		retentionStore:         &dbretention.Store{DB: database},
+		velocityStore:          &dbvelocity.Store{DB: database},
 	}
 }
@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+const (
+	// leaderboardDefaultRange is the window used when ?from= is omitted.
+	leaderboardDefaultRange = 30 * 24 * time.Hour
+	// leaderboardMaxRange bounds the scan; the model breakdown expands the
+	// tokens_v2 tree of every session in range.
+	leaderboardMaxRange = 366 * 24 * time.Hour
+)
+
+// parseLeaderboardRange reads ?from= and ?to= (RFC 3339 with timezone, like
+// the card-invalidation dates). to defaults to now and from to 30 days before
+// to; the window must be non-empty and at most leaderboardMaxRange.
+func parseLeaderboardRange(r *http.Request, now time.Time) (analytics.LeaderboardRequest, error) {
+	req := analytics.LeaderboardRequest{To: now}
+	if v := r.URL.Query().Get("to"); v != "" {
+		to, err := parseStrictTimestamp(v)
+		if err != nil {
+			return req, err
+		}
+		req.To = to
+	}
+	req.From = req.To.Add(-leaderboardDefaultRange)
+	if v := r.URL.Query().Get("from"); v != "" {
+		from, err := parseStrictTimestamp(v)
+		if err != nil {
+			return req, err
+		}
+		req.From = from
+	}
+	if !req.To.After(req.From) {
+		return req, errors.New("to must be after from")
+	}
+	if req.To.Sub(req.From) > leaderboardMaxRange {
+		return req, errors.New("range must not exceed 366 days")
+	}
+	return req, nil
+}
+
+// HandleAnalyticsLeaderboard serves anonymized cross-user aggregates for a date
+// range: the longest sessions by line count, the per-session cost percentiles,
+// the most used models, and the average tokens per session. No user or session
+// identifiers are returned. The route is registered only when
+// ENABLE_ANALYTICS_LEADERBOARD=true and sits behind the super-admin middleware.
+// GET /api/v1/analytics/leaderboard
+func (h *Handlers) HandleAnalyticsLeaderboard(w http.ResponseWriter, r *http.Request) {
+	req, err := parseLeaderboardRange(r, time.Now().UTC())
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	board, err := h.analyticsStore.GetLeaderboard(ctx, req)
+	if err != nil {
+		logger.Ctx(r.Context()).Error("Failed to compute analytics leaderboard", "error", err)
+		if errors.Is(err, db.ErrQueryTimeout) {
+			httputil.RespondQueryTimeout(w, db.LoadQueryTimeout())
+			return
+		}
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to compute leaderboard")
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, board)
+}
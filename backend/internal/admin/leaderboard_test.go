@@ -0,0 +1,236 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+const leaderboardPath = "/api/v1/analytics/leaderboard"
+
+func TestLeaderboardAPI_Gating(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+
+	t.Run("not registered without the env flag", func(t *testing.T) {
+		env.CleanDB(t)
+		adminUser := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+		testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+
+		ts := setupTestServer(t, env)
+		client := adminClient(t, env, ts, adminUser.ID)
+
+		resp, err := client.Get(leaderboardPath)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("unauthenticated gets 401", func(t *testing.T) {
+		env.CleanDB(t)
+		testutil.SetEnvForTest(t, "ENABLE_ANALYTICS_LEADERBOARD", "true")
+		ts := setupTestServer(t, env)
+		client := testutil.NewTestClient(t, ts)
+
+		resp, err := client.Get(leaderboardPath)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("non-admin gets 403", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "user@example.com", "User")
+		testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+		testutil.SetEnvForTest(t, "ENABLE_ANALYTICS_LEADERBOARD", "true")
+
+		ts := setupTestServer(t, env)
+		client := adminClient(t, env, ts, user.ID)
+
+		resp, err := client.Get(leaderboardPath)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("bad range gets 400", func(t *testing.T) {
+		env.CleanDB(t)
+		adminUser := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+		testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+		testutil.SetEnvForTest(t, "ENABLE_ANALYTICS_LEADERBOARD", "true")
+
+		ts := setupTestServer(t, env)
+		client := adminClient(t, env, ts, adminUser.ID)
+
+		resp, err := client.Get(leaderboardPath + "?from=2026-03-01")
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
+}
+
+// leaderboardResponse mirrors the JSON wire shape of the endpoint.
+type leaderboardResponse struct {
+	SessionCount       int `json:"session_count"`
+	PricedSessionCount int `json:"priced_session_count"`
+	LongestSessions    []struct {
+		Rank      int    `json:"rank"`
+		Provider  string `json:"provider"`
+		LineCount int64  `json:"line_count"`
+	} `json:"longest_sessions"`
+	CostPercentiles *struct {
+		P50 string `json:"p50"`
+		P90 string `json:"p90"`
+		P99 string `json:"p99"`
+		Avg string `json:"avg"`
+	} `json:"cost_percentiles"`
+	TopModels []struct {
+		Provider     string `json:"provider"`
+		Model        string `json:"model"`
+		SessionCount int    `json:"session_count"`
+	} `json:"top_models"`
+	AvgTokens *struct {
+		Input  float64 `json:"input"`
+		Output float64 `json:"output"`
+		Total  float64 `json:"total"`
+	} `json:"avg_tokens_per_session"`
+}
+
+func TestLeaderboardAPI_AggregatesAcrossUsers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	adminUser := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+	alice := testutil.CreateTestUser(t, env, "alice@example.com", "Alice")
+	bob := testutil.CreateTestUser(t, env, "bob@example.com", "Bob")
+	testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+	testutil.SetEnvForTest(t, "ENABLE_ANALYTICS_LEADERBOARD", "true")
+
+	seed := func(userID int64, externalID, provider, model, cost string, lines int, input, output int64) string {
+		sess := testutil.CreateTestSessionWithProvider(t, env, userID, externalID, provider)
+		testutil.CreateTestSyncFile(t, env, sess, externalID+".jsonl", "transcript", lines)
+		testutil.SeedTokensV2Card(t, env, sess, analytics.TokensV2Data{
+			TotalCostUSD: cost, TotalInput: input, TotalOutput: output,
+			ByProvider: map[string]analytics.TokensV2Provider{
+				provider: {CostUSD: cost, Models: map[string]analytics.TokensV2Model{
+					model: {Input: input, Output: output, CostUSD: cost},
+				}},
+			},
+		})
+		return sess
+	}
+	seed(alice.ID, "alice-long", models.ProviderClaudeCode, "opus-4-5", "3.00", 500, 1000, 200)
+	seed(alice.ID, "alice-short", models.ProviderClaudeCode, "opus-4-5", "1.00", 20, 300, 100)
+	seed(bob.ID, "bob-mid", models.ProviderCodex, "gpt-5", "2.00", 120, 200, 0)
+	// A session without a tokens card counts toward session_count and the
+	// line ranking but not toward cost or token figures.
+	unpriced := testutil.CreateTestSession(t, env, bob.ID, "bob-unpriced")
+	testutil.CreateTestSyncFile(t, env, unpriced, "bob-unpriced.jsonl", "transcript", 50)
+
+	ts := setupTestServer(t, env)
+	client := adminClient(t, env, ts, adminUser.ID)
+
+	resp, err := client.Get(leaderboardPath)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	testutil.RequireStatus(t, resp, http.StatusOK)
+	raw, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+
+	// Nothing identifying may leave the endpoint.
+	for _, leak := range []string{"alice", "bob", "@example.com", "user_id", "session_id", unpriced} {
+		if strings.Contains(string(raw), leak) {
+			t.Errorf("response contains %q: %s", leak, raw)
+		}
+	}
+
+	var body leaderboardResponse
+	if err := json.Unmarshal(raw, &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if body.SessionCount != 4 || body.PricedSessionCount != 3 {
+		t.Errorf("session_count=%d priced_session_count=%d, want 4 and 3", body.SessionCount, body.PricedSessionCount)
+	}
+
+	wantLines := []int64{500, 120, 50, 20}
+	if len(body.LongestSessions) != len(wantLines) {
+		t.Fatalf("longest_sessions = %+v, want %d entries", body.LongestSessions, len(wantLines))
+	}
+	for i, want := range wantLines {
+		got := body.LongestSessions[i]
+		if got.Rank != i+1 || got.LineCount != want {
+			t.Errorf("longest_sessions[%d] = %+v, want rank %d with %d lines", i, got, i+1, want)
+		}
+	}
+	if body.LongestSessions[1].Provider != models.ProviderCodex {
+		t.Errorf("longest_sessions[1].provider = %q, want codex", body.LongestSessions[1].Provider)
+	}
+
+	if body.CostPercentiles == nil {
+		t.Fatal("cost_percentiles is null")
+	}
+	if body.CostPercentiles.P50 != "2" || body.CostPercentiles.Avg != "2" {
+		t.Errorf("cost_percentiles = %+v, want p50 2 and avg 2", *body.CostPercentiles)
+	}
+
+	if len(body.TopModels) != 2 {
+		t.Fatalf("top_models = %+v, want 2 entries", body.TopModels)
+	}
+	if m := body.TopModels[0]; m.Provider != models.ProviderClaudeCode || m.Model != "opus-4-5" || m.SessionCount != 2 {
+		t.Errorf("top_models[0] = %+v, want claude-code opus-4-5 in 2 sessions", m)
+	}
+
+	if body.AvgTokens == nil {
+		t.Fatal("avg_tokens_per_session is null")
+	}
+	if body.AvgTokens.Input != 500 || body.AvgTokens.Output != 100 || body.AvgTokens.Total != 600 {
+		t.Errorf("avg_tokens_per_session = %+v, want input 500, output 100, total 600", *body.AvgTokens)
+	}
+
+	t.Run("window excludes sessions outside the range", func(t *testing.T) {
+		resp, err := client.Get(leaderboardPath + "?from=2020-01-01T00:00:00Z&to=2020-02-01T00:00:00Z")
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var empty leaderboardResponse
+		testutil.ParseJSON(t, resp, &empty)
+		if empty.SessionCount != 0 || len(empty.LongestSessions) != 0 || empty.CostPercentiles != nil || empty.AvgTokens != nil {
+			t.Errorf("expected an empty leaderboard, got %+v", empty)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/auth"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbuser "github.com/ConfabulousDev/confab-web/internal/db/user"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+// mergeTimeout bounds a merge: copying the source's objects dominates.
+const mergeTimeout = 5 * time.Minute
+
+// maxMergeConflictsReported caps the external IDs listed in a 409.
+const maxMergeConflictsReported = 20
+
+// MergeUserRequest is the body of POST /api/v1/admin/users/{id}/merge.
+type MergeUserRequest struct {
+	TargetUserID int64  `json:"target_user_id"`
+	Confirm      string `json:"confirm"`
+}
+
+// MergeUserResponse reports a completed merge.
+type MergeUserResponse struct {
+	SourceUserID int64 `json:"source_user_id"`
+	TargetUserID int64 `json:"target_user_id"`
+	dbuser.MergeResult
+	ObjectsCopied int `json:"objects_copied"`
+}
+
+// MergeConflictResponse is the 409 body when both users own a session with
+// the same external ID.
+type MergeConflictResponse struct {
+	httputil.ErrorResponse
+	ConflictingExternalIDs []string `json:"conflicting_external_ids"`
+}
+
+// HandleMergeUserAPI merges the {id} user (the source, typically a duplicate
+// created after a provider email change) into target_user_id: everything the
+// source owns moves to the target and the source is deleted. The admin must
+// echo the source's email in confirm.
+//
+// Order: guards and confirmation, deactivate the source so it can't sync
+// mid-merge, copy its objects under the target's prefix, move ownership in one
+// DB transaction, then delete the source's original objects. A failure before
+// the transaction commits leaves the source intact (but inactive); the copies
+// are overwritten by a retry.
+func (h *Handlers) HandleMergeUserAPI(w http.ResponseWriter, r *http.Request) {
+	log := logger.Ctx(r.Context())
+
+	sourceID, err := parseUserID(r)
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req MergeUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.TargetUserID <= 0 {
+		httputil.RespondError(w, http.StatusBadRequest, "target_user_id is required")
+		return
+	}
+	if req.TargetUserID == sourceID {
+		httputil.RespondError(w, http.StatusBadRequest, "Cannot merge a user into itself")
+		return
+	}
+
+	adminID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		httputil.RespondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), mergeTimeout)
+	defer cancel()
+
+	userStore := &dbuser.Store{DB: h.DB}
+
+	source, err := userStore.GetUserByID(ctx, sourceID)
+	if err != nil {
+		respondMergeUserLookupError(w, r, err, sourceID)
+		return
+	}
+	target, err := userStore.GetUserByID(ctx, req.TargetUserID)
+	if err != nil {
+		respondMergeUserLookupError(w, r, err, req.TargetUserID)
+		return
+	}
+	if target.Status != models.UserStatusActive {
+		httputil.RespondError(w, http.StatusBadRequest, "Target user is inactive")
+		return
+	}
+
+	conflicts, err := userStore.MergeConflicts(ctx, sourceID, target.ID, maxMergeConflictsReported)
+	if err != nil {
+		log.Error("Failed to check merge conflicts", "error", err, "source_user_id", sourceID, "target_user_id", target.ID)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to check merge conflicts")
+		return
+	}
+	if len(conflicts) > 0 {
+		respondMergeConflict(w, conflicts)
+		return
+	}
+
+	// The source is deleted, so it leaves the effective-admin set (g0bq). Its
+	// admin flag is not carried over to the target.
+	if h.guardLastAdmin(w, r, ctx, userStore, sourceID, false) {
+		return
+	}
+
+	// kyrr: echo the email of the account being deleted.
+	if !verifyConfirmation(source.Email, req.Confirm) {
+		respondConfirmationMismatch(w)
+		return
+	}
+
+	if err := userStore.UpdateUserStatus(ctx, sourceID, models.UserStatusInactive); err != nil {
+		log.Error("Failed to deactivate merge source", "error", err, "user_id", sourceID)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to deactivate source user")
+		return
+	}
+
+	copied, err := h.Storage.CopyAllUserData(ctx, sourceID, target.ID)
+	if err != nil {
+		log.Error("Failed to copy storage for merge", "error", err, "source_user_id", sourceID, "target_user_id", target.ID, "objects_copied", copied)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to copy storage")
+		return
+	}
+
+	result, err := userStore.MergeUsers(ctx, sourceID, target.ID, adminID)
+	if err != nil {
+		if errors.Is(err, db.ErrUserMergeConflict) {
+			// A session synced between the pre-check and the transaction.
+			conflicts, _ := userStore.MergeConflicts(ctx, sourceID, target.ID, maxMergeConflictsReported)
+			respondMergeConflict(w, conflicts)
+			return
+		}
+		log.Error("Failed to merge users", "error", err, "source_user_id", sourceID, "target_user_id", target.ID)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to merge users")
+		return
+	}
+
+	// The DB now points at the copies; the originals are garbage. A failure
+	// here leaves orphans but no inconsistency.
+	if err := h.Storage.DeleteAllUserData(ctx, sourceID); err != nil {
+		log.Warn("Failed to delete merged source storage", "error", err, "user_id", sourceID)
+	}
+
+	AuditLogFromRequest(r, h.DB, ActionUserMerge, map[string]interface{}{
+		"source_user_id":    sourceID,
+		"source_user_email": source.Email,
+		"target_user_id":    target.ID,
+		"target_user_email": target.Email,
+		"sessions_moved":    result.SessionsMoved,
+		"api_keys_moved":    result.APIKeysMoved,
+		"identities_moved":  result.IdentitiesMoved,
+		"objects_copied":    copied,
+	})
+
+	httputil.RespondJSON(w, http.StatusOK, MergeUserResponse{
+		SourceUserID:  sourceID,
+		TargetUserID:  target.ID,
+		MergeResult:   *result,
+		ObjectsCopied: copied,
+	})
+}
+
+func respondMergeUserLookupError(w http.ResponseWriter, r *http.Request, err error, userID int64) {
+	if errors.Is(err, db.ErrUserNotFound) {
+		httputil.RespondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	logger.Ctx(r.Context()).Error("Failed to load user for merge", "error", err, "user_id", userID)
+	httputil.RespondError(w, http.StatusInternalServerError, "Failed to load user")
+}
+
+func respondMergeConflict(w http.ResponseWriter, conflicts []string) {
+	if conflicts == nil {
+		conflicts = []string{}
+	}
+	httputil.RespondJSON(w, http.StatusConflict, MergeConflictResponse{
+		ErrorResponse: httputil.ErrorResponse{
+			Error: "Both users have sessions with the same external ID",
+			Code:  httputil.CodeConflict,
+		},
+		ConflictingExternalIDs: conflicts,
+	})
+}
@@ -0,0 +1,139 @@
+package admin_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/admin"
+	dbuser "github.com/ConfabulousDev/confab-web/internal/db/user"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestAdminMergeUserAPI(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+
+	t.Run("moves sessions and storage to the target and deletes the source", func(t *testing.T) {
+		env.CleanDB(t)
+
+		adminUser := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+		source := testutil.CreateTestUser(t, env, "old@example.com", "Old")
+		target := testutil.CreateTestUser(t, env, "new@example.com", "New")
+		testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+
+		sessionID := testutil.CreateTestSessionFull(t, env, source.ID, "old-ext-1", testutil.TestSessionFullOpts{})
+		testutil.UploadTestChunk(t, env, source.ID, models.ProviderClaudeCode, "old-ext-1", "transcript.jsonl", 1, 3, testutil.MinimalTranscript())
+		sourceKey := fmt.Sprintf("%d/claude-code/old-ext-1/chunks/transcript.jsonl/chunk_00000001_00000003.jsonl", source.ID)
+		targetKey := fmt.Sprintf("%d/claude-code/old-ext-1/chunks/transcript.jsonl/chunk_00000001_00000003.jsonl", target.ID)
+
+		ts := setupTestServer(t, env)
+		client := adminClient(t, env, ts, adminUser.ID)
+
+		resp, err := client.Post(fmt.Sprintf("/api/v1/admin/users/%d/merge", source.ID), admin.MergeUserRequest{
+			TargetUserID: target.ID,
+			Confirm:      "old@example.com",
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var body admin.MergeUserResponse
+		testutil.ParseJSON(t, resp, &body)
+		if body.SessionsMoved != 1 || body.IdentitiesMoved != 1 || body.ObjectsCopied != 1 {
+			t.Errorf("response = %+v, want 1 session, 1 identity, 1 object", body)
+		}
+
+		userStore := &dbuser.Store{DB: env.DB}
+		if _, err := userStore.GetUserByID(env.Ctx, source.ID); err == nil {
+			t.Error("expected source user to be deleted")
+		}
+		ids, err := userStore.GetUserSessionIDs(env.Ctx, target.ID)
+		if err != nil {
+			t.Fatalf("GetUserSessionIDs failed: %v", err)
+		}
+		if len(ids) != 1 || ids[0] != sessionID {
+			t.Errorf("target sessions = %v, want [%s]", ids, sessionID)
+		}
+
+		testutil.VerifyFileInS3(t, env, targetKey)
+		if _, err := env.Storage.Download(env.Ctx, sourceKey); err == nil {
+			t.Error("expected source S3 data to be deleted")
+		}
+	})
+
+	t.Run("refuses with 409 when both users have the same external ID", func(t *testing.T) {
+		env.CleanDB(t)
+
+		adminUser := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+		source := testutil.CreateTestUser(t, env, "old@example.com", "Old")
+		target := testutil.CreateTestUser(t, env, "new@example.com", "New")
+		testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+		testutil.CreateTestSession(t, env, source.ID, "shared-ext")
+		testutil.CreateTestSession(t, env, target.ID, "shared-ext")
+
+		ts := setupTestServer(t, env)
+		client := adminClient(t, env, ts, adminUser.ID)
+
+		resp, err := client.Post(fmt.Sprintf("/api/v1/admin/users/%d/merge", source.ID), admin.MergeUserRequest{
+			TargetUserID: target.ID,
+			Confirm:      "old@example.com",
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusConflict)
+		var body admin.MergeConflictResponse
+		testutil.ParseJSON(t, resp, &body)
+		if len(body.ConflictingExternalIDs) != 1 || body.ConflictingExternalIDs[0] != "shared-ext" {
+			t.Errorf("conflicting_external_ids = %v, want [shared-ext]", body.ConflictingExternalIDs)
+		}
+
+		userStore := &dbuser.Store{DB: env.DB}
+		got, err := userStore.GetUserByID(env.Ctx, source.ID)
+		if err != nil {
+			t.Fatalf("source user should still exist: %v", err)
+		}
+		if got.Status != models.UserStatusActive {
+			t.Errorf("source status = %s, want active (refused before any mutation)", got.Status)
+		}
+	})
+
+	t.Run("rejects a bad confirmation or a self-merge", func(t *testing.T) {
+		env.CleanDB(t)
+
+		adminUser := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+		source := testutil.CreateTestUser(t, env, "old@example.com", "Old")
+		target := testutil.CreateTestUser(t, env, "new@example.com", "New")
+		testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+
+		ts := setupTestServer(t, env)
+		client := adminClient(t, env, ts, adminUser.ID)
+		path := fmt.Sprintf("/api/v1/admin/users/%d/merge", source.ID)
+
+		for name, req := range map[string]admin.MergeUserRequest{
+			"wrong confirm": {TargetUserID: target.ID, Confirm: "new@example.com"},
+			"self merge":    {TargetUserID: source.ID, Confirm: "old@example.com"},
+		} {
+			resp, err := client.Post(path, req)
+			if err != nil {
+				t.Fatalf("%s: request failed: %v", name, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("%s: expected 400, got %d", name, resp.StatusCode)
+			}
+		}
+
+		userStore := &dbuser.Store{DB: env.DB}
+		if _, err := userStore.GetUserByID(env.Ctx, source.ID); err != nil {
+			t.Errorf("source user should still exist: %v", err)
+		}
+	})
+}
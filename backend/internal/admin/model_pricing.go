@@ -0,0 +1,163 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// maxModelPricePer1M bounds a per-million-token rate; anything higher is more
+// likely a typo (a per-token price times a million twice) than a real price.
+var maxModelPricePer1M = decimal.NewFromInt(10_000)
+
+// SetModelPricingRequest is the body of POST /api/v1/admin/model-pricing.
+// Rates are decimal strings in USD per million tokens. EffectiveFrom is RFC
+// 3339 and defaults to now; CacheCreation1hCostPer1M defaults to 0, which
+// bills 1-hour cache writes at the 5-minute rate.
+type SetModelPricingRequest struct {
+	ModelName                string `json:"model_name"`
+	InputCostPer1M           string `json:"input_cost_per_1m"`
+	OutputCostPer1M          string `json:"output_cost_per_1m"`
+	CacheCreationCostPer1M   string `json:"cache_creation_cost_per_1m"`
+	CacheCreation1hCostPer1M string `json:"cache_creation_1h_cost_per_1m"`
+	CacheReadCostPer1M       string `json:"cache_read_cost_per_1m"`
+	EffectiveFrom            string `json:"effective_from"`
+}
+
+// ModelPricingJSON is one model_pricing period. EffectiveTo is null while the
+// period is current.
+type ModelPricingJSON struct {
+	ModelName                string  `json:"model_name"`
+	InputCostPer1M           string  `json:"input_cost_per_1m"`
+	OutputCostPer1M          string  `json:"output_cost_per_1m"`
+	CacheCreationCostPer1M   string  `json:"cache_creation_cost_per_1m"`
+	CacheCreation1hCostPer1M string  `json:"cache_creation_1h_cost_per_1m"`
+	CacheReadCostPer1M       string  `json:"cache_read_cost_per_1m"`
+	EffectiveFrom            string  `json:"effective_from"`
+	EffectiveTo              *string `json:"effective_to"`
+}
+
+func modelPricingJSON(p *analytics.ModelPricingPeriod) *ModelPricingJSON {
+	out := &ModelPricingJSON{
+		ModelName:                p.ModelName,
+		InputCostPer1M:           p.Pricing.Input.String(),
+		OutputCostPer1M:          p.Pricing.Output.String(),
+		CacheCreationCostPer1M:   p.Pricing.CacheWrite.String(),
+		CacheCreation1hCostPer1M: p.Pricing.CacheWrite1h.String(),
+		CacheReadCostPer1M:       p.Pricing.CacheRead.String(),
+		EffectiveFrom:            p.EffectiveFrom.Format(time.RFC3339),
+	}
+	if p.EffectiveTo != nil {
+		to := p.EffectiveTo.Format(time.RFC3339)
+		out.EffectiveTo = &to
+	}
+	return out
+}
+
+// parseModelPrice parses one rate. An empty optional rate is zero.
+func parseModelPrice(field, raw string, required bool) (decimal.Decimal, string) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" && !required {
+		return decimal.Zero, ""
+	}
+	d, err := decimal.NewFromString(raw)
+	if err != nil || d.IsNegative() || d.GreaterThan(maxModelPricePer1M) {
+		return decimal.Zero, field + " must be a decimal string from 0 to 10000"
+	}
+	if d.Exponent() < -6 {
+		return decimal.Zero, field + " must have at most 6 decimal places"
+	}
+	return d, ""
+}
+
+// HandleSetModelPricing starts a new price period for a model: the model's
+// current period ends at effective_from and the new one runs from there.
+// Sessions first seen from effective_from on are priced at the new rates when
+// their cards are next computed; cards already computed keep their cost until
+// invalidated (POST /admin/cards/invalidate). 409 when effective_from is not
+// after the start of the model's latest period.
+func (h *Handlers) HandleSetModelPricing(w http.ResponseWriter, r *http.Request) {
+	var req SetModelPricingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	modelName := strings.TrimSpace(req.ModelName)
+	if modelName == "" {
+		httputil.RespondError(w, http.StatusBadRequest, "model_name is required")
+		return
+	}
+
+	var pricing analytics.ModelPricing
+	for _, f := range []struct {
+		field    string
+		raw      string
+		required bool
+		dst      *decimal.Decimal
+	}{
+		{"input_cost_per_1m", req.InputCostPer1M, true, &pricing.Input},
+		{"output_cost_per_1m", req.OutputCostPer1M, true, &pricing.Output},
+		{"cache_creation_cost_per_1m", req.CacheCreationCostPer1M, true, &pricing.CacheWrite},
+		{"cache_creation_1h_cost_per_1m", req.CacheCreation1hCostPer1M, false, &pricing.CacheWrite1h},
+		{"cache_read_cost_per_1m", req.CacheReadCostPer1M, true, &pricing.CacheRead},
+	} {
+		d, msg := parseModelPrice(f.field, f.raw, f.required)
+		if msg != "" {
+			httputil.RespondError(w, http.StatusBadRequest, msg)
+			return
+		}
+		*f.dst = d
+	}
+
+	effectiveFrom := time.Now().UTC()
+	if raw := strings.TrimSpace(req.EffectiveFrom); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			httputil.RespondError(w, http.StatusBadRequest, "effective_from must be an RFC 3339 timestamp")
+			return
+		}
+		effectiveFrom = t.UTC()
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	period, err := h.analyticsStore.SetModelPricing(ctx, modelName, pricing, effectiveFrom)
+	if err != nil {
+		if errors.Is(err, analytics.ErrModelPricingConflict) {
+			httputil.RespondErrorCode(w, http.StatusConflict, httputil.CodeConflict,
+				"effective_from must be after the start of the model's latest price period")
+			return
+		}
+		logger.Ctx(r.Context()).Error("Failed to set model pricing", "error", err)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to set model pricing")
+		return
+	}
+
+	// Apply the change in this process now; other processes reload on
+	// their own schedule.
+	if err := analytics.RefreshModelPricing(ctx, h.DB.Conn()); err != nil {
+		logger.Ctx(r.Context()).Warn("Failed to refresh model pricing", "error", err)
+	}
+
+	AuditLogFromRequest(r, h.DB, ActionModelPricingSet, map[string]interface{}{
+		"model_name":     period.ModelName,
+		"input":          period.Pricing.Input.String(),
+		"output":         period.Pricing.Output.String(),
+		"cache_write":    period.Pricing.CacheWrite.String(),
+		"cache_write_1h": period.Pricing.CacheWrite1h.String(),
+		"cache_read":     period.Pricing.CacheRead.String(),
+		"effective_from": period.EffectiveFrom.Format(time.RFC3339),
+	})
+
+	httputil.RespondJSON(w, http.StatusOK, modelPricingJSON(period))
+}
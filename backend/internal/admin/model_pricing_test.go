@@ -0,0 +1,151 @@
+package admin_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ConfabulousDev/confab-web/internal/admin"
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestModelPricingAPI(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	os.Setenv("LOG_FORMAT", "json")
+	t.Cleanup(func() { analytics.SetModelPricingPeriods(nil) })
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "user@test.com", "User")
+	adminUser := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+	testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+
+	ts := setupTestServer(t, env)
+	client := adminClient(t, env, ts, adminUser.ID)
+
+	post := func(c *testutil.TestClient, req admin.SetModelPricingRequest) *http.Response {
+		t.Helper()
+		resp, err := c.Post("/api/v1/admin/model-pricing", req)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		return resp
+	}
+	priceAt := func(model string, at time.Time) *analytics.ModelPricing {
+		t.Helper()
+		p, err := analytics.GetModelPricing(env.Ctx, env.DB.Conn(), model, at)
+		if err != nil {
+			t.Fatalf("GetModelPricing: %v", err)
+		}
+		return p
+	}
+	rates := func(input string) admin.SetModelPricingRequest {
+		return admin.SetModelPricingRequest{
+			ModelName:              "gpt-test",
+			InputCostPer1M:         input,
+			OutputCostPer1M:        "8",
+			CacheCreationCostPer1M: "0",
+			CacheReadCostPer1M:     "0.2",
+		}
+	}
+
+	t.Run("seeded prices are point-in-time", func(t *testing.T) {
+		for _, tc := range []struct {
+			at   time.Time
+			want string
+		}{
+			{time.Date(2026, 8, 31, 23, 0, 0, 0, time.UTC), "2"},
+			{time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), "3"},
+		} {
+			p := priceAt("claude-sonnet-5-20260701", tc.at)
+			if p == nil || !p.Input.Equal(decimal.RequireFromString(tc.want)) {
+				t.Errorf("sonnet-5 at %s = %+v, want input %s", tc.at, p, tc.want)
+			}
+		}
+		if p := priceAt("made-up-model", time.Now()); p != nil {
+			t.Errorf("unknown model price = %+v, want nil", p)
+		}
+	})
+
+	t.Run("non-admin gets 403", func(t *testing.T) {
+		resp := post(adminClient(t, env, ts, user.ID), rates("2"))
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusForbidden)
+	})
+
+	t.Run("invalid requests are rejected", func(t *testing.T) {
+		noModel := rates("2")
+		noModel.ModelName = " "
+		badFrom := rates("2")
+		badFrom.EffectiveFrom = "2027-01-01"
+		for name, req := range map[string]admin.SetModelPricingRequest{
+			"no model":      noModel,
+			"negative rate": rates("-1"),
+			"missing rate":  rates(""),
+			"too precise":   rates("0.0000001"),
+			"bad date":      badFrom,
+		} {
+			t.Run(name, func(t *testing.T) {
+				resp := post(client, req)
+				defer resp.Body.Close()
+				testutil.RequireStatus(t, resp, http.StatusBadRequest)
+			})
+		}
+	})
+
+	t.Run("new periods end the current one", func(t *testing.T) {
+		jan := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+		feb := time.Date(2027, 2, 1, 0, 0, 0, 0, time.UTC)
+
+		first := rates("2")
+		first.EffectiveFrom = jan.Format(time.RFC3339)
+		resp := post(client, first)
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var got admin.ModelPricingJSON
+		testutil.ParseJSON(t, resp, &got)
+		if got.ModelName != "gpt-test" || got.InputCostPer1M != "2" || got.EffectiveTo != nil {
+			t.Errorf("first period = %+v", got)
+		}
+
+		second := rates("2.5")
+		second.EffectiveFrom = feb.Format(time.RFC3339)
+		resp = post(client, second)
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		resp.Body.Close()
+
+		for _, tc := range []struct {
+			at   time.Time
+			want string
+		}{
+			{jan, "2"},
+			{feb.Add(-time.Second), "2"},
+			{feb, "2.5"},
+		} {
+			if p := priceAt("gpt-test", tc.at); p == nil || !p.Input.Equal(decimal.RequireFromString(tc.want)) {
+				t.Errorf("gpt-test at %s = %+v, want input %s", tc.at, p, tc.want)
+			}
+		}
+		if p := priceAt("gpt-test", jan.Add(-time.Second)); p != nil {
+			t.Errorf("gpt-test before its first period = %+v, want nil", p)
+		}
+
+		// The handler reloads this process's in-memory copy.
+		if _, ok := analytics.ActivePricingFamilies()["gpt-test"]; !ok {
+			t.Error("gpt-test missing from the active pricing families after the update")
+		}
+
+		// A period must start after the latest one.
+		stale := rates("9")
+		stale.EffectiveFrom = jan.Format(time.RFC3339)
+		resp = post(client, stale)
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusConflict)
+	})
+}
@@ -0,0 +1,162 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dborg"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// maxOrgNameLength bounds organization names.
+const maxOrgNameLength = 100
+
+// OrgJSON is an organization as returned by the admin API.
+type OrgJSON struct {
+	ID            int64   `json:"id"`
+	Name          string  `json:"name"`
+	MemberUserIDs []int64 `json:"member_user_ids"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+// OrgsListResponse is returned by GET /api/v1/admin/orgs.
+type OrgsListResponse struct {
+	Orgs []OrgJSON `json:"orgs"`
+}
+
+// CreateOrgRequest is the body of POST /api/v1/admin/orgs.
+type CreateOrgRequest struct {
+	Name string `json:"name"`
+}
+
+func orgJSON(o *dborg.Org) OrgJSON {
+	return OrgJSON{
+		ID:            o.ID,
+		Name:          o.Name,
+		MemberUserIDs: o.MemberUserIDs,
+		CreatedAt:     o.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// HandleListOrgs returns every organization with its members, ordered by name.
+func (h *Handlers) HandleListOrgs(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	orgs, err := h.orgStore.List(ctx)
+	if err != nil {
+		logger.Ctx(r.Context()).Error("Failed to list orgs", "error", err)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to list organizations")
+		return
+	}
+
+	out := make([]OrgJSON, 0, len(orgs))
+	for i := range orgs {
+		out = append(out, orgJSON(&orgs[i]))
+	}
+	httputil.RespondJSON(w, http.StatusOK, OrgsListResponse{Orgs: out})
+}
+
+// HandleCreateOrg creates an organization with no members. Returns 409 if
+// the name is taken.
+func (h *Handlers) HandleCreateOrg(w http.ResponseWriter, r *http.Request) {
+	var req CreateOrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxOrgNameLength {
+		httputil.RespondError(w, http.StatusBadRequest, "name must be 1-100 characters")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	org, err := h.orgStore.Create(ctx, name)
+	if err != nil {
+		if errors.Is(err, db.ErrOrgExists) {
+			httputil.RespondError(w, http.StatusConflict, "Organization already exists")
+			return
+		}
+		logger.Ctx(r.Context()).Error("Failed to create org", "error", err, "name", name)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to create organization")
+		return
+	}
+
+	AuditLogFromRequest(r, h.DB, ActionOrgCreate, map[string]interface{}{
+		"org_id": org.ID,
+		"name":   org.Name,
+	})
+
+	httputil.RespondJSON(w, http.StatusCreated, orgJSON(org))
+}
+
+// HandleAddOrgMember adds a user to an organization and returns the
+// organization. Adding an existing member succeeds without a change.
+func (h *Handlers) HandleAddOrgMember(w http.ResponseWriter, r *http.Request) {
+	h.changeOrgMember(w, r, true)
+}
+
+// HandleRemoveOrgMember removes a user from an organization and returns the
+// organization. Removing a non-member succeeds without a change.
+func (h *Handlers) HandleRemoveOrgMember(w http.ResponseWriter, r *http.Request) {
+	h.changeOrgMember(w, r, false)
+}
+
+func (h *Handlers) changeOrgMember(w http.ResponseWriter, r *http.Request, add bool) {
+	orgID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid organization ID")
+		return
+	}
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	action := ActionOrgMemberAdd
+	if add {
+		err = h.orgStore.AddMember(ctx, orgID, userID)
+	} else {
+		action = ActionOrgMemberRemove
+		err = h.orgStore.RemoveMember(ctx, orgID, userID)
+	}
+	var org *dborg.Org
+	if err == nil {
+		org, err = h.orgStore.Get(ctx, orgID)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrOrgNotFound):
+			httputil.RespondError(w, http.StatusNotFound, "Organization not found")
+		case errors.Is(err, db.ErrUserNotFound):
+			httputil.RespondError(w, http.StatusNotFound, "User not found")
+		default:
+			logger.Ctx(r.Context()).Error("Failed to change org membership", "error", err, "org_id", orgID, "user_id", userID)
+			httputil.RespondError(w, http.StatusInternalServerError, "Failed to change organization membership")
+		}
+		return
+	}
+
+	AuditLogFromRequest(r, h.DB, action, map[string]interface{}{
+		"org_id":  orgID,
+		"user_id": userID,
+	})
+
+	httputil.RespondJSON(w, http.StatusOK, orgJSON(org))
+}
@@ -0,0 +1,151 @@
+package admin_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/admin"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestOrgsAPI_AuthEnforcement(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "user@test.com", "User")
+	testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+	ts := setupTestServer(t, env)
+
+	t.Run("unauthenticated gets 401", func(t *testing.T) {
+		resp, err := testutil.NewTestClient(t, ts).Get("/api/v1/admin/orgs")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("non-admin gets 403", func(t *testing.T) {
+		client := adminClient(t, env, ts, user.ID)
+		resp, err := client.Post("/api/v1/admin/orgs", admin.CreateOrgRequest{Name: "acme"})
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestOrgsAPI_CreateListMembers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	adminUser := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+	member := testutil.CreateTestUser(t, env, "member@example.com", "Member")
+	testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+	ts := setupTestServer(t, env)
+	client := adminClient(t, env, ts, adminUser.ID)
+
+	resp, err := client.Post("/api/v1/admin/orgs", admin.CreateOrgRequest{Name: "  acme  "})
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	testutil.RequireStatus(t, resp, http.StatusCreated)
+	var created admin.OrgJSON
+	testutil.ParseJSON(t, resp, &created)
+	if created.Name != "acme" || len(created.MemberUserIDs) != 0 {
+		t.Errorf("created = %+v, want trimmed name and no members", created)
+	}
+	memberPath := fmt.Sprintf("/api/v1/admin/orgs/%d/members/%d", created.ID, member.ID)
+
+	t.Run("duplicate name gets 409", func(t *testing.T) {
+		resp, err := client.Post("/api/v1/admin/orgs", admin.CreateOrgRequest{Name: "acme"})
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusConflict {
+			t.Errorf("expected 409, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("blank name gets 400", func(t *testing.T) {
+		resp, err := client.Post("/api/v1/admin/orgs", admin.CreateOrgRequest{Name: "   "})
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("add member shows in list", func(t *testing.T) {
+		resp, err := client.Request("PUT", memberPath, nil)
+		if err != nil {
+			t.Fatalf("put: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var org admin.OrgJSON
+		testutil.ParseJSON(t, resp, &org)
+		if len(org.MemberUserIDs) != 1 || org.MemberUserIDs[0] != member.ID {
+			t.Errorf("members = %v, want [%d]", org.MemberUserIDs, member.ID)
+		}
+
+		resp, err = client.Get("/api/v1/admin/orgs")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var list admin.OrgsListResponse
+		testutil.ParseJSON(t, resp, &list)
+		if len(list.Orgs) != 1 || len(list.Orgs[0].MemberUserIDs) != 1 {
+			t.Errorf("list = %+v, want acme with one member", list.Orgs)
+		}
+	})
+
+	t.Run("remove member", func(t *testing.T) {
+		resp, err := client.Delete(memberPath)
+		if err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var org admin.OrgJSON
+		testutil.ParseJSON(t, resp, &org)
+		if len(org.MemberUserIDs) != 0 {
+			t.Errorf("members = %v, want none", org.MemberUserIDs)
+		}
+	})
+
+	t.Run("unknown org or user gets 404", func(t *testing.T) {
+		for _, path := range []string{
+			fmt.Sprintf("/api/v1/admin/orgs/999999/members/%d", member.ID),
+			fmt.Sprintf("/api/v1/admin/orgs/%d/members/999999", created.ID),
+		} {
+			resp, err := client.Request("PUT", path, nil)
+			if err != nil {
+				t.Fatalf("put: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNotFound {
+				t.Errorf("PUT %s: expected 404, got %d", path, resp.StatusCode)
+			}
+		}
+	})
+}
@@ -0,0 +1,423 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/auth"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbreconciliation"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+const (
+	// defaultReconciliationThresholdPct flags a row whose actual cost differs
+	// from the estimate by more than this share of the actual.
+	defaultReconciliationThresholdPct = 10.0
+	// maxCorrectionFactor bounds operator-set correction factors; anything
+	// further from 1 is more likely a typo than a pricing drift.
+	maxCorrectionFactor = 10
+	// maxCorrectionNoteLength bounds a correction factor's note.
+	maxCorrectionNoteLength = 500
+)
+
+// UsageImportResponse is the response for POST /api/v1/admin/reconciliation/import.
+type UsageImportResponse struct {
+	RowsImported int    `json:"rows_imported"`
+	Days         int    `json:"days"`
+	FirstDate    string `json:"first_date,omitempty"`
+	LastDate     string `json:"last_date,omitempty"`
+	// SkippedModels counts export rows per model name that is not an
+	// Anthropic model family; those rows are not stored.
+	SkippedModels map[string]int `json:"skipped_models"`
+}
+
+// ReconciliationRowJSON compares the estimate and the imported actual cost of
+// one model, for one day (Date set) or summed over the month's imported days.
+// ActualCostUSD, DeltaUSD and DeltaPct are null for a day with nothing
+// imported; DeltaPct is also null when the actual cost is zero.
+type ReconciliationRowJSON struct {
+	Date             string   `json:"date,omitempty"`
+	Model            string   `json:"model"`
+	EstimatedCostUSD string   `json:"estimated_cost_usd"`
+	ActualCostUSD    *string  `json:"actual_cost_usd"`
+	DeltaUSD         *string  `json:"delta_usd"`
+	DeltaPct         *float64 `json:"delta_pct"`
+	Flagged          bool     `json:"flagged"`
+}
+
+// ReconciliationTotalsJSON sums every model over the month's imported days.
+// SuggestedFactor is actual / estimated, a starting point for the month's
+// correction factor; null when nothing was estimated.
+type ReconciliationTotalsJSON struct {
+	EstimatedCostUSD string   `json:"estimated_cost_usd"`
+	ActualCostUSD    string   `json:"actual_cost_usd"`
+	DeltaUSD         string   `json:"delta_usd"`
+	DeltaPct         *float64 `json:"delta_pct"`
+	SuggestedFactor  *string  `json:"suggested_factor"`
+}
+
+// CorrectionFactorJSON is a month's operator-set cost correction factor.
+type CorrectionFactorJSON struct {
+	Month     string `json:"month"`
+	Factor    string `json:"factor"`
+	Note      string `json:"note"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// ReconciliationResponse is the response for GET /api/v1/admin/reconciliation.
+type ReconciliationResponse struct {
+	Month            string                   `json:"month"`
+	ThresholdPct     float64                  `json:"threshold_pct"`
+	ImportedDays     int                      `json:"imported_days"`
+	Totals           ReconciliationTotalsJSON `json:"totals"`
+	Models           []ReconciliationRowJSON  `json:"models"`
+	Days             []ReconciliationRowJSON  `json:"days"`
+	CorrectionFactor *CorrectionFactorJSON    `json:"correction_factor"`
+}
+
+// SetCorrectionFactorRequest is the body of
+// PUT /api/v1/admin/reconciliation/correction-factors/{month}.
+type SetCorrectionFactorRequest struct {
+	Factor string `json:"factor"`
+	Note   string `json:"note"`
+}
+
+func correctionFactorJSON(cf *dbreconciliation.CorrectionFactor) *CorrectionFactorJSON {
+	return &CorrectionFactorJSON{
+		Month:     cf.Month.Format("2006-01"),
+		Factor:    cf.Factor.String(),
+		Note:      cf.Note,
+		UpdatedAt: cf.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// parseMonth parses a "YYYY-MM" month into its first day, UTC.
+func parseMonth(s string) (time.Time, error) {
+	m, err := time.Parse("2006-01", s)
+	if err != nil {
+		return time.Time{}, errors.New("month must be YYYY-MM")
+	}
+	return m, nil
+}
+
+// HandleImportUsage stores an Anthropic console usage CSV export (the raw
+// request body) as the actual usage of every day it covers, replacing what was
+// imported for those days before. Card values are never changed.
+func (h *Handlers) HandleImportUsage(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		httputil.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	parsed, err := parseUsageCSV(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			httputil.RespondError(w, http.StatusRequestEntityTooLarge, "CSV is too large")
+			return
+		}
+		httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(parsed.Rows) == 0 {
+		httputil.RespondError(w, http.StatusBadRequest, "CSV has no rows for Anthropic models")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	if err := h.reconciliationStore.ReplaceDays(ctx, parsed.Rows, adminID); err != nil {
+		logger.Ctx(r.Context()).Error("Failed to import usage", "error", err)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to import usage")
+		return
+	}
+
+	days := map[time.Time]struct{}{}
+	for _, row := range parsed.Rows {
+		days[row.Date] = struct{}{}
+	}
+	resp := UsageImportResponse{
+		RowsImported:  len(parsed.Rows),
+		Days:          len(days),
+		FirstDate:     parsed.Rows[0].Date.Format(time.DateOnly),
+		LastDate:      parsed.Rows[len(parsed.Rows)-1].Date.Format(time.DateOnly),
+		SkippedModels: parsed.SkippedModels,
+	}
+
+	AuditLogFromRequest(r, h.DB, ActionUsageImport, map[string]interface{}{
+		"rows_imported": resp.RowsImported,
+		"first_date":    resp.FirstDate,
+		"last_date":     resp.LastDate,
+	})
+
+	httputil.RespondJSON(w, http.StatusOK, resp)
+}
+
+// HandleGetReconciliation compares the summed card estimates of ?month=
+// (YYYY-MM) against the imported actual usage, per model per day, flagging
+// rows that differ by more than ?threshold_pct= (default 10) of the actual.
+func (h *Handlers) HandleGetReconciliation(w http.ResponseWriter, r *http.Request) {
+	month, err := parseMonth(r.URL.Query().Get("month"))
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	threshold := defaultReconciliationThresholdPct
+	if v := r.URL.Query().Get("threshold_pct"); v != "" {
+		threshold, err = strconv.ParseFloat(v, 64)
+		if err != nil || threshold < 0 || threshold > 1000 {
+			httputil.RespondError(w, http.StatusBadRequest, "threshold_pct must be a number between 0 and 1000")
+			return
+		}
+	}
+	next := month.AddDate(0, 1, 0)
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	estimates, err := h.analyticsStore.EstimatedDailyModelCosts(ctx, month, next)
+	if err != nil {
+		logger.Ctx(r.Context()).Error("Failed to sum estimated costs", "error", err)
+		if errors.Is(err, db.ErrQueryTimeout) {
+			httputil.RespondQueryTimeout(w, db.LoadQueryTimeout())
+			return
+		}
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to compute reconciliation")
+		return
+	}
+	actuals, err := h.reconciliationStore.ListUsage(ctx, month, next)
+	if err != nil {
+		logger.Ctx(r.Context()).Error("Failed to list imported usage", "error", err)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to compute reconciliation")
+		return
+	}
+	factors, err := h.reconciliationStore.CorrectionFactors(ctx, month, next)
+	if err != nil {
+		logger.Ctx(r.Context()).Error("Failed to load correction factor", "error", err)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to compute reconciliation")
+		return
+	}
+
+	resp := reconcile(estimates, actuals, threshold)
+	resp.Month = month.Format("2006-01")
+	if len(factors) > 0 {
+		resp.CorrectionFactor = correctionFactorJSON(&factors[0])
+	}
+	httputil.RespondJSON(w, http.StatusOK, resp)
+}
+
+// HandleSetCorrectionFactor creates or replaces the correction factor of the
+// {month} path parameter (YYYY-MM).
+func (h *Handlers) HandleSetCorrectionFactor(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		httputil.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	month, err := parseMonth(chi.URLParam(r, "month"))
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var req SetCorrectionFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	factor, err := decimal.NewFromString(strings.TrimSpace(req.Factor))
+	if err != nil || !factor.IsPositive() || factor.GreaterThan(decimal.NewFromInt(maxCorrectionFactor)) {
+		httputil.RespondError(w, http.StatusBadRequest, "factor must be a decimal string greater than 0 and at most 10")
+		return
+	}
+	if factor.Exponent() < -4 {
+		httputil.RespondError(w, http.StatusBadRequest, "factor must have at most 4 decimal places")
+		return
+	}
+	note := strings.TrimSpace(req.Note)
+	if len(note) > maxCorrectionNoteLength {
+		httputil.RespondError(w, http.StatusBadRequest, "note must be at most 500 characters")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	cf, err := h.reconciliationStore.SetCorrectionFactor(ctx, month, factor, note, adminID)
+	if err != nil {
+		logger.Ctx(r.Context()).Error("Failed to set correction factor", "error", err)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to set correction factor")
+		return
+	}
+
+	AuditLogFromRequest(r, h.DB, ActionCorrectionFactorSet, map[string]interface{}{
+		"month":  cf.Month.Format("2006-01"),
+		"factor": cf.Factor.String(),
+	})
+
+	httputil.RespondJSON(w, http.StatusOK, correctionFactorJSON(cf))
+}
+
+// HandleDeleteCorrectionFactor removes the correction factor of the {month}
+// path parameter. 404 if it has none.
+func (h *Handlers) HandleDeleteCorrectionFactor(w http.ResponseWriter, r *http.Request) {
+	month, err := parseMonth(chi.URLParam(r, "month"))
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	deleted, err := h.reconciliationStore.DeleteCorrectionFactor(ctx, month)
+	if err != nil {
+		logger.Ctx(r.Context()).Error("Failed to delete correction factor", "error", err)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to delete correction factor")
+		return
+	}
+	if !deleted {
+		httputil.RespondError(w, http.StatusNotFound, "No correction factor for this month")
+		return
+	}
+
+	AuditLogFromRequest(r, h.DB, ActionCorrectionFactorDelete, map[string]interface{}{
+		"month": month.Format("2006-01"),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reconcile builds the per-day and per-model comparison. Days with nothing
+// imported are listed with their estimates but never flagged and are left
+// out of the model and month totals, so those compare like with like.
+func reconcile(estimates []analytics.EstimatedModelCost, actuals []dbreconciliation.UsageRow, thresholdPct float64) ReconciliationResponse {
+	type key struct {
+		date  string
+		model string
+	}
+	type pair struct {
+		estimated, actual decimal.Decimal
+		hasActual         bool
+	}
+	cells := map[key]*pair{}
+	cell := func(k key) *pair {
+		p := cells[k]
+		if p == nil {
+			p = &pair{}
+			cells[k] = p
+		}
+		return p
+	}
+	imported := map[string]bool{}
+	for _, a := range actuals {
+		date := a.Date.Format(time.DateOnly)
+		imported[date] = true
+		p := cell(key{date, a.Model})
+		p.actual = p.actual.Add(a.CostUSD)
+		p.hasActual = true
+	}
+	for _, e := range estimates {
+		p := cell(key{e.Date.Format(time.DateOnly), e.Model})
+		p.estimated = p.estimated.Add(e.CostUSD)
+	}
+
+	keys := make([]key, 0, len(cells))
+	for k := range cells {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].date != keys[j].date {
+			return keys[i].date < keys[j].date
+		}
+		return keys[i].model < keys[j].model
+	})
+
+	resp := ReconciliationResponse{
+		ThresholdPct: thresholdPct,
+		ImportedDays: len(imported),
+		Models:       []ReconciliationRowJSON{},
+		Days:         make([]ReconciliationRowJSON, 0, len(keys)),
+	}
+	byModel := map[string]*pair{}
+	var models []string
+	totalEstimated, totalActual := decimal.Zero, decimal.Zero
+	for _, k := range keys {
+		p := cells[k]
+		row := ReconciliationRowJSON{Date: k.date, Model: k.model, EstimatedCostUSD: p.estimated.StringFixed(2)}
+		if imported[k.date] {
+			compareCosts(&row, p.estimated, p.actual, thresholdPct)
+			m := byModel[k.model]
+			if m == nil {
+				m = &pair{}
+				byModel[k.model] = m
+				models = append(models, k.model)
+			}
+			m.estimated = m.estimated.Add(p.estimated)
+			m.actual = m.actual.Add(p.actual)
+			totalEstimated = totalEstimated.Add(p.estimated)
+			totalActual = totalActual.Add(p.actual)
+		}
+		resp.Days = append(resp.Days, row)
+	}
+
+	sort.Strings(models)
+	for _, model := range models {
+		m := byModel[model]
+		row := ReconciliationRowJSON{Model: model, EstimatedCostUSD: m.estimated.StringFixed(2)}
+		compareCosts(&row, m.estimated, m.actual, thresholdPct)
+		resp.Models = append(resp.Models, row)
+	}
+
+	resp.Totals = ReconciliationTotalsJSON{
+		EstimatedCostUSD: totalEstimated.StringFixed(2),
+		ActualCostUSD:    totalActual.StringFixed(2),
+		DeltaUSD:         totalActual.Sub(totalEstimated).StringFixed(2),
+		DeltaPct:         deltaPct(totalEstimated, totalActual),
+	}
+	if totalEstimated.IsPositive() {
+		f := totalActual.Div(totalEstimated).StringFixed(4)
+		resp.Totals.SuggestedFactor = &f
+	}
+	return resp
+}
+
+// compareCosts fills row's actual and delta fields and flags it when the
+// actual cost differs from the estimate by more than thresholdPct of the
+// actual, or when only one of them is zero.
+func compareCosts(row *ReconciliationRowJSON, estimated, actual decimal.Decimal, thresholdPct float64) {
+	actualStr := actual.StringFixed(2)
+	delta := actual.Sub(estimated)
+	deltaStr := delta.StringFixed(2)
+	row.ActualCostUSD = &actualStr
+	row.DeltaUSD = &deltaStr
+	row.DeltaPct = deltaPct(estimated, actual)
+	if row.DeltaPct == nil {
+		row.Flagged = estimated.IsPositive()
+		return
+	}
+	row.Flagged = *row.DeltaPct > thresholdPct || *row.DeltaPct < -thresholdPct
+}
+
+// deltaPct is (actual - estimated) as a percentage of actual, rounded to one
+// decimal; nil when actual is zero.
+func deltaPct(estimated, actual decimal.Decimal) *float64 {
+	if !actual.IsPositive() {
+		return nil
+	}
+	pct, _ := actual.Sub(estimated).Div(actual).Mul(decimal.NewFromInt(100)).Round(1).Float64()
+	return &pct
+}
@@ -0,0 +1,257 @@
+package admin
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbreconciliation"
+)
+
+// maxUsageImportRows bounds the data rows of one usage CSV import.
+const maxUsageImportRows = 200000
+
+// usageColumn is a field of the Anthropic usage export, matched by any of its
+// header names. The export has been renamed and reshaped before, so headers
+// are compared after normalizeUsageHeader and every known spelling is listed.
+type usageColumn struct {
+	field   string
+	headers []string
+	// sum adds up every matching column instead of taking the first (the
+	// export splits cache writes into 5-minute and 1-hour columns).
+	sum bool
+}
+
+var (
+	usageDateColumn  = usageColumn{field: "date", headers: []string{"usage_date_utc", "usage_date", "date_utc", "date", "day"}}
+	usageModelColumn = usageColumn{field: "model", headers: []string{"model", "model_version", "model_name"}}
+	usageCostColumn  = usageColumn{field: "cost", headers: []string{"cost_usd", "total_cost_usd", "amount_usd", "cost", "amount"}}
+
+	usageInputColumn      = usageColumn{field: "input tokens", headers: []string{"usage_input_tokens_no_cache", "uncached_input_tokens", "input_tokens"}}
+	usageOutputColumn     = usageColumn{field: "output tokens", headers: []string{"usage_output_tokens", "output_tokens"}}
+	usageCacheWriteColumn = usageColumn{field: "cache write tokens", sum: true, headers: []string{
+		"usage_input_tokens_cache_write_5m", "usage_input_tokens_cache_write_1h",
+		"cache_creation_input_tokens", "cache_write_tokens",
+	}}
+	usageCacheReadColumn = usageColumn{field: "cache read tokens", headers: []string{"usage_input_tokens_cache_read", "cache_read_input_tokens", "cache_read_tokens"}}
+)
+
+// normalizeUsageHeader lowercases a header and turns every run of
+// non-alphanumerics into a single underscore, so "Cost (USD)" and "cost_usd"
+// match.
+func normalizeUsageHeader(h string) string {
+	var b strings.Builder
+	pendingSep := false
+	for _, r := range strings.ToLower(strings.TrimPrefix(h, "\ufeff")) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if pendingSep && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			pendingSep = false
+			b.WriteRune(r)
+		} else {
+			pendingSep = true
+		}
+	}
+	return b.String()
+}
+
+// resolve returns the indexes of c's columns in the normalized header: the
+// first match, or every match for a sum column. Nil when absent.
+func (c usageColumn) resolve(header map[string]int) []int {
+	var idx []int
+	for _, name := range c.headers {
+		if i, ok := header[name]; ok {
+			idx = append(idx, i)
+			if !c.sum {
+				break
+			}
+		}
+	}
+	return idx
+}
+
+// UsageImportResult summarizes a parsed usage export.
+type UsageImportResult struct {
+	Rows []dbreconciliation.UsageRow
+	// SkippedModels counts data rows per model name that is not an Anthropic
+	// model family (and so has no card estimate to compare with).
+	SkippedModels map[string]int
+}
+
+// parseUsageCSV reads an Anthropic console usage export. The date, model and
+// cost columns are required; token columns are optional and unknown columns
+// are ignored. Rows are summed per (day, model family), since the export
+// splits each day by workspace, API key and token type. Errors name the
+// offending line.
+func parseUsageCSV(r io.Reader) (*UsageImportResult, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+
+	headerRow, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("CSV is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+	header := make(map[string]int, len(headerRow))
+	for i, h := range headerRow {
+		if name := normalizeUsageHeader(h); name != "" {
+			if _, dup := header[name]; !dup {
+				header[name] = i
+			}
+		}
+	}
+
+	var missing []string
+	required := map[string][]int{}
+	for _, c := range []usageColumn{usageDateColumn, usageModelColumn, usageCostColumn} {
+		idx := c.resolve(header)
+		if idx == nil {
+			missing = append(missing, fmt.Sprintf("%s (one of: %s)", c.field, strings.Join(c.headers, ", ")))
+		}
+		required[c.field] = idx
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("CSV is missing required columns: %s", strings.Join(missing, "; "))
+	}
+	dateIdx, modelIdx, costIdx := required["date"][0], required["model"][0], required["cost"][0]
+	inputIdx := usageInputColumn.resolve(header)
+	outputIdx := usageOutputColumn.resolve(header)
+	cacheWriteIdx := usageCacheWriteColumn.resolve(header)
+	cacheReadIdx := usageCacheReadColumn.resolve(header)
+
+	type key struct {
+		day   time.Time
+		model string
+	}
+	sums := map[key]*dbreconciliation.UsageRow{}
+	skipped := map[string]int{}
+	for n := 0; ; n++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		if n >= maxUsageImportRows {
+			return nil, fmt.Errorf("CSV has more than %d rows; import it in smaller date ranges", maxUsageImportRows)
+		}
+		field := func(i int) string {
+			if i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+
+		day, err := parseUsageDate(field(dateIdx))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rawModel := field(modelIdx)
+		if rawModel == "" {
+			return nil, fmt.Errorf("line %d: model is empty", line)
+		}
+		cost, err := parseUsageCost(field(costIdx))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		model, ok := analytics.AnthropicModelFamily(rawModel)
+		if !ok {
+			skipped[rawModel]++
+			continue
+		}
+
+		k := key{day: day, model: model}
+		row := sums[k]
+		if row == nil {
+			row = &dbreconciliation.UsageRow{Date: day, Model: model, CostUSD: decimal.Zero}
+			sums[k] = row
+		}
+		row.CostUSD = row.CostUSD.Add(cost)
+		for _, t := range []struct {
+			dst  *int64
+			idx  []int
+			name string
+		}{
+			{&row.InputTokens, inputIdx, usageInputColumn.field},
+			{&row.OutputTokens, outputIdx, usageOutputColumn.field},
+			{&row.CacheWriteTokens, cacheWriteIdx, usageCacheWriteColumn.field},
+			{&row.CacheReadTokens, cacheReadIdx, usageCacheReadColumn.field},
+		} {
+			for _, i := range t.idx {
+				v, err := parseUsageTokens(field(i))
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %s: %w", line, t.name, err)
+				}
+				*t.dst += v
+			}
+		}
+	}
+
+	rows := make([]dbreconciliation.UsageRow, 0, len(sums))
+	for _, row := range sums {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].Date.Equal(rows[j].Date) {
+			return rows[i].Date.Before(rows[j].Date)
+		}
+		return rows[i].Model < rows[j].Model
+	})
+	return &UsageImportResult{Rows: rows, SkippedModels: skipped}, nil
+}
+
+// parseUsageDate accepts a plain date or a timestamp whose first ten
+// characters are one ("2025-06-01", "2025-06-01T00:00:00Z").
+func parseUsageDate(s string) (time.Time, error) {
+	if len(s) >= len(time.DateOnly) {
+		if d, err := time.Parse(time.DateOnly, s[:len(time.DateOnly)]); err == nil {
+			return d, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", s)
+}
+
+// parseUsageCost parses a non-negative dollar amount, allowing a "$" prefix
+// and thousands separators.
+func parseUsageCost(s string) (decimal.Decimal, error) {
+	v := strings.NewReplacer("$", "", ",", "").Replace(s)
+	if v == "" {
+		return decimal.Zero, nil
+	}
+	d, err := decimal.NewFromString(v)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid cost %q", s)
+	}
+	if d.IsNegative() {
+		return decimal.Zero, fmt.Errorf("negative cost %q", s)
+	}
+	return d, nil
+}
+
+// parseUsageTokens parses a non-negative token count; blank is zero.
+func parseUsageTokens(s string) (int64, error) {
+	v := strings.ReplaceAll(s, ",", "")
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid count %q", s)
+	}
+	return n, nil
+}
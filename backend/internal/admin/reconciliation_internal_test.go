@@ -0,0 +1,165 @@
+package admin
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbreconciliation"
+)
+
+func TestParseUsageCSV_ConsoleExport(t *testing.T) {
+	csv := "\ufeffusage_date_utc,model_version,workspace,api_key,usage_input_tokens_no_cache,usage_input_tokens_cache_write_5m,usage_input_tokens_cache_write_1h,usage_input_tokens_cache_read,usage_output_tokens,web_search_count,cost_usd\n" +
+		"2026-03-01,claude-opus-4-5-20251101,Default,key-a,100,10,5,1000,50,0,1.50\n" +
+		"2026-03-01,claude-opus-4-5-20251101,Default,key-b,200,0,0,0,25,0,$2.25\n" +
+		"2026-03-01,claude-sonnet-4-5-20250929,Default,key-a,\"1,000\",0,0,0,0,0,0.10\n" +
+		"2026-03-02T00:00:00Z,Claude Haiku 4.5,Default,key-a,1,0,0,0,1,0,0.01\n" +
+		"2026-03-02,gpt-5,Default,key-a,1,0,0,0,1,0,9.99\n"
+
+	got, err := parseUsageCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("parseUsageCSV: %v", err)
+	}
+
+	day1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	want := []dbreconciliation.UsageRow{
+		{Date: day1, Model: "opus-4-5", CostUSD: decimal.RequireFromString("3.75"), InputTokens: 300, OutputTokens: 75, CacheWriteTokens: 15, CacheReadTokens: 1000},
+		{Date: day1, Model: "sonnet-4-5", CostUSD: decimal.RequireFromString("0.10"), InputTokens: 1000},
+		{Date: day2, Model: "haiku-4-5", CostUSD: decimal.RequireFromString("0.01"), InputTokens: 1, OutputTokens: 1},
+	}
+	if len(got.Rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(got.Rows), len(want), got.Rows)
+	}
+	for i, w := range want {
+		g := got.Rows[i]
+		if !g.Date.Equal(w.Date) || g.Model != w.Model || !g.CostUSD.Equal(w.CostUSD) ||
+			g.InputTokens != w.InputTokens || g.OutputTokens != w.OutputTokens ||
+			g.CacheWriteTokens != w.CacheWriteTokens || g.CacheReadTokens != w.CacheReadTokens {
+			t.Errorf("row %d = %+v, want %+v", i, g, w)
+		}
+	}
+	if got.SkippedModels["gpt-5"] != 1 || len(got.SkippedModels) != 1 {
+		t.Errorf("SkippedModels = %v, want gpt-5 once", got.SkippedModels)
+	}
+}
+
+func TestParseUsageCSV_HeaderSpellings(t *testing.T) {
+	// Reordered, renamed and extra columns; no token columns at all.
+	csv := "Region,Cost (USD),Model,Date\n" +
+		"us,0.50,claude-sonnet-4-20250514,2026-03-05\n"
+
+	got, err := parseUsageCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("parseUsageCSV: %v", err)
+	}
+	if len(got.Rows) != 1 || got.Rows[0].Model != "sonnet-4" || got.Rows[0].CostUSD.String() != "0.5" {
+		t.Errorf("rows = %+v", got.Rows)
+	}
+}
+
+func TestParseUsageCSV_Errors(t *testing.T) {
+	cases := map[string]struct {
+		csv  string
+		want string
+	}{
+		"empty":          {"", "CSV is empty"},
+		"missing cost":   {"date,model\n2026-03-01,claude-opus-4-5\n", "missing required columns: cost"},
+		"missing two":    {"cost_usd\n1\n", "date (one of:"},
+		"bad date":       {"date,model,cost_usd\n03/01/2026,claude-opus-4-5,1\n", "line 2: invalid date"},
+		"bad cost":       {"date,model,cost_usd\n2026-03-01,claude-opus-4-5,1\n2026-03-01,claude-opus-4-5,abc\n", "line 3: invalid cost"},
+		"negative cost":  {"date,model,cost_usd\n2026-03-01,claude-opus-4-5,-1\n", "line 2: negative cost"},
+		"empty model":    {"date,model,cost_usd\n2026-03-01,,1\n", "line 2: model is empty"},
+		"bad token":      {"date,model,cost_usd,output_tokens\n2026-03-01,claude-opus-4-5,1,many\n", "line 2: output tokens: invalid count"},
+		"malformed csv":  {"date,model,cost_usd\n\"2026-03-01,claude-opus-4-5,1\n", "invalid CSV"},
+		"non-csv header": {"\"unterminated\n", "invalid CSV header"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseUsageCSV(strings.NewReader(c.csv))
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("err = %v, want it to contain %q", err, c.want)
+			}
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	day1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	day3 := time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)
+	d := decimal.RequireFromString
+
+	estimates := []analytics.EstimatedModelCost{
+		{Date: day1, Model: "opus-4-5", CostUSD: d("10")},
+		{Date: day1, Model: "sonnet-4-5", CostUSD: d("1")},
+		{Date: day2, Model: "opus-4-5", CostUSD: d("5")},
+		{Date: day3, Model: "opus-4-5", CostUSD: d("7")}, // day not imported
+	}
+	actuals := []dbreconciliation.UsageRow{
+		{Date: day1, Model: "opus-4-5", CostUSD: d("10.50")}, // within 10%
+		{Date: day1, Model: "sonnet-4-5", CostUSD: d("2")},   // 50% off
+		{Date: day2, Model: "haiku-4-5", CostUSD: d("0.40")}, // not estimated
+		// day2 opus-4-5 estimated but absent from the import
+	}
+
+	got := reconcile(estimates, actuals, 10)
+
+	if got.ImportedDays != 2 {
+		t.Errorf("ImportedDays = %d, want 2", got.ImportedDays)
+	}
+	type row struct {
+		date, model, est, actual string
+		flagged                  bool
+	}
+	want := []row{
+		{"2026-03-01", "opus-4-5", "10.00", "10.50", false},
+		{"2026-03-01", "sonnet-4-5", "1.00", "2.00", true},
+		{"2026-03-02", "haiku-4-5", "0.00", "0.40", true},
+		{"2026-03-02", "opus-4-5", "5.00", "0.00", true},
+		{"2026-03-03", "opus-4-5", "7.00", "", false},
+	}
+	if len(got.Days) != len(want) {
+		t.Fatalf("got %d days, want %d: %+v", len(got.Days), len(want), got.Days)
+	}
+	for i, w := range want {
+		g := got.Days[i]
+		actual := ""
+		if g.ActualCostUSD != nil {
+			actual = *g.ActualCostUSD
+		}
+		if g.Date != w.date || g.Model != w.model || g.EstimatedCostUSD != w.est || actual != w.actual || g.Flagged != w.flagged {
+			t.Errorf("day %d = %+v (actual %q), want %+v", i, g, actual, w)
+		}
+	}
+	if g := got.Days[1]; g.DeltaPct == nil || *g.DeltaPct != 50 {
+		t.Errorf("sonnet delta_pct = %v, want 50", g.DeltaPct)
+	}
+	if g := got.Days[3]; g.DeltaPct != nil {
+		t.Errorf("zero-actual delta_pct = %v, want nil", *g.DeltaPct)
+	}
+
+	// Model and month totals cover imported days only (day 3 excluded).
+	if len(got.Models) != 3 || got.Models[1].Model != "opus-4-5" || got.Models[1].EstimatedCostUSD != "15.00" || *got.Models[1].ActualCostUSD != "10.50" {
+		t.Errorf("models = %+v", got.Models)
+	}
+	if got.Totals.EstimatedCostUSD != "16.00" || got.Totals.ActualCostUSD != "12.90" || got.Totals.DeltaUSD != "-3.10" {
+		t.Errorf("totals = %+v", got.Totals)
+	}
+	if got.Totals.SuggestedFactor == nil || *got.Totals.SuggestedFactor != "0.8063" {
+		t.Errorf("suggested factor = %v, want 0.8063", got.Totals.SuggestedFactor)
+	}
+}
+
+func TestReconcile_Empty(t *testing.T) {
+	got := reconcile(nil, nil, 10)
+	if got.Days == nil || got.Models == nil {
+		t.Error("expected non-nil days and models")
+	}
+	if got.Totals.SuggestedFactor != nil || got.Totals.DeltaPct != nil {
+		t.Errorf("totals = %+v, want no factor or percentage", got.Totals)
+	}
+}
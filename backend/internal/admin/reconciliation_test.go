@@ -0,0 +1,166 @@
+package admin_test
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/admin"
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestReconciliationAPI(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "user@test.com", "User")
+	adminUser := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+	testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+
+	// $10 of opus-4-5 and $1 of sonnet-4-5 estimated on 2026-03-01.
+	sess := testutil.CreateTestSessionWithProvider(t, env, user.ID, "reconcile-ext", models.ProviderClaudeCode)
+	if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET first_seen = '2026-03-01T12:00:00Z' WHERE id = $1`, sess); err != nil {
+		t.Fatalf("set first_seen: %v", err)
+	}
+	testutil.SeedTokensV2Card(t, env, sess, analytics.TokensV2Data{
+		TotalCostUSD: "11", ByProvider: map[string]analytics.TokensV2Provider{
+			models.ProviderClaudeCode: {CostUSD: "11", Models: map[string]analytics.TokensV2Model{
+				"opus-4-5":   {Input: 100, CostUSD: "10"},
+				"sonnet-4-5": {Input: 10, CostUSD: "1"},
+			}},
+		},
+	})
+
+	ts := setupTestServer(t, env)
+	client := adminClient(t, env, ts, adminUser.ID)
+
+	importCSV := func(c *testutil.TestClient, body string) *http.Response {
+		t.Helper()
+		resp, err := c.RequestWithHeaders(http.MethodPost, "/api/v1/admin/reconciliation/import", body,
+			map[string]string{"Content-Type": "text/csv"})
+		if err != nil {
+			t.Fatalf("import: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("non-admin gets 403", func(t *testing.T) {
+		resp := importCSV(adminClient(t, env, ts, user.ID), "date,model,cost_usd\n")
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("missing columns are rejected", func(t *testing.T) {
+		resp := importCSV(client, "date,model\n2026-03-01,claude-opus-4-5\n")
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+		var body map[string]string
+		testutil.ParseJSON(t, resp, &body)
+		if !strings.Contains(body["error"], "cost") {
+			t.Errorf("error = %q, want it to name the cost column", body["error"])
+		}
+	})
+
+	t.Run("import then reconcile", func(t *testing.T) {
+		resp := importCSV(client, "usage_date_utc,model_version,workspace,cost_usd\n"+
+			"2026-03-01,claude-opus-4-5-20251101,Default,6.00\n"+
+			"2026-03-01,claude-opus-4-5-20251101,Other,4.50\n"+
+			"2026-03-01,claude-sonnet-4-5-20250929,Default,2.00\n"+
+			"2026-03-01,gpt-5,Default,1.00\n")
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var imported admin.UsageImportResponse
+		testutil.ParseJSON(t, resp, &imported)
+		if imported.RowsImported != 2 || imported.Days != 1 || imported.FirstDate != "2026-03-01" || imported.SkippedModels["gpt-5"] != 1 {
+			t.Errorf("import = %+v", imported)
+		}
+
+		resp, err := client.Get("/api/v1/admin/reconciliation?month=2026-03")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var body admin.ReconciliationResponse
+		testutil.ParseJSON(t, resp, &body)
+
+		if body.Month != "2026-03" || body.ImportedDays != 1 || len(body.Days) != 2 {
+			t.Fatalf("unexpected response %+v", body)
+		}
+		opus, sonnet := body.Days[0], body.Days[1]
+		if opus.Model != "opus-4-5" || opus.EstimatedCostUSD != "10.00" || *opus.ActualCostUSD != "10.50" || opus.Flagged {
+			t.Errorf("opus = %+v", opus)
+		}
+		if sonnet.Model != "sonnet-4-5" || *sonnet.ActualCostUSD != "2.00" || !sonnet.Flagged {
+			t.Errorf("sonnet = %+v", sonnet)
+		}
+		if body.Totals.SuggestedFactor == nil || *body.Totals.SuggestedFactor != "1.1364" {
+			t.Errorf("suggested factor = %v, want 1.1364", body.Totals.SuggestedFactor)
+		}
+		if body.CorrectionFactor != nil {
+			t.Errorf("correction factor = %+v, want none", body.CorrectionFactor)
+		}
+	})
+
+	t.Run("month is required", func(t *testing.T) {
+		resp, err := client.Get("/api/v1/admin/reconciliation")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+		resp.Body.Close()
+	})
+
+	t.Run("correction factor", func(t *testing.T) {
+		resp, err := client.Request(http.MethodPut, "/api/v1/admin/reconciliation/correction-factors/2026-03",
+			admin.SetCorrectionFactorRequest{Factor: "0"})
+		if err != nil {
+			t.Fatalf("put: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+		resp.Body.Close()
+
+		resp, err = client.Request(http.MethodPut, "/api/v1/admin/reconciliation/correction-factors/2026-03",
+			admin.SetCorrectionFactorRequest{Factor: "1.1", Note: "March invoice"})
+		if err != nil {
+			t.Fatalf("put: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var cf admin.CorrectionFactorJSON
+		testutil.ParseJSON(t, resp, &cf)
+		if cf.Month != "2026-03" || cf.Factor != "1.1" || cf.Note != "March invoice" {
+			t.Errorf("factor = %+v", cf)
+		}
+
+		resp, err = client.Get("/api/v1/admin/reconciliation?month=2026-03")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		var body admin.ReconciliationResponse
+		testutil.ParseJSON(t, resp, &body)
+		if body.CorrectionFactor == nil || body.CorrectionFactor.Factor != "1.1" {
+			t.Errorf("correction factor = %+v, want 1.1", body.CorrectionFactor)
+		}
+
+		resp, err = client.Delete("/api/v1/admin/reconciliation/correction-factors/2026-03")
+		if err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusNoContent)
+		resp.Body.Close()
+
+		resp, err = client.Delete("/api/v1/admin/reconciliation/correction-factors/2026-03")
+		if err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusNotFound)
+		resp.Body.Close()
+	})
+}
@@ -0,0 +1,51 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// RetentionRunJSON is the latest retention prune of one table.
+type RetentionRunJSON struct {
+	Table            string `json:"table"`
+	RetentionSeconds int64  `json:"retention_seconds"`
+	LastPrunedAt     string `json:"last_pruned_at"`
+	LastRowsDeleted  int64  `json:"last_rows_deleted"`
+	TotalRowsDeleted int64  `json:"total_rows_deleted"`
+}
+
+// RetentionStatsResponse is the response for GET /api/v1/admin/retention.
+type RetentionStatsResponse struct {
+	Tables []RetentionRunJSON `json:"tables"`
+}
+
+// HandleRetentionStats reports when the worker last pruned each table and how
+// many rows it removed. Tables the worker has never pruned are absent.
+func (h *Handlers) HandleRetentionStats(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	runs, err := h.retentionStore.ListRuns(ctx)
+	if err != nil {
+		logger.Ctx(r.Context()).Error("Failed to list retention runs", "error", err)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to list retention runs")
+		return
+	}
+
+	out := make([]RetentionRunJSON, 0, len(runs))
+	for _, run := range runs {
+		out = append(out, RetentionRunJSON{
+			Table:            run.Table,
+			RetentionSeconds: run.RetentionSeconds,
+			LastPrunedAt:     run.LastPrunedAt.UTC().Format(time.RFC3339),
+			LastRowsDeleted:  run.LastRowsDeleted,
+			TotalRowsDeleted: run.TotalRowsDeleted,
+		})
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, RetentionStatsResponse{Tables: out})
+}
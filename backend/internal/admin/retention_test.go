@@ -0,0 +1,71 @@
+package admin_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/admin"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbretention"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestRetentionStatsAPI(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	if _, err := env.DB.Exec(env.Ctx, `TRUNCATE TABLE retention_prune_runs`); err != nil {
+		t.Fatalf("truncate retention_prune_runs: %v", err)
+	}
+
+	user := testutil.CreateTestUser(t, env, "user@test.com", "User")
+	adminUser := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+	testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+	ts := setupTestServer(t, env)
+
+	t.Run("non-admin gets 403", func(t *testing.T) {
+		resp, err := adminClient(t, env, ts, user.ID).Get("/api/v1/admin/retention")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("lists recorded prune runs", func(t *testing.T) {
+		store := &dbretention.Store{DB: env.DB}
+		policy := dbretention.Policy{Table: "device_codes", TimeColumn: "expires_at", Retention: 24 * time.Hour}
+		for _, n := range []int64{4, 3} {
+			if err := store.RecordRun(context.Background(), policy, n); err != nil {
+				t.Fatalf("RecordRun: %v", err)
+			}
+		}
+
+		resp, err := adminClient(t, env, ts, adminUser.ID).Get("/api/v1/admin/retention")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var body admin.RetentionStatsResponse
+		testutil.ParseJSON(t, resp, &body)
+
+		if len(body.Tables) != 1 {
+			t.Fatalf("tables = %+v, want one", body.Tables)
+		}
+		got := body.Tables[0]
+		if got.Table != "device_codes" || got.RetentionSeconds != 86400 || got.LastRowsDeleted != 3 || got.TotalRowsDeleted != 7 {
+			t.Errorf("run = %+v, want device_codes 86400s last 3 total 7", got)
+		}
+		if _, err := time.Parse(time.RFC3339, got.LastPrunedAt); err != nil {
+			t.Errorf("last_pruned_at %q is not RFC3339: %v", got.LastPrunedAt, err)
+		}
+	})
+}
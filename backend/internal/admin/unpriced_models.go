@@ -2,9 +2,11 @@ package admin
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/ConfabulousDev/confab-web/internal/db"
 	"github.com/ConfabulousDev/confab-web/internal/httputil"
 	"github.com/ConfabulousDev/confab-web/internal/logger"
 )
@@ -37,6 +39,10 @@ func (h *Handlers) HandleUnpricedModels(w http.ResponseWriter, r *http.Request)
 	rows, err := h.analyticsStore.UnpricedModels(ctx)
 	if err != nil {
 		logger.Ctx(r.Context()).Error("Failed to compute unpriced models", "error", err)
+		if errors.Is(err, db.ErrQueryTimeout) {
+			httputil.RespondQueryTimeout(w, db.LoadQueryTimeout())
+			return
+		}
 		httputil.RespondError(w, http.StatusInternalServerError, "Failed to compute unpriced models")
 		return
 	}
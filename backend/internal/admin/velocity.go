@@ -0,0 +1,62 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// velocityWindow is how far back the session velocity report looks.
+const velocityWindow = 24 * time.Hour
+
+// TrippedKeyJSON is an API key that sync/init refused new sessions to.
+type TrippedKeyJSON struct {
+	APIKeyID        int64  `json:"api_key_id"`
+	APIKeyName      string `json:"api_key_name"`
+	UserID          int64  `json:"user_id"`
+	UserEmail       string `json:"user_email"`
+	SessionsCreated int    `json:"sessions_created"`
+	Rejected        int    `json:"rejected"`
+	LastRejectedAt  string `json:"last_rejected_at"`
+}
+
+// SessionVelocityResponse is the response for GET /api/v1/admin/velocity.
+type SessionVelocityResponse struct {
+	WindowSeconds int64            `json:"window_seconds"`
+	Keys          []TrippedKeyJSON `json:"keys"`
+}
+
+// HandleSessionVelocity lists the API keys that hit a sync/init session
+// velocity limit in the last 24 hours, most refusals first.
+func (h *Handlers) HandleSessionVelocity(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	keys, err := h.velocityStore.ListTripped(ctx, velocityWindow)
+	if err != nil {
+		logger.Ctx(r.Context()).Error("Failed to list session velocity trips", "error", err)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to list session velocity trips")
+		return
+	}
+
+	out := make([]TrippedKeyJSON, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, TrippedKeyJSON{
+			APIKeyID:        k.APIKeyID,
+			APIKeyName:      k.APIKeyName,
+			UserID:          k.UserID,
+			UserEmail:       k.UserEmail,
+			SessionsCreated: k.SessionsCreated,
+			Rejected:        k.Rejected,
+			LastRejectedAt:  k.LastRejectedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, SessionVelocityResponse{
+		WindowSeconds: int64(velocityWindow / time.Second),
+		Keys:          out,
+	})
+}
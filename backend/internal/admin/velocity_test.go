@@ -0,0 +1,64 @@
+package admin_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/admin"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbvelocity"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestSessionVelocityAPI(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "user@test.com", "User")
+	adminUser := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+	testutil.SetEnvForTest(t, "SUPER_ADMIN_EMAILS", "admin@example.com")
+	ts := setupTestServer(t, env)
+
+	t.Run("non-admin gets 403", func(t *testing.T) {
+		resp, err := adminClient(t, env, ts, user.ID).Get("/api/v1/admin/velocity")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("lists keys that tripped a limit", func(t *testing.T) {
+		key := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Leaky Key")
+		store := &dbvelocity.Store{DB: env.DB}
+		for i := 0; i < 3; i++ {
+			if _, err := store.Admit(context.Background(), key.ID, dbvelocity.Limits{PerHour: 1}); err != nil {
+				t.Fatalf("Admit: %v", err)
+			}
+		}
+
+		resp, err := adminClient(t, env, ts, adminUser.ID).Get("/api/v1/admin/velocity")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var body admin.SessionVelocityResponse
+		testutil.ParseJSON(t, resp, &body)
+
+		if body.WindowSeconds != 86400 || len(body.Keys) != 1 {
+			t.Fatalf("unexpected response %+v", body)
+		}
+		got := body.Keys[0]
+		if got.APIKeyName != "Leaky Key" || got.UserEmail != "user@test.com" || got.SessionsCreated != 1 || got.Rejected != 2 {
+			t.Errorf("unexpected key %+v", got)
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package analytics
+
+import (
+	"fmt"
+	"time"
+)
+
+// ActivityHeatmap is the caller's sessions and tokens by local day of week
+// and hour of day (GET /api/v1/me/activity). Sessions are placed by when they
+// started (first_seen) in Timezone.
+type ActivityHeatmap struct {
+	Timezone string         `json:"timezone"`
+	Totals   ActivityCounts `json:"totals"`
+	// Buckets holds only the non-empty cells, ordered by day then hour.
+	Buckets []ActivityBucket `json:"buckets"`
+}
+
+// ActivityBucket is one cell of the heatmap.
+type ActivityBucket struct {
+	DayOfWeek int `json:"day_of_week"` // 0 = Sunday
+	Hour      int `json:"hour"`        // 0-23
+	ActivityCounts
+}
+
+// ActivityCounts are the session count and token totals of a cell or of the
+// whole heatmap. Sessions not yet analyzed count with zero tokens.
+type ActivityCounts struct {
+	SessionCount        int64 `json:"session_count"`
+	InputTokens         int64 `json:"input_tokens"`
+	OutputTokens        int64 `json:"output_tokens"`
+	CacheCreationTokens int64 `json:"cache_creation_tokens"`
+	CacheReadTokens     int64 `json:"cache_read_tokens"`
+}
+
+// ParseActivityTimezone resolves an IANA zone name for the heatmap; empty
+// means UTC. A zone name rather than a fixed offset keeps hour-of-day right
+// on both sides of a DST change. "Local" is refused: it names the server's
+// zone, not the caller's.
+func ParseActivityTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return time.LoadLocation(name)
+}
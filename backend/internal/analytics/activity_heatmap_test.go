@@ -0,0 +1,30 @@
+package analytics
+
+import "testing"
+
+func TestParseActivityTimezone(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"", "UTC", false},
+		{"UTC", "UTC", false},
+		{"America/New_York", "America/New_York", false},
+		{"Asia/Kolkata", "Asia/Kolkata", false},
+		{"Local", "", true},
+		{"Mars/Olympus_Mons", "", true},
+		{"../etc/passwd", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := ParseActivityTimezone(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseActivityTimezone(%q) err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && loc.String() != tt.want {
+				t.Errorf("ParseActivityTimezone(%q) = %s, want %s", tt.name, loc, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package analytics
+
+import "sort"
+
+// MaxTopAgentsByDuration caps AgentsAndSkillsDetails.TopAgentsByDuration.
+const MaxTopAgentsByDuration = 10
+
+// AgentDetail is the per-agent-type breakdown of the agents and skills card.
+// AgentID is the agent type (subagent_type), the key of AgentStats.
+type AgentDetail struct {
+	AgentID           string `json:"agent_id"`
+	InvocationCount   int    `json:"invocation_count"`
+	TotalDurationMs   int64  `json:"total_duration_ms"`
+	AverageDurationMs int64  `json:"average_duration_ms"`
+	ErrorCount        int    `json:"error_count"`
+	ChildAgentCount   int    `json:"child_agent_count"` // Agents launched by agents of this type
+}
+
+// SkillDetail is the per-skill breakdown of the agents and skills card.
+type SkillDetail struct {
+	Skill           string `json:"skill"`
+	InvocationCount int    `json:"invocation_count"`
+	ErrorCount      int    `json:"error_count"`
+}
+
+// AgentCallNode is one node of the simplified agent call tree. Sibling calls
+// of the same agent type are merged into a single node, so the tree shows
+// which agent types launched which rather than every individual call.
+type AgentCallNode struct {
+	AgentID         string          `json:"agent_id"`
+	InvocationCount int             `json:"invocation_count"`
+	TotalDurationMs int64           `json:"total_duration_ms"`
+	ErrorCount      int             `json:"error_count"`
+	Children        []AgentCallNode `json:"children"`
+}
+
+// AgentsAndSkillsDetails is the API response for the agents and skills
+// details endpoint.
+type AgentsAndSkillsDetails struct {
+	Agents              []AgentDetail   `json:"agents"`
+	TopAgentsByDuration []AgentDetail   `json:"top_agents_by_duration"`
+	Skills              []SkillDetail   `json:"skills"`
+	AgentCallTree       []AgentCallNode `json:"agent_call_tree"`
+	// CallsTruncated is set when the card hit MaxAgentCalls, so durations,
+	// child counts and the tree cover only the first calls.
+	CallsTruncated bool `json:"calls_truncated"`
+}
+
+// BuildAgentsAndSkillsDetails derives the typed details from a stored card.
+// Cards with per-call detail (Claude Code, v3+) report every call including
+// nested ones; otherwise the agent breakdown comes from AgentStats, with no
+// durations and an empty tree. A nil record yields empty details.
+func BuildAgentsAndSkillsDetails(record *AgentsAndSkillsCardRecord) AgentsAndSkillsDetails {
+	details := AgentsAndSkillsDetails{
+		Agents:              []AgentDetail{},
+		TopAgentsByDuration: []AgentDetail{},
+		Skills:              []SkillDetail{},
+		AgentCallTree:       []AgentCallNode{},
+	}
+	if record == nil {
+		return details
+	}
+
+	if len(record.AgentCalls) > 0 {
+		details.Agents = agentDetailsFromCalls(record.AgentCalls)
+		details.AgentCallTree = buildAgentCallTree(record.AgentCalls)
+		details.CallsTruncated = len(record.AgentCalls) >= MaxAgentCalls
+	} else {
+		for agentType, stats := range record.AgentStats {
+			if stats == nil {
+				continue
+			}
+			details.Agents = append(details.Agents, AgentDetail{
+				AgentID:         agentType,
+				InvocationCount: stats.Success + stats.Errors,
+				ErrorCount:      stats.Errors,
+			})
+		}
+	}
+	sort.Slice(details.Agents, func(i, j int) bool {
+		a, b := details.Agents[i], details.Agents[j]
+		if a.InvocationCount != b.InvocationCount {
+			return a.InvocationCount > b.InvocationCount
+		}
+		return a.AgentID < b.AgentID
+	})
+
+	for _, a := range details.Agents {
+		if a.TotalDurationMs > 0 {
+			details.TopAgentsByDuration = append(details.TopAgentsByDuration, a)
+		}
+	}
+	sort.SliceStable(details.TopAgentsByDuration, func(i, j int) bool {
+		return details.TopAgentsByDuration[i].TotalDurationMs > details.TopAgentsByDuration[j].TotalDurationMs
+	})
+	if len(details.TopAgentsByDuration) > MaxTopAgentsByDuration {
+		details.TopAgentsByDuration = details.TopAgentsByDuration[:MaxTopAgentsByDuration]
+	}
+
+	for skill, stats := range record.SkillStats {
+		if stats == nil {
+			continue
+		}
+		details.Skills = append(details.Skills, SkillDetail{
+			Skill:           skill,
+			InvocationCount: stats.Success + stats.Errors,
+			ErrorCount:      stats.Errors,
+		})
+	}
+	sort.Slice(details.Skills, func(i, j int) bool {
+		a, b := details.Skills[i], details.Skills[j]
+		if a.InvocationCount != b.InvocationCount {
+			return a.InvocationCount > b.InvocationCount
+		}
+		return a.Skill < b.Skill
+	})
+
+	return details
+}
+
+// agentDetailsFromCalls aggregates calls by agent type. A call's children are
+// the calls whose ParentAgentID is its AgentID.
+func agentDetailsFromCalls(calls []AgentCall) []AgentDetail {
+	typeOf := make(map[string]string, len(calls))
+	for _, c := range calls {
+		if c.AgentID != "" {
+			typeOf[c.AgentID] = c.AgentType
+		}
+	}
+
+	byType := make(map[string]*AgentDetail)
+	get := func(agentType string) *AgentDetail {
+		d := byType[agentType]
+		if d == nil {
+			d = &AgentDetail{AgentID: agentType}
+			byType[agentType] = d
+		}
+		return d
+	}
+	for _, c := range calls {
+		d := get(c.AgentType)
+		d.InvocationCount++
+		d.TotalDurationMs += c.DurationMs
+		if c.IsError {
+			d.ErrorCount++
+		}
+		if parentType, ok := typeOf[c.ParentAgentID]; ok && c.ParentAgentID != "" {
+			get(parentType).ChildAgentCount++
+		}
+	}
+
+	out := make([]AgentDetail, 0, len(byType))
+	for _, d := range byType {
+		if d.InvocationCount > 0 {
+			d.AverageDurationMs = d.TotalDurationMs / int64(d.InvocationCount)
+		}
+		out = append(out, *d)
+	}
+	return out
+}
+
+// buildAgentCallTree nests calls under their parent call and merges siblings
+// of the same type. Calls whose parent is the main transcript, or a call that
+// was not recorded, are roots.
+func buildAgentCallTree(calls []AgentCall) []AgentCallNode {
+	known := make(map[string]bool, len(calls))
+	for _, c := range calls {
+		if c.AgentID != "" {
+			known[c.AgentID] = true
+		}
+	}
+	childrenOf := make(map[string][]AgentCall)
+	var roots []AgentCall
+	for _, c := range calls {
+		if c.ParentAgentID == "" || !known[c.ParentAgentID] || c.ParentAgentID == c.AgentID {
+			roots = append(roots, c)
+			continue
+		}
+		childrenOf[c.ParentAgentID] = append(childrenOf[c.ParentAgentID], c)
+	}
+	return mergeAgentCalls(roots, childrenOf, make(map[string]bool))
+}
+
+// mergeAgentCalls groups sibling calls by type into nodes and recurses into
+// their children. visited guards against parent cycles in malformed input.
+func mergeAgentCalls(siblings []AgentCall, childrenOf map[string][]AgentCall, visited map[string]bool) []AgentCallNode {
+	nodes := []AgentCallNode{}
+	index := make(map[string]int)
+	children := make(map[string][]AgentCall)
+	for _, c := range siblings {
+		if c.AgentID != "" {
+			if visited[c.AgentID] {
+				continue
+			}
+			visited[c.AgentID] = true
+		}
+		i, ok := index[c.AgentType]
+		if !ok {
+			i = len(nodes)
+			index[c.AgentType] = i
+			nodes = append(nodes, AgentCallNode{AgentID: c.AgentType})
+		}
+		nodes[i].InvocationCount++
+		nodes[i].TotalDurationMs += c.DurationMs
+		if c.IsError {
+			nodes[i].ErrorCount++
+		}
+		if c.AgentID != "" {
+			children[c.AgentType] = append(children[c.AgentType], childrenOf[c.AgentID]...)
+		}
+	}
+	for i := range nodes {
+		nodes[i].Children = mergeAgentCalls(children[nodes[i].AgentID], childrenOf, visited)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].InvocationCount != nodes[j].InvocationCount {
+			return nodes[i].InvocationCount > nodes[j].InvocationCount
+		}
+		return nodes[i].AgentID < nodes[j].AgentID
+	})
+	return nodes
+}
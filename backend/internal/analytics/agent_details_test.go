@@ -0,0 +1,97 @@
+package analytics
+
+import "testing"
+
+func TestBuildAgentsAndSkillsDetails_FromCalls(t *testing.T) {
+	record := &AgentsAndSkillsCardRecord{
+		AgentStats: map[string]*AgentStats{"Explore": {Success: 1, Errors: 1}, "Plan": {Success: 1}},
+		SkillStats: map[string]*SkillStats{"commit": {Success: 2}, "review": {Success: 2, Errors: 1}},
+		AgentCalls: []AgentCall{
+			{AgentID: "e1", AgentType: "Explore", DurationMs: 30000},
+			{AgentID: "e2", AgentType: "Explore", DurationMs: 10000, IsError: true},
+			{AgentID: "p1", AgentType: "Plan", DurationMs: 50000},
+			{AgentID: "g1", AgentType: "general-purpose", ParentAgentID: "e1", DurationMs: 4000},
+			{AgentID: "g2", AgentType: "general-purpose", ParentAgentID: "e2", DurationMs: 2000},
+			{AgentID: "x1", AgentType: "Explore", ParentAgentID: "g1", DurationMs: 1000},
+		},
+	}
+
+	got := BuildAgentsAndSkillsDetails(record)
+
+	want := []AgentDetail{
+		{AgentID: "Explore", InvocationCount: 3, TotalDurationMs: 41000, AverageDurationMs: 13666, ErrorCount: 1, ChildAgentCount: 2},
+		{AgentID: "general-purpose", InvocationCount: 2, TotalDurationMs: 6000, AverageDurationMs: 3000, ChildAgentCount: 1},
+		{AgentID: "Plan", InvocationCount: 1, TotalDurationMs: 50000, AverageDurationMs: 50000},
+	}
+	if len(got.Agents) != len(want) {
+		t.Fatalf("Agents = %+v, want %d rows", got.Agents, len(want))
+	}
+	for i, w := range want {
+		if got.Agents[i] != w {
+			t.Errorf("Agents[%d] = %+v, want %+v", i, got.Agents[i], w)
+		}
+	}
+
+	if len(got.TopAgentsByDuration) != 3 || got.TopAgentsByDuration[0].AgentID != "Plan" ||
+		got.TopAgentsByDuration[1].AgentID != "Explore" || got.TopAgentsByDuration[2].AgentID != "general-purpose" {
+		t.Errorf("TopAgentsByDuration = %+v, want Plan, Explore, general-purpose", got.TopAgentsByDuration)
+	}
+
+	if len(got.Skills) != 2 || got.Skills[0] != (SkillDetail{Skill: "review", InvocationCount: 3, ErrorCount: 1}) ||
+		got.Skills[1] != (SkillDetail{Skill: "commit", InvocationCount: 2}) {
+		t.Errorf("Skills = %+v", got.Skills)
+	}
+
+	// main -> Explore (e1, e2) -> general-purpose (g1, g2) -> Explore (x1); main -> Plan
+	tree := got.AgentCallTree
+	if len(tree) != 2 || tree[0].AgentID != "Explore" || tree[0].InvocationCount != 2 || tree[0].TotalDurationMs != 40000 || tree[0].ErrorCount != 1 ||
+		tree[1].AgentID != "Plan" || len(tree[1].Children) != 0 {
+		t.Fatalf("tree roots = %+v", tree)
+	}
+	gp := tree[0].Children
+	if len(gp) != 1 || gp[0].AgentID != "general-purpose" || gp[0].InvocationCount != 2 || gp[0].TotalDurationMs != 6000 {
+		t.Fatalf("Explore children = %+v", gp)
+	}
+	if leaf := gp[0].Children; len(leaf) != 1 || leaf[0].AgentID != "Explore" || leaf[0].InvocationCount != 1 || leaf[0].Children == nil {
+		t.Errorf("general-purpose children = %+v", leaf)
+	}
+	if got.CallsTruncated {
+		t.Error("CallsTruncated = true, want false")
+	}
+}
+
+func TestBuildAgentsAndSkillsDetails_ParentCycle(t *testing.T) {
+	// Malformed calls that are each other's parents must not recurse forever;
+	// a call whose parent was never recorded is a root.
+	got := BuildAgentsAndSkillsDetails(&AgentsAndSkillsCardRecord{AgentCalls: []AgentCall{
+		{AgentID: "a", AgentType: "A", ParentAgentID: "b"},
+		{AgentID: "b", AgentType: "B", ParentAgentID: "a"},
+		{AgentID: "c", AgentType: "C", ParentAgentID: "missing"},
+	}})
+	if len(got.AgentCallTree) != 1 || got.AgentCallTree[0].AgentID != "C" {
+		t.Errorf("tree = %+v, want only the orphaned C as root", got.AgentCallTree)
+	}
+}
+
+func TestBuildAgentsAndSkillsDetails_StatsOnly(t *testing.T) {
+	// Codex/OpenCode cards have agent stats but no per-call detail.
+	got := BuildAgentsAndSkillsDetails(&AgentsAndSkillsCardRecord{
+		AgentStats: map[string]*AgentStats{"worker": {Success: 2, Errors: 1}, "explorer": {Success: 3}},
+		AgentCalls: []AgentCall{},
+	})
+	if len(got.Agents) != 2 ||
+		got.Agents[0] != (AgentDetail{AgentID: "explorer", InvocationCount: 3}) ||
+		got.Agents[1] != (AgentDetail{AgentID: "worker", InvocationCount: 3, ErrorCount: 1}) {
+		t.Errorf("Agents = %+v", got.Agents)
+	}
+	if len(got.TopAgentsByDuration) != 0 || len(got.AgentCallTree) != 0 {
+		t.Errorf("expected no durations or tree, got %+v / %+v", got.TopAgentsByDuration, got.AgentCallTree)
+	}
+}
+
+func TestBuildAgentsAndSkillsDetails_Nil(t *testing.T) {
+	got := BuildAgentsAndSkillsDetails(nil)
+	if got.Agents == nil || got.TopAgentsByDuration == nil || got.Skills == nil || got.AgentCallTree == nil {
+		t.Errorf("expected empty, non-nil lists, got %+v", got)
+	}
+}
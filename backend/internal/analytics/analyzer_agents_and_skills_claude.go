@@ -1,13 +1,20 @@
 package analytics
 
+import "time"
+
 // =============================================================================
 // Agents
 // =============================================================================
 
+// MaxAgentCalls caps the per-invocation agent calls kept on the agents and
+// skills card. Counts in AgentStats are never capped.
+const MaxAgentCalls = 1000
+
 // AgentsResult contains agent invocation metrics.
 type AgentsResult struct {
 	TotalInvocations int
 	AgentStats       map[string]*AgentStats
+	AgentCalls       []AgentCall // Main and nested invocations, in file order
 }
 
 // isAgentToolName reports whether the given tool name is the agent tool.
@@ -20,21 +27,23 @@ func isAgentToolName(name string) bool {
 // AgentsAnalyzer extracts agent usage metrics from transcripts.
 // It tracks invocations of the agent tool (named "Task" in older transcripts,
 // "Agent" in newer ones) by subagent_type and their outcomes.
-// Main-only: agent invocations are tracked in the main transcript.
+//
+// TotalInvocations and AgentStats count invocations from the main transcript
+// only. AgentCalls also records agents launched from agent files, with the
+// launching agent as parent, so the call tree can be rebuilt.
 type AgentsAnalyzer struct {
 	result               AgentsResult
 	toolUseIDToAgentType map[string]string
+	toolUseIDToStart     map[string]time.Time
 }
 
 // ProcessFile accumulates agent metrics from a single file.
-// Only the main transcript is processed.
 func (a *AgentsAnalyzer) ProcessFile(file *TranscriptFile, isMain bool) {
-	if !isMain {
-		return
+	if isMain {
+		a.result.AgentStats = make(map[string]*AgentStats)
 	}
-
-	a.result.AgentStats = make(map[string]*AgentStats)
 	a.toolUseIDToAgentType = make(map[string]string)
+	a.toolUseIDToStart = make(map[string]time.Time)
 
 	for _, line := range file.Lines {
 		// Find agent tool_use blocks and extract subagent_type.
@@ -44,6 +53,9 @@ func (a *AgentsAnalyzer) ProcessFile(file *TranscriptFile, isMain bool) {
 					if subagentType, ok := tool.Input["subagent_type"].(string); ok && subagentType != "" {
 						a.toolUseIDToAgentType[tool.ID] = subagentType
 					}
+					if ts, err := line.GetTimestamp(); err == nil {
+						a.toolUseIDToStart[tool.ID] = ts
+					}
 				}
 			}
 		}
@@ -65,6 +77,19 @@ func (a *AgentsAnalyzer) ProcessFile(file *TranscriptFile, isMain bool) {
 				agentType = "unknown"
 			}
 
+			if len(a.result.AgentCalls) < MaxAgentCalls {
+				a.result.AgentCalls = append(a.result.AgentCalls, AgentCall{
+					AgentID:       line.ToolUseResult.AgentID,
+					AgentType:     agentType,
+					ParentAgentID: file.AgentID,
+					DurationMs:    a.callDurationMs(line, toolUseID),
+					IsError:       isError,
+				})
+			}
+
+			if !isMain {
+				continue
+			}
 			if a.result.AgentStats[agentType] == nil {
 				a.result.AgentStats[agentType] = &AgentStats{}
 			}
@@ -79,7 +104,25 @@ func (a *AgentsAnalyzer) ProcessFile(file *TranscriptFile, isMain bool) {
 	}
 }
 
-// Finalize is a no-op for agents (main-only analyzer).
+// callDurationMs returns how long an agent call ran: the duration Claude Code
+// reports on the tool result, or else the time between the tool_use and its
+// result. It is 0 when neither is known or the timestamps run backwards.
+func (a *AgentsAnalyzer) callDurationMs(result *TranscriptLine, toolUseID string) int64 {
+	if d := result.ToolUseResult.TotalDurationMs; d > 0 {
+		return d
+	}
+	start, ok := a.toolUseIDToStart[toolUseID]
+	if !ok {
+		return 0
+	}
+	end, err := result.GetTimestamp()
+	if err != nil || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start).Milliseconds()
+}
+
+// Finalize is a no-op for agents.
 func (a *AgentsAnalyzer) Finalize(hasAgentFile func(string) bool) {}
 
 // Result returns the accumulated agent metrics.
@@ -90,6 +133,9 @@ func (a *AgentsAnalyzer) Result() *AgentsResult {
 // Analyze processes the file collection and returns agent metrics.
 func (a *AgentsAnalyzer) Analyze(fc *FileCollection) (*AgentsResult, error) {
 	a.ProcessFile(fc.Main, true)
+	for _, agent := range fc.Agents {
+		a.ProcessFile(agent, false)
+	}
 	a.Finalize(fc.HasAgentFile)
 	return a.Result(), nil
 }
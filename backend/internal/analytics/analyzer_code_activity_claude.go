@@ -13,18 +13,27 @@ type CodeActivityResult struct {
 	LinesRemoved      int
 	SearchCount       int
 	LanguageBreakdown map[string]int
+	OutOfTree         OutOfTreeAccess
+	Files             []CodeFileStats
 }
 
 // CodeActivityAnalyzer extracts code activity metrics from transcripts.
 // It tracks file operations from Read, Write, Edit, Glob, and Grep tools.
 // It processes all files (main + agents) to get complete activity.
+//
+// Read/Write/Edit paths outside cwd are tracked as out-of-tree access. An
+// empty cwd is taken from the first main-transcript line that records one.
 type CodeActivityAnalyzer struct {
+	cwd string
+
 	filesRead    map[string]bool
 	filesModified map[string]bool
 	extensions   map[string]int
 	linesAdded   int
 	linesRemoved int
 	searchCount  int
+	outOfTree    outOfTreeTracker
+	files        codeFileTracker
 }
 
 // ProcessFile accumulates code activity from a single file.
@@ -33,9 +42,14 @@ func (a *CodeActivityAnalyzer) ProcessFile(file *TranscriptFile, isMain bool) {
 		a.filesRead = make(map[string]bool)
 		a.filesModified = make(map[string]bool)
 		a.extensions = make(map[string]int)
+		a.outOfTree = outOfTreeTracker{cwd: a.cwd}
+		a.files = codeFileTracker{}
 	}
 
 	for _, line := range file.Lines {
+		if isMain && a.outOfTree.cwd == "" {
+			a.outOfTree.cwd = line.CWD
+		}
 		if !line.IsAssistantMessage() {
 			continue
 		}
@@ -46,25 +60,30 @@ func (a *CodeActivityAnalyzer) ProcessFile(file *TranscriptFile, isMain bool) {
 				if path := getFilePath(tool.Input); path != "" {
 					a.filesRead[path] = true
 					trackExtension(path, a.extensions)
+					a.outOfTree.track(path)
+					a.files.read(path, extensionLanguage(path))
 				}
 
 			case "Write":
 				if path := getFilePath(tool.Input); path != "" {
 					a.filesModified[path] = true
 					trackExtension(path, a.extensions)
-					if content, ok := tool.Input["content"].(string); ok {
-						a.linesAdded += countLines(content)
-					}
+					a.outOfTree.track(path)
+					content, _ := tool.Input["content"].(string)
+					a.linesAdded += countLines(content)
+					a.files.write(path, extensionLanguage(path), countLines(content), 0)
 				}
 
 			case "Edit":
 				if path := getFilePath(tool.Input); path != "" {
 					a.filesModified[path] = true
 					trackExtension(path, a.extensions)
+					a.outOfTree.track(path)
 					oldStr, _ := tool.Input["old_string"].(string)
 					newStr, _ := tool.Input["new_string"].(string)
 					a.linesRemoved += countLines(oldStr)
 					a.linesAdded += countLines(newStr)
+					a.files.write(path, extensionLanguage(path), countLines(newStr), countLines(oldStr))
 				}
 
 			case "Glob", "Grep":
@@ -94,6 +113,8 @@ func (a *CodeActivityAnalyzer) Result() *CodeActivityResult {
 		LinesRemoved:      a.linesRemoved,
 		SearchCount:       a.searchCount,
 		LanguageBreakdown: languageBreakdown,
+		OutOfTree:         a.outOfTree.Result(),
+		Files:             a.files.Result(),
 	}
 }
 
@@ -121,6 +142,12 @@ func trackExtension(path string, extensions map[string]int) {
 	}
 }
 
+// extensionLanguage returns path's extension without the dot, the language
+// key of the Claude Code language breakdown.
+func extensionLanguage(path string) string {
+	return strings.TrimPrefix(filepath.Ext(path), ".")
+}
+
 // countLines counts the number of lines in a string.
 // Empty string returns 0, otherwise count newlines + 1.
 // Trailing newlines are ignored (e.g., "hello\n" = 1 line, not 2).
@@ -15,14 +15,16 @@ import (
 // SearchCount is left at zero. Codex's web_search_call is semantically a
 // web search rather than the grep/glob "file search" that Claude's
 // SearchCount tracks.
-func computeCodexCodeActivity(out *ComputeResult, r *codex.ParsedRollout) {
+//
+// Per-file counts accumulate in files across rollouts.
+func computeCodexCodeActivity(out *ComputeResult, r *codex.ParsedRollout, files *codeFileTracker) {
 	for _, turn := range r.Turns {
 		for _, tc := range turn.ToolCalls {
 			if tc.Name != "apply_patch" {
 				continue
 			}
-			files, added, removed := parseApplyPatch(tc.Arguments, out.LanguageBreakdown)
-			out.FilesModified += files
+			touched, added, removed := parseApplyPatch(tc.Arguments, out.LanguageBreakdown, files)
+			out.FilesModified += touched
 			out.LinesAdded += added
 			out.LinesRemoved += removed
 		}
@@ -31,11 +33,13 @@ func computeCodexCodeActivity(out *ComputeResult, r *codex.ParsedRollout) {
 
 // parseApplyPatch parses a Codex apply_patch envelope, returning the number
 // of files touched (any of Add/Update/Delete) and the cumulative +/- line
-// counts. If langs is non-nil it's updated with file-extension language counts.
-func parseApplyPatch(envelope string, langs map[string]int) (files, added, removed int) {
+// counts. If langs is non-nil it's updated with file-extension language counts;
+// if fileStats is non-nil each file gets a write and its own +/- counts.
+func parseApplyPatch(envelope string, langs map[string]int, fileStats *codeFileTracker) (files, added, removed int) {
 	scanner := bufio.NewScanner(strings.NewReader(envelope))
 	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
 	inFile := false
+	var current *CodeFileStats
 	for scanner.Scan() {
 		line := scanner.Text()
 		switch {
@@ -44,19 +48,29 @@ func parseApplyPatch(envelope string, langs map[string]int) (files, added, remov
 			strings.HasPrefix(line, "*** Delete File: "):
 			files++
 			inFile = true
-			if langs != nil {
-				path := line[strings.Index(line, ": ")+2:]
-				if lang := languageFromPath(path); lang != "" {
-					langs[lang]++
-				}
+			path := line[strings.Index(line, ": ")+2:]
+			lang := languageFromPath(path)
+			if langs != nil && lang != "" {
+				langs[lang]++
+			}
+			if fileStats != nil {
+				fileStats.write(path, lang, 0, 0)
+				current = fileStats.file(path, lang)
 			}
 		case strings.HasPrefix(line, "*** End Patch"),
 			strings.HasPrefix(line, "*** Begin Patch"):
 			inFile = false
+			current = nil
 		case inFile && strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++"):
 			added++
+			if current != nil {
+				current.LinesAdded++
+			}
 		case inFile && strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---"):
 			removed++
+			if current != nil {
+				current.LinesRemoved++
+			}
 		}
 	}
 	return files, added, removed
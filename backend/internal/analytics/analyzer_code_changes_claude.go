@@ -0,0 +1,172 @@
+package analytics
+
+import (
+	"sort"
+	"strings"
+)
+
+// MaxCodeChangeHunks caps how many hunks the code changes card keeps. When a
+// session has more edits, the largest by diff size win.
+const MaxCodeChangeHunks = 100
+
+// MaxCodeChangeHunkLines caps the added and the removed lines stored per hunk,
+// and MaxCodeChangeLineBytes each stored line, so one huge edit can't bloat the
+// card. LinesBefore and LinesAfter still describe the full edit.
+const (
+	MaxCodeChangeHunkLines = 50
+	MaxCodeChangeLineBytes = 300
+)
+
+// CodeChangesResult contains the sampled Edit/MultiEdit hunks of a session.
+type CodeChangesResult struct {
+	TotalHunks int
+	Hunks      []EditHunk
+}
+
+// CodeChangesAnalyzer collects a diff hunk for each Edit call and each entry
+// of a MultiEdit call. It processes all files (main + agents), main first, so
+// hunks are kept in that order.
+type CodeChangesAnalyzer struct {
+	hunks []codeChangeHunk
+}
+
+// codeChangeHunk is a hunk before sampling, with its untruncated lines.
+type codeChangeHunk struct {
+	filePath       string
+	before, after  int
+	added, removed []string
+}
+
+// size is the hunk's diff size, the sampling key.
+func (h codeChangeHunk) size() int {
+	return len(h.added) + len(h.removed)
+}
+
+// ProcessFile collects the hunks of a single file's Edit/MultiEdit calls.
+func (a *CodeChangesAnalyzer) ProcessFile(file *TranscriptFile, isMain bool) {
+	if isMain {
+		a.hunks = nil
+	}
+
+	for _, line := range file.Lines {
+		if !line.IsAssistantMessage() {
+			continue
+		}
+
+		for _, tool := range line.GetToolUses() {
+			switch tool.Name {
+			case "Edit":
+				a.addEdit(getFilePath(tool.Input), tool.Input)
+
+			case "MultiEdit":
+				path := getFilePath(tool.Input)
+				edits, _ := tool.Input["edits"].([]interface{})
+				for _, e := range edits {
+					if edit, ok := e.(map[string]interface{}); ok {
+						a.addEdit(path, edit)
+					}
+				}
+			}
+		}
+	}
+}
+
+// addEdit records the hunk of one old_string → new_string replacement.
+// Edits that change nothing are skipped.
+func (a *CodeChangesAnalyzer) addEdit(path string, edit map[string]interface{}) {
+	if path == "" {
+		return
+	}
+	oldStr, _ := edit["old_string"].(string)
+	newStr, _ := edit["new_string"].(string)
+
+	h := diffEdit(oldStr, newStr)
+	if h.size() == 0 {
+		return
+	}
+	h.filePath = path
+	a.hunks = append(a.hunks, h)
+}
+
+// Finalize is a no-op; sampling happens in Result.
+func (a *CodeChangesAnalyzer) Finalize(hasAgentFile func(string) bool) {}
+
+// Result returns up to MaxCodeChangeHunks hunks, the largest by diff size
+// (earlier edits win ties), in the order they were made.
+func (a *CodeChangesAnalyzer) Result() *CodeChangesResult {
+	keep := make([]int, len(a.hunks))
+	for i := range keep {
+		keep[i] = i
+	}
+	if len(keep) > MaxCodeChangeHunks {
+		sort.SliceStable(keep, func(i, j int) bool {
+			return a.hunks[keep[i]].size() > a.hunks[keep[j]].size()
+		})
+		keep = keep[:MaxCodeChangeHunks]
+		sort.Ints(keep)
+	}
+
+	hunks := make([]EditHunk, 0, len(keep))
+	for _, i := range keep {
+		h := a.hunks[i]
+		hunks = append(hunks, EditHunk{
+			FilePath:     h.filePath,
+			LinesBefore:  h.before,
+			LinesAfter:   h.after,
+			AddedLines:   truncateHunkLines(h.added),
+			RemovedLines: truncateHunkLines(h.removed),
+		})
+	}
+	return &CodeChangesResult{TotalHunks: len(a.hunks), Hunks: hunks}
+}
+
+// Analyze processes the file collection and returns the sampled hunks.
+func (a *CodeChangesAnalyzer) Analyze(fc *FileCollection) (*CodeChangesResult, error) {
+	a.ProcessFile(fc.Main, true)
+	for _, agent := range fc.Agents {
+		a.ProcessFile(agent, false)
+	}
+	a.Finalize(fc.HasAgentFile)
+	return a.Result(), nil
+}
+
+// diffEdit turns a replacement into a hunk: the lines common to the start and
+// end of both strings are context, the rest of old is removed and the rest of
+// new is added. Line counts match the code activity card's.
+func diffEdit(oldStr, newStr string) codeChangeHunk {
+	oldLines, newLines := splitLines(oldStr), splitLines(newStr)
+	h := codeChangeHunk{before: len(oldLines), after: len(newLines)}
+
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	h.removed = oldLines[prefix : len(oldLines)-suffix]
+	h.added = newLines[prefix : len(newLines)-suffix]
+	return h
+}
+
+// splitLines splits s into lines, ignoring a trailing newline, consistent
+// with countLines.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// truncateHunkLines copies up to MaxCodeChangeHunkLines lines, each cut to
+// MaxCodeChangeLineBytes. Never returns nil, so the stored JSON is an array.
+func truncateHunkLines(lines []string) []string {
+	out := make([]string, 0, min(len(lines), MaxCodeChangeHunkLines))
+	for _, l := range lines[:min(len(lines), MaxCodeChangeHunkLines)] {
+		out = append(out, truncateBytes(l, MaxCodeChangeLineBytes))
+	}
+	return out
+}
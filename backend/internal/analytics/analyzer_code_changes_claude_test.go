@@ -0,0 +1,113 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCodeChanges_EditAndMultiEditHunks(t *testing.T) {
+	jsonl := makeAssistantMessage("a1", "2025-01-01T00:00:01Z", "claude-sonnet-4", 10, 5, []map[string]interface{}{
+		makeToolUseBlock("t1", "Edit", map[string]interface{}{
+			"file_path":  "/src/main.go",
+			"old_string": "func a() {\n\treturn 1\n}\n",
+			"new_string": "func a() {\n\treturn 2\n\t// done\n}\n",
+		}),
+	}) + "\n" +
+		makeAssistantMessage("a2", "2025-01-01T00:00:02Z", "claude-sonnet-4", 10, 5, []map[string]interface{}{
+			makeToolUseBlock("t2", "MultiEdit", map[string]interface{}{
+				"file_path": "/src/util.go",
+				"edits": []interface{}{
+					map[string]interface{}{"old_string": "x := 1", "new_string": "x := 10"},
+					// A no-op edit produces no hunk.
+					map[string]interface{}{"old_string": "same", "new_string": "same"},
+					map[string]interface{}{"old_string": "", "new_string": "y := 2\nz := 3"},
+				},
+			}),
+			makeToolUseBlock("t3", "Read", map[string]interface{}{"file_path": "/src/other.go"}),
+		}) + "\n"
+
+	fc, err := NewFileCollection([]byte(jsonl))
+	if err != nil {
+		t.Fatalf("NewFileCollection failed: %v", err)
+	}
+	result, err := ComputeFromFileCollection(context.Background(), fc)
+	if err != nil {
+		t.Fatalf("ComputeFromFileCollection failed: %v", err)
+	}
+
+	want := []EditHunk{
+		{FilePath: "/src/main.go", LinesBefore: 3, LinesAfter: 4,
+			AddedLines: []string{"\treturn 2", "\t// done"}, RemovedLines: []string{"\treturn 1"}},
+		{FilePath: "/src/util.go", LinesBefore: 1, LinesAfter: 1,
+			AddedLines: []string{"x := 10"}, RemovedLines: []string{"x := 1"}},
+		{FilePath: "/src/util.go", LinesBefore: 0, LinesAfter: 2,
+			AddedLines: []string{"y := 2", "z := 3"}, RemovedLines: []string{}},
+	}
+	if result.CodeChanges == nil || result.CodeChanges.TotalHunks != len(want) {
+		t.Fatalf("CodeChanges = %+v, want %d hunks", result.CodeChanges, len(want))
+	}
+	if !reflect.DeepEqual(result.CodeChanges.Hunks, want) {
+		t.Errorf("hunks = %+v\nwant %+v", result.CodeChanges.Hunks, want)
+	}
+}
+
+// TestCodeChanges_KeepsLargestHunksInOrder fills the card past its cap: the
+// largest edits survive, earlier edits win ties, and the kept hunks stay in
+// the order they were made.
+func TestCodeChanges_KeepsLargestHunksInOrder(t *testing.T) {
+	a := &CodeChangesAnalyzer{}
+	total := MaxCodeChangeHunks + 20
+	for i := 0; i < total; i++ {
+		// Every tenth edit adds five lines; the rest add one.
+		n := 1
+		if i%10 == 0 {
+			n = 5
+		}
+		a.addEdit(fmt.Sprintf("/f%d.go", i), map[string]interface{}{
+			"new_string": strings.Repeat("line\n", n),
+		})
+	}
+
+	// All 12 large edits, then the 88 earliest one-line edits: 0-97 plus the
+	// large edits at 100 and 110.
+	var want []string
+	for i := 0; i < total; i++ {
+		if i <= 97 || i == 100 || i == 110 {
+			want = append(want, fmt.Sprintf("/f%d.go", i))
+		}
+	}
+
+	result := a.Result()
+	if result.TotalHunks != total {
+		t.Errorf("TotalHunks = %d, want %d", result.TotalHunks, total)
+	}
+	var got []string
+	for _, h := range result.Hunks {
+		got = append(got, h.FilePath)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("kept hunks = %v\nwant %v", got, want)
+	}
+}
+
+func TestCodeChanges_TruncatesLargeHunks(t *testing.T) {
+	var lines []string
+	for i := 0; i < MaxCodeChangeHunkLines+10; i++ {
+		lines = append(lines, strings.Repeat("x", MaxCodeChangeLineBytes+5))
+	}
+	h := diffEdit("", strings.Join(lines, "\n"))
+	if h.after != len(lines) || len(h.added) != len(lines) {
+		t.Fatalf("hunk counts %d lines after, %d added; want %d", h.after, len(h.added), len(lines))
+	}
+
+	got := truncateHunkLines(h.added)
+	if len(got) != MaxCodeChangeHunkLines {
+		t.Errorf("stored %d lines, want %d", len(got), MaxCodeChangeHunkLines)
+	}
+	if len(got[0]) != MaxCodeChangeLineBytes {
+		t.Errorf("stored line of %d bytes, want %d", len(got[0]), MaxCodeChangeLineBytes)
+	}
+}
@@ -33,6 +33,9 @@ type ConversationResult struct {
 //   - Assistant Turn Duration: Time from user prompt to the last assistant message
 //     before the next user prompt (total response time including tool calls).
 //   - User Thinking Time: Time from the last assistant message to the next user prompt.
+//
+// Durations between out-of-order timestamps are clamped to zero (see
+// nonNegativeMs), so the totals and averages are never negative.
 type ConversationAnalyzer struct {
 	result ConversationResult
 }
@@ -69,17 +72,11 @@ func (a *ConversationAnalyzer) ProcessFile(file *TranscriptFile, isMain bool) {
 			}
 
 			if lastHumanPromptTime != nil && lastAssistantTime != nil && hadAssistantResponse {
-				duration := lastAssistantTime.Sub(*lastHumanPromptTime).Milliseconds()
-				if duration >= 0 {
-					assistantTurnDurations = append(assistantTurnDurations, duration)
-				}
+				assistantTurnDurations = append(assistantTurnDurations, nonNegativeMs(lastAssistantTime.Sub(*lastHumanPromptTime)))
 			}
 
 			if lastAssistantTime != nil {
-				thinkingTime := ts.Sub(*lastAssistantTime).Milliseconds()
-				if thinkingTime >= 0 {
-					userThinkingDurations = append(userThinkingDurations, thinkingTime)
-				}
+				userThinkingDurations = append(userThinkingDurations, nonNegativeMs(ts.Sub(*lastAssistantTime)))
 			}
 
 			lastHumanPromptTime = &ts
@@ -108,10 +105,7 @@ func (a *ConversationAnalyzer) ProcessFile(file *TranscriptFile, isMain bool) {
 		a.result.AssistantTurns++
 	}
 	if lastHumanPromptTime != nil && lastAssistantTime != nil && hadAssistantResponse {
-		duration := lastAssistantTime.Sub(*lastHumanPromptTime).Milliseconds()
-		if duration >= 0 {
-			assistantTurnDurations = append(assistantTurnDurations, duration)
-		}
+		assistantTurnDurations = append(assistantTurnDurations, nonNegativeMs(lastAssistantTime.Sub(*lastHumanPromptTime)))
 	}
 
 	// Compute timing stats
@@ -0,0 +1,144 @@
+package analytics
+
+import "time"
+
+// MaxConversationTurns caps how many per-turn rows are kept for one session.
+// Turns past the cap are dropped; the aggregate conversation card still
+// covers the whole session.
+const MaxConversationTurns = 10000
+
+// Conversation turn roles.
+const (
+	TurnRoleUser      = "user"
+	TurnRoleAssistant = "assistant"
+)
+
+// ConversationTurn is one row of per-turn detail behind the conversation card.
+// It is both the DB record (session_card_conversation_turns) and the API wire
+// shape.
+type ConversationTurn struct {
+	TurnIndex  int    `json:"turn_index"`  // 0-based position in the session
+	Role       string `json:"role"`        // TurnRoleUser or TurnRoleAssistant
+	LineNumber int    `json:"line_number"` // 1-based transcript line the turn starts on
+	// DurationMs is the assistant's response time (prompt → last assistant
+	// message) or the user's thinking time (last assistant message → prompt).
+	DurationMs *int64 `json:"duration_ms"`
+	TokenCount *int64 `json:"token_count"` // Output tokens (assistant turns only)
+	HasToolUse bool   `json:"has_tool_use"`
+}
+
+// ComputeConversationTurns extracts per-turn detail from the main transcript,
+// using the same turn semantics as ConversationAnalyzer: a user turn per human
+// prompt, and an assistant turn per prompt-triggered sequence that received at
+// least one new assistant message (deduplicated by message.id). Assistant
+// token counts use each message's final usage, as in the tokens card.
+// At most MaxConversationTurns turns are returned.
+func ComputeConversationTurns(fc *FileCollection) ([]ConversationTurn, error) {
+	turns := []ConversationTurn{}
+
+	var lastHumanPromptTime *time.Time
+	var lastAssistantTime *time.Time
+
+	// The open assistant turn, if any. Output tokens are tracked per message
+	// ID so repeated lines of one response count its final usage once.
+	var open *ConversationTurn
+	var openTokens map[string]int64
+	var openUnkeyedTokens int64
+	seenMessageIDs := make(map[string]bool)
+
+	closeAssistantTurn := func() {
+		if open == nil {
+			return
+		}
+		total := openUnkeyedTokens
+		for _, t := range openTokens {
+			total += t
+		}
+		open.TokenCount = &total
+		if lastHumanPromptTime != nil && lastAssistantTime != nil {
+			d := nonNegativeMs(lastAssistantTime.Sub(*lastHumanPromptTime))
+			open.DurationMs = &d
+		}
+		turns = append(turns, *open)
+		open = nil
+	}
+
+	for _, line := range fc.Main.Lines {
+		if len(turns) >= MaxConversationTurns {
+			break
+		}
+
+		if line.IsHumanMessage() {
+			closeAssistantTurn()
+			if len(turns) >= MaxConversationTurns {
+				break
+			}
+
+			turn := ConversationTurn{
+				TurnIndex:  len(turns),
+				Role:       TurnRoleUser,
+				LineNumber: line.LineNumber,
+			}
+			ts, err := line.GetTimestamp()
+			if err != nil {
+				lastHumanPromptTime = nil
+				lastAssistantTime = nil
+				turns = append(turns, turn)
+				continue
+			}
+			if lastAssistantTime != nil {
+				d := nonNegativeMs(ts.Sub(*lastAssistantTime))
+				turn.DurationMs = &d
+			}
+			turns = append(turns, turn)
+
+			lastHumanPromptTime = &ts
+			lastAssistantTime = nil
+			continue
+		}
+
+		if line.Type != "assistant" || line.Message == nil {
+			continue
+		}
+
+		msgID := line.GetMessageID()
+		isNew := msgID == "" || !seenMessageIDs[msgID]
+		if isNew && open == nil {
+			open = &ConversationTurn{
+				TurnIndex:  len(turns),
+				Role:       TurnRoleAssistant,
+				LineNumber: line.LineNumber,
+			}
+			openTokens = make(map[string]int64)
+			openUnkeyedTokens = 0
+		}
+		if msgID != "" {
+			seenMessageIDs[msgID] = true
+		}
+
+		if open != nil {
+			if line.HasToolUse() {
+				open.HasToolUse = true
+			}
+			if usage := line.Message.Usage; usage != nil {
+				if msgID == "" {
+					openUnkeyedTokens += usage.OutputTokens
+				} else if _, ok := openTokens[msgID]; ok || isNew {
+					// Last occurrence wins (final output_tokens).
+					openTokens[msgID] = usage.OutputTokens
+				}
+			}
+		}
+
+		if ts, err := line.GetTimestamp(); err == nil {
+			lastAssistantTime = &ts
+		}
+	}
+
+	// Handle unclosed turn at end of session
+	if len(turns) < MaxConversationTurns {
+		closeAssistantTurn()
+	}
+
+	return turns, nil
+}
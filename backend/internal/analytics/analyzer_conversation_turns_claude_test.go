@@ -0,0 +1,155 @@
+package analytics
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestComputeConversationTurns(t *testing.T) {
+	// u1 → msg-001 (3 lines, final output 80, tool use) → tool result →
+	// msg-002 → u2 → msg-003 (trailing turn).
+	jsonl := makeUserMessage("u1", "2025-01-01T00:00:00Z", "hello") + "\n" +
+		makeAssistantMessageWithMsgID("a1", "2025-01-01T00:00:01Z", "claude-sonnet-4", "msg-001", 100, 10, []map[string]interface{}{
+			makeThinkingBlock("thinking..."),
+		}) + "\n" +
+		makeAssistantMessageWithMsgID("a2", "2025-01-01T00:00:01Z", "claude-sonnet-4", "msg-001", 100, 50, []map[string]interface{}{
+			makeTextBlock("response"),
+		}) + "\n" +
+		makeAssistantMessageWithMsgID("a3", "2025-01-01T00:00:02Z", "claude-sonnet-4", "msg-001", 100, 80, []map[string]interface{}{
+			makeToolUseBlock("toolu_1", "Read", map[string]interface{}{}),
+		}) + "\n" +
+		makeUserMessageWithToolResults("r1", "2025-01-01T00:00:03Z", []map[string]interface{}{
+			makeToolResultBlock("toolu_1", "ok", false),
+		}) + "\n" +
+		makeAssistantMessageWithMsgID("a4", "2025-01-01T00:00:05Z", "claude-sonnet-4", "msg-002", 100, 20, []map[string]interface{}{
+			makeTextBlock("done"),
+		}) + "\n" +
+		makeUserMessage("u2", "2025-01-01T00:01:05Z", "thanks") + "\n" +
+		makeAssistantMessageWithMsgID("a5", "2025-01-01T00:01:07Z", "claude-sonnet-4", "msg-003", 100, 5, []map[string]interface{}{
+			makeTextBlock("welcome"),
+		}) + "\n"
+
+	fc, err := NewFileCollection([]byte(jsonl))
+	if err != nil {
+		t.Fatalf("NewFileCollection failed: %v", err)
+	}
+
+	turns, err := ComputeConversationTurns(fc)
+	if err != nil {
+		t.Fatalf("ComputeConversationTurns failed: %v", err)
+	}
+
+	want := []struct {
+		role       string
+		line       int
+		durationMs *int64
+		tokens     *int64
+		toolUse    bool
+	}{
+		{TurnRoleUser, 1, nil, nil, false},
+		{TurnRoleAssistant, 2, ptrInt64(5000), ptrInt64(100), true},
+		{TurnRoleUser, 7, ptrInt64(60000), nil, false},
+		{TurnRoleAssistant, 8, ptrInt64(2000), ptrInt64(5), false},
+	}
+	if len(turns) != len(want) {
+		t.Fatalf("got %d turns, want %d: %+v", len(turns), len(want), turns)
+	}
+	for i, w := range want {
+		got := turns[i]
+		if got.TurnIndex != i {
+			t.Errorf("turn %d: TurnIndex = %d", i, got.TurnIndex)
+		}
+		if got.Role != w.role {
+			t.Errorf("turn %d: Role = %q, want %q", i, got.Role, w.role)
+		}
+		if got.LineNumber != w.line {
+			t.Errorf("turn %d: LineNumber = %d, want %d", i, got.LineNumber, w.line)
+		}
+		if !equalI64Ptr(got.DurationMs, w.durationMs) {
+			t.Errorf("turn %d: DurationMs = %v, want %v", i, fmtI64Ptr(got.DurationMs), fmtI64Ptr(w.durationMs))
+		}
+		if !equalI64Ptr(got.TokenCount, w.tokens) {
+			t.Errorf("turn %d: TokenCount = %v, want %v", i, fmtI64Ptr(got.TokenCount), fmtI64Ptr(w.tokens))
+		}
+		if got.HasToolUse != w.toolUse {
+			t.Errorf("turn %d: HasToolUse = %v, want %v", i, got.HasToolUse, w.toolUse)
+		}
+	}
+
+	// Turn counts agree with the aggregate card.
+	conv, _ := (&ConversationAnalyzer{}).Analyze(fc)
+	var users, assistants int
+	for _, turn := range turns {
+		if turn.Role == TurnRoleUser {
+			users++
+		} else {
+			assistants++
+		}
+	}
+	if users != conv.UserTurns || assistants != conv.AssistantTurns {
+		t.Errorf("turns = %d user / %d assistant, card = %d / %d", users, assistants, conv.UserTurns, conv.AssistantTurns)
+	}
+}
+
+func TestComputeConversationTurns_ContextReplayDoesNotOpenTurn(t *testing.T) {
+	// msg-001 replayed after u2 with no new message: u2 gets no assistant turn.
+	jsonl := makeUserMessage("u1", "2025-01-01T00:00:00Z", "hello") + "\n" +
+		makeAssistantMessageWithMsgID("a1", "2025-01-01T00:00:01Z", "claude-sonnet-4", "msg-001", 100, 50, []map[string]interface{}{
+			makeTextBlock("response"),
+		}) + "\n" +
+		makeUserMessage("u2", "2025-01-01T00:01:00Z", "again") + "\n" +
+		makeAssistantMessageWithMsgID("a1r", "2025-01-01T00:01:01Z", "claude-sonnet-4", "msg-001", 100, 50, []map[string]interface{}{
+			makeTextBlock("response"),
+		}) + "\n"
+
+	fc, err := NewFileCollection([]byte(jsonl))
+	if err != nil {
+		t.Fatalf("NewFileCollection failed: %v", err)
+	}
+	turns, _ := ComputeConversationTurns(fc)
+
+	roles := make([]string, len(turns))
+	for i, turn := range turns {
+		roles[i] = turn.Role
+	}
+	if got := strings.Join(roles, ","); got != "user,assistant,user" {
+		t.Errorf("roles = %s, want user,assistant,user", got)
+	}
+}
+
+func TestComputeConversationTurns_Cap(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < MaxConversationTurns; i++ {
+		b.WriteString(makeUserMessage(fmt.Sprintf("u%d", i), "2025-01-01T00:00:00Z", "hi") + "\n")
+		b.WriteString(makeAssistantMessageWithMsgID(fmt.Sprintf("a%d", i), "2025-01-01T00:00:01Z", "claude-sonnet-4", fmt.Sprintf("msg-%d", i), 1, 1, []map[string]interface{}{
+			makeTextBlock("ok"),
+		}) + "\n")
+	}
+
+	fc, err := NewFileCollection([]byte(b.String()))
+	if err != nil {
+		t.Fatalf("NewFileCollection failed: %v", err)
+	}
+	turns, _ := ComputeConversationTurns(fc)
+	if len(turns) != MaxConversationTurns {
+		t.Fatalf("got %d turns, want cap %d", len(turns), MaxConversationTurns)
+	}
+	if last := turns[len(turns)-1]; last.TurnIndex != MaxConversationTurns-1 {
+		t.Errorf("last TurnIndex = %d, want %d", last.TurnIndex, MaxConversationTurns-1)
+	}
+}
+
+func equalI64Ptr(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func fmtI64Ptr(p *int64) string {
+	if p == nil {
+		return "nil"
+	}
+	return fmt.Sprint(*p)
+}
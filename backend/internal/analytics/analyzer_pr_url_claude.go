@@ -0,0 +1,132 @@
+package analytics
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ghPRCreatePattern matches a shell command that runs `gh pr create`, alone
+// or in a chain (`git push && gh pr create ...`).
+var ghPRCreatePattern = regexp.MustCompile(`(?:^|[\s;&|(])gh\s+pr\s+create\b`)
+
+// ghPRURLLinePattern matches the line gh pr create prints for the pull request
+// it opened: the URL and nothing else.
+var ghPRURLLinePattern = regexp.MustCompile(`^https://github\.com/[A-Za-z0-9-]+/[A-Za-z0-9._-]+/pull/[1-9][0-9]*$`)
+
+// PRURLAnalyzer finds the pull request a session opened. It is deliberately
+// conservative: a URL counts only when it is a whole line of the successful
+// result of a Bash call running gh pr create, so PR URLs the assistant
+// mentions in prose, or that other commands print, are never picked up. The
+// last PR opened in the main transcript wins; agent files are used only when
+// the main transcript opened none.
+type PRURLAnalyzer struct {
+	mainURL  string
+	agentURL string
+}
+
+// ProcessFile scans one file's gh pr create results.
+func (a *PRURLAnalyzer) ProcessFile(file *TranscriptFile, isMain bool) {
+	if isMain {
+		a.mainURL, a.agentURL = "", ""
+	}
+
+	prCreateCalls := make(map[string]bool)
+	for _, line := range file.Lines {
+		if line.IsAssistantMessage() {
+			for _, tool := range line.GetToolUses() {
+				if tool.Name != "Bash" || tool.ID == "" {
+					continue
+				}
+				if command, _ := tool.Input["command"].(string); ghPRCreatePattern.MatchString(command) {
+					prCreateCalls[tool.ID] = true
+				}
+			}
+			continue
+		}
+		if !line.IsUserMessage() || len(prCreateCalls) == 0 {
+			continue
+		}
+		for _, result := range toolResultTexts(line) {
+			if result.isError || !prCreateCalls[result.toolUseID] {
+				continue
+			}
+			if url := DetectPRURL(result.text); url != "" {
+				if isMain {
+					a.mainURL = url
+				} else {
+					a.agentURL = url
+				}
+			}
+		}
+	}
+}
+
+// Finalize is a no-op.
+func (a *PRURLAnalyzer) Finalize(hasAgentFile func(string) bool) {}
+
+// Result returns the detected PR URL, or "" when the session opened none.
+func (a *PRURLAnalyzer) Result() string {
+	if a.mainURL != "" {
+		return a.mainURL
+	}
+	return a.agentURL
+}
+
+// DetectPRURL returns the pull request URL in gh pr create output: the last
+// line that is exactly a github.com pull request URL. gh prints that URL on
+// its own line after any progress or warning lines.
+func DetectPRURL(output string) string {
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); ghPRURLLinePattern.MatchString(line) {
+			return line
+		}
+	}
+	return ""
+}
+
+// toolResultText is the text of one tool_result block.
+type toolResultText struct {
+	toolUseID string
+	isError   bool
+	text      string
+}
+
+// toolResultTexts returns the tool_result blocks of a user message with their
+// text content, which is either a string or a list of text blocks.
+func toolResultTexts(line *TranscriptLine) []toolResultText {
+	if line.Message == nil {
+		return nil
+	}
+	contentArray, ok := line.Message.Content.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var results []toolResultText
+	for _, item := range contentArray {
+		blockMap, ok := item.(map[string]interface{})
+		if !ok || blockMap["type"] != "tool_result" {
+			continue
+		}
+		result := toolResultText{}
+		result.toolUseID, _ = blockMap["tool_use_id"].(string)
+		result.isError, _ = blockMap["is_error"].(bool)
+		switch content := blockMap["content"].(type) {
+		case string:
+			result.text = content
+		case []interface{}:
+			var parts []string
+			for _, part := range content {
+				if partMap, ok := part.(map[string]interface{}); ok && partMap["type"] == "text" {
+					if text, ok := partMap["text"].(string); ok {
+						parts = append(parts, text)
+					}
+				}
+			}
+			result.text = strings.Join(parts, "\n")
+		}
+		results = append(results, result)
+	}
+	return results
+}
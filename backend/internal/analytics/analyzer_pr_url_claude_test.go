@@ -0,0 +1,168 @@
+package analytics
+
+import (
+	"context"
+	"testing"
+)
+
+// Output samples as gh prints them into a Claude Code Bash result.
+const (
+	ghPRCreateOutput = "https://github.com/acme/widgets/pull/42"
+
+	ghPRCreateOutputWithProgress = "\nCreating pull request for feat/login into main in acme/widgets\n\nhttps://github.com/acme/widgets/pull/42\n"
+
+	ghPRCreateOutputWithWarning = "Warning: 2 uncommitted changes\nhttps://github.com/acme/widgets/pull/42"
+
+	ghPRCreateOutputAfterPush = "remote: \nremote: Create a pull request for 'feat/login' on GitHub by visiting:\n" +
+		"remote:      https://github.com/acme/widgets/pull/new/feat/login\nremote: \n" +
+		"To github.com:acme/widgets.git\n * [new branch]      feat/login -> feat/login\n" +
+		"branch 'feat/login' set up to track 'origin/feat/login'.\n" +
+		"https://github.com/acme/widgets/pull/42"
+
+	ghPRCreateAlreadyExists = "a pull request for branch \"feat/login\" into branch \"main\" already exists:\nhttps://github.com/acme/widgets/pull/41"
+)
+
+func TestDetectPRURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"bare URL", ghPRCreateOutput, "https://github.com/acme/widgets/pull/42"},
+		{"progress lines", ghPRCreateOutputWithProgress, "https://github.com/acme/widgets/pull/42"},
+		{"warning line", ghPRCreateOutputWithWarning, "https://github.com/acme/widgets/pull/42"},
+		{"after git push", ghPRCreateOutputAfterPush, "https://github.com/acme/widgets/pull/42"},
+		{"CRLF", "https://github.com/acme/widgets/pull/42\r\n", "https://github.com/acme/widgets/pull/42"},
+		{"URL inside prose", "Opened https://github.com/acme/widgets/pull/42 for review", ""},
+		{"new-PR hint", "remote:      https://github.com/acme/widgets/pull/new/feat/login", ""},
+		{"issue URL", "https://github.com/acme/widgets/issues/42", ""},
+		{"PR sub-page", "https://github.com/acme/widgets/pull/42/files", ""},
+		{"other host", "https://github.example.com/acme/widgets/pull/42", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectPRURL(tt.output); got != tt.want {
+				t.Errorf("DetectPRURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// computePRURL runs a transcript through the full compute pipeline and
+// returns the detected PR URL.
+func computePRURL(t *testing.T, jsonl string) string {
+	t.Helper()
+	fc, err := NewFileCollection([]byte(jsonl))
+	if err != nil {
+		t.Fatalf("NewFileCollection failed: %v", err)
+	}
+	result, err := ComputeFromFileCollection(context.Background(), fc)
+	if err != nil {
+		t.Fatalf("ComputeFromFileCollection failed: %v", err)
+	}
+	return result.DetectedPRURL
+}
+
+// bashCall is one Bash tool call and its result, as transcript lines.
+func bashCall(id, command, output string, isError bool) string {
+	return makeAssistantMessage("a-"+id, "2025-01-01T00:00:01Z", "claude-sonnet-4", 10, 5, []map[string]interface{}{
+		makeToolUseBlock(id, "Bash", map[string]interface{}{"command": command}),
+	}) + "\n" +
+		makeUserMessageWithToolResults("u-"+id, "2025-01-01T00:00:02Z", []map[string]interface{}{
+			makeToolResultBlock(id, output, isError),
+		}) + "\n"
+}
+
+func TestPRURLAnalyzer(t *testing.T) {
+	const command = `git push -u origin feat/login && gh pr create --title "Add login" --body "$(cat <<'EOF'
+## Summary
+- Add login
+EOF
+)"`
+
+	tests := []struct {
+		name  string
+		jsonl string
+		want  string
+	}{
+		{
+			name:  "gh pr create result",
+			jsonl: bashCall("t1", command, ghPRCreateOutputAfterPush, false),
+			want:  "https://github.com/acme/widgets/pull/42",
+		},
+		{
+			name: "last PR opened wins",
+			jsonl: bashCall("t1", "gh pr create --fill", ghPRCreateOutput, false) +
+				bashCall("t2", "gh pr create --fill --base release", "https://github.com/acme/widgets/pull/43", false),
+			want: "https://github.com/acme/widgets/pull/43",
+		},
+		{
+			name:  "failed gh pr create",
+			jsonl: bashCall("t1", "gh pr create --fill", ghPRCreateAlreadyExists, true),
+		},
+		{
+			name:  "other gh command",
+			jsonl: bashCall("t1", "gh pr view 42 --json url -q .url", ghPRCreateOutput, false),
+		},
+		{
+			name: "PR URL in prose",
+			jsonl: makeUserMessage("u1", "2025-01-01T00:00:00Z", "Please review https://github.com/acme/widgets/pull/42") + "\n" +
+				makeAssistantMessage("a1", "2025-01-01T00:00:01Z", "claude-sonnet-4", 10, 5, []map[string]interface{}{
+					makeTextBlock("I opened the PR:\nhttps://github.com/acme/widgets/pull/42"),
+				}) + "\n",
+		},
+		{
+			name: "PR URL printed by another command",
+			jsonl: bashCall("t1", "gh pr create --fill", "Error: no commits between main and feat/login", true) +
+				bashCall("t2", "echo https://github.com/acme/widgets/pull/42", ghPRCreateOutput, false),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computePRURL(t, tt.jsonl); got != tt.want {
+				t.Errorf("DetectedPRURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPRURLAnalyzer_TextBlockContent(t *testing.T) {
+	jsonl := makeAssistantMessage("a1", "2025-01-01T00:00:01Z", "claude-sonnet-4", 10, 5, []map[string]interface{}{
+		makeToolUseBlock("t1", "Bash", map[string]interface{}{"command": "gh pr create --fill"}),
+	}) + "\n" +
+		makeUserMessageWithToolResults("u1", "2025-01-01T00:00:02Z", []map[string]interface{}{{
+			"type":        "tool_result",
+			"tool_use_id": "t1",
+			"content": []interface{}{
+				map[string]interface{}{"type": "text", "text": ghPRCreateOutputWithProgress},
+			},
+		}}) + "\n"
+
+	if got := computePRURL(t, jsonl); got != "https://github.com/acme/widgets/pull/42" {
+		t.Errorf("DetectedPRURL = %q, want the PR from the text block", got)
+	}
+}
+
+func TestPRURLAnalyzer_MainTranscriptWins(t *testing.T) {
+	a := &PRURLAnalyzer{}
+	parse := func(jsonl string) *TranscriptFile {
+		fc, err := NewFileCollection([]byte(jsonl))
+		if err != nil {
+			t.Fatalf("NewFileCollection failed: %v", err)
+		}
+		return fc.Main
+	}
+
+	a.ProcessFile(parse(bashCall("t1", "gh pr create --fill", ghPRCreateOutput, false)), true)
+	a.ProcessFile(parse(bashCall("t2", "gh pr create --fill", "https://github.com/acme/widgets/pull/50", false)), false)
+	if got := a.Result(); got != "https://github.com/acme/widgets/pull/42" {
+		t.Errorf("Result() = %q, want the main transcript's PR", got)
+	}
+
+	a.ProcessFile(parse(bashCall("t3", "ls", "README.md", false)), true)
+	a.ProcessFile(parse(bashCall("t4", "gh pr create --fill", "https://github.com/acme/widgets/pull/50", false)), false)
+	if got := a.Result(); got != "https://github.com/acme/widgets/pull/50" {
+		t.Errorf("Result() = %q, want the agent's PR when the main transcript opened none", got)
+	}
+}
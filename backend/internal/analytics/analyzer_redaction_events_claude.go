@@ -0,0 +1,161 @@
+package analytics
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxRedactionEvents caps how many per-redaction rows are kept for one
+// session. Events past the cap are dropped; the redactions card still counts
+// every redaction.
+const MaxRedactionEvents = 1000
+
+// MaxRedactionContextPreview is the length, in characters, of an event's
+// ContextPreview (the marker plus obfuscated surrounding text).
+const MaxRedactionContextPreview = 50
+
+// RedactionTypeUnspecified is the type of a marker that names none, such as a
+// bare [REDACTED].
+const RedactionTypeUnspecified = "UNSPECIFIED"
+
+// redactionEventPattern matches the redaction markers listed as events: the
+// CLI's [REDACTED:TYPE] (the only form the redactions card counts), the
+// angle-bracket <REDACTED:TYPE>, and the bare [REDACTED] / <REDACTED> some
+// tools write. The type is captured in group 1 or 2.
+var redactionEventPattern = regexp.MustCompile(`\[REDACTED(?::([A-Z][A-Z0-9_]*))?\]|<REDACTED(?::([A-Z][A-Z0-9_]*))?>`)
+
+// RedactionEvent is one redaction marker found in a transcript. It is both the
+// DB record (session_card_redaction_events) and the API wire shape.
+type RedactionEvent struct {
+	RedactionIndex int    `json:"redaction_index"` // 0-based position in the session
+	Type           string `json:"type"`            // marker type, or RedactionTypeUnspecified
+	LineNumber     int    `json:"line_number"`     // 1-based transcript line holding the marker
+	// ContextPreview is the marker with the text around it obfuscated: letters
+	// and digits become '*' (at most four in a row), so the shape of what was
+	// redacted shows ("****@****.***") without any of its content.
+	ContextPreview string `json:"context_preview"`
+}
+
+// ComputeRedactionEvents lists the redaction markers in the main transcript,
+// in line order and, within a line, in JSON key order. Like RedactionsAnalyzer
+// it walks every string value of each line; the "TYPE" placeholder is skipped.
+// At most MaxRedactionEvents events are returned.
+func ComputeRedactionEvents(fc *FileCollection) ([]RedactionEvent, error) {
+	events := []RedactionEvent{}
+	for _, line := range fc.Main.Lines {
+		if len(events) >= MaxRedactionEvents {
+			break
+		}
+		if line.RawData != nil {
+			collectRedactionEvents(line.RawData, line.LineNumber, &events)
+		}
+	}
+	return events, nil
+}
+
+// collectRedactionEvents walks a JSON value, appending an event per marker
+// until MaxRedactionEvents. Object keys are visited sorted so the event order
+// is stable across recomputes.
+func collectRedactionEvents(v interface{}, lineNumber int, events *[]RedactionEvent) {
+	switch val := v.(type) {
+	case string:
+		for _, m := range redactionEventPattern.FindAllStringSubmatchIndex(val, -1) {
+			if len(*events) >= MaxRedactionEvents {
+				return
+			}
+			typ := RedactionTypeUnspecified
+			if m[2] >= 0 {
+				typ = val[m[2]:m[3]]
+			} else if m[4] >= 0 {
+				typ = val[m[4]:m[5]]
+			}
+			if typ == "TYPE" {
+				continue
+			}
+			*events = append(*events, RedactionEvent{
+				RedactionIndex: len(*events),
+				Type:           typ,
+				LineNumber:     lineNumber,
+				ContextPreview: redactionContextPreview(val[:m[0]], val[m[0]:m[1]], val[m[1]:]),
+			})
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			collectRedactionEvents(val[k], lineNumber, events)
+		}
+	case []interface{}:
+		for _, elem := range val {
+			collectRedactionEvents(elem, lineNumber, events)
+		}
+	}
+}
+
+// redactionContextPreview builds an event's ContextPreview: the marker kept
+// verbatim, between obfuscated text from either side, within
+// MaxRedactionContextPreview characters. The room left is split evenly; what
+// one side is too short to use goes to the other.
+func redactionContextPreview(before, marker, after string) string {
+	budget := MaxRedactionContextPreview - utf8.RuneCountInString(marker)
+	if budget <= 0 {
+		return marker
+	}
+	// Masking collapses long runs, so read a few times the room needed from
+	// each side rather than the whole string (tool outputs can be megabytes).
+	window := 4 * budget
+	if len(before) > window {
+		cut := len(before) - window
+		for cut < len(before) && !utf8.RuneStart(before[cut]) {
+			cut++
+		}
+		before = before[cut:]
+	}
+	if len(after) > window {
+		cut := window
+		for cut > 0 && !utf8.RuneStart(after[cut]) {
+			cut--
+		}
+		after = after[:cut]
+	}
+	pre := []rune(obfuscateRedactionContext(before))
+	post := []rune(obfuscateRedactionContext(after))
+
+	preLen := min(len(pre), max(budget/2, budget-len(post)))
+	postLen := min(len(post), budget-preLen)
+	return string(pre[len(pre)-preLen:]) + marker + string(post[:postLen])
+}
+
+// obfuscateRedactionContext masks text next to a redaction marker: each run
+// of letters and digits becomes up to four '*', whitespace collapses to one
+// space, and punctuation is kept.
+func obfuscateRedactionContext(s string) string {
+	var b strings.Builder
+	run := 0
+	space := false
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if run < 4 {
+				b.WriteByte('*')
+			}
+			run++
+			space = false
+		case unicode.IsSpace(r):
+			if !space {
+				b.WriteByte(' ')
+			}
+			run, space = 0, true
+		default:
+			b.WriteRune(r)
+			run, space = 0, false
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package analytics
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestComputeRedactionEvents(t *testing.T) {
+	jsonl := makeUserMessage("u1", "2025-01-01T00:00:00Z", "my token is [REDACTED:GITHUB_TOKEN] ok") + "\n" +
+		makeUserMessage("u2", "2025-01-01T00:00:01Z", "nothing to see") + "\n" +
+		makeUserMessage("u3", "2025-01-01T00:00:02Z", "mail [REDACTED] and <REDACTED:EMAIL> and <REDACTED>, not [REDACTED:TYPE]") + "\n"
+
+	fc, err := NewFileCollection([]byte(jsonl))
+	if err != nil {
+		t.Fatalf("NewFileCollection failed: %v", err)
+	}
+
+	events, err := ComputeRedactionEvents(fc)
+	if err != nil {
+		t.Fatalf("ComputeRedactionEvents failed: %v", err)
+	}
+
+	want := []struct {
+		typ     string
+		line    int
+		preview string
+	}{
+		{"GITHUB_TOKEN", 1, "** **** ** [REDACTED:GITHUB_TOKEN] **"},
+		{RedactionTypeUnspecified, 3, "**** [REDACTED] *** <****:****> *** <****>, *** [*"},
+		{"EMAIL", 3, "**** [****] *** <REDACTED:EMAIL> *** <****>, *** ["},
+		{RedactionTypeUnspecified, 3, "*] *** <****:****> *** <REDACTED>, *** [****:****]"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		got := events[i]
+		if got.RedactionIndex != i || got.Type != w.typ || got.LineNumber != w.line {
+			t.Errorf("event %d = %+v, want type %s on line %d", i, got, w.typ, w.line)
+		}
+		if got.ContextPreview != w.preview {
+			t.Errorf("event %d preview = %q, want %q", i, got.ContextPreview, w.preview)
+		}
+	}
+}
+
+func TestComputeRedactionEvents_Cap(t *testing.T) {
+	content := strings.Repeat("[REDACTED:API_KEY] ", MaxRedactionEvents+5)
+	fc, err := NewFileCollection([]byte(makeUserMessage("u1", "2025-01-01T00:00:00Z", content) + "\n"))
+	if err != nil {
+		t.Fatalf("NewFileCollection failed: %v", err)
+	}
+
+	events, err := ComputeRedactionEvents(fc)
+	if err != nil {
+		t.Fatalf("ComputeRedactionEvents failed: %v", err)
+	}
+	if len(events) != MaxRedactionEvents {
+		t.Fatalf("got %d events, want %d", len(events), MaxRedactionEvents)
+	}
+	if last := events[len(events)-1]; last.RedactionIndex != MaxRedactionEvents-1 {
+		t.Errorf("last index = %d, want %d", last.RedactionIndex, MaxRedactionEvents-1)
+	}
+}
+
+func TestRedactionContextPreview(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		marker string
+		after  string
+		want   string
+	}{
+		{"masks letters and digits", "key=abc123 ", "[REDACTED]", " (user@example.com)", "***=**** [REDACTED] (****@****.***)"},
+		{"collapses whitespace", "a \n\t b", "[REDACTED]", "", "* *[REDACTED]"},
+		{"caps long runs", "supercalifragilistic", "[REDACTED]", "", "****[REDACTED]"},
+		{"unicode letters", "pässwörd: ", "<REDACTED>", "", "****: <REDACTED>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactionContextPreview(tt.before, tt.marker, tt.after); got != tt.want {
+				t.Errorf("preview = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("fits the column", func(t *testing.T) {
+		long := strings.Repeat("ab, ", 500)
+		got := redactionContextPreview(long, "[REDACTED:AWS_SECRET_ACCESS_KEY]", long)
+		if n := utf8.RuneCountInString(got); n != MaxRedactionContextPreview {
+			t.Errorf("preview has %d characters, want %d: %q", n, MaxRedactionContextPreview, got)
+		}
+		if !strings.Contains(got, "[REDACTED:AWS_SECRET_ACCESS_KEY]") || strings.Contains(got, "ab") {
+			t.Errorf("preview must keep the marker and mask the context: %q", got)
+		}
+	})
+}
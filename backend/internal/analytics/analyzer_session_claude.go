@@ -24,6 +24,12 @@ type SessionResult struct {
 	CompactionAuto      int
 	CompactionManual    int
 	CompactionAvgTimeMs *int
+
+	// Timestamp ordering anomalies in the main transcript: lines whose
+	// timestamp falls more than TimestampRegressionTolerance behind the
+	// previous timestamped line, and the first such line (1-based).
+	TimestampAnomalies        int
+	FirstTimestampAnomalyLine *int
 }
 
 // SessionAnalyzer extracts session-level metrics from transcripts.
@@ -34,6 +40,7 @@ type SessionAnalyzer struct {
 	firstTimestamp  *time.Time
 	lastTimestamp   *time.Time
 	compactionTimes []int64
+	order           timestampOrder
 }
 
 // ProcessFile accumulates session metrics from a single file.
@@ -69,6 +76,7 @@ func (a *SessionAnalyzer) processMainFile(file *TranscriptFile) {
 
 		ts, err := line.GetTimestamp()
 		if err == nil {
+			a.order.observe(ts, line.LineNumber)
 			if a.firstTimestamp == nil || ts.Before(*a.firstTimestamp) {
 				a.firstTimestamp = &ts
 			}
@@ -84,10 +92,7 @@ func (a *SessionAnalyzer) processMainFile(file *TranscriptFile) {
 				if line.LogicalParentUUID != "" {
 					if parentTime, ok := timestampByUUID[line.LogicalParentUUID]; ok {
 						if compactTime, err := line.GetTimestamp(); err == nil {
-							delta := compactTime.Sub(parentTime).Milliseconds()
-							if delta >= 0 {
-								a.compactionTimes = append(a.compactionTimes, delta)
-							}
+							a.compactionTimes = append(a.compactionTimes, nonNegativeMs(compactTime.Sub(parentTime)))
 						}
 					}
 				}
@@ -120,6 +125,9 @@ func (a *SessionAnalyzer) Finalize(hasAgentFile func(string) bool) {
 		a.result.DurationMs = &d
 	}
 
+	a.result.TimestampAnomalies = a.order.anomalies
+	a.result.FirstTimestampAnomalyLine = a.order.firstLine
+
 	// Compute models list
 	a.result.ModelsUsed = make([]string, 0, len(a.modelsUsed))
 	for m := range a.modelsUsed {
@@ -78,15 +78,18 @@ type SmartRecapResult struct {
 	InputTokens      int
 	OutputTokens     int
 	GenerationTimeMs int
+
+	// InputSelection records whether the transcript was cut to fit the input cap.
+	InputSelection RecapInputSelection
 }
 
 // SmartRecapAnalyzer generates AI-powered session recaps using Claude Haiku.
 type SmartRecapAnalyzer struct {
-	client             *anthropic.Client
-	model              string
-	maxOutputTokens    int
-	maxTranscriptChars int
-	systemPrompt       string
+	client              *anthropic.Client
+	model               string
+	maxOutputTokens     int
+	maxTranscriptTokens int
+	systemPrompt        string
 }
 
 // SmartRecapAnalyzerConfig holds tunable parameters for the analyzer.
@@ -111,11 +114,11 @@ func NewSmartRecapAnalyzer(client *anthropic.Client, model string, cfg SmartReca
 		systemPrompt = BuildSmartRecapSystemPrompt(nil)
 	}
 	return &SmartRecapAnalyzer{
-		client:             client,
-		model:              model,
-		maxOutputTokens:    maxOutput,
-		maxTranscriptChars: maxTranscriptTokens * 4,
-		systemPrompt:       systemPrompt,
+		client:              client,
+		model:               model,
+		maxOutputTokens:     maxOutput,
+		maxTranscriptTokens: maxTranscriptTokens,
+		systemPrompt:        systemPrompt,
 	}
 }
 
@@ -143,30 +146,14 @@ func (a *SmartRecapAnalyzer) Analyze(ctx context.Context, input GenerateInput, c
 	// Prepare the stats section
 	statsSection := PrepareStats(cardStats)
 
-	// Combine transcript and stats
-	userContent := transcript
-	if statsSection != "" {
-		userContent = transcript + "\n\n" + statsSection
-	}
-
-	// Track content size
-	contentLen := len(userContent)
-	truncated := false
-
-	// Truncate if too long (prioritize transcript, stats are at the end)
-	if contentLen > a.maxTranscriptChars {
-		// Truncate transcript portion, keep stats
-		maxTranscript := a.maxTranscriptChars - len(statsSection) - 100 // leave room for truncation message
-		if maxTranscript > 0 && len(transcript) > maxTranscript {
-			transcript = transcript[:maxTranscript] + "\n\n[Transcript truncated due to length]"
-			userContent = transcript + "\n\n" + statsSection
-		}
-		truncated = true
-	}
+	// Combine transcript and stats, fitting the transcript under the input cap
+	userContent, selection := SelectRecapInput(transcript, statsSection, a.maxTranscriptTokens)
 
 	span.SetAttributes(
-		attribute.Int("content.chars", contentLen),
-		attribute.Bool("content.truncated", truncated),
+		attribute.Int("content.chars", len(userContent)),
+		attribute.Int("content.tokens.original", selection.OriginalTokens),
+		attribute.Bool("content.truncated", selection.Truncated),
+		attribute.Int("content.omitted_entries", selection.OmittedEntries),
 		attribute.Bool("stats.included", statsSection != ""),
 	)
 
@@ -219,6 +206,7 @@ func (a *SmartRecapAnalyzer) Analyze(ctx context.Context, input GenerateInput, c
 	result.InputTokens = resp.Usage.InputTokens
 	result.OutputTokens = resp.Usage.OutputTokens
 	result.GenerationTimeMs = generationTimeMs
+	result.InputSelection = selection
 
 	// Record final metrics
 	span.SetAttributes(
@@ -476,6 +464,7 @@ const smartRecapInputFormat = `You are analyzing an AI coding agent's session. T
    - Assistant messages capture the agent's responses, which may include reasoning and the tool calls it made.
    - Tool calls and their results appear inline; results indicate success or failure.
    - Compaction markers indicate the session was auto-summarized.
+   - An <omitted entries="N" /> marker stands for N elements left out of a very long session; judge the session from what remains.
 
 2. <session_stats> - Computed analytics metrics (if available):
    - Token usage, costs, and cache hit rates
@@ -1010,6 +1010,57 @@ func TestAgentsAnalyzer_UnknownAgentType(t *testing.T) {
 	}
 }
 
+func TestAgentsAnalyzer_AgentCalls(t *testing.T) {
+	// Main launches an Explore agent (reported duration) and a Plan agent
+	// (duration from timestamps); the Explore agent launches a nested agent.
+	main := makeAssistantMessageWithStopReason("a1", "2025-01-01T00:00:01Z", "claude-sonnet-4", 100, 50, []map[string]interface{}{
+		makeToolUseBlock("toolu_explore", "Task", map[string]interface{}{"subagent_type": "Explore"}),
+	}, "tool_use") + "\n" +
+		makeUserMessageWithToolUseResult("u1", "2025-01-01T00:00:30Z", []map[string]interface{}{
+			makeToolResultBlock("toolu_explore", "Done", false),
+		}, map[string]interface{}{"agentId": "explore1", "totalDurationMs": float64(25000)}) + "\n" +
+		makeAssistantMessageWithStopReason("a2", "2025-01-01T00:00:31Z", "claude-sonnet-4", 100, 50, []map[string]interface{}{
+			makeToolUseBlock("toolu_plan", "Agent", map[string]interface{}{"subagent_type": "Plan"}),
+		}, "tool_use") + "\n" +
+		makeUserMessageWithToolUseResult("u2", "2025-01-01T00:00:41Z", []map[string]interface{}{
+			makeToolResultBlock("toolu_plan", "failed", true),
+		}, map[string]interface{}{"agentId": "plan1"}) + "\n"
+	nested := makeAssistantMessageWithStopReason("e1", "2025-01-01T00:00:05Z", "claude-sonnet-4", 100, 50, []map[string]interface{}{
+		makeToolUseBlock("toolu_nested", "Task", map[string]interface{}{"subagent_type": "general-purpose"}),
+	}, "tool_use") + "\n" +
+		makeUserMessageWithToolUseResult("e2", "2025-01-01T00:00:08Z", []map[string]interface{}{
+			makeToolResultBlock("toolu_nested", "Done", false),
+		}, map[string]interface{}{"agentId": "nested1"}) + "\n"
+
+	fc, err := NewFileCollectionWithAgents([]byte(main), map[string][]byte{"explore1": []byte(nested)})
+	if err != nil {
+		t.Fatalf("NewFileCollectionWithAgents failed: %v", err)
+	}
+
+	result, err := (&AgentsAnalyzer{}).Analyze(fc)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	// Counts stay main-only.
+	if result.TotalInvocations != 2 || result.AgentStats["general-purpose"] != nil {
+		t.Errorf("TotalInvocations = %d, AgentStats = %v; want 2 main-transcript calls", result.TotalInvocations, result.AgentStats)
+	}
+	want := []AgentCall{
+		{AgentID: "explore1", AgentType: "Explore", DurationMs: 25000},
+		{AgentID: "plan1", AgentType: "Plan", DurationMs: 10000, IsError: true},
+		{AgentID: "nested1", AgentType: "general-purpose", ParentAgentID: "explore1", DurationMs: 3000},
+	}
+	if len(result.AgentCalls) != len(want) {
+		t.Fatalf("AgentCalls = %+v, want %d calls", result.AgentCalls, len(want))
+	}
+	for i, w := range want {
+		if result.AgentCalls[i] != w {
+			t.Errorf("AgentCalls[%d] = %+v, want %+v", i, result.AgentCalls[i], w)
+		}
+	}
+}
+
 // TestAgentsAnalyzer_RealSession tests the analyzer against a real session transcript.
 // The test fixture is a copy of an actual Claude Code session.
 // Expected values derived from testdata/session_comprehensive.jsonl:
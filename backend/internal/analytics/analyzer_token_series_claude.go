@@ -0,0 +1,200 @@
+package analytics
+
+// MaxTokenSeriesPoints caps how many samples a token series holds. The
+// sampling interval grows with the transcript so a long session still fits.
+const MaxTokenSeriesPoints = 200
+
+// TokenSeriesPoint is the cumulative token usage up to and including a main
+// transcript line.
+type TokenSeriesPoint struct {
+	Line                int   `json:"line"` // 1-based main transcript line
+	InputTokens         int64 `json:"input_tokens"`
+	OutputTokens        int64 `json:"output_tokens"`
+	CacheCreationTokens int64 `json:"cache_creation_tokens"`
+	CacheReadTokens     int64 `json:"cache_read_tokens"`
+}
+
+// TokenSeries is cumulative token usage sampled every IntervalLines lines of
+// the main transcript. The last point is always the final line and equals the
+// tokens card totals; sub-agent usage lands on the line whose tool result
+// reports the agent (or the final line if none does).
+type TokenSeries struct {
+	IntervalLines int                `json:"interval_lines"`
+	TotalLines    int                `json:"total_lines"`
+	Points        []TokenSeriesPoint `json:"points"`
+}
+
+// tokenDelta is usage added (or, for a revised streaming usage, corrected) at
+// one main transcript line.
+type tokenDelta struct {
+	line                                    int
+	input, output, cacheCreation, cacheRead int64
+}
+
+func (d *tokenDelta) add(u *TokenUsage, sign int64) {
+	d.input += sign * u.InputTokens
+	d.output += sign * u.OutputTokens
+	d.cacheCreation += sign * u.CacheCreationInputTokens
+	d.cacheRead += sign * u.CacheReadInputTokens
+}
+
+// TokenSeriesAnalyzer builds a TokenSeries in the same pass as the tokens
+// card, using the same accounting: each assistant message counts its final
+// usage once, agent files count their own messages, and file-less agents fall
+// back to toolUseResult.usage.
+type TokenSeriesAnalyzer struct {
+	mainFile *TranscriptFile
+	// deltas holds the main transcript's usage changes in line order.
+	deltas []tokenDelta
+	// agentTotals is each agent file's summed usage, keyed by agent ID.
+	agentTotals map[string]*TokenUsage
+	// orphanAgents is usage from agent files with no ID to place them by.
+	orphanAgents TokenUsage
+	result       *TokenSeries
+}
+
+// ProcessFile records per-line usage for the main file and totals for agents.
+func (a *TokenSeriesAnalyzer) ProcessFile(file *TranscriptFile, isMain bool) {
+	if !isMain {
+		total := &TokenUsage{}
+		for _, group := range file.AssistantMessageGroups() {
+			if group.FinalUsage != nil {
+				addUsage(total, group.FinalUsage)
+			}
+		}
+		if file.AgentID == "" {
+			addUsage(&a.orphanAgents, total)
+			return
+		}
+		if a.agentTotals == nil {
+			a.agentTotals = make(map[string]*TokenUsage)
+		}
+		if prev, ok := a.agentTotals[file.AgentID]; ok {
+			addUsage(prev, total)
+		} else {
+			a.agentTotals[file.AgentID] = total
+		}
+		return
+	}
+
+	a.mainFile = file
+	// A message ID repeats across streamed lines with growing usage; the
+	// last occurrence is final, so each repeat replaces the previous value.
+	latest := make(map[string]*TokenUsage)
+	for _, line := range file.Lines {
+		if line.Type != "assistant" || line.Message == nil || line.Message.Usage == nil {
+			continue
+		}
+		usage := *line.Message.Usage
+		d := tokenDelta{line: line.LineNumber}
+		if msgID := line.GetMessageID(); msgID != "" {
+			if prev, ok := latest[msgID]; ok {
+				d.add(prev, -1)
+			}
+			latest[msgID] = &usage
+		}
+		d.add(&usage, 1)
+		a.deltas = append(a.deltas, d)
+	}
+}
+
+// Finalize places agent usage on the main transcript and samples the series.
+func (a *TokenSeriesAnalyzer) Finalize(hasAgentFile func(string) bool) {
+	if a.mainFile == nil {
+		return
+	}
+
+	deltas := a.deltas
+	placed := make(map[string]bool)
+	for _, line := range a.mainFile.Lines {
+		for _, agentResult := range line.GetAgentResults() {
+			var usage *TokenUsage
+			if hasAgentFile(agentResult.AgentID) {
+				if placed[agentResult.AgentID] {
+					continue
+				}
+				placed[agentResult.AgentID] = true
+				usage = a.agentTotals[agentResult.AgentID]
+			} else {
+				usage = agentResult.Usage
+			}
+			if usage == nil {
+				continue
+			}
+			d := tokenDelta{line: line.LineNumber}
+			d.add(usage, 1)
+			deltas = append(deltas, d)
+		}
+	}
+
+	totalLines := 0
+	if n := len(a.mainFile.Lines); n > 0 {
+		totalLines = a.mainFile.Lines[n-1].LineNumber
+	}
+	// Agents never referenced from the main transcript land on the last line.
+	unplaced := a.orphanAgents
+	for agentID, usage := range a.agentTotals {
+		if !placed[agentID] {
+			addUsage(&unplaced, usage)
+		}
+	}
+	tail := tokenDelta{line: totalLines}
+	tail.add(&unplaced, 1)
+	deltas = append(deltas, tail)
+
+	a.result = sampleTokenSeries(deltas, totalLines)
+}
+
+// Result returns the computed series, or nil before Finalize or for a session
+// without a main transcript.
+func (a *TokenSeriesAnalyzer) Result() *TokenSeries {
+	return a.result
+}
+
+// sampleTokenSeries accumulates deltas (in any order) and samples the running
+// totals every interval lines, ending with the final line.
+func sampleTokenSeries(deltas []tokenDelta, totalLines int) *TokenSeries {
+	interval := 1
+	if totalLines > MaxTokenSeriesPoints {
+		interval = (totalLines + MaxTokenSeriesPoints - 1) / MaxTokenSeriesPoints
+	}
+	series := &TokenSeries{IntervalLines: interval, TotalLines: totalLines, Points: []TokenSeriesPoint{}}
+	if totalLines == 0 {
+		return series
+	}
+
+	// Bucket each delta into the sample that first includes its line.
+	buckets := make([]tokenDelta, (totalLines+interval-1)/interval)
+	for _, d := range deltas {
+		i := 0
+		if d.line > 0 {
+			i = (d.line - 1) / interval
+		}
+		if i >= len(buckets) {
+			i = len(buckets) - 1
+		}
+		b := &buckets[i]
+		b.input += d.input
+		b.output += d.output
+		b.cacheCreation += d.cacheCreation
+		b.cacheRead += d.cacheRead
+	}
+
+	var running TokenSeriesPoint
+	for i, b := range buckets {
+		running.Line = min((i+1)*interval, totalLines)
+		running.InputTokens += b.input
+		running.OutputTokens += b.output
+		running.CacheCreationTokens += b.cacheCreation
+		running.CacheReadTokens += b.cacheRead
+		series.Points = append(series.Points, running)
+	}
+	return series
+}
+
+func addUsage(dst, u *TokenUsage) {
+	dst.InputTokens += u.InputTokens
+	dst.OutputTokens += u.OutputTokens
+	dst.CacheCreationInputTokens += u.CacheCreationInputTokens
+	dst.CacheReadInputTokens += u.CacheReadInputTokens
+}
@@ -0,0 +1,110 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestTokenSeries_FinalPointMatchesTokensCard locks the series to the tokens
+// card: with a referenced agent file, an unreferenced one and an empty one,
+// the last point must equal the card totals.
+func TestTokenSeries_FinalPointMatchesTokensCard(t *testing.T) {
+	fc := buildMultiAgentFixture(t)
+
+	result, err := ComputeFromFileCollection(context.Background(), fc)
+	if err != nil {
+		t.Fatalf("ComputeFromFileCollection failed: %v", err)
+	}
+	series := result.TokenSeries
+	if series == nil || len(series.Points) == 0 {
+		t.Fatalf("TokenSeries = %+v, want points", series)
+	}
+
+	last := series.Points[len(series.Points)-1]
+	if last.Line != series.TotalLines {
+		t.Errorf("last point line = %d, want total lines %d", last.Line, series.TotalLines)
+	}
+	if last.InputTokens != result.InputTokens || last.OutputTokens != result.OutputTokens ||
+		last.CacheCreationTokens != result.CacheCreationTokens || last.CacheReadTokens != result.CacheReadTokens {
+		t.Errorf("last point = %+v, want tokens card %d/%d/%d/%d", last,
+			result.InputTokens, result.OutputTokens, result.CacheCreationTokens, result.CacheReadTokens)
+	}
+
+	// agent1's tokens land on line 4, where its tool result reports it;
+	// agent2 is never referenced, so it only appears on the final line.
+	// Lines 1-3: a1 (100/50). Line 4: + agent1 (300/150).
+	if p := series.Points[2]; p.Line != 3 || p.InputTokens != 100 || p.OutputTokens != 50 {
+		t.Errorf("point at line 3 = %+v, want 100/50", p)
+	}
+	if p := series.Points[3]; p.Line != 4 || p.InputTokens != 400 || p.OutputTokens != 200 {
+		t.Errorf("point at line 4 = %+v, want 400/200", p)
+	}
+}
+
+func TestTokenSeries_StreamedMessageCountsFinalUsageOnce(t *testing.T) {
+	jsonl := makeUserMessage("u1", "2025-01-01T00:00:00Z", "hello") + "\n" +
+		makeAssistantMessageWithMsgID("a1", "2025-01-01T00:00:01Z", "claude-sonnet-4", "msg-001", 100, 10, []map[string]interface{}{
+			makeThinkingBlock("thinking..."),
+		}) + "\n" +
+		makeAssistantMessageWithMsgID("a2", "2025-01-01T00:00:02Z", "claude-sonnet-4", "msg-001", 100, 80, []map[string]interface{}{
+			makeTextBlock("response"),
+		}) + "\n"
+
+	fc, err := NewFileCollection([]byte(jsonl))
+	if err != nil {
+		t.Fatalf("NewFileCollection failed: %v", err)
+	}
+	result, err := ComputeFromFileCollection(context.Background(), fc)
+	if err != nil {
+		t.Fatalf("ComputeFromFileCollection failed: %v", err)
+	}
+
+	want := []TokenSeriesPoint{
+		{Line: 1},
+		{Line: 2, InputTokens: 100, OutputTokens: 10},
+		{Line: 3, InputTokens: 100, OutputTokens: 80},
+	}
+	got := result.TokenSeries.Points
+	if len(got) != len(want) {
+		t.Fatalf("points = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("point %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTokenSeries_SamplesLongTranscripts(t *testing.T) {
+	var sb strings.Builder
+	lines := MaxTokenSeriesPoints*2 + 1
+	for i := 0; i < lines; i++ {
+		sb.WriteString(makeAssistantMessageWithMsgID(fmt.Sprintf("a%d", i), "2025-01-01T00:00:01Z", "claude-sonnet-4", fmt.Sprintf("msg-%d", i), 1, 1, []map[string]interface{}{
+			makeTextBlock("x"),
+		}))
+		sb.WriteString("\n")
+	}
+
+	fc, err := NewFileCollection([]byte(sb.String()))
+	if err != nil {
+		t.Fatalf("NewFileCollection failed: %v", err)
+	}
+	result, err := ComputeFromFileCollection(context.Background(), fc)
+	if err != nil {
+		t.Fatalf("ComputeFromFileCollection failed: %v", err)
+	}
+
+	series := result.TokenSeries
+	if series.IntervalLines != 3 || len(series.Points) > MaxTokenSeriesPoints {
+		t.Errorf("interval %d with %d points, want interval 3 and at most %d points",
+			series.IntervalLines, len(series.Points), MaxTokenSeriesPoints)
+	}
+	if p := series.Points[0]; p.Line != 3 || p.InputTokens != 3 {
+		t.Errorf("first point = %+v, want line 3 with 3 input tokens", p)
+	}
+	if last := series.Points[len(series.Points)-1]; last.Line != lines || last.InputTokens != int64(lines) {
+		t.Errorf("last point = %+v, want line %d with %d input tokens", last, lines, lines)
+	}
+}
@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"log/slog"
+	"math"
 	"time"
 
 	"github.com/ConfabulousDev/confab-web/internal/models"
@@ -47,6 +48,26 @@ type TokensAnalyzer struct {
 	// totals, keyed by getModelFamily() family (fast turns under "<family> · fast").
 	// Lazily initialized so a zero-value analyzer needs no constructor.
 	byModel map[string]*v2ModelAgg
+	// thinking accumulates the estimated thinking share of output tokens over
+	// the messages whose split could be derived (see estimateThinkingTokens);
+	// thinkingDerived is false until one could.
+	thinkingTokens  int64
+	thinkingCost    decimal.Decimal
+	thinkingDerived bool
+}
+
+// estimateThinkingTokens estimates how many of a message's output tokens went
+// to extended thinking. Usage reports one output count per message, so it is
+// split in proportion to the characters of the thinking and visible content
+// blocks. ok is false when the split can't be derived: no usage, no content,
+// or thinking whose text the transcript doesn't carry.
+func estimateThinkingTokens(group AssistantMessageGroup) (tokens int64, ok bool) {
+	total := group.ThinkingChars + group.VisibleChars
+	if group.FinalUsage == nil || group.ThinkingUnknown || total == 0 {
+		return 0, false
+	}
+	share := float64(group.ThinkingChars) / float64(total)
+	return int64(math.Round(float64(group.FinalUsage.OutputTokens) * share)), true
 }
 
 // v2ModelAgg accumulates one model family's tokens + cost for the tokens_v2 tree.
@@ -157,6 +178,16 @@ func (a *TokensAnalyzer) ProcessFile(file *TranscriptFile, isMain bool) {
 		a.result.EstimatedCostUSD = a.result.EstimatedCostUSD.Add(cost)
 		a.accumulateV2(group.Model, group.IsFastMode, usage, cost)
 
+		if thinking, ok := estimateThinkingTokens(group); ok && group.Model != syntheticModelKey {
+			thinkingCost := decimal.NewFromInt(thinking).Mul(pricing.Output).Div(oneMillion)
+			if usage.Speed == SpeedFast {
+				thinkingCost = thinkingCost.Mul(fastModeMultiplier)
+			}
+			a.thinkingTokens += thinking
+			a.thinkingCost = a.thinkingCost.Add(thinkingCost)
+			a.thinkingDerived = true
+		}
+
 		if group.IsFastMode {
 			a.result.FastTurns++
 			a.result.FastCostUSD = a.result.FastCostUSD.Add(cost)
@@ -202,10 +233,18 @@ func (a *TokensAnalyzer) Finalize(hasAgentFile func(string) bool) {
 }
 
 // Result returns the accumulated token metrics, including the per-model tokens_v2
-// tree assembled from byModel. Idempotent: callers (claude_compute, Analyze) may
-// call it once after ProcessFile/Finalize have run.
+// tree assembled from byModel and its thinking estimate. Idempotent: callers
+// (claude_compute, Analyze) may call it once after ProcessFile/Finalize have run.
+// File-less sub-agents carry no content, so their output never counts as
+// thinking.
 func (a *TokensAnalyzer) Result() *TokensResult {
 	a.result.TokensV2 = buildV2Tree(models.ProviderClaudeCode, a.byModel)
+	if a.result.TokensV2 != nil && a.thinkingDerived {
+		tokens := a.thinkingTokens
+		cost := a.thinkingCost.String()
+		a.result.TokensV2.ThinkingTokens = &tokens
+		a.result.TokensV2.ThinkingCostUSD = &cost
+	}
 	return &a.result
 }
 
@@ -342,3 +342,105 @@ func TestTokensAnalyzer_V2Tree_SyntheticOnlyYieldsNilTree(t *testing.T) {
 		t.Errorf("TokensV2 = %+v, want nil for a synthetic-only session", result.TokensV2)
 	}
 }
+
+// TestTokensAnalyzer_ThinkingSplit pins the thinking estimate: each message's
+// output tokens are split by the characters of its thinking blocks versus its
+// text and tool_use blocks, summed across messages and priced at the output
+// rate.
+func TestTokensAnalyzer_ThinkingSplit(t *testing.T) {
+	const model = "claude-sonnet-4-20241022"
+	// A streamed message: its thinking and text blocks arrive on two lines
+	// sharing one message ID, the last carrying the final usage.
+	streamedThinking := makeAssistantMessage("a3", "2025-01-01T00:00:03Z", model, 10, 1,
+		[]map[string]interface{}{makeThinkingBlock(strings.Repeat("t", 200))})
+	streamedText := strings.Replace(makeAssistantMessage("a3b", "2025-01-01T00:00:04Z", model, 10, 80,
+		[]map[string]interface{}{makeTextBlock(strings.Repeat("v", 200))}), `"msg-a3b"`, `"msg-a3"`, 1)
+
+	jsonl := strings.Join([]string{
+		// 300 thinking chars, 100 visible: 3/4 of 400 output tokens.
+		makeAssistantMessage("a1", "2025-01-01T00:00:01Z", model, 10, 400, []map[string]interface{}{
+			makeThinkingBlock(strings.Repeat("t", 300)),
+			makeTextBlock(strings.Repeat("v", 100)),
+		}),
+		// No thinking: none of its output counts.
+		makeAssistantMessage("a2", "2025-01-01T00:00:02Z", model, 10, 100, []map[string]interface{}{
+			makeTextBlock("Running the tests."),
+			makeToolUseBlock("toolu_1", "Bash", map[string]interface{}{"command": "go test ./..."}),
+		}),
+		// 200 thinking chars, 200 visible across two lines: half of 80.
+		streamedThinking,
+		streamedText,
+	}, "\n") + "\n"
+	fc, err := NewFileCollection([]byte(jsonl))
+	if err != nil {
+		t.Fatalf("NewFileCollection: %v", err)
+	}
+
+	result, err := (&TokensAnalyzer{}).Analyze(fc)
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	v2 := result.TokensV2
+	if v2 == nil || v2.ThinkingTokens == nil || v2.ThinkingCostUSD == nil {
+		t.Fatalf("thinking split not derived: %+v", v2)
+	}
+	if want := int64(300 + 40); *v2.ThinkingTokens != want {
+		t.Errorf("ThinkingTokens = %d, want %d", *v2.ThinkingTokens, want)
+	}
+	sonnet, _ := LookupPricing(model)
+	wantCost := CalculateCost(sonnet, 0, 340, 0, 0)
+	if *v2.ThinkingCostUSD != wantCost.String() {
+		t.Errorf("ThinkingCostUSD = %s, want %s", *v2.ThinkingCostUSD, wantCost)
+	}
+}
+
+// TestTokensAnalyzer_ThinkingSplit_NoThinking: a session without thinking
+// blocks derives a split of zero, not null.
+func TestTokensAnalyzer_ThinkingSplit_NoThinking(t *testing.T) {
+	jsonl := makeAssistantMessage("a1", "2025-01-01T00:00:01Z", "claude-sonnet-4-20241022", 10, 50,
+		[]map[string]interface{}{makeTextBlock("Hello")}) + "\n"
+	fc, err := NewFileCollection([]byte(jsonl))
+	if err != nil {
+		t.Fatalf("NewFileCollection: %v", err)
+	}
+	result, err := (&TokensAnalyzer{}).Analyze(fc)
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	v2 := result.TokensV2
+	if v2 == nil || v2.ThinkingTokens == nil || *v2.ThinkingTokens != 0 {
+		t.Fatalf("ThinkingTokens = %v, want 0", v2)
+	}
+	if v2.ThinkingCostUSD == nil || *v2.ThinkingCostUSD != "0" {
+		t.Errorf("ThinkingCostUSD = %v, want 0", v2.ThinkingCostUSD)
+	}
+}
+
+// TestTokensAnalyzer_ThinkingSplit_Redacted: thinking whose text the
+// transcript doesn't carry can't be sized, so with no other message to split
+// the estimate is null.
+func TestTokensAnalyzer_ThinkingSplit_Redacted(t *testing.T) {
+	for name, block := range map[string]map[string]interface{}{
+		"signature only":    {"type": "thinking", "thinking": "", "signature": "sig"},
+		"redacted_thinking": {"type": "redacted_thinking", "data": "opaque"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			jsonl := makeAssistantMessage("a1", "2025-01-01T00:00:01Z", "claude-sonnet-4-20241022", 10, 50,
+				[]map[string]interface{}{block, makeTextBlock("Done")}) + "\n"
+			fc, err := NewFileCollection([]byte(jsonl))
+			if err != nil {
+				t.Fatalf("NewFileCollection: %v", err)
+			}
+			result, err := (&TokensAnalyzer{}).Analyze(fc)
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if result.TokensV2 == nil {
+				t.Fatal("TokensV2 not populated")
+			}
+			if result.TokensV2.ThinkingTokens != nil || result.TokensV2.ThinkingCostUSD != nil {
+				t.Errorf("thinking = %v / %v, want null", result.TokensV2.ThinkingTokens, result.TokensV2.ThinkingCostUSD)
+			}
+		})
+	}
+}
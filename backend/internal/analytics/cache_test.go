@@ -1,6 +1,8 @@
 package analytics
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
@@ -97,6 +99,13 @@ func TestCardsAllValid(t *testing.T) {
 				UpToLine:   upToLine,
 				Runs:       []WorkflowRun{},
 			},
+			CodeChanges: &CodeChangesCardRecord{
+				SessionID:  "test-session",
+				Version:    CodeChangesCardVersion,
+				ComputedAt: now,
+				UpToLine:   upToLine,
+				Hunks:      []EditHunk{},
+			},
 		}
 	}
 
@@ -112,6 +121,7 @@ func TestCardsAllValid(t *testing.T) {
 		cards.AgentsAndSkills.Version = version
 		cards.Redactions.Version = version
 		cards.Workflows.Version = version
+		cards.CodeChanges.Version = version
 		return cards
 	}
 
@@ -210,6 +220,123 @@ func TestCardsAllValid(t *testing.T) {
 	})
 }
 
+func TestDropFlaggedCards(t *testing.T) {
+	// A flag-gated op over the Workflows card alongside an ungated Tools op.
+	ops := []cardOp{
+		{
+			name:    "tools",
+			present: func(c *Cards) bool { return c.Tools != nil },
+		},
+		{
+			name:        "workflows",
+			present:     func(c *Cards) bool { return c.Workflows != nil },
+			featureFlag: "workflows_card",
+			clear:       func(c *Cards) { c.Workflows = nil },
+		},
+	}
+	newCards := func() *Cards {
+		return &Cards{Tools: &ToolsCardRecord{}, Workflows: &WorkflowsCardRecord{}}
+	}
+
+	t.Run("flag on keeps the card", func(t *testing.T) {
+		cards := newCards()
+		err := dropFlaggedCards(context.Background(), ops, cards, func(context.Context, string) (bool, error) { return true, nil })
+		if err != nil {
+			t.Fatalf("dropFlaggedCards error: %v", err)
+		}
+		if cards.Workflows == nil || cards.Tools == nil {
+			t.Errorf("expected both cards kept, got tools=%v workflows=%v", cards.Tools != nil, cards.Workflows != nil)
+		}
+		if len(cards.gatedOff) != 0 {
+			t.Errorf("gatedOff = %v, want empty", cards.gatedOff)
+		}
+	})
+
+	t.Run("flag off drops and marks only the gated card", func(t *testing.T) {
+		cards := newCards()
+		var asked []string
+		err := dropFlaggedCards(context.Background(), ops, cards, func(_ context.Context, flag string) (bool, error) {
+			asked = append(asked, flag)
+			return false, nil
+		})
+		if err != nil {
+			t.Fatalf("dropFlaggedCards error: %v", err)
+		}
+		if cards.Workflows != nil {
+			t.Error("expected gated workflows card dropped")
+		}
+		if cards.Tools == nil {
+			t.Error("ungated tools card must not be dropped")
+		}
+		if !cards.gatedOff["workflows"] || cards.gatedOff["tools"] {
+			t.Errorf("gatedOff = %v, want only workflows", cards.gatedOff)
+		}
+		if len(asked) != 1 || asked[0] != "workflows_card" {
+			t.Errorf("flags evaluated = %v, want [workflows_card]", asked)
+		}
+	})
+
+	t.Run("absent card is still marked", func(t *testing.T) {
+		cards := &Cards{Tools: &ToolsCardRecord{}}
+		err := dropFlaggedCards(context.Background(), ops, cards, func(context.Context, string) (bool, error) { return false, nil })
+		if err != nil {
+			t.Fatalf("dropFlaggedCards error: %v", err)
+		}
+		if !cards.gatedOff["workflows"] {
+			t.Error("a cached set without the gated card should still mark it gated off")
+		}
+	})
+
+	t.Run("lookup error is returned", func(t *testing.T) {
+		cards := newCards()
+		err := dropFlaggedCards(context.Background(), ops, cards, func(context.Context, string) (bool, error) {
+			return false, errors.New("db down")
+		})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestCardOps_FeatureFlaggedOpsCanClear(t *testing.T) {
+	for _, op := range cardOps {
+		if op.featureFlag != "" && op.clear == nil {
+			t.Errorf("card %s has featureFlag %q but no clear func", op.name, op.featureFlag)
+		}
+	}
+}
+
+// A card gated off for the owner is neither required by AllValid/AllPresent
+// nor able to make the set look stale.
+func TestCards_GatedOffCard(t *testing.T) {
+	now := time.Now().UTC()
+	th := DefaultRegularCardsThresholds()
+	cards := &Cards{
+		TokensV2:        &TokensV2CardRecord{Version: TokensV2CardVersion, ComputedAt: now, UpToLine: 100},
+		Session:         &SessionCardRecord{Version: SessionCardVersion, ComputedAt: now, UpToLine: 100},
+		Tools:           &ToolsCardRecord{Version: ToolsCardVersion, ComputedAt: now, UpToLine: 100},
+		CodeActivity:    &CodeActivityCardRecord{Version: CodeActivityCardVersion, ComputedAt: now, UpToLine: 100},
+		Conversation:    &ConversationCardRecord{Version: ConversationCardVersion, ComputedAt: now, UpToLine: 100},
+		AgentsAndSkills: &AgentsAndSkillsCardRecord{Version: AgentsAndSkillsCardVersion, ComputedAt: now, UpToLine: 100},
+		Redactions:      &RedactionsCardRecord{Version: RedactionsCardVersion, ComputedAt: now, UpToLine: 100},
+		Workflows:       &WorkflowsCardRecord{Version: WorkflowsCardVersion, ComputedAt: now, UpToLine: 100},
+	}
+	if cards.AllValid(100) || cards.AllPresent() {
+		t.Fatal("a set missing code_changes should not be valid or complete while the card is on")
+	}
+
+	cards.gatedOff = map[string]bool{"code_changes": true}
+	if !cards.AllValid(100) {
+		t.Error("AllValid should skip a gated-off card")
+	}
+	if !cards.AllPresent() {
+		t.Error("AllPresent should skip a gated-off card")
+	}
+	if cards.NeedsRecompute(th, 100, now.Add(-time.Hour), now) {
+		t.Error("NeedsRecompute should skip a gated-off card")
+	}
+}
+
 func TestTokensV2CardRecordIsValid(t *testing.T) {
 	t.Run("returns false when nil", func(t *testing.T) {
 		var card *TokensV2CardRecord
@@ -274,6 +401,7 @@ func TestCardsAllValid_Exhaustive(t *testing.T) {
 				AgentsAndSkills: &AgentsAndSkillsCardRecord{Version: AgentsAndSkillsCardVersion, ComputedAt: now, UpToLine: lineCount},
 				Redactions:      &RedactionsCardRecord{Version: RedactionsCardVersion, ComputedAt: now, UpToLine: lineCount},
 				Workflows:       &WorkflowsCardRecord{Version: WorkflowsCardVersion, ComputedAt: now, UpToLine: lineCount},
+				CodeChanges:     &CodeChangesCardRecord{Version: CodeChangesCardVersion, ComputedAt: now, UpToLine: lineCount},
 			}
 
 			// Nil out this one field
@@ -286,6 +414,33 @@ func TestCardsAllValid_Exhaustive(t *testing.T) {
 	}
 }
 
+// TestCardsSetComputedAt_Exhaustive verifies that setComputedAt stamps every
+// *CardRecord field in the Cards struct, so no card keeps the app clock's time.
+func TestCardsSetComputedAt_Exhaustive(t *testing.T) {
+	cards := &Cards{}
+	v := reflect.ValueOf(cards).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Ptr && strings.HasSuffix(field.Type().Elem().Name(), "CardRecord") {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+	}
+
+	dbNow := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	cards.setComputedAt(dbNow)
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() != reflect.Ptr || field.IsNil() {
+			continue
+		}
+		got := field.Elem().FieldByName("ComputedAt").Interface().(time.Time)
+		if !got.Equal(dbNow) {
+			t.Errorf("%s.ComputedAt = %v; setComputedAt does not stamp this card field", v.Type().Field(i).Name, got)
+		}
+	}
+}
+
 func TestSessionCardRecordIsValid(t *testing.T) {
 	t.Run("returns false when nil", func(t *testing.T) {
 		var card *SessionCardRecord
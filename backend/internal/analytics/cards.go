@@ -17,9 +17,25 @@ var AllCardTableNames = []string{
 	"session_card_agents_and_skills",
 	"session_card_redactions",
 	"session_card_workflows",
+	"session_card_code_changes",
 	"session_card_smart_recap",
 }
 
+// SessionDerivedTableNames lists every per-session table computed from a
+// session's synced lines: the cards, the conversation turns behind the
+// conversation card, the redaction events behind the redactions card, the
+// token series, the cost projection, the search index, and the cached smart
+// recap variants. A sync file reset deletes a session's rows from all of them
+// so they are rebuilt from the new content.
+var SessionDerivedTableNames = append(append([]string{}, AllCardTableNames...),
+	"session_card_conversation_turns",
+	"session_card_redaction_events",
+	"session_card_token_series",
+	"session_card_cost_projection",
+	"session_search_index",
+	"session_recap_alternatives",
+)
+
 // IsKnownCardTableName reports whether name is one of AllCardTableNames.
 func IsKnownCardTableName(name string) bool {
 	for _, n := range AllCardTableNames {
@@ -32,18 +48,24 @@ func IsKnownCardTableName(name string) bool {
 
 // Card version constants - increment when compute logic changes
 const (
-	TokensV2CardVersion        = 4 // v4: top-level total_cache_creation/total_cache_read scalars (pjnz)
-	SessionCardVersion         = 5 // v5: dedup assistant counts by message.id, non-exclusive breakdown
+	TokensV2CardVersion        = 5 // v5: estimated thinking_tokens/thinking_cost_usd (Claude Code)
+	SessionCardVersion         = 6 // v6: timestamp ordering anomalies; negative compaction times clamp to 0
 	ToolsCardVersion           = 3 // v3: Codex spawn_agent/wait_agent excluded — surfaced via AgentsAndSkills (CF-443)
-	CodeActivityCardVersion    = 2 // v2: Edit counts full old/new lines (matches GitHub diff)
-	ConversationCardVersion    = 3 // v3: AssistantTurns = user-prompt-triggered sequences (deduped)
-	AgentsAndSkillsCardVersion = 2 // v2: Codex subagent + skill support (CF-443)
+	CodeActivityCardVersion    = 4 // v4: per-file activity (code_files)
+	ConversationCardVersion    = 4 // v4: out-of-order timestamps clamp turn durations to 0 instead of dropping them
+	AgentsAndSkillsCardVersion = 3 // v3: per-invocation agent calls with durations and parent agents
 	RedactionsCardVersion      = 2 // v2: filter out "TYPE" placeholder
 	WorkflowsCardVersion       = 1 // v1: per-run workflow subagent aggregates (CF-534)
+	CodeChangesCardVersion     = 1 // v1: sampled Edit/MultiEdit diff hunks
 	SmartRecapCardVersion      = 1 // v1: initial AI-powered session recap
 	SearchIndexVersion         = 1 // v1: initial full-text search index
 )
 
+// CodeChangesCardFlag is the feature flag (see internal/features) the code
+// changes card is rolling out behind. Users outside it get no code changes
+// row, and the card is left out of every all-cards check for them.
+const CodeChangesCardFlag = "code_changes_card"
+
 // =============================================================================
 // Database record types (stored in session_card_* tables)
 // =============================================================================
@@ -83,6 +105,17 @@ type TokensV2Data struct {
 	TotalCacheCreation int64                       `json:"total_cache_creation"`
 	TotalCacheRead     int64                       `json:"total_cache_read"`
 	ByProvider         map[string]TokensV2Provider `json:"by_provider"`
+
+	// ThinkingTokens is the estimated part of TotalOutput spent on extended
+	// thinking, and ThinkingCostUSD those tokens at each model's output rate.
+	// Usage reports one output count per message, so each message's output
+	// is split in proportion to the characters of its thinking blocks versus
+	// its text and tool_use blocks. Best effort: messages whose thinking text
+	// is missing from the transcript (redacted) are left out, and both are
+	// null when no message could be split (providers other than Claude Code,
+	// or cards computed before v5).
+	ThinkingTokens  *int64  `json:"thinking_tokens"`
+	ThinkingCostUSD *string `json:"thinking_cost_usd"`
 }
 
 // TokensV2CardRecord is the DB record for the hierarchical (per-provider /
@@ -131,6 +164,11 @@ type SessionCardRecord struct {
 	CompactionAuto      int  `json:"compaction_auto"`
 	CompactionManual    int  `json:"compaction_manual"`
 	CompactionAvgTimeMs *int `json:"compaction_avg_time_ms,omitempty"`
+
+	// Timestamp ordering anomalies: lines whose timestamp goes backwards by
+	// more than TimestampRegressionTolerance, and the first such line.
+	TimestampAnomalies        int  `json:"timestamp_anomalies"`
+	FirstTimestampAnomalyLine *int `json:"first_timestamp_anomaly_line,omitempty"`
 }
 
 // ToolsCardRecord is the DB record for the tools card.
@@ -156,6 +194,8 @@ type CodeActivityCardRecord struct {
 	LinesRemoved      int            `json:"lines_removed"`
 	SearchCount       int            `json:"search_count"`
 	LanguageBreakdown map[string]int `json:"language_breakdown"` // extension -> count
+	OutOfTree         OutOfTreeAccess `json:"out_of_tree"`       // Read/Write/Edit paths outside the session cwd
+	CodeFiles         []CodeFileStats `json:"code_files"`        // Per-file activity, served by its own endpoint
 }
 
 // ConversationCardRecord is the DB record for the conversation card.
@@ -180,6 +220,17 @@ type AgentStats struct {
 	Errors  int `json:"errors"`
 }
 
+// AgentCall is one agent invocation. It is the JSONB storage shape of
+// session_card_agents_and_skills.agent_calls. ParentAgentID is the agent whose
+// transcript launched the call, empty for calls from the main transcript.
+type AgentCall struct {
+	AgentID       string `json:"agent_id"`
+	AgentType     string `json:"agent_type"`
+	ParentAgentID string `json:"parent_agent_id,omitempty"`
+	DurationMs    int64  `json:"duration_ms"`
+	IsError       bool   `json:"is_error,omitempty"`
+}
+
 // SkillStats holds success and error counts for a single skill.
 type SkillStats struct {
 	Success int `json:"success"`
@@ -196,6 +247,7 @@ type AgentsAndSkillsCardRecord struct {
 	SkillInvocations int                    `json:"skill_invocations"`
 	AgentStats       map[string]*AgentStats `json:"agent_stats"` // Per-agent-type success/error counts
 	SkillStats       map[string]*SkillStats `json:"skill_stats"` // Per-skill success/error counts
+	AgentCalls       []AgentCall            `json:"agent_calls"` // Per-invocation detail, capped at MaxAgentCalls (Claude Code only)
 }
 
 // RedactionsCardRecord is the DB record for the redactions card.
@@ -236,6 +288,30 @@ type WorkflowsCardRecord struct {
 	Runs       []WorkflowRun `json:"runs"`
 }
 
+// EditHunk is the diff of one Edit call (or one entry of a MultiEdit call).
+// It is both the JSONB storage shape (in session_card_code_changes.code_changes)
+// and the API wire shape. LinesBefore/LinesAfter count the old and new strings;
+// AddedLines/RemovedLines are the lines that differ once the lines common to
+// both ends are dropped, truncated to MaxCodeChangeHunkLines.
+type EditHunk struct {
+	FilePath     string   `json:"file_path"`
+	LinesBefore  int      `json:"lines_before"`
+	LinesAfter   int      `json:"lines_after"`
+	AddedLines   []string `json:"added_lines"`
+	RemovedLines []string `json:"removed_lines"`
+}
+
+// CodeChangesCardRecord is the DB record for the code changes card. Hunks is
+// a sample of at most MaxCodeChangeHunks of the session's TotalHunks edits.
+type CodeChangesCardRecord struct {
+	SessionID  string     `json:"session_id"`
+	Version    int        `json:"version"`
+	ComputedAt time.Time  `json:"computed_at"`
+	UpToLine   int64      `json:"up_to_line"`
+	TotalHunks int        `json:"total_hunks"`
+	Hunks      []EditHunk `json:"hunks"`
+}
+
 // SmartRecapCardRecord is the DB record for the AI-generated smart recap card.
 // Unlike other cards, this uses time-based invalidation due to LLM cost.
 type SmartRecapCardRecord struct {
@@ -258,6 +334,10 @@ type SmartRecapCardRecord struct {
 	OutputTokens     int    `json:"output_tokens"`
 	GenerationTimeMs *int   `json:"generation_time_ms,omitempty"`
 
+	// InputOmittedEntries counts the transcript elements left out to fit the
+	// input cap (see SelectRecapInput); 0 when the whole transcript was sent.
+	InputOmittedEntries int `json:"input_omitted_entries"`
+
 	// Race prevention (optimistic lock)
 	ComputingStartedAt *time.Time `json:"computing_started_at,omitempty"`
 }
@@ -284,9 +364,14 @@ type Cards struct {
 	AgentsAndSkills *AgentsAndSkillsCardRecord
 	Redactions      *RedactionsCardRecord
 	Workflows       *WorkflowsCardRecord
+	CodeChanges     *CodeChangesCardRecord
 
 	// Per-card computation errors (graceful degradation)
 	CardErrors map[string]string
+
+	// gatedOff names the cards whose feature flag is off for the session's
+	// owner (see ApplyFeatureFlags). They are cleared and count as valid.
+	gatedOff map[string]bool
 }
 
 // =============================================================================
@@ -304,6 +389,11 @@ type TokensCardData struct {
 	// Fast mode breakdown (omitted when no fast mode usage)
 	FastTurns   *int   `json:"fast_turns,omitempty"`
 	FastCostUSD string `json:"fast_cost_usd,omitempty"`
+
+	// Estimated thinking split of Output (see TokensV2Data.ThinkingTokens);
+	// null when it can't be derived.
+	ThinkingTokens  *int64  `json:"thinking_tokens"`
+	ThinkingCostUSD *string `json:"thinking_cost_usd"`
 }
 
 // SessionCardData is the API response format for the session card (includes compaction and message breakdown).
@@ -329,6 +419,11 @@ type SessionCardData struct {
 	CompactionAuto      int  `json:"compaction_auto"`
 	CompactionManual    int  `json:"compaction_manual"`
 	CompactionAvgTimeMs *int `json:"compaction_avg_time_ms,omitempty"`
+
+	// Timestamp ordering anomalies: lines whose timestamp goes backwards by
+	// more than TimestampRegressionTolerance, and the first such line.
+	TimestampAnomalies        int  `json:"timestamp_anomalies"`
+	FirstTimestampAnomalyLine *int `json:"first_timestamp_anomaly_line,omitempty"`
 }
 
 // ToolsCardData is the API response format for the tools card.
@@ -346,6 +441,7 @@ type CodeActivityCardData struct {
 	LinesRemoved      int            `json:"lines_removed"`
 	SearchCount       int            `json:"search_count"`
 	LanguageBreakdown map[string]int `json:"language_breakdown"`
+	OutOfTree         OutOfTreeAccess `json:"out_of_tree"`
 }
 
 // ConversationCardData is the API response format for the conversation card.
@@ -378,6 +474,22 @@ type WorkflowsCardData struct {
 	Runs []WorkflowRun `json:"runs"`
 }
 
+// CodeActivityFilesData is the API response format for a code activity card's
+// per-file breakdown. It is served by its own endpoint rather than in the
+// analytics response. TotalFiles counts the files matching the language
+// filter, before the limit.
+type CodeActivityFilesData struct {
+	TotalFiles int             `json:"total_files"`
+	Files      []CodeFileStats `json:"files"`
+}
+
+// CodeChangesCardData is the API response format for the code changes card.
+// It is served by its own endpoint rather than in the analytics response.
+type CodeChangesCardData struct {
+	TotalHunks int        `json:"total_hunks"`
+	Hunks      []EditHunk `json:"hunks"`
+}
+
 // SmartRecapCardData is the API response format for the AI-generated smart recap card.
 type SmartRecapCardData struct {
 	Recap                     string          `json:"recap"`
@@ -388,6 +500,8 @@ type SmartRecapCardData struct {
 	DefaultContextSuggestions []AnnotatedItem `json:"default_context_suggestions"`
 	ComputedAt                string          `json:"computed_at"`
 	ModelUsed                 string          `json:"model_used"`
+	InputTruncated            bool            `json:"input_truncated"`
+	InputOmittedEntries       int             `json:"input_omitted_entries,omitempty"`
 }
 
 // SmartRecapQuotaInfo contains quota information for smart recap generation.
@@ -416,6 +530,8 @@ var (
 	_ CardValidator = (*ConversationCardRecord)(nil)
 	_ CardValidator = (*AgentsAndSkillsCardRecord)(nil)
 	_ CardValidator = (*RedactionsCardRecord)(nil)
+	_ CardValidator = (*WorkflowsCardRecord)(nil)
+	_ CardValidator = (*CodeChangesCardRecord)(nil)
 )
 
 // IsValid checks if a tokens_v2 card record is valid for the current line count.
@@ -458,6 +574,11 @@ func (c *WorkflowsCardRecord) IsValid(currentLineCount int64) bool {
 	return c != nil && c.Version == WorkflowsCardVersion && c.UpToLine == currentLineCount
 }
 
+// IsValid checks if a code changes card record is valid for the current line count.
+func (c *CodeChangesCardRecord) IsValid(currentLineCount int64) bool {
+	return c != nil && c.Version == CodeChangesCardVersion && c.UpToLine == currentLineCount
+}
+
 // HasValidVersion checks if a smart recap card record exists with the correct version.
 // Used by API handlers to determine if a cached card can be returned.
 func (c *SmartRecapCardRecord) HasValidVersion() bool {
@@ -472,15 +593,17 @@ func (c *SmartRecapCardRecord) IsUpToDate(currentLineCount int64) bool {
 
 // CanAcquireLock checks if we can acquire the computing lock.
 // Returns true if no lock exists or the lock is stale (older than lockTimeoutSeconds).
-func (c *SmartRecapCardRecord) CanAcquireLock(lockTimeoutSeconds int) bool {
+// now must be the database clock (Store.Now), which stamped ComputingStartedAt.
+func (c *SmartRecapCardRecord) CanAcquireLock(lockTimeoutSeconds int, now time.Time) bool {
 	if c == nil || c.ComputingStartedAt == nil {
 		return true
 	}
 	// Lock is stale if older than timeout
-	return time.Since(*c.ComputingStartedAt).Seconds() >= float64(lockTimeoutSeconds)
+	return now.Sub(*c.ComputingStartedAt).Seconds() >= float64(lockTimeoutSeconds)
 }
 
-// AllValid checks if all cards are valid for the current line count.
+// AllValid checks if all cards are valid for the current line count. A card
+// gated off by ApplyFeatureFlags counts as valid.
 func (c *Cards) AllValid(currentLineCount int64) bool {
 	if c == nil {
 		return false
@@ -492,5 +615,203 @@ func (c *Cards) AllValid(currentLineCount int64) bool {
 		c.Conversation.IsValid(currentLineCount) &&
 		c.AgentsAndSkills.IsValid(currentLineCount) &&
 		c.Redactions.IsValid(currentLineCount) &&
-		c.Workflows.IsValid(currentLineCount)
+		c.Workflows.IsValid(currentLineCount) &&
+		(c.CodeChanges.IsValid(currentLineCount) || c.gatedOff["code_changes"])
+}
+
+// CardStaleness describes a stored card computed by an older card version.
+// Such cards are still served (stale-while-revalidate) until the precompute
+// worker replaces the whole set.
+type CardStaleness struct {
+	Stale          bool `json:"stale"`
+	StoredVersion  int  `json:"stored_version"`
+	CurrentVersion int  `json:"current_version"`
+	VersionDelta   int  `json:"version_delta"` // CurrentVersion - StoredVersion
+}
+
+// cardHeader is the shared header of one stored card, for set-wide checks.
+type cardHeader struct {
+	key            string // response card key
+	present        bool
+	version        int
+	currentVersion int
+	upToLine       int64
+	computedAt     time.Time
+}
+
+// headers lists the header of every regular card, present or not, except
+// those gated off by ApplyFeatureFlags. Keep in step with AllValid and the
+// cardOps registry.
+func (c *Cards) headers() []cardHeader {
+	h := func(key string, present bool, version, current int, upToLine int64, computedAt time.Time) cardHeader {
+		return cardHeader{key, present, version, current, upToLine, computedAt}
+	}
+	var out []cardHeader
+	if r := c.TokensV2; r != nil {
+		out = append(out, h("tokens_v2", true, r.Version, TokensV2CardVersion, r.UpToLine, r.ComputedAt))
+	} else {
+		out = append(out, cardHeader{key: "tokens_v2"})
+	}
+	if r := c.Session; r != nil {
+		out = append(out, h("session", true, r.Version, SessionCardVersion, r.UpToLine, r.ComputedAt))
+	} else {
+		out = append(out, cardHeader{key: "session"})
+	}
+	if r := c.Tools; r != nil {
+		out = append(out, h("tools", true, r.Version, ToolsCardVersion, r.UpToLine, r.ComputedAt))
+	} else {
+		out = append(out, cardHeader{key: "tools"})
+	}
+	if r := c.CodeActivity; r != nil {
+		out = append(out, h("code_activity", true, r.Version, CodeActivityCardVersion, r.UpToLine, r.ComputedAt))
+	} else {
+		out = append(out, cardHeader{key: "code_activity"})
+	}
+	if r := c.Conversation; r != nil {
+		out = append(out, h("conversation", true, r.Version, ConversationCardVersion, r.UpToLine, r.ComputedAt))
+	} else {
+		out = append(out, cardHeader{key: "conversation"})
+	}
+	if r := c.AgentsAndSkills; r != nil {
+		out = append(out, h("agents_and_skills", true, r.Version, AgentsAndSkillsCardVersion, r.UpToLine, r.ComputedAt))
+	} else {
+		out = append(out, cardHeader{key: "agents_and_skills"})
+	}
+	if r := c.Redactions; r != nil {
+		out = append(out, h("redactions", true, r.Version, RedactionsCardVersion, r.UpToLine, r.ComputedAt))
+	} else {
+		out = append(out, cardHeader{key: "redactions"})
+	}
+	if r := c.Workflows; r != nil {
+		out = append(out, h("workflows", true, r.Version, WorkflowsCardVersion, r.UpToLine, r.ComputedAt))
+	} else {
+		out = append(out, cardHeader{key: "workflows"})
+	}
+	if !c.gatedOff["code_changes"] {
+		if r := c.CodeChanges; r != nil {
+			out = append(out, h("code_changes", true, r.Version, CodeChangesCardVersion, r.UpToLine, r.ComputedAt))
+		} else {
+			out = append(out, cardHeader{key: "code_changes"})
+		}
+	}
+	return out
+}
+
+// setComputedAt stamps every present card with t.
+func (c *Cards) setComputedAt(t time.Time) {
+	if r := c.TokensV2; r != nil {
+		r.ComputedAt = t
+	}
+	if r := c.Session; r != nil {
+		r.ComputedAt = t
+	}
+	if r := c.Tools; r != nil {
+		r.ComputedAt = t
+	}
+	if r := c.CodeActivity; r != nil {
+		r.ComputedAt = t
+	}
+	if r := c.Conversation; r != nil {
+		r.ComputedAt = t
+	}
+	if r := c.AgentsAndSkills; r != nil {
+		r.ComputedAt = t
+	}
+	if r := c.Redactions; r != nil {
+		r.ComputedAt = t
+	}
+	if r := c.Workflows; r != nil {
+		r.ComputedAt = t
+	}
+	if r := c.CodeChanges; r != nil {
+		r.ComputedAt = t
+	}
+}
+
+// AllPresent reports whether every regular card has a stored row, whatever
+// its version or line watermark.
+func (c *Cards) AllPresent() bool {
+	if c == nil {
+		return false
+	}
+	for _, h := range c.headers() {
+		if !h.present {
+			return false
+		}
+	}
+	return true
+}
+
+// StaleVersions returns the stored cards whose version is behind the current
+// card version, keyed by response card key. The legacy "tokens" card is
+// derived from tokens_v2 and is reported alongside it. Returns nil when every
+// stored card is current.
+func (c *Cards) StaleVersions() map[string]CardStaleness {
+	if c == nil {
+		return nil
+	}
+	var stale map[string]CardStaleness
+	for _, h := range c.headers() {
+		if !h.present || h.version == h.currentVersion {
+			continue
+		}
+		if stale == nil {
+			stale = make(map[string]CardStaleness)
+		}
+		info := CardStaleness{
+			Stale:          true,
+			StoredVersion:  h.version,
+			CurrentVersion: h.currentVersion,
+			VersionDelta:   h.currentVersion - h.version,
+		}
+		stale[h.key] = info
+		if h.key == "tokens_v2" {
+			stale["tokens"] = info
+		}
+	}
+	return stale
+}
+
+// NeedsRecompute reports whether the precompute worker would pick this card
+// set up on its next poll. It mirrors the WHERE clause of
+// Precomputer.FindStaleSessions for a single session; keep the two in sync.
+// The optional warm-up case (PrecomputeConfig.WarmActiveWindow) is not
+// mirrored: it depends on who is active, not on the card set.
+func (c *Cards) NeedsRecompute(th StalenessThresholds, totalLines int64, firstSeen, now time.Time) bool {
+	if totalLines <= 0 {
+		return false
+	}
+	if !c.AllPresent() {
+		// Case 1: new session with enough content or old enough
+		return totalLines >= th.MinInitialLines || now.Sub(firstSeen) >= th.MinSessionAge
+	}
+
+	headers := c.headers()
+	minUpToLine := headers[0].upToLine
+	minComputedAt := headers[0].computedAt
+	for _, h := range headers {
+		if h.version != h.currentVersion {
+			// Case 2: version mismatch always recomputes
+			return true
+		}
+		if h.upToLine < minUpToLine {
+			minUpToLine = h.upToLine
+		}
+		if h.computedAt.Before(minComputedAt) {
+			minComputedAt = h.computedAt
+		}
+	}
+
+	// Case 3: line gap or time gap meets the percentage-based threshold
+	lineGap := totalLines - minUpToLine
+	if lineGap <= 0 {
+		return false
+	}
+	lineThreshold := max(th.BaseMinLines, int64(float64(minUpToLine)*th.ThresholdPct))
+	if lineGap >= lineThreshold {
+		return true
+	}
+	timeGap := now.Sub(minComputedAt).Seconds()
+	priorDuration := minComputedAt.Sub(firstSeen).Seconds()
+	return timeGap >= max(th.BaseMinTime.Seconds(), priorDuration*th.ThresholdPct)
 }
@@ -145,6 +145,7 @@ func TestSmartRecapCardRecord_IsUpToDate(t *testing.T) {
 }
 
 func TestSmartRecapCardRecord_CanAcquireLock(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
 	tests := []struct {
 		name               string
 		card               *SmartRecapCardRecord
@@ -168,7 +169,7 @@ func TestSmartRecapCardRecord_CanAcquireLock(t *testing.T) {
 		{
 			name: "fresh lock cannot acquire",
 			card: &SmartRecapCardRecord{
-				ComputingStartedAt: timePtr(time.Now().Add(-10 * time.Second)), // started 10 seconds ago
+				ComputingStartedAt: timePtr(now.Add(-10 * time.Second)), // started 10 seconds ago
 			},
 			lockTimeoutSeconds: 60,
 			want:               false,
@@ -176,7 +177,7 @@ func TestSmartRecapCardRecord_CanAcquireLock(t *testing.T) {
 		{
 			name: "stale lock can acquire",
 			card: &SmartRecapCardRecord{
-				ComputingStartedAt: timePtr(time.Now().Add(-120 * time.Second)), // started 2 minutes ago
+				ComputingStartedAt: timePtr(now.Add(-120 * time.Second)), // started 2 minutes ago
 			},
 			lockTimeoutSeconds: 60,
 			want:               true,
@@ -185,7 +186,7 @@ func TestSmartRecapCardRecord_CanAcquireLock(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := tt.card.CanAcquireLock(tt.lockTimeoutSeconds)
+			got := tt.card.CanAcquireLock(tt.lockTimeoutSeconds, now)
 			if got != tt.want {
 				t.Errorf("CanAcquireLock() = %v, want %v", got, tt.want)
 			}
@@ -240,6 +241,10 @@ func TestRegularCardRecord_IsValid(t *testing.T) {
 		{"workflows nil", (*WorkflowsCardRecord)(nil), upTo, false},
 		{"workflows valid", &WorkflowsCardRecord{Version: WorkflowsCardVersion, UpToLine: upTo}, upTo, true},
 		{"workflows wrong version", &WorkflowsCardRecord{Version: WorkflowsCardVersion + 1, UpToLine: upTo}, upTo, false},
+
+		{"code changes nil", (*CodeChangesCardRecord)(nil), upTo, false},
+		{"code changes valid", &CodeChangesCardRecord{Version: CodeChangesCardVersion, UpToLine: upTo}, upTo, true},
+		{"code changes wrong version", &CodeChangesCardRecord{Version: CodeChangesCardVersion + 1, UpToLine: upTo}, upTo, false},
 	}
 
 	for _, tt := range tests {
@@ -264,6 +269,7 @@ func TestCards_AllValid(t *testing.T) {
 		AgentsAndSkills: &AgentsAndSkillsCardRecord{Version: AgentsAndSkillsCardVersion, UpToLine: upTo},
 		Redactions:      &RedactionsCardRecord{Version: RedactionsCardVersion, UpToLine: upTo},
 		Workflows:       &WorkflowsCardRecord{Version: WorkflowsCardVersion, UpToLine: upTo},
+		CodeChanges:     &CodeChangesCardRecord{Version: CodeChangesCardVersion, UpToLine: upTo},
 	}
 
 	if !allFresh.AllValid(upTo) {
@@ -298,3 +304,96 @@ func TestCards_AllValid(t *testing.T) {
 		}
 	})
 }
+
+// freshCardSet returns a complete card set at the current versions.
+func freshCardSet(upTo int64, computedAt time.Time) *Cards {
+	return &Cards{
+		TokensV2:        &TokensV2CardRecord{Version: TokensV2CardVersion, UpToLine: upTo, ComputedAt: computedAt},
+		Session:         &SessionCardRecord{Version: SessionCardVersion, UpToLine: upTo, ComputedAt: computedAt},
+		Tools:           &ToolsCardRecord{Version: ToolsCardVersion, UpToLine: upTo, ComputedAt: computedAt},
+		CodeActivity:    &CodeActivityCardRecord{Version: CodeActivityCardVersion, UpToLine: upTo, ComputedAt: computedAt},
+		Conversation:    &ConversationCardRecord{Version: ConversationCardVersion, UpToLine: upTo, ComputedAt: computedAt},
+		AgentsAndSkills: &AgentsAndSkillsCardRecord{Version: AgentsAndSkillsCardVersion, UpToLine: upTo, ComputedAt: computedAt},
+		Redactions:      &RedactionsCardRecord{Version: RedactionsCardVersion, UpToLine: upTo, ComputedAt: computedAt},
+		Workflows:       &WorkflowsCardRecord{Version: WorkflowsCardVersion, UpToLine: upTo, ComputedAt: computedAt},
+		CodeChanges:     &CodeChangesCardRecord{Version: CodeChangesCardVersion, UpToLine: upTo, ComputedAt: computedAt},
+	}
+}
+
+func TestCards_StaleVersions(t *testing.T) {
+	c := freshCardSet(50, time.Now())
+	if !c.AllPresent() {
+		t.Fatal("AllPresent should be true for a complete set")
+	}
+	if got := c.StaleVersions(); got != nil {
+		t.Fatalf("StaleVersions = %v, want nil for current versions", got)
+	}
+
+	c.Conversation.Version = ConversationCardVersion - 2
+	c.TokensV2.Version = TokensV2CardVersion - 1
+	got := c.StaleVersions()
+	if len(got) != 3 {
+		t.Fatalf("StaleVersions = %v, want conversation, tokens_v2 and tokens", got)
+	}
+	if conv := got["conversation"]; !conv.Stale || conv.StoredVersion != ConversationCardVersion-2 || conv.VersionDelta != 2 {
+		t.Errorf("conversation = %+v, want stale with delta 2", conv)
+	}
+	if got["tokens"] != got["tokens_v2"] {
+		t.Errorf("tokens = %+v, want mirror of tokens_v2 %+v", got["tokens"], got["tokens_v2"])
+	}
+
+	c.Tools = nil
+	if c.AllPresent() {
+		t.Error("AllPresent should be false when Tools is nil")
+	}
+	if (*Cards)(nil).AllPresent() || (*Cards)(nil).StaleVersions() != nil {
+		t.Error("nil Cards should be neither present nor stale")
+	}
+}
+
+func TestCards_NeedsRecompute(t *testing.T) {
+	th := StalenessThresholds{
+		ThresholdPct:    0.20,
+		BaseMinLines:    5,
+		BaseMinTime:     3 * time.Minute,
+		MinInitialLines: 10,
+		MinSessionAge:   10 * time.Minute,
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	firstSeen := now.Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		cards      func() *Cards
+		totalLines int64
+		firstSeen  time.Time
+		want       bool
+	}{
+		{"no cards, small and young", func() *Cards { return nil }, 5, now.Add(-time.Minute), false},
+		{"no cards, enough lines", func() *Cards { return nil }, 10, now.Add(-time.Minute), true},
+		{"no cards, old enough", func() *Cards { return nil }, 5, firstSeen, true},
+		{"version mismatch", func() *Cards {
+			c := freshCardSet(100, now)
+			c.Workflows.Version = WorkflowsCardVersion - 1
+			return c
+		}, 100, firstSeen, true},
+		{"up to date", func() *Cards { return freshCardSet(100, now) }, 100, firstSeen, false},
+		{"line gap below pct threshold", func() *Cards { return freshCardSet(100, now) }, 119, firstSeen, false},
+		{"line gap meets pct threshold", func() *Cards { return freshCardSet(100, now) }, 120, firstSeen, true},
+		{"line gap meets base floor", func() *Cards { return freshCardSet(10, now) }, 15, firstSeen, true},
+		{"time gap meets threshold", func() *Cards {
+			// prior duration 10m → threshold MAX(3m, 2m) = 3m; gap is 5m
+			return freshCardSet(100, now.Add(-5*time.Minute))
+		}, 101, now.Add(-15 * time.Minute), true},
+		{"time gap without new lines", func() *Cards {
+			return freshCardSet(100, now.Add(-time.Hour))
+		}, 100, firstSeen, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cards().NeedsRecompute(th, tt.totalLines, tt.firstSeen, now); got != tt.want {
+				t.Errorf("NeedsRecompute = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -30,7 +30,8 @@ func ComputeFromJSONL(ctx context.Context, content []byte) (*ComputeResult, erro
 
 // ComputeFromFileCollection computes analytics from a FileCollection.
 // Delegates to ComputeStreaming with an adapter that yields agents from the in-memory collection.
-// sessionAt defaults to time.Time{} (zero value, before Sep 1 2026) for this convenience path.
+// sessionAt defaults to time.Time{} (zero value, before Sep 1 2026) for this convenience path,
+// and the cwd to the one the transcript records.
 func ComputeFromFileCollection(ctx context.Context, fc *FileCollection) (*ComputeResult, error) {
 	idx := 0
 	agentProvider := func(_ context.Context) (*TranscriptFile, error) {
@@ -42,7 +43,7 @@ func ComputeFromFileCollection(ctx context.Context, fc *FileCollection) (*Comput
 		return agent, nil
 	}
 
-	return ComputeStreaming(ctx, fc.Main, agentProvider, nil, time.Time{})
+	return ComputeStreaming(ctx, fc.Main, agentProvider, nil, time.Time{}, "")
 }
 
 // WorkflowInputs carries the side data the WorkflowsAnalyzer needs that the
@@ -65,7 +66,11 @@ type WorkflowInputs struct {
 //
 // sessionAt is the session's first_seen timestamp for date-aware pricing.
 // A zero time.Time is safe and routes to introductory pricing (before Sep 1 2026).
-func ComputeStreaming(ctx context.Context, main *TranscriptFile, agentProvider AgentProvider, wf *WorkflowInputs, sessionAt time.Time) (*ComputeResult, error) {
+//
+// cwd is the session's reported working directory, against which the code
+// activity card flags out-of-tree file access. Empty falls back to the cwd on
+// the main transcript's first line that has one.
+func ComputeStreaming(ctx context.Context, main *TranscriptFile, agentProvider AgentProvider, wf *WorkflowInputs, sessionAt time.Time, cwd string) (*ComputeResult, error) {
 	ctx, span := tracer.Start(ctx, "analytics.compute_streaming",
 		trace.WithAttributes(
 			attribute.Int64("main.lines", int64(len(main.Lines))),
@@ -80,11 +85,14 @@ func ComputeStreaming(ctx context.Context, main *TranscriptFile, agentProvider A
 	tokensAnalyzer := &TokensAnalyzer{log: log, sessionAt: sessionAt}
 	sessionAnalyzer := &SessionAnalyzer{}
 	toolsAnalyzer := &ToolsAnalyzer{}
-	codeActivityAnalyzer := &CodeActivityAnalyzer{}
+	codeActivityAnalyzer := &CodeActivityAnalyzer{cwd: cwd}
 	conversationAnalyzer := &ConversationAnalyzer{}
 	agentsAnalyzer := &AgentsAnalyzer{}
 	skillsAnalyzer := &SkillsAnalyzer{}
 	redactionsAnalyzer := &RedactionsAnalyzer{}
+	tokenSeriesAnalyzer := &TokenSeriesAnalyzer{}
+	codeChangesAnalyzer := &CodeChangesAnalyzer{}
+	prURLAnalyzer := &PRURLAnalyzer{}
 
 	processors := []FileProcessor{
 		tokensAnalyzer,
@@ -95,6 +103,9 @@ func ComputeStreaming(ctx context.Context, main *TranscriptFile, agentProvider A
 		agentsAnalyzer,
 		skillsAnalyzer,
 		redactionsAnalyzer,
+		tokenSeriesAnalyzer,
+		codeChangesAnalyzer,
+		prURLAnalyzer,
 	}
 
 	// Phase 1: Process main file through all analyzers
@@ -193,6 +204,9 @@ func ComputeStreaming(ctx context.Context, main *TranscriptFile, agentProvider A
 		CompactionManual:    session.CompactionManual,
 		CompactionAvgTimeMs: session.CompactionAvgTimeMs,
 
+		TimestampAnomalies:        session.TimestampAnomalies,
+		FirstTimestampAnomalyLine: session.FirstTimestampAnomalyLine,
+
 		// Tools
 		TotalToolCalls: tools.TotalCalls,
 		ToolStats:      tools.ToolStats,
@@ -205,6 +219,8 @@ func ComputeStreaming(ctx context.Context, main *TranscriptFile, agentProvider A
 		LinesRemoved:      codeActivity.LinesRemoved,
 		SearchCount:       codeActivity.SearchCount,
 		LanguageBreakdown: codeActivity.LanguageBreakdown,
+		OutOfTree:         codeActivity.OutOfTree,
+		CodeFiles:         codeActivity.Files,
 
 		// Conversation
 		UserTurns:                conversation.UserTurns,
@@ -218,6 +234,7 @@ func ComputeStreaming(ctx context.Context, main *TranscriptFile, agentProvider A
 		// Agents and skills
 		TotalAgentInvocations: agents.TotalInvocations,
 		AgentStats:            agents.AgentStats,
+		AgentCalls:            agents.AgentCalls,
 		TotalSkillInvocations: skills.TotalInvocations,
 		SkillStats:            skills.SkillStats,
 
@@ -228,6 +245,15 @@ func ComputeStreaming(ctx context.Context, main *TranscriptFile, agentProvider A
 		// Workflows
 		Workflows: workflowRuns,
 
+		// Token series
+		TokenSeries: tokenSeriesAnalyzer.Result(),
+
+		// Code changes
+		CodeChanges: codeChangesAnalyzer.Result(),
+
+		// Pull request
+		DetectedPRURL: prURLAnalyzer.Result(),
+
 		// Metadata
 		ValidationErrorCount: validationErrorCount,
 		SkippedAgentFiles:    skippedAgentFiles,
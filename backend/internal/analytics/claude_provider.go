@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"context"
+	"database/sql"
 	"io"
 	"log/slog"
 	"time"
@@ -25,6 +26,8 @@ type claudeRollout struct {
 	// createdAt is the session's first_seen timestamp, forwarded to ComputeStreaming
 	// for date-aware pricing (e.g. Sonnet 5 introductory rates).
 	createdAt    time.Time
+	// cwd is the session's reported working directory (sessions.cwd).
+	cwd string
 }
 
 // WorkflowJournalInfo describes a workflow run journal file to download.
@@ -45,6 +48,11 @@ func (p *claudeProvider) Parse(ctx context.Context, input ParseInput) (Rollout,
 	if main == nil {
 		return nil, nil
 	}
+	var cwd string
+	if err := input.DB.QueryRowContext(ctx,
+		`SELECT COALESCE(cwd, '') FROM sessions WHERE id = $1`, input.SessionID).Scan(&cwd); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
 	downloader := func(ctx context.Context, fileName string) ([]byte, error) {
 		return input.Store.DownloadAndMergeChunks(ctx, input.UserID, input.Provider, input.ExternalID, fileName)
 	}
@@ -54,15 +62,28 @@ func (p *claudeProvider) Parse(ctx context.Context, input ParseInput) (Rollout,
 		journalInfo: journalInfo,
 		downloader:  downloader,
 		createdAt:   input.CreatedAt,
+		cwd:         cwd,
 	}, nil
 }
 
 func (p *claudeProvider) ComputeCards(ctx context.Context, rollout Rollout) *ComputeResult {
 	r := rollout.(*claudeRollout)
-	computed, err := ComputeStreaming(ctx, r.main, r.agentProvider(ctx), r.buildWorkflowInputs(ctx), r.createdAt)
+	computed, err := ComputeStreaming(ctx, r.main, r.agentProvider(ctx), r.buildWorkflowInputs(ctx), r.createdAt, r.cwd)
 	if err != nil {
 		return &ComputeResult{CardErrors: map[string]string{"compute": err.Error()}}
 	}
+	turns, err := ComputeConversationTurns(&FileCollection{Main: r.main})
+	if err != nil {
+		slog.Warn("failed to compute conversation turns", "error", err)
+	} else {
+		computed.ConversationTurns = turns
+	}
+	events, err := ComputeRedactionEvents(&FileCollection{Main: r.main})
+	if err != nil {
+		slog.Warn("failed to compute redaction events", "error", err)
+	} else {
+		computed.RedactionEvents = events
+	}
 	return computed
 }
 
@@ -101,6 +122,11 @@ func (p *claudeProvider) SearchText(ctx context.Context, rollout Rollout) string
 	return umb.Finish()
 }
 
+func (p *claudeProvider) AgentSearchText(ctx context.Context, rollout Rollout, maxBytes int) string {
+	r := rollout.(*claudeRollout)
+	return ExtractAgentOutputText(r.materializeAgents(ctx), maxBytes)
+}
+
 func (p *claudeProvider) PrepareTranscript(ctx context.Context, rollout Rollout) (string, map[int]string, error) {
 	r := rollout.(*claudeRollout)
 	tb := NewTranscriptBuilder(DefaultFormatConfig())
@@ -112,6 +138,13 @@ func (p *claudeProvider) PrepareTranscript(ctx context.Context, rollout Rollout)
 	return transcript, idMap, nil
 }
 
+// ToolCalls implements ToolCallLister over the main transcript and every
+// agent file.
+func (p *claudeProvider) ToolCalls(ctx context.Context, rollout Rollout) ([]ToolCall, error) {
+	r := rollout.(*claudeRollout)
+	return ListClaudeToolCalls(r.main, r.materializeAgents(ctx)), nil
+}
+
 func (p *claudeProvider) ClearMessageIDs() bool { return false }
 func (p *claudeProvider) DisplayName() string   { return "Claude Code" }
 
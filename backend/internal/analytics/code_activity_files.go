@@ -0,0 +1,136 @@
+package analytics
+
+import (
+	"cmp"
+	"slices"
+)
+
+// MaxCodeActivityFiles caps the files stored per session on the code activity
+// card. Past it the most active files are kept (see codeFileTracker.Result).
+const MaxCodeActivityFiles = 500
+
+// CodeFileStats is one file's activity within a session. Language uses the
+// same key as the card's language_breakdown and is empty for files without a
+// recognized extension.
+type CodeFileStats struct {
+	FilePath     string `json:"file_path"`
+	Language     string `json:"language"`
+	ReadCount    int    `json:"read_count"`
+	WriteCount   int    `json:"write_count"`
+	LinesAdded   int    `json:"lines_added"`
+	LinesRemoved int    `json:"lines_removed"`
+}
+
+// codeFileTracker accumulates per-file read/write counts across a session's
+// tool calls. The zero value is ready to use.
+type codeFileTracker struct {
+	files map[string]*CodeFileStats
+}
+
+func (t *codeFileTracker) file(path, language string) *CodeFileStats {
+	if t.files == nil {
+		t.files = make(map[string]*CodeFileStats)
+	}
+	f, ok := t.files[path]
+	if !ok {
+		f = &CodeFileStats{FilePath: path, Language: language}
+		t.files[path] = f
+	}
+	return f
+}
+
+// read records one read of path.
+func (t *codeFileTracker) read(path, language string) {
+	t.file(path, language).ReadCount++
+}
+
+// write records one write (create, edit or delete) of path and its line delta.
+func (t *codeFileTracker) write(path, language string, added, removed int) {
+	f := t.file(path, language)
+	f.WriteCount++
+	f.LinesAdded += added
+	f.LinesRemoved += removed
+}
+
+// Result returns the tracked files sorted by SortCodeFilesByWrites, keeping
+// the first MaxCodeActivityFiles.
+func (t *codeFileTracker) Result() []CodeFileStats {
+	out := make([]CodeFileStats, 0, len(t.files))
+	for _, f := range t.files {
+		out = append(out, *f)
+	}
+	sortCodeFiles(out, SortCodeFilesByWrites)
+	if len(out) > MaxCodeActivityFiles {
+		out = out[:MaxCodeActivityFiles]
+	}
+	return out
+}
+
+// Code activity file orderings accepted by SelectCodeFiles.
+const (
+	SortCodeFilesByWrites = "writes_desc"
+	SortCodeFilesByReads  = "reads_desc"
+	SortCodeFilesByLines  = "lines_desc" // lines added + removed
+	SortCodeFilesByPath   = "path_asc"
+)
+
+// IsValidCodeFilesSort reports whether sort is a known code activity file
+// ordering.
+func IsValidCodeFilesSort(sort string) bool {
+	switch sort {
+	case SortCodeFilesByWrites, SortCodeFilesByReads, SortCodeFilesByLines, SortCodeFilesByPath:
+		return true
+	}
+	return false
+}
+
+// sortCodeFiles orders files in place. Ties fall back to writes, then reads,
+// then path, so every ordering is deterministic.
+func sortCodeFiles(files []CodeFileStats, sort string) {
+	slices.SortFunc(files, func(a, b CodeFileStats) int {
+		var c int
+		switch sort {
+		case SortCodeFilesByReads:
+			c = cmp.Compare(b.ReadCount, a.ReadCount)
+		case SortCodeFilesByLines:
+			c = cmp.Compare(b.LinesAdded+b.LinesRemoved, a.LinesAdded+a.LinesRemoved)
+		case SortCodeFilesByPath:
+			return cmp.Compare(a.FilePath, b.FilePath)
+		}
+		if c != 0 {
+			return c
+		}
+		if c = cmp.Compare(b.WriteCount, a.WriteCount); c != 0 {
+			return c
+		}
+		if c = cmp.Compare(b.ReadCount, a.ReadCount); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.FilePath, b.FilePath)
+	})
+}
+
+// CodeFilesQuery selects and orders the files of a code activity card.
+type CodeFilesQuery struct {
+	Language string // "" for all languages
+	Sort     string // one of the SortCodeFilesBy* orderings
+	Limit    int
+}
+
+// SelectCodeFiles filters files by language, orders them and returns the
+// first q.Limit, with the number that matched the filter. files is not
+// modified.
+func SelectCodeFiles(files []CodeFileStats, q CodeFilesQuery) (selected []CodeFileStats, total int) {
+	selected = make([]CodeFileStats, 0, len(files))
+	for _, f := range files {
+		if q.Language == "" || f.Language == q.Language {
+			selected = append(selected, f)
+		}
+	}
+	total = len(selected)
+	sortCodeFiles(selected, q.Sort)
+	if len(selected) > q.Limit {
+		selected = selected[:q.Limit]
+	}
+	return selected, total
+}
@@ -0,0 +1,136 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/codex"
+)
+
+func TestCodeFileTracker_ResultOrderAndCap(t *testing.T) {
+	var tr codeFileTracker
+	tr.read("/a.go", "go")
+	tr.read("/a.go", "go")
+	tr.write("/b.go", "go", 3, 1)
+	tr.write("/b.go", "go", 2, 0)
+	tr.read("/c.md", "md")
+	tr.write("/c.md", "md", 1, 1)
+
+	got := tr.Result()
+	want := []CodeFileStats{
+		{FilePath: "/b.go", Language: "go", WriteCount: 2, LinesAdded: 5, LinesRemoved: 1},
+		{FilePath: "/c.md", Language: "md", ReadCount: 1, WriteCount: 1, LinesAdded: 1, LinesRemoved: 1},
+		{FilePath: "/a.go", Language: "go", ReadCount: 2},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Result() = %+v, want %+v", got, want)
+	}
+
+	var big codeFileTracker
+	for i := range MaxCodeActivityFiles + 10 {
+		big.read(fmt.Sprintf("/r%03d.go", i), "go")
+	}
+	big.write("/written.go", "go", 1, 0)
+	files := big.Result()
+	if len(files) != MaxCodeActivityFiles {
+		t.Fatalf("len(Result()) = %d, want %d", len(files), MaxCodeActivityFiles)
+	}
+	if files[0].FilePath != "/written.go" {
+		t.Errorf("first file = %s, want the written one kept ahead of reads", files[0].FilePath)
+	}
+}
+
+func TestSelectCodeFiles(t *testing.T) {
+	files := []CodeFileStats{
+		{FilePath: "/z.go", Language: "go", ReadCount: 5, WriteCount: 1, LinesAdded: 1},
+		{FilePath: "/a.go", Language: "go", ReadCount: 1, WriteCount: 3, LinesAdded: 2, LinesRemoved: 2},
+		{FilePath: "/m.ts", Language: "ts", WriteCount: 2, LinesAdded: 40},
+		{FilePath: "/b.go", Language: "go", ReadCount: 1, WriteCount: 3},
+	}
+	paths := func(fs []CodeFileStats) string {
+		var s []string
+		for _, f := range fs {
+			s = append(s, f.FilePath)
+		}
+		return fmt.Sprint(s)
+	}
+
+	tests := []struct {
+		name      string
+		q         CodeFilesQuery
+		want      string
+		wantTotal int
+	}{
+		{"writes with ties", CodeFilesQuery{Sort: SortCodeFilesByWrites, Limit: 10}, "[/a.go /b.go /m.ts /z.go]", 4},
+		{"reads", CodeFilesQuery{Sort: SortCodeFilesByReads, Limit: 10}, "[/z.go /a.go /b.go /m.ts]", 4},
+		{"lines", CodeFilesQuery{Sort: SortCodeFilesByLines, Limit: 10}, "[/m.ts /a.go /z.go /b.go]", 4},
+		{"path", CodeFilesQuery{Sort: SortCodeFilesByPath, Limit: 10}, "[/a.go /b.go /m.ts /z.go]", 4},
+		{"language", CodeFilesQuery{Language: "go", Sort: SortCodeFilesByReads, Limit: 10}, "[/z.go /a.go /b.go]", 3},
+		{"limit", CodeFilesQuery{Language: "go", Sort: SortCodeFilesByWrites, Limit: 1}, "[/a.go]", 3},
+		{"unknown language", CodeFilesQuery{Language: "rust", Sort: SortCodeFilesByWrites, Limit: 10}, "[]", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total := SelectCodeFiles(files, tt.q)
+			if paths(got) != tt.want || total != tt.wantTotal {
+				t.Errorf("SelectCodeFiles() = %s (total %d), want %s (total %d)", paths(got), total, tt.want, tt.wantTotal)
+			}
+		})
+	}
+	if files[0].FilePath != "/z.go" {
+		t.Error("SelectCodeFiles reordered its input")
+	}
+}
+
+func TestCodeActivityAnalyzer_Files(t *testing.T) {
+	line := func(uuid, parent, content string) string {
+		return `{"type":"assistant","message":{"id":"msg_` + uuid + `","type":"message","model":"claude-sonnet-4-20241022","role":"assistant","content":` + content + `,"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":1,"output_tokens":1}},"uuid":"` + uuid + `","timestamp":"2025-01-01T00:00:01Z","parentUuid":"` + parent + `","isSidechain":false,"userType":"external","cwd":"/app","sessionId":"s","version":"1.0"}` + "\n"
+	}
+	content := `{"type":"user","message":{"role":"user","content":"go"},"uuid":"u1","timestamp":"2025-01-01T00:00:00Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/app","sessionId":"s","version":"1.0"}` + "\n" +
+		line("a1", "u1", `[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/app/main.go"}},{"type":"tool_use","id":"t2","name":"Edit","input":{"file_path":"/app/main.go","old_string":"a\nb","new_string":"a\nc\nd"}}]`) +
+		line("a2", "a1", `[{"type":"tool_use","id":"t3","name":"Write","input":{"file_path":"/app/README","content":"one\ntwo\n"}},{"type":"tool_use","id":"t4","name":"Grep","input":{"pattern":"x"}}]`)
+	result, err := ComputeFromJSONL(context.Background(), []byte(content))
+	if err != nil {
+		t.Fatalf("ComputeFromJSONL: %v", err)
+	}
+	want := []CodeFileStats{
+		{FilePath: "/app/main.go", Language: "go", ReadCount: 1, WriteCount: 1, LinesAdded: 3, LinesRemoved: 2},
+		{FilePath: "/app/README", Language: "", WriteCount: 1, LinesAdded: 2},
+	}
+	if fmt.Sprint(result.CodeFiles) != fmt.Sprint(want) {
+		t.Errorf("CodeFiles = %+v, want %+v", result.CodeFiles, want)
+	}
+	if cards := result.ToCards("s", 3); fmt.Sprint(cards.CodeActivity.CodeFiles) != fmt.Sprint(want) {
+		t.Errorf("card CodeFiles = %+v, want %+v", cards.CodeActivity.CodeFiles, want)
+	}
+}
+
+func TestComputeFromCodexRollout_ApplyPatchFiles(t *testing.T) {
+	r := &codex.ParsedRollout{
+		Model: "gpt-5",
+		Turns: []codex.Turn{{
+			TurnID: "t1",
+			ToolCalls: []codex.ToolCall{
+				{
+					Name:      "apply_patch",
+					Arguments: "*** Begin Patch\n*** Add File: foo.go\n+package foo\n+\n*** Update File: bar.py\n-old\n+new\n*** End Patch",
+					Status:    "completed",
+				},
+				{
+					Name:      "apply_patch",
+					Arguments: "*** Begin Patch\n*** Update File: foo.go\n-package foo\n+package bar\n+// x\n*** End Patch",
+					Status:    "completed",
+				},
+			},
+		}},
+	}
+	out := ComputeFromCodexRollout(context.Background(), []*codex.ParsedRollout{r})
+	want := []CodeFileStats{
+		{FilePath: "foo.go", Language: "go", WriteCount: 2, LinesAdded: 4, LinesRemoved: 1},
+		{FilePath: "bar.py", Language: "python", WriteCount: 1, LinesAdded: 1, LinesRemoved: 1},
+	}
+	if fmt.Sprint(out.CodeFiles) != fmt.Sprint(want) {
+		t.Errorf("CodeFiles = %+v, want %+v", out.CodeFiles, want)
+	}
+}
@@ -65,16 +65,18 @@ func ComputeFromCodexRollout(ctx context.Context, rollouts []*codex.ParsedRollou
 
 	// Remaining analyzers accumulate via += on result fields, so per-rollout
 	// dispatch produces the cross-rollout total.
+	var files codeFileTracker
 	for _, r := range rollouts {
 		if r == nil {
 			continue
 		}
 		computeCodexTools(result, r)
-		computeCodexCodeActivity(result, r)
+		computeCodexCodeActivity(result, r, &files)
 		computeCodexAgentsAndSkills(result, r)
 		computeCodexRedactions(result, r)
 		result.ValidationErrorCount += len(r.ValidationErrors)
 	}
+	result.CodeFiles = files.Result()
 
 	return result
 }
@@ -99,16 +101,18 @@ func computeFromCodexRolloutAt(ctx context.Context, rollouts []*codex.ParsedRoll
 	computeCodexSession(result, rollouts)
 	computeCodexConversation(result, rollouts[0])
 
+	var files codeFileTracker
 	for _, r := range rollouts {
 		if r == nil {
 			continue
 		}
 		computeCodexTools(result, r)
-		computeCodexCodeActivity(result, r)
+		computeCodexCodeActivity(result, r, &files)
 		computeCodexAgentsAndSkills(result, r)
 		computeCodexRedactions(result, r)
 		result.ValidationErrorCount += len(r.ValidationErrors)
 	}
+	result.CodeFiles = files.Result()
 
 	return result
 }
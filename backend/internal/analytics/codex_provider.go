@@ -67,6 +67,10 @@ func (p *codexProvider) SearchText(ctx context.Context, rollout Rollout) string
 	return ExtractCodexUserMessagesText(r.materialize(ctx))
 }
 
+// AgentSearchText is empty: SearchText already indexes subagent rollouts'
+// final assistant text.
+func (p *codexProvider) AgentSearchText(context.Context, Rollout, int) string { return "" }
+
 func (p *codexProvider) PrepareTranscript(ctx context.Context, rollout Rollout) (string, map[int]string, error) {
 	r := rollout.(*codexRollout)
 	transcript, idMap := PrepareCodexTranscript(r.materialize(ctx))
@@ -53,6 +53,10 @@ type ComputeResult struct {
 	CompactionManual    int
 	CompactionAvgTimeMs *int
 
+	// Timestamp ordering anomalies (from SessionAnalyzer)
+	TimestampAnomalies        int
+	FirstTimestampAnomalyLine *int
+
 	// Tools stats (from ToolsAnalyzer)
 	TotalToolCalls int
 	ToolStats      map[string]*ToolStats
@@ -65,6 +69,8 @@ type ComputeResult struct {
 	LinesRemoved      int
 	SearchCount       int
 	LanguageBreakdown map[string]int
+	OutOfTree         OutOfTreeAccess // Claude Code only
+	CodeFiles         []CodeFileStats // at most MaxCodeActivityFiles, most written first
 
 	// Conversation stats (from ConversationAnalyzer)
 	AvgAssistantTurnMs       *int64
@@ -73,9 +79,19 @@ type ComputeResult struct {
 	TotalUserDurationMs      *int64
 	AssistantUtilizationPct  *float64
 
+	// Per-turn detail (from ComputeConversationTurns). Nil for providers that
+	// don't produce it; persisted separately from the cards.
+	ConversationTurns []ConversationTurn
+
+	// Cumulative token usage across the transcript (from TokenSeriesAnalyzer).
+	// Nil for providers that don't produce it; persisted separately from the
+	// cards.
+	TokenSeries *TokenSeries
+
 	// Agent stats (from AgentsAnalyzer)
 	TotalAgentInvocations int
 	AgentStats            map[string]*AgentStats
+	AgentCalls            []AgentCall // Nil for providers without per-call detail
 
 	// Skill stats (from SkillsAnalyzer)
 	TotalSkillInvocations int
@@ -85,9 +101,22 @@ type ComputeResult struct {
 	TotalRedactions int
 	RedactionCounts map[string]int
 
+	// Per-redaction detail (from ComputeRedactionEvents). Nil for providers
+	// that don't produce it; persisted separately from the cards.
+	RedactionEvents []RedactionEvent
+
 	// Workflow runs (from WorkflowsAnalyzer; empty for non-workflow sessions)
 	Workflows []WorkflowRun
 
+	// Sampled Edit/MultiEdit hunks (from CodeChangesAnalyzer). Nil for
+	// providers without those tools.
+	CodeChanges *CodeChangesResult
+
+	// Pull request opened by the session's gh pr create (from
+	// PRURLAnalyzer); "" when none was found or the provider doesn't
+	// detect PRs.
+	DetectedPRURL string
+
 	// Validation stats (from parsing)
 	ValidationErrorCount int
 
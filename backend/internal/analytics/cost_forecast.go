@@ -0,0 +1,96 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CostForecastTrailingDays is the window of the trailing average daily spend
+// the month-end projection extrapolates from.
+const CostForecastTrailingDays = 7
+
+// CostForecast is the current calendar month's spend so far and where it is
+// headed (GET /api/v1/analytics/forecast). Dates and the month boundary are in
+// the caller's timezone.
+type CostForecast struct {
+	Month       string `json:"month"` // YYYY-MM
+	DaysElapsed int    `json:"days_elapsed"`
+	DaysInMonth int    `json:"days_in_month"`
+
+	ActualToDateUSD string `json:"actual_to_date_usd"`
+	// ProjectedMonthEndUSD is ActualToDateUSD plus TrailingDailyAvgUSD for
+	// each day left in the month.
+	ProjectedMonthEndUSD string `json:"projected_month_end_usd"`
+	// MonthToDateDailyAvgUSD is ActualToDateUSD over DaysElapsed.
+	MonthToDateDailyAvgUSD string `json:"month_to_date_daily_avg_usd"`
+	// TrailingDailyAvgUSD averages the last CostForecastTrailingDays days of
+	// the month so far (fewer early in the month).
+	TrailingDailyAvgUSD string `json:"trailing_daily_avg_usd"`
+
+	// Daily holds one entry per elapsed day, today last.
+	Daily []CostForecastDay `json:"daily"`
+}
+
+// CostForecastDay is one day's estimated spend.
+type CostForecastDay struct {
+	Date    string `json:"date"` // YYYY-MM-DD
+	CostUSD string `json:"cost_usd"`
+}
+
+// CostForecastMonth returns the calendar month containing now in the zone
+// given by tzOffset (minutes, JS getTimezoneOffset convention: positive =
+// behind UTC): its first instant, the first instant of the next month, and
+// the 1-based local day of now.
+func CostForecastMonth(now time.Time, tzOffset int) (start, end time.Time, today int) {
+	shift := time.Duration(tzOffset) * time.Minute
+	local := now.UTC().Add(-shift)
+	first := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return first.Add(shift), first.AddDate(0, 1, 0).Add(shift), local.Day()
+}
+
+// ForecastMonthlyCost projects month-end spend from daily, the spend of each
+// elapsed day of month (daily[0] is the 1st, the last entry is today, counted
+// as a whole day). The remaining days are assumed to cost the trailing
+// average, so a month whose usage has stopped projects to what it has
+// already spent, and one just starting projects from its first day alone.
+func ForecastMonthlyCost(month time.Time, daily []decimal.Decimal) CostForecast {
+	daysInMonth := time.Date(month.Year(), month.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	elapsed := min(len(daily), daysInMonth)
+	daily = daily[:elapsed]
+
+	forecast := CostForecast{
+		Month:       month.Format("2006-01"),
+		DaysElapsed: elapsed,
+		DaysInMonth: daysInMonth,
+		Daily:       make([]CostForecastDay, elapsed),
+	}
+
+	actual := decimal.Zero
+	for i, cost := range daily {
+		actual = actual.Add(cost)
+		forecast.Daily[i] = CostForecastDay{
+			Date:    time.Date(month.Year(), month.Month(), i+1, 0, 0, 0, 0, time.UTC).Format("2006-01-02"),
+			CostUSD: cost.StringFixed(2),
+		}
+	}
+
+	monthAvg, trailingAvg := decimal.Zero, decimal.Zero
+	if elapsed > 0 {
+		monthAvg = actual.Div(decimal.NewFromInt(int64(elapsed)))
+
+		window := daily[max(0, elapsed-CostForecastTrailingDays):]
+		trailing := decimal.Zero
+		for _, cost := range window {
+			trailing = trailing.Add(cost)
+		}
+		trailingAvg = trailing.Div(decimal.NewFromInt(int64(len(window))))
+	}
+	projected := actual.Add(trailingAvg.Mul(decimal.NewFromInt(int64(daysInMonth - elapsed))))
+
+	forecast.ActualToDateUSD = actual.StringFixed(2)
+	forecast.ProjectedMonthEndUSD = projected.StringFixed(2)
+	forecast.MonthToDateDailyAvgUSD = monthAvg.StringFixed(2)
+	forecast.TrailingDailyAvgUSD = trailingAvg.StringFixed(2)
+	return forecast
+}
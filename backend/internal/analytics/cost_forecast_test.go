@@ -0,0 +1,129 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func dollars(values ...string) []decimal.Decimal {
+	out := make([]decimal.Decimal, len(values))
+	for i, v := range values {
+		out[i] = decimal.RequireFromString(v)
+	}
+	return out
+}
+
+func TestForecastMonthlyCost(t *testing.T) {
+	october := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name         string
+		daily        []decimal.Decimal
+		wantActual   string
+		wantProject  string
+		wantMonthAvg string
+		wantTrailing string
+	}{
+		{
+			// First of the month with nothing spent yet.
+			name:         "month start, no history",
+			daily:        dollars("0"),
+			wantActual:   "0.00",
+			wantProject:  "0.00",
+			wantMonthAvg: "0.00",
+			wantTrailing: "0.00",
+		},
+		{
+			// One day in: the 1st's spend stands in for all 31 days.
+			name:         "month start, first day spent",
+			daily:        dollars("2"),
+			wantActual:   "2.00",
+			wantProject:  "62.00",
+			wantMonthAvg: "2.00",
+			wantTrailing: "2.00",
+		},
+		{
+			// Day 10: $1/day for three days, then $3/day for seven. The
+			// trailing week ($3/day) drives the remaining 21 days.
+			name:         "mid-month",
+			daily:        dollars("1", "1", "1", "3", "3", "3", "3", "3", "3", "3"),
+			wantActual:   "24.00",
+			wantProject:  "87.00",
+			wantMonthAvg: "2.40",
+			wantTrailing: "3.00",
+		},
+		{
+			// Heavy early usage, then nothing for the past week: the
+			// projection holds at what was already spent.
+			name:         "usage stopped",
+			daily:        dollars("5", "5", "5", "0", "0", "0", "0", "0", "0", "0", "0", "0"),
+			wantActual:   "15.00",
+			wantProject:  "15.00",
+			wantMonthAvg: "1.25",
+			wantTrailing: "0.00",
+		},
+		{
+			name:         "partial trailing week",
+			daily:        dollars("0", "0", "0", "0", "0", "0", "0", "0", "0", "0", "0", "7"),
+			wantActual:   "7.00",
+			wantProject:  "26.00",
+			wantMonthAvg: "0.58",
+			wantTrailing: "1.00",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ForecastMonthlyCost(october, tc.daily)
+			if got.Month != "2026-10" || got.DaysInMonth != 31 || got.DaysElapsed != len(tc.daily) {
+				t.Errorf("month = %s, days = %d/%d; want 2026-10, %d/31", got.Month, got.DaysElapsed, got.DaysInMonth, len(tc.daily))
+			}
+			if got.ActualToDateUSD != tc.wantActual || got.ProjectedMonthEndUSD != tc.wantProject {
+				t.Errorf("actual, projected = %s, %s; want %s, %s", got.ActualToDateUSD, got.ProjectedMonthEndUSD, tc.wantActual, tc.wantProject)
+			}
+			if got.MonthToDateDailyAvgUSD != tc.wantMonthAvg || got.TrailingDailyAvgUSD != tc.wantTrailing {
+				t.Errorf("month avg, trailing avg = %s, %s; want %s, %s", got.MonthToDateDailyAvgUSD, got.TrailingDailyAvgUSD, tc.wantMonthAvg, tc.wantTrailing)
+			}
+			if len(got.Daily) != len(tc.daily) {
+				t.Fatalf("daily series has %d entries, want %d", len(got.Daily), len(tc.daily))
+			}
+			if got.Daily[0].Date != "2026-10-01" {
+				t.Errorf("first day = %s, want 2026-10-01", got.Daily[0].Date)
+			}
+		})
+	}
+
+	t.Run("last day of the month projects to the actual", func(t *testing.T) {
+		daily := make([]decimal.Decimal, 28)
+		for i := range daily {
+			daily[i] = decimal.NewFromInt(1)
+		}
+		got := ForecastMonthlyCost(time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC), daily)
+		if got.DaysInMonth != 28 || got.ProjectedMonthEndUSD != "28.00" || got.Daily[27].Date != "2026-02-28" {
+			t.Errorf("got %d days, projected %s, last date %s", got.DaysInMonth, got.ProjectedMonthEndUSD, got.Daily[27].Date)
+		}
+	})
+}
+
+func TestCostForecastMonth(t *testing.T) {
+	// 2026-11-01 03:00 UTC is still October 31 in New York (UTC-4, tz_offset 240).
+	now := time.Date(2026, time.November, 1, 3, 0, 0, 0, time.UTC)
+
+	start, end, today := CostForecastMonth(now, 240)
+	if want := time.Date(2026, time.October, 1, 4, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("start = %s, want %s", start, want)
+	}
+	if want := time.Date(2026, time.November, 1, 4, 0, 0, 0, time.UTC); !end.Equal(want) {
+		t.Errorf("end = %s, want %s", end, want)
+	}
+	if today != 31 {
+		t.Errorf("today = %d, want 31", today)
+	}
+
+	start, _, today = CostForecastMonth(now, 0)
+	if want := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC); !start.Equal(want) || today != 1 {
+		t.Errorf("UTC: start = %s, today = %d; want %s, 1", start, today, want)
+	}
+}
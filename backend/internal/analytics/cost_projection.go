@@ -0,0 +1,92 @@
+package analytics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// MinCostProjectionLines is the fewest synced lines a session needs before
+// its cost rate is trusted enough to project from.
+const MinCostProjectionLines = 20
+
+// CostProjectionHistorySize is how many of the owner's earlier sessions are
+// sampled to estimate how long a session usually runs.
+const CostProjectionHistorySize = 10
+
+// Percentile points for the completion estimate and the low end of its
+// interval (the high end reuses pctP90).
+var (
+	pctP25 = decimal.RequireFromString("0.25")
+	pctP75 = decimal.RequireFromString("0.75")
+)
+
+// CostProjection estimates what a session will cost once it finishes, assuming
+// it keeps spending at its current per-line rate until it reaches the owner's
+// typical session length.
+type CostProjection struct {
+	// ProjectedTotalCostUSD is the current per-line rate times
+	// EstimatedCompletionLines, as a 2-decimal string.
+	ProjectedTotalCostUSD string `json:"projected_total_cost_usd"`
+	// EstimatedCompletionLines is the P75 of the owner's recent final line
+	// counts, or the current line count when the session is already longer.
+	EstimatedCompletionLines int64 `json:"estimated_completion_lines"`
+	// ConfidenceInterval spans the same projection at the P25 and P90 lengths.
+	ConfidenceInterval CostProjectionInterval `json:"confidence_interval"`
+}
+
+// CostProjectionInterval bounds a projected cost.
+type CostProjectionInterval struct {
+	LowUSD  string `json:"low_usd"`
+	HighUSD string `json:"high_usd"`
+}
+
+// CostProjectionRecord is a stored projection (session_card_cost_projection).
+// Projection is nil when the session had too few lines or its owner had no
+// earlier sessions to learn a typical length from.
+type CostProjectionRecord struct {
+	ComputedAt          *time.Time      `json:"computed_at,omitempty"`
+	UpToLine            int64           `json:"up_to_line"`
+	HistorySessionCount int             `json:"history_session_count"`
+	Projection          *CostProjection `json:"projection"`
+}
+
+// ProjectCost extrapolates totalCostUSD (spent over upToLine lines) to the
+// lengths in history, the final line counts of the owner's earlier sessions.
+// Returns nil when upToLine is below MinCostProjectionLines, history is empty,
+// or the cost does not parse.
+func ProjectCost(totalCostUSD string, upToLine int64, history []int64) *CostProjection {
+	if upToLine < MinCostProjectionLines || len(history) == 0 {
+		return nil
+	}
+	cost, err := decimal.NewFromString(totalCostUSD)
+	if err != nil {
+		return nil
+	}
+	rate := cost.Div(decimal.NewFromInt(upToLine))
+
+	sorted := make([]decimal.Decimal, len(history))
+	for i, lines := range history {
+		sorted[i] = decimal.NewFromInt(lines)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].LessThan(sorted[j]) })
+
+	// A session never finishes shorter than it already is.
+	linesAt := func(p decimal.Decimal) int64 {
+		return max(percentileCont(sorted, p).Ceil().IntPart(), upToLine)
+	}
+	costAt := func(lines int64) string {
+		return rate.Mul(decimal.NewFromInt(lines)).StringFixed(2)
+	}
+
+	completion := linesAt(pctP75)
+	return &CostProjection{
+		ProjectedTotalCostUSD:    costAt(completion),
+		EstimatedCompletionLines: completion,
+		ConfidenceInterval: CostProjectionInterval{
+			LowUSD:  costAt(linesAt(pctP25)),
+			HighUSD: costAt(linesAt(pctP90)),
+		},
+	}
+}
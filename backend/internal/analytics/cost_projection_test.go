@@ -0,0 +1,90 @@
+package analytics
+
+import "testing"
+
+func TestProjectCost(t *testing.T) {
+	t.Run("not enough data", func(t *testing.T) {
+		cases := []struct {
+			name     string
+			cost     string
+			upToLine int64
+			history  []int64
+		}{
+			{"fewer than 20 lines", "1.00", MinCostProjectionLines - 1, []int64{100}},
+			{"no earlier sessions", "1.00", 50, nil},
+			{"unparseable cost", "n/a", 50, []int64{100}},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				if got := ProjectCost(tc.cost, tc.upToLine, tc.history); got != nil {
+					t.Errorf("ProjectCost() = %+v, want nil", got)
+				}
+			})
+		}
+	})
+
+	cases := []struct {
+		name     string
+		cost     string
+		upToLine int64
+		history  []int64
+		want     CostProjection
+	}{
+		{
+			// $0.02/line; P25/P75/P90 of 100..400 are 175/325/370 lines.
+			name:     "extrapolates to the owner's percentiles",
+			cost:     "1.00",
+			upToLine: 50,
+			history:  []int64{400, 100, 300, 200},
+			want: CostProjection{
+				ProjectedTotalCostUSD:    "6.50",
+				EstimatedCompletionLines: 325,
+				ConfidenceInterval:       CostProjectionInterval{LowUSD: "3.50", HighUSD: "7.40"},
+			},
+		},
+		{
+			name:     "single earlier session",
+			cost:     "0.5",
+			upToLine: 20,
+			history:  []int64{40},
+			want: CostProjection{
+				ProjectedTotalCostUSD:    "1.00",
+				EstimatedCompletionLines: 40,
+				ConfidenceInterval:       CostProjectionInterval{LowUSD: "1.00", HighUSD: "1.00"},
+			},
+		},
+		{
+			name:     "already longer than usual keeps the current cost",
+			cost:     "10",
+			upToLine: 500,
+			history:  []int64{100, 200, 300},
+			want: CostProjection{
+				ProjectedTotalCostUSD:    "10.00",
+				EstimatedCompletionLines: 500,
+				ConfidenceInterval:       CostProjectionInterval{LowUSD: "10.00", HighUSD: "10.00"},
+			},
+		},
+		{
+			name:     "free session projects zero",
+			cost:     "0",
+			upToLine: 30,
+			history:  []int64{90},
+			want: CostProjection{
+				ProjectedTotalCostUSD:    "0.00",
+				EstimatedCompletionLines: 90,
+				ConfidenceInterval:       CostProjectionInterval{LowUSD: "0.00", HighUSD: "0.00"},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ProjectCost(tc.cost, tc.upToLine, tc.history)
+			if got == nil {
+				t.Fatal("ProjectCost() = nil")
+			}
+			if *got != tc.want {
+				t.Errorf("ProjectCost() = %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
@@ -119,14 +119,16 @@ func ComputeFromCursorRollout(ctx context.Context, rollouts [][]*CursorMessage,
 
 	// Tools / code activity / agents merge across every rollout — each analyzer
 	// accumulates via +=, so per-rollout dispatch composes the cross-rollout total.
+	var files codeFileTracker
 	for _, messages := range rollouts {
 		if len(messages) == 0 {
 			continue
 		}
 		computeCursorTools(result, messages)
-		computeCursorCodeActivity(result, messages)
+		computeCursorCodeActivity(result, messages, &files)
 		computeCursorAgents(result, messages)
 	}
+	result.CodeFiles = files.Result()
 
 	return result
 }
@@ -223,7 +225,7 @@ func computeCursorTools(out *ComputeResult, messages []*CursorMessage) {
 // Grep/Glob/SemanticSearch; WebSearch is a WEB search and is excluded (Codex
 // precedent). Cursor records no tool outputs, so line counts come from the tool
 // inputs (Write contents, StrReplace old/new strings).
-func computeCursorCodeActivity(out *ComputeResult, messages []*CursorMessage) {
+func computeCursorCodeActivity(out *ComputeResult, messages []*CursorMessage, files *codeFileTracker) {
 	for _, msg := range messages {
 		if msg.Role != "assistant" {
 			continue
@@ -237,24 +239,31 @@ func computeCursorCodeActivity(out *ComputeResult, messages []*CursorMessage) {
 				if fp := b.stringInput("path"); fp != "" {
 					out.FilesRead++
 					recordCursorLanguage(out, fp)
+					files.read(fp, languageFromPath(fp))
 				}
 			case cursorToolWrite:
 				if fp := b.stringInput("path"); fp != "" {
 					out.FilesModified++
 					recordCursorLanguage(out, fp)
-					out.LinesAdded += countLines(b.stringInput("contents"))
+					added := countLines(b.stringInput("contents"))
+					out.LinesAdded += added
+					files.write(fp, languageFromPath(fp), added, 0)
 				}
 			case cursorToolStrReplace:
 				if fp := b.stringInput("path"); fp != "" {
 					out.FilesModified++
 					recordCursorLanguage(out, fp)
-					out.LinesRemoved += countLines(b.stringInput("old_string"))
-					out.LinesAdded += countLines(b.stringInput("new_string"))
+					removed := countLines(b.stringInput("old_string"))
+					added := countLines(b.stringInput("new_string"))
+					out.LinesRemoved += removed
+					out.LinesAdded += added
+					files.write(fp, languageFromPath(fp), added, removed)
 				}
 			case cursorToolDelete:
 				if fp := b.stringInput("path"); fp != "" {
 					out.FilesModified++
 					recordCursorLanguage(out, fp)
+					files.write(fp, languageFromPath(fp), 0, 0)
 				}
 			case cursorToolGrep, cursorToolGlob, cursorToolSemanticSearch:
 				out.SearchCount++
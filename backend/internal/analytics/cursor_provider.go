@@ -98,6 +98,10 @@ func (p *cursorProvider) SearchText(ctx context.Context, rollout Rollout) string
 	return extractCursorSearchText(r.materialize(ctx))
 }
 
+// AgentSearchText is empty: SearchText already indexes assistant text across
+// all of the session's rollouts.
+func (p *cursorProvider) AgentSearchText(context.Context, Rollout, int) string { return "" }
+
 func (p *cursorProvider) PrepareTranscript(ctx context.Context, rollout Rollout) (string, map[int]string, error) {
 	r, ok := rollout.(*cursorRollout)
 	if !ok || r == nil {
@@ -172,6 +172,7 @@ func parseTranscriptFile(content []byte, agentID string) (*TranscriptFile, error
 			continue
 		}
 
+		line.LineNumber = lineNumber
 		lines = append(lines, line)
 	}
 
@@ -213,6 +214,14 @@ type AssistantMessageGroup struct {
 	HasToolUse bool        // True if ANY line in the group has tool_use
 	HasThinking bool       // True if ANY line in the group has thinking
 	IsFastMode bool        // True if any line has speed="fast"
+
+	// Characters of thinking and visible content summed over the group's
+	// lines (see TranscriptLine.OutputChars). A replayed message adds its
+	// blocks again, which leaves the ratio between the two unchanged.
+	// ThinkingUnknown is set when any line had thinking of unknown size.
+	ThinkingChars   int
+	VisibleChars    int
+	ThinkingUnknown bool
 }
 
 // AssistantMessageGroups groups assistant lines by message.id and returns
@@ -240,6 +249,7 @@ func (tf *TranscriptFile) AssistantMessageGroups() []AssistantMessageGroup {
 		hasToolUse := line.HasToolUse()
 		hasThinking := line.HasThinking()
 		isFast := line.Message.Usage != nil && line.Message.Usage.Speed == SpeedFast
+		thinkingChars, visibleChars, charsOK := line.OutputChars()
 
 		// Subsequent occurrence of known ID — merge flags, update usage (last wins)
 		if msgID != "" {
@@ -248,6 +258,9 @@ func (tf *TranscriptFile) AssistantMessageGroups() []AssistantMessageGroup {
 				groups[idx].HasToolUse = groups[idx].HasToolUse || hasToolUse
 				groups[idx].HasThinking = groups[idx].HasThinking || hasThinking
 				groups[idx].IsFastMode = groups[idx].IsFastMode || isFast
+				groups[idx].ThinkingChars += thinkingChars
+				groups[idx].VisibleChars += visibleChars
+				groups[idx].ThinkingUnknown = groups[idx].ThinkingUnknown || !charsOK
 				if line.Message.Usage != nil {
 					usage := *line.Message.Usage
 					groups[idx].FinalUsage = &usage
@@ -264,6 +277,10 @@ func (tf *TranscriptFile) AssistantMessageGroups() []AssistantMessageGroup {
 			HasToolUse:  hasToolUse,
 			HasThinking: hasThinking,
 			IsFastMode:  isFast,
+
+			ThinkingChars:   thinkingChars,
+			VisibleChars:    visibleChars,
+			ThinkingUnknown: !charsOK,
 		}
 		if line.Message.Usage != nil {
 			usage := *line.Message.Usage
@@ -0,0 +1,120 @@
+package analytics
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// claudeEnvelopeTags are the tagged blocks Claude Code injects into user-role
+// lines around (or instead of) what the human typed: slash-command
+// invocations and their output, `!` bash-mode input and output, hook output,
+// and system reminders.
+var claudeEnvelopeTags = []string{
+	"command-name", "command-message", "command-args", "command-contents",
+	"local-command-stdout", "local-command-stderr", "local-command-caveat",
+	"bash-input", "bash-stdout", "bash-stderr",
+	"user-prompt-submit-hook", "system-reminder",
+}
+
+// claudeEnvelopeBlock matches one complete envelope block. RE2 has no
+// backreferences, so each tag gets its own alternative; `.` spans newlines.
+var claudeEnvelopeBlock = func() *regexp.Regexp {
+	alts := make([]string, len(claudeEnvelopeTags))
+	for i, tag := range claudeEnvelopeTags {
+		alts[i] = "<" + tag + ">.*?</" + tag + ">"
+	}
+	return regexp.MustCompile("(?s)" + strings.Join(alts, "|"))
+}()
+
+// claudeInterruptPrefix starts the placeholder line Claude Code writes when the
+// user presses Esc ("[Request interrupted by user]", "... for tool use]").
+const claudeInterruptPrefix = "[Request interrupted by user"
+
+// claudePromptLine is the slice of a transcript line FirstClaudeHumanPrompt
+// needs; decoding only these fields keeps the per-chunk scan cheap.
+type claudePromptLine struct {
+	Type             string `json:"type"`
+	IsMeta           bool   `json:"isMeta"`
+	IsSidechain      bool   `json:"isSidechain"`
+	IsCompactSummary bool   `json:"isCompactSummary"`
+	Message          *struct {
+		Content json.RawMessage `json:"content"`
+	} `json:"message"`
+}
+
+// FirstClaudeHumanPrompt returns the first thing the human typed among lines
+// of a Claude Code transcript, cut to at most maxBytes on a rune boundary, or
+// "" when no line holds one. Tool results, meta and sidechain lines, compact
+// summaries, interrupt placeholders, and slash-command / bash-mode envelopes
+// are not prompts; injected blocks such as system reminders are stripped from
+// a prompt that has text of its own.
+func FirstClaudeHumanPrompt(lines []string, maxBytes int) string {
+	for _, line := range lines {
+		// Quick check to avoid JSON parsing on most lines.
+		if !strings.Contains(line, `"user"`) {
+			continue
+		}
+		if prompt := claudeHumanPrompt(line); prompt != "" {
+			return truncateBytes(prompt, maxBytes)
+		}
+	}
+	return ""
+}
+
+// claudeHumanPrompt returns the human-typed text of one transcript line, or ""
+// when the line is not a human prompt.
+func claudeHumanPrompt(line string) string {
+	var l claudePromptLine
+	if err := json.Unmarshal([]byte(line), &l); err != nil {
+		return ""
+	}
+	if l.Type != "user" || l.IsMeta || l.IsSidechain || l.IsCompactSummary || l.Message == nil {
+		return ""
+	}
+
+	var text string
+	if err := json.Unmarshal(l.Message.Content, &text); err != nil {
+		// Array content: text blocks (plus images) are a prompt; any
+		// tool_result block makes the whole line a tool result.
+		var blocks []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(l.Message.Content, &blocks); err != nil {
+			return ""
+		}
+		var parts []string
+		for _, b := range blocks {
+			switch b.Type {
+			case "tool_result":
+				return ""
+			case "text":
+				parts = append(parts, b.Text)
+			}
+		}
+		text = strings.Join(parts, "\n")
+	}
+
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, claudeInterruptPrefix) {
+		return ""
+	}
+	// A slash command is an invocation, not a prompt, even with arguments.
+	if strings.Contains(text, "<command-name>") {
+		return ""
+	}
+	return strings.TrimSpace(claudeEnvelopeBlock.ReplaceAllString(text, ""))
+}
+
+// truncateBytes cuts s to at most n bytes without splitting a rune.
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
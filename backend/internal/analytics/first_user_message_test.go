@@ -0,0 +1,74 @@
+package analytics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readFixtureLines(t *testing.T, name string) []string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "first_user_message", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestFirstClaudeHumanPrompt_Fixtures(t *testing.T) {
+	cases := []struct {
+		fixture string
+		want    string
+	}{
+		{"tool_result_first.jsonl", "Refactor main.go to read the port from an env var"},
+		{"command_wrapper.jsonl", "Add rate limiting to the login endpoint"},
+		{"normal_prompt.jsonl", "Why does this test fail?"},
+		{"no_prompt.jsonl", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.fixture, func(t *testing.T) {
+			if got := FirstClaudeHumanPrompt(readFixtureLines(t, tc.fixture), 8192); got != tc.want {
+				t.Errorf("FirstClaudeHumanPrompt() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClaudeHumanPrompt(t *testing.T) {
+	cases := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"plain string", `{"type":"user","message":{"content":"  hello  "}}`, "hello"},
+		{"text blocks joined", `{"type":"user","message":{"content":[{"type":"text","text":"a"},{"type":"text","text":"b"}]}}`, "a\nb"},
+		{"tool result", `{"type":"user","message":{"content":[{"type":"tool_result","content":"x"}]}}`, ""},
+		{"text beside tool result", `{"type":"user","message":{"content":[{"type":"text","text":"a"},{"type":"tool_result","content":"x"}]}}`, ""},
+		{"assistant", `{"type":"assistant","message":{"content":"hello"}}`, ""},
+		{"meta", `{"type":"user","isMeta":true,"message":{"content":"skill body"}}`, ""},
+		{"slash command with args", `{"type":"user","message":{"content":"<command-name>/fix</command-name><command-args>bug</command-args>"}}`, ""},
+		{"only a reminder", `{"type":"user","message":{"content":"<system-reminder>x</system-reminder>"}}`, ""},
+		{"interrupt for tool use", `{"type":"user","message":{"content":"[Request interrupted by user for tool use]"}}`, ""},
+		{"mismatched tags stay", `{"type":"user","message":{"content":"<bash-input>ls</bash-stdout>"}}`, "<bash-input>ls</bash-stdout>"},
+		{"not json", `{"type":"user"`, ""},
+		{"no message", `{"type":"user"}`, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := claudeHumanPrompt(tc.line); got != tc.want {
+				t.Errorf("claudeHumanPrompt() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFirstClaudeHumanPrompt_TruncatesOnRuneBoundary(t *testing.T) {
+	line := `{"type":"user","message":{"content":"héllo"}}`
+	if got := FirstClaudeHumanPrompt([]string{line}, 2); got != "h" {
+		t.Errorf("got %q, want %q", got, "h")
+	}
+	if got := FirstClaudeHumanPrompt([]string{line}, 3); got != "hé" {
+		t.Errorf("got %q, want %q", got, "hé")
+	}
+}
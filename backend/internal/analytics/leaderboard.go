@@ -0,0 +1,250 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+// leaderboard.go: the deployment-wide leaderboard behind the super-admin gate.
+// Every figure is an aggregate across all users' sessions in the range; no row
+// carries a user ID, session ID, title, or repo, so nothing in the response can
+// be traced back to a person. Costs and token counts come from the tokens_v2
+// scalars (db.V2*Expr), the same source the Trends and org analytics readers use.
+
+// leaderboardLimit caps the longest-sessions and top-models lists.
+const leaderboardLimit = 10
+
+// leaderboardSessionsCTE selects the non-duplicate sessions first seen in
+// [$1, $2). Every leaderboard query starts from it.
+const leaderboardSessionsCTE = `
+	WITH range_sessions AS (
+		SELECT s.id, s.session_type
+		FROM sessions s
+		WHERE s.merged_at IS NULL
+			AND s.first_seen >= $1
+			AND s.first_seen < $2
+	)`
+
+// leaderboardLongestSQL ranks range sessions by synced line count across all
+// their files (legacy todo files excluded).
+const leaderboardLongestSQL = leaderboardSessionsCTE + `,
+	session_lines AS (
+		SELECT rs.session_type, SUM(sf.last_synced_line)::bigint AS line_count
+		FROM range_sessions rs
+		JOIN sync_files sf ON sf.session_id = rs.id
+		WHERE sf.file_type != 'todo'
+		GROUP BY rs.id, rs.session_type
+	)
+	SELECT session_type, line_count
+	FROM session_lines
+	ORDER BY line_count DESC
+	LIMIT $3`
+
+// leaderboardModelScanSQL expands the tokens_v2 tree of the range sessions into
+// one row per (session, model); keys are normalized to families in Go.
+const leaderboardModelScanSQL = leaderboardSessionsCTE + `
+	SELECT rs.id, rs.session_type, mdl.key
+	FROM range_sessions rs
+	JOIN session_card_tokens_v2 v ON v.session_id = rs.id
+	CROSS JOIN LATERAL jsonb_each(v.data->'by_provider') AS prov(key, value)
+	CROSS JOIN LATERAL jsonb_each(prov.value->'models') AS mdl(key, value)`
+
+// leaderboardTotalsSQL returns one cost and token-count row per range session
+// that carries tokens_v2 data.
+var leaderboardTotalsSQL = leaderboardSessionsCTE + fmt.Sprintf(`
+	SELECT COALESCE(%s, '0'),
+		COALESCE(%s, '0')::bigint,
+		COALESCE(%s, '0')::bigint,
+		COALESCE(%s, '0')::bigint,
+		COALESCE(%s, '0')::bigint
+	FROM range_sessions rs
+	JOIN session_card_tokens_v2 v ON v.session_id = rs.id`,
+	db.V2TotalCostExpr("v"),
+	db.V2TotalInputExpr("v"),
+	db.V2TotalOutputExpr("v"),
+	db.V2TotalCacheCreationExpr("v"),
+	db.V2TotalCacheReadExpr("v"))
+
+// LeaderboardRequest is the [From, To) window of session first_seen times.
+type LeaderboardRequest struct {
+	From time.Time
+	To   time.Time
+}
+
+// Leaderboard is the anonymized cross-user aggregate for a date range.
+type Leaderboard struct {
+	// SessionCount is every non-duplicate session first seen in the range.
+	SessionCount int `json:"session_count"`
+	// PricedSessionCount is the subset with tokens_v2 data; the cost and token
+	// figures below are over these sessions only.
+	PricedSessionCount int                      `json:"priced_session_count"`
+	LongestSessions    []LeaderboardSession     `json:"longest_sessions"`
+	CostPercentiles    *CostDistributionStats   `json:"cost_percentiles"`
+	TopModels          []LeaderboardModel       `json:"top_models"`
+	AvgTokens          *LeaderboardTokenAverage `json:"avg_tokens_per_session"`
+}
+
+// LeaderboardSession is one entry of the longest-sessions ranking. Only the
+// provider and size are reported, never which session or whose.
+type LeaderboardSession struct {
+	Rank      int    `json:"rank"`
+	Provider  string `json:"provider"`
+	LineCount int64  `json:"line_count"`
+}
+
+// LeaderboardModel is a model family and the number of range sessions that
+// used it.
+type LeaderboardModel struct {
+	Provider     string `json:"provider"`
+	Model        string `json:"model"`
+	SessionCount int    `json:"session_count"`
+}
+
+// LeaderboardTokenAverage is the mean per-session token usage, split the way
+// the tokens card splits it.
+type LeaderboardTokenAverage struct {
+	Input         float64 `json:"input"`
+	Output        float64 `json:"output"`
+	CacheCreation float64 `json:"cache_creation"`
+	CacheRead     float64 `json:"cache_read"`
+	Total         float64 `json:"total"`
+}
+
+// GetLeaderboard computes the leaderboard for req. Cost percentiles and token
+// averages are nil when no session in the range has tokens_v2 data.
+func (s *Store) GetLeaderboard(ctx context.Context, req LeaderboardRequest) (*Leaderboard, error) {
+	ctx, span := tracer.Start(ctx, "analytics.get_leaderboard",
+		trace.WithAttributes(
+			attribute.String("from", req.From.Format(time.RFC3339)),
+			attribute.String("to", req.To.Format(time.RFC3339)),
+		))
+	defer span.End()
+
+	args := []any{req.From, req.To}
+	out := &Leaderboard{
+		LongestSessions: []LeaderboardSession{},
+		TopModels:       []LeaderboardModel{},
+	}
+
+	if err := s.queryRow(ctx, leaderboardSessionsCTE+"\nSELECT COUNT(*) FROM range_sessions", args, &out.SessionCount); err != nil {
+		return nil, fmt.Errorf("leaderboard session count: %w", err)
+	}
+
+	err := s.queryEach(ctx, leaderboardLongestSQL, []any{req.From, req.To, leaderboardLimit}, func(rows *sql.Rows) error {
+		var sessionType string
+		var lines int64
+		if err := rows.Scan(&sessionType, &lines); err != nil {
+			return fmt.Errorf("leaderboard longest scan: %w", err)
+		}
+		out.LongestSessions = append(out.LongestSessions, LeaderboardSession{
+			Rank:      len(out.LongestSessions) + 1,
+			Provider:  models.NormalizeProvider(sessionType),
+			LineCount: lines,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("leaderboard longest query: %w", err)
+	}
+
+	var costs []decimal.Decimal
+	var sum LeaderboardTokenAverage
+	err = s.queryEach(ctx, leaderboardTotalsSQL, args, func(rows *sql.Rows) error {
+		var costStr string
+		var input, output, cacheCreation, cacheRead int64
+		if err := rows.Scan(&costStr, &input, &output, &cacheCreation, &cacheRead); err != nil {
+			return fmt.Errorf("leaderboard totals scan: %w", err)
+		}
+		cost, err := decimal.NewFromString(costStr)
+		if err != nil {
+			cost = decimal.Zero
+		}
+		costs = append(costs, cost)
+		sum.Input += float64(input)
+		sum.Output += float64(output)
+		sum.CacheCreation += float64(cacheCreation)
+		sum.CacheRead += float64(cacheRead)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("leaderboard totals query: %w", err)
+	}
+	out.PricedSessionCount = len(costs)
+	if n := float64(len(costs)); n > 0 {
+		sort.Slice(costs, func(i, j int) bool { return costs[i].LessThan(costs[j]) })
+		out.CostPercentiles = costDistributionStats(costs)
+		out.AvgTokens = &LeaderboardTokenAverage{
+			Input:         sum.Input / n,
+			Output:        sum.Output / n,
+			CacheCreation: sum.CacheCreation / n,
+			CacheRead:     sum.CacheRead / n,
+			Total:         (sum.Input + sum.Output + sum.CacheCreation + sum.CacheRead) / n,
+		}
+	}
+
+	out.TopModels, err = s.leaderboardTopModels(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(
+		attribute.Int("leaderboard.sessions", out.SessionCount),
+		attribute.Int("leaderboard.priced_sessions", out.PricedSessionCount),
+	)
+	return out, nil
+}
+
+// leaderboardTopModels counts distinct range sessions per (provider, model
+// family) and returns the most used, ties broken by name. The Unknown key and
+// synthetic turns are not models and are skipped.
+func (s *Store) leaderboardTopModels(ctx context.Context, args []any) ([]LeaderboardModel, error) {
+	sessionsByModel := map[[2]string]map[string]struct{}{}
+	err := s.queryEach(ctx, leaderboardModelScanSQL, args, func(rows *sql.Rows) error {
+		var sessionID, sessionType, rawModel string
+		if err := rows.Scan(&sessionID, &sessionType, &rawModel); err != nil {
+			return fmt.Errorf("leaderboard models scan: %w", err)
+		}
+		provider := models.NormalizeProvider(sessionType)
+		model := normalizeV2ModelKey(provider, rawModel)
+		if model == "" || model == syntheticModelKey {
+			return nil
+		}
+		key := [2]string{provider, model}
+		if sessionsByModel[key] == nil {
+			sessionsByModel[key] = map[string]struct{}{}
+		}
+		sessionsByModel[key][sessionID] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("leaderboard models query: %w", err)
+	}
+
+	out := make([]LeaderboardModel, 0, len(sessionsByModel))
+	for key, sessions := range sessionsByModel {
+		out = append(out, LeaderboardModel{Provider: key[0], Model: key[1], SessionCount: len(sessions)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].SessionCount != out[j].SessionCount {
+			return out[i].SessionCount > out[j].SessionCount
+		}
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].Model < out[j].Model
+	})
+	if len(out) > leaderboardLimit {
+		out = out[:leaderboardLimit]
+	}
+	return out, nil
+}
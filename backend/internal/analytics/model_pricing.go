@@ -0,0 +1,92 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// ModelPricingPeriod is one model_pricing row: a family's prices over
+// [EffectiveFrom, EffectiveTo). A nil EffectiveTo is open-ended.
+type ModelPricingPeriod struct {
+	ID            int64
+	ModelName     string // pricing family key, as getModelFamily returns it
+	Pricing       ModelPricing
+	EffectiveFrom time.Time
+	EffectiveTo   *time.Time
+}
+
+// covers reports whether the period is in effect at t.
+func (p ModelPricingPeriod) covers(t time.Time) bool {
+	return !t.Before(p.EffectiveFrom) && (p.EffectiveTo == nil || t.Before(*p.EffectiveTo))
+}
+
+// pricingPeriods holds the model_pricing table in memory, family → periods
+// ordered by EffectiveFrom. It takes precedence over activePricing for the
+// families it lists (see pricingForModel). Empty until a process loads it
+// with RefreshModelPricing, so code that never touches the database (unit
+// tests, Analyze) prices from pricing.json alone.
+var pricingPeriods atomic.Pointer[map[string][]ModelPricingPeriod]
+
+func init() {
+	pricingPeriods.Store(&map[string][]ModelPricingPeriod{})
+}
+
+// SetModelPricingPeriods swaps in a full copy of the model_pricing table.
+func SetModelPricingPeriods(periods []ModelPricingPeriod) {
+	table := make(map[string][]ModelPricingPeriod)
+	for _, p := range periods {
+		table[p.ModelName] = append(table[p.ModelName], p)
+	}
+	for _, ps := range table {
+		sort.Slice(ps, func(i, j int) bool { return ps[i].EffectiveFrom.Before(ps[j].EffectiveFrom) })
+	}
+	pricingPeriods.Store(&table)
+}
+
+// periodPricing returns the family's model_pricing price in effect at t. The
+// bool is false when no period covers t, and the caller falls back to
+// pricing.json.
+func periodPricing(family string, t time.Time) (ModelPricing, bool) {
+	for _, p := range (*pricingPeriods.Load())[family] {
+		if p.covers(t) {
+			return p.Pricing, true
+		}
+	}
+	return zeroPricing, false
+}
+
+// RefreshModelPricing reloads the model_pricing table into memory. On error
+// the previously loaded table stays in effect.
+func RefreshModelPricing(ctx context.Context, conn *sql.DB) error {
+	periods, err := LoadModelPricing(ctx, conn)
+	if err != nil {
+		return err
+	}
+	SetModelPricingPeriods(periods)
+	return nil
+}
+
+// WatchModelPricing loads the model_pricing table now and then every interval
+// until ctx is done, so the API server's on-demand card computation sees
+// admin price changes made through other instances. The worker refreshes at
+// the top of each cycle instead. Failures are logged and retried on the next
+// tick.
+func WatchModelPricing(ctx context.Context, conn *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := RefreshModelPricing(ctx, conn); err != nil && ctx.Err() == nil {
+			logger.Warn("failed to refresh model pricing", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
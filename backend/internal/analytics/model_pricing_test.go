@@ -0,0 +1,89 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestPricingForModel_ModelPricingPeriods covers point-in-time pricing from
+// the model_pricing table: the period covering sessionAt wins over
+// pricing.json, with effective_to exclusive, and families or instants no
+// period covers fall back to pricing.json.
+func TestPricingForModel_ModelPricingPeriods(t *testing.T) {
+	t.Cleanup(func() { SetModelPricingPeriods(nil) })
+
+	jun1 := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	oct1 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	price := func(input int64) ModelPricing {
+		return ModelPricing{Input: decimal.NewFromInt(input), Output: decimal.NewFromInt(input * 5)}
+	}
+	// Listed out of order: SetModelPricingPeriods sorts by start.
+	SetModelPricingPeriods([]ModelPricingPeriod{
+		{ModelName: "opus-4-7", Pricing: price(7), EffectiveFrom: oct1},
+		{ModelName: "opus-4-7", Pricing: price(6), EffectiveFrom: jun1, EffectiveTo: &oct1},
+	})
+
+	tests := []struct {
+		name      string
+		model     string
+		sessionAt time.Time
+		wantInput int64
+	}{
+		{"before the first period falls back to pricing.json", "claude-opus-4-7", jun1.Add(-time.Second), 5},
+		{"first period", "claude-opus-4-7", jun1, 6},
+		{"effective_to is exclusive", "claude-opus-4-7", oct1, 7},
+		{"open-ended period", "claude-opus-4-7-20261101", oct1.AddDate(1, 0, 0), 7},
+		{"family without periods uses pricing.json", "claude-sonnet-4-6", oct1, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := pricingForModel(nil, tt.model, tt.sessionAt)
+			if want := decimal.NewFromInt(tt.wantInput); !p.Input.Equal(want) {
+				t.Errorf("pricingForModel(%s, %s).Input = %s, want %s", tt.model, tt.sessionAt, p.Input, want)
+			}
+		})
+	}
+}
+
+// TestPricingForModel_ModelPricingSonnet5 checks that Sonnet 5 seeded as two
+// periods (as migration 099 does) prices like the hard-coded intro/standard
+// date routing: $2 input before 2026-09-01, $3 from then on.
+func TestPricingForModel_ModelPricingSonnet5(t *testing.T) {
+	t.Cleanup(func() { SetModelPricingPeriods(nil) })
+
+	table := *activePricing.Load()
+	intro, standard := table["sonnet-5-intro"], table["sonnet-5"]
+	SetModelPricingPeriods([]ModelPricingPeriod{
+		{ModelName: "sonnet-5", Pricing: intro, EffectiveFrom: time.Unix(0, 0).UTC(), EffectiveTo: &sonnet5Sep1},
+		{ModelName: "sonnet-5", Pricing: standard, EffectiveFrom: sonnet5Sep1},
+	})
+
+	for _, tt := range []struct {
+		at        time.Time
+		wantInput float64
+	}{
+		{time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), 2},
+		{sonnet5Sep1.Add(-time.Second), 2},
+		{sonnet5Sep1, 3},
+		{sonnet5Sep1.AddDate(0, 1, 0), 3},
+	} {
+		p := pricingForModel(nil, "claude-sonnet-5", tt.at)
+		if want := decimal.NewFromFloat(tt.wantInput); !p.Input.Equal(want) {
+			t.Errorf("sonnet-5 at %s Input = %s, want %s", tt.at, p.Input, want)
+		}
+	}
+}
+
+func TestActivePricingFamilies_IncludesModelPricing(t *testing.T) {
+	t.Cleanup(func() { SetModelPricingPeriods(nil) })
+
+	if _, ok := ActivePricingFamilies()["gpt-9"]; ok {
+		t.Fatal("gpt-9 priced before any model_pricing row")
+	}
+	SetModelPricingPeriods([]ModelPricingPeriod{{ModelName: "gpt-9", EffectiveFrom: time.Unix(0, 0)}})
+	if _, ok := ActivePricingFamilies()["gpt-9"]; !ok {
+		t.Error("ActivePricingFamilies() is missing the model_pricing family gpt-9")
+	}
+}
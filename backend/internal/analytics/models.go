@@ -39,6 +39,17 @@ type AnalyticsResponse struct {
 	// Per-card computation errors (graceful degradation)
 	// Maps card key (e.g., "tokens", "session") to error message
 	CardErrors map[string]string `json:"card_errors,omitempty"`
+
+	// Stale is set when stored cards from an older card version are served
+	// while the precompute worker recomputes them. StaleCards maps each
+	// outdated card key to its version delta.
+	Stale      bool                     `json:"stale,omitempty"`
+	StaleCards map[string]CardStaleness `json:"stale_cards,omitempty"`
+
+	// TranscriptArchived is set when the session's raw transcript has been
+	// deleted by transcript retention. The cards are whatever was computed
+	// before archival and are never recomputed.
+	TranscriptArchived bool `json:"transcript_archived,omitempty"`
 }
 
 // TokenStats contains token usage information (legacy flat format).
@@ -787,7 +787,7 @@ func TestComputeStreaming_MatchesFileCollection(t *testing.T) {
 		idx++
 		return agent, nil
 	}
-	streamResult, err := ComputeStreaming(context.Background(), fc.Main, provider, nil, time.Time{})
+	streamResult, err := ComputeStreaming(context.Background(), fc.Main, provider, nil, time.Time{}, "")
 	if err != nil {
 		t.Fatalf("ComputeStreaming failed: %v", err)
 	}
@@ -858,7 +858,7 @@ func TestComputeStreaming_ProviderErrors(t *testing.T) {
 		}
 	}
 
-	result, err := ComputeStreaming(context.Background(), main, provider, nil, time.Time{})
+	result, err := ComputeStreaming(context.Background(), main, provider, nil, time.Time{}, "")
 	if err != nil {
 		t.Fatalf("ComputeStreaming failed: %v", err)
 	}
@@ -888,7 +888,7 @@ func TestComputeStreaming_NoAgents(t *testing.T) {
 		return nil, io.EOF
 	}
 
-	result, err := ComputeStreaming(context.Background(), main, provider, nil, time.Time{})
+	result, err := ComputeStreaming(context.Background(), main, provider, nil, time.Time{}, "")
 	if err != nil {
 		t.Fatalf("ComputeStreaming failed: %v", err)
 	}
@@ -0,0 +1,34 @@
+package analytics
+
+import "time"
+
+// MyRepos is the caller's sessions grouped by repository
+// (GET /api/v1/me/repos), for repo-first navigation.
+type MyRepos struct {
+	// Repos holds one entry per repository, most recently active first.
+	// Always non-nil.
+	Repos []RepoActivity `json:"repos"`
+	// Unassigned covers the sessions whose git_info names no repository.
+	Unassigned RepoActivityCounts `json:"unassigned"`
+}
+
+// RepoActivity is one repository's entry.
+type RepoActivity struct {
+	// Repo is the owner/name from git_info.repo_url, with forks collapsed to
+	// their upstream (db.RepoRootExpr) — the value the session list's repo
+	// filter takes.
+	Repo string `json:"repo"`
+	RepoActivityCounts
+}
+
+// RepoActivityCounts are the session count, token total and latest activity
+// of a repository or of the unassigned bucket. Sessions not yet analyzed
+// count with zero tokens.
+type RepoActivityCounts struct {
+	SessionCount int64 `json:"session_count"`
+	// TotalTokens sums input, output, cache creation and cache read tokens.
+	TotalTokens int64 `json:"total_tokens"`
+	// LastActivity is the latest last_message_at (first_seen until the first
+	// message) of the sessions; nil when there are none.
+	LastActivity *time.Time `json:"last_activity"`
+}
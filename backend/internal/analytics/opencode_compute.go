@@ -52,15 +52,17 @@ func ComputeFromOpenCodeRollout(ctx context.Context, rollouts [][]*OpenCodeMessa
 
 	// Remaining analyzers accumulate via += on result fields, so per-rollout
 	// dispatch produces the cross-rollout total.
+	var files codeFileTracker
 	for _, messages := range rollouts {
 		if len(messages) == 0 {
 			continue
 		}
 		computeOpenCodeTools(result, messages)
-		computeOpenCodeCodeActivity(result, messages)
+		computeOpenCodeCodeActivity(result, messages, &files)
 		computeOpenCodeAgentsAndSkills(result, messages)
 		computeOpenCodeRedactions(result, messages)
 	}
+	result.CodeFiles = files.Result()
 
 	return result
 }
@@ -85,15 +87,17 @@ func computeFromOpenCodeRolloutAt(ctx context.Context, rollouts [][]*OpenCodeMes
 	computeOpenCodeSession(result, rollouts)
 	computeOpenCodeConversation(result, rollouts[0])
 
+	var files codeFileTracker
 	for _, messages := range rollouts {
 		if len(messages) == 0 {
 			continue
 		}
 		computeOpenCodeTools(result, messages)
-		computeOpenCodeCodeActivity(result, messages)
+		computeOpenCodeCodeActivity(result, messages, &files)
 		computeOpenCodeAgentsAndSkills(result, messages)
 		computeOpenCodeRedactions(result, messages)
 	}
+	result.CodeFiles = files.Result()
 
 	return result
 }
@@ -339,7 +343,7 @@ func computeOpenCodeTools(out *ComputeResult, messages []*OpenCodeMessage) {
 	}
 }
 
-func computeOpenCodeCodeActivity(out *ComputeResult, messages []*OpenCodeMessage) {
+func computeOpenCodeCodeActivity(out *ComputeResult, messages []*OpenCodeMessage, files *codeFileTracker) {
 	for _, msg := range messages {
 		if msg.Info.Role != "assistant" {
 			continue
@@ -361,6 +365,7 @@ func computeOpenCodeCodeActivity(out *ComputeResult, messages []*OpenCodeMessage
 					if lang := languageFromPath(fp); lang != "" {
 						out.LanguageBreakdown[lang]++
 					}
+					files.read(fp, languageFromPath(fp))
 				}
 			case "Write":
 				if fp != "" {
@@ -368,7 +373,9 @@ func computeOpenCodeCodeActivity(out *ComputeResult, messages []*OpenCodeMessage
 					if lang := languageFromPath(fp); lang != "" {
 						out.LanguageBreakdown[lang]++
 					}
-					out.LinesAdded += countLines(getStringInput(state, "content"))
+					added := countLines(getStringInput(state, "content"))
+					out.LinesAdded += added
+					files.write(fp, languageFromPath(fp), added, 0)
 				}
 			case "Edit":
 				if fp != "" {
@@ -376,8 +383,11 @@ func computeOpenCodeCodeActivity(out *ComputeResult, messages []*OpenCodeMessage
 					if lang := languageFromPath(fp); lang != "" {
 						out.LanguageBreakdown[lang]++
 					}
-					out.LinesRemoved += countLines(getStringInput(state, "old_string"))
-					out.LinesAdded += countLines(getStringInput(state, "new_string"))
+					removed := countLines(getStringInput(state, "old_string"))
+					added := countLines(getStringInput(state, "new_string"))
+					out.LinesRemoved += removed
+					out.LinesAdded += added
+					files.write(fp, languageFromPath(fp), added, removed)
 				}
 			case "Grep", "Glob":
 				out.SearchCount++
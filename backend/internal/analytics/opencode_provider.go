@@ -63,6 +63,10 @@ func (p *opencodeProvider) SearchText(ctx context.Context, rollout Rollout) stri
 	return extractOpenCodeSearchText(r.materialize(ctx))
 }
 
+// AgentSearchText is empty: SearchText already indexes assistant text across
+// the parent and subagent sessions.
+func (p *opencodeProvider) AgentSearchText(context.Context, Rollout, int) string { return "" }
+
 func (p *opencodeProvider) PrepareTranscript(ctx context.Context, rollout Rollout) (string, map[int]string, error) {
 	r, ok := rollout.(*opencodeRollout)
 	if !ok || r == nil {
@@ -20,7 +20,7 @@ import (
 // every counted session contributes to all metrics. All active users appear
 // in the result, even those with zero qualifying sessions in the range.
 func (s *Store) GetOrgAnalytics(ctx context.Context, req OrgAnalyticsRequest) (*OrgAnalyticsResponse, error) {
-	ctx, span := tracer.Start(ctx, "analytics.get_org_analytics",
+	ctx, span := tracer.Start(preferReplica(ctx), "analytics.get_org_analytics",
 		trace.WithAttributes(
 			attribute.Int64("start_ts", req.StartTS),
 			attribute.Int64("end_ts", req.EndTS),
@@ -103,6 +103,7 @@ func (s *Store) orgUserAggregates(ctx context.Context, req OrgAnalyticsRequest,
 			INNER JOIN session_card_conversation cv ON s.id = cv.session_id
 			LEFT JOIN session_card_session sess ON s.id = sess.session_id
 			WHERE s.user_id = u.id
+				AND s.merged_at IS NULL
 				AND s.first_seen >= to_timestamp($1)
 				AND s.first_seen < to_timestamp($2)
 				AND s.session_type = ANY($3::text[])
@@ -117,14 +118,9 @@ func (s *Store) orgUserAggregates(ctx context.Context, req OrgAnalyticsRequest,
 		ORDER BY u.name ASC NULLS LAST, u.email ASC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, req.StartTS, req.EndTS, providerArg, repoArg, req.IncludeNoRepo)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	var users []OrgUserAnalytics
-	for rows.Next() {
+
+	err := s.queryEach(ctx, query, []any{req.StartTS, req.EndTS, providerArg, repoArg, req.IncludeNoRepo}, func(rows *sql.Rows) error {
 		var (
 			userID         int64
 			email          string
@@ -137,7 +133,7 @@ func (s *Store) orgUserAggregates(ctx context.Context, req OrgAnalyticsRequest,
 		)
 
 		if err := rows.Scan(&userID, &email, &name, &sessionCount, &totalCost, &totalDurMs, &totalAssistant, &totalUser); err != nil {
-			return nil, err
+			return err
 		}
 
 		ua := OrgUserAnalytics{
@@ -172,9 +168,9 @@ func (s *Store) orgUserAggregates(ctx context.Context, req OrgAnalyticsRequest,
 		}
 
 		users = append(users, ua)
-	}
-
-	if err := rows.Err(); err != nil {
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -200,7 +196,8 @@ func (s *Store) orgProvidersPresent(ctx context.Context, req OrgAnalyticsRequest
 		INNER JOIN session_card_tokens_v2 v ON s.id = v.session_id
 		INNER JOIN session_card_conversation cv ON s.id = cv.session_id
 		INNER JOIN users u ON s.user_id = u.id AND u.status = 'active'
-		WHERE s.first_seen >= to_timestamp($1)
+		WHERE s.merged_at IS NULL
+			AND s.first_seen >= to_timestamp($1)
 			AND s.first_seen < to_timestamp($2)
 			AND s.session_type = ANY($3::text[])
 			AND (
@@ -210,21 +207,17 @@ func (s *Store) orgProvidersPresent(ctx context.Context, req OrgAnalyticsRequest
 			)
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, req.StartTS, req.EndTS, providerArg, repoArg, req.IncludeNoRepo)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	seen := make(map[string]struct{})
-	for rows.Next() {
+
+	err := s.queryEach(ctx, query, []any{req.StartTS, req.EndTS, providerArg, repoArg, req.IncludeNoRepo}, func(rows *sql.Rows) error {
 		var raw string
 		if err := rows.Scan(&raw); err != nil {
-			return nil, err
+			return err
 		}
 		seen[models.NormalizeProvider(raw)] = struct{}{}
-	}
-	if err := rows.Err(); err != nil {
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
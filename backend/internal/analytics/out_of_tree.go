@@ -0,0 +1,214 @@
+package analytics
+
+import (
+	"path"
+	"strings"
+)
+
+// Out-of-tree path categories (OutOfTreePath.Category).
+const (
+	OutOfTreeHome     = "home"     // under a home directory, outside the cwd
+	OutOfTreeTemp     = "temp"     // a temp directory
+	OutOfTreeExternal = "external" // anywhere else
+)
+
+// MaxOutOfTreeSamples caps the out-of-tree paths kept on the code activity
+// card.
+const MaxOutOfTreeSamples = 20
+
+// OutOfTreePath is a sampled file path outside the session's cwd, as the tool
+// call gave it.
+type OutOfTreePath struct {
+	Path     string `json:"path"`
+	Category string `json:"category"`
+}
+
+// OutOfTreeAccess summarizes the files a session's Read/Write/Edit calls
+// touched outside its cwd. Counts are of distinct paths.
+type OutOfTreeAccess struct {
+	Home     int             `json:"home"`
+	Temp     int             `json:"temp"`
+	External int             `json:"external"`
+	Samples  []OutOfTreePath `json:"samples"` // first MaxOutOfTreeSamples, in order seen
+}
+
+// Total returns the number of distinct out-of-tree paths.
+func (o OutOfTreeAccess) Total() int {
+	return o.Home + o.Temp + o.External
+}
+
+// outOfTreeCategory reports where filePath lies relative to cwd: "" when it
+// is inside cwd, otherwise one of the OutOfTree* categories. Relative paths
+// are resolved against cwd; "~" against the home directory cwd sits in.
+// Backslashes are read as separators, Windows drive paths compare
+// case-insensitively, and macOS's /private/{tmp,var,etc} aliases match their
+// short forms. Symlinks are not resolved: a path is judged by its text.
+// An empty cwd or filePath classifies as inside, since there is nothing to
+// compare.
+func outOfTreeCategory(filePath, cwd string) string {
+	cwd = normalizeToolPath(cwd)
+	if cwd == "" || !isAbsToolPath(cwd) || strings.TrimSpace(filePath) == "" {
+		return ""
+	}
+	home := homeDirOf(cwd)
+
+	p := normalizeToolPath(filePath)
+	switch {
+	case p == "~" || strings.HasPrefix(p, "~/"):
+		if home == "" {
+			return OutOfTreeHome
+		}
+		p = home + strings.TrimPrefix(p, "~")
+	case !isAbsToolPath(p):
+		p = cwd + "/" + p
+	}
+	p = cleanToolPath(p)
+
+	if isUnder(p, cwd) {
+		return ""
+	}
+	if isTempPath(p) {
+		return OutOfTreeTemp
+	}
+	if homeDirOf(p) != "" {
+		return OutOfTreeHome
+	}
+	return OutOfTreeExternal
+}
+
+// normalizeToolPath converts backslashes to slashes, lowercases Windows drive
+// paths, and cleans the result. Relative paths stay relative.
+func normalizeToolPath(p string) string {
+	p = strings.TrimSpace(strings.ReplaceAll(p, `\`, "/"))
+	if p == "" {
+		return ""
+	}
+	if hasDriveLetter(p) {
+		p = strings.ToLower(p)
+		if len(p) == 2 || p[2] != '/' {
+			p = p[:2] + "/" + p[2:]
+		}
+	}
+	return cleanToolPath(p)
+}
+
+// cleanToolPath is path.Clean that also folds macOS's /private prefix and
+// keeps a drive root ("c:/") from losing its slash.
+func cleanToolPath(p string) string {
+	p = path.Clean(p)
+	for _, dir := range []string{"/private/tmp", "/private/var", "/private/etc"} {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			p = strings.TrimPrefix(p, "/private")
+			break
+		}
+	}
+	if len(p) == 2 && hasDriveLetter(p) {
+		p += "/"
+	}
+	return p
+}
+
+func hasDriveLetter(p string) bool {
+	if len(p) < 2 || p[1] != ':' {
+		return false
+	}
+	c := p[0] | 0x20
+	return c >= 'a' && c <= 'z'
+}
+
+func isAbsToolPath(p string) bool {
+	return strings.HasPrefix(p, "/") || hasDriveLetter(p)
+}
+
+// isUnder reports whether p is dir or inside it, by whole path segments, so
+// /work/app-old is not under /work/app.
+func isUnder(p, dir string) bool {
+	if p == dir || strings.HasSuffix(dir, "/") && strings.HasPrefix(p, dir) {
+		return true
+	}
+	return strings.HasPrefix(p, dir+"/")
+}
+
+// tempDirs are the system temp locations; Windows paths are lowercase to
+// match normalizeToolPath.
+var tempDirs = []string{"/tmp", "/var/tmp", "/var/folders", "/dev/shm", "c:/windows/temp"}
+
+func isTempPath(p string) bool {
+	for _, dir := range tempDirs {
+		if isUnder(p, dir) {
+			return true
+		}
+	}
+	if home := homeDirOf(p); home != "" {
+		return isUnder(p, home+"/appdata/local/temp")
+	}
+	return false
+}
+
+// homeDirOf returns the home directory p lies in (/home/<user>,
+// /Users/<user>, /root, or <drive>:/users/<user>), or "" when it is not
+// under one.
+func homeDirOf(p string) string {
+	if isUnder(p, "/root") {
+		return "/root"
+	}
+	var prefix string
+	switch {
+	case strings.HasPrefix(p, "/home/"):
+		prefix = "/home/"
+	case strings.HasPrefix(p, "/Users/"):
+		prefix = "/Users/"
+	case hasDriveLetter(p) && strings.HasPrefix(p[2:], "/users/"):
+		prefix = p[:len("c:/users/")]
+	default:
+		return ""
+	}
+	user, _, _ := strings.Cut(p[len(prefix):], "/")
+	if user == "" {
+		return ""
+	}
+	return prefix + user
+}
+
+// outOfTreeTracker accumulates OutOfTreeAccess, counting each path once.
+type outOfTreeTracker struct {
+	cwd    string
+	seen   map[string]bool
+	result OutOfTreeAccess
+}
+
+func (t *outOfTreeTracker) track(filePath string) {
+	category := outOfTreeCategory(filePath, t.cwd)
+	if category == "" {
+		return
+	}
+	if t.seen == nil {
+		t.seen = make(map[string]bool)
+	}
+	key := normalizeToolPath(filePath)
+	if t.seen[key] {
+		return
+	}
+	t.seen[key] = true
+
+	switch category {
+	case OutOfTreeHome:
+		t.result.Home++
+	case OutOfTreeTemp:
+		t.result.Temp++
+	default:
+		t.result.External++
+	}
+	if len(t.result.Samples) < MaxOutOfTreeSamples {
+		t.result.Samples = append(t.result.Samples, OutOfTreePath{Path: filePath, Category: category})
+	}
+}
+
+// Result returns the accumulated access, with an empty (not nil) sample list.
+func (t *outOfTreeTracker) Result() OutOfTreeAccess {
+	result := t.result
+	if result.Samples == nil {
+		result.Samples = []OutOfTreePath{}
+	}
+	return result
+}
@@ -0,0 +1,134 @@
+package analytics
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestOutOfTreeCategory(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		cwd  string
+		want string
+	}{
+		{"file in cwd", "/home/dev/app/main.go", "/home/dev/app", ""},
+		{"cwd itself", "/home/dev/app", "/home/dev/app", ""},
+		{"relative in cwd", "internal/db/db.go", "/home/dev/app", ""},
+		{"dot relative in cwd", "./go.mod", "/home/dev/app", ""},
+		{"relative escaping cwd", "../other/x.go", "/home/dev/app", OutOfTreeHome},
+		{"absolute with dot-dot back in", "/home/dev/app/../app/x.go", "/home/dev/app", ""},
+		{"sibling sharing a prefix", "/home/dev/app-old/x.go", "/home/dev/app", OutOfTreeHome},
+		{"trailing slash on cwd", "/home/dev/app/x.go", "/home/dev/app/", ""},
+		{"home dotfile", "/home/dev/.ssh/config", "/home/dev/app", OutOfTreeHome},
+		{"tilde path", "~/.aws/credentials", "/home/dev/app", OutOfTreeHome},
+		{"tilde into cwd", "~/app/main.go", "/home/dev/app", ""},
+		{"tilde without known home", "~/.netrc", "/srv/app", OutOfTreeHome},
+		{"other user's home", "/home/ops/notes.txt", "/home/dev/app", OutOfTreeHome},
+		{"root home", "/root/.bashrc", "/srv/app", OutOfTreeHome},
+		{"tmp", "/tmp/build.log", "/home/dev/app", OutOfTreeTemp},
+		{"var tmp", "/var/tmp/x", "/home/dev/app", OutOfTreeTemp},
+		{"macOS temp", "/var/folders/xy/T/scratch.txt", "/Users/dev/app", OutOfTreeTemp},
+		{"macOS private tmp", "/private/tmp/x", "/Users/dev/app", OutOfTreeTemp},
+		{"macOS private alias of cwd", "/private/var/www/app/x.go", "/var/www/app", ""},
+		{"macOS home", "/Users/dev/Library/Preferences/x.plist", "/Users/dev/app", OutOfTreeHome},
+		{"system file", "/etc/passwd", "/home/dev/app", OutOfTreeExternal},
+		{"tmp-looking prefix", "/tmpfiles/x", "/home/dev/app", OutOfTreeExternal},
+		{"windows in cwd", `C:\Users\dev\app\main.go`, `C:\Users\dev\app`, ""},
+		{"windows case-insensitive", `c:\users\DEV\APP\main.go`, `C:\Users\dev\app`, ""},
+		{"windows relative", `src\main.go`, `C:\Users\dev\app`, ""},
+		{"windows forward slashes", "C:/Users/dev/app/main.go", `C:\Users\dev\app`, ""},
+		{"windows home", `C:\Users\dev\.gitconfig`, `C:\Users\dev\app`, OutOfTreeHome},
+		{"windows temp", `C:\Users\dev\AppData\Local\Temp\x.txt`, `C:\Users\dev\app`, OutOfTreeTemp},
+		{"windows system temp", `C:\Windows\Temp\x.txt`, `C:\Users\dev\app`, OutOfTreeTemp},
+		{"windows other drive", `D:\data\x.csv`, `C:\Users\dev\app`, OutOfTreeExternal},
+		{"empty cwd", "/etc/passwd", "", ""},
+		{"relative cwd", "/etc/passwd", "app", ""},
+		{"empty path", "", "/home/dev/app", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := outOfTreeCategory(tt.path, tt.cwd); got != tt.want {
+				t.Errorf("outOfTreeCategory(%q, %q) = %q, want %q", tt.path, tt.cwd, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodeActivityAnalyzer_OutOfTree(t *testing.T) {
+	// makeBaseFields records cwd "/test"; the analyzer is given the session's
+	// reported cwd instead.
+	mainJSONL := makeAssistantMessage("a1", "2025-01-01T00:00:01Z", "claude-sonnet-4", 100, 50, []map[string]interface{}{
+		makeToolUseBlock("toolu_1", "Read", map[string]interface{}{"file_path": "/work/app/main.go"}),
+		makeToolUseBlock("toolu_2", "Read", map[string]interface{}{"file_path": "/home/dev/.ssh/config"}),
+		makeToolUseBlock("toolu_3", "Write", map[string]interface{}{"file_path": "/tmp/out.txt", "content": "x"}),
+		makeToolUseBlock("toolu_4", "Edit", map[string]interface{}{"file_path": "/etc/hosts", "old_string": "a", "new_string": "b"}),
+		makeToolUseBlock("toolu_5", "Read", map[string]interface{}{"file_path": "/etc/hosts"}), // repeat
+		makeToolUseBlock("toolu_6", "Grep", map[string]interface{}{"path": "/etc"}),            // not a file access
+	}) + "\n"
+	agentJSONL := makeAssistantMessage("aa1", "2025-01-01T00:00:02Z", "claude-haiku-3", 50, 25, []map[string]interface{}{
+		makeToolUseBlock("toolu_a1", "Read", map[string]interface{}{"file_path": "../shared/lib.go"}),
+	}) + "\n"
+
+	fc, err := NewFileCollectionWithAgents([]byte(mainJSONL), map[string][]byte{"agent1": []byte(agentJSONL)})
+	if err != nil {
+		t.Fatalf("NewFileCollectionWithAgents failed: %v", err)
+	}
+	result, err := (&CodeActivityAnalyzer{cwd: "/work/app"}).Analyze(fc)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	got := result.OutOfTree
+	if got.Home != 1 || got.Temp != 1 || got.External != 2 {
+		t.Errorf("home/temp/external = %d/%d/%d, want 1/1/2", got.Home, got.Temp, got.External)
+	}
+	want := []OutOfTreePath{
+		{Path: "/home/dev/.ssh/config", Category: OutOfTreeHome},
+		{Path: "/tmp/out.txt", Category: OutOfTreeTemp},
+		{Path: "/etc/hosts", Category: OutOfTreeExternal},
+		{Path: "../shared/lib.go", Category: OutOfTreeExternal},
+	}
+	if fmt.Sprint(got.Samples) != fmt.Sprint(want) {
+		t.Errorf("samples = %v, want %v", got.Samples, want)
+	}
+}
+
+func TestCodeActivityAnalyzer_OutOfTreeFallsBackToTranscriptCwd(t *testing.T) {
+	// No session cwd: the transcript's cwd ("/test") is used.
+	jsonl := makeAssistantMessage("a1", "2025-01-01T00:00:01Z", "claude-sonnet-4", 100, 50, []map[string]interface{}{
+		makeToolUseBlock("toolu_1", "Read", map[string]interface{}{"file_path": "/test/main.go"}),
+		makeToolUseBlock("toolu_2", "Read", map[string]interface{}{"file_path": "/opt/other.go"}),
+	}) + "\n"
+	fc, err := NewFileCollection([]byte(jsonl))
+	if err != nil {
+		t.Fatalf("NewFileCollection failed: %v", err)
+	}
+	result, err := (&CodeActivityAnalyzer{}).Analyze(fc)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if got := result.OutOfTree; got.Total() != 1 || got.External != 1 {
+		t.Errorf("out of tree = %+v, want one external path", got)
+	}
+}
+
+func TestCodeActivityAnalyzer_OutOfTreeSamplesCapped(t *testing.T) {
+	var blocks []map[string]interface{}
+	for i := 0; i < MaxOutOfTreeSamples+5; i++ {
+		blocks = append(blocks, makeToolUseBlock(fmt.Sprintf("toolu_%d", i), "Read",
+			map[string]interface{}{"file_path": fmt.Sprintf("/opt/f%d.go", i)}))
+	}
+	jsonl := makeAssistantMessage("a1", "2025-01-01T00:00:01Z", "claude-sonnet-4", 100, 50, blocks) + "\n"
+	fc, err := NewFileCollection([]byte(jsonl))
+	if err != nil {
+		t.Fatalf("NewFileCollection failed: %v", err)
+	}
+	result, err := (&CodeActivityAnalyzer{cwd: "/work/app"}).Analyze(fc)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if got := result.OutOfTree; got.External != MaxOutOfTreeSamples+5 || len(got.Samples) != MaxOutOfTreeSamples {
+		t.Errorf("external = %d with %d samples, want %d with %d", got.External, len(got.Samples), MaxOutOfTreeSamples+5, MaxOutOfTreeSamples)
+	}
+}
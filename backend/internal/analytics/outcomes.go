@@ -0,0 +1,34 @@
+package analytics
+
+import "time"
+
+// Outcomes is the caller's sessions grouped by the pull request they produced
+// (GET /api/v1/analytics/outcomes), so spend can be read per shipped change.
+type Outcomes struct {
+	// Outcomes holds one entry per pull request, most recently active first.
+	// Always non-nil.
+	Outcomes []PROutcome `json:"outcomes"`
+}
+
+// PROutcome is one pull request's entry. Several sessions can point at the
+// same PR (a follow-up session addressing review comments, say); their cost
+// and duration add up here. Sessions not yet analyzed count with zero cost
+// and duration.
+type PROutcome struct {
+	// PRURL is the session's pr_url, or its detected_pr_url when none was
+	// set by hand.
+	PRURL        string `json:"pr_url"`
+	SessionCount int64  `json:"session_count"`
+	// TotalCostUSD is the sessions' summed estimated cost, as a decimal
+	// string with two places.
+	TotalCostUSD string `json:"total_cost_usd"`
+	// TotalDurationMs sums the sessions' durations.
+	TotalDurationMs int64 `json:"total_duration_ms"`
+	// FirstSessionAt is the earliest first_seen of the sessions.
+	FirstSessionAt time.Time `json:"first_session_at"`
+	// LastActivity is the latest last_message_at (first_seen until the
+	// first message) of the sessions.
+	LastActivity time.Time `json:"last_activity"`
+	// SessionIDs lists the sessions, oldest first.
+	SessionIDs []string `json:"session_ids"`
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // TranscriptLine represents a single line from a Claude Code transcript.
@@ -30,6 +31,10 @@ type TranscriptLine struct {
 	// risk-evaluating mode added in 2.1.143). Empty when absent on older lines.
 	PermissionMode string `json:"permissionMode,omitempty"`
 
+	// CWD is the working directory Claude Code stamps on each user/assistant
+	// row. Empty when absent.
+	CWD string `json:"cwd,omitempty"`
+
 	// For system messages
 	Subtype           string           `json:"subtype,omitempty"`           // e.g., "compact_boundary"
 	CompactMetadata   *CompactMetadata `json:"compactMetadata,omitempty"`   // Compaction info
@@ -38,6 +43,10 @@ type TranscriptLine struct {
 	// RawData holds the full parsed JSON for analyzers that need to walk the entire structure
 	// (e.g., redaction counting). Not serialized.
 	RawData interface{} `json:"-"`
+
+	// LineNumber is the 1-based line in the source JSONL file. Set by
+	// parseTranscriptFile; zero for lines built any other way. Not serialized.
+	LineNumber int `json:"-"`
 }
 
 // MessageContent contains message details for user/assistant messages.
@@ -108,6 +117,7 @@ type ToolUseResult struct {
 	Usage             *TokenUsage `json:"usage,omitempty"`             // Cumulative token usage for the agent
 	TotalTokens       int64       `json:"totalTokens,omitempty"`       // Total tokens used by the agent
 	TotalToolUseCount int         `json:"totalToolUseCount,omitempty"` // Number of tool calls made by the agent
+	TotalDurationMs   int64       `json:"totalDurationMs,omitempty"`   // Wall-clock run time of the agent
 
 	// Bash tool result fields (Claude Code >= 2.1.143). interrupted/isImage/
 	// noOutputExpected appear on every recent Bash result; persistedOutput* and
@@ -259,6 +269,50 @@ func (l *TranscriptLine) HasThinking() bool {
 	return false
 }
 
+// OutputChars splits the characters of an assistant line's content between
+// thinking blocks and visible output (text blocks, and tool_use names plus
+// their inputs as JSON). ok is false when a thinking block's text is not in
+// the transcript (redacted_thinking, or a thinking block carrying only its
+// signature), since its share of the output is then unknown.
+func (l *TranscriptLine) OutputChars() (thinking, visible int, ok bool) {
+	if l.Message == nil || l.Message.Content == nil {
+		return 0, 0, true
+	}
+	if s, isString := l.Message.Content.(string); isString {
+		return 0, utf8.RuneCountInString(s), true
+	}
+	contentArray, isArray := l.Message.Content.([]interface{})
+	if !isArray {
+		return 0, 0, true
+	}
+	for _, item := range contentArray {
+		blockMap, isMap := item.(map[string]interface{})
+		if !isMap {
+			continue
+		}
+		switch blockMap["type"] {
+		case "thinking":
+			text, _ := blockMap["thinking"].(string)
+			if text == "" {
+				return 0, 0, false
+			}
+			thinking += utf8.RuneCountInString(text)
+		case "redacted_thinking":
+			return 0, 0, false
+		case "text":
+			text, _ := blockMap["text"].(string)
+			visible += utf8.RuneCountInString(text)
+		case "tool_use":
+			name, _ := blockMap["name"].(string)
+			visible += utf8.RuneCountInString(name)
+			if input, err := json.Marshal(blockMap["input"]); err == nil {
+				visible += utf8.RuneCount(input)
+			}
+		}
+	}
+	return thinking, visible, true
+}
+
 // IsHumanMessage returns true if this is a user message with human-typed content (not tool_result).
 // This distinguishes actual user input from tool result messages which are also type "user".
 func (l *TranscriptLine) IsHumanMessage() bool {
@@ -380,6 +434,9 @@ func parseToolUseResult(m map[string]interface{}) *ToolUseResult {
 	if totalToolUseCount, ok := m["totalToolUseCount"].(float64); ok {
 		result.TotalToolUseCount = int(totalToolUseCount)
 	}
+	if totalDurationMs, ok := m["totalDurationMs"].(float64); ok {
+		result.TotalDurationMs = int64(totalDurationMs)
+	}
 
 	// Bash tool result fields (CC >= 2.1.143). Hand-extracted like the rest of
 	// this function — struct json tags do nothing here, so each field needs its
@@ -5,9 +5,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/features"
 	"github.com/ConfabulousDev/confab-web/internal/logger"
 	"github.com/ConfabulousDev/confab-web/internal/models"
 	"github.com/ConfabulousDev/confab-web/internal/recapquota"
@@ -80,6 +84,58 @@ func DefaultSmartRecapThresholds() StalenessThresholds {
 	}
 }
 
+// LoadStalenessThresholds loads staleness thresholds from environment variables with a prefix.
+// For example, with prefix "WORKER_REGULAR", it reads:
+// - WORKER_REGULAR_THRESHOLD_PCT (e.g., "0.20")
+// - WORKER_REGULAR_BASE_MIN_LINES (e.g., "5")
+// - WORKER_REGULAR_BASE_MIN_TIME (e.g., "3m")
+// - WORKER_REGULAR_MIN_INITIAL_LINES (e.g., "10")
+// - WORKER_REGULAR_MIN_SESSION_AGE (e.g., "10m")
+//
+// Invalid or out-of-range values silently keep the default. Used by the
+// worker to configure the precomputer and by the API server to report
+// whether a recompute is pending for a session's cards.
+func LoadStalenessThresholds(prefix string, defaults StalenessThresholds) StalenessThresholds {
+	th := defaults
+
+	// Parse threshold percentage (e.g., "0.20" for 20%)
+	if pctStr := os.Getenv(prefix + "_THRESHOLD_PCT"); pctStr != "" {
+		if pct, err := strconv.ParseFloat(pctStr, 64); err == nil && pct >= 0 && pct <= 1 {
+			th.ThresholdPct = pct
+		}
+	}
+
+	// Parse base minimum lines
+	if linesStr := os.Getenv(prefix + "_BASE_MIN_LINES"); linesStr != "" {
+		if lines, err := strconv.ParseInt(linesStr, 10, 64); err == nil && lines >= 0 {
+			th.BaseMinLines = lines
+		}
+	}
+
+	// Parse base minimum time (duration string like "3m", "15m")
+	if timeStr := os.Getenv(prefix + "_BASE_MIN_TIME"); timeStr != "" {
+		if dur, err := time.ParseDuration(timeStr); err == nil && dur >= 0 {
+			th.BaseMinTime = dur
+		}
+	}
+
+	// Parse minimum initial lines
+	if linesStr := os.Getenv(prefix + "_MIN_INITIAL_LINES"); linesStr != "" {
+		if lines, err := strconv.ParseInt(linesStr, 10, 64); err == nil && lines >= 0 {
+			th.MinInitialLines = lines
+		}
+	}
+
+	// Parse minimum session age (duration string like "10m")
+	if ageStr := os.Getenv(prefix + "_MIN_SESSION_AGE"); ageStr != "" {
+		if dur, err := time.ParseDuration(ageStr); err == nil && dur >= 0 {
+			th.MinSessionAge = dur
+		}
+	}
+
+	return th
+}
+
 // PrecomputeConfig holds configuration for the precomputer.
 type PrecomputeConfig struct {
 	SmartRecapEnabled  bool
@@ -95,6 +151,18 @@ type PrecomputeConfig struct {
 	// Staleness thresholds for each bucket
 	RegularCardsThresholds StalenessThresholds
 	SmartRecapThresholds   StalenessThresholds
+
+	// SmartRecapMinInterval is the least time since a recap's computed_at
+	// before StreamStaleSmartRecapSessions re-selects the session because it
+	// grew, however large the line gap. Version mismatches and admin
+	// regenerations are not held back. 0 disables.
+	SmartRecapMinInterval time.Duration
+
+	// WarmActiveWindow enables cache warming for regular cards: for users with
+	// web activity within this window, the most recently synced session is
+	// recomputed as soon as it has any new lines, ahead of the thresholds
+	// above, so the dashboard they are looking at stays fresh. 0 disables.
+	WarmActiveWindow time.Duration
 }
 
 // Precomputer handles background analytics precomputation.
@@ -104,11 +172,13 @@ type Precomputer struct {
 	analyticsStore      *Store
 	config              PrecomputeConfig
 	smartRecapGenerator *SmartRecapGenerator
+	sessionStore        *dbsession.Store // nil without the wrapped DB; skips data_missing transitions
 }
 
 // NewPrecomputer creates a new Precomputer.
 // The database parameter is optional; if provided, it enables custom prompt
-// lookups in the smart recap generator. Pass nil in tests that don't need this.
+// lookups in the smart recap generator and data_missing state transitions.
+// Pass nil in tests that don't need this.
 func NewPrecomputer(rawDB *sql.DB, store *storage.S3Storage, analyticsStore *Store, config PrecomputeConfig, database ...*db.DB) *Precomputer {
 	p := &Precomputer{
 		db:             rawDB,
@@ -123,6 +193,9 @@ func NewPrecomputer(rawDB *sql.DB, store *storage.S3Storage, analyticsStore *Sto
 	if len(database) > 0 {
 		wrappedDB = database[0]
 	}
+	if wrappedDB != nil {
+		p.sessionStore = &dbsession.Store{DB: wrappedDB}
+	}
 	if config.SmartRecapEnabled && wrappedDB != nil {
 		p.smartRecapGenerator = NewSmartRecapGenerator(
 			analyticsStore,
@@ -140,14 +213,25 @@ func NewPrecomputer(rawDB *sql.DB, store *storage.S3Storage, analyticsStore *Sto
 	return p
 }
 
-// FindStaleSessions returns sessions where any card is stale based on configurable
-// staleness thresholds. The algorithm prioritizes:
+// StreamStaleSessions calls fn, in order, for each session where any card is
+// stale based on configurable staleness thresholds, reading rows as fn consumes
+// them instead of buffering the batch. It stops at the first error fn returns
+// and returns that error. The query stays open until fn has seen the last row,
+// so fn should not take long. The algorithm prioritizes:
 // 1. New sessions (no cards) with enough content or old enough
 // 2. Version mismatches (always recompute)
 // 3. Line gap or time gap exceeds threshold
 //
 // Sessions are ordered by: new sessions → version mismatch → largest line gap → last_sync_at
-func (p *Precomputer) FindStaleSessions(ctx context.Context, limit int) ([]StaleSession, error) {
+//
+// When PrecomputeConfig.WarmActiveWindow is set, each recently active user's
+// most recently synced session is also returned whenever it has uncomputed
+// lines (or missing cards), regardless of thresholds, and such warm sessions
+// sort ahead of everything else.
+//
+// Session metadata (title, summary, first user message) is deliberately not an
+// input: edits to it only stale the search index (see FindStaleSearchIndexSessions).
+func (p *Precomputer) StreamStaleSessions(ctx context.Context, limit int, fn func(StaleSession) error) error {
 	ctx, span := tracer.Start(ctx, "precompute.find_stale_sessions",
 		trace.WithAttributes(attribute.Int("limit", limit)))
 	defer span.End()
@@ -164,6 +248,9 @@ func (p *Precomputer) FindStaleSessions(ctx context.Context, limit int) ([]Stale
 	// line_gap = total_lines - min_up_to_line
 	// prior_duration = min_computed_at - first_seen (time covered by existing cards)
 	// time_gap = NOW() - min_computed_at
+	//
+	// 4. Warm-up (WarmActiveWindow > 0): the newest session of each user with a
+	//    live web session active within the window, whenever line_gap > 0.
 	query := `
 		WITH session_lines AS (
 			SELECT session_id, SUM(last_synced_line) as total_lines
@@ -172,6 +259,20 @@ func (p *Precomputer) FindStaleSessions(ctx context.Context, limit int) ([]Stale
 			GROUP BY session_id
 			HAVING SUM(last_synced_line) > 0
 		),
+		-- Most recently synced session per recently active user. Empty when
+		-- warming is disabled ($16 = 0).
+		warm_sessions AS (
+			SELECT DISTINCT ON (s.user_id) s.id AS session_id
+			FROM sessions s
+			WHERE $16::float8 > 0
+			  AND s.merged_at IS NULL
+			  AND s.user_id IN (
+				SELECT ws.user_id FROM web_sessions ws
+				WHERE ws.expires_at > NOW()
+				  AND COALESCE(ws.last_activity_at, ws.created_at) > NOW() - make_interval(secs => $16)
+			  )
+			ORDER BY s.user_id, COALESCE(s.last_sync_at, s.first_seen) DESC
+		),
 		card_status AS (
 			SELECT
 				sl.session_id,
@@ -184,6 +285,7 @@ func (p *Precomputer) FindStaleSessions(ctx context.Context, limit int) ([]Stale
 				CASE WHEN tv.session_id IS NOT NULL AND sc.session_id IS NOT NULL AND tl.session_id IS NOT NULL
 				     AND ca.session_id IS NOT NULL AND cv.session_id IS NOT NULL AND as_card.session_id IS NOT NULL
 				     AND rd.session_id IS NOT NULL AND wf.session_id IS NOT NULL
+				     AND (cc.session_id IS NOT NULL OR NOT flags.cc_on)
 				THEN TRUE ELSE FALSE END AS all_cards_exist,
 				-- Check if any existing card has wrong version (only meaningful when all cards exist)
 				CASE WHEN (tv.session_id IS NOT NULL AND tv.version != $1)
@@ -194,24 +296,30 @@ func (p *Precomputer) FindStaleSessions(ctx context.Context, limit int) ([]Stale
 				     OR (as_card.session_id IS NOT NULL AND as_card.version != $6)
 				     OR (rd.session_id IS NOT NULL AND rd.version != $7)
 				     OR (wf.session_id IS NOT NULL AND wf.version != $15)
+				     OR (flags.cc_on AND cc.session_id IS NOT NULL AND cc.version != $17)
 				THEN TRUE ELSE FALSE END AS has_version_mismatch,
-				-- Minimum up_to_line across all cards (most stale point)
+				-- Minimum up_to_line across all cards (most stale point). A
+				-- gated-off card is NULL, which LEAST ignores.
 				LEAST(
 					COALESCE(tv.up_to_line, 0), COALESCE(sc.up_to_line, 0),
 					COALESCE(tl.up_to_line, 0), COALESCE(ca.up_to_line, 0),
 					COALESCE(cv.up_to_line, 0), COALESCE(as_card.up_to_line, 0),
-					COALESCE(rd.up_to_line, 0), COALESCE(wf.up_to_line, 0)
+					COALESCE(rd.up_to_line, 0), COALESCE(wf.up_to_line, 0),
+					CASE WHEN flags.cc_on THEN COALESCE(cc.up_to_line, 0) END
 				) AS min_up_to_line,
 				-- Oldest computed_at across all cards (earliest computation)
 				LEAST(
 					COALESCE(tv.computed_at, NOW()), COALESCE(sc.computed_at, NOW()),
 					COALESCE(tl.computed_at, NOW()), COALESCE(ca.computed_at, NOW()),
 					COALESCE(cv.computed_at, NOW()), COALESCE(as_card.computed_at, NOW()),
-					COALESCE(rd.computed_at, NOW()), COALESCE(wf.computed_at, NOW())
+					COALESCE(rd.computed_at, NOW()), COALESCE(wf.computed_at, NOW()),
+					CASE WHEN flags.cc_on THEN COALESCE(cc.computed_at, NOW()) END
 				) AS min_computed_at,
-				s.last_sync_at
+				s.last_sync_at,
+				(warm.session_id IS NOT NULL) AS is_warm
 			FROM session_lines sl
 			JOIN sessions s ON sl.session_id = s.id
+			LEFT JOIN warm_sessions warm ON sl.session_id = warm.session_id
 			LEFT JOIN session_card_session sc ON sl.session_id = sc.session_id
 			LEFT JOIN session_card_tools tl ON sl.session_id = tl.session_id
 			LEFT JOIN session_card_code_activity ca ON sl.session_id = ca.session_id
@@ -219,13 +327,20 @@ func (p *Precomputer) FindStaleSessions(ctx context.Context, limit int) ([]Stale
 			LEFT JOIN session_card_agents_and_skills as_card ON sl.session_id = as_card.session_id
 			LEFT JOIN session_card_redactions rd ON sl.session_id = rd.session_id
 			LEFT JOIN session_card_workflows wf ON sl.session_id = wf.session_id
+			LEFT JOIN session_card_code_changes cc ON sl.session_id = cc.session_id
 			LEFT JOIN session_card_tokens_v2 tv ON sl.session_id = tv.session_id
-			-- Provider filter: pq.Array(models.AllowedProviders) is the
-			-- permanent allowlist (canonical forms + legacy aliases). See
-			-- internal/models/provider.go for the OSS self-hosted aliasing
-			-- rationale; TestRegistryCoversAllowedProviders is the guard
-			-- against drift between this list and the analytics registry.
+			-- Flag-gated cards (cardOp.featureFlag) only count for users the
+			-- flag is on for; for everyone else they are never written.
+			CROSS JOIN LATERAL (SELECT ` + features.EnabledSQL("s.user_id", "$18") + ` AS cc_on) flags
+			-- Provider filter: registeredSessionTypes() is every name in the
+			-- analytics registry (canonical forms + legacy aliases + any
+			-- pluggable session type), so eligibility is exactly "a handler
+			-- exists". See internal/models/provider.go for the OSS self-hosted
+			-- aliasing rationale; TestRegistryCoversAllowedProviders guards
+			-- that the registry is a superset of models.AllowedProviders.
 			WHERE s.session_type = ANY($14)
+			  -- Archived transcripts can't be re-read; their cards are final.
+			  AND s.transcript_archived_at IS NULL
 		),
 		stale_sessions AS (
 			SELECT
@@ -265,13 +380,18 @@ func (p *Precomputer) FindStaleSessions(ctx context.Context, limit int) ([]Stale
 				-- OR time gap meets threshold: MAX(base_min_time, prior_duration * pct)
 				OR time_gap_secs >= GREATEST($10::float8, prior_duration_secs * $9::float8)
 			))
+			-- Case 4: Warm-up - an active user's newest session with anything to compute
+			OR (is_warm AND (all_cards_exist = FALSE OR line_gap > 0))
 		ORDER BY
+			is_warm DESC,                 -- Warm-up sessions first (someone is likely looking)
 			staleness_category,           -- New sessions first, then version mismatches, then threshold
 			line_gap DESC NULLS LAST,     -- Largest line gap within category
 			last_sync_at DESC NULLS LAST  -- Most recently synced as tie-breaker
 		LIMIT $13
 	`
 
+	sessionTypesArg := pq.Array(registeredSessionTypes())
+	warmWindowSecs := p.config.WarmActiveWindow.Seconds()
 	rows, err := p.db.QueryContext(ctx, query,
 		TokensV2CardVersion,               // $1
 		SessionCardVersion,                // $2
@@ -286,8 +406,143 @@ func (p *Precomputer) FindStaleSessions(ctx context.Context, limit int) ([]Stale
 		th.MinInitialLines,                // $11
 		th.MinSessionAge.Seconds(),        // $12
 		limit,                             // $13
-		pq.Array(models.AllowedProviders), // $14
+		sessionTypesArg,                   // $14
 		WorkflowsCardVersion,              // $15
+		warmWindowSecs,                    // $16
+		CodeChangesCardVersion,            // $17
+		CodeChangesCardFlag,               // $18
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	defer rows.Close()
+
+	found := 0
+	for rows.Next() {
+		var s StaleSession
+		var rawProvider string
+		if err := rows.Scan(&s.SessionID, &s.UserID, &s.ExternalID, &rawProvider, &s.TotalLines, &s.CreatedAt); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		s.Provider = models.NormalizeProvider(rawProvider)
+		found++
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetAttributes(attribute.Int("sessions.found", found))
+	return nil
+}
+
+// RefreshModelPricing reloads the model_pricing table the cards are priced
+// from (see RefreshModelPricing in model_pricing.go).
+func (p *Precomputer) RefreshModelPricing(ctx context.Context) error {
+	return RefreshModelPricing(ctx, p.db)
+}
+
+// FindStaleSessions collects StreamStaleSessions into a slice. The worker uses
+// this form: it sizes its next batch and schedules recaps per user from the
+// whole batch, and must not hold the query open while computing cards.
+func (p *Precomputer) FindStaleSessions(ctx context.Context, limit int) ([]StaleSession, error) {
+	var sessions []StaleSession
+	if err := p.StreamStaleSessions(ctx, limit, func(s StaleSession) error {
+		sessions = append(sessions, s)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// DormantSessionAge is how long a session must go without a sync before
+// FindDormantStaleSessions considers it dormant.
+const DormantSessionAge = 7 * 24 * time.Hour
+
+// FindDormantStaleSessions returns sessions that have not synced for
+// DormantSessionAge and have any stale card: a card missing, on an old
+// version, or computed short of the session's current line count. Unlike
+// FindStaleSessions there are no thresholds, so the last few lines of a
+// session that went quiet still get computed. Most recently active first.
+//
+// The worker only asks for these once the active queues come back empty, so
+// nobody who is using the dashboard waits behind the catch-up. The card set
+// and filters, including flag-gated cards, match FindStaleSessions; keep the
+// two in sync.
+func (p *Precomputer) FindDormantStaleSessions(ctx context.Context, limit int) ([]StaleSession, error) {
+	ctx, span := tracer.Start(ctx, "precompute.find_dormant_stale_sessions",
+		trace.WithAttributes(attribute.Int("limit", limit)))
+	defer span.End()
+
+	// last_sync_at and first_seen are bare UTC TIMESTAMPs.
+	cutoff := time.Now().UTC().Add(-DormantSessionAge)
+
+	query := `
+		WITH session_lines AS (
+			SELECT session_id, SUM(last_synced_line) as total_lines
+			FROM sync_files
+			WHERE file_type IN ('transcript', 'agent')
+			GROUP BY session_id
+			HAVING SUM(last_synced_line) > 0
+		)
+		SELECT sl.session_id, s.user_id, s.external_id, s.session_type, sl.total_lines, s.first_seen
+		FROM session_lines sl
+		JOIN sessions s ON sl.session_id = s.id
+		LEFT JOIN session_card_session sc ON sl.session_id = sc.session_id
+		LEFT JOIN session_card_tools tl ON sl.session_id = tl.session_id
+		LEFT JOIN session_card_code_activity ca ON sl.session_id = ca.session_id
+		LEFT JOIN session_card_conversation cv ON sl.session_id = cv.session_id
+		LEFT JOIN session_card_agents_and_skills as_card ON sl.session_id = as_card.session_id
+		LEFT JOIN session_card_redactions rd ON sl.session_id = rd.session_id
+		LEFT JOIN session_card_workflows wf ON sl.session_id = wf.session_id
+		LEFT JOIN session_card_code_changes cc ON sl.session_id = cc.session_id
+		LEFT JOIN session_card_tokens_v2 tv ON sl.session_id = tv.session_id
+		CROSS JOIN LATERAL (SELECT ` + features.EnabledSQL("s.user_id", "$13") + ` AS cc_on) flags
+		WHERE s.session_type = ANY($11)
+		  AND s.transcript_archived_at IS NULL
+		  AND COALESCE(s.last_sync_at, s.first_seen) < $12
+		  AND (
+			-- Any card missing
+			tv.session_id IS NULL OR sc.session_id IS NULL OR tl.session_id IS NULL
+			OR ca.session_id IS NULL OR cv.session_id IS NULL OR as_card.session_id IS NULL
+			OR rd.session_id IS NULL OR wf.session_id IS NULL OR (flags.cc_on AND cc.session_id IS NULL)
+			-- Any card on an old version
+			OR tv.version != $1 OR sc.version != $2 OR tl.version != $3
+			OR ca.version != $4 OR cv.version != $5 OR as_card.version != $6
+			OR rd.version != $7 OR wf.version != $8 OR (flags.cc_on AND cc.version != $9)
+			-- Any card behind the synced lines
+			OR LEAST(tv.up_to_line, sc.up_to_line, tl.up_to_line, ca.up_to_line, cv.up_to_line,
+				as_card.up_to_line, rd.up_to_line, wf.up_to_line,
+				CASE WHEN flags.cc_on THEN cc.up_to_line END) < sl.total_lines
+		  )
+		ORDER BY COALESCE(s.last_sync_at, s.first_seen) DESC
+		LIMIT $10
+	`
+
+	rows, err := p.db.QueryContext(ctx, query,
+		TokensV2CardVersion,                // $1
+		SessionCardVersion,                 // $2
+		ToolsCardVersion,                   // $3
+		CodeActivityCardVersion,            // $4
+		ConversationCardVersion,            // $5
+		AgentsAndSkillsCardVersion,         // $6
+		RedactionsCardVersion,              // $7
+		WorkflowsCardVersion,               // $8
+		CodeChangesCardVersion,             // $9
+		limit,                              // $10
+		pq.Array(registeredSessionTypes()), // $11
+		cutoff,                             // $12
+		CodeChangesCardFlag,                // $13
 	)
 	if err != nil {
 		span.RecordError(err)
@@ -319,6 +574,24 @@ func (p *Precomputer) FindStaleSessions(ctx context.Context, limit int) ([]Stale
 	return sessions, nil
 }
 
+// markDataMissing moves a session whose sync files count lines but whose
+// transcript can't be read from storage to data_missing. Best-effort: a
+// failure is logged, and a forbidden transition (e.g. an archived session)
+// is expected and ignored.
+func (p *Precomputer) markDataMissing(ctx context.Context, session StaleSession) {
+	if p.sessionStore == nil {
+		return
+	}
+	changed, err := p.sessionStore.TransitionState(ctx, session.SessionID, dbsession.StateDataMissing, dbsession.StateReasonDataMissing)
+	if err != nil && !errors.Is(err, db.ErrInvalidStateTransition) && !errors.Is(err, db.ErrSessionNotFound) {
+		logger.Ctx(ctx).Error("failed to mark session data_missing", "error", err)
+		return
+	}
+	if changed {
+		logger.Ctx(ctx).Warn("session has synced lines but no stored transcript", "total_lines", session.TotalLines)
+	}
+}
+
 // PrecomputeRegularCards computes only the regular analytics cards for a
 // session. Smart recap is handled separately via PrecomputeSmartRecapOnly with
 // its own staleness thresholds.
@@ -351,6 +624,9 @@ func (p *Precomputer) PrecomputeRegularCards(ctx context.Context, session StaleS
 	}
 	if rollout == nil {
 		span.SetAttributes(attribute.Bool("session.empty", true))
+		if session.TotalLines > 0 {
+			p.markDataMissing(ctx, session)
+		}
 		return nil
 	}
 
@@ -366,16 +642,49 @@ func (p *Precomputer) PrecomputeRegularCards(ctx context.Context, session StaleS
 	}
 
 	cards := computed.ToCards(session.SessionID, session.TotalLines)
-	if err := p.analyticsStore.UpsertCards(ctx, cards); err != nil {
+	if err := cards.ApplyFeatureFlags(ctx, p.db, session.UserID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	// The projection is built before the write transaction so its read of
+	// prior sessions doesn't hold the card rows locked.
+	projection, err := p.analyticsStore.BuildCostProjection(ctx, session.SessionID, cards.TokensV2)
+	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
+	if err := p.analyticsStore.SaveComputedCards(ctx, session.SessionID, ComputedCardSet{
+		Cards:             cards,
+		ConversationTurns: computed.ConversationTurns,
+		RedactionEvents:   computed.RedactionEvents,
+		TokenSeries:       computed.TokenSeries,
+		CostProjection:    projection,
+		DetectedPRURL:     computed.DetectedPRURL,
+	}); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	p.refreshInterestScore(ctx, session.SessionID)
 
 	span.SetAttributes(attribute.Bool("session.computed", true))
 	return nil
 }
 
+// refreshInterestScore rescores the session for sort=interesting from the
+// cards just saved. A failure only leaves the previous score in place until
+// the next card update, so it is logged rather than returned.
+func (p *Precomputer) refreshInterestScore(ctx context.Context, sessionID string) {
+	if p.sessionStore == nil {
+		return
+	}
+	if err := p.sessionStore.RefreshInterestScore(ctx, sessionID); err != nil {
+		logger.Ctx(ctx).Warn("failed to refresh interest score", "error", err, "session_id", sessionID)
+	}
+}
+
 func (p *Precomputer) parseInput(session StaleSession) ParseInput {
 	return ParseInput{
 		DB:         p.db,
@@ -446,6 +755,7 @@ func (p *Precomputer) precomputeSmartRecap(ctx context.Context, session StaleSes
 		span.SetStatus(codes.Error, result.Error.Error())
 		return result.Error
 	}
+	p.refreshInterestScore(ctx, session.SessionID)
 
 	span.SetAttributes(
 		attribute.Bool("smart_recap.generated", true),
@@ -455,21 +765,29 @@ func (p *Precomputer) precomputeSmartRecap(ctx context.Context, session StaleSes
 	return nil
 }
 
-// FindStaleSmartRecapSessions returns sessions where smart recap is stale but regular cards are up-to-date.
+// StreamStaleSmartRecapSessions calls fn, in order, for each session where smart
+// recap is stale but regular cards are up-to-date, like StreamStaleSessions.
 // Smart recap is stale based on configurable staleness thresholds:
 // 1. Missing smart recap with enough content or old enough session
 // 2. Version mismatch (always recompute)
-// 3. Line gap or time gap exceeds threshold
+// 3. Line gap or time gap exceeds threshold, and PrecomputeConfig.SmartRecapMinInterval
+// has passed since the recap was computed
 //
-// This complements FindStaleSessions which finds sessions with stale regular cards.
-func (p *Precomputer) FindStaleSmartRecapSessions(ctx context.Context, limit int) ([]StaleSession, error) {
+// This complements StreamStaleSessions which finds sessions with stale regular cards.
+//
+// Results are interleaved across users: each user's stale sessions are ranked by
+// staleness priority, and the list is ordered by that per-user rank first, so a
+// user with a large backlog (e.g. after a version bump) gets one slot per round
+// rather than the whole batch. The worker's per-user in-flight cap relies on this
+// order to keep dispatch round-robin.
+func (p *Precomputer) StreamStaleSmartRecapSessions(ctx context.Context, limit int, fn func(StaleSession) error) error {
 	ctx, span := tracer.Start(ctx, "precompute.find_stale_smart_recap_sessions",
 		trace.WithAttributes(attribute.Int("limit", limit)))
 	defer span.End()
 
 	if !p.config.SmartRecapEnabled {
 		span.SetAttributes(attribute.Bool("smart_recap.disabled", true))
-		return nil, nil
+		return nil
 	}
 
 	th := p.config.SmartRecapThresholds
@@ -575,11 +893,9 @@ func (p *Precomputer) FindStaleSmartRecapSessions(ctx context.Context, limit int
 				AND sq.quota_month = TO_CHAR(NOW() AT TIME ZONE 'UTC', 'YYYY-MM')
 			LEFT JOIN regen_ts rt ON TRUE
 			LEFT JOIN admin_invalidations ai ON ai.session_id = sl.session_id
-			-- Provider filter: pq.Array(models.AllowedProviders) is the
-			-- permanent allowlist (canonical forms + legacy aliases). See
-			-- internal/models/provider.go for the OSS self-hosted aliasing
-			-- rationale.
+			-- Provider filter: registeredSessionTypes() — see FindStaleSessions.
 			WHERE s.session_type = ANY($16)
+				AND s.transcript_archived_at IS NULL
 				-- Quota check: skip for category 4 (global admin regen) and for
 				-- per-session admin invalidations (CF-343). Bypass clauses OR together.
 				AND (
@@ -592,34 +908,49 @@ func (p *Precomputer) FindStaleSmartRecapSessions(ctx context.Context, limit int
 					OR (ai.last_invalidated_at IS NOT NULL
 						AND (sr.session_id IS NULL OR sr.computed_at < ai.last_invalidated_at))
 				)
+		),
+		stale AS (
+			SELECT *,
+				-- Per-user priority rank; the outer ORDER BY interleaves users on it.
+				ROW_NUMBER() OVER (
+					PARTITION BY user_id
+					ORDER BY staleness_category, line_gap DESC NULLS LAST, last_sync_at DESC NULLS LAST
+				) AS user_rank
+			FROM recap_status
+			WHERE
+				-- Case 1: Missing smart recap with enough content OR old enough
+				(is_missing = TRUE AND (
+					total_lines >= $12::bigint  -- min_initial_lines
+					OR session_age_secs >= $13::float8  -- min_session_age in seconds
+				))
+				-- Case 2: Version mismatch - always recompute
+				OR (is_missing = FALSE AND has_version_mismatch = TRUE)
+				-- Case 3: Existing card with line_gap > 0 that meets threshold,
+				-- and at least min_interval since the recap was computed
+				OR (is_missing = FALSE AND has_version_mismatch = FALSE AND line_gap > 0
+					AND time_gap_secs >= $17::float8 AND (
+					-- Line gap meets threshold
+					line_gap >= line_threshold
+					-- OR time gap meets threshold: MAX(base_min_time, prior_duration * pct)
+					OR time_gap_secs >= GREATEST($11::float8, prior_duration_secs * $10::float8)
+				))
+				-- Case 4: Admin-triggered regeneration (computed_at < regen_requested_at)
+				OR needs_admin_regen = TRUE
 		)
 		SELECT session_id, user_id, external_id, session_type, total_lines, first_seen,
 			CASE WHEN needs_admin_regen THEN regen_requested_at ELSE NULL END AS regen_requested_at
-		FROM recap_status
-		WHERE
-			-- Case 1: Missing smart recap with enough content OR old enough
-			(is_missing = TRUE AND (
-				total_lines >= $12::bigint  -- min_initial_lines
-				OR session_age_secs >= $13::float8  -- min_session_age in seconds
-			))
-			-- Case 2: Version mismatch - always recompute
-			OR (is_missing = FALSE AND has_version_mismatch = TRUE)
-			-- Case 3: Existing card with line_gap > 0 that meets threshold
-			OR (is_missing = FALSE AND has_version_mismatch = FALSE AND line_gap > 0 AND (
-				-- Line gap meets threshold
-				line_gap >= line_threshold
-				-- OR time gap meets threshold: MAX(base_min_time, prior_duration * pct)
-				OR time_gap_secs >= GREATEST($11::float8, prior_duration_secs * $10::float8)
-			))
-			-- Case 4: Admin-triggered regeneration (computed_at < regen_requested_at)
-			OR needs_admin_regen = TRUE
+		FROM stale
 		ORDER BY
+			user_rank,                    -- Round-robin: every user's top session before anyone's second
 			staleness_category,           -- Missing first, then version mismatches, then threshold, then admin regen
 			line_gap DESC NULLS LAST,     -- Largest line gap within category
-			last_sync_at DESC NULLS LAST  -- Most recently synced as tie-breaker
+			last_sync_at DESC NULLS LAST, -- Most recently synced as tie-breaker
+			user_id                       -- Stable order between users within a round
 		LIMIT $14
 	`
 
+	sessionTypesArg := pq.Array(registeredSessionTypes())
+	minIntervalSecs := p.config.SmartRecapMinInterval.Seconds()
 	rows, err := p.db.QueryContext(ctx, query,
 		TokensV2CardVersion,               // $1
 		SessionCardVersion,                // $2
@@ -636,46 +967,86 @@ func (p *Precomputer) FindStaleSmartRecapSessions(ctx context.Context, limit int
 		th.MinSessionAge.Seconds(),        // $13
 		limit,                             // $14
 		p.config.SmartRecapQuota,          // $15
-		pq.Array(models.AllowedProviders), // $16
+		sessionTypesArg,                   // $16
+		minIntervalSecs,                   // $17
 	)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var sessions []StaleSession
+	found := 0
 	for rows.Next() {
 		var s StaleSession
 		var rawProvider string
 		if err := rows.Scan(&s.SessionID, &s.UserID, &s.ExternalID, &rawProvider, &s.TotalLines, &s.CreatedAt, &s.RegenRequestedAt); err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, err.Error())
-			return nil, err
+			return err
 		}
 		s.Provider = models.NormalizeProvider(rawProvider)
-		sessions = append(sessions, s)
+		found++
+		if err := fn(s); err != nil {
+			return err
+		}
 	}
 
 	if err := rows.Err(); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return nil, err
+		return err
 	}
 
-	span.SetAttributes(attribute.Int("sessions.found", len(sessions)))
+	span.SetAttributes(attribute.Int("sessions.found", found))
+	return nil
+}
+
+// FindStaleSmartRecapSessions collects StreamStaleSmartRecapSessions into a slice.
+func (p *Precomputer) FindStaleSmartRecapSessions(ctx context.Context, limit int) ([]StaleSession, error) {
+	var sessions []StaleSession
+	if err := p.StreamStaleSmartRecapSessions(ctx, limit, func(s StaleSession) error {
+		sessions = append(sessions, s)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
 	return sessions, nil
 }
 
-// FindStaleSearchIndexSessions returns sessions where the search index is stale
-// but all 7 regular cards are up-to-date. A session's search index is stale when:
+// searchIndexStaleCondition is the SQL condition for a stale search index,
+// cases 1-5 of StreamStaleSearchIndexSessions. It expects the aliases s
+// (sessions), u (users), sl (total_lines), si (session_search_index) and sr
+// (session_card_smart_recap), the last two LEFT JOINed, and takes the
+// placeholder carrying SearchIndexVersion. GetSearchIndexStatus shares it, so
+// a single session is judged as the worker would.
+func searchIndexStaleCondition(versionParam string) string {
+	return `(
+			-- 1. Never indexed
+			si.session_id IS NULL
+			-- 2. Version mismatch
+			OR si.version != ` + versionParam + `
+			-- 3. Transcript grew
+			OR si.indexed_up_to_line < sl.total_lines
+			-- 4. Recap changed (recap exists but not yet indexed, or recap recomputed after indexing)
+			OR (sr.session_id IS NOT NULL AND (si.recap_indexed_at IS NULL OR sr.computed_at > si.recap_indexed_at))
+			-- 5. Metadata changed (or the owner toggled agent-file indexing)
+			OR si.metadata_hash != MD5(COALESCE(s.custom_title, '') || '|' || COALESCE(s.suggested_session_title, '') || '|' || COALESCE(s.summary, '') || '|' || COALESCE(s.first_user_message, '')
+				|| CASE WHEN u.include_agent_files_in_search THEN '|agents' ELSE '' END)
+		)`
+}
+
+// StreamStaleSearchIndexSessions calls fn, in order, for each session where the
+// search index is stale but all 7 regular cards are up-to-date, like
+// StreamStaleSessions. A session's search index is stale when:
 // 1. No index exists (never indexed)
 // 2. Version mismatch (search logic changed)
 // 3. Transcript grew (indexed_up_to_line < total_lines)
 // 4. Recap changed (recap computed_at > recap_indexed_at, or recap exists but not indexed)
-// 5. Metadata changed (MD5 hash mismatch on titles/summary/first_user_message)
-func (p *Precomputer) FindStaleSearchIndexSessions(ctx context.Context, limit int) ([]StaleSession, error) {
+// 5. Metadata changed (MD5 hash mismatch on titles/summary/first_user_message,
+// plus the owner's include_agent_files_in_search setting — see extractMetadata)
+func (p *Precomputer) StreamStaleSearchIndexSessions(ctx context.Context, limit int, fn func(StaleSession) error) error {
 	ctx, span := tracer.Start(ctx, "precompute.find_stale_search_index_sessions",
 		trace.WithAttributes(attribute.Int("limit", limit)))
 	defer span.End()
@@ -691,6 +1062,7 @@ func (p *Precomputer) FindStaleSearchIndexSessions(ctx context.Context, limit in
 		SELECT sl.session_id, s.user_id, s.external_id, s.session_type, sl.total_lines, s.first_seen
 		FROM session_lines sl
 		JOIN sessions s ON sl.session_id = s.id
+		JOIN users u ON s.user_id = u.id
 		-- All 7 regular cards must be current
 		JOIN session_card_tokens_v2 tv ON sl.session_id = tv.session_id
 			AND tv.version = $1 AND tv.up_to_line = sl.total_lines
@@ -708,27 +1080,15 @@ func (p *Precomputer) FindStaleSearchIndexSessions(ctx context.Context, limit in
 			AND rd.version = $7 AND rd.up_to_line = sl.total_lines
 		LEFT JOIN session_search_index si ON sl.session_id = si.session_id
 		LEFT JOIN session_card_smart_recap sr ON sl.session_id = sr.session_id
-		-- Provider filter: pq.Array(models.AllowedProviders) is the
-		-- permanent allowlist (canonical forms + legacy aliases). See
-		-- internal/models/provider.go for the OSS self-hosted aliasing
-		-- rationale.
+		-- Provider filter: registeredSessionTypes() — see FindStaleSessions.
 		WHERE s.session_type = ANY($10)
-		  AND (
-			-- 1. Never indexed
-			si.session_id IS NULL
-			-- 2. Version mismatch
-			OR si.version != $8
-			-- 3. Transcript grew
-			OR si.indexed_up_to_line < sl.total_lines
-			-- 4. Recap changed (recap exists but not yet indexed, or recap recomputed after indexing)
-			OR (sr.session_id IS NOT NULL AND (si.recap_indexed_at IS NULL OR sr.computed_at > si.recap_indexed_at))
-			-- 5. Metadata changed
-			OR si.metadata_hash != MD5(COALESCE(s.custom_title, '') || '|' || COALESCE(s.suggested_session_title, '') || '|' || COALESCE(s.summary, '') || '|' || COALESCE(s.first_user_message, ''))
-		  )
+		  AND s.transcript_archived_at IS NULL
+		  AND ` + searchIndexStaleCondition("$8") + `
 		ORDER BY s.last_sync_at DESC NULLS LAST
 		LIMIT $9
 	`
 
+	sessionTypesArg := pq.Array(registeredSessionTypes())
 	rows, err := p.db.QueryContext(ctx, query,
 		TokensV2CardVersion,               // $1
 		SessionCardVersion,                // $2
@@ -739,34 +1099,49 @@ func (p *Precomputer) FindStaleSearchIndexSessions(ctx context.Context, limit in
 		RedactionsCardVersion,             // $7
 		SearchIndexVersion,                // $8
 		limit,                             // $9
-		pq.Array(models.AllowedProviders), // $10
+		sessionTypesArg,                   // $10
 	)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var sessions []StaleSession
+	found := 0
 	for rows.Next() {
 		var s StaleSession
 		var rawProvider string
 		if err := rows.Scan(&s.SessionID, &s.UserID, &s.ExternalID, &rawProvider, &s.TotalLines, &s.CreatedAt); err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, err.Error())
-			return nil, err
+			return err
 		}
 		s.Provider = models.NormalizeProvider(rawProvider)
-		sessions = append(sessions, s)
+		found++
+		if err := fn(s); err != nil {
+			return err
+		}
 	}
 	if err := rows.Err(); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return nil, err
+		return err
 	}
 
-	span.SetAttributes(attribute.Int("sessions.found", len(sessions)))
+	span.SetAttributes(attribute.Int("sessions.found", found))
+	return nil
+}
+
+// FindStaleSearchIndexSessions collects StreamStaleSearchIndexSessions into a slice.
+func (p *Precomputer) FindStaleSearchIndexSessions(ctx context.Context, limit int) ([]StaleSession, error) {
+	var sessions []StaleSession
+	if err := p.StreamStaleSearchIndexSessions(ctx, limit, func(s StaleSession) error {
+		sessions = append(sessions, s)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
 	return sessions, nil
 }
 
@@ -804,6 +1179,10 @@ func (p *Precomputer) BuildSearchIndexOnly(ctx context.Context, session StaleSes
 		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
+	if content.IncludeAgentFiles {
+		content.AgentText = sp.AgentSearchText(ctx, rollout, content.AgentTextBudget())
+		span.SetAttributes(attribute.Int("search.agent_text_bytes", len(content.AgentText)))
+	}
 
 	recapIndexedAt, err := p.loadRecapIndexedAt(ctx, session.SessionID)
 	if err != nil {
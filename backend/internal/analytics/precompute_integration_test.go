@@ -2,10 +2,13 @@ package analytics_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
 	"github.com/ConfabulousDev/confab-web/internal/models"
 	"github.com/ConfabulousDev/confab-web/internal/recapquota"
 	"github.com/ConfabulousDev/confab-web/internal/testutil"
@@ -421,6 +424,60 @@ func TestFindStaleSessions_RespectsLimit(t *testing.T) {
 	}
 }
 
+func TestStreamStaleSessions_StopsAtCallbackError(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "stream@test.com", "Stream User")
+	for i := 0; i < 3; i++ {
+		sessionID := testutil.CreateTestSession(t, env, user.ID, fmt.Sprintf("stream-external-id-%d", i))
+		testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 100)
+	}
+
+	analyticsStore := analytics.NewStore(env.DB.Conn())
+	precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, defaultTestConfig())
+
+	want, err := precomputer.FindStaleSessions(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("FindStaleSessions failed: %v", err)
+	}
+	if len(want) != 3 {
+		t.Fatalf("expected 3 stale sessions, got %d", len(want))
+	}
+
+	// The stream yields the same sessions in the same order.
+	var streamed []string
+	if err := precomputer.StreamStaleSessions(context.Background(), 100, func(s analytics.StaleSession) error {
+		streamed = append(streamed, s.SessionID)
+		return nil
+	}); err != nil {
+		t.Fatalf("StreamStaleSessions failed: %v", err)
+	}
+	for i, s := range want {
+		if i >= len(streamed) || streamed[i] != s.SessionID {
+			t.Fatalf("streamed %v, want the order of %v", streamed, want)
+		}
+	}
+
+	// An error from fn stops the stream and is returned as is.
+	stop := errors.New("stop")
+	calls := 0
+	err = precomputer.StreamStaleSessions(context.Background(), 100, func(analytics.StaleSession) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("StreamStaleSessions error = %v, want the callback's", err)
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times after returning an error, want 1", calls)
+	}
+}
+
 func TestFindStaleSessions_IgnoresEmptySessions(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
@@ -520,6 +577,70 @@ func TestPrecomputeRegularCards_ComputesAndUpsertsCards(t *testing.T) {
 	}
 }
 
+func TestPrecomputeRegularCards_WithAgentFiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "precompute-agents@test.com", "Precompute Agents User")
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "precompute-agents-external-id")
+
+	// Main transcript (4 lines) launching one agent whose file has 2 lines
+	agentFile := "agent-" + testutil.MinimalAgentID + ".jsonl"
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 4)
+	testutil.CreateTestSyncFile(t, env, sessionID, agentFile, "agent", 2)
+	testutil.UploadTestTranscript(t, env, user.ID, models.ProviderClaudeCode, "precompute-agents-external-id", "transcript.jsonl", testutil.MinimalTranscriptWithAgent())
+	testutil.UploadTestTranscript(t, env, user.ID, models.ProviderClaudeCode, "precompute-agents-external-id", agentFile, testutil.MinimalAgentTranscript())
+
+	analyticsStore := analytics.NewStore(env.DB.Conn())
+	precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, defaultTestConfig())
+
+	staleSession := analytics.StaleSession{
+		SessionID:  sessionID,
+		UserID:     user.ID,
+		ExternalID: "precompute-agents-external-id",
+		Provider:   models.ProviderClaudeCode,
+		TotalLines: 6,
+	}
+
+	if err := precomputer.PrecomputeRegularCards(context.Background(), staleSession); err != nil {
+		t.Fatalf("PrecomputeRegularCards failed: %v", err)
+	}
+
+	cards, err := analyticsStore.GetCards(context.Background(), sessionID)
+	if err != nil {
+		t.Fatalf("GetCards failed: %v", err)
+	}
+	if cards == nil {
+		t.Fatal("expected cards to be created, got nil")
+	}
+
+	if cards.AgentsAndSkills == nil {
+		t.Fatal("expected agents_and_skills card to be created")
+	}
+	if cards.AgentsAndSkills.AgentInvocations != 1 {
+		t.Errorf("agent_invocations = %d, want 1", cards.AgentsAndSkills.AgentInvocations)
+	}
+	if cards.AgentsAndSkills.UpToLine != 6 {
+		t.Errorf("agents_and_skills card up_to_line = %d, want 6 (4 + 2)", cards.AgentsAndSkills.UpToLine)
+	}
+
+	// Main turns (100 in / 50 out) plus the agent file (200 in / 80 out)
+	if cards.TokensV2 == nil {
+		t.Fatal("expected tokens_v2 card to be created")
+	}
+	if cards.TokensV2.Data.TotalInput != 300 || cards.TokensV2.Data.TotalOutput != 130 {
+		t.Errorf("tokens_v2 totals = %d in / %d out, want 300 / 130 (main + agent)",
+			cards.TokensV2.Data.TotalInput, cards.TokensV2.Data.TotalOutput)
+	}
+	if cards.TokensV2.UpToLine != 6 {
+		t.Errorf("tokens_v2 card up_to_line = %d, want 6 (4 + 2)", cards.TokensV2.UpToLine)
+	}
+}
+
 func TestPrecomputeRegularCards_EmptyTranscript(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
@@ -612,6 +733,111 @@ func TestPrecomputeRegularCards_UpdatesExistingCards(t *testing.T) {
 	}
 }
 
+// TestPrecomputeRegularCards_CancelledMidWrite_StaysStale kills a precompute
+// part-way through its write: an uncommitted row held by a second transaction
+// blocks the cost projection insert, which SaveComputedCards runs after the
+// cards, turns and token series. Cancelling the context then must roll back
+// every row, so the session is still selected by FindStaleSessions.
+func TestPrecomputeRegularCards_CancelledMidWrite_StaysStale(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "cancelled@test.com", "Cancelled User")
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "cancelled-external-id")
+	setSessionFirstSeen(t, env, sessionID, time.Now().Add(-time.Hour))
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 3)
+	testutil.UploadTestTranscript(t, env, user.ID, models.ProviderClaudeCode, "cancelled-external-id", "transcript.jsonl", testutil.MinimalTranscript())
+
+	analyticsStore := analytics.NewStore(env.DB.Conn())
+	precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, defaultTestConfig())
+
+	blocker, err := env.DB.Conn().BeginTx(env.Ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to begin blocking transaction: %v", err)
+	}
+	defer blocker.Rollback()
+	if _, err := blocker.ExecContext(env.Ctx,
+		`INSERT INTO session_card_cost_projection (session_id, computed_at, up_to_line, history_session_count)
+		 VALUES ($1, NOW(), 0, 0)`, sessionID); err != nil {
+		t.Fatalf("failed to insert blocking row: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- precomputer.PrecomputeRegularCards(ctx, analytics.StaleSession{
+			SessionID:  sessionID,
+			UserID:     user.ID,
+			ExternalID: "cancelled-external-id",
+			Provider:   models.ProviderClaudeCode,
+			TotalLines: 3,
+		})
+	}()
+
+	// Wait until the write is parked on the blocking row, i.e. the cards are
+	// already written inside its transaction.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var waiting int
+		if err := env.DB.QueryRow(env.Ctx,
+			`SELECT COUNT(*) FROM pg_stat_activity
+			 WHERE wait_event_type = 'Lock' AND query LIKE '%INSERT INTO session_card_cost_projection%'`,
+		).Scan(&waiting); err != nil {
+			t.Fatalf("failed to poll pg_stat_activity: %v", err)
+		}
+		if waiting > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("precompute never blocked on the cost projection row")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected PrecomputeRegularCards to fail after cancel")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("PrecomputeRegularCards did not return after cancel")
+	}
+	if err := blocker.Rollback(); err != nil {
+		t.Fatalf("failed to roll back blocking transaction: %v", err)
+	}
+
+	cards, err := analyticsStore.GetCards(context.Background(), sessionID)
+	if err != nil {
+		t.Fatalf("GetCards failed: %v", err)
+	}
+	if cards.AllPresent() || cards.TokensV2 != nil || cards.Session != nil {
+		t.Errorf("expected no cards after cancelled write, got tokens_v2=%v session=%v", cards.TokensV2 != nil, cards.Session != nil)
+	}
+	var turns int
+	if err := env.DB.QueryRow(env.Ctx,
+		"SELECT COUNT(*) FROM session_card_conversation_turns WHERE session_id = $1", sessionID,
+	).Scan(&turns); err != nil {
+		t.Fatalf("failed to count conversation turns: %v", err)
+	}
+	if turns != 0 {
+		t.Errorf("conversation turns = %d, want 0 after cancelled write", turns)
+	}
+
+	stale, err := precomputer.FindStaleSessions(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("FindStaleSessions failed: %v", err)
+	}
+	if len(stale) != 1 || stale[0].SessionID != sessionID {
+		t.Fatalf("expected the cancelled session to still be stale, got %v", sessionIDs(stale))
+	}
+}
+
 // =============================================================================
 // Helper functions
 // =============================================================================
@@ -722,6 +948,16 @@ func insertAllCards(t *testing.T, env *testutil.TestEnvironment, sessionID strin
 		t.Fatalf("failed to insert workflows card: %v", err)
 	}
 
+	// Code changes card
+	_, err = env.DB.Exec(env.Ctx, `
+		INSERT INTO session_card_code_changes (
+			session_id, version, computed_at, up_to_line, total_hunks, code_changes
+		) VALUES ($1, $2, $3, $4, 0, '[]')
+	`, sessionID, analytics.CodeChangesCardVersion, now, upToLine)
+	if err != nil {
+		t.Fatalf("failed to insert code changes card: %v", err)
+	}
+
 	// Tokens v2 card (always-written peer card; empty data for non-OpenCode)
 	_, err = env.DB.Exec(env.Ctx, `
 		INSERT INTO session_card_tokens_v2 (
@@ -1053,6 +1289,137 @@ func TestFindStaleSmartRecapSessions_NewLines_Found(t *testing.T) {
 	}
 }
 
+// TestFindStaleSmartRecapSessions_MinInterval checks that a session recapped
+// less than SmartRecapMinInterval ago is not re-selected, even with a line
+// gap far past the threshold, and is once the interval has passed.
+func TestFindStaleSmartRecapSessions_MinInterval(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "mininterval@test.com", "MinInterval User")
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "mininterval-external-id")
+
+	// 800 new lines since the recap at 200: far past threshold max(150, 40).
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 1000)
+	insertAllCards(t, env, sessionID, 1000)
+
+	config := analytics.PrecomputeConfig{
+		SmartRecapEnabled:      true,
+		AnthropicAPIKey:        "test-key",
+		SmartRecapModel:        "test-model",
+		SmartRecapQuota:        100,
+		LockTimeoutSeconds:     60,
+		RegularCardsThresholds: analytics.DefaultRegularCardsThresholds(),
+		SmartRecapThresholds:   analytics.DefaultSmartRecapThresholds(),
+		SmartRecapMinInterval:  time.Hour,
+	}
+	precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analytics.NewStore(env.DB.Conn()), config)
+
+	for _, tc := range []struct {
+		name      string
+		recapAge  time.Duration
+		wantStale bool
+	}{
+		{"recapped within the interval", 10 * time.Minute, false},
+		{"interval elapsed", 61 * time.Minute, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := env.DB.Exec(env.Ctx, `DELETE FROM session_card_smart_recap WHERE session_id = $1`, sessionID); err != nil {
+				t.Fatalf("failed to delete smart recap card: %v", err)
+			}
+			insertSmartRecapCard(t, env, sessionID, analytics.SmartRecapCardVersion, 200, time.Now().UTC().Add(-tc.recapAge))
+
+			sessions, err := precomputer.FindStaleSmartRecapSessions(context.Background(), 100)
+			if err != nil {
+				t.Fatalf("FindStaleSmartRecapSessions failed: %v", err)
+			}
+			if got := len(sessions) == 1; got != tc.wantStale {
+				t.Errorf("selected = %v (%d sessions), want %v", got, len(sessions), tc.wantStale)
+			}
+		})
+	}
+}
+
+// TestFindStaleSmartRecapSessions_InterleavesUsers seeds a user with a large
+// backlog of big sessions and a second user with a few small ones. Ordered
+// purely by staleness priority, the backlog user would fill the batch; the
+// query must instead alternate users until the smaller backlog runs out.
+func TestFindStaleSmartRecapSessions_InterleavesUsers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	heavy := testutil.CreateTestUser(t, env, "heavy@test.com", "Heavy User")
+	light := testutil.CreateTestUser(t, env, "light@test.com", "Light User")
+	for i := 0; i < 6; i++ {
+		lines := int64(2000 + i*100) // larger line gaps than every light session
+		sessionID := testutil.CreateTestSession(t, env, heavy.ID, fmt.Sprintf("heavy-%d", i))
+		testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", int(lines))
+		insertAllCards(t, env, sessionID, lines)
+	}
+	for i := 0; i < 3; i++ {
+		lines := int64(500 + i*100)
+		sessionID := testutil.CreateTestSession(t, env, light.ID, fmt.Sprintf("light-%d", i))
+		testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", int(lines))
+		insertAllCards(t, env, sessionID, lines)
+	}
+
+	analyticsStore := analytics.NewStore(env.DB.Conn())
+	precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, analytics.PrecomputeConfig{
+		SmartRecapEnabled:      true,
+		AnthropicAPIKey:        "test-key",
+		SmartRecapModel:        "test-model",
+		LockTimeoutSeconds:     60,
+		RegularCardsThresholds: analytics.DefaultRegularCardsThresholds(),
+		SmartRecapThresholds:   analytics.DefaultSmartRecapThresholds(),
+	})
+
+	sessions, err := precomputer.FindStaleSmartRecapSessions(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("FindStaleSmartRecapSessions failed: %v", err)
+	}
+	if len(sessions) != 9 {
+		t.Fatalf("expected 9 sessions, got %d", len(sessions))
+	}
+
+	// Rounds 1-3 alternate heavy/light (heavy first: its top session has the
+	// larger gap); the heavy user's remaining backlog follows.
+	want := []int64{heavy.ID, light.ID, heavy.ID, light.ID, heavy.ID, light.ID, heavy.ID, heavy.ID, heavy.ID}
+	for i, s := range sessions {
+		if s.UserID != want[i] {
+			t.Fatalf("position %d: user %d, want %d (order: %v)", i, s.UserID, want[i], userOrder(sessions))
+		}
+	}
+	// Within a user, staleness priority still applies: largest gap first.
+	if sessions[0].TotalLines != 2500 || sessions[2].TotalLines != 2400 {
+		t.Errorf("heavy user's sessions out of priority order: %d then %d", sessions[0].TotalLines, sessions[2].TotalLines)
+	}
+
+	// A small batch is split fairly rather than going to the heavy user.
+	batch, err := precomputer.FindStaleSmartRecapSessions(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("FindStaleSmartRecapSessions failed: %v", err)
+	}
+	if got := userOrder(batch); len(got) != 4 || got[1] != light.ID || got[3] != light.ID {
+		t.Errorf("limit 4 batch users = %v, want alternating", got)
+	}
+}
+
+func userOrder(sessions []analytics.StaleSession) []int64 {
+	ids := make([]int64, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.UserID
+	}
+	return ids
+}
+
 // =============================================================================
 // Two-Bucket Discovery Integration Tests
 // =============================================================================
@@ -1320,6 +1687,16 @@ func insertAllCardsWithComputedAt(t *testing.T, env *testutil.TestEnvironment, s
 		t.Fatalf("failed to insert workflows card: %v", err)
 	}
 
+	// Code changes card
+	_, err = env.DB.Exec(env.Ctx, `
+		INSERT INTO session_card_code_changes (
+			session_id, version, computed_at, up_to_line, total_hunks, code_changes
+		) VALUES ($1, $2, $3, $4, 0, '[]')
+	`, sessionID, analytics.CodeChangesCardVersion, computedAt, upToLine)
+	if err != nil {
+		t.Fatalf("failed to insert code changes card: %v", err)
+	}
+
 	// Tokens v2 card (always-written peer card; empty data for non-OpenCode)
 	_, err = env.DB.Exec(env.Ctx, `
 		INSERT INTO session_card_tokens_v2 (
@@ -1429,6 +1806,16 @@ func insertAllCardsWithWrongTokensVersion(t *testing.T, env *testutil.TestEnviro
 	if err != nil {
 		t.Fatalf("failed to insert workflows card: %v", err)
 	}
+
+	// Code changes card (correct version)
+	_, err = env.DB.Exec(env.Ctx, `
+		INSERT INTO session_card_code_changes (
+			session_id, version, computed_at, up_to_line, total_hunks, code_changes
+		) VALUES ($1, $2, $3, $4, 0, '[]')
+	`, sessionID, analytics.CodeChangesCardVersion, computedAt, upToLine)
+	if err != nil {
+		t.Fatalf("failed to insert code changes card: %v", err)
+	}
 }
 
 func TestFindStaleSessions_NewSession_BelowMinLines_Young_Skipped(t *testing.T) {
@@ -1681,6 +2068,85 @@ func TestFindStaleSessions_Cached_TimeThresholdMet_Found(t *testing.T) {
 	}
 }
 
+// TestSkewedAppClockDoesNotAffectStaleness computes cards on an app server
+// whose clock runs two hours behind the database's. Stored computed_at and
+// every time gap come from the database clock, so the fresh cards are not
+// stale, and the recompute-pending check agrees with FindStaleSessions.
+func TestSkewedAppClockDoesNotAffectStaleness(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "skew@test.com", "Skew User")
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "skew-external-id")
+	firstSeen := time.Now().Add(-1 * time.Hour)
+	setSessionFirstSeen(t, env, sessionID, firstSeen)
+	// 5 line gap: below the line threshold, so only the time gap could select it.
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 105)
+
+	skewedNow := time.Now().UTC().Add(-2 * time.Hour)
+	cards := (&analytics.ComputeResult{}).ToCards(sessionID, 100)
+	for _, at := range []*time.Time{
+		&cards.TokensV2.ComputedAt, &cards.Session.ComputedAt, &cards.Tools.ComputedAt,
+		&cards.CodeActivity.ComputedAt, &cards.Conversation.ComputedAt, &cards.AgentsAndSkills.ComputedAt,
+		&cards.Redactions.ComputedAt, &cards.Workflows.ComputedAt, &cards.CodeChanges.ComputedAt,
+	} {
+		*at = skewedNow
+	}
+
+	th := testThresholds()
+	analyticsStore := analytics.NewStore(env.DB.Conn())
+	if err := analyticsStore.SaveComputedCards(ctx, sessionID, analytics.ComputedCardSet{Cards: cards}); err != nil {
+		t.Fatalf("SaveComputedCards failed: %v", err)
+	}
+
+	dbNow, err := analyticsStore.Now(ctx)
+	if err != nil {
+		t.Fatalf("Now failed: %v", err)
+	}
+	stored, err := analyticsStore.GetCards(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("GetCards failed: %v", err)
+	}
+	if gap := dbNow.Sub(stored.Session.ComputedAt); gap < 0 || gap > time.Minute {
+		t.Errorf("stored computed_at = %v, want the database clock (%v)", stored.Session.ComputedAt, dbNow)
+	}
+	if !cards.Session.ComputedAt.Equal(stored.Session.ComputedAt) {
+		t.Errorf("saved record computed_at = %v, want the stored %v", cards.Session.ComputedAt, stored.Session.ComputedAt)
+	}
+
+	precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, analytics.PrecomputeConfig{
+		RegularCardsThresholds: th,
+	})
+	sessions, err := precomputer.FindStaleSessions(ctx, 100)
+	if err != nil {
+		t.Fatalf("FindStaleSessions failed: %v", err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("expected 0 stale sessions, got %d", len(sessions))
+	}
+	if stored.NeedsRecompute(th, 105, firstSeen.UTC(), dbNow) {
+		t.Error("NeedsRecompute against the database clock = true, want false")
+	}
+
+	// A smart recap saved without ComputedAt is stamped by the database too.
+	recap := &analytics.SmartRecapCardRecord{
+		SessionID: sessionID,
+		Version:   analytics.SmartRecapCardVersion,
+		UpToLine:  100,
+	}
+	if err := analyticsStore.UpsertSmartRecapCard(ctx, recap); err != nil {
+		t.Fatalf("UpsertSmartRecapCard failed: %v", err)
+	}
+	if gap := recap.ComputedAt.Sub(dbNow); gap < 0 || gap > time.Minute {
+		t.Errorf("smart recap computed_at = %v, want the database clock (%v)", recap.ComputedAt, dbNow)
+	}
+}
+
 func TestFindStaleSessions_LargeSession_SmallGap_Skipped(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
@@ -1785,6 +2251,7 @@ func TestFindStaleSessions_VersionMismatch_AlwaysFound(t *testing.T) {
 	_, _ = env.DB.Exec(env.Ctx, `INSERT INTO session_card_agents_and_skills (session_id, version, computed_at, up_to_line, agent_invocations, skill_invocations, agent_stats, skill_stats) VALUES ($1, $2, $3, $4, 0, 0, '{}', '{}')`, sessionID, analytics.AgentsAndSkillsCardVersion, now, 100)
 	_, _ = env.DB.Exec(env.Ctx, `INSERT INTO session_card_redactions (session_id, version, computed_at, up_to_line, total_redactions, redaction_counts) VALUES ($1, $2, $3, $4, 0, '{}')`, sessionID, analytics.RedactionsCardVersion, now, 100)
 	_, _ = env.DB.Exec(env.Ctx, `INSERT INTO session_card_workflows (session_id, version, computed_at, up_to_line, runs) VALUES ($1, $2, $3, $4, '[]')`, sessionID, analytics.WorkflowsCardVersion, now, 100)
+	_, _ = env.DB.Exec(env.Ctx, `INSERT INTO session_card_code_changes (session_id, version, computed_at, up_to_line, total_hunks, code_changes) VALUES ($1, $2, $3, $4, 0, '[]')`, sessionID, analytics.CodeChangesCardVersion, now, 100)
 
 	analyticsStore := analytics.NewStore(env.DB.Conn())
 	precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, analytics.PrecomputeConfig{
@@ -1865,6 +2332,111 @@ func TestFindStaleSessions_OrdersByPriority(t *testing.T) {
 	}
 }
 
+// setSessionLastSync updates a session's last_sync_at timestamp for testing.
+func setSessionLastSync(t *testing.T, env *testutil.TestEnvironment, sessionID string, lastSync time.Time) {
+	t.Helper()
+	_, err := env.DB.Exec(env.Ctx, "UPDATE sessions SET last_sync_at = $1 WHERE id = $2", lastSync.UTC(), sessionID)
+	if err != nil {
+		t.Fatalf("failed to set last_sync_at: %v", err)
+	}
+}
+
+func TestFindStaleSessions_WarmActiveUserNewestSessionFirst(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	// Active user: has a live web session. Their newest session has a small
+	// line gap that is below every threshold; an older one has a large gap.
+	active := testutil.CreateTestUser(t, env, "active@test.com", "Active User")
+	testutil.CreateTestWebSession(t, env, "active-web-session", active.ID, time.Now().Add(time.Hour))
+
+	activeOld := testutil.CreateTestSession(t, env, active.ID, "warm-old")
+	setSessionFirstSeen(t, env, activeOld, time.Now().Add(-3*time.Hour))
+	setSessionLastSync(t, env, activeOld, time.Now().Add(-2*time.Hour))
+	testutil.CreateTestSyncFile(t, env, activeOld, "transcript.jsonl", "transcript", 150)
+	insertAllCardsWithComputedAt(t, env, activeOld, 100, time.Now().Add(-1*time.Minute)) // 50 line gap
+
+	activeNewest := testutil.CreateTestSession(t, env, active.ID, "warm-newest")
+	setSessionFirstSeen(t, env, activeNewest, time.Now().Add(-1*time.Hour))
+	setSessionLastSync(t, env, activeNewest, time.Now().Add(-1*time.Minute))
+	testutil.CreateTestSyncFile(t, env, activeNewest, "transcript.jsonl", "transcript", 102)
+	insertAllCardsWithComputedAt(t, env, activeNewest, 100, time.Now().Add(-1*time.Minute)) // 2 line gap
+
+	// Inactive user with a brand-new session: normally the top priority.
+	idle := testutil.CreateTestUser(t, env, "idle@test.com", "Idle User")
+	idleNew := testutil.CreateTestSession(t, env, idle.ID, "idle-new")
+	setSessionFirstSeen(t, env, idleNew, time.Now().Add(-15*time.Minute))
+	testutil.CreateTestSyncFile(t, env, idleNew, "transcript.jsonl", "transcript", 50)
+
+	analyticsStore := analytics.NewStore(env.DB.Conn())
+
+	t.Run("disabled by default", func(t *testing.T) {
+		precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, analytics.PrecomputeConfig{
+			RegularCardsThresholds: testThresholds(),
+		})
+		sessions, err := precomputer.FindStaleSessions(context.Background(), 100)
+		if err != nil {
+			t.Fatalf("FindStaleSessions failed: %v", err)
+		}
+		if len(sessions) != 2 || sessions[0].SessionID != idleNew || sessions[1].SessionID != activeOld {
+			t.Errorf("expected [idle-new warm-old] without warming, got %v", sessionIDs(sessions))
+		}
+	})
+
+	t.Run("active user's newest session is prioritized", func(t *testing.T) {
+		precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, analytics.PrecomputeConfig{
+			RegularCardsThresholds: testThresholds(),
+			WarmActiveWindow:       15 * time.Minute,
+		})
+		sessions, err := precomputer.FindStaleSessions(context.Background(), 100)
+		if err != nil {
+			t.Fatalf("FindStaleSessions failed: %v", err)
+		}
+		want := []string{activeNewest, idleNew, activeOld}
+		got := sessionIDs(sessions)
+		if len(got) != len(want) {
+			t.Fatalf("expected %d sessions, got %v", len(want), got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("sessions[%d] = %s, want %s (full order %v)", i, got[i], want[i], got)
+			}
+		}
+	})
+
+	t.Run("warm session with no new lines is not selected", func(t *testing.T) {
+		// Cards at line 100 now cover the whole transcript (line gap 0).
+		if _, err := env.DB.Exec(env.Ctx, "UPDATE sync_files SET last_synced_line = 100 WHERE session_id = $1", activeNewest); err != nil {
+			t.Fatalf("failed to reset last_synced_line: %v", err)
+		}
+		precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, analytics.PrecomputeConfig{
+			RegularCardsThresholds: testThresholds(),
+			WarmActiveWindow:       15 * time.Minute,
+		})
+		sessions, err := precomputer.FindStaleSessions(context.Background(), 100)
+		if err != nil {
+			t.Fatalf("FindStaleSessions failed: %v", err)
+		}
+		for _, s := range sessions {
+			if s.SessionID == activeNewest {
+				t.Errorf("up-to-date warm session should not be selected, got %v", sessionIDs(sessions))
+			}
+		}
+	})
+}
+
+func sessionIDs(sessions []analytics.StaleSession) []string {
+	ids := make([]string, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.SessionID
+	}
+	return ids
+}
+
 // =============================================================================
 // Smart Recap Staleness Threshold Tests
 // =============================================================================
@@ -2683,6 +3255,68 @@ func TestFindStaleSearchIndexSessions_VersionMismatch_Found(t *testing.T) {
 	}
 }
 
+// TestSummaryEdit_StalesSearchIndexOnly pins the split between the two
+// staleness queries: a metadata-only edit must invalidate the search index
+// (its metadata_hash covers the summary) without re-selecting the session for
+// regular-card recompute, whose inputs are the transcript and card versions.
+func TestSummaryEdit_StalesSearchIndexOnly(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "summaryedit@test.com", "SummaryEdit User")
+	externalID := "summaryedit-external-id"
+	sessionID := testutil.CreateTestSession(t, env, user.ID, externalID)
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 100)
+	insertAllCards(t, env, sessionID, 100)
+
+	testutil.CreateTestSearchIndex(t, env, sessionID, "content", 100)
+	_, err := env.DB.Exec(env.Ctx,
+		"UPDATE session_search_index SET metadata_hash = MD5('|||') WHERE session_id = $1",
+		sessionID)
+	if err != nil {
+		t.Fatalf("failed to update metadata_hash: %v", err)
+	}
+
+	// Warm the session too, so the cache-warming path is also exercised.
+	config := defaultTestConfig()
+	config.WarmActiveWindow = 15 * time.Minute
+
+	analyticsStore := analytics.NewStore(env.DB.Conn())
+	precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, config)
+	ctx := context.Background()
+
+	assertStale := func(phase string, wantRegular, wantSearch bool) {
+		t.Helper()
+		regular, err := precomputer.FindStaleSessions(ctx, 100)
+		if err != nil {
+			t.Fatalf("%s: FindStaleSessions failed: %v", phase, err)
+		}
+		if got := len(regular) == 1; got != wantRegular {
+			t.Errorf("%s: regular cards stale = %v (%d sessions), want %v", phase, got, len(regular), wantRegular)
+		}
+		search, err := precomputer.FindStaleSearchIndexSessions(ctx, 100)
+		if err != nil {
+			t.Fatalf("%s: FindStaleSearchIndexSessions failed: %v", phase, err)
+		}
+		if got := len(search) == 1; got != wantSearch {
+			t.Errorf("%s: search index stale = %v (%d sessions), want %v", phase, got, len(search), wantSearch)
+		}
+	}
+
+	assertStale("before edit", false, false)
+
+	sessionStore := &dbsession.Store{DB: env.DB}
+	if err := sessionStore.UpdateSessionSummary(ctx, externalID, user.ID, "Edited summary"); err != nil {
+		t.Fatalf("UpdateSessionSummary failed: %v", err)
+	}
+
+	assertStale("after summary edit", false, true)
+}
+
 // =============================================================================
 // ExtractSearchContent Integration Tests
 // =============================================================================
@@ -2898,6 +3532,96 @@ func TestBuildSearchIndexOnly_EndToEnd(t *testing.T) {
 	}
 }
 
+// TestBuildSearchIndexOnly_AgentFilesToggle flips include_agent_files_in_search
+// on and off: each flip must mark the index stale (via the metadata hash), and
+// the rebuilt index must include or drop the subagent's output accordingly.
+func TestBuildSearchIndexOnly_AgentFilesToggle(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "agentsearch@test.com", "AgentSearch User")
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "agentsearch-external-id")
+
+	transcript := []byte(`{"type":"user","message":{"role":"user","content":"Find the config loader"},"uuid":"u1","timestamp":"2024-01-01T00:00:01Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"role":"assistant","content":"Delegating to a subagent."},"uuid":"a1","timestamp":"2024-01-01T00:00:02Z","parentUuid":"u1","isSidechain":false,"usage":{"input_tokens":10,"output_tokens":5}}
+`)
+	agent := []byte(`{"type":"user","message":{"role":"user","content":"Locate the loader"},"uuid":"au1","timestamp":"2024-01-01T00:00:03Z","parentUuid":null,"isSidechain":true,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0","agentId":"abc123"}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"The loader lives in zanzibar.go"}]},"uuid":"aa1","timestamp":"2024-01-01T00:00:04Z","parentUuid":"au1","isSidechain":true,"agentId":"abc123","usage":{"input_tokens":10,"output_tokens":5}}
+`)
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 2)
+	testutil.CreateTestSyncFile(t, env, sessionID, "agent-abc123.jsonl", "agent", 2)
+	testutil.UploadTestTranscript(t, env, user.ID, models.ProviderClaudeCode, "agentsearch-external-id", "transcript.jsonl", transcript)
+	testutil.UploadTestTranscript(t, env, user.ID, models.ProviderClaudeCode, "agentsearch-external-id", "agent-abc123.jsonl", agent)
+	insertAllCards(t, env, sessionID, 4)
+
+	analyticsStore := analytics.NewStore(env.DB.Conn())
+	precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, defaultTestConfig())
+
+	setToggle := func(on bool) {
+		t.Helper()
+		if _, err := env.DB.Exec(env.Ctx,
+			`UPDATE users SET include_agent_files_in_search = $2 WHERE id = $1`, user.ID, on); err != nil {
+			t.Fatalf("failed to set toggle: %v", err)
+		}
+	}
+	// reindex asserts the session is stale, rebuilds it, and asserts it is
+	// current again; it returns whether the agent-only term is searchable.
+	reindex := func() bool {
+		t.Helper()
+		stale, err := precomputer.FindStaleSearchIndexSessions(context.Background(), 100)
+		if err != nil {
+			t.Fatalf("FindStaleSearchIndexSessions failed: %v", err)
+		}
+		if len(stale) != 1 {
+			t.Fatalf("expected the session to be stale, got %d sessions", len(stale))
+		}
+		if err := precomputer.BuildSearchIndexOnly(context.Background(), stale[0]); err != nil {
+			t.Fatalf("BuildSearchIndexOnly failed: %v", err)
+		}
+		stale, err = precomputer.FindStaleSearchIndexSessions(context.Background(), 100)
+		if err != nil {
+			t.Fatalf("FindStaleSearchIndexSessions failed: %v", err)
+		}
+		if len(stale) != 0 {
+			t.Fatalf("expected no stale sessions after rebuild, got %d", len(stale))
+		}
+
+		var found bool
+		if err := env.DB.Conn().QueryRowContext(context.Background(),
+			`SELECT EXISTS(SELECT 1 FROM session_search_index WHERE session_id = $1 AND search_vector @@ to_tsquery('english', 'zanzibar'))`,
+			sessionID).Scan(&found); err != nil {
+			t.Fatalf("FTS query failed: %v", err)
+		}
+		return found
+	}
+
+	if reindex() {
+		t.Error("agent output indexed with the toggle off")
+	}
+
+	setToggle(true)
+	if !reindex() {
+		t.Error("agent output not indexed with the toggle on")
+	}
+	var agentLexemes int
+	if err := env.DB.Conn().QueryRowContext(context.Background(),
+		`SELECT length(agent_vector) FROM session_search_index WHERE session_id = $1`, sessionID).Scan(&agentLexemes); err != nil {
+		t.Fatalf("agent_vector query failed: %v", err)
+	}
+	if agentLexemes == 0 {
+		t.Error("expected agent text in agent_vector")
+	}
+
+	setToggle(false)
+	if reindex() {
+		t.Error("agent output still indexed after turning the toggle off")
+	}
+}
+
 // =============================================================================
 // Smart Recap Quota Filtering Integration Tests
 // =============================================================================
@@ -3260,3 +3984,167 @@ func TestWorkflowsCard_StoreRoundTrip(t *testing.T) {
 		t.Errorf("Runs[0] = %+v, want %+v", got.Workflows.Runs[0], want)
 	}
 }
+
+// =============================================================================
+// FindDormantStaleSessions
+// =============================================================================
+
+func TestFindDormantStaleSessions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "dormant@test.com", "Dormant User")
+	setLastSync := func(sessionID string, ago time.Duration) {
+		t.Helper()
+		if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET last_sync_at = $2 WHERE id = $1`,
+			sessionID, time.Now().UTC().Add(-ago)); err != nil {
+			t.Fatalf("set last_sync_at: %v", err)
+		}
+	}
+
+	// Dormant, cards a few lines behind (under FindStaleSessions' thresholds).
+	behind := testutil.CreateTestSession(t, env, user.ID, "dormant-behind")
+	testutil.CreateTestSyncFile(t, env, behind, "transcript.jsonl", "transcript", 100)
+	insertAllCards(t, env, behind, 95)
+	setLastSync(behind, 8*24*time.Hour)
+
+	// Dormant longer, no cards at all.
+	older := testutil.CreateTestSession(t, env, user.ID, "dormant-older")
+	testutil.CreateTestSyncFile(t, env, older, "transcript.jsonl", "transcript", 50)
+	setLastSync(older, 30*24*time.Hour)
+
+	// Dormant but up to date.
+	fresh := testutil.CreateTestSession(t, env, user.ID, "dormant-fresh")
+	testutil.CreateTestSyncFile(t, env, fresh, "transcript.jsonl", "transcript", 100)
+	insertAllCards(t, env, fresh, 100)
+	setLastSync(fresh, 8*24*time.Hour)
+
+	// Stale but synced recently: the regular bucket's job.
+	recent := testutil.CreateTestSession(t, env, user.ID, "recent-stale")
+	testutil.CreateTestSyncFile(t, env, recent, "transcript.jsonl", "transcript", 100)
+	setLastSync(recent, time.Hour)
+
+	analyticsStore := analytics.NewStore(env.DB.Conn())
+	precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, defaultTestConfig())
+
+	sessions, err := precomputer.FindDormantStaleSessions(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("FindDormantStaleSessions failed: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 dormant sessions, got %d: %+v", len(sessions), sessions)
+	}
+	// Most recently active first.
+	if sessions[0].SessionID != behind || sessions[1].SessionID != older {
+		t.Errorf("order = [%s %s], want [%s %s]", sessions[0].SessionID, sessions[1].SessionID, behind, older)
+	}
+	if sessions[0].TotalLines != 100 {
+		t.Errorf("total lines = %d, want 100", sessions[0].TotalLines)
+	}
+
+	limited, err := precomputer.FindDormantStaleSessions(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("FindDormantStaleSessions failed: %v", err)
+	}
+	if len(limited) != 1 || limited[0].SessionID != behind {
+		t.Errorf("limit 1 = %+v, want only %s", limited, behind)
+	}
+}
+
+// =============================================================================
+// Flag-gated cards
+// =============================================================================
+
+// TestPrecomputeRegularCards_CodeChangesFlag checks the code changes card's
+// rollout gate: only a user inside analytics.CodeChangesCardFlag gets a row,
+// and neither user's session is selected as stale again once computed, so
+// users outside the rollout are not recomputed on every pass.
+func TestPrecomputeRegularCards_CodeChangesFlag(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	ctx := context.Background()
+
+	enabled := testutil.CreateTestUser(t, env, "flag-on@test.com", "Flag On User")
+	disabled := testutil.CreateTestUser(t, env, "flag-off@test.com", "Flag Off User")
+	// Allowlist only; 0% so the off user's ID bucket can't let it in.
+	testutil.SetTestFeatureFlag(t, env, analytics.CodeChangesCardFlag, []int64{enabled.ID}, 0)
+
+	analyticsStore := analytics.NewStore(env.DB.Conn())
+	precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, defaultTestConfig())
+
+	sessions := map[int64]string{}
+	for _, user := range []*models.User{enabled, disabled} {
+		externalID := fmt.Sprintf("flag-external-id-%d", user.ID)
+		sessionID := testutil.CreateTestSession(t, env, user.ID, externalID)
+		testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 3)
+		testutil.UploadTestTranscript(t, env, user.ID, models.ProviderClaudeCode, externalID, "transcript.jsonl", testutil.MinimalTranscript())
+		sessions[user.ID] = sessionID
+
+		if err := precomputer.PrecomputeRegularCards(ctx, analytics.StaleSession{
+			SessionID:  sessionID,
+			UserID:     user.ID,
+			ExternalID: externalID,
+			Provider:   models.ProviderClaudeCode,
+			TotalLines: 3,
+		}); err != nil {
+			t.Fatalf("PrecomputeRegularCards(user %d) failed: %v", user.ID, err)
+		}
+	}
+
+	countCodeChanges := func(sessionID string) int {
+		t.Helper()
+		var n int
+		if err := env.DB.QueryRow(env.Ctx,
+			`SELECT COUNT(*) FROM session_card_code_changes WHERE session_id = $1`, sessionID).Scan(&n); err != nil {
+			t.Fatalf("count code changes: %v", err)
+		}
+		return n
+	}
+	if n := countCodeChanges(sessions[enabled.ID]); n != 1 {
+		t.Errorf("code changes rows for the enabled user = %d, want 1", n)
+	}
+	if n := countCodeChanges(sessions[disabled.ID]); n != 0 {
+		t.Errorf("code changes rows for the disabled user = %d, want 0", n)
+	}
+
+	// Both card sets are complete for their owner.
+	for userID, sessionID := range sessions {
+		cards, err := analyticsStore.GetCards(ctx, sessionID)
+		if err != nil {
+			t.Fatalf("GetCards failed: %v", err)
+		}
+		if err := cards.ApplyFeatureFlags(ctx, env.DB.Conn(), userID); err != nil {
+			t.Fatalf("ApplyFeatureFlags failed: %v", err)
+		}
+		if !cards.AllValid(3) {
+			t.Errorf("cards of user %d should be valid after precompute", userID)
+		}
+	}
+
+	stale, err := precomputer.FindStaleSessions(ctx, 100)
+	if err != nil {
+		t.Fatalf("FindStaleSessions failed: %v", err)
+	}
+	if len(stale) != 0 {
+		t.Errorf("FindStaleSessions = %+v, want nothing after precompute", stale)
+	}
+
+	for _, sessionID := range sessions {
+		setSessionLastSync(t, env, sessionID, time.Now().UTC().Add(-2*analytics.DormantSessionAge))
+	}
+	dormant, err := precomputer.FindDormantStaleSessions(ctx, 100)
+	if err != nil {
+		t.Fatalf("FindDormantStaleSessions failed: %v", err)
+	}
+	if len(dormant) != 0 {
+		t.Errorf("FindDormantStaleSessions = %+v, want nothing after precompute", dormant)
+	}
+}
@@ -153,14 +153,16 @@ var sonnet5Sep1 = time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
 // pricingForModel resolves pricing and applies the project's logging policy for
 // misses, attributing them to the given logger (which upstream enriches with
 // session_id + provider so a warning is traceable):
+//   - model_pricing period covering sessionAt → that price (model_pricing.go).
 //   - known model  → its pricing (with Sonnet 5 date-routing, see below).
 //   - empty model   → zero pricing, DEBUG only. Empty is an expected sentinel, not
 //     an anomaly, so it must never spam WARN during precompute.
 //   - non-empty but unknown → zero pricing, WARN. This is a genuine gap in
 //     pricing.json worth surfacing loudly (carries model + family + context).
 //
-// sessionAt is the session's first_seen timestamp, used to route Sonnet 5 sessions
-// to the correct introductory or standard pricing tier. A zero time.Time (year 0001)
+// sessionAt is the session's first_seen timestamp, used to pick the model_pricing
+// period in effect and to route Sonnet 5 sessions to the correct introductory or
+// standard pricing tier when model_pricing has no period for them. A zero time.Time (year 0001)
 // is before Sep 1 2026, so callers without a real timestamp correctly route to
 // intro rates — acceptable for test paths and convenience wrappers.
 //
@@ -176,6 +178,9 @@ func pricingForModel(log *slog.Logger, modelName string, sessionAt time.Time) Mo
 	// use the standard "sonnet-5" rates. getModelFamily is called once here to avoid
 	// a second call in LookupPricing below.
 	family := getModelFamily(modelName)
+	if pricing, ok := periodPricing(family, sessionAt); ok {
+		return pricing
+	}
 	if family == "sonnet-5" && sessionAt.Before(sonnet5Sep1) {
 		table := *activePricing.Load()
 		if p, ok := table["sonnet-5-intro"]; ok {
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 	"time"
 
+	"github.com/ConfabulousDev/confab-web/internal/models"
 	"github.com/ConfabulousDev/confab-web/internal/storage"
 )
 
@@ -18,6 +20,10 @@ type SessionProvider interface {
 	Parse(ctx context.Context, input ParseInput) (Rollout, error)
 	ComputeCards(ctx context.Context, rollout Rollout) *ComputeResult
 	SearchText(ctx context.Context, rollout Rollout) string
+	// AgentSearchText returns up to maxBytes of subagent output for the
+	// opt-in Weight-D search component, or "" when the provider has no
+	// separate agent output (or SearchText already covers it).
+	AgentSearchText(ctx context.Context, rollout Rollout, maxBytes int) string
 	PrepareTranscript(ctx context.Context, rollout Rollout) (xml string, idMap map[int]string, err error)
 	// ClearMessageIDs reports whether smart-recap items should drop message
 	// IDs (providers without stable frontend anchors).
@@ -63,6 +69,23 @@ func RegisterProvider(p SessionProvider, canonical string, aliases ...string) {
 		}
 		providerRegistry[name] = p
 	}
+	// Only the canonical name becomes a storable session type; aliases
+	// exist solely so legacy rows still dispatch.
+	models.RegisterSessionType(canonical)
+}
+
+// registeredSessionTypes returns every registered provider name (canonical
+// and alias), sorted. The stale-session queries filter session_type on this
+// list, so a session is eligible for precompute exactly when a handler can
+// process it — an explicitly typed session never loops as
+// stale-but-unprocessable.
+func registeredSessionTypes() []string {
+	names := make([]string, 0, len(providerRegistry))
+	for name := range providerRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // ProviderFor returns the provider registered for name (canonical or alias).
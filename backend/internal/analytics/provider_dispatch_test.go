@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"os"
+	"slices"
 	"strings"
 	"testing"
 
@@ -77,3 +78,24 @@ func TestPrecomputeGoHasNoProviderSwitchOrLiterals(t *testing.T) {
 		}
 	}
 }
+
+// TestRegisteredSessionTypesDriveStaleFilter locks the contract the stale
+// queries rely on: every registered name (canonical + alias) is in the
+// session_type filter, and registering a provider makes its canonical name a
+// storable session type (models.IsKnownSessionType) but not its aliases.
+func TestRegisteredSessionTypesDriveStaleFilter(t *testing.T) {
+	types := registeredSessionTypes()
+	for _, p := range models.AllowedProviders {
+		if !slices.Contains(types, p) {
+			t.Errorf("registeredSessionTypes() missing %q", p)
+		}
+	}
+	for _, p := range models.CanonicalProviders {
+		if !models.IsKnownSessionType(p) {
+			t.Errorf("canonical provider %q not registered as a session type", p)
+		}
+	}
+	if models.IsKnownSessionType(models.ProviderClaudeCodeLegacy) {
+		t.Error("legacy alias must not become a storable session type")
+	}
+}
@@ -0,0 +1,171 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+// reconciliation.go: the estimate side of the admin invoice reconciliation
+// view. Actual Anthropic usage is imported by the admin API into
+// usage_reconciliation (dbreconciliation); this file sums the tokens_v2 card
+// estimates it is compared against, and applies the operator-set monthly
+// correction factors to a Trends response on request. Card values themselves
+// are never adjusted.
+
+// CostCorrectionLabel is shown next to every corrected figure.
+const CostCorrectionLabel = "Estimate adjusted by operator-set monthly correction factors from Anthropic invoice reconciliation; not a measured cost"
+
+// anthropicFamilies are the model family prefixes billed on the Anthropic invoice.
+var anthropicFamilies = []string{"opus-", "sonnet-", "haiku-", "fable-"}
+
+// AnthropicModelFamily maps a model name as it appears in an Anthropic usage
+// export ("claude-opus-4-5-20251101", "Claude Opus 4.5") to the pricing-table
+// family the cards use ("opus-4-5"). The bool is false for names that are not
+// a known Anthropic family.
+func AnthropicModelFamily(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.NewReplacer(" ", "-", ".", "-", "_", "-").Replace(name)
+	family := strings.TrimSuffix(getModelFamily(name), fastModelKeySuffix)
+	return family, isAnthropicFamily(family)
+}
+
+func isAnthropicFamily(family string) bool {
+	for _, p := range anthropicFamilies {
+		if strings.HasPrefix(family, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// EstimatedModelCost is the summed card estimate for one model family on one
+// UTC day.
+type EstimatedModelCost struct {
+	Date    time.Time // UTC midnight
+	Model   string
+	CostUSD decimal.Decimal
+}
+
+// estimatedDailyModelCostSQL sums the tokens_v2 per-model cost of every
+// non-duplicate session first seen in [$1, $2), by UTC day, session type and
+// raw model key. Keys are folded into families in Go.
+const estimatedDailyModelCostSQL = `
+	SELECT (s.first_seen AT TIME ZONE 'UTC')::date, s.session_type, mdl.key,
+		SUM(COALESCE(mdl.value->>'cost_usd', '0')::numeric)::text
+	FROM sessions s
+	JOIN session_card_tokens_v2 v ON v.session_id = s.id
+	CROSS JOIN LATERAL jsonb_each(v.data->'by_provider') AS prov(key, value)
+	CROSS JOIN LATERAL jsonb_each(prov.value->'models') AS mdl(key, value)
+	WHERE s.merged_at IS NULL
+		AND s.first_seen >= $1
+		AND s.first_seen < $2
+	GROUP BY 1, 2, 3`
+
+// EstimatedDailyModelCosts returns the card-estimated cost of Anthropic model
+// families per UTC day for sessions first seen in [from, to), across all users.
+// A session's whole cost counts on the day it was first seen, which is how it
+// can differ from a day-by-day invoice for sessions that span midnight. Fast
+// mode is folded into its base family, as the invoice does not split it out.
+func (s *Store) EstimatedDailyModelCosts(ctx context.Context, from, to time.Time) ([]EstimatedModelCost, error) {
+	type key struct {
+		day   time.Time
+		model string
+	}
+	sums := map[key]decimal.Decimal{}
+	var order []key
+	err := s.queryEach(ctx, estimatedDailyModelCostSQL, []any{from, to}, func(rows *sql.Rows) error {
+		var day time.Time
+		var sessionType, rawModel, costStr string
+		if err := rows.Scan(&day, &sessionType, &rawModel, &costStr); err != nil {
+			return fmt.Errorf("estimated model cost scan: %w", err)
+		}
+		family := strings.TrimSuffix(normalizeV2ModelKey(models.NormalizeProvider(sessionType), rawModel), fastModelKeySuffix)
+		if !isAnthropicFamily(family) {
+			return nil
+		}
+		cost, err := decimal.NewFromString(costStr)
+		if err != nil {
+			cost = decimal.Zero
+		}
+		k := key{day: day.UTC(), model: family}
+		if _, ok := sums[k]; !ok {
+			order = append(order, k)
+		}
+		sums[k] = sums[k].Add(cost)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("estimated model cost query: %w", err)
+	}
+
+	out := make([]EstimatedModelCost, 0, len(order))
+	for _, k := range order {
+		out = append(out, EstimatedModelCost{Date: k.day, Model: k.model, CostUSD: sums[k]})
+	}
+	return out, nil
+}
+
+// TrendsCostCorrection reports the Tokens card's cost with the monthly
+// correction factors applied. The factors come from the Anthropic invoice, so
+// only the claude-code share of each day's cost is scaled; other providers'
+// cost is carried through unchanged. Days in a month without a factor keep
+// their estimate.
+type TrendsCostCorrection struct {
+	Label    string `json:"label"`
+	Provider string `json:"provider"`
+	// Factors maps each month ("YYYY-MM") in range that has a factor to it.
+	// Always non-nil.
+	Factors map[string]string `json:"factors"`
+	// EstimatedCostUSD and CorrectedCostUSD are the provider's cost before
+	// and after correction.
+	EstimatedCostUSD string `json:"estimated_cost_usd"`
+	CorrectedCostUSD string `json:"corrected_cost_usd"`
+	// CorrectedTotalCostUSD is Tokens.TotalCostUSD with the provider's share
+	// corrected.
+	CorrectedTotalCostUSD string `json:"corrected_total_cost_usd"`
+}
+
+// ApplyCostCorrection sets r.CostCorrection from factors, keyed by month
+// ("YYYY-MM"). Daily cost points are bucketed into months by their (local)
+// date. The cards are left as they are. No-op without a Tokens card.
+func (r *TrendsResponse) ApplyCostCorrection(factors map[string]decimal.Decimal) {
+	if r.Cards.Tokens == nil {
+		return
+	}
+	provider := models.ProviderClaudeCode
+	used := map[string]string{}
+	estimated, corrected := decimal.Zero, decimal.Zero
+	for _, day := range r.Cards.Tokens.DailyCosts {
+		cost, err := decimal.NewFromString(day.PerProvider[provider])
+		if err != nil {
+			continue
+		}
+		estimated = estimated.Add(cost)
+		month := day.Date[:min(len(day.Date), 7)]
+		if f, ok := factors[month]; ok {
+			used[month] = f.String()
+			cost = cost.Mul(f)
+		}
+		corrected = corrected.Add(cost)
+	}
+
+	total, err := decimal.NewFromString(r.Cards.Tokens.TotalCostUSD)
+	if err != nil {
+		total = decimal.Zero
+	}
+	r.CostCorrection = &TrendsCostCorrection{
+		Label:                 CostCorrectionLabel,
+		Provider:              provider,
+		Factors:               used,
+		EstimatedCostUSD:      estimated.StringFixed(2),
+		CorrectedCostUSD:      corrected.StringFixed(2),
+		CorrectedTotalCostUSD: total.Sub(estimated).Add(corrected).StringFixed(2),
+	}
+}
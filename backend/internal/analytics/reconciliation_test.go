@@ -0,0 +1,68 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestAnthropicModelFamily(t *testing.T) {
+	cases := map[string]string{
+		"claude-opus-4-5-20251101":   "opus-4-5",
+		"claude-sonnet-4-20250514":   "sonnet-4",
+		"Claude Haiku 4.5":           "haiku-4-5",
+		" claude_opus_4_1 ":          "opus-4-1",
+		"opus-4-5 · fast":            "opus-4-5",
+		"claude-fable-5":             "fable-5",
+		"gpt-5-2026-05-01":           "",
+		"claude-3-5-sonnet-20241022": "",
+		"":                           "",
+	}
+	for in, want := range cases {
+		got, ok := AnthropicModelFamily(in)
+		if ok != (want != "") || (ok && got != want) {
+			t.Errorf("AnthropicModelFamily(%q) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+}
+
+func TestApplyCostCorrection(t *testing.T) {
+	resp := &TrendsResponse{Cards: TrendsCards{Tokens: &TrendsTokensCard{
+		TotalCostUSD: "33",
+		DailyCosts: []DailyCostPoint{
+			{Date: "2026-02-28", CostUSD: "10", PerProvider: map[string]string{"claude-code": "10"}},
+			{Date: "2026-03-01", CostUSD: "13", PerProvider: map[string]string{"claude-code": "10", "codex": "3"}},
+			{Date: "2026-03-02", CostUSD: "10", PerProvider: map[string]string{"codex": "10"}},
+		},
+	}}}
+
+	resp.ApplyCostCorrection(map[string]decimal.Decimal{
+		"2026-03": decimal.RequireFromString("1.1"),
+		"2026-04": decimal.RequireFromString("2"),
+	})
+
+	cc := resp.CostCorrection
+	if cc == nil {
+		t.Fatal("expected a cost correction")
+	}
+	if cc.Label != CostCorrectionLabel || cc.Provider != "claude-code" {
+		t.Errorf("label/provider = %q/%q", cc.Label, cc.Provider)
+	}
+	if len(cc.Factors) != 1 || cc.Factors["2026-03"] != "1.1" {
+		t.Errorf("factors = %v, want only 2026-03", cc.Factors)
+	}
+	if cc.EstimatedCostUSD != "20.00" || cc.CorrectedCostUSD != "21.00" || cc.CorrectedTotalCostUSD != "34.00" {
+		t.Errorf("costs = %+v", cc)
+	}
+	if resp.Cards.Tokens.TotalCostUSD != "33" {
+		t.Error("the tokens card must keep its estimate")
+	}
+}
+
+func TestApplyCostCorrection_NoTokensCard(t *testing.T) {
+	resp := &TrendsResponse{}
+	resp.ApplyCostCorrection(map[string]decimal.Decimal{"2026-03": decimal.NewFromInt(2)})
+	if resp.CostCorrection != nil {
+		t.Error("expected no cost correction without a tokens card")
+	}
+}
@@ -11,17 +11,38 @@ import (
 
 const maxUserMessagesBytes = 500 * 1024 // 500KB
 
-// SearchIndexContent holds the three weighted text components for the search index.
+// maxSearchIndexBytes caps the combined text of all weighted components.
+// Agent text is lowest priority: it only gets what the others leave over.
+const maxSearchIndexBytes = 900 * 1024 // 900KB
+
+// agentSearchHashSuffix is appended to the metadata hash input when the
+// session owner indexes agent files, so toggling the setting changes the hash
+// and marks the user's indexes stale. Must match the staleness SQL in
+// FindStaleSearchIndexSessions.
+const agentSearchHashSuffix = "|agents"
+
+// SearchIndexContent holds the weighted text components for the search index.
 type SearchIndexContent struct {
 	MetadataText     string // Weight A: titles, summary, first user message
 	RecapText        string // Weight B: smart recap content
 	UserMessagesText string // Weight C: human messages from transcript
+	AgentText        string // Weight D: subagent output, when the owner opted in
 	MetadataHash     string // MD5 hash of metadata fields for change detection
+	// IncludeAgentFiles is the owner's include_agent_files_in_search setting
+	// at extraction time.
+	IncludeAgentFiles bool
+}
+
+// AgentTextBudget returns how many bytes of agent text fit under
+// maxSearchIndexBytes after the higher-priority components.
+func (c *SearchIndexContent) AgentTextBudget() int {
+	used := len(c.MetadataText) + len(c.RecapText) + len(c.UserMessagesText) + 3 // separators
+	return max(maxSearchIndexBytes-used, 0)
 }
 
 // CombinedText returns all text concatenated for storage in content_text.
 func (c *SearchIndexContent) CombinedText() string {
-	parts := make([]string, 0, 3)
+	parts := make([]string, 0, 4)
 	if c.MetadataText != "" {
 		parts = append(parts, c.MetadataText)
 	}
@@ -31,6 +52,9 @@ func (c *SearchIndexContent) CombinedText() string {
 	if c.UserMessagesText != "" {
 		parts = append(parts, c.UserMessagesText)
 	}
+	if c.AgentText != "" {
+		parts = append(parts, c.AgentText)
+	}
 	return strings.Join(parts, "\n")
 }
 
@@ -46,12 +70,13 @@ func ExtractSearchContentWithUserMessages(ctx context.Context, db *sql.DB, sessi
 	content := &SearchIndexContent{}
 
 	// Weight A: metadata from sessions table
-	metadataText, metadataHash, err := extractMetadata(ctx, db, sessionID)
+	metadataText, metadataHash, includeAgentFiles, err := extractMetadata(ctx, db, sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("extracting metadata: %w", err)
 	}
 	content.MetadataText = metadataText
 	content.MetadataHash = metadataHash
+	content.IncludeAgentFiles = includeAgentFiles
 
 	// Weight B: recap from session_card_smart_recap
 	recapText, err := extractRecapText(ctx, db, sessionID)
@@ -67,12 +92,18 @@ func ExtractSearchContentWithUserMessages(ctx context.Context, db *sql.DB, sessi
 }
 
 // extractMetadata queries session metadata fields and computes their MD5 hash.
-func extractMetadata(ctx context.Context, db *sql.DB, sessionID string) (text, hash string, err error) {
+// It also returns the owner's include_agent_files_in_search setting, which is
+// part of the hash input.
+func extractMetadata(ctx context.Context, db *sql.DB, sessionID string) (text, hash string, includeAgentFiles bool, err error) {
 	var customTitle, suggestedTitle, summary, firstMsg sql.NullString
-	query := `SELECT custom_title, suggested_session_title, summary, first_user_message FROM sessions WHERE id = $1`
-	err = db.QueryRowContext(ctx, query, sessionID).Scan(&customTitle, &suggestedTitle, &summary, &firstMsg)
+	query := `
+		SELECT s.custom_title, s.suggested_session_title, s.summary, s.first_user_message, u.include_agent_files_in_search
+		FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.id = $1`
+	err = db.QueryRowContext(ctx, query, sessionID).Scan(&customTitle, &suggestedTitle, &summary, &firstMsg, &includeAgentFiles)
 	if err != nil {
-		return "", "", err
+		return "", "", false, err
 	}
 
 	parts := make([]string, 0, 4)
@@ -93,9 +124,12 @@ func extractMetadata(ctx context.Context, db *sql.DB, sessionID string) (text, h
 
 	// Hash for change detection: MD5 of concatenated raw values (empty string for NULL)
 	hashInput := customTitle.String + "|" + suggestedTitle.String + "|" + summary.String + "|" + firstMsg.String
+	if includeAgentFiles {
+		hashInput += agentSearchHashSuffix
+	}
 	hash = fmt.Sprintf("%x", md5.Sum([]byte(hashInput)))
 
-	return text, hash, nil
+	return text, hash, includeAgentFiles, nil
 }
 
 // extractRecapText queries the smart recap card and flattens all text content.
@@ -278,3 +312,22 @@ func (u *UserMessagesBuilder) ProcessFile(tf *TranscriptFile) {
 func (u *UserMessagesBuilder) Finish() string {
 	return u.b.String()
 }
+
+// ExtractAgentOutputText collects the user-visible text of subagent files —
+// the assistant text blocks, not thinking or tool calls — up to maxBytes.
+// Human-role lines are skipped: SearchText already indexes them as Weight C.
+func ExtractAgentOutputText(agents []*TranscriptFile, maxBytes int) string {
+	if maxBytes <= 0 {
+		return ""
+	}
+	b := newSearchTextBuilder(maxBytes)
+	for _, tf := range agents {
+		for _, line := range tf.Lines {
+			if line.Type != "assistant" {
+				continue
+			}
+			b.Add(getAssistantTextContent(line))
+		}
+	}
+	return b.String()
+}
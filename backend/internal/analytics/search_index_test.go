@@ -183,6 +183,11 @@ func TestSearchIndexContentCombinedText(t *testing.T) {
 			content:  SearchIndexContent{RecapText: "recap", UserMessagesText: "msgs"},
 			expected: "recap\nmsgs",
 		},
+		{
+			name:     "agent text last",
+			content:  SearchIndexContent{MetadataText: "title", UserMessagesText: "msgs", AgentText: "agent"},
+			expected: "title\nmsgs\nagent",
+		},
 	}
 
 	for _, tt := range tests {
@@ -195,6 +200,53 @@ func TestSearchIndexContentCombinedText(t *testing.T) {
 	}
 }
 
+func TestExtractAgentOutputText(t *testing.T) {
+	agents := []*TranscriptFile{
+		{
+			AgentID: "agent-1",
+			Lines: []*TranscriptLine{
+				{Type: "user", Message: &MessageContent{Content: "Agent prompt"}},
+				{Type: "assistant", Message: &MessageContent{Content: []interface{}{
+					map[string]interface{}{"type": "thinking", "thinking": "private reasoning"},
+					map[string]interface{}{"type": "text", "text": "Found it in loader.go"},
+					map[string]interface{}{"type": "tool_use", "name": "Read"},
+				}}},
+			},
+		},
+		{
+			AgentID: "agent-2",
+			Lines: []*TranscriptLine{
+				{Type: "assistant", Message: &MessageContent{Content: "Second agent done"}},
+			},
+		},
+	}
+
+	result := ExtractAgentOutputText(agents, maxSearchIndexBytes)
+	if result != "Found it in loader.go\nSecond agent done" {
+		t.Errorf("ExtractAgentOutputText() = %q", result)
+	}
+
+	if got := ExtractAgentOutputText(agents, 8); got != "Found it" {
+		t.Errorf("capped at 8 bytes = %q, want %q", got, "Found it")
+	}
+	if got := ExtractAgentOutputText(agents, 0); got != "" {
+		t.Errorf("zero budget = %q, want empty", got)
+	}
+}
+
+func TestSearchIndexContentAgentTextBudget(t *testing.T) {
+	c := SearchIndexContent{MetadataText: "title", UserMessagesText: strings.Repeat("x", maxUserMessagesBytes)}
+	want := maxSearchIndexBytes - len("title") - maxUserMessagesBytes - 3
+	if got := c.AgentTextBudget(); got != want {
+		t.Errorf("AgentTextBudget() = %d, want %d", got, want)
+	}
+
+	full := SearchIndexContent{UserMessagesText: strings.Repeat("x", maxSearchIndexBytes)}
+	if got := full.AgentTextBudget(); got != 0 {
+		t.Errorf("AgentTextBudget() with no room = %d, want 0", got)
+	}
+}
+
 func TestFlattenJSONStringArray(t *testing.T) {
 	tests := []struct {
 		name     string
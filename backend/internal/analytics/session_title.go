@@ -0,0 +1,135 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/anthropic"
+)
+
+// SessionTitlePrompt is the system prompt for on-demand title generation.
+const SessionTitlePrompt = "Generate a 5-7 word title for this session based on the first user message and summary. " +
+	"Reply with the title only: no quotes, no trailing punctuation, no preamble."
+
+const (
+	// sessionTitleMaxOutputTokens bounds the reply; a 7-word title needs far less.
+	sessionTitleMaxOutputTokens = 64
+	// sessionTitleMaxInputChars caps each input field so a pasted log in the
+	// first message doesn't turn a title request into a full recap's cost.
+	sessionTitleMaxInputChars = 4000
+	// MaxSessionTitleLength matches the sessions.ai_generated_title column.
+	MaxSessionTitleLength = 255
+)
+
+// SessionTitleResult is a generated title with the tokens it cost.
+type SessionTitleResult struct {
+	Title        string
+	InputTokens  int
+	OutputTokens int
+}
+
+// TokensUsed is the total tokens billed for the generation.
+func (r *SessionTitleResult) TokensUsed() int {
+	return r.InputTokens + r.OutputTokens
+}
+
+// GenerateSessionTitle asks the model for a short title from the session's
+// first user message and summary. At least one of them must be non-empty.
+func GenerateSessionTitle(ctx context.Context, client *anthropic.Client, model, firstUserMessage, summary string) (*SessionTitleResult, error) {
+	ctx, span := tracer.Start(ctx, "analytics.session_title.generate",
+		trace.WithAttributes(attribute.String("llm.model", model)))
+	defer span.End()
+
+	userContent := BuildSessionTitleInput(firstUserMessage, summary)
+	if userContent == "" {
+		err := fmt.Errorf("no content to title")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	temperature := 0.25
+	resp, err := client.CreateMessage(ctx, &anthropic.MessagesRequest{
+		Model:       model,
+		MaxTokens:   sessionTitleMaxOutputTokens,
+		Temperature: &temperature,
+		System:      SessionTitlePrompt,
+		Messages: []anthropic.Message{
+			{Role: "user", Content: userContent},
+		},
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("LLM request failed: %w", err)
+	}
+
+	title := CleanSessionTitle(resp.GetTextContent())
+	if title == "" {
+		err := fmt.Errorf("LLM returned an empty title")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	result := &SessionTitleResult{
+		Title:        title,
+		InputTokens:  resp.Usage.InputTokens,
+		OutputTokens: resp.Usage.OutputTokens,
+	}
+	span.SetAttributes(
+		attribute.Int("llm.tokens.input", result.InputTokens),
+		attribute.Int("llm.tokens.output", result.OutputTokens),
+	)
+	return result, nil
+}
+
+// BuildSessionTitleInput formats the user turn of the title prompt, omitting
+// whichever of the two fields is empty. Returns "" when both are.
+func BuildSessionTitleInput(firstUserMessage, summary string) string {
+	var sb strings.Builder
+	if msg := strings.TrimSpace(firstUserMessage); msg != "" {
+		sb.WriteString("<first_user_message>\n")
+		sb.WriteString(truncateRunes(msg, sessionTitleMaxInputChars))
+		sb.WriteString("\n</first_user_message>\n")
+	}
+	if s := strings.TrimSpace(summary); s != "" {
+		sb.WriteString("<summary>\n")
+		sb.WriteString(truncateRunes(s, sessionTitleMaxInputChars))
+		sb.WriteString("\n</summary>\n")
+	}
+	return sb.String()
+}
+
+// CleanSessionTitle normalizes a model reply into a stored title: first
+// non-empty line, a leading "Title:" label and wrapping quotes removed,
+// trailing period dropped, and capped at MaxSessionTitleLength characters.
+func CleanSessionTitle(raw string) string {
+	var title string
+	for _, line := range strings.Split(raw, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			title = line
+			break
+		}
+	}
+	if len(title) >= 6 && strings.EqualFold(title[:6], "title:") {
+		title = strings.TrimSpace(title[6:])
+	}
+	title = strings.Trim(title, "\"'`*“”")
+	title = strings.TrimSpace(strings.TrimSuffix(title, "."))
+	return truncateRunes(title, MaxSessionTitleLength)
+}
+
+// truncateRunes cuts s to at most n characters without splitting a rune.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,90 @@
+package analytics_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/anthropic"
+)
+
+func TestCleanSessionTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"plain", "Fix OAuth login redirect loop", "Fix OAuth login redirect loop"},
+		{"quoted", `"Fix OAuth login redirect loop"`, "Fix OAuth login redirect loop"},
+		{"label and period", "Title: Add dark mode toggle.", "Add dark mode toggle"},
+		{"first non-empty line", "\n  Refactor API validation  \nThis session refactored...", "Refactor API validation"},
+		{"markdown bold", "**Debug flaky worker tests**", "Debug flaky worker tests"},
+		{"empty", "  \n ", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := analytics.CleanSessionTitle(tt.raw); got != tt.want {
+				t.Errorf("CleanSessionTitle(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+
+	long := analytics.CleanSessionTitle(strings.Repeat("é", 300))
+	if n := len([]rune(long)); n != analytics.MaxSessionTitleLength {
+		t.Errorf("long title has %d characters, want %d", n, analytics.MaxSessionTitleLength)
+	}
+}
+
+func TestBuildSessionTitleInput(t *testing.T) {
+	if got := analytics.BuildSessionTitleInput("  ", ""); got != "" {
+		t.Errorf("empty inputs = %q, want empty", got)
+	}
+
+	got := analytics.BuildSessionTitleInput("Fix the login bug", "")
+	if !strings.Contains(got, "<first_user_message>\nFix the login bug\n</first_user_message>") || strings.Contains(got, "<summary>") {
+		t.Errorf("message only = %q", got)
+	}
+
+	got = analytics.BuildSessionTitleInput("Fix the login bug", "Login fixed")
+	if !strings.Contains(got, "<summary>\nLogin fixed\n</summary>") {
+		t.Errorf("both = %q, want a summary section", got)
+	}
+}
+
+func TestGenerateSessionTitle(t *testing.T) {
+	var gotReq anthropic.MessagesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&gotReq); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(anthropic.MessagesResponse{
+			ID:      "msg_title",
+			Type:    "message",
+			Role:    "assistant",
+			Content: []anthropic.ContentBlock{{Type: "text", Text: `"Fix OAuth Login Redirect Loop"`}},
+			Usage:   anthropic.Usage{InputTokens: 120, OutputTokens: 9},
+		})
+	}))
+	defer server.Close()
+
+	client := anthropic.NewClient("test-key", anthropic.WithBaseURL(server.URL))
+	result, err := analytics.GenerateSessionTitle(context.Background(), client, "test-model", "Login keeps redirecting", "")
+	if err != nil {
+		t.Fatalf("GenerateSessionTitle: %v", err)
+	}
+	if result.Title != "Fix OAuth Login Redirect Loop" || result.TokensUsed() != 129 {
+		t.Errorf("result = %+v, want the cleaned title and 129 tokens", result)
+	}
+	if gotReq.Model != "test-model" || gotReq.System != analytics.SessionTitlePrompt {
+		t.Errorf("request model %q, system %q", gotReq.Model, gotReq.System)
+	}
+
+	if _, err := analytics.GenerateSessionTitle(context.Background(), client, "test-model", "", ""); err == nil {
+		t.Error("expected an error with nothing to title")
+	}
+}
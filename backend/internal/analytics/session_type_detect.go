@@ -0,0 +1,80 @@
+package analytics
+
+import (
+	"encoding/json"
+
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+// SessionTypeUnknown is what DetectSessionType returns when no line carries a
+// recognizable provider signature. It is a detection result only and is never
+// stored as a session_type.
+const SessionTypeUnknown = "unknown"
+
+// sessionTypeDetectLines is how many leading transcript lines DetectSessionType
+// inspects.
+const sessionTypeDetectLines = 10
+
+// detectLine is the permissive union of the top-level fields that tell the
+// provider transcript formats apart.
+type detectLine struct {
+	Type      string          `json:"type"`
+	Model     string          `json:"model"`
+	UUID      string          `json:"uuid"`
+	SessionID string          `json:"sessionId"`
+	Payload   json.RawMessage `json:"payload"`
+	Role      string          `json:"role"`
+	Message   json.RawMessage `json:"message"`
+	Info      *struct {
+		SessionID string `json:"sessionID"`
+		Role      string `json:"role"`
+	} `json:"info"`
+	Parts json.RawMessage `json:"parts"`
+}
+
+// DetectSessionType classifies a transcript by the shape of its first lines
+// (at most the first 10) and returns the canonical provider (models.Provider*)
+// of the first line with a recognizable signature, or SessionTypeUnknown.
+// Signatures:
+//
+//   - claude-code: {"type":"init","model":...} (stream-json), or a
+//     transcript line carrying both uuid and sessionId, or a "summary" line
+//   - codex: a session_meta / turn_context / response_item / event_msg
+//     envelope with a payload
+//   - cursor: a bare {"role":...,"message":...} row without a type, or a
+//     {"type":"turn_ended"} marker
+//   - opencode: an {"info":{"sessionID":...},"parts":[...]} message
+//
+// Malformed lines are skipped.
+func DetectSessionType(firstLines []string) string {
+	for i, raw := range firstLines {
+		if i >= sessionTypeDetectLines {
+			break
+		}
+		var line detectLine
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			continue
+		}
+		if t := detectLineType(&line); t != SessionTypeUnknown {
+			return t
+		}
+	}
+	return SessionTypeUnknown
+}
+
+func detectLineType(line *detectLine) string {
+	switch {
+	case line.Type == "init" && line.Model != "":
+		return models.ProviderClaudeCode
+	case line.UUID != "" && line.SessionID != "", line.Type == "summary":
+		return models.ProviderClaudeCode
+	case len(line.Payload) > 0 && (line.Type == "session_meta" || line.Type == "turn_context" ||
+		line.Type == "response_item" || line.Type == "event_msg"):
+		return models.ProviderCodex
+	case line.Info != nil && line.Info.SessionID != "" && len(line.Parts) > 0:
+		return models.ProviderOpencode
+	case line.Type == "" && line.Role != "" && len(line.Message) > 0, line.Type == "turn_ended":
+		return models.ProviderCursor
+	}
+	return SessionTypeUnknown
+}
@@ -0,0 +1,106 @@
+package analytics
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+// fixtureHead returns the first n lines of a testdata file.
+func fixtureHead(t *testing.T, path string, n int) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	return lines[:min(n, len(lines))]
+}
+
+func TestDetectSessionType_Fixtures(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		// Starts with a file-history-snapshot line, which carries no signature.
+		{"testdata/session_comprehensive.jsonl", models.ProviderClaudeCode},
+		{"../codex/testdata/sample_rollout.jsonl", models.ProviderCodex},
+		{"testdata/cursor/main.jsonl", models.ProviderCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := DetectSessionType(fixtureHead(t, tt.path, 10)); got != tt.want {
+				t.Errorf("DetectSessionType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectSessionType_Signatures(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{
+			name:  "claude code stream-json init",
+			lines: []string{`{"type":"system","subtype":"init","session_id":"s1"}`, `{"type":"init","model":"claude-sonnet-4-5","session_id":"s1"}`},
+			want:  models.ProviderClaudeCode,
+		},
+		{
+			name:  "claude code transcript line",
+			lines: []string{`{"type":"user","uuid":"u1","sessionId":"s1","message":{"role":"user","content":"hi"}}`},
+			want:  models.ProviderClaudeCode,
+		},
+		{
+			name:  "claude code summary line",
+			lines: []string{`{"type":"summary","summary":"Fix the bug","leafUuid":"u9"}`},
+			want:  models.ProviderClaudeCode,
+		},
+		{
+			name:  "codex turn_context",
+			lines: []string{`{"timestamp":"2026-05-13T01:00:00Z","type":"turn_context","payload":{"model":"gpt-5"}}`},
+			want:  models.ProviderCodex,
+		},
+		{
+			name:  "cursor turn marker",
+			lines: []string{`{"type":"turn_ended","status":"success"}`},
+			want:  models.ProviderCursor,
+		},
+		{
+			name:  "opencode message",
+			lines: []string{`{"info":{"id":"m1","sessionID":"ses_1","role":"user","time":{"created":1}},"parts":[{"type":"text","text":"hi"}]}`},
+			want:  models.ProviderOpencode,
+		},
+		{
+			name:  "malformed lines are skipped",
+			lines: []string{`not json`, ``, `{"type":"turn_ended","status":"success"}`},
+			want:  models.ProviderCursor,
+		},
+		{
+			name:  "no signature",
+			lines: []string{`{"hello":"world"}`, `{"type":"user"}`},
+			want:  SessionTypeUnknown,
+		},
+		{
+			name:  "empty input",
+			lines: nil,
+			want:  SessionTypeUnknown,
+		},
+		{
+			name: "only the first 10 lines are inspected",
+			lines: append(strings.Split(strings.Repeat(`{"x":1}`+"\n", 10), "\n")[:10],
+				`{"type":"turn_ended","status":"success"}`),
+			want: SessionTypeUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectSessionType(tt.lines); got != tt.want {
+				t.Errorf("DetectSessionType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/anthropic"
+)
+
+// Styles for smart recap variants (GET /sessions/{id}/cards/smart-recap/alternatives).
+const (
+	RecapStyleBullet    = "bullet"
+	RecapStyleProse     = "prose"
+	RecapStyleTechnical = "technical"
+)
+
+// RecapStyles lists the variant styles in the order the API documents them.
+var RecapStyles = []string{RecapStyleBullet, RecapStyleProse, RecapStyleTechnical}
+
+const (
+	// RecapAlternativeTTL is how long a generated variant is served from
+	// session_recap_alternatives before a request generates it again.
+	RecapAlternativeTTL = 24 * time.Hour
+	// RecapAlternativeDailyLimit caps the variants generated for one session
+	// in a rolling 24 hours. Cached reads don't count.
+	RecapAlternativeDailyLimit = 3
+	// recapAlternativeWindow is the rolling window of the daily limit.
+	recapAlternativeWindow = 24 * time.Hour
+)
+
+// recapStyleInstructions is appended to the smart recap system prompt for
+// each style. Only the recap field changes; the rest of the output schema is
+// kept so the response parses like a regular recap.
+var recapStyleInstructions = map[string]string{
+	RecapStyleBullet: "Write the \"recap\" field as 3-6 short bullet points, one per line, each starting with \"- \". " +
+		"Each bullet states one outcome or event; no introductory sentence.",
+	RecapStyleProse: "Write the \"recap\" field as one or two flowing paragraphs of plain prose for a reader who was not in the session. " +
+		"No lists, headings or line breaks within a paragraph.",
+	RecapStyleTechnical: "Write the \"recap\" field for an engineer reviewing the work: name the files, functions, commands and errors involved " +
+		"and the technical decisions made and why. Prefer precise terms over general descriptions.",
+}
+
+// IsRecapStyle reports whether style is one of RecapStyles.
+func IsRecapStyle(style string) bool {
+	_, ok := recapStyleInstructions[style]
+	return ok
+}
+
+// BuildRecapAlternativeSystemPrompt appends the style's instructions to an
+// assembled smart recap system prompt (see BuildSmartRecapSystemPrompt).
+func BuildRecapAlternativeSystemPrompt(systemPrompt, style string) string {
+	return systemPrompt + "\n\nRecap style: " + style + ". " + recapStyleInstructions[style] +
+		" This overrides any earlier guidance on the recap's form; all other fields are unchanged."
+}
+
+// RecapAlternativeResult is a generated recap variant with the tokens it cost.
+type RecapAlternativeResult struct {
+	Recap        string
+	InputTokens  int
+	OutputTokens int
+}
+
+// TokensUsed is the total tokens billed for the generation.
+func (r *RecapAlternativeResult) TokensUsed() int {
+	return r.InputTokens + r.OutputTokens
+}
+
+// GenerateAlternative asks the model for the session's recap in another
+// style, using the smart recap system prompt (including an admin override)
+// with the style's instructions appended. Unlike Generate it takes no lock
+// and saves nothing: the variant doesn't touch the smart recap card, and the
+// caller caches it and charges the quota.
+func (g *SmartRecapGenerator) GenerateAlternative(ctx context.Context, input GenerateInput, style string) (*RecapAlternativeResult, error) {
+	ctx, span := tracer.Start(ctx, "smart_recap.generate_alternative",
+		trace.WithAttributes(
+			attribute.String("session.id", input.SessionID),
+			attribute.String("recap.style", style),
+			attribute.String("llm.model", g.config.Model),
+		))
+	defer span.End()
+
+	if !IsRecapStyle(style) {
+		err := fmt.Errorf("unknown recap style %q", style)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var clientOpts []anthropic.ClientOption
+	if g.config.BaseURL != "" {
+		clientOpts = append(clientOpts, anthropic.WithBaseURL(g.config.BaseURL))
+	}
+	client := anthropic.NewClient(g.config.APIKey, clientOpts...)
+	analyzer := NewSmartRecapAnalyzer(client, g.config.Model, SmartRecapAnalyzerConfig{
+		MaxOutputTokens:     g.config.MaxOutputTokens,
+		MaxTranscriptTokens: g.config.MaxTranscriptTokens,
+		SystemPrompt:        BuildRecapAlternativeSystemPrompt(g.resolveSystemPrompt(ctx), style),
+	})
+
+	genCtx, genCancel := context.WithTimeout(ctx, g.config.GenerationTimeout)
+	defer genCancel()
+	result, err := analyzer.Analyze(genCtx, input, input.CardStats)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	recap := strings.TrimSpace(result.Recap)
+	if recap == "" {
+		err := fmt.Errorf("LLM returned an empty recap")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(
+		attribute.Int("llm.tokens.input", result.InputTokens),
+		attribute.Int("llm.tokens.output", result.OutputTokens),
+	)
+	return &RecapAlternativeResult{
+		Recap:        recap,
+		InputTokens:  result.InputTokens,
+		OutputTokens: result.OutputTokens,
+	}, nil
+}
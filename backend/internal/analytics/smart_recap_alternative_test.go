@@ -0,0 +1,90 @@
+package analytics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildRecapAlternativeSystemPrompt(t *testing.T) {
+	base := BuildSmartRecapSystemPrompt(nil)
+	for _, style := range RecapStyles {
+		if !IsRecapStyle(style) {
+			t.Errorf("IsRecapStyle(%q) = false", style)
+		}
+		prompt := BuildRecapAlternativeSystemPrompt(base, style)
+		if !strings.HasPrefix(prompt, base) {
+			t.Errorf("%s prompt does not start with the smart recap prompt", style)
+		}
+		if !strings.Contains(prompt, recapStyleInstructions[style]) {
+			t.Errorf("%s prompt is missing the style's instructions", style)
+		}
+	}
+	for _, style := range []string{"", "Bullet", "haiku"} {
+		if IsRecapStyle(style) {
+			t.Errorf("IsRecapStyle(%q) = true", style)
+		}
+	}
+}
+
+func TestDecideRecapAlternativeClaim(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	lockTimeout := 5 * time.Minute
+	row := func(style, text string, age time.Duration) recapAlternativeRow {
+		return recapAlternativeRow{style: style, recapText: text, createdAt: now.Add(-age)}
+	}
+
+	tests := []struct {
+		name string
+		rows []recapAlternativeRow
+		want string // claimed, cached, in_progress, limited
+	}{
+		{"no rows", nil, "claimed"},
+		{"fresh variant", []recapAlternativeRow{row(RecapStyleBullet, "- done", time.Hour)}, "cached"},
+		{"expired variant", []recapAlternativeRow{row(RecapStyleBullet, "- done", 25*time.Hour)}, "claimed"},
+		{"generation running", []recapAlternativeRow{row(RecapStyleBullet, "", time.Minute)}, "in_progress"},
+		{"abandoned generation", []recapAlternativeRow{row(RecapStyleBullet, "", time.Hour)}, "claimed"},
+		{"other styles under the limit", []recapAlternativeRow{
+			row(RecapStyleProse, "text", time.Hour),
+			row(RecapStyleTechnical, "", time.Minute),
+		}, "claimed"},
+		{"limit reached", []recapAlternativeRow{
+			row(RecapStyleProse, "text", 3*time.Hour),
+			row(RecapStyleTechnical, "text", time.Hour),
+			row("legacy", "text", 2*time.Hour),
+		}, "limited"},
+		{"limit counts only the window", []recapAlternativeRow{
+			row(RecapStyleProse, "text", 30*time.Hour),
+			row(RecapStyleTechnical, "text", time.Hour),
+			row("legacy", "", time.Hour),
+		}, "claimed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claim := decideRecapAlternativeClaim(tt.rows, "s1", RecapStyleBullet, now, lockTimeout)
+			got := "claimed"
+			switch {
+			case claim == nil:
+			case claim.Cached != nil:
+				got = "cached"
+			case claim.InProgress:
+				got = "in_progress"
+			case claim.Limited:
+				got = "limited"
+			}
+			if got != tt.want {
+				t.Errorf("claim = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// RetryAfter runs to when the oldest counted generation leaves the window.
+	claim := decideRecapAlternativeClaim([]recapAlternativeRow{
+		row(RecapStyleProse, "text", 3*time.Hour),
+		row(RecapStyleTechnical, "text", time.Hour),
+		row("legacy", "text", 2*time.Hour),
+	}, "s1", RecapStyleBullet, now, lockTimeout)
+	if claim.RetryAfter != 21*time.Hour {
+		t.Errorf("RetryAfter = %v, want 21h", claim.RetryAfter)
+	}
+}
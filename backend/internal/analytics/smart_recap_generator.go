@@ -158,11 +158,11 @@ func (g *SmartRecapGenerator) generate(ctx context.Context, input GenerateInput,
 		clearMessageIDs(result)
 	}
 
-	// Build the card record
+	// Build the card record. ComputedAt is left zero so the store stamps it
+	// with the database clock, which the staleness queries measure against.
 	card := &SmartRecapCardRecord{
 		SessionID:                 input.SessionID,
 		Version:                   SmartRecapCardVersion,
-		ComputedAt:                time.Now().UTC(),
 		UpToLine:                  input.LineCount,
 		Recap:                     result.Recap,
 		WentWell:                  result.WentWell,
@@ -174,6 +174,7 @@ func (g *SmartRecapGenerator) generate(ctx context.Context, input GenerateInput,
 		InputTokens:               result.InputTokens,
 		OutputTokens:              result.OutputTokens,
 		GenerationTimeMs:          &result.GenerationTimeMs,
+		InputOmittedEntries:       result.InputSelection.OmittedEntries,
 	}
 
 	// Use background context to ensure operations complete even if request was canceled
@@ -184,7 +185,7 @@ func (g *SmartRecapGenerator) generate(ctx context.Context, input GenerateInput,
 	// If we can't track usage, we must not produce the recap.
 	// Admin-triggered regeneration (skipQuota=true) bypasses this.
 	if !skipQuota {
-		if err := recapquota.Increment(saveCtx, g.db, input.UserID); err != nil {
+		if err := recapquota.IncrementForSession(saveCtx, g.db, input.UserID, input.SessionID); err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, "quota increment failed: "+err.Error())
 			_ = g.store.ClearSmartRecapLock(saveCtx, input.SessionID)
@@ -0,0 +1,162 @@
+package analytics
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RecapInputSelection describes how the recap input was fitted under the
+// input token cap. Token counts use the same chars/4 estimate as the cap.
+type RecapInputSelection struct {
+	Truncated      bool
+	OriginalTokens int
+	SelectedTokens int
+	OmittedEntries int // transcript elements left out, 0 when not truncated
+}
+
+// EstimateRecapTokens is the rough input-token estimate (characters / 4) the
+// recap input cap is measured in.
+func EstimateRecapTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// recapEntryStart matches the first line of a top-level transcript element,
+// which every transcript builder writes as `<tag id="N"` at the start of a line.
+var recapEntryStart = regexp.MustCompile(`^<([a-z_]+) id="\d+"`)
+
+// recapOmittedMarker stands in for a run of left-out elements.
+const recapOmittedMarker = `<omitted entries="%d" />`
+
+// SelectRecapInput assembles the recap prompt's user content from a prepared
+// transcript and stats section, keeping it within maxTokens (chars/4). A
+// transcript that doesn't fit is cut down element by element rather than at
+// an arbitrary byte: the first user message (the task) and every compaction
+// marker are kept, then as many of the most recent elements as fit. Each run
+// of dropped elements becomes an <omitted entries="N" /> marker. The stats
+// section is always kept. maxTokens <= 0 means DefaultMaxTranscriptTokens.
+//
+// The smart recap analyzer calls this right before the LLM request, so an
+// estimate built on the same function prices exactly what would be sent.
+func SelectRecapInput(transcript, statsSection string, maxTokens int) (string, RecapInputSelection) {
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxTranscriptTokens
+	}
+	maxChars := maxTokens * 4
+
+	join := func(transcript string) string {
+		if statsSection == "" {
+			return transcript
+		}
+		return transcript + "\n\n" + statsSection
+	}
+
+	content := join(transcript)
+	sel := RecapInputSelection{OriginalTokens: EstimateRecapTokens(content)}
+	if len(content) <= maxChars {
+		sel.SelectedTokens = sel.OriginalTokens
+		return content, sel
+	}
+
+	budget := maxChars - (len(content) - len(transcript))
+	selected, omitted := selectTranscriptEntries(transcript, budget)
+	content = join(selected)
+	sel.Truncated = true
+	sel.SelectedTokens = EstimateRecapTokens(content)
+	sel.OmittedEntries = omitted
+	return content, sel
+}
+
+// selectTranscriptEntries cuts a <transcript>…</transcript> document down to
+// at most budget characters (see SelectRecapInput for what is kept). It
+// returns the reduced document and how many elements were dropped.
+func selectTranscriptEntries(transcript string, budget int) (string, int) {
+	const open, closing = "<transcript>\n", "</transcript>"
+	body := strings.TrimSuffix(strings.TrimPrefix(transcript, open), closing)
+
+	// Split the body into elements, each keeping its trailing newline.
+	var entries []string
+	var kinds []string
+	for _, line := range strings.SplitAfter(body, "\n") {
+		if line == "" {
+			continue
+		}
+		if m := recapEntryStart.FindStringSubmatch(line); m != nil || len(entries) == 0 {
+			kind := ""
+			if m != nil {
+				kind = m[1]
+			}
+			entries = append(entries, line)
+			kinds = append(kinds, kind)
+			continue
+		}
+		entries[len(entries)-1] += line
+	}
+
+	// Track the kept size as elements are added, reserving the widest possible
+	// marker for every gap so the final document can only come out smaller.
+	keep := make([]bool, len(entries))
+	markerLen := len(fmt.Sprintf(recapOmittedMarker, len(entries))) + 1
+	dropped := func(i int) bool { return i >= 0 && i < len(entries) && !keep[i] }
+	gaps := 0
+	if len(entries) > 0 {
+		gaps = 1
+	}
+	kept := len(open) + len(closing)
+	try := func(i int) bool {
+		delta := 0
+		switch left, right := dropped(i-1), dropped(i+1); {
+		case left && right:
+			delta = 1
+		case !left && !right:
+			delta = -1
+		}
+		if kept+len(entries[i])+(gaps+delta)*markerLen > budget {
+			return false
+		}
+		keep[i] = true
+		kept += len(entries[i])
+		gaps += delta
+		return true
+	}
+
+	for i, kind := range kinds {
+		if kind == "user" {
+			try(i)
+			break
+		}
+	}
+	for i := len(kinds) - 1; i >= 0; i-- {
+		if kinds[i] == "compaction" {
+			try(i)
+		}
+	}
+	// The recent tail stays contiguous: stop at the first element that doesn't fit.
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !keep[i] && !try(i) {
+			break
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(open)
+	run, omitted := 0, 0
+	flush := func() {
+		if run > 0 {
+			fmt.Fprintf(&b, recapOmittedMarker+"\n", run)
+			omitted += run
+			run = 0
+		}
+	}
+	for i, e := range entries {
+		if !keep[i] {
+			run++
+			continue
+		}
+		flush()
+		b.WriteString(e)
+	}
+	flush()
+	b.WriteString(closing)
+	return b.String(), omitted
+}
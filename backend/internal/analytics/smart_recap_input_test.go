@@ -0,0 +1,84 @@
+package analytics
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildRecapTestTranscript writes n user/assistant pairs with a compaction
+// marker after pair compactAt, in the shape the transcript builders produce.
+func buildRecapTestTranscript(n, compactAt int) string {
+	var b strings.Builder
+	b.WriteString("<transcript>\n")
+	id := 0
+	for i := 1; i <= n; i++ {
+		id++
+		fmt.Fprintf(&b, "<user id=\"%d\">\nprompt %d %s\n</user>\n", id, i, strings.Repeat("u", 400))
+		id++
+		fmt.Fprintf(&b, "<assistant id=\"%d\">\n<thinking>%s</thinking>\nanswer %d\n<tools_called>Bash</tools_called>\n</assistant>\n", id, strings.Repeat("t", 400), i)
+		if i == compactAt {
+			id++
+			fmt.Fprintf(&b, "<compaction id=\"%d\" />\n", id)
+		}
+	}
+	b.WriteString("</transcript>")
+	return b.String()
+}
+
+func TestSelectRecapInput_UnderCapUnchanged(t *testing.T) {
+	transcript := buildRecapTestTranscript(3, 0)
+	got, sel := SelectRecapInput(transcript, "<session_stats/>", 10000)
+	if got != transcript+"\n\n<session_stats/>" {
+		t.Errorf("content changed under the cap:\n%s", got)
+	}
+	if sel.Truncated || sel.OmittedEntries != 0 || sel.SelectedTokens != sel.OriginalTokens {
+		t.Errorf("selection = %+v, want untruncated", sel)
+	}
+}
+
+func TestSelectRecapInput_OversizedTranscriptFitsCap(t *testing.T) {
+	const maxTokens = 2000
+	transcript := buildRecapTestTranscript(500, 100)
+	stats := "<session_stats>\n  <turns>500</turns>\n</session_stats>"
+
+	got, sel := SelectRecapInput(transcript, stats, maxTokens)
+
+	if len(got) > maxTokens*4 {
+		t.Fatalf("input is %d chars, want at most %d", len(got), maxTokens*4)
+	}
+	if !sel.Truncated || sel.OmittedEntries == 0 {
+		t.Fatalf("selection = %+v, want truncated with omitted entries", sel)
+	}
+	if sel.SelectedTokens > maxTokens || sel.OriginalTokens <= maxTokens {
+		t.Errorf("selection tokens = %d of %d, want under %d", sel.SelectedTokens, sel.OriginalTokens, maxTokens)
+	}
+	for _, want := range []string{
+		"<transcript>\n<user id=\"1\">\nprompt 1 ", // the task
+		"<compaction id=\"201\" />",                // the compaction boundary
+		"answer 500\n",                             // the most recent turn
+		"</transcript>\n\n" + stats,                // stats are always kept
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("selected input is missing %q", want)
+		}
+	}
+	if strings.Contains(got, "prompt 2 ") {
+		t.Error("kept an early turn ahead of the recent ones")
+	}
+	omitted := strings.Count(got, "<omitted entries=")
+	if omitted != 2 {
+		t.Errorf("got %d omitted markers, want 2 (before and after the compaction)", omitted)
+	}
+	if kept := strings.Count(got, " id=\""); kept+sel.OmittedEntries != 1001 {
+		t.Errorf("kept %d + omitted %d elements, want 1001", kept, sel.OmittedEntries)
+	}
+}
+
+func TestSelectRecapInput_DefaultCap(t *testing.T) {
+	transcript := buildRecapTestTranscript(1000, 0)
+	got, sel := SelectRecapInput(transcript, "", 0)
+	if !sel.Truncated || len(got) > DefaultMaxTranscriptTokens*4 {
+		t.Errorf("selection = %+v (%d chars), want truncated under the default cap", sel, len(got))
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ConfabulousDev/confab-web/internal/db"
 	"github.com/shopspring/decimal"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -19,11 +20,41 @@ var tracer = otel.Tracer("confab/analytics")
 // Store provides database operations for session analytics cards.
 type Store struct {
 	db *sql.DB
+	// queryTimeout limits each statement the store runs (see inQueryTx).
+	queryTimeout time.Duration
+	// replica, when set, serves the replica-safe reads (see WithReplica).
+	replica *db.DB
 }
 
-// NewStore creates a new analytics store.
-func NewStore(db *sql.DB) *Store {
-	return &Store{db: db}
+// NewStore creates a new analytics store. Its query timeout comes from
+// DB_QUERY_TIMEOUT_SECONDS (default 30s).
+func NewStore(conn *sql.DB) *Store {
+	return &Store{db: conn, queryTimeout: db.LoadQueryTimeout()}
+}
+
+// WithReplica returns a copy of the store whose read-only dashboard queries
+// (GetCards, GetTokenSeries, GetTrends, GetOrgAnalytics) run on database's
+// read replica while it is within DB_REPLICA_MAX_LAG_MS (db.DB.ReadConn).
+// Writes and every other read stay on the store's own connection. Without a
+// replica configured it behaves exactly like the original store.
+func (s *Store) WithReplica(database *db.DB) *Store {
+	c := *s
+	c.replica = database
+	return &c
+}
+
+// Now returns the database clock (UTC). Time gaps against stored computed_at
+// and first_seen values are measured from it rather than the app clock, the
+// same way the precompute queries use NOW().
+func (s *Store) Now(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `SELECT NOW()`).Scan(&now)
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read database clock: %w", err)
+	}
+	return now.UTC(), nil
 }
 
 // =============================================================================
@@ -81,6 +112,9 @@ func (r *ComputeResult) ToCards(sessionID string, lineCount int64) *Cards {
 			CompactionAuto:      r.CompactionAuto,
 			CompactionManual:    r.CompactionManual,
 			CompactionAvgTimeMs: r.CompactionAvgTimeMs,
+			// Ordering anomalies
+			TimestampAnomalies:        r.TimestampAnomalies,
+			FirstTimestampAnomalyLine: r.FirstTimestampAnomalyLine,
 		}
 	}
 
@@ -108,6 +142,8 @@ func (r *ComputeResult) ToCards(sessionID string, lineCount int64) *Cards {
 			LinesRemoved:      r.LinesRemoved,
 			SearchCount:       r.SearchCount,
 			LanguageBreakdown: r.LanguageBreakdown,
+			OutOfTree:         r.OutOfTree,
+			CodeFiles:         r.CodeFiles,
 		}
 	}
 
@@ -128,6 +164,10 @@ func (r *ComputeResult) ToCards(sessionID string, lineCount int64) *Cards {
 	}
 
 	if _, hasErr := r.CardErrors["agents_and_skills"]; !hasErr {
+		agentCalls := r.AgentCalls
+		if agentCalls == nil {
+			agentCalls = []AgentCall{}
+		}
 		cards.AgentsAndSkills = &AgentsAndSkillsCardRecord{
 			SessionID:        sessionID,
 			Version:          AgentsAndSkillsCardVersion,
@@ -137,6 +177,7 @@ func (r *ComputeResult) ToCards(sessionID string, lineCount int64) *Cards {
 			SkillInvocations: r.TotalSkillInvocations,
 			AgentStats:       r.AgentStats,
 			SkillStats:       r.SkillStats,
+			AgentCalls:       agentCalls,
 		}
 	}
 
@@ -167,6 +208,23 @@ func (r *ComputeResult) ToCards(sessionID string, lineCount int64) *Cards {
 		}
 	}
 
+	// Code changes is always written too (no hunks for providers without
+	// Edit/MultiEdit tools).
+	if _, hasErr := r.CardErrors["code_changes"]; !hasErr {
+		record := &CodeChangesCardRecord{
+			SessionID:  sessionID,
+			Version:    CodeChangesCardVersion,
+			ComputedAt: now,
+			UpToLine:   lineCount,
+			Hunks:      []EditHunk{},
+		}
+		if r.CodeChanges != nil {
+			record.TotalHunks = r.CodeChanges.TotalHunks
+			record.Hunks = r.CodeChanges.Hunks
+		}
+		cards.CodeChanges = record
+	}
+
 	return cards
 }
 
@@ -231,6 +289,9 @@ func (c *Cards) ToResponse() *AnalyticsResponse {
 			CacheCreation: v2.TotalCacheCreation,
 			CacheRead:     v2.TotalCacheRead,
 			EstimatedUSD:  v2.TotalCostUSD,
+
+			ThinkingTokens:  v2.ThinkingTokens,
+			ThinkingCostUSD: v2.ThinkingCostUSD,
 		}
 
 		// tokens_v2: hierarchical per-provider/per-model breakdown. The stored
@@ -274,6 +335,9 @@ func (c *Cards) ToResponse() *AnalyticsResponse {
 			CompactionAuto:      c.Session.CompactionAuto,
 			CompactionManual:    c.Session.CompactionManual,
 			CompactionAvgTimeMs: c.Session.CompactionAvgTimeMs,
+			// Ordering anomalies
+			TimestampAnomalies:        c.Session.TimestampAnomalies,
+			FirstTimestampAnomalyLine: c.Session.FirstTimestampAnomalyLine,
 		}
 	}
 
@@ -295,6 +359,7 @@ func (c *Cards) ToResponse() *AnalyticsResponse {
 			LinesRemoved:      c.CodeActivity.LinesRemoved,
 			SearchCount:       c.CodeActivity.SearchCount,
 			LanguageBreakdown: c.CodeActivity.LanguageBreakdown,
+			OutOfTree:         c.CodeActivity.OutOfTree,
 		}
 	}
 
@@ -357,7 +422,7 @@ func (s *Store) GetSmartRecapCard(ctx context.Context, sessionID string) (*Smart
 	query := `
 		SELECT session_id, version, computed_at, up_to_line,
 			recap, went_well, went_bad, human_suggestions, environment_suggestions, default_context_suggestions,
-			model_used, input_tokens, output_tokens, generation_time_ms,
+			model_used, input_tokens, output_tokens, generation_time_ms, input_omitted_entries,
 			computing_started_at
 		FROM session_card_smart_recap
 		WHERE session_id = $1
@@ -366,23 +431,26 @@ func (s *Store) GetSmartRecapCard(ctx context.Context, sessionID string) (*Smart
 	var record SmartRecapCardRecord
 	var wentWellJSON, wentBadJSON, humanSuggestionsJSON, envSuggestionsJSON, contextSuggestionsJSON []byte
 
-	err := s.db.QueryRowContext(ctx, query, sessionID).Scan(
-		&record.SessionID,
-		&record.Version,
-		&record.ComputedAt,
-		&record.UpToLine,
-		&record.Recap,
-		&wentWellJSON,
-		&wentBadJSON,
-		&humanSuggestionsJSON,
-		&envSuggestionsJSON,
-		&contextSuggestionsJSON,
-		&record.ModelUsed,
-		&record.InputTokens,
-		&record.OutputTokens,
-		&record.GenerationTimeMs,
-		&record.ComputingStartedAt,
-	)
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query, sessionID).Scan(
+			&record.SessionID,
+			&record.Version,
+			&record.ComputedAt,
+			&record.UpToLine,
+			&record.Recap,
+			&wentWellJSON,
+			&wentBadJSON,
+			&humanSuggestionsJSON,
+			&envSuggestionsJSON,
+			&contextSuggestionsJSON,
+			&record.ModelUsed,
+			&record.InputTokens,
+			&record.OutputTokens,
+			&record.GenerationTimeMs,
+			&record.InputOmittedEntries,
+			&record.ComputingStartedAt,
+		)
+	})
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -413,6 +481,8 @@ func (s *Store) GetSmartRecapCard(ctx context.Context, sessionID string) (*Smart
 }
 
 // UpsertSmartRecapCard inserts or updates a smart recap card, clearing the computing lock.
+// A zero ComputedAt is stamped with the database clock, and the stored value is
+// written back to record.ComputedAt.
 func (s *Store) UpsertSmartRecapCard(ctx context.Context, record *SmartRecapCardRecord) error {
 	ctx, span := tracer.Start(ctx, "analytics.upsert_smart_recap_card",
 		trace.WithAttributes(attribute.String("session.id", record.SessionID)))
@@ -443,9 +513,9 @@ func (s *Store) UpsertSmartRecapCard(ctx context.Context, record *SmartRecapCard
 		INSERT INTO session_card_smart_recap (
 			session_id, version, computed_at, up_to_line,
 			recap, went_well, went_bad, human_suggestions, environment_suggestions, default_context_suggestions,
-			model_used, input_tokens, output_tokens, generation_time_ms,
+			model_used, input_tokens, output_tokens, generation_time_ms, input_omitted_entries,
 			computing_started_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NULL)
+		) VALUES ($1, $2, COALESCE($3::timestamptz, NOW()), $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NULL)
 		ON CONFLICT (session_id) DO UPDATE SET
 			version = EXCLUDED.version,
 			computed_at = EXCLUDED.computed_at,
@@ -460,25 +530,35 @@ func (s *Store) UpsertSmartRecapCard(ctx context.Context, record *SmartRecapCard
 			input_tokens = EXCLUDED.input_tokens,
 			output_tokens = EXCLUDED.output_tokens,
 			generation_time_ms = EXCLUDED.generation_time_ms,
+			input_omitted_entries = EXCLUDED.input_omitted_entries,
 			computing_started_at = NULL
+		RETURNING computed_at
 	`
 
-	_, err = s.db.ExecContext(ctx, query,
-		record.SessionID,
-		record.Version,
-		record.ComputedAt,
-		record.UpToLine,
-		record.Recap,
-		wentWellJSON,
-		wentBadJSON,
-		humanSuggestionsJSON,
-		envSuggestionsJSON,
-		contextSuggestionsJSON,
-		record.ModelUsed,
-		record.InputTokens,
-		record.OutputTokens,
-		record.GenerationTimeMs,
-	)
+	var computedAt *time.Time
+	if !record.ComputedAt.IsZero() {
+		computedAt = &record.ComputedAt
+	}
+
+	err = s.inQueryTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query,
+			record.SessionID,
+			record.Version,
+			computedAt,
+			record.UpToLine,
+			record.Recap,
+			wentWellJSON,
+			wentBadJSON,
+			humanSuggestionsJSON,
+			envSuggestionsJSON,
+			contextSuggestionsJSON,
+			record.ModelUsed,
+			record.InputTokens,
+			record.OutputTokens,
+			record.GenerationTimeMs,
+			record.InputOmittedEntries,
+		).Scan(&record.ComputedAt)
+	})
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -509,7 +589,9 @@ func (s *Store) AcquireSmartRecapLock(ctx context.Context, sessionID string, loc
 	`
 
 	var returnedID string
-	err := s.db.QueryRowContext(ctx, query, sessionID, lockTimeoutSeconds).Scan(&returnedID)
+	err := s.inQueryTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query, sessionID, lockTimeoutSeconds).Scan(&returnedID)
+	})
 	if err == sql.ErrNoRows {
 		// Lock not acquired - another process has it
 		span.SetAttributes(attribute.Bool("lock.acquired", false))
@@ -537,7 +619,10 @@ func (s *Store) ClearSmartRecapLock(ctx context.Context, sessionID string) error
 		WHERE session_id = $1
 	`
 
-	_, err := s.db.ExecContext(ctx, query, sessionID)
+	err := s.inQueryTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query, sessionID)
+		return err
+	})
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -550,29 +635,42 @@ func (s *Store) ClearSmartRecapLock(ctx context.Context, sessionID string) error
 // =============================================================================
 
 // UpsertSearchIndex inserts or updates the search index for a session.
-// The tsvector is built server-side with weighted components:
-//   - Weight A: metadata (titles, summary, first message)
-//   - Weight B: smart recap content
-//   - Weight C: user messages from transcript
+// Each weighted component is stored as its own tsvector and search_vector is
+// their concatenation, built server-side:
+//   - Weight A: metadata (titles, summary, first message) -> metadata_vector
+//   - Weight B: smart recap content -> recap_vector
+//   - Weight C: user messages from transcript -> messages_vector
+//   - Weight D: subagent output (opt-in per user) -> agent_vector
 func (s *Store) UpsertSearchIndex(ctx context.Context, record *SearchIndexRecord, content *SearchIndexContent) error {
 	ctx, span := tracer.Start(ctx, "analytics.upsert_search_index",
 		trace.WithAttributes(attribute.String("session.id", record.SessionID)))
 	defer span.End()
 
 	query := `
+		WITH v AS (
+			SELECT
+				setweight(to_tsvector('english', COALESCE($4, '')), 'A') AS m,
+				setweight(to_tsvector('english', COALESCE($5, '')), 'B') AS r,
+				setweight(to_tsvector('english', COALESCE($6, '')), 'C') AS c,
+				setweight(to_tsvector('english', COALESCE($10, '')), 'D') AS d
+		)
 		INSERT INTO session_search_index (
-			session_id, version, content_text, search_vector,
+			session_id, version, content_text,
+			metadata_vector, recap_vector, messages_vector, agent_vector, search_vector,
 			indexed_up_to_line, recap_indexed_at, metadata_hash, updated_at
 		) VALUES (
 			$1, $2, $3,
-			setweight(to_tsvector('english', COALESCE($4, '')), 'A') ||
-			setweight(to_tsvector('english', COALESCE($5, '')), 'B') ||
-			setweight(to_tsvector('english', COALESCE($6, '')), 'C'),
+			(SELECT m FROM v), (SELECT r FROM v), (SELECT c FROM v), (SELECT d FROM v),
+			(SELECT m || r || c || d FROM v),
 			$7, $8, $9, NOW()
 		)
 		ON CONFLICT (session_id) DO UPDATE SET
 			version = EXCLUDED.version,
 			content_text = EXCLUDED.content_text,
+			metadata_vector = EXCLUDED.metadata_vector,
+			recap_vector = EXCLUDED.recap_vector,
+			messages_vector = EXCLUDED.messages_vector,
+			agent_vector = EXCLUDED.agent_vector,
 			search_vector = EXCLUDED.search_vector,
 			indexed_up_to_line = EXCLUDED.indexed_up_to_line,
 			recap_indexed_at = EXCLUDED.recap_indexed_at,
@@ -580,17 +678,21 @@ func (s *Store) UpsertSearchIndex(ctx context.Context, record *SearchIndexRecord
 			updated_at = NOW()
 	`
 
-	_, err := s.db.ExecContext(ctx, query,
-		record.SessionID,         // $1
-		record.Version,           // $2
-		content.CombinedText(),   // $3
-		content.MetadataText,     // $4
-		content.RecapText,        // $5
-		content.UserMessagesText, // $6
-		record.IndexedUpToLine,   // $7
-		record.RecapIndexedAt,    // $8
-		record.MetadataHash,      // $9
-	)
+	err := s.inQueryTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query,
+			record.SessionID,         // $1
+			record.Version,           // $2
+			content.CombinedText(),   // $3
+			content.MetadataText,     // $4
+			content.RecapText,        // $5
+			content.UserMessagesText, // $6
+			record.IndexedUpToLine,   // $7
+			record.RecapIndexedAt,    // $8
+			record.MetadataHash,      // $9
+			content.AgentText,        // $10
+		)
+		return err
+	})
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -609,15 +711,17 @@ func (s *Store) GetSearchIndex(ctx context.Context, sessionID string) (*SearchIn
 	`
 
 	var record SearchIndexRecord
-	err := s.db.QueryRowContext(ctx, query, sessionID).Scan(
-		&record.SessionID,
-		&record.Version,
-		&record.ContentText,
-		&record.IndexedUpToLine,
-		&record.RecapIndexedAt,
-		&record.MetadataHash,
-		&record.UpdatedAt,
-	)
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query, sessionID).Scan(
+			&record.SessionID,
+			&record.Version,
+			&record.ContentText,
+			&record.IndexedUpToLine,
+			&record.RecapIndexedAt,
+			&record.MetadataHash,
+			&record.UpdatedAt,
+		)
+	})
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
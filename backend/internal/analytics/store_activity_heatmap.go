@@ -0,0 +1,91 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// activityCountsColumns sums the session count and tokens-card counts over
+// the rows of the enclosing query (sessions s LEFT JOIN tokens_v2 t).
+var activityCountsColumns = `
+		COUNT(*),
+		COALESCE(SUM(COALESCE(` + db.V2TotalInputExpr("t") + `, '0')::bigint), 0),
+		COALESCE(SUM(COALESCE(` + db.V2TotalOutputExpr("t") + `, '0')::bigint), 0),
+		COALESCE(SUM(COALESCE(` + db.V2TotalCacheCreationExpr("t") + `, '0')::bigint), 0),
+		COALESCE(SUM(COALESCE(` + db.V2TotalCacheReadExpr("t") + `, '0')::bigint), 0)`
+
+// activitySessionsFrom selects the user's own sessions ($1); merged sessions
+// are skipped since their tokens are counted on the surviving session.
+const activitySessionsFrom = `
+	FROM sessions s
+	LEFT JOIN session_card_tokens_v2 t ON t.session_id = s.id
+	WHERE s.user_id = $1
+		AND s.merged_at IS NULL`
+
+// activityLocalStart is a session's start as local time in $2 (an IANA zone).
+// first_seen is a bare UTC timestamp, hence the double AT TIME ZONE.
+const activityLocalStart = `((s.first_seen AT TIME ZONE 'UTC') AT TIME ZONE $2)`
+
+var activityHeatmapSQL = `
+	SELECT
+		EXTRACT(DOW FROM ` + activityLocalStart + `)::int AS dow,
+		EXTRACT(HOUR FROM ` + activityLocalStart + `)::int AS hour,` + activityCountsColumns +
+	activitySessionsFrom + `
+	GROUP BY dow, hour
+	ORDER BY dow, hour`
+
+var activityTotalsSQL = `SELECT` + activityCountsColumns + activitySessionsFrom
+
+// GetActivityHeatmap returns the user's own sessions and token totals
+// bucketed by day of week and hour of day in loc. Totals come from a separate
+// ungrouped query over the same snapshot, so they equal the sum of the
+// buckets.
+func (s *Store) GetActivityHeatmap(ctx context.Context, userID int64, loc *time.Location) (*ActivityHeatmap, error) {
+	ctx, span := tracer.Start(ctx, "analytics.get_activity_heatmap",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.String("timezone", loc.String()),
+		))
+	defer span.End()
+
+	heatmap := &ActivityHeatmap{Timezone: loc.String(), Buckets: []ActivityBucket{}}
+	// Repeatable read: both queries see the same sessions.
+	err := s.inQueryTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}, func(ctx context.Context, tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, activityHeatmapSQL, userID, loc.String())
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var b ActivityBucket
+			dest := append([]any{&b.DayOfWeek, &b.Hour}, activityCountsDest(&b.ActivityCounts)...)
+			if err := rows.Scan(dest...); err != nil {
+				return err
+			}
+			heatmap.Buckets = append(heatmap.Buckets, b)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, activityTotalsSQL, userID).Scan(activityCountsDest(&heatmap.Totals)...)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get activity heatmap: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("buckets.count", len(heatmap.Buckets)))
+	return heatmap, nil
+}
+
+func activityCountsDest(c *ActivityCounts) []any {
+	return []any{&c.SessionCount, &c.InputTokens, &c.OutputTokens, &c.CacheCreationTokens, &c.CacheReadTokens}
+}
@@ -8,8 +8,9 @@ import (
 	"errors"
 	"fmt"
 	"strings"
-	"sync"
+	"time"
 
+	"github.com/ConfabulousDev/confab-web/internal/features"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
@@ -103,12 +104,19 @@ func (ct cardTable) upsertSQL() string {
 		strings.Join(sets, ", "))
 }
 
+// cardQuerier is satisfied by both *sql.DB and *sql.Tx, so the per-card
+// helpers run unchanged inside GetCards' and UpsertCards' transactions.
+type cardQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 // getCard runs the table's SELECT and binds the row via scanTargets. Returns
 // (nil, nil) when the session has no row in this table.
-func getCard[T any](ctx context.Context, s *Store, ct cardTable, sessionID string,
+func getCard[T any](ctx context.Context, q cardQuerier, ct cardTable, sessionID string,
 	scanTargets func(*T) []any) (*T, error) {
 	var record T
-	err := s.db.QueryRowContext(ctx, ct.selectSQL(), sessionID).Scan(scanTargets(&record)...)
+	err := q.QueryRowContext(ctx, ct.selectSQL(), sessionID).Scan(scanTargets(&record)...)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -119,9 +127,9 @@ func getCard[T any](ctx context.Context, s *Store, ct cardTable, sessionID strin
 }
 
 // upsertCard inserts or updates a single card row, binding values via bindValues.
-func upsertCard[T any](ctx context.Context, s *Store, ct cardTable, record *T,
+func upsertCard[T any](ctx context.Context, q cardQuerier, ct cardTable, record *T,
 	bindValues func(*T) []any) error {
-	_, err := s.db.ExecContext(ctx, ct.upsertSQL(), bindValues(record)...)
+	_, err := q.ExecContext(ctx, ct.upsertSQL(), bindValues(record)...)
 	return err
 }
 
@@ -139,26 +147,28 @@ func tokensV2Bind(r *TokensV2CardRecord) []any {
 	return []any{r.SessionID, r.Version, r.ComputedAt, r.UpToLine, jsonCol[TokensV2Data]{&r.Data}}
 }
 
-func (s *Store) getTokensV2Card(ctx context.Context, sessionID string) (*TokensV2CardRecord, error) {
-	return getCard(ctx, s, tokensV2Table, sessionID, tokensV2Scan)
+func getTokensV2Card(ctx context.Context, q cardQuerier, sessionID string) (*TokensV2CardRecord, error) {
+	return getCard(ctx, q, tokensV2Table, sessionID, tokensV2Scan)
 }
 
-func (s *Store) upsertTokensV2Card(ctx context.Context, record *TokensV2CardRecord) error {
-	return upsertCard(ctx, s, tokensV2Table, record, tokensV2Bind)
+func upsertTokensV2Card(ctx context.Context, q cardQuerier, record *TokensV2CardRecord) error {
+	return upsertCard(ctx, q, tokensV2Table, record, tokensV2Bind)
 }
 
 var sessionTable = cardTable{name: "session_card_session", dataCols: []string{
 	"total_messages", "user_messages", "assistant_messages",
 	"human_prompts", "tool_results", "text_responses", "tool_calls", "thinking_blocks",
 	"duration_ms", "models_used",
-	"compaction_auto", "compaction_manual", "compaction_avg_time_ms"}}
+	"compaction_auto", "compaction_manual", "compaction_avg_time_ms",
+	"timestamp_anomalies", "first_timestamp_anomaly_line"}}
 
 func sessionScan(r *SessionCardRecord) []any {
 	return []any{&r.SessionID, &r.Version, &r.ComputedAt, &r.UpToLine,
 		&r.TotalMessages, &r.UserMessages, &r.AssistantMessages,
 		&r.HumanPrompts, &r.ToolResults, &r.TextResponses, &r.ToolCalls, &r.ThinkingBlocks,
 		&r.DurationMs, jsonCol[[]string]{&r.ModelsUsed},
-		&r.CompactionAuto, &r.CompactionManual, &r.CompactionAvgTimeMs}
+		&r.CompactionAuto, &r.CompactionManual, &r.CompactionAvgTimeMs,
+		&r.TimestampAnomalies, &r.FirstTimestampAnomalyLine}
 }
 
 func sessionBind(r *SessionCardRecord) []any {
@@ -166,15 +176,16 @@ func sessionBind(r *SessionCardRecord) []any {
 		r.TotalMessages, r.UserMessages, r.AssistantMessages,
 		r.HumanPrompts, r.ToolResults, r.TextResponses, r.ToolCalls, r.ThinkingBlocks,
 		r.DurationMs, jsonCol[[]string]{&r.ModelsUsed},
-		r.CompactionAuto, r.CompactionManual, r.CompactionAvgTimeMs}
+		r.CompactionAuto, r.CompactionManual, r.CompactionAvgTimeMs,
+		r.TimestampAnomalies, r.FirstTimestampAnomalyLine}
 }
 
-func (s *Store) getSessionCard(ctx context.Context, sessionID string) (*SessionCardRecord, error) {
-	return getCard(ctx, s, sessionTable, sessionID, sessionScan)
+func getSessionCard(ctx context.Context, q cardQuerier, sessionID string) (*SessionCardRecord, error) {
+	return getCard(ctx, q, sessionTable, sessionID, sessionScan)
 }
 
-func (s *Store) upsertSessionCard(ctx context.Context, record *SessionCardRecord) error {
-	return upsertCard(ctx, s, sessionTable, record, sessionBind)
+func upsertSessionCard(ctx context.Context, q cardQuerier, record *SessionCardRecord) error {
+	return upsertCard(ctx, q, sessionTable, record, sessionBind)
 }
 
 var toolsTable = cardTable{name: "session_card_tools", dataCols: []string{
@@ -190,36 +201,44 @@ func toolsBind(r *ToolsCardRecord) []any {
 		r.TotalCalls, jsonCol[map[string]*ToolStats]{&r.ToolStats}, r.ErrorCount}
 }
 
-func (s *Store) getToolsCard(ctx context.Context, sessionID string) (*ToolsCardRecord, error) {
-	return getCard(ctx, s, toolsTable, sessionID, toolsScan)
+func getToolsCard(ctx context.Context, q cardQuerier, sessionID string) (*ToolsCardRecord, error) {
+	return getCard(ctx, q, toolsTable, sessionID, toolsScan)
 }
 
-func (s *Store) upsertToolsCard(ctx context.Context, record *ToolsCardRecord) error {
-	return upsertCard(ctx, s, toolsTable, record, toolsBind)
+func upsertToolsCard(ctx context.Context, q cardQuerier, record *ToolsCardRecord) error {
+	return upsertCard(ctx, q, toolsTable, record, toolsBind)
 }
 
 var codeActivityTable = cardTable{name: "session_card_code_activity", dataCols: []string{
 	"files_read", "files_modified", "lines_added", "lines_removed", "search_count",
-	"language_breakdown"}}
+	"language_breakdown",
+	"out_of_tree_home", "out_of_tree_temp", "out_of_tree_external", "out_of_tree_samples",
+	"code_files"}}
 
 func codeActivityScan(r *CodeActivityCardRecord) []any {
 	return []any{&r.SessionID, &r.Version, &r.ComputedAt, &r.UpToLine,
 		&r.FilesRead, &r.FilesModified, &r.LinesAdded, &r.LinesRemoved, &r.SearchCount,
-		jsonCol[map[string]int]{&r.LanguageBreakdown}}
+		jsonCol[map[string]int]{&r.LanguageBreakdown},
+		&r.OutOfTree.Home, &r.OutOfTree.Temp, &r.OutOfTree.External,
+		jsonSliceCol[OutOfTreePath]{&r.OutOfTree.Samples},
+		jsonSliceCol[CodeFileStats]{&r.CodeFiles}}
 }
 
 func codeActivityBind(r *CodeActivityCardRecord) []any {
 	return []any{r.SessionID, r.Version, r.ComputedAt, r.UpToLine,
 		r.FilesRead, r.FilesModified, r.LinesAdded, r.LinesRemoved, r.SearchCount,
-		jsonCol[map[string]int]{&r.LanguageBreakdown}}
+		jsonCol[map[string]int]{&r.LanguageBreakdown},
+		r.OutOfTree.Home, r.OutOfTree.Temp, r.OutOfTree.External,
+		jsonSliceCol[OutOfTreePath]{&r.OutOfTree.Samples},
+		jsonSliceCol[CodeFileStats]{&r.CodeFiles}}
 }
 
-func (s *Store) getCodeActivityCard(ctx context.Context, sessionID string) (*CodeActivityCardRecord, error) {
-	return getCard(ctx, s, codeActivityTable, sessionID, codeActivityScan)
+func getCodeActivityCard(ctx context.Context, q cardQuerier, sessionID string) (*CodeActivityCardRecord, error) {
+	return getCard(ctx, q, codeActivityTable, sessionID, codeActivityScan)
 }
 
-func (s *Store) upsertCodeActivityCard(ctx context.Context, record *CodeActivityCardRecord) error {
-	return upsertCard(ctx, s, codeActivityTable, record, codeActivityBind)
+func upsertCodeActivityCard(ctx context.Context, q cardQuerier, record *CodeActivityCardRecord) error {
+	return upsertCard(ctx, q, codeActivityTable, record, codeActivityBind)
 }
 
 var conversationTable = cardTable{name: "session_card_conversation", dataCols: []string{
@@ -238,35 +257,37 @@ func conversationBind(r *ConversationCardRecord) []any {
 		r.TotalAssistantDurationMs, r.TotalUserDurationMs, r.AssistantUtilizationPct}
 }
 
-func (s *Store) getConversationCard(ctx context.Context, sessionID string) (*ConversationCardRecord, error) {
-	return getCard(ctx, s, conversationTable, sessionID, conversationScan)
+func getConversationCard(ctx context.Context, q cardQuerier, sessionID string) (*ConversationCardRecord, error) {
+	return getCard(ctx, q, conversationTable, sessionID, conversationScan)
 }
 
-func (s *Store) upsertConversationCard(ctx context.Context, record *ConversationCardRecord) error {
-	return upsertCard(ctx, s, conversationTable, record, conversationBind)
+func upsertConversationCard(ctx context.Context, q cardQuerier, record *ConversationCardRecord) error {
+	return upsertCard(ctx, q, conversationTable, record, conversationBind)
 }
 
 var agentsAndSkillsTable = cardTable{name: "session_card_agents_and_skills", dataCols: []string{
-	"agent_invocations", "skill_invocations", "agent_stats", "skill_stats"}}
+	"agent_invocations", "skill_invocations", "agent_stats", "skill_stats", "agent_calls"}}
 
 func agentsAndSkillsScan(r *AgentsAndSkillsCardRecord) []any {
 	return []any{&r.SessionID, &r.Version, &r.ComputedAt, &r.UpToLine,
 		&r.AgentInvocations, &r.SkillInvocations,
-		jsonCol[map[string]*AgentStats]{&r.AgentStats}, jsonCol[map[string]*SkillStats]{&r.SkillStats}}
+		jsonCol[map[string]*AgentStats]{&r.AgentStats}, jsonCol[map[string]*SkillStats]{&r.SkillStats},
+		jsonSliceCol[AgentCall]{&r.AgentCalls}}
 }
 
 func agentsAndSkillsBind(r *AgentsAndSkillsCardRecord) []any {
 	return []any{r.SessionID, r.Version, r.ComputedAt, r.UpToLine,
 		r.AgentInvocations, r.SkillInvocations,
-		jsonCol[map[string]*AgentStats]{&r.AgentStats}, jsonCol[map[string]*SkillStats]{&r.SkillStats}}
+		jsonCol[map[string]*AgentStats]{&r.AgentStats}, jsonCol[map[string]*SkillStats]{&r.SkillStats},
+		jsonSliceCol[AgentCall]{&r.AgentCalls}}
 }
 
-func (s *Store) getAgentsAndSkillsCard(ctx context.Context, sessionID string) (*AgentsAndSkillsCardRecord, error) {
-	return getCard(ctx, s, agentsAndSkillsTable, sessionID, agentsAndSkillsScan)
+func getAgentsAndSkillsCard(ctx context.Context, q cardQuerier, sessionID string) (*AgentsAndSkillsCardRecord, error) {
+	return getCard(ctx, q, agentsAndSkillsTable, sessionID, agentsAndSkillsScan)
 }
 
-func (s *Store) upsertAgentsAndSkillsCard(ctx context.Context, record *AgentsAndSkillsCardRecord) error {
-	return upsertCard(ctx, s, agentsAndSkillsTable, record, agentsAndSkillsBind)
+func upsertAgentsAndSkillsCard(ctx context.Context, q cardQuerier, record *AgentsAndSkillsCardRecord) error {
+	return upsertCard(ctx, q, agentsAndSkillsTable, record, agentsAndSkillsBind)
 }
 
 var redactionsTable = cardTable{name: "session_card_redactions", dataCols: []string{
@@ -282,12 +303,12 @@ func redactionsBind(r *RedactionsCardRecord) []any {
 		r.TotalRedactions, jsonCol[map[string]int]{&r.RedactionCounts}}
 }
 
-func (s *Store) getRedactionsCard(ctx context.Context, sessionID string) (*RedactionsCardRecord, error) {
-	return getCard(ctx, s, redactionsTable, sessionID, redactionsScan)
+func getRedactionsCard(ctx context.Context, q cardQuerier, sessionID string) (*RedactionsCardRecord, error) {
+	return getCard(ctx, q, redactionsTable, sessionID, redactionsScan)
 }
 
-func (s *Store) upsertRedactionsCard(ctx context.Context, record *RedactionsCardRecord) error {
-	return upsertCard(ctx, s, redactionsTable, record, redactionsBind)
+func upsertRedactionsCard(ctx context.Context, q cardQuerier, record *RedactionsCardRecord) error {
+	return upsertCard(ctx, q, redactionsTable, record, redactionsBind)
 }
 
 var workflowsTable = cardTable{name: "session_card_workflows", dataCols: []string{"runs"}}
@@ -300,157 +321,300 @@ func workflowsBind(r *WorkflowsCardRecord) []any {
 	return []any{r.SessionID, r.Version, r.ComputedAt, r.UpToLine, jsonSliceCol[WorkflowRun]{&r.Runs}}
 }
 
-func (s *Store) getWorkflowsCard(ctx context.Context, sessionID string) (*WorkflowsCardRecord, error) {
-	return getCard(ctx, s, workflowsTable, sessionID, workflowsScan)
+func getWorkflowsCard(ctx context.Context, q cardQuerier, sessionID string) (*WorkflowsCardRecord, error) {
+	return getCard(ctx, q, workflowsTable, sessionID, workflowsScan)
+}
+
+func upsertWorkflowsCard(ctx context.Context, q cardQuerier, record *WorkflowsCardRecord) error {
+	return upsertCard(ctx, q, workflowsTable, record, workflowsBind)
+}
+
+var codeChangesTable = cardTable{name: "session_card_code_changes", dataCols: []string{
+	"total_hunks", "code_changes"}}
+
+func codeChangesScan(r *CodeChangesCardRecord) []any {
+	return []any{&r.SessionID, &r.Version, &r.ComputedAt, &r.UpToLine,
+		&r.TotalHunks, jsonSliceCol[EditHunk]{&r.Hunks}}
+}
+
+func codeChangesBind(r *CodeChangesCardRecord) []any {
+	return []any{r.SessionID, r.Version, r.ComputedAt, r.UpToLine,
+		r.TotalHunks, jsonSliceCol[EditHunk]{&r.Hunks}}
+}
+
+func getCodeChangesCard(ctx context.Context, q cardQuerier, sessionID string) (*CodeChangesCardRecord, error) {
+	return getCard(ctx, q, codeChangesTable, sessionID, codeChangesScan)
+}
+
+func upsertCodeChangesCard(ctx context.Context, q cardQuerier, record *CodeChangesCardRecord) error {
+	return upsertCard(ctx, q, codeChangesTable, record, codeChangesBind)
+}
+
+// GetCodeChangesCard returns a session's stored code changes card, or nil if
+// its cards were never computed. A card of an older version is returned as
+// stored.
+func (s *Store) GetCodeChangesCard(ctx context.Context, sessionID string) (*CodeChangesCardRecord, error) {
+	ctx, span := tracer.Start(ctx, "analytics.get_code_changes_card",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	var record *CodeChangesCardRecord
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		record, err = getCodeChangesCard(ctx, tx, sessionID)
+		return err
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get code changes card: %w", err)
+	}
+	return record, nil
+}
+
+// GetCodeActivityCard returns a session's stored code activity card, or nil
+// if its cards were never computed. A card of an older version is returned as
+// stored.
+func (s *Store) GetCodeActivityCard(ctx context.Context, sessionID string) (*CodeActivityCardRecord, error) {
+	ctx, span := tracer.Start(ctx, "analytics.get_code_activity_card",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	var record *CodeActivityCardRecord
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		record, err = getCodeActivityCard(ctx, tx, sessionID)
+		return err
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get code activity card: %w", err)
+	}
+	return record, nil
 }
 
-func (s *Store) upsertWorkflowsCard(ctx context.Context, record *WorkflowsCardRecord) error {
-	return upsertCard(ctx, s, workflowsTable, record, workflowsBind)
+// GetAgentsAndSkillsCard returns a session's stored agents and skills card, or
+// nil if its cards were never computed. A card of an older version is
+// returned as stored.
+func (s *Store) GetAgentsAndSkillsCard(ctx context.Context, sessionID string) (*AgentsAndSkillsCardRecord, error) {
+	ctx, span := tracer.Start(ctx, "analytics.get_agents_and_skills_card",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	var record *AgentsAndSkillsCardRecord
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		record, err = getAgentsAndSkillsCard(ctx, tx, sessionID)
+		return err
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get agents and skills card: %w", err)
+	}
+	return record, nil
 }
 
 // =============================================================================
 // Card registry + parallel GetCards/UpsertCards
 // =============================================================================
 
-// cardOp wires one card into the parallel GetCards/UpsertCards fan-outs.
-// fetch reads the card and returns a closure that assigns it into Cards (run
-// under the shared mutex); present reports whether the card is set for upsert,
-// and upsert writes it.
+// cardOp wires one card into GetCards/UpsertCards. fetch reads the card and
+// returns a closure that assigns it into Cards; present reports whether the
+// card is set for upsert, and upsert writes it.
+//
+// featureFlag gates a card that is still rolling out: when set,
+// Cards.ApplyFeatureFlags calls clear to drop the card for users the flag is
+// off for (see features.IsEnabled), and marks it so the all-cards checks skip
+// it. Cards with a featureFlag must also set clear, and the stale-session
+// queries must test the same flag (features.EnabledSQL) before requiring the
+// card — otherwise sessions of users without the flag would be re-selected
+// as stale forever.
 type cardOp struct {
-	name    string
-	fetch   func(ctx context.Context, s *Store, sessionID string) (func(*Cards), error)
-	present func(*Cards) bool
-	upsert  func(ctx context.Context, s *Store, c *Cards) error
+	name        string
+	fetch       func(ctx context.Context, q cardQuerier, sessionID string) (func(*Cards), error)
+	present     func(*Cards) bool
+	upsert      func(ctx context.Context, q cardQuerier, c *Cards) error
+	featureFlag string
+	clear       func(*Cards)
 }
 
 var cardOps = []cardOp{
 	{
 		name: "tokens_v2",
-		fetch: func(ctx context.Context, s *Store, id string) (func(*Cards), error) {
-			r, err := s.getTokensV2Card(ctx, id)
+		fetch: func(ctx context.Context, q cardQuerier, id string) (func(*Cards), error) {
+			r, err := getTokensV2Card(ctx, q, id)
 			return func(c *Cards) { c.TokensV2 = r }, err
 		},
 		present: func(c *Cards) bool { return c.TokensV2 != nil },
-		upsert:  func(ctx context.Context, s *Store, c *Cards) error { return s.upsertTokensV2Card(ctx, c.TokensV2) },
+		upsert: func(ctx context.Context, q cardQuerier, c *Cards) error {
+			return upsertTokensV2Card(ctx, q, c.TokensV2)
+		},
 	},
 	{
 		name: "session",
-		fetch: func(ctx context.Context, s *Store, id string) (func(*Cards), error) {
-			r, err := s.getSessionCard(ctx, id)
+		fetch: func(ctx context.Context, q cardQuerier, id string) (func(*Cards), error) {
+			r, err := getSessionCard(ctx, q, id)
 			return func(c *Cards) { c.Session = r }, err
 		},
 		present: func(c *Cards) bool { return c.Session != nil },
-		upsert:  func(ctx context.Context, s *Store, c *Cards) error { return s.upsertSessionCard(ctx, c.Session) },
+		upsert:  func(ctx context.Context, q cardQuerier, c *Cards) error { return upsertSessionCard(ctx, q, c.Session) },
 	},
 	{
 		name: "tools",
-		fetch: func(ctx context.Context, s *Store, id string) (func(*Cards), error) {
-			r, err := s.getToolsCard(ctx, id)
+		fetch: func(ctx context.Context, q cardQuerier, id string) (func(*Cards), error) {
+			r, err := getToolsCard(ctx, q, id)
 			return func(c *Cards) { c.Tools = r }, err
 		},
 		present: func(c *Cards) bool { return c.Tools != nil },
-		upsert:  func(ctx context.Context, s *Store, c *Cards) error { return s.upsertToolsCard(ctx, c.Tools) },
+		upsert:  func(ctx context.Context, q cardQuerier, c *Cards) error { return upsertToolsCard(ctx, q, c.Tools) },
 	},
 	{
 		name: "code_activity",
-		fetch: func(ctx context.Context, s *Store, id string) (func(*Cards), error) {
-			r, err := s.getCodeActivityCard(ctx, id)
+		fetch: func(ctx context.Context, q cardQuerier, id string) (func(*Cards), error) {
+			r, err := getCodeActivityCard(ctx, q, id)
 			return func(c *Cards) { c.CodeActivity = r }, err
 		},
 		present: func(c *Cards) bool { return c.CodeActivity != nil },
-		upsert: func(ctx context.Context, s *Store, c *Cards) error {
-			return s.upsertCodeActivityCard(ctx, c.CodeActivity)
+		upsert: func(ctx context.Context, q cardQuerier, c *Cards) error {
+			return upsertCodeActivityCard(ctx, q, c.CodeActivity)
 		},
 	},
 	{
 		name: "conversation",
-		fetch: func(ctx context.Context, s *Store, id string) (func(*Cards), error) {
-			r, err := s.getConversationCard(ctx, id)
+		fetch: func(ctx context.Context, q cardQuerier, id string) (func(*Cards), error) {
+			r, err := getConversationCard(ctx, q, id)
 			return func(c *Cards) { c.Conversation = r }, err
 		},
 		present: func(c *Cards) bool { return c.Conversation != nil },
-		upsert: func(ctx context.Context, s *Store, c *Cards) error {
-			return s.upsertConversationCard(ctx, c.Conversation)
+		upsert: func(ctx context.Context, q cardQuerier, c *Cards) error {
+			return upsertConversationCard(ctx, q, c.Conversation)
 		},
 	},
 	{
 		name: "agents_and_skills",
-		fetch: func(ctx context.Context, s *Store, id string) (func(*Cards), error) {
-			r, err := s.getAgentsAndSkillsCard(ctx, id)
+		fetch: func(ctx context.Context, q cardQuerier, id string) (func(*Cards), error) {
+			r, err := getAgentsAndSkillsCard(ctx, q, id)
 			return func(c *Cards) { c.AgentsAndSkills = r }, err
 		},
 		present: func(c *Cards) bool { return c.AgentsAndSkills != nil },
-		upsert: func(ctx context.Context, s *Store, c *Cards) error {
-			return s.upsertAgentsAndSkillsCard(ctx, c.AgentsAndSkills)
+		upsert: func(ctx context.Context, q cardQuerier, c *Cards) error {
+			return upsertAgentsAndSkillsCard(ctx, q, c.AgentsAndSkills)
 		},
 	},
 	{
 		name: "redactions",
-		fetch: func(ctx context.Context, s *Store, id string) (func(*Cards), error) {
-			r, err := s.getRedactionsCard(ctx, id)
+		fetch: func(ctx context.Context, q cardQuerier, id string) (func(*Cards), error) {
+			r, err := getRedactionsCard(ctx, q, id)
 			return func(c *Cards) { c.Redactions = r }, err
 		},
 		present: func(c *Cards) bool { return c.Redactions != nil },
-		upsert:  func(ctx context.Context, s *Store, c *Cards) error { return s.upsertRedactionsCard(ctx, c.Redactions) },
+		upsert: func(ctx context.Context, q cardQuerier, c *Cards) error {
+			return upsertRedactionsCard(ctx, q, c.Redactions)
+		},
 	},
 	{
 		name: "workflows",
-		fetch: func(ctx context.Context, s *Store, id string) (func(*Cards), error) {
-			r, err := s.getWorkflowsCard(ctx, id)
+		fetch: func(ctx context.Context, q cardQuerier, id string) (func(*Cards), error) {
+			r, err := getWorkflowsCard(ctx, q, id)
 			return func(c *Cards) { c.Workflows = r }, err
 		},
 		present: func(c *Cards) bool { return c.Workflows != nil },
-		upsert:  func(ctx context.Context, s *Store, c *Cards) error { return s.upsertWorkflowsCard(ctx, c.Workflows) },
+		upsert: func(ctx context.Context, q cardQuerier, c *Cards) error {
+			return upsertWorkflowsCard(ctx, q, c.Workflows)
+		},
 	},
+	{
+		name: "code_changes",
+		fetch: func(ctx context.Context, q cardQuerier, id string) (func(*Cards), error) {
+			r, err := getCodeChangesCard(ctx, q, id)
+			return func(c *Cards) { c.CodeChanges = r }, err
+		},
+		present: func(c *Cards) bool { return c.CodeChanges != nil },
+		upsert: func(ctx context.Context, q cardQuerier, c *Cards) error {
+			return upsertCodeChangesCard(ctx, q, c.CodeChanges)
+		},
+		featureFlag: CodeChangesCardFlag,
+		clear:       func(c *Cards) { c.CodeChanges = nil },
+	},
+}
+
+// ApplyFeatureFlags drops the cards whose feature flag is off for userID, the
+// session's owner, and marks them gated off so AllValid and the other
+// all-cards checks skip them. The precompute worker applies it to a computed
+// set before writing it, and the analytics handler to both the cached and
+// the freshly computed set.
+func (c *Cards) ApplyFeatureFlags(ctx context.Context, conn *sql.DB, userID int64) error {
+	if c == nil {
+		return nil
+	}
+	return dropFlaggedCards(ctx, cardOps, c, func(ctx context.Context, flag string) (bool, error) {
+		return features.IsEnabled(ctx, conn, userID, flag)
+	})
+}
+
+// dropFlaggedCards clears and marks gated off every card whose op has a
+// featureFlag that isEnabled reports off.
+func dropFlaggedCards(ctx context.Context, ops []cardOp, cards *Cards, isEnabled func(ctx context.Context, flag string) (bool, error)) error {
+	for _, op := range ops {
+		if op.featureFlag == "" {
+			continue
+		}
+		enabled, err := isEnabled(ctx, op.featureFlag)
+		if err != nil {
+			return fmt.Errorf("feature flag %s for card %s: %w", op.featureFlag, op.name, err)
+		}
+		if enabled {
+			continue
+		}
+		op.clear(cards)
+		if cards.gatedOff == nil {
+			cards.gatedOff = make(map[string]bool)
+		}
+		cards.gatedOff[op.name] = true
+	}
+	return nil
 }
 
 // GetCards retrieves all cached card data for a session.
 // Returns a Cards struct with nil fields for cards that don't exist.
-// All card queries run in parallel to minimize latency.
+//
+// All cards are read in one REPEATABLE READ snapshot, so together with
+// UpsertCards' single transaction a reader sees either the previous complete
+// set or the new one — never a mix of cards from two computations.
+// Outdated-version cards are returned as stored; callers decide whether to
+// serve them (see Cards.StaleVersions).
 func (s *Store) GetCards(ctx context.Context, sessionID string) (*Cards, error) {
-	ctx, span := tracer.Start(ctx, "analytics.get_cards",
+	ctx, span := tracer.Start(preferReplica(ctx), "analytics.get_cards",
 		trace.WithAttributes(attribute.String("session.id", sessionID)))
 	defer span.End()
 
 	cards := &Cards{}
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-	errs := make(chan error, len(cardOps))
-
-	for _, op := range cardOps {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			assign, err := op.fetch(ctx, s, sessionID)
+	err := s.inQueryTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}, func(ctx context.Context, tx *sql.Tx) error {
+		var allErrs []error
+		for _, op := range cardOps {
+			assign, err := op.fetch(ctx, tx, sessionID)
 			if err != nil {
-				errs <- fmt.Errorf("%s: %w", op.name, err)
-				return
+				allErrs = append(allErrs, fmt.Errorf("%s: %w", op.name, err))
+				continue
 			}
-			mu.Lock()
 			assign(cards)
-			mu.Unlock()
-		}()
-	}
-
-	wg.Wait()
-	close(errs)
-
-	var allErrs []error
-	for err := range errs {
-		allErrs = append(allErrs, err)
-	}
-	if len(allErrs) > 0 {
-		combined := errors.Join(allErrs...)
-		span.RecordError(combined)
-		span.SetStatus(codes.Error, combined.Error())
-		return nil, combined
+		}
+		return errors.Join(allErrs...)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
 	}
 
 	return cards, nil
 }
 
-// UpsertCards inserts or updates all set cards for a session.
-// All card upserts run in parallel to minimize latency.
+// UpsertCards inserts or updates all set cards for a session in a single
+// transaction, so concurrent GetCards calls never observe a partially-written
+// set (e.g. half the cards at a new version mid-recompute).
 func (s *Store) UpsertCards(ctx context.Context, cards *Cards) error {
 	// Get session ID from the first available card for tracing.
 	var sessionID string
@@ -464,35 +628,106 @@ func (s *Store) UpsertCards(ctx context.Context, cards *Cards) error {
 		trace.WithAttributes(attribute.String("session.id", sessionID)))
 	defer span.End()
 
-	var wg sync.WaitGroup
-	errs := make(chan error, len(cardOps))
+	err := s.inQueryTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		return upsertCards(ctx, tx, cards)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
 
+func upsertCards(ctx context.Context, q cardQuerier, cards *Cards) error {
 	for _, op := range cardOps {
 		if !op.present(cards) {
 			continue
 		}
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if err := op.upsert(ctx, s, cards); err != nil {
-				errs <- fmt.Errorf("%s: %w", op.name, err)
-			}
-		}()
+		if err := op.upsert(ctx, q, cards); err != nil {
+			return fmt.Errorf("%s: %w", op.name, err)
+		}
 	}
+	return nil
+}
 
-	wg.Wait()
-	close(errs)
+// ComputedCardSet is everything one regular-card computation stores for a
+// session. Nil ConversationTurns, RedactionEvents or TokenSeries leave those
+// rows untouched (providers that don't produce them); a nil CostProjection is
+// skipped. A non-empty DetectedPRURL is written to sessions.detected_pr_url;
+// an empty one keeps the previous value.
+type ComputedCardSet struct {
+	Cards             *Cards
+	ConversationTurns []ConversationTurn
+	RedactionEvents   []RedactionEvent
+	TokenSeries       *TokenSeries
+	CostProjection    *CostProjectionRecord
+	DetectedPRURL     string
+}
+
+// SaveComputedCards writes a computation's cards and the rows derived from
+// the same pass (conversation turns, redaction events, token series, cost
+// projection) in one transaction. A computation that is cancelled or fails part-way leaves the
+// previous state untouched, so FindStaleSessions still selects the session
+// instead of seeing fresh cards next to stale derived rows. Every card's
+// ComputedAt is overwritten with the database's NOW().
+func (s *Store) SaveComputedCards(ctx context.Context, sessionID string, set ComputedCardSet) error {
+	ctx, span := tracer.Start(ctx, "analytics.save_computed_cards",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.Bool("conversation_turns.present", set.ConversationTurns != nil),
+			attribute.Bool("redaction_events.present", set.RedactionEvents != nil),
+			attribute.Bool("token_series.present", set.TokenSeries != nil),
+		))
+	defer span.End()
 
-	var allErrs []error
-	for err := range errs {
-		allErrs = append(allErrs, err)
-	}
-	if len(allErrs) > 0 {
-		combined := errors.Join(allErrs...)
-		span.RecordError(combined)
-		span.SetStatus(codes.Error, combined.Error())
-		return combined
-	}
+	err := s.inQueryTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		// Stamp the cards with the database clock: FindStaleSessions measures
+		// time gaps from NOW(), so an app server whose clock drifts from the
+		// database's can't make fresh cards look old (or old ones fresh).
+		var dbNow time.Time
+		if err := tx.QueryRowContext(ctx, `SELECT NOW()`).Scan(&dbNow); err != nil {
+			return fmt.Errorf("failed to read database clock: %w", err)
+		}
+		set.Cards.setComputedAt(dbNow.UTC())
 
+		if err := upsertCards(ctx, tx, set.Cards); err != nil {
+			return err
+		}
+		if set.ConversationTurns != nil {
+			if err := replaceConversationTurns(ctx, tx, sessionID, set.ConversationTurns); err != nil {
+				return err
+			}
+		}
+		if set.RedactionEvents != nil {
+			if err := replaceRedactionEvents(ctx, tx, sessionID, set.RedactionEvents); err != nil {
+				return err
+			}
+		}
+		if set.TokenSeries != nil {
+			if err := upsertTokenSeries(ctx, tx, sessionID, set.TokenSeries); err != nil {
+				return err
+			}
+		}
+		if set.CostProjection != nil {
+			if err := upsertCostProjection(ctx, tx, sessionID, set.CostProjection); err != nil {
+				return err
+			}
+		}
+		if set.DetectedPRURL != "" {
+			// Only detected_pr_url: a PR URL the owner set wins on read.
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE sessions SET detected_pr_url = $2 WHERE id = $1 AND detected_pr_url IS DISTINCT FROM $2`,
+				sessionID, set.DetectedPRURL); err != nil {
+				return fmt.Errorf("failed to save detected PR URL: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
 	return nil
 }
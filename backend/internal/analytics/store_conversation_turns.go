@@ -0,0 +1,151 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// =============================================================================
+// Conversation turn operations (session_card_conversation_turns)
+// =============================================================================
+
+// ConversationTurnFilter narrows ListConversationTurns. Nil fields match all.
+type ConversationTurnFilter struct {
+	Role       *string
+	HasToolUse *bool
+}
+
+// ReplaceConversationTurns swaps a session's stored turns for turns in one
+// transaction. Precompute and the on-demand analytics path write the turns
+// through SaveComputedCards instead, so the rows always describe the same
+// transcript as the conversation card.
+func (s *Store) ReplaceConversationTurns(ctx context.Context, sessionID string, turns []ConversationTurn) error {
+	ctx, span := tracer.Start(ctx, "analytics.replace_conversation_turns",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.Int("turns.count", len(turns)),
+		))
+	defer span.End()
+
+	err := s.inQueryTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		return replaceConversationTurns(ctx, tx, sessionID, turns)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
+
+// replaceConversationTurns deletes and reinserts a session's turns on q, which
+// must be a transaction for the swap to be atomic.
+func replaceConversationTurns(ctx context.Context, q cardQuerier, sessionID string, turns []ConversationTurn) error {
+	if len(turns) > MaxConversationTurns {
+		turns = turns[:MaxConversationTurns]
+	}
+
+	if _, err := q.ExecContext(ctx,
+		`DELETE FROM session_card_conversation_turns WHERE session_id = $1`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete conversation turns: %w", err)
+	}
+	if len(turns) == 0 {
+		return nil
+	}
+
+	// One statement via unnest keeps a 10k-turn session well under the
+	// 65535 bind-parameter limit.
+	indexes := make([]int64, len(turns))
+	roles := make([]string, len(turns))
+	lineNumbers := make([]int64, len(turns))
+	durations := make([]sql.NullInt64, len(turns))
+	tokenCounts := make([]sql.NullInt64, len(turns))
+	toolUses := make([]bool, len(turns))
+	for i, t := range turns {
+		indexes[i] = int64(t.TurnIndex)
+		roles[i] = t.Role
+		lineNumbers[i] = int64(t.LineNumber)
+		if t.DurationMs != nil {
+			durations[i] = sql.NullInt64{Int64: *t.DurationMs, Valid: true}
+		}
+		if t.TokenCount != nil {
+			tokenCounts[i] = sql.NullInt64{Int64: *t.TokenCount, Valid: true}
+		}
+		toolUses[i] = t.HasToolUse
+	}
+
+	query := `
+		INSERT INTO session_card_conversation_turns (
+			session_id, turn_index, role, line_number, duration_ms, token_count, has_tool_use
+		)
+		SELECT $1::uuid, * FROM unnest($2::int[], $3::text[], $4::int[], $5::bigint[], $6::bigint[], $7::bool[])
+	`
+	if _, err := q.ExecContext(ctx, query,
+		sessionID,             // $1
+		pq.Array(indexes),     // $2
+		pq.Array(roles),       // $3
+		pq.Array(lineNumbers), // $4
+		pq.Array(durations),   // $5
+		pq.Array(tokenCounts), // $6
+		pq.Array(toolUses),    // $7
+	); err != nil {
+		return fmt.Errorf("failed to insert conversation turns: %w", err)
+	}
+	return nil
+}
+
+// ListConversationTurns returns up to limit of a session's turns matching
+// filter, ordered by turn_index and starting strictly after afterIndex (-1 =
+// from the start). Callers page by passing the last returned TurnIndex.
+func (s *Store) ListConversationTurns(ctx context.Context, sessionID string, filter ConversationTurnFilter, afterIndex, limit int) ([]ConversationTurn, error) {
+	ctx, span := tracer.Start(ctx, "analytics.list_conversation_turns",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.Int("turns.limit", limit),
+		))
+	defer span.End()
+
+	query := `
+		SELECT turn_index, role, line_number, duration_ms, token_count, has_tool_use
+		FROM session_card_conversation_turns
+		WHERE session_id = $1
+		  AND turn_index > $2
+		  AND ($3::text IS NULL OR role = $3)
+		  AND ($4::bool IS NULL OR has_tool_use = $4)
+		ORDER BY turn_index
+		LIMIT $5
+	`
+
+	turns := []ConversationTurn{}
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, sessionID, afterIndex, filter.Role, filter.HasToolUse, limit)
+		if err != nil {
+			return fmt.Errorf("failed to list conversation turns: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var t ConversationTurn
+			if err := rows.Scan(&t.TurnIndex, &t.Role, &t.LineNumber, &t.DurationMs, &t.TokenCount, &t.HasToolUse); err != nil {
+				return fmt.Errorf("failed to scan conversation turn: %w", err)
+			}
+			turns = append(turns, t)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to iterate conversation turns: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	return turns, nil
+}
@@ -0,0 +1,75 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// dailyOwnedCostSQL sums the tokens-card cost of the user's own sessions per
+// local day ($4 = tz offset minutes) for sessions starting in [$2, $3).
+// Sessions are dated by first_seen, as in trends; merged sessions are
+// skipped since their cost is counted on the surviving session, and demo
+// sessions since their cost was never spent.
+var dailyOwnedCostSQL = `
+	SELECT
+		(s.first_seen - make_interval(mins => $4))::date AS day,
+		COALESCE(SUM(COALESCE(` + db.V2TotalCostExpr("t") + `, '0')::numeric), 0) AS cost_usd
+	FROM sessions s
+	JOIN session_card_tokens_v2 t ON t.session_id = s.id
+	WHERE s.user_id = $1
+		AND s.merged_at IS NULL
+		AND NOT s.is_demo
+		AND s.first_seen >= $2
+		AND s.first_seen < $3
+	GROUP BY day`
+
+// GetCostForecast returns the user's spend for the calendar month containing
+// now (in the tzOffset zone) and its projected month-end total. Only the
+// user's own sessions count; shared sessions are someone else's spend.
+func (s *Store) GetCostForecast(ctx context.Context, userID int64, now time.Time, tzOffset int) (*CostForecast, error) {
+	ctx, span := tracer.Start(ctx, "analytics.get_cost_forecast",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.Int("tz_offset", tzOffset),
+		))
+	defer span.End()
+
+	start, end, today := CostForecastMonth(now, tzOffset)
+
+	daily := make([]decimal.Decimal, today)
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, dailyOwnedCostSQL, userID, start, end, tzOffset)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var day time.Time
+			var cost decimal.Decimal
+			if err := rows.Scan(&day, &cost); err != nil {
+				return err
+			}
+			// A clock skewed ahead of the server can date a session past
+			// today; fold it into today rather than drop it.
+			i := min(day.Day(), today) - 1
+			daily[i] = daily[i].Add(cost)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get daily cost: %w", err)
+	}
+
+	forecast := ForecastMonthlyCost(start.Add(-time.Duration(tzOffset)*time.Minute), daily)
+	return &forecast, nil
+}
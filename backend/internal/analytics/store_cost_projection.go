@@ -0,0 +1,165 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// =============================================================================
+// Cost projection operations (session_card_cost_projection)
+// =============================================================================
+
+// priorSessionLineCountsSQL returns the final line counts (transcript + agent
+// files, as precompute counts them) of the owner's most recent sessions that
+// started before $1, skipping merged sessions and ones with nothing synced.
+const priorSessionLineCountsSQL = `
+	SELECT lines.total_lines
+	FROM sessions cur
+	JOIN sessions prior ON prior.user_id = cur.user_id
+		AND prior.id != cur.id
+		AND prior.merged_at IS NULL
+		AND prior.first_seen < cur.first_seen
+	CROSS JOIN LATERAL (
+		SELECT SUM(sf.last_synced_line)::bigint AS total_lines
+		FROM sync_files sf
+		WHERE sf.session_id = prior.id AND sf.file_type IN ('transcript', 'agent')
+	) lines
+	WHERE cur.id = $1 AND lines.total_lines > 0
+	ORDER BY prior.first_seen DESC
+	LIMIT $2`
+
+// RefreshCostProjection recomputes and stores a session's cost projection
+// from its freshly computed tokens card; a nil tokens card stores an empty
+// projection. Precompute and the on-demand analytics path go through
+// BuildCostProjection and SaveComputedCards instead, so the projection is
+// written in the same transaction as the cards.
+func (s *Store) RefreshCostProjection(ctx context.Context, sessionID string, tokens *TokensV2CardRecord) error {
+	record, err := s.BuildCostProjection(ctx, sessionID, tokens)
+	if err != nil {
+		return err
+	}
+	return s.UpsertCostProjection(ctx, sessionID, record)
+}
+
+// BuildCostProjection computes a session's cost projection from its tokens
+// card and the line counts of the owner's prior sessions, without storing it.
+func (s *Store) BuildCostProjection(ctx context.Context, sessionID string, tokens *TokensV2CardRecord) (*CostProjectionRecord, error) {
+	ctx, span := tracer.Start(ctx, "analytics.build_cost_projection",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	var history []int64
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, priorSessionLineCountsSQL, sessionID, CostProjectionHistorySize)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var lines int64
+			if err := rows.Scan(&lines); err != nil {
+				return err
+			}
+			history = append(history, lines)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get prior session line counts: %w", err)
+	}
+
+	record := &CostProjectionRecord{HistorySessionCount: len(history)}
+	if tokens != nil {
+		record.UpToLine = tokens.UpToLine
+		record.Projection = ProjectCost(tokens.Data.TotalCostUSD, tokens.UpToLine, history)
+	}
+	span.SetAttributes(
+		attribute.Int("projection.history_sessions", len(history)),
+		attribute.Bool("projection.present", record.Projection != nil),
+	)
+	return record, nil
+}
+
+// UpsertCostProjection stores a session's cost projection, replacing any
+// previous one.
+func (s *Store) UpsertCostProjection(ctx context.Context, sessionID string, record *CostProjectionRecord) error {
+	ctx, span := tracer.Start(ctx, "analytics.upsert_cost_projection",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	err := s.inQueryTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		return upsertCostProjection(ctx, tx, sessionID, record)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
+
+func upsertCostProjection(ctx context.Context, q cardQuerier, sessionID string, record *CostProjectionRecord) error {
+	var projection []byte
+	if record.Projection != nil {
+		var err error
+		projection, err = json.Marshal(record.Projection)
+		if err != nil {
+			return fmt.Errorf("failed to marshal cost projection: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO session_card_cost_projection (session_id, computed_at, up_to_line, history_session_count, projection)
+		VALUES ($1, NOW(), $2, $3, $4)
+		ON CONFLICT (session_id) DO UPDATE SET
+			computed_at = NOW(),
+			up_to_line = EXCLUDED.up_to_line,
+			history_session_count = EXCLUDED.history_session_count,
+			projection = EXCLUDED.projection
+	`
+	if _, err := q.ExecContext(ctx, query, sessionID, record.UpToLine, record.HistorySessionCount, projection); err != nil {
+		return fmt.Errorf("failed to upsert cost projection: %w", err)
+	}
+	return nil
+}
+
+// GetCostProjection returns a session's stored cost projection, or nil if its
+// cards were never computed.
+func (s *Store) GetCostProjection(ctx context.Context, sessionID string) (*CostProjectionRecord, error) {
+	ctx, span := tracer.Start(ctx, "analytics.get_cost_projection",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	var record CostProjectionRecord
+	var projection []byte
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx,
+			`SELECT computed_at, up_to_line, history_session_count, projection
+			 FROM session_card_cost_projection WHERE session_id = $1`,
+			sessionID).Scan(&record.ComputedAt, &record.UpToLine, &record.HistorySessionCount, &projection)
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get cost projection: %w", err)
+	}
+	if projection != nil {
+		if err := json.Unmarshal(projection, &record.Projection); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("failed to unmarshal cost projection: %w", err)
+		}
+	}
+	return &record, nil
+}
@@ -0,0 +1,168 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrModelPricingConflict is returned by SetModelPricing when the new period
+// would not start after the model's latest one.
+var ErrModelPricingConflict = errors.New("model pricing period conflicts with an existing one")
+
+const modelPricingColumns = `id, model_name, input_cost_per_1m, output_cost_per_1m,
+	cache_creation_cost_per_1m, cache_creation_1h_cost_per_1m, cache_read_cost_per_1m,
+	effective_from, effective_to`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanModelPricingPeriod(row rowScanner) (ModelPricingPeriod, error) {
+	var p ModelPricingPeriod
+	var effectiveTo sql.NullTime
+	err := row.Scan(&p.ID, &p.ModelName, &p.Pricing.Input, &p.Pricing.Output,
+		&p.Pricing.CacheWrite, &p.Pricing.CacheWrite1h, &p.Pricing.CacheRead,
+		&p.EffectiveFrom, &effectiveTo)
+	if err != nil {
+		return p, err
+	}
+	p.EffectiveFrom = p.EffectiveFrom.UTC()
+	if effectiveTo.Valid {
+		to := effectiveTo.Time.UTC()
+		p.EffectiveTo = &to
+	}
+	return p, nil
+}
+
+// GetModelPricing returns the model_pricing price in effect for modelName at
+// at, or nil when the table has no period covering it (pricing.json then
+// applies). modelName may be a full model name; it is reduced to its pricing
+// family first. Card computation resolves prices the same way from the
+// in-memory copy RefreshModelPricing loads.
+func GetModelPricing(ctx context.Context, conn *sql.DB, modelName string, at time.Time) (*ModelPricing, error) {
+	family := getModelFamily(modelName)
+	ctx, span := tracer.Start(ctx, "analytics.get_model_pricing",
+		trace.WithAttributes(attribute.String("model.family", family)))
+	defer span.End()
+
+	p, err := scanModelPricingPeriod(conn.QueryRowContext(ctx, `
+		SELECT `+modelPricingColumns+`
+		FROM model_pricing
+		WHERE model_name = $1
+			AND effective_from <= $2
+			AND (effective_to IS NULL OR effective_to > $2)`, family, at))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get model pricing: %w", err)
+	}
+	return &p.Pricing, nil
+}
+
+// LoadModelPricing returns every model_pricing period, ordered by model and
+// start.
+func LoadModelPricing(ctx context.Context, conn *sql.DB) ([]ModelPricingPeriod, error) {
+	ctx, span := tracer.Start(ctx, "analytics.load_model_pricing")
+	defer span.End()
+
+	periods, err := func() ([]ModelPricingPeriod, error) {
+		rows, err := conn.QueryContext(ctx, `
+			SELECT `+modelPricingColumns+`
+			FROM model_pricing
+			ORDER BY model_name, effective_from`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		var periods []ModelPricingPeriod
+		for rows.Next() {
+			p, err := scanModelPricingPeriod(rows)
+			if err != nil {
+				return nil, err
+			}
+			periods = append(periods, p)
+		}
+		return periods, rows.Err()
+	}()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to load model pricing: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("periods.count", len(periods)))
+	return periods, nil
+}
+
+// SetModelPricing starts a new open-ended price period for modelName at
+// effectiveFrom, ending the model's current period there. modelName is
+// reduced to its pricing family, which the returned period carries. Periods
+// only ever append: effectiveFrom must be after the start of the model's
+// latest period, else ErrModelPricingConflict. Cards already computed for
+// sessions on or after effectiveFrom keep their cost until they are
+// recomputed.
+func (s *Store) SetModelPricing(ctx context.Context, modelName string, pricing ModelPricing, effectiveFrom time.Time) (*ModelPricingPeriod, error) {
+	modelName = getModelFamily(modelName)
+	ctx, span := tracer.Start(ctx, "analytics.set_model_pricing",
+		trace.WithAttributes(attribute.String("model.family", modelName)))
+	defer span.End()
+
+	var period ModelPricingPeriod
+	err := s.inQueryTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		var latestFrom time.Time
+		var latestTo sql.NullTime
+		err := tx.QueryRowContext(ctx, `
+			SELECT effective_from, effective_to
+			FROM model_pricing
+			WHERE model_name = $1
+			ORDER BY effective_from DESC
+			LIMIT 1
+			FOR UPDATE`, modelName).Scan(&latestFrom, &latestTo)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return err
+		case !effectiveFrom.After(latestFrom):
+			return ErrModelPricingConflict
+		case !latestTo.Valid || latestTo.Time.After(effectiveFrom):
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE model_pricing SET effective_to = $3
+				WHERE model_name = $1 AND effective_from = $2`,
+				modelName, latestFrom, effectiveFrom); err != nil {
+				return err
+			}
+		}
+
+		period, err = scanModelPricingPeriod(tx.QueryRowContext(ctx, `
+			INSERT INTO model_pricing (
+				model_name, input_cost_per_1m, output_cost_per_1m, cache_creation_cost_per_1m,
+				cache_creation_1h_cost_per_1m, cache_read_cost_per_1m, effective_from
+			) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING `+modelPricingColumns,
+			modelName, pricing.Input, pricing.Output, pricing.CacheWrite,
+			pricing.CacheWrite1h, pricing.CacheRead, effectiveFrom))
+		return err
+	})
+	if err != nil {
+		// A concurrent first insert for the same model loses on the unique
+		// indexes rather than on the row lock.
+		if !errors.Is(err, ErrModelPricingConflict) && db.IsUniqueViolation(err) {
+			err = ErrModelPricingConflict
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to set model pricing: %w", err)
+	}
+	return &period, nil
+}
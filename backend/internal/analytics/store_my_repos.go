@@ -0,0 +1,78 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// myReposSQL groups the user's own ($1) listable sessions by resolved repo.
+// Sessions without a repo_url (no git_info, or an empty one) fall in the NULL
+// group. Merged sessions are skipped since their tokens are counted on the
+// surviving session.
+var myReposSQL = `
+	SELECT repo, COUNT(*), COALESCE(SUM(tokens), 0), MAX(activity)
+	FROM (
+		SELECT
+			NULLIF(` + db.RepoRootExpr("s") + `, '') AS repo,
+			COALESCE(` + db.V2TotalInputExpr("t") + `, '0')::bigint
+				+ COALESCE(` + db.V2TotalOutputExpr("t") + `, '0')::bigint
+				+ COALESCE(` + db.V2TotalCacheCreationExpr("t") + `, '0')::bigint
+				+ COALESCE(` + db.V2TotalCacheReadExpr("t") + `, '0')::bigint AS tokens,
+			COALESCE(s.last_message_at, s.first_seen) AS activity
+		FROM sessions s
+		LEFT JOIN session_card_tokens_v2 t ON t.session_id = s.id
+		WHERE s.user_id = $1
+			AND s.merged_at IS NULL
+			AND ` + db.ListableSessionPredicate("s") + `
+	) r
+	GROUP BY repo
+	ORDER BY MAX(activity) DESC, repo`
+
+// GetMyRepos returns the user's own sessions grouped by repository. Only
+// sessions that appear in the session list count, so a repo's session_count
+// matches the list filtered to it.
+func (s *Store) GetMyRepos(ctx context.Context, userID int64) (*MyRepos, error) {
+	ctx, span := tracer.Start(ctx, "analytics.get_my_repos",
+		trace.WithAttributes(attribute.Int64("user.id", userID)))
+	defer span.End()
+
+	result := &MyRepos{Repos: []RepoActivity{}}
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, myReposSQL, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var repo sql.NullString
+			var counts RepoActivityCounts
+			var lastActivity time.Time
+			if err := rows.Scan(&repo, &counts.SessionCount, &counts.TotalTokens, &lastActivity); err != nil {
+				return err
+			}
+			lastActivity = lastActivity.UTC()
+			counts.LastActivity = &lastActivity
+			if !repo.Valid {
+				result.Unassigned = counts
+				continue
+			}
+			result.Repos = append(result.Repos, RepoActivity{Repo: repo.String, RepoActivityCounts: counts})
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get repos: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("repos.count", len(result.Repos)))
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// outcomesSQL groups the user's own ($1) sessions by effective PR URL: the
+// manual pr_url, else the detected one. Sessions with neither are skipped.
+// Merged sessions are skipped since their cost is counted on the surviving
+// session, and demo sessions since their cost was never spent.
+var outcomesSQL = `
+	SELECT
+		COALESCE(s.pr_url, s.detected_pr_url) AS pr_url,
+		COUNT(*),
+		COALESCE(SUM(COALESCE(` + db.V2TotalCostExpr("t") + `, '0')::numeric), 0),
+		COALESCE(SUM(sc.duration_ms), 0),
+		MIN(s.first_seen),
+		MAX(COALESCE(s.last_message_at, s.first_seen)),
+		array_agg(s.id::text ORDER BY s.first_seen, s.id)
+	FROM sessions s
+	LEFT JOIN session_card_tokens_v2 t ON t.session_id = s.id
+	LEFT JOIN session_card_session sc ON sc.session_id = s.id
+	WHERE s.user_id = $1
+		AND (s.pr_url IS NOT NULL OR s.detected_pr_url IS NOT NULL)
+		AND s.merged_at IS NULL
+		AND NOT s.is_demo
+	GROUP BY 1
+	ORDER BY MAX(COALESCE(s.last_message_at, s.first_seen)) DESC, 1`
+
+// GetOutcomes returns the user's own sessions grouped by the pull request
+// they produced, with cost and duration summed per PR.
+func (s *Store) GetOutcomes(ctx context.Context, userID int64) (*Outcomes, error) {
+	ctx, span := tracer.Start(ctx, "analytics.get_outcomes",
+		trace.WithAttributes(attribute.Int64("user.id", userID)))
+	defer span.End()
+
+	result := &Outcomes{Outcomes: []PROutcome{}}
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, outcomesSQL, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var o PROutcome
+			var cost decimal.Decimal
+			if err := rows.Scan(&o.PRURL, &o.SessionCount, &cost, &o.TotalDurationMs,
+				&o.FirstSessionAt, &o.LastActivity, pq.Array(&o.SessionIDs)); err != nil {
+				return err
+			}
+			o.TotalCostUSD = cost.StringFixed(2)
+			o.FirstSessionAt = o.FirstSessionAt.UTC()
+			o.LastActivity = o.LastActivity.UTC()
+			result.Outcomes = append(result.Outcomes, o)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get outcomes: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("outcomes.count", len(result.Outcomes)))
+	return result, nil
+}
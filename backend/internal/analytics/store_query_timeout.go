@@ -0,0 +1,87 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// readOnlyTx is the transaction mode for store reads.
+var readOnlyTx = &sql.TxOptions{ReadOnly: true}
+
+// replicaReadsKey marks a context whose read-only transactions may run on the
+// read replica (see Store.WithReplica).
+type replicaReadsKey struct{}
+
+// preferReplica marks ctx so inQueryTx sends its read-only transactions to the
+// replica. The replica-safe entry points call it first thing.
+func preferReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaReadsKey{}, true)
+}
+
+// connFor picks the connection for a transaction: the replica for read-only
+// ones under preferReplica on a store built WithReplica, else the primary.
+func (s *Store) connFor(ctx context.Context, opts *sql.TxOptions) *sql.DB {
+	if s.replica == nil || opts == nil || !opts.ReadOnly {
+		return s.db
+	}
+	if prefer, _ := ctx.Value(replicaReadsKey{}).(bool); !prefer {
+		return s.db
+	}
+	return s.replica.ReadConn(ctx)
+}
+
+// inQueryTx runs fn in a transaction whose statements are limited to the
+// store's query timeout (SET LOCAL statement_timeout, see db.WithQueryTimeout).
+// Every store query runs through it so a runaway aggregation is cancelled by
+// Postgres instead of holding a connection for minutes.
+//
+// Writes commit when fn succeeds. Read-only transactions are rolled back
+// instead, so a Trends card that degrades after its own statement was
+// cancelled still returns its degraded result. A timeout is returned as
+// db.ErrQueryTimeout.
+func (s *Store) inQueryTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	ctx, cancel := db.WithQueryTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	tx, err := db.BeginQueryTx(ctx, s.connFor(ctx, opts), opts)
+	if err != nil {
+		return db.AsQueryTimeout(err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(ctx, tx); err != nil {
+		return db.AsQueryTimeout(err)
+	}
+	if opts != nil && opts.ReadOnly {
+		return nil
+	}
+	return db.AsQueryTimeout(tx.Commit())
+}
+
+// queryEach runs a read-only query under the store's query timeout and calls
+// scan once per row.
+func (s *Store) queryEach(ctx context.Context, query string, args []any, scan func(rows *sql.Rows) error) error {
+	return s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			if err := scan(rows); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
+
+// queryRow runs a read-only single-row query under the store's query timeout
+// and scans it into dest. sql.ErrNoRows is returned unwrapped.
+func (s *Store) queryRow(ctx context.Context, query string, args []any, dest ...any) error {
+	return s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query, args...).Scan(dest...)
+	})
+}
@@ -0,0 +1,242 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// =============================================================================
+// Smart recap variant operations (session_recap_alternatives)
+// =============================================================================
+
+// RecapAlternative is a cached smart recap variant.
+type RecapAlternative struct {
+	SessionID string
+	Style     string
+	RecapText string
+	CreatedAt time.Time
+}
+
+// RecapAlternativeClaim is the outcome of ClaimRecapAlternative. Exactly one
+// of Claimed, Cached, InProgress and Limited holds.
+type RecapAlternativeClaim struct {
+	// Claimed: the caller must generate the variant and then call
+	// SaveRecapAlternative, or ReleaseRecapAlternative if generation fails.
+	Claimed bool
+	// Cached is a fresh variant saved since the caller last looked.
+	Cached *RecapAlternative
+	// InProgress: another request is generating this style.
+	InProgress bool
+	// Limited: the session reached RecapAlternativeDailyLimit. RetryAfter
+	// is when the oldest counted generation leaves the window.
+	Limited    bool
+	RetryAfter time.Duration
+}
+
+// recapAlternativeRow is one session_recap_alternatives row as the claim
+// reads it.
+type recapAlternativeRow struct {
+	style     string
+	recapText string
+	createdAt time.Time
+}
+
+// live reports whether a row counts at now: a saved variant inside the
+// window, or a generation started within the lock timeout. A placeholder
+// older than that was abandoned (the process died mid-generation).
+func (r recapAlternativeRow) live(now time.Time, window, lockTimeout time.Duration) bool {
+	if r.recapText == "" {
+		return now.Sub(r.createdAt) < lockTimeout
+	}
+	return now.Sub(r.createdAt) < window
+}
+
+// decideRecapAlternativeClaim decides a claim for style from the session's
+// rows at now. A nil result means the claim may proceed.
+func decideRecapAlternativeClaim(rows []recapAlternativeRow, sessionID, style string, now time.Time, lockTimeout time.Duration) *RecapAlternativeClaim {
+	counted := 0
+	var oldest time.Time
+	for _, r := range rows {
+		if r.style == style && r.live(now, RecapAlternativeTTL, lockTimeout) {
+			if r.recapText == "" {
+				return &RecapAlternativeClaim{InProgress: true}
+			}
+			return &RecapAlternativeClaim{Cached: &RecapAlternative{
+				SessionID: sessionID, Style: style, RecapText: r.recapText, CreatedAt: r.createdAt,
+			}}
+		}
+		if r.live(now, recapAlternativeWindow, lockTimeout) {
+			counted++
+			if oldest.IsZero() || r.createdAt.Before(oldest) {
+				oldest = r.createdAt
+			}
+		}
+	}
+	if counted >= RecapAlternativeDailyLimit {
+		return &RecapAlternativeClaim{Limited: true, RetryAfter: oldest.Add(recapAlternativeWindow).Sub(now)}
+	}
+	return nil
+}
+
+// GetRecapAlternative returns the session's saved variant in style if it is
+// younger than RecapAlternativeTTL, or nil.
+func (s *Store) GetRecapAlternative(ctx context.Context, sessionID, style string) (*RecapAlternative, error) {
+	ctx, span := tracer.Start(ctx, "analytics.get_recap_alternative",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.String("recap.style", style),
+		))
+	defer span.End()
+
+	query := `
+		SELECT recap_text, created_at
+		FROM session_recap_alternatives
+		WHERE session_id = $1 AND style = $2
+			AND recap_text <> ''
+			AND created_at > NOW() - make_interval(secs => $3)
+	`
+
+	alt := &RecapAlternative{SessionID: sessionID, Style: style}
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query, sessionID, style, RecapAlternativeTTL.Seconds()).Scan(&alt.RecapText, &alt.CreatedAt)
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get recap alternative: %w", err)
+	}
+	return alt, nil
+}
+
+// ClaimRecapAlternative reserves the generation of the session's variant in
+// style by writing an empty placeholder row, unless a fresh variant exists,
+// another request is generating it, or the session reached
+// RecapAlternativeDailyLimit. The session row is locked while deciding, so
+// concurrent claims for one session are serialized. Placeholders older than
+// lockTimeoutSeconds are treated as abandoned. Returns db.ErrSessionNotFound
+// if the session doesn't exist.
+func (s *Store) ClaimRecapAlternative(ctx context.Context, sessionID, style string, lockTimeoutSeconds int) (*RecapAlternativeClaim, error) {
+	ctx, span := tracer.Start(ctx, "analytics.claim_recap_alternative",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.String("recap.style", style),
+		))
+	defer span.End()
+
+	var claim *RecapAlternativeClaim
+	err := s.inQueryTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		var now time.Time
+		err := tx.QueryRowContext(ctx,
+			`SELECT NOW() FROM sessions WHERE id = $1 FOR UPDATE`, sessionID).Scan(&now)
+		if err == sql.ErrNoRows {
+			return db.ErrSessionNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx,
+			`SELECT style, recap_text, created_at FROM session_recap_alternatives WHERE session_id = $1`, sessionID)
+		if err != nil {
+			return err
+		}
+		var existing []recapAlternativeRow
+		for rows.Next() {
+			var r recapAlternativeRow
+			if err := rows.Scan(&r.style, &r.recapText, &r.createdAt); err != nil {
+				rows.Close()
+				return err
+			}
+			existing = append(existing, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		claim = decideRecapAlternativeClaim(existing, sessionID, style, now, time.Duration(lockTimeoutSeconds)*time.Second)
+		if claim != nil {
+			return nil
+		}
+		claim = &RecapAlternativeClaim{Claimed: true}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO session_recap_alternatives (session_id, style, recap_text, created_at)
+			VALUES ($1, $2, '', NOW())
+			ON CONFLICT (session_id, style) DO UPDATE SET recap_text = '', created_at = NOW()`,
+			sessionID, style)
+		return err
+	})
+	if errors.Is(err, db.ErrSessionNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to claim recap alternative: %w", err)
+	}
+	span.SetAttributes(attribute.Bool("claim.claimed", claim.Claimed))
+	return claim, nil
+}
+
+// SaveRecapAlternative stores a generated variant in the row claimed by
+// ClaimRecapAlternative. Its TTL runs from now.
+func (s *Store) SaveRecapAlternative(ctx context.Context, sessionID, style, recapText string) error {
+	ctx, span := tracer.Start(ctx, "analytics.save_recap_alternative",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.String("recap.style", style),
+		))
+	defer span.End()
+
+	query := `
+		INSERT INTO session_recap_alternatives (session_id, style, recap_text, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (session_id, style) DO UPDATE SET recap_text = $3, created_at = NOW()
+	`
+	err := s.inQueryTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query, sessionID, style, recapText)
+		return err
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to save recap alternative: %w", err)
+	}
+	return nil
+}
+
+// ReleaseRecapAlternative deletes the placeholder of a claim whose generation
+// failed, so the failure doesn't count against the daily limit. A saved
+// variant is left alone.
+func (s *Store) ReleaseRecapAlternative(ctx context.Context, sessionID, style string) error {
+	ctx, span := tracer.Start(ctx, "analytics.release_recap_alternative",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.String("recap.style", style),
+		))
+	defer span.End()
+
+	query := `DELETE FROM session_recap_alternatives WHERE session_id = $1 AND style = $2 AND recap_text = ''`
+	err := s.inQueryTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query, sessionID, style)
+		return err
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to release recap alternative: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// =============================================================================
+// Redaction event operations (session_card_redaction_events)
+// =============================================================================
+
+// maxRedactionTypeLen matches the width of session_card_redaction_events.type.
+const maxRedactionTypeLen = 64
+
+// RedactionEventFilter narrows ListRedactionEvents. Nil fields match all.
+type RedactionEventFilter struct {
+	Type *string
+}
+
+// replaceRedactionEvents deletes and reinserts a session's redaction events
+// on q, which must be a transaction for the swap to be atomic. Precompute and
+// the on-demand analytics path write them through SaveComputedCards, so the
+// rows always describe the same transcript as the redactions card.
+func replaceRedactionEvents(ctx context.Context, q cardQuerier, sessionID string, events []RedactionEvent) error {
+	if len(events) > MaxRedactionEvents {
+		events = events[:MaxRedactionEvents]
+	}
+
+	if _, err := q.ExecContext(ctx,
+		`DELETE FROM session_card_redaction_events WHERE session_id = $1`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete redaction events: %w", err)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	indexes := make([]int64, len(events))
+	types := make([]string, len(events))
+	lineNumbers := make([]int64, len(events))
+	previews := make([]string, len(events))
+	for i, e := range events {
+		indexes[i] = int64(e.RedactionIndex)
+		types[i] = truncateRunes(e.Type, maxRedactionTypeLen)
+		lineNumbers[i] = int64(e.LineNumber)
+		previews[i] = truncateRunes(e.ContextPreview, MaxRedactionContextPreview)
+	}
+
+	query := `
+		INSERT INTO session_card_redaction_events (
+			session_id, redaction_index, type, line_number, context_preview
+		)
+		SELECT $1::uuid, * FROM unnest($2::int[], $3::text[], $4::int[], $5::text[])
+	`
+	if _, err := q.ExecContext(ctx, query,
+		sessionID,             // $1
+		pq.Array(indexes),     // $2
+		pq.Array(types),       // $3
+		pq.Array(lineNumbers), // $4
+		pq.Array(previews),    // $5
+	); err != nil {
+		return fmt.Errorf("failed to insert redaction events: %w", err)
+	}
+	return nil
+}
+
+// ListRedactionEvents returns up to limit of a session's redaction events
+// matching filter, ordered by redaction_index and starting strictly after
+// afterIndex (-1 = from the start). Callers page by passing the last returned
+// RedactionIndex.
+func (s *Store) ListRedactionEvents(ctx context.Context, sessionID string, filter RedactionEventFilter, afterIndex, limit int) ([]RedactionEvent, error) {
+	ctx, span := tracer.Start(ctx, "analytics.list_redaction_events",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.Int("redaction_events.limit", limit),
+		))
+	defer span.End()
+
+	query := `
+		SELECT redaction_index, type, line_number, context_preview
+		FROM session_card_redaction_events
+		WHERE session_id = $1
+		  AND redaction_index > $2
+		  AND ($3::text IS NULL OR type = $3)
+		ORDER BY redaction_index
+		LIMIT $4
+	`
+
+	events := []RedactionEvent{}
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, sessionID, afterIndex, filter.Type, limit)
+		if err != nil {
+			return fmt.Errorf("failed to list redaction events: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var e RedactionEvent
+			if err := rows.Scan(&e.RedactionIndex, &e.Type, &e.LineNumber, &e.ContextPreview); err != nil {
+				return fmt.Errorf("failed to scan redaction event: %w", err)
+			}
+			events = append(events, e)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to iterate redaction events: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	return events, nil
+}
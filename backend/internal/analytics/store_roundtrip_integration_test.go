@@ -65,6 +65,10 @@ func buildAllCards(sessionID string) *analytics.Cards {
 			AgentInvocations: 3, SkillInvocations: 2,
 			AgentStats: map[string]*analytics.AgentStats{"explore": {Success: 2, Errors: 1}},
 			SkillStats: map[string]*analytics.SkillStats{"commit": {Success: 2, Errors: 0}},
+			AgentCalls: []analytics.AgentCall{
+				{AgentID: "a1", AgentType: "explore", DurationMs: 12000},
+				{AgentID: "a2", AgentType: "explore", ParentAgentID: "a1", DurationMs: 3000, IsError: true},
+			},
 		},
 		Redactions: &analytics.RedactionsCardRecord{
 			SessionID: sessionID, Version: analytics.RedactionsCardVersion, ComputedAt: rtComputedAt, UpToLine: 100,
@@ -77,6 +81,14 @@ func buildAllCards(sessionID string) *analytics.Cards {
 				EstimatedUSD: "0.50", SucceededAgents: 2, HasJournal: true, DurationMs: 1234,
 			}},
 		},
+		CodeChanges: &analytics.CodeChangesCardRecord{
+			SessionID: sessionID, Version: analytics.CodeChangesCardVersion, ComputedAt: rtComputedAt, UpToLine: 100,
+			TotalHunks: 4,
+			Hunks: []analytics.EditHunk{{
+				FilePath: "/src/main.go", LinesBefore: 2, LinesAfter: 3,
+				AddedLines: []string{"\treturn 2", "\t// done"}, RemovedLines: []string{"\treturn 1"},
+			}},
+		},
 	}
 }
 
@@ -125,6 +137,9 @@ func normalizeTimes(c *analytics.Cards) {
 	if c.Workflows != nil {
 		c.Workflows.ComputedAt = c.Workflows.ComputedAt.UTC()
 	}
+	if c.CodeChanges != nil {
+		c.CodeChanges.ComputedAt = c.CodeChanges.ComputedAt.UTC()
+	}
 }
 
 func TestStore_UpsertGetCards_RoundTrip(t *testing.T) {
@@ -159,6 +174,7 @@ func TestStore_UpsertGetCards_RoundTrip(t *testing.T) {
 	assertCardJSONEqual(t, "agents_and_skills", in.AgentsAndSkills, got.AgentsAndSkills)
 	assertCardJSONEqual(t, "redactions", in.Redactions, got.Redactions)
 	assertCardJSONEqual(t, "workflows", in.Workflows, got.Workflows)
+	assertCardJSONEqual(t, "code_changes", in.CodeChanges, got.CodeChanges)
 }
 
 func TestStore_UpsertWorkflowsCard_NilRunsStoredAsEmpty(t *testing.T) {
@@ -0,0 +1,98 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SearchIndexStatus is the freshness of one session's search index. The index
+// fields are nil when the session has never been indexed.
+type SearchIndexStatus struct {
+	Indexed         bool       `json:"indexed"`
+	Version         *int       `json:"version"`
+	IndexedUpToLine *int64     `json:"indexed_up_to_line"`
+	MetadataHash    *string    `json:"metadata_hash"`
+	IndexedAt       *time.Time `json:"indexed_at"`
+	// TotalLines is the session's synced transcript and agent lines, the
+	// count IndexedUpToLine is compared with.
+	TotalLines int64 `json:"total_lines"`
+	// Stale is true when the precompute worker will rebuild the index.
+	Stale bool `json:"stale"`
+}
+
+// searchIndexStatusSQL applies searchIndexStaleCondition to one session ($1).
+// Sessions the worker never indexes (no synced lines, an archived transcript,
+// a session type without an analytics handler) are never stale. The worker
+// also waits for the regular cards to be current before it reindexes; that
+// gate is left out, as it only delays an index that is already out of date.
+var searchIndexStatusSQL = `
+	WITH session_lines AS (
+		SELECT COALESCE(SUM(last_synced_line), 0) AS total_lines
+		FROM sync_files
+		WHERE session_id = $1 AND file_type IN ('transcript', 'agent')
+	)
+	SELECT si.session_id IS NOT NULL, si.version, si.indexed_up_to_line,
+		si.metadata_hash, si.updated_at, sl.total_lines,
+		COALESCE(sl.total_lines > 0
+			AND s.transcript_archived_at IS NULL
+			AND s.session_type = ANY($3)
+			AND ` + searchIndexStaleCondition("$2") + `, FALSE)
+	FROM sessions s
+	JOIN users u ON s.user_id = u.id
+	CROSS JOIN session_lines sl
+	LEFT JOIN session_search_index si ON si.session_id = s.id
+	LEFT JOIN session_card_smart_recap sr ON sr.session_id = s.id
+	WHERE s.id = $1`
+
+// GetSearchIndexStatus returns the session's search index freshness, or nil
+// if the session does not exist.
+func (s *Store) GetSearchIndexStatus(ctx context.Context, sessionID string) (*SearchIndexStatus, error) {
+	ctx, span := tracer.Start(ctx, "analytics.get_search_index_status",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	var status SearchIndexStatus
+	var version sql.NullInt64
+	var upToLine sql.NullInt64
+	var metadataHash sql.NullString
+	var indexedAt sql.NullTime
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, searchIndexStatusSQL,
+			sessionID, SearchIndexVersion, pq.Array(registeredSessionTypes()),
+		).Scan(&status.Indexed, &version, &upToLine, &metadataHash, &indexedAt,
+			&status.TotalLines, &status.Stale)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get search index status: %w", err)
+	}
+
+	if version.Valid {
+		v := int(version.Int64)
+		status.Version = &v
+	}
+	if upToLine.Valid {
+		status.IndexedUpToLine = &upToLine.Int64
+	}
+	if metadataHash.Valid {
+		status.MetadataHash = &metadataHash.String
+	}
+	if indexedAt.Valid {
+		t := indexedAt.Time.UTC()
+		status.IndexedAt = &t
+	}
+	span.SetAttributes(attribute.Bool("search_index.stale", status.Stale))
+	return &status, nil
+}
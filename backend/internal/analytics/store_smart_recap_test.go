@@ -190,6 +190,7 @@ func TestSmartRecapCard_UpsertAndGet(t *testing.T) {
 		InputTokens:               1000,
 		OutputTokens:              200,
 		GenerationTimeMs:          intPtr(1500),
+		InputOmittedEntries:       42,
 	}
 
 	// Upsert
@@ -223,6 +224,9 @@ func TestSmartRecapCard_UpsertAndGet(t *testing.T) {
 	if retrieved.InputTokens != card.InputTokens {
 		t.Errorf("InputTokens = %d, want %d", retrieved.InputTokens, card.InputTokens)
 	}
+	if retrieved.InputOmittedEntries != 42 {
+		t.Errorf("InputOmittedEntries = %d, want 42", retrieved.InputOmittedEntries)
+	}
 
 	// Lock should be cleared after upsert
 	if retrieved.ComputingStartedAt != nil {
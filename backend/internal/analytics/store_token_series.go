@@ -0,0 +1,89 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// =============================================================================
+// Token series operations (session_card_token_series)
+// =============================================================================
+
+// UpsertTokenSeries stores a session's token series, replacing any previous
+// one. Precompute and the on-demand analytics path write it through
+// SaveComputedCards instead, alongside the cards it was computed with.
+func (s *Store) UpsertTokenSeries(ctx context.Context, sessionID string, series *TokenSeries) error {
+	ctx, span := tracer.Start(ctx, "analytics.upsert_token_series",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.Int("series.points", len(series.Points)),
+		))
+	defer span.End()
+
+	err := s.inQueryTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		return upsertTokenSeries(ctx, tx, sessionID, series)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
+
+func upsertTokenSeries(ctx context.Context, q cardQuerier, sessionID string, series *TokenSeries) error {
+	points, err := json.Marshal(series.Points)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token series: %w", err)
+	}
+
+	query := `
+		INSERT INTO session_card_token_series (session_id, computed_at, interval_lines, total_lines, points)
+		VALUES ($1, NOW(), $2, $3, $4)
+		ON CONFLICT (session_id) DO UPDATE SET
+			computed_at = NOW(),
+			interval_lines = EXCLUDED.interval_lines,
+			total_lines = EXCLUDED.total_lines,
+			points = EXCLUDED.points
+	`
+	if _, err := q.ExecContext(ctx, query, sessionID, series.IntervalLines, series.TotalLines, points); err != nil {
+		return fmt.Errorf("failed to upsert token series: %w", err)
+	}
+	return nil
+}
+
+// GetTokenSeries returns a session's stored token series, or nil if its
+// cards were never computed (or its provider produces no series).
+func (s *Store) GetTokenSeries(ctx context.Context, sessionID string) (*TokenSeries, error) {
+	ctx, span := tracer.Start(preferReplica(ctx), "analytics.get_token_series",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	var series TokenSeries
+	var points []byte
+	err := s.inQueryTx(ctx, readOnlyTx, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx,
+			`SELECT interval_lines, total_lines, points FROM session_card_token_series WHERE session_id = $1`,
+			sessionID).Scan(&series.IntervalLines, &series.TotalLines, &points)
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get token series: %w", err)
+	}
+	if err := json.Unmarshal(points, &series.Points); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to unmarshal token series: %w", err)
+	}
+	return &series, nil
+}
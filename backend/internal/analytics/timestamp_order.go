@@ -0,0 +1,37 @@
+package analytics
+
+import "time"
+
+// TimestampRegressionTolerance is how far a transcript line's timestamp may fall
+// behind the previous timestamped line before it counts as an ordering anomaly.
+// Sub-second regressions are routine when several writers append to one
+// transcript and are not worth surfacing.
+const TimestampRegressionTolerance = time.Second
+
+// timestampOrder tracks timestamp regressions across the lines of one file.
+// Only lines that carry a timestamp take part; each regression beyond
+// TimestampRegressionTolerance against the previous timestamped line counts once.
+type timestampOrder struct {
+	prev      *time.Time
+	anomalies int
+	firstLine *int
+}
+
+// observe records the timestamp of the line at lineNumber (1-based).
+func (o *timestampOrder) observe(ts time.Time, lineNumber int) {
+	if o.prev != nil && o.prev.Sub(ts) > TimestampRegressionTolerance {
+		o.anomalies++
+		if o.firstLine == nil {
+			line := lineNumber
+			o.firstLine = &line
+		}
+	}
+	o.prev = &ts
+}
+
+// nonNegativeMs returns d in milliseconds, clamped to zero. A duration between
+// two out-of-order timestamps reads as "no time elapsed" rather than a negative
+// value that would skew totals and averages.
+func nonNegativeMs(d time.Duration) int64 {
+	return max(d.Milliseconds(), 0)
+}
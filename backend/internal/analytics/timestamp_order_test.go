@@ -0,0 +1,118 @@
+package analytics
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimestampOrder_Tolerance(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 10, 0, time.UTC)
+
+	var o timestampOrder
+	o.observe(base, 1)
+	o.observe(base.Add(-500*time.Millisecond), 2) // within tolerance
+	o.observe(base.Add(-1*time.Second), 3)        // exactly at tolerance
+	o.observe(base.Add(-3*time.Second), 4)        // 2s behind line 3: anomaly
+	o.observe(base.Add(time.Minute), 5)
+	o.observe(base, 6) // anomaly
+
+	if o.anomalies != 2 {
+		t.Errorf("anomalies = %d, want 2", o.anomalies)
+	}
+	if o.firstLine == nil || *o.firstLine != 4 {
+		t.Errorf("firstLine = %v, want 4", o.firstLine)
+	}
+}
+
+// skewedTranscript interleaves two writers whose clocks disagree by a few
+// seconds, so timestamps go backwards several times mid-session.
+func skewedTranscript() string {
+	text := []map[string]interface{}{makeTextBlock("ok")}
+	return makeUserMessage("u1", "2025-01-01T10:00:00Z", "first") + "\n" + // line 1
+		makeAssistantMessage("a1", "2025-01-01T10:00:05Z", "claude-sonnet-4", 10, 5, text) + "\n" + // line 2
+		makeUserMessage("u2", "2025-01-01T10:00:03Z", "second") + "\n" + // line 3: 2s back
+		makeAssistantMessage("a2", "2025-01-01T09:59:58Z", "claude-sonnet-4", 10, 5, text) + "\n" + // line 4: 5s back, before its prompt
+		makeCompactBoundaryMessageWithParent("c1", "2025-01-01T09:59:50Z", "auto", 50000, "a2") + "\n" + // line 5: 8s back, before its parent
+		makeUserMessage("u3", "2025-01-01T10:01:00Z", "third") + "\n" + // line 6
+		makeAssistantMessage("a3", "2025-01-01T10:00:59.500Z", "claude-sonnet-4", 10, 5, text) + "\n" // line 7: 0.5s back, tolerated
+}
+
+func TestSkewedTimestamps_AnomaliesAndClamping(t *testing.T) {
+	jsonl := []byte(skewedTranscript())
+
+	result, err := ComputeFromJSONL(context.Background(), jsonl)
+	if err != nil {
+		t.Fatalf("ComputeFromJSONL failed: %v", err)
+	}
+
+	cards := result.ToCards("session-skewed", 7)
+	if cards.Session.TimestampAnomalies != 3 {
+		t.Errorf("TimestampAnomalies = %d, want 3", cards.Session.TimestampAnomalies)
+	}
+	if cards.Session.FirstTimestampAnomalyLine == nil || *cards.Session.FirstTimestampAnomalyLine != 3 {
+		t.Errorf("FirstTimestampAnomalyLine = %v, want 3", cards.Session.FirstTimestampAnomalyLine)
+	}
+	if cards.Session.CompactionAvgTimeMs == nil || *cards.Session.CompactionAvgTimeMs != 0 {
+		t.Errorf("CompactionAvgTimeMs = %v, want clamped to 0", cards.Session.CompactionAvgTimeMs)
+	}
+	if cards.Conversation == nil {
+		t.Fatal("Conversation card should not be nil")
+	}
+	assertNoNegativeFields(t, "cards", reflect.ValueOf(cards))
+
+	fc, err := NewFileCollection(jsonl)
+	if err != nil {
+		t.Fatalf("NewFileCollection failed: %v", err)
+	}
+	turns, err := ComputeConversationTurns(fc)
+	if err != nil {
+		t.Fatalf("ComputeConversationTurns failed: %v", err)
+	}
+	if len(turns) == 0 {
+		t.Fatal("expected conversation turns")
+	}
+	assertNoNegativeFields(t, "turns", reflect.ValueOf(turns))
+
+	session, ok := cards.ToResponse().Cards["session"].(SessionCardData)
+	if !ok || session.TimestampAnomalies != 3 {
+		t.Errorf("response session card should carry the anomaly count, got %+v", session)
+	}
+}
+
+// assertNoNegativeFields walks v and fails on any negative integer or float
+// reachable through this package's structs, pointers, slices and maps.
+// Types from other packages (time.Time, decimal.Decimal) are not descended into.
+func assertNoNegativeFields(t *testing.T, path string, v reflect.Value) {
+	t.Helper()
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			assertNoNegativeFields(t, path, v.Elem())
+		}
+	case reflect.Struct:
+		if v.Type().PkgPath() != reflect.TypeOf(Cards{}).PkgPath() {
+			return
+		}
+		for i := range v.NumField() {
+			assertNoNegativeFields(t, path+"."+v.Type().Field(i).Name, v.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			assertNoNegativeFields(t, path+"[]", v.Index(i))
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			assertNoNegativeFields(t, path+"[]", v.MapIndex(k))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 {
+			t.Errorf("%s = %d, want >= 0", path, v.Int())
+		}
+	case reflect.Float32, reflect.Float64:
+		if v.Float() < 0 {
+			t.Errorf("%s = %v, want >= 0", path, v.Float())
+		}
+	}
+}
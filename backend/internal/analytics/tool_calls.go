@@ -0,0 +1,135 @@
+package analytics
+
+import (
+	"context"
+	"sort"
+)
+
+// Tool call result statuses.
+const (
+	ToolCallStatusSuccess = "success"
+	ToolCallStatusError   = "error"
+	// ToolCallStatusPending marks a call with no tool_result in the
+	// transcript: still running, interrupted, or never logged.
+	ToolCallStatusPending = "pending"
+)
+
+// ToolCall is one tool invocation parsed from a transcript, with its raw
+// arguments. It is the API wire shape of GET /sessions/{id}/tool-calls.
+type ToolCall struct {
+	CallIndex int                    `json:"call_index"` // 0-based position in the session
+	ID        string                 `json:"id,omitempty"`
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+	Status    string                 `json:"status"` // ToolCallStatus*
+	// AgentID is set for calls made by a subagent; LineNumber is then a line
+	// of that agent's file rather than of the main transcript.
+	AgentID    string `json:"agent_id,omitempty"`
+	LineNumber int    `json:"line_number"` // 1-based line holding the tool_use block
+}
+
+// ToolCallLister is implemented by providers whose transcripts keep each tool
+// call with its arguments. Providers without it can't serve the tool call
+// list.
+type ToolCallLister interface {
+	ToolCalls(ctx context.Context, rollout Rollout) ([]ToolCall, error)
+}
+
+// ListClaudeToolCalls lists the tool calls of a Claude Code session: the
+// main transcript first, then each agent file ordered by agent ID, in line
+// order within a file. It counts exactly what ToolsAnalyzer counts, so the
+// list's length is the tools card's total_calls, except for the calls the
+// card takes from subagent results whose agent file is missing.
+func ListClaudeToolCalls(main *TranscriptFile, agents []*TranscriptFile) []ToolCall {
+	files := append([]*TranscriptFile{main}, agents...)
+	sort.SliceStable(files[1:], func(i, j int) bool { return files[1+i].AgentID < files[1+j].AgentID })
+
+	calls := []ToolCall{}
+	seen := make(map[string]bool)
+	for _, file := range files {
+		status := claudeToolResultStatuses(file)
+		for _, line := range file.Lines {
+			if !line.IsAssistantMessage() {
+				continue
+			}
+			for _, tool := range line.GetToolUses() {
+				// Context replay re-logs tool_use blocks; keep the first.
+				if tool.ID != "" {
+					if seen[tool.ID] {
+						continue
+					}
+					seen[tool.ID] = true
+				}
+				call := ToolCall{
+					CallIndex:  len(calls),
+					ID:         tool.ID,
+					Name:       tool.Name,
+					Arguments:  tool.Input,
+					Status:     ToolCallStatusPending,
+					AgentID:    file.AgentID,
+					LineNumber: line.LineNumber,
+				}
+				if s, ok := status[tool.ID]; ok && tool.ID != "" {
+					call.Status = s
+				}
+				if call.Arguments == nil {
+					call.Arguments = map[string]interface{}{}
+				}
+				calls = append(calls, call)
+			}
+		}
+	}
+	return calls
+}
+
+// claudeToolResultStatuses maps each tool_use ID answered in file to the
+// status of its tool_result. An error result wins over a later success for
+// the same ID.
+func claudeToolResultStatuses(file *TranscriptFile) map[string]string {
+	status := make(map[string]string)
+	for _, line := range file.Lines {
+		if !line.IsUserMessage() {
+			continue
+		}
+		for _, block := range line.GetContentBlocks() {
+			if block.Type != "tool_result" || block.ToolUseID == "" {
+				continue
+			}
+			if block.IsError {
+				status[block.ToolUseID] = ToolCallStatusError
+			} else if status[block.ToolUseID] == "" {
+				status[block.ToolUseID] = ToolCallStatusSuccess
+			}
+		}
+	}
+	return status
+}
+
+// RedactToolArguments returns a copy of args with every string value
+// obfuscated like a redaction context preview: letters and digits become '*'
+// (at most four in a row), so paths and commands keep their shape without
+// their content. Keys, numbers and booleans are kept.
+func RedactToolArguments(args map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		redacted[k] = redactToolArgumentValue(v)
+	}
+	return redacted
+}
+
+func redactToolArgumentValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return obfuscateRedactionContext(val)
+	case map[string]interface{}:
+		return RedactToolArguments(val)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, elem := range val {
+			out[i] = redactToolArgumentValue(elem)
+		}
+		return out
+	default:
+		return v
+	}
+}
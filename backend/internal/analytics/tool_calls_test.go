@@ -0,0 +1,63 @@
+package analytics_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+)
+
+func TestListClaudeToolCalls(t *testing.T) {
+	main := `{"type":"user","message":{"role":"user","content":"go"},"uuid":"u1","timestamp":"2025-01-01T00:00:00Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/src","sessionId":"s","version":"1.0"}
+{"type":"assistant","message":{"id":"m1","type":"message","model":"claude-sonnet-4","role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go test ./..."}},{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"/src/main.go"}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":10,"output_tokens":5}},"uuid":"a1","timestamp":"2025-01-01T00:00:01Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/src","sessionId":"s","version":"1.0"}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"},{"type":"tool_result","tool_use_id":"t2","content":"missing","is_error":true}]},"uuid":"u2","timestamp":"2025-01-01T00:00:02Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/src","sessionId":"s","version":"1.0"}
+{"type":"assistant","message":{"id":"m1","type":"message","model":"claude-sonnet-4","role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go test ./..."}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":10,"output_tokens":5}},"uuid":"a2","timestamp":"2025-01-01T00:00:03Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/src","sessionId":"s","version":"1.0"}
+{"type":"assistant","message":{"id":"m2","type":"message","model":"claude-sonnet-4","role":"assistant","content":[{"type":"tool_use","id":"t3","name":"Grep","input":{"pattern":"TODO"}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":10,"output_tokens":5}},"uuid":"a3","timestamp":"2025-01-01T00:00:04Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/src","sessionId":"s","version":"1.0"}
+`
+	agent := `{"type":"assistant","message":{"id":"m3","type":"message","model":"claude-sonnet-4","role":"assistant","content":[{"type":"tool_use","id":"t4","name":"Edit","input":{}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":10,"output_tokens":5}},"uuid":"b1","timestamp":"2025-01-01T00:00:05Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/src","sessionId":"s","version":"1.0"}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t4","content":"done"}]},"uuid":"b2","timestamp":"2025-01-01T00:00:06Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/src","sessionId":"s","version":"1.0"}
+`
+	fc, err := analytics.NewFileCollectionWithAgents([]byte(main), map[string][]byte{"agent-1": []byte(agent)})
+	if err != nil {
+		t.Fatalf("NewFileCollectionWithAgents: %v", err)
+	}
+
+	calls := analytics.ListClaudeToolCalls(fc.Main, fc.Agents)
+	want := []analytics.ToolCall{
+		{CallIndex: 0, ID: "t1", Name: "Bash", Arguments: map[string]interface{}{"command": "go test ./..."}, Status: analytics.ToolCallStatusSuccess, LineNumber: 2},
+		{CallIndex: 1, ID: "t2", Name: "Read", Arguments: map[string]interface{}{"file_path": "/src/main.go"}, Status: analytics.ToolCallStatusError, LineNumber: 2},
+		{CallIndex: 2, ID: "t3", Name: "Grep", Arguments: map[string]interface{}{"pattern": "TODO"}, Status: analytics.ToolCallStatusPending, LineNumber: 5},
+		{CallIndex: 3, ID: "t4", Name: "Edit", Arguments: map[string]interface{}{}, Status: analytics.ToolCallStatusSuccess, AgentID: "agent-1", LineNumber: 1},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls =\n%+v\nwant\n%+v", calls, want)
+	}
+
+	tools, err := (&analytics.ToolsAnalyzer{}).Analyze(fc)
+	if err != nil {
+		t.Fatalf("ToolsAnalyzer: %v", err)
+	}
+	if len(calls) != tools.TotalCalls {
+		t.Errorf("listed %d calls, tools card counts %d", len(calls), tools.TotalCalls)
+	}
+}
+
+func TestRedactToolArguments(t *testing.T) {
+	args := map[string]interface{}{
+		"command": "cat /etc/secret-token",
+		"timeout": 30.0,
+		"nested":  map[string]interface{}{"paths": []interface{}{"a.go", true}},
+	}
+	got := analytics.RedactToolArguments(args)
+	want := map[string]interface{}{
+		"command": "*** /***/****-****",
+		"timeout": 30.0,
+		"nested":  map[string]interface{}{"paths": []interface{}{"*.**", true}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactToolArguments = %v, want %v", got, want)
+	}
+	if args["command"] != "cat /etc/secret-token" {
+		t.Error("RedactToolArguments modified its input")
+	}
+}
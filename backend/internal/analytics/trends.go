@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"sort"
@@ -120,7 +121,7 @@ func lowercaseAll(ss []string) []string {
 // aggregation routes through buildTrendsQuery so the visibility predicate
 // + owner narrowing live in exactly one place (CF-495).
 func (s *Store) GetTrends(ctx context.Context, userID int64, req TrendsRequest) (*TrendsResponse, error) {
-	ctx, span := tracer.Start(ctx, "analytics.get_trends",
+	ctx, span := tracer.Start(preferReplica(ctx), "analytics.get_trends",
 		trace.WithAttributes(
 			attribute.Int64("user.id", userID),
 			attribute.Int64("start_ts", req.StartTS),
@@ -347,12 +348,6 @@ func (s *Store) aggregateOverviewAndActivity(ctx context.Context, tq trendsQuery
 		ORDER BY dr.d, da.session_type
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, tq.args...)
-	if err != nil {
-		return nil, nil, nil, 0, err
-	}
-	defer rows.Close()
-
 	type dayBucket struct {
 		date                string
 		sessionCount        int
@@ -372,7 +367,7 @@ func (s *Store) aggregateOverviewAndActivity(ctx context.Context, tq trendsQuery
 		totalLinesRemoved        int
 	)
 
-	for rows.Next() {
+	err := s.queryEach(ctx, query, tq.args, func(rows *sql.Rows) error {
 		var sessionDate time.Time
 		var rawProvider string
 		var sessionCount, filesRead, filesModified, linesAdded, linesRemoved int
@@ -388,7 +383,7 @@ func (s *Store) aggregateOverviewAndActivity(ctx context.Context, tq trendsQuery
 			&linesRemoved,
 			&assistantDurationMs,
 		); err != nil {
-			return nil, nil, nil, 0, err
+			return err
 		}
 
 		dateKey := sessionDate.Format("2006-01-02")
@@ -400,7 +395,7 @@ func (s *Store) aggregateOverviewAndActivity(ctx context.Context, tq trendsQuery
 		}
 
 		if rawProvider == "" {
-			continue
+			return nil
 		}
 
 		canonical := models.NormalizeProvider(rawProvider)
@@ -416,9 +411,9 @@ func (s *Store) aggregateOverviewAndActivity(ctx context.Context, tq trendsQuery
 		totalFilesModified += filesModified
 		totalLinesAdded += linesAdded
 		totalLinesRemoved += linesRemoved
-	}
-
-	if err := rows.Err(); err != nil {
+		return nil
+	})
+	if err != nil {
 		return nil, nil, nil, 0, err
 	}
 
@@ -519,12 +514,6 @@ func (s *Store) aggregateTokens(ctx context.Context, tq trendsQuery) (*TrendsTok
 		ORDER BY dr.d, pdpp.session_type
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, tq.args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	type dailyAccum struct {
 		total       decimal.Decimal
 		perProvider map[string]decimal.Decimal
@@ -544,12 +533,12 @@ func (s *Store) aggregateTokens(ctx context.Context, tq trendsQuery) (*TrendsTok
 		totalCost          = decimal.Zero
 	)
 
-	for rows.Next() {
+	err := s.queryEach(ctx, query, tq.args, func(rows *sql.Rows) error {
 		var sessionDate time.Time
 		var rawProvider, costStr string
 		var input, output, cacheCreation, cacheRead int64
 		if err := rows.Scan(&sessionDate, &rawProvider, &input, &output, &cacheCreation, &cacheRead, &costStr); err != nil {
-			return nil, err
+			return err
 		}
 
 		dateKey := sessionDate.Format("2006-01-02")
@@ -561,7 +550,7 @@ func (s *Store) aggregateTokens(ctx context.Context, tq trendsQuery) (*TrendsTok
 		}
 
 		if rawProvider == "" {
-			continue
+			return nil
 		}
 
 		canonical := models.NormalizeProvider(rawProvider)
@@ -586,8 +575,9 @@ func (s *Store) aggregateTokens(ctx context.Context, tq trendsQuery) (*TrendsTok
 		totalCacheCreation += cacheCreation
 		totalCacheRead += cacheRead
 		totalCost = totalCost.Add(cost)
-	}
-	if err := rows.Err(); err != nil {
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -630,22 +620,16 @@ func (s *Store) aggregateTools(ctx context.Context, tq trendsQuery) (*TrendsTool
 		INNER JOIN session_card_tools t ON fs.id = t.session_id
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, tq.args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	totalCalls := 0
 	totalErrors := 0
 	aggregatedStats := make(map[string]*ToolStats)
 
-	for rows.Next() {
+	err := s.queryEach(ctx, query, tq.args, func(rows *sql.Rows) error {
 		var calls, errors int
 		var breakdownJSON []byte
 
 		if err := rows.Scan(&calls, &errors, &breakdownJSON); err != nil {
-			return nil, err
+			return err
 		}
 
 		totalCalls += calls
@@ -663,9 +647,9 @@ func (s *Store) aggregateTools(ctx context.Context, tq trendsQuery) (*TrendsTool
 				}
 			}
 		}
-	}
-
-	if err := rows.Err(); err != nil {
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -706,7 +690,7 @@ func (s *Store) aggregateTopSessions(ctx context.Context, tq trendsQuery, limit
 			fs.id,
 			s.external_id,
 			fs.session_type,
-			COALESCE(s.custom_title, s.suggested_session_title, s.summary, s.first_user_message) AS title,
+			COALESCE(s.custom_title, s.ai_generated_title, s.suggested_session_title, s.summary, s.first_user_message) AS title,
 			NULLIF(regexp_replace(regexp_replace(COALESCE(s.git_info->>'repo_url', ''), '\.git$', ''), '^.*[/:]([^/:]+/[^/:]+)$', '\1'), '') AS git_repo,
 			` + costExpr + `,
 			sess.duration_ms
@@ -718,14 +702,9 @@ func (s *Store) aggregateTopSessions(ctx context.Context, tq trendsQuery, limit
 		ORDER BY (` + costExpr + `)::numeric DESC
 		LIMIT ` + strconv.Itoa(normalizeTopN(limit))
 
-	rows, err := s.db.QueryContext(ctx, query, tq.args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	sessions := []TopSessionItem{}
-	for rows.Next() {
+
+	err := s.queryEach(ctx, query, tq.args, func(rows *sql.Rows) error {
 		var item TopSessionItem
 		var externalID string
 		var providerRaw string
@@ -741,7 +720,7 @@ func (s *Store) aggregateTopSessions(ctx context.Context, tq trendsQuery, limit
 			&costStr,
 			&item.DurationMs,
 		); err != nil {
-			return nil, err
+			return err
 		}
 
 		item.Provider = models.NormalizeProvider(providerRaw)
@@ -760,9 +739,9 @@ func (s *Store) aggregateTopSessions(ctx context.Context, tq trendsQuery, limit
 		item.EstimatedCostUSD = cost.String()
 
 		sessions = append(sessions, item)
-	}
-
-	if err := rows.Err(); err != nil {
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -777,23 +756,17 @@ func (s *Store) aggregateAgentsAndSkills(ctx context.Context, tq trendsQuery) (*
 		INNER JOIN session_card_agents_and_skills a ON fs.id = a.session_id
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, tq.args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	totalAgentInvocations := 0
 	totalSkillInvocations := 0
 	aggregatedAgentStats := make(map[string]*AgentStats)
 	aggregatedSkillStats := make(map[string]*SkillStats)
 
-	for rows.Next() {
+	err := s.queryEach(ctx, query, tq.args, func(rows *sql.Rows) error {
 		var agentInvocations, skillInvocations int
 		var agentStatsJSON, skillStatsJSON []byte
 
 		if err := rows.Scan(&agentInvocations, &skillInvocations, &agentStatsJSON, &skillStatsJSON); err != nil {
-			return nil, err
+			return err
 		}
 
 		totalAgentInvocations += agentInvocations
@@ -824,9 +797,9 @@ func (s *Store) aggregateAgentsAndSkills(ctx context.Context, tq trendsQuery) (*
 				}
 			}
 		}
-	}
-
-	if err := rows.Err(); err != nil {
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -847,21 +820,17 @@ func (s *Store) aggregateProvidersPresent(ctx context.Context, tq trendsQuery) (
 		SELECT DISTINCT session_type FROM filtered_sessions
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, tq.args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	seen := make(map[string]struct{})
-	for rows.Next() {
+
+	err := s.queryEach(ctx, query, tq.args, func(rows *sql.Rows) error {
 		var raw string
 		if err := rows.Scan(&raw); err != nil {
-			return nil, err
+			return err
 		}
 		seen[models.NormalizeProvider(raw)] = struct{}{}
-	}
-	if err := rows.Err(); err != nil {
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -904,7 +873,7 @@ func (s *Store) aggregateFilterOptions(ctx context.Context, userID int64, shareA
 	`
 
 	var owners, repos []string
-	if err := s.db.QueryRowContext(ctx, query, userID).Scan(pq.Array(&owners), pq.Array(&repos)); err != nil {
+	if err := s.queryRow(ctx, query, []any{userID}, pq.Array(&owners), pq.Array(&repos)); err != nil {
 		return TrendsFilterOptions{}, fmt.Errorf("aggregate filter options: %w", err)
 	}
 
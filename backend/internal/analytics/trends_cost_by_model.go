@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"sort"
 	"strings"
@@ -78,34 +79,25 @@ func (s *Store) aggregateCostByModel(ctx context.Context, tq trendsQuery, userID
 
 	// Denominator for the coverage caption: all filtered sessions in range.
 	var total int
-	if err := s.db.QueryRowContext(cbmCtx, tq.cteSQL+"\nSELECT COUNT(*) FROM filtered_sessions", tq.args...).Scan(&total); err != nil {
+	if err := s.queryRow(cbmCtx, tq.cteSQL+"\nSELECT COUNT(*) FROM filtered_sessions", tq.args, &total); err != nil {
 		if isTimeoutErr(err) {
 			return degradedCostByModelCard(ctx, userID, req, started, err), nil
 		}
 		return nil, fmt.Errorf("cost-by-model total count: %w", err)
 	}
 
-	rows, err := s.db.QueryContext(cbmCtx, tq.cteSQL+v2ModelScanSQL, tq.args...)
-	if err != nil {
-		if isTimeoutErr(err) {
-			return degradedCostByModelCard(ctx, userID, req, started, err), nil
-		}
-		return nil, fmt.Errorf("cost-by-model query: %w", err)
-	}
-	defer rows.Close()
-
 	buckets := map[string]*costByModelBucket{}
 	covered := map[string]struct{}{}
-	for rows.Next() {
+	err := s.queryEach(cbmCtx, tq.cteSQL+v2ModelScanSQL, tq.args, func(rows *sql.Rows) error {
 		var sessionID, sessionType, rawModel, costStr string
 		var input, output, cacheRead, cacheWrite int64
 		if err := rows.Scan(&sessionID, &sessionType, &rawModel, &costStr, &input, &output, &cacheRead, &cacheWrite); err != nil {
-			return nil, fmt.Errorf("cost-by-model scan: %w", err)
+			return fmt.Errorf("cost-by-model scan: %w", err)
 		}
 		provider := models.NormalizeProvider(sessionType)
 		model := normalizeV2ModelKey(provider, rawModel)
 		if model == syntheticModelKey {
-			continue // synthetic turns are not a real model — exclude (vtrz)
+			return nil // synthetic turns are not a real model — exclude (vtrz)
 		}
 		cost, err := decimal.NewFromString(costStr)
 		if err != nil {
@@ -125,12 +117,13 @@ func (s *Store) aggregateCostByModel(ctx context.Context, tq trendsQuery, userID
 		b.cacheWrite += cacheWrite
 		b.sessions[sessionID] = struct{}{}
 		covered[sessionID] = struct{}{}
-	}
-	if err := rows.Err(); err != nil {
+		return nil
+	})
+	if err != nil {
 		if isTimeoutErr(err) {
 			return degradedCostByModelCard(ctx, userID, req, started, err), nil
 		}
-		return nil, fmt.Errorf("cost-by-model rows: %w", err)
+		return nil, fmt.Errorf("cost-by-model query: %w", err)
 	}
 
 	ordered := make([]*costByModelBucket, 0, len(buckets))
@@ -232,28 +225,23 @@ func (s *Store) sessionsMatchingModels(ctx context.Context, userID int64, req Tr
 		visible_unique AS (SELECT DISTINCT id FROM visible_sessions)
 		SELECT vu.id, s.session_type, mdl.key` + visibleV2ModelKeysFrom
 
-	rows, err := s.db.QueryContext(ctx, query, userID)
-	if err != nil {
-		return nil, fmt.Errorf("sessions matching models: %w", err)
-	}
-	defer rows.Close()
-
 	matched := map[string]struct{}{}
-	for rows.Next() {
+	err := s.queryEach(ctx, query, []any{userID}, func(rows *sql.Rows) error {
 		var sessionID, sessionType, rawModel string
 		if err := rows.Scan(&sessionID, &sessionType, &rawModel); err != nil {
-			return nil, fmt.Errorf("sessions matching models scan: %w", err)
+			return fmt.Errorf("sessions matching models scan: %w", err)
 		}
 		norm := normalizeV2ModelKey(models.NormalizeProvider(sessionType), rawModel)
 		if norm == syntheticModelKey {
-			continue // not a selectable model (vtrz); never match it
+			return nil // not a selectable model (vtrz); never match it
 		}
 		if _, ok := selected[strings.ToLower(norm)]; ok {
 			matched[sessionID] = struct{}{}
 		}
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("sessions matching models rows: %w", err)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sessions matching models: %w", err)
 	}
 
 	out := make([]string, 0, len(matched))
@@ -285,33 +273,24 @@ func (s *Store) modelFilterOptions(ctx context.Context, userID int64, shareAllSe
 		`
 		WHERE ` + db.ListableSessionPredicate("s")
 
-	rows, err := s.db.QueryContext(ctx, query, userID)
-	if err != nil {
-		if isTimeoutErr(err) {
-			logger.Ctx(ctx).Warn("trends model filter-options timed out — dropdown empty", "user_id", userID)
-			return []string{}, nil
-		}
-		return nil, fmt.Errorf("model filter options: %w", err)
-	}
-	defer rows.Close()
-
 	seen := map[string]struct{}{}
-	for rows.Next() {
+	err := s.queryEach(ctx, query, []any{userID}, func(rows *sql.Rows) error {
 		var sessionType, rawModel string
 		if err := rows.Scan(&sessionType, &rawModel); err != nil {
-			return nil, fmt.Errorf("model filter options scan: %w", err)
+			return fmt.Errorf("model filter options scan: %w", err)
 		}
 		norm := normalizeV2ModelKey(models.NormalizeProvider(sessionType), rawModel)
 		if norm != "" && norm != syntheticModelKey {
 			seen[norm] = struct{}{}
 		}
-	}
-	if err := rows.Err(); err != nil {
+		return nil
+	})
+	if err != nil {
 		if isTimeoutErr(err) {
 			logger.Ctx(ctx).Warn("trends model filter-options timed out — dropdown empty", "user_id", userID)
 			return []string{}, nil
 		}
-		return nil, fmt.Errorf("model filter options rows: %w", err)
+		return nil, fmt.Errorf("model filter options: %w", err)
 	}
 
 	out := make([]string, 0, len(seen))
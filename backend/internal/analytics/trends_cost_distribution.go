@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"sort"
 	"strings"
@@ -145,7 +146,7 @@ func (s *Store) aggregateCostDistribution(ctx context.Context, tq trendsQuery, u
 
 	// Denominator for the coverage caption: all filtered sessions in range.
 	var total int
-	if err := s.db.QueryRowContext(cdCtx, tq.cteSQL+"\nSELECT COUNT(*) FROM filtered_sessions", tq.args...).Scan(&total); err != nil {
+	if err := s.queryRow(cdCtx, tq.cteSQL+"\nSELECT COUNT(*) FROM filtered_sessions", tq.args, &total); err != nil {
 		if isTimeoutErr(err) {
 			return degradedCostDistributionCard(ctx, userID, req, started, err), nil
 		}
@@ -176,18 +177,12 @@ func (s *Store) aggregateCostDistribution(ctx context.Context, tq trendsQuery, u
 // session with v2 data (the no-filter path). covered = the sessions priced
 // >= costDistributionMinCost (sub-cent/$0 sessions are excluded from the card).
 func (s *Store) costDistributionPerSessionValues(ctx context.Context, tq trendsQuery) ([]decimal.Decimal, map[string]struct{}, error) {
-	rows, err := s.db.QueryContext(ctx, tq.cteSQL+perSessionCostScanSQL, tq.args...)
-	if err != nil {
-		return nil, nil, fmt.Errorf("cost-distribution per-session query: %w", err)
-	}
-	defer rows.Close()
-
 	var values []decimal.Decimal
 	covered := map[string]struct{}{}
-	for rows.Next() {
+	err := s.queryEach(ctx, tq.cteSQL+perSessionCostScanSQL, tq.args, func(rows *sql.Rows) error {
 		var sessionID, costStr string
 		if err := rows.Scan(&sessionID, &costStr); err != nil {
-			return nil, nil, fmt.Errorf("cost-distribution per-session scan: %w", err)
+			return fmt.Errorf("cost-distribution per-session scan: %w", err)
 		}
 		cost, err := decimal.NewFromString(costStr)
 		if err != nil {
@@ -199,9 +194,10 @@ func (s *Store) costDistributionPerSessionValues(ctx context.Context, tq trendsQ
 		if cost.GreaterThanOrEqual(costDistributionMinCost) {
 			covered[sessionID] = struct{}{}
 		}
-	}
-	if err := rows.Err(); err != nil {
-		return nil, nil, fmt.Errorf("cost-distribution per-session rows: %w", err)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("cost-distribution per-session query: %w", err)
 	}
 	return values, covered, nil
 }
@@ -217,27 +213,21 @@ func (s *Store) costDistributionPerModelValues(ctx context.Context, tq trendsQue
 		selected[strings.ToLower(strings.TrimSpace(m))] = struct{}{}
 	}
 
-	rows, err := s.db.QueryContext(ctx, tq.cteSQL+v2ModelScanSQL, tq.args...)
-	if err != nil {
-		return nil, nil, fmt.Errorf("cost-distribution per-model query: %w", err)
-	}
-	defer rows.Close()
-
 	type pairKey struct{ session, model string }
 	pairCost := map[pairKey]decimal.Decimal{}
 	covered := map[string]struct{}{}
-	for rows.Next() {
+	err := s.queryEach(ctx, tq.cteSQL+v2ModelScanSQL, tq.args, func(rows *sql.Rows) error {
 		var sessionID, sessionType, rawModel, costStr string
 		var input, output, cacheRead, cacheWrite int64
 		if err := rows.Scan(&sessionID, &sessionType, &rawModel, &costStr, &input, &output, &cacheRead, &cacheWrite); err != nil {
-			return nil, nil, fmt.Errorf("cost-distribution per-model scan: %w", err)
+			return fmt.Errorf("cost-distribution per-model scan: %w", err)
 		}
 		norm := normalizeV2ModelKey(models.NormalizeProvider(sessionType), rawModel)
 		if norm == syntheticModelKey {
-			continue
+			return nil
 		}
 		if _, ok := selected[strings.ToLower(norm)]; !ok {
-			continue
+			return nil
 		}
 		cost, err := decimal.NewFromString(costStr)
 		if err != nil {
@@ -245,9 +235,10 @@ func (s *Store) costDistributionPerModelValues(ctx context.Context, tq trendsQue
 		}
 		k := pairKey{session: sessionID, model: norm}
 		pairCost[k] = pairCost[k].Add(cost)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, nil, fmt.Errorf("cost-distribution per-model rows: %w", err)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("cost-distribution per-model query: %w", err)
 	}
 
 	// covered is decided on each pair's FINAL total: a session counts only if it has
@@ -67,6 +67,9 @@ type TrendsResponse struct {
 	// changes (date/repo/provider/owner). Always non-nil; empty slices
 	// when nothing is visible. CF-495.
 	FilterOptions TrendsFilterOptions `json:"filter_options"`
+	// CostCorrection is set only when the caller asks for it
+	// (?apply_correction=true); see ApplyCostCorrection.
+	CostCorrection *TrendsCostCorrection `json:"cost_correction,omitempty"`
 }
 
 // TrendsFilterOptions surfaces the dropdown source for owners + repos + models
@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"sort"
 	"strings"
@@ -16,9 +17,10 @@ import (
 // within minutes instead of only as a backend WARN ("unknown model for pricing",
 // pricing.go) that someone has to grep for.
 //
-// The active pricing table lives in memory (activePricing, an atomic.Pointer —
-// NOT a DB table), so the gap is computed in Go: SQL extracts the distinct
-// (provider, model-key, session, last-computed) tuples from the tokens_v2 tree,
+// The active pricing table lives in memory (activePricing, an atomic.Pointer,
+// plus the loaded copy of the model_pricing table), so the gap is computed in
+// Go: SQL extracts the distinct (provider, model-key, session, last-computed)
+// tuples from the tokens_v2 tree,
 // and UnpricedModels subtracts the families present in ActivePricingFamilies().
 // Keys are normalized to bounded-cardinality families via the same
 // normalizeV2ModelKey + getModelFamily logic the cost surfaces use, so the result
@@ -37,8 +39,9 @@ type UnpricedModel struct {
 }
 
 // ActivePricingFamilies returns the set of family keys in the currently-active
-// pricing table (e.g. "opus-4-5", "gpt-5"). It reads the same lock-free
-// atomic.Pointer LookupPricing uses, so it reflects any runtime price refresh.
+// pricing table (e.g. "opus-4-5", "gpt-5"), plus the families the loaded
+// model_pricing table lists. It reads the same lock-free atomic.Pointers
+// pricingForModel uses, so it reflects any runtime price refresh.
 // Exported so the admin pricing-gap surface can diff stored model families
 // against priced ones without re-deriving the table.
 func ActivePricingFamilies() map[string]struct{} {
@@ -47,6 +50,9 @@ func ActivePricingFamilies() map[string]struct{} {
 	for family := range table {
 		fams[family] = struct{}{}
 	}
+	for family := range *pricingPeriods.Load() {
+		fams[family] = struct{}{}
+	}
 	return fams
 }
 
@@ -80,18 +86,12 @@ type unpricedBucket struct {
 func (s *Store) UnpricedModels(ctx context.Context) ([]UnpricedModel, error) {
 	priced := ActivePricingFamilies()
 
-	rows, err := s.db.QueryContext(ctx, unpricedModelScanSQL)
-	if err != nil {
-		return nil, fmt.Errorf("unpriced models query: %w", err)
-	}
-	defer rows.Close()
-
 	buckets := map[string]*unpricedBucket{}
-	for rows.Next() {
+	err := s.queryEach(ctx, unpricedModelScanSQL, nil, func(rows *sql.Rows) error {
 		var sessionType, sessionID, rawModel string
 		var computedAt time.Time
 		if err := rows.Scan(&sessionType, &sessionID, &rawModel, &computedAt); err != nil {
-			return nil, fmt.Errorf("unpriced models scan: %w", err)
+			return fmt.Errorf("unpriced models scan: %w", err)
 		}
 		provider := models.NormalizeProvider(sessionType)
 
@@ -100,10 +100,10 @@ func (s *Store) UnpricedModels(ctx context.Context) ([]UnpricedModel, error) {
 		// isn't mistaken for an unpriced family.
 		family := strings.TrimSuffix(normalizeV2ModelKey(provider, rawModel), fastModelKeySuffix)
 		if family == "" || family == syntheticModelKey {
-			continue // Unknown key / synthetic turn — not a real model
+			return nil // Unknown key / synthetic turn — not a real model
 		}
 		if _, ok := priced[family]; ok {
-			continue // present in the active pricing table — not a gap
+			return nil // present in the active pricing table — not a gap
 		}
 
 		groupKey := provider + "\x00" + family
@@ -116,9 +116,10 @@ func (s *Store) UnpricedModels(ctx context.Context) ([]UnpricedModel, error) {
 		if computedAt.After(b.lastSeen) {
 			b.lastSeen = computedAt
 		}
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("unpriced models rows: %w", err)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unpriced models query: %w", err)
 	}
 
 	out := make([]UnpricedModel, 0, len(buckets))
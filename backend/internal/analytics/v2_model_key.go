@@ -1,11 +1,8 @@
 package analytics
 
 import (
-	"context"
-	"errors"
-
+	"github.com/ConfabulousDev/confab-web/internal/db"
 	"github.com/ConfabulousDev/confab-web/internal/models"
-	"github.com/jackc/pgx/v5/pgconn"
 )
 
 // v2_model_key.go: provider-aware normalization of session_card_tokens_v2 model
@@ -48,18 +45,8 @@ func normalizeV2ModelKey(provider, rawKey string) string {
 
 // isTimeoutErr reports whether err is a query cancellation/deadline that should
 // degrade the cost-by-model card rather than fail the whole Trends response —
-// a context deadline (context.DeadlineExceeded, possibly wrapped) or a Postgres
-// query_canceled (SQLSTATE 57014).
+// a context deadline, a Postgres query_canceled (SQLSTATE 57014), or the store's
+// statement timeout (see db.IsQueryTimeout).
 func isTimeoutErr(err error) bool {
-	if err == nil {
-		return false
-	}
-	if errors.Is(err, context.DeadlineExceeded) {
-		return true
-	}
-	var pgErr *pgconn.PgError
-	if errors.As(err, &pgErr) && pgErr.Code == "57014" {
-		return true
-	}
-	return false
+	return db.IsQueryTimeout(err)
 }
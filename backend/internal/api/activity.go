@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// HandleGetMyActivity returns the authenticated user's activity heatmap:
+// session counts and token totals by local day of week and hour of day, with
+// sessions placed by when they started. Only the user's own sessions count.
+//
+// Query parameters:
+//   - tz: IANA time zone name (e.g. "America/New_York"); default UTC
+func HandleGetMyActivity(database *db.DB) http.HandlerFunc {
+	analyticsStore := analytics.NewStore(database.Conn())
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		loc, err := analytics.ParseActivityTimezone(r.URL.Query().Get("tz"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid tz")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		heatmap, err := analyticsStore.GetActivityHeatmap(ctx, userID, loc)
+		if err != nil {
+			log.Error("Failed to get activity heatmap", "error", err, "user_id", userID)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to get activity heatmap")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, heatmap)
+	}
+}
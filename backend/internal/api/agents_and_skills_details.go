@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleGetAgentsAndSkillsDetails returns the typed per-agent and per-skill
+// breakdown of a session's agents and skills card, with the slowest agent
+// types and the agent call tree. Uses the same canonical access model as
+// HandleGetSessionAnalytics (CF-132).
+//
+// The card is written when the session's cards are computed (precompute worker
+// or an analytics fetch); until then the response is empty.
+func HandleGetAgentsAndSkillsDetails(database *db.DB) http.HandlerFunc {
+	analyticsStore := analytics.NewStore(database.Conn())
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		if result := RequireCanonicalRead(ctx, w, database, sessionID); result == nil {
+			return
+		}
+
+		record, err := analyticsStore.GetAgentsAndSkillsCard(ctx, sessionID)
+		if err != nil {
+			log.Error("Failed to get agents and skills card", "error", err, "session_id", sessionID)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to get agents and skills details")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, analytics.BuildAgentsAndSkillsDetails(record))
+	}
+}
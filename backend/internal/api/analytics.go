@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log/slog"
 	"net/http"
 	"os"
@@ -18,6 +19,11 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
+// AnalyticsRecomputePendingHeader is set on analytics responses to "true"
+// when the precompute worker will recompute the session's cards on its next
+// poll, so the frontend can show a refresh indicator and re-poll.
+const AnalyticsRecomputePendingHeader = "X-Analytics-Recompute-Pending"
+
 // Smart recap configuration constants
 const (
 	defaultSmartRecapLockTimeoutSecs = 60
@@ -168,11 +174,20 @@ func providerClearMessageIDs(provider string) bool {
 // Analytics are cached in the database and recomputed when stale. CF-403
 // unified the dispatch: all provider-specific behavior is reached through
 // analytics.ProviderFor + the SessionProvider interface.
+//
+// After a card version bump, a complete set of outdated cards is served as-is
+// (stale: true, with per-card version deltas) instead of being recomputed
+// inline; the precompute worker replaces the set atomically. Every non-empty
+// response carries AnalyticsRecomputePendingHeader, derived from the same
+// staleness rules (and WORKER_REGULAR_* thresholds) the worker uses, unless
+// the database clock can't be read. Sessions whose transcript was archived
+// serve their stored cards unchanged, flagged transcript_archived.
 func HandleGetSessionAnalytics(database *db.DB, store *storage.S3Storage) http.HandlerFunc {
-	analyticsStore := analytics.NewStore(database.Conn())
+	analyticsStore := analytics.NewStore(database.Conn()).WithReplica(database)
 	sessionStore := &dbsession.Store{DB: database}
 	smartRecapConfig := loadSmartRecapConfig()
 	smartRecapGenerator := analytics.NewSmartRecapGenerator(analyticsStore, database, smartRecapConfig.generatorConfig())
+	recomputeThresholds := analytics.LoadStalenessThresholds("WORKER_REGULAR", analytics.DefaultRegularCardsThresholds())
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := logger.Ctx(r.Context())
@@ -218,39 +233,90 @@ func HandleGetSessionAnalytics(database *db.DB, store *storage.S3Storage) http.H
 			}
 		}
 
-		// Check if we have valid cached cards
+		sessionUserID, externalID, sessionProvider, err := sessionStore.GetSessionOwnerExternalIDAndProvider(dbCtx, sessionID)
+		if err != nil {
+			log.Error("Failed to get session info", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to get session info")
+			return
+		}
+
+		// Check if we have valid cached cards. Cards still rolling out are
+		// dropped for owners outside their flag, so they are neither served
+		// nor required for the set to count as valid.
 		cached, err := analyticsStore.GetCards(dbCtx, sessionID)
 		if err != nil {
 			log.Error("Failed to get cached cards", "error", err, "session_id", sessionID)
 			// Continue to compute fresh analytics
 		}
+		if err := cached.ApplyFeatureFlags(dbCtx, database.Conn(), sessionUserID); err != nil {
+			log.Error("Failed to evaluate card feature flags", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to get analytics")
+			return
+		}
+
+		// Archived transcript: the chunks are gone, so the stored cards are
+		// final. Serve them (and any existing smart recap) as-is; never
+		// recompute or generate.
+		if session.TranscriptArchivedAt != nil {
+			response := &analytics.AnalyticsResponse{}
+			if cached != nil {
+				response = cached.ToResponse()
+				if smartRecapConfig.Enabled {
+					if smartCard, err := analyticsStore.GetSmartRecapCard(dbCtx, sessionID); err == nil && smartCard != nil {
+						addSmartRecapToResponse(response, smartCard)
+					}
+				}
+			}
+			response.TranscriptArchived = true
+			w.Header().Set(AnalyticsRecomputePendingHeader, "false")
+			attachSuggestedTitle(database, sessionID, response)
+			respondJSON(w, http.StatusOK, response)
+			return
+		}
 
-		if cached.AllValid(totalLineCount) {
+		// The worker measures time gaps from the database's NOW(), so the
+		// header does too: app/DB clock skew can't make the two disagree.
+		setRecomputePending := func(cards *analytics.Cards) {
+			dbNow, err := analyticsStore.Now(dbCtx)
+			if err != nil {
+				log.Error("Failed to read database clock", "error", err, "session_id", sessionID)
+				return
+			}
+			pending := cards.NeedsRecompute(recomputeThresholds, totalLineCount, session.FirstSeen, dbNow)
+			w.Header().Set(AnalyticsRecomputePendingHeader, strconv.FormatBool(pending))
+		}
+
+		// A complete set with outdated versions is served stale rather than
+		// recomputed here, so readers never see a half-migrated set.
+		staleCards := cached.StaleVersions()
+		if cached.AllValid(totalLineCount) || (cached.AllPresent() && len(staleCards) > 0) {
 			// Cache hit - return cached data
 			response := cached.ToResponse()
+			if len(staleCards) > 0 {
+				response.Stale = true
+				response.StaleCards = staleCards
+			}
+			setRecomputePending(cached)
 
 			// Handle smart recap (if enabled) even for cached responses
 			if smartRecapConfig.Enabled {
-				sessionUserID, externalID, sessionProvider, err := sessionStore.GetSessionOwnerExternalIDAndProvider(dbCtx, sessionID)
-				if err == nil {
-					attachOrGenerateSmartRecap(r.Context(), &smartRecapContext{
-						database:        database,
-						analyticsStore:  analyticsStore,
-						store:           store,
-						config:          smartRecapConfig,
-						generator:       smartRecapGenerator,
-						sessionID:       sessionID,
-						sessionUserID:   sessionUserID,
-						sessionProvider: sessionProvider,
-						externalID:      externalID,
-						lineCount:       totalLineCount,
-						cardStats:       response.Cards,
-						response:        response,
-						log:             log,
-						isOwner:         result.AccessInfo.AccessType == db.SessionAccessOwner,
-						clearMessageIDs: providerClearMessageIDs(sessionProvider),
-					})
-				}
+				attachOrGenerateSmartRecap(r.Context(), &smartRecapContext{
+					database:        database,
+					analyticsStore:  analyticsStore,
+					store:           store,
+					config:          smartRecapConfig,
+					generator:       smartRecapGenerator,
+					sessionID:       sessionID,
+					sessionUserID:   sessionUserID,
+					sessionProvider: sessionProvider,
+					externalID:      externalID,
+					lineCount:       totalLineCount,
+					cardStats:       response.Cards,
+					response:        response,
+					log:             log,
+					isOwner:         result.AccessInfo.AccessType == db.SessionAccessOwner,
+					clearMessageIDs: providerClearMessageIDs(sessionProvider),
+				})
 			}
 
 			attachSuggestedTitle(database, sessionID, response)
@@ -259,13 +325,6 @@ func HandleGetSessionAnalytics(database *db.DB, store *storage.S3Storage) http.H
 		}
 
 		// Cache miss or stale — recompute via the provider registry.
-		sessionUserID, externalID, sessionProvider, err := sessionStore.GetSessionOwnerExternalIDAndProvider(dbCtx, sessionID)
-		if err != nil {
-			log.Error("Failed to get session info", "error", err, "session_id", sessionID)
-			respondError(w, http.StatusInternalServerError, "Failed to get session info")
-			return
-		}
-
 		sp, err := analytics.ProviderFor(sessionProvider)
 		if err != nil {
 			log.Error("provider lookup failed for analytics", "error", err, "session_id", sessionID, "provider", sessionProvider)
@@ -297,12 +356,29 @@ func HandleGetSessionAnalytics(database *db.DB, store *storage.S3Storage) http.H
 
 		// Convert to Cards and cache
 		cards := computed.ToCards(sessionID, totalLineCount)
-		if err := analyticsStore.UpsertCards(dbCtx, cards); err != nil {
+		if err := cards.ApplyFeatureFlags(dbCtx, database.Conn(), sessionUserID); err != nil {
+			log.Error("Failed to evaluate card feature flags", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to get analytics")
+			return
+		}
+		projection, err := analyticsStore.BuildCostProjection(dbCtx, sessionID, cards.TokensV2)
+		if err != nil {
+			log.Error("Failed to build cost projection", "error", err, "session_id", sessionID)
+		}
+		if err := analyticsStore.SaveComputedCards(dbCtx, sessionID, analytics.ComputedCardSet{
+			Cards:             cards,
+			ConversationTurns: computed.ConversationTurns,
+			RedactionEvents:   computed.RedactionEvents,
+			TokenSeries:       computed.TokenSeries,
+			CostProjection:    projection,
+			DetectedPRURL:     computed.DetectedPRURL,
+		}); err != nil {
 			log.Error("Failed to cache cards", "error", err, "session_id", sessionID)
 		}
 
 		response := cards.ToResponse()
 		response.ValidationErrorCount = computed.ValidationErrorCount
+		setRecomputePending(cards)
 
 		// Smart recap. The rollout is reused for PrepareTranscript so
 		// providers with lazy-materialize caches (Claude, Codex) don't
@@ -422,8 +498,16 @@ func attachOrGenerateSmartRecap(ctx context.Context, sc *smartRecapContext) {
 		return
 	}
 
-	if smartCard != nil && !smartCard.CanAcquireLock(sc.config.LockTimeoutSeconds) {
-		return
+	if smartCard != nil && smartCard.ComputingStartedAt != nil {
+		dbNow, err := sc.analyticsStore.Now(dbCtx)
+		if err != nil {
+			sc.log.Error("Failed to read database clock", "error", err, "session_id", sc.sessionID)
+			addCardError("Failed to load smart recap")
+			return
+		}
+		if !smartCard.CanAcquireLock(sc.config.LockTimeoutSeconds, dbNow) {
+			return
+		}
 	}
 
 	transcript := sc.transcript
@@ -484,7 +568,12 @@ func attachSuggestedTitle(database *db.DB, sessionID string, response *analytics
 
 // addSmartRecapToResponse adds the smart recap card data to the response.
 func addSmartRecapToResponse(response *analytics.AnalyticsResponse, card *analytics.SmartRecapCardRecord) {
-	response.Cards["smart_recap"] = analytics.SmartRecapCardData{
+	response.Cards["smart_recap"] = smartRecapCardData(card)
+}
+
+// smartRecapCardData converts a stored smart recap card to its API shape.
+func smartRecapCardData(card *analytics.SmartRecapCardRecord) analytics.SmartRecapCardData {
+	return analytics.SmartRecapCardData{
 		Recap:                     card.Recap,
 		WentWell:                  card.WentWell,
 		WentBad:                   card.WentBad,
@@ -493,6 +582,79 @@ func addSmartRecapToResponse(response *analytics.AnalyticsResponse, card *analyt
 		DefaultContextSuggestions: card.DefaultContextSuggestions,
 		ComputedAt:                card.ComputedAt.Format(time.RFC3339),
 		ModelUsed:                 card.ModelUsed,
+		InputTruncated:            card.InputOmittedEntries > 0,
+		InputOmittedEntries:       card.InputOmittedEntries,
+	}
+}
+
+// SessionRecapResponse is the response for GET /api/v1/sessions/{id}/recap:
+// the smart recap card plus the line count it covers.
+type SessionRecapResponse struct {
+	analytics.SmartRecapCardData
+	UpToLine int64 `json:"up_to_line"`
+}
+
+// HandleGetSessionRecap returns only the stored smart recap of a session,
+// without the other cards and without generating one. This endpoint is
+// owner-only. Returns 404 when the session has no recap yet; a row that only
+// holds a generation lock (version 0) counts as none.
+func HandleGetSessionRecap(database *db.DB) http.HandlerFunc {
+	analyticsStore := analytics.NewStore(database.Conn())
+	sessionStore := &dbsession.Store{DB: database}
+	smartRecapConfig := loadSmartRecapConfig()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		if !smartRecapConfig.Enabled {
+			respondError(w, http.StatusNotFound, "Smart recap not available")
+			return
+		}
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		dbCtx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		sessionUserID, _, _, err := sessionStore.GetSessionOwnerExternalIDAndProvider(dbCtx, sessionID)
+		if errors.Is(err, db.ErrSessionNotFound) {
+			respondError(w, http.StatusNotFound, "Session not found")
+			return
+		}
+		if err != nil {
+			log.Error("Failed to get session", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to get session")
+			return
+		}
+		if sessionUserID != userID {
+			respondError(w, http.StatusForbidden, "Only the session owner can read the recap")
+			return
+		}
+
+		card, err := analyticsStore.GetSmartRecapCard(dbCtx, sessionID)
+		if err != nil {
+			log.Error("Failed to get smart recap card", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to get smart recap")
+			return
+		}
+		if card == nil || card.Version == 0 {
+			respondError(w, http.StatusNotFound, "No smart recap for this session")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, SessionRecapResponse{
+			SmartRecapCardData: smartRecapCardData(card),
+			UpToLine:           card.UpToLine,
+		})
 	}
 }
 
@@ -552,6 +714,10 @@ func HandleRegenerateSmartRecap(database *db.DB, store *storage.S3Storage) http.
 			respondError(w, http.StatusBadRequest, "No transcript available")
 			return
 		}
+		if session.TranscriptArchivedAt != nil {
+			respondTranscriptArchived(w)
+			return
+		}
 
 		// Check quota
 		quota, err := recapquota.GetOrCreate(dbCtx, database.Conn(), userID)
@@ -570,9 +736,17 @@ func HandleRegenerateSmartRecap(database *db.DB, store *storage.S3Storage) http.
 		if err != nil {
 			log.Error("Failed to get smart recap card", "error", err, "session_id", sessionID)
 		}
-		if smartCard != nil && !smartCard.CanAcquireLock(smartRecapConfig.LockTimeoutSeconds) {
-			respondError(w, http.StatusConflict, "Generation already in progress")
-			return
+		if smartCard != nil && smartCard.ComputingStartedAt != nil {
+			dbNow, err := analyticsStore.Now(dbCtx)
+			if err != nil {
+				log.Error("Failed to read database clock", "error", err, "session_id", sessionID)
+				respondError(w, http.StatusInternalServerError, "Failed to check generation lock")
+				return
+			}
+			if !smartCard.CanAcquireLock(smartRecapConfig.LockTimeoutSeconds, dbNow) {
+				respondError(w, http.StatusConflict, "Generation already in progress")
+				return
+			}
 		}
 
 		cached, err := analyticsStore.GetCards(dbCtx, sessionID)
@@ -0,0 +1,140 @@
+package analytics_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Activity Heatmap HTTP Integration Tests
+//
+// GET /api/v1/me/activity
+// =============================================================================
+
+func TestGetMyActivity_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	store := analytics.NewStore(env.DB.Conn())
+
+	// startedAt creates a session of userID that started at (UTC) and, when
+	// tokens is non-nil, gives it a tokens card with those totals.
+	startedAt := func(t *testing.T, userID int64, externalID string, at time.Time, tokens *analytics.TokensV2Data) string {
+		t.Helper()
+		sessionID := testutil.CreateTestSession(t, env, userID, externalID)
+		if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET first_seen = $2 WHERE id = $1`, sessionID, at.UTC()); err != nil {
+			t.Fatalf("set first_seen: %v", err)
+		}
+		if tokens != nil {
+			if err := store.UpsertCards(env.Ctx, &analytics.Cards{TokensV2: &analytics.TokensV2CardRecord{
+				SessionID: sessionID, Version: analytics.TokensV2CardVersion, ComputedAt: time.Now().UTC(), UpToLine: 1, Data: *tokens,
+			}}); err != nil {
+				t.Fatalf("upsert tokens card: %v", err)
+			}
+		}
+		return sessionID
+	}
+
+	getActivity := func(t *testing.T, client *testutil.TestClient, query string) analytics.ActivityHeatmap {
+		t.Helper()
+		resp, err := client.Get("/api/v1/me/activity" + query)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var heatmap analytics.ActivityHeatmap
+		testutil.ParseJSON(t, resp, &heatmap)
+		return heatmap
+	}
+
+	// sumBuckets adds up every bucket, to compare against the totals.
+	sumBuckets := func(heatmap analytics.ActivityHeatmap) analytics.ActivityCounts {
+		var sum analytics.ActivityCounts
+		for _, b := range heatmap.Buckets {
+			sum.SessionCount += b.SessionCount
+			sum.InputTokens += b.InputTokens
+			sum.OutputTokens += b.OutputTokens
+			sum.CacheCreationTokens += b.CacheCreationTokens
+			sum.CacheReadTokens += b.CacheReadTokens
+		}
+		return sum
+	}
+
+	type cell struct{ day, hour int }
+	cells := func(heatmap analytics.ActivityHeatmap) map[cell]int64 {
+		m := map[cell]int64{}
+		for _, b := range heatmap.Buckets {
+			m[cell{b.DayOfWeek, b.Hour}] = b.SessionCount
+		}
+		return m
+	}
+
+	env.CleanDB(t)
+	user := testutil.CreateTestUser(t, env, "activity@example.com", "Activity")
+	other := testutil.CreateTestUser(t, env, "other@example.com", "Other")
+	client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+
+	// Monday 2026-01-05 14:xx UTC (09:xx in New York, EST).
+	startedAt(t, user.ID, "mon-1", time.Date(2026, 1, 5, 14, 10, 0, 0, time.UTC),
+		&analytics.TokensV2Data{TotalCostUSD: "0", TotalInput: 100, TotalOutput: 200, TotalCacheCreation: 10, TotalCacheRead: 20})
+	startedAt(t, user.ID, "mon-2", time.Date(2026, 1, 5, 14, 50, 0, 0, time.UTC),
+		&analytics.TokensV2Data{TotalCostUSD: "0", TotalInput: 1, TotalOutput: 2, TotalCacheCreation: 3, TotalCacheRead: 4})
+	// Saturday 2026-07-04 02:00 UTC (Friday 22:00 in New York, EDT); no card yet.
+	startedAt(t, user.ID, "sat", time.Date(2026, 7, 4, 2, 0, 0, 0, time.UTC), nil)
+	// Neither counts: merged away, or someone else's.
+	merged := startedAt(t, user.ID, "merged", time.Date(2026, 1, 5, 14, 0, 0, 0, time.UTC),
+		&analytics.TokensV2Data{TotalCostUSD: "0", TotalInput: 1000})
+	if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET merged_at = NOW() WHERE id = $1`, merged); err != nil {
+		t.Fatalf("mark merged: %v", err)
+	}
+	startedAt(t, other.ID, "theirs", time.Date(2026, 1, 5, 14, 0, 0, 0, time.UTC),
+		&analytics.TokensV2Data{TotalCostUSD: "0", TotalInput: 1000})
+
+	wantTotals := analytics.ActivityCounts{SessionCount: 3, InputTokens: 101, OutputTokens: 202, CacheCreationTokens: 13, CacheReadTokens: 24}
+
+	t.Run("buckets in UTC by default and sum to the totals", func(t *testing.T) {
+		heatmap := getActivity(t, client, "")
+		if heatmap.Timezone != "UTC" {
+			t.Errorf("timezone = %q, want UTC", heatmap.Timezone)
+		}
+		if heatmap.Totals != wantTotals {
+			t.Errorf("totals = %+v, want %+v", heatmap.Totals, wantTotals)
+		}
+		if sum := sumBuckets(heatmap); sum != heatmap.Totals {
+			t.Errorf("buckets sum to %+v, totals are %+v", sum, heatmap.Totals)
+		}
+		want := map[cell]int64{{1, 14}: 2, {6, 2}: 1}
+		if got := cells(heatmap); len(got) != len(want) || got[cell{1, 14}] != 2 || got[cell{6, 2}] != 1 {
+			t.Errorf("cells = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("buckets in the requested zone, following DST", func(t *testing.T) {
+		heatmap := getActivity(t, client, "?tz=America/New_York")
+		if heatmap.Timezone != "America/New_York" {
+			t.Errorf("timezone = %q, want America/New_York", heatmap.Timezone)
+		}
+		if sum := sumBuckets(heatmap); sum != wantTotals {
+			t.Errorf("buckets sum to %+v, want %+v", sum, wantTotals)
+		}
+		want := map[cell]int64{{1, 9}: 2, {5, 22}: 1}
+		if got := cells(heatmap); len(got) != len(want) || got[cell{1, 9}] != 2 || got[cell{5, 22}] != 1 {
+			t.Errorf("cells = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("rejects an unknown tz", func(t *testing.T) {
+		resp, err := client.Get("/api/v1/me/activity?tz=Mars/Olympus_Mons")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
+}
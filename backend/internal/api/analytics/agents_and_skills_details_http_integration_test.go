@@ -0,0 +1,76 @@
+package analytics_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Agents and Skills Details HTTP Integration Tests
+//
+// GET /api/v1/sessions/{id}/cards/agents-and-skills/details
+// =============================================================================
+
+func TestGetAgentsAndSkillsDetails_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+
+	jsonlContent := `{"type":"user","message":{"role":"user","content":"explore it"},"uuid":"u1","timestamp":"2025-01-01T00:00:00Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"id":"msg_1","type":"message","model":"claude-sonnet-4-20241022","role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Task","input":{"subagent_type":"Explore","prompt":"look around"}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50}},"uuid":"a1","timestamp":"2025-01-01T00:00:02Z","parentUuid":"u1","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"found it"}]},"toolUseResult":{"agentId":"agent-1","totalDurationMs":42000},"uuid":"u2","timestamp":"2025-01-01T00:00:44Z","parentUuid":"a1","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+`
+
+	env.CleanDB(t)
+	user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "test-session")
+	testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "test-session", "transcript.jsonl", 1, 3, []byte(jsonlContent))
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 3)
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+	getDetails := func(t *testing.T) analytics.AgentsAndSkillsDetails {
+		t.Helper()
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/cards/agents-and-skills/details", sessionID))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result analytics.AgentsAndSkillsDetails
+		testutil.ParseJSON(t, resp, &result)
+		return result
+	}
+
+	if before := getDetails(t); before.Agents == nil || len(before.Agents) != 0 || before.AgentCallTree == nil {
+		t.Errorf("expected empty lists before cards are computed, got %+v", before)
+	}
+
+	resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/analytics", sessionID))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	testutil.RequireStatus(t, resp, http.StatusOK)
+
+	details := getDetails(t)
+	want := analytics.AgentDetail{AgentID: "Explore", InvocationCount: 1, TotalDurationMs: 42000, AverageDurationMs: 42000}
+	if len(details.Agents) != 1 || details.Agents[0] != want {
+		t.Fatalf("agents = %+v, want [%+v]", details.Agents, want)
+	}
+	if len(details.TopAgentsByDuration) != 1 || details.TopAgentsByDuration[0].AgentID != "Explore" {
+		t.Errorf("top agents = %+v", details.TopAgentsByDuration)
+	}
+	if len(details.AgentCallTree) != 1 || details.AgentCallTree[0].AgentID != "Explore" || len(details.AgentCallTree[0].Children) != 0 {
+		t.Errorf("call tree = %+v", details.AgentCallTree)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/api"
 	"github.com/ConfabulousDev/confab-web/internal/models"
 	"github.com/ConfabulousDev/confab-web/internal/testutil"
 )
@@ -477,6 +478,78 @@ func TestGetSessionAnalytics_HTTP_Integration(t *testing.T) {
 			t.Error("expected computed_at to be set")
 		}
 	})
+
+	t.Run("serves outdated card versions stale while recompute is pending", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "test-session")
+
+		jsonlContent := `{"type":"user","message":{"role":"user","content":"hello"},"uuid":"u1","timestamp":"2025-01-01T00:00:00Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"id":"msg_1","type":"message","model":"claude-sonnet-4","role":"assistant","content":[{"type":"text","text":"Hello!"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50}},"uuid":"a1","timestamp":"2025-01-01T00:00:01Z","parentUuid":"u1","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+`
+		testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "test-session", "transcript.jsonl", 1, 2, []byte(jsonlContent))
+		testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 2)
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+		url := fmt.Sprintf("/api/v1/sessions/%s/analytics", sessionID)
+
+		// First request computes a current card set: nothing pending.
+		resp1, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("request 1 failed: %v", err)
+		}
+		resp1.Body.Close()
+		testutil.RequireStatus(t, resp1, http.StatusOK)
+		if got := resp1.Header.Get(api.AnalyticsRecomputePendingHeader); got != "false" {
+			t.Errorf("expected pending header false after compute, got %q", got)
+		}
+
+		// Simulate a conversation card version bump. The sentinel user_turns
+		// value proves the stored card is served rather than recomputed inline.
+		if _, err := env.DB.Exec(env.Ctx,
+			`UPDATE session_card_conversation SET version = version - 1, user_turns = 42 WHERE session_id = $1`,
+			sessionID); err != nil {
+			t.Fatalf("failed to downgrade card: %v", err)
+		}
+
+		resp2, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("request 2 failed: %v", err)
+		}
+		defer resp2.Body.Close()
+		testutil.RequireStatus(t, resp2, http.StatusOK)
+		if got := resp2.Header.Get(api.AnalyticsRecomputePendingHeader); got != "true" {
+			t.Errorf("expected pending header true for outdated cards, got %q", got)
+		}
+
+		var result struct {
+			Stale      bool                               `json:"stale"`
+			StaleCards map[string]analytics.CardStaleness `json:"stale_cards"`
+			Cards      struct {
+				Conversation struct {
+					UserTurns int `json:"user_turns"`
+				} `json:"conversation"`
+			} `json:"cards"`
+		}
+		testutil.ParseJSON(t, resp2, &result)
+
+		if !result.Stale {
+			t.Error("expected stale: true")
+		}
+		conv, ok := result.StaleCards["conversation"]
+		if !ok || conv.VersionDelta != 1 || conv.CurrentVersion != analytics.ConversationCardVersion {
+			t.Errorf("unexpected stale_cards: %+v", result.StaleCards)
+		}
+		if len(result.StaleCards) != 1 {
+			t.Errorf("expected only conversation to be stale, got %+v", result.StaleCards)
+		}
+		if result.Cards.Conversation.UserTurns != 42 {
+			t.Errorf("expected stored conversation card to be served, got user_turns=%d", result.Cards.Conversation.UserTurns)
+		}
+	})
 }
 
 // =============================================================================
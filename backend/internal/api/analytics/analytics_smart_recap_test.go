@@ -12,6 +12,7 @@ import (
 
 	"github.com/ConfabulousDev/confab-web/internal/analytics"
 	"github.com/ConfabulousDev/confab-web/internal/anthropic"
+	"github.com/ConfabulousDev/confab-web/internal/api"
 	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
 	"github.com/ConfabulousDev/confab-web/internal/recapquota"
 	"github.com/ConfabulousDev/confab-web/internal/testutil"
@@ -821,3 +822,93 @@ func TestSmartRecap_CacheMissTriggersGeneration(t *testing.T) {
 		t.Errorf("saved recap = %q, want %q", card.Recap, "Generated recap.")
 	}
 }
+
+// =============================================================================
+// GET /api/v1/sessions/{id}/recap
+// =============================================================================
+
+func TestGetSessionRecap(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	t.Setenv("SMART_RECAP_ENABLED", "true")
+	t.Setenv("ANTHROPIC_API_KEY", "test-api-key-not-used")
+	t.Setenv("SMART_RECAP_MODEL", "claude-haiku-4-5-20251101")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	owner := testutil.CreateTestUser(t, env, "recap-owner@test.com", "Owner")
+	other := testutil.CreateTestUser(t, env, "recap-other@test.com", "Other")
+	ownerToken := testutil.CreateTestWebSessionWithToken(t, env, owner.ID)
+	otherToken := testutil.CreateTestWebSessionWithToken(t, env, other.ID)
+	withRecap := testutil.CreateTestSession(t, env, owner.ID, "recap-present")
+	withoutRecap := testutil.CreateTestSession(t, env, owner.ID, "recap-absent")
+	lockOnly := testutil.CreateTestSession(t, env, owner.ID, "recap-lock-only")
+
+	store := analytics.NewStore(env.DB.Conn())
+	card := &analytics.SmartRecapCardRecord{
+		SessionID:                 withRecap,
+		Version:                   analytics.SmartRecapCardVersion,
+		ComputedAt:                time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		UpToLine:                  42,
+		Recap:                     "Refactored the billing worker.",
+		WentWell:                  []analytics.AnnotatedItem{{Text: "Tests first"}},
+		WentBad:                   []analytics.AnnotatedItem{{Text: "Flaky fixture"}},
+		HumanSuggestions:          []analytics.AnnotatedItem{{Text: "Smaller prompts"}},
+		EnvironmentSuggestions:    []analytics.AnnotatedItem{},
+		DefaultContextSuggestions: []analytics.AnnotatedItem{},
+		ModelUsed:                 "claude-haiku-4-5-20251101",
+	}
+	if err := store.UpsertSmartRecapCard(context.Background(), card); err != nil {
+		t.Fatalf("UpsertSmartRecapCard: %v", err)
+	}
+	// A generation in flight leaves a version-0 placeholder row.
+	if _, err := store.AcquireSmartRecapLock(context.Background(), lockOnly, 60); err != nil {
+		t.Fatalf("AcquireSmartRecapLock: %v", err)
+	}
+
+	ts := setupTestServerWithEnv(t, env)
+	ownerClient := testutil.NewTestClient(t, ts).WithSession(ownerToken)
+
+	t.Run("returns the stored recap", func(t *testing.T) {
+		resp, err := ownerClient.Get(fmt.Sprintf("/api/v1/sessions/%s/recap", withRecap))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		var got api.SessionRecapResponse
+		testutil.ParseJSON(t, resp, &got)
+		if got.Recap != card.Recap || got.UpToLine != 42 || got.ModelUsed != card.ModelUsed {
+			t.Errorf("unexpected recap %+v", got)
+		}
+		if got.ComputedAt != "2026-01-02T03:04:05Z" {
+			t.Errorf("computed_at = %q", got.ComputedAt)
+		}
+		if len(got.WentWell) != 1 || len(got.WentBad) != 1 || len(got.HumanSuggestions) != 1 {
+			t.Errorf("unexpected annotated items %+v", got)
+		}
+	})
+
+	for name, sessionID := range map[string]string{"no recap": withoutRecap, "generation lock only": lockOnly} {
+		t.Run(name+" is 404", func(t *testing.T) {
+			resp, err := ownerClient.Get(fmt.Sprintf("/api/v1/sessions/%s/recap", sessionID))
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			testutil.RequireStatus(t, resp, http.StatusNotFound)
+		})
+	}
+
+	t.Run("non-owner is 403", func(t *testing.T) {
+		resp, err := testutil.NewTestClient(t, ts).WithSession(otherToken).Get(fmt.Sprintf("/api/v1/sessions/%s/recap", withRecap))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusForbidden)
+	})
+}
@@ -0,0 +1,93 @@
+package analytics_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Code Activity Files HTTP Integration Tests
+//
+// GET /api/v1/sessions/{id}/cards/code-activity/files
+// =============================================================================
+
+func TestListCodeActivityFiles_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+
+	jsonlContent := `{"type":"user","message":{"role":"user","content":"fix it"},"uuid":"u1","timestamp":"2025-01-01T00:00:00Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"id":"msg_1","type":"message","model":"claude-sonnet-4-20241022","role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/test/main.go"}},{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"/test/util.go"}},{"type":"tool_use","id":"t3","name":"Edit","input":{"file_path":"/test/main.go","old_string":"a := 1\nb := 2","new_string":"a := 1\nb := 3\nc := 4"}},{"type":"tool_use","id":"t4","name":"Write","input":{"file_path":"/test/app.ts","content":"export {}\n"}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50}},"uuid":"a1","timestamp":"2025-01-01T00:00:02Z","parentUuid":"u1","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+`
+
+	env.CleanDB(t)
+	user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "test-session")
+	testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "test-session", "transcript.jsonl", 1, 2, []byte(jsonlContent))
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 2)
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+	getFiles := func(t *testing.T, query string) analytics.CodeActivityFilesData {
+		t.Helper()
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/cards/code-activity/files%s", sessionID, query))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result analytics.CodeActivityFilesData
+		testutil.ParseJSON(t, resp, &result)
+		return result
+	}
+
+	if before := getFiles(t, ""); before.TotalFiles != 0 || before.Files == nil || len(before.Files) != 0 {
+		t.Errorf("expected an empty file list before cards are computed, got %+v", before)
+	}
+
+	resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/analytics", sessionID))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	testutil.RequireStatus(t, resp, http.StatusOK)
+
+	t.Run("default order is most written first", func(t *testing.T) {
+		files := getFiles(t, "")
+		if files.TotalFiles != 3 || len(files.Files) != 3 {
+			t.Fatalf("files = %+v, want 3", files)
+		}
+		want := analytics.CodeFileStats{FilePath: "/test/main.go", Language: "go", ReadCount: 1, WriteCount: 1, LinesAdded: 3, LinesRemoved: 2}
+		if files.Files[0] != want {
+			t.Errorf("files[0] = %+v, want %+v", files.Files[0], want)
+		}
+		if files.Files[1].FilePath != "/test/app.ts" || files.Files[2].FilePath != "/test/util.go" {
+			t.Errorf("order = %s, %s; want /test/app.ts, /test/util.go", files.Files[1].FilePath, files.Files[2].FilePath)
+		}
+	})
+
+	t.Run("language filter, sort and limit", func(t *testing.T) {
+		files := getFiles(t, "?language=go&sort=path_asc&limit=1")
+		if files.TotalFiles != 2 || len(files.Files) != 1 || files.Files[0].FilePath != "/test/main.go" {
+			t.Errorf("files = %+v, want /test/main.go of 2 go files", files)
+		}
+	})
+
+	t.Run("invalid sort returns 400", func(t *testing.T) {
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/cards/code-activity/files?sort=size", sessionID))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
+}
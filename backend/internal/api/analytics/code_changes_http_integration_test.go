@@ -0,0 +1,77 @@
+package analytics_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Code Changes HTTP Integration Tests
+//
+// GET /api/v1/sessions/{id}/cards/code-changes
+// =============================================================================
+
+func TestGetCodeChanges_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+
+	jsonlContent := `{"type":"user","message":{"role":"user","content":"fix it"},"uuid":"u1","timestamp":"2025-01-01T00:00:00Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"id":"msg_1","type":"message","model":"claude-sonnet-4-20241022","role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Edit","input":{"file_path":"/test/main.go","old_string":"a := 1\nb := 2","new_string":"a := 1\nb := 3\nc := 4"}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50}},"uuid":"a1","timestamp":"2025-01-01T00:00:02Z","parentUuid":"u1","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+`
+
+	env.CleanDB(t)
+	user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+	testutil.SetTestFeatureFlag(t, env, analytics.CodeChangesCardFlag, []int64{user.ID}, 0)
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "test-session")
+	testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "test-session", "transcript.jsonl", 1, 2, []byte(jsonlContent))
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 2)
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+	getChanges := func(t *testing.T) analytics.CodeChangesCardData {
+		t.Helper()
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/cards/code-changes", sessionID))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result analytics.CodeChangesCardData
+		testutil.ParseJSON(t, resp, &result)
+		return result
+	}
+
+	if before := getChanges(t); before.TotalHunks != 0 || before.Hunks == nil || len(before.Hunks) != 0 {
+		t.Errorf("expected an empty hunk list before cards are computed, got %+v", before)
+	}
+
+	resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/analytics", sessionID))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	testutil.RequireStatus(t, resp, http.StatusOK)
+
+	changes := getChanges(t)
+	if changes.TotalHunks != 1 || len(changes.Hunks) != 1 {
+		t.Fatalf("code changes = %+v, want one hunk", changes)
+	}
+	h := changes.Hunks[0]
+	if h.FilePath != "/test/main.go" || h.LinesBefore != 2 || h.LinesAfter != 3 {
+		t.Errorf("hunk = %+v, want /test/main.go 2 → 3 lines", h)
+	}
+	if len(h.RemovedLines) != 1 || h.RemovedLines[0] != "b := 2" ||
+		len(h.AddedLines) != 2 || h.AddedLines[0] != "b := 3" || h.AddedLines[1] != "c := 4" {
+		t.Errorf("hunk lines = -%v +%v, want -[b := 2] +[b := 3 c := 4]", h.RemovedLines, h.AddedLines)
+	}
+}
@@ -0,0 +1,151 @@
+package analytics_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Conversation Turns HTTP Integration Tests
+//
+// GET /api/v1/sessions/{id}/cards/conversation/turns
+// =============================================================================
+
+func TestListConversationTurns_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+
+	// u1 → msg_1 (tool use) → u2 → msg_2: four turns.
+	jsonlContent := `{"type":"user","message":{"role":"user","content":"hello"},"uuid":"u1","timestamp":"2025-01-01T00:00:00Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"id":"msg_1","type":"message","model":"claude-sonnet-4-20241022","role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50}},"uuid":"a1","timestamp":"2025-01-01T00:00:02Z","parentUuid":"u1","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"user","message":{"role":"user","content":"thanks"},"uuid":"u2","timestamp":"2025-01-01T00:00:10Z","parentUuid":"a1","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"id":"msg_2","type":"message","model":"claude-sonnet-4-20241022","role":"assistant","content":[{"type":"text","text":"Bye!"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":200,"output_tokens":7}},"uuid":"a2","timestamp":"2025-01-01T00:00:11Z","parentUuid":"u2","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+`
+
+	setup := func(t *testing.T) (*testutil.TestClient, string) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "test-session")
+		testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "test-session", "transcript.jsonl", 1, 4, []byte(jsonlContent))
+		testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 4)
+
+		ts := setupTestServerWithEnv(t, env)
+		return testutil.NewTestClient(t, ts).WithSession(sessionToken), sessionID
+	}
+
+	listTurns := func(t *testing.T, client *testutil.TestClient, sessionID, query string) api.ConversationTurnsResponse {
+		t.Helper()
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/cards/conversation/turns%s", sessionID, query))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result api.ConversationTurnsResponse
+		testutil.ParseJSON(t, resp, &result)
+		return result
+	}
+
+	computeCards := func(t *testing.T, client *testutil.TestClient, sessionID string) {
+		t.Helper()
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/analytics", sessionID))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+	}
+
+	t.Run("empty before cards are computed", func(t *testing.T) {
+		client, sessionID := setup(t)
+		result := listTurns(t, client, sessionID, "")
+		if len(result.Turns) != 0 || result.HasMore {
+			t.Errorf("expected empty page, got %+v", result)
+		}
+	})
+
+	t.Run("returns turns written by the analytics compute", func(t *testing.T) {
+		client, sessionID := setup(t)
+		computeCards(t, client, sessionID)
+
+		result := listTurns(t, client, sessionID, "")
+		if len(result.Turns) != 4 {
+			t.Fatalf("expected 4 turns, got %d: %+v", len(result.Turns), result.Turns)
+		}
+		first := result.Turns[1]
+		if first.Role != "assistant" || first.LineNumber != 2 || !first.HasToolUse {
+			t.Errorf("unexpected first assistant turn: %+v", first)
+		}
+		if first.DurationMs == nil || *first.DurationMs != 2000 {
+			t.Errorf("expected duration 2000ms, got %v", first.DurationMs)
+		}
+		if first.TokenCount == nil || *first.TokenCount != 50 {
+			t.Errorf("expected 50 tokens, got %v", first.TokenCount)
+		}
+		if second := result.Turns[2]; second.DurationMs == nil || *second.DurationMs != 8000 {
+			t.Errorf("expected user thinking time 8000ms, got %v", second.DurationMs)
+		}
+	})
+
+	t.Run("filters by role and tool use", func(t *testing.T) {
+		client, sessionID := setup(t)
+		computeCards(t, client, sessionID)
+
+		result := listTurns(t, client, sessionID, "?role=assistant")
+		if len(result.Turns) != 2 {
+			t.Fatalf("expected 2 assistant turns, got %d", len(result.Turns))
+		}
+
+		result = listTurns(t, client, sessionID, "?role=assistant&has_tool_use=true")
+		if len(result.Turns) != 1 || result.Turns[0].TurnIndex != 1 {
+			t.Errorf("expected only turn 1, got %+v", result.Turns)
+		}
+	})
+
+	t.Run("paginates with cursor", func(t *testing.T) {
+		client, sessionID := setup(t)
+		computeCards(t, client, sessionID)
+
+		page1 := listTurns(t, client, sessionID, "?limit=3")
+		if len(page1.Turns) != 3 || !page1.HasMore || page1.NextCursor != "2" {
+			t.Fatalf("unexpected first page: %+v", page1)
+		}
+		page2 := listTurns(t, client, sessionID, "?limit=3&cursor="+page1.NextCursor)
+		if len(page2.Turns) != 1 || page2.HasMore || page2.Turns[0].TurnIndex != 3 {
+			t.Errorf("unexpected second page: %+v", page2)
+		}
+	})
+
+	t.Run("rejects invalid role", func(t *testing.T) {
+		client, sessionID := setup(t)
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/cards/conversation/turns?role=system", sessionID))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
+
+	t.Run("returns 404 for another user's private session", func(t *testing.T) {
+		_, sessionID := setup(t)
+		other := testutil.CreateTestUser(t, env, "other@example.com", "Other User")
+		otherToken := testutil.CreateTestWebSessionWithToken(t, env, other.ID)
+		client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithSession(otherToken)
+
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/cards/conversation/turns", sessionID))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusNotFound)
+	})
+}
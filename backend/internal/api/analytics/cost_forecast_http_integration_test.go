@@ -0,0 +1,136 @@
+package analytics_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Cost Forecast HTTP Integration Tests
+//
+// GET /api/v1/analytics/forecast
+// =============================================================================
+
+func TestGetCostForecast_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+
+	// pricedSession creates a session of userID started now, computes its
+	// cards through client, and pins its tokens-card cost to costUSD.
+	pricedSession := func(t *testing.T, client *testutil.TestClient, userID int64, externalID, costUSD string) string {
+		t.Helper()
+		sessionID := testutil.CreateTestSession(t, env, userID, externalID)
+		testutil.UploadTestTranscript(t, env, userID, models.ProviderClaudeCode, externalID, "transcript.jsonl", pricedTranscript(2))
+		testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 4)
+
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/analytics", sessionID))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		setCost(t, env, sessionID, costUSD)
+		return sessionID
+	}
+
+	getForecast := func(t *testing.T, client *testutil.TestClient, query string) analytics.CostForecast {
+		t.Helper()
+		resp, err := client.Get("/api/v1/analytics/forecast" + query)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var forecast analytics.CostForecast
+		testutil.ParseJSON(t, resp, &forecast)
+		return forecast
+	}
+
+	t.Run("sums the user's own sessions this month", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "forecast@example.com", "Forecast")
+		other := testutil.CreateTestUser(t, env, "other@example.com", "Other")
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+		otherClient := testutil.NewTestClient(t, ts).WithSession(testutil.CreateTestWebSessionWithToken(t, env, other.ID))
+
+		pricedSession(t, client, user.ID, "mine-1", "10.00")
+		pricedSession(t, client, user.ID, "mine-2", "2.50")
+		// Neither counts: merged away, or someone else's.
+		merged := pricedSession(t, client, user.ID, "merged", "100.00")
+		if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET merged_at = NOW() WHERE id = $1`, merged); err != nil {
+			t.Fatalf("mark merged: %v", err)
+		}
+		pricedSession(t, otherClient, other.ID, "theirs", "100.00")
+		// Last month's spend is outside the window.
+		old := pricedSession(t, client, user.ID, "last-month", "100.00")
+		if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET first_seen = date_trunc('month', NOW()) - INTERVAL '1 day' WHERE id = $1`, old); err != nil {
+			t.Fatalf("backdate session: %v", err)
+		}
+
+		forecast := getForecast(t, client, "")
+		if forecast.ActualToDateUSD != "12.50" {
+			t.Errorf("actual_to_date_usd = %s, want 12.50", forecast.ActualToDateUSD)
+		}
+		if len(forecast.Daily) != forecast.DaysElapsed || forecast.DaysElapsed < 1 {
+			t.Fatalf("daily series has %d entries for %d elapsed days", len(forecast.Daily), forecast.DaysElapsed)
+		}
+		if today := forecast.Daily[len(forecast.Daily)-1]; today.CostUSD != "12.50" {
+			t.Errorf("today = %+v, want 12.50", today)
+		}
+		if forecast.DaysElapsed == forecast.DaysInMonth && forecast.ProjectedMonthEndUSD != "12.50" {
+			t.Errorf("projected_month_end_usd on the last day = %s, want 12.50", forecast.ProjectedMonthEndUSD)
+		}
+	})
+
+	t.Run("serves a cached forecast until the TTL lapses", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "cached@example.com", "Cached")
+		client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+		sessionID := pricedSession(t, client, user.ID, "cached", "1.00")
+
+		first := getForecast(t, client, "")
+		if first.ActualToDateUSD != "1.00" {
+			t.Fatalf("first forecast = %s, want 1.00", first.ActualToDateUSD)
+		}
+		setCost(t, env, sessionID, "5.00")
+		if got := getForecast(t, client, "").ActualToDateUSD; got != "1.00" {
+			t.Errorf("second forecast = %s, want the cached 1.00", got)
+		}
+		// A different timezone is cached separately, so it reads fresh (unless
+		// UTC+1 has already rolled into next month).
+		if other := getForecast(t, client, "?tz_offset=-60"); other.Month == first.Month && other.ActualToDateUSD != "5.00" {
+			t.Errorf("forecast for another timezone = %s, want 5.00", other.ActualToDateUSD)
+		}
+	})
+
+	t.Run("rejects an invalid tz_offset", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "tz@example.com", "TZ")
+		client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+
+		resp, err := client.Get("/api/v1/analytics/forecast?tz_offset=9999")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
+}
+
+// setCost overwrites the total cost on a session's tokens card.
+func setCost(t *testing.T, env *testutil.TestEnvironment, sessionID, costUSD string) {
+	t.Helper()
+	if _, err := env.DB.Exec(env.Ctx,
+		`UPDATE session_card_tokens_v2 SET data = jsonb_set(data, '{total_cost_usd}', to_jsonb($2::text)) WHERE session_id = $1`,
+		sessionID, costUSD); err != nil {
+		t.Fatalf("set cost: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package analytics_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Cost Projection HTTP Integration Tests
+//
+// GET /api/v1/sessions/{id}/cards/cost-projection
+// =============================================================================
+
+// pricedTranscript returns a Claude transcript of n user/assistant exchanges
+// (2n lines), each assistant reply carrying token usage so the session has a
+// non-zero cost.
+func pricedTranscript(n int) []byte {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, `{"type":"user","message":{"role":"user","content":"question %d"},"uuid":"u%d","timestamp":"2025-01-01T00:00:00Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}`+"\n", i, i)
+		fmt.Fprintf(&b, `{"type":"assistant","message":{"id":"msg_%d","type":"message","model":"claude-sonnet-4-20241022","role":"assistant","content":[{"type":"text","text":"answer"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":1000,"output_tokens":500}},"uuid":"a%d","timestamp":"2025-01-01T00:00:01Z","parentUuid":"u%d","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}`+"\n", i, i, i)
+	}
+	return []byte(b.String())
+}
+
+func TestGetCostProjection_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+
+	// priorSession creates an earlier session of the user with the given final
+	// line count, first seen hoursAgo hours before now.
+	priorSession := func(t *testing.T, userID int64, externalID string, lines, hoursAgo int) string {
+		t.Helper()
+		sessionID := testutil.CreateTestSessionFull(t, env, userID, externalID, testutil.TestSessionFullOpts{SyncLines: lines})
+		if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET first_seen = NOW() - make_interval(hours => $2) WHERE id = $1`, sessionID, hoursAgo); err != nil {
+			t.Fatalf("backdate session: %v", err)
+		}
+		return sessionID
+	}
+
+	// computeProjection uploads a transcript for a new session, computes its
+	// cards via the analytics endpoint, and returns the analytics response and
+	// the stored projection.
+	computeProjection := func(t *testing.T, client *testutil.TestClient, userID int64, externalID string, exchanges int) (analytics.AnalyticsResponse, analytics.CostProjectionRecord) {
+		t.Helper()
+		content := pricedTranscript(exchanges)
+		sessionID := testutil.CreateTestSession(t, env, userID, externalID)
+		testutil.UploadTestTranscript(t, env, userID, models.ProviderClaudeCode, externalID, "transcript.jsonl", content)
+		testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 2*exchanges)
+
+		path := fmt.Sprintf("/api/v1/sessions/%s/cards/cost-projection", sessionID)
+		resp, err := client.Get(path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var before analytics.CostProjectionRecord
+		testutil.ParseJSON(t, resp, &before)
+		if before.Projection != nil || before.ComputedAt != nil {
+			t.Errorf("expected an empty projection before cards are computed, got %+v", before)
+		}
+
+		resp, err = client.Get(fmt.Sprintf("/api/v1/sessions/%s/analytics", sessionID))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var card analytics.AnalyticsResponse
+		testutil.ParseJSON(t, resp, &card)
+
+		resp, err = client.Get(path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var record analytics.CostProjectionRecord
+		testutil.ParseJSON(t, resp, &record)
+		if record.ComputedAt == nil {
+			t.Errorf("expected computed_at once cards are computed, got %+v", record)
+		}
+		return card, record
+	}
+
+	t.Run("short session has no projection", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "short@example.com", "Short")
+		client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+		priorSession(t, user.ID, "prior", 100, 24)
+
+		_, record := computeProjection(t, client, user.ID, "short-session", 5)
+		if record.Projection != nil {
+			t.Errorf("projection = %+v, want null under %d lines", record.Projection, analytics.MinCostProjectionLines)
+		}
+		if record.UpToLine != 10 || record.HistorySessionCount != 1 {
+			t.Errorf("record = %+v, want up_to_line 10 with 1 history session", record)
+		}
+	})
+
+	t.Run("no earlier sessions has no projection", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "first@example.com", "First")
+		client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+
+		_, record := computeProjection(t, client, user.ID, "first-session", 12)
+		if record.Projection != nil || record.HistorySessionCount != 0 {
+			t.Errorf("record = %+v, want null projection with no history", record)
+		}
+	})
+
+	t.Run("projects from the ten most recent earlier sessions", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "long@example.com", "Long")
+		other := testutil.CreateTestUser(t, env, "other@example.com", "Other")
+		client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+
+		// Ten recent sessions of 30..120 lines: P25 = 52.5, P75 = 97.5, P90 = 111.
+		for i := 0; i < 10; i++ {
+			priorSession(t, user.ID, fmt.Sprintf("recent-%d", i), 30+10*i, i+1)
+		}
+		// None of these may count: older than the ten most recent, merged
+		// away, or someone else's.
+		priorSession(t, user.ID, "too-old", 10_000, 100)
+		merged := priorSession(t, user.ID, "merged", 10_000, 1)
+		if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET merged_at = NOW() WHERE id = $1`, merged); err != nil {
+			t.Fatalf("mark merged: %v", err)
+		}
+		priorSession(t, other.ID, "other-user", 10_000, 1)
+
+		card, record := computeProjection(t, client, user.ID, "current", 12)
+		if record.HistorySessionCount != 10 || record.UpToLine != 24 {
+			t.Fatalf("record = %+v, want 10 history sessions up to line 24", record)
+		}
+		if record.Projection == nil {
+			t.Fatal("projection is null")
+		}
+		if !card.Cost.EstimatedUSD.IsPositive() {
+			t.Fatalf("tokens card cost = %s, want a priced session", card.Cost.EstimatedUSD)
+		}
+
+		rate := card.Cost.EstimatedUSD.Div(decimal.NewFromInt(24))
+		costAt := func(lines int64) string { return rate.Mul(decimal.NewFromInt(lines)).StringFixed(2) }
+		want := analytics.CostProjection{
+			ProjectedTotalCostUSD:    costAt(98),
+			EstimatedCompletionLines: 98,
+			ConfidenceInterval: analytics.CostProjectionInterval{
+				LowUSD:  costAt(53),
+				HighUSD: costAt(111),
+			},
+		}
+		if *record.Projection != want {
+			t.Errorf("projection = %+v, want %+v", *record.Projection, want)
+		}
+	})
+
+	t.Run("session longer than its history projects its current cost", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "marathon@example.com", "Marathon")
+		client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+		priorSession(t, user.ID, "brief-1", 20, 2)
+		priorSession(t, user.ID, "brief-2", 30, 1)
+
+		card, record := computeProjection(t, client, user.ID, "marathon", 25)
+		if record.Projection == nil {
+			t.Fatal("projection is null")
+		}
+		current := card.Cost.EstimatedUSD.StringFixed(2)
+		p := record.Projection
+		if p.EstimatedCompletionLines != 50 || p.ProjectedTotalCostUSD != current ||
+			p.ConfidenceInterval.LowUSD != current || p.ConfidenceInterval.HighUSD != current {
+			t.Errorf("projection = %+v, want 50 lines at the current cost %s", *p, current)
+		}
+	})
+}
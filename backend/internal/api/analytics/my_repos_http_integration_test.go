@@ -0,0 +1,101 @@
+package analytics_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Repos HTTP Integration Tests
+//
+// GET /api/v1/me/repos
+// =============================================================================
+
+func TestGetMyRepos_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "repos@example.com", "Repos")
+	other := testutil.CreateTestUser(t, env, "other@example.com", "Other")
+	client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+
+	// listable makes sessionID show in the session list, last active at, with
+	// a tokens card totalling tokens when non-zero.
+	listable := func(t *testing.T, sessionID string, at time.Time, tokens int64) {
+		t.Helper()
+		testutil.MakeSessionListable(t, env, sessionID)
+		if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET last_message_at = $2 WHERE id = $1`, sessionID, at.UTC()); err != nil {
+			t.Fatalf("set last_message_at: %v", err)
+		}
+		if tokens > 0 {
+			testutil.SeedTokensV2Card(t, env, sessionID, analytics.TokensV2Data{
+				TotalCostUSD: "0", TotalInput: tokens / 2, TotalOutput: tokens / 4, TotalCacheRead: tokens - tokens/2 - tokens/4,
+			})
+		}
+	}
+
+	day := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	api1 := testutil.CreateTestSessionWithGitInfo(t, env, user.ID, "api-1", "https://github.com/acme/api.git")
+	listable(t, api1, day, 100)
+	api2 := testutil.CreateTestSessionWithGitInfo(t, env, user.ID, "api-2", "git@github.com:acme/api.git")
+	listable(t, api2, day.Add(2*time.Hour), 40)
+	web := testutil.CreateTestSessionWithGitInfo(t, env, user.ID, "web", "https://github.com/acme/web")
+	listable(t, web, day.Add(5*time.Hour), 0)
+	noGit := testutil.CreateTestSession(t, env, user.ID, "no-git")
+	listable(t, noGit, day.Add(-time.Hour), 7)
+
+	// None of these count: not listable, merged away, or someone else's.
+	testutil.CreateTestSessionWithGitInfo(t, env, user.ID, "empty", "https://github.com/acme/api")
+	merged := testutil.CreateTestSessionWithGitInfo(t, env, user.ID, "merged", "https://github.com/acme/api")
+	listable(t, merged, day.Add(10*time.Hour), 1000)
+	if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET merged_at = NOW() WHERE id = $1`, merged); err != nil {
+		t.Fatalf("mark merged: %v", err)
+	}
+	theirs := testutil.CreateTestSessionWithGitInfo(t, env, other.ID, "theirs", "https://github.com/acme/api")
+	listable(t, theirs, day.Add(10*time.Hour), 1000)
+
+	resp, err := client.Get("/api/v1/me/repos")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	testutil.RequireStatus(t, resp, http.StatusOK)
+	var got analytics.MyRepos
+	testutil.ParseJSON(t, resp, &got)
+
+	type want struct {
+		repo         string
+		sessions     int64
+		tokens       int64
+		lastActivity time.Time
+	}
+	wantRepos := []want{
+		{"acme/web", 1, 0, day.Add(5 * time.Hour)},
+		{"acme/api", 2, 140, day.Add(2 * time.Hour)},
+	}
+	if len(got.Repos) != len(wantRepos) {
+		t.Fatalf("repos = %+v, want %d entries", got.Repos, len(wantRepos))
+	}
+	for i, w := range wantRepos {
+		r := got.Repos[i]
+		if r.Repo != w.repo || r.SessionCount != w.sessions || r.TotalTokens != w.tokens ||
+			r.LastActivity == nil || !r.LastActivity.Equal(w.lastActivity) {
+			t.Errorf("repos[%d] = %s: %d sessions, %d tokens, last %v; want %s: %d, %d, %v",
+				i, r.Repo, r.SessionCount, r.TotalTokens, r.LastActivity, w.repo, w.sessions, w.tokens, w.lastActivity)
+		}
+	}
+
+	u := got.Unassigned
+	if u.SessionCount != 1 || u.TotalTokens != 7 || u.LastActivity == nil || !u.LastActivity.Equal(day.Add(-time.Hour)) {
+		t.Errorf("unassigned = %d sessions, %d tokens, last %v; want 1, 7, %v",
+			u.SessionCount, u.TotalTokens, u.LastActivity, day.Add(-time.Hour))
+	}
+}
@@ -0,0 +1,171 @@
+package analytics_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// PR Outcomes HTTP Integration Tests
+//
+// PATCH /api/v1/sessions/{id}/pr-url
+// GET /api/v1/analytics/outcomes
+// =============================================================================
+
+func TestUpdateSessionPRURL_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	owner := testutil.CreateTestUser(t, env, "pr-owner@example.com", "Owner")
+	other := testutil.CreateTestUser(t, env, "pr-other@example.com", "Other")
+	ts := setupTestServerWithEnv(t, env)
+	ownerClient := testutil.NewTestClient(t, ts).WithSession(testutil.CreateTestWebSessionWithToken(t, env, owner.ID))
+	otherClient := testutil.NewTestClient(t, ts).WithSession(testutil.CreateTestWebSessionWithToken(t, env, other.ID))
+
+	sessionID := testutil.CreateTestSession(t, env, owner.ID, "pr-session")
+
+	patch := func(t *testing.T, client *testutil.TestClient, sessionID string, prURL *string) *http.Response {
+		t.Helper()
+		resp, err := client.Patch("/api/v1/sessions/"+sessionID+"/pr-url", api.UpdateSessionPRURLRequest{PRURL: prURL})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+	ptr := func(s string) *string { return &s }
+
+	t.Run("sets the normalized URL", func(t *testing.T) {
+		resp := patch(t, ownerClient, sessionID, ptr("https://www.github.com/acme/api/pull/7/files?w=1#diff"))
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var got db.SessionDetail
+		testutil.ParseJSON(t, resp, &got)
+		if got.PRURL == nil || *got.PRURL != "https://github.com/acme/api/pull/7" {
+			t.Errorf("pr_url = %v, want the normalized PR URL", got.PRURL)
+		}
+	})
+
+	t.Run("null clears it", func(t *testing.T) {
+		resp := patch(t, ownerClient, sessionID, nil)
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var got db.SessionDetail
+		testutil.ParseJSON(t, resp, &got)
+		if got.PRURL != nil {
+			t.Errorf("pr_url = %q, want cleared", *got.PRURL)
+		}
+	})
+
+	t.Run("rejects a URL that is not a PR", func(t *testing.T) {
+		resp := patch(t, ownerClient, sessionID, ptr("https://github.com/acme/api/issues/7"))
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+		var errBody httputil.ErrorResponse
+		testutil.ParseJSON(t, resp, &errBody)
+		if errBody.Code != httputil.CodeValidationFailed {
+			t.Errorf("code = %q, want %q", errBody.Code, httputil.CodeValidationFailed)
+		}
+	})
+
+	t.Run("rejects unknown sessions and non-owners", func(t *testing.T) {
+		resp := patch(t, ownerClient, "00000000-0000-0000-0000-000000000000", ptr("https://github.com/acme/api/pull/7"))
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusNotFound)
+
+		resp = patch(t, otherClient, sessionID, ptr("https://github.com/acme/api/pull/7"))
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusForbidden)
+	})
+}
+
+func TestGetOutcomes_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "outcomes@example.com", "Outcomes")
+	other := testutil.CreateTestUser(t, env, "outcomes-other@example.com", "Other")
+	client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+
+	const prA = "https://github.com/acme/api/pull/1"
+	const prB = "https://gitlab.com/acme/web/-/merge_requests/2"
+
+	day := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// session creates a session first seen at `at` linked to prURL and
+	// detectedPRURL (either may be ""), costing cost over durationMs.
+	session := func(t *testing.T, userID int64, externalID string, at time.Time, prURL, detectedPRURL, cost string, durationMs int64) string {
+		t.Helper()
+		sessionID := testutil.CreateTestSession(t, env, userID, externalID)
+		if _, err := env.DB.Exec(env.Ctx, `
+			UPDATE sessions SET first_seen = $2, last_message_at = $2,
+				pr_url = NULLIF($3, ''), detected_pr_url = NULLIF($4, '')
+			WHERE id = $1`, sessionID, at, prURL, detectedPRURL); err != nil {
+			t.Fatalf("update session: %v", err)
+		}
+		testutil.SeedTokensV2Card(t, env, sessionID, analytics.TokensV2Data{TotalCostUSD: cost})
+		if _, err := env.DB.Exec(env.Ctx, `
+			INSERT INTO session_card_session (session_id, version, computed_at, up_to_line, duration_ms)
+			VALUES ($1, 1, NOW(), 1, $2)`, sessionID, durationMs); err != nil {
+			t.Fatalf("insert session card: %v", err)
+		}
+		return sessionID
+	}
+
+	a1 := session(t, user.ID, "a1", day, prA, "", "1.25", 60_000)
+	// A detected URL never overrides a manual one: a2 counts towards prA.
+	a2 := session(t, user.ID, "a2", day.Add(time.Hour), prA, prB, "0.50", 30_000)
+	a3 := session(t, user.ID, "a3", day.Add(2*time.Hour), "", prA, "0.25", 10_000)
+	b1 := session(t, user.ID, "b1", day.Add(3*time.Hour), "", prB, "2.00", 120_000)
+
+	// None of these count: no PR, merged away, demo, or someone else's.
+	session(t, user.ID, "no-pr", day.Add(4*time.Hour), "", "", "9.00", 1)
+	merged := session(t, user.ID, "merged", day.Add(4*time.Hour), prA, "", "9.00", 1)
+	demo := session(t, user.ID, "demo", day.Add(4*time.Hour), prA, "", "9.00", 1)
+	if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET merged_at = NOW() WHERE id = $1`, merged); err != nil {
+		t.Fatalf("mark merged: %v", err)
+	}
+	if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET is_demo = TRUE WHERE id = $1`, demo); err != nil {
+		t.Fatalf("mark demo: %v", err)
+	}
+	session(t, other.ID, "theirs", day.Add(4*time.Hour), prA, "", "9.00", 1)
+
+	resp, err := client.Get("/api/v1/analytics/outcomes")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	testutil.RequireStatus(t, resp, http.StatusOK)
+	var got analytics.Outcomes
+	testutil.ParseJSON(t, resp, &got)
+
+	want := []analytics.PROutcome{
+		{
+			PRURL: prB, SessionCount: 1, TotalCostUSD: "2.00", TotalDurationMs: 120_000,
+			FirstSessionAt: day.Add(3 * time.Hour), LastActivity: day.Add(3 * time.Hour),
+			SessionIDs: []string{b1},
+		},
+		{
+			PRURL: prA, SessionCount: 3, TotalCostUSD: "2.00", TotalDurationMs: 100_000,
+			FirstSessionAt: day, LastActivity: day.Add(2 * time.Hour),
+			SessionIDs: []string{a1, a2, a3},
+		},
+	}
+	if !reflect.DeepEqual(got.Outcomes, want) {
+		t.Errorf("outcomes = %+v\nwant %+v", got.Outcomes, want)
+	}
+}
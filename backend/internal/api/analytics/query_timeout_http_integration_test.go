@@ -0,0 +1,69 @@
+package analytics_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestQueryTimeout_HTTP_Integration holds a lock on the token series table
+// inside a transaction stuck in pg_sleep, so the store's read blocks until
+// DB_QUERY_TIMEOUT_SECONDS cancels it and the handler answers 503.
+func TestQueryTimeout_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	t.Setenv("DB_QUERY_TIMEOUT_SECONDS", "1")
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "timeout@test.com", "Timeout User")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "slow-session")
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+	ctx := context.Background()
+	lockTx, err := env.DB.Conn().BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("begin lock tx: %v", err)
+	}
+	if _, err := lockTx.ExecContext(ctx, `LOCK TABLE session_card_token_series IN ACCESS EXCLUSIVE MODE`); err != nil {
+		lockTx.Rollback()
+		t.Fatalf("lock table: %v", err)
+	}
+	released := make(chan struct{})
+	go func() {
+		defer close(released)
+		lockTx.ExecContext(ctx, `SELECT pg_sleep(4)`)
+		lockTx.Rollback()
+	}()
+	defer func() { <-released }()
+
+	started := time.Now()
+	resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/tokens/series", sessionID))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	testutil.RequireStatus(t, resp, http.StatusServiceUnavailable)
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Errorf("request took %v, want it cut off after ~1s", elapsed)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+
+	var body httputil.QueryTimeoutResponse
+	testutil.ParseJSON(t, resp, &body)
+	if body.Error != "query timeout" || body.Code != httputil.CodeQueryTimeout || body.RetryAfter != 1 {
+		t.Errorf("body = %+v, want query timeout with retry_after 1", body)
+	}
+}
@@ -0,0 +1,146 @@
+package analytics_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Redaction Events HTTP Integration Tests
+//
+// GET /api/v1/sessions/{id}/cards/redactions/details
+// =============================================================================
+
+func TestListRedactionEvents_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+
+	// Three redactions: two on line 1, one on line 2.
+	jsonlContent := `{"type":"user","message":{"role":"user","content":"token [REDACTED:GITHUB_TOKEN] and [REDACTED]"},"uuid":"u1","timestamp":"2025-01-01T00:00:00Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"id":"msg_1","type":"message","model":"claude-sonnet-4-20241022","role":"assistant","content":[{"type":"text","text":"Saw <REDACTED:GITHUB_TOKEN> secret"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50}},"uuid":"a1","timestamp":"2025-01-01T00:00:02Z","parentUuid":"u1","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+`
+
+	setup := func(t *testing.T) (*testutil.TestClient, string) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "test-session")
+		testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "test-session", "transcript.jsonl", 1, 2, []byte(jsonlContent))
+		testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 2)
+
+		ts := setupTestServerWithEnv(t, env)
+		return testutil.NewTestClient(t, ts).WithSession(sessionToken), sessionID
+	}
+
+	listEvents := func(t *testing.T, client *testutil.TestClient, sessionID, query string) api.RedactionEventsResponse {
+		t.Helper()
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/cards/redactions/details%s", sessionID, query))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result api.RedactionEventsResponse
+		testutil.ParseJSON(t, resp, &result)
+		return result
+	}
+
+	computeCards := func(t *testing.T, client *testutil.TestClient, sessionID string) {
+		t.Helper()
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/analytics", sessionID))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+	}
+
+	t.Run("empty before cards are computed", func(t *testing.T) {
+		client, sessionID := setup(t)
+		result := listEvents(t, client, sessionID, "")
+		if len(result.Events) != 0 || result.HasMore {
+			t.Errorf("expected empty page, got %+v", result)
+		}
+	})
+
+	t.Run("returns events written by the analytics compute", func(t *testing.T) {
+		client, sessionID := setup(t)
+		computeCards(t, client, sessionID)
+
+		result := listEvents(t, client, sessionID, "")
+		if len(result.Events) != 3 {
+			t.Fatalf("expected 3 events, got %d: %+v", len(result.Events), result.Events)
+		}
+		if e := result.Events[0]; e.Type != "GITHUB_TOKEN" || e.LineNumber != 1 {
+			t.Errorf("unexpected first event: %+v", e)
+		}
+		if e := result.Events[1]; e.Type != "UNSPECIFIED" || e.LineNumber != 1 {
+			t.Errorf("unexpected second event: %+v", e)
+		}
+		if e := result.Events[2]; e.Type != "GITHUB_TOKEN" || e.LineNumber != 2 {
+			t.Errorf("unexpected third event: %+v", e)
+		}
+		for _, e := range result.Events {
+			if strings.Contains(e.ContextPreview, "token") || strings.Contains(e.ContextPreview, "secret") {
+				t.Errorf("context preview leaks transcript text: %q", e.ContextPreview)
+			}
+		}
+	})
+
+	t.Run("filters by type", func(t *testing.T) {
+		client, sessionID := setup(t)
+		computeCards(t, client, sessionID)
+
+		result := listEvents(t, client, sessionID, "?type=GITHUB_TOKEN")
+		if len(result.Events) != 2 || result.Events[1].RedactionIndex != 2 {
+			t.Errorf("expected events 0 and 2, got %+v", result.Events)
+		}
+	})
+
+	t.Run("paginates with cursor", func(t *testing.T) {
+		client, sessionID := setup(t)
+		computeCards(t, client, sessionID)
+
+		page1 := listEvents(t, client, sessionID, "?limit=2")
+		if len(page1.Events) != 2 || !page1.HasMore || page1.NextCursor != "1" {
+			t.Fatalf("unexpected first page: %+v", page1)
+		}
+		page2 := listEvents(t, client, sessionID, "?limit=2&cursor="+page1.NextCursor)
+		if len(page2.Events) != 1 || page2.HasMore || page2.Events[0].RedactionIndex != 2 {
+			t.Errorf("unexpected second page: %+v", page2)
+		}
+	})
+
+	t.Run("rejects invalid type", func(t *testing.T) {
+		client, sessionID := setup(t)
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/cards/redactions/details?type=api-key", sessionID))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
+
+	t.Run("returns 404 for another user's private session", func(t *testing.T) {
+		_, sessionID := setup(t)
+		other := testutil.CreateTestUser(t, env, "other@example.com", "Other User")
+		otherToken := testutil.CreateTestWebSessionWithToken(t, env, other.ID)
+		client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithSession(otherToken)
+
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/cards/redactions/details", sessionID))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusNotFound)
+	})
+}
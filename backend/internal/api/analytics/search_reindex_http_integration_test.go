@@ -0,0 +1,131 @@
+package analytics_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Search Reindex HTTP Integration Tests
+//
+// POST /api/v1/sessions/{id}/reindex
+// =============================================================================
+
+const reindexTranscript = `{"type":"user","message":{"role":"user","content":"tune the reindex endpoint"},"uuid":"u1","timestamp":"2026-06-05T00:00:00Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"id":"msg_1","type":"message","model":"claude-sonnet-4","role":"assistant","content":[{"type":"text","text":"Done."}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50}},"uuid":"a1","timestamp":"2026-06-05T00:00:01Z","parentUuid":"u1","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+`
+
+func TestReindexSession_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "reindex@example.com", "Reindex User")
+	other := testutil.CreateTestUser(t, env, "other@example.com", "Other User")
+	apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "reindex-key")
+	otherKey := testutil.CreateTestAPIKeyWithToken(t, env, other.ID, "other-key")
+
+	externalID := "reindex-session"
+	sessionID := testutil.CreateTestSession(t, env, user.ID, externalID)
+	testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, externalID, "transcript.jsonl", 1, 2, []byte(reindexTranscript))
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 2)
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+	path := fmt.Sprintf("/api/v1/sessions/%s/reindex", sessionID)
+	store := analytics.NewStore(env.DB.Conn())
+
+	reindex := func(t *testing.T) api.SearchReindexResponse {
+		t.Helper()
+		resp, err := client.Post(path, nil)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result api.SearchReindexResponse
+		testutil.ParseJSON(t, resp, &result)
+		return result
+	}
+
+	first := reindex(t)
+	if first.IndexedUpToLine != 2 || first.Version != analytics.SearchIndexVersion || first.MetadataHash == "" {
+		t.Fatalf("first reindex = %+v, want version %d up to line 2 with a hash", first, analytics.SearchIndexVersion)
+	}
+
+	t.Run("title change is indexed and no longer stale", func(t *testing.T) {
+		if _, err := env.DB.Exec(env.Ctx,
+			`UPDATE sessions SET custom_title = 'Quasar migration plan' WHERE id = $1`, sessionID); err != nil {
+			t.Fatalf("failed to rename session: %v", err)
+		}
+		status, err := store.GetSearchIndexStatus(env.Ctx, sessionID)
+		if err != nil {
+			t.Fatalf("GetSearchIndexStatus: %v", err)
+		}
+		if !status.Stale {
+			t.Fatalf("status after rename = %+v, want stale", status)
+		}
+
+		result := reindex(t)
+		if result.MetadataHash == first.MetadataHash {
+			t.Errorf("metadata_hash unchanged after rename: %s", result.MetadataHash)
+		}
+
+		record, err := store.GetSearchIndex(env.Ctx, sessionID)
+		if err != nil {
+			t.Fatalf("GetSearchIndex: %v", err)
+		}
+		if !strings.Contains(record.ContentText, "Quasar migration plan") {
+			t.Errorf("content_text = %q, want the new title", record.ContentText)
+		}
+		if record.MetadataHash != result.MetadataHash {
+			t.Errorf("stored metadata_hash = %s, response = %s", record.MetadataHash, result.MetadataHash)
+		}
+
+		status, err = store.GetSearchIndexStatus(env.Ctx, sessionID)
+		if err != nil {
+			t.Fatalf("GetSearchIndexStatus: %v", err)
+		}
+		if status.Stale {
+			t.Errorf("status after reindex = %+v, want fresh", status)
+		}
+	})
+
+	t.Run("other users get 403", func(t *testing.T) {
+		resp, err := testutil.NewTestClient(t, ts).WithAPIKey(otherKey.RawToken).Post(path, nil)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusForbidden)
+	})
+
+	t.Run("session without lines gets 404", func(t *testing.T) {
+		emptyID := testutil.CreateTestSession(t, env, user.ID, "reindex-empty")
+		resp, err := client.Post(fmt.Sprintf("/api/v1/sessions/%s/reindex", emptyID), nil)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusNotFound)
+	})
+
+	t.Run("unknown session gets 404", func(t *testing.T) {
+		resp, err := client.Post("/api/v1/sessions/00000000-0000-0000-0000-000000000000/reindex", nil)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusNotFound)
+	})
+}
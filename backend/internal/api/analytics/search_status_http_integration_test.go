@@ -0,0 +1,137 @@
+package analytics_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Search Status HTTP Integration Tests
+//
+// GET /api/v1/sessions/{id}/search-status
+// =============================================================================
+
+func TestGetSearchStatus_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "search@example.com", "Search User")
+	other := testutil.CreateTestUser(t, env, "other@example.com", "Other User")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+	otherToken := testutil.CreateTestWebSessionWithToken(t, env, other.ID)
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "search-status-session")
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 10)
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+	path := fmt.Sprintf("/api/v1/sessions/%s/search-status", sessionID)
+
+	get := func(t *testing.T) analytics.SearchIndexStatus {
+		t.Helper()
+		resp, err := client.Get(path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var status analytics.SearchIndexStatus
+		testutil.ParseJSON(t, resp, &status)
+		return status
+	}
+
+	t.Run("not indexed", func(t *testing.T) {
+		status := get(t)
+		if status.Indexed || !status.Stale || status.Version != nil || status.MetadataHash != nil {
+			t.Errorf("status = %+v, want not indexed and stale", status)
+		}
+		if status.TotalLines != 10 {
+			t.Errorf("total_lines = %d, want 10", status.TotalLines)
+		}
+	})
+
+	// Index the session as the worker would, with the hash of its current
+	// metadata.
+	store := analytics.NewStore(env.DB.Conn())
+	if err := store.UpsertSearchIndex(env.Ctx, &analytics.SearchIndexRecord{
+		SessionID:       sessionID,
+		Version:         analytics.SearchIndexVersion,
+		IndexedUpToLine: 10,
+	}, &analytics.SearchIndexContent{MetadataText: "search status"}); err != nil {
+		t.Fatalf("UpsertSearchIndex: %v", err)
+	}
+	if _, err := env.DB.Exec(env.Ctx, `
+		UPDATE session_search_index si
+		SET metadata_hash = MD5(COALESCE(s.custom_title, '') || '|' || COALESCE(s.suggested_session_title, '') || '|' || COALESCE(s.summary, '') || '|' || COALESCE(s.first_user_message, ''))
+		FROM sessions s
+		WHERE si.session_id = s.id AND s.id = $1`, sessionID); err != nil {
+		t.Fatalf("failed to set metadata_hash: %v", err)
+	}
+
+	t.Run("indexed and fresh", func(t *testing.T) {
+		status := get(t)
+		if !status.Indexed || status.Stale {
+			t.Fatalf("status = %+v, want indexed and fresh", status)
+		}
+		if status.Version == nil || *status.Version != analytics.SearchIndexVersion {
+			t.Errorf("version = %v, want %d", status.Version, analytics.SearchIndexVersion)
+		}
+		if status.IndexedUpToLine == nil || *status.IndexedUpToLine != 10 {
+			t.Errorf("indexed_up_to_line = %v, want 10", status.IndexedUpToLine)
+		}
+		if status.MetadataHash == nil || *status.MetadataHash == "" || status.IndexedAt == nil {
+			t.Errorf("metadata_hash = %v, indexed_at = %v, want both set", status.MetadataHash, status.IndexedAt)
+		}
+	})
+
+	t.Run("indexed and stale after a metadata change", func(t *testing.T) {
+		if _, err := env.DB.Exec(env.Ctx,
+			`UPDATE sessions SET custom_title = 'Renamed' WHERE id = $1`, sessionID); err != nil {
+			t.Fatalf("failed to rename session: %v", err)
+		}
+		if status := get(t); !status.Indexed || !status.Stale {
+			t.Errorf("status = %+v, want indexed and stale", status)
+		}
+	})
+
+	t.Run("indexed and stale after new lines", func(t *testing.T) {
+		// Undo the rename so only the line count is behind.
+		if _, err := env.DB.Exec(env.Ctx,
+			`UPDATE sessions SET custom_title = NULL WHERE id = $1`, sessionID); err != nil {
+			t.Fatalf("failed to reset title: %v", err)
+		}
+		if _, err := env.DB.Exec(env.Ctx,
+			`UPDATE sync_files SET last_synced_line = 12 WHERE session_id = $1`, sessionID); err != nil {
+			t.Fatalf("failed to extend transcript: %v", err)
+		}
+		status := get(t)
+		if !status.Stale || status.TotalLines != 12 {
+			t.Errorf("status = %+v, want stale with total_lines 12", status)
+		}
+	})
+
+	t.Run("other users get 403", func(t *testing.T) {
+		resp, err := testutil.NewTestClient(t, ts).WithSession(otherToken).Get(path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusForbidden)
+	})
+
+	t.Run("unknown session gets 404", func(t *testing.T) {
+		resp, err := client.Get("/api/v1/sessions/00000000-0000-0000-0000-000000000000/search-status")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusNotFound)
+	})
+}
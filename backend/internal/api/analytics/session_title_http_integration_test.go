@@ -0,0 +1,241 @@
+package analytics_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/anthropic"
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/recapquota"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestGenerateSessionTitle_HTTP_Integration covers POST
+// /sessions/{id}/generate-title: a stored title and quota charge on success,
+// the per-session hourly limit, and owner-only access.
+func TestGenerateSessionTitle_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(anthropic.MessagesResponse{
+			ID:         "msg_title",
+			Type:       "message",
+			Role:       "assistant",
+			StopReason: "end_turn",
+			Content:    []anthropic.ContentBlock{{Type: "text", Text: "Fix OAuth Login Redirect Loop"}},
+			Usage:      anthropic.Usage{InputTokens: 120, OutputTokens: 9},
+		})
+	}))
+	defer mockServer.Close()
+
+	os.Setenv("SMART_RECAP_ENABLED", "true")
+	os.Setenv("ANTHROPIC_API_KEY", "test-key")
+	os.Setenv("SMART_RECAP_MODEL", "test-model")
+	os.Setenv("SMART_RECAP_QUOTA_LIMIT", "20")
+	os.Setenv("TEST_SMART_RECAP_BASE_URL", mockServer.URL)
+	defer func() {
+		os.Unsetenv("SMART_RECAP_ENABLED")
+		os.Unsetenv("ANTHROPIC_API_KEY")
+		os.Unsetenv("SMART_RECAP_MODEL")
+		os.Unsetenv("SMART_RECAP_QUOTA_LIMIT")
+		os.Unsetenv("TEST_SMART_RECAP_BASE_URL")
+	}()
+
+	env := testutil.SetupTestEnvironment(t)
+
+	t.Run("generates, stores, and rate limits", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "title@test.com", "Title User")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "title-session")
+		if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET first_user_message = $2 WHERE id = $1`,
+			sessionID, "The login page keeps redirecting back to itself"); err != nil {
+			t.Fatalf("set first message: %v", err)
+		}
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+		path := fmt.Sprintf("/api/v1/sessions/%s/generate-title", sessionID)
+
+		resp, err := client.Post(path, nil)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result api.GenerateSessionTitleResponse
+		testutil.ParseJSON(t, resp, &result)
+		if result.Title != "Fix OAuth Login Redirect Loop" || result.TokensUsed != 129 {
+			t.Errorf("response = %+v", result)
+		}
+
+		var stored string
+		if err := env.DB.QueryRow(env.Ctx, `SELECT ai_generated_title FROM sessions WHERE id = $1`, sessionID).Scan(&stored); err != nil {
+			t.Fatalf("query title: %v", err)
+		}
+		if stored != result.Title {
+			t.Errorf("stored title = %q, want %q", stored, result.Title)
+		}
+
+		quota, err := recapquota.GetOrCreate(env.Ctx, env.DB.Conn(), user.ID)
+		if err != nil {
+			t.Fatalf("get quota: %v", err)
+		}
+		if quota.ComputeCount != 1 {
+			t.Errorf("compute_count = %d, want 1", quota.ComputeCount)
+		}
+
+		resp, err = client.Post(path, nil)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusTooManyRequests)
+		if resp.Header.Get("Retry-After") == "" {
+			t.Error("expected a Retry-After header")
+		}
+	})
+
+	t.Run("rejects non-owners and untitleable sessions", func(t *testing.T) {
+		env.CleanDB(t)
+
+		owner := testutil.CreateTestUser(t, env, "owner@test.com", "Owner")
+		other := testutil.CreateTestUser(t, env, "other@test.com", "Other")
+		ownerToken := testutil.CreateTestWebSessionWithToken(t, env, owner.ID)
+		otherToken := testutil.CreateTestWebSessionWithToken(t, env, other.ID)
+		sessionID := testutil.CreateTestSession(t, env, owner.ID, "empty-session")
+		path := fmt.Sprintf("/api/v1/sessions/%s/generate-title", sessionID)
+
+		ts := setupTestServerWithEnv(t, env)
+
+		resp, err := testutil.NewTestClient(t, ts).WithSession(otherToken).Post(path, nil)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusForbidden)
+
+		resp, err = testutil.NewTestClient(t, ts).WithSession(ownerToken).Post(path, nil)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
+}
+
+// TestCustomTitle_SurvivesRecapRegeneration_HTTP_Integration sets a custom
+// title, regenerates the Smart Recap, and checks the recap only moved
+// suggested_session_title while the resolved title stayed the user's. It also
+// covers PATCH /sessions/{id}/title refusing to write the machine titles.
+func TestCustomTitle_SurvivesRecapRegeneration_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(anthropic.MessagesResponse{
+			ID:         "msg_recap",
+			Type:       "message",
+			Role:       "assistant",
+			StopReason: "end_turn",
+			Content: []anthropic.ContentBlock{{
+				Type: "text",
+				Text: `"suggested_session_title": "Regenerated Title", "recap": "Regenerated recap.", "went_well": [], "went_bad": [], "human_suggestions": [], "environment_suggestions": [], "default_context_suggestions": []}`,
+			}},
+			Usage: anthropic.Usage{InputTokens: 200, OutputTokens: 80},
+		})
+	}))
+	defer mockServer.Close()
+
+	os.Setenv("SMART_RECAP_ENABLED", "true")
+	os.Setenv("ANTHROPIC_API_KEY", "test-key")
+	os.Setenv("SMART_RECAP_MODEL", "test-model")
+	os.Setenv("SMART_RECAP_QUOTA_LIMIT", "20")
+	os.Setenv("TEST_SMART_RECAP_BASE_URL", mockServer.URL)
+	defer func() {
+		os.Unsetenv("SMART_RECAP_ENABLED")
+		os.Unsetenv("ANTHROPIC_API_KEY")
+		os.Unsetenv("SMART_RECAP_MODEL")
+		os.Unsetenv("SMART_RECAP_QUOTA_LIMIT")
+		os.Unsetenv("TEST_SMART_RECAP_BASE_URL")
+	}()
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "custom-title@test.com", "Custom Title User")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "custom-title-session")
+	jsonlContent := `{"type":"assistant","message":{"id":"msg_1","type":"message","model":"claude-sonnet-4","role":"assistant","content":[{"type":"text","text":"Hello!"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50}},"uuid":"a1","timestamp":"2025-01-01T00:00:01Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+`
+	testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "custom-title-session", "transcript.jsonl", 1, 1, []byte(jsonlContent))
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 1)
+	if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET suggested_session_title = $2 WHERE id = $1`,
+		sessionID, "Original Suggestion"); err != nil {
+		t.Fatalf("set suggested title: %v", err)
+	}
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+	titlePath := fmt.Sprintf("/api/v1/sessions/%s/title", sessionID)
+
+	for _, body := range []map[string]interface{}{
+		{"suggested_session_title": "Sneaky"},
+		{"custom_title": "Mine", "ai_generated_title": "Sneaky"},
+	} {
+		resp, err := client.Patch(titlePath, body)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	}
+
+	resp, err := client.Patch(titlePath, map[string]interface{}{"custom_title": "My Chosen Title"})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	testutil.RequireStatus(t, resp, http.StatusOK)
+
+	resp, err = client.Post(fmt.Sprintf("/api/v1/sessions/%s/analytics/smart-recap/regenerate", sessionID), nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	testutil.RequireStatus(t, resp, http.StatusOK)
+
+	resp, err = client.Get(fmt.Sprintf("/api/v1/sessions/%s", sessionID))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	testutil.RequireStatus(t, resp, http.StatusOK)
+	var detail db.SessionDetail
+	testutil.ParseJSON(t, resp, &detail)
+
+	if detail.Title != "My Chosen Title" {
+		t.Errorf("title = %q, want the custom title", detail.Title)
+	}
+	if detail.CustomTitle == nil || *detail.CustomTitle != "My Chosen Title" {
+		t.Errorf("custom_title = %v, want unchanged", detail.CustomTitle)
+	}
+	if detail.SuggestedSessionTitle == nil || *detail.SuggestedSessionTitle != "Regenerated Title" {
+		t.Errorf("suggested_session_title = %v, want the regenerated suggestion", detail.SuggestedSessionTitle)
+	}
+	if detail.AIGeneratedTitle != nil {
+		t.Errorf("ai_generated_title = %q, want unset", *detail.AIGeneratedTitle)
+	}
+}
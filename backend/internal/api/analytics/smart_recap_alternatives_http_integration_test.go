@@ -0,0 +1,219 @@
+package analytics_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/anthropic"
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/recapquota"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestSmartRecapAlternatives_HTTP_Integration covers GET
+// /sessions/{id}/cards/smart-recap/alternatives: generation with the style's
+// prompt and a quota charge, the 24-hour cache, the per-session daily limit,
+// failed generations not counting, and owner-only access.
+func TestSmartRecapAlternatives_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	var (
+		mu      sync.Mutex
+		systems []string
+		failing bool
+	)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req anthropic.MessagesRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		systems = append(systems, req.System)
+		fail := failing
+		mu.Unlock()
+		if fail {
+			http.Error(w, `{"type":"error","error":{"type":"overloaded_error","message":"overloaded"}}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(anthropic.MessagesResponse{
+			ID:         "msg_alt",
+			Type:       "message",
+			Role:       "assistant",
+			StopReason: "end_turn",
+			Content: []anthropic.ContentBlock{{
+				Type: "text",
+				Text: `"suggested_session_title": "Ignored", "recap": "- Fixed the redirect loop\n- Added a test", "went_well": [], "went_bad": [], "human_suggestions": [], "environment_suggestions": [], "default_context_suggestions": []}`,
+			}},
+			Usage: anthropic.Usage{InputTokens: 200, OutputTokens: 80},
+		})
+	}))
+	defer mockServer.Close()
+	calls := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(systems)
+	}
+	systemPrompt := func(i int) string {
+		mu.Lock()
+		defer mu.Unlock()
+		return systems[i]
+	}
+
+	os.Setenv("SMART_RECAP_ENABLED", "true")
+	os.Setenv("ANTHROPIC_API_KEY", "test-key")
+	os.Setenv("SMART_RECAP_MODEL", "test-model")
+	os.Setenv("SMART_RECAP_QUOTA_LIMIT", "20")
+	os.Setenv("TEST_SMART_RECAP_BASE_URL", mockServer.URL)
+	defer func() {
+		os.Unsetenv("SMART_RECAP_ENABLED")
+		os.Unsetenv("ANTHROPIC_API_KEY")
+		os.Unsetenv("SMART_RECAP_MODEL")
+		os.Unsetenv("SMART_RECAP_QUOTA_LIMIT")
+		os.Unsetenv("TEST_SMART_RECAP_BASE_URL")
+	}()
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	owner := testutil.CreateTestUser(t, env, "alt-owner@test.com", "Owner")
+	other := testutil.CreateTestUser(t, env, "alt-other@test.com", "Other")
+	ownerToken := testutil.CreateTestWebSessionWithToken(t, env, owner.ID)
+	otherToken := testutil.CreateTestWebSessionWithToken(t, env, other.ID)
+	sessionID := testutil.CreateTestSession(t, env, owner.ID, "alt-session")
+	jsonlContent := `{"type":"assistant","message":{"id":"msg_1","type":"message","model":"claude-sonnet-4","role":"assistant","content":[{"type":"text","text":"Hello!"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50}},"uuid":"a1","timestamp":"2025-01-01T00:00:01Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+`
+	testutil.UploadTestChunk(t, env, owner.ID, models.ProviderClaudeCode, "alt-session", "transcript.jsonl", 1, 1, []byte(jsonlContent))
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 1)
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(ownerToken)
+	path := fmt.Sprintf("/api/v1/sessions/%s/cards/smart-recap/alternatives", sessionID)
+
+	get := func(t *testing.T, c *testutil.TestClient, query string, wantStatus int) *http.Response {
+		t.Helper()
+		resp, err := c.Get(path + query)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		testutil.RequireStatus(t, resp, wantStatus)
+		return resp
+	}
+	alternative := func(t *testing.T, style string) api.SmartRecapAlternativeResponse {
+		t.Helper()
+		var result api.SmartRecapAlternativeResponse
+		testutil.ParseJSON(t, get(t, client, "?style="+style, http.StatusOK), &result)
+		return result
+	}
+	computeCount := func(t *testing.T) int {
+		t.Helper()
+		quota, err := recapquota.GetOrCreate(env.Ctx, env.DB.Conn(), owner.ID)
+		if err != nil {
+			t.Fatalf("get quota: %v", err)
+		}
+		return quota.ComputeCount
+	}
+
+	t.Run("rejects bad styles and non-owners", func(t *testing.T) {
+		get(t, client, "", http.StatusBadRequest)
+		get(t, client, "?style=haiku", http.StatusBadRequest)
+		get(t, testutil.NewTestClient(t, ts).WithSession(otherToken), "?style=bullet", http.StatusForbidden)
+		if n := calls(); n != 0 {
+			t.Errorf("made %d LLM calls, want 0", n)
+		}
+	})
+
+	t.Run("generates then serves from cache", func(t *testing.T) {
+		result := alternative(t, "Bullet")
+		want := api.SmartRecapAlternativeResponse{Style: "bullet", Recap: "- Fixed the redirect loop\n- Added a test", TokensUsed: 280}
+		if result != want {
+			t.Errorf("response = %+v, want %+v", result, want)
+		}
+		if calls() != 1 || !strings.Contains(systemPrompt(0), "Recap style: bullet.") {
+			t.Errorf("LLM calls = %d, want one with the bullet style prompt", calls())
+		}
+		if n := computeCount(t); n != 1 {
+			t.Errorf("compute_count = %d, want 1", n)
+		}
+
+		result = alternative(t, "bullet")
+		want.Cached, want.TokensUsed = true, 0
+		if result != want {
+			t.Errorf("cached response = %+v, want %+v", result, want)
+		}
+		if n := calls(); n != 1 {
+			t.Errorf("LLM calls = %d after a cached read, want 1", n)
+		}
+		if n := computeCount(t); n != 1 {
+			t.Errorf("compute_count = %d after a cached read, want 1", n)
+		}
+	})
+
+	t.Run("failed generation does not count", func(t *testing.T) {
+		mu.Lock()
+		failing = true
+		mu.Unlock()
+		get(t, client, "?style=prose", http.StatusInternalServerError)
+		mu.Lock()
+		failing = false
+		mu.Unlock()
+
+		var rows int
+		if err := env.DB.QueryRow(env.Ctx, `SELECT COUNT(*) FROM session_recap_alternatives WHERE session_id = $1 AND style = 'prose'`, sessionID).Scan(&rows); err != nil {
+			t.Fatalf("count rows: %v", err)
+		}
+		if rows != 0 {
+			t.Errorf("failed generation left %d rows", rows)
+		}
+		if n := computeCount(t); n != 1 {
+			t.Errorf("compute_count = %d after a failure, want 1", n)
+		}
+	})
+
+	t.Run("limits generations per session per day", func(t *testing.T) {
+		if result := alternative(t, "prose"); result.Cached {
+			t.Error("prose variant was cached before it was generated")
+		}
+		if result := alternative(t, "technical"); result.Cached {
+			t.Error("technical variant was cached before it was generated")
+		}
+
+		// Let the bullet variant expire after the other two were generated:
+		// three generations fall in the window, so regenerating it is refused.
+		if _, err := env.DB.Exec(env.Ctx, `
+			UPDATE session_recap_alternatives SET created_at = NOW() - INTERVAL '25 hours'
+			WHERE session_id = $1 AND style = 'bullet'`, sessionID); err != nil {
+			t.Fatalf("expire bullet variant: %v", err)
+		}
+		if _, err := env.DB.Exec(env.Ctx, `
+			INSERT INTO session_recap_alternatives (session_id, style, recap_text, created_at)
+			VALUES ($1, 'retired', 'old', NOW() - INTERVAL '2 hours')`, sessionID); err != nil {
+			t.Fatalf("insert earlier variant: %v", err)
+		}
+		resp := get(t, client, "?style=bullet", http.StatusTooManyRequests)
+		if resp.Header.Get("Retry-After") == "" {
+			t.Error("expected a Retry-After header")
+		}
+
+		// Once the earliest generation leaves the window the style regenerates.
+		if _, err := env.DB.Exec(env.Ctx, `
+			UPDATE session_recap_alternatives SET created_at = NOW() - INTERVAL '25 hours'
+			WHERE session_id = $1 AND style = 'retired'`, sessionID); err != nil {
+			t.Fatalf("expire earlier variant: %v", err)
+		}
+		if result := alternative(t, "bullet"); result.Cached {
+			t.Error("expired bullet variant was served from cache")
+		}
+		if n := computeCount(t); n != 4 {
+			t.Errorf("compute_count = %d, want 4", n)
+		}
+	})
+}
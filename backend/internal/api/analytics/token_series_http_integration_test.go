@@ -0,0 +1,80 @@
+package analytics_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Token Series HTTP Integration Tests
+//
+// GET /api/v1/sessions/{id}/tokens/series
+// =============================================================================
+
+func TestGetTokenSeries_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+
+	jsonlContent := `{"type":"user","message":{"role":"user","content":"hello"},"uuid":"u1","timestamp":"2025-01-01T00:00:00Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"id":"msg_1","type":"message","model":"claude-sonnet-4-20241022","role":"assistant","content":[{"type":"text","text":"Hi"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50,"cache_read_input_tokens":30}},"uuid":"a1","timestamp":"2025-01-01T00:00:02Z","parentUuid":"u1","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"user","message":{"role":"user","content":"thanks"},"uuid":"u2","timestamp":"2025-01-01T00:00:10Z","parentUuid":"a1","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"id":"msg_2","type":"message","model":"claude-sonnet-4-20241022","role":"assistant","content":[{"type":"text","text":"Bye!"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":200,"output_tokens":7}},"uuid":"a2","timestamp":"2025-01-01T00:00:11Z","parentUuid":"u2","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+`
+
+	env.CleanDB(t)
+	user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "test-session")
+	testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "test-session", "transcript.jsonl", 1, 4, []byte(jsonlContent))
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 4)
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+	getSeries := func(t *testing.T) analytics.TokenSeries {
+		t.Helper()
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/tokens/series", sessionID))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result analytics.TokenSeries
+		testutil.ParseJSON(t, resp, &result)
+		return result
+	}
+
+	if before := getSeries(t); len(before.Points) != 0 {
+		t.Errorf("expected no points before cards are computed, got %+v", before)
+	}
+
+	resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/analytics", sessionID))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	testutil.RequireStatus(t, resp, http.StatusOK)
+	var card analytics.AnalyticsResponse
+	testutil.ParseJSON(t, resp, &card)
+
+	series := getSeries(t)
+	if len(series.Points) != 4 || series.IntervalLines != 1 || series.TotalLines != 4 {
+		t.Fatalf("series = %+v, want one point per line", series)
+	}
+	if p := series.Points[1]; p.InputTokens != 100 || p.OutputTokens != 50 || p.CacheReadTokens != 30 {
+		t.Errorf("point at line 2 = %+v, want 100/50 with 30 cache read", p)
+	}
+	last := series.Points[3]
+	if last.InputTokens != card.Tokens.Input || last.OutputTokens != card.Tokens.Output ||
+		last.CacheCreationTokens != card.Tokens.CacheCreation || last.CacheReadTokens != card.Tokens.CacheRead {
+		t.Errorf("final point = %+v, want tokens card %+v", last, card.Tokens)
+	}
+}
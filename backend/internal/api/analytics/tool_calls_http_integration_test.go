@@ -0,0 +1,142 @@
+package analytics_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Tool Calls HTTP Integration Tests
+//
+// GET /api/v1/sessions/{id}/tool-calls
+// =============================================================================
+
+func TestListToolCalls_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+
+	// Five calls, one replayed by context replay, one failed, one unanswered.
+	jsonlContent := `{"type":"user","message":{"role":"user","content":"fix it"},"uuid":"u1","timestamp":"2025-01-01T00:00:00Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"id":"msg_1","type":"message","model":"claude-sonnet-4-20241022","role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/test/main.go"}},{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"go test ./..."}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50}},"uuid":"a1","timestamp":"2025-01-01T00:00:01Z","parentUuid":"u1","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"package main"},{"type":"tool_result","tool_use_id":"t2","content":"FAIL","is_error":true}]},"uuid":"u2","timestamp":"2025-01-01T00:00:02Z","parentUuid":"a1","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"id":"msg_1","type":"message","model":"claude-sonnet-4-20241022","role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/test/main.go"}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50}},"uuid":"a2","timestamp":"2025-01-01T00:00:03Z","parentUuid":"u2","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"assistant","message":{"id":"msg_2","type":"message","model":"claude-sonnet-4-20241022","role":"assistant","content":[{"type":"tool_use","id":"t3","name":"Edit","input":{"file_path":"/test/main.go","old_string":"a","new_string":"b"}},{"type":"tool_use","id":"t4","name":"Bash","input":{"command":"go test ./..."}},{"type":"tool_use","id":"t5","name":"Grep","input":{"pattern":"TODO"}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50}},"uuid":"a3","timestamp":"2025-01-01T00:00:04Z","parentUuid":"a2","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t3","content":"ok"},{"type":"tool_result","tool_use_id":"t4","content":"ok"}]},"uuid":"u3","timestamp":"2025-01-01T00:00:05Z","parentUuid":"a3","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"test","version":"1.0"}
+`
+
+	env.CleanDB(t)
+	user := testutil.CreateTestUser(t, env, "tools@example.com", "Tools User")
+	other := testutil.CreateTestUser(t, env, "other@example.com", "Other User")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+	otherToken := testutil.CreateTestWebSessionWithToken(t, env, other.ID)
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "tools-session")
+	testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "tools-session", "transcript.jsonl", 1, 6, []byte(jsonlContent))
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 6)
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+	path := fmt.Sprintf("/api/v1/sessions/%s/tool-calls", sessionID)
+
+	list := func(t *testing.T, query string) api.ToolCallsResponse {
+		t.Helper()
+		resp, err := client.Get(path + query)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result api.ToolCallsResponse
+		testutil.ParseJSON(t, resp, &result)
+		return result
+	}
+
+	t.Run("matches the tools card", func(t *testing.T) {
+		resp, err := client.Get(fmt.Sprintf("/api/v1/sessions/%s/analytics", sessionID))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var body struct {
+			Cards struct {
+				Tools analytics.ToolsCardData `json:"tools"`
+			} `json:"cards"`
+		}
+		testutil.ParseJSON(t, resp, &body)
+
+		page := list(t, "")
+		if page.TotalCalls != body.Cards.Tools.TotalCalls || len(page.ToolCalls) != body.Cards.Tools.TotalCalls {
+			t.Fatalf("listed %d of %d calls, tools card total_calls = %d",
+				len(page.ToolCalls), page.TotalCalls, body.Cards.Tools.TotalCalls)
+		}
+
+		var names, statuses []string
+		for _, c := range page.ToolCalls {
+			names = append(names, c.Name)
+			statuses = append(statuses, c.Status)
+		}
+		if fmt.Sprint(names) != "[Read Bash Edit Bash Grep]" || fmt.Sprint(statuses) != "[success error success success pending]" {
+			t.Errorf("calls = %v %v", names, statuses)
+		}
+		if c := page.ToolCalls[2]; c.LineNumber != 5 || c.Arguments["new_string"] != "b" {
+			t.Errorf("Edit call = %+v, want line 5 with its arguments", c)
+		}
+	})
+
+	t.Run("pages by call index", func(t *testing.T) {
+		var got []analytics.ToolCall
+		query := "?limit=2"
+		for range 5 {
+			page := list(t, query)
+			got = append(got, page.ToolCalls...)
+			if !page.HasMore {
+				break
+			}
+			query = "?limit=2&cursor=" + page.NextCursor
+		}
+		all := list(t, "")
+		a, _ := json.Marshal(got)
+		b, _ := json.Marshal(all.ToolCalls)
+		if string(a) != string(b) {
+			t.Errorf("paged calls %s, want %s", a, b)
+		}
+		if past := list(t, "?cursor=10"); len(past.ToolCalls) != 0 || past.HasMore {
+			t.Errorf("page past the end = %+v, want empty", past)
+		}
+	})
+
+	t.Run("redacts arguments", func(t *testing.T) {
+		page := list(t, "?redact=true&limit=1")
+		if got := page.ToolCalls[0].Arguments["file_path"]; got != "/****/****.**" {
+			t.Errorf("redacted file_path = %v", got)
+		}
+	})
+
+	t.Run("rejects bad parameters and non-owners", func(t *testing.T) {
+		for _, query := range []string{"?limit=0", "?limit=5000", "?cursor=x", "?redact=maybe"} {
+			resp, err := client.Get(path + query)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			testutil.RequireStatus(t, resp, http.StatusBadRequest)
+		}
+
+		resp, err := testutil.NewTestClient(t, ts).WithSession(otherToken).Get(path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusForbidden)
+	})
+}
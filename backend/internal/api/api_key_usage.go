@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbauth"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// apiKeyUsageFlushInterval is how often counted chunk uploads are written to
+// api_key_usage. A crash loses at most this much usage.
+const apiKeyUsageFlushInterval = 30 * time.Second
+
+// apiKeyUsageDays is the length of the daily series in key usage.
+const apiKeyUsageDays = 30
+
+type apiKeyUsageKey struct {
+	keyID int64
+	day   time.Time
+}
+
+// APIKeyUsageRecorder counts chunk uploads per API key and UTC day in memory
+// and adds them to api_key_usage in one statement per flush, so uploads never
+// wait on a usage write. Flush failures are logged and the counts kept for
+// the next flush. A nil *APIKeyUsageRecorder records nothing.
+type APIKeyUsageRecorder struct {
+	store *dbauth.Store
+	now   func() time.Time
+
+	mu      sync.Mutex
+	pending map[apiKeyUsageKey]db.APIKeyUsageCounts
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewAPIKeyUsageRecorder returns a recorder writing to database every
+// interval until Close.
+func NewAPIKeyUsageRecorder(database *db.DB, interval time.Duration) *APIKeyUsageRecorder {
+	r := &APIKeyUsageRecorder{
+		store:   &dbauth.Store{DB: database},
+		now:     time.Now,
+		pending: make(map[apiKeyUsageKey]db.APIKeyUsageCounts),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go r.run(interval)
+	return r
+}
+
+// Record counts one stored chunk of lines and bytes for keyID.
+func (r *APIKeyUsageRecorder) Record(keyID int64, lines, bytes int) {
+	if r == nil {
+		return
+	}
+	k := apiKeyUsageKey{keyID: keyID, day: r.now().UTC().Truncate(24 * time.Hour)}
+	r.mu.Lock()
+	c := r.pending[k]
+	c.Requests++
+	c.Lines += int64(lines)
+	c.Bytes += int64(bytes)
+	r.pending[k] = c
+	r.mu.Unlock()
+}
+
+func (r *APIKeyUsageRecorder) run(interval time.Duration) {
+	defer close(r.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), DatabaseTimeout)
+			r.flush(ctx)
+			cancel()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// flush writes the pending counts. On failure they are merged back so the
+// next flush retries them.
+func (r *APIKeyUsageRecorder) flush(ctx context.Context) error {
+	r.mu.Lock()
+	batch := r.pending
+	r.pending = make(map[apiKeyUsageKey]db.APIKeyUsageCounts)
+	r.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	deltas := make([]db.APIKeyUsageDelta, 0, len(batch))
+	for k, c := range batch {
+		deltas = append(deltas, db.APIKeyUsageDelta{APIKeyID: k.keyID, Day: k.day, APIKeyUsageCounts: c})
+	}
+	err := r.store.AddAPIKeyUsage(ctx, deltas)
+	if err == nil {
+		return nil
+	}
+	logger.Warn("Failed to flush API key usage", "error", err, "deltas", len(deltas))
+
+	r.mu.Lock()
+	for k, c := range batch {
+		p := r.pending[k]
+		p.Requests += c.Requests
+		p.Lines += c.Lines
+		p.Bytes += c.Bytes
+		r.pending[k] = p
+	}
+	r.mu.Unlock()
+	return err
+}
+
+// Close stops the periodic flush and writes what is still pending. Call it
+// after the HTTP server has shut down, so no upload is counted afterwards.
+func (r *APIKeyUsageRecorder) Close(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	r.closeOnce.Do(func() { close(r.stop) })
+	<-r.done
+	return r.flush(ctx)
+}
+
+// HandleGetAPIKeyUsage returns what one of the user's API keys did: sessions
+// it created, chunk upload totals, and a 30-day daily series.
+// GET /api/v1/keys/{id}/usage
+//
+// Usage is flushed every 30 seconds, so the latest uploads may be missing.
+func HandleGetAPIKeyUsage(database *db.DB) http.HandlerFunc {
+	authStore := &dbauth.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		keyID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || keyID <= 0 {
+			respondError(w, http.StatusBadRequest, "Invalid key ID")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		usage, err := authStore.GetAPIKeyUsage(ctx, userID, keyID, apiKeyUsageDays)
+		if err != nil {
+			if errors.Is(err, db.ErrAPIKeyNotFound) {
+				respondError(w, http.StatusNotFound, "API key not found")
+				return
+			}
+			log.Error("Failed to get API key usage", "error", err, "key_id", keyID)
+			respondError(w, http.StatusInternalServerError, "Failed to get API key usage")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, usage)
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+func TestAPIKeyUsageRecorder_AggregatesByKeyAndDay(t *testing.T) {
+	now := time.Date(2026, 10, 15, 23, 59, 0, 0, time.UTC)
+	r := &APIKeyUsageRecorder{
+		now:     func() time.Time { return now },
+		pending: make(map[apiKeyUsageKey]db.APIKeyUsageCounts),
+	}
+
+	r.Record(1, 3, 100)
+	r.Record(1, 2, 50)
+	r.Record(2, 1, 10)
+	now = now.Add(2 * time.Minute) // next UTC day
+	r.Record(1, 4, 40)
+
+	day1 := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	want := map[apiKeyUsageKey]db.APIKeyUsageCounts{
+		{keyID: 1, day: day1}: {Requests: 2, Lines: 5, Bytes: 150},
+		{keyID: 2, day: day1}: {Requests: 1, Lines: 1, Bytes: 10},
+		{keyID: 1, day: day2}: {Requests: 1, Lines: 4, Bytes: 40},
+	}
+	if len(r.pending) != len(want) {
+		t.Fatalf("pending has %d entries, want %d: %v", len(r.pending), len(want), r.pending)
+	}
+	for k, c := range want {
+		if r.pending[k] != c {
+			t.Errorf("pending[%d, %s] = %+v, want %+v", k.keyID, k.day.Format("2006-01-02"), r.pending[k], c)
+		}
+	}
+}
+
+func TestAPIKeyUsageRecorder_NilIsNoop(t *testing.T) {
+	var r *APIKeyUsageRecorder
+	r.Record(1, 1, 1)
+	if err := r.Close(context.Background()); err != nil {
+		t.Errorf("Close on nil recorder = %v, want nil", err)
+	}
+}
@@ -51,6 +51,14 @@ type Options struct {
 // TestEnvironment supplies the DB and storage handles.
 func NewServer(t *testing.T, env *testutil.TestEnvironment, opts Options) *testutil.TestServer {
 	t.Helper()
+	ts, _ := NewServerWithAPI(t, env, opts)
+	return ts
+}
+
+// NewServerWithAPI is NewServer that also returns the *api.Server, for tests
+// that drive its lifecycle (e.g. Close flushing buffered usage).
+func NewServerWithAPI(t *testing.T, env *testutil.TestEnvironment, opts Options) (*testutil.TestServer, *api.Server) {
+	t.Helper()
 
 	testutil.SetEnvForTest(t, "CSRF_SECRET_KEY", DefaultCSRFSecret)
 	testutil.SetEnvForTest(t, "ALLOWED_ORIGINS", "http://localhost:3000")
@@ -84,5 +92,5 @@ func NewServer(t *testing.T, env *testutil.TestEnvironment, opts Options) *testu
 	}
 
 	srv := api.NewServer(env.DB, env.Storage, &cfg, nil, api.BuildInfo{})
-	return testutil.StartTestServer(t, env, srv.SetupRoutes())
+	return testutil.StartTestServer(t, env, srv.SetupRoutes()), srv
 }
@@ -0,0 +1,164 @@
+package auth_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/api/apitest"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestAPIKeyUsage_HTTP_Integration syncs a different session with each of two
+// keys and checks that sessions and chunk uploads are attributed to the key
+// that made them, and that buffered counts are written when the server closes.
+func TestAPIKeyUsage_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "usage@example.com", "Usage")
+	laptop := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Laptop")
+	ci := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "CI")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+
+	ts, srv := apitest.NewServerWithAPI(t, env, apitest.Options{})
+	web := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+	sync := func(rawToken, externalID string, chunks ...[]string) {
+		t.Helper()
+		client := testutil.NewTestClient(t, ts).WithAPIKey(rawToken)
+		resp, err := client.Post("/api/v1/sync/init", api.SyncInitRequest{
+			ExternalID:     externalID,
+			TranscriptPath: "/home/user/project/transcript.jsonl",
+		})
+		if err != nil {
+			t.Fatalf("sync/init failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var initResp api.SyncInitResponse
+		testutil.ParseJSON(t, resp, &initResp)
+
+		firstLine := 1
+		for _, lines := range chunks {
+			resp, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+				SessionID: initResp.SessionID,
+				FileName:  "transcript.jsonl",
+				FileType:  "transcript",
+				FirstLine: firstLine,
+				Lines:     lines,
+			})
+			if err != nil {
+				t.Fatalf("sync/chunk failed: %v", err)
+			}
+			testutil.RequireStatus(t, resp, http.StatusOK)
+			resp.Body.Close()
+			firstLine += len(lines)
+		}
+	}
+
+	sync(laptop.RawToken, "usage-laptop",
+		[]string{`{"type":"user","message":"one"}`, `{"type":"assistant","message":"two"}`, `{"type":"user","message":"three"}`},
+		[]string{`{"type":"assistant","message":"four"}`, `{"type":"user","message":"five"}`},
+	)
+	sync(ci.RawToken, "usage-ci", []string{`{"type":"user","message":"ci"}`})
+
+	var rows int
+	if err := env.DB.QueryRow(env.Ctx, `SELECT COUNT(*) FROM api_key_usage`).Scan(&rows); err != nil {
+		t.Fatalf("count api_key_usage: %v", err)
+	}
+	if rows != 0 {
+		t.Fatalf("expected usage to stay buffered until a flush, found %d rows", rows)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	getUsage := func(keyID int64) db.APIKeyUsage {
+		t.Helper()
+		resp, err := web.Get(fmt.Sprintf("/api/v1/keys/%d/usage", keyID))
+		if err != nil {
+			t.Fatalf("get usage failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var usage db.APIKeyUsage
+		testutil.ParseJSON(t, resp, &usage)
+		return usage
+	}
+
+	laptopUsage := getUsage(laptop.ID)
+	ciUsage := getUsage(ci.ID)
+
+	t.Run("sessions are attributed to the creating key", func(t *testing.T) {
+		if laptopUsage.CreatedSessionCount != 1 || ciUsage.CreatedSessionCount != 1 {
+			t.Errorf("created_session_count = %d (laptop), %d (ci), want 1 each",
+				laptopUsage.CreatedSessionCount, ciUsage.CreatedSessionCount)
+		}
+	})
+
+	t.Run("uploads are counted per key", func(t *testing.T) {
+		if laptopUsage.Totals.Requests != 2 || laptopUsage.Totals.Lines != 5 {
+			t.Errorf("laptop totals = %+v, want 2 requests and 5 lines", laptopUsage.Totals)
+		}
+		if ciUsage.Totals.Requests != 1 || ciUsage.Totals.Lines != 1 {
+			t.Errorf("ci totals = %+v, want 1 request and 1 line", ciUsage.Totals)
+		}
+		if ciUsage.Totals.Bytes <= 0 || laptopUsage.Totals.Bytes <= ciUsage.Totals.Bytes {
+			t.Errorf("bytes = %d (laptop), %d (ci), want both positive and laptop larger",
+				laptopUsage.Totals.Bytes, ciUsage.Totals.Bytes)
+		}
+	})
+
+	t.Run("daily series covers 30 days ending today", func(t *testing.T) {
+		if len(laptopUsage.Daily) != 30 {
+			t.Fatalf("daily has %d entries, want 30", len(laptopUsage.Daily))
+		}
+		today := laptopUsage.Daily[len(laptopUsage.Daily)-1]
+		if today.Date != time.Now().UTC().Format("2006-01-02") || today.APIKeyUsageCounts != laptopUsage.Totals {
+			t.Errorf("last day = %+v, want today with all of %+v", today, laptopUsage.Totals)
+		}
+	})
+
+	t.Run("key listing includes created session counts", func(t *testing.T) {
+		resp, err := web.Get("/api/v1/keys")
+		if err != nil {
+			t.Fatalf("list keys failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var keys []models.APIKey
+		testutil.ParseJSON(t, resp, &keys)
+		for _, k := range keys {
+			if k.CreatedSessionCount != 1 {
+				t.Errorf("key %q created_session_count = %d, want 1", k.Name, k.CreatedSessionCount)
+			}
+		}
+	})
+
+	t.Run("another user's key is not found", func(t *testing.T) {
+		other := testutil.CreateTestUser(t, env, "other@example.com", "Other")
+		otherKey := testutil.CreateTestAPIKeyWithToken(t, env, other.ID, "Other Key")
+
+		resp, err := web.Get(fmt.Sprintf("/api/v1/keys/%d/usage", otherKey.ID))
+		if err != nil {
+			t.Fatalf("get usage failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package auth_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbdataexport"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// POST/GET /api/v1/me/export - full-account data export
+// =============================================================================
+
+func TestDataExport_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+
+	t.Run("no export yet returns 404", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "export@example.com", "Export User")
+		client := testutil.NewTestClient(t, setupUserTestServer(t, env)).
+			WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+
+		resp, err := client.Get("/api/v1/me/export")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusNotFound)
+	})
+
+	t.Run("allows one export per day", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "export@example.com", "Export User")
+		client := testutil.NewTestClient(t, setupUserTestServer(t, env)).
+			WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+
+		resp, err := client.Post("/api/v1/me/export", nil)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusAccepted)
+		var queued api.DataExportResponse
+		testutil.ParseJSON(t, resp, &queued)
+		resp.Body.Close()
+		if queued.Status != dbdataexport.StatusPending || queued.DownloadURL != "" {
+			t.Errorf("queued export = %+v, want pending without a download URL", queued)
+		}
+
+		resp, err = client.Post("/api/v1/me/export", nil)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusTooManyRequests)
+		retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+		if err != nil || retryAfter <= 0 || retryAfter > 24*60*60 {
+			t.Errorf("Retry-After = %q, want seconds within a day", resp.Header.Get("Retry-After"))
+		}
+
+		resp, err = client.Get("/api/v1/me/export")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var latest api.DataExportResponse
+		testutil.ParseJSON(t, resp, &latest)
+		resp.Body.Close()
+		if latest.ID != queued.ID || latest.Status != dbdataexport.StatusPending {
+			t.Errorf("latest export = %+v, want the queued one still pending", latest)
+		}
+
+		// A day later the user may ask again.
+		if _, err := env.DB.Exec(env.Ctx,
+			`UPDATE user_data_exports SET requested_at = NOW() - INTERVAL '25 hours' WHERE id = $1`, queued.ID); err != nil {
+			t.Fatalf("backdate export: %v", err)
+		}
+		resp, err = client.Post("/api/v1/me/export", nil)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusAccepted)
+	})
+
+	t.Run("failed export doesn't count against the limit", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "export@example.com", "Export User")
+		client := testutil.NewTestClient(t, setupUserTestServer(t, env)).
+			WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+
+		resp, err := client.Post("/api/v1/me/export", nil)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var queued api.DataExportResponse
+		testutil.ParseJSON(t, resp, &queued)
+		resp.Body.Close()
+
+		if err := (&dbdataexport.Store{DB: env.DB}).MarkFailed(env.Ctx, queued.ID, "boom"); err != nil {
+			t.Fatalf("MarkFailed: %v", err)
+		}
+		resp, err = client.Post("/api/v1/me/export", nil)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusAccepted)
+	})
+
+	t.Run("ready export carries a download URL", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "export@example.com", "Export User")
+		client := testutil.NewTestClient(t, setupUserTestServer(t, env)).
+			WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+
+		exportStore := &dbdataexport.Store{DB: env.DB}
+		result, err := exportStore.Request(env.Ctx, user.ID, api.DataExportInterval)
+		if err != nil {
+			t.Fatalf("Request: %v", err)
+		}
+		if err := exportStore.MarkReady(env.Ctx, result.Export.ID, "exports/test.zip", 123, 0,
+			result.Export.RequestedAt.Add(api.DataExportInterval)); err != nil {
+			t.Fatalf("MarkReady: %v", err)
+		}
+
+		resp, err := client.Get("/api/v1/me/export")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var ready api.DataExportResponse
+		testutil.ParseJSON(t, resp, &ready)
+		resp.Body.Close()
+		if ready.Status != dbdataexport.StatusReady || ready.DownloadURL == "" {
+			t.Errorf("ready export = %+v, want a download URL", ready)
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package auth_test
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Idempotency-Key HTTP Integration Tests
+//
+// POST /api/v1/sessions/{id}/share and POST /api/v1/me/webhooks opt in to
+// Idempotency-Key replay.
+// =============================================================================
+
+type idempotentResult struct {
+	status   int
+	replayed bool
+	body     string
+}
+
+// postWithKey sends body with an Idempotency-Key header (none when key is
+// empty) and returns the response without failing the test, so it can run
+// from several goroutines.
+func postWithKey(client *testutil.TestClient, path string, body interface{}, key string) (idempotentResult, error) {
+	headers := map[string]string{}
+	if key != "" {
+		headers[api.IdempotencyKeyHeader] = key
+	}
+	resp, err := client.RequestWithHeaders(http.MethodPost, path, body, headers)
+	if err != nil {
+		return idempotentResult{}, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return idempotentResult{}, err
+	}
+	return idempotentResult{
+		status:   resp.StatusCode,
+		replayed: resp.Header.Get(api.IdempotentReplayedHeader) == "true",
+		body:     string(b),
+	}, nil
+}
+
+// raceWithKey sends n identical requests with one key at once.
+func raceWithKey(t *testing.T, client *testutil.TestClient, path string, body interface{}, key string, n int) []idempotentResult {
+	t.Helper()
+	results := make([]idempotentResult, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = postWithKey(client, path, body, key)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+	}
+	return results
+}
+
+// requireOneWinner checks that exactly one raced request ran (status
+// success) and every other one either replayed its response or was told the
+// key was in progress.
+func requireOneWinner(t *testing.T, results []idempotentResult, success int) idempotentResult {
+	t.Helper()
+	var winner *idempotentResult
+	for i, r := range results {
+		switch {
+		case r.status == success && !r.replayed:
+			if winner != nil {
+				t.Fatalf("two requests ran: %+v", results)
+			}
+			winner = &results[i]
+		case r.status == success && r.replayed:
+		case r.status == http.StatusConflict:
+		default:
+			t.Fatalf("unexpected response %+v", r)
+		}
+	}
+	if winner == nil {
+		t.Fatalf("no request ran: %+v", results)
+	}
+	for _, r := range results {
+		if r.replayed && r.body != winner.body {
+			t.Errorf("replayed body %s, want %s", r.body, winner.body)
+		}
+	}
+	return *winner
+}
+
+func countRows(t *testing.T, env *testutil.TestEnvironment, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := env.DB.QueryRow(env.Ctx, query, args...).Scan(&n); err != nil {
+		t.Fatalf("count query failed: %v", err)
+	}
+	return n
+}
+
+func TestIdempotencyKey_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+
+	t.Run("racing share creations create one share", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "owner@example.com", "Owner")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "idem-share")
+
+		ts := setupSharesTestServer(t, env)
+		client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+		path := "/api/v1/sessions/" + sessionID + "/share"
+		body := api.CreateShareRequest{IsPublic: true}
+
+		winner := requireOneWinner(t, raceWithKey(t, client, path, body, "share-key-1", 2), http.StatusOK)
+		if n := countRows(t, env, "SELECT COUNT(*) FROM session_shares WHERE session_id = $1", sessionID); n != 1 {
+			t.Fatalf("shares after race = %d, want 1", n)
+		}
+
+		// Once settled, a retry replays the stored response.
+		replay, err := postWithKey(client, path, body, "share-key-1")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if replay.status != http.StatusOK || !replay.replayed || replay.body != winner.body {
+			t.Errorf("replay = %+v, want the first response %s", replay, winner.body)
+		}
+
+		// The same key with another body is rejected, not replayed.
+		other, err := postWithKey(client, path, api.CreateShareRequest{IsPublic: true, SkipNotifications: true}, "share-key-1")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if other.status != http.StatusConflict {
+			t.Errorf("reused key with a different body: status %d, want 409", other.status)
+		}
+
+		// A new key, or no key, runs the request again.
+		for _, key := range []string{"share-key-2", "", ""} {
+			r, err := postWithKey(client, path, body, key)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if r.status != http.StatusOK || r.replayed {
+				t.Errorf("key %q: %+v, want a fresh 200", key, r)
+			}
+		}
+		if n := countRows(t, env, "SELECT COUNT(*) FROM session_shares WHERE session_id = $1", sessionID); n != 4 {
+			t.Errorf("shares = %d, want 4", n)
+		}
+	})
+
+	t.Run("racing webhook registrations create one endpoint", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "hooks@example.com", "Hooks")
+		other := testutil.CreateTestUser(t, env, "other@example.com", "Other")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+		otherToken := testutil.CreateTestWebSessionWithToken(t, env, other.ID)
+
+		ts := setupSharesTestServer(t, env)
+		client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+		body := api.CreateWebhookRequest{URL: "https://hooks.example.com/confab"}
+
+		requireOneWinner(t, raceWithKey(t, client, "/api/v1/me/webhooks", body, "hook-key", 4), http.StatusCreated)
+		if n := countRows(t, env, "SELECT COUNT(*) FROM webhook_endpoints WHERE user_id = $1", user.ID); n != 1 {
+			t.Fatalf("webhook endpoints after race = %d, want 1", n)
+		}
+
+		// Keys are per user: another user's identical request is its own.
+		r, err := postWithKey(testutil.NewTestClient(t, ts).WithSession(otherToken), "/api/v1/me/webhooks", body, "hook-key")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if r.status != http.StatusCreated || r.replayed {
+			t.Errorf("other user's request = %+v, want a fresh 201", r)
+		}
+	})
+
+	t.Run("rejects an overlong key", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "long@example.com", "Long")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+
+		ts := setupSharesTestServer(t, env)
+		client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+		long := make([]byte, api.MaxIdempotencyKeyLength+1)
+		for i := range long {
+			long[i] = 'k'
+		}
+		r, err := postWithKey(client, "/api/v1/me/webhooks", api.CreateWebhookRequest{URL: "https://hooks.example.com/confab"}, string(long))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if r.status != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", r.status)
+		}
+	})
+}
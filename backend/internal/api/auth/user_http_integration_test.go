@@ -4,8 +4,12 @@ import (
 	"github.com/ConfabulousDev/confab-web/internal/api"
 	"net/http"
 	"os"
+	"reflect"
 	"testing"
 
+	"github.com/ConfabulousDev/confab-web/internal/db/dbwebhook"
+	dbuser "github.com/ConfabulousDev/confab-web/internal/db/user"
+	"github.com/ConfabulousDev/confab-web/internal/notify"
 	"github.com/ConfabulousDev/confab-web/internal/testutil"
 )
 
@@ -210,3 +214,123 @@ func TestGetMe_HTTP_Integration(t *testing.T) {
 		testutil.RequireStatus(t, resp, http.StatusUnauthorized)
 	})
 }
+
+// =============================================================================
+// GET/PATCH /api/v1/me/preferences - Per-user preferences
+// =============================================================================
+
+func TestMyPreferences_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "prefs@example.com", "Prefs User")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+	ts := setupUserTestServer(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+	get := func(t *testing.T) dbuser.Preferences {
+		t.Helper()
+		resp, err := client.Get("/api/v1/me/preferences")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var prefs dbuser.Preferences
+		testutil.ParseJSON(t, resp, &prefs)
+		return prefs
+	}
+
+	// Responses list every notification type with its channels.
+	withChannels := func(p dbuser.Preferences) dbuser.Preferences {
+		p.Notifications = notify.ResolveChannels(p)
+		return p
+	}
+
+	t.Run("returns the defaults for a new user", func(t *testing.T) {
+		if got, want := get(t), withChannels(dbuser.DefaultPreferences()); !reflect.DeepEqual(got, want) {
+			t.Errorf("preferences = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("partial updates keep unspecified fields", func(t *testing.T) {
+		resp, err := client.Patch("/api/v1/me/preferences", map[string]any{"digest_frequency": "Weekly", "timezone": "Asia/Tokyo"})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		resp, err = client.Patch("/api/v1/me/preferences", map[string]any{"smart_recap_enabled": false})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		var patched dbuser.Preferences
+		testutil.ParseJSON(t, resp, &patched)
+		want := withChannels(dbuser.Preferences{SmartRecapEnabled: false, DigestFrequency: dbuser.DigestWeekly, NotifyOnRecap: false, Timezone: "Asia/Tokyo"})
+		if !reflect.DeepEqual(patched, want) {
+			t.Errorf("PATCH response = %+v, want %+v", patched, want)
+		}
+		if got := get(t); !reflect.DeepEqual(got, want) {
+			t.Errorf("GET after PATCH = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("sets notification channels and quiet hours", func(t *testing.T) {
+		resp, err := client.Patch("/api/v1/me/preferences", map[string]any{
+			"notifications": map[string]any{dbwebhook.EventAPIKeyVelocityExceeded: map[string]bool{"email": false, "webhook": true}},
+			"quiet_hours":   map[string]string{"start": "22:00", "end": "7:00"},
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		got := get(t)
+		if c := got.Notifications[dbwebhook.EventAPIKeyVelocityExceeded]; c.Email || !c.Webhook {
+			t.Errorf("channels = %+v, want webhook only", c)
+		}
+		if got.QuietHours == nil || *got.QuietHours != (dbuser.QuietHours{Start: "22:00", End: "07:00"}) {
+			t.Errorf("quiet_hours = %+v, want 22:00-07:00", got.QuietHours)
+		}
+	})
+
+	for name, body := range map[string]map[string]any{
+		"rejects an unknown digest frequency":   {"digest_frequency": "hourly"},
+		"rejects an unknown timezone":           {"timezone": "Nowhere/Special"},
+		"rejects an unknown notification type":  {"notifications": map[string]any{"no.such.type": map[string]bool{"email": true}}},
+		"rejects quiet hours that are not time": {"quiet_hours": map[string]string{"start": "late", "end": "07:00"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			before := get(t)
+			resp, err := client.Patch("/api/v1/me/preferences", body)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			testutil.RequireStatus(t, resp, http.StatusBadRequest)
+			if after := get(t); !reflect.DeepEqual(after, before) {
+				t.Errorf("rejected update changed preferences: %+v -> %+v", before, after)
+			}
+		})
+	}
+
+	t.Run("returns 401 for unauthenticated request", func(t *testing.T) {
+		resp, err := testutil.NewTestClient(t, ts).Get("/api/v1/me/preferences")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusUnauthorized)
+	})
+}
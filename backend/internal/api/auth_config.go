@@ -48,6 +48,14 @@ func (s *Server) handleAuthConfig(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
+	if s.emailLinkEnabled() {
+		providers = append(providers, providerInfo{
+			Name:        "email",
+			DisplayName: "Email link",
+			LoginURL:    "/api/v1/auth/email",
+		})
+	}
+
 	if s.oauthConfig.GitHubEnabled {
 		providers = append(providers, providerInfo{
 			Name:        "github",
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// DefaultCodeActivityFilesLimit is the number of files returned when no limit
+// is given. The maximum is analytics.MaxCodeActivityFiles, all a card stores.
+const DefaultCodeActivityFilesLimit = 50
+
+// parseCodeActivityFilesQuery parses the language, sort and limit query
+// parameters. sort defaults to writes_desc.
+func parseCodeActivityFilesQuery(r *http.Request) (analytics.CodeFilesQuery, error) {
+	q := r.URL.Query()
+	query := analytics.CodeFilesQuery{
+		Language: q.Get("language"),
+		Sort:     analytics.SortCodeFilesByWrites,
+		Limit:    DefaultCodeActivityFilesLimit,
+	}
+
+	if s := q.Get("sort"); s != "" {
+		if !analytics.IsValidCodeFilesSort(s) {
+			return query, errors.New("sort must be one of writes_desc, reads_desc, lines_desc, path_asc")
+		}
+		query.Sort = s
+	}
+	if s := q.Get("limit"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > analytics.MaxCodeActivityFiles {
+			return query, fmt.Errorf("limit must be between 1 and %d", analytics.MaxCodeActivityFiles)
+		}
+		query.Limit = v
+	}
+	return query, nil
+}
+
+// HandleListCodeActivityFiles returns the per-file breakdown of a session's
+// code activity card, filtered by language and sorted. Uses the same canonical
+// access model as HandleGetSessionAnalytics (CF-132).
+//
+// The files are written when the session's cards are computed (precompute
+// worker or an analytics fetch); until then, or for a card computed before
+// v4, the response has no files.
+func HandleListCodeActivityFiles(database *db.DB) http.HandlerFunc {
+	analyticsStore := analytics.NewStore(database.Conn())
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		query, perr := parseCodeActivityFilesQuery(r)
+		if perr != nil {
+			respondError(w, http.StatusBadRequest, perr.Error())
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		if result := RequireCanonicalRead(ctx, w, database, sessionID); result == nil {
+			return
+		}
+
+		record, err := analyticsStore.GetCodeActivityCard(ctx, sessionID)
+		if err != nil {
+			log.Error("Failed to get code activity card", "error", err, "session_id", sessionID)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to get code activity files")
+			return
+		}
+
+		data := analytics.CodeActivityFilesData{Files: []analytics.CodeFileStats{}}
+		if record != nil {
+			data.Files, data.TotalFiles = analytics.SelectCodeFiles(record.CodeFiles, query)
+		}
+		respondJSON(w, http.StatusOK, data)
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+)
+
+func TestParseCodeActivityFilesQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantErr  string
+		wantLang string
+		wantSort string
+		wantLim  int
+	}{
+		{name: "defaults", query: "", wantSort: analytics.SortCodeFilesByWrites, wantLim: DefaultCodeActivityFilesLimit},
+		{name: "all params", query: "language=go&sort=lines_desc&limit=20", wantLang: "go", wantSort: analytics.SortCodeFilesByLines, wantLim: 20},
+		{name: "max limit", query: "limit=500", wantSort: analytics.SortCodeFilesByWrites, wantLim: analytics.MaxCodeActivityFiles},
+		{name: "bad sort", query: "sort=size", wantErr: "sort must be one of"},
+		{name: "zero limit", query: "limit=0", wantErr: "limit must be between"},
+		{name: "limit too large", query: "limit=501", wantErr: "limit must be between"},
+		{name: "non-numeric limit", query: "limit=all", wantErr: "limit must be between"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/sessions/x/cards/code-activity/files?"+tt.query, nil)
+			q, err := parseCodeActivityFilesQuery(r)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if q.Language != tt.wantLang || q.Sort != tt.wantSort || q.Limit != tt.wantLim {
+				t.Errorf("query = %+v, want language %q sort %q limit %d", q, tt.wantLang, tt.wantSort, tt.wantLim)
+			}
+		})
+	}
+}
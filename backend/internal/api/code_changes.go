@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/features"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleGetCodeChanges returns the sampled Edit/MultiEdit diff hunks of a
+// session, for a "what changed" summary. Uses the same canonical access model
+// as HandleGetSessionAnalytics (CF-132).
+//
+// The card is written when the session's cards are computed (precompute worker
+// or an analytics fetch); until then the response has no hunks. While the card
+// is rolling out (analytics.CodeChangesCardFlag), an owner outside the flag
+// gets no hunks either.
+func HandleGetCodeChanges(database *db.DB) http.HandlerFunc {
+	analyticsStore := analytics.NewStore(database.Conn())
+	sessionStore := &dbsession.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		if result := RequireCanonicalRead(ctx, w, database, sessionID); result == nil {
+			return
+		}
+
+		record, err := analyticsStore.GetCodeChangesCard(ctx, sessionID)
+		if err != nil {
+			log.Error("Failed to get code changes card", "error", err, "session_id", sessionID)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to get code changes")
+			return
+		}
+
+		ownerID, _, _, err := sessionStore.GetSessionOwnerExternalIDAndProvider(ctx, sessionID)
+		if err != nil {
+			log.Error("Failed to get session owner", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to get code changes")
+			return
+		}
+		enabled, err := features.IsEnabled(ctx, database.Conn(), ownerID, analytics.CodeChangesCardFlag)
+		if err != nil {
+			log.Error("Failed to evaluate code changes feature flag", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to get code changes")
+			return
+		}
+
+		data := analytics.CodeChangesCardData{Hunks: []analytics.EditHunk{}}
+		if record != nil && enabled {
+			data.TotalHunks = record.TotalHunks
+			data.Hunks = record.Hunks
+		}
+		respondJSON(w, http.StatusOK, data)
+	}
+}
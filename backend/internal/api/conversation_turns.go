@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// Conversation turn pagination bounds.
+const (
+	DefaultConversationTurnsLimit = 100
+	MaxConversationTurnsLimit     = 1000
+)
+
+// ConversationTurnsResponse is one page of per-turn conversation detail.
+type ConversationTurnsResponse struct {
+	Turns      []analytics.ConversationTurn `json:"turns"`
+	HasMore    bool                         `json:"has_more"`
+	NextCursor string                       `json:"next_cursor,omitempty"`
+}
+
+// parseConversationTurnsQuery parses the role, has_tool_use, limit and cursor
+// query parameters. The cursor is the turn_index of the last turn on the
+// previous page; an absent cursor starts from the first turn (-1).
+func parseConversationTurnsQuery(r *http.Request) (filter analytics.ConversationTurnFilter, afterIndex, limit int, err error) {
+	q := r.URL.Query()
+	afterIndex = -1
+	limit = DefaultConversationTurnsLimit
+
+	if role := q.Get("role"); role != "" {
+		if role != analytics.TurnRoleUser && role != analytics.TurnRoleAssistant {
+			return filter, 0, 0, errors.New("role must be 'user' or 'assistant'")
+		}
+		filter.Role = &role
+	}
+	if s := q.Get("has_tool_use"); s != "" {
+		v, perr := strconv.ParseBool(s)
+		if perr != nil {
+			return filter, 0, 0, errors.New("has_tool_use must be true or false")
+		}
+		filter.HasToolUse = &v
+	}
+	if s := q.Get("limit"); s != "" {
+		v, perr := strconv.Atoi(s)
+		if perr != nil || v < 1 || v > MaxConversationTurnsLimit {
+			return filter, 0, 0, fmt.Errorf("limit must be between 1 and %d", MaxConversationTurnsLimit)
+		}
+		limit = v
+	}
+	if s := q.Get("cursor"); s != "" {
+		v, perr := strconv.Atoi(s)
+		if perr != nil || v < 0 {
+			return filter, 0, 0, errors.New("invalid cursor")
+		}
+		afterIndex = v
+	}
+	return filter, afterIndex, limit, nil
+}
+
+// HandleListConversationTurns returns per-turn conversation detail for a
+// session, filtered by role and tool use and paginated by turn index. Uses
+// the same canonical access model as HandleGetSessionAnalytics (CF-132).
+//
+// Turns are written when the session's cards are computed (precompute worker
+// or an analytics fetch); a session whose cards were never computed returns
+// an empty page.
+func HandleListConversationTurns(database *db.DB) http.HandlerFunc {
+	analyticsStore := analytics.NewStore(database.Conn())
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		filter, afterIndex, limit, perr := parseConversationTurnsQuery(r)
+		if perr != nil {
+			respondError(w, http.StatusBadRequest, perr.Error())
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		if result := RequireCanonicalRead(ctx, w, database, sessionID); result == nil {
+			return
+		}
+
+		// Fetch one extra row to detect whether another page exists.
+		turns, err := analyticsStore.ListConversationTurns(ctx, sessionID, filter, afterIndex, limit+1)
+		if err != nil {
+			log.Error("Failed to list conversation turns", "error", err, "session_id", sessionID)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to list conversation turns")
+			return
+		}
+
+		resp := ConversationTurnsResponse{Turns: turns}
+		if len(turns) > limit {
+			resp.Turns = turns[:limit]
+			resp.HasMore = true
+			resp.NextCursor = strconv.Itoa(resp.Turns[limit-1].TurnIndex)
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseConversationTurnsQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantErr   string
+		wantAfter int
+		wantLimit int
+		wantRole  string
+		wantTool  string
+	}{
+		{name: "defaults", query: "", wantAfter: -1, wantLimit: DefaultConversationTurnsLimit},
+		{name: "all params", query: "role=assistant&has_tool_use=true&limit=5&cursor=41", wantAfter: 41, wantLimit: 5, wantRole: "assistant", wantTool: "true"},
+		{name: "tool use false", query: "has_tool_use=false", wantAfter: -1, wantLimit: DefaultConversationTurnsLimit, wantTool: "false"},
+		{name: "max limit", query: "limit=1000", wantAfter: -1, wantLimit: MaxConversationTurnsLimit},
+		{name: "bad role", query: "role=system", wantErr: "role must be"},
+		{name: "bad has_tool_use", query: "has_tool_use=maybe", wantErr: "has_tool_use must be"},
+		{name: "zero limit", query: "limit=0", wantErr: "limit must be between"},
+		{name: "limit too large", query: "limit=1001", wantErr: "limit must be between"},
+		{name: "negative cursor", query: "cursor=-1", wantErr: "invalid cursor"},
+		{name: "non-numeric cursor", query: "cursor=abc", wantErr: "invalid cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/sessions/x/cards/conversation/turns?"+tt.query, nil)
+			filter, after, limit, err := parseConversationTurnsQuery(r)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if after != tt.wantAfter || limit != tt.wantLimit {
+				t.Errorf("after, limit = %d, %d, want %d, %d", after, limit, tt.wantAfter, tt.wantLimit)
+			}
+			gotRole := ""
+			if filter.Role != nil {
+				gotRole = *filter.Role
+			}
+			if gotRole != tt.wantRole {
+				t.Errorf("role = %q, want %q", gotRole, tt.wantRole)
+			}
+			gotTool := ""
+			if filter.HasToolUse != nil {
+				if *filter.HasToolUse {
+					gotTool = "true"
+				} else {
+					gotTool = "false"
+				}
+			}
+			if gotTool != tt.wantTool {
+				t.Errorf("has_tool_use = %q, want %q", gotTool, tt.wantTool)
+			}
+		})
+	}
+}
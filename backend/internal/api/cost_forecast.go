@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// costForecastCacheTTL is how long a user's forecast is served from memory.
+// The tokens cards behind it only change when the precompute worker runs.
+const costForecastCacheTTL = 10 * time.Minute
+
+// tz_offset bounds: real UTC offsets run from UTC+14 to UTC-12.
+const (
+	minTZOffsetMinutes = -14 * 60
+	maxTZOffsetMinutes = 12 * 60
+)
+
+type costForecastKey struct {
+	userID   int64
+	tzOffset int
+}
+
+type costForecastEntry struct {
+	forecast  *analytics.CostForecast
+	fetchedAt time.Time
+}
+
+// costForecastCache holds each user's forecast per timezone for
+// costForecastCacheTTL. Expired entries are swept on write.
+type costForecastCache struct {
+	mu      sync.Mutex
+	entries map[costForecastKey]costForecastEntry
+	now     func() time.Time
+}
+
+func newCostForecastCache() *costForecastCache {
+	return &costForecastCache{entries: map[costForecastKey]costForecastEntry{}, now: time.Now}
+}
+
+func (c *costForecastCache) get(key costForecastKey) *analytics.CostForecast {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || c.now().Sub(entry.fetchedAt) >= costForecastCacheTTL {
+		return nil
+	}
+	return entry.forecast
+}
+
+func (c *costForecastCache) put(key costForecastKey, forecast *analytics.CostForecast) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for k, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) >= costForecastCacheTTL {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = costForecastEntry{forecast: forecast, fetchedAt: now}
+}
+
+// parseTZOffset parses the optional tz_offset query parameter (minutes, JS
+// getTimezoneOffset convention: positive = behind UTC). Default 0 (UTC).
+func parseTZOffset(r *http.Request) (int, bool) {
+	s := r.URL.Query().Get("tz_offset")
+	if s == "" {
+		return 0, true
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < minTZOffsetMinutes || v > maxTZOffsetMinutes {
+		return 0, false
+	}
+	return v, true
+}
+
+// HandleGetCostForecast returns the authenticated user's estimated spend for
+// the current calendar month and a month-end projection at the trailing
+// 7-day rate, for the dashboard's "at this pace" figure. Only the user's own
+// sessions count.
+//
+// Query parameters:
+//   - tz_offset: Client timezone offset in minutes (from JS getTimezoneOffset(); positive=behind UTC)
+//
+// Responses are cached per user and timezone for costForecastCacheTTL, so a
+// session priced by the precompute worker can take up to that long to show.
+func HandleGetCostForecast(database *db.DB) http.HandlerFunc {
+	analyticsStore := analytics.NewStore(database.Conn())
+	cache := newCostForecastCache()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		tzOffset, ok := parseTZOffset(r)
+		if !ok {
+			respondError(w, http.StatusBadRequest, "Invalid tz_offset")
+			return
+		}
+
+		key := costForecastKey{userID: userID, tzOffset: tzOffset}
+		if forecast := cache.get(key); forecast != nil {
+			respondJSON(w, http.StatusOK, forecast)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		forecast, err := analyticsStore.GetCostForecast(ctx, userID, time.Now(), tzOffset)
+		if err != nil {
+			log.Error("Failed to compute cost forecast", "error", err, "user_id", userID)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to compute cost forecast")
+			return
+		}
+
+		cache.put(key, forecast)
+		respondJSON(w, http.StatusOK, forecast)
+	}
+}
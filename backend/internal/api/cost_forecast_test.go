@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+)
+
+func TestParseTZOffset(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   int
+		wantOK bool
+	}{
+		{"", 0, true},
+		{"tz_offset=240", 240, true},
+		{"tz_offset=-330", -330, true},
+		{"tz_offset=-840", -840, true},
+		{"tz_offset=720", 720, true},
+		{"tz_offset=721", 0, false},
+		{"tz_offset=-841", 0, false},
+		{"tz_offset=abc", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/analytics/forecast?"+tt.query, nil)
+			got, ok := parseTZOffset(r)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseTZOffset = %d, %v; want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCostForecastCache(t *testing.T) {
+	now := time.Date(2026, time.October, 15, 12, 0, 0, 0, time.UTC)
+	cache := newCostForecastCache()
+	cache.now = func() time.Time { return now }
+
+	key := costForecastKey{userID: 1, tzOffset: 240}
+	forecast := &analytics.CostForecast{Month: "2026-10"}
+	cache.put(key, forecast)
+
+	if got := cache.get(key); got != forecast {
+		t.Errorf("fresh entry: got %v, want cached forecast", got)
+	}
+	if got := cache.get(costForecastKey{userID: 1, tzOffset: 0}); got != nil {
+		t.Errorf("other timezone: got %v, want miss", got)
+	}
+	if got := cache.get(costForecastKey{userID: 2, tzOffset: 240}); got != nil {
+		t.Errorf("other user: got %v, want miss", got)
+	}
+
+	now = now.Add(costForecastCacheTTL)
+	if got := cache.get(key); got != nil {
+		t.Errorf("expired entry: got %v, want miss", got)
+	}
+
+	cache.put(costForecastKey{userID: 2}, forecast)
+	if _, ok := cache.entries[key]; ok {
+		t.Error("expired entry not swept on write")
+	}
+}
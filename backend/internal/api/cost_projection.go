@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleGetCostProjection returns a session's projected final cost, for users
+// watching a long session against their budget. Uses the same canonical access
+// model as HandleGetSessionAnalytics (CF-132).
+//
+// The projection is written when the session's cards are computed (precompute
+// worker or an analytics fetch). "projection" is null when the cards were never
+// computed, the session has fewer than analytics.MinCostProjectionLines lines,
+// or the owner has no earlier sessions to estimate a length from.
+func HandleGetCostProjection(database *db.DB) http.HandlerFunc {
+	analyticsStore := analytics.NewStore(database.Conn())
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		if result := RequireCanonicalRead(ctx, w, database, sessionID); result == nil {
+			return
+		}
+
+		record, err := analyticsStore.GetCostProjection(ctx, sessionID)
+		if err != nil {
+			log.Error("Failed to get cost projection", "error", err, "session_id", sessionID)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to get cost projection")
+			return
+		}
+		if record == nil {
+			record = &analytics.CostProjectionRecord{}
+		}
+		respondJSON(w, http.StatusOK, record)
+	}
+}
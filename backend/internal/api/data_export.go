@@ -0,0 +1,264 @@
+package api
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbdataexport"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	dbuser "github.com/ConfabulousDev/confab-web/internal/db/user"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/storage"
+)
+
+// DataExportInterval is how often a user may request a full data export.
+const DataExportInterval = 24 * time.Hour
+
+// DataExportResponse is the JSON shape of a full-account data export.
+type DataExportResponse struct {
+	ID           int64      `json:"id"`
+	Status       string     `json:"status"`
+	RequestedAt  time.Time  `json:"requested_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	SizeBytes    *int64     `json:"size_bytes,omitempty"`
+	SessionCount *int       `json:"session_count,omitempty"`
+	// DownloadURL is a presigned archive URL, set while the export is ready.
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+func newDataExportResponse(e *dbdataexport.Export) *DataExportResponse {
+	return &DataExportResponse{
+		ID:           e.ID,
+		Status:       e.Status,
+		RequestedAt:  e.RequestedAt,
+		CompletedAt:  e.CompletedAt,
+		ExpiresAt:    e.ExpiresAt,
+		SizeBytes:    e.SizeBytes,
+		SessionCount: e.SessionCount,
+	}
+}
+
+// dataExportAccount is the account.json entry of a data export.
+type dataExportAccount struct {
+	User         *models.User `json:"user"`
+	SessionCount int          `json:"session_count"`
+	ExportedAt   time.Time    `json:"exported_at"`
+}
+
+// handleRequestDataExport queues a full-account data export for the worker.
+// POST /api/v1/me/export
+//
+// Answers 202 with the queued export, or 429 with Retry-After when the user
+// already requested one within DataExportInterval (failed exports don't count).
+func (s *Server) handleRequestDataExport(w http.ResponseWriter, r *http.Request) {
+	log := logger.Ctx(r.Context())
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	result, err := (&dbdataexport.Store{DB: s.db}).Request(ctx, userID, DataExportInterval)
+	if err != nil {
+		log.Error("Failed to request data export", "error", err)
+		respondError(w, http.StatusInternalServerError, "Failed to request data export")
+		return
+	}
+	if !result.Created {
+		retryAfter := time.Until(result.Export.RequestedAt.Add(DataExportInterval))
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		respondErrorCode(w, http.StatusTooManyRequests, httputil.CodeTooManyRequests,
+			"A data export was requested within the last 24 hours")
+		return
+	}
+
+	log.Info("Data export requested", "export_id", result.Export.ID)
+	respondJSON(w, http.StatusAccepted, newDataExportResponse(result.Export))
+}
+
+// handleGetDataExport returns the caller's most recent data export, with a
+// fresh presigned download URL while it is ready.
+// GET /api/v1/me/export
+func (s *Server) handleGetDataExport(w http.ResponseWriter, r *http.Request) {
+	log := logger.Ctx(r.Context())
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	export, err := (&dbdataexport.Store{DB: s.db}).Latest(ctx, userID)
+	if err != nil {
+		log.Error("Failed to get data export", "error", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get data export")
+		return
+	}
+	if export == nil {
+		respondErrorCode(w, http.StatusNotFound, httputil.CodeNotFound, "No data export requested")
+		return
+	}
+
+	resp := newDataExportResponse(export)
+	if export.Status == dbdataexport.StatusReady && export.ObjectKey != nil && export.ExpiresAt != nil {
+		if ttl := time.Until(*export.ExpiresAt); ttl > 0 {
+			resp.DownloadURL, err = s.storage.PresignDataExport(ctx, *export.ObjectKey, DataExportFilename(export), ttl)
+			if err != nil {
+				log.Error("Failed to presign data export", "error", err, "export_id", export.ID)
+				respondStorageError(w, err, "Failed to create download link")
+				return
+			}
+		}
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// DataExportFilename is the download filename of an export archive, e.g.
+// "confabulous-export-2026-03-01.zip".
+func DataExportFilename(e *dbdataexport.Export) string {
+	return "confabulous-export-" + e.RequestedAt.UTC().Format("2006-01-02") + ".zip"
+}
+
+// WriteUserDataExport writes a zip of everything userID owns to w and
+// returns how many sessions it holds. The archive has an account.json with
+// the user row, and one sessions/<date>-<title>-<id>/ directory per session
+// holding the same entries as a zip session download (transcript, agents/,
+// metadata.json) plus analytics.json with the cached cards. Every synced
+// file is included; sessions whose transcripts were archived by retention
+// keep their metadata and cards only. Files are downloaded and written one at
+// a time, so only one file's chunks are held in memory.
+func WriteUserDataExport(ctx context.Context, database *db.DB, store *storage.S3Storage, userID int64, w io.Writer) (int, error) {
+	user, err := (&dbuser.Store{DB: database}).GetUserByID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user: %w", err)
+	}
+	sessionIDs, err := (&dbuser.Store{DB: database}).GetUserSessionIDs(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	sessionStore := &dbsession.Store{DB: database}
+	analyticsStore := analytics.NewStore(database.Conn())
+	exportedAt := time.Now().UTC()
+	zw := zip.NewWriter(w)
+
+	count := 0
+	for _, sessionID := range sessionIDs {
+		session, err := sessionStore.GetSessionDetail(ctx, sessionID, userID)
+		if errors.Is(err, db.ErrSessionNotFound) {
+			continue // deleted since the listing
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to get session %s: %w", sessionID, err)
+		}
+		if err := writeSessionExport(ctx, zw, store, analyticsStore, session, userID, exportedAt); err != nil {
+			return 0, fmt.Errorf("failed to export session %s: %w", sessionID, err)
+		}
+		count++
+	}
+
+	err = writeZipEntry(zw, "account.json", exportedAt, func(entry io.Writer) error {
+		enc := json.NewEncoder(entry)
+		enc.SetIndent("", "  ")
+		return enc.Encode(dataExportAccount{User: user, SessionCount: count, ExportedAt: exportedAt})
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// writeSessionExport adds one session's directory to a data export.
+func writeSessionExport(ctx context.Context, zw *zip.Writer, store *storage.S3Storage, analyticsStore *analytics.Store, session *db.SessionDetail, userID int64, exportedAt time.Time) error {
+	dir := "sessions/" + sessionDownloadStem(session) + "-" + session.ID[:min(8, len(session.ID))] + "/"
+	modified := session.FirstSeen
+	if session.LastSyncAt != nil {
+		modified = *session.LastSyncAt
+	}
+	writeJSON := func(name string, v any) error {
+		return writeZipEntry(zw, dir+name, modified, func(entry io.Writer) error {
+			enc := json.NewEncoder(entry)
+			enc.SetIndent("", "  ")
+			return enc.Encode(v)
+		})
+	}
+
+	metadata := sessionDownloadMetadata{Session: session, ExportedAt: exportedAt}
+	smartCard, err := analyticsStore.GetSmartRecapCard(ctx, session.ID)
+	if err != nil {
+		return err
+	}
+	if smartCard != nil && smartCard.HasValidVersion() {
+		metadata.SmartRecap = convertSmartRecap(smartCard)
+	}
+	if err := writeJSON("metadata.json", metadata); err != nil {
+		return err
+	}
+
+	cards, err := analyticsStore.GetCards(ctx, session.ID)
+	if err != nil {
+		return err
+	}
+	if resp := cards.ToResponse(); len(resp.Cards) > 0 {
+		if err := writeJSON("analytics.json", resp); err != nil {
+			return err
+		}
+	}
+
+	if session.TranscriptArchivedAt != nil {
+		return nil
+	}
+	used := make(map[string]bool)
+	for _, f := range session.Files {
+		sub := ""
+		if f.FileType == "agent" {
+			sub = "agents/"
+		}
+		name := zipEntryName(dir+sub, f.FileName, used)
+
+		listCtx, listCancel := context.WithTimeout(ctx, StorageTimeout)
+		keys, err := store.ListChunks(listCtx, userID, session.Provider, session.ExternalID, f.FileName)
+		listCancel()
+		if err != nil {
+			return fmt.Errorf("failed to list chunks of %s: %w", f.FileName, err)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		downloadCtx, downloadCancel := context.WithTimeout(ctx, chunkDownloadTimeout(len(keys)))
+		chunks, err := store.DownloadChunks(downloadCtx, keys)
+		downloadCancel()
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", f.FileName, err)
+		}
+		err = writeZipEntry(zw, name, modified, func(entry io.Writer) error {
+			return storage.WriteMergedChunks(entry, chunks)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
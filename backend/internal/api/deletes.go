@@ -2,8 +2,11 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/ConfabulousDev/confab-web/internal/db"
@@ -94,3 +97,146 @@ func HandleDeleteSession(database *db.DB, store *storage.S3Storage) http.Handler
 		})
 	}
 }
+
+// MaxBulkDeleteSessionIDs caps the explicit ID list accepted by bulk delete.
+const MaxBulkDeleteSessionIDs = 1000
+
+// bulkDeleteBatchSize is how many sessions each bulk-delete batch selects and
+// removes. Each batch is its own short DB statement so a large delete never
+// holds one long transaction open.
+const bulkDeleteBatchSize = 100
+
+// BulkDeleteSessionsRequest is the request body for POST /api/v1/sessions/bulk-delete.
+// At least one filter criterion is required; criteria are ANDed.
+type BulkDeleteSessionsRequest struct {
+	SessionIDs []string   `json:"session_ids,omitempty"`
+	OlderThan  *time.Time `json:"older_than,omitempty"` // RFC3339; matches last activity strictly before this
+	Tag        *string    `json:"tag,omitempty"`        // only "demo" (sessions seeded by POST /api/v1/me/demo)
+	DryRun     bool       `json:"dry_run"`
+}
+
+// filter validates the request and converts it to a db.BulkDeleteFilter.
+func (req BulkDeleteSessionsRequest) filter() (db.BulkDeleteFilter, error) {
+	if req.Tag != nil && *req.Tag != DemoSessionTag {
+		return db.BulkDeleteFilter{}, fmt.Errorf("tag filter is not supported for %q: the only session tag is %q", *req.Tag, DemoSessionTag)
+	}
+	if len(req.SessionIDs) > MaxBulkDeleteSessionIDs {
+		return db.BulkDeleteFilter{}, fmt.Errorf("session_ids exceeds maximum of %d entries", MaxBulkDeleteSessionIDs)
+	}
+	f := db.BulkDeleteFilter{SessionIDs: req.SessionIDs, OlderThan: req.OlderThan, DemoOnly: req.Tag != nil}
+	if f.IsEmpty() {
+		return db.BulkDeleteFilter{}, errors.New("at least one of session_ids, older_than or tag is required")
+	}
+	return f, nil
+}
+
+// HandleBulkDeleteSessions deletes every session owned by the caller that
+// matches the request filter, cascading S3 chunks and DB rows exactly like
+// HandleDeleteSession. With dry_run it only reports how many would go.
+func HandleBulkDeleteSessions(database *db.DB, store *storage.S3Storage) http.HandlerFunc {
+	sessionStore := &dbsession.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		var req BulkDeleteSessionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		filter, err := req.filter()
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if req.DryRun {
+			ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+			defer cancel()
+
+			count, err := sessionStore.CountBulkDeleteTargets(ctx, userID, filter)
+			if err != nil {
+				log.Error("Failed to count bulk delete targets", "error", err)
+				respondError(w, http.StatusInternalServerError, "Failed to count sessions")
+				return
+			}
+			respondJSON(w, http.StatusOK, map[string]interface{}{
+				"dry_run":       true,
+				"matched_count": count,
+				"deleted_count": 0,
+			})
+			return
+		}
+
+		deleted, err := deleteMatchingSessions(r.Context(), sessionStore, store, userID, filter)
+		if err != nil {
+			log.Error("Failed to bulk delete sessions", "error", err, "deleted_so_far", deleted)
+			respondError(w, http.StatusInternalServerError, "Failed to delete sessions")
+			return
+		}
+
+		// Audit log: bulk delete completed
+		log.Info("Sessions bulk deleted", "deleted_count", deleted)
+
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"dry_run":       false,
+			"matched_count": deleted,
+			"deleted_count": deleted,
+		})
+	}
+}
+
+// deleteMatchingSessions deletes every session of userID matching filter in
+// batches of bulkDeleteBatchSize: S3 chunks first (failures are logged and
+// skipped, as in HandleDeleteSession), then the DB rows. It returns how many
+// sessions were deleted, including those deleted before an error.
+func deleteMatchingSessions(ctx context.Context, sessionStore *dbsession.Store, store *storage.S3Storage, userID int64, filter db.BulkDeleteFilter) (int64, error) {
+	log := logger.Ctx(ctx)
+
+	var deleted int64
+	afterID := ""
+	for {
+		listCtx, listCancel := context.WithTimeout(ctx, DatabaseTimeout)
+		batch, err := sessionStore.ListBulkDeleteTargets(listCtx, userID, filter, afterID, bulkDeleteBatchSize)
+		listCancel()
+		if err != nil {
+			return deleted, err
+		}
+		if len(batch) == 0 {
+			return deleted, nil
+		}
+
+		// Step 1: Delete S3 chunks per session (errors logged, like single delete)
+		ids := make([]string, len(batch))
+		for i, target := range batch {
+			ids[i] = target.ID
+			storageCtx, storageCancel := context.WithTimeout(ctx, StorageTimeout)
+			if err := store.DeleteAllSessionChunks(storageCtx, userID, target.Provider, target.ExternalID); err != nil {
+				log.Error("Failed to delete session chunks",
+					"error", err,
+					"session_id", target.ID,
+					"external_id", target.ExternalID)
+			}
+			storageCancel()
+		}
+
+		// Step 2: Delete the batch from the database (CASCADE)
+		dbCtx, dbCancel := context.WithTimeout(ctx, DatabaseTimeout)
+		n, err := sessionStore.DeleteSessionsFromDB(dbCtx, userID, ids)
+		dbCancel()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += n
+		afterID = ids[len(ids)-1]
+
+		if len(batch) < bulkDeleteBatchSize {
+			return deleted, nil
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBulkDeleteSessionsRequest_Filter(t *testing.T) {
+	cutoff := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tag := "scratch"
+	demoTag := DemoSessionTag
+
+	tooMany := make([]string, MaxBulkDeleteSessionIDs+1)
+	for i := range tooMany {
+		tooMany[i] = "id"
+	}
+
+	tests := []struct {
+		name    string
+		req     BulkDeleteSessionsRequest
+		wantErr string
+	}{
+		{"empty filter rejected", BulkDeleteSessionsRequest{}, "at least one of"},
+		{"dry run alone rejected", BulkDeleteSessionsRequest{DryRun: true}, "at least one of"},
+		{"empty id list rejected", BulkDeleteSessionsRequest{SessionIDs: []string{}}, "at least one of"},
+		{"tag rejected", BulkDeleteSessionsRequest{Tag: &tag, OlderThan: &cutoff}, "tag filter is not supported"},
+		{"demo tag accepted", BulkDeleteSessionsRequest{Tag: &demoTag}, ""},
+		{"too many ids rejected", BulkDeleteSessionsRequest{SessionIDs: tooMany}, "exceeds maximum"},
+		{"ids accepted", BulkDeleteSessionsRequest{SessionIDs: []string{"a", "b"}}, ""},
+		{"older_than accepted", BulkDeleteSessionsRequest{OlderThan: &cutoff}, ""},
+		{"combined accepted", BulkDeleteSessionsRequest{SessionIDs: []string{"a"}, OlderThan: &cutoff}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := tt.req.filter()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("filter() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("filter() unexpected error: %v", err)
+			}
+			if f.IsEmpty() {
+				t.Error("expected non-empty filter")
+			}
+			if len(f.SessionIDs) != len(tt.req.SessionIDs) {
+				t.Errorf("SessionIDs len = %d, want %d", len(f.SessionIDs), len(tt.req.SessionIDs))
+			}
+			if (f.OlderThan == nil) != (tt.req.OlderThan == nil) {
+				t.Errorf("OlderThan = %v, want %v", f.OlderThan, tt.req.OlderThan)
+			}
+			if f.DemoOnly != (tt.req.Tag != nil) {
+				t.Errorf("DemoOnly = %v, want %v", f.DemoOnly, tt.req.Tag != nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/demodata"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// DemoSessionTag is the bulk-delete tag that selects demo sessions.
+const DemoSessionTag = "demo"
+
+// DemoSessionsResponse is the response for POST and DELETE /api/v1/me/demo.
+type DemoSessionsResponse struct {
+	// SessionIDs lists the demo sessions (POST only).
+	SessionIDs   []string `json:"session_ids,omitempty"`
+	DeletedCount int64    `json:"deleted_count"`
+}
+
+// handleSeedDemoSessions creates the bundled demo sessions for the caller.
+// POST /api/v1/me/demo
+//
+// Each file is uploaded through handleSyncChunk, so demo sessions get cards,
+// recaps and search like synced ones. Seeding again re-uploads only files
+// that are missing (e.g. after a failed seed).
+func (s *Server) handleSeedDemoSessions(w http.ResponseWriter, r *http.Request) {
+	log := logger.Ctx(r.Context())
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := demodata.Sessions(time.Now())
+	if err != nil {
+		log.Error("Failed to load demo sessions", "error", err)
+		respondError(w, http.StatusInternalServerError, "Failed to create demo sessions")
+		return
+	}
+
+	sessionStore := &dbsession.Store{DB: s.db}
+	ids := make([]string, 0, len(sessions))
+	for _, demo := range sessions {
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		sessionID, synced, err := sessionStore.CreateDemoSession(ctx, userID, dbsession.DemoSessionParams{
+			ExternalID:     demo.ExternalID,
+			TranscriptPath: demo.TranscriptPath,
+			CWD:            demo.CWD,
+		})
+		cancel()
+		if err != nil {
+			if errors.Is(err, dbsession.ErrNotDemoSession) {
+				respondError(w, http.StatusConflict, fmt.Sprintf("A session with external_id %q already exists", demo.ExternalID))
+				return
+			}
+			log.Error("Failed to create demo session", "error", err, "external_id", demo.ExternalID)
+			respondError(w, http.StatusInternalServerError, "Failed to create demo sessions")
+			return
+		}
+
+		for _, f := range demo.Files {
+			if _, ok := synced[f.Name]; ok {
+				continue
+			}
+			if err := s.uploadDemoFile(r, sessionID, demo.Summary, f); err != nil {
+				log.Error("Failed to upload demo file", "error", err, "session_id", sessionID, "file_name", f.Name)
+				respondError(w, http.StatusInternalServerError, "Failed to create demo sessions")
+				return
+			}
+		}
+		ids = append(ids, sessionID)
+	}
+
+	log.Info("Demo sessions seeded", "session_count", len(ids))
+	respondJSON(w, http.StatusOK, DemoSessionsResponse{SessionIDs: ids})
+}
+
+// uploadDemoFile sends a demo file as a single chunk through the sync/chunk
+// handler, with the caller's authenticated request context. The transcript
+// chunk carries summary as its metadata.
+func (s *Server) uploadDemoFile(r *http.Request, sessionID, summary string, f demodata.File) error {
+	chunk := SyncChunkRequest{
+		SessionID: sessionID,
+		FileName:  f.Name,
+		FileType:  f.Type,
+		FirstLine: 1,
+		Lines:     f.Lines,
+	}
+	if f.Type == "transcript" {
+		chunk.Metadata = &SyncChunkMetadata{Summary: &summary}
+	}
+	body, err := json.Marshal(chunk)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, "/api/v1/sync/chunk", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	rec := httptest.NewRecorder()
+	s.handleSyncChunk(rec, req)
+	if rec.Code != http.StatusOK {
+		return fmt.Errorf("sync/chunk returned %d: %s", rec.Code, bytes.TrimSpace(rec.Body.Bytes()))
+	}
+	return nil
+}
+
+// handleDeleteDemoSessions deletes the caller's demo sessions the way
+// POST /api/v1/sessions/bulk-delete does with {"tag": "demo"}.
+// DELETE /api/v1/me/demo
+func (s *Server) handleDeleteDemoSessions(w http.ResponseWriter, r *http.Request) {
+	log := logger.Ctx(r.Context())
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	sessionStore := &dbsession.Store{DB: s.db}
+	deleted, err := deleteMatchingSessions(r.Context(), sessionStore, s.storage, userID, db.BulkDeleteFilter{DemoOnly: true})
+	if err != nil {
+		log.Error("Failed to delete demo sessions", "error", err, "deleted_so_far", deleted)
+		respondError(w, http.StatusInternalServerError, "Failed to delete demo sessions")
+		return
+	}
+
+	log.Info("Demo sessions deleted", "deleted_count", deleted)
+	respondJSON(w, http.StatusOK, DemoSessionsResponse{DeletedCount: deleted})
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// FingerprintLines is how many leading transcript lines feed a session's
+// content fingerprint. A session is fingerprinted once its transcript
+// reaches this size; shorter sessions are never flagged as duplicates.
+const FingerprintLines = 100
+
+// fingerprintPrime is the multiplier for the polynomial rolling hash (the
+// 64-bit FNV prime — any large odd constant works).
+const fingerprintPrime = 1099511628211
+
+// foldFingerprint folds lines into a rolling hash state:
+// state' = state*prime + fnv64a(line), mod 2^64. The fold is resumable, so
+// the sync path can advance it chunk by chunk without re-reading S3, and
+// order-sensitive, so reordered transcripts don't collide.
+func foldFingerprint(state uint64, lines []string) uint64 {
+	for _, line := range lines {
+		h := fnv.New64a()
+		h.Write([]byte(line))
+		state = state*fingerprintPrime + h.Sum64()
+	}
+	return state
+}
+
+// formatFingerprint renders a completed state as the stored fingerprint.
+func formatFingerprint(state uint64) string {
+	return fmt.Sprintf("%016x", state)
+}
+
+// advanceContentFingerprint folds a transcript chunk's lines into the file's
+// rolling hash when the chunk overlaps the first FingerprintLines lines, and
+// stamps the session's content fingerprint once that many lines are in.
+// Best-effort: failures are logged and never fail the chunk upload, and a
+// chunk that doesn't continue the stored state exactly is skipped.
+func advanceContentFingerprint(ctx context.Context, store *dbsession.Store, sessionID, fileName string, firstLine int, lines []string) {
+	if firstLine > FingerprintLines {
+		return
+	}
+	log := logger.Ctx(ctx)
+
+	state, folded, done, err := store.GetFingerprintState(ctx, sessionID, fileName)
+	if err != nil {
+		log.Warn("Failed to read fingerprint state", "error", err, "session_id", sessionID, "file_name", fileName)
+		return
+	}
+	if done || folded != firstLine-1 || folded >= FingerprintLines {
+		return
+	}
+
+	take := min(len(lines), FingerprintLines-folded)
+	state = foldFingerprint(state, lines[:take])
+
+	var fingerprint *string
+	if folded+take == FingerprintLines {
+		fp := formatFingerprint(state)
+		fingerprint = &fp
+	}
+	if _, err := store.AdvanceFingerprint(ctx, sessionID, fileName, folded, state, folded+take, fingerprint); err != nil {
+		log.Warn("Failed to advance fingerprint", "error", err, "session_id", sessionID, "file_name", fileName)
+	}
+}
+
+// HandleMergeDuplicateSession soft-deletes a session flagged as a probable
+// duplicate (duplicate_of in the session list). Owner-only. The session is
+// hidden from lists and analytics aggregates but its data is kept, with a
+// pointer to the original.
+func HandleMergeDuplicateSession(database *db.DB) http.HandlerFunc {
+	sessionStore := &dbsession.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		if _, _, err := sessionStore.VerifySessionOwnership(ctx, sessionID, userID); err != nil {
+			if errors.Is(err, db.ErrSessionNotFound) {
+				respondError(w, http.StatusNotFound, "Session not found")
+				return
+			}
+			if errors.Is(err, db.ErrForbidden) {
+				respondError(w, http.StatusForbidden, "Access denied")
+				return
+			}
+			log.Error("Failed to verify session ownership", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to retrieve session information")
+			return
+		}
+
+		mergedInto, err := sessionStore.MergeDuplicateSession(ctx, sessionID, userID)
+		if err != nil {
+			if errors.Is(err, db.ErrNotDuplicate) {
+				respondError(w, http.StatusConflict, "Session is not an unmerged duplicate")
+				return
+			}
+			log.Error("Failed to merge duplicate session", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to merge duplicate session")
+			return
+		}
+
+		// Audit log: duplicate merged
+		log.Info("Duplicate session merged",
+			"session_id", sessionID,
+			"merged_into", mergedInto)
+
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success":     true,
+			"session_id":  sessionID,
+			"merged_into": mergedInto,
+		})
+	}
+}
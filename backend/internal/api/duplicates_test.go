@@ -0,0 +1,58 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+)
+
+func fingerprintTestLines(n int, prefix string) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf(`{"%s":%d}`, prefix, i)
+	}
+	return lines
+}
+
+func TestFoldFingerprint(t *testing.T) {
+	base := fingerprintTestLines(FingerprintLines, "line")
+
+	t.Run("identical prefix yields identical fingerprint", func(t *testing.T) {
+		a := formatFingerprint(foldFingerprint(0, base))
+		b := formatFingerprint(foldFingerprint(0, append([]string(nil), base...)))
+		if a != b {
+			t.Errorf("fingerprints differ: %s vs %s", a, b)
+		}
+	})
+
+	t.Run("divergent prefix yields different fingerprint", func(t *testing.T) {
+		divergent := append([]string(nil), base...)
+		divergent[FingerprintLines/2] = `{"line":"edited"}`
+		if foldFingerprint(0, base) == foldFingerprint(0, divergent) {
+			t.Error("expected divergent prefixes to produce different fingerprints")
+		}
+	})
+
+	t.Run("reordered lines yield different fingerprint", func(t *testing.T) {
+		swapped := append([]string(nil), base...)
+		swapped[0], swapped[1] = swapped[1], swapped[0]
+		if foldFingerprint(0, base) == foldFingerprint(0, swapped) {
+			t.Error("expected order-sensitive fingerprint")
+		}
+	})
+
+	t.Run("chunked fold matches single fold", func(t *testing.T) {
+		whole := foldFingerprint(0, base)
+		state := foldFingerprint(0, base[:7])
+		state = foldFingerprint(state, base[7:40])
+		state = foldFingerprint(state, base[40:])
+		if state != whole {
+			t.Errorf("chunked fold = %x, want %x", state, whole)
+		}
+	})
+
+	t.Run("format is fixed width", func(t *testing.T) {
+		if got := formatFingerprint(1); got != "0000000000000001" {
+			t.Errorf("formatFingerprint(1) = %q", got)
+		}
+	})
+}
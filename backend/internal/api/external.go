@@ -92,6 +92,10 @@ func (s *Server) serveCondensedTranscript(w http.ResponseWriter, r *http.Request
 		respondError(w, http.StatusNotFound, "No transcript available for this session")
 		return
 	}
+	if session.TranscriptArchivedAt != nil {
+		respondTranscriptArchived(w)
+		return
+	}
 
 	// Get session owner info for S3 path
 	sessionStore := &dbsession.Store{DB: s.db}
@@ -171,18 +175,8 @@ func (s *Server) serveCondensedTranscript(w http.ResponseWriter, r *http.Request
 
 // buildCondensedMetadata constructs the metadata portion of the condensed transcript response.
 func buildCondensedMetadata(session *db.SessionDetail, totalLines int64) CondensedTranscriptMetadata {
-	// Derive title: custom > suggested > summary > first user message
-	title := ""
-	switch {
-	case session.CustomTitle != nil:
-		title = *session.CustomTitle
-	case session.SuggestedSessionTitle != nil:
-		title = *session.SuggestedSessionTitle
-	case session.Summary != nil:
-		title = *session.Summary
-	case session.FirstUserMessage != nil:
-		title = *session.FirstUserMessage
-	}
+	title := db.ResolveSessionTitle(session.CustomTitle, session.AIGeneratedTitle,
+		session.SuggestedSessionTitle, session.Summary, session.FirstUserMessage)
 
 	meta := CondensedTranscriptMetadata{
 		SessionID:  session.ID,
@@ -320,6 +314,10 @@ func (s *Server) serveSessionFileDownload(w http.ResponseWriter, r *http.Request
 		respondError(w, http.StatusNotFound, "File not found")
 		return
 	}
+	if result.Session.TranscriptArchivedAt != nil {
+		respondTranscriptArchived(w)
+		return
+	}
 
 	// Get session owner info for S3 path
 	sessionStore := &dbsession.Store{DB: s.db}
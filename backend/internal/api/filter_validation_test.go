@@ -1,9 +1,11 @@
 package api
 
 import (
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/ConfabulousDev/confab-web/internal/db"
 	"github.com/ConfabulousDev/confab-web/internal/validation"
 )
 
@@ -86,3 +88,84 @@ func TestValidateSearchQuery(t *testing.T) {
 		t.Error("expected error for oversized query, got nil")
 	}
 }
+
+func TestParseStates(t *testing.T) {
+	got, err := parseStates("Idle, ended,,")
+	if err != nil {
+		t.Fatalf("parseStates: %v", err)
+	}
+	if strings.Join(got, ",") != "idle,ended" {
+		t.Errorf("parseStates = %v, want [idle ended]", got)
+	}
+
+	if got, err := parseStates(""); got != nil || err != nil {
+		t.Errorf("parseStates(\"\") = %v, %v; want nil, nil", got, err)
+	}
+
+	for _, bad := range []string{"deleted", "gone", "active,paused"} {
+		if _, err := parseStates(bad); err == nil {
+			t.Errorf("parseStates(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestParseSessionSort(t *testing.T) {
+	for in, want := range map[string]string{
+		"":             db.SessionSortRecent,
+		"recent":       db.SessionSortRecent,
+		" Interesting": db.SessionSortInteresting,
+	} {
+		if got, keys, err := parseSessionSort(in); err != nil || got != want || keys != nil {
+			t.Errorf("parseSessionSort(%q) = %q, %v, %v; want %q", in, got, keys, err, want)
+		}
+	}
+
+	for in, want := range map[string][]string{
+		"cost_desc":                             {db.SessionSortCostDesc},
+		"Cost_Desc, created_at_asc":             {db.SessionSortCostDesc, db.SessionSortCreatedAtAsc},
+		"duration_asc,line_count_desc,cost_asc": {db.SessionSortDurationAsc, db.SessionSortLineCountDesc, db.SessionSortCostAsc},
+	} {
+		if sort, keys, err := parseSessionSort(in); err != nil || sort != db.SessionSortRecent || !slices.Equal(keys, want) {
+			t.Errorf("parseSessionSort(%q) = %q, %v, %v; want keys %v", in, sort, keys, err, want)
+		}
+	}
+
+	for _, bad := range []string{
+		"cost",                  // unknown
+		"line_count_asc",        // not offered
+		"interesting,cost_desc", // named orderings stand alone
+		"cost_desc,cost_asc",    // one field twice
+		"cost_desc,duration_desc,created_at_desc,line_count_desc", // too many
+	} {
+		if _, _, err := parseSessionSort(bad); err == nil {
+			t.Errorf("parseSessionSort(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestParseOutOfTreeAccess(t *testing.T) {
+	if got, err := parseOutOfTreeAccess(""); err != nil || got != nil {
+		t.Errorf("parseOutOfTreeAccess(\"\") = %v, %v; want nil, nil", got, err)
+	}
+	for in, want := range map[string]bool{"true": true, "1": true, "false": false} {
+		if got, err := parseOutOfTreeAccess(in); err != nil || got == nil || *got != want {
+			t.Errorf("parseOutOfTreeAccess(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := parseOutOfTreeAccess("yes"); err == nil {
+		t.Error("parseOutOfTreeAccess(\"yes\") succeeded, want an error")
+	}
+}
+
+func TestParseListInclude(t *testing.T) {
+	for in, want := range map[string]bool{"": false, "growth": true, " Growth,,": true} {
+		if got, err := parseListInclude(in); err != nil || got != want {
+			t.Errorf("parseListInclude(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"files", "growth,cost"} {
+		if _, err := parseListInclude(bad); err == nil {
+			t.Errorf("parseListInclude(%q) succeeded, want an error", bad)
+		}
+	}
+}
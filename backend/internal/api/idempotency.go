@@ -0,0 +1,171 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/auth"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbidempotency"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// IdempotencyKeyHeader is the request header that makes a retried POST
+// return the first attempt's response instead of repeating it.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set to "true" on a response served from a
+// stored idempotency record.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+const (
+	// IdempotencyKeyTTL is how long a completed request's response is replayed.
+	IdempotencyKeyTTL = 24 * time.Hour
+	// MaxIdempotencyKeyLength bounds the Idempotency-Key header.
+	MaxIdempotencyKeyLength = 255
+	// idempotencyPendingTimeout is how long an unfinished request holds its
+	// key. A request still running after that (or whose process died) no
+	// longer blocks retries.
+	idempotencyPendingTimeout = 10 * time.Minute
+	// maxIdempotentResponseBytes caps the stored response. A larger response
+	// is served but not stored, so a retry runs the request again.
+	maxIdempotentResponseBytes = 1 << 20
+)
+
+// idempotent makes h honor the Idempotency-Key header. Routes opt in by
+// wrapping their handler in server.go; requests without the header pass
+// straight through. It must run inside an auth middleware: keys are scoped
+// to the user, method and path.
+//
+// The first request with a key runs h and stores its response; repeats with
+// the same body within IdempotencyKeyTTL get that response back with
+// Idempotent-Replayed: true. A repeat with a different body, or one arriving
+// while the first is still running, gets 409. Responses with status 429 or
+// 5xx are not stored, so the client can retry them with the same key.
+func idempotent(database *db.DB, h http.HandlerFunc) http.HandlerFunc {
+	store := &dbidempotency.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			h(w, r)
+			return
+		}
+		log := logger.Ctx(r.Context())
+
+		if len(key) > MaxIdempotencyKeyLength {
+			respondError(w, http.StatusBadRequest, "Idempotency-Key must be at most "+strconv.Itoa(MaxIdempotencyKeyLength)+" characters")
+			return
+		}
+		userID, ok := auth.GetUserID(r.Context())
+		if !ok {
+			respondError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				respondError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+				return
+			}
+			respondError(w, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		keyHash := idempotencyKeyHash(userID, r.Method, r.URL.Path, key)
+		bodySum := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(bodySum[:])
+
+		dbCtx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		claim, err := store.Claim(dbCtx, keyHash, userID, requestHash, IdempotencyKeyTTL, idempotencyPendingTimeout)
+		cancel()
+		if err != nil {
+			log.Error("Failed to claim idempotency key", "error", err, "user_id", userID, "path", r.URL.Path)
+			respondError(w, http.StatusInternalServerError, "Failed to process request")
+			return
+		}
+
+		if !claim.Claimed {
+			existing := claim.Existing
+			switch {
+			case existing.RequestHash != requestHash:
+				respondError(w, http.StatusConflict, "Idempotency-Key was already used with a different request body")
+			case existing.StatusCode == nil:
+				respondError(w, http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+			default:
+				if existing.ContentType != "" {
+					w.Header().Set("Content-Type", existing.ContentType)
+				}
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(*existing.StatusCode)
+				w.Write(existing.Body)
+			}
+			return
+		}
+
+		rec := &idempotencyRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(rec, r)
+
+		// Detached context: the response is already written, and the record
+		// must be settled even if the client went away.
+		saveCtx, saveCancel := context.WithTimeout(context.WithoutCancel(r.Context()), DatabaseTimeout)
+		defer saveCancel()
+		if rec.status == http.StatusTooManyRequests || rec.status >= 500 || rec.overflow {
+			if err := store.Release(saveCtx, keyHash); err != nil {
+				log.Error("Failed to release idempotency key", "error", err, "user_id", userID, "path", r.URL.Path)
+			}
+			return
+		}
+		if err := store.Complete(saveCtx, keyHash, rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+			log.Error("Failed to store idempotent response", "error", err, "user_id", userID, "path", r.URL.Path)
+		}
+	}
+}
+
+// idempotencyKeyHash scopes a client key to the user, method and path, so
+// one key reused on another route or by another user is a different record.
+func idempotencyKeyHash(userID int64, method, path, key string) string {
+	sum := sha256.Sum256([]byte(strconv.FormatInt(userID, 10) + "\x00" + method + "\x00" + path + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotencyRecorder passes a response through while keeping a copy of its
+// status and body, up to maxIdempotentResponseBytes.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	overflow    bool
+}
+
+func (r *idempotencyRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *idempotencyRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	if !r.overflow {
+		if r.body.Len()+len(b) > maxIdempotentResponseBytes {
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(b)
+		}
+	}
+	return r.ResponseWriter.Write(b)
+}
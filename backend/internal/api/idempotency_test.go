@@ -0,0 +1,65 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIdempotent_WithoutKeyPassesThrough(t *testing.T) {
+	calls := 0
+	h := idempotent(nil, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	for range 2 {
+		rr := httptest.NewRecorder()
+		h(rr, httptest.NewRequest(http.MethodPost, "/api/v1/me/webhooks", bytes.NewReader([]byte(`{}`))))
+		if rr.Code != http.StatusCreated || rr.Header().Get(IdempotentReplayedHeader) != "" {
+			t.Fatalf("status %d, replayed %q; want a plain 201", rr.Code, rr.Header().Get(IdempotentReplayedHeader))
+		}
+	}
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2", calls)
+	}
+}
+
+func TestIdempotencyKeyHash_Scope(t *testing.T) {
+	base := idempotencyKeyHash(1, http.MethodPost, "/api/v1/me/webhooks", "k")
+	if base != idempotencyKeyHash(1, http.MethodPost, "/api/v1/me/webhooks", "k") {
+		t.Fatal("hash is not stable")
+	}
+	for name, other := range map[string]string{
+		"user":   idempotencyKeyHash(2, http.MethodPost, "/api/v1/me/webhooks", "k"),
+		"path":   idempotencyKeyHash(1, http.MethodPost, "/api/v1/sessions/s1/share", "k"),
+		"key":    idempotencyKeyHash(1, http.MethodPost, "/api/v1/me/webhooks", "k2"),
+		"joined": idempotencyKeyHash(1, http.MethodPost, "/api/v1/me/webhooksk", ""),
+	} {
+		if other == base {
+			t.Errorf("changing the %s keeps the hash", name)
+		}
+	}
+}
+
+func TestIdempotencyRecorder(t *testing.T) {
+	rr := httptest.NewRecorder()
+	rec := &idempotencyRecorder{ResponseWriter: rr, status: http.StatusOK}
+	rec.WriteHeader(http.StatusAccepted)
+	rec.Write([]byte(`{"ok":`))
+	rec.Write([]byte(`true}`))
+	if rec.status != http.StatusAccepted || rec.body.String() != `{"ok":true}` || rec.overflow {
+		t.Fatalf("recorded %d %q overflow=%v", rec.status, rec.body.String(), rec.overflow)
+	}
+	if rr.Code != http.StatusAccepted || rr.Body.String() != `{"ok":true}` {
+		t.Errorf("passed through %d %q", rr.Code, rr.Body.String())
+	}
+
+	big := &idempotencyRecorder{ResponseWriter: httptest.NewRecorder(), status: http.StatusOK}
+	big.Write(make([]byte, maxIdempotentResponseBytes))
+	big.Write([]byte("x"))
+	if !big.overflow || big.body.Len() != 0 {
+		t.Errorf("overflow=%v, kept %d bytes; want the copy dropped", big.overflow, big.body.Len())
+	}
+}
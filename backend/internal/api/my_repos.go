@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// HandleGetMyRepos returns the authenticated user's sessions grouped by
+// repository: session count, total tokens and last activity per repo, plus
+// an unassigned bucket for sessions without git info. Drives the dashboard's
+// repo-first navigation. Only the user's own sessions count.
+func HandleGetMyRepos(database *db.DB) http.HandlerFunc {
+	analyticsStore := analytics.NewStore(database.Conn())
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		repos, err := analyticsStore.GetMyRepos(ctx, userID)
+		if err != nil {
+			log.Error("Failed to get repos", "error", err, "user_id", userID)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to get repos")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, repos)
+	}
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbnotify"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbwebhook"
+	dbuser "github.com/ConfabulousDev/confab-web/internal/db/user"
+	"github.com/ConfabulousDev/confab-web/internal/email"
+	"github.com/ConfabulousDev/confab-web/internal/notify"
+)
+
+// newNotifier builds the dispatcher the server's notification producers send
+// through. Without an email service, notifications go out by webhook only.
+// Notifications held back by quiet hours are sent by the worker, which builds
+// its own dispatcher over the same queue.
+func newNotifier(database *db.DB, emailService *email.RateLimitedService) *notify.Dispatcher {
+	users := &dbuser.Store{DB: database}
+	senders := map[string]notify.Sender{
+		notify.ChannelWebhook: notify.NewWebhookSender(&dbwebhook.Store{DB: database}),
+	}
+	if emailService != nil {
+		senders[notify.ChannelEmail] = notify.NewEmailSender(users, emailService.SendNotification)
+	}
+	return notify.NewDispatcher(users, &dbnotify.Store{DB: database}, senders)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/ConfabulousDev/confab-web/internal/analytics"
@@ -25,7 +26,7 @@ import (
 //   - repos: Comma-separated repo names (owner/name form) to include.
 //   - include_no_repo: Include sessions without a repo_url. Defaults to true.
 func HandleGetOrgAnalytics(database *db.DB) http.HandlerFunc {
-	analyticsStore := analytics.NewStore(database.Conn())
+	analyticsStore := analytics.NewStore(database.Conn()).WithReplica(database)
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := logger.Ctx(r.Context())
@@ -74,6 +75,10 @@ func HandleGetOrgAnalytics(database *db.DB) http.HandlerFunc {
 		response, err := analyticsStore.GetOrgAnalytics(r.Context(), req)
 		if err != nil {
 			log.Error("Failed to get org analytics", "error", err)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
 			respondError(w, http.StatusInternalServerError, "Failed to compute org analytics")
 			return
 		}
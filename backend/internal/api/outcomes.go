@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// HandleGetOutcomes returns the authenticated user's sessions grouped by the
+// pull request they produced (pr_url, else detected_pr_url): session count,
+// total cost and duration per PR. Only the user's own sessions count.
+func HandleGetOutcomes(database *db.DB) http.HandlerFunc {
+	analyticsStore := analytics.NewStore(database.Conn())
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		outcomes, err := analyticsStore.GetOutcomes(ctx, userID)
+		if err != nil {
+			log.Error("Failed to get outcomes", "error", err, "user_id", userID)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to get outcomes")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, outcomes)
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/ratelimit"
+)
+
+// Default limit multipliers for the tiers above standard, applied to both the
+// rate and the burst of each per-user limiter.
+const (
+	defaultTrustedRateLimitMultiplier  = 5
+	defaultInternalRateLimitMultiplier = 20
+)
+
+// rateLimitTierMultipliers scales the standard limits of a per-user limiter
+// for each rate limit tier. The standard tier is always 1.
+type rateLimitTierMultipliers map[models.RateLimitTier]float64
+
+// rateLimitTierMultipliersFromEnv resolves the tier multipliers from
+// RATE_LIMIT_TIER_TRUSTED_MULTIPLIER and RATE_LIMIT_TIER_INTERNAL_MULTIPLIER.
+// A value that is not a positive number fails startup.
+func rateLimitTierMultipliersFromEnv() rateLimitTierMultipliers {
+	return rateLimitTierMultipliers{
+		models.RateLimitTierStandard: 1,
+		models.RateLimitTierTrusted:  positiveFloatFromEnv("RATE_LIMIT_TIER_TRUSTED_MULTIPLIER", defaultTrustedRateLimitMultiplier),
+		models.RateLimitTierInternal: positiveFloatFromEnv("RATE_LIMIT_TIER_INTERNAL_MULTIPLIER", defaultInternalRateLimitMultiplier),
+	}
+}
+
+// positiveFloatFromEnv reads a positive number from env var name, or def when
+// it is unset. Anything else is fatal, like nonNegativeIntFromEnv.
+func positiveFloatFromEnv(name string, def float64) float64 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || !(v > 0) {
+		logger.Fatal("invalid "+name, "value", raw)
+	}
+	return v
+}
+
+// newTieredLimiter builds a per-user limiter whose standard tier gets
+// standard and every other tier gets standard scaled by its multiplier.
+func newTieredLimiter(standard ratelimit.Limit, multipliers rateLimitTierMultipliers, maxBuckets int) *ratelimit.TieredRateLimiter {
+	limits := make(map[string]ratelimit.Limit, len(multipliers))
+	for tier, factor := range multipliers {
+		limits[string(tier)] = standard.Scale(factor)
+	}
+	return ratelimit.NewTieredRateLimiter(limits, string(models.RateLimitTierStandard), maxBuckets)
+}
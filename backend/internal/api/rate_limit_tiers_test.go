@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/auth"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/ratelimit"
+)
+
+func TestRateLimitTierMultipliersFromEnv(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		t.Setenv("RATE_LIMIT_TIER_TRUSTED_MULTIPLIER", "")
+		t.Setenv("RATE_LIMIT_TIER_INTERNAL_MULTIPLIER", "")
+		got := rateLimitTierMultipliersFromEnv()
+		want := rateLimitTierMultipliers{
+			models.RateLimitTierStandard: 1,
+			models.RateLimitTierTrusted:  defaultTrustedRateLimitMultiplier,
+			models.RateLimitTierInternal: defaultInternalRateLimitMultiplier,
+		}
+		for _, tier := range models.RateLimitTiers {
+			if got[tier] != want[tier] {
+				t.Errorf("%s multiplier = %v, want %v", tier, got[tier], want[tier])
+			}
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		t.Setenv("RATE_LIMIT_TIER_TRUSTED_MULTIPLIER", "2.5")
+		t.Setenv("RATE_LIMIT_TIER_INTERNAL_MULTIPLIER", "100")
+		got := rateLimitTierMultipliersFromEnv()
+		if got[models.RateLimitTierStandard] != 1 || got[models.RateLimitTierTrusted] != 2.5 || got[models.RateLimitTierInternal] != 100 {
+			t.Errorf("multipliers = %v, want standard 1, trusted 2.5, internal 100", got)
+		}
+	})
+}
+
+// TestTieredLimiter_ByUserTier wires the limiter the way SetupRoutes does:
+// keyed by user ID, with the tier auth stored in the request context.
+func TestTieredLimiter_ByUserTier(t *testing.T) {
+	limiter := newTieredLimiter(ratelimit.Limit{RPS: 0.0001, Burst: 3}, rateLimitTierMultipliers{
+		models.RateLimitTierStandard: 1,
+		models.RateLimitTierTrusted:  4,
+		models.RateLimitTierInternal: 20,
+	}, 1000)
+	defer limiter.Stop()
+
+	handler := ratelimit.TieredMiddleware(limiter, ratelimit.UserKeyFunc(auth.GetUserIDContextKey()), auth.RateLimitTierFunc)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	// allowed sends requests as userID until one is throttled (or max is
+	// reached) and returns how many got through.
+	allowed := func(userID int64, tier models.RateLimitTier, max int) int {
+		for i := 0; i < max; i++ {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/sync/chunk", nil)
+			ctx := auth.SetUserIDForTest(req.Context(), userID)
+			if tier != "" {
+				ctx = auth.WithRateLimitTier(ctx, tier)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req.WithContext(ctx))
+			if rec.Code == http.StatusTooManyRequests {
+				return i
+			}
+		}
+		return max
+	}
+
+	if n := allowed(1, models.RateLimitTierStandard, 100); n != 3 {
+		t.Errorf("standard user got %d requests through, want 3", n)
+	}
+	if n := allowed(2, models.RateLimitTierTrusted, 100); n != 12 {
+		t.Errorf("trusted user got %d requests through, want 12", n)
+	}
+	if n := allowed(3, models.RateLimitTierInternal, 100); n != 60 {
+		t.Errorf("internal user got %d requests through, want 60", n)
+	}
+	// No tier in context (e.g. a test helper skipped auth) means standard.
+	if n := allowed(4, "", 100); n != 3 {
+		t.Errorf("user without a tier got %d requests through, want 3", n)
+	}
+}
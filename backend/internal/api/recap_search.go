@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/validation"
+)
+
+// Result bounds for GET /api/v1/sessions/search/recap.
+const (
+	DefaultRecapSearchLimit = 20
+	MaxRecapSearchLimit     = 100
+)
+
+// RecapSearchResponse lists the caller's sessions whose smart recap matches.
+type RecapSearchResponse struct {
+	Results []dbsession.RecapSearchResult `json:"results"`
+}
+
+// HandleSearchSmartRecaps searches the smart recaps of the caller's own
+// sessions directly, so a new recap is findable before the worker folds it
+// into the search index. GET /api/v1/sessions/search/recap?q=&limit=
+func HandleSearchSmartRecaps(database *db.DB) http.HandlerFunc {
+	sessionStore := &dbsession.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		q := r.URL.Query().Get("q")
+		if q == "" {
+			respondError(w, http.StatusBadRequest, "q is required")
+			return
+		}
+		if err := validation.ValidateSearchQuery(q); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		limit := DefaultRecapSearchLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > MaxRecapSearchLimit {
+				respondError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", MaxRecapSearchLimit))
+				return
+			}
+			limit = n
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		results, err := sessionStore.SearchSmartRecaps(ctx, userID, q, limit)
+		if err != nil {
+			log.Error("Failed to search smart recaps", "error", err)
+			respondError(w, http.StatusInternalServerError, "Failed to search recaps")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, RecapSearchResponse{Results: results})
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// Redaction event pagination bounds.
+const (
+	DefaultRedactionEventsLimit = 100
+	MaxRedactionEventsLimit     = 1000
+)
+
+// redactionTypeParam is the shape of a redaction marker type (GITHUB_TOKEN,
+// UNSPECIFIED, ...).
+var redactionTypeParam = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,63}$`)
+
+// RedactionEventsResponse is one page of per-redaction detail.
+type RedactionEventsResponse struct {
+	Events     []analytics.RedactionEvent `json:"events"`
+	HasMore    bool                       `json:"has_more"`
+	NextCursor string                     `json:"next_cursor,omitempty"`
+}
+
+// parseRedactionEventsQuery parses the type, limit and cursor query
+// parameters. The cursor is the redaction_index of the last event on the
+// previous page; an absent cursor starts from the first event (-1).
+func parseRedactionEventsQuery(r *http.Request) (filter analytics.RedactionEventFilter, afterIndex, limit int, err error) {
+	q := r.URL.Query()
+	afterIndex = -1
+	limit = DefaultRedactionEventsLimit
+
+	if typ := q.Get("type"); typ != "" {
+		if !redactionTypeParam.MatchString(typ) {
+			return filter, 0, 0, errors.New("type must be an upper-case redaction type such as GITHUB_TOKEN")
+		}
+		filter.Type = &typ
+	}
+	if s := q.Get("limit"); s != "" {
+		v, perr := strconv.Atoi(s)
+		if perr != nil || v < 1 || v > MaxRedactionEventsLimit {
+			return filter, 0, 0, fmt.Errorf("limit must be between 1 and %d", MaxRedactionEventsLimit)
+		}
+		limit = v
+	}
+	if s := q.Get("cursor"); s != "" {
+		v, perr := strconv.Atoi(s)
+		if perr != nil || v < 0 {
+			return filter, 0, 0, errors.New("invalid cursor")
+		}
+		afterIndex = v
+	}
+	return filter, afterIndex, limit, nil
+}
+
+// HandleListRedactionEvents returns per-redaction detail for a session,
+// optionally filtered by type and paginated by redaction index. Uses the same
+// canonical access model as HandleGetSessionAnalytics (CF-132).
+//
+// Events are written alongside the redactions card (precompute worker or an
+// analytics fetch); a session whose cards were never computed returns an
+// empty page. Context previews are obfuscated before they are stored.
+func HandleListRedactionEvents(database *db.DB) http.HandlerFunc {
+	analyticsStore := analytics.NewStore(database.Conn())
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		filter, afterIndex, limit, perr := parseRedactionEventsQuery(r)
+		if perr != nil {
+			respondError(w, http.StatusBadRequest, perr.Error())
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		if result := RequireCanonicalRead(ctx, w, database, sessionID); result == nil {
+			return
+		}
+
+		// Fetch one extra row to detect whether another page exists.
+		events, err := analyticsStore.ListRedactionEvents(ctx, sessionID, filter, afterIndex, limit+1)
+		if err != nil {
+			log.Error("Failed to list redaction events", "error", err, "session_id", sessionID)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to list redaction events")
+			return
+		}
+
+		resp := RedactionEventsResponse{Events: events}
+		if len(events) > limit {
+			resp.Events = events[:limit]
+			resp.HasMore = true
+			resp.NextCursor = strconv.Itoa(resp.Events[limit-1].RedactionIndex)
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseRedactionEventsQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantErr   string
+		wantAfter int
+		wantLimit int
+		wantType  string
+	}{
+		{name: "defaults", query: "", wantAfter: -1, wantLimit: DefaultRedactionEventsLimit},
+		{name: "all params", query: "type=GITHUB_TOKEN&limit=5&cursor=41", wantAfter: 41, wantLimit: 5, wantType: "GITHUB_TOKEN"},
+		{name: "unspecified type", query: "type=UNSPECIFIED", wantAfter: -1, wantLimit: DefaultRedactionEventsLimit, wantType: "UNSPECIFIED"},
+		{name: "max limit", query: "limit=1000", wantAfter: -1, wantLimit: MaxRedactionEventsLimit},
+		{name: "lower-case type", query: "type=github_token", wantErr: "type must be"},
+		{name: "type with punctuation", query: "type=API-KEY", wantErr: "type must be"},
+		{name: "type too long", query: "type=" + strings.Repeat("A", 65), wantErr: "type must be"},
+		{name: "zero limit", query: "limit=0", wantErr: "limit must be between"},
+		{name: "limit too large", query: "limit=1001", wantErr: "limit must be between"},
+		{name: "negative cursor", query: "cursor=-1", wantErr: "invalid cursor"},
+		{name: "non-numeric cursor", query: "cursor=abc", wantErr: "invalid cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/sessions/x/cards/redactions/details?"+tt.query, nil)
+			filter, after, limit, err := parseRedactionEventsQuery(r)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if after != tt.wantAfter || limit != tt.wantLimit {
+				t.Errorf("after, limit = %d, %d, want %d, %d", after, limit, tt.wantAfter, tt.wantLimit)
+			}
+			gotType := ""
+			if filter.Type != nil {
+				gotType = *filter.Type
+			}
+			if gotType != tt.wantType {
+				t.Errorf("type = %q, want %q", gotType, tt.wantType)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// SearchReindexResponse is a session's search index as just rebuilt.
+type SearchReindexResponse struct {
+	Version         int       `json:"version"`
+	IndexedUpToLine int64     `json:"indexed_up_to_line"`
+	MetadataHash    string    `json:"metadata_hash"`
+	IndexedAt       time.Time `json:"indexed_at"`
+}
+
+// HandleReindexSession rebuilds a session's search index now, with the
+// builder the precompute worker uses, so a title or summary edit is
+// searchable without waiting for the next worker cycle. Owner-only.
+func HandleReindexSession(database *db.DB, store *storage.S3Storage) http.HandlerFunc {
+	sessionStore := &dbsession.Store{DB: database}
+	analyticsStore := analytics.NewStore(database.Conn())
+	precomputer := analytics.NewPrecomputer(database.Conn(), store, analyticsStore, analytics.PrecomputeConfig{}, database)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		dbCtx, dbCancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer dbCancel()
+
+		sessionUserID, externalID, sessionProvider, err := sessionStore.GetSessionOwnerExternalIDAndProvider(dbCtx, sessionID)
+		if err != nil {
+			log.Error("Failed to get session", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusNotFound, "Session not found")
+			return
+		}
+		if sessionUserID != userID {
+			respondError(w, http.StatusForbidden, "Only the session owner can reindex it")
+			return
+		}
+
+		session, err := sessionStore.GetSessionDetail(dbCtx, sessionID, userID)
+		if err != nil {
+			log.Error("Failed to get session detail", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to get session")
+			return
+		}
+		if session.TranscriptArchivedAt != nil {
+			respondTranscriptArchived(w)
+			return
+		}
+		if _, err := analytics.ProviderFor(sessionProvider); err != nil {
+			respondError(w, http.StatusBadRequest, "Search indexing is not available for this session type")
+			return
+		}
+
+		// The status carries the line count the worker would index up to.
+		status, err := analyticsStore.GetSearchIndexStatus(dbCtx, sessionID)
+		if err != nil {
+			log.Error("Failed to get search index status", "error", err, "session_id", sessionID)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to reindex session")
+			return
+		}
+		if status == nil || status.TotalLines == 0 {
+			respondError(w, http.StatusNotFound, "No transcript available for this session")
+			return
+		}
+
+		// Building downloads and parses the transcript, so it gets the
+		// storage budget rather than the database one.
+		buildCtx, buildCancel := context.WithTimeout(r.Context(), StorageTimeout)
+		defer buildCancel()
+
+		if err := precomputer.BuildSearchIndexOnly(buildCtx, analytics.StaleSession{
+			SessionID:  sessionID,
+			UserID:     sessionUserID,
+			ExternalID: externalID,
+			Provider:   sessionProvider,
+			TotalLines: status.TotalLines,
+			CreatedAt:  session.FirstSeen,
+		}); err != nil {
+			log.Error("Failed to build search index", "error", err, "session_id", sessionID)
+			respondStorageError(w, err, "Failed to reindex session")
+			return
+		}
+
+		record, err := analyticsStore.GetSearchIndex(buildCtx, sessionID)
+		if err != nil {
+			log.Error("Failed to get search index", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to reindex session")
+			return
+		}
+		if record == nil {
+			// The transcript parsed to nothing, so there was nothing to index.
+			respondError(w, http.StatusNotFound, "No transcript available for this session")
+			return
+		}
+
+		log.Info("Session reindexed", "session_id", sessionID, "indexed_up_to_line", record.IndexedUpToLine)
+		respondJSON(w, http.StatusOK, SearchReindexResponse{
+			Version:         record.Version,
+			IndexedUpToLine: record.IndexedUpToLine,
+			MetadataHash:    record.MetadataHash,
+			IndexedAt:       record.UpdatedAt.UTC(),
+		})
+	}
+}
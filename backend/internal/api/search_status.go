@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleGetSearchStatus returns the freshness of a session's search index:
+// whether it is indexed, how far, with which version and metadata hash, and
+// whether the precompute worker will rebuild it. Owner-only.
+func HandleGetSearchStatus(database *db.DB) http.HandlerFunc {
+	sessionStore := &dbsession.Store{DB: database}
+	analyticsStore := analytics.NewStore(database.Conn())
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		sessionUserID, _, _, err := sessionStore.GetSessionOwnerExternalIDAndProvider(ctx, sessionID)
+		if err != nil {
+			log.Error("Failed to get session", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusNotFound, "Session not found")
+			return
+		}
+		if sessionUserID != userID {
+			respondError(w, http.StatusForbidden, "Only the session owner can view its search status")
+			return
+		}
+
+		status, err := analyticsStore.GetSearchIndexStatus(ctx, sessionID)
+		if err != nil {
+			log.Error("Failed to get search index status", "error", err, "session_id", sessionID)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to get search status")
+			return
+		}
+		if status == nil {
+			respondError(w, http.StatusNotFound, "Session not found")
+			return
+		}
+		respondJSON(w, http.StatusOK, status)
+	}
+}
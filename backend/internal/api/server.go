@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,9 +17,13 @@ import (
 	"github.com/ConfabulousDev/confab-web/internal/auth"
 	"github.com/ConfabulousDev/confab-web/internal/clientip"
 	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbvelocity"
 	dbuser "github.com/ConfabulousDev/confab-web/internal/db/user"
 	"github.com/ConfabulousDev/confab-web/internal/email"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/ingestpolicy"
 	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/notify"
 	"github.com/ConfabulousDev/confab-web/internal/pricingsource"
 	"github.com/ConfabulousDev/confab-web/internal/ratelimit"
 	"github.com/ConfabulousDev/confab-web/internal/storage"
@@ -70,13 +75,22 @@ type Server struct {
 	saasFooterEnabled   bool                      // When true, SaaS footer is shown (ENABLE_SAAS_FOOTER=true)
 	saasTermlyEnabled   bool                      // When true, Termly cookie consent is enabled (ENABLE_SAAS_TERMLY=true)
 	orgAnalyticsEnabled bool                      // When true, org-wide analytics view is enabled (ENABLE_ORG_ANALYTICS=true)
+	leaderboardEnabled  bool                      // When true, the admin analytics leaderboard is served (ENABLE_ANALYTICS_LEADERBOARD=true)
 	smartRecapEnabled   bool                      // When true, smart recap generation is active (SMART_RECAP_ENABLED=true)
+	syncJSONLimits      httputil.JSONLimits       // Body-shape limits for sync/init and sync/chunk (SYNC_JSON_*)
+	syncInitVelocity    dbvelocity.Limits         // Per-API-key caps on sessions created by sync/init (SYNC_INIT_MAX_SESSIONS_PER_*)
+	ingestPolicy        *ingestpolicy.Policy      // Content denylist enforced on sync/chunk (INGEST_DENYLIST_FILE; nil = off)
+	strictFilePolicy    bool                      // Refuse sync/chunk files outside the session type's allowlist instead of only logging (SYNC_FILE_POLICY_STRICT)
+	syncProgress        *SyncProgressBroker       // Fans out stored chunks to /sessions/{id}/sync/events streams
+	shareAccessLog      *ShareAccessLog           // Records views through shares (DISABLE_SHARE_ACCESS_LOG=true → nil, off)
+	apiKeyUsage         *APIKeyUsageRecorder      // Batches per-API-key chunk upload counters into api_key_usage
+	notifier            *notify.Dispatcher        // Sends user notifications by their channel choices and quiet hours (nil → none sent)
 	globalLimiter       ratelimit.RateLimiter     // Global rate limiter for all requests
 	authLimiter         ratelimit.RateLimiter     // Stricter limiter for auth endpoints
-	uploadLimiter       ratelimit.RateLimiter     // Stricter limiter for uploads
+	uploadLimiter       ratelimit.TieredLimiter   // Stricter per-user limiter for uploads, by rate limit tier
 	validationLimiter   ratelimit.RateLimiter     // Moderate limiter for API key validation
 	clientErrorLimiter  ratelimit.RateLimiter     // Limiter for client error reporting
-	externalReadLimiter ratelimit.RateLimiter     // Limiter for external API read endpoints
+	externalReadLimiter ratelimit.TieredLimiter   // Per-user limiter for external API read endpoints, by rate limit tier
 	updateChecker       UpdateChecker             // Reports whether a newer backend release is available (nil → treated as disabled)
 	pricingSource       *pricingsource.Source     // Serves the effective model price table on /api/v1/pricing
 	buildInfo           BuildInfo                 // Compile-time build identity served on /api/v1/version
@@ -94,6 +108,7 @@ func NewServer(database *db.DB, store *storage.S3Storage, oauthConfig *auth.OAut
 	}
 	saasFooterEnabled := os.Getenv("ENABLE_SAAS_FOOTER") == "true"
 	updateCheckDisabled := os.Getenv("DISABLE_UPDATE_CHECK") == "true" || saasFooterEnabled
+	tierMultipliers := rateLimitTierMultipliersFromEnv()
 	return &Server{
 		db:                  database,
 		storage:             store,
@@ -106,7 +121,16 @@ func NewServer(database *db.DB, store *storage.S3Storage, oauthConfig *auth.OAut
 		saasFooterEnabled:   saasFooterEnabled,
 		saasTermlyEnabled:   os.Getenv("ENABLE_SAAS_TERMLY") == "true",
 		orgAnalyticsEnabled: os.Getenv("ENABLE_ORG_ANALYTICS") == "true",
+		leaderboardEnabled:  os.Getenv("ENABLE_ANALYTICS_LEADERBOARD") == "true",
 		smartRecapEnabled:   os.Getenv("SMART_RECAP_ENABLED") == "true",
+		syncJSONLimits:      syncJSONLimitsFromEnv(),
+		syncInitVelocity:    syncInitVelocityFromEnv(),
+		ingestPolicy:        ingestPolicyFromEnv(),
+		strictFilePolicy:    syncFilePolicyStrictFromEnv(),
+		syncProgress:        NewSyncProgressBroker(),
+		shareAccessLog:      shareAccessLogFromEnv(database),
+		apiKeyUsage:         NewAPIKeyUsageRecorder(database, apiKeyUsageFlushInterval),
+		notifier:            newNotifier(database, emailService),
 		// Global rate limiter: 100 requests per second, burst of 200
 		// Generous limit to allow normal usage while preventing DoS.
 		// Keyed by client IP, so the bucket cap is sized for the widest fan-out.
@@ -115,17 +139,18 @@ func NewServer(database *db.DB, store *storage.S3Storage, oauthConfig *auth.OAut
 		// Reasonable limit to prevent brute force while allowing normal dev usage
 		authLimiter: ratelimit.NewInMemoryRateLimiter(1, 30, 5_000),
 		// Upload endpoints: 10000 requests per hour = 2.78 req/sec, burst of 2000
-		// Keyed by user ID (not IP) to allow backfill of many sessions
-		uploadLimiter: ratelimit.NewInMemoryRateLimiter(2.78, 2000, 50_000),
+		// Keyed by user ID (not IP) to allow backfill of many sessions.
+		// These are the standard-tier limits; higher tiers get a multiple.
+		uploadLimiter: newTieredLimiter(ratelimit.Limit{RPS: 2.78, Burst: 2000}, tierMultipliers, 50_000),
 		// Validation endpoint: 30 requests per minute = 0.5 req/sec, burst of 10
 		// Moderate limit for CLI validation checks while preventing abuse
 		validationLimiter: ratelimit.NewInMemoryRateLimiter(0.5, 10, 5_000),
 		// Client error reporting: 0.5 req/sec, burst of 5
 		// Low limit for fire-and-forget error reports from frontend
 		clientErrorLimiter: ratelimit.NewInMemoryRateLimiter(0.5, 5, 5_000),
-		// External API: 30 req/sec, burst of 60 per user
+		// External API: 30 req/sec, burst of 60 per user (standard tier)
 		// Generous read-only limit for machine consumers (agents, CLI, scripts)
-		externalReadLimiter: ratelimit.NewInMemoryRateLimiter(30, 60, 20_000),
+		externalReadLimiter: newTieredLimiter(ratelimit.Limit{RPS: 30, Burst: 60}, tierMultipliers, 20_000),
 		updateChecker:       updatecheck.NewChecker(build.Version, updateCheckDisabled),
 		// SaaS blanks the URL so the canonical instance serves its embedded
 		// table without fetching from itself; self-host pulls from confabulous.dev.
@@ -134,6 +159,12 @@ func NewServer(database *db.DB, store *storage.S3Storage, oauthConfig *auth.OAut
 	}
 }
 
+// Close flushes buffered per-API-key usage. Call it after the HTTP server has
+// shut down.
+func (s *Server) Close(ctx context.Context) error {
+	return s.apiKeyUsage.Close(ctx)
+}
+
 // parseAllowedOrigins parses ALLOWED_ORIGINS env var into CORS and CSRF formats.
 // Returns (corsOrigins, csrfTrustedOrigins).
 // CORS needs full URLs like "https://example.com"; CSRF needs host:port like
@@ -176,6 +207,12 @@ func parseTrustedProxyHeaders() []string {
 	return headers
 }
 
+// emailLinkEnabled reports whether the email magic-link login routes are
+// served: AUTH_EMAIL_LINK_ENABLED and a configured email service.
+func (s *Server) emailLinkEnabled() bool {
+	return s.oauthConfig.EmailLinkEnabled && s.emailService != nil
+}
+
 // SetupRoutes configures HTTP routes
 func (s *Server) SetupRoutes() http.Handler {
 	r := chi.NewRouter()
@@ -225,9 +262,9 @@ func (s *Server) SetupRoutes() http.Handler {
 		// AllowedMethods: HTTP methods that can be used
 		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		// AllowedHeaders: Headers that can be sent by the client
-		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", IdempotencyKeyHeader},
 		// ExposedHeaders: Headers that can be accessed by the client
-		ExposedHeaders: []string{"Link"},
+		ExposedHeaders: []string{"Link", AnalyticsRecomputePendingHeader, IdempotentReplayedHeader},
 		// AllowCredentials: Allow cookies and auth headers
 		AllowCredentials: true,
 		// MaxAge: How long the browser can cache CORS responses (5 minutes)
@@ -260,6 +297,7 @@ func (s *Server) SetupRoutes() http.Handler {
 
 	// Health check (no additional rate limiting needed)
 	r.Get("/health", withMaxBody(MaxBodyXS, s.handleHealth))
+	r.Get("/health/ready", withMaxBody(MaxBodyXS, s.handleReady))
 
 	// Public help pages
 	r.Get("/help/delete-account", withMaxBody(MaxBodyXS, s.handleDeleteAccountHelp))
@@ -331,6 +369,15 @@ func (s *Server) SetupRoutes() http.Handler {
 		// Public auth config endpoint (no auth required)
 		r.Get("/auth/config", withMaxBody(MaxBodyXS, s.handleAuthConfig))
 
+		// Email magic-link login (if enabled and the email service is
+		// configured). The request POST is public and outside the CSRF group, so
+		// it gets the cross-origin guard; verify is a GET opened from a mail
+		// client and must accept cross-site navigation.
+		if s.emailLinkEnabled() {
+			r.Post("/auth/email", withMaxBody(MaxBodyS, crossOriginGuard(trustedOrigins, ratelimit.HandlerFunc(s.authLimiter, auth.HandleEmailLoginRequest(s.db, s.oauthConfig, s.emailService, backendURL)))))
+			r.Get("/auth/email/verify", withMaxBody(MaxBodyXS, ratelimit.HandlerFunc(s.authLimiter, auth.HandleEmailLoginVerify(s.db, s.oauthConfig))))
+		}
+
 		// Public build-info endpoint (no auth, no external deps): reports what
 		// build this server is. "Is there a newer release?" stays in /auth/config.
 		r.Get("/version", withMaxBody(MaxBodyXS, s.handleVersion))
@@ -354,15 +401,18 @@ func (s *Server) SetupRoutes() http.Handler {
 
 			// Sync endpoints with user-based rate limiting and zstd decompression
 			r.Group(func(r chi.Router) {
-				// Rate limit by user ID (not IP) to allow backfill of many sessions
-				r.Use(ratelimit.MiddlewareWithKey(s.uploadLimiter, ratelimit.UserKeyFunc(auth.GetUserIDContextKey())))
+				// Rate limit by user ID (not IP) to allow backfill of many sessions,
+				// with the limits of the user's rate limit tier
+				r.Use(ratelimit.TieredMiddleware(s.uploadLimiter, ratelimit.UserKeyFunc(auth.GetUserIDContextKey()), auth.RateLimitTierFunc))
 				// Decompress zstd-compressed request bodies
 				r.Use(decompressMiddleware())
 
 				// Incremental sync endpoints (for daemon-based uploads)
 				r.Post("/sync/init", withMaxBody(MaxBodyM, s.handleSyncInit))
 				r.Post("/sync/chunk", withMaxBody(MaxBodyXL, s.handleSyncChunk))
+				r.Post("/sync/chunk/validate", withMaxBody(MaxBodyXL, s.handleSyncChunkValidate))
 				r.Post("/sync/event", withMaxBody(MaxBodyM, s.handleSyncEvent))
+				r.Post("/sync/file/reset", withMaxBody(MaxBodyS, s.handleSyncFileReset))
 			})
 
 			// Session metadata update (by external_id for CLI convenience)
@@ -372,15 +422,37 @@ func (s *Server) SetupRoutes() http.Handler {
 
 		// Protected routes for web dashboard (require web session)
 		// CSRF protection applied here to prevent forged requests
+		// POSTs wrapped in idempotent() replay their response for a repeated
+		// Idempotency-Key (idempotency.go). Sync endpoints don't opt in: the
+		// CLI protocol has its own resume semantics.
 		r.Group(func(r chi.Router) {
 			r.Use(csrfMiddleware)
 			r.Use(auth.RequireSession(s.db, s.oauthConfig))
 
 			r.Get("/me", withMaxBody(MaxBodyXS, s.handleGetMe))
+			r.Patch("/me/settings", withMaxBody(MaxBodyXS, s.handleUpdateMySettings))
+			r.Get("/me/preferences", withMaxBody(MaxBodyXS, s.handleGetMyPreferences))
+			r.Patch("/me/preferences", withMaxBody(MaxBodyXS, s.handleUpdateMyPreferences))
+			r.Get("/me/storage", withMaxBody(MaxBodyXS, s.handleGetMyStorage))
+			r.Post("/me/export", withMaxBody(MaxBodyXS, s.handleRequestDataExport))
+			r.Get("/me/export", withMaxBody(MaxBodyXS, s.handleGetDataExport))
+			r.Get("/me/activity", withMaxBody(MaxBodyXS, HandleGetMyActivity(s.db)))
+			r.Get("/me/repos", withMaxBody(MaxBodyXS, HandleGetMyRepos(s.db)))
+			r.Get("/me/webhooks", withMaxBody(MaxBodyXS, HandleListWebhooks(s.db)))
+			r.Post("/me/webhooks", withMaxBody(MaxBodyS, idempotent(s.db, HandleCreateWebhook(s.db))))
+			r.Delete("/me/webhooks/{id}", withMaxBody(MaxBodyXS, HandleDeleteWebhook(s.db)))
+			r.Post("/me/demo", withMaxBody(MaxBodyXS, s.handleSeedDemoSessions))
+			r.Delete("/me/demo", withMaxBody(MaxBodyXS, s.handleDeleteDemoSessions))
 
 			// Trends - aggregated analytics across sessions
 			r.Get("/trends", withMaxBody(MaxBodyXS, HandleGetTrends(s.db)))
 
+			// Current month's spend and month-end projection (cached per user)
+			r.Get("/analytics/forecast", withMaxBody(MaxBodyXS, HandleGetCostForecast(s.db)))
+
+			// Cost and duration grouped by the pull request sessions produced
+			r.Get("/analytics/outcomes", withMaxBody(MaxBodyXS, HandleGetOutcomes(s.db)))
+
 			// Organization analytics (requires ENABLE_ORG_ANALYTICS=true).
 			// WARNING: exposes all users' names, emails, session counts, and costs
 			// to any authenticated user. Only enable for trusted-team deployments.
@@ -389,32 +461,59 @@ func (s *Server) SetupRoutes() http.Handler {
 				r.Get("/org/repos", withMaxBody(MaxBodyXS, HandleGetOrgRepos(s.db)))
 			}
 
+			// Anonymized cross-user leaderboard (requires
+			// ENABLE_ANALYTICS_LEADERBOARD=true). Super-admin only.
+			if s.leaderboardEnabled {
+				r.With(admin.Middleware(s.db)).Get("/analytics/leaderboard", withMaxBody(MaxBodyXS, adminHandlers.HandleAnalyticsLeaderboard))
+			}
+
 			// API key management
 			r.Post("/keys", withMaxBody(MaxBodyM, HandleCreateAPIKey(s.db)))
 			r.Get("/keys", withMaxBody(MaxBodyXS, HandleListAPIKeys(s.db)))
+			r.Get("/keys/{id}/usage", withMaxBody(MaxBodyXS, HandleGetAPIKeyUsage(s.db)))
 			r.Delete("/keys/{id}", withMaxBody(MaxBodyXS, HandleDeleteAPIKey(s.db)))
 
 			// Session listing (requires auth)
 			r.Get("/sessions", withMaxBody(MaxBodyXS, HandleListSessions(s.db)))
+			// Smart recap search, straight from the recap table (own sessions)
+			r.Get("/sessions/search/recap", withMaxBody(MaxBodyXS, HandleSearchSmartRecaps(s.db)))
 			// Session title update (requires auth + ownership)
 			r.Patch("/sessions/{id}/title", withMaxBody(MaxBodyS, HandleUpdateSessionTitle(s.db)))
+			// Session PR link (requires auth + ownership)
+			r.Patch("/sessions/{id}/pr-url", withMaxBody(MaxBodyS, HandleUpdateSessionPRURL(s.db)))
+			// Session visibility: private or org (requires auth + ownership)
+			r.Patch("/sessions/{id}/visibility", withMaxBody(MaxBodyXS, HandleUpdateSessionVisibility(s.db)))
 
 			// Session deletion
 			r.Delete("/sessions/{id}", withMaxBody(MaxBodyXS, HandleDeleteSession(s.db, s.storage)))
+			r.Post("/sessions/bulk-delete", withMaxBody(MaxBodyL, HandleBulkDeleteSessions(s.db, s.storage)))
+
+			// Duplicate merge (soft delete, owner-only)
+			r.Post("/sessions/{id}/merge-duplicate", withMaxBody(MaxBodyXS, HandleMergeDuplicateSession(s.db)))
+			// Session merge (source transcript appended, source deleted; owner-only)
+			r.Post("/sessions/{id}/merge", withMaxBody(MaxBodyXS, HandleMergeSession(s.db, s.storage)))
+			// Split session repair (secondary placed at an offset and kept as merged; owner-only)
+			r.Post("/sessions/merge", withMaxBody(MaxBodyXS, HandleMergeSplitSessions(s.db, s.storage)))
 
 			// Session sharing
 			// Note: FRONTEND_URL is validated at startup in main.go
 			frontendURL := os.Getenv("FRONTEND_URL")
-			r.Post("/sessions/{id}/share", withMaxBody(MaxBodyM, HandleCreateShare(s.db, frontendURL, s.emailService, s.sharesEnabled, s.shareDailyQuota)))
+			r.Post("/sessions/{id}/share", withMaxBody(MaxBodyM, idempotent(s.db, HandleCreateShare(s.db, frontendURL, s.emailService, s.sharesEnabled, s.shareDailyQuota))))
 			r.Get("/sessions/{id}/shares", withMaxBody(MaxBodyXS, HandleListShares(s.db)))
 			r.Get("/shares", withMaxBody(MaxBodyXS, HandleListAllUserShares(s.db)))
 			r.Delete("/shares/{shareID}", withMaxBody(MaxBodyXS, HandleRevokeShare(s.db)))
+			r.Get("/sessions/{id}/share/{shareID}/stats", withMaxBody(MaxBodyXS, HandleGetShareAccessStats(s.db)))
 
 			// GitHub links - delete (owner-only)
 			r.Delete("/sessions/{id}/github-links/{linkID}", withMaxBody(MaxBodyXS, HandleDeleteGitHubLink(s.db)))
 
-			// Smart recap regeneration (owner-only)
-			r.Post("/sessions/{id}/analytics/smart-recap/regenerate", withMaxBody(MaxBodyXS, HandleRegenerateSmartRecap(s.db, s.storage)))
+			// Smart recap read and regeneration (owner-only)
+			r.Get("/sessions/{id}/recap", withMaxBody(MaxBodyXS, HandleGetSessionRecap(s.db)))
+			r.Post("/sessions/{id}/analytics/smart-recap/regenerate", withMaxBody(MaxBodyXS, idempotent(s.db, HandleRegenerateSmartRecap(s.db, s.storage))))
+			r.Get("/sessions/{id}/cards/smart-recap/alternatives", withMaxBody(MaxBodyXS, HandleGetSmartRecapAlternative(s.db, s.storage)))
+
+			// AI session title generation (owner-only, counts against recap quota)
+			r.Post("/sessions/{id}/generate-title", withMaxBody(MaxBodyXS, HandleGenerateSessionTitle(s.db)))
 
 			// Client error reporting (for frontend observability)
 			r.Post("/client-errors", withMaxBody(MaxBodyM, ratelimit.HandlerFunc(s.clientErrorLimiter, HandleReportClientErrors())))
@@ -431,7 +530,9 @@ func (s *Server) SetupRoutes() http.Handler {
 				r.Post("/users/{id}/activate", withMaxBody(MaxBodyXS, adminHandlers.HandleActivateUserAPI))
 				r.Post("/users/{id}/grant-admin", withMaxBody(MaxBodyXS, adminHandlers.HandleGrantAdminAPI))
 				r.Post("/users/{id}/revoke-admin", withMaxBody(MaxBodyXS, adminHandlers.HandleRevokeAdminAPI))
+				r.Put("/users/{id}/rate-limit-tier", withMaxBody(MaxBodyXS, adminHandlers.HandleSetRateLimitTierAPI))
 				r.Delete("/users/{id}", withMaxBody(MaxBodyXS, adminHandlers.HandleDeleteUserAPI))
+				r.Post("/users/{id}/merge", withMaxBody(MaxBodyXS, adminHandlers.HandleMergeUserAPI))
 				r.Get("/system-shares", withMaxBody(MaxBodyXS, adminHandlers.HandleListSystemSharesAPI))
 				r.Post("/system-shares", withMaxBody(MaxBodyXS, adminHandlers.HandleCreateSystemShareAPI))
 
@@ -457,6 +558,38 @@ func (s *Server) SetupRoutes() http.Handler {
 				// active pricing table — surfaces a newly-released unpriced model
 				// without grepping the "unknown model for pricing" WARN logs.
 				r.Get("/unpriced-models", withMaxBody(MaxBodyXS, adminHandlers.HandleUnpricedModels))
+
+				// Per-user feature flags for gradual rollout of new analytics
+				// features; evaluated by internal/features with a 5-minute cache.
+				r.Get("/feature-flags", withMaxBody(MaxBodyXS, adminHandlers.HandleListFeatureFlags))
+				r.Post("/feature-flags", withMaxBody(MaxBodyM, adminHandlers.HandleCreateFeatureFlag))
+				r.Patch("/feature-flags/{name}", withMaxBody(MaxBodyM, adminHandlers.HandleUpdateFeatureFlag))
+
+				// Organizations: members read each other's org-visible sessions.
+				r.Get("/orgs", withMaxBody(MaxBodyXS, adminHandlers.HandleListOrgs))
+				r.Post("/orgs", withMaxBody(MaxBodyXS, adminHandlers.HandleCreateOrg))
+				r.Put("/orgs/{id}/members/{userID}", withMaxBody(MaxBodyXS, adminHandlers.HandleAddOrgMember))
+				r.Delete("/orgs/{id}/members/{userID}", withMaxBody(MaxBodyXS, adminHandlers.HandleRemoveOrgMember))
+
+				// Last retention prune per table, recorded by the worker.
+				r.Get("/retention", withMaxBody(MaxBodyXS, adminHandlers.HandleRetentionStats))
+
+				// API keys refused new sessions by the sync/init velocity limits.
+				r.Get("/velocity", withMaxBody(MaxBodyXS, adminHandlers.HandleSessionVelocity))
+
+				// Invoice reconciliation: imported Anthropic usage exports vs the
+				// summed card estimates, and per-month cost correction factors
+				// that /trends?apply_correction=true reports alongside estimates.
+				r.Post("/reconciliation/import", withMaxBody(MaxBodyXL, adminHandlers.HandleImportUsage))
+				r.Get("/reconciliation", withMaxBody(MaxBodyXS, adminHandlers.HandleGetReconciliation))
+				r.Put("/reconciliation/correction-factors/{month}", withMaxBody(MaxBodyXS, adminHandlers.HandleSetCorrectionFactor))
+				r.Delete("/reconciliation/correction-factors/{month}", withMaxBody(MaxBodyXS, adminHandlers.HandleDeleteCorrectionFactor))
+
+				// Point-in-time model prices (model_pricing table)
+				r.Post("/model-pricing", withMaxBody(MaxBodyXS, adminHandlers.HandleSetModelPricing))
+
+				// Rendered email templates with sample data (?kind=invite).
+				r.Get("/email-preview", withMaxBody(MaxBodyXS, adminHandlers.HandleEmailPreview))
 			})
 		})
 
@@ -468,18 +601,46 @@ func (s *Server) SetupRoutes() http.Handler {
 			r.Get("/sessions/by-external-id/{external_id}", withMaxBody(MaxBodyXS, HandleLookupSessionByExternalID(s.db)))
 			// GitHub links - create (CLI or web)
 			r.Post("/sessions/{id}/github-links", withMaxBody(MaxBodyM, HandleCreateGitHubLink(s.db)))
+			// Raw tool calls parsed from the transcript (owner-only, CLI or web)
+			r.Get("/sessions/{id}/tool-calls", withMaxBody(MaxBodyXS, HandleListToolCalls(s.db, s.storage)))
+			// Search index freshness (owner-only, CLI or web)
+			r.Get("/sessions/{id}/search-status", withMaxBody(MaxBodyXS, HandleGetSearchStatus(s.db)))
+			// Rebuild the search index now instead of on the worker's next cycle (owner-only, CLI or web)
+			r.Post("/sessions/{id}/reindex", withMaxBody(MaxBodyXS, HandleReindexSession(s.db, s.storage)))
+			// Reassign a session's external_id, re-keying its chunks (owner-only, CLI or web)
+			r.Patch("/sessions/{id}/external-id", withMaxBody(MaxBodyS, HandleReassignExternalID(s.db, s.storage)))
 		})
 
 		// Canonical session access (CF-132) - supports optional authentication
 		// Works for: owner access, public shares, system shares, recipient shares
 		r.Group(func(r chi.Router) {
 			r.Use(auth.OptionalAuth(s.db, s.oauthConfig))
-			r.Get("/sessions/{id}", withMaxBody(MaxBodyXS, HandleGetSession(s.db)))
+			r.Get("/sessions/{id}", withMaxBody(MaxBodyXS, HandleGetSession(s.db, s.shareAccessLog)))
 			// Canonical shared sync file access endpoint (CF-132)
 			// Uses same session access logic as /sessions/{id}
-			r.Get("/sessions/{id}/sync/file", withMaxBody(MaxBodyXS, s.handleCanonicalSyncFileRead))
+			r.Get("/sessions/{id}/sync/file", withMaxBody(MaxBodyXS, withReplicaHeader(s.handleCanonicalSyncFileRead)))
+			// Transcript sample for list-page previews (first and last chunks only)
+			r.Get("/sessions/{id}/preview", withMaxBody(MaxBodyXS, withReplicaHeader(s.handleSessionPreview)))
+			// Live sync progress as server-sent events (one stream per open dashboard)
+			r.Get("/sessions/{id}/sync/events", withMaxBody(MaxBodyXS, HandleSyncProgressEvents(s.db, s.syncProgress, syncProgressHeartbeat)))
+			// Whole-session download as one .jsonl or .zip artifact
+			r.Get("/sessions/{id}/download", withMaxBody(MaxBodyXS, withReplicaHeader(s.handleDownloadSession)))
 			// Session analytics (computed from JSONL, cached in DB)
 			r.Get("/sessions/{id}/analytics", withMaxBody(MaxBodyXS, HandleGetSessionAnalytics(s.db, s.storage)))
+			// Per-turn conversation detail (written alongside the cached cards)
+			r.Get("/sessions/{id}/cards/conversation/turns", withMaxBody(MaxBodyXS, HandleListConversationTurns(s.db)))
+			// Per-redaction detail with obfuscated context (written alongside the cached cards)
+			r.Get("/sessions/{id}/cards/redactions/details", withMaxBody(MaxBodyXS, HandleListRedactionEvents(s.db)))
+			// Cumulative token usage across the transcript (written alongside the cached cards)
+			r.Get("/sessions/{id}/tokens/series", withMaxBody(MaxBodyXS, HandleGetTokenSeries(s.db)))
+			// Projected final cost at the current spend rate (written alongside the cached cards)
+			r.Get("/sessions/{id}/cards/cost-projection", withMaxBody(MaxBodyXS, HandleGetCostProjection(s.db)))
+			// Per-file reads/writes of the code activity card (written alongside the cached cards)
+			r.Get("/sessions/{id}/cards/code-activity/files", withMaxBody(MaxBodyXS, HandleListCodeActivityFiles(s.db)))
+			// Sampled Edit/MultiEdit diff hunks (written alongside the cached cards)
+			r.Get("/sessions/{id}/cards/code-changes", withMaxBody(MaxBodyXS, HandleGetCodeChanges(s.db)))
+			// Typed per-agent/per-skill breakdown and agent call tree (from the agents and skills card)
+			r.Get("/sessions/{id}/cards/agents-and-skills/details", withMaxBody(MaxBodyXS, HandleGetAgentsAndSkillsDetails(s.db)))
 			// GitHub links - list (viewable by anyone with session access)
 			r.Get("/sessions/{id}/github-links", withMaxBody(MaxBodyXS, HandleListGitHubLinks(s.db)))
 		})
@@ -489,11 +650,11 @@ func (s *Server) SetupRoutes() http.Handler {
 		// Uses canonical access model (CF-132) for session access control
 		r.Group(func(r chi.Router) {
 			r.Use(auth.RequireAPIKey(s.db, s.oauthConfig.AllowedEmailDomains))
-			r.Use(ratelimit.MiddlewareWithKey(s.externalReadLimiter, ratelimit.UserKeyFunc(auth.GetUserIDContextKey())))
+			r.Use(ratelimit.TieredMiddleware(s.externalReadLimiter, ratelimit.UserKeyFunc(auth.GetUserIDContextKey()), auth.RateLimitTierFunc))
 
 			r.Get("/sessions/{id}/condensed-transcript", withMaxBody(MaxBodyXS, s.handleCondensedTranscript))
 			r.Get("/sessions/{id}/files", withMaxBody(MaxBodyXS, s.handleListSessionFiles))
-			r.Get("/sessions/{id}/files/download", withMaxBody(MaxBodyXS, s.handleDownloadSessionFile))
+			r.Get("/sessions/{id}/files/download", withMaxBody(MaxBodyXS, withReplicaHeader(s.handleDownloadSessionFile)))
 		})
 
 	})
@@ -534,6 +695,43 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// readyCheckTimeout caps the primary ping and storage checks of GET /health/ready.
+const readyCheckTimeout = 2 * time.Second
+
+// ReadyResponse is the body of GET /health/ready.
+type ReadyResponse struct {
+	Status  string           `json:"status"`  // "ok" or "unavailable"
+	Primary string           `json:"primary"` // "ok" or the ping error
+	Replica db.ReplicaStatus `json:"replica"`
+	Storage *storage.Health  `json:"storage,omitempty"` // nil when no storage is configured
+}
+
+// handleReady reports whether the server can serve requests: 503 when the
+// primary database does not answer a ping. The read replica's state and lag
+// are informational; reads fall back to the primary when it is unusable, so it
+// never fails readiness. Object storage is reported per endpoint and is
+// informational too: a storage outage breaks reads and syncs, not the
+// server, and restarting it would not help.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+	defer cancel()
+
+	resp := ReadyResponse{Status: "ok", Primary: "ok"}
+	status := http.StatusOK
+	if err := s.db.Conn().PingContext(ctx); err != nil {
+		logger.Ctx(r.Context()).Warn("readiness check: primary database unreachable", "error", err)
+		resp.Status = "unavailable"
+		resp.Primary = err.Error()
+		status = http.StatusServiceUnavailable
+	}
+	resp.Replica = s.db.ReplicaStatus(ctx)
+	if s.storage != nil {
+		health := s.storage.Health(ctx)
+		resp.Storage = &health
+	}
+	respondJSON(w, status, resp)
+}
+
 // handleDeleteAccountHelp serves a help page explaining how to request account deletion
 func (s *Server) handleDeleteAccountHelp(w http.ResponseWriter, r *http.Request) {
 	html := fmt.Sprintf(`<!DOCTYPE html>
@@ -790,17 +988,21 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
-// respondError writes an error JSON response
+// respondError writes an error JSON response carrying the generic code for
+// the status (see httputil.CodeForStatus)
 func respondError(w http.ResponseWriter, status int, message string) {
-	respondJSON(w, status, map[string]string{
-		"error": message,
-	})
+	httputil.RespondError(w, status, message)
+}
+
+// respondErrorCode writes an error JSON response with a specific error code
+func respondErrorCode(w http.ResponseWriter, status int, code httputil.ErrorCode, message string) {
+	httputil.RespondErrorCode(w, status, code, message)
 }
 
 // respondStorageError returns an appropriate error response based on the storage error type
 func respondStorageError(w http.ResponseWriter, err error, defaultMsg string) {
 	if errors.Is(err, storage.ErrObjectNotFound) {
-		respondError(w, http.StatusNotFound, "File not found in storage")
+		respondErrorCode(w, http.StatusNotFound, httputil.CodeFileNotFound, "File not found in storage")
 		return
 	}
 	if errors.Is(err, storage.ErrAccessDenied) {
@@ -815,6 +1017,19 @@ func respondStorageError(w http.ResponseWriter, err error, defaultMsg string) {
 	respondError(w, http.StatusInternalServerError, defaultMsg)
 }
 
+// respondTranscriptArchived answers a raw transcript read or upload for a
+// session whose chunks the worker's transcript retention has deleted. Cards,
+// search and session metadata are still served for such sessions.
+func respondTranscriptArchived(w http.ResponseWriter) {
+	respondErrorCode(w, http.StatusGone, httputil.CodeTranscriptArchived, "Session transcript has been archived")
+}
+
+// respondQueryTimeout answers a request whose analytics query ran past
+// DB_QUERY_TIMEOUT_SECONDS (db.ErrQueryTimeout).
+func respondQueryTimeout(w http.ResponseWriter) {
+	httputil.RespondQueryTimeout(w, db.LoadQueryTimeout())
+}
+
 // requireUserID extracts the authenticated user ID from the request context.
 // If the user is not authenticated, it writes a 401 response and returns false.
 func requireUserID(w http.ResponseWriter, r *http.Request) (int64, bool) {
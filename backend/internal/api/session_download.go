@@ -0,0 +1,276 @@
+package api
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/storage"
+)
+
+// maxDownloadSlugLen caps the title portion of a download filename.
+const maxDownloadSlugLen = 60
+
+// sessionDownloadMetadata is the metadata.json entry of a zip download.
+type sessionDownloadMetadata struct {
+	Session    *db.SessionDetail `json:"session"`
+	SmartRecap *SmartRecapExport `json:"smart_recap,omitempty"`
+	ExportedAt time.Time         `json:"exported_at"`
+}
+
+// downloadFile is one synced file queued for a download, with its chunk keys
+// listed up front so storage errors can still be reported with a status code.
+type downloadFile struct {
+	entryName string
+	fileName  string
+	chunkKeys []string
+}
+
+// handleDownloadSession streams a session as a single artifact.
+// Uses canonical access model (CF-132) — owner, recipient, system, and public shares.
+// GET /api/v1/sessions/{id}/download?format=jsonl|zip
+//
+// format=jsonl (the default) serves the merged transcript, byte-for-byte the
+// same as a full GET /sessions/{id}/sync/file read. format=zip serves an
+// archive holding the transcript, each agent file under agents/, and a
+// metadata.json with the session row and cached smart recap. Files are
+// downloaded and written one at a time, so only one file's chunks are held in
+// memory. A storage failure after the response has started aborts the
+// connection rather than ending it cleanly, so clients never mistake a
+// truncated download for a complete one.
+func (s *Server) handleDownloadSession(w http.ResponseWriter, r *http.Request) {
+	log := logger.Ctx(r.Context())
+
+	sessionID := chi.URLParam(r, "id")
+	if sessionID == "" {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "session_id is required")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = "jsonl"
+	case "jsonl", "zip":
+	default:
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "format must be jsonl or zip")
+		return
+	}
+
+	dbCtx, dbCancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer dbCancel()
+
+	result := RequireCanonicalRead(dbCtx, w, s.db, sessionID)
+	if result == nil {
+		return
+	}
+	session := result.Session
+
+	files := classifySessionFiles(session.Files)
+	if files == nil {
+		respondErrorCode(w, http.StatusNotFound, httputil.CodeFileNotFound, "No transcript available for this session")
+		return
+	}
+	if session.TranscriptArchivedAt != nil {
+		respondTranscriptArchived(w)
+		return
+	}
+
+	sessionStore := &dbsession.Store{DB: s.db}
+	sessionUserID, externalID, provider, err := sessionStore.GetSessionOwnerExternalIDAndProvider(dbCtx, sessionID)
+	if err != nil {
+		log.Error("Failed to get session info", "error", err, "session_id", sessionID)
+		respondError(w, http.StatusInternalServerError, "Failed to get session info")
+		return
+	}
+
+	// Queue the transcript, plus the agent files for a zip.
+	used := make(map[string]bool)
+	queue := []downloadFile{{
+		entryName: zipEntryName("", files.transcript.FileName, used),
+		fileName:  files.transcript.FileName,
+	}}
+	if format == "zip" {
+		for _, af := range files.agents {
+			queue = append(queue, downloadFile{
+				entryName: zipEntryName("agents/", af.FileName, used),
+				fileName:  af.FileName,
+			})
+		}
+	}
+
+	listCtx, listCancel := context.WithTimeout(r.Context(), StorageTimeout)
+	defer listCancel()
+	for i := range queue {
+		queue[i].chunkKeys, err = s.storage.ListChunks(listCtx, sessionUserID, provider, externalID, queue[i].fileName)
+		if err != nil {
+			log.Error("Failed to list chunks", "error", err, "session_id", sessionID, "file_name", queue[i].fileName)
+			respondStorageError(w, err, "Failed to list chunks")
+			return
+		}
+	}
+	if len(queue[0].chunkKeys) == 0 {
+		respondErrorCode(w, http.StatusNotFound, httputil.CodeFileNotFound, "File not found")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// downloadChunks fetches one file's chunks, first extending the write
+	// deadline so a slow download doesn't get the connection killed mid-stream.
+	downloadChunks := func(f downloadFile) ([]storage.ChunkInfo, error) {
+		timeout := chunkDownloadTimeout(len(f.chunkKeys))
+		if err := rc.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+			log.Warn("Failed to extend write deadline", "error", err)
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		return s.storage.DownloadChunks(ctx, f.chunkKeys)
+	}
+
+	if format == "jsonl" {
+		chunks, err := downloadChunks(queue[0])
+		if err != nil {
+			respondStorageError(w, err, "Failed to download file chunk")
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+sessionDownloadFilename(session, "jsonl")+`"`)
+		w.WriteHeader(http.StatusOK)
+		if err := storage.WriteMergedChunks(w, chunks); err != nil {
+			log.Error("Failed to stream session download", "error", err, "session_id", sessionID)
+			panic(http.ErrAbortHandler)
+		}
+		log.Info("Session download",
+			"session_id", sessionID,
+			"format", format,
+			"access_type", result.AccessInfo.AccessType,
+			"viewer_user_id", result.ViewerUserID)
+		return
+	}
+
+	// Fetch the cached smart recap before the response starts (no generation triggered).
+	metadata := sessionDownloadMetadata{Session: session, ExportedAt: time.Now().UTC()}
+	smartCard, err := analytics.NewStore(s.db.Conn()).GetSmartRecapCard(dbCtx, sessionID)
+	if err == nil && smartCard != nil && smartCard.HasValidVersion() {
+		metadata.SmartRecap = convertSmartRecap(smartCard)
+	}
+	modified := session.FirstSeen
+	if session.LastSyncAt != nil {
+		modified = *session.LastSyncAt
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+sessionDownloadFilename(session, "zip")+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(w)
+	for _, f := range queue {
+		if len(f.chunkKeys) == 0 {
+			continue
+		}
+		chunks, err := downloadChunks(f)
+		if err == nil {
+			err = writeZipEntry(zw, f.entryName, modified, func(entry io.Writer) error {
+				return storage.WriteMergedChunks(entry, chunks)
+			})
+		}
+		if err != nil {
+			log.Error("Failed to stream session download", "error", err, "session_id", sessionID, "file_name", f.fileName)
+			panic(http.ErrAbortHandler)
+		}
+	}
+	err = writeZipEntry(zw, "metadata.json", modified, func(entry io.Writer) error {
+		enc := json.NewEncoder(entry)
+		enc.SetIndent("", "  ")
+		return enc.Encode(metadata)
+	})
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		log.Error("Failed to finish session download", "error", err, "session_id", sessionID)
+		panic(http.ErrAbortHandler)
+	}
+
+	log.Info("Session download",
+		"session_id", sessionID,
+		"format", format,
+		"file_count", len(queue),
+		"access_type", result.AccessInfo.AccessType,
+		"viewer_user_id", result.ViewerUserID)
+}
+
+// writeZipEntry adds a deflated entry to zw and fills it with write.
+func writeZipEntry(zw *zip.Writer, name string, modified time.Time, write func(io.Writer) error) error {
+	entry, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	return write(entry)
+}
+
+// zipEntryName turns a client-supplied sync file name into a safe archive
+// path under dir. Path separators and other unsafe characters become '_',
+// leading dots are dropped so no entry can be hidden or climb out with "..",
+// and a numeric suffix keeps names that collide after sanitizing distinct.
+func zipEntryName(dir, fileName string, used map[string]bool) string {
+	base := strings.TrimLeft(sanitizeContentDispositionFilename(fileName), ".")
+	if base == "" {
+		base = "file.jsonl"
+	}
+	name := dir + base
+	if used[name] {
+		stem, ext := base, ""
+		if i := strings.LastIndexByte(base, '.'); i > 0 {
+			stem, ext = base[:i], base[i:]
+		}
+		for n := 2; used[name]; n++ {
+			name = dir + stem + "-" + strconv.Itoa(n) + ext
+		}
+	}
+	used[name] = true
+	return name
+}
+
+// sessionDownloadFilename builds the Content-Disposition filename for a
+// download: the first-seen date and a slug of the session title, e.g.
+// "2026-03-01-fix-the-login-redirect.zip".
+func sessionDownloadFilename(session *db.SessionDetail, ext string) string {
+	return sanitizeContentDispositionFilename(sessionDownloadStem(session) + "." + ext)
+}
+
+// sessionDownloadStem is a download filename without its extension, e.g.
+// "2026-03-01-fix-the-login-redirect". It contains only [a-z0-9-].
+func sessionDownloadStem(session *db.SessionDetail) string {
+	title := db.ResolveSessionTitle(session.CustomTitle, session.AIGeneratedTitle,
+		session.SuggestedSessionTitle, session.Summary, session.FirstUserMessage)
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if b.Len() >= maxDownloadSlugLen {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+	slug := strings.TrimRight(b.String(), "-")
+	if slug == "" {
+		slug = "session"
+	}
+	return session.FirstSeen.UTC().Format("2006-01-02") + "-" + slug
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+func TestSessionDownloadFilename(t *testing.T) {
+	firstSeen := time.Date(2026, 3, 1, 23, 30, 0, 0, time.FixedZone("PST", -8*60*60))
+	ptr := func(s string) *string { return &s }
+
+	cases := []struct {
+		name    string
+		session db.SessionDetail
+		want    string
+	}{
+		{
+			name:    "custom title wins",
+			session: db.SessionDetail{CustomTitle: ptr("Fix the Login Redirect!"), Summary: ptr("ignored")},
+			want:    "2026-03-02-fix-the-login-redirect.zip",
+		},
+		{
+			name:    "header injection is flattened",
+			session: db.SessionDetail{Summary: ptr("a\"\r\nContent-Type: text/html")},
+			want:    "2026-03-02-a-content-type-text-html.zip",
+		},
+		{
+			name:    "path segments are flattened",
+			session: db.SessionDetail{Summary: ptr("../../etc/passwd")},
+			want:    "2026-03-02-etc-passwd.zip",
+		},
+		{
+			name:    "no usable characters falls back",
+			session: db.SessionDetail{FirstUserMessage: ptr("日本語")},
+			want:    "2026-03-02-session.zip",
+		},
+		{
+			name:    "untitled falls back",
+			session: db.SessionDetail{},
+			want:    "2026-03-02-session.zip",
+		},
+		{
+			name:    "long titles are capped",
+			session: db.SessionDetail{Summary: ptr("word abcdefghij abcdefghij abcdefghij abcdefghij abcdefghij abcdefghij abcdefghij")},
+			want:    "2026-03-02-word-abcdefghij-abcdefghij-abcdefghij-abcdefghij-abcdefghij.zip",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.session.FirstSeen = firstSeen
+			if got := sessionDownloadFilename(&tc.session, "zip"); got != tc.want {
+				t.Errorf("sessionDownloadFilename() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestZipEntryName(t *testing.T) {
+	used := make(map[string]bool)
+	cases := []struct {
+		dir, in, want string
+	}{
+		{"", "transcript.jsonl", "transcript.jsonl"},
+		{"agents/", "agent-abc.jsonl", "agents/agent-abc.jsonl"},
+		{"agents/", "../../etc/passwd", "agents/_.._etc_passwd"},
+		{"agents/", "..", "agents/file.jsonl"},
+		{"agents/", ".hidden", "agents/hidden"},
+		{"agents/", "agent abc.jsonl", "agents/agent_abc.jsonl"},
+		{"agents/", "agent_abc.jsonl", "agents/agent_abc-2.jsonl"},
+		{"agents/", "agent?abc.jsonl", "agents/agent_abc-3.jsonl"},
+	}
+	for _, tc := range cases {
+		if got := zipEntryName(tc.dir, tc.in, used); got != tc.want {
+			t.Errorf("zipEntryName(%q, %q) = %q, want %q", tc.dir, tc.in, got, tc.want)
+		}
+	}
+}
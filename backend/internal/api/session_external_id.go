@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/storage"
+	"github.com/ConfabulousDev/confab-web/internal/validation"
+	"github.com/go-chi/chi/v5"
+)
+
+// ReassignExternalIDRequest is the request body for
+// PATCH /api/v1/sessions/{id}/external-id
+type ReassignExternalIDRequest struct {
+	ExternalID string `json:"external_id"`
+}
+
+// ReassignExternalIDResponse is the response for
+// PATCH /api/v1/sessions/{id}/external-id
+type ReassignExternalIDResponse struct {
+	SessionID          string `json:"session_id"`
+	ExternalID         string `json:"external_id"`
+	PreviousExternalID string `json:"previous_external_id"`
+}
+
+// HandleReassignExternalID gives a session a new external ID, for a client
+// tool that regenerated the ID of a session it still wants to resume: the
+// session's chunks are copied to the new ID's storage prefix, the session row
+// is updated, and the old chunks are deleted. Sync files, cards and
+// everything else keyed by the session ID stay as they are, so a sync/init
+// with the new ID resumes the session. Owner-only. 409 if another of the
+// owner's sessions of the same type already has the ID, or if the session
+// synced during the copy. Meant for a client that has stopped syncing under
+// the old ID.
+// PATCH /api/v1/sessions/{id}/external-id
+func HandleReassignExternalID(database *db.DB, store *storage.S3Storage) http.HandlerFunc {
+	sessionStore := &dbsession.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		var req ReassignExternalIDRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeInvalidRequestBody, "Invalid request body")
+			return
+		}
+		if err := validation.ValidateExternalID(req.ExternalID); err != nil {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, err.Error())
+			return
+		}
+
+		dbCtx, dbCancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer dbCancel()
+
+		externalID, provider, err := sessionStore.VerifySessionOwnership(dbCtx, sessionID, userID)
+		if err != nil {
+			if errors.Is(err, db.ErrSessionNotFound) {
+				respondErrorCode(w, http.StatusNotFound, httputil.CodeSessionNotFound, "Session not found")
+				return
+			}
+			if errors.Is(err, db.ErrForbidden) {
+				respondError(w, http.StatusForbidden, "Access denied")
+				return
+			}
+			log.Error("Failed to verify session ownership", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to retrieve session information")
+			return
+		}
+
+		resp := ReassignExternalIDResponse{SessionID: sessionID, ExternalID: req.ExternalID, PreviousExternalID: externalID}
+		if req.ExternalID == externalID {
+			respondJSON(w, http.StatusOK, resp)
+			return
+		}
+
+		archived, err := sessionStore.IsTranscriptArchived(dbCtx, sessionID)
+		if err != nil {
+			log.Error("Failed to check transcript archive", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to retrieve session information")
+			return
+		}
+		if archived {
+			respondTranscriptArchived(w)
+			return
+		}
+
+		// Refuse a taken ID before copying anything: the copy would land in
+		// the other session's storage prefix. The DB update re-checks.
+		inUse, err := sessionStore.ExternalIDInUse(dbCtx, sessionID, req.ExternalID)
+		if err != nil {
+			log.Error("Failed to check external ID", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to retrieve session information")
+			return
+		}
+		if inUse {
+			respondErrorCode(w, http.StatusConflict, httputil.CodeConflict, "Another session already has this external_id")
+			return
+		}
+
+		files, err := sessionStore.ListSyncFileStates(dbCtx, sessionID)
+		if err != nil {
+			log.Error("Failed to list sync files", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to get sync state")
+			return
+		}
+
+		// Copy first: until the DB commit nothing reads the new prefix, and
+		// on any failure the copies are removed again.
+		storageCtx, storageCancel := context.WithTimeout(r.Context(), StorageTimeout)
+		defer storageCancel()
+		copied, err := store.CopyAllSessionChunks(storageCtx, userID, provider, externalID, req.ExternalID)
+		cleanup := func() {
+			cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), StorageTimeout)
+			defer cancel()
+			for _, key := range copied {
+				if err := store.Delete(cleanupCtx, key); err != nil {
+					log.Warn("Failed to remove copied chunk after aborted reassignment", "error", err, "key", key)
+				}
+			}
+		}
+		if err != nil {
+			log.Error("Failed to copy chunks for external ID reassignment", "error", err, "session_id", sessionID)
+			cleanup()
+			respondStorageError(w, err, "Failed to copy file chunks")
+			return
+		}
+
+		reassignCtx, reassignCancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer reassignCancel()
+		if err := sessionStore.ReassignExternalID(reassignCtx, userID, sessionID, externalID, req.ExternalID, files); err != nil {
+			cleanup()
+			switch {
+			case errors.Is(err, db.ErrExternalIDTaken):
+				respondErrorCode(w, http.StatusConflict, httputil.CodeConflict, "Another session already has this external_id")
+			case errors.Is(err, db.ErrReassignConflict):
+				respondErrorCode(w, http.StatusConflict, httputil.CodeConflict, "The session synced during the reassignment; try again")
+			case errors.Is(err, db.ErrSessionNotFound):
+				respondErrorCode(w, http.StatusNotFound, httputil.CodeSessionNotFound, "Session not found")
+			default:
+				log.Error("Failed to reassign external ID", "error", err, "session_id", sessionID)
+				respondError(w, http.StatusInternalServerError, "Failed to reassign external_id")
+			}
+			return
+		}
+
+		// The old chunks are now unreferenced copies.
+		deleteCtx, deleteCancel := context.WithTimeout(r.Context(), StorageTimeout)
+		defer deleteCancel()
+		if err := store.DeleteAllSessionChunks(deleteCtx, userID, provider, externalID); err != nil {
+			log.Error("Failed to delete chunks under the previous external ID",
+				"error", err,
+				"session_id", sessionID,
+				"external_id", externalID)
+			// Continue anyway - chunks will be orphaned but the reassignment is done
+		}
+
+		// Audit log: external ID reassigned
+		log.Info("Session external ID reassigned",
+			"session_id", sessionID,
+			"external_id", req.ExternalID,
+			"previous_external_id", externalID,
+			"chunks", len(copied))
+
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
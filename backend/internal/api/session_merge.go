@@ -0,0 +1,356 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/storage"
+)
+
+// MergeSessionRequest is the request body for POST /api/v1/sessions/{id}/merge
+type MergeSessionRequest struct {
+	SourceSessionID string `json:"source_session_id"`
+}
+
+// MergedFile reports where one of the source's files landed in the target.
+type MergedFile struct {
+	SourceFileName string `json:"source_file_name"`
+	FileName       string `json:"file_name"`
+	FirstLine      int    `json:"first_line"`
+	LastLine       int    `json:"last_line"`
+}
+
+// MergeSessionResponse is the response for POST /api/v1/sessions/{id}/merge
+type MergeSessionResponse struct {
+	SessionID       string       `json:"session_id"`
+	MergedSessionID string       `json:"merged_session_id"`
+	Files           []MergedFile `json:"files"`
+}
+
+// MergeSplitSessionsRequest is the request body for POST /api/v1/sessions/merge
+type MergeSplitSessionsRequest struct {
+	PrimarySessionID   string `json:"primary_session_id"`
+	SecondarySessionID string `json:"secondary_session_id"`
+	// SecondaryLineOffset is the primary transcript line the secondary's
+	// transcript follows. Required.
+	SecondaryLineOffset *int `json:"secondary_line_offset"`
+}
+
+// sessionMergeOptions distinguishes the two merge endpoints.
+type sessionMergeOptions struct {
+	// transcriptOffset, when set, is where the caller expects the source
+	// transcript to start in the target's; see HandleMergeSplitSessions.
+	transcriptOffset *int
+	// keepSource marks the source merged into the target instead of
+	// deleting it.
+	keepSource bool
+}
+
+// mergeSide is one session of a merge, as the handler resolved it.
+type mergeSide struct {
+	id, externalID, provider string
+	files                    []db.SyncFileState
+}
+
+// HandleMergeSession folds another of the caller's sessions into this one:
+// the source's transcript lines are appended after the target's (chunks are
+// re-keyed, not rewritten), its agent files are appended to or added beside
+// the target's, the target's cards are dropped so analytics recompute, and
+// the source session is deleted. Owner-only on both sessions. Both files of
+// every pair must be contiguous in storage so the merged transcript has no
+// holes. Meant for finished sessions: a client still syncing either one will
+// see its next chunk rejected.
+// POST /api/v1/sessions/{id}/merge
+func HandleMergeSession(database *db.DB, store *storage.S3Storage) http.HandlerFunc {
+	sessionStore := &dbsession.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		targetID := chi.URLParam(r, "id")
+		if targetID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		var req MergeSessionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeInvalidRequestBody, "Invalid request body")
+			return
+		}
+		if req.SourceSessionID == "" {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "source_session_id is required")
+			return
+		}
+		if req.SourceSessionID == targetID {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "A session cannot be merged into itself")
+			return
+		}
+
+		mergeSessions(w, r, sessionStore, store, userID, targetID, req.SourceSessionID, sessionMergeOptions{})
+	}
+}
+
+// HandleMergeSplitSessions repairs a session that was split in two, e.g. by
+// a sync init retried after a network error: the secondary's transcript is
+// placed after line secondary_line_offset of the primary's, its other files
+// are merged as HandleMergeSession does, the primary's cards are dropped so
+// analytics recompute, and the secondary is marked merged into the primary
+// rather than deleted. Owner-only on both sessions. The offset must be the
+// primary transcript's line count: a smaller one would overlap lines the
+// primary already has (409), a larger one would leave a gap (400).
+// POST /api/v1/sessions/merge
+func HandleMergeSplitSessions(database *db.DB, store *storage.S3Storage) http.HandlerFunc {
+	sessionStore := &dbsession.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		var req MergeSplitSessionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeInvalidRequestBody, "Invalid request body")
+			return
+		}
+		if req.PrimarySessionID == "" || req.SecondarySessionID == "" {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "primary_session_id and secondary_session_id are required")
+			return
+		}
+		if req.SecondaryLineOffset == nil || *req.SecondaryLineOffset < 0 {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "secondary_line_offset must be a non-negative line number")
+			return
+		}
+		if req.PrimarySessionID == req.SecondarySessionID {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "A session cannot be merged into itself")
+			return
+		}
+
+		mergeSessions(w, r, sessionStore, store, userID, req.PrimarySessionID, req.SecondarySessionID, sessionMergeOptions{
+			transcriptOffset: req.SecondaryLineOffset,
+			keepSource:       true,
+		})
+	}
+}
+
+// mergeSessions merges sourceID into targetID for the two merge handlers,
+// which have validated the IDs, and writes the response.
+func mergeSessions(w http.ResponseWriter, r *http.Request, sessionStore *dbsession.Store, store *storage.S3Storage, userID int64, targetID, sourceID string, opts sessionMergeOptions) {
+	log := logger.Ctx(r.Context())
+
+	dbCtx, dbCancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer dbCancel()
+
+	target := mergeSide{id: targetID}
+	source := mergeSide{id: sourceID}
+	for _, side := range []*mergeSide{&target, &source} {
+		var err error
+		side.externalID, side.provider, err = sessionStore.VerifySessionOwnership(dbCtx, side.id, userID)
+		if err != nil {
+			if errors.Is(err, db.ErrSessionNotFound) {
+				respondErrorCode(w, http.StatusNotFound, httputil.CodeSessionNotFound, "Session not found")
+				return
+			}
+			if errors.Is(err, db.ErrForbidden) {
+				respondError(w, http.StatusForbidden, "Access denied")
+				return
+			}
+			log.Error("Failed to verify session ownership", "error", err, "session_id", side.id)
+			respondError(w, http.StatusInternalServerError, "Failed to retrieve session information")
+			return
+		}
+
+		archived, err := sessionStore.IsTranscriptArchived(dbCtx, side.id)
+		if err != nil {
+			log.Error("Failed to check transcript archive", "error", err, "session_id", side.id)
+			respondError(w, http.StatusInternalServerError, "Failed to retrieve session information")
+			return
+		}
+		if archived {
+			respondTranscriptArchived(w)
+			return
+		}
+
+		side.files, err = sessionStore.ListSyncFileStates(dbCtx, side.id)
+		if err != nil {
+			log.Error("Failed to list sync files", "error", err, "session_id", side.id)
+			respondError(w, http.StatusInternalServerError, "Failed to get sync state")
+			return
+		}
+	}
+
+	// Chunk keys are provider-scoped and each provider's transcript
+	// format is its own, so only sessions of one provider concatenate.
+	// Codex threads are also tracked in codex_rollouts by file, which a
+	// merge would orphan.
+	if target.provider != source.provider {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "Sessions from different providers cannot be merged")
+		return
+	}
+	if target.provider == models.ProviderCodex {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "Codex sessions cannot be merged")
+		return
+	}
+
+	plan := dbsession.PlanSessionMerge(target.files, source.files)
+
+	if opts.transcriptOffset != nil {
+		for _, f := range plan {
+			if f.FileType != "transcript" {
+				continue
+			}
+			if offset := *opts.transcriptOffset; offset < f.Offset {
+				respondErrorCode(w, http.StatusConflict, httputil.CodeConflict,
+					fmt.Sprintf("secondary_line_offset %d overlaps the primary transcript, which has %d lines", offset, f.Offset))
+				return
+			} else if offset > f.Offset {
+				respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed,
+					fmt.Sprintf("secondary_line_offset %d would leave a gap after the primary transcript's %d lines", offset, f.Offset))
+				return
+			}
+		}
+	}
+
+	storageCtx, storageCancel := context.WithTimeout(r.Context(), StorageTimeout)
+	defer storageCancel()
+
+	// Validate every file involved before copying anything.
+	sourceKeys := make([][]string, len(plan))
+	for i, f := range plan {
+		if f.Offset+f.Lines > storage.MaxLineNumber {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed,
+				fmt.Sprintf("Merged file %s would exceed %d lines", f.TargetFileName, storage.MaxLineNumber))
+			return
+		}
+
+		keys, err := store.ListChunks(storage.PrimaryOnly(storageCtx), userID, source.provider, source.externalID, f.SourceFileName)
+		if err == nil {
+			err = storage.CheckContiguous(keys, f.Lines)
+		}
+		if err == nil && !f.NewFile {
+			var targetKeys []string
+			targetKeys, err = store.ListChunks(storage.PrimaryOnly(storageCtx), userID, target.provider, target.externalID, f.TargetFileName)
+			if err == nil {
+				err = storage.CheckContiguous(targetKeys, f.Offset)
+			}
+		}
+		if errors.Is(err, storage.ErrChunksNotContiguous) {
+			respondErrorCode(w, http.StatusConflict, httputil.CodeConflict,
+				fmt.Sprintf("File %s cannot be merged: %v", f.SourceFileName, err))
+			return
+		}
+		if err != nil {
+			log.Error("Failed to list chunks for merge", "error", err, "session_id", source.id, "file_name", f.SourceFileName)
+			respondStorageError(w, err, "Failed to read file chunks")
+			return
+		}
+		sourceKeys[i] = keys
+	}
+
+	// Copy the source's chunks into the target. Until the DB commit the
+	// copies sit past the target's last_synced_line; on any failure they
+	// are removed again and the target is unchanged.
+	var copied []string
+	cleanup := func() {
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), StorageTimeout)
+		defer cancel()
+		for _, key := range copied {
+			if err := store.Delete(cleanupCtx, key); err != nil {
+				log.Warn("Failed to remove copied chunk after aborted merge", "error", err, "key", key)
+			}
+		}
+	}
+	for i := range plan {
+		f := &plan[i]
+		for _, key := range sourceKeys[i] {
+			first, last, _ := storage.ParseChunkKey(key)
+			newKey, err := store.CopyChunk(storageCtx, key, userID, target.provider, target.externalID, f.TargetFileName, first+f.Offset, last+f.Offset)
+			if err != nil {
+				log.Error("Failed to copy chunk for merge", "error", err, "session_id", target.id, "key", key)
+				cleanup()
+				respondStorageError(w, err, "Failed to copy file chunks")
+				return
+			}
+			copied = append(copied, newKey)
+			f.Chunks++
+		}
+	}
+
+	mergeCtx, mergeCancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer mergeCancel()
+
+	mergeFn := sessionStore.MergeSessions
+	if opts.keepSource {
+		mergeFn = sessionStore.SoftMergeSessions
+	}
+	if err := mergeFn(mergeCtx, userID, target.id, source.id, plan, analytics.SessionDerivedTableNames); err != nil {
+		cleanup()
+		if errors.Is(err, db.ErrAlreadyMerged) {
+			respondErrorCode(w, http.StatusConflict, httputil.CodeConflict, "A session has already been merged")
+			return
+		}
+		if errors.Is(err, db.ErrMergeConflict) {
+			respondErrorCode(w, http.StatusConflict, httputil.CodeConflict, "A session synced during the merge; try again")
+			return
+		}
+		if errors.Is(err, db.ErrSessionNotFound) {
+			respondErrorCode(w, http.StatusNotFound, httputil.CodeSessionNotFound, "Session not found")
+			return
+		}
+		log.Error("Failed to merge sessions", "error", err, "session_id", target.id, "source_session_id", source.id)
+		respondError(w, http.StatusInternalServerError, "Failed to merge sessions")
+		return
+	}
+
+	// The source row is gone; its chunks are now unreferenced copies. A
+	// kept source still owns them.
+	if !opts.keepSource {
+		deleteCtx, deleteCancel := context.WithTimeout(r.Context(), StorageTimeout)
+		defer deleteCancel()
+		if err := store.DeleteAllSessionChunks(deleteCtx, userID, source.provider, source.externalID); err != nil {
+			log.Error("Failed to delete merged source chunks",
+				"error", err,
+				"session_id", source.id,
+				"external_id", source.externalID)
+			// Continue anyway - chunks will be orphaned but the merge is done
+		}
+	}
+
+	// Audit log: sessions merged
+	log.Info("Sessions merged",
+		"session_id", target.id,
+		"merged_session_id", source.id,
+		"source_kept", opts.keepSource,
+		"files", len(plan),
+		"chunks", len(copied))
+
+	files := make([]MergedFile, 0, len(plan))
+	for _, f := range plan {
+		files = append(files, MergedFile{
+			SourceFileName: f.SourceFileName,
+			FileName:       f.TargetFileName,
+			FirstLine:      f.Offset + 1,
+			LastLine:       f.Offset + f.Lines,
+		})
+	}
+	respondJSON(w, http.StatusOK, MergeSessionResponse{
+		SessionID:       target.id,
+		MergedSessionID: source.id,
+		Files:           files,
+	})
+}
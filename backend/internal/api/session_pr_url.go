@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/validation"
+	"github.com/go-chi/chi/v5"
+)
+
+// UpdateSessionPRURLRequest is the request body for
+// PATCH /api/v1/sessions/{id}/pr-url
+type UpdateSessionPRURLRequest struct {
+	// PRURL is a GitHub pull request or GitLab merge request URL. Use
+	// nil/null (or a blank string) to clear it; the detected URL, if any,
+	// then applies again.
+	PRURL *string `json:"pr_url"`
+}
+
+// HandleUpdateSessionPRURL sets or clears the pull request a session
+// produced. The manual value always wins over the URL detected from the
+// transcript, which this endpoint never touches.
+func HandleUpdateSessionPRURL(database *db.DB) http.HandlerFunc {
+	sessionStore := &dbsession.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		var req UpdateSessionPRURLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeInvalidRequestBody, "Invalid request body")
+			return
+		}
+
+		var prURL *string
+		if req.PRURL != nil && strings.TrimSpace(*req.PRURL) != "" {
+			normalized, err := validation.NormalizePRURL(*req.PRURL)
+			if err != nil {
+				respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, err.Error())
+				return
+			}
+			prURL = &normalized
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		if err := sessionStore.UpdateSessionPRURL(ctx, sessionID, userID, prURL); err != nil {
+			if errors.Is(err, db.ErrSessionNotFound) {
+				respondError(w, http.StatusNotFound, "Session not found")
+				return
+			}
+			if errors.Is(err, db.ErrForbidden) {
+				respondError(w, http.StatusForbidden, "You don't have permission to modify this session")
+				return
+			}
+			log.Error("Failed to update session PR URL", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to update session PR URL")
+			return
+		}
+
+		// Return the updated session, read back from the primary as the
+		// title update does.
+		session, err := sessionStore.GetSessionDetail(db.WithPrimary(ctx), sessionID, userID)
+		if err != nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, session)
+	}
+}
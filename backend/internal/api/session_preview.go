@@ -0,0 +1,256 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// previewHeadLines is the window at the start of the transcript searched for
+// the first user message and the assistant samples.
+const previewHeadLines = 200
+
+// previewTextMaxChars caps the text of each sampled line.
+const previewTextMaxChars = 500
+
+const (
+	defaultPreviewTailLines      = 5
+	maxPreviewTailLines          = 50
+	defaultPreviewAssistantLines = 3
+	maxPreviewAssistantLines     = 10
+)
+
+// SessionPreviewResponse is a cheap sample of a session's transcript for list
+// pages: how it started and where it stands.
+type SessionPreviewResponse struct {
+	TotalLines       int           `json:"total_lines"`
+	FirstUserMessage *PreviewLine  `json:"first_user_message,omitempty"`
+	AssistantLines   []PreviewLine `json:"assistant_lines"`
+	TailLines        []PreviewLine `json:"tail_lines"`
+}
+
+// PreviewLine is one sampled transcript line. Text is the line's message text
+// (string content or text blocks), truncated to 500 characters; it is empty
+// for lines without any, such as tool calls.
+type PreviewLine struct {
+	Line int    `json:"line"`
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// handleSessionPreview samples a session's main transcript: the first user
+// message and a few assistant replies from the first lines, plus the last
+// lines. Only the chunks holding those lines are downloaded, never the
+// middle of the transcript. Uses canonical access model (CF-132).
+// GET /api/v1/sessions/{id}/preview?tail=5&assistant=3
+func (s *Server) handleSessionPreview(w http.ResponseWriter, r *http.Request) {
+	log := logger.Ctx(r.Context())
+
+	sessionID := chi.URLParam(r, "id")
+	if sessionID == "" {
+		respondError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	tailN, ok := parsePreviewCount(w, r, "tail", defaultPreviewTailLines, 1, maxPreviewTailLines)
+	if !ok {
+		return
+	}
+	assistantN, ok := parsePreviewCount(w, r, "assistant", defaultPreviewAssistantLines, 0, maxPreviewAssistantLines)
+	if !ok {
+		return
+	}
+
+	dbCtx, dbCancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer dbCancel()
+
+	result := RequireCanonicalRead(dbCtx, w, s.db, sessionID)
+	if result == nil {
+		return
+	}
+
+	session := result.Session
+	files := classifySessionFiles(session.Files)
+	if files == nil {
+		respondError(w, http.StatusNotFound, "No transcript available for this session")
+		return
+	}
+	if session.TranscriptArchivedAt != nil {
+		respondTranscriptArchived(w)
+		return
+	}
+
+	lastLine := files.transcript.LastSyncedLine
+	if lastLine == 0 {
+		respondJSON(w, http.StatusOK, buildSessionPreview(nil, nil, 0, assistantN))
+		return
+	}
+
+	sessionStore := &dbsession.Store{DB: s.db}
+	sessionUserID, externalID, provider, err := sessionStore.GetSessionOwnerExternalIDAndProvider(dbCtx, sessionID)
+	if err != nil {
+		log.Error("Failed to get session owner info", "error", err, "session_id", sessionID)
+		respondError(w, http.StatusInternalServerError, "Failed to get session info")
+		return
+	}
+
+	storageCtx, storageCancel := context.WithTimeout(r.Context(), StorageTimeout)
+	defer storageCancel()
+
+	keys, err := s.storage.ListChunks(storageCtx, sessionUserID, provider, externalID, files.transcript.FileName)
+	if err != nil {
+		log.Error("Failed to list transcript chunks", "error", err, "session_id", sessionID)
+		respondStorageError(w, err, "Failed to load preview")
+		return
+	}
+
+	// A transcript that fits in the head window is read once and the tail
+	// sliced from it.
+	headLast := min(lastLine, previewHeadLines)
+	head, err := s.storage.DownloadRange(storageCtx, keys, 1, headLast)
+	if err != nil {
+		log.Error("Failed to download transcript head", "error", err, "session_id", sessionID)
+		respondStorageError(w, err, "Failed to load preview")
+		return
+	}
+	tail := head
+	if lastLine > headLast {
+		tail, err = s.storage.DownloadRange(storageCtx, keys, lastLine-tailN+1, lastLine)
+		if err != nil {
+			log.Error("Failed to download transcript tail", "error", err, "session_id", sessionID)
+			respondStorageError(w, err, "Failed to load preview")
+			return
+		}
+	}
+
+	tailFirst := 1
+	if lastLine > headLast {
+		tailFirst = lastLine - tailN + 1
+	}
+	preview := buildSessionPreview(splitPreviewLines(head, 1), lastNPreviewLines(splitPreviewLines(tail, tailFirst), tailN), lastLine, assistantN)
+	respondJSON(w, http.StatusOK, preview)
+}
+
+// parsePreviewCount reads an optional count query param in minN..maxN.
+// Writes a 400 and returns false when it is invalid.
+func parsePreviewCount(w http.ResponseWriter, r *http.Request, name string, defaultN, minN, maxN int) (int, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return defaultN, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < minN || n > maxN {
+		respondError(w, http.StatusBadRequest, name+" must be an integer from "+strconv.Itoa(minN)+" to "+strconv.Itoa(maxN))
+		return 0, false
+	}
+	return n, true
+}
+
+// previewRawLine is one non-blank transcript line and its 1-based line number
+// in the file.
+type previewRawLine struct {
+	n    int
+	data []byte
+}
+
+// splitPreviewLines splits merged chunk bytes that start at line firstLine
+// into lines, dropping blanks. Each kept line keeps its number in the file, so
+// a blank line does not shift the lines after it.
+func splitPreviewLines(data []byte, firstLine int) []previewRawLine {
+	var lines []previewRawLine
+	for i, line := range bytes.Split(data, []byte{'\n'}) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			lines = append(lines, previewRawLine{n: firstLine + i, data: line})
+		}
+	}
+	return lines
+}
+
+func lastNPreviewLines(lines []previewRawLine, n int) []previewRawLine {
+	if len(lines) > n {
+		return lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// buildSessionPreview samples head (lines from line 1) and tail (the lines
+// ending at lastLine). Lines that don't parse are skipped as samples but
+// still listed in the tail, with an empty type and text.
+func buildSessionPreview(head, tail []previewRawLine, lastLine, assistantN int) SessionPreviewResponse {
+	preview := SessionPreviewResponse{
+		TotalLines:     lastLine,
+		AssistantLines: []PreviewLine{},
+		TailLines:      []PreviewLine{},
+	}
+
+	for _, raw := range head {
+		line, err := analytics.ParseLine(raw.data)
+		if err != nil {
+			continue
+		}
+		switch {
+		case preview.FirstUserMessage == nil && line.IsHumanMessage():
+			p := newPreviewLine(raw.n, line)
+			preview.FirstUserMessage = &p
+		case len(preview.AssistantLines) < assistantN && line.Type == "assistant":
+			if p := newPreviewLine(raw.n, line); p.Text != "" {
+				preview.AssistantLines = append(preview.AssistantLines, p)
+			}
+		}
+	}
+
+	for _, raw := range tail {
+		p := PreviewLine{Line: raw.n}
+		if line, err := analytics.ParseLine(raw.data); err == nil {
+			p = newPreviewLine(p.Line, line)
+		}
+		preview.TailLines = append(preview.TailLines, p)
+	}
+	return preview
+}
+
+func newPreviewLine(n int, line *analytics.TranscriptLine) PreviewLine {
+	return PreviewLine{Line: n, Type: line.Type, Text: truncatePreviewText(previewText(line))}
+}
+
+// previewText returns a line's string content or its text blocks joined.
+func previewText(line *analytics.TranscriptLine) string {
+	if line.Message == nil || line.Message.Content == nil {
+		return ""
+	}
+	if s, ok := line.Message.Content.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	blocks, ok := line.Message.Content.([]interface{})
+	if !ok {
+		return ""
+	}
+	var texts []string
+	for _, item := range blocks {
+		block, ok := item.(map[string]interface{})
+		if !ok || block["type"] != "text" {
+			continue
+		}
+		if text, ok := block["text"].(string); ok {
+			texts = append(texts, text)
+		}
+	}
+	return strings.TrimSpace(strings.Join(texts, "\n"))
+}
+
+func truncatePreviewText(s string) string {
+	if utf8.RuneCountInString(s) <= previewTextMaxChars {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:previewTextMaxChars]) + "…"
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+// previewLines splits lines as the handler does a download starting at line
+// first.
+func previewLines(first int, lines ...string) []previewRawLine {
+	return splitPreviewLines([]byte(strings.Join(lines, "\n")+"\n"), first)
+}
+
+func TestBuildSessionPreview(t *testing.T) {
+	head := previewLines(1,
+		`{"type":"system","content":"init"}`,
+		`{"type":"user","message":{"role":"user","content":"Fix the login bug"}}`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","name":"Read"}]}}`,
+		`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1"}]}}`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Found it in auth.go"}]}}`,
+		`not json`,
+		`{"type":"user","message":{"role":"user","content":"Thanks"}}`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Fixed."}]}}`,
+	)
+	tail := previewLines(298,
+		`{"type":"user","message":{"role":"user","content":"Ship it"}}`,
+		`not json`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Done."}]}}`,
+	)
+
+	preview := buildSessionPreview(head, tail, 300, 1)
+
+	if preview.TotalLines != 300 {
+		t.Errorf("TotalLines = %d, want 300", preview.TotalLines)
+	}
+	if preview.FirstUserMessage == nil || preview.FirstUserMessage.Text != "Fix the login bug" || preview.FirstUserMessage.Line != 2 {
+		t.Errorf("FirstUserMessage = %+v, want line 2 %q", preview.FirstUserMessage, "Fix the login bug")
+	}
+	// The tool-only assistant line has no text and is not a sample; the limit
+	// of 1 stops before "Fixed.".
+	if len(preview.AssistantLines) != 1 || preview.AssistantLines[0].Text != "Found it in auth.go" || preview.AssistantLines[0].Line != 5 {
+		t.Errorf("AssistantLines = %+v, want only line 5", preview.AssistantLines)
+	}
+
+	want := []PreviewLine{
+		{Line: 298, Type: "user", Text: "Ship it"},
+		{Line: 299},
+		{Line: 300, Type: "assistant", Text: "Done."},
+	}
+	if len(preview.TailLines) != len(want) {
+		t.Fatalf("TailLines = %+v, want %+v", preview.TailLines, want)
+	}
+	for i := range want {
+		if preview.TailLines[i] != want[i] {
+			t.Errorf("TailLines[%d] = %+v, want %+v", i, preview.TailLines[i], want[i])
+		}
+	}
+}
+
+// Blank lines count toward line numbers: every sample keeps its line in the
+// file, head and tail alike.
+func TestBuildSessionPreview_BlankLinesKeepNumbering(t *testing.T) {
+	head := previewLines(1,
+		`{"type":"system","content":"init"}`,
+		``,
+		`{"type":"user","message":{"role":"user","content":"Fix the login bug"}}`,
+		`   `,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Found it"}]}}`,
+	)
+	tail := previewLines(297,
+		`{"type":"user","message":{"role":"user","content":"Ship it"}}`,
+		``,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Done."}]}}`,
+		``,
+	)
+
+	preview := buildSessionPreview(head, lastNPreviewLines(tail, 4), 300, 3)
+
+	if preview.FirstUserMessage == nil || preview.FirstUserMessage.Line != 3 {
+		t.Errorf("FirstUserMessage = %+v, want line 3", preview.FirstUserMessage)
+	}
+	if len(preview.AssistantLines) != 1 || preview.AssistantLines[0].Line != 5 {
+		t.Errorf("AssistantLines = %+v, want line 5", preview.AssistantLines)
+	}
+	want := []PreviewLine{
+		{Line: 297, Type: "user", Text: "Ship it"},
+		{Line: 299, Type: "assistant", Text: "Done."},
+	}
+	if len(preview.TailLines) != len(want) {
+		t.Fatalf("TailLines = %+v, want %+v", preview.TailLines, want)
+	}
+	for i := range want {
+		if preview.TailLines[i] != want[i] {
+			t.Errorf("TailLines[%d] = %+v, want %+v", i, preview.TailLines[i], want[i])
+		}
+	}
+}
+
+func TestBuildSessionPreview_Empty(t *testing.T) {
+	preview := buildSessionPreview(nil, nil, 0, 3)
+	if preview.FirstUserMessage != nil || preview.AssistantLines == nil || preview.TailLines == nil {
+		t.Errorf("empty preview = %+v, want no first message and empty (non-nil) samples", preview)
+	}
+}
+
+func TestTruncatePreviewText(t *testing.T) {
+	short := strings.Repeat("é", previewTextMaxChars)
+	if got := truncatePreviewText(short); got != short {
+		t.Errorf("text at the limit was truncated")
+	}
+	got := truncatePreviewText(short + "é")
+	if got != short+"…" {
+		t.Errorf("truncatePreviewText() kept %d runes, want %d plus an ellipsis", len([]rune(got)), previewTextMaxChars)
+	}
+}
+
+func TestLastNPreviewLines(t *testing.T) {
+	lines := splitPreviewLines([]byte("a\n\nb\nc\n"), 10)
+	if got := lastNPreviewLines(lines, 2); len(got) != 2 || string(got[0].data) != "b" || got[0].n != 12 || string(got[1].data) != "c" || got[1].n != 13 {
+		t.Errorf("lastNPreviewLines = %+v, want [b@12 c@13]", got)
+	}
+	if got := lastNPreviewLines(lines, 10); len(got) != 3 {
+		t.Errorf("lastNPreviewLines(10) returned %d lines, want all 3", len(got))
+	}
+}
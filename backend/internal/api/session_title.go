@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/anthropic"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/recapquota"
+	"github.com/go-chi/chi/v5"
+)
+
+// titleGenerationInterval is the minimum time between two AI title
+// generations for the same session.
+const titleGenerationInterval = time.Hour
+
+// GenerateSessionTitleResponse is the response for POST /sessions/{id}/generate-title.
+type GenerateSessionTitleResponse struct {
+	Title      string `json:"title"`
+	TokensUsed int    `json:"tokens_used"`
+}
+
+// HandleGenerateSessionTitle asks the smart recap model for a short title
+// based on the session's first user message and summary, and stores it as
+// the session's ai_generated_title. Owner-only; each generation counts
+// against the owner's smart recap quota, and a session can be retitled at
+// most once per titleGenerationInterval.
+func HandleGenerateSessionTitle(database *db.DB) http.HandlerFunc {
+	sessionStore := &dbsession.Store{DB: database}
+	smartRecapConfig := loadSmartRecapConfig()
+
+	var clientOpts []anthropic.ClientOption
+	if smartRecapConfig.BaseURL != "" {
+		clientOpts = append(clientOpts, anthropic.WithBaseURL(smartRecapConfig.BaseURL))
+	}
+	client := anthropic.NewClient(smartRecapConfig.APIKey, clientOpts...)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		if !smartRecapConfig.Enabled {
+			respondError(w, http.StatusNotFound, "Smart recap not available")
+			return
+		}
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		dbCtx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		sessionUserID, _, _, err := sessionStore.GetSessionOwnerExternalIDAndProvider(dbCtx, sessionID)
+		if err != nil {
+			log.Error("Failed to get session", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusNotFound, "Session not found")
+			return
+		}
+
+		if sessionUserID != userID {
+			respondError(w, http.StatusForbidden, "Only the session owner can generate a title")
+			return
+		}
+
+		session, err := sessionStore.GetSessionDetail(dbCtx, sessionID, userID)
+		if err != nil {
+			log.Error("Failed to get session detail", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to get session")
+			return
+		}
+
+		var firstUserMessage, summary string
+		if session.FirstUserMessage != nil {
+			firstUserMessage = *session.FirstUserMessage
+		}
+		if session.Summary != nil {
+			summary = *session.Summary
+		}
+		if analytics.BuildSessionTitleInput(firstUserMessage, summary) == "" {
+			respondError(w, http.StatusBadRequest, "Session has no first user message or summary to title")
+			return
+		}
+
+		quota, err := recapquota.GetOrCreate(dbCtx, database.Conn(), userID)
+		if err != nil {
+			log.Error("Failed to get quota", "error", err, "user_id", userID)
+			respondError(w, http.StatusInternalServerError, "Failed to check quota")
+			return
+		}
+
+		if smartRecapConfig.QuotaEnabled() && quota.ComputeCount >= smartRecapConfig.QuotaLimit {
+			respondError(w, http.StatusForbidden, "Recap generation limit reached")
+			return
+		}
+
+		claim, err := sessionStore.ClaimTitleGeneration(dbCtx, sessionID, titleGenerationInterval)
+		if err != nil {
+			if errors.Is(err, db.ErrSessionNotFound) {
+				respondError(w, http.StatusNotFound, "Session not found")
+				return
+			}
+			log.Error("Failed to claim title generation", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to generate title")
+			return
+		}
+		if !claim.Claimed {
+			retryAfter := titleGenerationInterval
+			if claim.LastGeneratedAt != nil {
+				retryAfter = claim.LastGeneratedAt.Add(titleGenerationInterval).Sub(claim.ClaimedAt)
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			respondError(w, http.StatusTooManyRequests, "A title was generated for this session within the last hour")
+			return
+		}
+
+		result, err := analytics.GenerateSessionTitle(r.Context(), client, smartRecapConfig.Model, firstUserMessage, summary)
+		if err != nil {
+			log.Error("Failed to generate session title", "error", err, "session_id", sessionID)
+			// Detached context: the request may have been cancelled, and the
+			// slot should be freed either way.
+			releaseCtx, releaseCancel := context.WithTimeout(context.WithoutCancel(r.Context()), DatabaseTimeout)
+			defer releaseCancel()
+			if releaseErr := sessionStore.ReleaseTitleGeneration(releaseCtx, sessionID, claim); releaseErr != nil {
+				log.Error("Failed to release title generation", "error", releaseErr, "session_id", sessionID)
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to generate title")
+			return
+		}
+
+		saveCtx, saveCancel := context.WithTimeout(context.WithoutCancel(r.Context()), DatabaseTimeout)
+		defer saveCancel()
+
+		if err := sessionStore.SetAIGeneratedTitle(saveCtx, sessionID, result.Title); err != nil {
+			log.Error("Failed to save generated title", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to save title")
+			return
+		}
+		if err := recapquota.IncrementForSession(saveCtx, database.Conn(), userID, sessionID); err != nil {
+			log.Error("Failed to increment recap quota", "error", err, "user_id", userID)
+		}
+
+		respondJSON(w, http.StatusOK, GenerateSessionTitleResponse{
+			Title:      result.Title,
+			TokensUsed: result.TokensUsed(),
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// UpdateSessionVisibilityRequest is the request body for
+// PATCH /api/v1/sessions/{id}/visibility
+type UpdateSessionVisibilityRequest struct {
+	Visibility string `json:"visibility"` // "private" or "org"
+}
+
+// HandleUpdateSessionVisibility sets who besides the owner and explicit
+// shares can read a session: nobody ("private", the default) or members of
+// the owner's organizations ("org"). Owner-only; org members can read an
+// org-visible session but never modify it.
+func HandleUpdateSessionVisibility(database *db.DB) http.HandlerFunc {
+	sessionStore := &dbsession.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		var req UpdateSessionVisibilityRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeInvalidRequestBody, "Invalid request body")
+			return
+		}
+		if !db.IsValidSessionVisibility(req.Visibility) {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "visibility must be private or org")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		if err := sessionStore.UpdateSessionVisibility(ctx, sessionID, userID, req.Visibility); err != nil {
+			if errors.Is(err, db.ErrSessionNotFound) {
+				respondError(w, http.StatusNotFound, "Session not found")
+				return
+			}
+			if errors.Is(err, db.ErrForbidden) {
+				respondError(w, http.StatusForbidden, "You don't have permission to modify this session")
+				return
+			}
+			log.Error("Failed to update session visibility", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to update session visibility")
+			return
+		}
+
+		// Return the updated session, read back from the primary as the
+		// title update does.
+		session, err := sessionStore.GetSessionDetail(db.WithPrimary(ctx), sessionID, userID)
+		if err != nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, session)
+	}
+}
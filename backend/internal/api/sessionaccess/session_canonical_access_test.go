@@ -38,7 +38,7 @@ func TestHandleGetSession_OwnerAccess(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
@@ -77,7 +77,7 @@ func TestHandleGetSession_PublicShareAccess_Unauthenticated(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
@@ -120,7 +120,7 @@ func TestHandleGetSession_PublicShareAccess_Authenticated(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
@@ -159,7 +159,7 @@ func TestHandleGetSession_PublicShareHidesOwnerEmail(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	api.HandleGetSession(env.DB)(w, req)
+	api.HandleGetSession(env.DB, nil)(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
 
@@ -198,7 +198,7 @@ func TestHandleGetSession_RecipientShareExposesOwnerEmail(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	api.HandleGetSession(env.DB)(w, req)
+	api.HandleGetSession(env.DB, nil)(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
 
@@ -260,7 +260,7 @@ func TestHandleGetSession_OwnerSeesFullGitInfo(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	api.HandleGetSession(env.DB)(w, req)
+	api.HandleGetSession(env.DB, nil)(w, req)
 	testutil.AssertStatus(t, w, http.StatusOK)
 
 	var session db.SessionDetail
@@ -300,7 +300,7 @@ func TestHandleGetSession_PublicShareRedactsGitInfo(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	api.HandleGetSession(env.DB)(w, req)
+	api.HandleGetSession(env.DB, nil)(w, req)
 	testutil.AssertStatus(t, w, http.StatusOK)
 
 	assertNoGitSecretsInBody(t, w.Body.String())
@@ -346,7 +346,7 @@ func TestHandleGetSession_RecipientRedactsGitInfo(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	api.HandleGetSession(env.DB)(w, req)
+	api.HandleGetSession(env.DB, nil)(w, req)
 	testutil.AssertStatus(t, w, http.StatusOK)
 
 	assertNoGitSecretsInBody(t, w.Body.String())
@@ -388,7 +388,7 @@ func TestHandleGetSession_SystemShareAccess(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
@@ -427,7 +427,7 @@ func TestHandleGetSession_RecipientShareAccess(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
@@ -464,7 +464,7 @@ func TestHandleGetSession_NoAccess_Authenticated(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	// Should return 404 (not found) to not reveal session existence
@@ -492,7 +492,7 @@ func TestHandleGetSession_NoAccess_Unauthenticated(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	// Should return 404 (not found) to not reveal session existence
@@ -522,7 +522,7 @@ func TestHandleGetSession_SystemShareRequiresAuth(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	// Should return 401 (system shares require auth - prompt user to sign in)
@@ -553,7 +553,7 @@ func TestHandleGetSession_PrivateShareRequiresAuth(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	// Should return 401 (private shares require auth - prompt user to sign in)
@@ -590,7 +590,7 @@ func TestHandleGetSession_InactiveOwnerBlocksAccess(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	// Should return 403 (forbidden due to inactive owner)
@@ -615,7 +615,7 @@ func TestHandleGetSession_SessionNotFound(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusNotFound)
@@ -648,7 +648,7 @@ func TestHandleGetSession_OwnerHostnameUsernameVisible(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
@@ -695,7 +695,7 @@ func TestHandleGetSession_SharedAccessHostnameUsernameHidden(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
@@ -742,7 +742,7 @@ func TestHandleGetSession_InvalidUUID(t *testing.T) {
 			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 			w := httptest.NewRecorder()
-			handler := api.HandleGetSession(env.DB)
+			handler := api.HandleGetSession(env.DB, nil)
 			handler(w, req)
 
 			// All invalid IDs should return 404 or 400, never 500
@@ -784,7 +784,7 @@ func TestHandleGetSession_ExpiredPublicShare(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	// Expired share = no access = 404
@@ -822,7 +822,7 @@ func TestHandleGetSession_ExpiredSystemShare(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	// Expired share = no access = 404
@@ -860,7 +860,7 @@ func TestHandleGetSession_ExpiredRecipientShare(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	// Expired share = no access = 404
@@ -892,7 +892,7 @@ func TestHandleGetSession_WrongRecipient(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	// Wrong user = no access = 404
@@ -939,7 +939,7 @@ func TestHandleGetSession_Precedence_OwnerOverRecipient(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
@@ -981,7 +981,7 @@ func TestHandleGetSession_Precedence_RecipientOverSystem(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
@@ -1020,7 +1020,7 @@ func TestHandleGetSession_Precedence_SystemOverPublic(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
@@ -1068,7 +1068,7 @@ func TestHandleGetSession_AllSharesExpired(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	// All shares expired = no access = 404
@@ -1106,7 +1106,7 @@ func TestHandleGetSession_RecipientHostnameUsernameHidden(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
@@ -1154,7 +1154,7 @@ func TestHandleGetSession_SystemShareHostnameUsernameHidden(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
@@ -1198,7 +1198,7 @@ func TestHandleGetSession_InactiveOwnerBlocksOwnerAccess(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	// Deactivated owner = forbidden
@@ -1232,7 +1232,7 @@ func TestHandleGetSession_APIKeyOwnerAccess(t *testing.T) {
 	req = req.WithContext(reqCtx)
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	testutil.AssertStatus(t, w, http.StatusOK)
@@ -1275,7 +1275,7 @@ func TestHandleGetSession_APIKeyCrossUserDenied(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	// Should return 404 (not 401/403) to not reveal session existence
@@ -1303,7 +1303,7 @@ func TestHandleGetSession_APIKeyInvalidDenied(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	// Invalid API key = unauthenticated = 404 (no public share)
@@ -1343,7 +1343,7 @@ func TestHandleGetSession_APIKeyInactiveUserDenied(t *testing.T) {
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	w := httptest.NewRecorder()
-	handler := api.HandleGetSession(env.DB)
+	handler := api.HandleGetSession(env.DB, nil)
 	handler(w, req)
 
 	// Inactive user's API key = unauthenticated = 404 (falls through to no access)
@@ -1402,7 +1402,7 @@ func TestHandleGetSession_InactiveOwnerBlocksAllAccess(t *testing.T) {
 			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 			w := httptest.NewRecorder()
-			handler := api.HandleGetSession(env.DB)
+			handler := api.HandleGetSession(env.DB, nil)
 			handler(w, req)
 
 			// All access types blocked when owner is inactive
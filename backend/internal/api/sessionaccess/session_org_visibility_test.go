@@ -0,0 +1,198 @@
+package sessionaccess_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dborg"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+	"github.com/go-chi/chi/v5"
+)
+
+// =============================================================================
+// Org visibility: org members can read org-visible sessions, never modify them
+// =============================================================================
+
+// orgFixture creates an owner and a member of the same org, plus one
+// org-visible and one private session owned by the owner.
+type orgFixture struct {
+	owner, member       *models.User
+	orgSession, private string
+}
+
+func setupOrgFixture(t *testing.T, env *testutil.TestEnvironment) orgFixture {
+	t.Helper()
+	ctx := context.Background()
+
+	owner := testutil.CreateTestUser(t, env, "owner@example.com", "Owner")
+	member := testutil.CreateTestUser(t, env, "member@example.com", "Member")
+
+	orgs := &dborg.Store{DB: env.DB}
+	org, err := orgs.Create(ctx, "acme")
+	if err != nil {
+		t.Fatalf("failed to create org: %v", err)
+	}
+	for _, u := range []*models.User{owner, member} {
+		if err := orgs.AddMember(ctx, org.ID, u.ID); err != nil {
+			t.Fatalf("failed to add org member: %v", err)
+		}
+	}
+
+	orgSession := testutil.CreateTestSession(t, env, owner.ID, "org-session")
+	private := testutil.CreateTestSession(t, env, owner.ID, "private-session")
+	setVisibility(t, env, owner.ID, orgSession, db.SessionVisibilityOrg)
+
+	return orgFixture{owner: owner, member: member, orgSession: orgSession, private: private}
+}
+
+func setVisibility(t *testing.T, env *testutil.TestEnvironment, ownerID int64, sessionID, visibility string) {
+	t.Helper()
+	w := serveSessionRequest(t, api.HandleUpdateSessionVisibility(env.DB), "PATCH",
+		"/api/v1/sessions/"+sessionID+"/visibility", map[string]string{"visibility": visibility}, sessionID, ownerID)
+	testutil.AssertStatus(t, w, http.StatusOK)
+}
+
+func serveSessionRequest(t *testing.T, handler http.HandlerFunc, method, url string, body interface{}, sessionID string, userID int64) *httptest.ResponseRecorder {
+	t.Helper()
+	req := testutil.AuthenticatedRequest(t, method, url, body, userID)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", sessionID)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+// TestHandleGetSession_OrgMemberReadsOrgVisible tests that an org member can
+// read an org-visible session, with shared (not owner) treatment
+func TestHandleGetSession_OrgMemberReadsOrgVisible(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	f := setupOrgFixture(t, env)
+
+	w := serveSessionRequest(t, api.HandleGetSession(env.DB, nil), "GET",
+		"/api/v1/sessions/"+f.orgSession, nil, f.orgSession, f.member.ID)
+	testutil.AssertStatus(t, w, http.StatusOK)
+
+	var session db.SessionDetail
+	testutil.ParseJSONResponse(t, w, &session)
+
+	if session.IsOwner == nil || *session.IsOwner {
+		t.Error("expected IsOwner = false for org access")
+	}
+	if session.Visibility != db.SessionVisibilityOrg {
+		t.Errorf("expected visibility %q, got %q", db.SessionVisibilityOrg, session.Visibility)
+	}
+	if session.Hostname != nil {
+		t.Error("expected Hostname = nil for org access")
+	}
+}
+
+// TestHandleGetSession_OrgMemberCannotReadPrivate tests that org membership
+// alone does not expose the owner's private sessions
+func TestHandleGetSession_OrgMemberCannotReadPrivate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	f := setupOrgFixture(t, env)
+
+	w := serveSessionRequest(t, api.HandleGetSession(env.DB, nil), "GET",
+		"/api/v1/sessions/"+f.private, nil, f.private, f.member.ID)
+	testutil.AssertStatus(t, w, http.StatusNotFound)
+}
+
+// TestHandleGetSession_NonMemberCannotReadOrgVisible tests that users outside
+// the owner's orgs get no access to org-visible sessions
+func TestHandleGetSession_NonMemberCannotReadOrgVisible(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	f := setupOrgFixture(t, env)
+	outsider := testutil.CreateTestUser(t, env, "outsider@example.com", "Outsider")
+
+	w := serveSessionRequest(t, api.HandleGetSession(env.DB, nil), "GET",
+		"/api/v1/sessions/"+f.orgSession, nil, f.orgSession, outsider.ID)
+	testutil.AssertStatus(t, w, http.StatusNotFound)
+}
+
+// TestOrgMemberCannotModifySessions tests that org members are refused every
+// write, whether the session is org-visible or private
+func TestOrgMemberCannotModifySessions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	f := setupOrgFixture(t, env)
+
+	title := "hijacked"
+	for _, sessionID := range []string{f.orgSession, f.private} {
+		writes := []struct {
+			name    string
+			handler http.HandlerFunc
+			method  string
+			path    string
+			body    interface{}
+		}{
+			{"title", api.HandleUpdateSessionTitle(env.DB), "PATCH", "/title", api.UpdateSessionTitleRequest{CustomTitle: &title}},
+			{"visibility", api.HandleUpdateSessionVisibility(env.DB), "PATCH", "/visibility", api.UpdateSessionVisibilityRequest{Visibility: db.SessionVisibilityPrivate}},
+			{"delete", api.HandleDeleteSession(env.DB, nil), "DELETE", "", nil},
+		}
+		for _, wr := range writes {
+			w := serveSessionRequest(t, wr.handler, wr.method, "/api/v1/sessions/"+sessionID+wr.path, wr.body, sessionID, f.member.ID)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("%s on %s: expected status 403, got %d: %s", wr.name, sessionID, w.Code, w.Body.String())
+			}
+		}
+	}
+
+	// The org-visible session is untouched and still readable by the member.
+	w := serveSessionRequest(t, api.HandleGetSession(env.DB, nil), "GET",
+		"/api/v1/sessions/"+f.orgSession, nil, f.orgSession, f.member.ID)
+	testutil.AssertStatus(t, w, http.StatusOK)
+
+	var session db.SessionDetail
+	testutil.ParseJSONResponse(t, w, &session)
+	if session.Visibility != db.SessionVisibilityOrg {
+		t.Errorf("expected visibility to stay %q, got %q", db.SessionVisibilityOrg, session.Visibility)
+	}
+	if session.CustomTitle != nil {
+		t.Errorf("expected custom title to stay unset, got %q", *session.CustomTitle)
+	}
+}
+
+// TestHandleUpdateSessionVisibility_InvalidValue tests validation of the
+// visibility value
+func TestHandleUpdateSessionVisibility_InvalidValue(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	owner := testutil.CreateTestUser(t, env, "owner@example.com", "Owner")
+	sessionID := testutil.CreateTestSession(t, env, owner.ID, "test-session")
+
+	w := serveSessionRequest(t, api.HandleUpdateSessionVisibility(env.DB), "PATCH",
+		"/api/v1/sessions/"+sessionID+"/visibility", map[string]string{"visibility": "unlisted"}, sessionID, owner.ID)
+	testutil.AssertStatus(t, w, http.StatusBadRequest)
+}
@@ -0,0 +1,130 @@
+package sessions_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// POST /api/v1/sessions/bulk-delete - Bulk delete owned sessions by filter
+// =============================================================================
+
+type bulkDeleteResponse struct {
+	DryRun       bool `json:"dry_run"`
+	MatchedCount int  `json:"matched_count"`
+	DeletedCount int  `json:"deleted_count"`
+}
+
+func TestBulkDeleteSessions_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+
+	countSessions := func(t *testing.T, userID int64) int {
+		t.Helper()
+		var n int
+		if err := env.DB.QueryRow(env.Ctx, "SELECT COUNT(*) FROM sessions WHERE user_id = $1", userID).Scan(&n); err != nil {
+			t.Fatalf("failed to count sessions: %v", err)
+		}
+		return n
+	}
+
+	t.Run("dry run reports count without deleting", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+		s1 := testutil.CreateTestSession(t, env, user.ID, "bulk-1")
+		s2 := testutil.CreateTestSession(t, env, user.ID, "bulk-2")
+		testutil.CreateTestSession(t, env, user.ID, "bulk-keep")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+		resp, err := client.Post("/api/v1/sessions/bulk-delete", api.BulkDeleteSessionsRequest{
+			SessionIDs: []string{s1, s2},
+			DryRun:     true,
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		var result bulkDeleteResponse
+		testutil.ParseJSON(t, resp, &result)
+		if !result.DryRun || result.MatchedCount != 2 || result.DeletedCount != 0 {
+			t.Errorf("unexpected dry-run result: %+v", result)
+		}
+		if n := countSessions(t, user.ID); n != 3 {
+			t.Errorf("expected 3 sessions after dry run, got %d", n)
+		}
+	})
+
+	t.Run("deletes only matching sessions the caller owns", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		other := testutil.CreateTestUser(t, env, "other@example.com", "Other User")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+		s1 := testutil.CreateTestSession(t, env, user.ID, "bulk-1")
+		testutil.CreateTestSession(t, env, user.ID, "bulk-keep")
+		otherSession := testutil.CreateTestSession(t, env, other.ID, "other-1")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+		resp, err := client.Post("/api/v1/sessions/bulk-delete", api.BulkDeleteSessionsRequest{
+			SessionIDs: []string{s1, otherSession},
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		var result bulkDeleteResponse
+		testutil.ParseJSON(t, resp, &result)
+		if result.DryRun || result.DeletedCount != 1 {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		if n := countSessions(t, user.ID); n != 1 {
+			t.Errorf("expected 1 remaining session for caller, got %d", n)
+		}
+		if n := countSessions(t, other.ID); n != 1 {
+			t.Errorf("expected other user's session to survive, got %d", n)
+		}
+	})
+
+	t.Run("rejects empty filter", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+		testutil.CreateTestSession(t, env, user.ID, "bulk-keep")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+		resp, err := client.Post("/api/v1/sessions/bulk-delete", map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+		if n := countSessions(t, user.ID); n != 1 {
+			t.Errorf("expected session to survive, got %d", n)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package sessions_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// POST/DELETE /api/v1/me/demo - Seed and remove demo sessions
+// =============================================================================
+
+func TestDemoSessions_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "demo-seed@example.com", "Demo Seed")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+	ownSession := testutil.CreateTestSession(t, env, user.ID, "own-session")
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+	seed := func() []string {
+		t.Helper()
+		resp, err := client.Post("/api/v1/me/demo", nil)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result api.DemoSessionsResponse
+		testutil.ParseJSON(t, resp, &result)
+		return result.SessionIDs
+	}
+
+	ids := seed()
+	if len(ids) != 3 {
+		t.Fatalf("seeded %d sessions, want 3", len(ids))
+	}
+
+	var demoCount, fileCount, agentFiles int
+	if err := env.DB.QueryRow(env.Ctx,
+		`SELECT COUNT(*) FROM sessions WHERE user_id = $1 AND is_demo`, user.ID).Scan(&demoCount); err != nil {
+		t.Fatalf("count demo sessions: %v", err)
+	}
+	if err := env.DB.QueryRow(env.Ctx,
+		`SELECT COUNT(*), COUNT(*) FILTER (WHERE file_type = 'agent')
+		 FROM sync_files sf JOIN sessions s ON s.id = sf.session_id
+		 WHERE s.user_id = $1 AND sf.last_synced_line > 0`, user.ID).Scan(&fileCount, &agentFiles); err != nil {
+		t.Fatalf("count sync files: %v", err)
+	}
+	if demoCount != 3 || fileCount != 4 || agentFiles != 1 {
+		t.Errorf("demo sessions = %d, files = %d (agent %d), want 3, 4 (1)", demoCount, fileCount, agentFiles)
+	}
+
+	// Seeding again reuses the same sessions and uploads nothing new.
+	again := seed()
+	if len(again) != len(ids) {
+		t.Fatalf("re-seed returned %d sessions, want %d", len(again), len(ids))
+	}
+	for i := range ids {
+		if again[i] != ids[i] {
+			t.Errorf("re-seed session %d = %s, want %s", i, again[i], ids[i])
+		}
+	}
+
+	resp, err := client.Delete("/api/v1/me/demo")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	testutil.RequireStatus(t, resp, http.StatusOK)
+	var deleted api.DemoSessionsResponse
+	testutil.ParseJSON(t, resp, &deleted)
+	if deleted.DeletedCount != 3 {
+		t.Errorf("deleted_count = %d, want 3", deleted.DeletedCount)
+	}
+
+	var remaining int
+	var ownKept bool
+	if err := env.DB.QueryRow(env.Ctx,
+		`SELECT COUNT(*), BOOL_OR(id = $2) FROM sessions WHERE user_id = $1`, user.ID, ownSession).Scan(&remaining, &ownKept); err != nil {
+		t.Fatalf("count sessions: %v", err)
+	}
+	if remaining != 1 || !ownKept {
+		t.Errorf("remaining sessions = %d (own kept %v), want only the user's own session", remaining, ownKept)
+	}
+}
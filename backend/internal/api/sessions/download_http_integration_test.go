@@ -0,0 +1,183 @@
+package sessions_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// GET /api/v1/sessions/{id}/download
+// =============================================================================
+
+// getDownload fetches a session download and returns the response with its
+// body fully read.
+func getDownload(t *testing.T, client *testutil.TestClient, sessionID, format string) (*http.Response, []byte) {
+	t.Helper()
+	resp, err := client.Get("/api/v1/sessions/" + sessionID + "/download?format=" + format)
+	if err != nil {
+		t.Fatalf("download request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return resp, body
+}
+
+// readZip returns each entry of a zip archive keyed by name.
+func readZip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	entries := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open entry %s: %v", f.Name, err)
+		}
+		entries[f.Name], err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read entry %s: %v", f.Name, err)
+		}
+	}
+	return entries
+}
+
+func TestDownloadSession_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "download@example.com", "Download User")
+	other := testutil.CreateTestUser(t, env, "other@example.com", "Other User")
+	apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Key")
+	ts := setupTestServerWithEnv(t, env)
+	cli := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+	web := testutil.NewTestClient(t, ts).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+	otherWeb := testutil.NewTestClient(t, ts).WithSession(testutil.CreateTestWebSessionWithToken(t, env, other.ID))
+	anon := testutil.NewTestClient(t, ts)
+
+	// One chunk per line, then an overlapping re-upload, so the download has
+	// to go through the multi-chunk merge rather than a single raw chunk.
+	lines := append(exchangeLines("one"), exchangeLines("two")...)
+	sessionID := syncMergeSession(t, cli, "download-session", lines)
+	postMergeChunk(t, cli, sessionID, "download-session.jsonl", "transcript", 2, lines[1:3])
+	postMergeChunk(t, cli, sessionID, "agent-sub1.jsonl", "agent", 1, exchangeLines("agent"))
+
+	transcript := readSyncFile(t, web, sessionID, "download-session.jsonl")
+	agent := readSyncFile(t, web, sessionID, "agent-sub1.jsonl")
+
+	t.Run("jsonl matches the sync/file read byte-for-byte", func(t *testing.T) {
+		resp, body := getDownload(t, web, sessionID, "jsonl")
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		if string(body) != transcript {
+			t.Errorf("jsonl download differs from sync/file:\n got %q\nwant %q", body, transcript)
+		}
+		disposition := resp.Header.Get("Content-Disposition")
+		if !strings.HasPrefix(disposition, `attachment; filename="`) || !strings.HasSuffix(disposition, `.jsonl"`) {
+			t.Errorf("Content-Disposition = %q", disposition)
+		}
+	})
+
+	t.Run("format defaults to jsonl", func(t *testing.T) {
+		resp, body := getDownload(t, web, sessionID, "")
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		if string(body) != transcript {
+			t.Errorf("default download differs from sync/file")
+		}
+	})
+
+	t.Run("zip holds the transcript, agents, and metadata", func(t *testing.T) {
+		resp, body := getDownload(t, web, sessionID, "zip")
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		if ct := resp.Header.Get("Content-Type"); ct != "application/zip" {
+			t.Errorf("Content-Type = %q, want application/zip", ct)
+		}
+		if disposition := resp.Header.Get("Content-Disposition"); !strings.HasSuffix(disposition, `.zip"`) {
+			t.Errorf("Content-Disposition = %q", disposition)
+		}
+
+		entries := readZip(t, body)
+		var names []string
+		for name := range entries {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		want := []string{"agents/agent-sub1.jsonl", "download-session.jsonl", "metadata.json"}
+		if strings.Join(names, ",") != strings.Join(want, ",") {
+			t.Fatalf("zip entries = %v, want %v", names, want)
+		}
+		if string(entries["download-session.jsonl"]) != transcript {
+			t.Errorf("zipped transcript differs from sync/file")
+		}
+		if string(entries["agents/agent-sub1.jsonl"]) != agent {
+			t.Errorf("zipped agent file differs from sync/file")
+		}
+
+		var metadata struct {
+			Session struct {
+				ID  string  `json:"id"`
+				CWD *string `json:"cwd"`
+			} `json:"session"`
+		}
+		if err := json.Unmarshal(entries["metadata.json"], &metadata); err != nil {
+			t.Fatalf("decode metadata.json: %v", err)
+		}
+		if metadata.Session.ID != sessionID {
+			t.Errorf("metadata session id = %q, want %q", metadata.Session.ID, sessionID)
+		}
+		if metadata.Session.CWD == nil {
+			t.Error("owner metadata should include cwd")
+		}
+	})
+
+	t.Run("unknown format is rejected", func(t *testing.T) {
+		resp, _ := getDownload(t, web, sessionID, "tar")
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
+
+	t.Run("no access gets 404", func(t *testing.T) {
+		resp, _ := getDownload(t, otherWeb, sessionID, "zip")
+		testutil.RequireStatus(t, resp, http.StatusNotFound)
+	})
+
+	t.Run("public share allows download with redacted metadata", func(t *testing.T) {
+		testutil.CreateTestShare(t, env, sessionID, true, nil, nil)
+
+		resp, body := getDownload(t, anon, sessionID, "zip")
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		entries := readZip(t, body)
+		if string(entries["download-session.jsonl"]) != transcript {
+			t.Errorf("shared zip transcript differs from sync/file")
+		}
+		var metadata struct {
+			Session map[string]any `json:"session"`
+		}
+		if err := json.Unmarshal(entries["metadata.json"], &metadata); err != nil {
+			t.Fatalf("decode metadata.json: %v", err)
+		}
+		for _, field := range []string{"cwd", "transcript_path", "hostname", "username"} {
+			if _, ok := metadata.Session[field]; ok {
+				t.Errorf("shared metadata leaks %s", field)
+			}
+		}
+	})
+}
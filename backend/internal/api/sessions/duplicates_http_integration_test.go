@@ -0,0 +1,193 @@
+package sessions_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Duplicate detection (content fingerprint) + POST /api/v1/sessions/{id}/merge-duplicate
+// =============================================================================
+
+// syncTranscript creates a session via sync/init and uploads lines in two
+// chunks so the fingerprint is folded incrementally. first_seen is pinned so
+// "earliest" is deterministic, and a summary makes the session listable.
+func syncTranscript(t *testing.T, env *testutil.TestEnvironment, client *testutil.TestClient, externalID string, lines []string, firstSeen time.Time) string {
+	t.Helper()
+
+	resp, err := client.Post("/api/v1/sync/init", api.SyncInitRequest{
+		ExternalID:     externalID,
+		TranscriptPath: "/home/user/project/transcript.jsonl",
+		CWD:            "/home/user/project",
+	})
+	if err != nil {
+		t.Fatalf("sync init failed: %v", err)
+	}
+	testutil.RequireStatus(t, resp, http.StatusOK)
+	var initResp api.SyncInitResponse
+	testutil.ParseJSON(t, resp, &initResp)
+
+	half := len(lines) / 2
+	for _, chunk := range []api.SyncChunkRequest{
+		{SessionID: initResp.SessionID, FileName: "transcript.jsonl", FileType: "transcript", FirstLine: 1, Lines: lines[:half]},
+		{SessionID: initResp.SessionID, FileName: "transcript.jsonl", FileType: "transcript", FirstLine: half + 1, Lines: lines[half:]},
+	} {
+		resp, err := client.Post("/api/v1/sync/chunk", chunk)
+		if err != nil {
+			t.Fatalf("chunk upload failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		resp.Body.Close()
+	}
+
+	if _, err := env.DB.Exec(env.Ctx,
+		`UPDATE sessions SET first_seen = $2, summary = $3 WHERE id = $1`,
+		initResp.SessionID, firstSeen, "summary "+externalID); err != nil {
+		t.Fatalf("failed to update session: %v", err)
+	}
+	return initResp.SessionID
+}
+
+func transcriptLines(n int, tag string) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf(`{"type":"user","n":%d,"tag":%q}`, i, tag)
+	}
+	return lines
+}
+
+func TestDuplicateDetection_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+
+	listSessions := func(t *testing.T, client *testutil.TestClient) map[string]db.SessionListItem {
+		t.Helper()
+		resp, err := client.Get("/api/v1/sessions")
+		if err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result db.SessionListResult
+		testutil.ParseJSON(t, resp, &result)
+		byID := make(map[string]db.SessionListItem, len(result.Sessions))
+		for _, s := range result.Sessions {
+			byID[s.ID] = s
+		}
+		return byID
+	}
+
+	t.Run("identical prefix is flagged and can be merged", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+
+		ts := setupTestServerWithEnv(t, env)
+		cli := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+		web := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+		// Same first FingerprintLines lines; the copy has extra trailing lines.
+		lines := transcriptLines(api.FingerprintLines, "same")
+		now := time.Now().UTC()
+		original := syncTranscript(t, env, cli, "machine-a", lines, now.Add(-time.Hour))
+		duplicate := syncTranscript(t, env, cli, "machine-b", append(append([]string(nil), lines...), `{"extra":1}`), now)
+
+		byID := listSessions(t, web)
+		if byID[original].DuplicateOf != nil {
+			t.Errorf("original should not be flagged, got duplicate_of=%v", *byID[original].DuplicateOf)
+		}
+		if got := byID[duplicate].DuplicateOf; got == nil || *got != original {
+			t.Fatalf("duplicate_of = %v, want %s", got, original)
+		}
+
+		resp, err := web.Post("/api/v1/sessions/"+duplicate+"/merge-duplicate", nil)
+		if err != nil {
+			t.Fatalf("merge failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var merge map[string]interface{}
+		testutil.ParseJSON(t, resp, &merge)
+		if merge["merged_into"] != original {
+			t.Errorf("merged_into = %v, want %s", merge["merged_into"], original)
+		}
+
+		byID = listSessions(t, web)
+		if _, ok := byID[duplicate]; ok {
+			t.Error("merged duplicate should be hidden from the list")
+		}
+		if _, ok := byID[original]; !ok {
+			t.Error("original should remain listed")
+		}
+
+		// Soft delete only: the row is kept with a pointer to the original.
+		var mergedInto string
+		if err := env.DB.QueryRow(env.Ctx, `SELECT merged_into::text FROM sessions WHERE id = $1`, duplicate).Scan(&mergedInto); err != nil {
+			t.Fatalf("merged session row missing: %v", err)
+		}
+		if mergedInto != original {
+			t.Errorf("merged_into column = %s, want %s", mergedInto, original)
+		}
+
+		// Merging again is a conflict, not a second write.
+		resp, err = web.Post("/api/v1/sessions/"+duplicate+"/merge-duplicate", nil)
+		if err != nil {
+			t.Fatalf("merge failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusConflict)
+		resp.Body.Close()
+	})
+
+	t.Run("divergent prefix is not flagged", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+
+		ts := setupTestServerWithEnv(t, env)
+		cli := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+		web := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+		lines := transcriptLines(api.FingerprintLines, "same")
+		divergent := append([]string(nil), lines...)
+		divergent[api.FingerprintLines-1] = `{"type":"user","tag":"different"}`
+
+		now := time.Now().UTC()
+		first := syncTranscript(t, env, cli, "machine-a", lines, now.Add(-time.Hour))
+		second := syncTranscript(t, env, cli, "machine-b", divergent, now)
+
+		byID := listSessions(t, web)
+		if byID[first].DuplicateOf != nil || byID[second].DuplicateOf != nil {
+			t.Error("divergent sessions should not be flagged as duplicates")
+		}
+
+		resp, err := web.Post("/api/v1/sessions/"+second+"/merge-duplicate", nil)
+		if err != nil {
+			t.Fatalf("merge failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusConflict)
+		resp.Body.Close()
+
+		// Detection never deletes: both sessions are still present.
+		var count int
+		if err := env.DB.QueryRow(env.Ctx, `SELECT COUNT(*) FROM sessions WHERE user_id = $1 AND merged_at IS NULL`, user.ID).Scan(&count); err != nil {
+			t.Fatalf("failed to count sessions: %v", err)
+		}
+		if count != 2 {
+			t.Errorf("expected 2 unmerged sessions, got %d", count)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package sessions_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// GET /api/v1/sessions?sort=interesting - Sessions ordered by interest score
+// =============================================================================
+
+func TestListSessionsByInterest_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "interest@example.com", "Interest User")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+	sessionStore := &dbsession.Store{DB: env.DB}
+
+	// Same last activity for all three, so only the cards decide the order.
+	carded := func(externalID, cost string) string {
+		t.Helper()
+		id := testutil.CreateTestSessionFull(t, env, user.ID, externalID, testutil.TestSessionFullOpts{Summary: externalID})
+		if _, err := env.DB.Exec(env.Ctx, `
+			INSERT INTO session_card_session (
+				session_id, version, computed_at, up_to_line,
+				total_messages, user_messages, assistant_messages,
+				human_prompts, tool_results, text_responses, tool_calls, thinking_blocks,
+				duration_ms, models_used,
+				compaction_auto, compaction_manual, compaction_avg_time_ms
+			) VALUES ($1, $2, now(), 100, 0, 0, 0, 0, 0, 0, 0, 0, 0, '[]', 0, 0, 0)`,
+			id, analytics.SessionCardVersion); err != nil {
+			t.Fatalf("insert session card: %v", err)
+		}
+		testutil.SeedTokensV2Card(t, env, id, analytics.TokensV2Data{TotalCostUSD: cost})
+		return id
+	}
+	cheap := carded("cheap", "0.10")
+	pricey := carded("pricey", "12.00")
+	uncarded := testutil.CreateTestSessionFull(t, env, user.ID, "uncarded", testutil.TestSessionFullOpts{Summary: "uncarded"})
+	if _, err := env.DB.Exec(env.Ctx,
+		`UPDATE sessions SET first_seen = '2026-01-01', last_message_at = '2026-01-01'`); err != nil {
+		t.Fatalf("align activity: %v", err)
+	}
+	for _, id := range []string{cheap, pricey, uncarded} {
+		if err := sessionStore.RefreshInterestScore(env.Ctx, id); err != nil {
+			t.Fatalf("RefreshInterestScore(%s): %v", id, err)
+		}
+	}
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+	t.Run("orders by score with the baseline for uncarded sessions", func(t *testing.T) {
+		resp, err := client.Get("/api/v1/sessions?sort=interesting")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		var result db.SessionListResult
+		testutil.ParseJSON(t, resp, &result)
+
+		// Baseline 1 sits between $0.10 and $12.00 under the default weights.
+		want := []string{pricey, uncarded, cheap}
+		if len(result.Sessions) != len(want) {
+			t.Fatalf("got %d sessions, want %d", len(result.Sessions), len(want))
+		}
+		for i, id := range want {
+			if got := result.Sessions[i]; got.ID != id || got.InterestScore == nil {
+				t.Errorf("session %d = %s (score %v), want %s with a score", i, got.ID, got.InterestScore, id)
+			}
+		}
+	})
+
+	t.Run("recent order omits the score", func(t *testing.T) {
+		resp, err := client.Get("/api/v1/sessions")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		var result db.SessionListResult
+		testutil.ParseJSON(t, resp, &result)
+		for _, s := range result.Sessions {
+			if s.InterestScore != nil {
+				t.Errorf("session %s has interest_score %v without sort=interesting", s.ID, *s.InterestScore)
+			}
+		}
+	})
+
+	for name, query := range map[string]string{
+		"rejects an unknown sort":    "sort=cheapest",
+		"rejects sort with a search": "sort=interesting&q=pricey",
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp, err := client.Get("/api/v1/sessions?" + query)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			testutil.RequireStatus(t, resp, http.StatusBadRequest)
+		})
+	}
+}
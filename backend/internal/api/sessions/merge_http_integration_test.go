@@ -0,0 +1,449 @@
+package sessions_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// POST /api/v1/sessions/{id}/merge
+// =============================================================================
+
+// exchangeLines is one user prompt and one assistant reply (100 input / 50
+// output tokens), tagged so the merged read shows which session a line came from.
+func exchangeLines(tag string) []string {
+	return []string{
+		fmt.Sprintf(`{"type":"user","message":{"role":"user","content":"hello %s"},"uuid":"u-%s","timestamp":"2025-01-01T00:00:00Z","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","sessionId":"%s","version":"1.0"}`, tag, tag, tag),
+		fmt.Sprintf(`{"type":"assistant","message":{"id":"msg-%s","type":"message","model":"claude-sonnet-4","role":"assistant","content":[{"type":"text","text":"Hi %s"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":50}},"uuid":"a-%s","timestamp":"2025-01-01T00:00:01Z","parentUuid":"u-%s","isSidechain":false,"userType":"external","cwd":"/test","sessionId":"%s","version":"1.0"}`, tag, tag, tag, tag, tag),
+	}
+}
+
+// syncMergeSession creates a session via sync/init and uploads lines one
+// chunk per line into the transcript {externalID}.jsonl.
+func syncMergeSession(t *testing.T, client *testutil.TestClient, externalID string, lines []string) string {
+	t.Helper()
+	resp, err := client.Post("/api/v1/sync/init", api.SyncInitRequest{
+		ExternalID:     externalID,
+		TranscriptPath: "/home/user/project/" + externalID + ".jsonl",
+		CWD:            "/home/user/project",
+	})
+	if err != nil {
+		t.Fatalf("sync init failed: %v", err)
+	}
+	testutil.RequireStatus(t, resp, http.StatusOK)
+	var initResp api.SyncInitResponse
+	testutil.ParseJSON(t, resp, &initResp)
+
+	for i, line := range lines {
+		postMergeChunk(t, client, initResp.SessionID, externalID+".jsonl", "transcript", i+1, []string{line})
+	}
+	return initResp.SessionID
+}
+
+func postMergeChunk(t *testing.T, client *testutil.TestClient, sessionID, fileName, fileType string, firstLine int, lines []string) {
+	t.Helper()
+	resp, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+		SessionID: sessionID, FileName: fileName, FileType: fileType, FirstLine: firstLine, Lines: lines,
+	})
+	if err != nil {
+		t.Fatalf("chunk upload failed: %v", err)
+	}
+	resp.Body.Close()
+	testutil.RequireStatus(t, resp, http.StatusOK)
+}
+
+func readSyncFile(t *testing.T, client *testutil.TestClient, sessionID, fileName string) string {
+	t.Helper()
+	resp, err := client.Get("/api/v1/sessions/" + sessionID + "/sync/file?file_name=" + fileName)
+	if err != nil {
+		t.Fatalf("read request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	testutil.RequireStatus(t, resp, http.StatusOK)
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+type conversationCard struct {
+	Cards struct {
+		Conversation struct {
+			UserTurns int `json:"user_turns"`
+		} `json:"conversation"`
+	} `json:"cards"`
+}
+
+func getUserTurns(t *testing.T, client *testutil.TestClient, sessionID string) int {
+	t.Helper()
+	resp, err := client.Get("/api/v1/sessions/" + sessionID + "/analytics")
+	if err != nil {
+		t.Fatalf("analytics request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	testutil.RequireStatus(t, resp, http.StatusOK)
+	var result conversationCard
+	testutil.ParseJSON(t, resp, &result)
+	return result.Cards.Conversation.UserTurns
+}
+
+func TestMergeSession_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+
+	type fixture struct {
+		cli, web       *testutil.TestClient
+		userID         int64
+		target, source string
+	}
+	setup := func(t *testing.T) fixture {
+		t.Helper()
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "merge@example.com", "Merge User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Key")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+		ts := setupTestServerWithEnv(t, env)
+		f := fixture{
+			cli:    testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken),
+			web:    testutil.NewTestClient(t, ts).WithSession(sessionToken),
+			userID: user.ID,
+		}
+
+		// Each transcript is named after its session, as Claude Code names
+		// them; both sessions have the same agent file, the source one more.
+		f.target = syncMergeSession(t, f.cli, "merge-target", exchangeLines("t"))
+		f.source = syncMergeSession(t, f.cli, "merge-source", exchangeLines("s"))
+		postMergeChunk(t, f.cli, f.target, "agent-shared.jsonl", "agent", 1, []string{`{"agent":"t1"}`})
+		postMergeChunk(t, f.cli, f.source, "agent-shared.jsonl", "agent", 1, []string{`{"agent":"s1"}`, `{"agent":"s2"}`})
+		postMergeChunk(t, f.cli, f.source, "agent-own.jsonl", "agent", 1, []string{`{"agent":"own"}`})
+		return f
+	}
+
+	merge := func(t *testing.T, client *testutil.TestClient, target, source string) *http.Response {
+		t.Helper()
+		resp, err := client.Post("/api/v1/sessions/"+target+"/merge", api.MergeSessionRequest{SourceSessionID: source})
+		if err != nil {
+			t.Fatalf("merge request failed: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("appends the source and recomputes cards", func(t *testing.T) {
+		f := setup(t)
+
+		if got := getUserTurns(t, f.web, f.target); got != 1 {
+			t.Fatalf("target user_turns before merge = %d, want 1", got)
+		}
+
+		resp := merge(t, f.web, f.target, f.source)
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result api.MergeSessionResponse
+		testutil.ParseJSON(t, resp, &result)
+		if result.SessionID != f.target || result.MergedSessionID != f.source {
+			t.Errorf("response = %+v, want target %s and source %s", result, f.target, f.source)
+		}
+		landed := make(map[string]api.MergedFile, len(result.Files))
+		for _, mf := range result.Files {
+			landed[mf.SourceFileName] = mf
+		}
+		for source, want := range map[string]api.MergedFile{
+			"merge-source.jsonl": {SourceFileName: "merge-source.jsonl", FileName: "merge-target.jsonl", FirstLine: 3, LastLine: 4},
+			"agent-shared.jsonl": {SourceFileName: "agent-shared.jsonl", FileName: "agent-shared.jsonl", FirstLine: 2, LastLine: 3},
+			"agent-own.jsonl":    {SourceFileName: "agent-own.jsonl", FileName: "agent-own.jsonl", FirstLine: 1, LastLine: 1},
+		} {
+			if landed[source] != want {
+				t.Errorf("file %s landed as %+v, want %+v", source, landed[source], want)
+			}
+		}
+
+		// The merged reads are the target's lines followed by the source's.
+		target, source := exchangeLines("t"), exchangeLines("s")
+		wantTranscript := target[0] + "\n" + target[1] + "\n" + source[0] + "\n" + source[1] + "\n"
+		if got := readSyncFile(t, f.web, f.target, "merge-target.jsonl"); got != wantTranscript {
+			t.Errorf("merged transcript = %q, want %q", got, wantTranscript)
+		}
+		if got := readSyncFile(t, f.web, f.target, "agent-shared.jsonl"); got != "{\"agent\":\"t1\"}\n{\"agent\":\"s1\"}\n{\"agent\":\"s2\"}\n" {
+			t.Errorf("merged shared agent = %q", got)
+		}
+		if got := readSyncFile(t, f.web, f.target, "agent-own.jsonl"); got != "{\"agent\":\"own\"}\n" {
+			t.Errorf("moved agent = %q", got)
+		}
+
+		// Cards were dropped and recompute from the merged transcript.
+		var cards int
+		if err := env.DB.QueryRow(env.Ctx,
+			`SELECT COUNT(*) FROM session_card_conversation WHERE session_id = $1`, f.target).Scan(&cards); err != nil {
+			t.Fatalf("count cards: %v", err)
+		}
+		if cards != 0 {
+			t.Error("merge should drop the target's cards")
+		}
+		if got := getUserTurns(t, f.web, f.target); got != 2 {
+			t.Errorf("target user_turns after merge = %d, want 2", got)
+		}
+
+		// The source session and its chunks are gone.
+		var remaining int
+		if err := env.DB.QueryRow(env.Ctx, `SELECT COUNT(*) FROM sessions WHERE id = $1`, f.source).Scan(&remaining); err != nil {
+			t.Fatalf("count sessions: %v", err)
+		}
+		if remaining != 0 {
+			t.Error("source session should be deleted")
+		}
+		keys, err := env.Storage.ListChunks(env.Ctx, f.userID, models.ProviderClaudeCode, "merge-source", "merge-source.jsonl")
+		if err != nil {
+			t.Fatalf("list source chunks: %v", err)
+		}
+		if len(keys) != 0 {
+			t.Errorf("source chunks should be deleted, found %v", keys)
+		}
+
+		var lastSynced, chunkCount int
+		if err := env.DB.QueryRow(env.Ctx,
+			`SELECT last_synced_line, chunk_count FROM sync_files WHERE session_id = $1 AND file_name = 'merge-target.jsonl'`,
+			f.target).Scan(&lastSynced, &chunkCount); err != nil {
+			t.Fatalf("read target sync state: %v", err)
+		}
+		if lastSynced != 4 || chunkCount != 4 {
+			t.Errorf("target transcript last_synced_line=%d chunk_count=%d, want 4 and 4", lastSynced, chunkCount)
+		}
+	})
+
+	t.Run("rejects a source with a hole in its transcript", func(t *testing.T) {
+		f := setup(t)
+
+		keys, err := env.Storage.ListChunks(env.Ctx, f.userID, models.ProviderClaudeCode, "merge-source", "merge-source.jsonl")
+		if err != nil || len(keys) != 2 {
+			t.Fatalf("list source chunks: %v %v", keys, err)
+		}
+		if err := env.Storage.Delete(env.Ctx, keys[0]); err != nil {
+			t.Fatalf("delete chunk: %v", err)
+		}
+
+		resp := merge(t, f.web, f.target, f.source)
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusConflict)
+		var body httputil.ErrorResponse
+		testutil.ParseJSON(t, resp, &body)
+		if body.Code != httputil.CodeConflict {
+			t.Errorf("code = %q, want conflict", body.Code)
+		}
+
+		// Nothing moved: both sessions and the target's transcript are intact.
+		target := exchangeLines("t")
+		if got := readSyncFile(t, f.web, f.target, "merge-target.jsonl"); got != target[0]+"\n"+target[1]+"\n" {
+			t.Errorf("target transcript changed: %q", got)
+		}
+		var remaining int
+		if err := env.DB.QueryRow(env.Ctx, `SELECT COUNT(*) FROM sessions WHERE id = $1`, f.source).Scan(&remaining); err != nil {
+			t.Fatalf("count sessions: %v", err)
+		}
+		if remaining != 1 {
+			t.Error("source session should be kept")
+		}
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		f := setup(t)
+
+		other := testutil.CreateTestUser(t, env, "other@example.com", "Other User")
+		otherSession := testutil.CreateTestSession(t, env, other.ID, "other-session")
+
+		cases := []struct {
+			name   string
+			source string
+			status int
+		}{
+			{"merge into itself", f.target, http.StatusBadRequest},
+			{"missing source", "", http.StatusBadRequest},
+			{"unknown source", "00000000-0000-0000-0000-000000000000", http.StatusNotFound},
+			{"someone else's session", otherSession, http.StatusForbidden},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				resp := merge(t, f.web, f.target, tc.source)
+				resp.Body.Close()
+				testutil.RequireStatus(t, resp, tc.status)
+			})
+		}
+	})
+}
+
+// =============================================================================
+// POST /api/v1/sessions/merge
+// =============================================================================
+
+func TestMergeSplitSessions_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+
+	type fixture struct {
+		web                         *testutil.TestClient
+		userID                      int64
+		primary, secondary, another string
+	}
+	setup := func(t *testing.T) fixture {
+		t.Helper()
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "split@example.com", "Split User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Key")
+		ts := setupTestServerWithEnv(t, env)
+		cli := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+		f := fixture{
+			web:    testutil.NewTestClient(t, ts).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID)),
+			userID: user.ID,
+		}
+		f.primary = syncMergeSession(t, cli, "split-primary", exchangeLines("p"))
+		f.secondary = syncMergeSession(t, cli, "split-secondary", exchangeLines("s"))
+		f.another = syncMergeSession(t, cli, "split-another", exchangeLines("a"))
+		return f
+	}
+
+	merge := func(t *testing.T, client *testutil.TestClient, primary, secondary string, offset *int) *http.Response {
+		t.Helper()
+		resp, err := client.Post("/api/v1/sessions/merge", api.MergeSplitSessionsRequest{
+			PrimarySessionID: primary, SecondarySessionID: secondary, SecondaryLineOffset: offset,
+		})
+		if err != nil {
+			t.Fatalf("merge request failed: %v", err)
+		}
+		return resp
+	}
+	offset := func(n int) *int { return &n }
+
+	mergedInto := func(t *testing.T, sessionID string) *string {
+		t.Helper()
+		var into *string
+		if err := env.DB.QueryRow(env.Ctx, `SELECT merged_into::text FROM sessions WHERE id = $1`, sessionID).Scan(&into); err != nil {
+			t.Fatalf("read merged_into: %v", err)
+		}
+		return into
+	}
+
+	t.Run("places the secondary at the offset and marks it merged", func(t *testing.T) {
+		f := setup(t)
+
+		if got := getUserTurns(t, f.web, f.primary); got != 1 {
+			t.Fatalf("primary user_turns before merge = %d, want 1", got)
+		}
+
+		resp := merge(t, f.web, f.primary, f.secondary, offset(2))
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result api.MergeSessionResponse
+		testutil.ParseJSON(t, resp, &result)
+		want := api.MergedFile{SourceFileName: "split-secondary.jsonl", FileName: "split-primary.jsonl", FirstLine: 3, LastLine: 4}
+		if result.SessionID != f.primary || result.MergedSessionID != f.secondary || len(result.Files) != 1 || result.Files[0] != want {
+			t.Errorf("response = %+v, want primary %s, secondary %s, files [%+v]", result, f.primary, f.secondary, want)
+		}
+
+		primary, secondary := exchangeLines("p"), exchangeLines("s")
+		wantTranscript := primary[0] + "\n" + primary[1] + "\n" + secondary[0] + "\n" + secondary[1] + "\n"
+		if got := readSyncFile(t, f.web, f.primary, "split-primary.jsonl"); got != wantTranscript {
+			t.Errorf("merged transcript = %q, want %q", got, wantTranscript)
+		}
+		var lastSynced, chunkCount int
+		if err := env.DB.QueryRow(env.Ctx,
+			`SELECT last_synced_line, chunk_count FROM sync_files WHERE session_id = $1 AND file_name = 'split-primary.jsonl'`,
+			f.primary).Scan(&lastSynced, &chunkCount); err != nil {
+			t.Fatalf("read primary sync state: %v", err)
+		}
+		if lastSynced != 4 || chunkCount != 4 {
+			t.Errorf("primary transcript last_synced_line=%d chunk_count=%d, want 4 and 4", lastSynced, chunkCount)
+		}
+
+		// The primary recomputes from the merged transcript.
+		if got := getUserTurns(t, f.web, f.primary); got != 2 {
+			t.Errorf("primary user_turns after merge = %d, want 2", got)
+		}
+
+		// The secondary is kept, merged into the primary, with its chunks.
+		if into := mergedInto(t, f.secondary); into == nil || *into != f.primary {
+			t.Errorf("secondary merged_into = %v, want %s", into, f.primary)
+		}
+		keys, err := env.Storage.ListChunks(env.Ctx, f.userID, models.ProviderClaudeCode, "split-secondary", "split-secondary.jsonl")
+		if err != nil {
+			t.Fatalf("list secondary chunks: %v", err)
+		}
+		if len(keys) != 2 {
+			t.Errorf("secondary chunks = %v, want both kept", keys)
+		}
+
+		// A merged secondary can't be merged again.
+		again := merge(t, f.web, f.another, f.secondary, offset(2))
+		defer again.Body.Close()
+		testutil.RequireStatus(t, again, http.StatusConflict)
+		if into := mergedInto(t, f.secondary); into == nil || *into != f.primary {
+			t.Errorf("secondary merged_into after second merge = %v, want %s", into, f.primary)
+		}
+	})
+
+	t.Run("rejects an offset that overlaps the primary", func(t *testing.T) {
+		f := setup(t)
+
+		resp := merge(t, f.web, f.primary, f.secondary, offset(1))
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusConflict)
+		var body httputil.ErrorResponse
+		testutil.ParseJSON(t, resp, &body)
+		if body.Code != httputil.CodeConflict {
+			t.Errorf("code = %q, want conflict", body.Code)
+		}
+
+		primary := exchangeLines("p")
+		if got := readSyncFile(t, f.web, f.primary, "split-primary.jsonl"); got != primary[0]+"\n"+primary[1]+"\n" {
+			t.Errorf("primary transcript changed: %q", got)
+		}
+		if into := mergedInto(t, f.secondary); into != nil {
+			t.Errorf("secondary merged_into = %s, want unmerged", *into)
+		}
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		f := setup(t)
+
+		other := testutil.CreateTestUser(t, env, "other@example.com", "Other User")
+		otherSession := testutil.CreateTestSession(t, env, other.ID, "other-session")
+
+		cases := []struct {
+			name      string
+			secondary string
+			offset    *int
+			status    int
+		}{
+			{"offset leaves a gap", f.secondary, offset(5), http.StatusBadRequest},
+			{"missing offset", f.secondary, nil, http.StatusBadRequest},
+			{"negative offset", f.secondary, offset(-1), http.StatusBadRequest},
+			{"merge into itself", f.primary, offset(2), http.StatusBadRequest},
+			{"missing secondary", "", offset(2), http.StatusBadRequest},
+			{"unknown secondary", "00000000-0000-0000-0000-000000000000", offset(2), http.StatusNotFound},
+			{"someone else's session", otherSession, offset(2), http.StatusForbidden},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				resp := merge(t, f.web, f.primary, tc.secondary, tc.offset)
+				resp.Body.Close()
+				testutil.RequireStatus(t, resp, tc.status)
+			})
+		}
+	})
+}
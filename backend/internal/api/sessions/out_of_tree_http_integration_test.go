@@ -0,0 +1,108 @@
+package sessions_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// GET /api/v1/sessions?has_out_of_tree_access= - Out-of-tree file access flag
+// =============================================================================
+
+func TestListSessionsOutOfTreeAccess_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "out-of-tree@example.com", "Out Of Tree User")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+
+	codeActivity := func(sessionID string, home, temp, external int) {
+		t.Helper()
+		if _, err := env.DB.Exec(env.Ctx, `
+			INSERT INTO session_card_code_activity (
+				session_id, version, computed_at, up_to_line,
+				out_of_tree_home, out_of_tree_temp, out_of_tree_external, out_of_tree_samples
+			) VALUES ($1, $2, now(), 100, $3, $4, $5, '[]')`,
+			sessionID, analytics.CodeActivityCardVersion, home, temp, external); err != nil {
+			t.Fatalf("insert code activity card: %v", err)
+		}
+	}
+	touchedHome := testutil.CreateTestSessionFull(t, env, user.ID, "touched-home", testutil.TestSessionFullOpts{Summary: "home"})
+	codeActivity(touchedHome, 1, 0, 0)
+	touchedEtc := testutil.CreateTestSessionFull(t, env, user.ID, "touched-etc", testutil.TestSessionFullOpts{Summary: "etc"})
+	codeActivity(touchedEtc, 0, 0, 2)
+	inTree := testutil.CreateTestSessionFull(t, env, user.ID, "in-tree", testutil.TestSessionFullOpts{Summary: "in tree"})
+	codeActivity(inTree, 0, 0, 0)
+	noCard := testutil.CreateTestSessionFull(t, env, user.ID, "no-card", testutil.TestSessionFullOpts{Summary: "no card"})
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+	list := func(t *testing.T, query string) map[string]bool {
+		t.Helper()
+		resp, err := client.Get("/api/v1/sessions" + query)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		var result db.SessionListResult
+		testutil.ParseJSON(t, resp, &result)
+		flags := make(map[string]bool, len(result.Sessions))
+		for _, s := range result.Sessions {
+			flags[s.ID] = s.HasOutOfTreeAccess
+		}
+		return flags
+	}
+
+	t.Run("flags each session", func(t *testing.T) {
+		got := list(t, "")
+		want := map[string]bool{touchedHome: true, touchedEtc: true, inTree: false, noCard: false}
+		if len(got) != len(want) {
+			t.Fatalf("got %d sessions, want %d", len(got), len(want))
+		}
+		for id, flag := range want {
+			if got[id] != flag {
+				t.Errorf("session %s has_out_of_tree_access = %v, want %v", id, got[id], flag)
+			}
+		}
+	})
+
+	t.Run("filters to sessions with access", func(t *testing.T) {
+		got := list(t, "?has_out_of_tree_access=true")
+		if len(got) != 2 || !got[touchedHome] || !got[touchedEtc] {
+			t.Errorf("got %v, want only %s and %s", got, touchedHome, touchedEtc)
+		}
+	})
+
+	t.Run("filters to sessions without access", func(t *testing.T) {
+		got := list(t, "?has_out_of_tree_access=false")
+		if _, ok := got[inTree]; len(got) != 2 || !ok {
+			t.Errorf("got %v, want only %s and %s", got, inTree, noCard)
+		}
+		if _, ok := got[noCard]; !ok {
+			t.Errorf("session without a code activity card missing from has_out_of_tree_access=false")
+		}
+	})
+
+	t.Run("rejects a non-boolean value", func(t *testing.T) {
+		resp, err := client.Get("/api/v1/sessions?has_out_of_tree_access=maybe")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,116 @@
+package sessions_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// GET /api/v1/sessions/{id}/preview
+// =============================================================================
+
+// previewChunk returns lines first..last of a transcript whose first line is
+// a user prompt and whose other lines are numbered assistant replies.
+func previewChunk(first, last int) []byte {
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		if n == 1 {
+			b.WriteString(`{"type":"user","message":{"role":"user","content":"Refactor the billing module"}}` + "\n")
+			continue
+		}
+		fmt.Fprintf(&b, `{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"reply %d"}]}}`+"\n", n)
+	}
+	return []byte(b.String())
+}
+
+func TestSessionPreview_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+
+	t.Run("samples the first user message, assistant lines and the tail", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "preview@example.com", "Preview")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "preview-ext")
+		testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 450)
+		for first := 1; first <= 450; first += 150 {
+			testutil.UploadTestChunk(t, env, user.ID, models.ProviderClaudeCode, "preview-ext", "transcript.jsonl",
+				first, first+149, previewChunk(first, first+149))
+		}
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+		resp, err := client.Get("/api/v1/sessions/" + sessionID + "/preview?tail=3&assistant=2")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		var preview api.SessionPreviewResponse
+		testutil.ParseJSON(t, resp, &preview)
+
+		if preview.TotalLines != 450 {
+			t.Errorf("total_lines = %d, want 450", preview.TotalLines)
+		}
+		if preview.FirstUserMessage == nil || preview.FirstUserMessage.Text != "Refactor the billing module" {
+			t.Errorf("first_user_message = %+v, want the opening prompt", preview.FirstUserMessage)
+		}
+		if len(preview.AssistantLines) != 2 || preview.AssistantLines[0].Text != "reply 2" || preview.AssistantLines[1].Text != "reply 3" {
+			t.Errorf("assistant_lines = %+v, want replies 2 and 3", preview.AssistantLines)
+		}
+		if len(preview.TailLines) != 3 || preview.TailLines[0].Line != 448 || preview.TailLines[2].Text != "reply 450" {
+			t.Errorf("tail_lines = %+v, want lines 448-450", preview.TailLines)
+		}
+	})
+
+	t.Run("rejects an out-of-range tail", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "preview@example.com", "Preview")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "preview-ext")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+		resp, err := client.Get("/api/v1/sessions/" + sessionID + "/preview?tail=0")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
+
+	t.Run("another user's private session is not found", func(t *testing.T) {
+		env.CleanDB(t)
+
+		owner := testutil.CreateTestUser(t, env, "owner@example.com", "Owner")
+		other := testutil.CreateTestUser(t, env, "other@example.com", "Other")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, other.ID)
+		sessionID := testutil.CreateTestSession(t, env, owner.ID, "preview-ext")
+		testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 1)
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+		resp, err := client.Get("/api/v1/sessions/" + sessionID + "/preview")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusNotFound)
+	})
+}
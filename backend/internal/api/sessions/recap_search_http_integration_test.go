@@ -0,0 +1,119 @@
+package sessions_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// GET /api/v1/sessions/search/recap
+// =============================================================================
+
+func TestSearchSmartRecaps_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "recap@example.com", "Recap User")
+	other := testutil.CreateTestUser(t, env, "other@example.com", "Other User")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+
+	store := analytics.NewStore(env.DB.Conn())
+	upsert := func(t *testing.T, sessionID, recap string, wentWell ...analytics.AnnotatedItem) {
+		t.Helper()
+		if err := store.UpsertSmartRecapCard(env.Ctx, &analytics.SmartRecapCardRecord{
+			SessionID:  sessionID,
+			Version:    analytics.SmartRecapCardVersion,
+			ComputedAt: time.Now().UTC(),
+			UpToLine:   10,
+			Recap:      recap,
+			WentWell:   wentWell,
+			ModelUsed:  "test-model",
+		}); err != nil {
+			t.Fatalf("UpsertSmartRecapCard: %v", err)
+		}
+	}
+
+	recapSession := testutil.CreateTestSession(t, env, user.ID, "recap-match")
+	upsert(t, recapSession, "Migrated the billing service to the new ledger schema.")
+	itemSession := testutil.CreateTestSession(t, env, user.ID, "recap-item-match")
+	upsert(t, itemSession, "Fixed flaky tests.",
+		analytics.AnnotatedItem{Text: "Kept the ledger invariants documented", MessageID: "msg-1"})
+	unrelated := testutil.CreateTestSession(t, env, user.ID, "recap-unrelated")
+	upsert(t, unrelated, "Tuned the CSS for the dashboard.")
+	othersSession := testutil.CreateTestSession(t, env, other.ID, "recap-other")
+	upsert(t, othersSession, "Also touched the ledger.")
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+	search := func(t *testing.T, query string) api.RecapSearchResponse {
+		t.Helper()
+		resp, err := client.Get("/api/v1/sessions/search/recap?q=" + query)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result api.RecapSearchResponse
+		testutil.ParseJSON(t, resp, &result)
+		return result
+	}
+
+	t.Run("matches recap and suggestion text of own sessions only", func(t *testing.T) {
+		result := search(t, "ledg")
+		got := map[string]string{}
+		for _, r := range result.Results {
+			got[r.SessionID] = r.Snippet
+		}
+		if len(got) != 2 || got[recapSession] == "" || got[itemSession] == "" {
+			t.Fatalf("results = %+v, want the two own ledger sessions", result.Results)
+		}
+		if !strings.Contains(got[recapSession], "**ledger**") {
+			t.Errorf("snippet = %q, want the match highlighted", got[recapSession])
+		}
+		if strings.Contains(got[itemSession], "message_id") || strings.Contains(got[itemSession], "msg-1") {
+			t.Errorf("snippet = %q, want item text without JSON keys or IDs", got[itemSession])
+		}
+	})
+
+	t.Run("regenerated recap is searchable at once", func(t *testing.T) {
+		upsert(t, unrelated, "Replaced the ledger export job.")
+		if got := len(search(t, "ledger").Results); got != 3 {
+			t.Errorf("results = %d, want 3 after the recap changed", got)
+		}
+	})
+
+	t.Run("query without words returns no results", func(t *testing.T) {
+		if result := search(t, "%28%29"); len(result.Results) != 0 {
+			t.Errorf("results = %+v, want none", result.Results)
+		}
+	})
+
+	t.Run("missing q gets 400", func(t *testing.T) {
+		resp, err := client.Get("/api/v1/sessions/search/recap")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
+
+	t.Run("limit out of range gets 400", func(t *testing.T) {
+		resp, err := client.Get("/api/v1/sessions/search/recap?q=ledger&limit=101")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
+}
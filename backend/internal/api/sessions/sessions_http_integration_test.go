@@ -244,6 +244,46 @@ func TestListSessions_HTTP_Integration(t *testing.T) {
 		}
 	})
 
+	t.Run("filters by state", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+
+		testutil.CreateTestSessionFull(t, env, user.ID, "active-1", testutil.TestSessionFullOpts{Summary: "active"})
+		endedID := testutil.CreateTestSessionFull(t, env, user.ID, "ended-1", testutil.TestSessionFullOpts{Summary: "ended"})
+		if _, err := (&dbsession.Store{DB: env.DB}).TransitionState(env.Ctx, endedID,
+			dbsession.StateEnded, dbsession.StateReasonIdleSweep); err != nil {
+			t.Fatalf("TransitionState: %v", err)
+		}
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+		resp, err := client.Get("/api/v1/sessions?state=ended")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		var result db.SessionListResult
+		testutil.ParseJSON(t, resp, &result)
+		if len(result.Sessions) != 1 {
+			t.Fatalf("expected 1 session, got %d", len(result.Sessions))
+		}
+		if got := result.Sessions[0]; got.ID != endedID || got.State != dbsession.StateEnded || got.StateChangedAt.IsZero() {
+			t.Errorf("session = %s in state %q (changed %v), want %s ended", got.ID, got.State, got.StateChangedAt, endedID)
+		}
+
+		resp2, err := client.Get("/api/v1/sessions?state=deleted")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp2.Body.Close()
+		testutil.RequireStatus(t, resp2, http.StatusBadRequest)
+	})
+
 	t.Run("provider=claude-code matches legacy 'Claude Code' rows", func(t *testing.T) {
 		env.CleanDB(t)
 
@@ -72,7 +72,7 @@ func TestSharedSessionPrivacy_OwnerSeesPII(t *testing.T) {
 		req = req.WithContext(context.WithValue(reqCtx, chi.RouteCtxKey, rctx))
 
 		rr := httptest.NewRecorder()
-		handler := api.HandleGetSession(env.DB)
+		handler := api.HandleGetSession(env.DB, nil)
 		handler.ServeHTTP(rr, req)
 
 		if rr.Code != http.StatusOK {
@@ -140,7 +140,7 @@ func TestSharedSessionPrivacy_PublicShare(t *testing.T) {
 		req = req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
 
 		rr := httptest.NewRecorder()
-		handler := api.HandleGetSession(env.DB)
+		handler := api.HandleGetSession(env.DB, nil)
 		handler.ServeHTTP(rr, req)
 
 		if rr.Code != http.StatusOK {
@@ -201,7 +201,7 @@ func TestSharedSessionPrivacy_PrivateShare(t *testing.T) {
 		req = req.WithContext(reqCtx)
 
 		rr := httptest.NewRecorder()
-		handler := api.HandleGetSession(env.DB)
+		handler := api.HandleGetSession(env.DB, nil)
 		handler.ServeHTTP(rr, req)
 
 		if rr.Code != http.StatusOK {
@@ -0,0 +1,133 @@
+package sessions_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// GET /api/v1/sessions?sort=<keys> - Multi-key session list sort
+// =============================================================================
+
+func TestListSessionsBySortKeys_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "sortkeys@example.com", "Sort User")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+
+	session := func(externalID, firstSeen string, lines int) string {
+		t.Helper()
+		id := testutil.CreateTestSessionFull(t, env, user.ID, externalID, testutil.TestSessionFullOpts{Summary: externalID, SyncLines: lines})
+		if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET first_seen = $2 WHERE id = $1`, id, firstSeen); err != nil {
+			t.Fatalf("set first_seen: %v", err)
+		}
+		return id
+	}
+	carded := func(id, cost string, durationMs int64) {
+		t.Helper()
+		if _, err := env.DB.Exec(env.Ctx, `
+			INSERT INTO session_card_session (
+				session_id, version, computed_at, up_to_line,
+				total_messages, user_messages, assistant_messages,
+				human_prompts, tool_results, text_responses, tool_calls, thinking_blocks,
+				duration_ms, models_used,
+				compaction_auto, compaction_manual, compaction_avg_time_ms
+			) VALUES ($1, $2, now(), 100, 0, 0, 0, 0, 0, 0, 0, 0, $3, '[]', 0, 0, 0)`,
+			id, analytics.SessionCardVersion, durationMs); err != nil {
+			t.Fatalf("insert session card: %v", err)
+		}
+		testutil.SeedTokensV2Card(t, env, id, analytics.TokensV2Data{TotalCostUSD: cost})
+	}
+
+	// a and c cost the same, so cost alone leaves them tied.
+	a := session("a", "2026-01-01", 10)
+	b := session("b", "2026-01-02", 300)
+	c := session("c", "2026-01-03", 50)
+	uncarded := session("uncarded", "2026-01-04", 20)
+	carded(a, "5.00", 60_000)
+	carded(b, "0.50", 600_000)
+	carded(c, "5.00", 1_000)
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+
+	list := func(t *testing.T, query string) []db.SessionListItem {
+		t.Helper()
+		resp, err := client.Get("/api/v1/sessions?" + query)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		var result db.SessionListResult
+		testutil.ParseJSON(t, resp, &result)
+		return result.Sessions
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"sort=created_at_asc", []string{a, b, c, uncarded}},
+		{"sort=created_at_desc", []string{uncarded, c, b, a}},
+		{"sort=cost_desc,created_at_desc", []string{c, a, b, uncarded}},
+		{"sort=cost_desc,created_at_asc", []string{a, c, b, uncarded}},
+		{"sort=cost_asc,created_at_asc", []string{b, a, c, uncarded}},
+		{"sort=duration_desc", []string{b, a, c, uncarded}},
+		{"sort=duration_asc", []string{c, a, b, uncarded}},
+		{"sort=line_count_desc", []string{b, c, uncarded, a}},
+		{"sort=cost_desc&sort=created_at_desc", []string{c, a, b, uncarded}},
+		{"sort=Cost_Asc,Line_Count_Desc", []string{b, c, a, uncarded}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := list(t, tt.query)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d sessions, want %d", len(got), len(tt.want))
+			}
+			for i, id := range tt.want {
+				if got[i].ID != id {
+					t.Errorf("session %d = %s (%s), want %s", i, got[i].ExternalID, got[i].ID, id)
+				}
+			}
+		})
+	}
+
+	t.Run("rows carry the card duration", func(t *testing.T) {
+		for _, s := range list(t, "sort=duration_desc") {
+			if (s.DurationMs == nil) != (s.ID == uncarded) {
+				t.Errorf("session %s duration_ms = %v", s.ExternalID, s.DurationMs)
+			}
+		}
+	})
+
+	for name, query := range map[string]string{
+		"rejects an unknown key":        "sort=cost",
+		"rejects more than three keys":  "sort=cost_desc,duration_desc,created_at_desc,line_count_desc",
+		"rejects a field twice":         "sort=cost_desc,cost_asc",
+		"rejects keys with a search":    "sort=cost_desc&q=a",
+		"rejects keys with interesting": "sort=interesting&sort=cost_desc",
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp, err := client.Get("/api/v1/sessions?" + query)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			testutil.RequireStatus(t, resp, http.StatusBadRequest)
+		})
+	}
+}
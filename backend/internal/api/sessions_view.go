@@ -4,12 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/ConfabulousDev/confab-web/internal/db"
 	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
 	"github.com/ConfabulousDev/confab-web/internal/logger"
 	"github.com/ConfabulousDev/confab-web/internal/validation"
 )
@@ -54,6 +58,93 @@ func parseProviders(value string) ([]string, error) {
 	return out, nil
 }
 
+// parseStates parses the `?state=` filter (case-insensitive) into lifecycle
+// states. `deleted` is rejected: deleted sessions are never listed.
+// Returns nil for an empty/missing param.
+func parseStates(value string) ([]string, error) {
+	raw := parseCommaSeparated(value)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	listable := make([]string, 0, len(dbsession.States))
+	for _, st := range dbsession.States {
+		if st != dbsession.StateDeleted {
+			listable = append(listable, st)
+		}
+	}
+	out := make([]string, 0, len(raw))
+	for _, v := range raw {
+		st := strings.ToLower(v)
+		if !slices.Contains(listable, st) {
+			return nil, fmt.Errorf("unknown state %q: must be one of %s", v, strings.Join(listable, ", "))
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// parseSessionSort parses the `?sort=` ordering (case-insensitive; repeated
+// parameters arrive comma-joined). Empty means db.SessionSortRecent. A lone
+// db.SessionSortRecent or db.SessionSortInteresting selects that ordering;
+// otherwise the value is up to db.MaxSessionSortKeys db.SessionSortKeys,
+// returned as keys, at most one per field.
+func parseSessionSort(value string) (sort string, keys []string, err error) {
+	raw := parseCommaSeparated(value)
+	if len(raw) == 0 {
+		return db.SessionSortRecent, nil, nil
+	}
+	if first := strings.ToLower(raw[0]); len(raw) == 1 && (first == db.SessionSortRecent || first == db.SessionSortInteresting) {
+		return first, nil, nil
+	}
+	if len(raw) > db.MaxSessionSortKeys {
+		return "", nil, fmt.Errorf("sort takes at most %d keys, got %d", db.MaxSessionSortKeys, len(raw))
+	}
+	fields := make(map[string]bool, len(raw))
+	for _, v := range raw {
+		key := strings.ToLower(v)
+		if !slices.Contains(db.SessionSortKeys, key) {
+			return "", nil, fmt.Errorf("unknown sort %q: must be %s, %s, or up to %d of %s",
+				v, db.SessionSortRecent, db.SessionSortInteresting, db.MaxSessionSortKeys, strings.Join(db.SessionSortKeys, ", "))
+		}
+		field := strings.TrimSuffix(strings.TrimSuffix(key, "_asc"), "_desc")
+		if fields[field] {
+			return "", nil, fmt.Errorf("sort names %s more than once", field)
+		}
+		fields[field] = true
+		keys = append(keys, key)
+	}
+	return db.SessionSortRecent, keys, nil
+}
+
+// parseOutOfTreeAccess parses the `?has_out_of_tree_access=` filter. Returns
+// nil for an empty/missing param.
+func parseOutOfTreeAccess(value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, errors.New("has_out_of_tree_access must be true or false")
+	}
+	return &v, nil
+}
+
+// listIncludeGrowth is the `?include=` value that adds each session's growth
+// history to the list.
+const listIncludeGrowth = "growth"
+
+// parseListInclude parses the `?include=` list of optional session list
+// fields (case-insensitive). Returns whether growth was requested.
+func parseListInclude(value string) (growth bool, err error) {
+	for _, v := range parseCommaSeparated(value) {
+		if strings.ToLower(v) != listIncludeGrowth {
+			return false, fmt.Errorf("unknown include %q: must be %s", v, listIncludeGrowth)
+		}
+		growth = true
+	}
+	return growth, nil
+}
+
 // HandleListSessions lists all sessions visible to the authenticated user.
 // Supports server-side filtering, cursor-based pagination, and returns pre-materialized filter options.
 func HandleListSessions(database *db.DB) http.HandlerFunc {
@@ -74,26 +165,56 @@ func HandleListSessions(database *db.DB) http.HandlerFunc {
 			respondError(w, http.StatusBadRequest, perr.Error())
 			return
 		}
+		states, serr := parseStates(r.URL.Query().Get("state"))
+		if serr != nil {
+			respondError(w, http.StatusBadRequest, serr.Error())
+			return
+		}
+		outOfTree, oerr := parseOutOfTreeAccess(r.URL.Query().Get("has_out_of_tree_access"))
+		if oerr != nil {
+			respondError(w, http.StatusBadRequest, oerr.Error())
+			return
+		}
+		sort, sortKeys, sortErr := parseSessionSort(strings.Join(r.URL.Query()["sort"], ","))
+		if sortErr != nil {
+			respondError(w, http.StatusBadRequest, sortErr.Error())
+			return
+		}
+		includeGrowth, ierr := parseListInclude(r.URL.Query().Get("include"))
+		if ierr != nil {
+			respondError(w, http.StatusBadRequest, ierr.Error())
+			return
+		}
 		params := db.SessionListParams{
-			Repos:     parseCommaSeparated(r.URL.Query().Get("repo")),
-			Branches:  parseCommaSeparated(r.URL.Query().Get("branch")),
-			Owners:    parseCommaSeparated(r.URL.Query().Get("owner")),
-			PRs:       parseCommaSeparated(r.URL.Query().Get("pr")),
-			Providers: providers,
-			Cursor:    r.URL.Query().Get("cursor"),
-			PageSize:  db.DefaultPageSize,
+			Repos:           parseCommaSeparated(r.URL.Query().Get("repo")),
+			Branches:        parseCommaSeparated(r.URL.Query().Get("branch")),
+			Owners:          parseCommaSeparated(r.URL.Query().Get("owner")),
+			PRs:             parseCommaSeparated(r.URL.Query().Get("pr")),
+			Providers:       providers,
+			States:          states,
+			OutOfTreeAccess: outOfTree,
+			Sort:            sort,
+			SortKeys:        sortKeys,
+			IncludeGrowth:   includeGrowth,
+			Cursor:          r.URL.Query().Get("cursor"),
+			PageSize:        db.DefaultPageSize,
 		}
 
 		// Parse search query
 		if q := r.URL.Query().Get("q"); q != "" {
 			params.Query = &q
 		}
+		// Search results are ordered by relevance.
+		if params.Query != nil && (params.Sort != db.SessionSortRecent || len(params.SortKeys) > 0) {
+			respondError(w, http.StatusBadRequest, "sort cannot be combined with q")
+			return
+		}
 
 		// Validate filter param bounds
 		for name, values := range map[string][]string{
 			"repo": params.Repos, "branch": params.Branches,
 			"owner": params.Owners, "pr": params.PRs,
-			"provider": params.Providers,
+			"provider": params.Providers, "state": params.States,
 		} {
 			if err := validation.ValidateFilterValues(name, values); err != nil {
 				respondError(w, http.StatusBadRequest, err.Error())
@@ -131,8 +252,9 @@ func HandleListSessions(database *db.DB) http.HandlerFunc {
 // - Recipient share: authenticated user who is a share recipient
 //
 // This handler supports optional authentication - it extracts user ID from
-// the session cookie if present, but doesn't require it.
-func HandleGetSession(database *db.DB) http.HandlerFunc {
+// the session cookie if present, but doesn't require it. Views granted by a
+// share are counted in shareAccess (nil records nothing).
+func HandleGetSession(database *db.DB, shareAccess *ShareAccessLog) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		sessionID := chi.URLParam(r, "id")
 		if sessionID == "" {
@@ -147,6 +269,9 @@ func HandleGetSession(database *db.DB) http.HandlerFunc {
 		if result == nil {
 			return
 		}
+		if shareID := result.AccessInfo.ShareID; shareID != nil {
+			shareAccess.Record(r, *shareID)
+		}
 
 		respondJSON(w, http.StatusOK, result.Session)
 	}
@@ -203,6 +328,12 @@ func HandleLookupSessionByExternalID(database *db.DB) http.HandlerFunc {
 type UpdateSessionTitleRequest struct {
 	// CustomTitle is the new title. Use nil/null to clear and revert to auto-derived title.
 	CustomTitle *string `json:"custom_title"`
+
+	// The machine-written titles are decoded only so a request that tries to
+	// set them is rejected rather than silently ignored. Smart Recap and
+	// title generation own them; this endpoint never writes them.
+	SuggestedSessionTitle json.RawMessage `json:"suggested_session_title,omitempty"`
+	AIGeneratedTitle      json.RawMessage `json:"ai_generated_title,omitempty"`
 }
 
 // HandleUpdateSessionTitle updates the custom title for a session
@@ -232,11 +363,21 @@ func HandleUpdateSessionTitle(database *db.DB) http.HandlerFunc {
 			return
 		}
 
+		if len(req.SuggestedSessionTitle) > 0 || len(req.AIGeneratedTitle) > 0 {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed,
+				"suggested_session_title and ai_generated_title are machine-generated and cannot be set; use custom_title")
+			return
+		}
+
 		// Validate custom title length if provided
 		if req.CustomTitle != nil && len(*req.CustomTitle) > db.MaxCustomTitleLength {
 			respondError(w, http.StatusBadRequest, "Custom title exceeds maximum length of 255 characters")
 			return
 		}
+		// A blank title is no override; store NULL so resolution falls through.
+		if req.CustomTitle != nil && strings.TrimSpace(*req.CustomTitle) == "" {
+			req.CustomTitle = nil
+		}
 
 		// Create context with timeout for database operation
 		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
@@ -258,8 +399,9 @@ func HandleUpdateSessionTitle(database *db.DB) http.HandlerFunc {
 			return
 		}
 
-		// Return the updated session
-		session, err := sessionStore.GetSessionDetail(ctx, sessionID, userID)
+		// Return the updated session, read back from the primary so the new
+		// title is in it even when a replica serves session reads.
+		session, err := sessionStore.GetSessionDetail(db.WithPrimary(ctx), sessionID, userID)
 		if err != nil {
 			// Title was updated but failed to fetch - return success without body
 			w.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ConfabulousDev/confab-web/internal/clientip"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbaccess "github.com/ConfabulousDev/confab-web/internal/db/access"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// shareAccessMaxInFlight caps concurrent share access writes. Views past it
+// are dropped rather than queued, so a burst can't pile up goroutines.
+const shareAccessMaxInFlight = 16
+
+// shareAccessStatsDays is the length of the daily series in share stats.
+const shareAccessStatsDays = 30
+
+// ShareAccessLog records views of sessions opened through a share. Writes
+// happen in the background and failures are only logged, so recording never
+// slows or fails the shared read. A nil *ShareAccessLog records nothing.
+type ShareAccessLog struct {
+	store *dbaccess.Store
+	slots chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewShareAccessLog returns a log writing to database.
+func NewShareAccessLog(database *db.DB) *ShareAccessLog {
+	return &ShareAccessLog{
+		store: &dbaccess.Store{DB: database},
+		slots: make(chan struct{}, shareAccessMaxInFlight),
+	}
+}
+
+// shareAccessLogFromEnv returns nil, turning recording off, when
+// DISABLE_SHARE_ACCESS_LOG is "true".
+func shareAccessLogFromEnv(database *db.DB) *ShareAccessLog {
+	if os.Getenv("DISABLE_SHARE_ACCESS_LOG") == "true" {
+		return nil
+	}
+	return NewShareAccessLog(database)
+}
+
+// Record counts a view of shareID by the requester. It returns immediately.
+func (l *ShareAccessLog) Record(r *http.Request, shareID int64) {
+	if l == nil {
+		return
+	}
+	select {
+	case l.slots <- struct{}{}:
+	default:
+		return
+	}
+
+	viewer := shareViewerHash(shareID, requestIP(r))
+	class := userAgentClass(r.Header.Get("User-Agent"))
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		defer func() { <-l.slots }()
+		ctx, cancel := context.WithTimeout(context.Background(), DatabaseTimeout)
+		defer cancel()
+		if err := l.store.RecordShareAccess(ctx, shareID, viewer, class); err != nil {
+			logger.Warn("Failed to record share access", "error", err, "share_id", shareID)
+		}
+	}()
+}
+
+// wait blocks until in-flight writes finish (tests only).
+func (l *ShareAccessLog) wait() {
+	l.wg.Wait()
+}
+
+// requestIP is the client IP from the clientip middleware, falling back to
+// RemoteAddr when the middleware didn't run.
+func requestIP(r *http.Request) string {
+	if ip := clientip.FromRequest(r).Primary; ip != "" {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// shareViewerHash approximates a viewer: the share ID and the client IP
+// truncated to its /24 (IPv4) or /48 (IPv6) network. Salting with the share ID
+// keeps one person's views of different shares from being linked.
+func shareViewerHash(shareID int64, ip string) string {
+	sum := sha256.Sum256([]byte(strconv.FormatInt(shareID, 10) + "|" + truncateIP(ip)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// truncateIP zeroes the host part of ip, or returns "" if it doesn't parse.
+func truncateIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// User agent classes stored in share_access_log.
+const (
+	userAgentBrowser = "browser"
+	userAgentCLI     = "cli"
+	userAgentBot     = "bot"
+	userAgentOther   = "other"
+)
+
+// userAgentClass buckets a User-Agent header coarsely enough that it can't
+// identify anyone.
+func userAgentClass(ua string) string {
+	if ParseCLIUserAgent(ua) != nil {
+		return userAgentCLI
+	}
+	lower := strings.ToLower(ua)
+	for _, marker := range []string{"bot", "crawl", "spider", "preview", "curl/", "wget/", "python-requests", "go-http-client"} {
+		if strings.Contains(lower, marker) {
+			return userAgentBot
+		}
+	}
+	if strings.HasPrefix(ua, "Mozilla/") {
+		return userAgentBrowser
+	}
+	return userAgentOther
+}
+
+// HandleGetShareAccessStats returns the recorded views of one of the
+// caller's shares: total views, approximate unique viewers, views by user
+// agent class and a daily series over the last shareAccessStatsDays days.
+// Owner-only; a share of another session or user answers 404.
+func HandleGetShareAccessStats(database *db.DB) http.HandlerFunc {
+	accessStore := &dbaccess.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+		shareID, err := strconv.ParseInt(chi.URLParam(r, "shareID"), 10, 64)
+		if err != nil || shareID <= 0 {
+			respondError(w, http.StatusBadRequest, "Invalid share ID")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		stats, err := accessStore.GetShareAccessStats(ctx, sessionID, shareID, userID, shareAccessStatsDays)
+		if err != nil {
+			if errors.Is(err, db.ErrUnauthorized) {
+				respondError(w, http.StatusNotFound, "Share not found or unauthorized")
+				return
+			}
+			log.Error("Failed to get share access stats", "error", err, "share_id", shareID)
+			respondError(w, http.StatusInternalServerError, "Failed to get share stats")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, stats)
+	}
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ConfabulousDev/confab-web/internal/auth"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestTruncateIP(t *testing.T) {
+	tests := map[string]string{
+		"203.0.113.77":           "203.0.113.0",
+		"203.0.113.1":            "203.0.113.0",
+		"2001:db8:abcd:12:34::1": "2001:db8:abcd::",
+		"::ffff:198.51.100.9":    "198.51.100.0",
+		"not-an-ip":              "",
+		"":                       "",
+	}
+	for in, want := range tests {
+		if got := truncateIP(in); got != want {
+			t.Errorf("truncateIP(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestShareViewerHash(t *testing.T) {
+	a := shareViewerHash(1, "203.0.113.77")
+	if len(a) != 32 {
+		t.Errorf("hash length = %d, want 32", len(a))
+	}
+	if b := shareViewerHash(1, "203.0.113.200"); b != a {
+		t.Error("addresses in the same /24 should hash alike")
+	}
+	if c := shareViewerHash(1, "203.0.114.77"); c == a {
+		t.Error("addresses in different /24s should hash differently")
+	}
+	if d := shareViewerHash(2, "203.0.113.77"); d == a {
+		t.Error("the same viewer of different shares should hash differently")
+	}
+}
+
+func TestUserAgentClass(t *testing.T) {
+	tests := map[string]string{
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15": userAgentBrowser,
+		"confab/1.2.3 (darwin; arm64)":                                             userAgentCLI,
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)": userAgentBot,
+		"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)":               userAgentBot,
+		"curl/8.4.0":        userAgentBot,
+		"SomethingElse/1.0": userAgentOther,
+		"":                  userAgentOther,
+	}
+	for ua, want := range tests {
+		if got := userAgentClass(ua); got != want {
+			t.Errorf("userAgentClass(%q) = %q, want %q", ua, got, want)
+		}
+	}
+}
+
+func TestShareAccessLogFromEnv_Disabled(t *testing.T) {
+	t.Setenv("DISABLE_SHARE_ACCESS_LOG", "true")
+	if l := shareAccessLogFromEnv(nil); l != nil {
+		t.Error("expected a nil log when DISABLE_SHARE_ACCESS_LOG=true")
+	}
+	// A nil log is a no-op.
+	var l *ShareAccessLog
+	l.Record(httptest.NewRequest(http.MethodGet, "/", nil), 1)
+}
+
+// getSessionAs serves GET /api/v1/sessions/{id} for userID (0 = anonymous).
+func getSessionAs(t *testing.T, handler http.HandlerFunc, sessionID string, userID int64) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/sessions/"+sessionID, nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64)")
+	ctx := req.Context()
+	if userID != 0 {
+		ctx = context.WithValue(ctx, auth.GetUserIDContextKey(), userID)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", sessionID)
+	req = req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	return rr
+}
+
+func TestHandleGetSession_RecordsShareViews(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	owner := testutil.CreateTestUser(t, env, "owner@example.com", "Owner")
+	sessionID := testutil.CreateTestSession(t, env, owner.ID, "share-view-ext")
+	shareID := testutil.CreateTestShare(t, env, sessionID, true, nil, nil)
+
+	countViews := func() int64 {
+		t.Helper()
+		var n int64
+		if err := env.DB.QueryRow(env.Ctx,
+			`SELECT COALESCE(SUM(views), 0) FROM share_access_log WHERE share_id = $1`, shareID).Scan(&n); err != nil {
+			t.Fatalf("count views: %v", err)
+		}
+		return n
+	}
+
+	t.Run("disabled log records nothing", func(t *testing.T) {
+		getSessionAs(t, HandleGetSession(env.DB, nil), sessionID, 0)
+		if n := countViews(); n != 0 {
+			t.Errorf("views = %d, want 0", n)
+		}
+	})
+
+	shareLog := NewShareAccessLog(env.DB)
+	handler := HandleGetSession(env.DB, shareLog)
+
+	t.Run("owner views are not counted", func(t *testing.T) {
+		getSessionAs(t, handler, sessionID, owner.ID)
+		shareLog.wait()
+		if n := countViews(); n != 0 {
+			t.Errorf("views = %d, want 0", n)
+		}
+	})
+
+	t.Run("public share views are counted", func(t *testing.T) {
+		getSessionAs(t, handler, sessionID, 0)
+		getSessionAs(t, handler, sessionID, 0)
+		shareLog.wait()
+		if n := countViews(); n != 2 {
+			t.Errorf("views = %d, want 2", n)
+		}
+
+		var class string
+		if err := env.DB.QueryRow(env.Ctx,
+			`SELECT user_agent_class FROM share_access_log WHERE share_id = $1`, shareID).Scan(&class); err != nil {
+			t.Fatalf("query class: %v", err)
+		}
+		if class != userAgentBrowser {
+			t.Errorf("user_agent_class = %q, want %q", class, userAgentBrowser)
+		}
+	})
+}
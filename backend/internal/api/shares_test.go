@@ -218,6 +218,18 @@ func (f *fakeEmailRecorder) SendShareInvitation(_ context.Context, params email.
 	return nil
 }
 
+func (f *fakeEmailRecorder) SendMagicLink(context.Context, email.MagicLinkParams) error {
+	return nil
+}
+
+func (f *fakeEmailRecorder) SendDataExportReady(context.Context, email.DataExportReadyParams) error {
+	return nil
+}
+
+func (f *fakeEmailRecorder) SendNotification(context.Context, email.NotificationParams) error {
+	return nil
+}
+
 // postShare drives HandleCreateShare with an authenticated userID and the chi
 // {id} URL param set, returning the recorder for assertions.
 func postShare(t *testing.T, handler http.HandlerFunc, userID int64, sessionID, body string) *httptest.ResponseRecorder {
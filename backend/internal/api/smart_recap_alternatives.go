@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/recapquota"
+	"github.com/ConfabulousDev/confab-web/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// SmartRecapAlternativeResponse is the response for
+// GET /sessions/{id}/cards/smart-recap/alternatives.
+type SmartRecapAlternativeResponse struct {
+	Style      string `json:"style"`
+	Recap      string `json:"recap"`
+	Cached     bool   `json:"cached"`
+	TokensUsed int    `json:"tokens_used"`
+}
+
+// HandleGetSmartRecapAlternative returns the session's smart recap rewritten
+// in the requested style (?style=bullet|prose|technical). A variant is
+// generated on first request from the transcript, with the style's
+// instructions appended to the smart recap system prompt, and then served
+// from session_recap_alternatives for analytics.RecapAlternativeTTL.
+// Owner-only; each generation counts against the owner's smart recap quota,
+// and a session gets at most analytics.RecapAlternativeDailyLimit generations
+// a day. Cached responses are free and report tokens_used 0.
+func HandleGetSmartRecapAlternative(database *db.DB, store *storage.S3Storage) http.HandlerFunc {
+	analyticsStore := analytics.NewStore(database.Conn())
+	sessionStore := &dbsession.Store{DB: database}
+	smartRecapConfig := loadSmartRecapConfig()
+	smartRecapGenerator := analytics.NewSmartRecapGenerator(analyticsStore, database, smartRecapConfig.generatorConfig())
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		if !smartRecapConfig.Enabled {
+			respondError(w, http.StatusNotFound, "Smart recap not available")
+			return
+		}
+
+		style := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("style")))
+		if !analytics.IsRecapStyle(style) {
+			respondError(w, http.StatusBadRequest, "style must be one of "+strings.Join(analytics.RecapStyles, ", "))
+			return
+		}
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		dbCtx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		sessionUserID, externalID, sessionProvider, err := sessionStore.GetSessionOwnerExternalIDAndProvider(dbCtx, sessionID)
+		if err != nil {
+			log.Error("Failed to get session", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusNotFound, "Session not found")
+			return
+		}
+
+		if sessionUserID != userID {
+			respondError(w, http.StatusForbidden, "Only the session owner can generate recap variants")
+			return
+		}
+
+		cached, err := analyticsStore.GetRecapAlternative(dbCtx, sessionID, style)
+		if err != nil {
+			log.Error("Failed to get recap alternative", "error", err, "session_id", sessionID, "style", style)
+			respondError(w, http.StatusInternalServerError, "Failed to get recap variant")
+			return
+		}
+		if cached != nil {
+			respondJSON(w, http.StatusOK, SmartRecapAlternativeResponse{Style: style, Recap: cached.RecapText, Cached: true})
+			return
+		}
+
+		session, err := sessionStore.GetSessionDetail(dbCtx, sessionID, userID)
+		if err != nil {
+			log.Error("Failed to get session detail", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to get session")
+			return
+		}
+
+		totalLineCount := totalTranscriptAndAgentLines(session.Files)
+		if totalLineCount == 0 {
+			respondError(w, http.StatusBadRequest, "No transcript available")
+			return
+		}
+		if session.TranscriptArchivedAt != nil {
+			respondTranscriptArchived(w)
+			return
+		}
+
+		quota, err := recapquota.GetOrCreate(dbCtx, database.Conn(), userID)
+		if err != nil {
+			log.Error("Failed to get quota", "error", err, "user_id", userID)
+			respondError(w, http.StatusInternalServerError, "Failed to check quota")
+			return
+		}
+
+		if smartRecapConfig.QuotaEnabled() && quota.ComputeCount >= smartRecapConfig.QuotaLimit {
+			respondError(w, http.StatusForbidden, "Recap generation limit reached")
+			return
+		}
+
+		claim, err := analyticsStore.ClaimRecapAlternative(dbCtx, sessionID, style, smartRecapConfig.LockTimeoutSeconds)
+		if err != nil {
+			if errors.Is(err, db.ErrSessionNotFound) {
+				respondError(w, http.StatusNotFound, "Session not found")
+				return
+			}
+			log.Error("Failed to claim recap alternative", "error", err, "session_id", sessionID, "style", style)
+			respondError(w, http.StatusInternalServerError, "Failed to generate recap variant")
+			return
+		}
+		switch {
+		case claim.Cached != nil:
+			respondJSON(w, http.StatusOK, SmartRecapAlternativeResponse{Style: style, Recap: claim.Cached.RecapText, Cached: true})
+			return
+		case claim.InProgress:
+			respondError(w, http.StatusConflict, "Generation already in progress")
+			return
+		case claim.Limited:
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(claim.RetryAfter.Seconds()))))
+			respondError(w, http.StatusTooManyRequests, "Recap variant limit reached for this session today")
+			return
+		}
+
+		// From here on a failure must free the claim. Detached context: the
+		// request may have been cancelled, and the slot should be freed either way.
+		release := func() {
+			releaseCtx, releaseCancel := context.WithTimeout(context.WithoutCancel(r.Context()), DatabaseTimeout)
+			defer releaseCancel()
+			if err := analyticsStore.ReleaseRecapAlternative(releaseCtx, sessionID, style); err != nil {
+				log.Error("Failed to release recap alternative", "error", err, "session_id", sessionID, "style", style)
+			}
+		}
+
+		cards, err := analyticsStore.GetCards(dbCtx, sessionID)
+		if err != nil {
+			log.Error("Failed to get cached cards", "error", err, "session_id", sessionID)
+		}
+		var cardStats map[string]interface{}
+		if cards != nil {
+			cardStats = cards.ToResponse().Cards
+		}
+
+		transcript, idMap := providerTranscriptForRecap(r.Context(), database, store, sessionID, sessionUserID, sessionProvider, externalID, log)
+		if transcript == "" {
+			release()
+			respondError(w, http.StatusInternalServerError, "Failed to download transcript")
+			return
+		}
+
+		result, err := smartRecapGenerator.GenerateAlternative(r.Context(), analytics.GenerateInput{
+			SessionID:  sessionID,
+			UserID:     sessionUserID,
+			LineCount:  totalLineCount,
+			Transcript: transcript,
+			IDMap:      idMap,
+			CardStats:  cardStats,
+		}, style)
+		if err != nil {
+			log.Error("Failed to generate recap alternative", "error", err, "session_id", sessionID, "style", style)
+			release()
+			respondError(w, http.StatusInternalServerError, "Failed to generate recap variant")
+			return
+		}
+
+		saveCtx, saveCancel := context.WithTimeout(context.WithoutCancel(r.Context()), DatabaseTimeout)
+		defer saveCancel()
+
+		// Charge the quota before saving, as smart recap generation does: a
+		// variant whose usage can't be tracked is not served.
+		if err := recapquota.IncrementForSession(saveCtx, database.Conn(), userID, sessionID); err != nil {
+			log.Error("Failed to increment recap quota", "error", err, "user_id", userID)
+			release()
+			respondError(w, http.StatusInternalServerError, "Failed to generate recap variant")
+			return
+		}
+		if err := analyticsStore.SaveRecapAlternative(saveCtx, sessionID, style, result.Recap); err != nil {
+			log.Error("Failed to save recap alternative", "error", err, "session_id", sessionID, "style", style)
+			release()
+			respondError(w, http.StatusInternalServerError, "Failed to save recap variant")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, SmartRecapAlternativeResponse{
+			Style:      style,
+			Recap:      result.Recap,
+			TokensUsed: result.TokensUsed(),
+		})
+	}
+}
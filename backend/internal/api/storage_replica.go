@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ConfabulousDev/confab-web/internal/storage"
+)
+
+// HeaderStorageReplica is set to "true" on a transcript read that was served,
+// in whole or in part, by the S3 read failover. The content may then lag the
+// primary by the replication delay.
+const HeaderStorageReplica = "Confab-Storage-Replica"
+
+// withReplicaHeader marks the request context with storage.WithReplicaReadMarker
+// and adds HeaderStorageReplica when the response starts, if any storage read
+// made so far was served by the failover. Reads after the response has started
+// cannot change the header.
+func withReplicaHeader(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, served := storage.WithReplicaReadMarker(r.Context())
+		h(&replicaHeaderWriter{ResponseWriter: w, served: served}, r.WithContext(ctx))
+	}
+}
+
+type replicaHeaderWriter struct {
+	http.ResponseWriter
+	served  func() bool
+	started bool
+}
+
+func (rw *replicaHeaderWriter) WriteHeader(code int) {
+	if !rw.started {
+		rw.started = true
+		if rw.served() {
+			rw.Header().Set(HeaderStorageReplica, "true")
+		}
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *replicaHeaderWriter) Write(b []byte) (int, error) {
+	if !rw.started {
+		rw.WriteHeader(http.StatusOK)
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *replicaHeaderWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithReplicaHeader_NoReplicaRead(t *testing.T) {
+	h := withReplicaHeader(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("primary content"))
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/sessions/x/sync/file", nil))
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "primary content" {
+		t.Fatalf("response = %d %q, want 200 with the handler's body", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get(HeaderStorageReplica); got != "" {
+		t.Errorf("%s = %q, want unset", HeaderStorageReplica, got)
+	}
+}
+
+func TestReplicaHeaderWriter(t *testing.T) {
+	served := false
+	rec := httptest.NewRecorder()
+	rw := &replicaHeaderWriter{ResponseWriter: rec, served: func() bool { return served }}
+
+	served = true
+	rw.WriteHeader(http.StatusOK)
+	served = false
+	rw.WriteHeader(http.StatusOK) // a second call must not re-evaluate
+
+	if got := rec.Header().Get(HeaderStorageReplica); got != "true" {
+		t.Errorf("%s = %q, want true", HeaderStorageReplica, got)
+	}
+	if rw.Unwrap() != rec {
+		t.Error("Unwrap did not return the underlying writer")
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// Per-session breakdown size for GET /api/v1/me/storage.
+const (
+	DefaultStorageUsageLimit = 100
+	MaxStorageUsageLimit     = 1000
+)
+
+// handleGetMyStorage returns the chunk storage the caller's sessions occupy:
+// user-wide totals and the largest sessions first, up to ?limit=.
+func (s *Server) handleGetMyStorage(w http.ResponseWriter, r *http.Request) {
+	log := logger.Ctx(r.Context())
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	limit := DefaultStorageUsageLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxStorageUsageLimit {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", MaxStorageUsageLimit))
+			return
+		}
+		limit = n
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	usage, err := (&dbsession.Store{DB: s.db}).GetUserStorageUsage(ctx, userID, limit)
+	if err != nil {
+		log.Error("Failed to get storage usage", "error", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get storage usage")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, usage)
+}
@@ -1,23 +1,29 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/auth"
 	"github.com/ConfabulousDev/confab-web/internal/db"
 	dbcodex "github.com/ConfabulousDev/confab-web/internal/db/codex"
 	dbcursor "github.com/ConfabulousDev/confab-web/internal/db/cursor"
 	dbevents "github.com/ConfabulousDev/confab-web/internal/db/events"
 	dbgithub "github.com/ConfabulousDev/confab-web/internal/db/github"
 	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/ingestpolicy"
 	"github.com/ConfabulousDev/confab-web/internal/logger"
 	"github.com/ConfabulousDev/confab-web/internal/models"
 	"github.com/ConfabulousDev/confab-web/internal/storage"
@@ -51,6 +57,13 @@ type SyncInitRequest struct {
 	// ("claude-code", "codex", "opencode", "cursor").
 	Provider *string `json:"provider,omitempty"`
 
+	// SessionType is the explicit name for the stored sessions.session_type
+	// and drives analytics type-gating. Same value space as Provider: a
+	// canonical provider or a type with a registered analytics handler
+	// (models.IsKnownSessionType); anything else is rejected with 400. When
+	// both fields are sent they must agree. Nil falls back to Provider.
+	SessionType *string `json:"session_type,omitempty"`
+
 	// ===========================================================================
 	// DEPRECATED: The following top-level fields are deprecated.
 	// Use the nested Metadata struct instead for consistency with the chunk API.
@@ -67,16 +80,26 @@ type SyncInitRequest struct {
 // SyncInitResponse is the response for POST /api/v1/sync/init
 type SyncInitResponse struct {
 	SessionID string `json:"session_id"`
-	// Provider echoes the resolved provider for this session, so clients
-	// can verify which agent identity the backend recorded. One of the
-	// canonical providers in models.CanonicalProviders.
+	// Provider echoes the session's stored provider, so clients can verify
+	// which agent identity the backend recorded. For a resumed session it is
+	// the detected type if the first transcript chunk reclassified it. One
+	// of the canonical providers in models.CanonicalProviders.
 	Provider string                       `json:"provider"`
 	Files    map[string]SyncFileStateResp `json:"files"`
+	// Limits are the metadata size limits sync/init and sync/chunk enforce.
+	Limits SyncLimits `json:"limits"`
+}
+
+// SyncLimits reports the server's sync limits to the client.
+type SyncLimits struct {
+	Metadata validation.SyncMetadataLimits `json:"metadata"`
 }
 
 // SyncFileStateResp represents the sync state for a single file in API responses
 type SyncFileStateResp struct {
 	LastSyncedLine int `json:"last_synced_line"`
+	// Generation advances each time the file is reset via POST /sync/file/reset.
+	Generation int `json:"generation"`
 }
 
 // SyncChunkMetadata contains optional mutable metadata that can be updated with each chunk
@@ -155,6 +178,23 @@ type SyncChunkResponse struct {
 	LastSyncedLine int `json:"last_synced_line"`
 }
 
+// SyncFileResetRequest is the request body for POST /api/v1/sync/file/reset
+type SyncFileResetRequest struct {
+	SessionID      string `json:"session_id"`
+	FileName       string `json:"file_name"`
+	Reason         string `json:"reason"`           // "compaction" or "manual"
+	LocalLineCount int    `json:"local_line_count"` // Line count of the rewritten local file
+}
+
+// SyncFileResetResponse is the response for POST /api/v1/sync/file/reset
+type SyncFileResetResponse struct {
+	FileName           string `json:"file_name"`
+	Generation         int    `json:"generation"`
+	LastSyncedLine     int    `json:"last_synced_line"`
+	ArchivedGeneration int    `json:"archived_generation"`
+	ArchivedLines      int    `json:"archived_lines"`
+}
+
 // SyncEventRequest is the request body for POST /api/v1/sync/event
 type SyncEventRequest struct {
 	SessionID string          `json:"session_id"`
@@ -168,10 +208,135 @@ type SyncEventResponse struct {
 	Success bool `json:"success"`
 }
 
+// ============================================================================
+// Body Decoding Limits
+// ============================================================================
+
+// Defaults for the sync body-shape limits. Legitimate sync payloads are flat
+// (git_info is the deepest field, a few levels down), so the depth cap only
+// bites on hostile input. The array cap bounds lines per chunk; the CLI sends
+// far fewer.
+const (
+	defaultSyncJSONMaxDepth    = 32
+	defaultSyncJSONMaxArrayLen = 100_000
+)
+
+// syncJSONLimitsFromEnv resolves the body-shape limits for sync/init and
+// sync/chunk from SYNC_JSON_MAX_DEPTH, SYNC_JSON_MAX_ARRAY_LEN, and
+// SYNC_JSON_DISALLOW_UNKNOWN_FIELDS. A depth or length of 0 disables that
+// check; a negative or non-numeric value is rejected at startup. Unknown
+// fields are accepted by default so a newer CLI can add fields without
+// breaking against an older server.
+func syncJSONLimitsFromEnv() httputil.JSONLimits {
+	return httputil.JSONLimits{
+		MaxDepth:              nonNegativeIntFromEnv("SYNC_JSON_MAX_DEPTH", defaultSyncJSONMaxDepth),
+		MaxArrayLen:           nonNegativeIntFromEnv("SYNC_JSON_MAX_ARRAY_LEN", defaultSyncJSONMaxArrayLen),
+		DisallowUnknownFields: os.Getenv("SYNC_JSON_DISALLOW_UNKNOWN_FIELDS") == "true",
+	}
+}
+
+// ingestPolicyFromEnv loads the sync/chunk content denylist from the file
+// named by INGEST_DENYLIST_FILE (one "name = pattern" rule per line). Unset
+// disables the check; an unreadable file or invalid rule fails startup.
+func ingestPolicyFromEnv() *ingestpolicy.Policy {
+	path := os.Getenv("INGEST_DENYLIST_FILE")
+	if path == "" {
+		return nil
+	}
+	policy, err := ingestpolicy.LoadFile(path)
+	if err != nil {
+		logger.Fatal("invalid INGEST_DENYLIST_FILE", "error", err)
+	}
+	logger.Info("Ingest denylist loaded", "path", path, "rules", policy.Len())
+	return policy
+}
+
+// checkIngestPolicy matches a chunk's lines, and the free-text metadata that
+// is derived from them, against policy. On a match it returns where the
+// content was ("line 12", "metadata.summary") and the rule's name.
+func checkIngestPolicy(policy *ingestpolicy.Policy, req *SyncChunkRequest) (where, rule string, denied bool) {
+	if policy == nil {
+		return "", "", false
+	}
+	if rule, index, ok := policy.MatchLines(req.Lines); ok {
+		return fmt.Sprintf("line %d", req.FirstLine+index), rule, true
+	}
+	if req.Metadata != nil {
+		if req.Metadata.Summary != nil {
+			if rule, ok := policy.Match(*req.Metadata.Summary); ok {
+				return "metadata.summary", rule, true
+			}
+		}
+		if req.Metadata.FirstUserMessage != nil {
+			if rule, ok := policy.Match(*req.Metadata.FirstUserMessage); ok {
+				return "metadata.first_user_message", rule, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// respondMetadataError writes the 400 for a failed ValidateSyncMetadata,
+// naming the field and its limit.
+func respondMetadataError(w http.ResponseWriter, err error) {
+	var limitErr *validation.FieldLimitError
+	if errors.As(err, &limitErr) {
+		httputil.RespondFieldLimit(w, limitErr.Field, limitErr.Limit, err.Error())
+		return
+	}
+	respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, err.Error())
+}
+
+// nonNegativeIntFromEnv reads a non-negative integer from the named variable,
+// falling back to def when unset. Invalid values fail startup.
+func nonNegativeIntFromEnv(name string, def int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		logger.Fatal("invalid "+name, "value", raw)
+	}
+	return v
+}
+
+// decodeSyncBody decodes a sync request body under the server's limits. On
+// failure it writes a 400 naming the problem and returns false.
+func (s *Server) decodeSyncBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if err := httputil.DecodeJSON(r.Body, dst, s.syncJSONLimits); err != nil {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeInvalidRequestBody, "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
 // ============================================================================
 // Handlers
 // ============================================================================
 
+// resolveSessionType picks the session_type a sync/init request stores.
+// session_type wins over provider; both omitted (nil pointers) default to
+// claude-code for backward compatibility with existing CLI clients. An
+// explicit empty string flows through to ValidateProvider and is rejected —
+// distinct from "field omitted entirely".
+func resolveSessionType(req SyncInitRequest) (string, error) {
+	if req.SessionType != nil && req.Provider != nil && *req.SessionType != *req.Provider {
+		return "", fmt.Errorf("session_type %q conflicts with provider %q", *req.SessionType, *req.Provider)
+	}
+	sessionType := models.ProviderClaudeCode
+	switch {
+	case req.SessionType != nil:
+		sessionType = *req.SessionType
+	case req.Provider != nil:
+		sessionType = *req.Provider
+	}
+	if err := validation.ValidateProvider(sessionType); err != nil {
+		return "", err
+	}
+	return sessionType, nil
+}
+
 // handleSyncInit initializes or resumes a sync session
 // POST /api/v1/sync/init
 func (s *Server) handleSyncInit(w http.ResponseWriter, r *http.Request) {
@@ -185,45 +350,36 @@ func (s *Server) handleSyncInit(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request
 	var req SyncInitRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+	if !s.decodeSyncBody(w, r, &req) {
 		return
 	}
 
 	// Validate required fields
 	if req.ExternalID == "" {
-		respondError(w, http.StatusBadRequest, "external_id is required")
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "external_id is required")
 		return
 	}
 	if req.TranscriptPath == "" {
-		respondError(w, http.StatusBadRequest, "transcript_path is required")
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "transcript_path is required")
 		return
 	}
 
 	// Validate field lengths
 	if err := validation.ValidateExternalID(req.ExternalID); err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, err.Error())
 		return
 	}
 	if err := validation.ValidateTranscriptPath(req.TranscriptPath); err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, err.Error())
 		return
 	}
 
-	// Resolve provider. A missing field (nil pointer) is defaulted to
-	// claude-code for backward compatibility with existing CLI clients.
-	// An explicit empty string flows through to ValidateProvider and is
-	// rejected — distinct from "field omitted entirely".
-	var provider string
-	if req.Provider == nil {
-		provider = models.ProviderClaudeCode
-	} else {
-		provider = *req.Provider
-	}
-	if err := validation.ValidateProvider(provider); err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+	provider, err := resolveSessionType(req)
+	if err != nil {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, err.Error())
 		return
 	}
+	providerExplicit := req.SessionType != nil || req.Provider != nil
 
 	// Extract metadata, preferring new nested format over deprecated top-level fields
 	cwd := req.CWD
@@ -240,26 +396,27 @@ func (s *Server) handleSyncInit(w http.ResponseWriter, r *http.Request) {
 		username = req.Metadata.Username
 	}
 
-	// Validate cwd regardless of which field it came from
-	if err := validation.ValidateCWD(cwd); err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
-		return
-	}
-
-	// Validate hostname and username
-	if err := validation.ValidateHostname(hostname); err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
-		return
-	}
-	if err := validation.ValidateUsername(username); err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+	// Size limits apply to the resolved values, so they hold the same whichever
+	// format the client used.
+	if err := validation.ValidateSyncMetadata(validation.SyncMetadata{
+		CWD:      cwd,
+		GitInfo:  gitInfo,
+		Hostname: hostname,
+		Username: username,
+	}); err != nil {
+		respondMetadataError(w, err)
 		return
 	}
 
 	// CF-494: validate git_info shape (new remotes/tracking_remote fields).
 	// Old-shape payloads pass through untouched; tolerant to malformed JSON.
 	if err := validation.ValidateGitInfo(gitInfo); err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, err.Error())
+		return
+	}
+
+	// Abuse guard: cap how fast one API key can create new sessions.
+	if !s.admitSyncSession(w, r, userID, req.ExternalID, provider, providerExplicit) {
 		return
 	}
 
@@ -268,16 +425,22 @@ func (s *Server) handleSyncInit(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	params := db.SyncSessionParams{
-		ExternalID:     req.ExternalID,
-		TranscriptPath: req.TranscriptPath,
-		CWD:            cwd,
-		GitInfo:        gitInfo,
-		Hostname:       hostname,
-		Username:       username,
-		Provider:       provider,
-	}
+		ExternalID:       req.ExternalID,
+		TranscriptPath:   req.TranscriptPath,
+		CWD:              cwd,
+		GitInfo:          gitInfo,
+		Hostname:         hostname,
+		Username:         username,
+		Provider:         provider,
+		ProviderExplicit: providerExplicit,
+	}
+	if keyID, ok := auth.GetAPIKeyID(r.Context()); ok {
+		params.APIKeyID = &keyID
+	}
+	// A resumed session reports its stored type, which differs from the
+	// request's when the type was detected from the first transcript chunk.
 	sessionStore := &dbsession.Store{DB: s.db}
-	sessionID, files, err := sessionStore.FindOrCreateSyncSession(ctx, userID, params)
+	sessionID, provider, files, err := sessionStore.FindOrCreateSyncSession(ctx, userID, params)
 	if err != nil {
 		log.Error("Failed to find/create sync session", "error", err, "user_id", userID, "external_id", req.ExternalID)
 		respondError(w, http.StatusInternalServerError, "Failed to initialize sync session")
@@ -289,6 +452,7 @@ func (s *Server) handleSyncInit(w http.ResponseWriter, r *http.Request) {
 	for fileName, state := range files {
 		respFiles[fileName] = SyncFileStateResp{
 			LastSyncedLine: state.LastSyncedLine,
+			Generation:     state.Generation,
 		}
 	}
 
@@ -302,6 +466,7 @@ func (s *Server) handleSyncInit(w http.ResponseWriter, r *http.Request) {
 		SessionID: sessionID,
 		Provider:  provider,
 		Files:     respFiles,
+		Limits:    SyncLimits{Metadata: validation.CurrentSyncMetadataLimits()},
 	})
 }
 
@@ -318,149 +483,23 @@ func (s *Server) handleSyncChunk(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request
 	var req SyncChunkRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+	if !s.decodeSyncBody(w, r, &req) {
 		return
 	}
 
-	// Validate required fields
-	if req.SessionID == "" {
-		respondError(w, http.StatusBadRequest, "session_id is required")
-		return
-	}
-	if req.FileName == "" {
-		respondError(w, http.StatusBadRequest, "file_name is required")
-		return
-	}
-	if req.FileType == "" {
-		respondError(w, http.StatusBadRequest, "file_type is required")
-		return
-	}
-	if req.FileType == "todo" {
-		respondError(w, http.StatusBadRequest, "todo file sync is no longer supported")
-		return
-	}
-	if req.FirstLine < 1 {
-		respondError(w, http.StatusBadRequest, "first_line must be >= 1")
-		return
-	}
-	if len(req.Lines) == 0 {
-		respondError(w, http.StatusBadRequest, "lines array cannot be empty")
-		return
-	}
-
-	// Validate field lengths
-	if err := validation.ValidateSyncFileName(req.FileName); err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+	// Everything the dry run (POST /sync/chunk/validate) checks, in the same
+	// code. Holds the file's upload lock until the handler returns.
+	plan, ok := s.validateSyncChunk(w, r, userID, &req, false)
+	if !ok {
 		return
 	}
-	if req.Metadata != nil {
-		if req.Metadata.Summary != nil {
-			if err := validation.ValidateSummary(*req.Metadata.Summary); err != nil {
-				respondError(w, http.StatusBadRequest, err.Error())
-				return
-			}
-		}
-		if req.Metadata.FirstUserMessage != nil {
-			if err := validation.ValidateFirstUserMessage(*req.Metadata.FirstUserMessage); err != nil {
-				respondError(w, http.StatusBadRequest, err.Error())
-				return
-			}
-		}
-		if req.Metadata.CodexRollout != nil {
-			cr := req.Metadata.CodexRollout
-			if err := validation.ValidateCodexRolloutMetadata(
-				cr.ThreadUUID, cr.ParentThreadUUID,
-				cr.RolloutPath, cr.CWD, cr.Model, cr.Source, cr.ThreadSource,
-				cr.AgentPath, cr.AgentRole, cr.AgentNickname,
-			); err != nil {
-				respondError(w, http.StatusBadRequest, err.Error())
-				return
-			}
-		}
-		// zsr6: validate the Cursor metadata.model length (persisted into the
-		// cursor_session_meta sidecar below). Empty/absent is valid (skipped).
-		if req.Metadata.Model != nil {
-			if err := validation.ValidateCursorModel(*req.Metadata.Model); err != nil {
-				respondError(w, http.StatusBadRequest, err.Error())
-				return
-			}
-		}
-		// CF-494: validate git_info shape (new remotes/tracking_remote fields).
-		if req.Metadata.GitInfo != nil && req.FileType == "transcript" {
-			if err := validation.ValidateGitInfo(req.Metadata.GitInfo); err != nil {
-				respondError(w, http.StatusBadRequest, err.Error())
-				return
-			}
-		}
-	}
+	defer plan.release()
+	externalID, provider := plan.externalID, plan.provider
+	syncState, generation := plan.syncState, plan.generation
 
-	// Verify session ownership and get external_id (needed for S3 key)
 	dbCtx, dbCancel := context.WithTimeout(r.Context(), DatabaseTimeout)
 	defer dbCancel()
-
 	sessionStore := &dbsession.Store{DB: s.db}
-	externalID, provider, err := sessionStore.VerifySessionOwnership(dbCtx, req.SessionID, userID)
-	if err != nil {
-		if errors.Is(err, db.ErrSessionNotFound) {
-			respondError(w, http.StatusNotFound, "Session not found")
-			return
-		}
-		if errors.Is(err, db.ErrForbidden) {
-			respondError(w, http.StatusForbidden, "Access denied")
-			return
-		}
-		log.Error("Failed to verify session ownership", "error", err, "session_id", req.SessionID)
-		respondError(w, http.StatusInternalServerError, "Failed to verify session")
-		return
-	}
-
-	// codex_rollout metadata is only meaningful for codex sessions. Check
-	// after VerifySessionOwnership so we don't leak the existence of someone
-	// else's claude-code session via this validation path.
-	if req.Metadata != nil && req.Metadata.CodexRollout != nil && provider != models.ProviderCodex {
-		respondError(w, http.StatusBadRequest,
-			"codex_rollout metadata is only supported for codex sessions")
-		return
-	}
-
-	// Get current sync state to validate chunk continuity
-	syncState, err := sessionStore.GetSyncFileState(dbCtx, req.SessionID, req.FileName)
-	expectedFirstLine := 1
-	if err == nil {
-		// File exists - next chunk must continue from where we left off
-		expectedFirstLine = syncState.LastSyncedLine + 1
-	} else if !errors.Is(err, db.ErrFileNotFound) {
-		log.Error("Failed to get sync state", "error", err, "session_id", req.SessionID, "file_name", req.FileName)
-		respondError(w, http.StatusInternalServerError, "Failed to get sync state")
-		return
-	}
-	// ErrFileNotFound is fine - it's a new file, expectedFirstLine stays 1
-
-	// Validate chunk continuity (no gaps, no overlaps)
-	if req.FirstLine != expectedFirstLine {
-		log.Warn("Chunk continuity error",
-			"session_id", req.SessionID,
-			"file_name", req.FileName,
-			"expected_first_line", expectedFirstLine,
-			"actual_first_line", req.FirstLine)
-		respondError(w, http.StatusBadRequest,
-			fmt.Sprintf("first_line must be %d (got %d) - chunks must be contiguous", expectedFirstLine, req.FirstLine))
-		return
-	}
-
-	// Soft limit check on chunk count (if known)
-	// This is a soft limit - races may allow slightly exceeding it, but reads will self-heal
-	if syncState != nil && syncState.ChunkCount != nil && *syncState.ChunkCount >= storage.MaxChunksPerFile {
-		log.Warn("Chunk limit exceeded",
-			"session_id", req.SessionID,
-			"file_name", req.FileName,
-			"chunk_count", *syncState.ChunkCount,
-			"limit", storage.MaxChunksPerFile)
-		respondError(w, http.StatusBadRequest,
-			fmt.Sprintf("File has too many chunks (limit: %d). Consider starting a new session.", storage.MaxChunksPerFile))
-		return
-	}
 
 	// Build chunk content (lines joined by newlines, with trailing newline).
 	//
@@ -515,8 +554,34 @@ func (s *Server) handleSyncChunk(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Calculate last line number
-	lastLine := req.FirstLine + len(req.Lines) - 1
+	lastLine := plan.lastLine
+
+	// Record the upload before it happens, so an object that lands without
+	// its sync-state update below (a DB failure or crash in between) is never
+	// untracked: the worker settles events left pending (see
+	// ReconcileChunkUpload). A failure here uploads nothing.
+	chunkKey, err := storage.ChunkKey(userID, provider, externalID, req.FileName, req.FirstLine, lastLine)
+	if err != nil {
+		log.Error("Failed to build chunk key", "error", err, "session_id", req.SessionID)
+		respondError(w, http.StatusInternalServerError, "Failed to upload chunk")
+		return
+	}
+	uploadEventID, err := sessionStore.RecordChunkUpload(dbCtx, dbsession.ChunkUpload{
+		SessionID:     req.SessionID,
+		FileName:      req.FileName,
+		FileType:      req.FileType,
+		Generation:    generation,
+		FirstLine:     req.FirstLine,
+		LastLine:      lastLine,
+		S3Key:         chunkKey,
+		ChunkBytes:    int64(content.Len()),
+		LastMessageAt: latestTimestamp,
+	})
+	if err != nil {
+		log.Error("Failed to record chunk upload", "error", err, "session_id", req.SessionID, "file_name", req.FileName)
+		respondError(w, http.StatusInternalServerError, "Failed to upload chunk")
+		return
+	}
 
 	// Upload chunk to S3
 	storageCtx, storageCancel := context.WithTimeout(r.Context(), StorageTimeout)
@@ -582,17 +647,72 @@ func (s *Server) handleSyncChunk(w http.ResponseWriter, r *http.Request) {
 		createdAt = clampFutureTimestamp(req.Metadata.CreatedAt)
 	}
 
-	if err := sessionStore.UpdateSyncFileState(updateCtx, req.SessionID, req.FileName, req.FileType, lastLine, latestTimestamp, createdAt, summary, firstUserMessage, gitInfo); err != nil {
+	// Claude Code's CLI-supplied first_user_message is often a tool_result
+	// blob or a slash-command envelope. Prefer the first human prompt parsed
+	// from the chunk itself; the store lets it replace a client value but not
+	// an earlier parsed one. The client value stays the fallback for chunks
+	// without a prompt and for other providers.
+	firstUserMessageDerived := false
+	if parseClaudeCode {
+		if prompt := analytics.FirstClaudeHumanPrompt(req.Lines, validation.MaxFirstUserMessageLength); prompt != "" {
+			firstUserMessage = &prompt
+			firstUserMessageDerived = true
+		}
+	}
+
+	if err := sessionStore.UpdateSyncFileState(updateCtx, req.SessionID, req.FileName, req.FileType, generation, lastLine, int64(content.Len()), latestTimestamp, createdAt, summary, firstUserMessage, firstUserMessageDerived, gitInfo); err != nil {
+		if errors.Is(err, db.ErrGenerationChanged) {
+			// A reset of this file committed while the chunk was uploading, so
+			// the object holds lines of the archived generation in the live
+			// prefix. Remove it; the worker deletes it instead if this fails.
+			if delErr := s.storage.Delete(storageCtx, s3Key); delErr != nil {
+				log.Warn("Failed to delete chunk of a reset generation", "error", delErr, "session_id", req.SessionID, "s3_key", s3Key)
+			} else if err := sessionStore.DiscardChunkUpload(updateCtx, uploadEventID); err != nil {
+				log.Warn("Failed to discard chunk upload", "error", err, "session_id", req.SessionID, "upload_event_id", uploadEventID)
+			}
+			respondErrorCode(w, http.StatusConflict, httputil.CodeConflict, "File was reset during the upload; call sync/init for its current state")
+			return
+		}
+		if errors.Is(err, db.ErrTranscriptArchived) {
+			// The worker archived the transcript while the chunk was
+			// uploading, possibly after deleting the session's chunks. Remove
+			// the object the same way.
+			if delErr := s.storage.Delete(storageCtx, s3Key); delErr != nil {
+				log.Warn("Failed to delete chunk of an archived transcript", "error", delErr, "session_id", req.SessionID, "s3_key", s3Key)
+			} else if err := sessionStore.DiscardChunkUpload(updateCtx, uploadEventID); err != nil {
+				log.Warn("Failed to discard chunk upload", "error", err, "session_id", req.SessionID, "upload_event_id", uploadEventID)
+			}
+			respondTranscriptArchived(w)
+			return
+		}
 		log.Error("Failed to update sync state",
 			"error", err,
 			"session_id", req.SessionID,
 			"file_name", req.FileName,
 			"last_line", lastLine)
-		// Note: S3 chunk was already uploaded - consider this a partial success
-		// The next sync will detect the mismatch and can retry
+		// The chunk is in S3 but untracked. A client retry of the same chunk
+		// supersedes the pending upload event; otherwise the worker replays
+		// this update from it.
 		respondError(w, http.StatusInternalServerError, "Failed to update sync state")
 		return
 	}
+	if err := sessionStore.ConfirmChunkUpload(updateCtx, uploadEventID); err != nil {
+		// Harmless: the worker finds the chunk already recorded and marks the
+		// event superseded.
+		log.Warn("Failed to confirm chunk upload", "error", err, "session_id", req.SessionID, "upload_event_id", uploadEventID)
+	}
+
+	// Tell open dashboards. chunk_count mirrors the increment
+	// UpdateSyncFileState just applied.
+	chunkCount := 1
+	if syncState != nil && syncState.ChunkCount != nil {
+		chunkCount = *syncState.ChunkCount + 1
+	}
+	s.syncProgress.Publish(req.SessionID, SyncProgress{
+		FileName:       req.FileName,
+		LastSyncedLine: lastLine,
+		ChunkCount:     chunkCount,
+	})
 
 	// Create GitHub links extracted from pr-link transcript lines
 	// Errors here must not fail the chunk upload
@@ -609,6 +729,12 @@ func (s *Server) handleSyncChunk(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Duplicate-detection fingerprint over the first FingerprintLines lines
+	// of the transcript. Best-effort; never fails the upload.
+	if req.FileType == "transcript" {
+		advanceContentFingerprint(updateCtx, sessionStore, req.SessionID, req.FileName, req.FirstLine, req.Lines)
+	}
+
 	// Codex rollout sidecar (CF-385). Runs after S3 + sync state are
 	// committed so a failure here leaves no orphan content — only a delayed
 	// metadata registration that the next chunk's upsert will reconcile.
@@ -667,6 +793,10 @@ func (s *Server) handleSyncChunk(w http.ResponseWriter, r *http.Request) {
 		"last_line", lastLine,
 		"s3_key", s3Key)
 
+	if keyID, ok := auth.GetAPIKeyID(r.Context()); ok {
+		s.apiKeyUsage.Record(keyID, len(req.Lines), content.Len())
+	}
+
 	respondJSON(w, http.StatusOK, SyncChunkResponse{
 		LastSyncedLine: lastLine,
 	})
@@ -714,6 +844,202 @@ func filterLinesAfterOffset(content []byte, offset int, firstLineNum int) []byte
 	return bytes.Join(remaining, []byte("\n"))
 }
 
+// numberLines prefixes each line of content with its absolute transcript line
+// number followed by a tab ("<n>\t<line>"), starting at firstLineNum. A trailing
+// newline in content is preserved.
+func numberLines(content []byte, firstLineNum int) []byte {
+	if len(content) == 0 {
+		return content
+	}
+
+	body, trailingNewline := bytes.CutSuffix(content, []byte("\n"))
+	lines := bytes.Split(body, []byte("\n"))
+
+	out := make([]byte, 0, len(content)+len(lines)*8)
+	for i, line := range lines {
+		if i > 0 {
+			out = append(out, '\n')
+		}
+		out = strconv.AppendInt(out, int64(firstLineNum+i), 10)
+		out = append(out, '\t')
+		out = append(out, line...)
+	}
+	if trailingNewline {
+		out = append(out, '\n')
+	}
+	return out
+}
+
+// syncFileLine is one element of the format=json sync file read response.
+type syncFileLine struct {
+	N    int    `json:"n"`
+	Text string `json:"text"`
+}
+
+// writeLinesJSON writes content as
+// {"lines":[{"n":<n>,"text":"<line>"},...],"last_line":<n>,"total_lines":<n>},
+// numbering lines from firstLineNum. Elements are encoded one at a time through
+// a buffered writer, so a large file is never held as a second, JSON-encoded
+// copy in memory. When content is empty, last_line is emptyLastLine.
+func writeLinesJSON(w io.Writer, content []byte, firstLineNum, emptyLastLine, totalLines int) error {
+	bw := bufio.NewWriterSize(w, 32*1024)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	bw.WriteString(`{"lines":[`)
+	lastLine := emptyLastLine
+	if len(content) > 0 {
+		body, _ := bytes.CutSuffix(content, []byte("\n"))
+		for i, line := range bytes.Split(body, []byte("\n")) {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			lastLine = firstLineNum + i
+			if err := enc.Encode(syncFileLine{N: lastLine, Text: string(line)}); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Fprintf(bw, `],"last_line":%d,"total_lines":%d}`, lastLine, totalLines)
+	return bw.Flush()
+}
+
+// handleSyncFileReset starts a new generation of a file the client rewrote
+// shorter than what was already synced (e.g. after compaction), which chunk
+// continuity would otherwise reject forever. The live chunks move to an
+// archived generation in storage, the file restarts at line 0, and the
+// session's cards and search index are dropped so analytics recompute from
+// the new content.
+// POST /api/v1/sync/file/reset
+func (s *Server) handleSyncFileReset(w http.ResponseWriter, r *http.Request) {
+	log := logger.Ctx(r.Context())
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req SyncFileResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	if req.SessionID == "" {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "session_id is required")
+		return
+	}
+	if req.FileName == "" {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "file_name is required")
+		return
+	}
+	if err := validation.ValidateSyncFileName(req.FileName); err != nil {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, err.Error())
+		return
+	}
+	if req.Reason != dbsession.ResetReasonCompaction && req.Reason != dbsession.ResetReasonManual {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "reason must be compaction or manual")
+		return
+	}
+	if req.LocalLineCount < 0 {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "local_line_count must be >= 0")
+		return
+	}
+
+	dbCtx, dbCancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer dbCancel()
+
+	sessionStore := &dbsession.Store{DB: s.db}
+	externalID, provider, err := sessionStore.VerifySessionOwnership(dbCtx, req.SessionID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrSessionNotFound) {
+			respondErrorCode(w, http.StatusNotFound, httputil.CodeSessionNotFound, "Session not found")
+			return
+		}
+		if errors.Is(err, db.ErrForbidden) {
+			respondError(w, http.StatusForbidden, "Access denied")
+			return
+		}
+		log.Error("Failed to verify session ownership", "error", err, "session_id", req.SessionID)
+		respondError(w, http.StatusInternalServerError, "Failed to verify session")
+		return
+	}
+
+	archived, err := sessionStore.IsTranscriptArchived(dbCtx, req.SessionID)
+	if err != nil {
+		log.Error("Failed to check transcript archive", "error", err, "session_id", req.SessionID)
+		respondError(w, http.StatusInternalServerError, "Failed to verify session")
+		return
+	}
+	if archived {
+		respondTranscriptArchived(w)
+		return
+	}
+
+	state, err := sessionStore.GetSyncFileState(dbCtx, req.SessionID, req.FileName)
+	if err != nil {
+		if errors.Is(err, db.ErrFileNotFound) {
+			respondErrorCode(w, http.StatusNotFound, httputil.CodeFileNotFound, "File not found")
+			return
+		}
+		log.Error("Failed to get sync state", "error", err, "session_id", req.SessionID, "file_name", req.FileName)
+		respondError(w, http.StatusInternalServerError, "Failed to get sync state")
+		return
+	}
+
+	// The chunks are moved while ResetSyncFile holds the file's row lock, so a
+	// concurrent sync/chunk can't record a chunk of the old generation in the
+	// live prefix after the move: its sync-state update waits for the reset
+	// and then fails the generation check. If the move fails the file is
+	// unchanged and the client retries.
+	resetCtx, resetCancel := context.WithTimeout(r.Context(), DatabaseTimeout+StorageTimeout)
+	defer resetCancel()
+
+	var moved int
+	var archiveErr error
+	newState, err := sessionStore.ResetSyncFile(resetCtx, req.SessionID, req.FileName, state.Generation, req.Reason, req.LocalLineCount, analytics.SessionDerivedTableNames, func() error {
+		storageCtx, storageCancel := context.WithTimeout(resetCtx, StorageTimeout)
+		defer storageCancel()
+		moved, archiveErr = s.storage.ArchiveChunkGeneration(storageCtx, userID, provider, externalID, req.FileName, state.Generation)
+		return archiveErr
+	})
+	if err != nil {
+		if archiveErr != nil {
+			log.Error("Failed to archive chunk generation",
+				"error", archiveErr,
+				"session_id", req.SessionID,
+				"file_name", req.FileName,
+				"generation", state.Generation)
+			respondStorageError(w, archiveErr, "Failed to archive file chunks")
+			return
+		}
+		if errors.Is(err, db.ErrGenerationChanged) {
+			respondErrorCode(w, http.StatusConflict, httputil.CodeConflict, "File was reset concurrently; call sync/init for its current state")
+			return
+		}
+		log.Error("Failed to reset sync file", "error", err, "session_id", req.SessionID, "file_name", req.FileName)
+		respondError(w, http.StatusInternalServerError, "Failed to reset sync state")
+		return
+	}
+
+	log.Info("Sync file reset",
+		"session_id", req.SessionID,
+		"file_name", req.FileName,
+		"reason", req.Reason,
+		"archived_generation", state.Generation,
+		"archived_lines", state.LastSyncedLine,
+		"archived_chunks", moved,
+		"local_line_count", req.LocalLineCount)
+
+	respondJSON(w, http.StatusOK, SyncFileResetResponse{
+		FileName:           req.FileName,
+		Generation:         newState.Generation,
+		LastSyncedLine:     newState.LastSyncedLine,
+		ArchivedGeneration: state.Generation,
+		ArchivedLines:      state.LastSyncedLine,
+	})
+}
+
 // handleSyncEvent records a session lifecycle event
 // POST /api/v1/sync/event
 func (s *Server) handleSyncEvent(w http.ResponseWriter, r *http.Request) {
@@ -728,25 +1054,25 @@ func (s *Server) handleSyncEvent(w http.ResponseWriter, r *http.Request) {
 	// Parse request
 	var req SyncEventRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid JSON body")
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeInvalidRequestBody, "Invalid JSON body")
 		return
 	}
 
 	// Validate required fields
 	if req.SessionID == "" {
-		respondError(w, http.StatusBadRequest, "session_id is required")
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "session_id is required")
 		return
 	}
 	if req.EventType == "" {
-		respondError(w, http.StatusBadRequest, "event_type is required")
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "event_type is required")
 		return
 	}
 	if req.EventType != "session_end" {
-		respondError(w, http.StatusBadRequest, "invalid event_type: must be 'session_end'")
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "invalid event_type: must be 'session_end'")
 		return
 	}
 	if req.Timestamp.IsZero() {
-		respondError(w, http.StatusBadRequest, "timestamp is required")
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "timestamp is required")
 		return
 	}
 
@@ -758,7 +1084,7 @@ func (s *Server) handleSyncEvent(w http.ResponseWriter, r *http.Request) {
 	_, _, err := sessionStore.VerifySessionOwnership(dbCtx, req.SessionID, userID)
 	if err != nil {
 		if errors.Is(err, db.ErrSessionNotFound) {
-			respondError(w, http.StatusNotFound, "Session not found")
+			respondErrorCode(w, http.StatusNotFound, httputil.CodeSessionNotFound, "Session not found")
 			return
 		}
 		if errors.Is(err, db.ErrForbidden) {
@@ -797,17 +1123,32 @@ func (s *Server) handleSyncEvent(w http.ResponseWriter, r *http.Request) {
 // ============================================================================
 
 // handleCanonicalSyncFileRead reads and concatenates all chunks for a file via canonical access (CF-132)
-// GET /api/v1/sessions/{id}/sync/file?file_name=...&line_offset=...
+// GET /api/v1/sessions/{id}/sync/file?file_name=...&line_offset=...&with_line_numbers=...&format=...&generation=...
 // Supports: owner access, public shares, system shares, recipient shares
 //
 // The optional line_offset parameter enables incremental fetching:
 // - If line_offset is 0 or omitted, returns all lines
 // - If line_offset > 0, returns only lines after line_offset (lines N+1 onwards)
 //
+// The optional with_line_numbers=true parameter prefixes each returned line with
+// its absolute line number and a tab ("<n>\t<line>"), so numbering starts at
+// line_offset+1. The default output is the raw JSONL, unchanged.
+//
+// format=json returns {"lines":[{"n":..,"text":..}],"last_line":..,"total_lines":..}
+// instead, built from the same merge and offset logic (with_line_numbers is
+// ignored there, since every line already carries n). last_line is the number
+// of the last returned line, or min(line_offset, total_lines) when none are
+// returned, so it can be passed back as the next line_offset. The Accept header
+// is deliberately not consulted: clients that send a blanket
+// "Accept: application/json" must keep receiving the raw text.
+//
+// generation=N reads an archived generation of a file that was reset via
+// POST /sync/file/reset; the live generation is served when it is omitted.
+//
 // Optimizations:
 // - DB short-circuit: if line_offset >= last_synced_line, returns empty without S3 access
 // - Chunk filtering: only downloads chunks containing lines > line_offset
-// - Self-healing: corrects DB chunk_count if it differs from actual S3 count (owner only)
+// - Self-healing: corrects DB chunk_count if it differs from actual S3 count (owner only, live generation)
 func (s *Server) handleCanonicalSyncFileRead(w http.ResponseWriter, r *http.Request) {
 	log := logger.Ctx(r.Context())
 
@@ -815,13 +1156,16 @@ func (s *Server) handleCanonicalSyncFileRead(w http.ResponseWriter, r *http.Requ
 	sessionID := chi.URLParam(r, "id")
 	fileName := r.URL.Query().Get("file_name")
 	lineOffsetStr := r.URL.Query().Get("line_offset")
+	withLineNumbersStr := r.URL.Query().Get("with_line_numbers")
+	format := r.URL.Query().Get("format")
+	generationStr := r.URL.Query().Get("generation")
 
 	if sessionID == "" {
-		respondError(w, http.StatusBadRequest, "session_id is required")
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "session_id is required")
 		return
 	}
 	if fileName == "" {
-		respondError(w, http.StatusBadRequest, "file_name is required")
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "file_name is required")
 		return
 	}
 
@@ -831,15 +1175,47 @@ func (s *Server) handleCanonicalSyncFileRead(w http.ResponseWriter, r *http.Requ
 		var err error
 		lineOffset, err = strconv.Atoi(lineOffsetStr)
 		if err != nil {
-			respondError(w, http.StatusBadRequest, "line_offset must be a valid integer")
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "line_offset must be a valid integer")
 			return
 		}
 		if lineOffset < 0 {
-			respondError(w, http.StatusBadRequest, "line_offset must be non-negative")
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "line_offset must be non-negative")
+			return
+		}
+	}
+
+	var withLineNumbers bool
+	if withLineNumbersStr != "" {
+		var err error
+		withLineNumbers, err = strconv.ParseBool(withLineNumbersStr)
+		if err != nil {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "with_line_numbers must be a boolean")
+			return
+		}
+	}
+
+	// generation selects an archived generation of a file that was reset;
+	// omitted means the live one.
+	generation := -1
+	if generationStr != "" {
+		var err error
+		generation, err = strconv.Atoi(generationStr)
+		if err != nil || generation < 0 {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "generation must be a non-negative integer")
 			return
 		}
 	}
 
+	var jsonMode bool
+	switch format {
+	case "", "text":
+	case "json":
+		jsonMode = true
+	default:
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "format must be text or json")
+		return
+	}
+
 	// Check canonical access (CF-132 unified access model)
 	dbCtx, dbCancel := context.WithTimeout(r.Context(), DatabaseTimeout)
 	defer dbCancel()
@@ -851,7 +1227,7 @@ func (s *Server) handleCanonicalSyncFileRead(w http.ResponseWriter, r *http.Requ
 
 	// Handle no access - sync endpoint always returns 404 (no AuthMayHelp prompt)
 	if result.AccessInfo.AccessType == db.SessionAccessNone {
-		respondError(w, http.StatusNotFound, "Session not found")
+		respondErrorCode(w, http.StatusNotFound, httputil.CodeSessionNotFound, "Session not found")
 		return
 	}
 
@@ -876,15 +1252,58 @@ func (s *Server) handleCanonicalSyncFileRead(w http.ResponseWriter, r *http.Requ
 		}
 	}
 	if fileInfo == nil {
-		respondError(w, http.StatusNotFound, "File not found")
+		respondErrorCode(w, http.StatusNotFound, httputil.CodeFileNotFound, "File not found")
+		return
+	}
+	if session.TranscriptArchivedAt != nil {
+		respondTranscriptArchived(w)
 		return
 	}
 
-	// Short-circuit: if line_offset >= last_synced_line, no new lines exist
-	// Return empty response without touching S3
-	if lineOffset >= fileInfo.LastSyncedLine {
+	// An archived generation is read from its own storage prefix and sized by
+	// the line count recorded when the file was reset.
+	lastSyncedLine := fileInfo.LastSyncedLine
+	archivedGeneration := generation >= 0 && generation != fileInfo.Generation
+	if archivedGeneration {
+		gen, err := sessionStore.GetSyncFileGeneration(dbCtx, sessionID, fileName, generation)
+		if errors.Is(err, db.ErrFileNotFound) {
+			respondErrorCode(w, http.StatusNotFound, httputil.CodeFileNotFound, "File generation not found")
+			return
+		}
+		if err != nil {
+			log.Error("Failed to get sync file generation", "error", err, "session_id", sessionID, "generation", generation)
+			respondError(w, http.StatusInternalServerError, "Failed to get session info")
+			return
+		}
+		lastSyncedLine = gen.LineCount
+	}
+
+	// respondLines writes the (possibly empty) selected lines in the requested
+	// format. firstLineNum is the absolute number of content's first line.
+	respondLines := func(content []byte, firstLineNum int) {
+		if jsonMode {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			if err := writeLinesJSON(w, content, firstLineNum, min(lineOffset, lastSyncedLine), lastSyncedLine); err != nil {
+				log.Warn("Failed to write sync file JSON", "error", err, "session_id", sessionID)
+			}
+			return
+		}
+		if withLineNumbers {
+			content = numberLines(content, firstLineNum)
+		}
+		// Use text/plain for JSONL files (multiple JSON objects, one per line)
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
+		if content != nil {
+			w.Write(content)
+		}
+	}
+
+	// Short-circuit: if line_offset >= last_synced_line, no new lines exist
+	// Return empty response without touching S3
+	if lineOffset >= lastSyncedLine {
+		respondLines(nil, 0)
 		return
 	}
 
@@ -892,7 +1311,12 @@ func (s *Server) handleCanonicalSyncFileRead(w http.ResponseWriter, r *http.Requ
 	listCtx, listCancel := context.WithTimeout(r.Context(), StorageTimeout)
 	defer listCancel()
 
-	chunkKeys, err := s.storage.ListChunks(listCtx, sessionUserID, provider, externalID, fileName)
+	var chunkKeys []string
+	if archivedGeneration {
+		chunkKeys, err = s.storage.ListGenerationChunks(listCtx, sessionUserID, provider, externalID, fileName, generation)
+	} else {
+		chunkKeys, err = s.storage.ListChunks(listCtx, sessionUserID, provider, externalID, fileName)
+	}
 	if err != nil {
 		log.Error("Failed to list chunks", "error", err, "session_id", sessionID, "file_name", fileName)
 		respondStorageError(w, err, "Failed to list chunks")
@@ -900,14 +1324,14 @@ func (s *Server) handleCanonicalSyncFileRead(w http.ResponseWriter, r *http.Requ
 	}
 
 	if len(chunkKeys) == 0 {
-		respondError(w, http.StatusNotFound, "File not found")
+		respondErrorCode(w, http.StatusNotFound, httputil.CodeFileNotFound, "File not found")
 		return
 	}
 
 	// Self-healing: update DB chunk_count if it differs from actual S3 count (owner only)
 	// This corrects any drift from races or failed uploads
 	// Only do this when lineOffset == 0 (full read) to avoid extra DB calls on incremental fetches
-	if isOwner && lineOffset == 0 {
+	if isOwner && lineOffset == 0 && !archivedGeneration {
 		// Get current chunk_count from DB for comparison
 		syncState, err := sessionStore.GetSyncFileState(dbCtx, sessionID, fileName)
 		if err == nil {
@@ -952,18 +1376,12 @@ func (s *Server) handleCanonicalSyncFileRead(w http.ResponseWriter, r *http.Requ
 
 	// If no relevant chunks after filtering, return empty response
 	if len(chunkKeys) == 0 {
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusOK)
+		respondLines(nil, 0)
 		return
 	}
 
 	// Download chunks and parse their line ranges.
-	// Scale the timeout for large sessions: 10 parallel downloads at ~100ms each
-	// means ~100ms amortized per chunk. Use 500ms/chunk for headroom, capped at 5 min.
-	downloadTimeout := StorageTimeout + time.Duration(len(chunkKeys))*500*time.Millisecond
-	if downloadTimeout > 5*time.Minute {
-		downloadTimeout = 5 * time.Minute
-	}
+	downloadTimeout := chunkDownloadTimeout(len(chunkKeys))
 	// Extend the HTTP write deadline so the server doesn't kill the connection
 	// before the download+merge+write completes for large sessions.
 	rc := http.NewResponseController(w)
@@ -980,8 +1398,7 @@ func (s *Server) handleCanonicalSyncFileRead(w http.ResponseWriter, r *http.Requ
 	}
 
 	if len(chunks) == 0 {
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusOK)
+		respondLines(nil, 0)
 		return
 	}
 
@@ -1006,21 +1423,33 @@ func (s *Server) handleCanonicalSyncFileRead(w http.ResponseWriter, r *http.Requ
 		merged = filterLinesAfterOffset(merged, lineOffset, minFirstLine)
 	}
 
+	firstLineNum := minFirstLine
+	if lineOffset >= minFirstLine {
+		firstLineNum = lineOffset + 1
+	}
+
 	log.Info("Canonical sync file read",
 		"session_id", sessionID,
 		"file_name", fileName,
 		"chunk_count", len(chunks),
 		"line_offset", lineOffset,
+		"with_line_numbers", withLineNumbers,
+		"format", format,
 		"access_type", result.AccessInfo.AccessType,
 		"viewer_user_id", result.ViewerUserID)
 
-	// Write response
-	// Use text/plain for JSONL files (multiple JSON objects, one per line)
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	if merged != nil {
-		w.Write(merged)
+	respondLines(merged, firstLineNum)
+}
+
+// chunkDownloadTimeout scales the download budget for a file with the given
+// number of chunks: 10 parallel downloads at ~100ms each means ~100ms
+// amortized per chunk. Uses 500ms/chunk for headroom, capped at 5 min.
+func chunkDownloadTimeout(chunkCount int) time.Duration {
+	timeout := StorageTimeout + time.Duration(chunkCount)*500*time.Millisecond
+	if timeout > 5*time.Minute {
+		timeout = 5 * time.Minute
 	}
+	return timeout
 }
 
 // extractTextFromMessage extracts the first text content from a message entry
@@ -1148,20 +1577,20 @@ func (s *Server) handleUpdateSessionSummary(w http.ResponseWriter, r *http.Reque
 	// Get external_id from URL
 	externalID := chi.URLParam(r, "external_id")
 	if externalID == "" {
-		respondError(w, http.StatusBadRequest, "external_id is required")
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "external_id is required")
 		return
 	}
 
 	// Parse request body
 	var req UpdateSummaryRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
 	// Validate summary length
 	if err := validation.ValidateSummary(req.Summary); err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, err.Error())
 		return
 	}
 
@@ -1173,7 +1602,7 @@ func (s *Server) handleUpdateSessionSummary(w http.ResponseWriter, r *http.Reque
 	err := sessionStore.UpdateSessionSummary(ctx, externalID, userID, req.Summary)
 	if err != nil {
 		if errors.Is(err, db.ErrSessionNotFound) {
-			respondError(w, http.StatusNotFound, "Session not found")
+			respondErrorCode(w, http.StatusNotFound, httputil.CodeSessionNotFound, "Session not found")
 			return
 		}
 		if errors.Is(err, db.ErrForbidden) {
@@ -0,0 +1,180 @@
+package sync_test
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestReassignExternalID_HTTP_Integration covers
+// PATCH /api/v1/sessions/{id}/external-id: after the reassignment a sync/init
+// with the new external ID resumes the same session where it left off.
+func TestReassignExternalID_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+
+	syncInit := func(t *testing.T, client *testutil.TestClient, externalID string) api.SyncInitResponse {
+		t.Helper()
+		resp, err := client.Post("/api/v1/sync/init", api.SyncInitRequest{
+			ExternalID:     externalID,
+			TranscriptPath: "/home/user/project/transcript.jsonl",
+			CWD:            "/home/user/project",
+		})
+		if err != nil {
+			t.Fatalf("init request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result api.SyncInitResponse
+		testutil.ParseJSON(t, resp, &result)
+		return result
+	}
+
+	postChunk := func(t *testing.T, client *testutil.TestClient, sessionID string, firstLine int, lines []string) {
+		t.Helper()
+		resp, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "transcript.jsonl",
+			FileType:  "transcript",
+			FirstLine: firstLine,
+			Lines:     lines,
+		})
+		if err != nil {
+			t.Fatalf("chunk request failed: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+	}
+
+	reassign := func(t *testing.T, client *testutil.TestClient, sessionID, externalID string) *http.Response {
+		t.Helper()
+		resp, err := client.Patch("/api/v1/sessions/"+sessionID+"/external-id", api.ReassignExternalIDRequest{ExternalID: externalID})
+		if err != nil {
+			t.Fatalf("reassign request failed: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("sync/init with the new external ID resumes the session", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "reassign@example.com", "Reassign User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Key")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		sessionID := syncInit(t, client, "old-external-id").SessionID
+		postChunk(t, client, sessionID, 1, []string{`{"n":1}`, `{"n":2}`})
+		postChunk(t, client, sessionID, 3, []string{`{"n":3}`})
+
+		resp := reassign(t, client, sessionID, "new-external-id")
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result api.ReassignExternalIDResponse
+		testutil.ParseJSON(t, resp, &result)
+		if result.SessionID != sessionID || result.ExternalID != "new-external-id" || result.PreviousExternalID != "old-external-id" {
+			t.Errorf("reassign response = %+v", result)
+		}
+
+		resumed := syncInit(t, client, "new-external-id")
+		if resumed.SessionID != sessionID {
+			t.Fatalf("sync/init resumed session %s, want %s", resumed.SessionID, sessionID)
+		}
+		if got := resumed.Files["transcript.jsonl"].LastSyncedLine; got != 3 {
+			t.Errorf("resumed last_synced_line = %d, want 3", got)
+		}
+
+		// Syncing continues under the new ID and reads the re-keyed chunks.
+		postChunk(t, client, sessionID, 4, []string{`{"n":4}`})
+		readResp, err := client.Get("/api/v1/sessions/" + sessionID + "/sync/file?file_name=transcript.jsonl")
+		if err != nil {
+			t.Fatalf("read request failed: %v", err)
+		}
+		defer readResp.Body.Close()
+		testutil.RequireStatus(t, readResp, http.StatusOK)
+		body, _ := io.ReadAll(readResp.Body)
+		if want := "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n{\"n\":4}\n"; string(body) != want {
+			t.Errorf("file = %q, want %q", body, want)
+		}
+
+		// The old external ID no longer names a session.
+		if fresh := syncInit(t, client, "old-external-id"); fresh.SessionID == sessionID || len(fresh.Files) != 0 {
+			t.Errorf("sync/init with the old ID = %+v, want a new empty session", fresh)
+		}
+	})
+
+	t.Run("rejects an external ID another session has", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "collide@example.com", "Collide User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Key")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		sessionID := syncInit(t, client, "first").SessionID
+		postChunk(t, client, sessionID, 1, []string{`{"n":1}`})
+		otherID := syncInit(t, client, "second").SessionID
+
+		resp := reassign(t, client, sessionID, "second")
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusConflict)
+		var errBody httputil.ErrorResponse
+		testutil.ParseJSON(t, resp, &errBody)
+		if errBody.Code != httputil.CodeConflict {
+			t.Errorf("code = %q, want %q", errBody.Code, httputil.CodeConflict)
+		}
+
+		if got := syncInit(t, client, "first"); got.SessionID != sessionID || got.Files["transcript.jsonl"].LastSyncedLine != 1 {
+			t.Errorf("sync/init with the kept ID = %+v, want the unchanged session", got)
+		}
+		if got := syncInit(t, client, "second"); got.SessionID != otherID {
+			t.Errorf("sync/init with the taken ID resumed %s, want %s", got.SessionID, otherID)
+		}
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		env.CleanDB(t)
+
+		owner := testutil.CreateTestUser(t, env, "owner@example.com", "Owner")
+		ownerKey := testutil.CreateTestAPIKeyWithToken(t, env, owner.ID, "Key")
+		other := testutil.CreateTestUser(t, env, "other@example.com", "Other")
+		otherKey := testutil.CreateTestAPIKeyWithToken(t, env, other.ID, "Key")
+
+		ts := setupTestServerWithEnv(t, env)
+		ownerClient := testutil.NewTestClient(t, ts).WithAPIKey(ownerKey.RawToken)
+		otherClient := testutil.NewTestClient(t, ts).WithAPIKey(otherKey.RawToken)
+
+		sessionID := syncInit(t, ownerClient, "owned").SessionID
+
+		cases := []struct {
+			name       string
+			client     *testutil.TestClient
+			sessionID  string
+			externalID string
+			status     int
+		}{
+			{"empty external ID", ownerClient, sessionID, "", http.StatusBadRequest},
+			{"unknown session", ownerClient, "00000000-0000-0000-0000-000000000000", "renamed", http.StatusNotFound},
+			{"not the owner", otherClient, sessionID, "renamed", http.StatusForbidden},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				resp := reassign(t, tc.client, tc.sessionID, tc.externalID)
+				defer resp.Body.Close()
+				testutil.RequireStatus(t, resp, tc.status)
+			})
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package sync_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestSyncChunk_SessionOwnerCache_HTTP_Integration checks that the session
+// owner cache never outlives the session: once a cached session is deleted, a
+// chunk for it gets a 404, and a cached owner grants nothing to another user.
+func TestSyncChunk_SessionOwnerCache_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	env.DB.SessionOwners = db.NewSessionOwnerCache(time.Hour)
+
+	user := testutil.CreateTestUser(t, env, "owner-cache@example.com", "Owner")
+	other := testutil.CreateTestUser(t, env, "other@example.com", "Other")
+	apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+	otherKey := testutil.CreateTestAPIKeyWithToken(t, env, other.ID, "Other Key")
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "owner-cache-session")
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+	otherClient := testutil.NewTestClient(t, ts).WithAPIKey(otherKey.RawToken)
+	web := testutil.NewTestClient(t, ts).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+
+	chunk := func(c *testutil.TestClient, firstLine int) *http.Response {
+		t.Helper()
+		resp, err := c.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "transcript.jsonl",
+			FileType:  "transcript",
+			FirstLine: firstLine,
+			Lines:     []string{`{"type":"user","message":"Hello"}`},
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	// Caches the owner.
+	testutil.RequireStatus(t, chunk(client, 1), http.StatusOK)
+	if _, ok := env.DB.SessionOwners.Get(sessionID); !ok {
+		t.Fatal("expected the ownership check to be cached")
+	}
+
+	t.Run("cached owner does not admit another user", func(t *testing.T) {
+		testutil.RequireStatus(t, chunk(otherClient, 2), http.StatusForbidden)
+	})
+
+	t.Run("deleted session is not served from the cache", func(t *testing.T) {
+		resp, err := web.Delete("/api/v1/sessions/" + sessionID)
+		if err != nil {
+			t.Fatalf("delete failed: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		if _, ok := env.DB.SessionOwners.Get(sessionID); ok {
+			t.Error("expected delete to invalidate the cached owner")
+		}
+
+		testutil.RequireStatus(t, chunk(client, 2), http.StatusNotFound)
+	})
+}
@@ -0,0 +1,162 @@
+package sync_test
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestStorageUsage_HTTP_Integration checks that GET /api/v1/me/storage tracks
+// the bytes written by sync/chunk, keeps them across a file reset, and drops
+// them when the session is deleted.
+func TestStorageUsage_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "storage@example.com", "Storage User")
+	other := testutil.CreateTestUser(t, env, "storage-other@example.com", "Other User")
+	apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Key")
+	otherKey := testutil.CreateTestAPIKeyWithToken(t, env, other.ID, "Key")
+	big := testutil.CreateTestSession(t, env, user.ID, "storage-big")
+	small := testutil.CreateTestSession(t, env, user.ID, "storage-small")
+	otherSession := testutil.CreateTestSession(t, env, other.ID, "storage-other")
+
+	ts := setupTestServerWithEnv(t, env)
+	cli := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+	otherCli := testutil.NewTestClient(t, ts).WithAPIKey(otherKey.RawToken)
+	web := testutil.NewTestClient(t, ts).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+
+	// upload posts a chunk and returns the size of the object it stores: the
+	// lines newline-joined with a trailing newline.
+	upload := func(t *testing.T, client *testutil.TestClient, sessionID, fileName, fileType string, firstLine int, lines ...string) int64 {
+		t.Helper()
+		resp, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  fileName,
+			FileType:  fileType,
+			FirstLine: firstLine,
+			Lines:     lines,
+		})
+		if err != nil {
+			t.Fatalf("chunk request failed: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		return int64(len(strings.Join(lines, "\n") + "\n"))
+	}
+
+	getUsage := func(t *testing.T, query string) db.UserStorageUsage {
+		t.Helper()
+		resp, err := web.Get("/api/v1/me/storage" + query)
+		if err != nil {
+			t.Fatalf("storage request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var usage db.UserStorageUsage
+		testutil.ParseJSON(t, resp, &usage)
+		return usage
+	}
+
+	var bigBytes, smallBytes int64
+	bigBytes += upload(t, cli, big, "transcript.jsonl", "transcript", 1, `{"n":1}`, `{"n":2}`)
+	bigBytes += upload(t, cli, big, "transcript.jsonl", "transcript", 3, `{"n":3,"text":"a longer line"}`)
+	bigBytes += upload(t, cli, big, "agent-a.jsonl", "agent", 1, `{"agent":1}`)
+	smallBytes += upload(t, cli, small, "transcript.jsonl", "transcript", 1, `{"n":1}`)
+	upload(t, otherCli, otherSession, "transcript.jsonl", "transcript", 1, `{"someone":"else"}`)
+
+	t.Run("uploads add up per session", func(t *testing.T) {
+		usage := getUsage(t, "")
+		if usage.TotalBytes != bigBytes+smallBytes || usage.ChunkCount != 4 || usage.SessionCount != 2 || usage.PendingFileCount != 0 {
+			t.Errorf("totals = %+v, want %d bytes in 4 chunks over 2 sessions", usage, bigBytes+smallBytes)
+		}
+		if len(usage.Sessions) != 2 {
+			t.Fatalf("sessions = %+v, want 2", usage.Sessions)
+		}
+		if s := usage.Sessions[0]; s.SessionID != big || s.StoredBytes != bigBytes || s.ChunkCount != 3 {
+			t.Errorf("largest session = %+v, want %s with %d bytes in 3 chunks", s, big, bigBytes)
+		}
+		if s := usage.Sessions[1]; s.SessionID != small || s.StoredBytes != smallBytes || s.ExternalID != "storage-small" {
+			t.Errorf("second session = %+v, want %s with %d bytes", s, small, smallBytes)
+		}
+	})
+
+	t.Run("limit caps the breakdown, not the totals", func(t *testing.T) {
+		usage := getUsage(t, "?limit=1")
+		if len(usage.Sessions) != 1 || usage.Sessions[0].SessionID != big {
+			t.Errorf("sessions = %+v, want only %s", usage.Sessions, big)
+		}
+		if usage.TotalBytes != bigBytes+smallBytes || usage.SessionCount != 2 {
+			t.Errorf("totals = %+v, want all sessions counted", usage)
+		}
+	})
+
+	t.Run("rejects an out-of-range limit", func(t *testing.T) {
+		for _, q := range []string{"?limit=0", "?limit=1001", "?limit=many"} {
+			resp, err := web.Get("/api/v1/me/storage" + q)
+			if err != nil {
+				t.Fatalf("storage request failed: %v", err)
+			}
+			resp.Body.Close()
+			testutil.RequireStatus(t, resp, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("reset keeps the archived generation's bytes", func(t *testing.T) {
+		resp, err := cli.Post("/api/v1/sync/file/reset", api.SyncFileResetRequest{
+			SessionID:      small,
+			FileName:       "transcript.jsonl",
+			Reason:         "compaction",
+			LocalLineCount: 1,
+		})
+		if err != nil {
+			t.Fatalf("reset request failed: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		if usage := getUsage(t, ""); usage.TotalBytes != bigBytes+smallBytes {
+			t.Errorf("total after reset = %d, want %d", usage.TotalBytes, bigBytes+smallBytes)
+		}
+
+		smallBytes += upload(t, cli, small, "transcript.jsonl", "transcript", 1, `{"compacted":true}`)
+		if usage := getUsage(t, ""); usage.TotalBytes != bigBytes+smallBytes {
+			t.Errorf("total after re-upload = %d, want %d", usage.TotalBytes, bigBytes+smallBytes)
+		}
+	})
+
+	t.Run("deleting a session drops its bytes", func(t *testing.T) {
+		resp, err := web.Delete("/api/v1/sessions/" + big)
+		if err != nil {
+			t.Fatalf("delete request failed: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		usage := getUsage(t, "")
+		if usage.TotalBytes != smallBytes || usage.SessionCount != 1 || len(usage.Sessions) != 1 || usage.Sessions[0].SessionID != small {
+			t.Errorf("usage after delete = %+v, want only %s with %d bytes", usage, small, smallBytes)
+		}
+	})
+
+	t.Run("archived transcripts count as freed", func(t *testing.T) {
+		if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET transcript_archived_at = NOW() WHERE id = $1`, small); err != nil {
+			t.Fatalf("archive session: %v", err)
+		}
+		usage := getUsage(t, "")
+		if usage.TotalBytes != 0 || usage.SessionCount != 0 || len(usage.Sessions) != 0 {
+			t.Errorf("usage after archive = %+v, want empty", usage)
+		}
+	})
+}
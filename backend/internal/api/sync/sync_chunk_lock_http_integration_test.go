@@ -0,0 +1,156 @@
+package sync_test
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// POST /api/v1/sync/chunk - Per-file upload lock
+// =============================================================================
+
+func TestSyncChunk_FileLock_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+
+	t.Run("concurrent uploads of the same chunk store it once", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "lock@example.com", "Lock User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "test-session-lock")
+
+		ts := setupTestServerWithEnv(t, env)
+
+		const uploaders = 2
+		statuses := make([]int, uploaders)
+		var wg sync.WaitGroup
+		for i := range uploaders {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+				resp, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+					SessionID: sessionID,
+					FileName:  "transcript.jsonl",
+					FileType:  "transcript",
+					FirstLine: 1,
+					Lines: []string{
+						fmt.Sprintf(`{"type":"user","message":"uploader %d"}`, i),
+						`{"type":"assistant","message":"ok"}`,
+					},
+				})
+				if err != nil {
+					t.Errorf("uploader %d: request failed: %v", i, err)
+					return
+				}
+				defer resp.Body.Close()
+				statuses[i] = resp.StatusCode
+			}(i)
+		}
+		wg.Wait()
+
+		// The loser either hit the lock (429) or ran after the winner
+		// committed and failed the continuity check (400 chunk_overlap).
+		succeeded := 0
+		for i, status := range statuses {
+			switch status {
+			case http.StatusOK:
+				succeeded++
+			case http.StatusTooManyRequests, http.StatusBadRequest:
+			default:
+				t.Errorf("uploader %d: unexpected status %d", i, status)
+			}
+		}
+		if succeeded != 1 {
+			t.Fatalf("%d uploads succeeded, want exactly 1 (statuses %v)", succeeded, statuses)
+		}
+
+		var lastSyncedLine, chunkCount int
+		if err := env.DB.QueryRow(env.Ctx,
+			`SELECT last_synced_line, chunk_count FROM sync_files WHERE session_id = $1 AND file_name = $2`,
+			sessionID, "transcript.jsonl").Scan(&lastSyncedLine, &chunkCount); err != nil {
+			t.Fatalf("failed to query sync_files: %v", err)
+		}
+		if lastSyncedLine != 2 || chunkCount != 1 {
+			t.Errorf("last_synced_line = %d, chunk_count = %d; want 2 and 1", lastSyncedLine, chunkCount)
+		}
+	})
+
+	t.Run("returns 429 while the file is locked", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "lock@example.com", "Lock User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "test-session-locked")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+		chunk := api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "transcript.jsonl",
+			FileType:  "transcript",
+			FirstLine: 1,
+			Lines:     []string{`{"type":"user","message":"Hello"}`},
+		}
+
+		// Hold the lock an in-flight upload would hold.
+		tx, err := env.DB.Conn().BeginTx(env.Ctx, nil)
+		if err != nil {
+			t.Fatalf("failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+		var acquired bool
+		if err := tx.QueryRowContext(env.Ctx,
+			`SELECT pg_try_advisory_xact_lock(hashtext($1 || $2))`,
+			sessionID, "transcript.jsonl").Scan(&acquired); err != nil || !acquired {
+			t.Fatalf("failed to take the lock: acquired=%v err=%v", acquired, err)
+		}
+
+		resp, err := client.Post("/api/v1/sync/chunk", chunk)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusTooManyRequests)
+		if got := resp.Header.Get("Retry-After"); got != "1" {
+			t.Errorf("Retry-After = %q, want 1", got)
+		}
+		var body httputil.ErrorResponse
+		testutil.ParseJSON(t, resp, &body)
+		if body.Code != httputil.CodeChunkInProgress {
+			t.Errorf("code = %q, want %q", body.Code, httputil.CodeChunkInProgress)
+		}
+
+		// Another file of the same session is not blocked.
+		agent := chunk
+		agent.FileName = "agent-abc123.jsonl"
+		agent.FileType = "agent"
+		resp2, err := client.Post("/api/v1/sync/chunk", agent)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp2.Body.Close()
+		testutil.RequireStatus(t, resp2, http.StatusOK)
+
+		// Releasing the lock lets the retry through.
+		if err := tx.Rollback(); err != nil {
+			t.Fatalf("failed to release the lock: %v", err)
+		}
+		resp3, err := client.Post("/api/v1/sync/chunk", chunk)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp3.Body.Close()
+		testutil.RequireStatus(t, resp3, http.StatusOK)
+	})
+}
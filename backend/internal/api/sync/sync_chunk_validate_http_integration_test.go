@@ -0,0 +1,196 @@
+package sync_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// POST /api/v1/sync/chunk/validate - Dry run of sync/chunk
+// =============================================================================
+
+func TestSyncChunkValidate_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+
+	// post sends body to path and returns the status and the raw JSON body.
+	post := func(t *testing.T, client *testutil.TestClient, path string, body api.SyncChunkRequest) (int, []byte) {
+		t.Helper()
+		resp, err := client.Post(path, body)
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("read %s response: %v", path, err)
+		}
+		return resp.StatusCode, data
+	}
+
+	countState := func(t *testing.T, sessionID string) (syncFiles, uploadEvents int) {
+		t.Helper()
+		if err := env.DB.QueryRow(env.Ctx, `SELECT COUNT(*) FROM sync_files WHERE session_id = $1`, sessionID).Scan(&syncFiles); err != nil {
+			t.Fatalf("count sync_files: %v", err)
+		}
+		if err := env.DB.QueryRow(env.Ctx, `SELECT COUNT(*) FROM chunk_upload_events WHERE session_id = $1`, sessionID).Scan(&uploadEvents); err != nil {
+			t.Fatalf("count chunk_upload_events: %v", err)
+		}
+		return syncFiles, uploadEvents
+	}
+
+	t.Run("reports what a valid chunk would change and stores nothing", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "validate@example.com", "User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "K")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "validate-ext")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		chunk := api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "transcript.jsonl",
+			FileType:  "transcript",
+			FirstLine: 1,
+			Lines:     []string{`{"type":"user","message":"hi"}`, `{"type":"assistant","message":"hello"}`},
+		}
+
+		status, body := post(t, client, "/api/v1/sync/chunk/validate", chunk)
+		if status != http.StatusOK {
+			t.Fatalf("validate status = %d: %s", status, body)
+		}
+		var dryRun api.SyncChunkValidateResponse
+		if err := json.Unmarshal(body, &dryRun); err != nil {
+			t.Fatalf("decode validate response: %v", err)
+		}
+		wantBytes := len(chunk.Lines[0]) + len(chunk.Lines[1]) + 2
+		if !dryRun.Valid || dryRun.LastSyncedLine != 2 || dryRun.ChunkBytes != wantBytes || dryRun.SessionType != "claude-code" || dryRun.Reclassified {
+			t.Errorf("validate = %+v, want valid, last_synced_line 2, %d bytes, claude-code", dryRun, wantBytes)
+		}
+
+		if syncFiles, uploadEvents := countState(t, sessionID); syncFiles != 0 || uploadEvents != 0 {
+			t.Errorf("dry run left sync_files=%d chunk_upload_events=%d, want 0", syncFiles, uploadEvents)
+		}
+
+		// The real upload agrees with the dry run.
+		status, body = post(t, client, "/api/v1/sync/chunk", chunk)
+		if status != http.StatusOK {
+			t.Fatalf("chunk status = %d: %s", status, body)
+		}
+		var stored api.SyncChunkResponse
+		if err := json.Unmarshal(body, &stored); err != nil {
+			t.Fatalf("decode chunk response: %v", err)
+		}
+		if stored.LastSyncedLine != dryRun.LastSyncedLine {
+			t.Errorf("sync/chunk last_synced_line = %d, dry run said %d", stored.LastSyncedLine, dryRun.LastSyncedLine)
+		}
+	})
+
+	t.Run("refuses the same chunks with the same codes as sync/chunk", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "validate@example.com", "User")
+		other := testutil.CreateTestUser(t, env, "other@example.com", "Other")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "K")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "validate-ext")
+		othersSession := testutil.CreateTestSession(t, env, other.ID, "other-ext")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		chunk := func(session string, firstLine int) api.SyncChunkRequest {
+			return api.SyncChunkRequest{
+				SessionID: session,
+				FileName:  "transcript.jsonl",
+				FileType:  "transcript",
+				FirstLine: firstLine,
+				Lines:     []string{`{"type":"user","message":"line"}`, `{"type":"assistant","message":"line"}`},
+			}
+		}
+		if status, body := post(t, client, "/api/v1/sync/chunk", chunk(sessionID, 1)); status != http.StatusOK {
+			t.Fatalf("seed chunk status = %d: %s", status, body)
+		}
+
+		tests := []struct {
+			name       string
+			body       api.SyncChunkRequest
+			wantStatus int
+		}{
+			{"gap", chunk(sessionID, 5), http.StatusBadRequest},
+			{"overlap", chunk(sessionID, 1), http.StatusBadRequest},
+			{"unknown session", chunk("00000000-0000-0000-0000-000000000000", 1), http.StatusNotFound},
+			{"another user's session", chunk(othersSession, 1), http.StatusForbidden},
+			{"codex_rollout on a claude-code session", func() api.SyncChunkRequest {
+				c := chunk(sessionID, 3)
+				c.Metadata = &api.SyncChunkMetadata{CodexRollout: &api.SyncCodexRolloutMetadata{ThreadUUID: "00000000-0000-0000-0000-000000000001"}}
+				return c
+			}(), http.StatusBadRequest},
+			{"valid continuation", chunk(sessionID, 3), http.StatusOK},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				validateStatus, validateBody := post(t, client, "/api/v1/sync/chunk/validate", tt.body)
+				chunkStatus, chunkBody := post(t, client, "/api/v1/sync/chunk", tt.body)
+
+				var validateErr, chunkErr httputil.ErrorResponse
+				_ = json.Unmarshal(validateBody, &validateErr)
+				_ = json.Unmarshal(chunkBody, &chunkErr)
+
+				if chunkStatus != tt.wantStatus {
+					t.Errorf("sync/chunk status = %d, want %d: %s", chunkStatus, tt.wantStatus, chunkBody)
+				}
+				if validateStatus != chunkStatus || validateErr.Code != chunkErr.Code {
+					t.Errorf("validate = %d %q, sync/chunk = %d %q", validateStatus, validateErr.Code, chunkStatus, chunkErr.Code)
+				}
+			})
+		}
+	})
+
+	t.Run("reports a reclassification without applying it", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "detect@example.com", "User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "K")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "detect-ext") // default session_type
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		status, body := post(t, client, "/api/v1/sync/chunk/validate", api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "transcript.jsonl",
+			FileType:  "transcript",
+			FirstLine: 1,
+			Lines:     []string{`{"role":"user","message":{"content":[{"type":"text","text":"hi"}]}}`},
+		})
+		if status != http.StatusOK {
+			t.Fatalf("validate status = %d: %s", status, body)
+		}
+		var dryRun api.SyncChunkValidateResponse
+		if err := json.Unmarshal(body, &dryRun); err != nil {
+			t.Fatalf("decode validate response: %v", err)
+		}
+		if dryRun.SessionType != "cursor" || !dryRun.Reclassified {
+			t.Errorf("validate = %+v, want session_type cursor, reclassified", dryRun)
+		}
+
+		var sessionType string
+		if err := env.DB.QueryRow(env.Ctx, "SELECT session_type FROM sessions WHERE id = $1", sessionID).Scan(&sessionType); err != nil {
+			t.Fatalf("query session_type: %v", err)
+		}
+		if sessionType != "claude-code" {
+			t.Errorf("session_type = %q after a dry run, want claude-code", sessionType)
+		}
+	})
+}
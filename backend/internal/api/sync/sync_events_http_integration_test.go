@@ -0,0 +1,96 @@
+package sync_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestSyncEvents_HTTP_Integration opens the dashboard's SSE stream and checks
+// that a chunk uploaded through the CLI endpoint arrives as sync_progress.
+func TestSyncEvents_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	owner := testutil.CreateTestUser(t, env, "events@example.com", "Events")
+	apiKey := testutil.CreateTestAPIKeyWithToken(t, env, owner.ID, "Test Key")
+	sessionID := testutil.CreateTestSession(t, env, owner.ID, "events-session")
+	stranger := testutil.CreateTestUser(t, env, "stranger@example.com", "Stranger")
+
+	ts := setupTestServerWithEnv(t, env)
+	cli := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+	eventsPath := "/api/v1/sessions/" + sessionID + "/sync/events"
+
+	t.Run("owner receives sync_progress for an uploaded chunk", func(t *testing.T) {
+		web := testutil.NewTestClient(t, ts).WithSession(testutil.CreateTestWebSessionWithToken(t, env, owner.ID))
+		stream, err := web.Get(eventsPath)
+		if err != nil {
+			t.Fatalf("open stream: %v", err)
+		}
+		defer stream.Body.Close()
+		testutil.RequireStatus(t, stream, http.StatusOK)
+		if got := stream.Header.Get("Content-Type"); got != "text/event-stream" {
+			t.Fatalf("Content-Type = %q, want text/event-stream", got)
+		}
+
+		resp, err := cli.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "transcript.jsonl",
+			FileType:  "transcript",
+			FirstLine: 1,
+			Lines:     []string{`{"type":"user","message":"one"}`, `{"type":"user","message":"two"}`},
+		})
+		if err != nil {
+			t.Fatalf("upload chunk: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		// The response headers were flushed before the upload, so the
+		// subscription is already registered.
+		scanner := bufio.NewScanner(stream.Body)
+		var event, data string
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "event: ") {
+				event = line[len("event: "):]
+			} else if strings.HasPrefix(line, "data: ") {
+				data = line[len("data: "):]
+			} else if line == "" && event != "" {
+				break
+			}
+		}
+		if event != "sync_progress" {
+			t.Fatalf("event = %q, want sync_progress (scan err: %v)", event, scanner.Err())
+		}
+		var got api.SyncProgress
+		if err := json.Unmarshal([]byte(data), &got); err != nil {
+			t.Fatalf("decode %q: %v", data, err)
+		}
+		want := api.SyncProgress{FileName: "transcript.jsonl", LastSyncedLine: 2, ChunkCount: 1}
+		if got != want {
+			t.Errorf("progress = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("non-owner gets 404", func(t *testing.T) {
+		web := testutil.NewTestClient(t, ts).WithSession(testutil.CreateTestWebSessionWithToken(t, env, stranger.ID))
+		resp, err := web.Get(eventsPath)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,123 @@
+package sync_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestSyncChunk_FilePolicy_HTTP_Integration checks the sync/chunk file
+// allowlist: warn-only by default, and a structured 400 listing the allowed
+// values once SYNC_FILE_POLICY_STRICT is on.
+func TestSyncChunk_FilePolicy_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+	env := testutil.SetupTestEnvironment(t)
+
+	t.Run("warn-only mode accepts files outside the allowlist", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "filepolicy-warn@example.com", "Warn")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "filepolicy-warn")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		resp, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "helper.jsonl",
+			FileType:  "agent",
+			FirstLine: 1,
+			Lines:     []string{`{"type":"user","message":"Hello"}`},
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+	})
+
+	t.Run("strict mode", func(t *testing.T) {
+		t.Setenv("SYNC_FILE_POLICY_STRICT", "true")
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "filepolicy-strict@example.com", "Strict")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		claudeID := testutil.CreateTestSession(t, env, user.ID, "filepolicy-claude")
+		codexID := testutil.CreateTestSessionWithProvider(t, env, user.ID, "filepolicy-codex", models.ProviderCodex)
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		post := func(sessionID, fileType, fileName string) *http.Response {
+			t.Helper()
+			resp, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+				SessionID: sessionID,
+				FileName:  fileName,
+				FileType:  fileType,
+				FirstLine: 1,
+				Lines:     []string{`{"type":"user","message":"Hello"}`},
+			})
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			return resp
+		}
+
+		resp := post(claudeID, "screenshot", "shot.jsonl")
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+		var body api.SyncFileRejectedResponse
+		testutil.ParseJSON(t, resp, &body)
+		if body.Code != httputil.CodeUnsupportedFileType || body.SessionType != models.ProviderClaudeCode {
+			t.Errorf("body = %+v, want unsupported_file_type for claude-code", body)
+		}
+		if len(body.AllowedFileTypes) != 3 {
+			t.Errorf("allowed_file_types = %v, want transcript, agent, workflow_journal", body.AllowedFileTypes)
+		}
+
+		resp = post(claudeID, "agent", "helper.jsonl")
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+		body = api.SyncFileRejectedResponse{}
+		testutil.ParseJSON(t, resp, &body)
+		if body.Code != httputil.CodeInvalidFileName || len(body.AllowedFileNames) == 0 {
+			t.Errorf("body = %+v, want invalid_file_name with allowed_file_names", body)
+		}
+
+		resp = post(codexID, "workflow_journal", "subagents/workflows/r/journal.jsonl")
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+		body = api.SyncFileRejectedResponse{}
+		testutil.ParseJSON(t, resp, &body)
+		if body.Code != httputil.CodeUnsupportedFileType || body.SessionType != models.ProviderCodex {
+			t.Errorf("body = %+v, want unsupported_file_type for codex", body)
+		}
+
+		var syncFiles int
+		if err := env.DB.QueryRow(env.Ctx, `SELECT COUNT(*) FROM sync_files`).Scan(&syncFiles); err != nil {
+			t.Fatalf("count sync_files: %v", err)
+		}
+		if syncFiles != 0 {
+			t.Errorf("rejected chunks left %d sync_files rows, want 0", syncFiles)
+		}
+
+		for _, ok := range []struct{ sessionID, fileType, fileName string }{
+			{claudeID, "transcript", "transcript.jsonl"},
+			{claudeID, "agent", "agent-a1.jsonl"},
+			{claudeID, "agent", "subagents/workflows/run-1/agent-a2.jsonl"},
+			{claudeID, "workflow_journal", "subagents/workflows/run-1/journal.jsonl"},
+			{codexID, "agent", "rollout-019e2ce8-subagent.jsonl"},
+		} {
+			resp := post(ok.sessionID, ok.fileType, ok.fileName)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("%s %q: status %d, want 200", ok.fileType, ok.fileName, resp.StatusCode)
+			}
+		}
+	})
+}
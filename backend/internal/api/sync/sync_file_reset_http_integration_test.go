@@ -0,0 +1,153 @@
+package sync_test
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestSyncFileReset_HTTP_Integration covers the rewrite flow for a file the
+// client compacted below what was already synced: reset, re-upload from line
+// 1, and read both the live and the archived generation.
+func TestSyncFileReset_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+
+	postChunk := func(t *testing.T, client *testutil.TestClient, sessionID string, firstLine int, lines []string) *http.Response {
+		t.Helper()
+		resp, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "transcript.jsonl",
+			FileType:  "transcript",
+			FirstLine: firstLine,
+			Lines:     lines,
+		})
+		if err != nil {
+			t.Fatalf("chunk request failed: %v", err)
+		}
+		return resp
+	}
+
+	readFile := func(t *testing.T, client *testutil.TestClient, sessionID, query string) string {
+		t.Helper()
+		resp, err := client.Get("/api/v1/sessions/" + sessionID + "/sync/file?file_name=transcript.jsonl" + query)
+		if err != nil {
+			t.Fatalf("read request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	t.Run("archives the old generation and restarts at line 1", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "reset@example.com", "Reset User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Key")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "reset-session")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		resp := postChunk(t, client, sessionID, 1, []string{`{"n":1}`, `{"n":2}`, `{"n":3}`})
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		testutil.CreateTestSearchIndex(t, env, sessionID, "old words", 3)
+
+		resp, err := client.Post("/api/v1/sync/file/reset", api.SyncFileResetRequest{
+			SessionID:      sessionID,
+			FileName:       "transcript.jsonl",
+			Reason:         "compaction",
+			LocalLineCount: 1,
+		})
+		if err != nil {
+			t.Fatalf("reset request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var result api.SyncFileResetResponse
+		testutil.ParseJSON(t, resp, &result)
+		if result.Generation != 1 || result.LastSyncedLine != 0 || result.ArchivedGeneration != 0 || result.ArchivedLines != 3 {
+			t.Errorf("reset response = %+v, want generation 1 at line 0 archiving 3 lines of generation 0", result)
+		}
+
+		var indexed int
+		row := env.DB.QueryRow(env.Ctx, "SELECT COUNT(*) FROM session_search_index WHERE session_id = $1", sessionID)
+		if err := row.Scan(&indexed); err != nil {
+			t.Fatalf("query search index: %v", err)
+		}
+		if indexed != 0 {
+			t.Error("reset should drop the session's search index")
+		}
+
+		// The compacted file uploads from line 1 again.
+		resp = postChunk(t, client, sessionID, 1, []string{`{"n":"compacted"}`})
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		if got := readFile(t, client, sessionID, ""); got != "{\"n\":\"compacted\"}\n" {
+			t.Errorf("live read = %q, want only the new generation", got)
+		}
+		if got := readFile(t, client, sessionID, "&generation=1"); got != "{\"n\":\"compacted\"}\n" {
+			t.Errorf("generation=1 read = %q, want the live generation", got)
+		}
+		if got := readFile(t, client, sessionID, "&generation=0"); got != "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n" {
+			t.Errorf("generation=0 read = %q, want the archived lines", got)
+		}
+
+		resp, err = client.Get("/api/v1/sessions/" + sessionID + "/sync/file?file_name=transcript.jsonl&generation=5")
+		if err != nil {
+			t.Fatalf("read request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusNotFound)
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "reset-invalid@example.com", "Reset User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Key")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "reset-invalid")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		cases := []struct {
+			name   string
+			req    api.SyncFileResetRequest
+			status int
+			code   httputil.ErrorCode
+		}{
+			{"unknown reason", api.SyncFileResetRequest{SessionID: sessionID, FileName: "transcript.jsonl", Reason: "truncate"}, http.StatusBadRequest, httputil.CodeValidationFailed},
+			{"negative line count", api.SyncFileResetRequest{SessionID: sessionID, FileName: "transcript.jsonl", Reason: "manual", LocalLineCount: -1}, http.StatusBadRequest, httputil.CodeValidationFailed},
+			{"file never synced", api.SyncFileResetRequest{SessionID: sessionID, FileName: "transcript.jsonl", Reason: "manual"}, http.StatusNotFound, httputil.CodeFileNotFound},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				resp, err := client.Post("/api/v1/sync/file/reset", tc.req)
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				defer resp.Body.Close()
+				testutil.RequireStatus(t, resp, tc.status)
+				var body httputil.ErrorResponse
+				testutil.ParseJSON(t, resp, &body)
+				if body.Code != tc.code {
+					t.Errorf("code = %q, want %q", body.Code, tc.code)
+				}
+			})
+		}
+	})
+}
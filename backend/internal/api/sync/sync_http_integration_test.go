@@ -9,9 +9,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
 	"github.com/ConfabulousDev/confab-web/internal/api"
 	"github.com/ConfabulousDev/confab-web/internal/api/apitest"
 	dbgithub "github.com/ConfabulousDev/confab-web/internal/db/github"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
 	"github.com/ConfabulousDev/confab-web/internal/models"
 	"github.com/ConfabulousDev/confab-web/internal/storage"
 	"github.com/ConfabulousDev/confab-web/internal/testutil"
@@ -462,6 +464,63 @@ func TestSyncChunk_HTTP_Integration(t *testing.T) {
 		if !strings.Contains(result["error"], "first_line must be 2") {
 			t.Errorf("expected error about first_line must be 2, got: %s", result["error"])
 		}
+		if result["code"] != string(httputil.CodeChunkOverlap) {
+			t.Errorf("code = %q, want %q", result["code"], httputil.CodeChunkOverlap)
+		}
+	})
+
+	t.Run("rejects chunk overlapping the middle of a multi-line chunk", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "test-session-overlap-multi")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		// Lines 1-3 in one chunk
+		resp1, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "transcript.jsonl",
+			FileType:  "transcript",
+			FirstLine: 1,
+			Lines: []string{
+				`{"type":"user","message":"one"}`,
+				`{"type":"user","message":"two"}`,
+				`{"type":"user","message":"three"}`,
+			},
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp1.Body.Close()
+		testutil.RequireStatus(t, resp1, http.StatusOK)
+
+		// Starting at line 2 overlaps lines 2-3; the next line is 4, not 2
+		resp2, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "transcript.jsonl",
+			FileType:  "transcript",
+			FirstLine: 2,
+			Lines:     []string{`{"type":"user","message":"two again"}`},
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp2.Body.Close()
+
+		testutil.RequireStatus(t, resp2, http.StatusBadRequest)
+
+		var result map[string]string
+		testutil.ParseJSON(t, resp2, &result)
+
+		if !strings.Contains(result["error"], "first_line must be 4 (got 2)") {
+			t.Errorf("expected error about first_line must be 4, got: %s", result["error"])
+		}
+		if result["code"] != string(httputil.CodeChunkOverlap) {
+			t.Errorf("code = %q, want %q", result["code"], httputil.CodeChunkOverlap)
+		}
 	})
 
 	t.Run("updates git_info from chunk metadata", func(t *testing.T) {
@@ -987,6 +1046,154 @@ func TestSyncInit_MetadataNesting_HTTP_Integration(t *testing.T) {
 			t.Errorf("expected error about cwd, got: %s", result["error"])
 		}
 	})
+
+	t.Run("size limits are exact and identical for both formats", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		cwdOf := func(n int) string { return "/" + strings.Repeat("a", n-1) }
+		// gitInfoOf builds a git_info object of exactly n bytes of JSON.
+		gitInfoOf := func(n int) json.RawMessage {
+			return json.RawMessage(`{"branch":"` + strings.Repeat("b", n-len(`{"branch":""}`)) + `"}`)
+		}
+		// Both request formats carry the same cwd and git_info.
+		formats := map[string]func(cwd string, gitInfo json.RawMessage, extra map[string]interface{}) map[string]interface{}{
+			"nested": func(cwd string, gitInfo json.RawMessage, extra map[string]interface{}) map[string]interface{} {
+				metadata := map[string]interface{}{"cwd": cwd, "git_info": gitInfo}
+				for k, v := range extra {
+					metadata[k] = v
+				}
+				return map[string]interface{}{"metadata": metadata}
+			},
+			"top-level": func(cwd string, gitInfo json.RawMessage, extra map[string]interface{}) map[string]interface{} {
+				return map[string]interface{}{"cwd": cwd, "git_info": gitInfo, "metadata": extra}
+			},
+		}
+		small := gitInfoOf(64)
+		tests := []struct {
+			name      string
+			cwd       string
+			gitInfo   json.RawMessage
+			extra     map[string]interface{}
+			wantField string // "" expects 200
+			wantLimit int
+		}{
+			{"cwd at limit", cwdOf(validation.MaxCWDLength), small, nil, "", 0},
+			{"cwd over limit", cwdOf(validation.MaxCWDLength + 1), small, nil, "cwd", validation.MaxCWDLength},
+			{"git_info at limit", "/p", gitInfoOf(validation.MaxGitInfoBytes), nil, "", 0},
+			{"git_info over limit", "/p", gitInfoOf(validation.MaxGitInfoBytes + 1), nil, "git_info", validation.MaxGitInfoBytes},
+			{
+				"total at limit",
+				cwdOf(validation.MaxCWDLength), gitInfoOf(validation.MaxSyncMetadataBytes - validation.MaxCWDLength), nil,
+				"", 0,
+			},
+			{
+				"total over limit",
+				cwdOf(validation.MaxCWDLength), gitInfoOf(validation.MaxSyncMetadataBytes - validation.MaxCWDLength),
+				map[string]interface{}{"hostname": "h"},
+				"metadata", validation.MaxSyncMetadataBytes,
+			},
+		}
+		for format, build := range formats {
+			for i, tt := range tests {
+				t.Run(format+"/"+tt.name, func(t *testing.T) {
+					reqBody := build(tt.cwd, tt.gitInfo, tt.extra)
+					reqBody["external_id"] = fmt.Sprintf("limits-%s-%d", format, i)
+					reqBody["transcript_path"] = "/home/user/project/transcript.jsonl"
+
+					resp, err := client.Post("/api/v1/sync/init", reqBody)
+					if err != nil {
+						t.Fatalf("request failed: %v", err)
+					}
+					defer resp.Body.Close()
+
+					if tt.wantField == "" {
+						testutil.RequireStatus(t, resp, http.StatusOK)
+						var result api.SyncInitResponse
+						testutil.ParseJSON(t, resp, &result)
+						if result.Limits.Metadata != validation.CurrentSyncMetadataLimits() {
+							t.Errorf("limits.metadata = %+v, want %+v", result.Limits.Metadata, validation.CurrentSyncMetadataLimits())
+						}
+						return
+					}
+					testutil.RequireStatus(t, resp, http.StatusBadRequest)
+					var result httputil.FieldLimitResponse
+					testutil.ParseJSON(t, resp, &result)
+					if result.Code != httputil.CodeValidationFailed || result.Field != tt.wantField || result.Limit != tt.wantLimit {
+						t.Errorf("error = %+v, want %s on %s (limit %d)",
+							result, httputil.CodeValidationFailed, tt.wantField, tt.wantLimit)
+					}
+				})
+			}
+		}
+	})
+
+	t.Run("chunk metadata limits are exact", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "chunk-limits-session")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		str := func(n int) *string {
+			s := strings.Repeat("a", n)
+			return &s
+		}
+		nextLine := 1
+		tests := []struct {
+			name      string
+			metadata  api.SyncChunkMetadata
+			wantField string // "" expects 200
+			wantLimit int
+		}{
+			{"summary at limit", api.SyncChunkMetadata{Summary: str(validation.MaxSummaryLength)}, "", 0},
+			{"summary over limit", api.SyncChunkMetadata{Summary: str(validation.MaxSummaryLength + 1)}, "summary", validation.MaxSummaryLength},
+			{"first_user_message at limit", api.SyncChunkMetadata{FirstUserMessage: str(validation.MaxFirstUserMessageLength)}, "", 0},
+			{
+				"first_user_message over limit",
+				api.SyncChunkMetadata{FirstUserMessage: str(validation.MaxFirstUserMessageLength + 1)},
+				"first_user_message", validation.MaxFirstUserMessageLength,
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				metadata := tt.metadata
+				resp, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+					SessionID: sessionID,
+					FileName:  "transcript.jsonl",
+					FileType:  "transcript",
+					FirstLine: nextLine,
+					Lines:     []string{`{"type":"summary","summary":"s"}`},
+					Metadata:  &metadata,
+				})
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				defer resp.Body.Close()
+
+				if tt.wantField == "" {
+					testutil.RequireStatus(t, resp, http.StatusOK)
+					nextLine++
+					return
+				}
+				testutil.RequireStatus(t, resp, http.StatusBadRequest)
+				var result httputil.FieldLimitResponse
+				testutil.ParseJSON(t, resp, &result)
+				if result.Code != httputil.CodeValidationFailed || result.Field != tt.wantField || result.Limit != tt.wantLimit {
+					t.Errorf("error = %+v, want %s on %s (limit %d)",
+						result, httputil.CodeValidationFailed, tt.wantField, tt.wantLimit)
+				}
+			})
+		}
+	})
 }
 
 // =============================================================================
@@ -1097,6 +1304,9 @@ func TestSyncChunk_EdgeCases_HTTP_Integration(t *testing.T) {
 		if !strings.Contains(result["error"], "first_line must be 3") {
 			t.Errorf("expected error about first_line must be 3, got: %s", result["error"])
 		}
+		if result["code"] != string(httputil.CodeChunkGap) {
+			t.Errorf("code = %q, want %q", result["code"], httputil.CodeChunkGap)
+		}
 	})
 
 	t.Run("returns 400 for invalid first_line (must be >= 1)", func(t *testing.T) {
@@ -1990,6 +2200,69 @@ func TestSyncChunk_FirstUserMessage_HTTP_Integration(t *testing.T) {
 			t.Errorf("expected first_user_message 'Preserved Message' to be preserved, got %v", firstUserMessage)
 		}
 	})
+
+	t.Run("human prompt parsed from the transcript replaces the client value", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "first-msg-derived-test")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		postChunk := func(firstLine int, lines []string, clientValue string) {
+			t.Helper()
+			resp, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+				SessionID: sessionID,
+				FileName:  "transcript.jsonl",
+				FileType:  "transcript",
+				FirstLine: firstLine,
+				Lines:     lines,
+				Metadata:  &api.SyncChunkMetadata{FirstUserMessage: &clientValue},
+			})
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			testutil.RequireStatus(t, resp, http.StatusOK)
+		}
+		stored := func() string {
+			t.Helper()
+			var msg *string
+			if err := env.DB.QueryRow(env.Ctx, "SELECT first_user_message FROM sessions WHERE id = $1", sessionID).Scan(&msg); err != nil {
+				t.Fatalf("failed to query session first_user_message: %v", err)
+			}
+			if msg == nil {
+				return ""
+			}
+			return *msg
+		}
+
+		// Only a tool result and a slash command: nothing to parse, the
+		// client's value is the fallback.
+		postChunk(1, []string{
+			`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ls output"}]}}`,
+			`{"type":"user","message":{"role":"user","content":"<command-name>/clear</command-name><command-message>clear</command-message><command-args></command-args>"}}`,
+		}, "ls output")
+		if got := stored(); got != "ls output" {
+			t.Errorf("after tool-result chunk: first_user_message = %q, want the client fallback", got)
+		}
+
+		postChunk(3, []string{
+			`{"type":"user","message":{"role":"user","content":"Fix the flaky login test"}}`,
+		}, "ls output")
+		if got := stored(); got != "Fix the flaky login test" {
+			t.Errorf("after prompt chunk: first_user_message = %q, want the parsed prompt", got)
+		}
+
+		postChunk(4, []string{
+			`{"type":"user","message":{"role":"user","content":"Now update the docs"}}`,
+		}, "something else")
+		if got := stored(); got != "Fix the flaky login test" {
+			t.Errorf("after later prompt: first_user_message = %q, want the first parsed prompt", got)
+		}
+	})
 }
 
 // =============================================================================
@@ -2155,6 +2428,9 @@ func TestSyncChunk_ChunkCountTracking_HTTP_Integration(t *testing.T) {
 		if !strings.Contains(result["error"], "too many chunks") {
 			t.Errorf("expected error about too many chunks, got: %s", result["error"])
 		}
+		if result["code"] != string(httputil.CodeChunkLimitExceeded) {
+			t.Errorf("code = %q, want %q", result["code"], httputil.CodeChunkLimitExceeded)
+		}
 	})
 }
 
@@ -2483,6 +2759,145 @@ func TestSyncFileRead_LineOffset_HTTP_Integration(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("with_line_numbers numbers lines from line_offset+1 across chunk boundaries", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "offset-test-numbered")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		chunks := []api.SyncChunkRequest{
+			{SessionID: sessionID, FileName: "transcript.jsonl", FileType: "transcript", FirstLine: 1, Lines: []string{`{"line":1}`, `{"line":2}`, `{"line":3}`}},
+			{SessionID: sessionID, FileName: "transcript.jsonl", FileType: "transcript", FirstLine: 4, Lines: []string{`{"line":4}`, `{"line":5}`, `{"line":6}`}},
+		}
+		for _, chunk := range chunks {
+			resp, _ := client.Post("/api/v1/sync/chunk", chunk)
+			resp.Body.Close()
+		}
+
+		// Request lines after line 2 (should return lines 3-6, numbered 3-6)
+		resp, err := client.Get("/api/v1/sessions/" + sessionID + "/sync/file?file_name=transcript.jsonl&line_offset=2&with_line_numbers=true")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		body := make([]byte, 4096)
+		n, _ := resp.Body.Read(body)
+		lines := strings.Split(strings.TrimSpace(string(body[:n])), "\n")
+		expected := []string{"3\t{\"line\":3}", "4\t{\"line\":4}", "5\t{\"line\":5}", "6\t{\"line\":6}"}
+		if len(lines) != len(expected) {
+			t.Fatalf("expected %d lines, got %d: %v", len(expected), len(lines), lines)
+		}
+		for i, line := range lines {
+			if line != expected[i] {
+				t.Errorf("line %d: expected %q, got %q", i, expected[i], line)
+			}
+		}
+	})
+
+	t.Run("returns 400 for invalid with_line_numbers", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "offset-test-numbered-invalid")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		resp, err := client.Get("/api/v1/sessions/" + sessionID + "/sync/file?file_name=transcript.jsonl&with_line_numbers=maybe")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
+
+	t.Run("format=json returns numbered lines with offset, and an empty array past the end", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "offset-test-json")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		chunks := []api.SyncChunkRequest{
+			{SessionID: sessionID, FileName: "transcript.jsonl", FileType: "transcript", FirstLine: 1, Lines: []string{`{"line":1}`, `{"line":2}`, `{"line":3}`}},
+			{SessionID: sessionID, FileName: "transcript.jsonl", FileType: "transcript", FirstLine: 4, Lines: []string{`{"line":4}`, `{"line":5}`}},
+		}
+		for _, chunk := range chunks {
+			resp, _ := client.Post("/api/v1/sync/chunk", chunk)
+			resp.Body.Close()
+		}
+
+		type jsonLines struct {
+			Lines []struct {
+				N    int    `json:"n"`
+				Text string `json:"text"`
+			} `json:"lines"`
+			LastLine   int `json:"last_line"`
+			TotalLines int `json:"total_lines"`
+		}
+		get := func(query string) jsonLines {
+			t.Helper()
+			resp, err := client.Get("/api/v1/sessions/" + sessionID + "/sync/file?file_name=transcript.jsonl&format=json" + query)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			testutil.RequireStatus(t, resp, http.StatusOK)
+			if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			var body jsonLines
+			testutil.ParseJSON(t, resp, &body)
+			return body
+		}
+
+		body := get("&line_offset=2")
+		if len(body.Lines) != 3 || body.Lines[0].N != 3 || body.Lines[0].Text != `{"line":3}` || body.Lines[2].N != 5 {
+			t.Errorf("lines = %+v, want lines 3-5", body.Lines)
+		}
+		if body.LastLine != 5 || body.TotalLines != 5 {
+			t.Errorf("last_line = %d, total_lines = %d, want 5, 5", body.LastLine, body.TotalLines)
+		}
+
+		empty := get("&line_offset=5")
+		if empty.Lines == nil || len(empty.Lines) != 0 {
+			t.Errorf("lines = %#v, want empty array", empty.Lines)
+		}
+		if empty.LastLine != 5 || empty.TotalLines != 5 {
+			t.Errorf("empty: last_line = %d, total_lines = %d, want 5, 5", empty.LastLine, empty.TotalLines)
+		}
+	})
+
+	t.Run("returns 400 for unknown format", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "offset-test-format-invalid")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		resp, err := client.Get("/api/v1/sessions/" + sessionID + "/sync/file?file_name=transcript.jsonl&format=xml")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
 }
 
 // =============================================================================
@@ -3698,6 +4113,126 @@ func TestSyncInit_Provider_HTTP_Integration(t *testing.T) {
 	})
 }
 
+// TestSyncInit_SessionType_HTTP_Integration covers the explicit
+// `session_type` field: a valid type is stored verbatim and is what the
+// precompute stale filter gates on; unknown types are rejected up front.
+func TestSyncInit_SessionType_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+	env := testutil.SetupTestEnvironment(t)
+
+	t.Run("explicit session_type is stored and drives stale eligibility", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "session-type@example.com", "User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "K")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		resp, err := client.Post("/api/v1/sync/init", api.SyncInitRequest{
+			ExternalID:     "session-type-ext",
+			TranscriptPath: "/p/rollout.jsonl",
+			SessionType:    strPtr(models.ProviderCodex),
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		var result api.SyncInitResponse
+		testutil.ParseJSON(t, resp, &result)
+
+		var stored string
+		if err := env.DB.QueryRow(env.Ctx,
+			"SELECT session_type FROM sessions WHERE id = $1", result.SessionID).Scan(&stored); err != nil {
+			t.Fatalf("query session_type: %v", err)
+		}
+		if stored != models.ProviderCodex {
+			t.Fatalf("DB session_type = %q, want %q", stored, models.ProviderCodex)
+		}
+
+		testutil.CreateTestSyncFile(t, env, result.SessionID, "rollout.jsonl", "transcript", 100)
+
+		analyticsStore := analytics.NewStore(env.DB.Conn())
+		precomputer := analytics.NewPrecomputer(env.DB.Conn(), env.Storage, analyticsStore, analytics.PrecomputeConfig{
+			RegularCardsThresholds: analytics.DefaultRegularCardsThresholds(),
+			SmartRecapThresholds:   analytics.DefaultSmartRecapThresholds(),
+		})
+		stale, err := precomputer.FindStaleSessions(env.Ctx, 100)
+		if err != nil {
+			t.Fatalf("FindStaleSessions failed: %v", err)
+		}
+		var found bool
+		for _, s := range stale {
+			if s.SessionID == result.SessionID {
+				found = true
+				if s.Provider != models.ProviderCodex {
+					t.Errorf("stale session Provider = %q, want %q", s.Provider, models.ProviderCodex)
+				}
+			}
+		}
+		if !found {
+			t.Error("expected explicitly typed session to be stale-eligible")
+		}
+	})
+
+	t.Run("rejects unknown session_type", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "unknown-type@example.com", "User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "K")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		resp, err := client.Post("/api/v1/sync/init", api.SyncInitRequest{
+			ExternalID:     "unknown-type-ext",
+			TranscriptPath: "/p/transcript.jsonl",
+			SessionType:    strPtr("other-tool"),
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+
+		var count int
+		if err := env.DB.QueryRow(env.Ctx, "SELECT COUNT(*) FROM sessions WHERE user_id = $1", user.ID).Scan(&count); err != nil {
+			t.Fatalf("count sessions: %v", err)
+		}
+		if count != 0 {
+			t.Errorf("expected no session for rejected type, got %d", count)
+		}
+	})
+
+	t.Run("rejects session_type conflicting with provider", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "conflict-type@example.com", "User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "K")
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		resp, err := client.Post("/api/v1/sync/init", api.SyncInitRequest{
+			ExternalID:     "conflict-type-ext",
+			TranscriptPath: "/p/transcript.jsonl",
+			SessionType:    strPtr(models.ProviderCodex),
+			Provider:       strPtr(models.ProviderCursor),
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+	})
+}
+
 // TestSyncChunk_Provider_HTTP_Integration covers the chunk endpoint's
 // provider-awareness: codex sessions accept transcript chunks, anything else
 // is rejected, and the chunk handler must not attempt Claude-Code parsing.
@@ -3918,6 +4453,165 @@ func TestSyncChunk_Provider_HTTP_Integration(t *testing.T) {
 			t.Error("last_sync_at is NULL after chunk upload")
 		}
 	})
+
+	t.Run("reclassifies a defaulted claude-code session from its first transcript chunk", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "detect@example.com", "User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "K")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "detect-ext") // default session_type
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		resp, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "transcript.jsonl",
+			FileType:  "transcript",
+			FirstLine: 1,
+			Lines:     []string{`{"role":"user","message":{"content":[{"type":"text","text":"hi"}]}}`},
+		})
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		var sessionType string
+		if err := env.DB.QueryRow(env.Ctx, "SELECT session_type FROM sessions WHERE id = $1", sessionID).Scan(&sessionType); err != nil {
+			t.Fatalf("query session_type: %v", err)
+		}
+		if sessionType != "cursor" {
+			t.Errorf("session_type = %q, want cursor", sessionType)
+		}
+
+		// The chunk was stored under the detected provider's prefix, so it reads back.
+		readResp, err := client.Get("/api/v1/sessions/" + sessionID + "/sync/file?file_name=transcript.jsonl")
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		defer readResp.Body.Close()
+		testutil.RequireStatus(t, readResp, http.StatusOK)
+	})
+
+	t.Run("does not reclassify once a file has synced", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "detect-late@example.com", "User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "K")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "detect-late-ext")
+		testutil.CreateTestSyncFile(t, env, sessionID, "agent-1.jsonl", "agent", 1)
+
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		resp, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "transcript.jsonl",
+			FileType:  "transcript",
+			FirstLine: 1,
+			Lines:     []string{`{"timestamp":"2026-01-01T00:00:00Z","type":"session_meta","payload":{"id":"019e"}}`},
+		})
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+
+		var sessionType string
+		if err := env.DB.QueryRow(env.Ctx, "SELECT session_type FROM sessions WHERE id = $1", sessionID).Scan(&sessionType); err != nil {
+			t.Fatalf("query session_type: %v", err)
+		}
+		if sessionType != "claude-code" {
+			t.Errorf("session_type = %q, want claude-code (already has synced files)", sessionType)
+		}
+	})
+
+	cursorLine := `{"role":"user","message":{"content":[{"type":"text","text":"hi"}]}}`
+	initSession := func(t *testing.T, client *testutil.TestClient, sessionType *string) api.SyncInitResponse {
+		t.Helper()
+		resp, err := client.Post("/api/v1/sync/init", api.SyncInitRequest{
+			ExternalID:     "reinit-ext",
+			TranscriptPath: "/tmp/reinit.jsonl",
+			SessionType:    sessionType,
+		})
+		if err != nil {
+			t.Fatalf("init: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var out api.SyncInitResponse
+		testutil.ParseJSON(t, resp, &out)
+		return out
+	}
+	postFirstChunk := func(t *testing.T, client *testutil.TestClient, sessionID string) {
+		t.Helper()
+		resp, err := client.Post("/api/v1/sync/chunk", api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "transcript.jsonl",
+			FileType:  "transcript",
+			FirstLine: 1,
+			Lines:     []string{cursorLine},
+		})
+		if err != nil {
+			t.Fatalf("chunk: %v", err)
+		}
+		resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+	}
+
+	t.Run("re-init without a type resumes a reclassified session", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "reinit@example.com", "User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "K")
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		first := initSession(t, client, nil)
+		postFirstChunk(t, client, first.SessionID)
+
+		again := initSession(t, client, nil)
+		if again.SessionID != first.SessionID {
+			t.Errorf("re-init session_id = %s, want %s (duplicate session created)", again.SessionID, first.SessionID)
+		}
+		if again.Provider != "cursor" {
+			t.Errorf("re-init provider = %q, want the detected cursor", again.Provider)
+		}
+		if files := again.Files["transcript.jsonl"]; files.LastSyncedLine != 1 {
+			t.Errorf("re-init transcript last_synced_line = %d, want 1", files.LastSyncedLine)
+		}
+
+		var count int
+		if err := env.DB.QueryRow(env.Ctx,
+			"SELECT COUNT(*) FROM sessions WHERE user_id = $1 AND external_id = 'reinit-ext'", user.ID).Scan(&count); err != nil {
+			t.Fatalf("count sessions: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("sessions for external_id = %d, want 1", count)
+		}
+	})
+
+	t.Run("does not reclassify an explicit claude-code session", func(t *testing.T) {
+		env.CleanDB(t)
+
+		user := testutil.CreateTestUser(t, env, "explicit@example.com", "User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "K")
+		ts := setupTestServerWithEnv(t, env)
+		client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+		claudeCode := "claude-code"
+		first := initSession(t, client, &claudeCode)
+		postFirstChunk(t, client, first.SessionID)
+
+		var sessionType, source string
+		if err := env.DB.QueryRow(env.Ctx,
+			"SELECT session_type, session_type_source FROM sessions WHERE id = $1", first.SessionID).Scan(&sessionType, &source); err != nil {
+			t.Fatalf("query session_type: %v", err)
+		}
+		if sessionType != "claude-code" || source != "explicit" {
+			t.Errorf("session_type = %q (%s), want explicit claude-code", sessionType, source)
+		}
+	})
 }
 
 // =============================================================================
@@ -5390,3 +6084,117 @@ func TestSyncCursor_HTTP_Integration(t *testing.T) {
 		}
 	})
 }
+
+// =============================================================================
+// Body decoding limits (SYNC_JSON_*) on sync/init and sync/chunk
+// =============================================================================
+
+func TestSyncBodyLimits_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+
+	// deepGitInfo nests git_info depth levels below the metadata object.
+	deepGitInfo := func(depth int) string {
+		return strings.Repeat(`{"a":`, depth) + "1" + strings.Repeat("}", depth)
+	}
+
+	requireBodyError := func(t *testing.T, resp *http.Response, want string) {
+		t.Helper()
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusBadRequest)
+		var result map[string]string
+		testutil.ParseJSON(t, resp, &result)
+		if result["code"] != string(httputil.CodeInvalidRequestBody) {
+			t.Errorf("code = %q, want %q", result["code"], httputil.CodeInvalidRequestBody)
+		}
+		if !strings.Contains(result["error"], want) {
+			t.Errorf("error = %q, want it to contain %q", result["error"], want)
+		}
+	}
+
+	t.Run("init rejects a deeply nested git_info", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithAPIKey(apiKey.RawToken)
+
+		body := `{"external_id":"deep","transcript_path":"/t.jsonl","metadata":{"git_info":` + deepGitInfo(64) + `}}`
+		resp, err := client.Post("/api/v1/sync/init", body)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		requireBodyError(t, resp, "maximum nesting depth of 32")
+	})
+
+	t.Run("init accepts ordinary nesting and unknown fields by default", func(t *testing.T) {
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithAPIKey(apiKey.RawToken)
+
+		body := `{"external_id":"ok","transcript_path":"/t.jsonl","future_field":1,"metadata":{"git_info":` + deepGitInfo(5) + `}}`
+		resp, err := client.Post("/api/v1/sync/init", body)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+	})
+
+	t.Run("chunk rejects unknown fields when configured", func(t *testing.T) {
+		env.CleanDB(t)
+		testutil.SetEnvForTest(t, "SYNC_JSON_DISALLOW_UNKNOWN_FIELDS", "true")
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "test-session")
+		client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithAPIKey(apiKey.RawToken)
+
+		body := fmt.Sprintf(`{"session_id":%q,"file_name":"transcript.jsonl","file_type":"transcript","first_line":1,"lines":["{}"],"surprise":true}`, sessionID)
+		resp, err := client.Post("/api/v1/sync/chunk", body)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		requireBodyError(t, resp, `unknown field "surprise"`)
+
+		valid := api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "transcript.jsonl",
+			FileType:  "transcript",
+			FirstLine: 1,
+			Lines:     []string{`{"type":"user"}`},
+		}
+		resp, err = client.Post("/api/v1/sync/chunk", valid)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		testutil.RequireStatus(t, resp, http.StatusOK)
+	})
+
+	t.Run("chunk rejects too many lines", func(t *testing.T) {
+		env.CleanDB(t)
+		testutil.SetEnvForTest(t, "SYNC_JSON_MAX_ARRAY_LEN", "3")
+		user := testutil.CreateTestUser(t, env, "test@example.com", "Test User")
+		apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Test Key")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "test-session")
+		client := testutil.NewTestClient(t, setupTestServerWithEnv(t, env)).WithAPIKey(apiKey.RawToken)
+
+		reqBody := api.SyncChunkRequest{
+			SessionID: sessionID,
+			FileName:  "transcript.jsonl",
+			FileType:  "transcript",
+			FirstLine: 1,
+			Lines:     []string{"{}", "{}", "{}", "{}"},
+		}
+		resp, err := client.Post("/api/v1/sync/chunk", reqBody)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		requireBodyError(t, resp, `"lines" has more than 3 elements`)
+	})
+}
@@ -0,0 +1,86 @@
+package sync_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestSyncInit_SessionVelocity_HTTP_Integration exercises the per-API-key cap
+// on new sessions: the call past the limit gets a structured 429, resuming an
+// existing session still works, and other keys are unaffected.
+func TestSyncInit_SessionVelocity_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+	t.Setenv("SYNC_INIT_MAX_SESSIONS_PER_HOUR", "2")
+	t.Setenv("SYNC_INIT_MAX_SESSIONS_PER_DAY", "0")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "velocity@example.com", "Velocity")
+	apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Leaky Key")
+	otherKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Other Key")
+
+	ts := setupTestServerWithEnv(t, env)
+	client := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+	initSession := func(c *testutil.TestClient, externalID string) *http.Response {
+		t.Helper()
+		resp, err := c.Post("/api/v1/sync/init", api.SyncInitRequest{
+			ExternalID:     externalID,
+			TranscriptPath: "/home/user/project/transcript.jsonl",
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	for _, id := range []string{"velocity-1", "velocity-2"} {
+		resp := initSession(client, id)
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		resp.Body.Close()
+	}
+
+	t.Run("new session past the limit gets 429", func(t *testing.T) {
+		resp := initSession(client, "velocity-3")
+		testutil.RequireStatus(t, resp, http.StatusTooManyRequests)
+		if resp.Header.Get("Retry-After") == "" {
+			t.Error("expected a Retry-After header")
+		}
+		var body httputil.ErrorResponse
+		testutil.ParseJSON(t, resp, &body)
+		if body.Code != httputil.CodeSessionVelocityExceeded {
+			t.Errorf("code = %q, want %q", body.Code, httputil.CodeSessionVelocityExceeded)
+		}
+
+		var count int
+		if err := env.DB.QueryRow(env.Ctx,
+			`SELECT COUNT(*) FROM sessions WHERE external_id = 'velocity-3'`).Scan(&count); err != nil {
+			t.Fatalf("count sessions: %v", err)
+		}
+		if count != 0 {
+			t.Error("refused sync/init must not create a session")
+		}
+	})
+
+	t.Run("resuming an existing session is exempt", func(t *testing.T) {
+		resp := initSession(client, "velocity-1")
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		resp.Body.Close()
+	})
+
+	t.Run("other keys have their own counters", func(t *testing.T) {
+		resp := initSession(testutil.NewTestClient(t, ts).WithAPIKey(otherKey.RawToken), "velocity-3")
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		resp.Body.Close()
+	})
+}
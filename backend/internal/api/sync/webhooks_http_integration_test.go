@@ -0,0 +1,242 @@
+package sync_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/api"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbwebhook"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+	"github.com/ConfabulousDev/confab-web/internal/webhooks"
+)
+
+// TestSyncInit_SessionCreatedWebhook_HTTP_Integration checks that sync/init
+// queues exactly one session.created delivery per new session (none on
+// resume), and that the dispatcher delivers it signed with the endpoint's
+// secret.
+func TestSyncInit_SessionCreatedWebhook_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "hooks@example.com", "Hooks")
+	apiKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "CLI")
+	sessionToken := testutil.CreateTestWebSessionWithToken(t, env, user.ID)
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan received, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{r.Header, body}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	ts := setupTestServerWithEnv(t, env)
+	web := testutil.NewTestClient(t, ts).WithSession(sessionToken)
+	cli := testutil.NewTestClient(t, ts).WithAPIKey(apiKey.RawToken)
+
+	resp, err := web.Post("/api/v1/me/webhooks", api.CreateWebhookRequest{URL: receiver.URL + "/confab"})
+	if err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+	testutil.RequireStatus(t, resp, http.StatusCreated)
+	var endpoint api.WebhookResponse
+	testutil.ParseJSON(t, resp, &endpoint)
+	if endpoint.Secret == "" || len(endpoint.Events) != 1 || endpoint.Events[0] != dbwebhook.EventSessionCreated {
+		t.Fatalf("endpoint = %+v", endpoint)
+	}
+
+	initReq := api.SyncInitRequest{
+		ExternalID:     "hooked-session",
+		TranscriptPath: "/home/user/project/transcript.jsonl",
+		Metadata: &api.SyncInitMetadata{
+			CWD:     "/home/user/project",
+			GitInfo: json.RawMessage(`{"repo_url":"https://github.com/acme/app.git","branch":"main"}`),
+		},
+	}
+	var sessionID string
+	for range 2 { // create, then resume
+		resp, err := cli.Post("/api/v1/sync/init", initReq)
+		if err != nil {
+			t.Fatalf("sync/init: %v", err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var body api.SyncInitResponse
+		testutil.ParseJSON(t, resp, &body)
+		sessionID = body.SessionID
+	}
+
+	var queued int
+	if err := env.DB.QueryRow(env.Ctx,
+		`SELECT COUNT(*) FROM webhook_deliveries WHERE endpoint_id = $1 AND status = 'pending'`, endpoint.ID).Scan(&queued); err != nil {
+		t.Fatalf("count deliveries: %v", err)
+	}
+	if queued != 1 {
+		t.Fatalf("queued deliveries = %d, want exactly 1", queued)
+	}
+
+	// The init responses came back before anything was sent.
+	select {
+	case r := <-got:
+		t.Fatalf("delivery sent synchronously: %s", r.body)
+	default:
+	}
+
+	dispatcher := webhooks.NewDispatcher(&dbwebhook.Store{DB: env.DB}, webhooks.Config{AllowPrivateTargets: true})
+	if n, err := dispatcher.DeliverDue(context.Background()); err != nil || n != 1 {
+		t.Fatalf("DeliverDue = %d, %v; want 1, nil", n, err)
+	}
+
+	var r received
+	select {
+	case r = <-got:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery received")
+	}
+	if err := webhooks.Verify(endpoint.Secret, r.header.Get(webhooks.HeaderSignature), r.body, time.Minute, time.Now()); err != nil {
+		t.Errorf("signature: %v", err)
+	}
+	if r.header.Get(webhooks.HeaderEvent) != dbwebhook.EventSessionCreated {
+		t.Errorf("%s = %q", webhooks.HeaderEvent, r.header.Get(webhooks.HeaderEvent))
+	}
+
+	var payload struct {
+		Event     string `json:"event"`
+		CreatedAt string `json:"created_at"`
+		Data      struct {
+			SessionID  string            `json:"session_id"`
+			ExternalID string            `json:"external_id"`
+			Provider   string            `json:"provider"`
+			CWD        string            `json:"cwd"`
+			GitInfo    map[string]string `json:"git_info"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(r.body, &payload); err != nil {
+		t.Fatalf("payload %s: %v", r.body, err)
+	}
+	if payload.Event != dbwebhook.EventSessionCreated || payload.Data.SessionID != sessionID ||
+		payload.Data.ExternalID != "hooked-session" || payload.Data.Provider != "claude-code" ||
+		payload.Data.CWD != "/home/user/project" || payload.Data.GitInfo["branch"] != "main" ||
+		payload.Data.GitInfo["repo_url"] != "https://github.com/acme/app.git" {
+		t.Errorf("payload = %s", r.body)
+	}
+	if _, err := time.Parse(time.RFC3339, payload.CreatedAt); err != nil {
+		t.Errorf("created_at %q: %v", payload.CreatedAt, err)
+	}
+
+	var status string
+	if err := env.DB.QueryRow(env.Ctx,
+		`SELECT status FROM webhook_deliveries WHERE endpoint_id = $1`, endpoint.ID).Scan(&status); err != nil {
+		t.Fatalf("delivery status: %v", err)
+	}
+	if status != dbwebhook.StatusDelivered {
+		t.Errorf("status = %q, want delivered", status)
+	}
+	if n, err := dispatcher.DeliverDue(context.Background()); err != nil || n != 0 {
+		t.Errorf("second DeliverDue = %d, %v; want nothing left", n, err)
+	}
+	select {
+	case r := <-got:
+		t.Errorf("extra delivery: %s", r.body)
+	default:
+	}
+}
+
+// TestWebhookEndpoints_HTTP_Integration covers registering, listing and
+// deleting webhook endpoints.
+func TestWebhookEndpoints_HTTP_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping HTTP integration test in short mode")
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "hooks@example.com", "Hooks")
+	other := testutil.CreateTestUser(t, env, "other@example.com", "Other")
+	ts := setupTestServerWithEnv(t, env)
+	web := testutil.NewTestClient(t, ts).WithSession(testutil.CreateTestWebSessionWithToken(t, env, user.ID))
+	otherWeb := testutil.NewTestClient(t, ts).WithSession(testutil.CreateTestWebSessionWithToken(t, env, other.ID))
+
+	t.Run("invalid URL and unknown events are rejected", func(t *testing.T) {
+		for _, req := range []api.CreateWebhookRequest{
+			{URL: "ftp://example.com/hook"},
+			{URL: "https://example.com/hook", Events: []string{"session.deleted"}},
+		} {
+			resp, err := web.Post("/api/v1/me/webhooks", req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testutil.RequireStatus(t, resp, http.StatusBadRequest)
+			resp.Body.Close()
+		}
+	})
+
+	var created api.WebhookResponse
+	t.Run("create, list, delete", func(t *testing.T) {
+		resp, err := web.Post("/api/v1/me/webhooks", api.CreateWebhookRequest{URL: "https://hooks.example.com/a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusCreated)
+		testutil.ParseJSON(t, resp, &created)
+
+		resp, err = web.Get("/api/v1/me/webhooks")
+		if err != nil {
+			t.Fatal(err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusOK)
+		var list []api.WebhookResponse
+		testutil.ParseJSON(t, resp, &list)
+		if len(list) != 1 || list[0].ID != created.ID || list[0].URL != "https://hooks.example.com/a" || list[0].Secret != "" {
+			t.Errorf("list = %+v, want the endpoint without its secret", list)
+		}
+
+		resp, err = otherWeb.Delete("/api/v1/me/webhooks/" + strconv.FormatInt(created.ID, 10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusNotFound)
+		resp.Body.Close()
+
+		resp, err = web.Delete("/api/v1/me/webhooks/" + strconv.FormatInt(created.ID, 10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		testutil.RequireStatus(t, resp, http.StatusNoContent)
+		resp.Body.Close()
+	})
+
+	t.Run("per-user limit", func(t *testing.T) {
+		for i := range dbwebhook.MaxEndpointsPerUser + 1 {
+			resp, err := web.Post("/api/v1/me/webhooks", api.CreateWebhookRequest{URL: "https://hooks.example.com/limit"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := http.StatusCreated
+			if i == dbwebhook.MaxEndpointsPerUser {
+				want = http.StatusConflict
+			}
+			testutil.RequireStatus(t, resp, want)
+			resp.Body.Close()
+		}
+	})
+}
@@ -0,0 +1,350 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/storage"
+	"github.com/ConfabulousDev/confab-web/internal/validation"
+)
+
+// syncChunkPlan is what validateSyncChunk learned about a chunk it accepted.
+type syncChunkPlan struct {
+	externalID   string
+	provider     string // Session type the chunk is stored under, after any reclassification
+	reclassified bool   // The chunk changed (or, for a dry run, would change) the session type
+	syncState    *db.SyncFileState
+	generation   int
+	lastLine     int
+	release      func() // Releases the file's upload lock; a no-op for a dry run
+}
+
+// validateSyncChunk runs every check POST /api/v1/sync/chunk makes before it
+// stores anything: required fields, name and metadata limits, the ingest
+// denylist, ownership, archive state, the file allowlist, codex_rollout
+// support, continuity against last_synced_line, and the chunk limit. On
+// failure it writes the error response and returns false.
+//
+// sync/chunk and its dry run (POST /api/v1/sync/chunk/validate) both call
+// it, so they cannot disagree on a verdict. dryRun only changes the two steps
+// with side effects: the upload lock is not taken, and a detected session
+// type is checked with CanReclassifySessionType instead of applied.
+// Otherwise the caller must defer plan.release().
+func (s *Server) validateSyncChunk(w http.ResponseWriter, r *http.Request, userID int64, req *SyncChunkRequest, dryRun bool) (_ *syncChunkPlan, ok bool) {
+	log := logger.Ctx(r.Context())
+
+	// Validate required fields
+	if req.SessionID == "" {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "session_id is required")
+		return nil, false
+	}
+	if req.FileName == "" {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "file_name is required")
+		return nil, false
+	}
+	if req.FileType == "" {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "file_type is required")
+		return nil, false
+	}
+	if req.FileType == "todo" {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeUnsupportedFileType, "todo file sync is no longer supported")
+		return nil, false
+	}
+	if req.FirstLine < 1 {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "first_line must be >= 1")
+		return nil, false
+	}
+	if len(req.Lines) == 0 {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, "lines array cannot be empty")
+		return nil, false
+	}
+
+	// Validate field lengths
+	if err := validation.ValidateSyncFileName(req.FileName); err != nil {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, err.Error())
+		return nil, false
+	}
+	if req.Metadata != nil {
+		if err := validation.ValidateSyncMetadata(validation.SyncMetadata{
+			GitInfo:          req.Metadata.GitInfo,
+			Summary:          req.Metadata.Summary,
+			FirstUserMessage: req.Metadata.FirstUserMessage,
+			Model:            req.Metadata.Model,
+		}); err != nil {
+			respondMetadataError(w, err)
+			return nil, false
+		}
+		if req.Metadata.CodexRollout != nil {
+			cr := req.Metadata.CodexRollout
+			if err := validation.ValidateCodexRolloutMetadata(
+				cr.ThreadUUID, cr.ParentThreadUUID,
+				cr.RolloutPath, cr.CWD, cr.Model, cr.Source, cr.ThreadSource,
+				cr.AgentPath, cr.AgentRole, cr.AgentNickname,
+			); err != nil {
+				respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, err.Error())
+				return nil, false
+			}
+		}
+		// CF-494: validate git_info shape (new remotes/tracking_remote fields).
+		if req.Metadata.GitInfo != nil && req.FileType == "transcript" {
+			if err := validation.ValidateGitInfo(req.Metadata.GitInfo); err != nil {
+				respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, err.Error())
+				return nil, false
+			}
+		}
+	}
+
+	// Refuse denylisted content before anything is stored. Unlike redaction,
+	// the chunk never reaches S3; the client sees which rule fired.
+	if where, rule, denied := checkIngestPolicy(s.ingestPolicy, req); denied {
+		log.Warn("Chunk refused by ingest denylist",
+			"session_id", req.SessionID,
+			"file_name", req.FileName,
+			"rule", rule,
+			"where", where)
+		respondErrorCode(w, http.StatusUnprocessableEntity, httputil.CodeContentDenied,
+			fmt.Sprintf("%s matches ingest denylist rule %q; the chunk was not stored", where, rule))
+		return nil, false
+	}
+
+	// Verify session ownership and get external_id (needed for S3 key)
+	dbCtx, dbCancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer dbCancel()
+
+	sessionStore := &dbsession.Store{DB: s.db}
+	externalID, provider, err := sessionStore.VerifySessionOwnership(dbCtx, req.SessionID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrSessionNotFound) {
+			respondErrorCode(w, http.StatusNotFound, httputil.CodeSessionNotFound, "Session not found")
+			return nil, false
+		}
+		if errors.Is(err, db.ErrForbidden) {
+			respondError(w, http.StatusForbidden, "Access denied")
+			return nil, false
+		}
+		log.Error("Failed to verify session ownership", "error", err, "session_id", req.SessionID)
+		respondError(w, http.StatusInternalServerError, "Failed to verify session")
+		return nil, false
+	}
+
+	plan := &syncChunkPlan{externalID: externalID, release: func() {}}
+
+	// Serialize uploads to this file. Without the lock, two chunks for the
+	// same file could both pass the continuity check below before either
+	// records its sync state, and both be stored. Held until the handler
+	// returns (plan.release); the loser retries rather than waits. A dry run
+	// stores nothing, so it neither takes nor waits for the lock.
+	if !dryRun {
+		releaseLock, locked, err := sessionStore.TryLockSyncFile(r.Context(), req.SessionID, req.FileName)
+		if err != nil {
+			log.Error("Failed to lock sync file", "error", err, "session_id", req.SessionID, "file_name", req.FileName)
+			respondError(w, http.StatusInternalServerError, "Failed to get sync state")
+			return nil, false
+		}
+		if !locked {
+			log.Warn("Concurrent chunk upload refused",
+				"session_id", req.SessionID,
+				"file_name", req.FileName)
+			w.Header().Set("Retry-After", "1")
+			respondErrorCode(w, http.StatusTooManyRequests, httputil.CodeChunkInProgress,
+				"Another chunk for this file is being uploaded; retry shortly")
+			return nil, false
+		}
+		plan.release = releaseLock
+		defer func() {
+			if !ok {
+				releaseLock()
+			}
+		}()
+	}
+
+	// Sessions from clients that omit session_type default to claude-code.
+	// On the first transcript chunk, before anything is uploaded under the
+	// provider-scoped S3 prefix, classify the transcript by its shape and
+	// correct the type if it is clearly another provider.
+	if req.FileType == "transcript" && req.FirstLine == 1 && provider == models.ProviderClaudeCode {
+		if detected := analytics.DetectSessionType(req.Lines); detected != analytics.SessionTypeUnknown && detected != provider {
+			// The dry run reports the type the chunk would be stored under.
+			var changed bool
+			if dryRun {
+				changed, err = sessionStore.CanReclassifySessionType(dbCtx, req.SessionID, detected)
+			} else {
+				changed, err = sessionStore.ReclassifySessionType(dbCtx, req.SessionID, detected)
+			}
+			if err != nil {
+				log.Error("Failed to reclassify session type", "error", err, "session_id", req.SessionID, "detected", detected)
+				respondError(w, http.StatusInternalServerError, "Failed to verify session")
+				return nil, false
+			}
+			if changed {
+				if !dryRun {
+					log.Info("Reclassified session type from transcript",
+						"session_id", req.SessionID,
+						"from", provider,
+						"to", detected)
+				}
+				provider = detected
+				plan.reclassified = true
+			}
+		}
+	}
+
+	// The archived transcript's chunks are gone; appending to it would leave
+	// a file that no longer starts at line 1.
+	archived, err := sessionStore.IsTranscriptArchived(dbCtx, req.SessionID)
+	if err != nil {
+		log.Error("Failed to check transcript archive", "error", err, "session_id", req.SessionID)
+		respondError(w, http.StatusInternalServerError, "Failed to verify session")
+		return nil, false
+	}
+	if archived {
+		respondTranscriptArchived(w)
+		return nil, false
+	}
+
+	// Check the file against the session type's allowlist once the type is
+	// final (after reclassification), and after ownership for the same
+	// reason as the codex_rollout check below.
+	if !s.enforceSyncFilePolicy(w, r, req, provider) {
+		return nil, false
+	}
+
+	// codex_rollout metadata is only meaningful for codex sessions. Check
+	// after VerifySessionOwnership so we don't leak the existence of someone
+	// else's claude-code session via this validation path.
+	if req.Metadata != nil && req.Metadata.CodexRollout != nil && provider != models.ProviderCodex {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed,
+			"codex_rollout metadata is only supported for codex sessions")
+		return nil, false
+	}
+
+	// Get current sync state to validate chunk continuity
+	syncState, err := sessionStore.GetSyncFileState(dbCtx, req.SessionID, req.FileName)
+	expectedFirstLine := 1
+	generation := 0
+	if err == nil {
+		// File exists - next chunk must continue from where we left off
+		expectedFirstLine = syncState.LastSyncedLine + 1
+		generation = syncState.Generation
+	} else if !errors.Is(err, db.ErrFileNotFound) {
+		log.Error("Failed to get sync state", "error", err, "session_id", req.SessionID, "file_name", req.FileName)
+		respondError(w, http.StatusInternalServerError, "Failed to get sync state")
+		return nil, false
+	}
+	// ErrFileNotFound is fine - it's a new file, expectedFirstLine stays 1
+
+	// Validate chunk continuity (no gaps, no overlaps)
+	if req.FirstLine != expectedFirstLine {
+		log.Warn("Chunk continuity error",
+			"session_id", req.SessionID,
+			"file_name", req.FileName,
+			"expected_first_line", expectedFirstLine,
+			"actual_first_line", req.FirstLine)
+		code := httputil.CodeChunkGap
+		if req.FirstLine < expectedFirstLine {
+			code = httputil.CodeChunkOverlap
+		}
+		respondErrorCode(w, http.StatusBadRequest, code,
+			fmt.Sprintf("first_line must be %d (got %d) - chunks must be contiguous", expectedFirstLine, req.FirstLine))
+		return nil, false
+	}
+
+	// Soft limit check on chunk count (if known)
+	// This is a soft limit - races may allow slightly exceeding it, but reads will self-heal
+	if syncState != nil && syncState.ChunkCount != nil && *syncState.ChunkCount >= storage.MaxChunksPerFile {
+		log.Warn("Chunk limit exceeded",
+			"session_id", req.SessionID,
+			"file_name", req.FileName,
+			"chunk_count", *syncState.ChunkCount,
+			"limit", storage.MaxChunksPerFile)
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeChunkLimitExceeded,
+			fmt.Sprintf("File has too many chunks (limit: %d). Consider starting a new session.", storage.MaxChunksPerFile))
+		return nil, false
+	}
+
+	plan.provider = provider
+	plan.syncState = syncState
+	plan.generation = generation
+	plan.lastLine = req.FirstLine + len(req.Lines) - 1
+	return plan, true
+}
+
+// SyncChunkValidateResponse is the response for POST /api/v1/sync/chunk/validate:
+// what POST /api/v1/sync/chunk would do with the same body.
+type SyncChunkValidateResponse struct {
+	Valid          bool           `json:"valid"`
+	LastSyncedLine int            `json:"last_synced_line"` // What sync/chunk would return; the next chunk starts one line later
+	ChunkBytes     int            `json:"chunk_bytes"`      // Bytes the chunk would store
+	SessionType    string         `json:"session_type"`     // Session type the chunk would be stored under
+	Reclassified   bool           `json:"reclassified"`     // The chunk would change the session's type to session_type
+	Redactions     map[string]int `json:"redactions"`       // [REDACTED:TYPE] markers in the lines by type, as the redactions card counts them
+}
+
+// handleSyncChunkValidate is a dry run of sync/chunk for CLI development: it
+// runs validateSyncChunk on the same body and reports what the upload would
+// change, without writing to S3, sync_files or the session. An invalid chunk
+// gets exactly the error sync/chunk would return.
+// POST /api/v1/sync/chunk/validate
+func (s *Server) handleSyncChunkValidate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req SyncChunkRequest
+	if !s.decodeSyncBody(w, r, &req) {
+		return
+	}
+
+	plan, ok := s.validateSyncChunk(w, r, userID, &req, true)
+	if !ok {
+		return
+	}
+
+	respondJSON(w, http.StatusOK, SyncChunkValidateResponse{
+		Valid:          true,
+		LastSyncedLine: plan.lastLine,
+		ChunkBytes:     chunkContentLen(req.Lines),
+		SessionType:    plan.provider,
+		Reclassified:   plan.reclassified,
+		Redactions:     chunkRedactions(req.Lines),
+	})
+}
+
+// chunkContentLen is the size of the object sync/chunk stores for lines:
+// each line followed by a newline.
+func chunkContentLen(lines []string) int {
+	n := 0
+	for _, line := range lines {
+		n += len(line) + 1
+	}
+	return n
+}
+
+// chunkRedactions counts the [REDACTED:TYPE] markers in lines with the
+// analyzer behind the redactions card. Redaction happens in the CLI; this
+// shows what the card will count once the chunk is stored.
+func chunkRedactions(lines []string) map[string]int {
+	counts := map[string]int{}
+	fc, err := analytics.NewFileCollection([]byte(strings.Join(lines, "\n")))
+	if err != nil {
+		return counts
+	}
+	result, err := (&analytics.RedactionsAnalyzer{}).Analyze(fc)
+	if err != nil {
+		return counts
+	}
+	for redactionType, n := range result.RedactionCounts {
+		counts[redactionType] = n
+	}
+	return counts
+}
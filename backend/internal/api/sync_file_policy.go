@@ -0,0 +1,137 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+// syncFileRule lists the file_name shapes (path.Match patterns, so "*" never
+// crosses a "/") a session type uploads under one file_type.
+type syncFileRule struct {
+	FileType string
+	Names    []string
+}
+
+// syncFileRules is the sync/chunk allowlist per canonical session type,
+// derived from what each provider's CLI uploads today. Transcript names are
+// any top-level .jsonl: the CLIs send the agent's own file name (a session
+// UUID, a Codex rollout-<ts>-<uuid>), not a fixed "transcript.jsonl".
+var syncFileRules = map[string][]syncFileRule{
+	models.ProviderClaudeCode: {
+		{FileType: "transcript", Names: []string{"*.jsonl"}},
+		{FileType: "agent", Names: []string{
+			"agent-*.jsonl",
+			"subagents/agent-*.jsonl",
+			"subagents/workflows/*/agent-*.jsonl",
+		}},
+		{FileType: "workflow_journal", Names: []string{"subagents/workflows/*/journal.jsonl"}},
+	},
+	models.ProviderCodex: {
+		{FileType: "transcript", Names: []string{"*.jsonl"}},
+		{FileType: "agent", Names: []string{"*.jsonl"}},
+	},
+	models.ProviderOpencode: {
+		{FileType: "transcript", Names: []string{"*.jsonl"}},
+		{FileType: "agent", Names: []string{"*.jsonl", "subagents/*.jsonl"}},
+	},
+	models.ProviderCursor: {
+		{FileType: "transcript", Names: []string{"*.jsonl"}},
+		{FileType: "agent", Names: []string{"subagents/*.jsonl"}},
+	},
+}
+
+// defaultSyncFileRules applies to session types registered by an analytics
+// provider outside the canonical set: a main transcript plus agent files.
+var defaultSyncFileRules = []syncFileRule{
+	{FileType: "transcript", Names: []string{"*.jsonl"}},
+	{FileType: "agent", Names: []string{"*.jsonl", "subagents/*.jsonl"}},
+}
+
+// SyncFileRejectedResponse is the 400 body for a chunk whose file_type or
+// file_name the session type doesn't accept. AllowedFileNames lists the
+// patterns for the requested file_type, and is omitted when the type itself
+// was rejected.
+type SyncFileRejectedResponse struct {
+	httputil.ErrorResponse
+	SessionType      string   `json:"session_type"`
+	AllowedFileTypes []string `json:"allowed_file_types"`
+	AllowedFileNames []string `json:"allowed_file_names,omitempty"`
+}
+
+// syncFilePolicyStrictFromEnv reports whether sync/chunk rejects files that
+// break the allowlist (SYNC_FILE_POLICY_STRICT=true). Off by default, the
+// check only logs, so operators can confirm from the warnings that no
+// legitimate client is affected before enforcing it.
+func syncFilePolicyStrictFromEnv() bool {
+	return os.Getenv("SYNC_FILE_POLICY_STRICT") == "true"
+}
+
+// checkSyncFile matches fileType and fileName against sessionType's
+// allowlist. It returns nil when the file is allowed.
+func checkSyncFile(sessionType, fileType, fileName string) *SyncFileRejectedResponse {
+	sessionType = models.NormalizeProvider(sessionType)
+	rules, ok := syncFileRules[sessionType]
+	if !ok {
+		rules = defaultSyncFileRules
+	}
+
+	allowedTypes := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		allowedTypes = append(allowedTypes, rule.FileType)
+	}
+	i := slices.IndexFunc(rules, func(rule syncFileRule) bool { return rule.FileType == fileType })
+	if i < 0 {
+		return &SyncFileRejectedResponse{
+			ErrorResponse: httputil.ErrorResponse{
+				Error: fmt.Sprintf("file_type %q is not accepted for %s sessions", fileType, sessionType),
+				Code:  httputil.CodeUnsupportedFileType,
+			},
+			SessionType:      sessionType,
+			AllowedFileTypes: allowedTypes,
+		}
+	}
+
+	for _, pattern := range rules[i].Names {
+		if matched, _ := path.Match(pattern, fileName); matched {
+			return nil
+		}
+	}
+	return &SyncFileRejectedResponse{
+		ErrorResponse: httputil.ErrorResponse{
+			Error: fmt.Sprintf("file_name %q is not an accepted %s file name for %s sessions", fileName, fileType, sessionType),
+			Code:  httputil.CodeInvalidFileName,
+		},
+		SessionType:      sessionType,
+		AllowedFileTypes: allowedTypes,
+		AllowedFileNames: rules[i].Names,
+	}
+}
+
+// enforceSyncFilePolicy applies checkSyncFile to a chunk. Violations are
+// always logged; in strict mode the chunk is also refused with a 400 and
+// false is returned.
+func (s *Server) enforceSyncFilePolicy(w http.ResponseWriter, r *http.Request, req *SyncChunkRequest, sessionType string) bool {
+	rejected := checkSyncFile(sessionType, req.FileType, req.FileName)
+	if rejected == nil {
+		return true
+	}
+	logger.Ctx(r.Context()).Warn("Chunk file outside sync file allowlist",
+		"session_id", req.SessionID,
+		"session_type", rejected.SessionType,
+		"file_type", req.FileType,
+		"file_name", req.FileName,
+		"code", rejected.Code,
+		"strict", s.strictFilePolicy)
+	if !s.strictFilePolicy {
+		return true
+	}
+	respondJSON(w, http.StatusBadRequest, rejected)
+	return false
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+func TestCheckSyncFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		sessionType string
+		fileType    string
+		fileName    string
+		wantCode    httputil.ErrorCode // "" = allowed
+	}{
+		{"claude transcript", models.ProviderClaudeCode, "transcript", "transcript.jsonl", ""},
+		{"claude transcript named by session", models.ProviderClaudeCode, "transcript", "7f3c2a1e-0b4d-4e8a-9c61-2d5b7e9f0a13.jsonl", ""},
+		{"claude agent", models.ProviderClaudeCode, "agent", "agent-a1b2c3.jsonl", ""},
+		{"claude nested agent", models.ProviderClaudeCode, "agent", "subagents/agent-a1b2c3.jsonl", ""},
+		{"claude workflow agent", models.ProviderClaudeCode, "agent", "subagents/workflows/run-1/agent-a1.jsonl", ""},
+		{"claude workflow journal", models.ProviderClaudeCode, "workflow_journal", "subagents/workflows/run-1/journal.jsonl", ""},
+		{"legacy claude alias", models.ProviderClaudeCodeLegacy, "agent", "agent-a1.jsonl", ""},
+		{"codex rollout", models.ProviderCodex, "transcript", "rollout-2026-01-01T00-00-00-019e2ce8.jsonl", ""},
+		{"codex subagent rollout", models.ProviderCodex, "agent", "rollout-019e2ce8-subagent.jsonl", ""},
+		{"cursor subagent", models.ProviderCursor, "agent", "subagents/9c4d4938-subagent.jsonl", ""},
+		{"opencode agent", models.ProviderOpencode, "agent", "ses_abc.jsonl", ""},
+		{"registered type uses defaults", "acme-agent", "agent", "subagents/x.jsonl", ""},
+
+		{"unknown file type", models.ProviderClaudeCode, "screenshot", "shot.png", httputil.CodeUnsupportedFileType},
+		{"agent file on codex", models.ProviderCodex, "workflow_journal", "subagents/workflows/r/journal.jsonl", httputil.CodeUnsupportedFileType},
+		{"claude agent without prefix", models.ProviderClaudeCode, "agent", "helper.jsonl", httputil.CodeInvalidFileName},
+		{"transcript in a subdirectory", models.ProviderClaudeCode, "transcript", "nested/transcript.jsonl", httputil.CodeInvalidFileName},
+		{"transcript not jsonl", models.ProviderCodex, "transcript", "rollout.json", httputil.CodeInvalidFileName},
+		{"cursor agent at top level", models.ProviderCursor, "agent", "9c4d4938.jsonl", httputil.CodeInvalidFileName},
+		{"journal outside a workflow run", models.ProviderClaudeCode, "workflow_journal", "journal.jsonl", httputil.CodeInvalidFileName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkSyncFile(tt.sessionType, tt.fileType, tt.fileName)
+			if tt.wantCode == "" {
+				if got != nil {
+					t.Fatalf("checkSyncFile = %+v, want allowed", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("checkSyncFile allowed %s %q, want %q", tt.fileType, tt.fileName, tt.wantCode)
+			}
+			if got.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", got.Code, tt.wantCode)
+			}
+			if len(got.AllowedFileTypes) == 0 {
+				t.Error("AllowedFileTypes is empty")
+			}
+			if (tt.wantCode == httputil.CodeInvalidFileName) != (len(got.AllowedFileNames) > 0) {
+				t.Errorf("AllowedFileNames = %v for code %q", got.AllowedFileNames, got.Code)
+			}
+		})
+	}
+}
+
+func TestCheckSyncFile_ListsAllowedValues(t *testing.T) {
+	got := checkSyncFile(models.ProviderClaudeCodeLegacy, "binary-blob", "blob.bin")
+	if got == nil {
+		t.Fatal("checkSyncFile allowed binary-blob")
+	}
+	if got.SessionType != models.ProviderClaudeCode {
+		t.Errorf("SessionType = %q, want the canonical %q", got.SessionType, models.ProviderClaudeCode)
+	}
+	want := []string{"transcript", "agent", "workflow_journal"}
+	if len(got.AllowedFileTypes) != len(want) {
+		t.Fatalf("AllowedFileTypes = %v, want %v", got.AllowedFileTypes, want)
+	}
+	for i := range want {
+		if got.AllowedFileTypes[i] != want[i] {
+			t.Errorf("AllowedFileTypes = %v, want %v", got.AllowedFileTypes, want)
+			break
+		}
+	}
+}
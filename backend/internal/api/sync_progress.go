@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// syncProgressHeartbeat is how often an idle sync-progress stream sends an
+// SSE comment, so proxies and load balancers don't close it as dead.
+const syncProgressHeartbeat = 30 * time.Second
+
+// syncProgressBuffer is how many undelivered events a subscriber may queue.
+// Each event carries the file's absolute state, so when a slow reader falls
+// this far behind the oldest queued event is dropped, not the newest.
+const syncProgressBuffer = 16
+
+// SyncProgress is one sync_progress event: a file's sync state right after a
+// chunk was stored.
+type SyncProgress struct {
+	FileName       string `json:"file_name"`
+	LastSyncedLine int    `json:"last_synced_line"`
+	ChunkCount     int    `json:"chunk_count"`
+}
+
+// SyncProgressBroker fans out sync progress from handleSyncChunk to the
+// dashboards streaming GET /api/v1/sessions/{id}/sync/events. It is in-memory:
+// a subscriber only hears about chunks handled by the same server instance.
+type SyncProgressBroker struct {
+	mu   sync.Mutex
+	subs map[string]map[chan SyncProgress]struct{} // session ID → subscriber channels
+}
+
+// NewSyncProgressBroker creates a broker with no subscribers.
+func NewSyncProgressBroker() *SyncProgressBroker {
+	return &SyncProgressBroker{subs: map[string]map[chan SyncProgress]struct{}{}}
+}
+
+// Subscribe registers for a session's progress events. The returned cancel
+// func unregisters and closes the channel; it is safe to call more than once.
+func (b *SyncProgressBroker) Subscribe(sessionID string) (<-chan SyncProgress, func()) {
+	ch := make(chan SyncProgress, syncProgressBuffer)
+
+	b.mu.Lock()
+	if b.subs[sessionID] == nil {
+		b.subs[sessionID] = map[chan SyncProgress]struct{}{}
+	}
+	b.subs[sessionID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs[sessionID], ch)
+			if len(b.subs[sessionID]) == 0 {
+				delete(b.subs, sessionID)
+			}
+			close(ch)
+		})
+	}
+}
+
+// Publish delivers p to every subscriber of the session without blocking.
+// A subscriber whose buffer is full loses its oldest queued event.
+func (b *SyncProgressBroker) Publish(sessionID string, p SyncProgress) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[sessionID] {
+		select {
+		case ch <- p:
+			continue
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- p:
+		default:
+		}
+	}
+}
+
+// subscribers returns how many streams are open for the session.
+func (b *SyncProgressBroker) subscribers(sessionID string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs[sessionID])
+}
+
+// HandleSyncProgressEvents streams a session's sync progress as server-sent
+// events: an `event: sync_progress` per stored chunk, and a comment every
+// heartbeat while idle. Access follows the canonical session read rules. The
+// stream ends when the client disconnects.
+func HandleSyncProgressEvents(database *db.DB, broker *SyncProgressBroker, heartbeat time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		result := RequireCanonicalRead(ctx, w, database, sessionID)
+		cancel()
+		if result == nil {
+			return
+		}
+
+		streamSyncProgress(w, r, broker, sessionID, heartbeat)
+	}
+}
+
+// streamSyncProgress writes the SSE stream for an already-authorized session.
+func streamSyncProgress(w http.ResponseWriter, r *http.Request, broker *SyncProgressBroker, sessionID string, heartbeat time.Duration) {
+	log := logger.Ctx(r.Context())
+
+	rc := http.NewResponseController(w)
+	// The stream outlives HTTP_WRITE_TIMEOUT by design.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Warn("Failed to clear write deadline for sync events", "error", err, "session_id", sessionID)
+	}
+
+	events, unsubscribe := broker.Subscribe(sessionID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // keep nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.Warn("Sync events stream not flushable", "error", err, "session_id", sessionID)
+		return
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case p := <-events:
+			data, err := json.Marshal(p)
+			if err != nil {
+				log.Error("Failed to encode sync progress", "error", err, "session_id", sessionID)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: sync_progress\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
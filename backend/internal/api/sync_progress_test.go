@@ -0,0 +1,177 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent is one parsed server-sent event; comment holds a ": ..." line.
+type sseEvent struct {
+	event   string
+	data    string
+	comment string
+}
+
+// readSSE parses events from an SSE body onto a channel until the body ends.
+func readSSE(t *testing.T, resp *http.Response) <-chan sseEvent {
+	t.Helper()
+	out := make(chan sseEvent, 16)
+	go func() {
+		defer close(out)
+		scanner := bufio.NewScanner(resp.Body)
+		var ev sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				out <- ev
+				ev = sseEvent{}
+			case strings.HasPrefix(line, ":"):
+				ev.comment = strings.TrimSpace(line[1:])
+			case strings.HasPrefix(line, "event: "):
+				ev.event = line[len("event: "):]
+			case strings.HasPrefix(line, "data: "):
+				ev.data = line[len("data: "):]
+			}
+		}
+	}()
+	return out
+}
+
+func nextSSE(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("stream ended")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an SSE event")
+	}
+	return sseEvent{}
+}
+
+// startSyncProgressStream serves streamSyncProgress for session-1 and opens a
+// stream to it, returning once the broker has registered the subscriber.
+func startSyncProgressStream(t *testing.T, broker *SyncProgressBroker, heartbeat time.Duration) (*http.Response, context.CancelFunc) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streamSyncProgress(w, r, broker, "session-1", heartbeat)
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", got)
+	}
+	waitForSubscribers(t, broker, "session-1", 1)
+	return resp, cancel
+}
+
+func waitForSubscribers(t *testing.T, broker *SyncProgressBroker, sessionID string, want int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for broker.subscribers(sessionID) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("subscribers = %d, want %d", broker.subscribers(sessionID), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSyncProgressStream(t *testing.T) {
+	t.Run("emits sync_progress for the session", func(t *testing.T) {
+		broker := NewSyncProgressBroker()
+		resp, _ := startSyncProgressStream(t, broker, time.Hour)
+		events := readSSE(t, resp)
+
+		broker.Publish("session-2", SyncProgress{FileName: "other.jsonl", LastSyncedLine: 9, ChunkCount: 9})
+		broker.Publish("session-1", SyncProgress{FileName: "transcript.jsonl", LastSyncedLine: 25, ChunkCount: 2})
+
+		ev := nextSSE(t, events)
+		if ev.event != "sync_progress" {
+			t.Fatalf("event = %q, want sync_progress", ev.event)
+		}
+		var got SyncProgress
+		if err := json.Unmarshal([]byte(ev.data), &got); err != nil {
+			t.Fatalf("decode data %q: %v", ev.data, err)
+		}
+		want := SyncProgress{FileName: "transcript.jsonl", LastSyncedLine: 25, ChunkCount: 2}
+		if got != want {
+			t.Errorf("progress = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("sends heartbeats while idle", func(t *testing.T) {
+		broker := NewSyncProgressBroker()
+		resp, _ := startSyncProgressStream(t, broker, 20*time.Millisecond)
+		events := readSSE(t, resp)
+
+		if ev := nextSSE(t, events); ev.comment != "heartbeat" || ev.event != "" {
+			t.Errorf("got %+v, want a heartbeat comment", ev)
+		}
+	})
+
+	t.Run("client disconnect unsubscribes", func(t *testing.T) {
+		broker := NewSyncProgressBroker()
+		_, cancel := startSyncProgressStream(t, broker, time.Hour)
+
+		cancel()
+		waitForSubscribers(t, broker, "session-1", 0)
+		// Publishing with no subscribers left is a no-op.
+		broker.Publish("session-1", SyncProgress{FileName: "transcript.jsonl", LastSyncedLine: 1, ChunkCount: 1})
+	})
+}
+
+func TestSyncProgressBroker(t *testing.T) {
+	t.Run("cancel closes the channel and is idempotent", func(t *testing.T) {
+		broker := NewSyncProgressBroker()
+		ch, cancel := broker.Subscribe("s")
+		cancel()
+		cancel()
+		if _, ok := <-ch; ok {
+			t.Error("channel still open after cancel")
+		}
+		if n := broker.subscribers("s"); n != 0 {
+			t.Errorf("subscribers = %d, want 0", n)
+		}
+	})
+
+	t.Run("full buffer drops the oldest event", func(t *testing.T) {
+		broker := NewSyncProgressBroker()
+		ch, cancel := broker.Subscribe("s")
+		defer cancel()
+
+		for i := 1; i <= syncProgressBuffer+3; i++ {
+			broker.Publish("s", SyncProgress{FileName: "f", LastSyncedLine: i, ChunkCount: i})
+		}
+		if first := <-ch; first.ChunkCount != 4 {
+			t.Errorf("oldest queued chunk_count = %d, want 4", first.ChunkCount)
+		}
+		var last SyncProgress
+		for len(ch) > 0 {
+			last = <-ch
+		}
+		if last.ChunkCount != syncProgressBuffer+3 {
+			t.Errorf("newest chunk_count = %d, want %d", last.ChunkCount, syncProgressBuffer+3)
+		}
+	})
+}
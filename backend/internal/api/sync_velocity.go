@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ConfabulousDev/confab-web/internal/auth"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbvelocity"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbwebhook"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/notify"
+)
+
+// Default per-API-key caps on sessions created through sync/init. They sit
+// well above a first-time backfill of a busy machine's history but stop a
+// leaked key from creating sessions without bound.
+const (
+	defaultSyncInitMaxSessionsPerHour = 1000
+	defaultSyncInitMaxSessionsPerDay  = 5000
+)
+
+// velocityAlertRejections is how many refused sync/init calls in one hour make
+// a key worth an operator's attention. The refusal that reaches it logs a
+// SECURITY_EVENT; the key also shows up in GET /api/v1/admin/velocity.
+const velocityAlertRejections = 10
+
+// syncInitVelocityFromEnv resolves the per-key session velocity limits from
+// SYNC_INIT_MAX_SESSIONS_PER_HOUR and SYNC_INIT_MAX_SESSIONS_PER_DAY. A value
+// of 0 disables that window; a negative or non-numeric value fails startup.
+func syncInitVelocityFromEnv() dbvelocity.Limits {
+	return dbvelocity.Limits{
+		PerHour: nonNegativeIntFromEnv("SYNC_INIT_MAX_SESSIONS_PER_HOUR", defaultSyncInitMaxSessionsPerHour),
+		PerDay:  nonNegativeIntFromEnv("SYNC_INIT_MAX_SESSIONS_PER_DAY", defaultSyncInitMaxSessionsPerDay),
+	}
+}
+
+// admitSyncSession applies the API key's session velocity limits to a
+// sync/init call. Resuming a session that already exists is always allowed
+// and not counted; only a call that would create one is. On refusal it writes
+// a 429 with code session_velocity_exceeded and a Retry-After header and
+// returns false.
+func (s *Server) admitSyncSession(w http.ResponseWriter, r *http.Request, userID int64, externalID, provider string, explicit bool) bool {
+	keyID, ok := auth.GetAPIKeyID(r.Context())
+	if !ok || !s.syncInitVelocity.Enabled() {
+		return true
+	}
+	log := logger.Ctx(r.Context())
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	exists, err := (&dbsession.Store{DB: s.db}).SyncSessionExists(ctx, userID, externalID, provider, explicit)
+	if err != nil {
+		log.Error("Failed to look up sync session", "error", err, "external_id", externalID)
+		respondError(w, http.StatusInternalServerError, "Failed to initialize sync session")
+		return false
+	}
+	if exists {
+		return true
+	}
+
+	decision, err := (&dbvelocity.Store{DB: s.db}).Admit(ctx, keyID, s.syncInitVelocity)
+	if err != nil {
+		log.Error("Failed to check session velocity", "error", err, "api_key_id", keyID)
+		respondError(w, http.StatusInternalServerError, "Failed to initialize sync session")
+		return false
+	}
+	if decision.Allowed {
+		return true
+	}
+
+	log.Warn("Session velocity limit exceeded",
+		"api_key_id", keyID,
+		"window", string(decision.Window),
+		"count", decision.Count,
+		"limit", decision.Limit,
+		"rejected_this_hour", decision.Rejected)
+	if decision.Rejected == velocityAlertRejections {
+		log.Warn("SECURITY_EVENT",
+			"security", true, // marker for filtering security events
+			"event", "session_velocity_tripped",
+			"api_key_id", keyID,
+			"window", string(decision.Window),
+			"limit", decision.Limit,
+			"rejected_this_hour", decision.Rejected)
+		s.notifyVelocityTripped(ctx, userID, keyID, decision)
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(decision.RetryAfter.Seconds()))))
+	respondErrorCode(w, http.StatusTooManyRequests, httputil.CodeSessionVelocityExceeded,
+		fmt.Sprintf("This API key created %d new sessions in the last %s (limit %d); resuming existing sessions still works",
+			decision.Count, decision.Window, decision.Limit))
+	return false
+}
+
+// notifyVelocityTripped tells the key's owner that it tripped the velocity
+// alert, through the notification dispatcher. The type is urgent, so it goes
+// out during quiet hours too. A failure is only logged: the caller still gets
+// its 429.
+func (s *Server) notifyVelocityTripped(ctx context.Context, userID, keyID int64, decision dbvelocity.Decision) {
+	if s.notifier == nil {
+		return
+	}
+	var keysURL string
+	if s.frontendURL != "" {
+		keysURL = strings.TrimSuffix(s.frontendURL, "/") + "/keys"
+	}
+	err := s.notifier.Notify(ctx, notify.Notification{
+		UserID:  userID,
+		Type:    dbwebhook.EventAPIKeyVelocityExceeded,
+		Subject: "One of your Confabulous API keys is over its session limit",
+		Body: fmt.Sprintf("Your API key %d has been refused %d new sessions in the last hour: it created %d sessions in the last %s, over its limit of %d. "+
+			"Resuming existing sessions still works. If you don't recognize this activity, revoke the key.",
+			keyID, decision.Rejected, decision.Count, decision.Window, decision.Limit),
+		URL: keysURL,
+		Data: map[string]any{
+			"api_key_id":         keyID,
+			"window":             string(decision.Window),
+			"count":              decision.Count,
+			"limit":              decision.Limit,
+			"rejected_this_hour": decision.Rejected,
+		},
+	})
+	if err != nil {
+		logger.Ctx(ctx).Error("Failed to send velocity notification", "error", err, "api_key_id", keyID)
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleGetTokenSeries returns cumulative token usage sampled across a
+// session's transcript, for the dashboard's token sparkline. Uses the same
+// canonical access model as HandleGetSessionAnalytics (CF-132).
+//
+// The series is written when the session's cards are computed (precompute
+// worker or an analytics fetch); a session whose cards were never computed,
+// or whose provider has no series, returns no points.
+func HandleGetTokenSeries(database *db.DB) http.HandlerFunc {
+	analyticsStore := analytics.NewStore(database.Conn()).WithReplica(database)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		if result := RequireCanonicalRead(ctx, w, database, sessionID); result == nil {
+			return
+		}
+
+		series, err := analyticsStore.GetTokenSeries(ctx, sessionID)
+		if err != nil {
+			log.Error("Failed to get token series", "error", err, "session_id", sessionID)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to get token series")
+			return
+		}
+		if series == nil {
+			series = &analytics.TokenSeries{Points: []analytics.TokenSeriesPoint{}}
+		}
+		respondJSON(w, http.StatusOK, series)
+	}
+}
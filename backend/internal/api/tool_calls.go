@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// Tool call pagination bounds.
+const (
+	DefaultToolCallsLimit = 100
+	MaxToolCallsLimit     = 1000
+)
+
+// ToolCallsResponse is one page of a session's tool calls.
+type ToolCallsResponse struct {
+	ToolCalls  []analytics.ToolCall `json:"tool_calls"`
+	TotalCalls int                  `json:"total_calls"`
+	HasMore    bool                 `json:"has_more"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// parseToolCallsQuery parses the limit, cursor and redact query parameters.
+// The cursor is the call_index of the last call on the previous page; an
+// absent cursor starts from the first call (-1).
+func parseToolCallsQuery(r *http.Request) (afterIndex, limit int, redact bool, err error) {
+	q := r.URL.Query()
+	afterIndex = -1
+	limit = DefaultToolCallsLimit
+
+	if s := q.Get("limit"); s != "" {
+		v, perr := strconv.Atoi(s)
+		if perr != nil || v < 1 || v > MaxToolCallsLimit {
+			return 0, 0, false, fmt.Errorf("limit must be between 1 and %d", MaxToolCallsLimit)
+		}
+		limit = v
+	}
+	if s := q.Get("cursor"); s != "" {
+		v, perr := strconv.Atoi(s)
+		if perr != nil || v < 0 {
+			return 0, 0, false, errors.New("invalid cursor")
+		}
+		afterIndex = v
+	}
+	if s := q.Get("redact"); s != "" {
+		v, perr := strconv.ParseBool(s)
+		if perr != nil {
+			return 0, 0, false, errors.New("redact must be true or false")
+		}
+		redact = v
+	}
+	return afterIndex, limit, redact, nil
+}
+
+// HandleListToolCalls returns a session's individual tool calls with their
+// arguments, result status and line, parsed from the transcript on each
+// request and paginated by call index. Owner-only. redact=true obfuscates
+// every string argument (analytics.RedactToolArguments). Only providers
+// implementing analytics.ToolCallLister are supported; others get 400.
+func HandleListToolCalls(database *db.DB, store *storage.S3Storage) http.HandlerFunc {
+	sessionStore := &dbsession.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		afterIndex, limit, redact, perr := parseToolCallsQuery(r)
+		if perr != nil {
+			respondError(w, http.StatusBadRequest, perr.Error())
+			return
+		}
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		dbCtx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		sessionUserID, externalID, sessionProvider, err := sessionStore.GetSessionOwnerExternalIDAndProvider(dbCtx, sessionID)
+		if err != nil {
+			log.Error("Failed to get session", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusNotFound, "Session not found")
+			return
+		}
+
+		if sessionUserID != userID {
+			respondError(w, http.StatusForbidden, "Only the session owner can list tool calls")
+			return
+		}
+
+		session, err := sessionStore.GetSessionDetail(dbCtx, sessionID, userID)
+		if err != nil {
+			log.Error("Failed to get session detail", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to get session")
+			return
+		}
+		if session.TranscriptArchivedAt != nil {
+			respondTranscriptArchived(w)
+			return
+		}
+
+		sp, err := analytics.ProviderFor(sessionProvider)
+		if err != nil {
+			log.Error("provider lookup failed for tool calls", "error", err, "session_id", sessionID, "provider", sessionProvider)
+			respondError(w, http.StatusInternalServerError, "unsupported provider")
+			return
+		}
+		lister, ok := sp.(analytics.ToolCallLister)
+		if !ok {
+			respondError(w, http.StatusBadRequest, "Tool calls are not available for "+sp.DisplayName()+" sessions")
+			return
+		}
+
+		rollout, err := sp.Parse(r.Context(), providerParseInput(database, store, sessionID, sessionUserID, sessionProvider, externalID))
+		if err != nil {
+			log.Error("Failed to parse session for tool calls", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to download transcript")
+			return
+		}
+		if rollout == nil {
+			respondError(w, http.StatusNotFound, "No transcript available for this session")
+			return
+		}
+
+		calls, err := lister.ToolCalls(r.Context(), rollout)
+		if err != nil {
+			log.Error("Failed to list tool calls", "error", err, "session_id", sessionID)
+			respondError(w, http.StatusInternalServerError, "Failed to list tool calls")
+			return
+		}
+
+		resp := ToolCallsResponse{ToolCalls: []analytics.ToolCall{}, TotalCalls: len(calls)}
+		if start := afterIndex + 1; start < len(calls) {
+			end := min(start+limit, len(calls))
+			resp.ToolCalls = calls[start:end]
+			if end < len(calls) {
+				resp.HasMore = true
+				resp.NextCursor = strconv.Itoa(resp.ToolCalls[len(resp.ToolCalls)-1].CallIndex)
+			}
+		}
+		if redact {
+			for i := range resp.ToolCalls {
+				resp.ToolCalls[i].Arguments = analytics.RedactToolArguments(resp.ToolCalls[i].Arguments)
+			}
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
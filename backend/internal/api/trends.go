@@ -1,13 +1,19 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/shopspring/decimal"
+
 	"github.com/ConfabulousDev/confab-web/internal/analytics"
 	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbreconciliation"
 	"github.com/ConfabulousDev/confab-web/internal/logger"
 	"github.com/ConfabulousDev/confab-web/internal/validation"
 )
@@ -87,12 +93,16 @@ func parseDateRangeParams(w http.ResponseWriter, r *http.Request) *dateRangePara
 //     Case-insensitive. Privacy invariant: narrows within the visible set;
 //     cannot broaden access to sessions the caller couldn't already see via
 //     /api/v1/sessions. Omitted/empty = aggregate across all visible owners.
+//   - apply_correction: "true" adds a cost_correction block with the claude-code
+//     cost scaled by the operator-set monthly correction factors (see the admin
+//     reconciliation API). The cards keep their estimates.
 //
 // Response includes a `filter_options.{owners,repos}` block mirroring the
 // SessionFilterOptions shape on /api/v1/sessions — static across active
 // filter changes, derived from the visible-session set.
 func HandleGetTrends(database *db.DB) http.HandlerFunc {
-	analyticsStore := analytics.NewStore(database.Conn())
+	analyticsStore := analytics.NewStore(database.Conn()).WithReplica(database)
+	reconciliationStore := &dbreconciliation.Store{DB: database}
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := logger.Ctx(r.Context())
@@ -173,10 +183,51 @@ func HandleGetTrends(database *db.DB) http.HandlerFunc {
 		response, err := analyticsStore.GetTrends(r.Context(), userID, req)
 		if err != nil {
 			log.Error("Failed to get trends", "error", err, "user_id", userID)
+			if errors.Is(err, db.ErrQueryTimeout) {
+				respondQueryTimeout(w)
+				return
+			}
 			respondError(w, http.StatusInternalServerError, "Failed to compute trends")
 			return
 		}
 
+		if r.URL.Query().Get("apply_correction") == "true" {
+			dbCtx, dbCancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+			factors, err := correctionFactorsFor(dbCtx, reconciliationStore, response.DateRange)
+			dbCancel()
+			if err != nil {
+				log.Error("Failed to load cost correction factors", "error", err)
+				respondError(w, http.StatusInternalServerError, "Failed to load cost correction factors")
+				return
+			}
+			response.ApplyCostCorrection(factors)
+		}
+
 		respondJSON(w, http.StatusOK, response)
 	}
 }
+
+// correctionFactorsFor returns the cost correction factors, keyed by "YYYY-MM",
+// of every month that overlaps dr.
+func correctionFactorsFor(ctx context.Context, store *dbreconciliation.Store, dr analytics.DateRange) (map[string]decimal.Decimal, error) {
+	start, err := time.Parse(time.DateOnly, dr.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("parse start date: %w", err)
+	}
+	end, err := time.Parse(time.DateOnly, dr.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("parse end date: %w", err)
+	}
+	from := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(end.Year(), end.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+
+	rows, err := store.CorrectionFactors(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	factors := make(map[string]decimal.Decimal, len(rows))
+	for _, cf := range rows {
+		factors[cf.Month.Format("2006-01")] = cf.Factor
+	}
+	return factors, nil
+}
@@ -2,12 +2,16 @@ package api
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/ConfabulousDev/confab-web/internal/admin"
 	dbuser "github.com/ConfabulousDev/confab-web/internal/db/user"
+	"github.com/ConfabulousDev/confab-web/internal/httputil"
 	"github.com/ConfabulousDev/confab-web/internal/logger"
 	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/notify"
 )
 
 // MeResponse extends the User model with onboarding status fields
@@ -16,6 +20,21 @@ type MeResponse struct {
 	HasOwnSessions bool `json:"has_own_sessions"`
 	HasAPIKeys     bool `json:"has_api_keys"`
 	IsAdmin        bool `json:"is_admin"`
+	UserSettings
+}
+
+// UserSettings are the caller's own preferences, returned inline on
+// GET /api/v1/me and by PATCH /api/v1/me/settings.
+type UserSettings struct {
+	// IncludeAgentFilesInSearch indexes subagent output (agent files) for
+	// search in addition to the main transcript.
+	IncludeAgentFilesInSearch bool `json:"include_agent_files_in_search"`
+}
+
+// UpdateUserSettingsRequest is the body of PATCH /api/v1/me/settings.
+// Omitted fields are left unchanged.
+type UpdateUserSettingsRequest struct {
+	IncludeAgentFilesInSearch *bool `json:"include_agent_files_in_search"`
 }
 
 // handleGetMe returns the current authenticated user's info
@@ -52,6 +71,13 @@ func (s *Server) handleGetMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	includeAgentFiles, err := userStore.GetIncludeAgentFilesInSearch(ctx, userID)
+	if err != nil {
+		log.Error("Failed to get user settings", "error", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get user")
+		return
+	}
+
 	respondJSON(w, http.StatusOK, MeResponse{
 		User:           *user,
 		HasOwnSessions: hasOwnSessions,
@@ -59,6 +85,112 @@ func (s *Server) handleGetMe(w http.ResponseWriter, r *http.Request) {
 		// Union: env super-admin OR the users.is_admin column (5k4v). Set on the
 		// outer MeResponse field, which JSON-shadows the embedded User.IsAdmin
 		// (`json:"-"`).
-		IsAdmin: admin.IsSuperAdmin(user.Email) || user.IsAdmin,
+		IsAdmin:      admin.IsSuperAdmin(user.Email) || user.IsAdmin,
+		UserSettings: UserSettings{IncludeAgentFilesInSearch: includeAgentFiles},
 	})
 }
+
+// handleUpdateMySettings updates the current user's preferences and returns
+// the resulting settings. Changing include_agent_files_in_search marks all of
+// the user's search indexes stale; the worker rebuilds them in the background.
+func (s *Server) handleUpdateMySettings(w http.ResponseWriter, r *http.Request) {
+	log := logger.Ctx(r.Context())
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateUserSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	userStore := &dbuser.Store{DB: s.db}
+	if req.IncludeAgentFilesInSearch != nil {
+		if err := userStore.SetIncludeAgentFilesInSearch(ctx, userID, *req.IncludeAgentFilesInSearch); err != nil {
+			log.Error("Failed to update user settings", "error", err)
+			respondError(w, http.StatusInternalServerError, "Failed to update settings")
+			return
+		}
+	}
+
+	includeAgentFiles, err := userStore.GetIncludeAgentFilesInSearch(ctx, userID)
+	if err != nil {
+		log.Error("Failed to get user settings", "error", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get settings")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, UserSettings{IncludeAgentFilesInSearch: includeAgentFiles})
+}
+
+// handleGetMyPreferences returns the current user's preferences, with the
+// defaults for any the user never set. notifications lists every
+// notification type, resolved by notify.ResolveChannels.
+func (s *Server) handleGetMyPreferences(w http.ResponseWriter, r *http.Request) {
+	log := logger.Ctx(r.Context())
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	prefs, err := (&dbuser.Store{DB: s.db}).GetPreferences(ctx, userID)
+	if err != nil {
+		log.Error("Failed to get user preferences", "error", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get preferences")
+		return
+	}
+
+	prefs.Notifications = notify.ResolveChannels(prefs)
+	respondJSON(w, http.StatusOK, prefs)
+}
+
+// handleUpdateMyPreferences applies a partial update to the current user's
+// preferences and returns all of them. Omitted fields are left unchanged.
+func (s *Server) handleUpdateMyPreferences(w http.ResponseWriter, r *http.Request) {
+	log := logger.Ctx(r.Context())
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req dbuser.PreferencesUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeInvalidRequestBody, "Invalid request body")
+		return
+	}
+	if err := req.Normalize(); err != nil {
+		respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed, err.Error())
+		return
+	}
+	for name := range req.Notifications {
+		if _, ok := notify.LookupType(name); !ok {
+			respondErrorCode(w, http.StatusBadRequest, httputil.CodeValidationFailed,
+				fmt.Sprintf("notifications: unknown notification type %q", name))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+	defer cancel()
+
+	prefs, err := (&dbuser.Store{DB: s.db}).UpdatePreferences(ctx, userID, req)
+	if err != nil {
+		log.Error("Failed to update user preferences", "error", err)
+		respondError(w, http.StatusInternalServerError, "Failed to update preferences")
+		return
+	}
+
+	prefs.Notifications = notify.ResolveChannels(prefs)
+	respondJSON(w, http.StatusOK, prefs)
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbwebhook"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/validation"
+	"github.com/ConfabulousDev/confab-web/internal/webhooks"
+)
+
+// webhookEvents lists the event types an endpoint can subscribe to.
+var webhookEvents = []string{dbwebhook.EventSessionCreated, dbwebhook.EventAPIKeyVelocityExceeded}
+
+// CreateWebhookRequest is the request body for registering a webhook endpoint.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"` // Defaults to every event type
+}
+
+// WebhookResponse is a registered webhook endpoint. Secret is only set in the
+// response to creating it.
+type WebhookResponse struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HandleCreateWebhook registers a webhook endpoint for the authenticated user
+// and returns its signing secret, which is not shown again.
+func HandleCreateWebhook(database *db.DB) http.HandlerFunc {
+	store := &dbwebhook.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		var req CreateWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := validation.ValidateWebhookURL(req.URL); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(req.Events) == 0 {
+			req.Events = webhookEvents
+		}
+		for _, event := range req.Events {
+			if !slices.Contains(webhookEvents, event) {
+				respondError(w, http.StatusBadRequest, fmt.Sprintf("Unknown event type %q", event))
+				return
+			}
+		}
+
+		secret, err := webhooks.NewSecret()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to generate webhook secret")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		endpoint, err := store.CreateEndpoint(ctx, userID, req.URL, secret, req.Events)
+		if err != nil {
+			if errors.Is(err, dbwebhook.ErrEndpointLimitExceeded) {
+				respondError(w, http.StatusConflict, fmt.Sprintf("Webhook limit reached. You can register at most %d webhooks.", dbwebhook.MaxEndpointsPerUser))
+				return
+			}
+			log.Error("Failed to create webhook", "error", err)
+			respondError(w, http.StatusInternalServerError, "Failed to create webhook")
+			return
+		}
+
+		// Audit log: webhook created (the URL may carry a token, so it's not logged)
+		log.Info("Webhook created", "webhook_id", endpoint.ID, "events", endpoint.Events)
+
+		resp := newWebhookResponse(endpoint)
+		resp.Secret = endpoint.Secret
+		respondJSON(w, http.StatusCreated, resp)
+	}
+}
+
+// HandleListWebhooks lists the authenticated user's webhook endpoints,
+// without their secrets.
+func HandleListWebhooks(database *db.DB) http.HandlerFunc {
+	store := &dbwebhook.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		endpoints, err := store.ListEndpoints(ctx, userID)
+		if err != nil {
+			log.Error("Failed to list webhooks", "error", err)
+			respondError(w, http.StatusInternalServerError, "Failed to list webhooks")
+			return
+		}
+
+		resp := make([]WebhookResponse, 0, len(endpoints))
+		for i := range endpoints {
+			resp = append(resp, newWebhookResponse(&endpoints[i]))
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// HandleDeleteWebhook deletes a webhook endpoint and its pending deliveries.
+func HandleDeleteWebhook(database *db.DB) http.HandlerFunc {
+	store := &dbwebhook.Store{DB: database}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.Ctx(r.Context())
+
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid webhook ID")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), DatabaseTimeout)
+		defer cancel()
+
+		deleted, err := store.DeleteEndpoint(ctx, userID, id)
+		if err != nil {
+			log.Error("Failed to delete webhook", "error", err, "webhook_id", id)
+			respondError(w, http.StatusInternalServerError, "Failed to delete webhook")
+			return
+		}
+		if !deleted {
+			respondError(w, http.StatusNotFound, "Webhook not found")
+			return
+		}
+
+		// Audit log: webhook deleted
+		log.Info("Webhook deleted", "webhook_id", id)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newWebhookResponse(e *dbwebhook.Endpoint) WebhookResponse {
+	return WebhookResponse{
+		ID:        e.ID,
+		URL:       e.URL,
+		Events:    e.Events,
+		CreatedAt: e.CreatedAt,
+	}
+}
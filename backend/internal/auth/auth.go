@@ -20,7 +20,10 @@ import (
 
 type contextKey string
 
-const userIDContextKey contextKey = "userID"
+const (
+	userIDContextKey   contextKey = "userID"
+	apiKeyIDContextKey contextKey = "apiKeyID"
+)
 
 // GetUserIDContextKey returns the context key for user ID
 func GetUserIDContextKey() contextKey {
@@ -72,6 +75,7 @@ type apiKeyAuthResult struct {
 	userID       int64
 	userEmail    string
 	userReadOnly bool // CF-483: stashed in request ctx for EnforceReadOnly
+	userTier     models.RateLimitTier
 }
 
 // TryAPIKeyAuth attempts to authenticate using an API key from the Authorization header.
@@ -95,7 +99,7 @@ func TryAPIKeyAuth(r *http.Request, database *db.DB) *apiKeyAuthResult {
 	authStore := &dbauth.Store{DB: database}
 
 	// Validate key in database
-	userID, keyID, userEmail, userStatus, userReadOnly, err := authStore.ValidateAPIKey(r.Context(), keyHash)
+	userID, keyID, userEmail, userStatus, userReadOnly, userTier, err := authStore.ValidateAPIKey(r.Context(), keyHash)
 	if err != nil {
 		log := logger.Ctx(r.Context())
 		log.Warn("API key validation failed",
@@ -121,7 +125,7 @@ func TryAPIKeyAuth(r *http.Request, database *db.DB) *apiKeyAuthResult {
 		}
 	}()
 
-	return &apiKeyAuthResult{userID: userID, userEmail: userEmail, userReadOnly: userReadOnly}
+	return &apiKeyAuthResult{userID: userID, userEmail: userEmail, userReadOnly: userReadOnly, userTier: userTier}
 }
 
 // RequireAPIKey returns an HTTP middleware that requires API key authentication.
@@ -154,7 +158,7 @@ func RequireAPIKey(database *db.DB, allowedDomains []string) func(http.Handler)
 			keyHash := HashAPIKey(rawKey)
 
 			// Validate key in database
-			userID, keyID, userEmail, userStatus, userReadOnly, err := authStore.ValidateAPIKey(r.Context(), keyHash)
+			userID, keyID, userEmail, userStatus, userReadOnly, userTier, err := authStore.ValidateAPIKey(r.Context(), keyHash)
 			if err != nil {
 				log := logger.Ctx(r.Context())
 				log.Warn("API key validation failed",
@@ -198,9 +202,11 @@ func RequireAPIKey(database *db.DB, allowedDomains []string) func(http.Handler)
 			// Enrich OpenTelemetry span with user info
 			enrichSpanWithUser(ctx, userID, userEmail, true, false)
 
-			// Add user ID + read-only flag (CF-483) to request context
+			// Add user ID, key ID, read-only flag (CF-483) + rate limit tier to request context
 			ctx = context.WithValue(ctx, userIDContextKey, userID)
+			ctx = context.WithValue(ctx, apiKeyIDContextKey, keyID)
 			ctx = WithReadOnly(ctx, userReadOnly)
+			ctx = WithRateLimitTier(ctx, userTier)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -212,6 +218,13 @@ func GetUserID(ctx context.Context) (int64, bool) {
 	return userID, ok
 }
 
+// GetAPIKeyID extracts the ID of the API key that authenticated the request.
+// Only RequireAPIKey sets it; requests authenticated any other way report false.
+func GetAPIKeyID(ctx context.Context) (int64, bool) {
+	keyID, ok := ctx.Value(apiKeyIDContextKey).(int64)
+	return keyID, ok
+}
+
 // setLogUserID sets the user ID on the logger's response writer.
 // It unwraps the ResponseWriter chain to find the LogUserIDSetter.
 func setLogUserID(w http.ResponseWriter, userID int64) {
@@ -175,6 +175,7 @@ func AutoImpersonateIfDemo(w http.ResponseWriter, r *http.Request, database *db.
 		userID:       session.UserID,
 		userEmail:    session.UserEmail,
 		userReadOnly: session.ReadOnly,
+		userTier:     session.RateLimitTier,
 	}
 }
 
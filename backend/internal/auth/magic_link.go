@@ -0,0 +1,306 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbauth"
+	"github.com/ConfabulousDev/confab-web/internal/email"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/validation"
+)
+
+// MagicLinkTTL is how long an emailed login link stays valid.
+const MagicLinkTTL = 15 * time.Minute
+
+// Sentinel errors from verifyMagicLinkToken and redemption. All map to a
+// login-page redirect; expired and already-used links get their own copy so
+// users know to request a new one.
+var (
+	errMagicLinkInvalid = errors.New("invalid magic link token")
+	errMagicLinkExpired = errors.New("magic link expired")
+	errMagicLinkUsed    = errors.New("magic link already used")
+)
+
+// magicLinkClaims is what a verified token carries. ID is a random nonce
+// that, once redeemed, can't sign in again.
+type magicLinkClaims struct {
+	ID        string
+	Email     string
+	ExpiresAt time.Time
+}
+
+// MagicLinkSender sends login link emails, rate-limited per account.
+// *email.RateLimitedService satisfies it.
+type MagicLinkSender interface {
+	SendMagicLink(ctx context.Context, userID int64, params email.MagicLinkParams) error
+}
+
+// EmailLoginRequest is the body of POST /api/v1/auth/email.
+type EmailLoginRequest struct {
+	Email string `json:"email"`
+}
+
+// signMagicLinkToken returns a login token for email:
+// base64url("<expires_unix>|<id>|<email>") + "." + base64url(HMAC-SHA256(secret,
+// "magic-link:" + payload)). The "magic-link:" prefix keeps these MACs
+// disjoint from other uses of the same secret (DemoSessionCookieID). id is a
+// random nonce (no "|") that magic_link_redemptions records on first use.
+func signMagicLinkToken(secret, id, email string, expiresAt time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(expiresAt.Unix(), 10) + "|" + id + "|" + email))
+	return payload + "." + magicLinkMAC(secret, payload)
+}
+
+func magicLinkMAC(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("magic-link:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyMagicLinkToken checks the token's signature and expiry and returns its
+// claims. The MAC is compared in constant time before the payload is trusted.
+// Whether the token was already used is checked separately, against the
+// database, once the rest of the login has passed.
+func verifyMagicLinkToken(secret, token string, now time.Time) (magicLinkClaims, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || payload == "" || sig == "" {
+		return magicLinkClaims{}, errMagicLinkInvalid
+	}
+	if !hmac.Equal([]byte(sig), []byte(magicLinkMAC(secret, payload))) {
+		return magicLinkClaims{}, errMagicLinkInvalid
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return magicLinkClaims{}, errMagicLinkInvalid
+	}
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) != 3 || parts[1] == "" {
+		return magicLinkClaims{}, errMagicLinkInvalid
+	}
+	expiresUnix, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return magicLinkClaims{}, errMagicLinkInvalid
+	}
+	expiresAt := time.Unix(expiresUnix, 0)
+	if !now.Before(expiresAt) {
+		return magicLinkClaims{}, errMagicLinkExpired
+	}
+	return magicLinkClaims{ID: parts[1], Email: parts[2], ExpiresAt: expiresAt}, nil
+}
+
+// writeEmailLoginJSON writes a small JSON body for the magic-link request endpoint.
+func writeEmailLoginJSON(w http.ResponseWriter, statusCode int, body map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
+
+// HandleEmailLoginRequest handles POST /api/v1/auth/email: it emails a signed,
+// short-lived login link to an existing, active account whose email passes the
+// ALLOWED_EMAIL_DOMAINS allow-list.
+//
+// The response is the same 202 whether or not a link was sent (unknown email,
+// disallowed domain, inactive account, or per-account email rate limit), so the
+// endpoint cannot be used to enumerate accounts. Only malformed input gets a 400.
+// Magic links sign in to existing accounts only; they never create users.
+func HandleEmailLoginRequest(database *db.DB, config *OAuthConfig, sender MagicLinkSender, backendURL string) http.HandlerFunc {
+	authStore := &dbauth.Store{DB: database}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := logger.Ctx(ctx)
+
+		var req EmailLoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeEmailLoginJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+			return
+		}
+		emailAddr := validation.NormalizeEmail(req.Email)
+		if !validation.IsValidEmail(emailAddr) {
+			writeEmailLoginJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid email address"})
+			return
+		}
+
+		accepted := func() {
+			writeEmailLoginJSON(w, http.StatusAccepted, map[string]string{"status": "sent"})
+		}
+
+		if IsDemoLoginEmail(config.DemoIdentityEmail, emailAddr) {
+			log.Warn("magic link requested for demo identity", "email", emailAddr)
+			accepted()
+			return
+		}
+		// Same allow-list + user cap the OAuth callbacks enforce.
+		if err := checkUserEligibility(ctx, database, emailAddr, config.AllowedEmailDomains); err != nil {
+			log.Warn("magic link requested for ineligible email", "email", emailAddr, "error", err)
+			accepted()
+			return
+		}
+
+		user, err := authStore.GetUserByEmail(ctx, emailAddr)
+		if err != nil {
+			if errors.Is(err, db.ErrUserNotFound) {
+				log.Info("magic link requested for unknown email", "email", emailAddr)
+			} else {
+				log.Error("Failed to look up user for magic link", "error", err, "email", emailAddr)
+			}
+			accepted()
+			return
+		}
+		if user.Status == models.UserStatusInactive {
+			log.Warn("magic link requested for inactive user", "email", emailAddr)
+			accepted()
+			return
+		}
+
+		tokenID, err := generateRandomString(22)
+		if err != nil {
+			log.Error("Failed to generate magic link ID", "error", err)
+			accepted()
+			return
+		}
+		expiresAt := time.Now().UTC().Add(MagicLinkTTL)
+		token := signMagicLinkToken(config.CSRFSecretKey, tokenID, user.Email, expiresAt)
+		params := email.MagicLinkParams{
+			ToEmail:   user.Email,
+			LoginURL:  backendURL + "/api/v1/auth/email/verify?token=" + url.QueryEscape(token),
+			ExpiresAt: expiresAt,
+		}
+		if err := sender.SendMagicLink(ctx, user.ID, params); err != nil {
+			if errors.Is(err, email.ErrRateLimitExceeded) {
+				log.Warn("magic link email rate limit exceeded", "user_id", user.ID)
+			} else {
+				log.Error("Failed to send magic link", "error", err, "user_id", user.ID)
+			}
+			accepted()
+			return
+		}
+
+		log.Info("magic link sent", "user_id", user.ID)
+		accepted()
+	}
+}
+
+// HandleEmailLoginVerify handles GET /api/v1/auth/email/verify?token=. A valid,
+// unexpired, unused token for an allowed, active account gets a web session and
+// the standard post-login redirect; every failure redirects to the login page.
+// The token is redeemed just before the session is created, so a link that
+// fails a check can still be used once the problem is fixed, but a link that
+// signed in once never works again.
+//
+// Eligibility is re-checked here rather than trusted from issue time, so an
+// ALLOWED_EMAIL_DOMAINS change or deactivation takes effect on links already
+// in flight.
+func HandleEmailLoginVerify(database *db.DB, config *OAuthConfig) http.HandlerFunc {
+	authStore := &dbauth.Store{DB: database}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := logger.Ctx(ctx)
+		frontendURL := os.Getenv("FRONTEND_URL")
+
+		claims, err := verifyMagicLinkToken(config.CSRFSecretKey, r.URL.Query().Get("token"), time.Now())
+		if err != nil {
+			redirectMagicLinkRejected(w, r, frontendURL, err)
+			return
+		}
+		emailAddr := claims.Email
+
+		if IsDemoLoginEmail(config.DemoIdentityEmail, emailAddr) {
+			log.Warn("magic link login attempt for demo identity rejected", "email", emailAddr)
+			redirectDemoLoginRejected(w, r, frontendURL)
+			return
+		}
+
+		// Check email domain restriction + user cap (shared with OAuth).
+		if err := checkUserEligibility(ctx, database, emailAddr, config.AllowedEmailDomains); err != nil {
+			redirectUserIneligible(w, r, frontendURL, "email", emailAddr, err)
+			return
+		}
+
+		user, err := authStore.GetUserByEmail(ctx, emailAddr)
+		if err != nil {
+			if errors.Is(err, db.ErrUserNotFound) {
+				log.Warn("magic link for unknown user", "email", emailAddr)
+				http.Redirect(w, r, fmt.Sprintf("%s/login?error=invalid_link&error_description=%s",
+					frontendURL, url.QueryEscape("This sign-in link is invalid. Please request a new one.")), http.StatusTemporaryRedirect)
+				return
+			}
+			log.Error("Failed to look up user for magic link", "error", err, "email", emailAddr)
+			http.Error(w, "Failed to look up user", http.StatusInternalServerError)
+			return
+		}
+
+		// w8tz: reject deactivated accounts BEFORE minting a session.
+		if user.Status == models.UserStatusInactive {
+			log.Warn("magic link login blocked for inactive user", "email", emailAddr)
+			redirectInactiveUser(w, r, frontendURL)
+			return
+		}
+
+		first, err := authStore.RedeemMagicLink(ctx, claims.ID, claims.ExpiresAt)
+		if err != nil {
+			log.Error("Failed to redeem magic link", "error", err, "user_id", user.ID)
+			http.Error(w, "Failed to redeem sign-in link", http.StatusInternalServerError)
+			return
+		}
+		if !first {
+			redirectMagicLinkRejected(w, r, frontendURL, errMagicLinkUsed)
+			return
+		}
+
+		// Create web session
+		sessionID, err := generateRandomString(32)
+		if err != nil {
+			http.Error(w, "Failed to create session", http.StatusInternalServerError)
+			return
+		}
+
+		expiresAt := time.Now().UTC().Add(SessionDuration)
+		if err := authStore.CreateWebSession(ctx, sessionID, user.ID, expiresAt); err != nil {
+			http.Error(w, "Failed to save session", http.StatusInternalServerError)
+			return
+		}
+
+		// Set session cookie
+		http.SetCookie(w, &http.Cookie{
+			Name:     SessionCookieName,
+			Value:    sessionID,
+			Path:     "/",
+			Expires:  expiresAt,
+			HttpOnly: true,
+			Secure:   cookieSecure(),
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		log.Info("Magic link login successful", "user_id", user.ID, "email", emailAddr)
+		handlePostLoginRedirect(w, r, frontendURL, user.Email, "", false)
+	}
+}
+
+// redirectMagicLinkRejected sends a rejected magic link back to the login page
+// with copy matching why it was rejected.
+func redirectMagicLinkRejected(w http.ResponseWriter, r *http.Request, frontendURL string, err error) {
+	errorCode, description := "invalid_link", "This sign-in link is invalid. Please request a new one."
+	switch {
+	case errors.Is(err, errMagicLinkExpired):
+		errorCode, description = "link_expired", "This sign-in link has expired. Please request a new one."
+	case errors.Is(err, errMagicLinkUsed):
+		errorCode, description = "link_used", "This sign-in link has already been used. Please request a new one."
+	}
+	logger.Ctx(r.Context()).Warn("Rejected magic link", "error", err)
+	http.Redirect(w, r, fmt.Sprintf("%s/login?error=%s&error_description=%s",
+		frontendURL, errorCode, url.QueryEscape(description)), http.StatusTemporaryRedirect)
+}
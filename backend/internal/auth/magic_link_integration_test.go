@@ -0,0 +1,143 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/auth"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbauth"
+	"github.com/ConfabulousDev/confab-web/internal/email"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// recordingMagicLinkSender captures magic-link emails so the test can follow
+// the link the way a user would.
+type recordingMagicLinkSender struct {
+	sent []email.MagicLinkParams
+}
+
+func (s *recordingMagicLinkSender) SendMagicLink(_ context.Context, _ int64, params email.MagicLinkParams) error {
+	s.sent = append(s.sent, params)
+	return nil
+}
+
+// TestEmailMagicLink_ValidLinkEstablishesSession walks the full flow: request
+// a link for an existing allow-listed account, follow the emailed URL, and get
+// a web session that validates against the database. Following it again is
+// rejected.
+func TestEmailMagicLink_ValidLinkEstablishesSession(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+	t.Setenv("FRONTEND_URL", "http://frontend.test")
+
+	user := testutil.CreateTestUser(t, env, "linkuser@company.com", "Link User")
+	config := &auth.OAuthConfig{
+		EmailLinkEnabled:    true,
+		CSRFSecretKey:       "test-csrf-secret-key-that-is-32-chars-long",
+		AllowedEmailDomains: []string{"company.com"},
+	}
+	sender := &recordingMagicLinkSender{}
+
+	req := httptest.NewRequest("POST", "/api/v1/auth/email", strings.NewReader(`{"email":"LinkUser@company.com"}`))
+	rec := httptest.NewRecorder()
+	auth.HandleEmailLoginRequest(env.DB, config, sender, "http://backend.test")(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("request status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(sender.sent))
+	}
+	if sender.sent[0].ToEmail != user.Email {
+		t.Errorf("ToEmail = %q, want %q", sender.sent[0].ToEmail, user.Email)
+	}
+
+	link, err := url.Parse(sender.sent[0].LoginURL)
+	if err != nil {
+		t.Fatalf("bad login URL: %v", err)
+	}
+	if link.Host != "backend.test" || link.Path != "/api/v1/auth/email/verify" {
+		t.Errorf("login URL = %s, want backend verify endpoint", link)
+	}
+
+	req = httptest.NewRequest("GET", link.RequestURI(), nil)
+	rec = httptest.NewRecorder()
+	auth.HandleEmailLoginVerify(env.DB, config)(rec, req)
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("verify status = %d, want %d", rec.Code, http.StatusTemporaryRedirect)
+	}
+	if loc := rec.Header().Get("Location"); strings.Contains(loc, "error=") {
+		t.Fatalf("verify redirected to error: %s", loc)
+	}
+
+	var sessionCookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == auth.SessionCookieName {
+			sessionCookie = c
+		}
+	}
+	if sessionCookie == nil {
+		t.Fatal("no session cookie set")
+	}
+
+	authStore := &dbauth.Store{DB: env.DB}
+	session, err := authStore.GetWebSession(env.Ctx, sessionCookie.Value, auth.DefaultSessionIdleTimeout)
+	if err != nil {
+		t.Fatalf("session not persisted: %v", err)
+	}
+	if session.UserID != user.ID {
+		t.Errorf("session user_id = %d, want %d", session.UserID, user.ID)
+	}
+
+	// The same link must not sign in a second time.
+	req = httptest.NewRequest("GET", link.RequestURI(), nil)
+	rec = httptest.NewRecorder()
+	auth.HandleEmailLoginVerify(env.DB, config)(rec, req)
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("reuse status = %d, want %d", rec.Code, http.StatusTemporaryRedirect)
+	}
+	loc, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("bad Location: %v", err)
+	}
+	if loc.Path != "/login" || loc.Query().Get("error") != "link_used" {
+		t.Errorf("reuse Location = %s, want /login?error=link_used", loc)
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == auth.SessionCookieName {
+			t.Error("session cookie set on a reused link")
+		}
+	}
+}
+
+// TestEmailMagicLink_UnknownEmailSendsNothing asserts magic links never create
+// accounts and the response does not reveal whether the account exists.
+func TestEmailMagicLink_UnknownEmailSendsNothing(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	config := &auth.OAuthConfig{EmailLinkEnabled: true, CSRFSecretKey: "test-csrf-secret-key-that-is-32-chars-long"}
+	sender := &recordingMagicLinkSender{}
+
+	req := httptest.NewRequest("POST", "/api/v1/auth/email", strings.NewReader(`{"email":"nobody@company.com"}`))
+	rec := httptest.NewRecorder()
+	auth.HandleEmailLoginRequest(env.DB, config, sender, "http://backend.test")(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("sent %d emails for an unknown address, want 0", len(sender.sent))
+	}
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/email"
+)
+
+const testMagicLinkSecret = "test-csrf-secret-key-that-is-32-chars-long"
+
+func TestMagicLinkToken_RoundTrip(t *testing.T) {
+	now := time.Now()
+	token := signMagicLinkToken(testMagicLinkSecret, "link-1", "user@example.com", now.Add(MagicLinkTTL))
+
+	got, err := verifyMagicLinkToken(testMagicLinkSecret, token, now)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if got.Email != "user@example.com" || got.ID != "link-1" {
+		t.Errorf("claims = %+v, want link-1 for user@example.com", got)
+	}
+	if !got.ExpiresAt.Equal(now.Add(MagicLinkTTL).Truncate(time.Second)) {
+		t.Errorf("ExpiresAt = %v, want %v", got.ExpiresAt, now.Add(MagicLinkTTL).Truncate(time.Second))
+	}
+}
+
+func TestMagicLinkToken_Expired(t *testing.T) {
+	now := time.Now()
+	token := signMagicLinkToken(testMagicLinkSecret, "link-1", "user@example.com", now.Add(-time.Second))
+
+	if _, err := verifyMagicLinkToken(testMagicLinkSecret, token, now); !errors.Is(err, errMagicLinkExpired) {
+		t.Errorf("err = %v, want errMagicLinkExpired", err)
+	}
+}
+
+func TestMagicLinkToken_Invalid(t *testing.T) {
+	now := time.Now()
+	token := signMagicLinkToken(testMagicLinkSecret, "link-1", "user@example.com", now.Add(MagicLinkTTL))
+	payload, sig, _ := strings.Cut(token, ".")
+	forged := signMagicLinkToken(testMagicLinkSecret, "link-1", "attacker@example.com", now.Add(MagicLinkTTL))
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+	// A pre-nonce "<expires>|<email>" token, correctly signed.
+	legacyPayload := base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(now.Add(MagicLinkTTL).Unix(), 10) + "|user@example.com"))
+	legacy := legacyPayload + "." + magicLinkMAC(testMagicLinkSecret, legacyPayload)
+
+	tests := []struct {
+		name   string
+		secret string
+		token  string
+	}{
+		{"empty", testMagicLinkSecret, ""},
+		{"no signature", testMagicLinkSecret, payload},
+		{"wrong secret", "a-different-secret-that-is-32-chars-long", token},
+		{"swapped payload", testMagicLinkSecret, forgedPayload + "." + sig},
+		{"truncated signature", testMagicLinkSecret, payload + "." + sig[:len(sig)-2]},
+		{"no link id", testMagicLinkSecret, legacy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifyMagicLinkToken(tt.secret, tt.token, now); !errors.Is(err, errMagicLinkInvalid) {
+				t.Errorf("err = %v, want errMagicLinkInvalid", err)
+			}
+		})
+	}
+}
+
+// fakeMagicLinkSender records sends instead of emailing.
+type fakeMagicLinkSender struct {
+	sent []email.MagicLinkParams
+}
+
+func (f *fakeMagicLinkSender) SendMagicLink(_ context.Context, _ int64, params email.MagicLinkParams) error {
+	f.sent = append(f.sent, params)
+	return nil
+}
+
+// The domain allow-list is checked before any database access, so these run
+// with a nil DB.
+
+func TestHandleEmailLoginRequest_DisallowedDomainSendsNothing(t *testing.T) {
+	config := &OAuthConfig{CSRFSecretKey: testMagicLinkSecret, AllowedEmailDomains: []string{"company.com"}}
+	sender := &fakeMagicLinkSender{}
+
+	req := httptest.NewRequest("POST", "/api/v1/auth/email", strings.NewReader(`{"email":"user@other.com"}`))
+	rec := httptest.NewRecorder()
+	HandleEmailLoginRequest(nil, config, sender, "http://backend.test")(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d (no enumeration)", rec.Code, http.StatusAccepted)
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("sent %d emails for a disallowed domain, want 0", len(sender.sent))
+	}
+}
+
+func TestHandleEmailLoginRequest_InvalidEmail(t *testing.T) {
+	config := &OAuthConfig{CSRFSecretKey: testMagicLinkSecret}
+
+	req := httptest.NewRequest("POST", "/api/v1/auth/email", strings.NewReader(`{"email":"not-an-email"}`))
+	rec := httptest.NewRecorder()
+	HandleEmailLoginRequest(nil, config, &fakeMagicLinkSender{}, "http://backend.test")(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleEmailLoginVerify_Rejections(t *testing.T) {
+	t.Setenv("FRONTEND_URL", "http://frontend.test")
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		token     string
+		wantError string
+	}{
+		{"expired link", signMagicLinkToken(testMagicLinkSecret, "link-1", "user@company.com", now.Add(-time.Minute)), "link_expired"},
+		{"tampered link", signMagicLinkToken("a-different-secret-that-is-32-chars-long", "link-1", "user@company.com", now.Add(MagicLinkTTL)), "invalid_link"},
+		{"disallowed email", signMagicLinkToken(testMagicLinkSecret, "link-1", "user@other.com", now.Add(MagicLinkTTL)), "access_denied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &OAuthConfig{CSRFSecretKey: testMagicLinkSecret, AllowedEmailDomains: []string{"company.com"}}
+			req := httptest.NewRequest("GET", "/api/v1/auth/email/verify?token="+url.QueryEscape(tt.token), nil)
+			rec := httptest.NewRecorder()
+			HandleEmailLoginVerify(nil, config)(rec, req)
+
+			if rec.Code != http.StatusTemporaryRedirect {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusTemporaryRedirect)
+			}
+			loc, err := url.Parse(rec.Header().Get("Location"))
+			if err != nil {
+				t.Fatalf("bad Location: %v", err)
+			}
+			if loc.Path != "/login" || loc.Query().Get("error") != tt.wantError {
+				t.Errorf("Location = %s, want /login?error=%s", loc, tt.wantError)
+			}
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == SessionCookieName {
+					t.Errorf("session cookie set on rejected link")
+				}
+			}
+		})
+	}
+}
@@ -217,7 +217,8 @@ var (
 )
 
 // checkUserEligibility performs the email-domain allow-list + user-cap checks
-// shared by every OAuth callback (GitHub/Google/OIDC). It returns:
+// shared by every login path that starts from an email (magic link, and the
+// OAuth callbacks via checkOAuthEligibility). It returns:
 //   - errEmailDomainNotPermitted if the email's domain is not allowed,
 //   - errUserCapReached if the user cap blocks a new login,
 //   - the underlying error from CanUserLogin if the eligibility check itself
@@ -240,6 +241,43 @@ func checkUserEligibility(ctx context.Context, database *db.DB, email string, al
 	return nil
 }
 
+// checkOAuthEligibility is checkUserEligibility for an OAuth callback. A login
+// whose provider identity is already linked is a returning user even when the
+// provider now reports a different email, so the user cap (which looks users
+// up by email) does not apply; the new email must still be valid and pass the
+// domain allow-list, since FindOrCreateUserByOAuth stores it.
+func checkOAuthEligibility(ctx context.Context, database *db.DB, info models.OAuthUserInfo, allowedDomains []string) error {
+	if !validation.IsAllowedEmailDomain(info.Email, allowedDomains) {
+		return errEmailDomainNotPermitted
+	}
+
+	if database != nil && validation.IsValidEmail(info.Email) {
+		authStore := &dbauth.Store{DB: database}
+		linked, err := authStore.IdentityExists(ctx, info.Provider, info.ProviderID)
+		if err != nil {
+			return err
+		}
+		if linked {
+			return nil
+		}
+	}
+
+	return checkUserEligibility(ctx, database, info.Email, allowedDomains)
+}
+
+// logEmailNotUpdated warns when a returning OAuth login's new provider email
+// was not stored because another account already holds it (see
+// FindOrCreateUserByOAuth). That account is usually a duplicate to merge.
+func logEmailNotUpdated(log *slog.Logger, dbUser *models.User, info models.OAuthUserInfo) {
+	if !strings.EqualFold(dbUser.Email, info.Email) {
+		log.Warn("OAuth email change not applied; another account holds the new email",
+			"user_id", dbUser.ID,
+			"stored_email", dbUser.Email,
+			"provider_email", info.Email,
+			"provider", string(info.Provider))
+	}
+}
+
 // redirectUserIneligible maps a checkUserEligibility error to the historical
 // per-provider log line + login-page redirect, then writes the redirect. It is
 // the caller's single response path when eligibility fails.
@@ -379,6 +417,10 @@ type OAuthConfig struct {
 	// Password authentication
 	PasswordEnabled bool
 
+	// Email magic-link login (AUTH_EMAIL_LINK_ENABLED). Signs in existing
+	// accounts only; requires the email service.
+	EmailLinkEnabled bool
+
 	// GitHub OAuth (optional)
 	GitHubEnabled      bool
 	GitHubClientID     string
@@ -480,6 +522,7 @@ type sessionAuthResult struct {
 	userID       int64
 	userEmail    string
 	userReadOnly bool // CF-483: stashed in request ctx for EnforceReadOnly
+	userTier     models.RateLimitTier
 }
 
 // TrySessionAuth attempts to authenticate using a session cookie.
@@ -511,7 +554,7 @@ func TrySessionAuth(r *http.Request, database *db.DB) *sessionAuthResult {
 		return nil
 	}
 
-	return &sessionAuthResult{userID: session.UserID, userEmail: session.UserEmail, userReadOnly: session.ReadOnly}
+	return &sessionAuthResult{userID: session.UserID, userEmail: session.UserEmail, userReadOnly: session.ReadOnly, userTier: session.RateLimitTier}
 }
 
 // RequireSession returns an HTTP middleware that requires session cookie authentication.
@@ -564,9 +607,10 @@ func RequireSession(database *db.DB, config *OAuthConfig) func(http.Handler) htt
 			// Enrich OpenTelemetry span with user info
 			enrichSpanWithUser(ctx, authResult.userID, authResult.userEmail, false, true)
 
-			// Add user ID + read-only flag (CF-483) to context
+			// Add user ID, read-only flag (CF-483) + rate limit tier to context
 			ctx = context.WithValue(ctx, userIDContextKey, authResult.userID)
 			ctx = WithReadOnly(ctx, authResult.userReadOnly)
+			ctx = WithRateLimitTier(ctx, authResult.userTier)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -587,6 +631,7 @@ func RequireSessionOrAPIKey(database *db.DB, config *OAuthConfig) func(http.Hand
 			var userID int64
 			var userEmail string
 			var userReadOnly bool
+			var userTier models.RateLimitTier
 			var authAPIKey, authSession bool
 
 			// Try session cookie first
@@ -594,17 +639,20 @@ func RequireSessionOrAPIKey(database *db.DB, config *OAuthConfig) func(http.Hand
 				userID = sessionAuth.userID
 				userEmail = sessionAuth.userEmail
 				userReadOnly = sessionAuth.userReadOnly
+				userTier = sessionAuth.userTier
 				authSession = true
 			} else if apiKeyAuth := TryAPIKeyAuth(r, database); apiKeyAuth != nil {
 				// Fall back to API key
 				userID = apiKeyAuth.userID
 				userEmail = apiKeyAuth.userEmail
 				userReadOnly = apiKeyAuth.userReadOnly
+				userTier = apiKeyAuth.userTier
 				authAPIKey = true
 			} else if demoAuth := AutoImpersonateIfDemo(w, r, database, config.DemoIdentityEmail, config.CSRFSecretKey); demoAuth != nil {
 				userID = demoAuth.userID
 				userEmail = demoAuth.userEmail
 				userReadOnly = demoAuth.userReadOnly
+				userTier = demoAuth.userTier
 				authSession = true
 			} else {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -627,9 +675,10 @@ func RequireSessionOrAPIKey(database *db.DB, config *OAuthConfig) func(http.Hand
 			// Enrich OpenTelemetry span with user info
 			enrichSpanWithUser(ctx, userID, userEmail, authAPIKey, authSession)
 
-			// Add user ID + read-only flag (CF-483) to context
+			// Add user ID, read-only flag (CF-483) + rate limit tier to context
 			ctx = context.WithValue(ctx, userIDContextKey, userID)
 			ctx = WithReadOnly(ctx, userReadOnly)
+			ctx = WithRateLimitTier(ctx, userTier)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -654,6 +703,7 @@ func OptionalAuth(database *db.DB, config *OAuthConfig) func(http.Handler) http.
 			var userID int64
 			var userEmail string
 			var userReadOnly bool
+			var userTier models.RateLimitTier
 			var authAPIKey, authSession bool
 
 			// Try API key first, then session cookie
@@ -661,16 +711,19 @@ func OptionalAuth(database *db.DB, config *OAuthConfig) func(http.Handler) http.
 				userID = apiKeyAuth.userID
 				userEmail = apiKeyAuth.userEmail
 				userReadOnly = apiKeyAuth.userReadOnly
+				userTier = apiKeyAuth.userTier
 				authAPIKey = true
 			} else if sessionAuth := TrySessionAuth(r, database); sessionAuth != nil {
 				userID = sessionAuth.userID
 				userEmail = sessionAuth.userEmail
 				userReadOnly = sessionAuth.userReadOnly
+				userTier = sessionAuth.userTier
 				authSession = true
 			} else if demoAuth := AutoImpersonateIfDemo(w, r, database, config.DemoIdentityEmail, config.CSRFSecretKey); demoAuth != nil {
 				userID = demoAuth.userID
 				userEmail = demoAuth.userEmail
 				userReadOnly = demoAuth.userReadOnly
+				userTier = demoAuth.userTier
 				authSession = true
 			} else {
 				// No auth - when domain restrictions are in place, require authentication
@@ -695,6 +748,7 @@ func OptionalAuth(database *db.DB, config *OAuthConfig) func(http.Handler) http.
 			enrichSpanWithUser(ctx, userID, userEmail, authAPIKey, authSession)
 			ctx = context.WithValue(ctx, userIDContextKey, userID)
 			ctx = WithReadOnly(ctx, userReadOnly)
+			ctx = WithRateLimitTier(ctx, userTier)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
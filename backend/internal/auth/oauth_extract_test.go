@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/models"
 )
 
 // TestValidateOAuthCallback covers the shared state+PKCE+code validation block
@@ -129,3 +131,20 @@ func TestCheckUserEligibility(t *testing.T) {
 		}
 	})
 }
+
+// TestCheckOAuthEligibility: a returning identity skips the user cap, but the
+// email it would store is still domain-checked, before any DB lookup.
+func TestCheckOAuthEligibility(t *testing.T) {
+	t.Run("rejects a changed email outside the allow-list", func(t *testing.T) {
+		info := models.OAuthUserInfo{
+			Provider:   models.ProviderGitHub,
+			ProviderID: "12345",
+			Email:      "moved@elsewhere.com",
+		}
+		// nil db proves the domain check runs first and short-circuits.
+		err := checkOAuthEligibility(context.Background(), nil, info, []string{"good.com"})
+		if !errors.Is(err, errEmailDomainNotPermitted) {
+			t.Fatalf("err = %v, want errEmailDomainNotPermitted", err)
+		}
+	})
+}
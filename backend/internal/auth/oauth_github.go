@@ -116,19 +116,12 @@ func HandleGitHubCallback(config *OAuthConfig, database *db.DB) http.HandlerFunc
 			return
 		}
 
-		// Check email domain restriction + user cap (shared across providers).
-		if err := checkUserEligibility(ctx, database, user.Email, config.AllowedEmailDomains); err != nil {
-			redirectUserIneligible(w, r, frontendURL, "github", user.Email, err)
-			return
-		}
-
 		// Use login (username) as fallback if name is empty
 		displayName := user.Name
 		if displayName == "" {
 			displayName = user.Login
 		}
 
-		// Find or create user in database using generic OAuth function
 		oauthInfo := models.OAuthUserInfo{
 			Provider:         models.ProviderGitHub,
 			ProviderID:       fmt.Sprintf("%d", user.ID),
@@ -137,6 +130,14 @@ func HandleGitHubCallback(config *OAuthConfig, database *db.DB) http.HandlerFunc
 			Name:             displayName,
 			AvatarURL:        user.AvatarURL,
 		}
+
+		// Check email domain restriction + user cap (shared across providers).
+		if err := checkOAuthEligibility(ctx, database, oauthInfo, config.AllowedEmailDomains); err != nil {
+			redirectUserIneligible(w, r, frontendURL, "github", user.Email, err)
+			return
+		}
+
+		// Find or create user in database using generic OAuth function
 		dbUser, err := authStore.FindOrCreateUserByOAuth(ctx, oauthInfo, config.AutoLinkEmail)
 		if err != nil {
 			if errors.Is(err, db.ErrAutoLinkDisabled) {
@@ -152,6 +153,8 @@ func HandleGitHubCallback(config *OAuthConfig, database *db.DB) http.HandlerFunc
 			return
 		}
 
+		logEmailNotUpdated(log, dbUser, oauthInfo)
+
 		// w8tz: reject deactivated accounts BEFORE minting a session, so the
 		// login loop (app→401→login→app) can never start for an inactive user.
 		if dbUser.Status == models.UserStatusInactive {
@@ -116,13 +116,6 @@ func HandleGoogleCallback(config *OAuthConfig, database *db.DB) http.HandlerFunc
 			return
 		}
 
-		// Check email domain restriction + user cap (shared across providers).
-		if err := checkUserEligibility(ctx, database, user.Email, config.AllowedEmailDomains); err != nil {
-			redirectUserIneligible(w, r, frontendURL, "google", user.Email, err)
-			return
-		}
-
-		// Find or create user in database
 		oauthInfo := models.OAuthUserInfo{
 			Provider:   models.ProviderGoogle,
 			ProviderID: user.ID,
@@ -130,6 +123,14 @@ func HandleGoogleCallback(config *OAuthConfig, database *db.DB) http.HandlerFunc
 			Name:       user.Name,
 			AvatarURL:  user.Picture,
 		}
+
+		// Check email domain restriction + user cap (shared across providers).
+		if err := checkOAuthEligibility(ctx, database, oauthInfo, config.AllowedEmailDomains); err != nil {
+			redirectUserIneligible(w, r, frontendURL, "google", user.Email, err)
+			return
+		}
+
+		// Find or create user in database
 		dbUser, err := authStore.FindOrCreateUserByOAuth(ctx, oauthInfo, config.AutoLinkEmail)
 		if err != nil {
 			if errors.Is(err, db.ErrAutoLinkDisabled) {
@@ -145,6 +146,8 @@ func HandleGoogleCallback(config *OAuthConfig, database *db.DB) http.HandlerFunc
 			return
 		}
 
+		logEmailNotUpdated(log, dbUser, oauthInfo)
+
 		// w8tz: reject deactivated accounts BEFORE minting a session, so the
 		// login loop (app→401→login→app) can never start for an inactive user.
 		if dbUser.Status == models.UserStatusInactive {
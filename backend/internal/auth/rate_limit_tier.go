@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+// rateLimitTierKey carries the resolved user's rate limit tier through the
+// request context, like readOnlyKey.
+type rateLimitTierKey struct{}
+
+// WithRateLimitTier returns ctx with the user's rate limit tier stored.
+func WithRateLimitTier(ctx context.Context, tier models.RateLimitTier) context.Context {
+	return context.WithValue(ctx, rateLimitTierKey{}, tier)
+}
+
+// RateLimitTierFromContext returns the request's user's rate limit tier, or
+// RateLimitTierStandard when no user has been resolved.
+func RateLimitTierFromContext(ctx context.Context) models.RateLimitTier {
+	if tier, ok := ctx.Value(rateLimitTierKey{}).(models.RateLimitTier); ok && tier != "" {
+		return tier
+	}
+	return models.RateLimitTierStandard
+}
+
+// RateLimitTierFunc reads the tier for ratelimit.TieredMiddleware. Mount the
+// middleware after auth, as with ratelimit.UserKeyFunc.
+func RateLimitTierFunc(r *http.Request) string {
+	return string(RateLimitTierFromContext(r.Context()))
+}
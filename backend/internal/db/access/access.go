@@ -3,6 +3,7 @@ package access
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"go.opentelemetry.io/otel/attribute"
@@ -14,7 +15,9 @@ import (
 )
 
 // GetSessionAccessType determines how a user can access a session.
-// Checks in order of specificity: owner, recipient, system, public.
+// Checks in order of specificity: owner, recipient, system, org, public.
+// Org access applies to an org-visible session whose owner shares an
+// organization with the viewer; it is read-only like a share.
 // Returns the access type and the share ID (if applicable).
 // viewerUserID can be nil for unauthenticated users.
 func (s *Store) GetSessionAccessType(ctx context.Context, sessionID string, viewerUserID *int64) (*db.SessionAccessInfo, error) {
@@ -26,10 +29,18 @@ func (s *Store) GetSessionAccessType(ctx context.Context, sessionID string, view
 		span.SetAttributes(attribute.Int64("user.id", *viewerUserID))
 	}
 
-	// First, check if session exists and get owner
+	// First, check if session exists and get owner, and whether the viewer
+	// reaches it through org visibility
 	var ownerUserID int64
-	err := s.conn().QueryRowContext(ctx,
-		`SELECT user_id FROM sessions WHERE id = $1`, sessionID).Scan(&ownerUserID)
+	var orgVisible bool
+	err := s.conn().QueryRowContext(ctx, `
+		SELECT s.user_id,
+		       s.visibility = 'org' AND $2::bigint IS NOT NULL AND EXISTS (
+		           SELECT 1 FROM org_members owner_m
+		           JOIN org_members viewer_m ON viewer_m.org_id = owner_m.org_id
+		           WHERE owner_m.user_id = s.user_id AND viewer_m.user_id = $2
+		       )
+		FROM sessions s WHERE s.id = $1`, sessionID, viewerUserID).Scan(&ownerUserID, &orgVisible)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, db.ErrSessionNotFound
@@ -89,6 +100,10 @@ func (s *Store) GetSessionAccessType(ctx context.Context, sessionID string, view
 
 	if err == sql.ErrNoRows {
 		// No shares exist for this session
+		if orgVisible {
+			span.SetAttributes(attribute.String("access.type", "org"))
+			return &db.SessionAccessInfo{AccessType: db.SessionAccessOrg}, nil
+		}
 		span.SetAttributes(attribute.String("access.type", "none"))
 		return &db.SessionAccessInfo{AccessType: db.SessionAccessNone}, nil
 	}
@@ -98,6 +113,12 @@ func (s *Store) GetSessionAccessType(ctx context.Context, sessionID string, view
 		return nil, fmt.Errorf("failed to check share access: %w", err)
 	}
 
+	// Org access outranks a public share: the viewer is signed in and keeps
+	// the owner's email.
+	if orgVisible && (accessType == "public" || accessType == "none") {
+		accessType = "org"
+	}
+
 	span.SetAttributes(attribute.String("access.type", accessType))
 
 	switch accessType {
@@ -107,6 +128,8 @@ func (s *Store) GetSessionAccessType(ctx context.Context, sessionID string, view
 		return &db.SessionAccessInfo{AccessType: db.SessionAccessSystem, ShareID: &shareID}, nil
 	case "public":
 		return &db.SessionAccessInfo{AccessType: db.SessionAccessPublic, ShareID: &shareID}, nil
+	case "org":
+		return &db.SessionAccessInfo{AccessType: db.SessionAccessOrg}, nil
 	default:
 		// "none" - has shares but viewer has no access
 		return &db.SessionAccessInfo{AccessType: db.SessionAccessNone, AuthMayHelp: authMayHelp}, nil
@@ -150,7 +173,14 @@ func (s *Store) GetSessionDetailWithAccess(ctx context.Context, sessionID string
 		db.SessionDetailScanTargets(&session, &gitInfoBytes),
 		&ownerStatus,
 	)
-	err := s.conn().QueryRowContext(ctx, sessionQuery, sessionID).Scan(scanTargets...)
+	// Read from the replica when usable; a session created within the
+	// replica lag is retried on the primary.
+	conn := s.DB.ReadConn(ctx)
+	err := conn.QueryRowContext(ctx, sessionQuery, sessionID).Scan(scanTargets...)
+	if errors.Is(err, sql.ErrNoRows) && !s.DB.IsPrimary(conn) {
+		conn = s.conn()
+		err = conn.QueryRowContext(ctx, sessionQuery, sessionID).Scan(scanTargets...)
+	}
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, db.ErrSessionNotFound
@@ -163,6 +193,7 @@ func (s *Store) GetSessionDetailWithAccess(ctx context.Context, sessionID string
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 	session.Provider = models.NormalizeProvider(session.Provider)
+	session.ResolveTitle()
 
 	// Check if session owner is deactivated
 	if ownerStatus == models.UserStatusInactive {
@@ -205,7 +236,7 @@ func (s *Store) GetSessionDetailWithAccess(ctx context.Context, sessionID string
 	if !isOwner {
 		session.GitInfo = db.SanitizeGitInfoForSharing(session.GitInfo)
 	}
-	if err := db.LoadSessionSyncFiles(ctx, s.DB, &session); err != nil {
+	if err := db.LoadSessionSyncFiles(ctx, conn, &session); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		return nil, err
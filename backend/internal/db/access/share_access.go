@@ -0,0 +1,156 @@
+package access
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// RecordShareAccess counts one view of a share by the viewer identified by
+// viewerHash, on today's (UTC) row for that viewer and user agent class.
+func (s *Store) RecordShareAccess(ctx context.Context, shareID int64, viewerHash, userAgentClass string) error {
+	ctx, span := tracer.Start(ctx, "db.record_share_access",
+		trace.WithAttributes(
+			attribute.Int64("share.id", shareID),
+			attribute.String("share.user_agent_class", userAgentClass),
+		))
+	defer span.End()
+
+	_, err := s.conn().ExecContext(ctx, `
+		INSERT INTO share_access_log (share_id, day, viewer_hash, user_agent_class)
+		VALUES ($1, (NOW() AT TIME ZONE 'UTC')::date, $2, $3)
+		ON CONFLICT (share_id, day, viewer_hash, user_agent_class)
+		DO UPDATE SET views = share_access_log.views + 1, last_viewed_at = NOW()`,
+		shareID, viewerHash, userAgentClass)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to record share access: %w", err)
+	}
+	return nil
+}
+
+// GetShareAccessStats returns the recorded views of a share of sessionID,
+// with a daily series over the trailing days (UTC, today included). Returns
+// db.ErrUnauthorized if the share doesn't exist, isn't a share of sessionID,
+// or the session isn't owned by userID.
+func (s *Store) GetShareAccessStats(ctx context.Context, sessionID string, shareID, userID int64, days int) (*db.ShareAccessStats, error) {
+	ctx, span := tracer.Start(ctx, "db.get_share_access_stats",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.Int64("share.id", shareID),
+			attribute.Int64("user.id", userID),
+		))
+	defer span.End()
+
+	stats, err := s.getShareAccessStats(ctx, sessionID, shareID, userID, days)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int64("share.total_views", stats.TotalViews))
+	return stats, nil
+}
+
+func (s *Store) getShareAccessStats(ctx context.Context, sessionID string, shareID, userID int64, days int) (*db.ShareAccessStats, error) {
+	var owned bool
+	err := s.conn().QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM session_shares ss
+			JOIN sessions s ON s.id = ss.session_id
+			WHERE ss.id = $1 AND ss.session_id = $2 AND s.user_id = $3
+		)`, shareID, sessionID, userID).Scan(&owned)
+	if err != nil {
+		if db.IsInvalidUUIDError(err) {
+			return nil, db.ErrUnauthorized
+		}
+		return nil, fmt.Errorf("failed to verify share ownership: %w", err)
+	}
+	if !owned {
+		return nil, db.ErrUnauthorized
+	}
+
+	stats := &db.ShareAccessStats{
+		ShareID:          shareID,
+		ViewsByUserAgent: map[string]int64{},
+		Daily:            []db.ShareAccessDay{},
+	}
+
+	var lastViewed sql.NullTime
+	if err := s.conn().QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(views), 0), COUNT(DISTINCT viewer_hash), MAX(last_viewed_at)
+		FROM share_access_log
+		WHERE share_id = $1`, shareID).Scan(&stats.TotalViews, &stats.UniqueViewers, &lastViewed); err != nil {
+		return nil, fmt.Errorf("failed to get share access totals: %w", err)
+	}
+	if lastViewed.Valid {
+		stats.LastViewedAt = &lastViewed.Time
+	}
+
+	err = s.queryRows(ctx, `
+		SELECT user_agent_class, SUM(views)
+		FROM share_access_log
+		WHERE share_id = $1
+		GROUP BY user_agent_class`, []any{shareID}, func(rows *sql.Rows) error {
+		var class string
+		var views int64
+		if err := rows.Scan(&class, &views); err != nil {
+			return err
+		}
+		stats.ViewsByUserAgent[class] = views
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share views by user agent: %w", err)
+	}
+
+	err = s.queryRows(ctx, `
+		WITH days AS (
+			SELECT generate_series(
+				(NOW() AT TIME ZONE 'UTC')::date - ($2::int - 1),
+				(NOW() AT TIME ZONE 'UTC')::date,
+				INTERVAL '1 day')::date AS day
+		)
+		SELECT d.day, COALESCE(SUM(l.views), 0), COUNT(DISTINCT l.viewer_hash)
+		FROM days d
+		LEFT JOIN share_access_log l ON l.share_id = $1 AND l.day = d.day
+		GROUP BY d.day
+		ORDER BY d.day`, []any{shareID, days}, func(rows *sql.Rows) error {
+		var day time.Time
+		var d db.ShareAccessDay
+		if err := rows.Scan(&day, &d.Views, &d.UniqueViewers); err != nil {
+			return err
+		}
+		d.Date = day.Format("2006-01-02")
+		stats.Daily = append(stats.Daily, d)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily share views: %w", err)
+	}
+
+	return stats, nil
+}
+
+// queryRows runs query and calls scan once per row.
+func (s *Store) queryRows(ctx context.Context, query string, args []any, scan func(rows *sql.Rows) error) error {
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
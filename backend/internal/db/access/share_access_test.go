@@ -0,0 +1,118 @@
+package access_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/access"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestShareAccessStats_Aggregates records views from several viewers, classes
+// and days and checks the totals, breakdown and daily series.
+func TestShareAccessStats_Aggregates(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &access.Store{DB: env.DB}
+	ctx := context.Background()
+
+	owner := testutil.CreateTestUser(t, env, "owner@example.com", "Owner")
+	sessionID := testutil.CreateTestSession(t, env, owner.ID, "share-access-ext")
+	shareID := testutil.CreateTestShare(t, env, sessionID, true, nil, nil)
+
+	for _, v := range []struct{ viewer, class string }{
+		{"viewer-a", "browser"},
+		{"viewer-a", "browser"},
+		{"viewer-b", "browser"},
+		{"viewer-b", "cli"},
+	} {
+		if err := store.RecordShareAccess(ctx, shareID, v.viewer, v.class); err != nil {
+			t.Fatalf("RecordShareAccess failed: %v", err)
+		}
+	}
+	// A bot view two days ago.
+	if _, err := env.DB.Exec(ctx, `
+		INSERT INTO share_access_log (share_id, day, viewer_hash, user_agent_class, views, last_viewed_at)
+		VALUES ($1, (NOW() AT TIME ZONE 'UTC')::date - 2, 'viewer-c', 'bot', 3, NOW() - INTERVAL '2 days')`,
+		shareID); err != nil {
+		t.Fatalf("insert old view: %v", err)
+	}
+
+	stats, err := store.GetShareAccessStats(ctx, sessionID, shareID, owner.ID, 7)
+	if err != nil {
+		t.Fatalf("GetShareAccessStats failed: %v", err)
+	}
+
+	if stats.TotalViews != 7 {
+		t.Errorf("TotalViews = %d, want 7", stats.TotalViews)
+	}
+	if stats.UniqueViewers != 3 {
+		t.Errorf("UniqueViewers = %d, want 3", stats.UniqueViewers)
+	}
+	if stats.LastViewedAt == nil || time.Since(*stats.LastViewedAt) > time.Minute {
+		t.Errorf("LastViewedAt = %v, want about now", stats.LastViewedAt)
+	}
+	want := map[string]int64{"browser": 3, "cli": 1, "bot": 3}
+	for class, n := range want {
+		if stats.ViewsByUserAgent[class] != n {
+			t.Errorf("ViewsByUserAgent[%s] = %d, want %d", class, stats.ViewsByUserAgent[class], n)
+		}
+	}
+	if len(stats.ViewsByUserAgent) != len(want) {
+		t.Errorf("ViewsByUserAgent = %v, want %v", stats.ViewsByUserAgent, want)
+	}
+
+	if len(stats.Daily) != 7 {
+		t.Fatalf("len(Daily) = %d, want 7", len(stats.Daily))
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+	if last := stats.Daily[6]; last.Date != today || last.Views != 4 || last.UniqueViewers != 2 {
+		t.Errorf("today = %+v, want {%s 4 2}", last, today)
+	}
+	if old := stats.Daily[4]; old.Views != 3 || old.UniqueViewers != 1 {
+		t.Errorf("two days ago = %+v, want 3 views from 1 viewer", old)
+	}
+	if empty := stats.Daily[0]; empty.Views != 0 || empty.UniqueViewers != 0 {
+		t.Errorf("six days ago = %+v, want no views", empty)
+	}
+}
+
+// TestShareAccessStats_OwnerOnly checks that only the session owner can read
+// a share's stats, and only through the share's own session.
+func TestShareAccessStats_OwnerOnly(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &access.Store{DB: env.DB}
+	ctx := context.Background()
+
+	owner := testutil.CreateTestUser(t, env, "owner@example.com", "Owner")
+	other := testutil.CreateTestUser(t, env, "other@example.com", "Other")
+	sessionID := testutil.CreateTestSession(t, env, owner.ID, "share-access-ext")
+	otherSessionID := testutil.CreateTestSession(t, env, owner.ID, "other-ext")
+	shareID := testutil.CreateTestShare(t, env, sessionID, true, nil, nil)
+
+	cases := map[string]struct {
+		sessionID string
+		userID    int64
+	}{
+		"another user":       {sessionID, other.ID},
+		"another session":    {otherSessionID, owner.ID},
+		"invalid session ID": {"not-a-uuid", owner.ID},
+	}
+	for name, c := range cases {
+		if _, err := store.GetShareAccessStats(ctx, c.sessionID, shareID, c.userID, 30); !errors.Is(err, db.ErrUnauthorized) {
+			t.Errorf("%s: err = %v, want ErrUnauthorized", name, err)
+		}
+	}
+}
@@ -18,11 +18,27 @@ type DB struct {
 	// ShareAllSessions makes all sessions visible to all authenticated users
 	// as system shares (no database rows needed). For on-prem deployments.
 	ShareAllSessions bool
+
+	// SearchWeights ranks full-text session search results by which indexed
+	// component matched (metadata, recap, user messages).
+	SearchWeights SearchWeights
+
+	// InterestWeights score sessions for the session list's sort=interesting
+	// (see InterestScore).
+	InterestWeights InterestWeights
+
+	// SessionOwners caches session ownership checks (SESSION_OWNER_CACHE_TTL).
+	// Nil when disabled.
+	SessionOwners *SessionOwnerCache
+
+	// replica serves ReadConn when DATABASE_REPLICA_URL is set (AttachReplica).
+	replica *replica
 }
 
 // Connect establishes a connection to PostgreSQL
 func Connect(dsn string) (*DB, error) {
-	conn, err := sql.Open("pgx", dsn)
+	slowQueries := LoadSlowQueryThreshold()
+	conn, err := openPool(dsn, slowQueries)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -49,7 +65,28 @@ func Connect(dsn string) (*DB, error) {
 		logger.Info("db connection pool: idle timeout configured", "conn_max_idle_time", d)
 	}
 
-	return &DB{conn: conn}, nil
+	if slowQueries != DefaultSlowQueryThreshold {
+		logger.Info("slow query logging configured", "threshold", slowQueries)
+	}
+
+	weights := loadSearchWeights()
+	if weights != DefaultSearchWeights {
+		logger.Info("search ranking weights configured", "a", weights.A, "b", weights.B, "c", weights.C)
+	}
+
+	interest := loadInterestWeights()
+	if interest != DefaultInterestWeights {
+		logger.Info("interest score weights configured",
+			"cost", interest.Cost, "duration", interest.Duration, "tool_errors", interest.ToolErrors,
+			"recap", interest.Recap, "baseline", interest.Baseline, "half_life", interest.HalfLife)
+	}
+
+	owners := loadSessionOwnerCache()
+	if owners != nil {
+		logger.Info("session owner cache enabled", "ttl", owners.ttl)
+	}
+
+	return &DB{conn: conn, SearchWeights: weights, InterestWeights: interest, SessionOwners: owners}, nil
 }
 
 // parseConnMaxIdleTime reads DB_CONN_MAX_IDLE_TIME and parses it as a
@@ -97,8 +134,11 @@ func ConnectWithRetry(ctx context.Context, dsn string) (*DB, error) {
 	}
 }
 
-// Close closes the database connection
+// Close closes the database connection, and the read replica's if attached
 func (db *DB) Close() error {
+	if db.replica != nil {
+		db.replica.conn.Close()
+	}
 	return db.conn.Close()
 }
 
@@ -0,0 +1,129 @@
+package dbauth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// AddAPIKeyUsage adds a batch of per-key daily chunk upload counts in one
+// statement. Deltas for keys deleted since they were counted are dropped
+// rather than failing the batch.
+func (s *Store) AddAPIKeyUsage(ctx context.Context, deltas []db.APIKeyUsageDelta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	ctx, span := tracer.Start(ctx, "db.add_api_key_usage",
+		trace.WithAttributes(attribute.Int("usage.deltas", len(deltas))))
+	defer span.End()
+
+	keyIDs := make([]int64, len(deltas))
+	days := make([]string, len(deltas))
+	requests := make([]int64, len(deltas))
+	lines := make([]int64, len(deltas))
+	bytes := make([]int64, len(deltas))
+	for i, d := range deltas {
+		keyIDs[i] = d.APIKeyID
+		days[i] = d.Day.UTC().Format("2006-01-02")
+		requests[i] = d.Requests
+		lines[i] = d.Lines
+		bytes[i] = d.Bytes
+	}
+
+	_, err := s.conn().ExecContext(ctx, `
+		INSERT INTO api_key_usage (api_key_id, day, requests, lines, bytes)
+		SELECT u.api_key_id, u.day, u.requests, u.lines, u.bytes
+		FROM unnest($1::bigint[], $2::date[], $3::bigint[], $4::bigint[], $5::bigint[])
+		     AS u(api_key_id, day, requests, lines, bytes)
+		JOIN api_keys k ON k.id = u.api_key_id
+		ON CONFLICT (api_key_id, day) DO UPDATE SET
+			requests = api_key_usage.requests + EXCLUDED.requests,
+			lines = api_key_usage.lines + EXCLUDED.lines,
+			bytes = api_key_usage.bytes + EXCLUDED.bytes,
+			updated_at = NOW()`,
+		pq.Array(keyIDs), pq.Array(days), pq.Array(requests), pq.Array(lines), pq.Array(bytes))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to add API key usage: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKeyUsage returns the usage of one of userID's API keys: sessions it
+// created, lifetime upload totals, and a daily series over the trailing days
+// (UTC, today included). Returns db.ErrAPIKeyNotFound when the key doesn't
+// exist or belongs to another user.
+func (s *Store) GetAPIKeyUsage(ctx context.Context, userID, keyID int64, days int) (*db.APIKeyUsage, error) {
+	ctx, span := tracer.Start(ctx, "db.get_api_key_usage",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.Int64("key.id", keyID),
+		))
+	defer span.End()
+
+	usage, err := s.getAPIKeyUsage(ctx, userID, keyID, days)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	return usage, nil
+}
+
+func (s *Store) getAPIKeyUsage(ctx context.Context, userID, keyID int64, days int) (*db.APIKeyUsage, error) {
+	usage := &db.APIKeyUsage{APIKeyID: keyID, Daily: []db.APIKeyUsageDay{}}
+
+	err := s.conn().QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM sessions WHERE created_by_api_key_id = k.id),
+			COALESCE(SUM(u.requests), 0), COALESCE(SUM(u.lines), 0), COALESCE(SUM(u.bytes), 0)
+		FROM api_keys k
+		LEFT JOIN api_key_usage u ON u.api_key_id = k.id
+		WHERE k.id = $1 AND k.user_id = $2
+		GROUP BY k.id`, keyID, userID).Scan(
+		&usage.CreatedSessionCount, &usage.Totals.Requests, &usage.Totals.Lines, &usage.Totals.Bytes)
+	if err == sql.ErrNoRows {
+		return nil, db.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key usage totals: %w", err)
+	}
+
+	rows, err := s.conn().QueryContext(ctx, `
+		WITH days AS (
+			SELECT generate_series(
+				(NOW() AT TIME ZONE 'UTC')::date - ($2::int - 1),
+				(NOW() AT TIME ZONE 'UTC')::date,
+				INTERVAL '1 day')::date AS day
+		)
+		SELECT d.day, COALESCE(u.requests, 0), COALESCE(u.lines, 0), COALESCE(u.bytes, 0)
+		FROM days d
+		LEFT JOIN api_key_usage u ON u.api_key_id = $1 AND u.day = d.day
+		ORDER BY d.day`, keyID, days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily API key usage: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day time.Time
+		var d db.APIKeyUsageDay
+		if err := rows.Scan(&day, &d.Requests, &d.Lines, &d.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan daily API key usage: %w", err)
+		}
+		d.Date = day.Format("2006-01-02")
+		usage.Daily = append(usage.Daily, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating daily API key usage: %w", err)
+	}
+	return usage, nil
+}
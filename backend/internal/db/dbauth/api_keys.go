@@ -17,30 +17,31 @@ import (
 // ValidateAPIKey checks if an API key is valid and returns the associated
 // user info. The userReadOnly flag (CF-483) lets callers stash the value
 // into the request context so EnforceReadOnly can block writes from
-// API-key auth as well as session auth.
-func (s *Store) ValidateAPIKey(ctx context.Context, keyHash string) (userID int64, keyID int64, userEmail string, userStatus models.UserStatus, userReadOnly bool, err error) {
+// API-key auth as well as session auth; userTier likewise feeds the per-user
+// rate limiters.
+func (s *Store) ValidateAPIKey(ctx context.Context, keyHash string) (userID int64, keyID int64, userEmail string, userStatus models.UserStatus, userReadOnly bool, userTier models.RateLimitTier, err error) {
 	ctx, span := tracer.Start(ctx, "db.validate_api_key")
 	defer span.End()
 
 	query := `
-		SELECT ak.id, ak.user_id, u.email, u.status, u.read_only
+		SELECT ak.id, ak.user_id, u.email, u.status, u.read_only, u.rate_limit_tier
 		FROM api_keys ak
 		JOIN users u ON ak.user_id = u.id
 		WHERE ak.key_hash = $1
 	`
 
-	err = s.conn().QueryRowContext(ctx, query, keyHash).Scan(&keyID, &userID, &userEmail, &userStatus, &userReadOnly)
+	err = s.conn().QueryRowContext(ctx, query, keyHash).Scan(&keyID, &userID, &userEmail, &userStatus, &userReadOnly, &userTier)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return 0, 0, "", "", false, fmt.Errorf("invalid API key")
+			return 0, 0, "", "", false, "", fmt.Errorf("invalid API key")
 		}
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return 0, 0, "", "", false, fmt.Errorf("failed to validate API key: %w", err)
+		return 0, 0, "", "", false, "", fmt.Errorf("failed to validate API key: %w", err)
 	}
 
 	span.SetAttributes(attribute.Int64("user.id", userID))
-	return userID, keyID, userEmail, userStatus, userReadOnly, nil
+	return userID, keyID, userEmail, userStatus, userReadOnly, userTier, nil
 }
 
 // UpdateAPIKeyLastUsed updates the last_used_at timestamp for an API key
@@ -119,7 +120,12 @@ func (s *Store) ListAPIKeys(ctx context.Context, userID int64) ([]models.APIKey,
 		trace.WithAttributes(attribute.Int64("user.id", userID)))
 	defer span.End()
 
-	query := `SELECT id, user_id, name, created_at, last_used_at FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`
+	query := `
+		SELECT k.id, k.user_id, k.name, k.created_at, k.last_used_at,
+		       (SELECT COUNT(*) FROM sessions s WHERE s.created_by_api_key_id = k.id)
+		FROM api_keys k
+		WHERE k.user_id = $1
+		ORDER BY k.created_at DESC`
 
 	rows, err := s.conn().QueryContext(ctx, query, userID)
 	if err != nil {
@@ -132,7 +138,7 @@ func (s *Store) ListAPIKeys(ctx context.Context, userID int64) ([]models.APIKey,
 	var keys []models.APIKey
 	for rows.Next() {
 		var key models.APIKey
-		if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.CreatedAt, &key.LastUsedAt); err != nil {
+		if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.CreatedAt, &key.LastUsedAt, &key.CreatedSessionCount); err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, err.Error())
 			return nil, fmt.Errorf("failed to scan API key: %w", err)
@@ -0,0 +1,36 @@
+package dbauth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RedeemMagicLink records that the magic-link token tokenID was used and
+// reports whether this was its first use. expiresAt is the token's own
+// expiry, after which the row can be pruned.
+func (s *Store) RedeemMagicLink(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error) {
+	ctx, span := tracer.Start(ctx, "db.redeem_magic_link",
+		trace.WithAttributes(attribute.String("magic_link.id", tokenID)))
+	defer span.End()
+
+	res, err := s.conn().ExecContext(ctx, `
+		INSERT INTO magic_link_redemptions (token_id, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (token_id) DO NOTHING`, tokenID, expiresAt)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return false, fmt.Errorf("failed to redeem magic link: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to redeem magic link: %w", err)
+	}
+	span.SetAttributes(attribute.Bool("magic_link.first_use", n == 1))
+	return n == 1, nil
+}
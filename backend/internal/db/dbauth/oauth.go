@@ -14,6 +14,8 @@ import (
 )
 
 // FindOrCreateUserByOAuth finds or creates a user by OAuth provider identity.
+// A user found by identity takes the provider's current email unless another
+// account holds it; the returned user carries the email actually stored.
 // It handles account linking: if an identity doesn't exist but the email matches
 // an existing user, it links the new identity to that user — but ONLY when
 // autoLinkEmail is true. When false (the default), an email collision with no
@@ -47,9 +49,24 @@ func (s *Store) FindOrCreateUserByOAuth(ctx context.Context, info models.OAuthUs
 	)
 
 	if err == nil {
-		// User found via identity - update profile info and username
-		updateSQL := `UPDATE users SET email = $1, name = $2, avatar_url = $3, updated_at = NOW() WHERE id = $4`
-		if _, err = tx.ExecContext(ctx, updateSQL, info.Email, info.Name, info.AvatarURL, user.ID); err != nil {
+		// User found via identity - update profile info and username. The
+		// identity, not the email, is what the provider keeps stable, so a
+		// changed email is adopted here. If another account already holds
+		// the new email (typically a duplicate created before this lookup
+		// existed), the stored email is kept; an admin merge resolves that.
+		updateSQL := `
+			UPDATE users SET
+				email = CASE
+					WHEN EXISTS (SELECT 1 FROM users o WHERE LOWER(o.email) = LOWER($1) AND o.id <> $4) THEN email
+					ELSE $1
+				END,
+				name = $2, avatar_url = $3, updated_at = NOW()
+			WHERE id = $4
+			RETURNING email, name, avatar_url, updated_at
+		`
+		if err = tx.QueryRowContext(ctx, updateSQL, info.Email, info.Name, info.AvatarURL, user.ID).Scan(
+			&user.Email, &user.Name, &user.AvatarURL, &user.UpdatedAt,
+		); err != nil {
 			return nil, fmt.Errorf("failed to update user: %w", err)
 		}
 
@@ -157,3 +174,23 @@ func (s *Store) FindOrCreateUserByOAuth(ctx context.Context, info models.OAuthUs
 
 	return &user, nil
 }
+
+// IdentityExists reports whether a provider identity is linked to any user.
+// The OAuth callbacks use it to treat a login whose email changed at the
+// provider as a returning user.
+func (s *Store) IdentityExists(ctx context.Context, provider models.OAuthProvider, providerID string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "db.identity_exists",
+		trace.WithAttributes(attribute.String("oauth.provider", string(provider))))
+	defer span.End()
+
+	var exists bool
+	err := s.conn().QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM user_identities WHERE provider = $1 AND provider_id = $2)`,
+		provider, providerID).Scan(&exists)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return false, fmt.Errorf("failed to check identity: %w", err)
+	}
+	return exists, nil
+}
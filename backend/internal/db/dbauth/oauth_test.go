@@ -658,3 +658,62 @@ func TestFindOrCreateUserByOAuth_AutoLinkDisabled_ExistingIdentityUnaffected(t *
 		t.Errorf("expected same user on return, got %d then %d", first.ID, second.ID)
 	}
 }
+
+// TestFindOrCreateUserByOAuth_EmailChangedAtProvider verifies a returning
+// identity with a new provider email stays on its account and adopts the
+// email, unless another account already holds it.
+func TestFindOrCreateUserByOAuth_EmailChangedAtProvider(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping database test in short mode")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+	store := &dbauth.Store{DB: env.DB}
+	ctx := context.Background()
+
+	info := models.OAuthUserInfo{
+		Provider:   models.ProviderGitHub,
+		ProviderID: "github-email-change",
+		Email:      "before@example.com",
+		Name:       "Changer",
+	}
+	original, err := store.FindOrCreateUserByOAuth(ctx, info, false)
+	if err != nil {
+		t.Fatalf("first login failed: %v", err)
+	}
+
+	exists, err := store.IdentityExists(ctx, info.Provider, info.ProviderID)
+	if err != nil || !exists {
+		t.Fatalf("IdentityExists = %v, %v; want true", exists, err)
+	}
+	if exists, _ := store.IdentityExists(ctx, info.Provider, "github-unknown"); exists {
+		t.Error("IdentityExists reported an unlinked identity")
+	}
+
+	info.Email = "after@example.com"
+	changed, err := store.FindOrCreateUserByOAuth(ctx, info, false)
+	if err != nil {
+		t.Fatalf("login with changed email failed: %v", err)
+	}
+	if changed.ID != original.ID {
+		t.Errorf("user ID = %d, want %d (same account)", changed.ID, original.ID)
+	}
+	if changed.Email != "after@example.com" {
+		t.Errorf("email = %q, want after@example.com", changed.Email)
+	}
+
+	// Another account takes a new address; the identity can't adopt it.
+	other := testutil.CreateTestUser(t, env, "Taken@example.com", "Other")
+	info.Email = "taken@example.com"
+	kept, err := store.FindOrCreateUserByOAuth(ctx, info, false)
+	if err != nil {
+		t.Fatalf("login with taken email failed: %v", err)
+	}
+	if kept.ID != original.ID {
+		t.Errorf("user ID = %d, want %d (never the holder %d)", kept.ID, original.ID, other.ID)
+	}
+	if kept.Email != "after@example.com" {
+		t.Errorf("email = %q, want the stored after@example.com", kept.Email)
+	}
+}
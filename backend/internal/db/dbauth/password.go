@@ -127,7 +127,8 @@ func (s *Store) AuthenticatePassword(ctx context.Context, email, password string
 // pg_advisory_xact_lock so concurrent server starts serialize the admin
 // bootstrap (count-check + create). The value is meaningless on its own; it
 // only has to be stable and not collide with another advisory lock the app
-// takes (this is currently the only one). Keep it constant across releases.
+// takes (sync/chunk's per-file locks are hashtext values, which fit in 32
+// bits, so any key above that range is safe). Keep it constant across releases.
 const bootstrapAdvisoryLockKey int64 = 7421968455123001 // "7ys0 bootstrap"
 
 // CreatePasswordUser creates a new user with password authentication.
@@ -51,7 +51,7 @@ func (s *Store) GetWebSession(ctx context.Context, sessionID string, idleTimeout
 	applyIdle := idleTimeout > 0
 
 	query := `
-		SELECT ws.id, ws.user_id, u.email, u.status, u.read_only, ws.created_at, ws.expires_at, ws.last_activity_at
+		SELECT ws.id, ws.user_id, u.email, u.status, u.read_only, u.rate_limit_tier, ws.created_at, ws.expires_at, ws.last_activity_at
 		FROM web_sessions ws
 		JOIN users u ON ws.user_id = u.id
 		WHERE ws.id = $1 AND ws.expires_at > NOW()`
@@ -71,6 +71,7 @@ func (s *Store) GetWebSession(ctx context.Context, sessionID string, idleTimeout
 		&session.UserEmail,
 		&session.UserStatus,
 		&session.ReadOnly,
+		&session.RateLimitTier,
 		&session.CreatedAt,
 		&session.ExpiresAt,
 		&session.LastActivityAt,
@@ -0,0 +1,285 @@
+package dbdataexport
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+var tracer = otel.Tracer("confab/db/dataexport")
+
+// Export statuses. An export moves pending → processing → ready → expired,
+// or to failed from processing.
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusReady      = "ready"
+	StatusFailed     = "failed"
+	StatusExpired    = "expired"
+)
+
+// Export is a user_data_exports row.
+type Export struct {
+	ID           int64
+	UserID       int64
+	Status       string
+	RequestedAt  time.Time
+	StartedAt    *time.Time
+	CompletedAt  *time.Time
+	ExpiresAt    *time.Time
+	ObjectKey    *string
+	SizeBytes    *int64
+	SessionCount *int
+	Error        *string
+}
+
+// RequestResult is the outcome of Request. When Created is false, Export is
+// the earlier request that blocked this one.
+type RequestResult struct {
+	Export  *Export
+	Created bool
+}
+
+// Store provides data export database operations.
+type Store struct {
+	DB *db.DB
+}
+
+// conn returns the underlying *sql.DB connection.
+func (s *Store) conn() *sql.DB { return s.DB.Conn() }
+
+const exportCols = `id, user_id, status, requested_at, started_at, completed_at,
+	expires_at, object_key, size_bytes, session_count, error`
+
+func scanExport(row interface{ Scan(...any) error }) (*Export, error) {
+	var e Export
+	err := row.Scan(&e.ID, &e.UserID, &e.Status, &e.RequestedAt, &e.StartedAt, &e.CompletedAt,
+		&e.ExpiresAt, &e.ObjectKey, &e.SizeBytes, &e.SessionCount, &e.Error)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Request queues an export for userID unless the user already requested one
+// less than minInterval ago. Failed exports don't count, so a user can retry
+// after a failure. The user's row is locked for the check and insert, so
+// concurrent requests can't both pass.
+func (s *Store) Request(ctx context.Context, userID int64, minInterval time.Duration) (*RequestResult, error) {
+	ctx, span := tracer.Start(ctx, "db.request_data_export",
+		trace.WithAttributes(attribute.Int64("user.id", userID)))
+	defer span.End()
+
+	result, err := s.request(ctx, userID, minInterval)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Bool("export.created", result.Created))
+	return result, nil
+}
+
+func (s *Store) request(ctx context.Context, userID int64, minInterval time.Duration) (*RequestResult, error) {
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, db.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	previous, err := scanExport(tx.QueryRowContext(ctx, `
+		SELECT `+exportCols+`
+		FROM user_data_exports
+		WHERE user_id = $1 AND status != $2 AND requested_at > NOW() - make_interval(secs => $3)
+		ORDER BY requested_at DESC
+		LIMIT 1`,
+		userID, StatusFailed, minInterval.Seconds()))
+	if err == nil {
+		return &RequestResult{Export: previous}, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check recent exports: %w", err)
+	}
+
+	created, err := scanExport(tx.QueryRowContext(ctx, `
+		INSERT INTO user_data_exports (user_id) VALUES ($1)
+		RETURNING `+exportCols, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert data export: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit data export: %w", err)
+	}
+	return &RequestResult{Export: created, Created: true}, nil
+}
+
+// Latest returns the user's most recent export, or nil if they never
+// requested one.
+func (s *Store) Latest(ctx context.Context, userID int64) (*Export, error) {
+	ctx, span := tracer.Start(ctx, "db.latest_data_export",
+		trace.WithAttributes(attribute.Int64("user.id", userID)))
+	defer span.End()
+
+	e, err := scanExport(s.conn().QueryRowContext(ctx, `
+		SELECT `+exportCols+`
+		FROM user_data_exports
+		WHERE user_id = $1
+		ORDER BY requested_at DESC
+		LIMIT 1`, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get latest data export: %w", err)
+	}
+	return e, nil
+}
+
+// Claim marks the oldest pending export as processing and returns it, or nil
+// when the queue is empty. An export left processing for longer than
+// staleAfter (its worker died mid-build) is claimed again. Rows are locked
+// FOR UPDATE SKIP LOCKED, so concurrent workers claim different exports.
+func (s *Store) Claim(ctx context.Context, staleAfter time.Duration) (*Export, error) {
+	ctx, span := tracer.Start(ctx, "db.claim_data_export")
+	defer span.End()
+
+	e, err := scanExport(s.conn().QueryRowContext(ctx, `
+		UPDATE user_data_exports
+		SET status = $1, started_at = NOW()
+		WHERE id = (
+			SELECT id FROM user_data_exports
+			WHERE status = $2
+			   OR (status = $1 AND started_at < NOW() - make_interval(secs => $3))
+			ORDER BY requested_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+exportCols,
+		StatusProcessing, StatusPending, staleAfter.Seconds()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to claim data export: %w", err)
+	}
+	span.SetAttributes(
+		attribute.Int64("export.id", e.ID),
+		attribute.Int64("user.id", e.UserID),
+	)
+	return e, nil
+}
+
+// MarkReady records a built archive. expiresAt is when the worker deletes it.
+func (s *Store) MarkReady(ctx context.Context, id int64, objectKey string, sizeBytes int64, sessionCount int, expiresAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "db.mark_data_export_ready",
+		trace.WithAttributes(attribute.Int64("export.id", id)))
+	defer span.End()
+
+	_, err := s.conn().ExecContext(ctx, `
+		UPDATE user_data_exports
+		SET status = $2, completed_at = NOW(), expires_at = $3,
+		    object_key = $4, size_bytes = $5, session_count = $6, error = NULL
+		WHERE id = $1`,
+		id, StatusReady, expiresAt, objectKey, sizeBytes, sessionCount)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to mark data export ready: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records why an export could not be built.
+func (s *Store) MarkFailed(ctx context.Context, id int64, reason string) error {
+	ctx, span := tracer.Start(ctx, "db.mark_data_export_failed",
+		trace.WithAttributes(attribute.Int64("export.id", id)))
+	defer span.End()
+
+	_, err := s.conn().ExecContext(ctx, `
+		UPDATE user_data_exports
+		SET status = $2, completed_at = NOW(), error = $3
+		WHERE id = $1`,
+		id, StatusFailed, reason)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to mark data export failed: %w", err)
+	}
+	return nil
+}
+
+// ListExpired returns up to limit ready exports whose expires_at has passed.
+func (s *Store) ListExpired(ctx context.Context, limit int) ([]Export, error) {
+	ctx, span := tracer.Start(ctx, "db.list_expired_data_exports",
+		trace.WithAttributes(attribute.Int("limit", limit)))
+	defer span.End()
+
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT `+exportCols+`
+		FROM user_data_exports
+		WHERE status = $1 AND expires_at <= NOW()
+		ORDER BY expires_at
+		LIMIT $2`, StatusReady, limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to list expired data exports: %w", err)
+	}
+	defer rows.Close()
+
+	var exports []Export
+	for rows.Next() {
+		e, err := scanExport(rows)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("failed to scan data export: %w", err)
+		}
+		exports = append(exports, *e)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("error iterating data exports: %w", err)
+	}
+	return exports, nil
+}
+
+// MarkExpired records that an export's archive has been deleted.
+func (s *Store) MarkExpired(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "db.mark_data_export_expired",
+		trace.WithAttributes(attribute.Int64("export.id", id)))
+	defer span.End()
+
+	_, err := s.conn().ExecContext(ctx, `
+		UPDATE user_data_exports SET status = $2, object_key = NULL WHERE id = $1`,
+		id, StatusExpired)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to mark data export expired: %w", err)
+	}
+	return nil
+}
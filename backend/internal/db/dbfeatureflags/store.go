@@ -0,0 +1,131 @@
+package dbfeatureflags
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// Flag represents a row in the feature_flags table.
+type Flag struct {
+	Name           string
+	EnabledUserIDs []int64
+	EnabledPct     int
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Update holds the fields to change on an existing flag. Nil fields are left
+// as they are.
+type Update struct {
+	EnabledUserIDs *[]int64
+	EnabledPct     *int
+}
+
+// Store provides feature flag database operations.
+type Store struct {
+	DB *db.DB
+}
+
+// conn returns the underlying *sql.DB connection.
+func (s *Store) conn() *sql.DB { return s.DB.Conn() }
+
+const flagColumns = `flag_name, enabled_user_ids, enabled_pct, created_at, updated_at`
+
+func scanFlag(row interface{ Scan(...any) error }) (*Flag, error) {
+	var f Flag
+	var userIDs pq.Int64Array
+	if err := row.Scan(&f.Name, &userIDs, &f.EnabledPct, &f.CreatedAt, &f.UpdatedAt); err != nil {
+		return nil, err
+	}
+	f.EnabledUserIDs = []int64(userIDs)
+	if f.EnabledUserIDs == nil {
+		f.EnabledUserIDs = []int64{}
+	}
+	return &f, nil
+}
+
+// List returns all flags ordered by name.
+func (s *Store) List(ctx context.Context) ([]Flag, error) {
+	rows, err := s.conn().QueryContext(ctx,
+		`SELECT `+flagColumns+` FROM feature_flags ORDER BY flag_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := []Flag{}
+	for rows.Next() {
+		f, err := scanFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, *f)
+	}
+	return flags, rows.Err()
+}
+
+// Get retrieves a flag by name. Returns db.ErrFeatureFlagNotFound when the
+// flag doesn't exist.
+func (s *Store) Get(ctx context.Context, name string) (*Flag, error) {
+	f, err := scanFlag(s.conn().QueryRowContext(ctx,
+		`SELECT `+flagColumns+` FROM feature_flags WHERE flag_name = $1`, name))
+	if err == sql.ErrNoRows {
+		return nil, db.ErrFeatureFlagNotFound
+	}
+	return f, err
+}
+
+// Create inserts a new flag. Returns db.ErrFeatureFlagExists when a flag with
+// the same name already exists.
+func (s *Store) Create(ctx context.Context, name string, enabledUserIDs []int64, enabledPct int) (*Flag, error) {
+	if enabledUserIDs == nil {
+		enabledUserIDs = []int64{}
+	}
+	f, err := scanFlag(s.conn().QueryRowContext(ctx, `
+		INSERT INTO feature_flags (flag_name, enabled_user_ids, enabled_pct)
+		VALUES ($1, $2, $3)
+		RETURNING `+flagColumns,
+		name, pq.Int64Array(enabledUserIDs), enabledPct))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, db.ErrFeatureFlagExists
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// Update applies the non-nil fields of u to the named flag and returns the
+// updated row. Returns db.ErrFeatureFlagNotFound when the flag doesn't exist.
+func (s *Store) Update(ctx context.Context, name string, u Update) (*Flag, error) {
+	var userIDs any
+	if u.EnabledUserIDs != nil {
+		ids := *u.EnabledUserIDs
+		if ids == nil {
+			ids = []int64{}
+		}
+		userIDs = pq.Int64Array(ids)
+	}
+	var pct any
+	if u.EnabledPct != nil {
+		pct = *u.EnabledPct
+	}
+
+	f, err := scanFlag(s.conn().QueryRowContext(ctx, `
+		UPDATE feature_flags SET
+			enabled_user_ids = COALESCE($2::bigint[], enabled_user_ids),
+			enabled_pct = COALESCE($3::int, enabled_pct),
+			updated_at = now()
+		WHERE flag_name = $1
+		RETURNING `+flagColumns,
+		name, userIDs, pct))
+	if err == sql.ErrNoRows {
+		return nil, db.ErrFeatureFlagNotFound
+	}
+	return f, err
+}
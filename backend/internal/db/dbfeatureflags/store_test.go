@@ -0,0 +1,126 @@
+package dbfeatureflags_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dbfeatureflags"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func setupStore(t *testing.T) *dbfeatureflags.Store {
+	t.Helper()
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	if _, err := env.DB.Exec(env.Ctx, `TRUNCATE TABLE feature_flags`); err != nil {
+		t.Fatalf("truncate feature_flags: %v", err)
+	}
+	return &dbfeatureflags.Store{DB: env.DB}
+}
+
+func TestFeatureFlags_CreateGetList(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store := setupStore(t)
+	ctx := context.Background()
+
+	created, err := store.Create(ctx, "workflows_v2", []int64{3, 1}, 10)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.EnabledPct != 10 || !reflect.DeepEqual(created.EnabledUserIDs, []int64{3, 1}) {
+		t.Errorf("created = %+v, want pct 10 and users [3 1]", created)
+	}
+	if created.CreatedAt.IsZero() || created.UpdatedAt.IsZero() {
+		t.Error("expected non-zero timestamps")
+	}
+
+	if _, err := store.Create(ctx, "a_flag", nil, 0); err != nil {
+		t.Fatalf("Create with nil user IDs failed: %v", err)
+	}
+
+	got, err := store.Get(ctx, "a_flag")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.EnabledUserIDs == nil || len(got.EnabledUserIDs) != 0 {
+		t.Errorf("EnabledUserIDs = %#v, want empty non-nil slice", got.EnabledUserIDs)
+	}
+
+	flags, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(flags) != 2 || flags[0].Name != "a_flag" || flags[1].Name != "workflows_v2" {
+		t.Errorf("List = %+v, want [a_flag workflows_v2]", flags)
+	}
+}
+
+func TestFeatureFlags_CreateDuplicate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store := setupStore(t)
+	ctx := context.Background()
+
+	if _, err := store.Create(ctx, "dup", nil, 0); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := store.Create(ctx, "dup", nil, 50); !errors.Is(err, db.ErrFeatureFlagExists) {
+		t.Errorf("second Create err = %v, want ErrFeatureFlagExists", err)
+	}
+}
+
+func TestFeatureFlags_UpdatePartial(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store := setupStore(t)
+	ctx := context.Background()
+
+	if _, err := store.Create(ctx, "rollout", []int64{7}, 5); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	pct := 50
+	updated, err := store.Update(ctx, "rollout", dbfeatureflags.Update{EnabledPct: &pct})
+	if err != nil {
+		t.Fatalf("Update pct failed: %v", err)
+	}
+	if updated.EnabledPct != 50 || !reflect.DeepEqual(updated.EnabledUserIDs, []int64{7}) {
+		t.Errorf("after pct update = %+v, want pct 50 and users kept", updated)
+	}
+
+	ids := []int64{}
+	updated, err = store.Update(ctx, "rollout", dbfeatureflags.Update{EnabledUserIDs: &ids})
+	if err != nil {
+		t.Fatalf("Update users failed: %v", err)
+	}
+	if updated.EnabledPct != 50 || len(updated.EnabledUserIDs) != 0 {
+		t.Errorf("after users update = %+v, want pct kept and users cleared", updated)
+	}
+}
+
+func TestFeatureFlags_NotFound(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store := setupStore(t)
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, db.ErrFeatureFlagNotFound) {
+		t.Errorf("Get err = %v, want ErrFeatureFlagNotFound", err)
+	}
+	pct := 1
+	if _, err := store.Update(ctx, "missing", dbfeatureflags.Update{EnabledPct: &pct}); !errors.Is(err, db.ErrFeatureFlagNotFound) {
+		t.Errorf("Update err = %v, want ErrFeatureFlagNotFound", err)
+	}
+}
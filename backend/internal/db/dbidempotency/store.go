@@ -0,0 +1,141 @@
+package dbidempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+var tracer = otel.Tracer("confab/db/idempotency")
+
+// Record is an idempotency_keys row as a claim sees it.
+type Record struct {
+	UserID      int64
+	RequestHash string
+	// StatusCode is nil while the first request is still being handled.
+	StatusCode  *int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+// ClaimResult is the outcome of Claim. When Claimed is false, Existing is
+// the live record that holds the key.
+type ClaimResult struct {
+	Claimed  bool
+	Existing *Record
+}
+
+// Store provides idempotency key database operations.
+type Store struct {
+	DB *db.DB
+}
+
+// conn returns the underlying *sql.DB connection.
+func (s *Store) conn() *sql.DB { return s.DB.Conn() }
+
+// Claim reserves keyHash for a request whose body hashes to requestHash. It
+// succeeds when no live record holds the key: a completed record counts for
+// ttl and an in-progress one for pendingTimeout, after which it is taken to
+// be abandoned. The reservation is a single upsert, so of two concurrent
+// claims for one key exactly one succeeds; the other gets the winner's
+// record.
+func (s *Store) Claim(ctx context.Context, keyHash string, userID int64, requestHash string, ttl, pendingTimeout time.Duration) (*ClaimResult, error) {
+	ctx, span := tracer.Start(ctx, "db.claim_idempotency_key",
+		trace.WithAttributes(attribute.Int64("user.id", userID)))
+	defer span.End()
+
+	result, err := s.claim(ctx, keyHash, userID, requestHash, ttl, pendingTimeout)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Bool("idempotency.claimed", result.Claimed))
+	return result, nil
+}
+
+func (s *Store) claim(ctx context.Context, keyHash string, userID int64, requestHash string, ttl, pendingTimeout time.Duration) (*ClaimResult, error) {
+	// A record released between the upsert and the read is gone; claiming
+	// again then succeeds, or finds whoever took the key next.
+	for range 2 {
+		var claimed string
+		err := s.conn().QueryRowContext(ctx, `
+			INSERT INTO idempotency_keys (key_hash, user_id, request_hash)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (key_hash) DO UPDATE
+				SET request_hash = EXCLUDED.request_hash, status_code = NULL,
+					content_type = '', response_body = NULL, created_at = NOW()
+				WHERE idempotency_keys.created_at < NOW() - make_interval(secs => $4)
+					OR (idempotency_keys.status_code IS NULL
+						AND idempotency_keys.created_at < NOW() - make_interval(secs => $5))
+			RETURNING key_hash`,
+			keyHash, userID, requestHash, ttl.Seconds(), pendingTimeout.Seconds()).Scan(&claimed)
+		if err == nil {
+			return &ClaimResult{Claimed: true}, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+		}
+
+		var rec Record
+		err = s.conn().QueryRowContext(ctx, `
+			SELECT user_id, request_hash, status_code, content_type, response_body, created_at
+			FROM idempotency_keys
+			WHERE key_hash = $1`, keyHash).
+			Scan(&rec.UserID, &rec.RequestHash, &rec.StatusCode, &rec.ContentType, &rec.Body, &rec.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read idempotency key: %w", err)
+		}
+		return &ClaimResult{Existing: &rec}, nil
+	}
+	return nil, fmt.Errorf("failed to claim idempotency key: record released twice during the claim")
+}
+
+// Complete stores the response of the request that claimed keyHash. Replays
+// are served it until the record is ttl old, counted from the claim.
+func (s *Store) Complete(ctx context.Context, keyHash string, statusCode int, contentType string, body []byte) error {
+	ctx, span := tracer.Start(ctx, "db.complete_idempotency_key",
+		trace.WithAttributes(attribute.Int("http.status_code", statusCode)))
+	defer span.End()
+
+	_, err := s.conn().ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $2, content_type = $3, response_body = $4
+		WHERE key_hash = $1 AND status_code IS NULL`,
+		keyHash, statusCode, contentType, body)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Release deletes the in-progress record for keyHash, so a retry with the
+// same key runs the request again. A completed record is left alone.
+func (s *Store) Release(ctx context.Context, keyHash string) error {
+	ctx, span := tracer.Start(ctx, "db.release_idempotency_key")
+	defer span.End()
+
+	_, err := s.conn().ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE key_hash = $1 AND status_code IS NULL`, keyHash)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
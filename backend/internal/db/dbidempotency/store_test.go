@@ -0,0 +1,140 @@
+package dbidempotency_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db/dbidempotency"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestClaim_ConcurrentClaimsHaveOneWinner(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbidempotency.Store{DB: env.DB}
+	ctx := context.Background()
+	user := testutil.CreateTestUser(t, env, "idem@example.com", "Idem")
+
+	const n = 8
+	results := make([]*dbidempotency.ClaimResult, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = store.Claim(ctx, "key-a", user.ID, "body-a", time.Hour, time.Minute)
+		}()
+	}
+	wg.Wait()
+
+	claimed := 0
+	for i, r := range results {
+		if errs[i] != nil {
+			t.Fatalf("Claim: %v", errs[i])
+		}
+		if r.Claimed {
+			claimed++
+			continue
+		}
+		if r.Existing == nil || r.Existing.StatusCode != nil || r.Existing.RequestHash != "body-a" {
+			t.Errorf("losing claim = %+v, want the in-progress record", r.Existing)
+		}
+	}
+	if claimed != 1 {
+		t.Fatalf("%d claims won, want 1", claimed)
+	}
+
+	if err := store.Complete(ctx, "key-a", 201, "application/json", []byte(`{"id":1}`)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	got, err := store.Claim(ctx, "key-a", user.ID, "body-b", time.Hour, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim after Complete: %v", err)
+	}
+	if got.Claimed || got.Existing.StatusCode == nil || *got.Existing.StatusCode != 201 ||
+		got.Existing.ContentType != "application/json" || string(got.Existing.Body) != `{"id":1}` ||
+		got.Existing.RequestHash != "body-a" {
+		t.Fatalf("Claim after Complete = %+v, want the stored response", got.Existing)
+	}
+
+	// Release leaves a completed record alone.
+	if err := store.Release(ctx, "key-a"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if got, err := store.Claim(ctx, "key-a", user.ID, "body-a", time.Hour, time.Minute); err != nil || got.Claimed {
+		t.Fatalf("Claim after releasing a completed record = %+v, %v; want it kept", got, err)
+	}
+}
+
+func TestClaim_ReleaseAndExpiry(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbidempotency.Store{DB: env.DB}
+	ctx := context.Background()
+	user := testutil.CreateTestUser(t, env, "idem@example.com", "Idem")
+
+	claim := func(key string) bool {
+		t.Helper()
+		got, err := store.Claim(ctx, key, user.ID, "body", time.Hour, time.Minute)
+		if err != nil {
+			t.Fatalf("Claim(%s): %v", key, err)
+		}
+		return got.Claimed
+	}
+
+	// A released claim can be taken again at once.
+	if !claim("released") {
+		t.Fatal("first claim lost")
+	}
+	if err := store.Release(ctx, "released"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if !claim("released") {
+		t.Error("claim after Release lost, want it free")
+	}
+
+	// An in-progress record past the pending timeout is abandoned.
+	if !claim("stale") {
+		t.Fatal("first claim lost")
+	}
+	if _, err := env.DB.Exec(env.Ctx,
+		`UPDATE idempotency_keys SET created_at = NOW() - INTERVAL '2 minutes' WHERE key_hash = 'stale'`); err != nil {
+		t.Fatalf("age record: %v", err)
+	}
+	if !claim("stale") {
+		t.Error("claim over an abandoned record lost")
+	}
+
+	// A completed record counts until the TTL, then the key is free again.
+	if !claim("done") {
+		t.Fatal("first claim lost")
+	}
+	if err := store.Complete(ctx, "done", 200, "application/json", []byte(`{}`)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if _, err := env.DB.Exec(env.Ctx,
+		`UPDATE idempotency_keys SET created_at = NOW() - INTERVAL '30 minutes' WHERE key_hash = 'done'`); err != nil {
+		t.Fatalf("age record: %v", err)
+	}
+	if claim("done") {
+		t.Error("completed record inside the TTL was replaced")
+	}
+	if _, err := env.DB.Exec(env.Ctx,
+		`UPDATE idempotency_keys SET created_at = NOW() - INTERVAL '2 hours' WHERE key_hash = 'done'`); err != nil {
+		t.Fatalf("age record: %v", err)
+	}
+	if !claim("done") {
+		t.Error("claim over an expired record lost")
+	}
+}
@@ -0,0 +1,135 @@
+package dbnotify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+var tracer = otel.Tracer("confab/db/notify")
+
+// Queued is a claimed notification_queue row: a notification held back by
+// the user's quiet hours. Attempts counts this attempt.
+type Queued struct {
+	ID        int64
+	UserID    int64
+	Type      string
+	Channel   string
+	Payload   json.RawMessage
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// Store provides notification queue database operations.
+type Store struct {
+	DB *db.DB
+}
+
+// conn returns the underlying *sql.DB connection.
+func (s *Store) conn() *sql.DB { return s.DB.Conn() }
+
+// Enqueue holds a notification for userID on one channel until deliverAfter.
+func (s *Store) Enqueue(ctx context.Context, userID int64, notificationType, channel string, payload json.RawMessage, deliverAfter time.Time) error {
+	ctx, span := tracer.Start(ctx, "db.enqueue_notification",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.String("notification.type", notificationType),
+			attribute.String("notification.channel", channel),
+		))
+	defer span.End()
+
+	_, err := s.conn().ExecContext(ctx, `
+		INSERT INTO notification_queue (user_id, notification_type, channel, payload, deliver_after)
+		VALUES ($1, $2, $3, $4, $5)`,
+		userID, notificationType, channel, []byte(payload), deliverAfter)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to enqueue notification: %w", err)
+	}
+	return nil
+}
+
+// ClaimDue returns up to limit queued notifications whose quiet window has
+// ended, in the order they fell due and, within that, the order they were
+// queued. It counts the attempt and moves deliver_after lease into the
+// future, so a flush that dies mid-send leaves the row to be claimed again.
+// Rows are locked FOR UPDATE SKIP LOCKED, so concurrent workers claim
+// different notifications.
+func (s *Store) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]Queued, error) {
+	ctx, span := tracer.Start(ctx, "db.claim_queued_notifications",
+		trace.WithAttributes(attribute.Int("limit", limit)))
+	defer span.End()
+
+	// RETURNING has no order, so the claimed rows are sorted by the
+	// deliver_after they had before the lease moved it.
+	rows, err := s.conn().QueryContext(ctx, `
+		WITH due AS (
+			SELECT id, deliver_after FROM notification_queue
+			WHERE deliver_after <= NOW()
+			ORDER BY deliver_after, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE notification_queue q
+			SET attempts = q.attempts + 1,
+			    deliver_after = NOW() + make_interval(secs => $2)
+			FROM due
+			WHERE q.id = due.id
+			RETURNING q.id, q.user_id, q.notification_type, q.channel, q.payload, q.attempts, q.created_at,
+				due.deliver_after AS due_at
+		)
+		SELECT id, user_id, notification_type, channel, payload, attempts, created_at
+		FROM claimed
+		ORDER BY due_at, id`,
+		limit, lease.Seconds())
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to claim queued notifications: %w", err)
+	}
+	defer rows.Close()
+
+	queued := []Queued{}
+	for rows.Next() {
+		var q Queued
+		var payload []byte
+		if err := rows.Scan(&q.ID, &q.UserID, &q.Type, &q.Channel, &payload, &q.Attempts, &q.CreatedAt); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("failed to scan queued notification: %w", err)
+		}
+		q.Payload = payload
+		queued = append(queued, q)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("error iterating queued notifications: %w", err)
+	}
+	span.SetAttributes(attribute.Int("notification.claimed", len(queued)))
+	return queued, nil
+}
+
+// Delete removes a queued notification once it is sent or given up on.
+func (s *Store) Delete(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "db.delete_queued_notification",
+		trace.WithAttributes(attribute.Int64("notification.id", id)))
+	defer span.End()
+
+	if _, err := s.conn().ExecContext(ctx, `DELETE FROM notification_queue WHERE id = $1`, id); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to delete queued notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package dbnotify_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db/dbnotify"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestClaimDue_OrderAndLease(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbnotify.Store{DB: env.DB}
+	ctx := context.Background()
+	user := testutil.CreateTestUser(t, env, "quiet@example.com", "Quiet")
+
+	now := time.Now()
+	enqueue := func(t *testing.T, channel string, deliverAfter time.Time) {
+		t.Helper()
+		if err := store.Enqueue(ctx, user.ID, "test.event", channel, []byte(`{"subject":"hi"}`), deliverAfter); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	// Queued out of order: the later window first, then two sharing a window.
+	enqueue(t, "email", now.Add(-time.Minute))
+	enqueue(t, "webhook", now.Add(-time.Hour))
+	enqueue(t, "email", now.Add(-time.Hour))
+	enqueue(t, "email", now.Add(time.Hour)) // still quiet
+
+	claimed, err := store.ClaimDue(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDue: %v", err)
+	}
+	if len(claimed) != 3 {
+		t.Fatalf("claimed %d, want the 3 due", len(claimed))
+	}
+	// Earliest window first; within one window, queue order.
+	if claimed[0].Channel != "webhook" || claimed[1].Channel != "email" || claimed[0].ID > claimed[1].ID ||
+		claimed[2].ID > claimed[0].ID {
+		t.Errorf("claim order = %+v", claimed)
+	}
+	if claimed[0].Attempts != 1 || claimed[0].UserID != user.ID || claimed[0].Type != "test.event" ||
+		string(claimed[0].Payload) != `{"subject": "hi"}` {
+		t.Errorf("claimed[0] = %+v", claimed[0])
+	}
+
+	// Leased: not due again until the lease runs out.
+	if again, err := store.ClaimDue(ctx, 10, time.Minute); err != nil || len(again) != 0 {
+		t.Fatalf("second ClaimDue = %+v, %v; want nothing", again, err)
+	}
+
+	for _, q := range claimed {
+		if err := store.Delete(ctx, q.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+	}
+	var remaining int
+	if err := env.DB.QueryRow(env.Ctx, `SELECT COUNT(*) FROM notification_queue`).Scan(&remaining); err != nil || remaining != 1 {
+		t.Errorf("queued after delete = %d, %v; want the one still quiet", remaining, err)
+	}
+}
@@ -0,0 +1,127 @@
+package dborg
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// Org is an orgs row with its members.
+type Org struct {
+	ID            int64
+	Name          string
+	MemberUserIDs []int64 // ascending; never nil
+	CreatedAt     time.Time
+}
+
+// Store provides organization database operations.
+type Store struct {
+	DB *db.DB
+}
+
+// conn returns the underlying *sql.DB connection.
+func (s *Store) conn() *sql.DB { return s.DB.Conn() }
+
+const orgQuery = `
+	SELECT o.id, o.name, o.created_at,
+	       COALESCE(array_agg(m.user_id ORDER BY m.user_id) FILTER (WHERE m.user_id IS NOT NULL), '{}')
+	FROM orgs o
+	LEFT JOIN org_members m ON m.org_id = o.id`
+
+func scanOrg(row interface{ Scan(...any) error }) (*Org, error) {
+	var o Org
+	var members pq.Int64Array
+	if err := row.Scan(&o.ID, &o.Name, &o.CreatedAt, &members); err != nil {
+		return nil, err
+	}
+	o.MemberUserIDs = []int64(members)
+	if o.MemberUserIDs == nil {
+		o.MemberUserIDs = []int64{}
+	}
+	return &o, nil
+}
+
+// List returns all organizations ordered by name. Returns an empty slice, not
+// nil, when there are none.
+func (s *Store) List(ctx context.Context) ([]Org, error) {
+	rows, err := s.conn().QueryContext(ctx, orgQuery+` GROUP BY o.id ORDER BY o.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []Org{}
+	for rows.Next() {
+		o, err := scanOrg(rows)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, *o)
+	}
+	return orgs, rows.Err()
+}
+
+// Get returns one organization, or db.ErrOrgNotFound.
+func (s *Store) Get(ctx context.Context, id int64) (*Org, error) {
+	o, err := scanOrg(s.conn().QueryRowContext(ctx, orgQuery+` WHERE o.id = $1 GROUP BY o.id`, id))
+	if err == sql.ErrNoRows {
+		return nil, db.ErrOrgNotFound
+	}
+	return o, err
+}
+
+// Create inserts an organization with no members. Returns db.ErrOrgExists
+// when the name is taken.
+func (s *Store) Create(ctx context.Context, name string) (*Org, error) {
+	o := Org{Name: name, MemberUserIDs: []int64{}}
+	err := s.conn().QueryRowContext(ctx,
+		`INSERT INTO orgs (name) VALUES ($1) RETURNING id, created_at`, name).Scan(&o.ID, &o.CreatedAt)
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, db.ErrOrgExists
+		}
+		return nil, err
+	}
+	return &o, nil
+}
+
+// AddMember adds userID to the organization; adding an existing member is a
+// no-op. Returns db.ErrOrgNotFound or db.ErrUserNotFound when either is
+// missing.
+func (s *Store) AddMember(ctx context.Context, orgID, userID int64) error {
+	var orgExists, userExists bool
+	if err := s.conn().QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM orgs WHERE id = $1), EXISTS(SELECT 1 FROM users WHERE id = $2)`,
+		orgID, userID).Scan(&orgExists, &userExists); err != nil {
+		return err
+	}
+	if !orgExists {
+		return db.ErrOrgNotFound
+	}
+	if !userExists {
+		return db.ErrUserNotFound
+	}
+	_, err := s.conn().ExecContext(ctx,
+		`INSERT INTO org_members (org_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, orgID, userID)
+	return err
+}
+
+// RemoveMember removes userID from the organization; removing a non-member
+// is a no-op. Returns db.ErrOrgNotFound when the organization is missing.
+func (s *Store) RemoveMember(ctx context.Context, orgID, userID int64) error {
+	var orgExists bool
+	if err := s.conn().QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM orgs WHERE id = $1)`, orgID).Scan(&orgExists); err != nil {
+		return err
+	}
+	if !orgExists {
+		return db.ErrOrgNotFound
+	}
+	_, err := s.conn().ExecContext(ctx,
+		`DELETE FROM org_members WHERE org_id = $1 AND user_id = $2`, orgID, userID)
+	return err
+}
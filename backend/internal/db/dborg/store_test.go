@@ -0,0 +1,146 @@
+package dborg_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dborg"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func setupStore(t *testing.T) (*testutil.TestEnvironment, *dborg.Store) {
+	t.Helper()
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	return env, &dborg.Store{DB: env.DB}
+}
+
+func TestOrgs_CreateGetList(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	_, store := setupStore(t)
+	ctx := context.Background()
+
+	created, err := store.Create(ctx, "zeta")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.ID == 0 || created.CreatedAt.IsZero() || created.MemberUserIDs == nil {
+		t.Errorf("created = %+v, want an ID, a timestamp and empty members", created)
+	}
+	if _, err := store.Create(ctx, "alpha"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	got, err := store.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.Name != "zeta" || len(got.MemberUserIDs) != 0 {
+		t.Errorf("Get = %+v, want zeta with no members", got)
+	}
+
+	orgs, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(orgs) != 2 || orgs[0].Name != "alpha" || orgs[1].Name != "zeta" {
+		t.Errorf("List = %+v, want [alpha zeta]", orgs)
+	}
+}
+
+func TestOrgs_DuplicateName(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	_, store := setupStore(t)
+	ctx := context.Background()
+
+	if _, err := store.Create(ctx, "acme"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := store.Create(ctx, "acme"); !errors.Is(err, db.ErrOrgExists) {
+		t.Errorf("duplicate Create error = %v, want ErrOrgExists", err)
+	}
+}
+
+func TestOrgs_Members(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env, store := setupStore(t)
+	ctx := context.Background()
+
+	a := testutil.CreateTestUser(t, env, "a@example.com", "A")
+	b := testutil.CreateTestUser(t, env, "b@example.com", "B")
+	org, err := store.Create(ctx, "acme")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	for _, id := range []int64{b.ID, a.ID, a.ID} {
+		if err := store.AddMember(ctx, org.ID, id); err != nil {
+			t.Fatalf("AddMember(%d) failed: %v", id, err)
+		}
+	}
+	got, err := store.Get(ctx, org.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	want := []int64{a.ID, b.ID}
+	if b.ID < a.ID {
+		want = []int64{b.ID, a.ID}
+	}
+	if !reflect.DeepEqual(got.MemberUserIDs, want) {
+		t.Errorf("members = %v, want %v", got.MemberUserIDs, want)
+	}
+
+	if err := store.RemoveMember(ctx, org.ID, a.ID); err != nil {
+		t.Fatalf("RemoveMember failed: %v", err)
+	}
+	if err := store.RemoveMember(ctx, org.ID, a.ID); err != nil {
+		t.Fatalf("RemoveMember of a non-member failed: %v", err)
+	}
+	got, err = store.Get(ctx, org.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !reflect.DeepEqual(got.MemberUserIDs, []int64{b.ID}) {
+		t.Errorf("members after remove = %v, want [%d]", got.MemberUserIDs, b.ID)
+	}
+}
+
+func TestOrgs_NotFound(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env, store := setupStore(t)
+	ctx := context.Background()
+	user := testutil.CreateTestUser(t, env, "a@example.com", "A")
+
+	if _, err := store.Get(ctx, 999999); !errors.Is(err, db.ErrOrgNotFound) {
+		t.Errorf("Get error = %v, want ErrOrgNotFound", err)
+	}
+	if err := store.AddMember(ctx, 999999, user.ID); !errors.Is(err, db.ErrOrgNotFound) {
+		t.Errorf("AddMember error = %v, want ErrOrgNotFound", err)
+	}
+	if err := store.RemoveMember(ctx, 999999, user.ID); !errors.Is(err, db.ErrOrgNotFound) {
+		t.Errorf("RemoveMember error = %v, want ErrOrgNotFound", err)
+	}
+
+	org, err := store.Create(ctx, "acme")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := store.AddMember(ctx, org.ID, 999999); !errors.Is(err, db.ErrUserNotFound) {
+		t.Errorf("AddMember error = %v, want ErrUserNotFound", err)
+	}
+}
@@ -0,0 +1,194 @@
+package dbreconciliation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+var tracer = otel.Tracer("confab/db/reconciliation")
+
+// UsageRow is the actual usage of one model family on one UTC day.
+type UsageRow struct {
+	Date             time.Time // UTC midnight
+	Model            string
+	CostUSD          decimal.Decimal
+	InputTokens      int64
+	OutputTokens     int64
+	CacheWriteTokens int64
+	CacheReadTokens  int64
+}
+
+// CorrectionFactor is the operator-set ratio of actual to estimated cost for
+// one calendar month.
+type CorrectionFactor struct {
+	Month     time.Time // first day of the month, UTC
+	Factor    decimal.Decimal
+	Note      string
+	UpdatedAt time.Time
+}
+
+// Store provides usage reconciliation database operations.
+type Store struct {
+	DB *db.DB
+}
+
+// conn returns the underlying *sql.DB connection.
+func (s *Store) conn() *sql.DB { return s.DB.Conn() }
+
+// ReplaceDays stores rows as the actual usage of every day they cover. All
+// existing rows for those days are deleted first, in the same transaction, so
+// re-importing an export replaces it rather than adding to it. Rows must be
+// unique per (Date, Model).
+func (s *Store) ReplaceDays(ctx context.Context, rows []UsageRow, importedBy int64) error {
+	ctx, span := tracer.Start(ctx, "db.reconciliation.replace_days",
+		trace.WithAttributes(attribute.Int("rows.count", len(rows))))
+	defer span.End()
+
+	if err := s.replaceDays(ctx, rows, importedBy); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *Store) replaceDays(ctx context.Context, rows []UsageRow, importedBy int64) error {
+	days := map[time.Time]struct{}{}
+	for _, r := range rows {
+		days[r.Date] = struct{}{}
+	}
+	dayList := make([]string, 0, len(days))
+	for d := range days {
+		dayList = append(dayList, d.Format(time.DateOnly))
+	}
+
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin reconciliation import: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM usage_reconciliation WHERE usage_date = ANY($1::date[])`, pq.Array(dayList)); err != nil {
+		return fmt.Errorf("failed to clear imported days: %w", err)
+	}
+	for _, r := range rows {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO usage_reconciliation
+				(usage_date, model, cost_usd, input_tokens, output_tokens, cache_write_tokens, cache_read_tokens, imported_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			r.Date.Format(time.DateOnly), r.Model, r.CostUSD.String(),
+			r.InputTokens, r.OutputTokens, r.CacheWriteTokens, r.CacheReadTokens, importedBy); err != nil {
+			return fmt.Errorf("failed to insert usage for %s %s: %w", r.Date.Format(time.DateOnly), r.Model, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit reconciliation import: %w", err)
+	}
+	return nil
+}
+
+// ListUsage returns the imported usage for days in [from, to), ordered by day
+// then model; an empty slice, not nil, when there is none.
+func (s *Store) ListUsage(ctx context.Context, from, to time.Time) ([]UsageRow, error) {
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT usage_date, model, cost_usd, input_tokens, output_tokens, cache_write_tokens, cache_read_tokens
+		FROM usage_reconciliation
+		WHERE usage_date >= $1::date AND usage_date < $2::date
+		ORDER BY usage_date, model`,
+		from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list imported usage: %w", err)
+	}
+	defer rows.Close()
+
+	out := []UsageRow{}
+	for rows.Next() {
+		var r UsageRow
+		if err := rows.Scan(&r.Date, &r.Model, &r.CostUSD, &r.InputTokens, &r.OutputTokens, &r.CacheWriteTokens, &r.CacheReadTokens); err != nil {
+			return nil, fmt.Errorf("failed to scan imported usage: %w", err)
+		}
+		r.Date = r.Date.UTC()
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list imported usage: %w", err)
+	}
+	return out, nil
+}
+
+// SetCorrectionFactor creates or replaces the correction factor for month
+// (the first day of a month, UTC).
+func (s *Store) SetCorrectionFactor(ctx context.Context, month time.Time, factor decimal.Decimal, note string, updatedBy int64) (*CorrectionFactor, error) {
+	cf := CorrectionFactor{}
+	err := s.conn().QueryRowContext(ctx, `
+		INSERT INTO usage_correction_factors (month, factor, note, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (month) DO UPDATE
+			SET factor = EXCLUDED.factor, note = EXCLUDED.note,
+				updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING month, factor, note, updated_at`,
+		month.Format(time.DateOnly), factor.String(), note, updatedBy).
+		Scan(&cf.Month, &cf.Factor, &cf.Note, &cf.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set correction factor: %w", err)
+	}
+	cf.Month = cf.Month.UTC()
+	return &cf, nil
+}
+
+// DeleteCorrectionFactor removes month's correction factor and reports whether
+// there was one.
+func (s *Store) DeleteCorrectionFactor(ctx context.Context, month time.Time) (bool, error) {
+	res, err := s.conn().ExecContext(ctx,
+		`DELETE FROM usage_correction_factors WHERE month = $1`, month.Format(time.DateOnly))
+	if err != nil {
+		return false, fmt.Errorf("failed to delete correction factor: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete correction factor: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CorrectionFactors returns the factors for months starting in [from, to),
+// oldest first; an empty slice, not nil, when none are set.
+func (s *Store) CorrectionFactors(ctx context.Context, from, to time.Time) ([]CorrectionFactor, error) {
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT month, factor, note, updated_at
+		FROM usage_correction_factors
+		WHERE month >= $1::date AND month < $2::date
+		ORDER BY month`,
+		from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list correction factors: %w", err)
+	}
+	defer rows.Close()
+
+	out := []CorrectionFactor{}
+	for rows.Next() {
+		var cf CorrectionFactor
+		if err := rows.Scan(&cf.Month, &cf.Factor, &cf.Note, &cf.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan correction factor: %w", err)
+		}
+		cf.Month = cf.Month.UTC()
+		out = append(out, cf)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list correction factors: %w", err)
+	}
+	return out, nil
+}
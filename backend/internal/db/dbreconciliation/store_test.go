@@ -0,0 +1,107 @@
+package dbreconciliation_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ConfabulousDev/confab-web/internal/db/dbreconciliation"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestReplaceDays_ReplacesImportedDays(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbreconciliation.Store{DB: env.DB}
+	ctx := context.Background()
+	admin := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+
+	day1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	usage := func(day time.Time, model, cost string) dbreconciliation.UsageRow {
+		return dbreconciliation.UsageRow{Date: day, Model: model, CostUSD: decimal.RequireFromString(cost), InputTokens: 10}
+	}
+
+	if err := store.ReplaceDays(ctx, []dbreconciliation.UsageRow{
+		usage(day1, "opus-4-5", "1.25"),
+		usage(day1, "sonnet-4-5", "0.50"),
+		usage(day2, "opus-4-5", "2"),
+	}, admin.ID); err != nil {
+		t.Fatalf("ReplaceDays: %v", err)
+	}
+	// Re-importing day 1 drops its sonnet row and leaves day 2 alone.
+	if err := store.ReplaceDays(ctx, []dbreconciliation.UsageRow{usage(day1, "opus-4-5", "1.30")}, admin.ID); err != nil {
+		t.Fatalf("ReplaceDays: %v", err)
+	}
+
+	rows, err := store.ListUsage(ctx, day1, day1.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("ListUsage: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2: %+v", len(rows), rows)
+	}
+	if !rows[0].Date.Equal(day1) || rows[0].Model != "opus-4-5" || rows[0].CostUSD.String() != "1.3" || rows[0].InputTokens != 10 {
+		t.Errorf("day 1 = %+v", rows[0])
+	}
+	if !rows[1].Date.Equal(day2) || rows[1].CostUSD.String() != "2" {
+		t.Errorf("day 2 = %+v", rows[1])
+	}
+
+	empty, err := store.ListUsage(ctx, day1.AddDate(0, 1, 0), day1.AddDate(0, 2, 0))
+	if err != nil || empty == nil || len(empty) != 0 {
+		t.Errorf("ListUsage(next month) = %v, %v; want empty", empty, err)
+	}
+}
+
+func TestCorrectionFactors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbreconciliation.Store{DB: env.DB}
+	ctx := context.Background()
+	admin := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := march.AddDate(0, 1, 0)
+
+	if _, err := store.SetCorrectionFactor(ctx, march, decimal.RequireFromString("1.05"), "first pass", admin.ID); err != nil {
+		t.Fatalf("SetCorrectionFactor: %v", err)
+	}
+	cf, err := store.SetCorrectionFactor(ctx, march, decimal.RequireFromString("1.08"), "invoice", admin.ID)
+	if err != nil {
+		t.Fatalf("SetCorrectionFactor: %v", err)
+	}
+	if !cf.Month.Equal(march) || cf.Factor.String() != "1.08" || cf.Note != "invoice" {
+		t.Errorf("factor = %+v", cf)
+	}
+	if _, err := store.SetCorrectionFactor(ctx, april, decimal.RequireFromString("0.9"), "", admin.ID); err != nil {
+		t.Fatalf("SetCorrectionFactor: %v", err)
+	}
+
+	factors, err := store.CorrectionFactors(ctx, march, april)
+	if err != nil {
+		t.Fatalf("CorrectionFactors: %v", err)
+	}
+	if len(factors) != 1 || factors[0].Factor.String() != "1.08" {
+		t.Errorf("March factors = %+v, want just 1.08", factors)
+	}
+
+	deleted, err := store.DeleteCorrectionFactor(ctx, march)
+	if err != nil || !deleted {
+		t.Fatalf("DeleteCorrectionFactor = %v, %v; want true", deleted, err)
+	}
+	deleted, err = store.DeleteCorrectionFactor(ctx, march)
+	if err != nil || deleted {
+		t.Errorf("second DeleteCorrectionFactor = %v, %v; want false", deleted, err)
+	}
+}
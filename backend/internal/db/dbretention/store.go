@@ -0,0 +1,183 @@
+package dbretention
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+var tracer = otel.Tracer("confab/db/retention")
+
+// Policy says how long rows of one table are kept. Rows whose TimeColumn is
+// older than now - Retention are pruned; a zero Retention disables pruning.
+type Policy struct {
+	Table      string
+	TimeColumn string
+	Retention  time.Duration
+}
+
+// Defaults lists every prunable table with its default retention. Table and
+// column names are interpolated into SQL, so Prune only accepts pairs that
+// appear here.
+var Defaults = []Policy{
+	// Admin card-invalidation audit trail. Rows also bypass the smart-recap
+	// quota on the next recompute, which happens long before a year is up.
+	{Table: "admin_card_invalidations", TimeColumn: "invalidated_at", Retention: 365 * 24 * time.Hour},
+	// Expired browser sessions are never read again.
+	{Table: "web_sessions", TimeColumn: "expires_at", Retention: 30 * 24 * time.Hour},
+	// Device codes expire within minutes of creation.
+	{Table: "device_codes", TimeColumn: "expires_at", Retention: 24 * time.Hour},
+	// Session velocity counters only matter for the last 24 hours; a week
+	// keeps recent trips visible to the admin velocity endpoint.
+	{Table: "api_key_session_velocity", TimeColumn: "bucket_start", Retention: 7 * 24 * time.Hour},
+	// Settled chunk upload events; pending ones (confirmed_at NULL) are never
+	// pruned. A few days leaves time to look into a burst of replays.
+	{Table: "chunk_upload_events", TimeColumn: "confirmed_at", Retention: 3 * 24 * time.Hour},
+	// Finished webhook deliveries; pending ones (completed_at NULL) are never
+	// pruned. A month covers most "did my endpoint get it?" questions.
+	{Table: "webhook_deliveries", TimeColumn: "completed_at", Retention: 30 * 24 * time.Hour},
+	// Redeemed magic-link IDs only matter until the link itself expires.
+	{Table: "magic_link_redemptions", TimeColumn: "expires_at", Retention: 24 * time.Hour},
+	// Daily share view counts. A year of history is plenty for the share
+	// stats endpoint, whose daily series only covers 30 days.
+	{Table: "share_access_log", TimeColumn: "last_viewed_at", Retention: 365 * 24 * time.Hour},
+	// Session state transitions. Nothing reads them in the request path; a
+	// year keeps enough history to answer "why did this session end?".
+	{Table: "session_state_log", TimeColumn: "created_at", Retention: 365 * 24 * time.Hour},
+	// Idempotency-Key records are replayed for 24 hours; a second day
+	// covers clients retrying just past the window.
+	{Table: "idempotency_keys", TimeColumn: "created_at", Retention: 48 * time.Hour},
+	// Daily per-API-key upload counters. Like share views, a year of history
+	// backs lifetime totals; the key usage series only covers 30 days.
+	{Table: "api_key_usage", TimeColumn: "updated_at", Retention: 365 * 24 * time.Hour},
+}
+
+// Run is a retention_prune_runs row: the latest prune of one table.
+type Run struct {
+	Table            string
+	RetentionSeconds int64
+	LastPrunedAt     time.Time
+	LastRowsDeleted  int64
+	TotalRowsDeleted int64
+}
+
+// Store provides retention pruning database operations.
+type Store struct {
+	DB *db.DB
+}
+
+// conn returns the underlying *sql.DB connection.
+func (s *Store) conn() *sql.DB { return s.DB.Conn() }
+
+func isKnown(p Policy) bool {
+	for _, d := range Defaults {
+		if d.Table == p.Table && d.TimeColumn == p.TimeColumn {
+			return true
+		}
+	}
+	return false
+}
+
+// Prune deletes rows of p.Table older than p.Retention, at most batchSize per
+// statement and maxRows in total, oldest first, and returns how many rows it
+// deleted. Each batch locks its rows with FOR UPDATE SKIP LOCKED, so several
+// instances pruning the same table at once split the work instead of blocking
+// on or double-counting each other's rows.
+func (s *Store) Prune(ctx context.Context, p Policy, batchSize, maxRows int) (int64, error) {
+	if !isKnown(p) {
+		return 0, fmt.Errorf("prune: unknown table/column %s.%s", p.Table, p.TimeColumn)
+	}
+	if p.Retention <= 0 || batchSize <= 0 || maxRows <= 0 {
+		return 0, nil
+	}
+
+	// Several of these columns are bare TIMESTAMPs; a UTC cutoff keeps the
+	// worker's local timezone from skewing the window.
+	cutoff := time.Now().UTC().Add(-p.Retention)
+
+	ctx, span := tracer.Start(ctx, "db.retention.prune",
+		trace.WithAttributes(
+			attribute.String("retention.table", p.Table),
+			attribute.String("retention.cutoff", cutoff.String()),
+		))
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s
+		WHERE ctid = ANY(ARRAY(
+			SELECT ctid FROM %[1]s
+			WHERE %[2]s < $1
+			ORDER BY %[2]s
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		))`, p.Table, p.TimeColumn)
+
+	var total int64
+	for total < int64(maxRows) {
+		limit := min(int64(batchSize), int64(maxRows)-total)
+		result, err := s.conn().ExecContext(ctx, query, cutoff, limit)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return total, fmt.Errorf("failed to prune %s: %w", p.Table, err)
+		}
+		deleted, _ := result.RowsAffected()
+		total += deleted
+		if deleted < limit {
+			break
+		}
+	}
+
+	span.SetAttributes(attribute.Int64("retention.deleted", total))
+	return total, nil
+}
+
+// RecordRun upserts the retention_prune_runs row for a table after a prune,
+// adding deleted to its running total.
+func (s *Store) RecordRun(ctx context.Context, p Policy, deleted int64) error {
+	_, err := s.conn().ExecContext(ctx, `
+		INSERT INTO retention_prune_runs
+			(table_name, retention_seconds, last_pruned_at, last_rows_deleted, total_rows_deleted)
+		VALUES ($1, $2, NOW(), $3, $3)
+		ON CONFLICT (table_name) DO UPDATE SET
+			retention_seconds = EXCLUDED.retention_seconds,
+			last_pruned_at = EXCLUDED.last_pruned_at,
+			last_rows_deleted = EXCLUDED.last_rows_deleted,
+			total_rows_deleted = retention_prune_runs.total_rows_deleted + EXCLUDED.last_rows_deleted`,
+		p.Table, int64(p.Retention/time.Second), deleted)
+	if err != nil {
+		return fmt.Errorf("failed to record prune of %s: %w", p.Table, err)
+	}
+	return nil
+}
+
+// ListRuns returns the latest prune of every table that has been pruned,
+// ordered by table name.
+func (s *Store) ListRuns(ctx context.Context) ([]Run, error) {
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT table_name, retention_seconds, last_pruned_at, last_rows_deleted, total_rows_deleted
+		FROM retention_prune_runs
+		ORDER BY table_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []Run{}
+	for rows.Next() {
+		var r Run
+		if err := rows.Scan(&r.Table, &r.RetentionSeconds, &r.LastPrunedAt, &r.LastRowsDeleted, &r.TotalRowsDeleted); err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
@@ -0,0 +1,146 @@
+package dbretention_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db/dbretention"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestPrune_RejectsUnknownTable(t *testing.T) {
+	store := &dbretention.Store{} // nil DB — the allowlist check runs first
+
+	for _, p := range []dbretention.Policy{
+		{Table: "sessions", TimeColumn: "first_seen", Retention: time.Hour},
+		{Table: "web_sessions", TimeColumn: "created_at", Retention: time.Hour},
+	} {
+		if _, err := store.Prune(context.Background(), p, 10, 10); err == nil {
+			t.Errorf("Prune(%s.%s) should be rejected", p.Table, p.TimeColumn)
+		}
+	}
+}
+
+// insertWebSession adds a web session that expired age ago.
+func insertWebSession(t *testing.T, env *testutil.TestEnvironment, id string, userID int64, age time.Duration) {
+	t.Helper()
+	expires := time.Now().UTC().Add(-age)
+	if _, err := env.DB.Exec(env.Ctx,
+		`INSERT INTO web_sessions (id, user_id, created_at, expires_at) VALUES ($1, $2, $3, $3)`,
+		id, userID, expires); err != nil {
+		t.Fatalf("insert web session: %v", err)
+	}
+}
+
+func remainingWebSessions(t *testing.T, env *testutil.TestEnvironment) map[string]bool {
+	t.Helper()
+	rows, err := env.DB.Conn().QueryContext(env.Ctx, `SELECT id FROM web_sessions`)
+	if err != nil {
+		t.Fatalf("query web sessions: %v", err)
+	}
+	defer rows.Close()
+	ids := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		ids[id] = true
+	}
+	return ids
+}
+
+func TestPrune_BatchCappedLeavesNewerRows(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	if _, err := env.DB.Exec(env.Ctx, `TRUNCATE TABLE retention_prune_runs`); err != nil {
+		t.Fatalf("truncate retention_prune_runs: %v", err)
+	}
+	user := testutil.CreateTestUser(t, env, "prune@example.com", "Prune")
+	store := &dbretention.Store{DB: env.DB}
+	ctx := context.Background()
+
+	policy := dbretention.Policy{Table: "web_sessions", TimeColumn: "expires_at", Retention: 30 * 24 * time.Hour}
+	for i, age := range []time.Duration{90, 80, 70, 60, 50} {
+		insertWebSession(t, env, "old-"+string(rune('a'+i)), user.ID, age*24*time.Hour)
+	}
+	insertWebSession(t, env, "recent-expired", user.ID, 2*24*time.Hour)
+	insertWebSession(t, env, "live", user.ID, -24*time.Hour)
+
+	// Batches of 2, capped at 3 rows: the three oldest go, nothing else.
+	deleted, err := store.Prune(ctx, policy, 2, 3)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("deleted = %d, want 3 (row cap)", deleted)
+	}
+	left := remainingWebSessions(t, env)
+	for _, id := range []string{"old-d", "old-e", "recent-expired", "live"} {
+		if !left[id] {
+			t.Errorf("%s should survive the capped run", id)
+		}
+	}
+	if len(left) != 4 {
+		t.Errorf("remaining = %v, want 4 rows", left)
+	}
+	if err := store.RecordRun(ctx, policy, deleted); err != nil {
+		t.Fatalf("RecordRun: %v", err)
+	}
+
+	// The next run finishes the backlog and stops short of the cap.
+	deleted, err = store.Prune(ctx, policy, 2, 100)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("second run deleted = %d, want 2", deleted)
+	}
+	left = remainingWebSessions(t, env)
+	if len(left) != 2 || !left["recent-expired"] || !left["live"] {
+		t.Errorf("remaining = %v, want only rows inside the retention window", left)
+	}
+	if err := store.RecordRun(ctx, policy, deleted); err != nil {
+		t.Fatalf("RecordRun: %v", err)
+	}
+
+	runs, err := store.ListRuns(ctx)
+	if err != nil {
+		t.Fatalf("ListRuns: %v", err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("runs = %+v, want one row", runs)
+	}
+	r := runs[0]
+	if r.Table != "web_sessions" || r.LastRowsDeleted != 2 || r.TotalRowsDeleted != 5 || r.RetentionSeconds != 30*24*3600 {
+		t.Errorf("run = %+v, want web_sessions last 2 total 5", r)
+	}
+	if time.Since(r.LastPrunedAt) > time.Minute {
+		t.Errorf("LastPrunedAt = %s, want recent", r.LastPrunedAt)
+	}
+}
+
+func TestPrune_ZeroRetentionIsNoop(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	user := testutil.CreateTestUser(t, env, "noop@example.com", "Noop")
+	insertWebSession(t, env, "ancient", user.ID, 1000*24*time.Hour)
+
+	store := &dbretention.Store{DB: env.DB}
+	deleted, err := store.Prune(context.Background(), dbretention.Policy{Table: "web_sessions", TimeColumn: "expires_at"}, 10, 10)
+	if err != nil || deleted != 0 {
+		t.Errorf("Prune with zero retention = (%d, %v), want (0, nil)", deleted, err)
+	}
+	if left := remainingWebSessions(t, env); !left["ancient"] {
+		t.Error("zero retention must not delete anything")
+	}
+}
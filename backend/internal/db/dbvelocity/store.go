@@ -0,0 +1,180 @@
+package dbvelocity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+var tracer = otel.Tracer("confab/db/velocity")
+
+// Limits caps how many new sessions one API key may create. A zero field
+// disables that window.
+type Limits struct {
+	PerHour int // sessions in the current clock hour
+	PerDay  int // sessions in the current hour and the 23 before it
+}
+
+// Enabled reports whether any window is capped.
+func (l Limits) Enabled() bool { return l.PerHour > 0 || l.PerDay > 0 }
+
+// Window names the limit a refused admission hit.
+type Window string
+
+const (
+	WindowHour Window = "hour"
+	WindowDay  Window = "day"
+)
+
+// Decision is the outcome of Admit.
+type Decision struct {
+	Allowed bool
+	// Window, Count and Limit describe the exceeded limit when !Allowed.
+	// Count excludes the refused session.
+	Window Window
+	Count  int
+	Limit  int
+	// RetryAfter is the time until the next hour bucket starts, the earliest
+	// moment either window's count can drop. Zero when allowed.
+	RetryAfter time.Duration
+	// Rejected is how many admissions the key has had refused in the current
+	// hour, including this one. Zero when allowed.
+	Rejected int
+}
+
+// TrippedKey is an API key that had sessions refused within the listing
+// window, with its totals over that window.
+type TrippedKey struct {
+	APIKeyID        int64
+	APIKeyName      string
+	UserID          int64
+	UserEmail       string
+	SessionsCreated int
+	Rejected        int
+	LastRejectedAt  time.Time // start of the latest hour bucket with a refusal
+}
+
+// Store provides API key velocity database operations.
+type Store struct {
+	DB *db.DB
+}
+
+// conn returns the underlying *sql.DB connection.
+func (s *Store) conn() *sql.DB { return s.DB.Conn() }
+
+// Admit records one new session for keyID if neither window of limits is
+// full, otherwise records a refusal. Buckets are hours on the database clock,
+// so every instance counts into the same rows. The current bucket's row is
+// locked for the whole check, so concurrent admissions for one key serialize
+// and cannot both take the last slot.
+func (s *Store) Admit(ctx context.Context, keyID int64, limits Limits) (Decision, error) {
+	ctx, span := tracer.Start(ctx, "db.velocity.admit",
+		trace.WithAttributes(attribute.Int64("api_key.id", keyID)))
+	defer span.End()
+
+	decision, err := s.admit(ctx, keyID, limits)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return Decision{}, err
+	}
+	span.SetAttributes(attribute.Bool("velocity.allowed", decision.Allowed))
+	return decision, nil
+}
+
+func (s *Store) admit(ctx context.Context, keyID int64, limits Limits) (Decision, error) {
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		return Decision{}, fmt.Errorf("failed to begin velocity transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Create (or lock) the current bucket. NOW() is fixed for the
+	// transaction, so every statement below agrees on the hour.
+	var bucket, now time.Time
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO api_key_session_velocity (api_key_id, bucket_start)
+		VALUES ($1, date_trunc('hour', NOW()))
+		ON CONFLICT (api_key_id, bucket_start) DO UPDATE SET api_key_id = EXCLUDED.api_key_id
+		RETURNING bucket_start, NOW()`, keyID).Scan(&bucket, &now); err != nil {
+		return Decision{}, fmt.Errorf("failed to lock velocity bucket: %w", err)
+	}
+
+	var hourCount, dayCount int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(sessions_created) FILTER (WHERE bucket_start = $2::timestamptz), 0),
+			COALESCE(SUM(sessions_created), 0)
+		FROM api_key_session_velocity
+		WHERE api_key_id = $1 AND bucket_start > $2::timestamptz - INTERVAL '24 hours'`,
+		keyID, bucket).Scan(&hourCount, &dayCount); err != nil {
+		return Decision{}, fmt.Errorf("failed to count velocity buckets: %w", err)
+	}
+
+	decision := Decision{Allowed: true}
+	switch {
+	case limits.PerHour > 0 && hourCount >= limits.PerHour:
+		decision = Decision{Window: WindowHour, Count: hourCount, Limit: limits.PerHour}
+	case limits.PerDay > 0 && dayCount >= limits.PerDay:
+		decision = Decision{Window: WindowDay, Count: dayCount, Limit: limits.PerDay}
+	}
+
+	if decision.Allowed {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE api_key_session_velocity SET sessions_created = sessions_created + 1
+			WHERE api_key_id = $1 AND bucket_start = $2`, keyID, bucket)
+	} else {
+		decision.RetryAfter = bucket.Add(time.Hour).Sub(now)
+		err = tx.QueryRowContext(ctx, `
+			UPDATE api_key_session_velocity SET rejected = rejected + 1
+			WHERE api_key_id = $1 AND bucket_start = $2
+			RETURNING rejected`, keyID, bucket).Scan(&decision.Rejected)
+	}
+	if err != nil {
+		return Decision{}, fmt.Errorf("failed to update velocity bucket: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Decision{}, fmt.Errorf("failed to commit velocity transaction: %w", err)
+	}
+	return decision, nil
+}
+
+// ListTripped returns every API key with at least one refusal in the last
+// window, most refusals first.
+func (s *Store) ListTripped(ctx context.Context, window time.Duration) ([]TrippedKey, error) {
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT k.id, k.name, u.id, u.email,
+			SUM(v.sessions_created), SUM(v.rejected),
+			MAX(v.bucket_start) FILTER (WHERE v.rejected > 0)
+		FROM api_key_session_velocity v
+		JOIN api_keys k ON k.id = v.api_key_id
+		JOIN users u ON u.id = k.user_id
+		WHERE v.bucket_start > NOW() - make_interval(secs => $1)
+		GROUP BY k.id, k.name, u.id, u.email
+		HAVING SUM(v.rejected) > 0
+		ORDER BY SUM(v.rejected) DESC, k.id`, window.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []TrippedKey{}
+	for rows.Next() {
+		var k TrippedKey
+		if err := rows.Scan(&k.APIKeyID, &k.APIKeyName, &k.UserID, &k.UserEmail,
+			&k.SessionsCreated, &k.Rejected, &k.LastRejectedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
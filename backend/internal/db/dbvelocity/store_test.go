@@ -0,0 +1,155 @@
+package dbvelocity_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db/dbvelocity"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// setupKey returns a fresh API key for the velocity tests.
+func setupKey(t *testing.T, env *testutil.TestEnvironment) int64 {
+	t.Helper()
+	env.CleanDB(t)
+	user := testutil.CreateTestUser(t, env, "velocity@example.com", "Velocity")
+	return testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Velocity Key").ID
+}
+
+// insertBucket writes a bucket hoursAgo hours before the current one.
+func insertBucket(t *testing.T, env *testutil.TestEnvironment, keyID int64, hoursAgo, sessions int) {
+	t.Helper()
+	if _, err := env.DB.Exec(env.Ctx, `
+		INSERT INTO api_key_session_velocity (api_key_id, bucket_start, sessions_created)
+		VALUES ($1, date_trunc('hour', NOW()) - make_interval(hours => $2), $3)`,
+		keyID, hoursAgo, sessions); err != nil {
+		t.Fatalf("insert bucket: %v", err)
+	}
+}
+
+func admitN(t *testing.T, store *dbvelocity.Store, keyID int64, limits dbvelocity.Limits, n int) dbvelocity.Decision {
+	t.Helper()
+	var d dbvelocity.Decision
+	for i := 0; i < n; i++ {
+		var err error
+		d, err = store.Admit(context.Background(), keyID, limits)
+		if err != nil {
+			t.Fatalf("Admit: %v", err)
+		}
+	}
+	return d
+}
+
+func TestAdmit_HourlyBoundary(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	env := testutil.SetupTestEnvironment(t)
+	keyID := setupKey(t, env)
+	store := &dbvelocity.Store{DB: env.DB}
+	limits := dbvelocity.Limits{PerHour: 3}
+
+	if d := admitN(t, store, keyID, limits, 3); !d.Allowed {
+		t.Fatalf("third session should be allowed, got %+v", d)
+	}
+
+	d := admitN(t, store, keyID, limits, 1)
+	if d.Allowed || d.Window != dbvelocity.WindowHour || d.Count != 3 || d.Limit != 3 || d.Rejected != 1 {
+		t.Fatalf("fourth session: want hourly refusal 3/3 (1st), got %+v", d)
+	}
+	if d.RetryAfter <= 0 || d.RetryAfter > time.Hour {
+		t.Errorf("RetryAfter = %s, want within the hour", d.RetryAfter)
+	}
+
+	// Refusals keep counting without consuming slots.
+	if d := admitN(t, store, keyID, limits, 1); d.Allowed || d.Count != 3 || d.Rejected != 2 {
+		t.Errorf("fifth session: want refusal 3/3 (2nd), got %+v", d)
+	}
+}
+
+func TestAdmit_RolloverAcrossHours(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	env := testutil.SetupTestEnvironment(t)
+	keyID := setupKey(t, env)
+	store := &dbvelocity.Store{DB: env.DB}
+
+	// The previous hour is full, but the hourly window starts fresh.
+	insertBucket(t, env, keyID, 1, 5)
+	if d := admitN(t, store, keyID, dbvelocity.Limits{PerHour: 5}, 1); !d.Allowed {
+		t.Fatalf("previous hour must not count toward the hourly limit, got %+v", d)
+	}
+
+	// It still counts toward the 24-hour window: 5 + 1 reaches a limit of 6.
+	d := admitN(t, store, keyID, dbvelocity.Limits{PerHour: 5, PerDay: 6}, 1)
+	if d.Allowed || d.Window != dbvelocity.WindowDay || d.Count != 6 {
+		t.Fatalf("want daily refusal at 6, got %+v", d)
+	}
+}
+
+func TestAdmit_DailyWindowIs24Buckets(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	env := testutil.SetupTestEnvironment(t)
+	keyID := setupKey(t, env)
+	store := &dbvelocity.Store{DB: env.DB}
+	limits := dbvelocity.Limits{PerDay: 10}
+
+	insertBucket(t, env, keyID, 24, 100) // just outside the window
+	insertBucket(t, env, keyID, 23, 9)   // oldest bucket inside it
+
+	if d := admitN(t, store, keyID, limits, 1); !d.Allowed {
+		t.Fatalf("10th session of the day should be allowed, got %+v", d)
+	}
+	if d := admitN(t, store, keyID, limits, 1); d.Allowed || d.Window != dbvelocity.WindowDay || d.Count != 10 {
+		t.Fatalf("11th session of the day: want refusal at 10, got %+v", d)
+	}
+}
+
+func TestAdmit_ZeroLimitsDisable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	env := testutil.SetupTestEnvironment(t)
+	keyID := setupKey(t, env)
+	store := &dbvelocity.Store{DB: env.DB}
+
+	insertBucket(t, env, keyID, 1, 1000)
+	if d := admitN(t, store, keyID, dbvelocity.Limits{}, 5); !d.Allowed {
+		t.Errorf("zero limits must allow everything, got %+v", d)
+	}
+}
+
+func TestListTripped(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	env := testutil.SetupTestEnvironment(t)
+	keyID := setupKey(t, env)
+	user := testutil.CreateTestUser(t, env, "quiet@example.com", "Quiet")
+	quietKey := testutil.CreateTestAPIKeyWithToken(t, env, user.ID, "Quiet Key").ID
+	store := &dbvelocity.Store{DB: env.DB}
+	ctx := context.Background()
+
+	insertBucket(t, env, keyID, 2, 4)
+	admitN(t, store, keyID, dbvelocity.Limits{PerHour: 1}, 3) // 1 created, 2 refused
+	admitN(t, store, quietKey, dbvelocity.Limits{PerHour: 1}, 1)
+
+	keys, err := store.ListTripped(ctx, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("ListTripped: %v", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("want only the tripped key, got %+v", keys)
+	}
+	k := keys[0]
+	if k.APIKeyID != keyID || k.UserEmail != "velocity@example.com" || k.SessionsCreated != 5 || k.Rejected != 2 {
+		t.Errorf("unexpected row %+v", k)
+	}
+	if time.Since(k.LastRejectedAt) > time.Hour {
+		t.Errorf("LastRejectedAt = %s, want the current hour", k.LastRejectedAt)
+	}
+}
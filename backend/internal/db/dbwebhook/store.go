@@ -0,0 +1,326 @@
+package dbwebhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+var tracer = otel.Tracer("confab/db/webhook")
+
+// EventSessionCreated is sent when sync/init creates a session. The delivery
+// is queued by dbsession.FindOrCreateSyncSession in the statement that
+// inserts the session.
+const EventSessionCreated = "session.created"
+
+// EventAPIKeyVelocityExceeded is sent when an API key keeps creating sessions
+// past its velocity limit. It is a notify.Dispatcher notification type, queued
+// through QueueEvent.
+const EventAPIKeyVelocityExceeded = "api_key.velocity_exceeded"
+
+// MaxEndpointsPerUser caps how many webhook endpoints one user can register.
+const MaxEndpointsPerUser = 5
+
+// Delivery statuses. A delivery moves pending → delivered, or pending →
+// failed once its attempts run out.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// ErrEndpointLimitExceeded is returned by CreateEndpoint when the user already
+// has MaxEndpointsPerUser endpoints.
+var ErrEndpointLimitExceeded = errors.New("webhook endpoint limit exceeded")
+
+// Endpoint is a webhook_endpoints row.
+type Endpoint struct {
+	ID        int64
+	UserID    int64
+	URL       string
+	Secret    string
+	Events    []string
+	CreatedAt time.Time
+}
+
+// Delivery is a claimed webhook_deliveries row with its endpoint's URL and
+// secret. Attempts counts this attempt.
+type Delivery struct {
+	ID         int64
+	EndpointID int64
+	EventType  string
+	Payload    json.RawMessage
+	Attempts   int
+	CreatedAt  time.Time
+	URL        string
+	Secret     string
+}
+
+// Store provides webhook database operations.
+type Store struct {
+	DB *db.DB
+}
+
+// conn returns the underlying *sql.DB connection.
+func (s *Store) conn() *sql.DB { return s.DB.Conn() }
+
+// CreateEndpoint registers an endpoint for userID subscribed to events. The
+// user's row is locked for the count and insert, so concurrent requests can't
+// both get past MaxEndpointsPerUser.
+func (s *Store) CreateEndpoint(ctx context.Context, userID int64, url, secret string, events []string) (*Endpoint, error) {
+	ctx, span := tracer.Start(ctx, "db.create_webhook_endpoint",
+		trace.WithAttributes(attribute.Int64("user.id", userID)))
+	defer span.End()
+
+	e, err := s.createEndpoint(ctx, userID, url, secret, events)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int64("webhook.endpoint_id", e.ID))
+	return e, nil
+}
+
+func (s *Store) createEndpoint(ctx context.Context, userID int64, url, secret string, events []string) (*Endpoint, error) {
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, db.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM webhook_endpoints WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count webhook endpoints: %w", err)
+	}
+	if count >= MaxEndpointsPerUser {
+		return nil, ErrEndpointLimitExceeded
+	}
+
+	e := Endpoint{UserID: userID, URL: url, Secret: secret}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO webhook_endpoints (user_id, url, secret, events)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, events, created_at`,
+		userID, url, secret, pq.Array(events)).Scan(&e.ID, pq.Array(&e.Events), &e.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert webhook endpoint: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit webhook endpoint: %w", err)
+	}
+	return &e, nil
+}
+
+// ListEndpoints returns the user's endpoints, oldest first.
+func (s *Store) ListEndpoints(ctx context.Context, userID int64) ([]Endpoint, error) {
+	ctx, span := tracer.Start(ctx, "db.list_webhook_endpoints",
+		trace.WithAttributes(attribute.Int64("user.id", userID)))
+	defer span.End()
+
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT id, user_id, url, secret, events, created_at
+		FROM webhook_endpoints
+		WHERE user_id = $1
+		ORDER BY created_at, id`, userID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	endpoints := []Endpoint{}
+	for rows.Next() {
+		var e Endpoint
+		if err := rows.Scan(&e.ID, &e.UserID, &e.URL, &e.Secret, pq.Array(&e.Events), &e.CreatedAt); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("failed to scan webhook endpoint: %w", err)
+		}
+		endpoints = append(endpoints, e)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("error iterating webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// DeleteEndpoint removes one of the user's endpoints and its deliveries. It
+// reports false when the user has no endpoint with that ID.
+func (s *Store) DeleteEndpoint(ctx context.Context, userID, endpointID int64) (bool, error) {
+	ctx, span := tracer.Start(ctx, "db.delete_webhook_endpoint",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.Int64("webhook.endpoint_id", endpointID),
+		))
+	defer span.End()
+
+	res, err := s.conn().ExecContext(ctx,
+		`DELETE FROM webhook_endpoints WHERE id = $1 AND user_id = $2`, endpointID, userID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return false, fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+	return n > 0, nil
+}
+
+// QueueEvent queues a delivery of payload to each of the user's endpoints
+// subscribed to event and returns how many it queued.
+func (s *Store) QueueEvent(ctx context.Context, userID int64, event string, payload json.RawMessage) (int, error) {
+	ctx, span := tracer.Start(ctx, "db.queue_webhook_event",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.String("webhook.event", event),
+		))
+	defer span.End()
+
+	res, err := s.conn().ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (endpoint_id, event_type, payload)
+		SELECT id, $2, $3 FROM webhook_endpoints
+		WHERE user_id = $1 AND $2 = ANY(events)`,
+		userID, event, []byte(payload))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, fmt.Errorf("failed to queue webhook event: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to queue webhook event: %w", err)
+	}
+	span.SetAttributes(attribute.Int64("webhook.queued", n))
+	return int(n), nil
+}
+
+// ClaimDue returns up to limit pending deliveries whose next attempt is due,
+// oldest first, and counts the attempt. Their next_attempt_at moves lease
+// into the future, so a dispatcher that dies mid-send leaves the delivery to
+// be retried once the lease runs out. Rows are locked FOR UPDATE SKIP LOCKED,
+// so concurrent dispatchers claim different deliveries.
+func (s *Store) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	ctx, span := tracer.Start(ctx, "db.claim_webhook_deliveries",
+		trace.WithAttributes(attribute.Int("limit", limit)))
+	defer span.End()
+
+	rows, err := s.conn().QueryContext(ctx, `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = $1 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1,
+		    last_attempt_at = NOW(),
+		    next_attempt_at = NOW() + make_interval(secs => $3)
+		FROM due, webhook_endpoints e
+		WHERE d.id = due.id AND e.id = d.endpoint_id
+		RETURNING d.id, d.endpoint_id, d.event_type, d.payload, d.attempts, d.created_at, e.url, e.secret`,
+		StatusPending, limit, lease.Seconds())
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		var payload []byte
+		if err := rows.Scan(&d.ID, &d.EndpointID, &d.EventType, &payload, &d.Attempts, &d.CreatedAt, &d.URL, &d.Secret); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+	span.SetAttributes(attribute.Int("webhook.claimed", len(deliveries)))
+	return deliveries, nil
+}
+
+// MarkDelivered records a successful attempt.
+func (s *Store) MarkDelivered(ctx context.Context, id int64, statusCode int) error {
+	ctx, span := tracer.Start(ctx, "db.mark_webhook_delivered",
+		trace.WithAttributes(attribute.Int64("webhook.delivery_id", id)))
+	defer span.End()
+
+	_, err := s.conn().ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, completed_at = NOW(), last_status_code = $3, last_error = NULL
+		WHERE id = $1`,
+		id, StatusDelivered, statusCode)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to mark webhook delivered: %w", err)
+	}
+	return nil
+}
+
+// MarkAttemptFailed records a failed attempt. statusCode is nil when no
+// response arrived. A nil retryAt gives up: the delivery is marked failed.
+func (s *Store) MarkAttemptFailed(ctx context.Context, id int64, statusCode *int, reason string, retryAt *time.Time) error {
+	ctx, span := tracer.Start(ctx, "db.mark_webhook_attempt_failed",
+		trace.WithAttributes(
+			attribute.Int64("webhook.delivery_id", id),
+			attribute.Bool("webhook.retry", retryAt != nil),
+		))
+	defer span.End()
+
+	var err error
+	if retryAt != nil {
+		_, err = s.conn().ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET next_attempt_at = $2, last_status_code = $3, last_error = $4
+			WHERE id = $1`,
+			id, *retryAt, statusCode, reason)
+	} else {
+		_, err = s.conn().ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = $2, completed_at = NOW(), last_status_code = $3, last_error = $4
+			WHERE id = $1`,
+			id, StatusFailed, statusCode, reason)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+	return nil
+}
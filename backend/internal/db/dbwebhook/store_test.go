@@ -0,0 +1,80 @@
+package dbwebhook_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db/dbwebhook"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestClaimDue_RetryAndGiveUp(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbwebhook.Store{DB: env.DB}
+	ctx := context.Background()
+	user := testutil.CreateTestUser(t, env, "hooks@example.com", "Hooks")
+
+	endpoint, err := store.CreateEndpoint(ctx, user.ID, "https://hooks.example.com", "whsec_test", []string{dbwebhook.EventSessionCreated})
+	if err != nil {
+		t.Fatalf("CreateEndpoint: %v", err)
+	}
+	if _, err := env.DB.Exec(env.Ctx,
+		`INSERT INTO webhook_deliveries (endpoint_id, event_type, payload) VALUES ($1, $2, '{"event":"session.created"}')`,
+		endpoint.ID, dbwebhook.EventSessionCreated); err != nil {
+		t.Fatalf("queue delivery: %v", err)
+	}
+
+	claimed, err := store.ClaimDue(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDue: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].Attempts != 1 || claimed[0].URL != "https://hooks.example.com" ||
+		claimed[0].Secret != "whsec_test" || string(claimed[0].Payload) != `{"event": "session.created"}` {
+		t.Fatalf("claimed = %+v", claimed)
+	}
+	id := claimed[0].ID
+
+	// Leased: not due again until the lease runs out.
+	if again, err := store.ClaimDue(ctx, 10, time.Minute); err != nil || len(again) != 0 {
+		t.Fatalf("second ClaimDue = %+v, %v; want nothing", again, err)
+	}
+
+	// A retry scheduled in the past is due right away.
+	code := 503
+	past := time.Now().Add(-time.Second)
+	if err := store.MarkAttemptFailed(ctx, id, &code, "endpoint responded 503", &past); err != nil {
+		t.Fatalf("MarkAttemptFailed: %v", err)
+	}
+	claimed, err = store.ClaimDue(ctx, 10, time.Minute)
+	if err != nil || len(claimed) != 1 || claimed[0].Attempts != 2 {
+		t.Fatalf("retry ClaimDue = %+v, %v; want attempt 2", claimed, err)
+	}
+
+	// Giving up completes the delivery; it is never claimed again.
+	if err := store.MarkAttemptFailed(ctx, id, nil, "connection refused", nil); err != nil {
+		t.Fatalf("MarkAttemptFailed: %v", err)
+	}
+	var status string
+	var completed *time.Time
+	if err := env.DB.QueryRow(env.Ctx, `SELECT status, completed_at FROM webhook_deliveries WHERE id = $1`, id).Scan(&status, &completed); err != nil {
+		t.Fatalf("read delivery: %v", err)
+	}
+	if status != dbwebhook.StatusFailed || completed == nil {
+		t.Errorf("status = %q, completed_at = %v; want failed and set", status, completed)
+	}
+
+	deleted, err := store.DeleteEndpoint(ctx, user.ID, endpoint.ID)
+	if err != nil || !deleted {
+		t.Fatalf("DeleteEndpoint = %v, %v", deleted, err)
+	}
+	var remaining int
+	if err := env.DB.QueryRow(env.Ctx, `SELECT COUNT(*) FROM webhook_deliveries`).Scan(&remaining); err != nil || remaining != 0 {
+		t.Errorf("deliveries after endpoint delete = %d, %v; want 0", remaining, err)
+	}
+}
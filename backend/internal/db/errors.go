@@ -8,16 +8,44 @@ var (
 	// Session errors
 	ErrSessionNotFound = errors.New("session not found")
 	ErrUnauthorized    = errors.New("unauthorized")
+	// ErrNotDuplicate is returned when merging a session that has no earlier
+	// unmerged session with the same content fingerprint (or is already merged).
+	ErrNotDuplicate = errors.New("session is not a duplicate")
+	// ErrMergeConflict is returned when a session merge finds either
+	// session's sync files changed since the merge was planned.
+	ErrMergeConflict = errors.New("sessions changed during merge")
+	// ErrAlreadyMerged is returned when a session merge that keeps the
+	// source finds either session already merged into another.
+	ErrAlreadyMerged = errors.New("session is already merged")
+	// ErrExternalIDTaken is returned when reassigning a session's external
+	// ID to one another of the user's sessions of that type already has.
+	ErrExternalIDTaken = errors.New("another session already has this external ID")
+	// ErrReassignConflict is returned when an external ID reassignment finds
+	// the session's external ID or sync files changed since it was planned.
+	ErrReassignConflict = errors.New("session changed during external ID reassignment")
+	// ErrInvalidStateTransition is returned when a session state change is
+	// not in the allowed-transition matrix (see dbsession.CanTransition).
+	ErrInvalidStateTransition = errors.New("invalid session state transition")
 
 	// Share errors
 	ErrForbidden = errors.New("forbidden")
 
 	// File errors
 	ErrFileNotFound = errors.New("file not found")
+	// ErrGenerationChanged is returned when a sync file reset loses a race
+	// with another reset of the same file, or a chunk read the generation
+	// before a reset that committed first.
+	ErrGenerationChanged = errors.New("sync file generation changed")
+	// ErrTranscriptArchived is returned when a chunk's sync state is recorded
+	// for a session whose transcript was archived after the upload checked.
+	ErrTranscriptArchived = errors.New("session transcript is archived")
 
 	// User errors
 	ErrUserNotFound  = errors.New("user not found")
 	ErrOwnerInactive = errors.New("session owner is inactive")
+	// ErrUserMergeConflict is returned when both users in an account merge
+	// have a session with the same external ID.
+	ErrUserMergeConflict = errors.New("users have sessions with the same external ID")
 
 	// API key errors
 	ErrAPIKeyNotFound      = errors.New("API key not found")
@@ -43,4 +71,17 @@ var (
 
 	// Codex rollout errors
 	ErrRolloutNotFound = errors.New("rollout not found")
+
+	// Query errors
+	// ErrQueryTimeout is returned when a query runs past its statement
+	// timeout (see WithQueryTimeout).
+	ErrQueryTimeout = errors.New("query timeout")
+
+	// Feature flag errors
+	ErrFeatureFlagNotFound = errors.New("feature flag not found")
+	ErrFeatureFlagExists   = errors.New("feature flag already exists")
+
+	// Organization errors
+	ErrOrgNotFound = errors.New("organization not found")
+	ErrOrgExists   = errors.New("organization already exists")
 )
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// GrowthHistoryCap is the most points a sync file's growth history keeps.
+const GrowthHistoryCap = 50
+
+// GrowthPoint is one entry of a sync file's growth history: the file's
+// last_synced_line after the chunk upload at At.
+type GrowthPoint struct {
+	At   time.Time `json:"at"`
+	Line int       `json:"line"`
+}
+
+// DownsampleGrowth thins points until at most limit remain. Each pass keeps
+// the newer half as is and every other point of the older half, so older
+// history gets coarser while the first and last points always stay.
+//
+// The sync_file_growth_append SQL function (migration 094) applies the same
+// rule when a chunk upload appends a point; this version bounds histories on
+// read, e.g. ones written under a larger cap. It returns points itself when
+// nothing needs dropping, and never thins below 3 points.
+func DownsampleGrowth(points []GrowthPoint, limit int) []GrowthPoint {
+	for len(points) > limit && len(points) > 3 {
+		half := len(points) / 2
+		thinned := make([]GrowthPoint, 0, len(points)-half/2)
+		for i := 0; i < half; i += 2 {
+			thinned = append(thinned, points[i])
+		}
+		points = append(thinned, points[half:]...)
+	}
+	return points
+}
+
+// UnmarshalGrowth decodes a sync_files.growth value, bounded to
+// GrowthHistoryCap points.
+// Exported for use by sub-packages (session).
+func UnmarshalGrowth(raw []byte) ([]GrowthPoint, error) {
+	points := []GrowthPoint{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &points); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal growth: %w", err)
+		}
+	}
+	return DownsampleGrowth(points, GrowthHistoryCap), nil
+}
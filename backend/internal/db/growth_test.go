@@ -0,0 +1,81 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func growthLines(points []GrowthPoint) []int {
+	lines := make([]int, len(points))
+	for i, p := range points {
+		lines[i] = p.Line
+	}
+	return lines
+}
+
+func TestDownsampleGrowth(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	series := func(n int) []GrowthPoint {
+		points := make([]GrowthPoint, n)
+		for i := range points {
+			points[i] = GrowthPoint{At: start.Add(time.Duration(i) * time.Minute), Line: (i + 1) * 10}
+		}
+		return points
+	}
+
+	t.Run("under the cap is unchanged", func(t *testing.T) {
+		points := series(GrowthHistoryCap)
+		if got := DownsampleGrowth(points, GrowthHistoryCap); len(got) != GrowthHistoryCap {
+			t.Errorf("len = %d, want %d", len(got), GrowthHistoryCap)
+		}
+	})
+
+	t.Run("thins the older half", func(t *testing.T) {
+		got := growthLines(DownsampleGrowth(series(9), 8))
+		want := []int{10, 30, 50, 60, 70, 80, 90}
+		if len(got) != len(want) {
+			t.Fatalf("lines = %v, want %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("lines = %v, want %v", got, want)
+			}
+		}
+	})
+
+	t.Run("far over the cap takes several passes", func(t *testing.T) {
+		got := DownsampleGrowth(series(1000), GrowthHistoryCap)
+		if len(got) > GrowthHistoryCap {
+			t.Errorf("len = %d, want at most %d", len(got), GrowthHistoryCap)
+		}
+		if got[0].Line != 10 || got[len(got)-1].Line != 10000 {
+			t.Errorf("first/last = %d/%d, want 10/10000", got[0].Line, got[len(got)-1].Line)
+		}
+	})
+
+	t.Run("never thins below three points", func(t *testing.T) {
+		if got := DownsampleGrowth(series(3), 1); len(got) != 3 {
+			t.Errorf("len = %d, want 3", len(got))
+		}
+	})
+
+	// Appending one point at a time, as chunk uploads do, must hold the cap
+	// after every append and keep the first and newest points in order.
+	for _, limit := range []int{4, 5, 10, GrowthHistoryCap} {
+		var points []GrowthPoint
+		for i, p := range series(500) {
+			points = DownsampleGrowth(append(points, p), limit)
+			if len(points) > limit {
+				t.Fatalf("cap %d: %d points after append %d", limit, len(points), i)
+			}
+			if points[0].Line != 10 || points[len(points)-1] != p {
+				t.Fatalf("cap %d: first/last = %d/%d after append %d", limit, points[0].Line, points[len(points)-1].Line, i)
+			}
+			for j := 1; j < len(points); j++ {
+				if !points[j].At.After(points[j-1].At) {
+					t.Fatalf("cap %d: points out of order after append %d: %v", limit, i, growthLines(points))
+				}
+			}
+		}
+	}
+}
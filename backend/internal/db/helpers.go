@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -63,18 +64,19 @@ func UnmarshalSessionGitInfo(session *SessionDetail, gitInfoBytes []byte) error
 	return nil
 }
 
-// LoadSessionSyncFiles loads sync files for a session from the database.
+// LoadSessionSyncFiles loads sync files for a session from conn, the pool the
+// session row was read from (primary or replica), so both agree.
 // Excludes todo files - they are transient state not useful for transcript history.
 // Exported for use by sub-packages (session, access).
-func LoadSessionSyncFiles(ctx context.Context, d *DB, session *SessionDetail) error {
+func LoadSessionSyncFiles(ctx context.Context, conn *sql.DB, session *SessionDetail) error {
 	filesQuery := `
-		SELECT file_name, file_type, last_synced_line, updated_at
+		SELECT file_name, file_type, last_synced_line, updated_at, generation, growth
 		FROM sync_files
 		WHERE session_id = $1 AND file_type != 'todo'
 		ORDER BY file_type DESC, file_name ASC
 	`
 
-	rows, err := d.conn.QueryContext(ctx, filesQuery, session.ID)
+	rows, err := conn.QueryContext(ctx, filesQuery, session.ID)
 	if err != nil {
 		return fmt.Errorf("failed to query sync files: %w", err)
 	}
@@ -83,9 +85,13 @@ func LoadSessionSyncFiles(ctx context.Context, d *DB, session *SessionDetail) er
 	session.Files = make([]SyncFileDetail, 0)
 	for rows.Next() {
 		var file SyncFileDetail
-		if err := rows.Scan(&file.FileName, &file.FileType, &file.LastSyncedLine, &file.UpdatedAt); err != nil {
+		var growth []byte
+		if err := rows.Scan(&file.FileName, &file.FileType, &file.LastSyncedLine, &file.UpdatedAt, &file.Generation, &growth); err != nil {
 			return fmt.Errorf("failed to scan sync file: %w", err)
 		}
+		if file.Growth, err = UnmarshalGrowth(growth); err != nil {
+			return err
+		}
 		session.Files = append(session.Files, file)
 	}
 
@@ -27,7 +27,7 @@ func TestLoadSessionSyncFiles_LoadsAllAndExcludesTodo(t *testing.T) {
 	testutil.CreateTestSyncFile(t, env, sessionID, "todo.json", "todo", 1) // must be excluded
 
 	session := &db.SessionDetail{ID: sessionID}
-	if err := db.LoadSessionSyncFiles(context.Background(), env.DB, session); err != nil {
+	if err := db.LoadSessionSyncFiles(context.Background(), env.DB.Conn(), session); err != nil {
 		t.Fatalf("LoadSessionSyncFiles: %v", err)
 	}
 
@@ -52,7 +52,7 @@ func TestLoadSessionSyncFiles_EmptyResult(t *testing.T) {
 	sessionID := testutil.CreateTestSession(t, env, user.ID, "ext-empty")
 
 	session := &db.SessionDetail{ID: sessionID}
-	if err := db.LoadSessionSyncFiles(context.Background(), env.DB, session); err != nil {
+	if err := db.LoadSessionSyncFiles(context.Background(), env.DB.Conn(), session); err != nil {
 		t.Fatalf("LoadSessionSyncFiles: %v", err)
 	}
 	if session.Files == nil {
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// InterestWeights tune the session interest score behind the session list's
+// sort=interesting. Each weight scales one card signal into the session's
+// quality; HalfLife sets how fast recency outweighs it.
+type InterestWeights struct {
+	Cost       float64 // per USD of estimated cost
+	Duration   float64 // per hour of session duration
+	ToolErrors float64 // per failed tool call
+	Recap      float64 // once, when a smart recap exists
+	// Baseline is the quality assumed for a session whose cards have not been
+	// computed yet, so new sessions are not buried until analytics run.
+	Baseline float64
+	HalfLife time.Duration
+}
+
+// DefaultInterestWeights value a dollar of spend like an hour of work or a
+// recap, and ten failed tool calls like a dollar. A session without cards
+// scores like a one-dollar session. Quality counts for as much as a week of
+// recency per doubling.
+var DefaultInterestWeights = InterestWeights{
+	Cost:       1.0,
+	Duration:   1.0,
+	ToolErrors: 0.1,
+	Recap:      1.0,
+	Baseline:   1.0,
+	HalfLife:   7 * 24 * time.Hour,
+}
+
+// InterestInputs are the signals InterestScore combines. HasCards is false
+// until the session's cards are first computed; the card fields are ignored
+// then.
+type InterestInputs struct {
+	LastActivity time.Time // last_message_at, else first_seen
+	HasCards     bool
+	CostUSD      float64
+	Duration     time.Duration
+	ToolErrors   int
+	HasRecap     bool
+}
+
+// InterestScore scores a session for sort=interesting. The session's quality
+// q is the weighted sum of its card signals (w.Baseline without cards), and
+//
+//	score = log2(1 + q) + LastActivity / w.HalfLife
+//
+// with LastActivity in seconds since the epoch. Ordering by score is ordering
+// by (1+q)·2^(-age/HalfLife) at any moment, so the recency decay is built in
+// and a stored score never needs re-decaying as time passes: a session
+// HalfLife older must have double the 1+q to rank level. Negative signals
+// count as zero. The function is pure; it reads no clock.
+func InterestScore(in InterestInputs, w InterestWeights) float64 {
+	if w.HalfLife <= 0 {
+		w.HalfLife = DefaultInterestWeights.HalfLife
+	}
+
+	quality := w.Baseline
+	if in.HasCards {
+		quality = w.Cost*math.Max(in.CostUSD, 0) +
+			w.Duration*math.Max(in.Duration.Hours(), 0) +
+			w.ToolErrors*float64(max(in.ToolErrors, 0))
+		if in.HasRecap {
+			quality += w.Recap
+		}
+	}
+	if !(quality > 0) { // also catches NaN
+		quality = 0
+	}
+
+	return math.Log2(1+quality) + float64(in.LastActivity.Unix())/w.HalfLife.Seconds()
+}
+
+// loadInterestWeights reads INTEREST_WEIGHT_COST, INTEREST_WEIGHT_DURATION,
+// INTEREST_WEIGHT_TOOL_ERRORS, INTEREST_WEIGHT_RECAP, INTEREST_BASELINE and
+// INTEREST_HALF_LIFE (a Go duration). Unset, unparseable or negative values,
+// and a non-positive half-life, keep the default.
+func loadInterestWeights() InterestWeights {
+	w := DefaultInterestWeights
+	for _, f := range []struct {
+		key string
+		dst *float64
+	}{
+		{"INTEREST_WEIGHT_COST", &w.Cost},
+		{"INTEREST_WEIGHT_DURATION", &w.Duration},
+		{"INTEREST_WEIGHT_TOOL_ERRORS", &w.ToolErrors},
+		{"INTEREST_WEIGHT_RECAP", &w.Recap},
+		{"INTEREST_BASELINE", &w.Baseline},
+	} {
+		if v, err := strconv.ParseFloat(os.Getenv(f.key), 64); err == nil && v >= 0 && !math.IsInf(v, 0) {
+			*f.dst = v
+		}
+	}
+	if d, err := time.ParseDuration(os.Getenv("INTEREST_HALF_LIFE")); err == nil && d > 0 {
+		w.HalfLife = d
+	}
+	return w
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestInterestScore(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	w := DefaultInterestWeights
+	recency := float64(now.Unix()) / w.HalfLife.Seconds()
+
+	tests := []struct {
+		name string
+		in   InterestInputs
+		want float64 // minus the recency term
+	}{
+		{"no cards scores the baseline", InterestInputs{LastActivity: now, CostUSD: 100}, 1},
+		{"cards with no signal", InterestInputs{LastActivity: now, HasCards: true}, 0},
+		{"cost", InterestInputs{LastActivity: now, HasCards: true, CostUSD: 3}, 2},
+		{"duration", InterestInputs{LastActivity: now, HasCards: true, Duration: 90 * time.Minute, HasRecap: true}, math.Log2(3.5)},
+		{"tool errors", InterestInputs{LastActivity: now, HasCards: true, ToolErrors: 10}, 1},
+		{"all signals", InterestInputs{LastActivity: now, HasCards: true, CostUSD: 3, Duration: 2 * time.Hour, ToolErrors: 10, HasRecap: true}, 3},
+		{"negative signals count as zero", InterestInputs{LastActivity: now, HasCards: true, CostUSD: -5, Duration: -time.Hour, ToolErrors: -3}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InterestScore(tt.in, w) - recency; math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("InterestScore() - recency = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterestScore_RecencyDecay(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	w := DefaultInterestWeights
+	score := func(at time.Time, cost float64) float64 {
+		return InterestScore(InterestInputs{LastActivity: at, HasCards: true, CostUSD: cost}, w)
+	}
+
+	// One half-life older with double the 1+q ranks level.
+	older := now.Add(-w.HalfLife)
+	if a, b := score(now, 1), score(older, 3); math.Abs(a-b) > 1e-9 {
+		t.Errorf("score(now, $1) = %v, score(-half-life, $3) = %v, want equal", a, b)
+	}
+	if score(now, 0) <= score(older, 0.5) {
+		t.Error("a recent session with nothing of note should outrank a week-old 50-cent session")
+	}
+	if score(older, 10) <= score(now, 1) {
+		t.Error("a week-old $10 session should outrank a recent $1 session")
+	}
+}
+
+func TestInterestScore_ZeroHalfLifeUsesDefault(t *testing.T) {
+	in := InterestInputs{LastActivity: time.Unix(1_700_000_000, 0), HasCards: true, CostUSD: 1}
+	w := DefaultInterestWeights
+	want := InterestScore(in, w)
+	w.HalfLife = 0
+	if got := InterestScore(in, w); got != want {
+		t.Errorf("InterestScore() with zero half-life = %v, want %v", got, want)
+	}
+}
+
+func TestLoadInterestWeights(t *testing.T) {
+	t.Setenv("INTEREST_WEIGHT_COST", "2.5")
+	t.Setenv("INTEREST_WEIGHT_DURATION", "-1") // negative: default kept
+	t.Setenv("INTEREST_WEIGHT_TOOL_ERRORS", "garbage")
+	t.Setenv("INTEREST_WEIGHT_RECAP", "0")
+	t.Setenv("INTEREST_BASELINE", "Inf")
+	t.Setenv("INTEREST_HALF_LIFE", "72h")
+
+	w := loadInterestWeights()
+	want := DefaultInterestWeights
+	want.Cost = 2.5
+	want.Recap = 0
+	want.HalfLife = 72 * time.Hour
+	if w != want {
+		t.Errorf("loadInterestWeights() = %+v, want %+v", w, want)
+	}
+
+	t.Setenv("INTEREST_HALF_LIFE", "-1h")
+	if got := loadInterestWeights().HalfLife; got != DefaultInterestWeights.HalfLife {
+		t.Errorf("negative INTEREST_HALF_LIFE gave %v, want the default", got)
+	}
+}
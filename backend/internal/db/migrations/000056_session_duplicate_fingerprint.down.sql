@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_sessions_user_content_fingerprint;
+
+ALTER TABLE sessions DROP COLUMN merged_at;
+ALTER TABLE sessions DROP COLUMN merged_into;
+ALTER TABLE sessions DROP COLUMN content_fingerprint;
+
+ALTER TABLE sync_files DROP COLUMN fingerprint_lines;
+ALTER TABLE sync_files DROP COLUMN fingerprint_state;
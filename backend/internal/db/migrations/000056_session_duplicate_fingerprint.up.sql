@@ -0,0 +1,28 @@
+-- Duplicate detection across machines: the same Claude Code transcript synced
+-- from two machines (shared home dir via dotfile sync) lands as two sessions
+-- with different external_ids and double-counts cost.
+--
+-- Design notes:
+--   * sync_files.fingerprint_state / fingerprint_lines hold the resumable
+--     rolling hash over the first N transcript lines. The sync chunk handler
+--     folds each chunk's lines in as they arrive (chunk continuity is already
+--     enforced), so no S3 read-back is ever needed. Nullable, no DB default
+--     (app convention); NULL lines reads as 0. Pre-existing files that have
+--     already synced past line 1 never match the resume check and simply stay
+--     un-fingerprinted.
+--   * sessions.content_fingerprint is set once, when a transcript reaches N
+--     lines. Sessions of the same user sharing a fingerprint are flagged as
+--     probable duplicates at read time — detection never deletes anything.
+--   * sessions.merged_at marks a duplicate the user explicitly merged (soft
+--     delete). merged_into points at the original; ON DELETE SET NULL keeps
+--     the soft-deleted row hidden even if the original is later deleted.
+ALTER TABLE sync_files ADD COLUMN fingerprint_state BIGINT;
+ALTER TABLE sync_files ADD COLUMN fingerprint_lines INTEGER;
+
+ALTER TABLE sessions ADD COLUMN content_fingerprint VARCHAR(64);
+ALTER TABLE sessions ADD COLUMN merged_into UUID REFERENCES sessions(id) ON DELETE SET NULL;
+ALTER TABLE sessions ADD COLUMN merged_at TIMESTAMP;
+
+CREATE INDEX idx_sessions_user_content_fingerprint
+    ON sessions(user_id, content_fingerprint)
+    WHERE content_fingerprint IS NOT NULL;
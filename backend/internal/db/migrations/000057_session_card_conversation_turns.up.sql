@@ -0,0 +1,18 @@
+-- Per-turn conversation detail backing GET /sessions/{id}/cards/conversation/turns.
+-- Rewritten wholesale whenever the conversation card is recomputed; capped at
+-- analytics.MaxConversationTurns rows per session.
+CREATE TABLE session_card_conversation_turns (
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    turn_index INT NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    line_number INT NOT NULL,
+
+    -- Nullable: not computable without timestamps (or, for user turns,
+    -- without a preceding assistant response)
+    duration_ms BIGINT,
+    -- Output tokens for assistant turns; NULL for user turns
+    token_count BIGINT,
+    has_tool_use BOOLEAN NOT NULL DEFAULT FALSE,
+
+    PRIMARY KEY (session_id, turn_index)
+);
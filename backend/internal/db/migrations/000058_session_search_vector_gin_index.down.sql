@@ -0,0 +1,2 @@
+-- idx_session_search_vector belongs to 000037; rolling back 000058 keeps it.
+SELECT 1;
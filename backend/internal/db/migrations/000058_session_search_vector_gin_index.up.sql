@@ -0,0 +1,10 @@
+-- Ensure the full-text GIN index on session_search_index.search_vector exists.
+-- 000037 creates it, but nothing re-checks it on databases that were restored
+-- or repaired by hand; without it session search and
+-- FindStaleSearchIndexSessions fall back to sequential scans that degrade
+-- badly at scale. IF NOT EXISTS makes this a no-op where the index exists.
+--
+-- CONCURRENTLY cannot run inside a transaction block, so this file holds a
+-- single statement (see 000033).
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_session_search_vector
+    ON session_search_index USING GIN (search_vector);
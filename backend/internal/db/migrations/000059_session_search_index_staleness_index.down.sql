@@ -0,0 +1 @@
+DROP INDEX CONCURRENTLY IF EXISTS idx_session_search_index_staleness;
@@ -0,0 +1,11 @@
+-- Covering index for the search-index staleness check in
+-- FindStaleSearchIndexSessions, which LEFT JOINs session_search_index on
+-- session_id and compares version and indexed_up_to_line. With both columns
+-- in the index the join is answered by an index-only scan instead of heap
+-- fetches of the (large) content_text rows.
+--
+-- session_search_index has no user_id column; the per-user side of the query
+-- is served by the sessions indexes (idx_sessions_user_first_seen) through
+-- the JOIN on sessions.
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_session_search_index_staleness
+    ON session_search_index(session_id, version, indexed_up_to_line);
@@ -0,0 +1,13 @@
+-- Per-user feature flags for gradual rollout of new analytics features
+-- (e.g. a new card type). A flag is on for a user when the user is in
+-- enabled_user_ids OR user_id % 100 < enabled_pct. See internal/features.
+CREATE TABLE feature_flags (
+    flag_name        TEXT PRIMARY KEY,
+    enabled_user_ids BIGINT[] NOT NULL DEFAULT '{}',
+    enabled_pct      INT NOT NULL DEFAULT 0 CHECK (enabled_pct BETWEEN 0 AND 100),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE feature_flags IS 'Per-user feature flags: explicit allowlist plus percentage rollout';
+COMMENT ON COLUMN feature_flags.enabled_pct IS 'Percentage rollout (0-100); enabled when user_id % 100 < enabled_pct';
@@ -0,0 +1,2 @@
+ALTER TABLE session_card_session DROP COLUMN first_timestamp_anomaly_line;
+ALTER TABLE session_card_session DROP COLUMN timestamp_anomalies;
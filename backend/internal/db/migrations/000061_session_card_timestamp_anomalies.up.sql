@@ -0,0 +1,8 @@
+-- Transcript lines whose timestamp goes backwards by more than a second are
+-- counted as ordering anomalies on the session card. Durations spanning such
+-- lines are clamped to zero, so the count explains why timings may look off.
+ALTER TABLE session_card_session ADD COLUMN timestamp_anomalies INT NOT NULL DEFAULT 0;
+ALTER TABLE session_card_session ADD COLUMN first_timestamp_anomaly_line INT;
+
+COMMENT ON COLUMN session_card_session.timestamp_anomalies IS 'Number of transcript lines whose timestamp regresses by more than 1s';
+COMMENT ON COLUMN session_card_session.first_timestamp_anomaly_line IS 'Line number of the first timestamp regression (NULL if none)';
@@ -0,0 +1,13 @@
+-- Latest retention prune per table, written by the worker's housekeeping step
+-- (internal/db/dbretention) and read by the admin retention endpoint.
+CREATE TABLE retention_prune_runs (
+    table_name          TEXT PRIMARY KEY,
+    retention_seconds   BIGINT NOT NULL,
+    last_pruned_at      TIMESTAMPTZ NOT NULL,
+    last_rows_deleted   BIGINT NOT NULL DEFAULT 0,
+    total_rows_deleted  BIGINT NOT NULL DEFAULT 0
+);
+
+COMMENT ON TABLE retention_prune_runs IS 'Latest retention prune per table (worker housekeeping)';
+COMMENT ON COLUMN retention_prune_runs.retention_seconds IS 'Retention window the latest prune used';
+COMMENT ON COLUMN retention_prune_runs.total_rows_deleted IS 'Rows deleted across all prunes of the table';
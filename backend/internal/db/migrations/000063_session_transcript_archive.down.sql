@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_sessions_transcript_archive_candidates;
+
+ALTER TABLE sessions DROP COLUMN transcript_archived_at;
@@ -0,0 +1,12 @@
+-- Transcript retention: the worker deletes a session's S3 chunks once its last
+-- sync is older than WORKER_TRANSCRIPT_RETENTION, keeping the computed cards,
+-- search index and sync_files rows. transcript_archived_at records when that
+-- happened; raw-file reads answer 410 Gone from then on and the precompute
+-- worker stops considering the session. NULL = transcript still in storage.
+ALTER TABLE sessions ADD COLUMN transcript_archived_at TIMESTAMP;
+
+-- Candidate scan for the archive step: unarchived sessions ordered by their
+-- last activity.
+CREATE INDEX idx_sessions_transcript_archive_candidates
+    ON sessions ((COALESCE(last_sync_at, first_seen)))
+    WHERE transcript_archived_at IS NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE session_search_index
+    DROP COLUMN messages_vector,
+    DROP COLUMN recap_vector,
+    DROP COLUMN metadata_vector;
@@ -0,0 +1,14 @@
+-- Store each weighted component of the session search index separately so
+-- they can be inspected and re-ranked independently; search_vector remains
+-- their concatenation (A: metadata, B: smart recap, C: user messages).
+ALTER TABLE session_search_index
+    ADD COLUMN metadata_vector TSVECTOR NOT NULL DEFAULT '',
+    ADD COLUMN recap_vector TSVECTOR NOT NULL DEFAULT '',
+    ADD COLUMN messages_vector TSVECTOR NOT NULL DEFAULT '';
+
+-- Existing rows already carry the weights in search_vector; split them out
+-- rather than waiting for a reindex (archived sessions are never reindexed).
+UPDATE session_search_index SET
+    metadata_vector = ts_filter(search_vector, '{a}'),
+    recap_vector = ts_filter(search_vector, '{b}'),
+    messages_vector = ts_filter(search_vector, '{c}');
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS sync_file_generations;
+
+ALTER TABLE sync_files DROP COLUMN generation;
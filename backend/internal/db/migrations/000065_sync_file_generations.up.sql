@@ -0,0 +1,23 @@
+-- Sync file resets: when an agent rewrites a file shorter than what was
+-- already synced (e.g. Claude Code compaction), POST /sync/file/reset moves
+-- the file's chunks to an archived generation in S3 and restarts the file at
+-- line 0. sync_files.generation counts those resets; the current chunks/
+-- prefix always holds the live generation.
+ALTER TABLE sync_files ADD COLUMN generation INTEGER NOT NULL DEFAULT 0;
+
+-- One row per archived generation, so ?generation=N reads know how many lines
+-- the archived chunks hold and why the file was reset.
+CREATE TABLE sync_file_generations (
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    file_name VARCHAR(512) NOT NULL,
+    generation INTEGER NOT NULL,
+    file_type VARCHAR(50) NOT NULL,
+    line_count INTEGER NOT NULL,
+    chunk_count INTEGER,
+    reason VARCHAR(32) NOT NULL,
+    -- Line count the client reported for its rewritten local file.
+    local_line_count INTEGER NOT NULL,
+    reset_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (session_id, file_name, generation)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE sessions DROP COLUMN ai_title_generated_at;
+ALTER TABLE sessions DROP COLUMN ai_generated_title;
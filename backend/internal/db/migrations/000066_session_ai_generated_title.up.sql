@@ -0,0 +1,7 @@
+-- On-demand session titles: POST /sessions/{id}/generate-title asks the smart
+-- recap model for a short title and stores it here, separate from the
+-- suggested_session_title the recap itself produces. ai_title_generated_at
+-- enforces the one-generation-per-hour limit per session; it is stamped when a
+-- generation starts and rolled back if the model call fails.
+ALTER TABLE sessions ADD COLUMN ai_generated_title VARCHAR(255);
+ALTER TABLE sessions ADD COLUMN ai_title_generated_at TIMESTAMP;
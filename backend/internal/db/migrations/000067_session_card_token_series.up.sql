@@ -0,0 +1,11 @@
+-- Cumulative token usage across a session's transcript, backing
+-- GET /sessions/{id}/tokens/series. Rewritten whenever the cards are
+-- recomputed; points is the sampled series (at most
+-- analytics.MaxTokenSeriesPoints entries).
+CREATE TABLE session_card_token_series (
+    session_id UUID PRIMARY KEY REFERENCES sessions(id) ON DELETE CASCADE,
+    computed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    interval_lines INT NOT NULL,
+    total_lines INT NOT NULL,
+    points JSONB NOT NULL
+);
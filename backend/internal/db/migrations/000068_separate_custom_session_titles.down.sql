@@ -0,0 +1,6 @@
+-- The UP migration only clears custom_title values that duplicated the
+-- suggested title or were blank. It is irreversible by design: a cleared row
+-- still resolves to the same text via suggested_session_title, so there is
+-- nothing to restore.
+
+SELECT 1;
@@ -0,0 +1,12 @@
+-- custom_title is reserved for titles a user typed. A frontend that wrote the
+-- Smart Recap suggestion into custom_title pinned that text: later recaps
+-- updated suggested_session_title but the stale copy kept winning title
+-- resolution. Clear copies that still equal the suggestion (the resolved title
+-- is unchanged, since it falls back to the same text) and normalise blank
+-- custom titles, which mean "no override", to NULL.
+
+UPDATE sessions
+SET custom_title = NULL
+WHERE custom_title IS NOT NULL
+  AND (BTRIM(custom_title) = ''
+       OR custom_title = suggested_session_title);
@@ -0,0 +1,3 @@
+ALTER TABLE session_search_index DROP COLUMN agent_vector;
+
+ALTER TABLE users DROP COLUMN include_agent_files_in_search;
@@ -0,0 +1,7 @@
+-- Per-user opt-in to index subagent output for search. When on, the search
+-- index build adds the user-visible text of a session's agent files as its
+-- own weight-D component, stored in agent_vector alongside the A/B/C vectors.
+ALTER TABLE users ADD COLUMN include_agent_files_in_search BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE session_search_index
+    ADD COLUMN agent_vector TSVECTOR NOT NULL DEFAULT '';
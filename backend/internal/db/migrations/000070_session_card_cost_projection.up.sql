@@ -0,0 +1,12 @@
+-- Projected final cost of a session if it keeps spending at its current
+-- per-line rate until it reaches a typical length for its owner, backing
+-- GET /sessions/{id}/cards/cost-projection. Rewritten whenever the cards are
+-- recomputed; projection is NULL when there is not enough data to project
+-- (see analytics.ProjectCost).
+CREATE TABLE session_card_cost_projection (
+    session_id UUID PRIMARY KEY REFERENCES sessions(id) ON DELETE CASCADE,
+    computed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    up_to_line BIGINT NOT NULL,
+    history_session_count INT NOT NULL,
+    projection JSONB
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_sync_file_generations_stored_bytes_unknown;
+DROP INDEX IF EXISTS idx_sync_files_stored_bytes_unknown;
+ALTER TABLE sync_file_generations DROP COLUMN IF EXISTS stored_bytes;
+ALTER TABLE sync_files DROP COLUMN IF EXISTS stored_bytes;
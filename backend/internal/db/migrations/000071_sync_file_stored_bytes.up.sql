@@ -0,0 +1,17 @@
+-- Per-user storage accounting: bytes of chunk objects stored in S3 for each
+-- live sync file and each archived generation. Grown on every chunk upload,
+-- carried over by resets and merges, and dropped with the session row.
+-- NULL means unknown (files synced before accounting); the worker backfills
+-- those from the object sizes in storage. Adding the column without a default
+-- leaves existing rows NULL; rows created from now on start at 0.
+ALTER TABLE sync_files ADD COLUMN stored_bytes BIGINT;
+ALTER TABLE sync_files ALTER COLUMN stored_bytes SET DEFAULT 0;
+ALTER TABLE sync_file_generations ADD COLUMN stored_bytes BIGINT;
+ALTER TABLE sync_file_generations ALTER COLUMN stored_bytes SET DEFAULT 0;
+
+-- Files that never had a chunk hold nothing, so there is nothing to backfill.
+UPDATE sync_files SET stored_bytes = 0 WHERE chunk_count = 0;
+UPDATE sync_file_generations SET stored_bytes = 0 WHERE chunk_count = 0;
+
+CREATE INDEX idx_sync_files_stored_bytes_unknown ON sync_files (session_id) WHERE stored_bytes IS NULL;
+CREATE INDEX idx_sync_file_generations_stored_bytes_unknown ON sync_file_generations (session_id) WHERE stored_bytes IS NULL;
@@ -0,0 +1 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS first_user_message_source;
@@ -0,0 +1,7 @@
+-- Where sessions.first_user_message came from: 'server' when sync/chunk
+-- parsed it out of the transcript (the first human prompt, skipping tool
+-- results and command envelopes), 'client' when it is the CLI-supplied
+-- metadata value. A server value replaces a client one; the first server
+-- value wins. Existing rows stay NULL (unknown) and are never replaced, since
+-- a later chunk's prompt is not the session's first.
+ALTER TABLE sessions ADD COLUMN first_user_message_source VARCHAR(16);
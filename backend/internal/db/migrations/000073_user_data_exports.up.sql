@@ -0,0 +1,28 @@
+-- Full-account data exports (GDPR). POST /api/v1/me/export queues a row, the
+-- worker claims it, writes a zip of every session to object storage, and
+-- emails a presigned link. The archive is deleted once expires_at passes.
+CREATE TABLE user_data_exports (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status          VARCHAR(16) NOT NULL DEFAULT 'pending',
+    requested_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at      TIMESTAMPTZ,
+    completed_at    TIMESTAMPTZ,
+    expires_at      TIMESTAMPTZ,
+    object_key      TEXT,
+    size_bytes      BIGINT,
+    session_count   INT,
+    error           TEXT,
+    CONSTRAINT user_data_exports_status_check
+        CHECK (status IN ('pending', 'processing', 'ready', 'failed', 'expired'))
+);
+
+-- Latest export per user (status endpoint and the one-per-day limit).
+CREATE INDEX idx_user_data_exports_user_requested ON user_data_exports (user_id, requested_at DESC);
+-- Worker queue and expiry sweeps.
+CREATE INDEX idx_user_data_exports_pending ON user_data_exports (requested_at) WHERE status IN ('pending', 'processing');
+CREATE INDEX idx_user_data_exports_ready_expires ON user_data_exports (expires_at) WHERE status = 'ready';
+
+COMMENT ON TABLE user_data_exports IS 'Full-account data export jobs (worker-built zip archives)';
+COMMENT ON COLUMN user_data_exports.object_key IS 'Archive key in the bucket; cleared when the archive expires';
+COMMENT ON COLUMN user_data_exports.error IS 'Why the export failed (internal; not returned by the API)';
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS session_state_log;
+DROP INDEX IF EXISTS idx_sessions_state_sweep;
+DROP INDEX IF EXISTS idx_sessions_user_state;
+ALTER TABLE sessions
+    DROP CONSTRAINT IF EXISTS sessions_state_check,
+    DROP COLUMN IF EXISTS state_changed_at,
+    DROP COLUMN IF EXISTS state;
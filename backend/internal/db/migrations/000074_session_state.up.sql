@@ -0,0 +1,51 @@
+-- Explicit session lifecycle state. Every change goes through
+-- dbsession.TransitionState (or its batch form), which validates it against
+-- the allowed-transition matrix and appends a session_state_log row:
+--   active       chunks synced recently (set by the chunk upload)
+--   idle         no sync for WORKER_SESSION_IDLE_AFTER (worker sweep)
+--   ended        no sync for WORKER_SESSION_ENDED_AFTER (worker sweep)
+--   archived     raw transcript deleted by transcript retention
+--   data_missing the precomputer found synced lines but no stored data
+--   deleted      only ever seen in session_state_log; the row is gone
+ALTER TABLE sessions
+    ADD COLUMN state VARCHAR(16) NOT NULL DEFAULT 'active',
+    ADD COLUMN state_changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD CONSTRAINT sessions_state_check
+        CHECK (state IN ('active', 'idle', 'ended', 'archived', 'data_missing', 'deleted'));
+
+-- Classify existing sessions with the default sweep windows (30m idle, 24h
+-- ended). last_sync_at, first_seen and transcript_archived_at are bare UTC
+-- TIMESTAMPs.
+UPDATE sessions SET
+    state = CASE
+        WHEN transcript_archived_at IS NOT NULL THEN 'archived'
+        WHEN COALESCE(last_sync_at, first_seen) < (NOW() AT TIME ZONE 'UTC') - INTERVAL '24 hours' THEN 'ended'
+        WHEN COALESCE(last_sync_at, first_seen) < (NOW() AT TIME ZONE 'UTC') - INTERVAL '30 minutes' THEN 'idle'
+        ELSE 'active'
+    END,
+    state_changed_at = COALESCE(transcript_archived_at, last_sync_at, first_seen) AT TIME ZONE 'UTC';
+
+-- List filter (?state=) within one owner's sessions.
+CREATE INDEX idx_sessions_user_state ON sessions (user_id, state);
+-- Idle sweep candidates: sessions that can still go idle or end.
+CREATE INDEX idx_sessions_state_sweep
+    ON sessions ((COALESCE(last_sync_at, first_seen)))
+    WHERE state IN ('active', 'idle');
+
+-- Audit trail of state transitions. No FK to sessions: the 'deleted' entry
+-- must outlive the row. Rows go with the owner's account.
+CREATE TABLE session_state_log (
+    id          BIGSERIAL PRIMARY KEY,
+    session_id  UUID NOT NULL,
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_state  VARCHAR(16) NOT NULL,
+    to_state    VARCHAR(16) NOT NULL,
+    reason      VARCHAR(32) NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_session_state_log_session ON session_state_log (session_id, id);
+CREATE INDEX idx_session_state_log_user ON session_state_log (user_id);
+
+COMMENT ON COLUMN sessions.state IS 'Lifecycle state; changed only through dbsession.TransitionState';
+COMMENT ON TABLE session_state_log IS 'Append-only audit trail of session state transitions';
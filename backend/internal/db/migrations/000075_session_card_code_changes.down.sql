@@ -0,0 +1 @@
+DROP TABLE IF EXISTS session_card_code_changes;
@@ -0,0 +1,21 @@
+-- Code changes card table (line-based invalidation)
+-- Stores a sample of the diff hunks from Edit/MultiEdit tool calls: per edit,
+-- the file, the old/new line counts and the added/removed lines. Only the
+-- largest edits by diff size are kept (see analytics.MaxCodeChangeHunks).
+CREATE TABLE session_card_code_changes (
+    session_id UUID PRIMARY KEY REFERENCES sessions(id) ON DELETE CASCADE,
+    version INT NOT NULL DEFAULT 1,
+    computed_at TIMESTAMPTZ NOT NULL,
+    up_to_line BIGINT NOT NULL,
+
+    total_hunks INT NOT NULL DEFAULT 0,
+    code_changes JSONB NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX idx_session_card_code_changes_version ON session_card_code_changes(version);
+
+COMMENT ON TABLE session_card_code_changes IS 'Cached sample of Edit/MultiEdit diff hunks for sessions';
+COMMENT ON COLUMN session_card_code_changes.version IS 'Compute logic version for cache invalidation';
+COMMENT ON COLUMN session_card_code_changes.up_to_line IS 'JSONL line count (across transcript+agent files) when computed';
+COMMENT ON COLUMN session_card_code_changes.total_hunks IS 'Number of edit hunks in the session, before sampling';
+COMMENT ON COLUMN session_card_code_changes.code_changes IS 'JSON array of sampled hunks in transcript order: file_path, lines_before, lines_after, added_lines, removed_lines';
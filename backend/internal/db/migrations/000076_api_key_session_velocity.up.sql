@@ -0,0 +1,19 @@
+-- Per-API-key counters of sessions created by sync/init, one row per key and
+-- hour (internal/db/dbvelocity). sync/init refuses new sessions once a key's
+-- current-hour or rolling-24-hour total reaches its limit, and counts the
+-- refusals here so the admin velocity endpoint can list tripped keys. The
+-- worker prunes buckets older than WORKER_RETENTION_API_KEY_SESSION_VELOCITY.
+CREATE TABLE api_key_session_velocity (
+    api_key_id        BIGINT NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    bucket_start      TIMESTAMPTZ NOT NULL,
+    sessions_created  INTEGER NOT NULL DEFAULT 0,
+    rejected          INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (api_key_id, bucket_start)
+);
+
+-- Admin listing of recent trips and retention pruning.
+CREATE INDEX idx_api_key_session_velocity_bucket ON api_key_session_velocity (bucket_start);
+
+COMMENT ON TABLE api_key_session_velocity IS 'Hourly sync/init session-creation counters per API key';
+COMMENT ON COLUMN api_key_session_velocity.bucket_start IS 'Start of the hour (date_trunc on the database clock)';
+COMMENT ON COLUMN api_key_session_velocity.rejected IS 'sync/init calls refused in this hour for exceeding a velocity limit';
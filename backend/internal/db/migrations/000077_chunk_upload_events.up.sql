@@ -0,0 +1,36 @@
+-- One row per sync/chunk upload, written before the chunk goes to S3 and
+-- confirmed once sync_files records it (internal/db/session/chunk_events.go).
+-- A row left unconfirmed means the upload may have landed without the
+-- sync-state update (a DB failure or crash in between); the worker settles
+-- such rows after WORKER_CHUNK_RECONCILE_AFTER by replaying the update,
+-- deleting the orphaned object, or noting that nothing needs doing. Settled
+-- rows are pruned after WORKER_RETENTION_CHUNK_UPLOAD_EVENTS.
+CREATE TABLE chunk_upload_events (
+    id               BIGSERIAL PRIMARY KEY,
+    session_id       UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    file_name        TEXT NOT NULL,
+    file_type        TEXT NOT NULL,
+    first_line       INTEGER NOT NULL,
+    last_line        INTEGER NOT NULL,
+    s3_key           TEXT NOT NULL,
+    chunk_bytes      BIGINT NOT NULL,
+    last_message_at  TIMESTAMPTZ,
+    uploaded_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    confirmed_at     TIMESTAMPTZ,
+    resolution       TEXT CHECK (resolution IN ('synced', 'replayed', 'superseded', 'discarded', 'missing'))
+);
+
+-- Worker scan for unconfirmed uploads, oldest first.
+CREATE INDEX idx_chunk_upload_events_pending ON chunk_upload_events (uploaded_at) WHERE confirmed_at IS NULL;
+
+-- A retried upload of the same chunk supersedes the earlier pending row.
+CREATE INDEX idx_chunk_upload_events_pending_key ON chunk_upload_events (session_id, file_name, s3_key) WHERE confirmed_at IS NULL;
+
+-- Retention pruning of settled rows.
+CREATE INDEX idx_chunk_upload_events_confirmed ON chunk_upload_events (confirmed_at) WHERE confirmed_at IS NOT NULL;
+
+COMMENT ON TABLE chunk_upload_events IS 'Sync chunk uploads, confirmed once sync_files records them; unconfirmed rows are reconciled by the worker';
+COMMENT ON COLUMN chunk_upload_events.chunk_bytes IS 'Object size, added to sync_files.stored_bytes if the worker replays the update';
+COMMENT ON COLUMN chunk_upload_events.last_message_at IS 'Latest per-line transcript timestamp in the chunk, replayed into sessions.last_message_at';
+COMMENT ON COLUMN chunk_upload_events.confirmed_at IS 'When the upload was settled; NULL while it is pending';
+COMMENT ON COLUMN chunk_upload_events.resolution IS 'How it was settled: synced by the handler, or replayed/superseded/discarded/missing by the worker';
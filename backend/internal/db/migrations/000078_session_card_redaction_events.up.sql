@@ -0,0 +1,14 @@
+-- Per-redaction detail backing GET /sessions/{id}/cards/redactions/details.
+-- Rewritten wholesale whenever the redactions card is recomputed; capped at
+-- analytics.MaxRedactionEvents rows per session.
+CREATE TABLE session_card_redaction_events (
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    redaction_index INT NOT NULL,
+    -- Marker type (e.g. GITHUB_TOKEN), or UNSPECIFIED for a bare [REDACTED]
+    type VARCHAR(64) NOT NULL,
+    line_number INT NOT NULL,
+    -- The marker with its surrounding text obfuscated; never the raw text
+    context_preview VARCHAR(50) NOT NULL,
+
+    PRIMARY KEY (session_id, redaction_index)
+);
@@ -0,0 +1,23 @@
+-- Admin merges of a duplicate account into another (POST
+-- /api/v1/admin/users/{id}/merge). The merge moves the source user's
+-- sessions, API keys, identities and settings to the target and deletes the
+-- source, so this row is what remains of it.
+--
+-- No FKs to users(id): the source is gone by the time the row commits, and
+-- the record must survive the target's or the admin's later deletion too.
+CREATE TABLE user_merges (
+    id                  BIGSERIAL PRIMARY KEY,
+    source_user_id      BIGINT NOT NULL,
+    source_email        VARCHAR(255) NOT NULL,
+    target_user_id      BIGINT NOT NULL,
+    admin_user_id       BIGINT NOT NULL,
+    sessions_moved      INTEGER NOT NULL,
+    api_keys_moved      INTEGER NOT NULL,
+    identities_moved    INTEGER NOT NULL,
+    merged_at           TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_merges_target ON user_merges (target_user_id);
+
+COMMENT ON TABLE user_merges IS 'Audit log of admin account merges (source deleted, data moved to target)';
+COMMENT ON COLUMN user_merges.source_email IS 'Email of the deleted source account at merge time';
@@ -0,0 +1,21 @@
+-- Views of sessions opened through a share (GET /api/v1/sessions/{id} with
+-- share access), for the owner's GET /api/v1/sessions/{id}/share/{shareID}/stats.
+--
+-- Aggregated per share, UTC day, approximate viewer and user agent class: a
+-- repeat view bumps views on the existing row, so volume grows with distinct
+-- viewers per day rather than with page loads. viewer_hash is a hash of the
+-- share ID and the viewer's truncated IP (/24 for IPv4, /48 for IPv6); neither
+-- the IP nor the user agent string is stored.
+CREATE TABLE share_access_log (
+    share_id            BIGINT NOT NULL REFERENCES session_shares(id) ON DELETE CASCADE,
+    day                 DATE NOT NULL,
+    viewer_hash         VARCHAR(64) NOT NULL,
+    user_agent_class    VARCHAR(16) NOT NULL,
+    views               INTEGER NOT NULL DEFAULT 1,
+    last_viewed_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (share_id, day, viewer_hash, user_agent_class)
+);
+
+COMMENT ON TABLE share_access_log IS 'Daily per-viewer view counts of shared sessions (DISABLE_SHARE_ACCESS_LOG turns recording off)';
+COMMENT ON COLUMN share_access_log.viewer_hash IS 'Hash of share ID + truncated client IP; approximates unique viewers';
+COMMENT ON COLUMN share_access_log.user_agent_class IS 'browser, cli, bot or other';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS usage_correction_factors;
+DROP TABLE IF EXISTS usage_reconciliation;
@@ -0,0 +1,35 @@
+-- Actual Anthropic usage imported from the console's usage CSV export
+-- (POST /api/v1/admin/reconciliation/import), compared against the summed
+-- tokens_v2 card estimates by GET /api/v1/admin/reconciliation.
+--
+-- One row per UTC day and model family. The export's per-workspace, per-key and
+-- per-token-type rows are summed into it on import; re-importing a day replaces
+-- all of that day's rows. Card values are never adjusted from this table.
+CREATE TABLE usage_reconciliation (
+    usage_date          DATE NOT NULL,
+    model               VARCHAR(128) NOT NULL,
+    cost_usd            NUMERIC(14, 6) NOT NULL,
+    input_tokens        BIGINT NOT NULL DEFAULT 0,
+    output_tokens       BIGINT NOT NULL DEFAULT 0,
+    cache_write_tokens  BIGINT NOT NULL DEFAULT 0,
+    cache_read_tokens   BIGINT NOT NULL DEFAULT 0,
+    imported_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    imported_by         BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    PRIMARY KEY (usage_date, model)
+);
+
+COMMENT ON TABLE usage_reconciliation IS 'Actual daily Anthropic usage per model family, imported from the console CSV export';
+COMMENT ON COLUMN usage_reconciliation.model IS 'Model family as used by the pricing table (e.g. opus-4-5)';
+
+-- Operator-set correction factors (actual / estimated cost) per calendar month.
+-- GET /api/v1/trends?apply_correction=true reports a corrected total next to
+-- the estimate; the estimate itself is never changed.
+CREATE TABLE usage_correction_factors (
+    month       DATE PRIMARY KEY CHECK (EXTRACT(DAY FROM month) = 1),
+    factor      NUMERIC(8, 4) NOT NULL CHECK (factor > 0),
+    note        TEXT NOT NULL DEFAULT '',
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_by  BIGINT REFERENCES users(id) ON DELETE SET NULL
+);
+
+COMMENT ON TABLE usage_correction_factors IS 'Per-month cost correction factors from invoice reconciliation; applied only on request';
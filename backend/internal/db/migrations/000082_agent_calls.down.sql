@@ -0,0 +1 @@
+ALTER TABLE session_card_agents_and_skills DROP COLUMN agent_calls;
@@ -0,0 +1,6 @@
+-- Per-invocation agent calls (agent id, type, parent agent, duration) on the
+-- agents and skills card, for the agent details endpoint. Existing rows are
+-- recomputed by the precompute worker after the card version bump.
+ALTER TABLE session_card_agents_and_skills ADD COLUMN agent_calls JSONB NOT NULL DEFAULT '[]';
+
+COMMENT ON COLUMN session_card_agents_and_skills.agent_calls IS 'Per-invocation agent calls with parent agent and duration (capped)';
@@ -0,0 +1,43 @@
+-- Outbound webhooks. A user registers endpoints; events (so far only
+-- session.created, enqueued in the statement that inserts the session) are
+-- queued in webhook_deliveries and POSTed, signed with the endpoint's secret,
+-- by the worker's webhook dispatcher.
+CREATE TABLE webhook_endpoints (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url         TEXT NOT NULL,
+    secret      TEXT NOT NULL,
+    events      TEXT[] NOT NULL DEFAULT '{session.created}',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_endpoints_user ON webhook_endpoints (user_id);
+
+CREATE TABLE webhook_deliveries (
+    id                BIGSERIAL PRIMARY KEY,
+    endpoint_id       BIGINT NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    event_type        VARCHAR(64) NOT NULL,
+    payload           JSONB NOT NULL,
+    status            VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts          INT NOT NULL DEFAULT 0,
+    next_attempt_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_attempt_at   TIMESTAMPTZ,
+    last_status_code  INT,
+    last_error        TEXT,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at      TIMESTAMPTZ,
+    CONSTRAINT webhook_deliveries_status_check
+        CHECK (status IN ('pending', 'delivered', 'failed'))
+);
+
+-- Dispatcher queue.
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+-- Recent deliveries per endpoint (listing) and retention pruning.
+CREATE INDEX idx_webhook_deliveries_endpoint ON webhook_deliveries (endpoint_id, created_at DESC);
+CREATE INDEX idx_webhook_deliveries_completed ON webhook_deliveries (completed_at) WHERE completed_at IS NOT NULL;
+
+COMMENT ON TABLE webhook_endpoints IS 'User-registered outbound webhook endpoints';
+COMMENT ON COLUMN webhook_endpoints.secret IS 'HMAC-SHA256 signing secret; stored in clear because every delivery is signed with it';
+COMMENT ON TABLE webhook_deliveries IS 'Outbound webhook queue: one row per event per endpoint';
+COMMENT ON COLUMN webhook_deliveries.next_attempt_at IS 'When the dispatcher may (re)try; pushed forward while an attempt is in flight';
+COMMENT ON COLUMN webhook_deliveries.completed_at IS 'When the delivery succeeded or was given up on (NULL while pending)';
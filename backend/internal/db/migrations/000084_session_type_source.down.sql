@@ -0,0 +1 @@
+ALTER TABLE sessions DROP COLUMN session_type_source;
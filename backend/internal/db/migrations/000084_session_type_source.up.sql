@@ -0,0 +1,9 @@
+-- How a session's session_type was decided: 'default' (sync/init sent no
+-- type), 'explicit' (the client sent one), or 'detected' (a default type
+-- corrected from the first transcript chunk). Only 'default' rows may be
+-- reclassified, and a sync/init without a type still finds 'detected' rows.
+-- Existing rows predate the distinction and are treated as 'default'.
+ALTER TABLE sessions ADD COLUMN session_type_source TEXT NOT NULL DEFAULT 'default'
+    CHECK (session_type_source IN ('default', 'explicit', 'detected'));
+
+COMMENT ON COLUMN sessions.session_type_source IS 'How session_type was decided: default, explicit, or detected';
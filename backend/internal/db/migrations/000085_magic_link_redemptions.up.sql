@@ -0,0 +1,10 @@
+-- Magic-link tokens are stateless HMACs; this records the ones already used
+-- so a link can sign in only once. Rows are useless after expires_at and are
+-- pruned by the worker's retention pass.
+CREATE TABLE magic_link_redemptions (
+    token_id    TEXT PRIMARY KEY,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    redeemed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_magic_link_redemptions_expires_at ON magic_link_redemptions (expires_at);
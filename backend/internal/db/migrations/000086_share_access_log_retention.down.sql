@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_share_access_log_last_viewed_at;
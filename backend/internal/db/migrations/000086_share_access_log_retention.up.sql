@@ -0,0 +1,3 @@
+-- The retention worker prunes share_access_log by last_viewed_at
+-- (WORKER_RETENTION_SHARE_ACCESS_LOG), oldest first.
+CREATE INDEX idx_share_access_log_last_viewed_at ON share_access_log (last_viewed_at);
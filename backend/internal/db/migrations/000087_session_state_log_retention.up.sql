@@ -0,0 +1,3 @@
+-- The retention worker prunes session_state_log by created_at
+-- (WORKER_RETENTION_SESSION_STATE_LOG), oldest first.
+CREATE INDEX idx_session_state_log_created_at ON session_state_log (created_at);
@@ -0,0 +1 @@
+ALTER TABLE chunk_upload_events DROP COLUMN IF EXISTS generation;
@@ -0,0 +1,6 @@
+-- The sync_files generation a chunk upload was read against, so the worker
+-- never replays a chunk of an archived generation onto the file after a
+-- POST /sync/file/reset.
+ALTER TABLE chunk_upload_events ADD COLUMN generation INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN chunk_upload_events.generation IS 'sync_files.generation when the chunk was uploaded; a mismatch means the file was reset since';
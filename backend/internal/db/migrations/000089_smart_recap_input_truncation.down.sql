@@ -0,0 +1 @@
+ALTER TABLE session_card_smart_recap DROP COLUMN IF EXISTS input_omitted_entries;
@@ -0,0 +1,6 @@
+-- How many transcript elements the smart recap left out to keep its input
+-- under SMART_RECAP_MAX_TRANSCRIPT_TOKENS. 0 means the whole transcript was
+-- sent; recaps generated before this column are recorded as 0.
+ALTER TABLE session_card_smart_recap ADD COLUMN input_omitted_entries INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN session_card_smart_recap.input_omitted_entries IS 'Transcript elements dropped to fit the recap input cap; 0 = not truncated';
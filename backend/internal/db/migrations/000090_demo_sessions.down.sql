@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_sessions_user_demo;
+ALTER TABLE sessions DROP COLUMN IF EXISTS is_demo;
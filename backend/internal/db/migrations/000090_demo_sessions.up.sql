@@ -0,0 +1,8 @@
+-- Sample sessions seeded by POST /api/v1/me/demo. They are real sessions
+-- (uploaded through sync/chunk) but are left out of cost forecasts and recap
+-- quota counting, and DELETE /api/v1/me/demo removes them in bulk.
+ALTER TABLE sessions ADD COLUMN is_demo BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_demo ON sessions(user_id) WHERE is_demo;
+
+COMMENT ON COLUMN sessions.is_demo IS 'Seeded sample session; excluded from cost forecasts and recap quota';
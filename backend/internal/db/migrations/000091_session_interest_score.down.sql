@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_sessions_interest_score;
+ALTER TABLE sessions DROP COLUMN IF EXISTS interest_score;
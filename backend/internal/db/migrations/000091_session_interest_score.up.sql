@@ -0,0 +1,32 @@
+-- Interest score for the session list's sort=interesting (db.InterestScore):
+-- log2(1 + quality) + last activity / half-life. The recency term is built in,
+-- so the score is written once per card update and never re-decayed. New
+-- sessions are written with the no-cards baseline; rows written by neither
+-- path keep 0 and sort last.
+ALTER TABLE sessions ADD COLUMN interest_score DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- Backfill with DefaultInterestWeights (cost 1/USD, duration 1/hour, 0.1 per
+-- tool error, 1 for a recap, baseline 1, half-life 7 days). Operators who set
+-- INTEREST_* weights get them applied as the precomputer refreshes sessions.
+UPDATE sessions s
+SET interest_score = ln(1 + GREATEST(q.quality, 0)) / ln(2)
+	+ EXTRACT(EPOCH FROM COALESCE(s.last_message_at, s.first_seen)) / 604800.0
+FROM (
+	SELECT s2.id,
+		CASE WHEN sc.session_id IS NULL THEN 1.0
+		ELSE COALESCE((v.data->>'total_cost_usd')::float8, 0)
+			+ COALESCE(sc.duration_ms, 0) / 3600000.0
+			+ 0.1 * COALESCE(t.error_count, 0)
+			+ CASE WHEN r.recap <> '' THEN 1.0 ELSE 0 END
+		END AS quality
+	FROM sessions s2
+	LEFT JOIN session_card_session sc ON sc.session_id = s2.id
+	LEFT JOIN session_card_tokens_v2 v ON v.session_id = s2.id
+	LEFT JOIN session_card_tools t ON t.session_id = s2.id
+	LEFT JOIN session_card_smart_recap r ON r.session_id = s2.id
+) q
+WHERE q.id = s.id;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_interest_score ON sessions(interest_score DESC, id DESC);
+
+COMMENT ON COLUMN sessions.interest_score IS 'Recency-decayed interest (db.InterestScore); refreshed by the precomputer after card updates';
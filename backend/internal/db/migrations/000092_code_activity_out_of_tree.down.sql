@@ -0,0 +1,5 @@
+ALTER TABLE session_card_code_activity
+    DROP COLUMN IF EXISTS out_of_tree_samples,
+    DROP COLUMN IF EXISTS out_of_tree_external,
+    DROP COLUMN IF EXISTS out_of_tree_temp,
+    DROP COLUMN IF EXISTS out_of_tree_home;
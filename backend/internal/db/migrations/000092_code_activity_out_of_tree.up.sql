@@ -0,0 +1,11 @@
+-- Files touched outside the session's cwd by Read/Write/Edit calls, split
+-- into home-directory, temp-dir and external paths, with a capped sample.
+-- Existing rows are recomputed by the precompute worker after the card
+-- version bump; until then they read as no out-of-tree access.
+ALTER TABLE session_card_code_activity
+    ADD COLUMN out_of_tree_home INT NOT NULL DEFAULT 0,
+    ADD COLUMN out_of_tree_temp INT NOT NULL DEFAULT 0,
+    ADD COLUMN out_of_tree_external INT NOT NULL DEFAULT 0,
+    ADD COLUMN out_of_tree_samples JSONB NOT NULL DEFAULT '[]';
+
+COMMENT ON COLUMN session_card_code_activity.out_of_tree_samples IS 'First out-of-tree paths seen, with category (home, temp, external)';
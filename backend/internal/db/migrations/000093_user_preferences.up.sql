@@ -0,0 +1,15 @@
+-- Per-user preferences (dbuser.Preferences). A user without a row has the
+-- defaults, which mirror dbuser.DefaultPreferences; PATCH /api/v1/me/preferences
+-- writes the row on first change.
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    smart_recap_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    digest_frequency TEXT NOT NULL DEFAULT 'off'
+        CHECK (digest_frequency IN ('off', 'daily', 'weekly')),
+    notify_on_recap BOOLEAN NOT NULL DEFAULT FALSE,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE user_preferences IS 'Per-user preferences (dbuser.Preferences); a missing row means the defaults';
@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS sync_file_growth_append(JSONB, JSONB, INT);
+ALTER TABLE sync_files DROP COLUMN IF EXISTS growth;
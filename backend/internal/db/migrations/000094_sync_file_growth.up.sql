@@ -0,0 +1,25 @@
+-- Growth history of each sync file (db.GrowthPoint): the last_synced_line
+-- after each chunk upload, for the session list's growth sparkline.
+ALTER TABLE sync_files ADD COLUMN IF NOT EXISTS growth JSONB NOT NULL DEFAULT '[]';
+
+-- Appends point to growth and, while it holds more than cap points, keeps the
+-- newer half and every other point of the older half. Mirrors
+-- db.DownsampleGrowth, so the first and last points always stay. Called from
+-- the UPDATE that advances last_synced_line.
+CREATE OR REPLACE FUNCTION sync_file_growth_append(growth JSONB, point JSONB, cap INT)
+RETURNS JSONB AS $$
+DECLARE
+    pts JSONB := COALESCE(growth, '[]'::jsonb) || jsonb_build_array(point);
+    n INT := jsonb_array_length(pts);
+    half INT;
+BEGIN
+    WHILE n > cap AND n > 3 LOOP
+        half := n / 2;
+        SELECT jsonb_agg(e ORDER BY i) INTO pts
+        FROM jsonb_array_elements(pts) WITH ORDINALITY AS t(e, i)
+        WHERE i - 1 >= half OR (i - 1) % 2 = 0;
+        n := jsonb_array_length(pts);
+    END LOOP;
+    RETURN pts;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_session_card_session_duration;
+DROP INDEX IF EXISTS idx_session_card_tokens_v2_cost;
+DROP INDEX IF EXISTS idx_sessions_first_seen_id;
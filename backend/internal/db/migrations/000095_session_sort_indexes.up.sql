@@ -0,0 +1,11 @@
+-- Indexes for the session list's sort keys (db.SessionSortKeys).
+-- created_at_* orders sessions by first_seen, with id breaking ties.
+CREATE INDEX IF NOT EXISTS idx_sessions_first_seen_id ON sessions(first_seen DESC, id DESC);
+
+-- cost_* and duration_* order by a card column; these let a short page walk
+-- the card table in order instead of sorting every visible session.
+-- line_count_desc orders by a sum over sync_files and has no index.
+CREATE INDEX IF NOT EXISTS idx_session_card_tokens_v2_cost
+    ON session_card_tokens_v2 (((data->>'total_cost_usd')::float8));
+CREATE INDEX IF NOT EXISTS idx_session_card_session_duration
+    ON session_card_session (duration_ms);
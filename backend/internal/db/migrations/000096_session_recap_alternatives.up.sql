@@ -0,0 +1,18 @@
+-- Smart recap variants in another style, generated on demand by
+-- GET /api/v1/sessions/{id}/cards/smart-recap/alternatives and served from
+-- here for 24 hours.
+--
+-- One row per session and style. While a variant is being generated its row
+-- holds an empty recap_text, which keeps concurrent requests from generating
+-- the same style twice; a failed generation deletes the row. The per-session
+-- daily limit counts the rows created in the last 24 hours.
+CREATE TABLE session_recap_alternatives (
+    session_id  UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    style       VARCHAR(16) NOT NULL,
+    recap_text  TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (session_id, style)
+);
+
+COMMENT ON TABLE session_recap_alternatives IS 'Cached smart recap variants per session and style (bullet, prose, technical)';
+COMMENT ON COLUMN session_recap_alternatives.recap_text IS 'Generated recap; empty while generation is in progress';
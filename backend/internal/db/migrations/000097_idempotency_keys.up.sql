@@ -0,0 +1,25 @@
+-- Idempotency-Key records for opt-in mutating dashboard endpoints (shares,
+-- webhooks, smart recap regenerate). A replayed request within 24 hours gets
+-- the stored response instead of repeating the side effect.
+--
+-- key_hash is SHA-256 of the user, method, path and client key, so keys are
+-- scoped per user and per route; request_hash is SHA-256 of the body, which
+-- tells a replay from a reused key. status_code is NULL while the first
+-- request is still being handled; a failed (5xx or 429) request deletes its
+-- row so the client can retry with the same key.
+CREATE TABLE idempotency_keys (
+    key_hash      TEXT PRIMARY KEY,
+    user_id       BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    request_hash  TEXT NOT NULL,
+    status_code   INT,
+    content_type  TEXT NOT NULL DEFAULT '',
+    response_body BYTEA,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_idempotency_keys_user ON idempotency_keys (user_id);
+-- Retention pruning walks rows oldest first.
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at);
+
+COMMENT ON TABLE idempotency_keys IS 'Stored responses of mutating requests sent with an Idempotency-Key header';
+COMMENT ON COLUMN idempotency_keys.status_code IS 'Stored response status; NULL while the first request is in progress';
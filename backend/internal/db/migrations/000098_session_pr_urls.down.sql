@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_sessions_user_pr_url;
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS detected_pr_url,
+    DROP COLUMN IF EXISTS pr_url;
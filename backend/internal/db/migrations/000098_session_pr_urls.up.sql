@@ -0,0 +1,15 @@
+-- The pull request a session produced, for GET /api/v1/analytics/outcomes.
+-- pr_url is set by the owner (PATCH /api/v1/sessions/{id}/pr-url);
+-- detected_pr_url is written by card computation when the transcript shows a
+-- `gh pr create` result. They are separate columns so detection never
+-- overwrites a manual value: the session's PR is COALESCE(pr_url,
+-- detected_pr_url).
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS pr_url TEXT,
+    ADD COLUMN IF NOT EXISTS detected_pr_url TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_pr_url ON sessions(user_id)
+    WHERE pr_url IS NOT NULL OR detected_pr_url IS NOT NULL;
+
+COMMENT ON COLUMN sessions.pr_url IS 'Owner-set GitHub PR or GitLab MR URL (normalized); wins over detected_pr_url';
+COMMENT ON COLUMN sessions.detected_pr_url IS 'PR URL detected from gh pr create output during card computation';
@@ -0,0 +1,83 @@
+-- Point-in-time model prices. Each row prices one model family (the key
+-- getModelFamily derives from a model name, e.g. "opus-4-7", "gpt-5") over
+-- [effective_from, effective_to); a NULL effective_to is open-ended. Card
+-- computation prices a session at its first_seen, so a price change applies to
+-- sessions started after it without touching older ones. Families without a row
+-- fall back to the pricing.json table. Rates are USD per million tokens.
+CREATE TABLE IF NOT EXISTS model_pricing (
+    id BIGSERIAL PRIMARY KEY,
+    model_name TEXT NOT NULL,
+    input_cost_per_1m NUMERIC(12, 6) NOT NULL,
+    output_cost_per_1m NUMERIC(12, 6) NOT NULL,
+    cache_creation_cost_per_1m NUMERIC(12, 6) NOT NULL,
+    cache_creation_1h_cost_per_1m NUMERIC(12, 6) NOT NULL DEFAULT 0,
+    cache_read_cost_per_1m NUMERIC(12, 6) NOT NULL,
+    effective_from TIMESTAMPTZ NOT NULL,
+    effective_to TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT model_pricing_rates_non_negative CHECK (
+        input_cost_per_1m >= 0 AND output_cost_per_1m >= 0
+        AND cache_creation_cost_per_1m >= 0 AND cache_creation_1h_cost_per_1m >= 0
+        AND cache_read_cost_per_1m >= 0
+    ),
+    CONSTRAINT model_pricing_period_valid CHECK (effective_to IS NULL OR effective_to > effective_from),
+    CONSTRAINT model_pricing_model_from_unique UNIQUE (model_name, effective_from)
+);
+
+-- At most one open-ended period per model.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_model_pricing_open
+    ON model_pricing (model_name) WHERE effective_to IS NULL;
+
+COMMENT ON TABLE model_pricing IS 'Point-in-time per-model prices (USD per million tokens); updated via POST /api/v1/admin/model-pricing';
+COMMENT ON COLUMN model_pricing.model_name IS 'Pricing family key (getModelFamily), e.g. opus-4-7 or gpt-5';
+COMMENT ON COLUMN model_pricing.cache_creation_cost_per_1m IS '5-minute cache writes';
+COMMENT ON COLUMN model_pricing.cache_creation_1h_cost_per_1m IS '1-hour cache writes; 0 falls back to cache_creation_cost_per_1m';
+COMMENT ON COLUMN model_pricing.effective_to IS 'Exclusive end of the period; NULL while current';
+
+-- Seed with the pricing.json prices (updated_at 2026-07-06). Sonnet 5's
+-- introductory rates, previously a hard-coded date switch, become its first
+-- period.
+INSERT INTO model_pricing (
+    model_name, input_cost_per_1m, output_cost_per_1m, cache_creation_cost_per_1m,
+    cache_creation_1h_cost_per_1m, cache_read_cost_per_1m, effective_from, effective_to
+) VALUES
+    ('fable-5', 10, 50, 12.5, 20, 1, '1970-01-01 00:00:00+00', NULL),
+    ('opus-4-8', 5, 25, 6.25, 10, 0.5, '1970-01-01 00:00:00+00', NULL),
+    ('opus-4-7', 5, 25, 6.25, 10, 0.5, '1970-01-01 00:00:00+00', NULL),
+    ('opus-4-6', 5, 25, 6.25, 10, 0.5, '1970-01-01 00:00:00+00', NULL),
+    ('opus-4-5', 5, 25, 6.25, 10, 0.5, '1970-01-01 00:00:00+00', NULL),
+    ('opus-4-1', 15, 75, 18.75, 30, 1.5, '1970-01-01 00:00:00+00', NULL),
+    ('opus-4', 15, 75, 18.75, 30, 1.5, '1970-01-01 00:00:00+00', NULL),
+    ('sonnet-5', 2, 10, 2.5, 4, 0.2, '1970-01-01 00:00:00+00', '2026-09-01 00:00:00+00'),
+    ('sonnet-5', 3, 15, 3.75, 6, 0.3, '2026-09-01 00:00:00+00', NULL),
+    ('sonnet-4-6', 3, 15, 3.75, 6, 0.3, '1970-01-01 00:00:00+00', NULL),
+    ('sonnet-4-5', 3, 15, 3.75, 6, 0.3, '1970-01-01 00:00:00+00', NULL),
+    ('sonnet-4', 3, 15, 3.75, 6, 0.3, '1970-01-01 00:00:00+00', NULL),
+    ('sonnet-3-7', 3, 15, 3.75, 6, 0.3, '1970-01-01 00:00:00+00', NULL),
+    ('haiku-4-5', 1, 5, 1.25, 2, 0.1, '1970-01-01 00:00:00+00', NULL),
+    ('haiku-3-5', 0.8, 4, 1, 1.6, 0.08, '1970-01-01 00:00:00+00', NULL),
+    ('opus-3', 15, 75, 18.75, 30, 1.5, '1970-01-01 00:00:00+00', NULL),
+    ('haiku-3', 0.25, 1.25, 0.3, 0.5, 0.03, '1970-01-01 00:00:00+00', NULL),
+    ('gpt-5', 1.25, 10, 0, 0, 0.125, '1970-01-01 00:00:00+00', NULL),
+    ('gpt-5-mini', 0.25, 2, 0, 0, 0.025, '1970-01-01 00:00:00+00', NULL),
+    ('gpt-5-nano', 0.05, 0.4, 0, 0, 0.005, '1970-01-01 00:00:00+00', NULL),
+    ('gpt-5.4', 2.5, 15, 0, 0, 0.25, '1970-01-01 00:00:00+00', NULL),
+    ('gpt-5.4-mini', 0.75, 4.5, 0, 0, 0.075, '1970-01-01 00:00:00+00', NULL),
+    ('gpt-5.5', 5, 30, 0, 0, 0.5, '1970-01-01 00:00:00+00', NULL),
+    ('gpt-4o', 2.5, 10, 0, 0, 1.25, '1970-01-01 00:00:00+00', NULL),
+    ('gpt-4o-mini', 0.15, 0.6, 0, 0, 0.075, '1970-01-01 00:00:00+00', NULL),
+    ('gpt-4-turbo', 10, 30, 0, 0, 0, '1970-01-01 00:00:00+00', NULL),
+    ('o1', 15, 60, 0, 0, 7.5, '1970-01-01 00:00:00+00', NULL),
+    ('o1-mini', 1.1, 4.4, 0, 0, 0.55, '1970-01-01 00:00:00+00', NULL),
+    ('o3', 2, 8, 0, 0, 0.5, '1970-01-01 00:00:00+00', NULL),
+    ('o3-mini', 1.1, 4.4, 0, 0, 0.55, '1970-01-01 00:00:00+00', NULL),
+    ('o4-mini', 1.1, 4.4, 0, 0, 0.275, '1970-01-01 00:00:00+00', NULL),
+    ('gemini-2.5-pro', 1.25, 5, 0, 0, 0.315, '1970-01-01 00:00:00+00', NULL),
+    ('gemini-2.5-flash', 0.075, 0.3, 0, 0, 0.01875, '1970-01-01 00:00:00+00', NULL),
+    ('deepseek-v3', 0.27, 1.1, 0, 0, 0.07, '1970-01-01 00:00:00+00', NULL),
+    ('deepseek-r1', 0.55, 2.19, 0, 0, 0.14, '1970-01-01 00:00:00+00', NULL),
+    ('grok-3', 3, 15, 0, 0, 0, '1970-01-01 00:00:00+00', NULL),
+    ('grok-3-mini', 0.3, 0.5, 0, 0, 0, '1970-01-01 00:00:00+00', NULL),
+    ('mistral-large-2411', 2, 6, 0, 0, 0, '1970-01-01 00:00:00+00', NULL),
+    ('mistral-small-2501', 0.1, 0.3, 0, 0, 0, '1970-01-01 00:00:00+00', NULL)
+ON CONFLICT (model_name, effective_from) DO NOTHING;
@@ -0,0 +1,2 @@
+ALTER TABLE session_card_code_activity
+    DROP COLUMN IF EXISTS code_files;
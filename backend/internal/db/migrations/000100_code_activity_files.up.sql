@@ -0,0 +1,9 @@
+-- Per-file activity for the code activity card (reads, writes, lines added and
+-- removed), capped at 500 files per session and served by
+-- GET /api/v1/sessions/{id}/cards/code-activity/files. Existing rows are
+-- recomputed by the precompute worker after the card version bump; until then
+-- they read as no files.
+ALTER TABLE session_card_code_activity
+    ADD COLUMN code_files JSONB NOT NULL DEFAULT '[]';
+
+COMMENT ON COLUMN session_card_code_activity.code_files IS 'Per-file read/write counts and line deltas, most written first, at most 500';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_sessions_org_visible;
+ALTER TABLE sessions DROP COLUMN IF EXISTS visibility;
+DROP TABLE IF EXISTS org_members;
+DROP TABLE IF EXISTS orgs;
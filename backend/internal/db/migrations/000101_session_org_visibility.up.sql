@@ -0,0 +1,29 @@
+-- Organizations and per-session visibility. A session with visibility 'org'
+-- is readable (never writable) by every user who shares an organization with
+-- its owner; 'private' sessions stay owner-and-shares only. Organizations and
+-- their members are managed by admins (/api/v1/admin/orgs).
+CREATE TABLE orgs (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE org_members (
+    org_id     BIGINT NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+-- Membership lookups start from the user (viewer or session owner).
+CREATE INDEX idx_org_members_user ON org_members (user_id);
+
+ALTER TABLE sessions ADD COLUMN visibility TEXT NOT NULL DEFAULT 'private'
+    CONSTRAINT sessions_visibility_valid CHECK (visibility IN ('private', 'org'));
+
+-- The session list's org branch scans only org-visible sessions.
+CREATE INDEX idx_sessions_org_visible ON sessions (user_id) WHERE visibility = 'org';
+
+COMMENT ON TABLE orgs IS 'Organizations whose members can read each other''s org-visible sessions';
+COMMENT ON TABLE org_members IS 'Organization membership; a user may belong to several organizations';
+COMMENT ON COLUMN sessions.visibility IS 'private (owner and explicit shares) or org (also readable by members of the owner''s organizations)';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS api_key_usage;
+DROP INDEX IF EXISTS idx_sessions_created_by_api_key_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS created_by_api_key_id;
@@ -0,0 +1,32 @@
+-- Per-API-key usage (GET /api/v1/keys/{id}/usage): which key created each
+-- session, and daily counters of the chunk uploads made with each key.
+--
+-- created_by_api_key_id is set by sync/init when it creates a session; it
+-- stays NULL for sessions created from the web, imported, or synced before
+-- this column existed, and reverts to NULL when the key is deleted.
+ALTER TABLE sessions
+    ADD COLUMN created_by_api_key_id BIGINT REFERENCES api_keys(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_sessions_created_by_api_key_id ON sessions (created_by_api_key_id)
+    WHERE created_by_api_key_id IS NOT NULL;
+
+-- Chunk upload counters, aggregated in memory by the API server and added in
+-- batches, so there is no write per chunk. The retention worker prunes rows by
+-- updated_at (WORKER_RETENTION_API_KEY_USAGE).
+CREATE TABLE api_key_usage (
+    api_key_id  BIGINT NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    day         DATE NOT NULL,
+    requests    BIGINT NOT NULL DEFAULT 0,
+    lines       BIGINT NOT NULL DEFAULT 0,
+    bytes       BIGINT NOT NULL DEFAULT 0,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (api_key_id, day)
+);
+
+CREATE INDEX idx_api_key_usage_updated_at ON api_key_usage (updated_at);
+
+COMMENT ON COLUMN sessions.created_by_api_key_id IS 'API key whose sync/init created the session; NULL for web-created or imported sessions';
+COMMENT ON TABLE api_key_usage IS 'Daily (UTC) chunk upload counters per API key';
+COMMENT ON COLUMN api_key_usage.requests IS 'Chunk uploads stored';
+COMMENT ON COLUMN api_key_usage.lines IS 'Lines in stored chunks';
+COMMENT ON COLUMN api_key_usage.bytes IS 'Bytes of stored chunk content';
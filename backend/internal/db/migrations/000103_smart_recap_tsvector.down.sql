@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_session_card_smart_recap_tsvector;
+ALTER TABLE session_card_smart_recap DROP COLUMN IF EXISTS recap_tsvector;
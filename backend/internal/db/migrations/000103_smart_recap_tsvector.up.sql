@@ -0,0 +1,21 @@
+-- Full-text search over smart recaps that does not wait for the search index.
+-- session_search_index folds the recap in only when the worker rebuilds it, so
+-- a freshly generated recap is unsearchable until then. The column follows
+-- every write to the row. Suggestion lists hold strings (legacy) or
+-- {"text", "message_id"} objects; jsonb_to_tsvector indexes their string
+-- values without the keys.
+ALTER TABLE session_card_smart_recap ADD COLUMN recap_tsvector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(recap, '')), 'A') ||
+        setweight(
+            jsonb_to_tsvector('english', COALESCE(went_well, '[]'), '["string"]') ||
+            jsonb_to_tsvector('english', COALESCE(went_bad, '[]'), '["string"]') ||
+            jsonb_to_tsvector('english', COALESCE(human_suggestions, '[]'), '["string"]') ||
+            jsonb_to_tsvector('english', COALESCE(environment_suggestions, '[]'), '["string"]') ||
+            jsonb_to_tsvector('english', COALESCE(default_context_suggestions, '[]'), '["string"]'),
+            'B')
+    ) STORED;
+
+CREATE INDEX idx_session_card_smart_recap_tsvector ON session_card_smart_recap USING GIN (recap_tsvector);
+
+COMMENT ON COLUMN session_card_smart_recap.recap_tsvector IS 'Search vector over the recap (weight A) and suggestion lists (weight B); used by GET /api/v1/sessions/search/recap';
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS notification_queue;
+ALTER TABLE user_preferences
+    DROP CONSTRAINT IF EXISTS user_preferences_quiet_hours_check,
+    DROP COLUMN IF EXISTS quiet_hours_end,
+    DROP COLUMN IF EXISTS quiet_hours_start,
+    DROP COLUMN IF EXISTS notification_channels;
@@ -0,0 +1,30 @@
+-- Notification preferences (dbuser.Preferences.Notifications and QuietHours)
+-- and the queue of notifications held back by quiet hours. notify.Dispatcher
+-- sends a notification at once, or queues it until the user's quiet window
+-- ends; the worker flushes due rows each cycle.
+ALTER TABLE user_preferences
+    ADD COLUMN notification_channels JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN quiet_hours_start TEXT,
+    ADD COLUMN quiet_hours_end TEXT,
+    ADD CONSTRAINT user_preferences_quiet_hours_check
+        CHECK ((quiet_hours_start IS NULL) = (quiet_hours_end IS NULL));
+
+CREATE TABLE notification_queue (
+    id                 BIGSERIAL PRIMARY KEY,
+    user_id            BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    notification_type  TEXT NOT NULL,
+    channel            TEXT NOT NULL CHECK (channel IN ('email', 'webhook')),
+    payload            JSONB NOT NULL,
+    attempts           INT NOT NULL DEFAULT 0,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deliver_after      TIMESTAMPTZ NOT NULL
+);
+
+-- Flush order: due first, then oldest.
+CREATE INDEX idx_notification_queue_due ON notification_queue (deliver_after, id);
+CREATE INDEX idx_notification_queue_user ON notification_queue (user_id);
+
+COMMENT ON COLUMN user_preferences.notification_channels IS 'Per notification type: {"email": bool, "webhook": bool}; types not listed use their defaults';
+COMMENT ON COLUMN user_preferences.quiet_hours_start IS 'Start of the daily quiet window, HH:MM in the user''s timezone (NULL = no quiet hours)';
+COMMENT ON TABLE notification_queue IS 'Notifications held back by quiet hours, sent by the worker once deliver_after passes';
+COMMENT ON COLUMN notification_queue.deliver_after IS 'End of the quiet window; pushed forward while a flush attempt is in flight';
@@ -0,0 +1,9 @@
+-- Per-user rate limit tier (models.RateLimitTier). The per-user limiters on
+-- the sync and external API routes pick their limit set by tier; the limits
+-- for each tier come from RATE_LIMIT_TIER_*_MULTIPLIER. Everyone starts on
+-- the standard tier; admins move users with
+-- PUT /api/v1/admin/users/{id}/rate-limit-tier.
+ALTER TABLE users ADD COLUMN rate_limit_tier TEXT NOT NULL DEFAULT 'standard'
+    CHECK (rate_limit_tier IN ('standard', 'trusted', 'internal'));
+
+COMMENT ON COLUMN users.rate_limit_tier IS 'Rate limit tier (models.RateLimitTier): standard, trusted, or internal';
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DefaultQueryTimeout bounds analytics queries when DB_QUERY_TIMEOUT_SECONDS
+// is unset.
+const DefaultQueryTimeout = 30 * time.Second
+
+// queryTimeoutGrace is how long the context deadline trails the statement
+// timeout. Postgres cancelling the statement itself leaves the connection
+// reusable; the client-side deadline is only a backstop.
+const queryTimeoutGrace = time.Second
+
+// LoadQueryTimeout reads DB_QUERY_TIMEOUT_SECONDS. Unset, unparseable or
+// non-positive values keep DefaultQueryTimeout.
+func LoadQueryTimeout() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("DB_QUERY_TIMEOUT_SECONDS")); err == nil && v > 0 {
+		return time.Duration(v) * time.Second
+	}
+	return DefaultQueryTimeout
+}
+
+type queryTimeoutKey struct{}
+
+// WithQueryTimeout bounds ctx by d and records d so BeginQueryTx can apply it
+// server-side as SET LOCAL statement_timeout. A non-positive d leaves ctx
+// unbounded.
+func WithQueryTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	ctx = context.WithValue(ctx, queryTimeoutKey{}, d)
+	return context.WithTimeout(ctx, d+queryTimeoutGrace)
+}
+
+// BeginQueryTx begins a transaction on conn. When ctx carries a timeout from
+// WithQueryTimeout, every statement in the transaction is limited to it.
+func BeginQueryTx(ctx context.Context, conn *sql.DB, opts *sql.TxOptions) (*sql.Tx, error) {
+	tx, err := conn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := ctx.Value(queryTimeoutKey{}).(time.Duration); ok {
+		// SET takes no bind parameters; the value is an integer we format.
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", d.Milliseconds())); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to set statement timeout: %w", err)
+		}
+	}
+	return tx, nil
+}
+
+// IsQueryTimeout reports whether err is a query timeout: ErrQueryTimeout, a
+// statement Postgres cancelled (SQLSTATE 57014), or an expired context.
+func IsQueryTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrQueryTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "57014"
+}
+
+// AsQueryTimeout wraps a query timeout so errors.Is(err, ErrQueryTimeout)
+// holds, keeping the original error in the chain. Other errors pass through.
+func AsQueryTimeout(err error) error {
+	if err == nil || errors.Is(err, ErrQueryTimeout) || !IsQueryTimeout(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrQueryTimeout, err)
+}
@@ -0,0 +1,80 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestLoadQueryTimeout(t *testing.T) {
+	for _, tt := range []struct {
+		env  string
+		want time.Duration
+	}{
+		{"", db.DefaultQueryTimeout},
+		{"5", 5 * time.Second},
+		{"0", db.DefaultQueryTimeout},
+		{"-3", db.DefaultQueryTimeout},
+		{"soon", db.DefaultQueryTimeout},
+	} {
+		t.Setenv("DB_QUERY_TIMEOUT_SECONDS", tt.env)
+		if got := db.LoadQueryTimeout(); got != tt.want {
+			t.Errorf("DB_QUERY_TIMEOUT_SECONDS=%q: got %v, want %v", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestAsQueryTimeout(t *testing.T) {
+	if err := db.AsQueryTimeout(nil); err != nil {
+		t.Errorf("nil = %v, want nil", err)
+	}
+	other := errors.New("boom")
+	if err := db.AsQueryTimeout(other); err != other {
+		t.Errorf("other error = %v, want it unchanged", err)
+	}
+	err := db.AsQueryTimeout(context.DeadlineExceeded)
+	if !errors.Is(err, db.ErrQueryTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("deadline = %v, want ErrQueryTimeout wrapping the deadline", err)
+	}
+}
+
+// TestBeginQueryTx_StatementTimeout runs pg_sleep past a 1s timeout and
+// expects Postgres (not the context backstop) to cancel it.
+func TestBeginQueryTx_StatementTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	env := testutil.SetupTestEnvironment(t)
+
+	ctx, cancel := db.WithQueryTimeout(context.Background(), time.Second)
+	defer cancel()
+	tx, err := db.BeginQueryTx(ctx, env.DB.Conn(), nil)
+	if err != nil {
+		t.Fatalf("BeginQueryTx: %v", err)
+	}
+	defer tx.Rollback()
+
+	var timeout string
+	if err := tx.QueryRowContext(ctx, `SHOW statement_timeout`).Scan(&timeout); err != nil {
+		t.Fatalf("show statement_timeout: %v", err)
+	}
+	if timeout != "1s" {
+		t.Errorf("statement_timeout = %q, want 1s", timeout)
+	}
+
+	started := time.Now()
+	_, err = tx.ExecContext(ctx, `SELECT pg_sleep(5)`)
+	if !errors.Is(db.AsQueryTimeout(err), db.ErrQueryTimeout) {
+		t.Fatalf("pg_sleep err = %v, want a query timeout", err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the server-side statement timeout", err)
+	}
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Errorf("pg_sleep ran %v, want it cancelled after ~1s", elapsed)
+	}
+}
@@ -0,0 +1,230 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+// DefaultReplicaMaxLag is how far a read replica may trail the primary before
+// ReadConn falls back to the primary (DB_REPLICA_MAX_LAG_MS).
+const DefaultReplicaMaxLag = 5 * time.Second
+
+// replicaCheckInterval bounds how often ReadConn re-measures replica lag;
+// reads in between reuse the last verdict.
+const replicaCheckInterval = time.Second
+
+// replicaCheckTimeout caps the lag query, so a hung replica costs a read at
+// most this much before it falls back to the primary.
+const replicaCheckTimeout = time.Second
+
+// replicaLagQuery reports whether the server is a standby and how many
+// milliseconds of replay it trails by. A standby that has replayed everything
+// it received reports 0, so an idle primary doesn't read as lag.
+const replicaLagQuery = `
+	SELECT pg_is_in_recovery(),
+		COALESCE(CASE
+			WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) * 1000
+		END, 0)
+`
+
+// replica is a read-only standby plus the cached verdict of its last lag check.
+type replica struct {
+	conn   *sql.DB
+	maxLag time.Duration
+	inSync bool // AttachReplicaForTest: skip the lag check, always usable
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	usable    bool
+	lagMs     float64
+	checkErr  error
+}
+
+// ReplicaStatus is the read replica's state as of its last lag check, for
+// GET /health/ready.
+type ReplicaStatus struct {
+	Configured bool       `json:"configured"`
+	Usable     bool       `json:"usable"` // ReadConn currently returns the replica
+	LagMs      float64    `json:"lag_ms"`
+	MaxLagMs   int64      `json:"max_lag_ms"`
+	CheckedAt  *time.Time `json:"checked_at,omitempty"`
+	Error      string     `json:"error,omitempty"` // why the last lag check failed
+}
+
+type primaryKey struct{}
+
+// WithPrimary returns a context under which ReadConn returns the primary, for
+// reads that must see the caller's own writes (e.g. returning a row the
+// handler just updated).
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// forcesPrimary reports whether ctx came from WithPrimary.
+func forcesPrimary(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryKey{}).(bool)
+	return forced
+}
+
+// ConnectReplica opens a connection pool to a read replica
+// (DATABASE_REPLICA_URL), sized like the primary's.
+func ConnectReplica(dsn string) (*sql.DB, error) {
+	conn, err := openPool(dsn, LoadSlowQueryThreshold())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replica: %w", err)
+	}
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to ping replica: %w", err)
+	}
+	conn.SetMaxOpenConns(500)
+	conn.SetMaxIdleConns(100)
+	conn.SetConnMaxLifetime(20 * time.Minute)
+	if d, ok := parseConnMaxIdleTime(); ok {
+		conn.SetConnMaxIdleTime(d)
+	}
+	return conn, nil
+}
+
+// LoadReplicaMaxLag reads DB_REPLICA_MAX_LAG_MS. Unset, non-numeric or
+// non-positive values give DefaultReplicaMaxLag.
+func LoadReplicaMaxLag() time.Duration {
+	ms, err := strconv.Atoi(os.Getenv("DB_REPLICA_MAX_LAG_MS"))
+	if err != nil || ms <= 0 {
+		return DefaultReplicaMaxLag
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// AttachReplica routes ReadConn to conn while it trails the primary by at
+// most maxLag. Call it once at startup, before serving requests; Close closes
+// the replica too.
+func (db *DB) AttachReplica(conn *sql.DB, maxLag time.Duration) {
+	db.replica = &replica{conn: conn, maxLag: maxLag}
+}
+
+// AttachReplicaForTest attaches conn as a replica that is always in sync, so
+// tests can point it at the primary's database (which is not a standby) and
+// still exercise replica routing.
+func (db *DB) AttachReplicaForTest(conn *sql.DB) {
+	db.replica = &replica{conn: conn, inSync: true}
+}
+
+// ReadConn returns the connection for read-only queries that can tolerate
+// data a few seconds old (analytics cards, trends, the session list and
+// detail, search): the read replica when one is attached, is in recovery, and
+// is within its lag bound; otherwise the primary. Writes, and reads that must
+// see the caller's own writes, use Conn or run under WithPrimary. The choice
+// is recorded on the current span as db.read_replica.
+func (db *DB) ReadConn(ctx context.Context) *sql.DB {
+	conn := db.conn
+	if db.replica != nil && !forcesPrimary(ctx) && db.replica.ready(ctx) {
+		conn = db.replica.conn
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("db.read_replica", conn != db.conn))
+	return conn
+}
+
+// IsPrimary reports whether conn is the primary's pool, e.g. to retry a miss
+// on the primary when ReadConn served it from the replica.
+func (db *DB) IsPrimary(conn *sql.DB) bool {
+	return conn == db.conn
+}
+
+// ReplicaStatus returns the replica's last lag check, re-measuring it when
+// older than replicaCheckInterval.
+func (db *DB) ReplicaStatus(ctx context.Context) ReplicaStatus {
+	if db.replica == nil {
+		return ReplicaStatus{}
+	}
+	r := db.replica
+	r.ready(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := ReplicaStatus{
+		Configured: true,
+		Usable:     r.inSync || r.usable,
+		LagMs:      r.lagMs,
+		MaxLagMs:   r.maxLag.Milliseconds(),
+	}
+	if !r.checkedAt.IsZero() {
+		checkedAt := r.checkedAt
+		status.CheckedAt = &checkedAt
+	}
+	if r.checkErr != nil {
+		status.Error = r.checkErr.Error()
+	}
+	return status
+}
+
+// MonitorReplica re-measures replica lag every replicaCheckInterval until ctx
+// ends, so reads find a fresh verdict instead of paying for the check. A
+// no-op without a replica.
+func (db *DB) MonitorReplica(ctx context.Context) {
+	if db.replica == nil {
+		return
+	}
+	ticker := time.NewTicker(replicaCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			db.replica.ready(ctx)
+		}
+	}
+}
+
+// ready reports whether the replica may serve reads, re-measuring its lag at
+// most once per replicaCheckInterval.
+func (r *replica) ready(ctx context.Context) bool {
+	if r.inSync {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checkedAt) < replicaCheckInterval {
+		return r.usable
+	}
+
+	checkCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), replicaCheckTimeout)
+	defer cancel()
+	var inRecovery bool
+	var lagMs float64
+	err := r.conn.QueryRowContext(checkCtx, replicaLagQuery).Scan(&inRecovery, &lagMs)
+	usable := err == nil && replicaUsable(inRecovery, lagMs, r.maxLag)
+
+	if usable != r.usable || r.checkedAt.IsZero() {
+		if usable {
+			logger.Info("read replica in use", "lag_ms", lagMs)
+		} else {
+			logger.Warn("read replica unavailable, reading from primary",
+				"error", err, "in_recovery", inRecovery, "lag_ms", lagMs, "max_lag_ms", r.maxLag.Milliseconds())
+		}
+	}
+	r.checkedAt = time.Now()
+	r.usable = usable
+	r.lagMs = lagMs
+	r.checkErr = err
+	return usable
+}
+
+// replicaUsable is the lag verdict: the server must still be a standby (a
+// promoted or misconfigured "replica" no longer follows the primary) and
+// trail it by no more than maxLag.
+func replicaUsable(inRecovery bool, lagMs float64, maxLag time.Duration) bool {
+	return inRecovery && lagMs <= float64(maxLag.Milliseconds())
+}
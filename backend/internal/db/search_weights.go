@@ -0,0 +1,51 @@
+package db
+
+import (
+	"os"
+	"strconv"
+)
+
+// SearchWeights are the ts_rank_cd weights applied to the session search
+// index's weighted components when ordering search results. Each must be in
+// [0, 1]; Postgres rejects larger weights.
+type SearchWeights struct {
+	A float64 // metadata: titles, summary, first user message
+	B float64 // smart recap
+	C float64 // user messages from the transcript
+}
+
+// DefaultSearchWeights are Postgres's own ts_rank_cd defaults for A/B/C.
+var DefaultSearchWeights = SearchWeights{A: 1.0, B: 0.4, C: 0.2}
+
+// searchWeightD is the weight for D-labeled lexemes: subagent output, indexed
+// only for users who opt in, and unlabeled rows written before weighting
+// existed.
+const searchWeightD = 0.1
+
+// loadSearchWeights reads SEARCH_WEIGHT_A, SEARCH_WEIGHT_B and SEARCH_WEIGHT_C.
+// Unset, unparseable or out-of-range values keep the default.
+func loadSearchWeights() SearchWeights {
+	w := DefaultSearchWeights
+	for _, f := range []struct {
+		key string
+		dst *float64
+	}{
+		{"SEARCH_WEIGHT_A", &w.A},
+		{"SEARCH_WEIGHT_B", &w.B},
+		{"SEARCH_WEIGHT_C", &w.C},
+	} {
+		if v, err := strconv.ParseFloat(os.Getenv(f.key), 64); err == nil && v >= 0 && v <= 1 {
+			*f.dst = v
+		}
+	}
+	return w
+}
+
+// RankArray returns the weights in the {D, C, B, A} order ts_rank_cd expects.
+// The zero value ranks with the defaults.
+func (w SearchWeights) RankArray() []float64 {
+	if w == (SearchWeights{}) {
+		w = DefaultSearchWeights
+	}
+	return []float64{searchWeightD, w.C, w.B, w.A}
+}
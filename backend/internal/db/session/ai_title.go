@@ -0,0 +1,104 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// TitleGenerationClaim is the outcome of ClaimTitleGeneration. When Claimed
+// is false, LastGeneratedAt is the stamp that blocked the claim; when true,
+// it is the stamp the claim replaced (nil for a first generation), which
+// ReleaseTitleGeneration restores if the generation fails.
+type TitleGenerationClaim struct {
+	Claimed         bool
+	ClaimedAt       time.Time
+	LastGeneratedAt *time.Time
+}
+
+// ClaimTitleGeneration stamps ai_title_generated_at for a session unless a
+// title was generated less than minInterval ago. The check and stamp are one
+// statement, so concurrent requests for the same session can't both claim.
+// Returns ErrSessionNotFound if the session doesn't exist.
+func (s *Store) ClaimTitleGeneration(ctx context.Context, sessionID string, minInterval time.Duration) (*TitleGenerationClaim, error) {
+	ctx, span := tracer.Start(ctx, "db.claim_title_generation",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	// ai_title_generated_at is a bare TIMESTAMP; stamp and compare in UTC, at
+	// the column's microsecond precision so ReleaseTitleGeneration can match
+	// the stamp exactly.
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	claim := &TitleGenerationClaim{ClaimedAt: now}
+
+	err := s.conn().QueryRowContext(ctx, `
+		UPDATE sessions s
+		SET ai_title_generated_at = $2
+		FROM (SELECT id, ai_title_generated_at FROM sessions WHERE id = $1 FOR UPDATE) prev
+		WHERE s.id = prev.id
+		  AND (prev.ai_title_generated_at IS NULL OR prev.ai_title_generated_at <= $3)
+		RETURNING prev.ai_title_generated_at`,
+		sessionID, now, now.Add(-minInterval)).Scan(&claim.LastGeneratedAt)
+	if err == nil {
+		claim.Claimed = true
+		return claim, nil
+	}
+	if err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to claim title generation: %w", err)
+	}
+
+	// Not claimed: either the session is missing or it is inside the window.
+	err = s.conn().QueryRowContext(ctx,
+		`SELECT ai_title_generated_at FROM sessions WHERE id = $1`, sessionID).Scan(&claim.LastGeneratedAt)
+	if err == sql.ErrNoRows {
+		return nil, db.ErrSessionNotFound
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to read title generation time: %w", err)
+	}
+	return claim, nil
+}
+
+// ReleaseTitleGeneration undoes a claim whose generation failed, restoring
+// the previous stamp so the failure doesn't count against the rate limit. It
+// is a no-op if another claim has replaced this one since.
+func (s *Store) ReleaseTitleGeneration(ctx context.Context, sessionID string, claim *TitleGenerationClaim) error {
+	ctx, span := tracer.Start(ctx, "db.release_title_generation",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	if _, err := s.conn().ExecContext(ctx,
+		`UPDATE sessions SET ai_title_generated_at = $3 WHERE id = $1 AND ai_title_generated_at = $2`,
+		sessionID, claim.ClaimedAt, claim.LastGeneratedAt); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to release title generation: %w", err)
+	}
+	return nil
+}
+
+// SetAIGeneratedTitle stores a title produced by POST /sessions/{id}/generate-title.
+func (s *Store) SetAIGeneratedTitle(ctx context.Context, sessionID, title string) error {
+	ctx, span := tracer.Start(ctx, "db.set_ai_generated_title",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	if _, err := s.conn().ExecContext(ctx,
+		`UPDATE sessions SET ai_generated_title = $2 WHERE id = $1`, sessionID, title); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to set AI generated title: %w", err)
+	}
+	return nil
+}
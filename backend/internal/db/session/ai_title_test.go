@@ -0,0 +1,76 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestClaimTitleGeneration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "title@test.com", "Title User")
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "title-me")
+
+	first, err := store.ClaimTitleGeneration(ctx, sessionID, time.Hour)
+	if err != nil {
+		t.Fatalf("ClaimTitleGeneration: %v", err)
+	}
+	if !first.Claimed || first.LastGeneratedAt != nil {
+		t.Fatalf("first claim = %+v, want claimed with no previous stamp", first)
+	}
+
+	// Inside the window the claim is refused and reports the blocking stamp.
+	second, err := store.ClaimTitleGeneration(ctx, sessionID, time.Hour)
+	if err != nil {
+		t.Fatalf("second ClaimTitleGeneration: %v", err)
+	}
+	if second.Claimed || second.LastGeneratedAt == nil || !second.LastGeneratedAt.Equal(first.ClaimedAt) {
+		t.Errorf("second claim = %+v, want refused by %v", second, first.ClaimedAt)
+	}
+
+	// A failed generation releases the slot.
+	if err := store.ReleaseTitleGeneration(ctx, sessionID, first); err != nil {
+		t.Fatalf("ReleaseTitleGeneration: %v", err)
+	}
+	third, err := store.ClaimTitleGeneration(ctx, sessionID, time.Hour)
+	if err != nil || !third.Claimed {
+		t.Fatalf("claim after release = (%+v, %v), want claimed", third, err)
+	}
+
+	if err := store.SetAIGeneratedTitle(ctx, sessionID, "Fix OAuth login redirect loop"); err != nil {
+		t.Fatalf("SetAIGeneratedTitle: %v", err)
+	}
+	detail, err := store.GetSessionDetail(ctx, sessionID, user.ID)
+	if err != nil {
+		t.Fatalf("GetSessionDetail: %v", err)
+	}
+	if detail.AIGeneratedTitle == nil || *detail.AIGeneratedTitle != "Fix OAuth login redirect loop" {
+		t.Errorf("AIGeneratedTitle = %v", detail.AIGeneratedTitle)
+	}
+
+	// An expired window can be claimed again.
+	if _, err := env.DB.Exec(ctx, `UPDATE sessions SET ai_title_generated_at = $2 WHERE id = $1`,
+		sessionID, time.Now().UTC().Add(-2*time.Hour)); err != nil {
+		t.Fatalf("age stamp: %v", err)
+	}
+	if again, err := store.ClaimTitleGeneration(ctx, sessionID, time.Hour); err != nil || !again.Claimed {
+		t.Errorf("claim after window = (%+v, %v), want claimed", again, err)
+	}
+
+	if _, err := store.ClaimTitleGeneration(ctx, "00000000-0000-0000-0000-000000000000", time.Hour); !errors.Is(err, db.ErrSessionNotFound) {
+		t.Errorf("missing session err = %v, want ErrSessionNotFound", err)
+	}
+}
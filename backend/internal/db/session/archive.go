@@ -0,0 +1,207 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+// TranscriptArchive identifies a session whose raw transcript chunks are
+// being removed from storage: the owner, provider and external ID locate its
+// S3 prefix.
+type TranscriptArchive struct {
+	SessionID  string
+	UserID     int64
+	Provider   string
+	ExternalID string
+}
+
+// archiveEligible is the WHERE clause a session s must pass to have its
+// transcript archived, with the cutoff as $1: not archived yet, last synced
+// (or first seen, if never synced) before the cutoff, and a search index that
+// already covers every transcript and agent line — the index is built only
+// once all regular cards are current — so nothing derived from the
+// transcript is lost with it.
+const archiveEligible = `
+	s.transcript_archived_at IS NULL
+	AND COALESCE(s.last_sync_at, s.first_seen) < $1
+	AND si.indexed_up_to_line >= (
+		SELECT COALESCE(SUM(sf.last_synced_line), 0)
+		FROM sync_files sf
+		WHERE sf.session_id = s.id AND sf.file_type IN ('transcript', 'agent')
+	)`
+
+// FindTranscriptArchiveCandidates returns the IDs of up to limit sessions
+// whose transcript may be archived (see ClaimTranscriptArchive) with
+// olderThan as the retention window, longest idle first. Nothing is stamped;
+// each candidate still has to be claimed.
+func (s *Store) FindTranscriptArchiveCandidates(ctx context.Context, olderThan time.Duration, limit int) ([]string, error) {
+	// last_sync_at and first_seen are bare TIMESTAMPs; compare against a UTC
+	// cutoff so the worker's local timezone can't skew the window.
+	cutoff := time.Now().UTC().Add(-olderThan)
+
+	ctx, span := tracer.Start(ctx, "db.find_transcript_archive_candidates",
+		trace.WithAttributes(
+			attribute.String("archive.cutoff", cutoff.String()),
+			attribute.Int("limit", limit),
+		))
+	defer span.End()
+
+	query := `
+		SELECT s.id
+		FROM sessions s
+		JOIN session_search_index si ON si.session_id = s.id
+		WHERE ` + archiveEligible + `
+		ORDER BY COALESCE(s.last_sync_at, s.first_seen)
+		LIMIT $2`
+
+	rows, err := s.conn().QueryContext(ctx, query, cutoff, limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to find transcript archive candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("failed to scan transcript archive candidate: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to find transcript archive candidates: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("archive.candidates", len(ids)))
+	return ids, nil
+}
+
+// ClaimTranscriptArchive stamps transcript_archived_at on a session that is
+// still eligible for archiving with olderThan as the retention window, while
+// holding the advisory lock TryLockSyncFile takes for each of its sync files.
+// The locks are session-level, on a connection of their own, and stay held
+// until release is called, so the caller deletes the chunks under them too:
+// a sync/chunk upload in flight when the claim is attempted makes it fail
+// (claimed is false), and one that starts later waits out the locks and then
+// finds the session archived. A chunk for a file the session never synced
+// takes no lock here; UpdateSyncFileState refuses it with
+// ErrTranscriptArchived instead. release is nil when claimed is false.
+func (s *Store) ClaimTranscriptArchive(ctx context.Context, sessionID string, olderThan time.Duration) (claim TranscriptArchive, release func(), claimed bool, err error) {
+	cutoff := time.Now().UTC().Add(-olderThan)
+
+	ctx, span := tracer.Start(ctx, "db.claim_transcript_archive",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.String("archive.cutoff", cutoff.String()),
+		))
+	defer span.End()
+
+	conn, err := s.conn().Conn(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return TranscriptArchive{}, nil, false, fmt.Errorf("failed to get transcript archive connection: %w", err)
+	}
+	// Session-level advisory locks outlive transactions, so they are dropped
+	// before the connection returns to the pool; if that fails the
+	// connection is discarded, which drops them with it.
+	release = func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock_all()`); err != nil {
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		_ = conn.Close()
+	}
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, `
+		SELECT COALESCE(bool_and(pg_try_advisory_lock(hashtext(session_id::text || file_name))), true)
+		FROM sync_files
+		WHERE session_id = $1`, sessionID).Scan(&locked)
+	if err != nil {
+		release()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return TranscriptArchive{}, nil, false, fmt.Errorf("failed to lock transcript archive sync files: %w", err)
+	}
+	if !locked {
+		release()
+		span.SetAttributes(attribute.Bool("archive.claimed", false))
+		return TranscriptArchive{}, nil, false, nil
+	}
+
+	// Eligibility is checked again under the locks: a chunk may have been
+	// synced since the session was found.
+	query := `
+		UPDATE sessions s
+		SET transcript_archived_at = NOW()
+		FROM session_search_index si
+		WHERE s.id = $2 AND si.session_id = s.id AND ` + archiveEligible + `
+		RETURNING s.id, s.user_id, s.session_type, s.external_id`
+	err = conn.QueryRowContext(ctx, query, cutoff, sessionID).Scan(&claim.SessionID, &claim.UserID, &claim.Provider, &claim.ExternalID)
+	if err == sql.ErrNoRows {
+		release()
+		span.SetAttributes(attribute.Bool("archive.claimed", false))
+		return TranscriptArchive{}, nil, false, nil
+	}
+	if err != nil {
+		release()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return TranscriptArchive{}, nil, false, fmt.Errorf("failed to claim transcript archive: %w", err)
+	}
+	claim.Provider = models.NormalizeProvider(claim.Provider)
+
+	span.SetAttributes(attribute.Bool("archive.claimed", true))
+	return claim, release, true, nil
+}
+
+// ReleaseTranscriptArchive clears transcript_archived_at for a session whose
+// chunks could not be deleted, so a later cycle claims it again. Call it
+// before releasing the claim's locks.
+func (s *Store) ReleaseTranscriptArchive(ctx context.Context, sessionID string) error {
+	ctx, span := tracer.Start(ctx, "db.release_transcript_archive",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	if _, err := s.conn().ExecContext(ctx,
+		`UPDATE sessions SET transcript_archived_at = NULL WHERE id = $1`, sessionID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to release transcript archive: %w", err)
+	}
+	return nil
+}
+
+// IsTranscriptArchived reports whether a session's transcript chunks have been
+// archived (deleted from storage). A missing session reports false.
+func (s *Store) IsTranscriptArchived(ctx context.Context, sessionID string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "db.is_transcript_archived",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	var archived bool
+	err := s.conn().QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM sessions WHERE id = $1 AND transcript_archived_at IS NOT NULL)`,
+		sessionID).Scan(&archived)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return false, fmt.Errorf("failed to check transcript archive: %w", err)
+	}
+	return archived, nil
+}
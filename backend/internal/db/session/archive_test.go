@@ -0,0 +1,219 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// seedArchiveSession creates a session with a 10-line transcript, last synced
+// idle ago, and a search index covering indexedLines (none when negative).
+func seedArchiveSession(t *testing.T, env *testutil.TestEnvironment, userID int64, externalID string, idle time.Duration, indexedLines int64) string {
+	t.Helper()
+	sessionID := testutil.CreateTestSession(t, env, userID, externalID)
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 10)
+	lastSync := time.Now().UTC().Add(-idle)
+	if _, err := env.DB.Exec(env.Ctx,
+		`UPDATE sessions SET first_seen = $2, last_sync_at = $2 WHERE id = $1`, sessionID, lastSync); err != nil {
+		t.Fatalf("set last_sync_at: %v", err)
+	}
+	if indexedLines >= 0 {
+		testutil.CreateTestSearchIndex(t, env, sessionID, "archived words", indexedLines)
+	}
+	return sessionID
+}
+
+func TestClaimTranscriptArchive(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "archive@test.com", "Archive User")
+	const day = 24 * time.Hour
+	eligible := seedArchiveSession(t, env, user.ID, "old-indexed", 100*day, 10)
+	seedArchiveSession(t, env, user.ID, "old-unindexed", 100*day, -1)
+	seedArchiveSession(t, env, user.ID, "old-partially-indexed", 100*day, 5)
+	seedArchiveSession(t, env, user.ID, "recent", day, 10)
+
+	candidates, err := store.FindTranscriptArchiveCandidates(ctx, 30*day, 10)
+	if err != nil {
+		t.Fatalf("FindTranscriptArchiveCandidates: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0] != eligible {
+		t.Fatalf("candidates = %v, want only the idle, fully indexed session", candidates)
+	}
+
+	got, release, claimed, err := store.ClaimTranscriptArchive(ctx, eligible, 30*day)
+	if err != nil || !claimed {
+		t.Fatalf("ClaimTranscriptArchive = (%v, %v), want (true, nil)", claimed, err)
+	}
+	release()
+	if got.SessionID != eligible || got.UserID != user.ID || got.ExternalID != "old-indexed" || got.Provider != models.ProviderClaudeCode {
+		t.Errorf("claimed = %+v", got)
+	}
+
+	archived, err := store.IsTranscriptArchived(ctx, eligible)
+	if err != nil || !archived {
+		t.Fatalf("IsTranscriptArchived = (%v, %v), want (true, nil)", archived, err)
+	}
+	detail, err := store.GetSessionDetail(ctx, eligible, user.ID)
+	if err != nil {
+		t.Fatalf("GetSessionDetail: %v", err)
+	}
+	if detail.TranscriptArchivedAt == nil {
+		t.Error("SessionDetail.TranscriptArchivedAt should be set after the claim")
+	}
+
+	// An archived session is never found or claimed twice.
+	if again, err := store.FindTranscriptArchiveCandidates(ctx, 30*day, 10); err != nil || len(again) != 0 {
+		t.Errorf("candidates after claim = (%v, %v), want nothing", again, err)
+	}
+	if _, _, claimed, err := store.ClaimTranscriptArchive(ctx, eligible, 30*day); err != nil || claimed {
+		t.Errorf("second claim = (%v, %v), want (false, nil)", claimed, err)
+	}
+
+	// Releasing (after a failed chunk delete) makes it claimable again.
+	if err := store.ReleaseTranscriptArchive(ctx, eligible); err != nil {
+		t.Fatalf("ReleaseTranscriptArchive: %v", err)
+	}
+	if archived, _ := store.IsTranscriptArchived(ctx, eligible); archived {
+		t.Error("released session should not be archived")
+	}
+	if again, err := store.FindTranscriptArchiveCandidates(ctx, 30*day, 10); err != nil || len(again) != 1 {
+		t.Errorf("candidates after release = (%v, %v), want the released session", again, err)
+	}
+}
+
+func TestFindTranscriptArchiveCandidates_RespectsLimit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "limit@test.com", "Limit User")
+	const day = 24 * time.Hour
+	oldest := seedArchiveSession(t, env, user.ID, "oldest", 300*day, 10)
+	seedArchiveSession(t, env, user.ID, "older", 200*day, 10)
+	seedArchiveSession(t, env, user.ID, "old", 100*day, 10)
+
+	candidates, err := store.FindTranscriptArchiveCandidates(ctx, 30*day, 1)
+	if err != nil {
+		t.Fatalf("FindTranscriptArchiveCandidates: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0] != oldest {
+		t.Errorf("candidates = %v, want just the longest-idle session", candidates)
+	}
+}
+
+// The claim and a sync/chunk upload of the same file exclude each other
+// through the file's advisory lock.
+func TestClaimTranscriptArchive_SyncFileLock(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "archive-lock@test.com", "Archive Lock User")
+	const day = 24 * time.Hour
+	sessionID := seedArchiveSession(t, env, user.ID, "old-indexed", 100*day, 10)
+
+	// An upload in flight makes the claim back off without archiving.
+	releaseUpload, locked, err := store.TryLockSyncFile(ctx, sessionID, "transcript.jsonl")
+	if err != nil || !locked {
+		t.Fatalf("TryLockSyncFile = (%v, %v), want (true, nil)", locked, err)
+	}
+	if _, _, claimed, err := store.ClaimTranscriptArchive(ctx, sessionID, 30*day); err != nil || claimed {
+		t.Errorf("claim during upload = (%v, %v), want (false, nil)", claimed, err)
+	}
+	if archived, _ := store.IsTranscriptArchived(ctx, sessionID); archived {
+		t.Error("session should not be archived while an upload holds the lock")
+	}
+	releaseUpload()
+
+	// A held claim refuses uploads until it is released.
+	_, releaseClaim, claimed, err := store.ClaimTranscriptArchive(ctx, sessionID, 30*day)
+	if err != nil || !claimed {
+		t.Fatalf("ClaimTranscriptArchive = (%v, %v), want (true, nil)", claimed, err)
+	}
+	if _, locked, err := store.TryLockSyncFile(ctx, sessionID, "transcript.jsonl"); err != nil || locked {
+		t.Errorf("TryLockSyncFile during claim = (%v, %v), want (false, nil)", locked, err)
+	}
+	releaseClaim()
+	releaseUpload, locked, err = store.TryLockSyncFile(ctx, sessionID, "transcript.jsonl")
+	if err != nil || !locked {
+		t.Fatalf("TryLockSyncFile after claim = (%v, %v), want (true, nil)", locked, err)
+	}
+	releaseUpload()
+}
+
+// A session synced after it was found is not claimed.
+func TestClaimTranscriptArchive_RechecksEligibility(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "archive-recheck@test.com", "Archive Recheck User")
+	const day = 24 * time.Hour
+	sessionID := seedArchiveSession(t, env, user.ID, "old-indexed", 100*day, 10)
+
+	if err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 10, 0, nil, nil, nil, nil, false, nil); err != nil {
+		t.Fatalf("UpdateSyncFileState: %v", err)
+	}
+	if _, _, claimed, err := store.ClaimTranscriptArchive(ctx, sessionID, 30*day); err != nil || claimed {
+		t.Errorf("claim after a fresh sync = (%v, %v), want (false, nil)", claimed, err)
+	}
+}
+
+// A chunk whose upload raced the archive (e.g. the session's first chunk of a
+// new file) cannot record its sync state.
+func TestUpdateSyncFileState_RefusesArchivedTranscript(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "archive-update@test.com", "Archive Update User")
+	const day = 24 * time.Hour
+	sessionID := seedArchiveSession(t, env, user.ID, "old-indexed", 100*day, 10)
+	_, release, claimed, err := store.ClaimTranscriptArchive(ctx, sessionID, 30*day)
+	if err != nil || !claimed {
+		t.Fatalf("ClaimTranscriptArchive = (%v, %v), want (true, nil)", claimed, err)
+	}
+	release()
+
+	err = store.UpdateSyncFileState(ctx, sessionID, "agent-new.jsonl", "agent", 0, 5, 0, nil, nil, nil, nil, false, nil)
+	if !errors.Is(err, db.ErrTranscriptArchived) {
+		t.Fatalf("UpdateSyncFileState = %v, want ErrTranscriptArchived", err)
+	}
+	if _, err := store.GetSyncFileState(ctx, sessionID, "agent-new.jsonl"); !errors.Is(err, db.ErrFileNotFound) {
+		t.Errorf("GetSyncFileState = %v, want ErrFileNotFound (nothing recorded)", err)
+	}
+}
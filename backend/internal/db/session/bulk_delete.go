@@ -0,0 +1,188 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+// buildBulkDeleteWhere returns the WHERE clause (without the keyword) that
+// selects the user's sessions matching filter. $1 is always the user ID.
+// An empty filter yields "FALSE" so a caller that forgot to validate can
+// never select every session.
+func buildBulkDeleteWhere(pb *paramBuilder, filter db.BulkDeleteFilter) string {
+	if filter.IsEmpty() {
+		return "FALSE"
+	}
+	where := "s.user_id = $1"
+	if len(filter.SessionIDs) > 0 {
+		// Compare as text so a malformed ID matches nothing instead of
+		// failing the whole statement with an invalid-UUID error.
+		where += " AND s.id::text = ANY(" + pb.addArray(filter.SessionIDs) + ")"
+	}
+	if filter.OlderThan != nil {
+		where += " AND COALESCE(s.last_message_at, s.first_seen) < " + pb.add(*filter.OlderThan)
+	}
+	if filter.DemoOnly {
+		where += " AND s.is_demo"
+	}
+	return where
+}
+
+// CountBulkDeleteTargets returns how many of the user's sessions match filter.
+// Used for bulk-delete dry runs.
+func (s *Store) CountBulkDeleteTargets(ctx context.Context, userID int64, filter db.BulkDeleteFilter) (int, error) {
+	ctx, span := tracer.Start(ctx, "db.count_bulk_delete_targets",
+		trace.WithAttributes(attribute.Int64("user.id", userID)))
+	defer span.End()
+
+	pb := newParamBuilder(userID)
+	query := `SELECT COUNT(*) FROM sessions s WHERE ` + buildBulkDeleteWhere(pb, filter)
+
+	var count int
+	if err := s.conn().QueryRowContext(ctx, query, pb.args...).Scan(&count); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, fmt.Errorf("failed to count bulk delete targets: %w", err)
+	}
+	span.SetAttributes(attribute.Int("bulk_delete.count", count))
+	return count, nil
+}
+
+// ListBulkDeleteTargets returns up to limit of the user's sessions matching
+// filter, ordered by id and starting strictly after afterID (empty = from the
+// start). Callers page through matches with the last returned ID so each
+// batch is a short, independent query. Provider is normalized via
+// models.NormalizeProvider so it can be passed straight to chunk storage.
+func (s *Store) ListBulkDeleteTargets(ctx context.Context, userID int64, filter db.BulkDeleteFilter, afterID string, limit int) ([]db.BulkDeleteTarget, error) {
+	ctx, span := tracer.Start(ctx, "db.list_bulk_delete_targets",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.Int("bulk_delete.limit", limit),
+		))
+	defer span.End()
+
+	pb := newParamBuilder(userID)
+	query := `SELECT s.id, s.external_id, s.session_type FROM sessions s WHERE ` + buildBulkDeleteWhere(pb, filter)
+	if afterID != "" {
+		query += ` AND s.id::text > ` + pb.add(afterID)
+	}
+	query += ` ORDER BY s.id::text LIMIT ` + pb.add(limit)
+
+	rows, err := s.conn().QueryContext(ctx, query, pb.args...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to list bulk delete targets: %w", err)
+	}
+	defer rows.Close()
+
+	var targets []db.BulkDeleteTarget
+	for rows.Next() {
+		var t db.BulkDeleteTarget
+		if err := rows.Scan(&t.ID, &t.ExternalID, &t.Provider); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("failed to scan bulk delete target: %w", err)
+		}
+		t.Provider = models.NormalizeProvider(t.Provider)
+		targets = append(targets, t)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to iterate bulk delete targets: %w", err)
+	}
+	return targets, nil
+}
+
+// DeleteSessionsFromDB deletes the given sessions owned by userID in one
+// transaction (CASCADE removes sync_files, shares, cards, etc.), logging each
+// as a transition to deleted. IDs the user doesn't own are ignored. Returns
+// the number of sessions deleted.
+func (s *Store) DeleteSessionsFromDB(ctx context.Context, userID int64, sessionIDs []string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "db.delete_sessions",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.Int("bulk_delete.batch_size", len(sessionIDs)),
+		))
+	defer span.End()
+
+	if len(sessionIDs) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	owned, err := ownedSessionIDs(ctx, tx, userID, sessionIDs)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	if len(owned) == 0 {
+		return 0, nil
+	}
+
+	// Log the deletions before the rows (and their states) are gone.
+	if _, err := transitionStates(ctx, tx, owned, StateDeleted, StateReasonBulkDelete); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, fmt.Errorf("failed to update session states: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM sessions WHERE user_id = $1 AND id = ANY($2::uuid[])`,
+		userID, pq.Array(owned))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	s.DB.SessionOwners.Invalidate(owned...)
+
+	deleted, _ := result.RowsAffected()
+	return deleted, nil
+}
+
+// ownedSessionIDs returns which of sessionIDs userID owns. IDs are compared as
+// text so a malformed one matches nothing instead of failing the statement.
+func ownedSessionIDs(ctx context.Context, tx *sql.Tx, userID int64, sessionIDs []string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id FROM sessions WHERE user_id = $1 AND id::text = ANY($2)`,
+		userID, pq.Array(sessionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var owned []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		owned = append(owned, id)
+	}
+	return owned, rows.Err()
+}
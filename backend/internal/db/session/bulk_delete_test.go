@@ -0,0 +1,117 @@
+package session_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// =============================================================================
+// Bulk delete filter matching
+// =============================================================================
+
+func TestBulkDeleteTargets_FilterMatching(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "bulk@test.com", "Bulk User")
+	other := testutil.CreateTestUser(t, env, "other@test.com", "Other User")
+
+	oldA := testutil.CreateTestSession(t, env, user.ID, "old-a")
+	oldB := testutil.CreateTestSession(t, env, user.ID, "old-b")
+	recent := testutil.CreateTestSession(t, env, user.ID, "recent")
+	otherOld := testutil.CreateTestSession(t, env, other.ID, "other-old")
+
+	setActivity := func(sessionID string, at time.Time) {
+		t.Helper()
+		if _, err := env.DB.Exec(env.Ctx,
+			`UPDATE sessions SET first_seen = $2, last_message_at = $2 WHERE id = $1`, sessionID, at); err != nil {
+			t.Fatalf("failed to set activity: %v", err)
+		}
+	}
+	now := time.Now().UTC()
+	setActivity(oldA, now.AddDate(0, 0, -60))
+	setActivity(oldB, now.AddDate(0, 0, -45))
+	setActivity(recent, now.AddDate(0, 0, -1))
+	setActivity(otherOld, now.AddDate(0, 0, -90))
+
+	cutoff := now.AddDate(0, 0, -30)
+
+	listIDs := func(filter db.BulkDeleteFilter) []string {
+		t.Helper()
+		targets, err := store.ListBulkDeleteTargets(ctx, user.ID, filter, "", 100)
+		if err != nil {
+			t.Fatalf("ListBulkDeleteTargets failed: %v", err)
+		}
+		ids := make([]string, len(targets))
+		for i, tgt := range targets {
+			ids[i] = tgt.ID
+		}
+		sort.Strings(ids)
+		return ids
+	}
+	sorted := func(ids ...string) []string {
+		sort.Strings(ids)
+		return ids
+	}
+	assertIDs := func(name string, got, want []string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s: got %v, want %v", name, got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("%s: got %v, want %v", name, got, want)
+			}
+		}
+	}
+
+	assertIDs("older_than", listIDs(db.BulkDeleteFilter{OlderThan: &cutoff}), sorted(oldA, oldB))
+	assertIDs("explicit ids skip other users", listIDs(db.BulkDeleteFilter{SessionIDs: []string{recent, otherOld, "not-a-uuid"}}), sorted(recent))
+	assertIDs("ids AND older_than", listIDs(db.BulkDeleteFilter{SessionIDs: []string{oldA, recent}, OlderThan: &cutoff}), sorted(oldA))
+	assertIDs("empty filter", listIDs(db.BulkDeleteFilter{}), nil)
+
+	count, err := store.CountBulkDeleteTargets(ctx, user.ID, db.BulkDeleteFilter{OlderThan: &cutoff})
+	if err != nil {
+		t.Fatalf("CountBulkDeleteTargets failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+
+	// Keyset paging: one-at-a-time batches still visit every match exactly once
+	first, err := store.ListBulkDeleteTargets(ctx, user.ID, db.BulkDeleteFilter{OlderThan: &cutoff}, "", 1)
+	if err != nil || len(first) != 1 {
+		t.Fatalf("first page: %v (%d rows)", err, len(first))
+	}
+	second, err := store.ListBulkDeleteTargets(ctx, user.ID, db.BulkDeleteFilter{OlderThan: &cutoff}, first[0].ID, 1)
+	if err != nil || len(second) != 1 || second[0].ID == first[0].ID {
+		t.Fatalf("second page: %v (%v)", err, second)
+	}
+
+	deleted, err := store.DeleteSessionsFromDB(ctx, user.ID, []string{oldA, otherOld})
+	if err != nil {
+		t.Fatalf("DeleteSessionsFromDB failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted = %d, want 1 (other user's session must be untouched)", deleted)
+	}
+	var remaining int
+	if err := env.DB.QueryRow(env.Ctx, `SELECT COUNT(*) FROM sessions WHERE id = $1`, otherOld).Scan(&remaining); err != nil {
+		t.Fatalf("failed to query sessions: %v", err)
+	}
+	if remaining != 1 {
+		t.Error("expected other user's session to survive")
+	}
+}
@@ -0,0 +1,44 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+func TestBuildBulkDeleteWhere(t *testing.T) {
+	cutoff := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("empty filter matches nothing", func(t *testing.T) {
+		pb := newParamBuilder(1)
+		if got := buildBulkDeleteWhere(pb, db.BulkDeleteFilter{}); got != "FALSE" {
+			t.Errorf("where = %q, want FALSE", got)
+		}
+		if len(pb.args) != 1 {
+			t.Errorf("args = %d, want only the user ID", len(pb.args))
+		}
+	})
+
+	t.Run("ids and older_than are ANDed and owner-scoped", func(t *testing.T) {
+		pb := newParamBuilder(1)
+		got := buildBulkDeleteWhere(pb, db.BulkDeleteFilter{SessionIDs: []string{"a"}, OlderThan: &cutoff})
+		for _, want := range []string{"s.user_id = $1", "s.id::text = ANY($2)", "COALESCE(s.last_message_at, s.first_seen) < $3"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("where = %q, missing %q", got, want)
+			}
+		}
+		if len(pb.args) != 3 {
+			t.Errorf("args = %d, want 3", len(pb.args))
+		}
+	})
+
+	t.Run("demo_only alone is a filter", func(t *testing.T) {
+		pb := newParamBuilder(1)
+		got := buildBulkDeleteWhere(pb, db.BulkDeleteFilter{DemoOnly: true})
+		if got != "s.user_id = $1 AND s.is_demo" {
+			t.Errorf("where = %q, want owner-scoped is_demo", got)
+		}
+	})
+}
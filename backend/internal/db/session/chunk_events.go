@@ -0,0 +1,303 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// How a chunk upload event was settled, stored in chunk_upload_events.resolution.
+const (
+	ChunkUploadSynced     = "synced"     // the handler recorded it in sync_files
+	ChunkUploadReplayed   = "replayed"   // the worker applied the missed sync_files update
+	ChunkUploadSuperseded = "superseded" // a later upload already covers the range
+	ChunkUploadDiscarded  = "discarded"  // the worker deleted the orphaned object
+	ChunkUploadMissing    = "missing"    // the object never landed; nothing to do
+)
+
+// ChunkUpload describes a sync chunk about to be uploaded to S3.
+type ChunkUpload struct {
+	SessionID     string
+	FileName      string
+	FileType      string
+	Generation    int // sync_files.generation the chunk was read against
+	FirstLine     int
+	LastLine      int
+	S3Key         string
+	ChunkBytes    int64
+	LastMessageAt *time.Time // latest per-line timestamp in the chunk, if any
+}
+
+// ChunkObjects is the object-store surface ReconcileChunkUpload needs.
+// *storage.S3Storage satisfies it.
+type ChunkObjects interface {
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// RecordChunkUpload inserts a pending event for a chunk before it is uploaded
+// and returns its id. A pending event for the same object (an earlier attempt
+// whose sync-state update failed) is marked superseded: this upload rewrites
+// the object and its own event takes over. That update waits on a worker
+// holding the old event, so the worker can never delete the object after
+// this upload has rewritten it.
+func (s *Store) RecordChunkUpload(ctx context.Context, u ChunkUpload) (int64, error) {
+	ctx, span := tracer.Start(ctx, "db.record_chunk_upload",
+		trace.WithAttributes(
+			attribute.String("session.id", u.SessionID),
+			attribute.String("file.name", u.FileName),
+			attribute.Int("chunk.first_line", u.FirstLine),
+			attribute.Int("chunk.last_line", u.LastLine),
+		))
+	defer span.End()
+
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE chunk_upload_events SET confirmed_at = NOW(), resolution = $4
+		WHERE session_id = $1 AND file_name = $2 AND s3_key = $3 AND confirmed_at IS NULL`,
+		u.SessionID, u.FileName, u.S3Key, ChunkUploadSuperseded); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, fmt.Errorf("failed to supersede pending chunk upload: %w", err)
+	}
+
+	var id int64
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO chunk_upload_events
+			(session_id, file_name, file_type, first_line, last_line, s3_key, chunk_bytes, last_message_at, generation)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`,
+		u.SessionID, u.FileName, u.FileType, u.FirstLine, u.LastLine, u.S3Key, u.ChunkBytes, u.LastMessageAt, u.Generation,
+	).Scan(&id); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, fmt.Errorf("failed to record chunk upload: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return id, nil
+}
+
+// ConfirmChunkUpload marks an event synced once UpdateSyncFileState has
+// recorded its chunk. An event already settled is left alone.
+func (s *Store) ConfirmChunkUpload(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "db.confirm_chunk_upload",
+		trace.WithAttributes(attribute.Int64("chunk_upload.id", id)))
+	defer span.End()
+
+	if _, err := s.conn().ExecContext(ctx, `
+		UPDATE chunk_upload_events SET confirmed_at = NOW(), resolution = $2
+		WHERE id = $1 AND confirmed_at IS NULL`, id, ChunkUploadSynced); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to confirm chunk upload: %w", err)
+	}
+	return nil
+}
+
+// DiscardChunkUpload marks an event discarded once the handler has deleted
+// its object itself (the file was reset during the upload). An event already
+// settled is left alone.
+func (s *Store) DiscardChunkUpload(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "db.discard_chunk_upload",
+		trace.WithAttributes(attribute.Int64("chunk_upload.id", id)))
+	defer span.End()
+
+	if _, err := s.conn().ExecContext(ctx, `
+		UPDATE chunk_upload_events SET confirmed_at = NOW(), resolution = $2
+		WHERE id = $1 AND confirmed_at IS NULL`, id, ChunkUploadDiscarded); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to discard chunk upload: %w", err)
+	}
+	return nil
+}
+
+// ListStaleChunkUploads returns the ids of up to limit events still pending
+// olderThan after their upload started, oldest first.
+func (s *Store) ListStaleChunkUploads(ctx context.Context, olderThan time.Duration, limit int) ([]int64, error) {
+	ctx, span := tracer.Start(ctx, "db.list_stale_chunk_uploads",
+		trace.WithAttributes(attribute.Int("limit", limit)))
+	defer span.End()
+
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT id FROM chunk_upload_events
+		WHERE confirmed_at IS NULL AND uploaded_at < NOW() - make_interval(secs => $1)
+		ORDER BY uploaded_at
+		LIMIT $2`, olderThan.Seconds(), limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to list stale chunk uploads: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("failed to scan chunk upload: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to list stale chunk uploads: %w", err)
+	}
+	span.SetAttributes(attribute.Int("chunk_uploads.count", len(ids)))
+	return ids, nil
+}
+
+// ReconcileChunkUpload settles a pending event and returns its resolution,
+// or "" when another worker settled it first. With the event and its
+// sync_files row locked, it checks whether the object exists and compares
+// the chunk to the file's last synced line:
+//   - object gone: missing (the upload failed before landing)
+//   - the file ends just before the chunk in the same generation: replayed,
+//     by applying the sync_files update the handler never committed
+//   - the file already reaches the chunk's last line: superseded (a retry
+//     covered it, or after a reset the new generation rewrote the object;
+//     overlapping chunks are harmless to reads)
+//   - anything else: discarded, by deleting the object, which would
+//     otherwise run past the synced line or leave a gap
+//
+// The locks are held across the object-store calls so a concurrent retry of
+// the same chunk (see RecordChunkUpload) cannot interleave with a delete.
+// The replay restores line counts, stored bytes and last_message_at; chunk
+// metadata such as summaries and git info is left to the next upload.
+func (s *Store) ReconcileChunkUpload(ctx context.Context, id int64, objects ChunkObjects) (string, error) {
+	ctx, span := tracer.Start(ctx, "db.reconcile_chunk_upload",
+		trace.WithAttributes(attribute.Int64("chunk_upload.id", id)))
+	defer span.End()
+
+	fail := func(err error) (string, error) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return "", err
+	}
+
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		return fail(fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer tx.Rollback()
+
+	var u ChunkUpload
+	var uploadedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT session_id, file_name, file_type, generation, first_line, last_line, s3_key, chunk_bytes, last_message_at, uploaded_at
+		FROM chunk_upload_events
+		WHERE id = $1 AND confirmed_at IS NULL
+		FOR UPDATE`, id,
+	).Scan(&u.SessionID, &u.FileName, &u.FileType, &u.Generation, &u.FirstLine, &u.LastLine, &u.S3Key, &u.ChunkBytes, &u.LastMessageAt, &uploadedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return fail(fmt.Errorf("failed to load chunk upload: %w", err))
+	}
+
+	lastSynced, generation := 0, 0
+	err = tx.QueryRowContext(ctx, `
+		SELECT last_synced_line, generation FROM sync_files
+		WHERE session_id = $1 AND file_name = $2
+		FOR UPDATE`, u.SessionID, u.FileName,
+	).Scan(&lastSynced, &generation)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fail(fmt.Errorf("failed to load sync file state: %w", err))
+	}
+
+	exists, err := objects.Exists(ctx, u.S3Key)
+	if err != nil {
+		return fail(fmt.Errorf("failed to check chunk object: %w", err))
+	}
+
+	var resolution string
+	switch {
+	case !exists:
+		resolution = ChunkUploadMissing
+	case generation == u.Generation && lastSynced == u.FirstLine-1:
+		if err := replayChunkUpload(ctx, tx, u, uploadedAt); err != nil {
+			return fail(err)
+		}
+		resolution = ChunkUploadReplayed
+	case lastSynced >= u.LastLine:
+		resolution = ChunkUploadSuperseded
+	default:
+		if err := objects.Delete(ctx, u.S3Key); err != nil {
+			return fail(fmt.Errorf("failed to delete orphaned chunk: %w", err))
+		}
+		resolution = ChunkUploadDiscarded
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE chunk_upload_events SET confirmed_at = NOW(), resolution = $2 WHERE id = $1`,
+		id, resolution); err != nil {
+		return fail(fmt.Errorf("failed to settle chunk upload: %w", err))
+	}
+	if err := tx.Commit(); err != nil {
+		return fail(fmt.Errorf("failed to commit: %w", err))
+	}
+	span.SetAttributes(attribute.String("chunk_upload.resolution", resolution))
+	return resolution, nil
+}
+
+// replayChunkUpload applies the sync_files and sessions updates of
+// UpdateSyncFileState for a chunk whose upload landed but whose update did
+// not. The upsert only applies while the file still ends just before the
+// chunk; a concurrent first upload of the file makes it fail, and the event
+// is retried on a later cycle. The growth point is dated uploadedAt.
+func replayChunkUpload(ctx context.Context, tx *sql.Tx, u ChunkUpload, uploadedAt time.Time) error {
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO sync_files (session_id, file_name, file_type, last_synced_line, chunk_count, stored_bytes, growth, updated_at)
+		VALUES ($1, $2, $3, $4, 1, $5, jsonb_build_array(jsonb_build_object('at', $7::timestamptz, 'line', $4::int)), NOW())
+		ON CONFLICT (session_id, file_name) DO UPDATE SET
+			last_synced_line = $4,
+			chunk_count = COALESCE(sync_files.chunk_count, 0) + 1,
+			stored_bytes = sync_files.stored_bytes + $5,
+			growth = sync_file_growth_append(sync_files.growth, jsonb_build_object('at', $7::timestamptz, 'line', $4::int), $8),
+			updated_at = NOW()
+		WHERE sync_files.last_synced_line = $6`,
+		u.SessionID, u.FileName, u.FileType, u.LastLine, u.ChunkBytes, u.FirstLine-1, uploadedAt, db.GrowthHistoryCap)
+	if err != nil {
+		return fmt.Errorf("failed to replay sync file state: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("sync file state of %s moved during replay", u.FileName)
+	}
+
+	// last_sync_at moves to the original upload time, not now, so a replay
+	// does not make a quiet session look freshly synced.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE sessions SET
+			last_sync_at = GREATEST(last_sync_at, $2),
+			last_message_at = CASE WHEN $3::timestamptz IS NOT NULL AND (last_message_at IS NULL OR last_message_at < $3)
+				THEN $3 ELSE last_message_at END
+		WHERE id = $1`,
+		u.SessionID, uploadedAt, u.LastMessageAt); err != nil {
+		return fmt.Errorf("failed to replay session metadata: %w", err)
+	}
+	return nil
+}
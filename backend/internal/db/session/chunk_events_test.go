@@ -0,0 +1,235 @@
+package session_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// fakeChunkObjects is an in-memory ChunkObjects.
+type fakeChunkObjects struct {
+	keys    map[string]bool
+	deleted []string
+}
+
+func (f *fakeChunkObjects) Exists(_ context.Context, key string) (bool, error) {
+	return f.keys[key], nil
+}
+
+func (f *fakeChunkObjects) Delete(_ context.Context, key string) error {
+	delete(f.keys, key)
+	f.deleted = append(f.deleted, key)
+	return nil
+}
+
+// recordStaleUpload records a pending upload of lines first..last and
+// backdates it past the reconcile window.
+func recordStaleUpload(t *testing.T, env *testutil.TestEnvironment, store *dbsession.Store, sessionID string, first, last int, lastMessageAt *time.Time) (int64, string) {
+	t.Helper()
+	key := "chunks/transcript.jsonl/" + time.Now().Format(time.RFC3339Nano)
+	id, err := store.RecordChunkUpload(context.Background(), dbsession.ChunkUpload{
+		SessionID:     sessionID,
+		FileName:      "transcript.jsonl",
+		FileType:      "transcript",
+		FirstLine:     first,
+		LastLine:      last,
+		S3Key:         key,
+		ChunkBytes:    100,
+		LastMessageAt: lastMessageAt,
+	})
+	if err != nil {
+		t.Fatalf("RecordChunkUpload: %v", err)
+	}
+	if _, err := env.DB.Exec(env.Ctx,
+		`UPDATE chunk_upload_events SET uploaded_at = NOW() - INTERVAL '10 minutes' WHERE id = $1`, id); err != nil {
+		t.Fatalf("backdate upload: %v", err)
+	}
+	return id, key
+}
+
+func eventResolution(t *testing.T, env *testutil.TestEnvironment, id int64) string {
+	t.Helper()
+	var resolution *string
+	if err := env.DB.QueryRow(env.Ctx,
+		`SELECT resolution FROM chunk_upload_events WHERE id = $1`, id).Scan(&resolution); err != nil {
+		t.Fatalf("load event: %v", err)
+	}
+	if resolution == nil {
+		return ""
+	}
+	return *resolution
+}
+
+func TestReconcileChunkUpload(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	ctx := context.Background()
+	store := &dbsession.Store{DB: env.DB}
+
+	setup := func(t *testing.T, synced int) string {
+		t.Helper()
+		env.CleanDB(t)
+		user := testutil.CreateTestUser(t, env, "reconcile@test.com", "Reconcile")
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "reconcile-session")
+		if synced > 0 {
+			if err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, synced, 50, nil, nil, nil, nil, false, nil); err != nil {
+				t.Fatalf("UpdateSyncFileState: %v", err)
+			}
+		}
+		return sessionID
+	}
+
+	t.Run("replays a missed sync-state update", func(t *testing.T) {
+		sessionID := setup(t, 10)
+		msgAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+		id, key := recordStaleUpload(t, env, store, sessionID, 11, 20, &msgAt)
+		objects := &fakeChunkObjects{keys: map[string]bool{key: true}}
+
+		ids, err := store.ListStaleChunkUploads(ctx, 5*time.Minute, 10)
+		if err != nil || len(ids) != 1 || ids[0] != id {
+			t.Fatalf("ListStaleChunkUploads = %v, %v; want [%d]", ids, err, id)
+		}
+		got, err := store.ReconcileChunkUpload(ctx, id, objects)
+		if err != nil || got != dbsession.ChunkUploadReplayed {
+			t.Fatalf("ReconcileChunkUpload = %q, %v; want replayed", got, err)
+		}
+
+		state, err := store.GetSyncFileState(ctx, sessionID, "transcript.jsonl")
+		if err != nil {
+			t.Fatalf("GetSyncFileState: %v", err)
+		}
+		if state.LastSyncedLine != 20 || state.ChunkCount == nil || *state.ChunkCount != 2 {
+			t.Errorf("state after replay = %+v, want line 20 and 2 chunks", state)
+		}
+		var storedBytes int64
+		var lastMessageAt time.Time
+		if err := env.DB.QueryRow(env.Ctx, `
+			SELECT sf.stored_bytes, s.last_message_at FROM sync_files sf JOIN sessions s ON s.id = sf.session_id
+			WHERE sf.session_id = $1`, sessionID).Scan(&storedBytes, &lastMessageAt); err != nil {
+			t.Fatalf("load replayed state: %v", err)
+		}
+		if storedBytes != 150 || !lastMessageAt.Equal(msgAt) {
+			t.Errorf("stored_bytes = %d, last_message_at = %s; want 150, %s", storedBytes, lastMessageAt, msgAt)
+		}
+
+		// Settled events are neither listed nor settled again.
+		if ids, _ := store.ListStaleChunkUploads(ctx, 5*time.Minute, 10); len(ids) != 0 {
+			t.Errorf("settled event still listed: %v", ids)
+		}
+		if got, err := store.ReconcileChunkUpload(ctx, id, objects); err != nil || got != "" {
+			t.Errorf("second ReconcileChunkUpload = %q, %v; want no-op", got, err)
+		}
+	})
+
+	t.Run("replays the first chunk of a new file", func(t *testing.T) {
+		sessionID := setup(t, 0)
+		id, key := recordStaleUpload(t, env, store, sessionID, 1, 5, nil)
+
+		got, err := store.ReconcileChunkUpload(ctx, id, &fakeChunkObjects{keys: map[string]bool{key: true}})
+		if err != nil || got != dbsession.ChunkUploadReplayed {
+			t.Fatalf("ReconcileChunkUpload = %q, %v; want replayed", got, err)
+		}
+		if state, err := store.GetSyncFileState(ctx, sessionID, "transcript.jsonl"); err != nil || state.LastSyncedLine != 5 {
+			t.Errorf("state = %+v, %v; want line 5", state, err)
+		}
+	})
+
+	t.Run("a retry that already covered the chunk supersedes it", func(t *testing.T) {
+		sessionID := setup(t, 25)
+		id, key := recordStaleUpload(t, env, store, sessionID, 11, 20, nil)
+		objects := &fakeChunkObjects{keys: map[string]bool{key: true}}
+
+		got, err := store.ReconcileChunkUpload(ctx, id, objects)
+		if err != nil || got != dbsession.ChunkUploadSuperseded {
+			t.Fatalf("ReconcileChunkUpload = %q, %v; want superseded", got, err)
+		}
+		if len(objects.deleted) != 0 {
+			t.Errorf("superseded chunk must be kept, deleted %v", objects.deleted)
+		}
+	})
+
+	t.Run("an orphan past the synced line is deleted", func(t *testing.T) {
+		sessionID := setup(t, 15)
+		id, key := recordStaleUpload(t, env, store, sessionID, 11, 20, nil)
+		objects := &fakeChunkObjects{keys: map[string]bool{key: true}}
+
+		got, err := store.ReconcileChunkUpload(ctx, id, objects)
+		if err != nil || got != dbsession.ChunkUploadDiscarded {
+			t.Fatalf("ReconcileChunkUpload = %q, %v; want discarded", got, err)
+		}
+		if len(objects.deleted) != 1 || objects.deleted[0] != key {
+			t.Errorf("deleted = %v, want [%s]", objects.deleted, key)
+		}
+		if state, _ := store.GetSyncFileState(ctx, sessionID, "transcript.jsonl"); state.LastSyncedLine != 15 {
+			t.Errorf("LastSyncedLine = %d, want 15 (unchanged)", state.LastSyncedLine)
+		}
+	})
+
+	t.Run("a chunk of a reset generation is never replayed", func(t *testing.T) {
+		sessionID := setup(t, 10)
+		if _, err := store.ResetSyncFile(ctx, sessionID, "transcript.jsonl", 0, dbsession.ResetReasonCompaction, 0, analytics.SessionDerivedTableNames, func() error { return nil }); err != nil {
+			t.Fatalf("ResetSyncFile: %v", err)
+		}
+		// Read against generation 0; lines 1-5 would otherwise line up with
+		// the empty generation 1.
+		id, key := recordStaleUpload(t, env, store, sessionID, 1, 5, nil)
+		objects := &fakeChunkObjects{keys: map[string]bool{key: true}}
+
+		got, err := store.ReconcileChunkUpload(ctx, id, objects)
+		if err != nil || got != dbsession.ChunkUploadDiscarded {
+			t.Fatalf("ReconcileChunkUpload = %q, %v; want discarded", got, err)
+		}
+		if len(objects.deleted) != 1 || objects.deleted[0] != key {
+			t.Errorf("deleted = %v, want [%s]", objects.deleted, key)
+		}
+		if state, _ := store.GetSyncFileState(ctx, sessionID, "transcript.jsonl"); state.LastSyncedLine != 0 || state.Generation != 1 {
+			t.Errorf("state = %+v, want an empty generation 1", state)
+		}
+	})
+
+	t.Run("an upload that never landed is missing", func(t *testing.T) {
+		sessionID := setup(t, 10)
+		id, _ := recordStaleUpload(t, env, store, sessionID, 11, 20, nil)
+
+		got, err := store.ReconcileChunkUpload(ctx, id, &fakeChunkObjects{})
+		if err != nil || got != dbsession.ChunkUploadMissing {
+			t.Fatalf("ReconcileChunkUpload = %q, %v; want missing", got, err)
+		}
+		if state, _ := store.GetSyncFileState(ctx, sessionID, "transcript.jsonl"); state.LastSyncedLine != 10 {
+			t.Errorf("LastSyncedLine = %d, want 10 (unchanged)", state.LastSyncedLine)
+		}
+	})
+
+	t.Run("recording a retry supersedes the pending event for the same object", func(t *testing.T) {
+		sessionID := setup(t, 10)
+		upload := dbsession.ChunkUpload{
+			SessionID: sessionID, FileName: "transcript.jsonl", FileType: "transcript",
+			FirstLine: 11, LastLine: 20, S3Key: "chunks/transcript.jsonl/retry", ChunkBytes: 100,
+		}
+		first, err := store.RecordChunkUpload(ctx, upload)
+		if err != nil {
+			t.Fatalf("RecordChunkUpload: %v", err)
+		}
+		second, err := store.RecordChunkUpload(ctx, upload)
+		if err != nil {
+			t.Fatalf("RecordChunkUpload (retry): %v", err)
+		}
+		if err := store.ConfirmChunkUpload(ctx, second); err != nil {
+			t.Fatalf("ConfirmChunkUpload: %v", err)
+		}
+
+		if got := eventResolution(t, env, first); got != dbsession.ChunkUploadSuperseded {
+			t.Errorf("first attempt resolution = %q, want superseded", got)
+		}
+		if got := eventResolution(t, env, second); got != dbsession.ChunkUploadSynced {
+			t.Errorf("retry resolution = %q, want synced", got)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+func TestRankCursorRoundTrip(t *testing.T) {
+	ts := time.Date(2026, 3, 1, 12, 30, 0, 123456789, time.UTC)
+	rank := float64(float32(0.0333333)) // ts_rank_cd returns real
+
+	gotRank, gotTime, gotID, err := decodeRankCursor(encodeRankCursor(rank, ts, "abc-id"))
+	if err != nil {
+		t.Fatalf("decodeRankCursor: %v", err)
+	}
+	if gotRank != rank || !gotTime.Equal(ts) || gotID != "abc-id" {
+		t.Errorf("round trip = (%v, %v, %q), want (%v, %v, %q)", gotRank, gotTime, gotID, rank, ts, "abc-id")
+	}
+	if float64(float32(gotRank)) != gotRank {
+		t.Errorf("rank %v no longer fits a real exactly", gotRank)
+	}
+}
+
+func TestDecodeRankCursor_RejectsPlainCursor(t *testing.T) {
+	// A chronological cursor (from an unfiltered page) must not decode as a
+	// search cursor; the query then restarts from the first page.
+	if _, _, _, err := decodeRankCursor(encodeCursor(time.Now(), "abc-id")); err == nil {
+		t.Error("plain cursor should not decode as a rank cursor")
+	}
+}
+
+func TestInterestCursorRoundTrip(t *testing.T) {
+	score := 2925.123456789012 // recency term dominates; every digit matters
+
+	gotScore, gotID, err := decodeInterestCursor(encodeInterestCursor(score, "abc-id"))
+	if err != nil {
+		t.Fatalf("decodeInterestCursor: %v", err)
+	}
+	if gotScore != score || gotID != "abc-id" {
+		t.Errorf("round trip = (%v, %q), want (%v, %q)", gotScore, gotID, score, "abc-id")
+	}
+}
+
+func TestDecodeInterestCursor_RejectsPlainCursor(t *testing.T) {
+	// A chronological cursor from a page sorted by recency restarts the
+	// interesting order from the first page.
+	if _, _, err := decodeInterestCursor(encodeCursor(time.Now(), "abc-id")); err == nil {
+		t.Error("plain cursor should not decode as an interest cursor")
+	}
+}
+
+func TestSortCursorRoundTrip(t *testing.T) {
+	cols := sortColumnsFor([]string{db.SessionSortCostDesc, db.SessionSortCreatedAtAsc})
+	cost, duration := "12.345678901234", int64(90_000)
+	last := db.SessionListItem{
+		ID:               "abc-id",
+		FirstSeen:        time.Date(2026, 3, 1, 12, 30, 0, 123456000, time.UTC),
+		EstimatedCostUSD: &cost,
+		DurationMs:       &duration,
+	}
+
+	values, id, err := decodeSortCursor(encodeSortCursor(cols, last), cols)
+	if err != nil {
+		t.Fatalf("decodeSortCursor: %v", err)
+	}
+	if id != "abc-id" || len(values) != 3 {
+		t.Fatalf("decoded %v, %q", values, id)
+	}
+	if values[0] != false || values[1] != 12.345678901234 || !values[2].(time.Time).Equal(last.FirstSeen) {
+		t.Errorf("values = %v, want [false 12.345678901234 %s]", values, last.FirstSeen)
+	}
+
+	// An uncomputed card encodes as missing with a zero value.
+	values, _, err = decodeSortCursor(encodeSortCursor(cols, db.SessionListItem{ID: "x"}), cols)
+	if err != nil || values[0] != true || values[1] != 0.0 {
+		t.Errorf("uncarded values = %v, %v; want [true 0 ...]", values, err)
+	}
+}
+
+func TestDecodeSortCursor_RejectsOtherCursors(t *testing.T) {
+	cols := sortColumnsFor([]string{db.SessionSortCostDesc})
+	for name, cursor := range map[string]string{
+		"plain":        encodeCursor(time.Now(), "abc-id"),
+		"interest":     encodeInterestCursor(1.5, "abc-id"),
+		"another sort": encodeSortCursor(sortColumnsFor([]string{db.SessionSortCreatedAtDesc}), db.SessionListItem{ID: "abc-id"}),
+	} {
+		if _, _, err := decodeSortCursor(cursor, cols); err == nil {
+			t.Errorf("%s cursor should not decode as a cost sort cursor", name)
+		}
+	}
+}
+
+func TestSortKeysetPredicate(t *testing.T) {
+	cols := sortColumnsFor([]string{db.SessionSortCreatedAtAsc, db.SessionSortLineCountDesc})
+	pb := newParamBuilder(1)
+	got := sortKeysetPredicate(pb, cols, []any{time.Now(), 10.0}, "abc-id")
+	want := "((s.first_seen > $2) OR " +
+		"(s.first_seen = $2 AND COALESCE(sf_stats.total_lines, 0)::float8 < $3::float8) OR " +
+		"(s.first_seen = $2 AND COALESCE(sf_stats.total_lines, 0)::float8 = $3::float8 AND s.id < $4::uuid))"
+	if got != want {
+		t.Errorf("predicate =\n%s\nwant\n%s", got, want)
+	}
+	if len(pb.args) != 4 {
+		t.Errorf("bound %d args, want 4", len(pb.args))
+	}
+	if order := sortOrderBy(cols); !strings.HasSuffix(order, "s.id DESC") || !strings.HasPrefix(order, "s.first_seen ASC") {
+		t.Errorf("order by = %s", order)
+	}
+}
@@ -0,0 +1,71 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+// ErrNotDemoSession is returned by CreateDemoSession when the external ID is
+// already taken by one of the user's real sessions.
+var ErrNotDemoSession = errors.New("session exists and is not a demo session")
+
+// DemoSessionParams describes a sample session for CreateDemoSession.
+type DemoSessionParams struct {
+	ExternalID     string
+	TranscriptPath string
+	CWD            string
+}
+
+// CreateDemoSession finds or creates the user's claude-code sample session
+// with the given external ID and marks it is_demo. Unlike
+// FindOrCreateSyncSession it queues no session.created webhook: seeding
+// sample data is not activity. It returns the session ID and the state of the
+// files already synced, so a repeated seed skips them.
+func (s *Store) CreateDemoSession(ctx context.Context, userID int64, params DemoSessionParams) (string, map[string]db.SyncFileState, error) {
+	ctx, span := tracer.Start(ctx, "db.create_demo_session",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.String("session.external_id", params.ExternalID),
+		))
+	defer span.End()
+
+	// The conflict update only matches an existing demo row, so a real
+	// session that happens to share the external ID returns no row.
+	query := `
+		INSERT INTO sessions (id, user_id, external_id, first_seen, session_type, session_type_source, cwd, transcript_path, last_sync_at, is_demo, interest_score)
+		VALUES ($1, $2, $3, NOW(), $4, $5, $6, $7, NOW(), TRUE, $8)
+		ON CONFLICT (user_id, session_type, external_id) DO UPDATE
+			SET last_sync_at = NOW()
+			WHERE sessions.is_demo
+		RETURNING id
+	`
+	var sessionID string
+	err := s.conn().QueryRowContext(ctx, query, uuid.New().String(), userID, params.ExternalID,
+		models.ProviderClaudeCode, db.SessionTypeSourceExplicit, params.CWD, params.TranscriptPath, s.newSessionInterestScore()).Scan(&sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, ErrNotDemoSession
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return "", nil, fmt.Errorf("failed to create demo session: %w", err)
+	}
+
+	_, files, err := s.getSyncFilesForSession(ctx, sessionID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return "", nil, err
+	}
+	return sessionID, files, nil
+}
@@ -0,0 +1,153 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// duplicateOfExpr returns a scalar subquery yielding the earliest unmerged
+// session owned by the same user that shares alias's content fingerprint, or
+// NULL when alias is not a probable duplicate. "Earliest" is (first_seen, id)
+// so the original is stable across calls. userParam restricts the flag to the
+// viewer's own sessions — a shared session never reports a duplicate_of.
+func duplicateOfExpr(alias, userParam string) string {
+	return `(SELECT orig.id::text FROM sessions orig
+				WHERE ` + alias + `.content_fingerprint IS NOT NULL
+				  AND ` + alias + `.user_id = ` + userParam + `
+				  AND orig.user_id = ` + alias + `.user_id
+				  AND orig.content_fingerprint = ` + alias + `.content_fingerprint
+				  AND orig.merged_at IS NULL
+				  AND (orig.first_seen, orig.id) < (` + alias + `.first_seen, ` + alias + `.id)
+				ORDER BY orig.first_seen, orig.id
+				LIMIT 1)`
+}
+
+// GetFingerprintState returns the rolling-hash state for one sync file and
+// whether the session already has a content fingerprint. A file with no
+// state yet reports (0, 0). The state is an opaque uint64 stored bit-for-bit
+// in a BIGINT column.
+func (s *Store) GetFingerprintState(ctx context.Context, sessionID, fileName string) (state uint64, lines int, done bool, err error) {
+	ctx, span := tracer.Start(ctx, "db.get_fingerprint_state",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.String("file.name", fileName),
+		))
+	defer span.End()
+
+	var rawState int64
+	query := `
+		SELECT COALESCE(sf.fingerprint_state, 0), COALESCE(sf.fingerprint_lines, 0),
+		       s.content_fingerprint IS NOT NULL
+		FROM sync_files sf
+		JOIN sessions s ON s.id = sf.session_id
+		WHERE sf.session_id = $1 AND sf.file_name = $2`
+	err = s.conn().QueryRowContext(ctx, query, sessionID, fileName).Scan(&rawState, &lines, &done)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, false, db.ErrFileNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, 0, false, fmt.Errorf("failed to get fingerprint state: %w", err)
+	}
+	return uint64(rawState), lines, done, nil
+}
+
+// AdvanceFingerprint stores a new rolling-hash state for one sync file,
+// conditional on the file still being at fromLines (so a concurrent or
+// replayed chunk can't fold the same lines twice). When fingerprint is
+// non-nil the session's content_fingerprint is set too — first writer wins,
+// an existing fingerprint is never overwritten. Returns false when the
+// conditional update matched nothing.
+func (s *Store) AdvanceFingerprint(ctx context.Context, sessionID, fileName string, fromLines int, state uint64, lines int, fingerprint *string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "db.advance_fingerprint",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.String("file.name", fileName),
+			attribute.Int("fingerprint.lines", lines),
+		))
+	defer span.End()
+
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE sync_files
+		SET fingerprint_state = $3, fingerprint_lines = $4
+		WHERE session_id = $1 AND file_name = $2 AND COALESCE(fingerprint_lines, 0) = $5`,
+		sessionID, fileName, int64(state), lines, fromLines)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return false, fmt.Errorf("failed to update fingerprint state: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	if fingerprint != nil {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE sessions SET content_fingerprint = $2 WHERE id = $1 AND content_fingerprint IS NULL`,
+			sessionID, *fingerprint); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return false, fmt.Errorf("failed to set content fingerprint: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return false, fmt.Errorf("failed to commit fingerprint state: %w", err)
+	}
+	return true, nil
+}
+
+// MergeDuplicateSession soft-deletes sessionID as a duplicate of the earliest
+// unmerged session sharing its content fingerprint: merged_at is stamped and
+// merged_into points at the original. Merged sessions drop out of
+// db.VisibleSessionsCTE, so they leave the session list and every analytics
+// aggregate, but no data is removed. Returns the original's ID, or
+// db.ErrNotDuplicate when the session has no original or is already merged.
+func (s *Store) MergeDuplicateSession(ctx context.Context, sessionID string, userID int64) (string, error) {
+	ctx, span := tracer.Start(ctx, "db.merge_duplicate_session",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.Int64("user.id", userID),
+		))
+	defer span.End()
+
+	var mergedInto string
+	query := `
+		UPDATE sessions s
+		SET merged_into = d.original_id::uuid, merged_at = NOW()
+		FROM (SELECT ` + duplicateOfExpr("s2", "$2") + ` AS original_id
+		      FROM sessions s2 WHERE s2.id = $1) d
+		WHERE s.id = $1 AND s.user_id = $2
+		  AND s.merged_at IS NULL
+		  AND d.original_id IS NOT NULL
+		RETURNING s.merged_into::text`
+	err := s.conn().QueryRowContext(ctx, query, sessionID, userID).Scan(&mergedInto)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", db.ErrNotDuplicate
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return "", fmt.Errorf("failed to merge duplicate session: %w", err)
+	}
+	span.SetAttributes(attribute.String("session.merged_into", mergedInto))
+	return mergedInto, nil
+}
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+// ReassignExternalID points sessionID at newExternalID after the caller has
+// copied the session's chunks to the new ID's storage prefix
+// (storage.CopyAllSessionChunks). Everything else keyed by the session ID
+// (sync files, cards, shares, links) stays as it is; chunk_upload_events keys
+// are rewritten to the new prefix so chunk reconciliation finds the copies.
+//
+// The session must still have oldExternalID and the sync files the copy was
+// planned from (files, as ListSyncFileStates returned them); otherwise
+// nothing changes and db.ErrReassignConflict is returned. Returns
+// db.ErrExternalIDTaken if another of the user's sessions of the same type
+// has newExternalID, and db.ErrSessionNotFound unless userID owns the
+// session.
+func (s *Store) ReassignExternalID(ctx context.Context, userID int64, sessionID, oldExternalID, newExternalID string, files []db.SyncFileState) error {
+	ctx, span := tracer.Start(ctx, "db.reassign_external_id",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.Int64("user.id", userID),
+		))
+	defer span.End()
+
+	err := s.reassignExternalID(ctx, userID, sessionID, oldExternalID, newExternalID, files)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	s.DB.SessionOwners.Invalidate(sessionID)
+	return nil
+}
+
+func (s *Store) reassignExternalID(ctx context.Context, userID int64, sessionID, oldExternalID, newExternalID string, files []db.SyncFileState) error {
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the session so a concurrent reassignment, merge or delete waits.
+	var externalID, provider string
+	err = tx.QueryRowContext(ctx,
+		`SELECT external_id, session_type FROM sessions WHERE id = $1 AND user_id = $2 FOR UPDATE`,
+		sessionID, userID).Scan(&externalID, &provider)
+	if err == sql.ErrNoRows {
+		return db.ErrSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock session: %w", err)
+	}
+	if externalID != oldExternalID {
+		return db.ErrReassignConflict
+	}
+
+	// A chunk synced since the copy would exist only under the old ID.
+	current, err := listSyncFileStates(ctx, tx, sessionID)
+	if err != nil {
+		return err
+	}
+	if !sameSyncFileStates(current, files) {
+		return db.ErrReassignConflict
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE sessions SET external_id = $2 WHERE id = $1`, sessionID, newExternalID)
+	if db.IsUniqueViolation(err) {
+		return db.ErrExternalIDTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update external ID: %w", err)
+	}
+
+	// Same layout as storage's session prefixes: {userID}/{provider}/{externalID}/.
+	provider = models.NormalizeProvider(provider)
+	oldPrefix := fmt.Sprintf("%d/%s/%s/", userID, provider, oldExternalID)
+	newPrefix := fmt.Sprintf("%d/%s/%s/", userID, provider, newExternalID)
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE chunk_upload_events
+		SET s3_key = $3 || substr(s3_key, length($2) + 1)
+		WHERE session_id = $1 AND left(s3_key, length($2)) = $2`,
+		sessionID, oldPrefix, newPrefix); err != nil {
+		return fmt.Errorf("failed to re-key chunk upload events: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// ExternalIDInUse reports whether another session of sessionID's owner and
+// type has externalID. Sessions merged away as duplicates count: their rows
+// keep the ID.
+func (s *Store) ExternalIDInUse(ctx context.Context, sessionID, externalID string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "db.external_id_in_use",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	var inUse bool
+	err := s.conn().QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM sessions o
+			JOIN sessions s ON s.user_id = o.user_id AND s.session_type = o.session_type
+			WHERE s.id = $1 AND o.id <> s.id AND o.external_id = $2)`,
+		sessionID, externalID).Scan(&inUse)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return false, fmt.Errorf("failed to check external ID: %w", err)
+	}
+	return inUse, nil
+}
+
+// sameSyncFileStates reports whether two ListSyncFileStates results hold the
+// same files at the same line and generation.
+func sameSyncFileStates(a, b []db.SyncFileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].FileName != b[i].FileName || a[i].LastSyncedLine != b[i].LastSyncedLine || a[i].Generation != b[i].Generation {
+			return false
+		}
+	}
+	return true
+}
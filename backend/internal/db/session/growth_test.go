@@ -0,0 +1,112 @@
+package session_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func growthLines(points []db.GrowthPoint) []int {
+	lines := make([]int, len(points))
+	for i, p := range points {
+		lines[i] = p.Line
+	}
+	return lines
+}
+
+func TestSyncFileGrowth(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "growth@test.com", "Growth User")
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "growing")
+	quietID := testutil.CreateTestSession(t, env, user.ID, "quiet")
+
+	// sync_file_growth_append must thin exactly like db.DownsampleGrowth.
+	var want []db.GrowthPoint
+	for i := 1; i <= 3*db.GrowthHistoryCap; i++ {
+		if err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, i*10, 100, nil, nil, nil, nil, false, nil); err != nil {
+			t.Fatalf("UpdateSyncFileState %d: %v", i, err)
+		}
+		want = db.DownsampleGrowth(append(want, db.GrowthPoint{Line: i * 10}), db.GrowthHistoryCap)
+
+		var stored int
+		if err := env.DB.QueryRow(ctx, `SELECT jsonb_array_length(growth) FROM sync_files WHERE session_id = $1`, sessionID).Scan(&stored); err != nil {
+			t.Fatalf("read growth length: %v", err)
+		}
+		if stored != len(want) {
+			t.Fatalf("after upload %d: stored %d points, want %d", i, stored, len(want))
+		}
+	}
+
+	detail, err := store.GetSessionDetail(ctx, sessionID, user.ID)
+	if err != nil {
+		t.Fatalf("GetSessionDetail: %v", err)
+	}
+	got := detail.Files[0].Growth
+	if !slices.Equal(growthLines(got), growthLines(want)) {
+		t.Fatalf("growth lines = %v, want %v", growthLines(got), growthLines(want))
+	}
+	if got[0].Line != 10 || got[len(got)-1].Line != 3*db.GrowthHistoryCap*10 {
+		t.Errorf("first/last = %d/%d, want the first and latest uploads", got[0].Line, got[len(got)-1].Line)
+	}
+	if got[0].At.IsZero() || got[len(got)-1].At.Before(got[0].At) {
+		t.Errorf("timestamps = %s .. %s", got[0].At, got[len(got)-1].At)
+	}
+
+	t.Run("list includes growth on request", func(t *testing.T) {
+		list, err := store.ListUserSessionsPaginated(ctx, user.ID, db.SessionListParams{IncludeGrowth: true})
+		if err != nil {
+			t.Fatalf("ListUserSessionsPaginated: %v", err)
+		}
+		for _, item := range list.Sessions {
+			switch item.ID {
+			case sessionID:
+				if !slices.Equal(growthLines(item.Growth), growthLines(want)) {
+					t.Errorf("listed growth = %v, want %v", growthLines(item.Growth), growthLines(want))
+				}
+			case quietID:
+				if item.Growth != nil {
+					t.Errorf("session without a transcript has growth %v", item.Growth)
+				}
+			}
+		}
+
+		list, err = store.ListUserSessionsPaginated(ctx, user.ID, db.SessionListParams{})
+		if err != nil {
+			t.Fatalf("ListUserSessionsPaginated: %v", err)
+		}
+		for _, item := range list.Sessions {
+			if item.Growth != nil {
+				t.Errorf("session %s has growth without include=growth", item.ID)
+			}
+		}
+	})
+
+	t.Run("reset empties the history", func(t *testing.T) {
+		if _, err := store.ResetSyncFile(ctx, sessionID, "transcript.jsonl", 0, dbsession.ResetReasonManual, 0, analytics.SessionDerivedTableNames, func() error { return nil }); err != nil {
+			t.Fatalf("ResetSyncFile: %v", err)
+		}
+		if err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 1, 5, 100, nil, nil, nil, nil, false, nil); err != nil {
+			t.Fatalf("UpdateSyncFileState: %v", err)
+		}
+		detail, err := store.GetSessionDetail(ctx, sessionID, user.ID)
+		if err != nil {
+			t.Fatalf("GetSessionDetail: %v", err)
+		}
+		if lines := growthLines(detail.Files[0].Growth); !slices.Equal(lines, []int{5}) {
+			t.Errorf("growth after reset = %v, want [5]", lines)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// newSessionInterestScore is the interest score a session is created with:
+// the no-cards baseline at the current time. RefreshInterestScore replaces it
+// once cards exist.
+func (s *Store) newSessionInterestScore() float64 {
+	return db.InterestScore(db.InterestInputs{LastActivity: time.Now()}, s.DB.InterestWeights)
+}
+
+// RefreshInterestScore recomputes a session's interest_score from its cards
+// (cost from tokens_v2, duration from the session card, tool errors from the
+// tools card, and whether a smart recap exists) under s.DB.InterestWeights.
+// A session without a session card scores the baseline. A missing session is
+// not an error.
+func (s *Store) RefreshInterestScore(ctx context.Context, sessionID string) error {
+	ctx, span := tracer.Start(ctx, "db.refresh_interest_score",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	var in db.InterestInputs
+	var cost sql.NullString
+	var durationMs sql.NullInt64
+	err := s.conn().QueryRowContext(ctx, `
+		SELECT COALESCE(s.last_message_at, s.first_seen),
+			sc.session_id IS NOT NULL,
+			`+db.V2TotalCostExpr("v")+`,
+			sc.duration_ms,
+			COALESCE(t.error_count, 0),
+			COALESCE(r.recap <> '', FALSE)
+		FROM sessions s
+		LEFT JOIN session_card_session sc ON sc.session_id = s.id
+		LEFT JOIN session_card_tokens_v2 v ON v.session_id = s.id
+		LEFT JOIN session_card_tools t ON t.session_id = s.id
+		LEFT JOIN session_card_smart_recap r ON r.session_id = s.id
+		WHERE s.id = $1`, sessionID).Scan(&in.LastActivity, &in.HasCards, &cost, &durationMs, &in.ToolErrors, &in.HasRecap)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to read interest inputs: %w", err)
+	}
+	if cost.Valid {
+		in.CostUSD, _ = strconv.ParseFloat(cost.String, 64)
+	}
+	in.Duration = time.Duration(durationMs.Int64) * time.Millisecond
+
+	score := db.InterestScore(in, s.DB.InterestWeights)
+	if _, err := s.conn().ExecContext(ctx,
+		`UPDATE sessions SET interest_score = $2 WHERE id = $1`, sessionID, score); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to update interest score: %w", err)
+	}
+	span.SetAttributes(attribute.Float64("session.interest_score", score))
+	return nil
+}
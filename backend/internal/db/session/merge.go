@@ -0,0 +1,301 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// MergeFile is one source sync file's place in a session merge. Its chunks
+// are re-keyed into TargetFileName at lines Offset+1..Offset+Lines.
+type MergeFile struct {
+	SourceFileName string
+	TargetFileName string
+	FileType       string
+	// Offset is the target file's last_synced_line the source lines follow;
+	// 0 when the target has no such file yet (NewFile).
+	Offset  int
+	Lines   int
+	NewFile bool
+	// Chunks is how many chunks were copied; MergeSessions adds it to the
+	// target file's chunk_count.
+	Chunks int
+}
+
+// PlanSessionMerge pairs each of the source's sync files with the target file
+// its lines are appended to. The two transcripts pair with each other whatever
+// their names (Claude Code names them after the session's external ID); any
+// other file, e.g. an agent-*.jsonl, pairs with the target file of the same
+// name, or becomes a new target file at its original line numbers. Legacy
+// todo files are not merged.
+func PlanSessionMerge(target, source []db.SyncFileState) []MergeFile {
+	targetByName := make(map[string]db.SyncFileState, len(target))
+	var targetTranscript *db.SyncFileState
+	transcripts := 0
+	for i, f := range target {
+		targetByName[f.FileName] = f
+		if f.FileType == "transcript" {
+			targetTranscript = &target[i]
+			transcripts++
+		}
+	}
+	if transcripts != 1 {
+		targetTranscript = nil
+	}
+
+	var plan []MergeFile
+	for _, f := range source {
+		if f.FileType == "todo" {
+			continue
+		}
+		mf := MergeFile{SourceFileName: f.FileName, FileType: f.FileType, Lines: f.LastSyncedLine}
+		dst, ok := targetByName[f.FileName]
+		if f.FileType == "transcript" && targetTranscript != nil {
+			dst, ok = *targetTranscript, true
+		}
+		if ok {
+			mf.TargetFileName = dst.FileName
+			mf.Offset = dst.LastSyncedLine
+		} else {
+			mf.TargetFileName = f.FileName
+			mf.NewFile = true
+		}
+		plan = append(plan, mf)
+	}
+	return plan
+}
+
+// MergeSessions folds sourceID into targetID after the caller has copied the
+// source's chunks into the target's storage prefix per files: each target
+// file's last_synced_line and chunk_count grow by the source's (or the file is
+// created), the target keeps the earliest first_seen and the latest
+// last_message_at, fills a missing summary / first message / git info from the
+// source, takes over the source's GitHub links, and has its derivedTables rows
+// (see analytics.SessionDerivedTableNames) deleted so analytics recompute
+// from the merged transcript. The source session is then deleted.
+//
+// Every file is checked against the line counts the plan was built from; if
+// either session synced in the meantime nothing changes and
+// db.ErrMergeConflict is returned. Both sessions must belong to userID.
+func (s *Store) MergeSessions(ctx context.Context, userID int64, targetID, sourceID string, files []MergeFile, derivedTables []string) error {
+	ctx, span := tracer.Start(ctx, "db.merge_sessions",
+		trace.WithAttributes(
+			attribute.String("session.id", targetID),
+			attribute.String("session.source_id", sourceID),
+			attribute.Int64("user.id", userID),
+			attribute.Int("merge.files", len(files)),
+		))
+	defer span.End()
+
+	return s.mergeSessions(ctx, span, userID, targetID, sourceID, files, derivedTables, false)
+}
+
+// SoftMergeSessions folds sourceID into targetID like MergeSessions, but
+// keeps the source: instead of being deleted it is marked merged into the
+// target (merged_into, merged_at), as a merged duplicate is, so it leaves
+// lists and aggregates with its data intact. Returns db.ErrAlreadyMerged when
+// either session is already merged.
+func (s *Store) SoftMergeSessions(ctx context.Context, userID int64, targetID, sourceID string, files []MergeFile, derivedTables []string) error {
+	ctx, span := tracer.Start(ctx, "db.soft_merge_sessions",
+		trace.WithAttributes(
+			attribute.String("session.id", targetID),
+			attribute.String("session.source_id", sourceID),
+			attribute.Int64("user.id", userID),
+			attribute.Int("merge.files", len(files)),
+		))
+	defer span.End()
+
+	return s.mergeSessions(ctx, span, userID, targetID, sourceID, files, derivedTables, true)
+}
+
+func (s *Store) mergeSessions(ctx context.Context, span trace.Span, userID int64, targetID, sourceID string, files []MergeFile, derivedTables []string, keepSource bool) error {
+
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock both rows (in id order) so a concurrent merge or delete of either
+	// session waits for this one.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT merged_at IS NOT NULL FROM sessions
+		WHERE id = ANY($1::uuid[]) AND user_id = $2
+		ORDER BY id
+		FOR UPDATE`, pq.Array([]string{targetID, sourceID}), userID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to lock sessions: %w", err)
+	}
+	locked, merged := 0, false
+	for rows.Next() {
+		var m bool
+		if err := rows.Scan(&m); err != nil {
+			rows.Close()
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return fmt.Errorf("failed to lock sessions: %w", err)
+		}
+		locked++
+		merged = merged || m
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to lock sessions: %w", err)
+	}
+	if locked != 2 {
+		return db.ErrSessionNotFound
+	}
+	if keepSource && merged {
+		return db.ErrAlreadyMerged
+	}
+
+	var sourceFiles int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM sync_files WHERE session_id = $1 AND file_type != 'todo'`,
+		sourceID).Scan(&sourceFiles); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to count source files: %w", err)
+	}
+	if sourceFiles != len(files) {
+		return db.ErrMergeConflict
+	}
+
+	for _, f := range files {
+		var sourceLines int
+		err := tx.QueryRowContext(ctx,
+			`SELECT last_synced_line FROM sync_files WHERE session_id = $1 AND file_name = $2`,
+			sourceID, f.SourceFileName).Scan(&sourceLines)
+		if err == sql.ErrNoRows || (err == nil && sourceLines != f.Lines) {
+			return db.ErrMergeConflict
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return fmt.Errorf("failed to check source file %s: %w", f.SourceFileName, err)
+		}
+
+		var result sql.Result
+		if f.NewFile {
+			result, err = tx.ExecContext(ctx, `
+				INSERT INTO sync_files (session_id, file_name, file_type, last_synced_line, chunk_count, stored_bytes)
+				SELECT $1, $2, $3, $4, $5, stored_bytes
+				FROM sync_files WHERE session_id = $6 AND file_name = $7
+				ON CONFLICT (session_id, file_name) DO NOTHING`,
+				targetID, f.TargetFileName, f.FileType, f.Lines, f.Chunks, sourceID, f.SourceFileName)
+		} else {
+			result, err = tx.ExecContext(ctx, `
+				UPDATE sync_files
+				SET last_synced_line = last_synced_line + $3,
+					chunk_count = COALESCE(chunk_count, 0) + $4,
+					stored_bytes = stored_bytes + (
+						SELECT stored_bytes FROM sync_files WHERE session_id = $6 AND file_name = $7),
+					updated_at = NOW()
+				WHERE session_id = $1 AND file_name = $2 AND last_synced_line = $5`,
+				targetID, f.TargetFileName, f.Lines, f.Chunks, f.Offset, sourceID, f.SourceFileName)
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return fmt.Errorf("failed to merge file %s: %w", f.SourceFileName, err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return db.ErrMergeConflict
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE sessions t
+		SET first_seen = LEAST(t.first_seen, src.first_seen),
+			last_message_at = GREATEST(t.last_message_at, src.last_message_at),
+			last_sync_at = GREATEST(t.last_sync_at, src.last_sync_at),
+			summary = COALESCE(t.summary, src.summary),
+			first_user_message = COALESCE(t.first_user_message, src.first_user_message),
+			first_user_message_source = CASE WHEN t.first_user_message IS NULL
+				THEN src.first_user_message_source ELSE t.first_user_message_source END,
+			git_info = COALESCE(t.git_info, src.git_info),
+			pr_url = COALESCE(t.pr_url, src.pr_url),
+			detected_pr_url = COALESCE(t.detected_pr_url, src.detected_pr_url)
+		FROM sessions src
+		WHERE t.id = $1 AND src.id = $2`, targetID, sourceID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to merge session metadata: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE session_github_links l
+		SET session_id = $1
+		WHERE l.session_id = $2
+		  AND NOT EXISTS (
+			SELECT 1 FROM session_github_links t
+			WHERE t.session_id = $1 AND t.link_type = l.link_type
+			  AND t.owner = l.owner AND t.repo = l.repo AND t.ref = l.ref)`,
+		targetID, sourceID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to move github links: %w", err)
+	}
+
+	// Duplicates soft-merged into the source now point at the target.
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE sessions SET merged_into = $1 WHERE merged_into = $2`,
+		targetID, sourceID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to repoint merged duplicates: %w", err)
+	}
+
+	for _, table := range derivedTables {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE session_id = $1`, table), targetID); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+
+	if keepSource {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE sessions SET merged_into = $1, merged_at = NOW() WHERE id = $2`,
+			targetID, sourceID); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return fmt.Errorf("failed to mark source session merged: %w", err)
+		}
+	} else {
+		if _, err := transitionStates(ctx, tx, []string{sourceID}, StateDeleted, StateReasonMerged); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return fmt.Errorf("failed to update source session state: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1`, sourceID); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return fmt.Errorf("failed to delete source session: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	if !keepSource {
+		s.DB.SessionOwners.Invalidate(sourceID)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+func TestPlanSessionMerge(t *testing.T) {
+	target := []db.SyncFileState{
+		{FileName: "target.jsonl", FileType: "transcript", LastSyncedLine: 10},
+		{FileName: "agent-a.jsonl", FileType: "agent", LastSyncedLine: 4},
+	}
+	source := []db.SyncFileState{
+		{FileName: "agent-a.jsonl", FileType: "agent", LastSyncedLine: 3},
+		{FileName: "agent-b.jsonl", FileType: "agent", LastSyncedLine: 2},
+		{FileName: "source.jsonl", FileType: "transcript", LastSyncedLine: 7},
+		{FileName: "todos.json", FileType: "todo", LastSyncedLine: 1},
+	}
+
+	got := PlanSessionMerge(target, source)
+	want := []MergeFile{
+		{SourceFileName: "agent-a.jsonl", TargetFileName: "agent-a.jsonl", FileType: "agent", Offset: 4, Lines: 3},
+		{SourceFileName: "agent-b.jsonl", TargetFileName: "agent-b.jsonl", FileType: "agent", Lines: 2, NewFile: true},
+		{SourceFileName: "source.jsonl", TargetFileName: "target.jsonl", FileType: "transcript", Offset: 10, Lines: 7},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PlanSessionMerge() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	t.Run("target without a transcript gets the source's", func(t *testing.T) {
+		got := PlanSessionMerge(nil, source[2:3])
+		want := []MergeFile{{SourceFileName: "source.jsonl", TargetFileName: "source.jsonl", FileType: "transcript", Lines: 7, NewFile: true}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("PlanSessionMerge() = %+v, want %+v", got, want)
+		}
+	})
+}
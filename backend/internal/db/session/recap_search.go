@@ -0,0 +1,86 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RecapSearchResult is one smart recap matching a search.
+type RecapSearchResult struct {
+	SessionID string `json:"session_id"`
+	// Snippet is a plain-text excerpt of the recap and its suggestions with
+	// the matched words wrapped in ** (no HTML: recaps are model output).
+	Snippet    string    `json:"snippet"`
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// recapSearchText is the recap followed by the text of each suggestion item,
+// whether stored as a legacy string or an {"text", ...} object. It is what
+// the snippet is cut from.
+const recapSearchText = `r.recap || ' ' || COALESCE((
+	SELECT string_agg(COALESCE(item->>'text', item #>> '{}'), ' ')
+	FROM jsonb_array_elements(r.went_well || r.went_bad || r.human_suggestions
+		|| r.environment_suggestions || r.default_context_suggestions) AS item
+), '')`
+
+// SearchSmartRecaps searches the smart recaps of userID's own sessions through
+// session_card_smart_recap.recap_tsvector, so a recap is findable as soon as it
+// is written rather than after the worker rebuilds session_search_index.
+// Words match by prefix (BuildPrefixTsquery). Results are ordered by
+// relevance, then newest recap. A query with no searchable words returns none.
+func (s *Store) SearchSmartRecaps(ctx context.Context, userID int64, query string, limit int) ([]RecapSearchResult, error) {
+	ctx, span := tracer.Start(ctx, "db.search_smart_recaps",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.Int("limit", limit),
+		))
+	defer span.End()
+
+	tsquery := BuildPrefixTsquery(query)
+	if tsquery == "" {
+		return []RecapSearchResult{}, nil
+	}
+
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT r.session_id,
+			ts_headline('english', `+recapSearchText+`, to_tsquery('english', $2),
+				'StartSel="**", StopSel="**", MaxWords=35, MinWords=15, MaxFragments=2'),
+			r.computed_at
+		FROM session_card_smart_recap r
+		JOIN sessions s ON s.id = r.session_id
+		WHERE s.user_id = $1
+		  AND r.recap_tsvector @@ to_tsquery('english', $2)
+		ORDER BY ts_rank_cd(r.recap_tsvector, to_tsquery('english', $2)) DESC, r.computed_at DESC
+		LIMIT $3`, userID, tsquery, limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to search smart recaps: %w", err)
+	}
+	defer rows.Close()
+
+	results := []RecapSearchResult{}
+	for rows.Next() {
+		var res RecapSearchResult
+		if err := rows.Scan(&res.SessionID, &res.Snippet, &res.ComputedAt); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("failed to scan smart recap match: %w", err)
+		}
+		res.ComputedAt = res.ComputedAt.UTC()
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to search smart recaps: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("results.count", len(results)))
+	return results, nil
+}
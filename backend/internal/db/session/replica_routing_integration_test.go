@@ -0,0 +1,99 @@
+package session_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestReplicaRouting_Integration attaches a second pool on the same database
+// as an in-sync replica and checks, through the db.read_replica span
+// attribute, which pool the session list and detail reads used.
+func TestReplicaRouting_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	dsn, err := env.PostgresContainer.ConnectionString(env.Ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("connection string: %v", err)
+	}
+	replica, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open replica pool: %v", err)
+	}
+	defer replica.Close()
+	env.DB.AttachReplicaForTest(replica)
+
+	user := testutil.CreateTestUser(t, env, "replica@example.com", "Replica")
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "replica-session")
+	store := &dbsession.Store{DB: env.DB}
+
+	// readReplica returns the db.read_replica attribute of the last ended
+	// span with the given name.
+	readReplica := func(t *testing.T, spanName string) bool {
+		t.Helper()
+		spans := recorder.Ended()
+		for i := len(spans) - 1; i >= 0; i-- {
+			if spans[i].Name() != spanName {
+				continue
+			}
+			for _, kv := range spans[i].Attributes() {
+				if kv.Key == attribute.Key("db.read_replica") {
+					return kv.Value.AsBool()
+				}
+			}
+			t.Fatalf("span %s has no db.read_replica attribute", spanName)
+		}
+		t.Fatalf("no %s span recorded", spanName)
+		return false
+	}
+
+	for _, tt := range []struct {
+		name        string
+		ctx         context.Context
+		wantReplica bool
+	}{
+		{"replica by default", context.Background(), true},
+		{"primary under WithPrimary", db.WithPrimary(context.Background()), false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := store.ListUserSessionsPaginated(tt.ctx, user.ID, db.SessionListParams{})
+			if err != nil {
+				t.Fatalf("ListUserSessionsPaginated: %v", err)
+			}
+			if len(list.Sessions) != 1 {
+				t.Errorf("listed %d sessions, want 1", len(list.Sessions))
+			}
+			if got := readReplica(t, "db.list_user_sessions_paginated"); got != tt.wantReplica {
+				t.Errorf("list read_replica = %v, want %v", got, tt.wantReplica)
+			}
+
+			detail, err := store.GetSessionDetail(tt.ctx, sessionID, user.ID)
+			if err != nil {
+				t.Fatalf("GetSessionDetail: %v", err)
+			}
+			if detail.ID != sessionID {
+				t.Errorf("detail ID = %s, want %s", detail.ID, sessionID)
+			}
+			if got := readReplica(t, "db.get_session_detail"); got != tt.wantReplica {
+				t.Errorf("detail read_replica = %v, want %v", got, tt.wantReplica)
+			}
+		})
+	}
+}
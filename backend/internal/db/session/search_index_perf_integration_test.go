@@ -0,0 +1,118 @@
+package session_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// searchIndexPerfSessions is the number of seeded sessions; one in
+// searchIndexPerfNeedleEvery carries the rare search term.
+const (
+	searchIndexPerfSessions    = 10000
+	searchIndexPerfNeedleEvery = 1000
+	searchIndexPerfBudget      = 50 * time.Millisecond
+)
+
+// TestSearchVectorIndex_Performance seeds 10,000 indexed sessions and times
+// the session-search predicate (search_vector @@ tsquery) with the GIN index
+// dropped and then restored (migrations 000037/000058). The indexed query must
+// stay under searchIndexPerfBudget; both timings are logged for comparison.
+func TestSearchVectorIndex_Performance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	user := testutil.CreateTestUser(t, env, "perf@test.com", "Perf")
+	ctx := context.Background()
+
+	if _, err := env.DB.Exec(ctx, `
+		INSERT INTO sessions (id, user_id, external_id, first_seen)
+		SELECT gen_random_uuid(), $1, 'perf-' || g, NOW()
+		FROM generate_series(1, $2) g
+	`, user.ID, searchIndexPerfSessions); err != nil {
+		t.Fatalf("failed to seed sessions: %v", err)
+	}
+	if _, err := env.DB.Exec(ctx, `
+		INSERT INTO session_search_index (
+			session_id, version, content_text, search_vector,
+			indexed_up_to_line, metadata_hash, updated_at
+		)
+		SELECT id, 1, content, setweight(to_tsvector('english', content), 'A'), 100, '', NOW()
+		FROM (
+			SELECT id,
+				'refactor the transcript parser and fix flaky sync tests ' || external_id ||
+				CASE WHEN substring(external_id FROM 6)::int % $1 = 0 THEN ' zanzibar' ELSE '' END AS content
+			FROM sessions WHERE user_id = $2
+		) seeded
+	`, searchIndexPerfNeedleEvery, user.ID); err != nil {
+		t.Fatalf("failed to seed search index: %v", err)
+	}
+
+	// Restore the index however the test exits; other tests share the schema.
+	t.Cleanup(func() {
+		if _, err := env.DB.Exec(context.Background(),
+			`CREATE INDEX IF NOT EXISTS idx_session_search_vector ON session_search_index USING GIN (search_vector)`); err != nil {
+			t.Errorf("failed to restore idx_session_search_vector: %v", err)
+		}
+	})
+
+	query := `
+		SELECT s.id
+		FROM sessions s
+		JOIN session_search_index ssi ON s.id = ssi.session_id
+		WHERE s.user_id = $1 AND ssi.search_vector @@ to_tsquery('english', $2)
+	`
+	// measure returns the fastest of several runs, so one cold-cache run does
+	// not dominate.
+	measure := func() time.Duration {
+		t.Helper()
+		best := time.Duration(-1)
+		for i := 0; i < 5; i++ {
+			start := time.Now()
+			rows, err := env.DB.Conn().QueryContext(ctx, query, user.ID, "zanzibar:*")
+			if err != nil {
+				t.Fatalf("search query failed: %v", err)
+			}
+			n := 0
+			for rows.Next() {
+				n++
+			}
+			rows.Close()
+			elapsed := time.Since(start)
+			if want := searchIndexPerfSessions / searchIndexPerfNeedleEvery; n != want {
+				t.Fatalf("search returned %d sessions, want %d", n, want)
+			}
+			if best < 0 || elapsed < best {
+				best = elapsed
+			}
+		}
+		return best
+	}
+
+	if _, err := env.DB.Exec(ctx, `DROP INDEX IF EXISTS idx_session_search_vector`); err != nil {
+		t.Fatalf("failed to drop index: %v", err)
+	}
+	if _, err := env.DB.Exec(ctx, `ANALYZE session_search_index`); err != nil {
+		t.Fatalf("failed to analyze: %v", err)
+	}
+	before := measure()
+
+	if _, err := env.DB.Exec(ctx,
+		`CREATE INDEX idx_session_search_vector ON session_search_index USING GIN (search_vector)`); err != nil {
+		t.Fatalf("failed to create index: %v", err)
+	}
+	if _, err := env.DB.Exec(ctx, `ANALYZE session_search_index`); err != nil {
+		t.Fatalf("failed to analyze: %v", err)
+	}
+	after := measure()
+
+	t.Logf("search over %d sessions: %v without GIN index, %v with", searchIndexPerfSessions, before, after)
+	if after >= searchIndexPerfBudget {
+		t.Errorf("indexed search took %v, want < %v", after, searchIndexPerfBudget)
+	}
+}
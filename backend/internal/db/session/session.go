@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -77,7 +79,7 @@ func (s *Store) ListUserSessions(ctx context.Context, userID int64) ([]db.Sessio
 	}
 	defer rows.Close()
 
-	sessions, err := scanSessionListItems(rows)
+	sessions, err := scanSessionListItems(rows, false)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -88,24 +90,34 @@ func (s *Store) ListUserSessions(ctx context.Context, userID int64) ([]db.Sessio
 	return sessions, nil
 }
 
-func scanSessionListItems(rows *sql.Rows) ([]db.SessionListItem, error) {
+// scanSessionListItems scans sessionSelectCols plus the access columns. With
+// withRank, each row carries trailing search_rank and interest_score columns
+// as well.
+func scanSessionListItems(rows *sql.Rows, withRank bool) ([]db.SessionListItem, error) {
 	sessions := make([]db.SessionListItem, 0)
 	for rows.Next() {
 		var session db.SessionListItem
 		var gitRepoURL *string
 		var githubPRs pq.StringArray
 		var githubCommits pq.StringArray
-		if err := rows.Scan(
+		dest := []interface{}{
 			&session.ID, &session.ExternalID, &session.FirstSeen,
 			&session.FileCount, &session.LastSyncTime, &session.CustomTitle,
-			&session.SuggestedSessionTitle, &session.Summary, &session.FirstUserMessage,
+			&session.SuggestedSessionTitle, &session.AIGeneratedTitle, &session.Summary, &session.FirstUserMessage,
 			&session.Provider, &session.TotalLines, &gitRepoURL, &session.GitBranch,
-			&githubPRs, &githubCommits, &session.EstimatedCostUSD,
+			&githubPRs, &githubCommits, &session.EstimatedCostUSD, &session.DuplicateOf,
+			&session.State, &session.StateChangedAt, &session.IsDemo, &session.HasOutOfTreeAccess,
+			&session.DurationMs,
 			&session.IsOwner, &session.AccessType, &session.SharedByEmail, &session.OwnerEmail,
-		); err != nil {
+		}
+		if withRank {
+			dest = append(dest, &session.SearchRank, &session.InterestScore)
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return nil, fmt.Errorf("failed to scan session: %w", err)
 		}
 		session.Provider = models.NormalizeProvider(session.Provider)
+		session.ResolveTitle()
 		if gitRepoURL != nil && *gitRepoURL != "" {
 			session.GitRepo = db.ExtractRepoName(*gitRepoURL)
 			session.GitRepoURL = gitRepoURL
@@ -165,6 +177,13 @@ func (s *Store) ListUserSessionsPaginated(ctx context.Context, userID int64, par
 		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
+	if params.IncludeGrowth {
+		if err := s.loadListGrowth(ctx, sessions); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+	}
 
 	span.SetAttributes(
 		attribute.Int("sessions.count", len(sessions)),
@@ -212,7 +231,7 @@ func (s *Store) queryFilterOptions(ctx context.Context, userID int64) (db.Sessio
 	`
 
 	var repos, branches, owners []string
-	err := s.conn().QueryRowContext(ctx, query, userID).Scan(pq.Array(&repos), pq.Array(&branches), pq.Array(&owners))
+	err := s.DB.ReadConn(ctx).QueryRowContext(ctx, query, userID).Scan(pq.Array(&repos), pq.Array(&branches), pq.Array(&owners))
 	if err != nil {
 		return db.SessionFilterOptions{}, fmt.Errorf("failed to query scoped filter options: %w", err)
 	}
@@ -224,6 +243,56 @@ func (s *Store) queryFilterOptions(ctx context.Context, userID int64) (db.Sessio
 	}, nil
 }
 
+// encodeRankCursor is the keyset cursor for search results, which are
+// ordered by relevance first: `rank|RFC3339Nano|UUID`.
+func encodeRankCursor(rank float64, t time.Time, id string) string {
+	raw := strconv.FormatFloat(rank, 'g', -1, 64) + "|" + t.Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeRankCursor(cursor string) (float64, time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, time.Time{}, "", fmt.Errorf("invalid cursor encoding: %w", err)
+	}
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) != 3 {
+		return 0, time.Time{}, "", fmt.Errorf("invalid cursor format")
+	}
+	rank, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, time.Time{}, "", fmt.Errorf("invalid cursor rank: %w", err)
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return 0, time.Time{}, "", fmt.Errorf("invalid cursor time: %w", err)
+	}
+	return rank, t, parts[2], nil
+}
+
+// encodeInterestCursor is the keyset cursor for sort=interesting: the last
+// row's interest_score and ID.
+func encodeInterestCursor(score float64, id string) string {
+	raw := strconv.FormatFloat(score, 'g', -1, 64) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeInterestCursor(cursor string) (float64, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid cursor encoding: %w", err)
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("invalid cursor format")
+	}
+	score, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid cursor score: %w", err)
+	}
+	return score, parts[1], nil
+}
+
 func encodeCursor(t time.Time, id string) string {
 	raw := t.Format(time.RFC3339Nano) + "|" + id
 	return base64.RawURLEncoding.EncodeToString([]byte(raw))
@@ -248,7 +317,11 @@ func decodeCursor(cursor string) (time.Time, string, error) {
 // buildPushdownFilters returns SQL fragments appended to the outer query.
 // CF-495: owner filter now applies uniformly to d.owner_email (post-dedup
 // from db.VisibleSessionsCTE), so the historical owned/shared split is gone.
-func buildPushdownFilters(pb *paramBuilder, params db.SessionListParams) (commonFilters, ownerFilter, searchJoin string) {
+//
+// For a full-text query, rankExpr is the session's ts_rank_cd relevance under
+// weights (0 when only a commit-SHA or ID prefix matched); it is empty when
+// there is nothing to rank.
+func buildPushdownFilters(pb *paramBuilder, params db.SessionListParams, weights db.SearchWeights) (commonFilters, ownerFilter, searchJoin, rankExpr string) {
 	// 0407: the listability gate is the shared db.ListableSessionPredicate so
 	// the list and the filter-option dropdowns can never drift. The sf_stats
 	// join is still used for the SELECT columns (file_count / total_lines).
@@ -266,6 +339,17 @@ func buildPushdownFilters(pb *paramBuilder, params db.SessionListParams) (common
 		p := pb.addArray(models.ExpandWithAliases(params.Providers))
 		commonFilters += "\n\t\t\t\tAND s.session_type = ANY(" + p + ")"
 	}
+	if len(params.States) > 0 {
+		p := pb.addArray(params.States)
+		commonFilters += "\n\t\t\t\tAND s.state = ANY(" + p + ")"
+	}
+	if params.OutOfTreeAccess != nil {
+		if *params.OutOfTreeAccess {
+			commonFilters += "\n\t\t\t\tAND " + outOfTreeAccessExpr
+		} else {
+			commonFilters += "\n\t\t\t\tAND NOT " + outOfTreeAccessExpr
+		}
+	}
 	if len(params.Owners) > 0 {
 		p := pb.addArray(lowercaseSlice(params.Owners))
 		ownerFilter = "\n\t\t\t\tAND LOWER(d.owner_email) = ANY(" + p + ")"
@@ -280,6 +364,8 @@ func buildPushdownFilters(pb *paramBuilder, params db.SessionListParams) (common
 			tsqueryParam := pb.add(tsquery)
 			rawQueryParam := pb.add(*params.Query)
 			searchJoin = "\n\t\t\tLEFT JOIN session_search_index ssi ON s.id = ssi.session_id"
+			weightsParam := pb.add(pq.Array(weights.RankArray()))
+			rankExpr = "COALESCE(ts_rank_cd(" + weightsParam + "::real[], ssi.search_vector, to_tsquery('english', " + tsqueryParam + ")), 0)"
 			searchPredicate := "ssi.search_vector @@ to_tsquery('english', " + tsqueryParam + ")" +
 				" OR EXISTS (SELECT 1 FROM session_github_links sgl WHERE sgl.session_id = s.id AND sgl.link_type = 'commit' AND LOWER(sgl.ref) LIKE LOWER(" + rawQueryParam + ")||'%')"
 			// CF-573: also match session identifiers by prefix — the confab UUID
@@ -328,17 +414,23 @@ func BuildPrefixTsquery(query string) string {
 // tokens_v2 card (session_card_tokens_v2) via the shared db.V2TotalCostExpr
 // fragment (37cg). It is read raw (nullable text) so a session without a v2 card
 // yields a nil cost — the same shape the flat v1 card produced when absent.
+// duplicate_of follows it; both callers bind $1 = viewer userID. The session
+// card's duration_ms comes last.
 var sessionSelectCols = `
 				s.id, s.external_id, s.first_seen,
 				COALESCE(sf_stats.file_count, 0) as file_count,
 				s.last_message_at, s.custom_title, s.suggested_session_title,
-				s.summary, s.first_user_message, s.session_type,
+				s.ai_generated_title, s.summary, s.first_user_message, s.session_type,
 				COALESCE(sf_stats.total_lines, 0) as total_lines,
 				s.git_info->>'repo_url' as git_repo_url,
 				s.git_info->>'branch' as git_branch,
 				COALESCE(gpr.prs, ARRAY[]::text[]) as github_prs,
 				COALESCE(gcr.commits, ARRAY[]::text[]) as github_commits,
-				` + db.V2TotalCostExpr("v")
+				` + db.V2TotalCostExpr("v") + `,
+				` + duplicateOfExpr("s", "$1") + ` as duplicate_of,
+				s.state, s.state_changed_at, s.is_demo,
+				` + outOfTreeAccessExpr + ` as has_out_of_tree_access,
+				sc.duration_ms`
 
 var sessionStatsJoins = `
 			LEFT JOIN (
@@ -347,7 +439,14 @@ var sessionStatsJoins = `
 			) sf_stats ON s.id = sf_stats.session_id
 			LEFT JOIN github_pr_refs gpr ON s.id = gpr.session_id
 			LEFT JOIN github_commit_refs gcr ON s.id = gcr.session_id
-			LEFT JOIN session_card_tokens_v2 v ON s.id = v.session_id`
+			LEFT JOIN session_card_tokens_v2 v ON s.id = v.session_id
+			LEFT JOIN session_card_code_activity ca ON s.id = ca.session_id
+			LEFT JOIN session_card_session sc ON s.id = sc.session_id`
+
+// outOfTreeAccessExpr is true when the session's code activity card counts
+// any file access outside the session cwd (home, temp or external). Sessions
+// without the card read false.
+const outOfTreeAccessExpr = `COALESCE(ca.out_of_tree_home + ca.out_of_tree_temp + ca.out_of_tree_external > 0, FALSE)`
 
 const githubRefCTEs = `
 		github_pr_refs AS (
@@ -360,7 +459,7 @@ const githubRefCTEs = `
 		)`
 
 // dedupedVisibleCTE wraps db.VisibleSessionsCTE with a DISTINCT ON (id) pass
-// that picks the highest-priority access_type per session: owner > private_share > system_share.
+// that picks the highest-priority access_type per session: owner > private_share > system_share > org_share.
 // The aliased columns become d.id, d.access_type, d.shared_by_email, d.owner_email
 // for the outer query. Used by every paginated/list query.
 const dedupedVisibleCTE = `
@@ -372,7 +471,8 @@ const dedupedVisibleCTE = `
 				WHEN 'owner' THEN 1
 				WHEN 'private_share' THEN 2
 				WHEN 'system_share' THEN 3
-				ELSE 4
+				WHEN 'org_share' THEN 4
+				ELSE 5
 			END
 		)`
 
@@ -383,9 +483,19 @@ const dedupedVisibleCTE = `
 // helper rather than per-branch CASE expressions.
 func (s *Store) buildFilteredSessionsQuery(userID int64, params db.SessionListParams) (string, []interface{}) {
 	pb := newParamBuilder(userID)
-	commonFilters, ownerFilter, searchJoin := buildPushdownFilters(pb, params)
+	commonFilters, ownerFilter, searchJoin, rankExpr := buildPushdownFilters(pb, params, s.DB.SearchWeights)
 	limitP := pb.add(params.PageSize + 1)
 
+	rankCol := "NULL::real"
+	if rankExpr != "" {
+		rankCol = rankExpr
+	}
+	byInterest := rankExpr == "" && len(params.SortKeys) == 0 && params.Sort == db.SessionSortInteresting
+	interestCol := "NULL::float8"
+	if byInterest {
+		interestCol = "s.interest_score"
+	}
+
 	query := `
 		WITH` + githubRefCTEs + `,
 		` + db.VisibleSessionsCTE(s.DB.ShareAllSessions) + `,` + dedupedVisibleCTE + `
@@ -393,11 +503,65 @@ func (s *Store) buildFilteredSessionsQuery(userID int64, params db.SessionListPa
 				(d.access_type = 'owner') as is_owner,
 				d.access_type,
 				d.shared_by_email,
-				d.owner_email
+				d.owner_email,
+				` + rankCol + ` as search_rank,
+				` + interestCol + ` as interest_score
 			FROM deduped_visible d
 			JOIN sessions s ON d.id = s.id` + sessionStatsJoins + searchJoin + `
 			WHERE 1=1` + commonFilters + ownerFilter
 
+	// Search results are ordered by relevance, then recency; the cursor
+	// carries the rank so keyset pagination follows the same order.
+	if rankExpr != "" {
+		if params.Cursor != "" {
+			cursorRank, cursorTime, cursorID, err := decodeRankCursor(params.Cursor)
+			if err == nil {
+				cursorRankP := pb.add(cursorRank)
+				cursorTimeP := pb.add(cursorTime)
+				cursorIDP := pb.add(cursorID)
+				query += `
+				AND (` + rankExpr + `, COALESCE(s.last_message_at, s.first_seen), s.id) < (` + cursorRankP + `::float8::real, ` + cursorTimeP + `, ` + cursorIDP + `)`
+			}
+		}
+		query += `
+			ORDER BY search_rank DESC, COALESCE(s.last_message_at, s.first_seen) DESC, s.id DESC
+			LIMIT ` + limitP
+		return query, pb.args
+	}
+
+	// Sort keys: the cursor carries every sort column's value.
+	if sortCols := sortColumnsFor(params.SortKeys); len(sortCols) > 0 {
+		if params.Cursor != "" {
+			values, cursorID, err := decodeSortCursor(params.Cursor, sortCols)
+			if err == nil {
+				query += `
+				AND ` + sortKeysetPredicate(pb, sortCols, values, cursorID)
+			}
+		}
+		query += `
+			ORDER BY ` + sortOrderBy(sortCols) + `
+			LIMIT ` + limitP
+		return query, pb.args
+	}
+
+	// sort=interesting walks idx_sessions_interest_score; the cursor carries
+	// the score.
+	if byInterest {
+		if params.Cursor != "" {
+			cursorScore, cursorID, err := decodeInterestCursor(params.Cursor)
+			if err == nil {
+				cursorScoreP := pb.add(cursorScore)
+				cursorIDP := pb.add(cursorID)
+				query += `
+				AND (s.interest_score, s.id) < (` + cursorScoreP + `::float8, ` + cursorIDP + `)`
+			}
+		}
+		query += `
+			ORDER BY s.interest_score DESC, s.id DESC
+			LIMIT ` + limitP
+		return query, pb.args
+	}
+
 	if params.Cursor != "" {
 		cursorTime, cursorID, err := decodeCursor(params.Cursor)
 		if err == nil {
@@ -415,16 +579,20 @@ func (s *Store) buildFilteredSessionsQuery(userID int64, params db.SessionListPa
 	return query, pb.args
 }
 
+// queryPaginatedSessions runs the filtered list query. Like the filter
+// options, it runs on the read replica when one is configured
+// (db.DB.ReadConn); a session synced or changed a moment ago may show its
+// previous state for up to DB_REPLICA_MAX_LAG_MS.
 func (s *Store) queryPaginatedSessions(ctx context.Context, userID int64, params db.SessionListParams) ([]db.SessionListItem, bool, string, error) {
 	query, args := s.buildFilteredSessionsQuery(userID, params)
 
-	rows, err := s.conn().QueryContext(ctx, query, args...)
+	rows, err := s.DB.ReadConn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, false, "", fmt.Errorf("failed to query paginated sessions: %w", err)
 	}
 	defer rows.Close()
 
-	sessions, err := scanSessionListItems(rows)
+	sessions, err := scanSessionListItems(rows, true)
 	if err != nil {
 		return nil, false, "", err
 	}
@@ -441,13 +609,70 @@ func (s *Store) queryPaginatedSessions(ctx context.Context, userID int64, params
 		if last.LastSyncTime != nil {
 			cursorTime = *last.LastSyncTime
 		}
-		nextCursor = encodeCursor(cursorTime, last.ID)
+		if last.SearchRank != nil {
+			nextCursor = encodeRankCursor(*last.SearchRank, cursorTime, last.ID)
+		} else if sortCols := sortColumnsFor(params.SortKeys); len(sortCols) > 0 {
+			nextCursor = encodeSortCursor(sortCols, last)
+		} else if last.InterestScore != nil {
+			nextCursor = encodeInterestCursor(*last.InterestScore, last.ID)
+		} else {
+			nextCursor = encodeCursor(cursorTime, last.ID)
+		}
 	}
 
 	return sessions, hasMore, nextCursor, nil
 }
 
-// GetSessionDetail returns detailed information about a session by its UUID primary key
+// loadListGrowth fills Growth for a page of sessions from each one's
+// transcript file; a session with several transcript files uses the longest.
+// It reads from the same pool as the page (db.DB.ReadConn). Sessions without
+// a transcript keep a nil Growth.
+func (s *Store) loadListGrowth(ctx context.Context, sessions []db.SessionListItem) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+	ids := make([]string, len(sessions))
+	for i, sess := range sessions {
+		ids[i] = sess.ID
+	}
+
+	rows, err := s.DB.ReadConn(ctx).QueryContext(ctx, `
+		SELECT DISTINCT ON (session_id) session_id, growth
+		FROM sync_files
+		WHERE session_id = ANY($1) AND file_type = 'transcript'
+		ORDER BY session_id, last_synced_line DESC`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to query session growth: %w", err)
+	}
+	defer rows.Close()
+
+	growth := make(map[string][]db.GrowthPoint, len(sessions))
+	for rows.Next() {
+		var id string
+		var raw []byte
+		if err := rows.Scan(&id, &raw); err != nil {
+			return fmt.Errorf("failed to scan session growth: %w", err)
+		}
+		points, err := db.UnmarshalGrowth(raw)
+		if err != nil {
+			return err
+		}
+		growth[id] = points
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating session growth: %w", err)
+	}
+
+	for i := range sessions {
+		sessions[i].Growth = growth[sessions[i].ID]
+	}
+	return nil
+}
+
+// GetSessionDetail returns detailed information about a session by its UUID
+// primary key. It reads from the replica when one is usable (db.DB.ReadConn),
+// retrying on the primary when the replica has no such session yet; callers
+// returning a session they just changed pass a db.WithPrimary context.
 func (s *Store) GetSessionDetail(ctx context.Context, sessionID string, userID int64) (*db.SessionDetail, error) {
 	ctx, span := tracer.Start(ctx, "db.get_session_detail",
 		trace.WithAttributes(
@@ -467,9 +692,17 @@ func (s *Store) GetSessionDetail(ctx context.Context, sessionID string, userID i
 		JOIN users u ON s.user_id = u.id
 		WHERE s.id = $1 AND s.user_id = $2
 	`
-	err := s.conn().QueryRowContext(ctx, sessionQuery, sessionID, userID).Scan(
+	conn := s.DB.ReadConn(ctx)
+	err := conn.QueryRowContext(ctx, sessionQuery, sessionID, userID).Scan(
 		db.SessionDetailScanTargets(&session, &gitInfoBytes)...,
 	)
+	if errors.Is(err, sql.ErrNoRows) && !s.DB.IsPrimary(conn) {
+		// Created within the replica lag.
+		conn = s.conn()
+		err = conn.QueryRowContext(ctx, sessionQuery, sessionID, userID).Scan(
+			db.SessionDetailScanTargets(&session, &gitInfoBytes)...,
+		)
+	}
 	if err != nil {
 		if err == sql.ErrNoRows || db.IsInvalidUUIDError(err) {
 			return nil, db.ErrSessionNotFound
@@ -479,13 +712,14 @@ func (s *Store) GetSessionDetail(ctx context.Context, sessionID string, userID i
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 	session.Provider = models.NormalizeProvider(session.Provider)
+	session.ResolveTitle()
 
 	if err := db.UnmarshalSessionGitInfo(&session, gitInfoBytes); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
-	if err := db.LoadSessionSyncFiles(ctx, s.DB, &session); err != nil {
+	if err := db.LoadSessionSyncFiles(ctx, conn, &session); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		return nil, err
@@ -503,7 +737,37 @@ func (s *Store) DeleteSessionFromDB(ctx context.Context, sessionID string, userI
 		))
 	defer span.End()
 
-	result, err := s.conn().ExecContext(ctx, `DELETE FROM sessions WHERE id = $1 AND user_id = $2`, sessionID, userID)
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var owned bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM sessions WHERE id = $1 AND user_id = $2)`, sessionID, userID).Scan(&owned)
+	if err != nil {
+		if db.IsInvalidUUIDError(err) {
+			return db.ErrSessionNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if !owned {
+		return db.ErrSessionNotFound
+	}
+
+	// Log the deletion before the row (and its state) is gone.
+	if _, err := transitionStates(ctx, tx, []string{sessionID}, StateDeleted, StateReasonDelete); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to update session state: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1 AND user_id = $2`, sessionID, userID)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -515,6 +779,12 @@ func (s *Store) DeleteSessionFromDB(ctx context.Context, sessionID string, userI
 		return db.ErrSessionNotFound
 	}
 
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	s.DB.SessionOwners.Invalidate(sessionID)
 	return nil
 }
 
@@ -529,6 +799,15 @@ func (s *Store) VerifySessionOwnership(ctx context.Context, sessionID string, us
 		))
 	defer span.End()
 
+	// A cached owner only short-circuits a match; anyone else is checked
+	// against the database, so a stale entry can never grant access.
+	owners := s.DB.SessionOwners
+	if owner, ok := owners.Get(sessionID); ok && owner.UserID == userID {
+		span.SetAttributes(attribute.String("result", "owner"), attribute.Bool("cache.hit", true))
+		return owner.ExternalID, owner.Provider, nil
+	}
+	epoch := owners.Epoch()
+
 	query := `SELECT external_id, session_type FROM sessions WHERE id = $1 AND user_id = $2`
 	err = s.conn().QueryRowContext(ctx, query, sessionID, userID).Scan(&externalID, &provider)
 	if err == sql.ErrNoRows {
@@ -556,6 +835,7 @@ func (s *Store) VerifySessionOwnership(ctx context.Context, sessionID string, us
 		return "", "", fmt.Errorf("failed to verify session ownership: %w", err)
 	}
 	provider = models.NormalizeProvider(provider)
+	owners.Put(sessionID, db.SessionOwner{UserID: userID, ExternalID: externalID, Provider: provider}, epoch)
 	span.SetAttributes(
 		attribute.String("result", "owner"),
 		attribute.String("session.provider", provider),
@@ -637,6 +917,88 @@ func (s *Store) UpdateSessionCustomTitle(ctx context.Context, sessionID string,
 	return nil
 }
 
+// UpdateSessionPRURL sets the session's pr_url, or clears it when prURL is
+// nil. The caller normalizes the URL (validation.NormalizePRURL).
+// detected_pr_url is left alone. Returns db.ErrForbidden when the session
+// belongs to another user.
+func (s *Store) UpdateSessionPRURL(ctx context.Context, sessionID string, userID int64, prURL *string) error {
+	ctx, span := tracer.Start(ctx, "db.update_session_pr_url",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.Int64("user.id", userID),
+		))
+	defer span.End()
+
+	query := `UPDATE sessions SET pr_url = $1 WHERE id = $2 AND user_id = $3`
+	result, err := s.conn().ExecContext(ctx, query, prURL, sessionID, userID)
+	if err != nil {
+		if db.IsInvalidUUIDError(err) {
+			return db.ErrSessionNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to update session pr_url: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		var exists bool
+		checkQuery := `SELECT EXISTS(SELECT 1 FROM sessions WHERE id = $1)`
+		if checkErr := s.conn().QueryRowContext(ctx, checkQuery, sessionID).Scan(&exists); checkErr != nil {
+			return fmt.Errorf("failed to check session existence: %w", checkErr)
+		}
+		if exists {
+			return db.ErrForbidden
+		}
+		return db.ErrSessionNotFound
+	}
+	return nil
+}
+
+// UpdateSessionVisibility sets the session's visibility (the caller
+// validates it with db.IsValidSessionVisibility). Returns db.ErrForbidden
+// when the session belongs to another user.
+func (s *Store) UpdateSessionVisibility(ctx context.Context, sessionID string, userID int64, visibility string) error {
+	ctx, span := tracer.Start(ctx, "db.update_session_visibility",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.Int64("user.id", userID),
+			attribute.String("session.visibility", visibility),
+		))
+	defer span.End()
+
+	query := `UPDATE sessions SET visibility = $1 WHERE id = $2 AND user_id = $3`
+	result, err := s.conn().ExecContext(ctx, query, visibility, sessionID, userID)
+	if err != nil {
+		if db.IsInvalidUUIDError(err) {
+			return db.ErrSessionNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to update session visibility: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		var exists bool
+		checkQuery := `SELECT EXISTS(SELECT 1 FROM sessions WHERE id = $1)`
+		if checkErr := s.conn().QueryRowContext(ctx, checkQuery, sessionID).Scan(&exists); checkErr != nil {
+			return fmt.Errorf("failed to check session existence: %w", checkErr)
+		}
+		if exists {
+			return db.ErrForbidden
+		}
+		return db.ErrSessionNotFound
+	}
+	return nil
+}
+
 // UpdateSessionSuggestedTitle updates the suggested_session_title field for a session.
 func (s *Store) UpdateSessionSuggestedTitle(ctx context.Context, sessionID string, suggestedTitle string) error {
 	ctx, span := tracer.Start(ctx, "db.update_session_suggested_title",
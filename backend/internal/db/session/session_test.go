@@ -1466,6 +1466,84 @@ func TestListUserSessionsPaginated_QuerySearch(t *testing.T) {
 	}
 }
 
+// TestListUserSessionsPaginated_QuerySearchRanking checks that search results
+// are ordered by which weighted index component matched, not by recency, that
+// the cursor pages through them in that order, and that the weights are
+// configurable.
+func TestListUserSessionsPaginated_QuerySearchRanking(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	defer func() { env.DB.SearchWeights = db.SearchWeights{} }()
+
+	user := testutil.CreateTestUser(t, env, "ranking@test.com", "Ranking User")
+	seed := func(extID string, age time.Duration, metadata, recap, messages string) string {
+		id := testutil.CreateTestSessionFull(t, env, user.ID, extID, testutil.TestSessionFullOpts{Summary: extID})
+		if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET last_message_at = $2 WHERE id = $1`,
+			id, time.Now().UTC().Add(-age)); err != nil {
+			t.Fatalf("set last_message_at: %v", err)
+		}
+		testutil.CreateTestWeightedSearchIndex(t, env, id, metadata, recap, messages, 100)
+		return id
+	}
+	// Recency runs opposite to match quality.
+	inMetadata := seed("in-metadata", 3*time.Hour, "kubernetes rollout", "", "")
+	inRecap := seed("in-recap", 2*time.Hour, "", "kubernetes rollout", "")
+	inMessages := seed("in-messages", time.Hour, "", "", "kubernetes rollout")
+
+	ctx := context.Background()
+	q := "kubernetes"
+	var got []string
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		result, err := store.ListUserSessionsPaginated(ctx, user.ID, db.SessionListParams{Query: &q, Cursor: cursor, PageSize: 1})
+		if err != nil {
+			t.Fatalf("ListUserSessionsPaginated: %v", err)
+		}
+		for _, s := range result.Sessions {
+			if s.SearchRank == nil || *s.SearchRank <= 0 {
+				t.Errorf("session %s: search_rank = %v, want > 0", s.ExternalID, s.SearchRank)
+			}
+			got = append(got, s.ID)
+		}
+		if !result.HasMore {
+			break
+		}
+		cursor = result.NextCursor
+	}
+	want := []string{inMetadata, inRecap, inMessages}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("default weights order = %v, want metadata, recap, messages %v", got, want)
+	}
+
+	env.DB.SearchWeights = db.SearchWeights{A: 0.1, B: 0.5, C: 1.0}
+	result, err := store.ListUserSessionsPaginated(ctx, user.ID, db.SessionListParams{Query: &q})
+	if err != nil {
+		t.Fatalf("ListUserSessionsPaginated: %v", err)
+	}
+	got = got[:0]
+	for _, s := range result.Sessions {
+		got = append(got, s.ID)
+	}
+	want = []string{inMessages, inRecap, inMetadata}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("inverted weights order = %v, want messages, recap, metadata %v", got, want)
+	}
+
+	// Plain listing stays chronological and carries no rank.
+	result, err = store.ListUserSessionsPaginated(ctx, user.ID, db.SessionListParams{})
+	if err != nil {
+		t.Fatalf("ListUserSessionsPaginated: %v", err)
+	}
+	if len(result.Sessions) != 3 || result.Sessions[0].ID != inMessages || result.Sessions[0].SearchRank != nil {
+		t.Errorf("unfiltered list should be newest first without search_rank, got %+v", result.Sessions)
+	}
+}
+
 // TestListUserSessionsPaginated_IDSearch tests CF-573: searching by confab ID
 // (sessions.id UUID) or external session ID (sessions.external_id) returns the
 // matching session, via full value or prefix, gated by a 4-char minimum length.
@@ -0,0 +1,184 @@
+package session
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// sortColumnKind is the Go type of a sort column's value in a sort cursor.
+type sortColumnKind int
+
+const (
+	sortTime sortColumnKind = iota
+	sortFloat
+	sortBool
+)
+
+// sortColumn is one ORDER BY column of a db.SessionSortKeys key. value reads
+// the column's value back from a scanned row, so a cursor can carry it.
+type sortColumn struct {
+	expr  string // SQL over the list query's aliases; never NULL
+	desc  bool
+	kind  sortColumnKind
+	value func(db.SessionListItem) any
+}
+
+// Card-backed keys sort on a (missing, value) pair: missing is false for
+// sessions with the card, so uncomputed sessions come last in either
+// direction, and value is 0 for them.
+var (
+	sortCostExpr     = "(" + db.V2TotalCostExpr("v") + ")::float8"
+	sortDurationExpr = "sc.duration_ms::float8"
+)
+
+func costMissing(item db.SessionListItem) any { return item.EstimatedCostUSD == nil }
+
+func costValue(item db.SessionListItem) any {
+	if item.EstimatedCostUSD == nil {
+		return 0.0
+	}
+	cost, _ := strconv.ParseFloat(*item.EstimatedCostUSD, 64)
+	return cost
+}
+
+func durationMissing(item db.SessionListItem) any { return item.DurationMs == nil }
+
+func durationValue(item db.SessionListItem) any {
+	if item.DurationMs == nil {
+		return 0.0
+	}
+	return float64(*item.DurationMs)
+}
+
+// sessionSortColumns maps each sort key to its ORDER BY columns.
+var sessionSortColumns = map[string][]sortColumn{
+	db.SessionSortCreatedAtAsc: {
+		{expr: "s.first_seen", kind: sortTime, value: func(i db.SessionListItem) any { return i.FirstSeen }},
+	},
+	db.SessionSortCreatedAtDesc: {
+		{expr: "s.first_seen", desc: true, kind: sortTime, value: func(i db.SessionListItem) any { return i.FirstSeen }},
+	},
+	db.SessionSortCostDesc: {
+		{expr: sortCostExpr + " IS NULL", kind: sortBool, value: costMissing},
+		{expr: "COALESCE(" + sortCostExpr + ", 0)", desc: true, kind: sortFloat, value: costValue},
+	},
+	db.SessionSortCostAsc: {
+		{expr: sortCostExpr + " IS NULL", kind: sortBool, value: costMissing},
+		{expr: "COALESCE(" + sortCostExpr + ", 0)", kind: sortFloat, value: costValue},
+	},
+	db.SessionSortDurationDesc: {
+		{expr: sortDurationExpr + " IS NULL", kind: sortBool, value: durationMissing},
+		{expr: "COALESCE(" + sortDurationExpr + ", 0)", desc: true, kind: sortFloat, value: durationValue},
+	},
+	db.SessionSortDurationAsc: {
+		{expr: sortDurationExpr + " IS NULL", kind: sortBool, value: durationMissing},
+		{expr: "COALESCE(" + sortDurationExpr + ", 0)", kind: sortFloat, value: durationValue},
+	},
+	db.SessionSortLineCountDesc: {
+		{expr: "COALESCE(sf_stats.total_lines, 0)::float8", desc: true, kind: sortFloat, value: func(i db.SessionListItem) any { return float64(i.TotalLines) }},
+	},
+}
+
+// sortColumnsFor expands sort keys into their columns. Unknown keys are
+// skipped; the handler validates them.
+func sortColumnsFor(keys []string) []sortColumn {
+	var cols []sortColumn
+	for _, key := range keys {
+		cols = append(cols, sessionSortColumns[key]...)
+	}
+	return cols
+}
+
+// sortOrderBy is the ORDER BY list for cols, with s.id DESC breaking ties.
+func sortOrderBy(cols []sortColumn) string {
+	terms := make([]string, 0, len(cols)+1)
+	for _, c := range cols {
+		dir := "ASC"
+		if c.desc {
+			dir = "DESC"
+		}
+		terms = append(terms, c.expr+" "+dir)
+	}
+	return strings.Join(append(terms, "s.id DESC"), ", ")
+}
+
+// sortKeysetPredicate matches the rows after the cursor row in sortOrderBy
+// order. Columns may run in different directions, so it is spelled out as
+// "first column past the cursor, or equal and the next column past it, ...",
+// ending with the s.id tie-break.
+func sortKeysetPredicate(pb *paramBuilder, cols []sortColumn, values []any, id string) string {
+	var alternatives, equal []string
+	for i, c := range cols {
+		p := pb.add(values[i])
+		switch c.kind {
+		case sortFloat:
+			p += "::float8"
+		case sortBool:
+			p += "::boolean"
+		}
+		op := ">"
+		if c.desc {
+			op = "<"
+		}
+		alternatives = append(alternatives, "("+strings.Join(append(equal[:len(equal):len(equal)], c.expr+" "+op+" "+p), " AND ")+")")
+		equal = append(equal, c.expr+" = "+p)
+	}
+	idP := pb.add(id)
+	alternatives = append(alternatives, "("+strings.Join(append(equal, "s.id < "+idP+"::uuid"), " AND ")+")")
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+// encodeSortCursor encodes the last row's sort column values and ID.
+func encodeSortCursor(cols []sortColumn, last db.SessionListItem) string {
+	values := make([]any, 0, len(cols)+1)
+	for _, c := range cols {
+		values = append(values, c.value(last))
+	}
+	raw, _ := json.Marshal(append(values, last.ID))
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeSortCursor decodes a cursor from encodeSortCursor for the same
+// columns. A cursor from another ordering fails to decode.
+func decodeSortCursor(cursor string, cols []sortColumn) ([]any, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid cursor encoding: %w", err)
+	}
+	var parts []json.RawMessage
+	if err := json.Unmarshal(raw, &parts); err != nil || len(parts) != len(cols)+1 {
+		return nil, "", fmt.Errorf("invalid cursor format")
+	}
+	values := make([]any, len(cols))
+	for i, c := range cols {
+		var err error
+		switch c.kind {
+		case sortTime:
+			var t time.Time
+			err = json.Unmarshal(parts[i], &t)
+			values[i] = t
+		case sortFloat:
+			var f float64
+			err = json.Unmarshal(parts[i], &f)
+			values[i] = f
+		case sortBool:
+			var b bool
+			err = json.Unmarshal(parts[i], &b)
+			values[i] = b
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid cursor value %d: %w", i, err)
+		}
+	}
+	var id string
+	if err := json.Unmarshal(parts[len(cols)], &id); err != nil {
+		return nil, "", fmt.Errorf("invalid cursor id: %w", err)
+	}
+	return values, id, nil
+}
@@ -0,0 +1,88 @@
+package session_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestListUserSessionsPaginated_SortKeysPages walks every key in small pages:
+// the cursor must continue exactly where the previous page stopped, across
+// ties and uncarded sessions.
+func TestListUserSessionsPaginated_SortKeysPages(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "sortpages@test.com", "Sort Pages")
+	for i := range 7 {
+		id := testutil.CreateTestSessionFull(t, env, user.ID, fmt.Sprintf("s%d", i), testutil.TestSessionFullOpts{Summary: "s", SyncLines: 10 * (i%3 + 1)})
+		// Pairs share a start time, a cost and a duration, so every key ties.
+		if _, err := env.DB.Exec(ctx, `UPDATE sessions SET first_seen = $2 WHERE id = $1`, id, fmt.Sprintf("2026-01-0%d", i/2+1)); err != nil {
+			t.Fatalf("set first_seen: %v", err)
+		}
+		if i%3 == 2 {
+			continue // no cards
+		}
+		if _, err := env.DB.Exec(ctx, `
+			INSERT INTO session_card_session (session_id, version, computed_at, up_to_line, duration_ms)
+			VALUES ($1, $2, now(), 100, $3)`, id, analytics.SessionCardVersion, int64(i/2)*1000); err != nil {
+			t.Fatalf("insert session card: %v", err)
+		}
+		testutil.SeedTokensV2Card(t, env, id, analytics.TokensV2Data{TotalCostUSD: fmt.Sprintf("%d.25", i/2)})
+	}
+
+	ids := func(items []db.SessionListItem) []string {
+		out := make([]string, len(items))
+		for i, item := range items {
+			out[i] = item.ID
+		}
+		return out
+	}
+
+	for _, keys := range [][]string{
+		{db.SessionSortCreatedAtAsc}, {db.SessionSortCreatedAtDesc},
+		{db.SessionSortCostDesc}, {db.SessionSortCostAsc},
+		{db.SessionSortDurationDesc}, {db.SessionSortDurationAsc},
+		{db.SessionSortLineCountDesc},
+		{db.SessionSortCostAsc, db.SessionSortLineCountDesc, db.SessionSortCreatedAtDesc},
+	} {
+		t.Run(fmt.Sprint(keys), func(t *testing.T) {
+			all, err := store.ListUserSessionsPaginated(ctx, user.ID, db.SessionListParams{SortKeys: keys})
+			if err != nil {
+				t.Fatalf("ListUserSessionsPaginated: %v", err)
+			}
+			if len(all.Sessions) != 7 {
+				t.Fatalf("got %d sessions, want 7", len(all.Sessions))
+			}
+
+			var paged []db.SessionListItem
+			params := db.SessionListParams{SortKeys: keys, PageSize: 2}
+			for range 10 {
+				page, err := store.ListUserSessionsPaginated(ctx, user.ID, params)
+				if err != nil {
+					t.Fatalf("ListUserSessionsPaginated: %v", err)
+				}
+				paged = append(paged, page.Sessions...)
+				if !page.HasMore {
+					break
+				}
+				params.Cursor = page.NextCursor
+			}
+			if !slices.Equal(ids(paged), ids(all.Sessions)) {
+				t.Errorf("paged order %v, want %v", ids(paged), ids(all.Sessions))
+			}
+		})
+	}
+}
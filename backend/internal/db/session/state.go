@@ -0,0 +1,306 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// Session lifecycle states, stored in sessions.state.
+const (
+	StateActive      = "active"       // chunks synced recently
+	StateIdle        = "idle"         // no sync for the worker's idle window
+	StateEnded       = "ended"        // no sync for the worker's ended window
+	StateArchived    = "archived"     // raw transcript deleted by transcript retention
+	StateDataMissing = "data_missing" // synced lines but no stored data to read
+	StateDeleted     = "deleted"      // row deleted; only seen in session_state_log
+)
+
+// States lists every session state, in lifecycle order.
+var States = []string{StateActive, StateIdle, StateEnded, StateArchived, StateDataMissing, StateDeleted}
+
+// Reasons recorded in session_state_log.
+const (
+	StateReasonSync                = "sync"                 // chunk upload
+	StateReasonIdleSweep           = "idle_sweep"           // worker idle/ended sweep
+	StateReasonTranscriptRetention = "transcript_retention" // worker transcript archival
+	StateReasonDataMissing         = "data_missing"         // precomputer found no stored data
+	StateReasonDelete              = "delete"               // DELETE /sessions/{id}
+	StateReasonBulkDelete          = "bulk_delete"          // bulk delete job
+	StateReasonMerged              = "merged"               // merged into another session
+)
+
+// stateTransitions is the allowed-transition matrix: the states each state
+// may move to. A sync revives idle, ended and data_missing sessions; archived
+// sessions no longer accept uploads, so only deletion leaves that state, and
+// nothing leaves deleted.
+var stateTransitions = map[string][]string{
+	StateActive:      {StateIdle, StateEnded, StateArchived, StateDataMissing, StateDeleted},
+	StateIdle:        {StateActive, StateEnded, StateArchived, StateDataMissing, StateDeleted},
+	StateEnded:       {StateActive, StateArchived, StateDataMissing, StateDeleted},
+	StateDataMissing: {StateActive, StateArchived, StateDeleted},
+	StateArchived:    {StateDeleted},
+	StateDeleted:     {},
+}
+
+// ValidState reports whether state is a known session state.
+func ValidState(state string) bool {
+	_, ok := stateTransitions[state]
+	return ok
+}
+
+// CanTransition reports whether a session in state from may move to state to.
+// Staying in the same state is not a transition.
+func CanTransition(from, to string) bool {
+	for _, s := range stateTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedFrom returns the states that may move to state to.
+func allowedFrom(to string) []string {
+	var from []string
+	for _, s := range States {
+		if CanTransition(s, to) {
+			from = append(from, s)
+		}
+	}
+	return from
+}
+
+// StateLogEntry is one row of session_state_log.
+type StateLogEntry struct {
+	ID        int64     `json:"id"`
+	SessionID string    `json:"session_id"`
+	FromState string    `json:"from_state"`
+	ToState   string    `json:"to_state"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// stateQuerier is satisfied by both *sql.DB and *sql.Tx, so transitions can
+// join a caller's transaction.
+type stateQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// transitionStates is the single place session state changes: it moves every
+// session in sessionIDs whose current state may go to `to`, stamps
+// state_changed_at and appends a session_state_log row per change, all in one
+// statement. Sessions already in `to`, missing, or in a state that can't move
+// there are skipped. The candidate rows are locked first, so a concurrent
+// transition is re-checked against the state it committed. Returns the IDs
+// that changed.
+func transitionStates(ctx context.Context, q stateQuerier, sessionIDs []string, to, reason string) ([]string, error) {
+	if len(sessionIDs) == 0 {
+		return nil, nil
+	}
+	if !ValidState(to) {
+		return nil, fmt.Errorf("unknown session state %q", to)
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		WITH prev AS (
+			SELECT id, user_id, state
+			FROM sessions
+			WHERE id = ANY($1::uuid[]) AND state = ANY($3)
+			FOR UPDATE
+		), changed AS (
+			UPDATE sessions s
+			SET state = $2, state_changed_at = NOW()
+			FROM prev
+			WHERE s.id = prev.id
+			RETURNING s.id, s.user_id, prev.state AS from_state
+		)
+		INSERT INTO session_state_log (session_id, user_id, from_state, to_state, reason)
+		SELECT id, user_id, from_state, $2, $4 FROM changed
+		RETURNING session_id`,
+		pq.Array(sessionIDs), to, pq.Array(allowedFrom(to)), reason)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changed []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		changed = append(changed, id)
+	}
+	return changed, rows.Err()
+}
+
+// TransitionState moves a session to state `to`, recording reason in
+// session_state_log. Returns false without error when the session is already
+// in that state, db.ErrSessionNotFound when it doesn't exist, and an error
+// wrapping db.ErrInvalidStateTransition when the matrix forbids the change.
+func (s *Store) TransitionState(ctx context.Context, sessionID, to, reason string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "db.transition_session_state",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.String("state.to", to),
+			attribute.String("state.reason", reason),
+		))
+	defer span.End()
+
+	changed, err := transitionStates(ctx, s.conn(), []string{sessionID}, to, reason)
+	if err != nil {
+		if db.IsInvalidUUIDError(err) {
+			return false, db.ErrSessionNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return false, fmt.Errorf("failed to transition session state: %w", err)
+	}
+	if len(changed) > 0 {
+		return true, nil
+	}
+
+	var current string
+	err = s.conn().QueryRowContext(ctx, `SELECT state FROM sessions WHERE id = $1`, sessionID).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, db.ErrSessionNotFound
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return false, fmt.Errorf("failed to get session state: %w", err)
+	}
+	if current == to {
+		return false, nil
+	}
+	return false, fmt.Errorf("%w: %s to %s", db.ErrInvalidStateTransition, current, to)
+}
+
+// SweepIdleSessions ends up to limit active or idle sessions whose last sync
+// (or first_seen, if never synced) is older than endedAfter, then marks up to
+// limit active sessions idle past idleAfter. A non-positive window skips that
+// step. Candidates are locked FOR UPDATE SKIP LOCKED, so concurrent workers
+// sweep disjoint sessions. Returns how many sessions went idle and ended.
+func (s *Store) SweepIdleSessions(ctx context.Context, idleAfter, endedAfter time.Duration, limit int) (idled, ended int, err error) {
+	ctx, span := tracer.Start(ctx, "db.sweep_idle_sessions",
+		trace.WithAttributes(
+			attribute.String("sweep.idle_after", idleAfter.String()),
+			attribute.String("sweep.ended_after", endedAfter.String()),
+			attribute.Int("limit", limit),
+		))
+	defer span.End()
+
+	if endedAfter > 0 {
+		ended, err = s.sweepStates(ctx, []string{StateActive, StateIdle}, StateEnded, endedAfter, limit)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return 0, 0, fmt.Errorf("failed to sweep ended sessions: %w", err)
+		}
+	}
+	if idleAfter > 0 {
+		idled, err = s.sweepStates(ctx, []string{StateActive}, StateIdle, idleAfter, limit)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return 0, ended, fmt.Errorf("failed to sweep idle sessions: %w", err)
+		}
+	}
+
+	span.SetAttributes(attribute.Int("sweep.idled", idled), attribute.Int("sweep.ended", ended))
+	return idled, ended, nil
+}
+
+// sweepStates moves up to limit sessions in one of from, unsynced for longer
+// than olderThan, to state to.
+func (s *Store) sweepStates(ctx context.Context, from []string, to string, olderThan time.Duration, limit int) (int, error) {
+	// last_sync_at and first_seen are bare TIMESTAMPs; compare against a UTC
+	// cutoff so the worker's local timezone can't skew the window.
+	cutoff := time.Now().UTC().Add(-olderThan)
+
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM sessions
+		WHERE state = ANY($1) AND COALESCE(last_sync_at, first_seen) < $2
+		ORDER BY COALESCE(last_sync_at, first_seen)
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED`,
+		pq.Array(from), cutoff, limit)
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	changed, err := transitionStates(ctx, tx, ids, to, StateReasonIdleSweep)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return len(changed), nil
+}
+
+// ListStateLog returns a session's state transitions, oldest first. It reads
+// the log only, so the history of a deleted session is still returned.
+func (s *Store) ListStateLog(ctx context.Context, sessionID string) ([]StateLogEntry, error) {
+	ctx, span := tracer.Start(ctx, "db.list_session_state_log",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT id, session_id, from_state, to_state, reason, created_at
+		FROM session_state_log
+		WHERE session_id = $1
+		ORDER BY id`, sessionID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to list session state log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []StateLogEntry
+	for rows.Next() {
+		var e StateLogEntry
+		if err := rows.Scan(&e.ID, &e.SessionID, &e.FromState, &e.ToState, &e.Reason, &e.CreatedAt); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("failed to scan session state log: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to list session state log: %w", err)
+	}
+	return entries, nil
+}
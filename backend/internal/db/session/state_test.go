@@ -0,0 +1,224 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestCanTransition pins every cell of the transition matrix.
+func TestCanTransition(t *testing.T) {
+	allowed := map[string][]string{
+		dbsession.StateActive:      {dbsession.StateIdle, dbsession.StateEnded, dbsession.StateArchived, dbsession.StateDataMissing, dbsession.StateDeleted},
+		dbsession.StateIdle:        {dbsession.StateActive, dbsession.StateEnded, dbsession.StateArchived, dbsession.StateDataMissing, dbsession.StateDeleted},
+		dbsession.StateEnded:       {dbsession.StateActive, dbsession.StateArchived, dbsession.StateDataMissing, dbsession.StateDeleted},
+		dbsession.StateDataMissing: {dbsession.StateActive, dbsession.StateArchived, dbsession.StateDeleted},
+		dbsession.StateArchived:    {dbsession.StateDeleted},
+		dbsession.StateDeleted:     nil,
+	}
+	if len(allowed) != len(dbsession.States) {
+		t.Fatalf("matrix covers %d states, States has %d", len(allowed), len(dbsession.States))
+	}
+
+	for _, from := range dbsession.States {
+		want := make(map[string]bool)
+		for _, to := range allowed[from] {
+			want[to] = true
+		}
+		for _, to := range dbsession.States {
+			if got := dbsession.CanTransition(from, to); got != want[to] {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want[to])
+			}
+		}
+	}
+
+	for _, bad := range []string{"", "paused", "ACTIVE"} {
+		if dbsession.ValidState(bad) {
+			t.Errorf("ValidState(%q) = true", bad)
+		}
+		if dbsession.CanTransition(dbsession.StateActive, bad) || dbsession.CanTransition(bad, dbsession.StateActive) {
+			t.Errorf("CanTransition allows unknown state %q", bad)
+		}
+	}
+}
+
+// setLastSync backdates a session's last sync.
+func setLastSync(t *testing.T, env *testutil.TestEnvironment, sessionID string, ago time.Duration) {
+	t.Helper()
+	if _, err := env.DB.Exec(env.Ctx,
+		`UPDATE sessions SET last_sync_at = $2 WHERE id = $1`, sessionID, time.Now().UTC().Add(-ago)); err != nil {
+		t.Fatalf("set last_sync_at: %v", err)
+	}
+}
+
+func sessionState(t *testing.T, env *testutil.TestEnvironment, sessionID string) string {
+	t.Helper()
+	var state string
+	if err := env.DB.QueryRow(env.Ctx, `SELECT state FROM sessions WHERE id = $1`, sessionID).Scan(&state); err != nil {
+		t.Fatalf("get state: %v", err)
+	}
+	return state
+}
+
+func syncChunk(t *testing.T, store *dbsession.Store, sessionID string, line int) {
+	t.Helper()
+	if err := store.UpdateSyncFileState(context.Background(), sessionID, "transcript.jsonl", "transcript", 0,
+		line, 100, nil, nil, nil, nil, false, nil); err != nil {
+		t.Fatalf("UpdateSyncFileState: %v", err)
+	}
+}
+
+// TestSessionStateLifecycle drives a session through sync → idle → ended →
+// archived → deleted and checks the state, the detail response and the log.
+func TestSessionStateLifecycle(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "state@test.com", "State User")
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "lifecycle")
+	// A recently synced session is left alone by the sweep.
+	other := testutil.CreateTestSession(t, env, user.ID, "recent")
+
+	syncChunk(t, store, sessionID, 10)
+	syncChunk(t, store, other, 10)
+	if got := sessionState(t, env, sessionID); got != dbsession.StateActive {
+		t.Fatalf("after sync state = %s, want active", got)
+	}
+
+	setLastSync(t, env, sessionID, time.Hour)
+	idled, ended, err := store.SweepIdleSessions(ctx, 30*time.Minute, 24*time.Hour, 10)
+	if err != nil || idled != 1 || ended != 0 {
+		t.Fatalf("first sweep = (%d, %d, %v), want one idled", idled, ended, err)
+	}
+	if got := sessionState(t, env, sessionID); got != dbsession.StateIdle {
+		t.Fatalf("after idle sweep state = %s, want idle", got)
+	}
+	if got := sessionState(t, env, other); got != dbsession.StateActive {
+		t.Errorf("recently synced session state = %s, want active", got)
+	}
+
+	setLastSync(t, env, sessionID, 48*time.Hour)
+	idled, ended, err = store.SweepIdleSessions(ctx, 30*time.Minute, 24*time.Hour, 10)
+	if err != nil || idled != 0 || ended != 1 {
+		t.Fatalf("second sweep = (%d, %d, %v), want one ended", idled, ended, err)
+	}
+
+	changed, err := store.TransitionState(ctx, sessionID, dbsession.StateArchived, dbsession.StateReasonTranscriptRetention)
+	if err != nil || !changed {
+		t.Fatalf("archive = (%v, %v), want changed", changed, err)
+	}
+	detail, err := store.GetSessionDetail(ctx, sessionID, user.ID)
+	if err != nil {
+		t.Fatalf("GetSessionDetail: %v", err)
+	}
+	if detail.State != dbsession.StateArchived || time.Since(detail.StateChangedAt) > time.Minute {
+		t.Errorf("detail state = %s changed %v, want archived just now", detail.State, detail.StateChangedAt)
+	}
+
+	// Archived is final short of deletion: a stray sync doesn't revive it and
+	// a direct transition is rejected.
+	syncChunk(t, store, sessionID, 11)
+	if got := sessionState(t, env, sessionID); got != dbsession.StateArchived {
+		t.Errorf("after sync of archived session state = %s, want archived", got)
+	}
+	if _, err := store.TransitionState(ctx, sessionID, dbsession.StateActive, dbsession.StateReasonSync); !errors.Is(err, db.ErrInvalidStateTransition) {
+		t.Errorf("archived → active err = %v, want ErrInvalidStateTransition", err)
+	}
+	// Repeating the current state is a no-op.
+	if changed, err := store.TransitionState(ctx, sessionID, dbsession.StateArchived, dbsession.StateReasonTranscriptRetention); err != nil || changed {
+		t.Errorf("archived → archived = (%v, %v), want a no-op", changed, err)
+	}
+
+	if err := store.DeleteSessionFromDB(ctx, sessionID, user.ID); err != nil {
+		t.Fatalf("DeleteSessionFromDB: %v", err)
+	}
+	if _, err := store.TransitionState(ctx, sessionID, dbsession.StateActive, dbsession.StateReasonSync); !errors.Is(err, db.ErrSessionNotFound) {
+		t.Errorf("transition of deleted session err = %v, want ErrSessionNotFound", err)
+	}
+
+	log, err := store.ListStateLog(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("ListStateLog: %v", err)
+	}
+	want := []struct{ from, to, reason string }{
+		{dbsession.StateActive, dbsession.StateIdle, dbsession.StateReasonIdleSweep},
+		{dbsession.StateIdle, dbsession.StateEnded, dbsession.StateReasonIdleSweep},
+		{dbsession.StateEnded, dbsession.StateArchived, dbsession.StateReasonTranscriptRetention},
+		{dbsession.StateArchived, dbsession.StateDeleted, dbsession.StateReasonDelete},
+	}
+	if len(log) != len(want) {
+		t.Fatalf("state log = %+v, want %d entries", log, len(want))
+	}
+	for i, w := range want {
+		if log[i].FromState != w.from || log[i].ToState != w.to || log[i].Reason != w.reason {
+			t.Errorf("log[%d] = %s → %s (%s), want %s → %s (%s)",
+				i, log[i].FromState, log[i].ToState, log[i].Reason, w.from, w.to, w.reason)
+		}
+	}
+}
+
+// TestSessionStateRevivalAndBulkDelete covers a sync reviving an ended and a
+// data_missing session, and bulk delete logging only the owner's sessions.
+func TestSessionStateRevivalAndBulkDelete(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "state@test.com", "State User")
+	intruder := testutil.CreateTestUser(t, env, "intruder@test.com", "Intruder")
+	ended := testutil.CreateTestSession(t, env, user.ID, "ended")
+	missing := testutil.CreateTestSession(t, env, user.ID, "missing")
+
+	if _, err := store.TransitionState(ctx, ended, dbsession.StateEnded, dbsession.StateReasonIdleSweep); err != nil {
+		t.Fatalf("TransitionState ended: %v", err)
+	}
+	if _, err := store.TransitionState(ctx, missing, dbsession.StateDataMissing, dbsession.StateReasonDataMissing); err != nil {
+		t.Fatalf("TransitionState data_missing: %v", err)
+	}
+	for _, id := range []string{ended, missing} {
+		syncChunk(t, store, id, 5)
+		if got := sessionState(t, env, id); got != dbsession.StateActive {
+			t.Errorf("after sync state = %s, want active", got)
+		}
+	}
+
+	// IDs the caller doesn't own are neither deleted nor logged.
+	if n, err := store.DeleteSessionsFromDB(ctx, intruder.ID, []string{ended, "not-a-uuid"}); err != nil || n != 0 {
+		t.Fatalf("intruder bulk delete = (%d, %v), want nothing", n, err)
+	}
+	if n, err := store.DeleteSessionsFromDB(ctx, user.ID, []string{ended, missing, "not-a-uuid"}); err != nil || n != 2 {
+		t.Fatalf("bulk delete = (%d, %v), want 2", n, err)
+	}
+
+	for _, id := range []string{ended, missing} {
+		log, err := store.ListStateLog(ctx, id)
+		if err != nil {
+			t.Fatalf("ListStateLog: %v", err)
+		}
+		if len(log) != 3 {
+			t.Fatalf("state log = %+v, want 3 entries", log)
+		}
+		if last := log[2]; last.FromState != dbsession.StateActive || last.ToState != dbsession.StateDeleted || last.Reason != dbsession.StateReasonBulkDelete {
+			t.Errorf("last entry = %+v, want active → deleted by bulk_delete", last)
+		}
+		if revived := log[1]; revived.ToState != dbsession.StateActive || revived.Reason != dbsession.StateReasonSync {
+			t.Errorf("revival entry = %+v, want → active by sync", revived)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+// GetUserStorageUsage totals the stored_bytes and chunk_count of the user's
+// sync files and archived generations, and returns up to limit sessions
+// ordered by stored bytes, largest first. Sessions with an archived transcript
+// hold no chunks and are left out.
+func (s *Store) GetUserStorageUsage(ctx context.Context, userID int64, limit int) (*db.UserStorageUsage, error) {
+	ctx, span := tracer.Start(ctx, "db.get_user_storage_usage",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.Int("limit", limit),
+		))
+	defer span.End()
+
+	// Window totals are computed before LIMIT, so every row carries the
+	// user-wide figures.
+	query := `
+		WITH per_session AS (
+			SELECT s.id, s.external_id, s.session_type,
+				s.custom_title, s.ai_generated_title, s.suggested_session_title,
+				s.summary, s.first_user_message,
+				u.stored_bytes, u.chunk_count, u.pending_files
+			FROM sessions s
+			CROSS JOIN LATERAL (
+				SELECT COALESCE(SUM(f.stored_bytes), 0)::bigint AS stored_bytes,
+					COALESCE(SUM(f.chunk_count), 0)::bigint AS chunk_count,
+					COUNT(*) FILTER (WHERE f.stored_bytes IS NULL)::int AS pending_files,
+					COUNT(*) AS files
+				FROM (
+					SELECT stored_bytes, chunk_count FROM sync_files WHERE session_id = s.id
+					UNION ALL
+					SELECT stored_bytes, chunk_count FROM sync_file_generations WHERE session_id = s.id
+				) f
+			) u
+			WHERE s.user_id = $1
+			  AND s.transcript_archived_at IS NULL
+			  AND u.files > 0
+		)
+		SELECT id, external_id, session_type,
+			custom_title, ai_generated_title, suggested_session_title, summary, first_user_message,
+			stored_bytes, chunk_count, pending_files,
+			SUM(stored_bytes) OVER ()::bigint,
+			SUM(chunk_count) OVER ()::bigint,
+			COUNT(*) OVER ()::int,
+			SUM(pending_files) OVER ()::int
+		FROM per_session
+		ORDER BY stored_bytes DESC, id
+		LIMIT $2`
+
+	rows, err := s.conn().QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := &db.UserStorageUsage{Sessions: []db.SessionStorageUsage{}}
+	for rows.Next() {
+		var u db.SessionStorageUsage
+		var customTitle, aiTitle, suggestedTitle, summary, firstUserMessage *string
+		if err := rows.Scan(&u.SessionID, &u.ExternalID, &u.Provider,
+			&customTitle, &aiTitle, &suggestedTitle, &summary, &firstUserMessage,
+			&u.StoredBytes, &u.ChunkCount, &u.PendingFileCount,
+			&usage.TotalBytes, &usage.ChunkCount, &usage.SessionCount, &usage.PendingFileCount); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("failed to scan storage usage: %w", err)
+		}
+		u.Provider = models.NormalizeProvider(u.Provider)
+		u.Title = db.ResolveSessionTitle(customTitle, aiTitle, suggestedTitle, summary, firstUserMessage)
+		usage.Sessions = append(usage.Sessions, u)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+
+	span.SetAttributes(
+		attribute.Int64("storage.total_bytes", usage.TotalBytes),
+		attribute.Int("storage.session_count", usage.SessionCount),
+	)
+	return usage, nil
+}
+
+// StoredBytesBackfill is a session with sync files or archived generations
+// whose stored_bytes is still unknown (synced before storage accounting).
+// The owner, provider and external ID locate its chunks in storage.
+type StoredBytesBackfill struct {
+	SessionID  string
+	UserID     int64
+	Provider   string
+	ExternalID string
+	// Files maps each live file awaiting backfill to its updated_at when
+	// listed; a file that syncs again before the backfill lands keeps NULL and
+	// is retried.
+	Files map[string]*time.Time
+	// Generations are the archived generations awaiting backfill. Their
+	// chunks never change once the row exists.
+	Generations []StoredBytesGeneration
+}
+
+// StoredBytesGeneration names one archived generation of a sync file.
+type StoredBytesGeneration struct {
+	FileName   string
+	Generation int
+}
+
+// ListStoredBytesBackfills returns up to limit sessions that still have sync
+// files or archived generations with unknown stored_bytes, skipping sessions
+// whose transcript was archived. The caller lists each session's chunks
+// after this call and passes their sizes to BackfillStoredBytes.
+func (s *Store) ListStoredBytesBackfills(ctx context.Context, limit int) ([]StoredBytesBackfill, error) {
+	ctx, span := tracer.Start(ctx, "db.list_stored_bytes_backfills",
+		trace.WithAttributes(attribute.Int("limit", limit)))
+	defer span.End()
+
+	fail := func(err error) ([]StoredBytesBackfill, error) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to list stored bytes backfills: %w", err)
+	}
+
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT s.id, s.user_id, s.session_type, s.external_id
+		FROM sessions s
+		WHERE s.id IN (
+			SELECT session_id FROM sync_files WHERE stored_bytes IS NULL
+			UNION
+			SELECT session_id FROM sync_file_generations WHERE stored_bytes IS NULL
+		)
+		AND s.transcript_archived_at IS NULL
+		LIMIT $1`, limit)
+	if err != nil {
+		return fail(err)
+	}
+	var backfills []StoredBytesBackfill
+	index := make(map[string]int)
+	for rows.Next() {
+		b := StoredBytesBackfill{Files: make(map[string]*time.Time)}
+		if err := rows.Scan(&b.SessionID, &b.UserID, &b.Provider, &b.ExternalID); err != nil {
+			rows.Close()
+			return fail(err)
+		}
+		b.Provider = models.NormalizeProvider(b.Provider)
+		index[b.SessionID] = len(backfills)
+		backfills = append(backfills, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fail(err)
+	}
+	if len(backfills) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(backfills))
+	for _, b := range backfills {
+		ids = append(ids, b.SessionID)
+	}
+
+	rows, err = s.conn().QueryContext(ctx, `
+		SELECT session_id, file_name, updated_at
+		FROM sync_files
+		WHERE session_id = ANY($1) AND stored_bytes IS NULL`, pq.Array(ids))
+	if err != nil {
+		return fail(err)
+	}
+	for rows.Next() {
+		var sessionID, fileName string
+		var updatedAt *time.Time
+		if err := rows.Scan(&sessionID, &fileName, &updatedAt); err != nil {
+			rows.Close()
+			return fail(err)
+		}
+		backfills[index[sessionID]].Files[fileName] = updatedAt
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fail(err)
+	}
+
+	rows, err = s.conn().QueryContext(ctx, `
+		SELECT session_id, file_name, generation
+		FROM sync_file_generations
+		WHERE session_id = ANY($1) AND stored_bytes IS NULL`, pq.Array(ids))
+	if err != nil {
+		return fail(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sessionID string
+		var g StoredBytesGeneration
+		if err := rows.Scan(&sessionID, &g.FileName, &g.Generation); err != nil {
+			return fail(err)
+		}
+		b := &backfills[index[sessionID]]
+		b.Generations = append(b.Generations, g)
+	}
+	if err := rows.Err(); err != nil {
+		return fail(err)
+	}
+
+	span.SetAttributes(attribute.Int("backfill.sessions", len(backfills)))
+	return backfills, nil
+}
+
+// BackfillStoredBytes records the sizes of b's chunks, as listed from
+// storage after ListStoredBytesBackfills returned b. A file or generation
+// missing from the maps has no chunks and gets 0. A live file that synced
+// since it was listed (its updated_at moved) is left NULL for a later pass,
+// since the listing may predate its newest chunk.
+func (s *Store) BackfillStoredBytes(ctx context.Context, b StoredBytesBackfill, fileBytes map[string]int64, generationBytes map[StoredBytesGeneration]int64) error {
+	ctx, span := tracer.Start(ctx, "db.backfill_stored_bytes",
+		trace.WithAttributes(
+			attribute.String("session.id", b.SessionID),
+			attribute.Int("files.count", len(b.Files)),
+			attribute.Int("generations.count", len(b.Generations)),
+		))
+	defer span.End()
+
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for fileName, updatedAt := range b.Files {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE sync_files SET stored_bytes = $3
+			WHERE session_id = $1 AND file_name = $2
+			  AND stored_bytes IS NULL AND updated_at IS NOT DISTINCT FROM $4`,
+			b.SessionID, fileName, fileBytes[fileName], updatedAt); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return fmt.Errorf("failed to backfill sync file stored bytes: %w", err)
+		}
+	}
+	for _, g := range b.Generations {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE sync_file_generations SET stored_bytes = $4
+			WHERE session_id = $1 AND file_name = $2 AND generation = $3
+			  AND stored_bytes IS NULL`,
+			b.SessionID, g.FileName, g.Generation, generationBytes[g]); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return fmt.Errorf("failed to backfill generation stored bytes: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -18,7 +18,7 @@ import (
 )
 
 // FindOrCreateSyncSession finds an existing session by (user_id, provider,
-// external_id) or creates a new one.
+// external_id) or creates a new one, and returns its stored session_type.
 //
 // Provider handling:
 //   - An empty params.Provider is defaulted to "claude-code" so the DB layer
@@ -29,9 +29,14 @@ import (
 //     pre-CF-347 rows that still hold the legacy "Claude Code" display
 //     form. This is the permanent aliasing layer (Confab is OSS
 //     self-hosted, no one-time backfill) — see internal/models/provider.go.
+//   - A lookup with a defaulted provider (params.ProviderExplicit false) also
+//     matches a session whose type was detected from its first transcript
+//     chunk (ReclassifySessionType), so re-running sync/init resumes it
+//     instead of creating a claude-code duplicate. The stored type is
+//     returned for the response.
 //   - The INSERT writes the canonical form parameterized — never a hardcoded
 //     legacy literal.
-func (s *Store) FindOrCreateSyncSession(ctx context.Context, userID int64, params db.SyncSessionParams) (sessionID string, files map[string]db.SyncFileState, err error) {
+func (s *Store) FindOrCreateSyncSession(ctx context.Context, userID int64, params db.SyncSessionParams) (sessionID, sessionType string, files map[string]db.SyncFileState, err error) {
 	if params.Provider == "" {
 		params.Provider = models.ProviderClaudeCode
 	}
@@ -44,64 +49,107 @@ func (s *Store) FindOrCreateSyncSession(ctx context.Context, userID int64, param
 		))
 	defer span.End()
 
-	selectQuery, selectArgs := buildSessionLookupQuery(userID, params.ExternalID, params.Provider)
+	selectQuery, selectArgs := buildSessionLookupQuery(userID, params.ExternalID, params.Provider, params.ProviderExplicit)
 
-	err = s.conn().QueryRowContext(ctx, selectQuery, selectArgs...).Scan(&sessionID)
+	err = s.conn().QueryRowContext(ctx, selectQuery, selectArgs...).Scan(&sessionID, &sessionType)
 	if err == nil {
 		span.SetAttributes(attribute.Bool("session.created", false))
 		if err := s.updateSessionMetadata(ctx, sessionID, params); err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, err.Error())
-			return "", nil, fmt.Errorf("failed to update session metadata: %w", err)
+			return "", "", nil, fmt.Errorf("failed to update session metadata: %w", err)
 		}
 		sid, files, err := s.getSyncFilesForSession(ctx, sessionID)
 		if err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, err.Error())
 		}
-		return sid, files, err
+		return sid, models.NormalizeProvider(sessionType), files, err
 	}
 	if err != sql.ErrNoRows {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return "", nil, fmt.Errorf("failed to find session: %w", err)
+		return "", "", nil, fmt.Errorf("failed to find session: %w", err)
 	}
 
+	// The insert also queues a session.created webhook delivery for each of
+	// the user's subscribed endpoints (dbwebhook.EventSessionCreated). Doing
+	// both in one statement means a session is announced exactly once: a
+	// racing insert that loses on the unique index queues nothing.
 	sessionID = uuid.New().String()
+	typeSource := db.SessionTypeSourceDefault
+	if params.ProviderExplicit {
+		typeSource = db.SessionTypeSourceExplicit
+	}
 	insertQuery := `
-		INSERT INTO sessions (id, user_id, external_id, first_seen, session_type, cwd, transcript_path, git_info, hostname, username, last_sync_at)
-		VALUES ($1, $2, $3, NOW(), $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), NOW())
+		WITH created AS (
+			INSERT INTO sessions (id, user_id, external_id, first_seen, session_type, session_type_source, cwd, transcript_path, git_info, hostname, username, last_sync_at, interest_score, created_by_api_key_id)
+			VALUES ($1, $2, $3, NOW(), $4, $10, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), NOW(), $11, $12)
+			RETURNING id, user_id, external_id, first_seen, session_type, cwd, git_info
+		)
+		INSERT INTO webhook_deliveries (endpoint_id, event_type, payload)
+		SELECT e.id, 'session.created', jsonb_build_object(
+			'event', 'session.created',
+			'created_at', to_char(c.first_seen AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.MS"Z"'),
+			'data', jsonb_build_object(
+				'session_id', c.id,
+				'external_id', c.external_id,
+				'provider', c.session_type,
+				'cwd', c.cwd,
+				'git_info', c.git_info
+			)
+		)
+		FROM created c
+		JOIN webhook_endpoints e ON e.user_id = c.user_id AND 'session.created' = ANY(e.events)
 	`
-	_, err = s.conn().ExecContext(ctx, insertQuery, sessionID, userID, params.ExternalID, params.Provider, params.CWD, params.TranscriptPath, params.GitInfo, params.Hostname, params.Username)
+	_, err = s.conn().ExecContext(ctx, insertQuery, sessionID, userID, params.ExternalID, params.Provider, params.CWD, params.TranscriptPath, params.GitInfo, params.Hostname, params.Username, typeSource, s.newSessionInterestScore(), params.APIKeyID)
 	if err == nil {
 		span.SetAttributes(attribute.Bool("session.created", true))
-		return sessionID, make(map[string]db.SyncFileState), nil
+		return sessionID, params.Provider, make(map[string]db.SyncFileState), nil
 	}
 
 	if db.IsUniqueViolation(err) {
 		span.SetAttributes(attribute.Bool("session.race_condition", true))
-		err = s.conn().QueryRowContext(ctx, selectQuery, selectArgs...).Scan(&sessionID)
+		err = s.conn().QueryRowContext(ctx, selectQuery, selectArgs...).Scan(&sessionID, &sessionType)
 		if err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, err.Error())
-			return "", nil, fmt.Errorf("failed to find session after conflict: %w", err)
+			return "", "", nil, fmt.Errorf("failed to find session after conflict: %w", err)
 		}
 		if err := s.updateSessionMetadata(ctx, sessionID, params); err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, err.Error())
-			return "", nil, fmt.Errorf("failed to update session metadata: %w", err)
+			return "", "", nil, fmt.Errorf("failed to update session metadata: %w", err)
 		}
 		sid, files, err := s.getSyncFilesForSession(ctx, sessionID)
 		if err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, err.Error())
 		}
-		return sid, files, err
+		return sid, models.NormalizeProvider(sessionType), files, err
 	}
 
 	span.RecordError(err)
 	span.SetStatus(codes.Error, err.Error())
-	return "", nil, fmt.Errorf("failed to create session: %w", err)
+	return "", "", nil, fmt.Errorf("failed to create session: %w", err)
+}
+
+// SyncSessionExists reports whether FindOrCreateSyncSession would resume an
+// existing session for these arguments rather than create one.
+func (s *Store) SyncSessionExists(ctx context.Context, userID int64, externalID, provider string, explicit bool) (bool, error) {
+	if provider == "" {
+		provider = models.ProviderClaudeCode
+	}
+	query, args := buildSessionLookupQuery(userID, externalID, provider, explicit)
+	var sessionID, sessionType string
+	err := s.conn().QueryRowContext(ctx, query, args...).Scan(&sessionID, &sessionType)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to find session: %w", err)
+	}
+	return true, nil
 }
 
 // buildSessionLookupQuery returns the SELECT-by-provider query and its
@@ -109,9 +157,17 @@ func (s *Store) FindOrCreateSyncSession(ctx context.Context, userID int64, param
 // legacy session_type rows (e.g. the pre-CF-347 'Claude Code' display
 // form) match canonical-form requests. This is the permanent
 // provider-aliasing layer — see internal/models/provider.go.
-func buildSessionLookupQuery(userID int64, externalID, provider string) (string, []any) {
-	return `SELECT id FROM sessions WHERE user_id = $1 AND external_id = $2 AND session_type = ANY($3)`,
-		[]any{userID, externalID, pq.Array(models.ExpandWithAliases([]string{provider}))}
+//
+// A lookup whose provider was defaulted (explicit false) also matches a
+// 'detected' row, one reclassified away from the default; that row is
+// preferred, since it is the one a defaulted sync/init created.
+func buildSessionLookupQuery(userID int64, externalID, provider string, explicit bool) (string, []any) {
+	return `SELECT id, session_type FROM sessions
+		WHERE user_id = $1 AND external_id = $2
+		  AND (session_type = ANY($3) OR (NOT $4 AND session_type_source = $5))
+		ORDER BY session_type_source = $5 DESC
+		LIMIT 1`,
+		[]any{userID, externalID, pq.Array(models.ExpandWithAliases([]string{provider})), explicit, db.SessionTypeSourceDetected}
 }
 
 func (s *Store) updateSessionMetadata(ctx context.Context, sessionID string, params db.SyncSessionParams) error {
@@ -131,7 +187,7 @@ func (s *Store) updateSessionMetadata(ctx context.Context, sessionID string, par
 
 func (s *Store) getSyncFilesForSession(ctx context.Context, sessionID string) (string, map[string]db.SyncFileState, error) {
 	files := make(map[string]db.SyncFileState)
-	filesQuery := `SELECT file_name, file_type, last_synced_line FROM sync_files WHERE session_id = $1`
+	filesQuery := `SELECT file_name, file_type, last_synced_line, generation FROM sync_files WHERE session_id = $1`
 	rows, err := s.conn().QueryContext(ctx, filesQuery, sessionID)
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to query sync files: %w", err)
@@ -140,7 +196,7 @@ func (s *Store) getSyncFilesForSession(ctx context.Context, sessionID string) (s
 
 	for rows.Next() {
 		var state db.SyncFileState
-		if err := rows.Scan(&state.FileName, &state.FileType, &state.LastSyncedLine); err != nil {
+		if err := rows.Scan(&state.FileName, &state.FileType, &state.LastSyncedLine, &state.Generation); err != nil {
 			return "", nil, fmt.Errorf("failed to scan sync file: %w", err)
 		}
 		files[state.FileName] = state
@@ -154,18 +210,34 @@ func (s *Store) getSyncFilesForSession(ctx context.Context, sessionID string) (s
 }
 
 // UpdateSyncFileState upserts the sync-file row (advancing its high-water mark)
-// and refreshes session metadata.
+// and refreshes session metadata. chunkBytes is the size of the chunk just
+// uploaded and is added to the file's stored_bytes (which stays NULL until the
+// worker backfills a file synced before storage accounting).
+// firstUserMessageDerived marks firstUserMessage as parsed from the transcript
+// rather than supplied by the client: it replaces a client value (or an empty
+// one) but never an earlier derived value or one that predates the source
+// column. A client value only fills an empty field.
 // createdAt is the optional session start anchor (Cursor meta.json createdAtMs):
 // when earlier than the current first_seen it LOWERS first_seen to refine the
 // interpolation start; first_seen is never raised. Pass nil to leave it
 // untouched (every non-Cursor provider).
-func (s *Store) UpdateSyncFileState(ctx context.Context, sessionID, fileName, fileType string, lastSyncedLine int, lastMessageAt, createdAt *time.Time, summary, firstUserMessage *string, gitInfo json.RawMessage) error {
+// generation is the file generation the caller read before uploading the
+// chunk (0 for a new file). If a reset advanced it since, ErrGenerationChanged
+// is returned and nothing changes: the chunk belongs to the archived
+// generation. The update waits on ResetSyncFile's row lock, so a chunk can
+// never land between a reset's archive and its commit.
+// If the session's transcript was archived since the caller checked,
+// ErrTranscriptArchived is returned and nothing changes.
+// The same upsert appends (now, lastSyncedLine) to the file's growth history,
+// bounded at db.GrowthHistoryCap by sync_file_growth_append.
+func (s *Store) UpdateSyncFileState(ctx context.Context, sessionID, fileName, fileType string, generation, lastSyncedLine int, chunkBytes int64, lastMessageAt, createdAt *time.Time, summary, firstUserMessage *string, firstUserMessageDerived bool, gitInfo json.RawMessage) error {
 	ctx, span := tracer.Start(ctx, "db.update_sync_file_state",
 		trace.WithAttributes(
 			attribute.String("session.id", sessionID),
 			attribute.String("file.name", fileName),
 			attribute.String("file.type", fileType),
 			attribute.Int("sync.last_line", lastSyncedLine),
+			attribute.Int("file.generation", generation),
 		))
 	defer span.End()
 
@@ -178,19 +250,25 @@ func (s *Store) UpdateSyncFileState(ctx context.Context, sessionID, fileName, fi
 	defer tx.Rollback()
 
 	syncQuery := `
-		INSERT INTO sync_files (session_id, file_name, file_type, last_synced_line, chunk_count, updated_at)
-		VALUES ($1, $2, $3, $4, 1, NOW())
+		INSERT INTO sync_files (session_id, file_name, file_type, last_synced_line, chunk_count, stored_bytes, growth, updated_at)
+		VALUES ($1, $2, $3, $4, 1, $5, jsonb_build_array(jsonb_build_object('at', NOW(), 'line', $4::int)), NOW())
 		ON CONFLICT (session_id, file_name) DO UPDATE SET
 			last_synced_line = $4,
 			chunk_count = COALESCE(sync_files.chunk_count, 0) + 1,
+			stored_bytes = sync_files.stored_bytes + $5,
+			growth = sync_file_growth_append(sync_files.growth, jsonb_build_object('at', NOW(), 'line', $4::int), $7),
 			updated_at = NOW()
+		WHERE sync_files.generation = $6
 	`
-	_, err = tx.ExecContext(ctx, syncQuery, sessionID, fileName, fileType, lastSyncedLine)
+	result, err := tx.ExecContext(ctx, syncQuery, sessionID, fileName, fileType, lastSyncedLine, chunkBytes, generation, db.GrowthHistoryCap)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		return fmt.Errorf("failed to update sync file state: %w", err)
 	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return db.ErrGenerationChanged
+	}
 
 	sessionQuery := `UPDATE sessions SET last_sync_at = NOW()`
 	args := []interface{}{sessionID}
@@ -214,8 +292,19 @@ func (s *Store) UpdateSyncFileState(ctx context.Context, sessionID, fileName, fi
 		args = append(args, *summary)
 		argIdx++
 	}
-	if firstUserMessage != nil {
+	if firstUserMessage != nil && firstUserMessageDerived {
+		// SET expressions see the row's old values, so both columns test the
+		// same condition.
+		keep := "first_user_message_source = 'server' OR (first_user_message_source IS NULL AND first_user_message IS NOT NULL)"
+		sessionQuery += fmt.Sprintf(
+			", first_user_message = CASE WHEN %s THEN first_user_message ELSE $%d END"+
+				", first_user_message_source = CASE WHEN %s THEN first_user_message_source ELSE 'server' END",
+			keep, argIdx, keep)
+		args = append(args, *firstUserMessage)
+		argIdx++
+	} else if firstUserMessage != nil {
 		sessionQuery += fmt.Sprintf(", first_user_message = COALESCE(first_user_message, $%d)", argIdx)
+		sessionQuery += ", first_user_message_source = CASE WHEN first_user_message IS NULL THEN 'client' ELSE first_user_message_source END"
 		args = append(args, *firstUserMessage)
 		argIdx++
 	}
@@ -223,13 +312,24 @@ func (s *Store) UpdateSyncFileState(ctx context.Context, sessionID, fileName, fi
 		sessionQuery += fmt.Sprintf(", git_info = $%d", argIdx)
 		args = append(args, gitInfo)
 	}
-	sessionQuery += " WHERE id = $1"
-	_, err = tx.ExecContext(ctx, sessionQuery, args...)
+	sessionQuery += " WHERE id = $1 AND transcript_archived_at IS NULL"
+	result, err = tx.ExecContext(ctx, sessionQuery, args...)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		return fmt.Errorf("failed to update session metadata: %w", err)
 	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return db.ErrTranscriptArchived
+	}
+
+	// A sync revives an idle, ended or data_missing session; an active one is
+	// left alone (and logs nothing).
+	if _, err = transitionStates(ctx, tx, []string{sessionID}, StateActive, StateReasonSync); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to update session state: %w", err)
+	}
 
 	if err = tx.Commit(); err != nil {
 		span.RecordError(err)
@@ -240,6 +340,46 @@ func (s *Store) UpdateSyncFileState(ctx context.Context, sessionID, fileName, fi
 	return nil
 }
 
+// TryLockSyncFile takes the advisory lock that serializes sync/chunk uploads
+// to one file of a session, keyed on hashtext(session_id || file_name) with
+// the session ID in its canonical text form; ClaimTranscriptArchive takes the
+// same locks.
+// pg_try_advisory_xact_lock does not wait: acquired is false when another
+// upload holds it. The lock lives in a transaction of its own that stays open,
+// holding a pool connection, until release is called; release is nil when
+// the lock was not acquired.
+func (s *Store) TryLockSyncFile(ctx context.Context, sessionID, fileName string) (release func(), acquired bool, err error) {
+	ctx, span := tracer.Start(ctx, "db.try_lock_sync_file",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.String("file.name", fileName),
+		))
+	defer span.End()
+
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, false, fmt.Errorf("failed to begin sync file lock transaction: %w", err)
+	}
+	err = tx.QueryRowContext(ctx,
+		`SELECT pg_try_advisory_xact_lock(hashtext($1::uuid::text || $2))`,
+		sessionID, fileName).Scan(&acquired)
+	if err != nil || !acquired {
+		_ = tx.Rollback()
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, false, fmt.Errorf("failed to lock sync file: %w", err)
+	}
+	span.SetAttributes(attribute.Bool("sync.lock_acquired", acquired))
+	if !acquired {
+		return nil, false, nil
+	}
+	return func() { _ = tx.Rollback() }, true, nil
+}
+
 // GetSyncFileState retrieves the sync state for a specific file
 func (s *Store) GetSyncFileState(ctx context.Context, sessionID, fileName string) (*db.SyncFileState, error) {
 	ctx, span := tracer.Start(ctx, "db.get_sync_file_state",
@@ -249,9 +389,9 @@ func (s *Store) GetSyncFileState(ctx context.Context, sessionID, fileName string
 		))
 	defer span.End()
 
-	query := `SELECT file_name, file_type, last_synced_line, chunk_count FROM sync_files WHERE session_id = $1 AND file_name = $2`
+	query := `SELECT file_name, file_type, last_synced_line, chunk_count, generation FROM sync_files WHERE session_id = $1 AND file_name = $2`
 	var state db.SyncFileState
-	err := s.conn().QueryRowContext(ctx, query, sessionID, fileName).Scan(&state.FileName, &state.FileType, &state.LastSyncedLine, &state.ChunkCount)
+	err := s.conn().QueryRowContext(ctx, query, sessionID, fileName).Scan(&state.FileName, &state.FileType, &state.LastSyncedLine, &state.ChunkCount, &state.Generation)
 	if err == sql.ErrNoRows {
 		return nil, db.ErrFileNotFound
 	}
@@ -264,6 +404,46 @@ func (s *Store) GetSyncFileState(ctx context.Context, sessionID, fileName string
 	return &state, nil
 }
 
+// ListSyncFileStates returns every sync file of a session, ordered by name.
+func (s *Store) ListSyncFileStates(ctx context.Context, sessionID string) ([]db.SyncFileState, error) {
+	ctx, span := tracer.Start(ctx, "db.list_sync_file_states",
+		trace.WithAttributes(attribute.String("session.id", sessionID)))
+	defer span.End()
+
+	states, err := listSyncFileStates(ctx, s.conn(), sessionID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	return states, nil
+}
+
+// listSyncFileStates is ListSyncFileStates on q, which may be a transaction.
+func listSyncFileStates(ctx context.Context, q stateQuerier, sessionID string) ([]db.SyncFileState, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT file_name, file_type, last_synced_line, chunk_count, generation
+		FROM sync_files WHERE session_id = $1
+		ORDER BY file_name`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync files: %w", err)
+	}
+	defer rows.Close()
+
+	var states []db.SyncFileState
+	for rows.Next() {
+		var state db.SyncFileState
+		if err := rows.Scan(&state.FileName, &state.FileType, &state.LastSyncedLine, &state.ChunkCount, &state.Generation); err != nil {
+			return nil, fmt.Errorf("failed to scan sync file: %w", err)
+		}
+		states = append(states, state)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sync files: %w", err)
+	}
+	return states, nil
+}
+
 // UpdateSyncFileChunkCount sets the chunk_count for a file (used for self-healing on read)
 func (s *Store) UpdateSyncFileChunkCount(ctx context.Context, sessionID, fileName string, chunkCount int) error {
 	ctx, span := tracer.Start(ctx, "db.update_sync_file_chunk_count",
@@ -283,3 +463,75 @@ func (s *Store) UpdateSyncFileChunkCount(ctx context.Context, sessionID, fileNam
 	}
 	return nil
 }
+
+// ReclassifySessionType changes a session's session_type to detected, but only
+// while it still holds the claude-code default (canonical or legacy form, and
+// not sent explicitly by the client) and before any file has been synced — chunks are stored under a provider-scoped
+// S3 prefix, so the type must be settled before the first upload. Reports
+// whether the row changed. A (user_id, session_type, external_id) collision
+// with an existing session of the detected type leaves the row as is.
+func (s *Store) ReclassifySessionType(ctx context.Context, sessionID, detected string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "db.reclassify_session_type",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.String("session.provider", detected),
+		))
+	defer span.End()
+
+	query := `
+		UPDATE sessions SET session_type = $2, session_type_source = $4
+		WHERE id = $1
+		  AND session_type = ANY($3)
+		  AND session_type_source = $5
+		  AND NOT EXISTS (SELECT 1 FROM sync_files WHERE session_id = $1)`
+	res, err := s.conn().ExecContext(ctx, query, sessionID, detected,
+		pq.Array(models.ExpandWithAliases([]string{models.ProviderClaudeCode})),
+		db.SessionTypeSourceDetected, db.SessionTypeSourceDefault)
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			span.SetAttributes(attribute.String("result", "conflict"))
+			return false, nil
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return false, fmt.Errorf("failed to reclassify session type: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to reclassify session type: %w", err)
+	}
+	return n == 1, nil
+}
+
+// CanReclassifySessionType reports whether ReclassifySessionType would change
+// the session to detected right now, without changing it. Used by the
+// sync/chunk dry run.
+func (s *Store) CanReclassifySessionType(ctx context.Context, sessionID, detected string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "db.can_reclassify_session_type",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.String("session.provider", detected),
+		))
+	defer span.End()
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM sessions s
+			WHERE s.id = $1
+			  AND s.session_type = ANY($3)
+			  AND s.session_type_source = $4
+			  AND NOT EXISTS (SELECT 1 FROM sync_files WHERE session_id = $1)
+			  AND NOT EXISTS (
+				SELECT 1 FROM sessions o
+				WHERE o.user_id = s.user_id AND o.session_type = $2 AND o.external_id = s.external_id))`
+	var can bool
+	err := s.conn().QueryRowContext(ctx, query, sessionID, detected,
+		pq.Array(models.ExpandWithAliases([]string{models.ProviderClaudeCode})),
+		db.SessionTypeSourceDefault).Scan(&can)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return false, fmt.Errorf("failed to check session reclassification: %w", err)
+	}
+	return can, nil
+}
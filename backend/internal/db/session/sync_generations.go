@@ -0,0 +1,155 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// Sync file reset reasons accepted by POST /api/v1/sync/file/reset.
+const (
+	ResetReasonCompaction = "compaction"
+	ResetReasonManual     = "manual"
+)
+
+// ResetSyncFile records the file's live generation as archived and starts a
+// new, empty one: last_synced_line and chunk_count drop to 0, the growth
+// history empties and the generation advances. generation is the live generation the caller read; if
+// another reset advanced it first, ErrGenerationChanged is returned and
+// nothing changes. The session's rows in derivedTables (see
+// analytics.SessionDerivedTableNames) are deleted in the same transaction so
+// analytics recompute from the new content.
+//
+// archive moves the generation's chunks aside in storage. It runs while the
+// sync_files row is locked, so no sync/chunk can record a chunk between the
+// move and the commit (UpdateSyncFileState waits on the lock, then sees the
+// new generation). If archive fails the transaction rolls back and the file
+// is unchanged; a retry after a failed commit finds nothing left to move.
+func (s *Store) ResetSyncFile(ctx context.Context, sessionID, fileName string, generation int, reason string, localLineCount int, derivedTables []string, archive func() error) (*db.SyncFileState, error) {
+	ctx, span := tracer.Start(ctx, "db.reset_sync_file",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.String("file.name", fileName),
+			attribute.Int("file.generation", generation),
+			attribute.String("reset.reason", reason),
+		))
+	defer span.End()
+
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the row for the rest of the reset. A concurrent reset that already
+	// advanced the generation leaves no matching row.
+	var locked int
+	err = tx.QueryRowContext(ctx, `
+		SELECT generation FROM sync_files
+		WHERE session_id = $1 AND file_name = $2 AND generation = $3
+		FOR UPDATE`,
+		sessionID, fileName, generation).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return nil, db.ErrGenerationChanged
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to lock sync file: %w", err)
+	}
+
+	if err := archive(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	// Archive the live generation's bookkeeping.
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO sync_file_generations
+			(session_id, file_name, generation, file_type, line_count, chunk_count, stored_bytes, reason, local_line_count)
+		SELECT session_id, file_name, generation, file_type, last_synced_line, chunk_count, stored_bytes, $4, $5
+		FROM sync_files
+		WHERE session_id = $1 AND file_name = $2 AND generation = $3
+		ON CONFLICT (session_id, file_name, generation) DO NOTHING`,
+		sessionID, fileName, generation, reason, localLineCount)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to archive sync file generation: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, db.ErrGenerationChanged
+	}
+
+	state := db.SyncFileState{FileName: fileName}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE sync_files
+		SET generation = generation + 1,
+			last_synced_line = 0,
+			chunk_count = 0,
+			stored_bytes = 0,
+			fingerprint_state = NULL,
+			fingerprint_lines = NULL,
+			growth = '[]',
+			updated_at = NOW()
+		WHERE session_id = $1 AND file_name = $2
+		RETURNING file_type, last_synced_line, chunk_count, generation`,
+		sessionID, fileName).Scan(&state.FileType, &state.LastSyncedLine, &state.ChunkCount, &state.Generation)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to reset sync file: %w", err)
+	}
+
+	for _, table := range derivedTables {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE session_id = $1`, table), sessionID); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return &state, nil
+}
+
+// GetSyncFileGeneration returns an archived generation of a file, or
+// ErrFileNotFound if the file was never reset past it.
+func (s *Store) GetSyncFileGeneration(ctx context.Context, sessionID, fileName string, generation int) (*db.SyncFileGeneration, error) {
+	ctx, span := tracer.Start(ctx, "db.get_sync_file_generation",
+		trace.WithAttributes(
+			attribute.String("session.id", sessionID),
+			attribute.String("file.name", fileName),
+			attribute.Int("file.generation", generation),
+		))
+	defer span.End()
+
+	var g db.SyncFileGeneration
+	err := s.conn().QueryRowContext(ctx, `
+		SELECT generation, file_type, line_count, chunk_count, reason, local_line_count, reset_at
+		FROM sync_file_generations
+		WHERE session_id = $1 AND file_name = $2 AND generation = $3`,
+		sessionID, fileName, generation).Scan(&g.Generation, &g.FileType, &g.LineCount, &g.ChunkCount, &g.Reason, &g.LocalLineCount, &g.ResetAt)
+	if err == sql.ErrNoRows {
+		return nil, db.ErrFileNotFound
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get sync file generation: %w", err)
+	}
+	return &g, nil
+}
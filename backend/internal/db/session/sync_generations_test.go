@@ -0,0 +1,160 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/analytics"
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbsession "github.com/ConfabulousDev/confab-web/internal/db/session"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestResetSyncFile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "reset@test.com", "Reset User")
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "reset-me")
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 40)
+	testutil.CreateTestSearchIndex(t, env, sessionID, "before compaction", 40)
+
+	archived := 0
+	state, err := store.ResetSyncFile(ctx, sessionID, "transcript.jsonl", 0, dbsession.ResetReasonCompaction, 12, analytics.SessionDerivedTableNames, func() error {
+		archived++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ResetSyncFile: %v", err)
+	}
+	if archived != 1 {
+		t.Errorf("archive ran %d times, want 1", archived)
+	}
+	if state.Generation != 1 || state.LastSyncedLine != 0 || state.ChunkCount == nil || *state.ChunkCount != 0 {
+		t.Errorf("state = %+v, want generation 1 with no lines or chunks", state)
+	}
+	if live, err := store.GetSyncFileState(ctx, sessionID, "transcript.jsonl"); err != nil || live.Generation != 1 {
+		t.Errorf("GetSyncFileState = (%+v, %v), want generation 1", live, err)
+	}
+
+	gen, err := store.GetSyncFileGeneration(ctx, sessionID, "transcript.jsonl", 0)
+	if err != nil {
+		t.Fatalf("GetSyncFileGeneration: %v", err)
+	}
+	if gen.LineCount != 40 || gen.Reason != dbsession.ResetReasonCompaction || gen.LocalLineCount != 12 || gen.FileType != "transcript" {
+		t.Errorf("archived generation = %+v", gen)
+	}
+	if _, err := store.GetSyncFileGeneration(ctx, sessionID, "transcript.jsonl", 1); !errors.Is(err, db.ErrFileNotFound) {
+		t.Errorf("live generation lookup err = %v, want ErrFileNotFound", err)
+	}
+
+	var indexed int
+	if err := env.DB.QueryRow(ctx, `SELECT COUNT(*) FROM session_search_index WHERE session_id = $1`, sessionID).Scan(&indexed); err != nil {
+		t.Fatalf("count search index: %v", err)
+	}
+	if indexed != 0 {
+		t.Error("reset should delete the search index so it is rebuilt")
+	}
+
+	// A second reset based on the stale generation loses the race without
+	// touching storage.
+	archived = 0
+	if _, err := store.ResetSyncFile(ctx, sessionID, "transcript.jsonl", 0, dbsession.ResetReasonManual, 0, analytics.SessionDerivedTableNames, func() error {
+		archived++
+		return nil
+	}); !errors.Is(err, db.ErrGenerationChanged) {
+		t.Errorf("stale reset err = %v, want ErrGenerationChanged", err)
+	}
+	if archived != 0 {
+		t.Error("a stale reset must not archive chunks")
+	}
+
+	// A chunk read against the archived generation is refused.
+	if err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 41, 100, nil, nil, nil, nil, false, nil); !errors.Is(err, db.ErrGenerationChanged) {
+		t.Errorf("stale chunk err = %v, want ErrGenerationChanged", err)
+	}
+	if err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 1, 10, 100, nil, nil, nil, nil, false, nil); err != nil {
+		t.Errorf("chunk of the live generation: %v", err)
+	}
+}
+
+func TestResetSyncFile_ArchiveFailureLeavesFileUnchanged(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "reset-fail@test.com", "Reset User")
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "reset-fail")
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 40)
+
+	errStorage := errors.New("storage down")
+	if _, err := store.ResetSyncFile(ctx, sessionID, "transcript.jsonl", 0, dbsession.ResetReasonCompaction, 12, analytics.SessionDerivedTableNames, func() error {
+		return errStorage
+	}); !errors.Is(err, errStorage) {
+		t.Fatalf("ResetSyncFile err = %v, want the archive error", err)
+	}
+	live, err := store.GetSyncFileState(ctx, sessionID, "transcript.jsonl")
+	if err != nil || live.Generation != 0 || live.LastSyncedLine != 40 {
+		t.Errorf("GetSyncFileState = (%+v, %v), want generation 0 at line 40", live, err)
+	}
+}
+
+// TestResetSyncFile_ChunkDuringArchiveWaits checks that a chunk recorded
+// while the reset is moving chunks waits for the reset and is then refused,
+// instead of landing in the live generation after the move.
+func TestResetSyncFile_ChunkDuringArchiveWaits(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "reset-race@test.com", "Reset User")
+	sessionID := testutil.CreateTestSession(t, env, user.ID, "reset-race")
+	testutil.CreateTestSyncFile(t, env, sessionID, "transcript.jsonl", "transcript", 40)
+
+	chunkErr := make(chan error, 1)
+	_, err := store.ResetSyncFile(ctx, sessionID, "transcript.jsonl", 0, dbsession.ResetReasonCompaction, 12, analytics.SessionDerivedTableNames, func() error {
+		go func() {
+			chunkErr <- store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 50, 100, nil, nil, nil, nil, false, nil)
+		}()
+		select {
+		case err := <-chunkErr:
+			t.Errorf("chunk update finished during the archive (err = %v), want it to wait", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ResetSyncFile: %v", err)
+	}
+
+	select {
+	case err := <-chunkErr:
+		if !errors.Is(err, db.ErrGenerationChanged) {
+			t.Errorf("chunk update err = %v, want ErrGenerationChanged", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("chunk update still blocked after the reset committed")
+	}
+	live, err := store.GetSyncFileState(ctx, sessionID, "transcript.jsonl")
+	if err != nil || live.Generation != 1 || live.LastSyncedLine != 0 {
+		t.Errorf("GetSyncFileState = (%+v, %v), want an empty generation 1", live, err)
+	}
+}
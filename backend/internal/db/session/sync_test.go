@@ -33,7 +33,7 @@ func TestUpdateSyncFileState_BasicUpsert(t *testing.T) {
 	ctx := context.Background()
 
 	// Create new sync file state
-	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 100, nil, nil, nil, nil, nil)
+	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 100, 0, nil, nil, nil, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (create) failed: %v", err)
 	}
@@ -51,7 +51,7 @@ func TestUpdateSyncFileState_BasicUpsert(t *testing.T) {
 	}
 
 	// Update existing sync file state
-	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 200, nil, nil, nil, nil, nil)
+	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 200, 0, nil, nil, nil, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (update) failed: %v", err)
 	}
@@ -82,7 +82,7 @@ func TestUpdateSyncFileState_ChunkCountIncrement(t *testing.T) {
 	ctx := context.Background()
 
 	// First chunk upload - chunk_count should be 1
-	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 100, nil, nil, nil, nil, nil)
+	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 100, 0, nil, nil, nil, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (1) failed: %v", err)
 	}
@@ -96,7 +96,7 @@ func TestUpdateSyncFileState_ChunkCountIncrement(t *testing.T) {
 	}
 
 	// Second chunk upload - chunk_count should be 2
-	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 200, nil, nil, nil, nil, nil)
+	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 200, 0, nil, nil, nil, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (2) failed: %v", err)
 	}
@@ -110,7 +110,7 @@ func TestUpdateSyncFileState_ChunkCountIncrement(t *testing.T) {
 	}
 
 	// Third chunk upload - chunk_count should be 3
-	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 300, nil, nil, nil, nil, nil)
+	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 300, 0, nil, nil, nil, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (3) failed: %v", err)
 	}
@@ -151,7 +151,7 @@ func TestUpdateSyncFileState_LastMessageAt(t *testing.T) {
 
 	// Set initial last_message_at (from NULL)
 	initialTime := time.Now().Add(-time.Hour).UTC()
-	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 100, &initialTime, nil, nil, nil, nil)
+	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 100, 0, &initialTime, nil, nil, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (initial) failed: %v", err)
 	}
@@ -168,7 +168,7 @@ func TestUpdateSyncFileState_LastMessageAt(t *testing.T) {
 
 	// Try to set older time - should NOT update
 	olderTime := time.Now().Add(-2 * time.Hour).UTC()
-	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 150, &olderTime, nil, nil, nil, nil)
+	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 150, 0, &olderTime, nil, nil, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (older) failed: %v", err)
 	}
@@ -189,7 +189,7 @@ func TestUpdateSyncFileState_LastMessageAt(t *testing.T) {
 
 	// Set newer time - SHOULD update
 	newerTime := time.Now().UTC()
-	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 200, &newerTime, nil, nil, nil, nil)
+	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 200, 0, &newerTime, nil, nil, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (newer) failed: %v", err)
 	}
@@ -221,7 +221,7 @@ func TestUpdateSyncFileState_Summary(t *testing.T) {
 
 	// Set initial summary
 	summary1 := "First summary"
-	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 100, nil, nil, &summary1, nil, nil)
+	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 100, 0, nil, nil, &summary1, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (summary1) failed: %v", err)
 	}
@@ -238,7 +238,7 @@ func TestUpdateSyncFileState_Summary(t *testing.T) {
 
 	// Update summary (last write wins)
 	summary2 := "Updated summary"
-	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 150, nil, nil, &summary2, nil, nil)
+	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 150, 0, nil, nil, &summary2, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (summary2) failed: %v", err)
 	}
@@ -254,7 +254,7 @@ func TestUpdateSyncFileState_Summary(t *testing.T) {
 
 	// Clear summary with empty string
 	emptyStr := ""
-	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 200, nil, nil, &emptyStr, nil, nil)
+	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 200, 0, nil, nil, &emptyStr, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (empty summary) failed: %v", err)
 	}
@@ -286,7 +286,7 @@ func TestUpdateSyncFileState_FirstUserMessage(t *testing.T) {
 
 	// Set initial first_user_message
 	msg1 := "First user message"
-	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 100, nil, nil, nil, &msg1, nil)
+	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 100, 0, nil, nil, nil, &msg1, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (msg1) failed: %v", err)
 	}
@@ -303,7 +303,7 @@ func TestUpdateSyncFileState_FirstUserMessage(t *testing.T) {
 
 	// Try to update first_user_message (first write wins - should NOT update)
 	msg2 := "Second user message"
-	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 150, nil, nil, nil, &msg2, nil)
+	err = store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 150, 0, nil, nil, nil, &msg2, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (msg2) failed: %v", err)
 	}
@@ -318,6 +318,76 @@ func TestUpdateSyncFileState_FirstUserMessage(t *testing.T) {
 	}
 }
 
+// TestUpdateSyncFileState_DerivedFirstUserMessage tests that a value parsed
+// from the transcript replaces a client-supplied one, the first parsed value
+// wins, and a title that predates the source column is left alone.
+func TestUpdateSyncFileState_DerivedFirstUserMessage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "derived@test.com", "Derived User")
+
+	update := func(t *testing.T, sessionID, msg string, derived bool) {
+		t.Helper()
+		if err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 100, 0, nil, nil, nil, &msg, derived, nil); err != nil {
+			t.Fatalf("UpdateSyncFileState(%q, derived=%v) failed: %v", msg, derived, err)
+		}
+	}
+	stored := func(t *testing.T, sessionID string) (msg, source *string) {
+		t.Helper()
+		if err := env.DB.QueryRow(ctx, "SELECT first_user_message, first_user_message_source FROM sessions WHERE id = $1",
+			sessionID).Scan(&msg, &source); err != nil {
+			t.Fatalf("query first_user_message failed: %v", err)
+		}
+		return msg, source
+	}
+	expect := func(t *testing.T, sessionID, wantMsg, wantSource string) {
+		t.Helper()
+		msg, source := stored(t, sessionID)
+		if msg == nil || *msg != wantMsg || source == nil || *source != wantSource {
+			t.Errorf("first_user_message = %v (source %v), want %q (source %q)", msg, source, wantMsg, wantSource)
+		}
+	}
+
+	t.Run("derived replaces client, then first derived wins", func(t *testing.T) {
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "derived-replaces")
+		update(t, sessionID, "[tool_result blob]", false)
+		expect(t, sessionID, "[tool_result blob]", "client")
+
+		update(t, sessionID, "real prompt", true)
+		expect(t, sessionID, "real prompt", "server")
+
+		update(t, sessionID, "later prompt", true)
+		update(t, sessionID, "client again", false)
+		expect(t, sessionID, "real prompt", "server")
+	})
+
+	t.Run("client fills an empty field only", func(t *testing.T) {
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "client-fallback")
+		update(t, sessionID, "first prompt", true)
+		update(t, sessionID, "client value", false)
+		expect(t, sessionID, "first prompt", "server")
+	})
+
+	t.Run("legacy titles are kept", func(t *testing.T) {
+		sessionID := testutil.CreateTestSession(t, env, user.ID, "legacy")
+		if _, err := env.DB.Exec(ctx, "UPDATE sessions SET first_user_message = 'legacy title' WHERE id = $1", sessionID); err != nil {
+			t.Fatalf("set legacy title: %v", err)
+		}
+		update(t, sessionID, "derived prompt", true)
+		msg, source := stored(t, sessionID)
+		if msg == nil || *msg != "legacy title" || source != nil {
+			t.Errorf("first_user_message = %v (source %v), want the legacy title untouched", msg, source)
+		}
+	})
+}
+
 // TestUpdateSyncFileState_GitInfo tests git_info update
 func TestUpdateSyncFileState_GitInfo(t *testing.T) {
 	if testing.Short() {
@@ -335,7 +405,7 @@ func TestUpdateSyncFileState_GitInfo(t *testing.T) {
 
 	// Set git_info
 	gitInfo := json.RawMessage(`{"repo_url": "https://github.com/example/repo", "branch": "main"}`)
-	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 100, nil, nil, nil, nil, gitInfo)
+	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 100, 0, nil, nil, nil, nil, false, gitInfo)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (gitinfo) failed: %v", err)
 	}
@@ -383,7 +453,7 @@ func TestUpdateSyncFileState_CombinedParameters(t *testing.T) {
 	firstMsg := "Combined first message"
 	gitInfo := json.RawMessage(`{"repo_url": "https://github.com/combined/repo", "branch": "develop"}`)
 
-	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 100, &msgTime, nil, &summary, &firstMsg, gitInfo)
+	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 100, 0, &msgTime, nil, &summary, &firstMsg, false, gitInfo)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (combined) failed: %v", err)
 	}
@@ -431,7 +501,7 @@ func TestUpdateSyncFileState_NoOptionalParameters(t *testing.T) {
 	ctx := context.Background()
 
 	// Update with no optional parameters
-	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 100, nil, nil, nil, nil, nil)
+	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 100, 0, nil, nil, nil, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState (no opts) failed: %v", err)
 	}
@@ -484,7 +554,7 @@ func TestUpdateSyncFileChunkCount(t *testing.T) {
 	ctx := context.Background()
 
 	// Create initial sync file state
-	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 100, nil, nil, nil, nil, nil)
+	err := store.UpdateSyncFileState(ctx, sessionID, "transcript.jsonl", "transcript", 0, 100, 0, nil, nil, nil, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState failed: %v", err)
 	}
@@ -532,7 +602,7 @@ func TestFindOrCreateSyncSession_CreateNew(t *testing.T) {
 		Username:       "myuser",
 	}
 
-	sessionID, files, err := store.FindOrCreateSyncSession(ctx, user.ID, params)
+	sessionID, _, files, err := store.FindOrCreateSyncSession(ctx, user.ID, params)
 	if err != nil {
 		t.Fatalf("FindOrCreateSyncSession failed: %v", err)
 	}
@@ -587,23 +657,23 @@ func TestFindOrCreateSyncSession_FindExisting(t *testing.T) {
 		CWD:            "/home/user/project",
 	}
 
-	sessionID1, _, err := store.FindOrCreateSyncSession(ctx, user.ID, params)
+	sessionID1, _, _, err := store.FindOrCreateSyncSession(ctx, user.ID, params)
 	if err != nil {
 		t.Fatalf("FindOrCreateSyncSession (1) failed: %v", err)
 	}
 
 	// Add some sync files
-	err = store.UpdateSyncFileState(ctx, sessionID1, "transcript.jsonl", "transcript", 100, nil, nil, nil, nil, nil)
+	err = store.UpdateSyncFileState(ctx, sessionID1, "transcript.jsonl", "transcript", 0, 100, 0, nil, nil, nil, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState failed: %v", err)
 	}
-	err = store.UpdateSyncFileState(ctx, sessionID1, "todo.jsonl", "todo", 50, nil, nil, nil, nil, nil)
+	err = store.UpdateSyncFileState(ctx, sessionID1, "todo.jsonl", "todo", 0, 50, 0, nil, nil, nil, nil, false, nil)
 	if err != nil {
 		t.Fatalf("UpdateSyncFileState failed: %v", err)
 	}
 
 	// Find existing session
-	sessionID2, files, err := store.FindOrCreateSyncSession(ctx, user.ID, params)
+	sessionID2, _, files, err := store.FindOrCreateSyncSession(ctx, user.ID, params)
 	if err != nil {
 		t.Fatalf("FindOrCreateSyncSession (2) failed: %v", err)
 	}
@@ -645,7 +715,7 @@ func TestFindOrCreateSyncSession_UpdatesMetadata(t *testing.T) {
 		Username:       "user1",
 	}
 
-	sessionID, _, err := store.FindOrCreateSyncSession(ctx, user.ID, params1)
+	sessionID, _, _, err := store.FindOrCreateSyncSession(ctx, user.ID, params1)
 	if err != nil {
 		t.Fatalf("FindOrCreateSyncSession (1) failed: %v", err)
 	}
@@ -660,7 +730,7 @@ func TestFindOrCreateSyncSession_UpdatesMetadata(t *testing.T) {
 		Username:       "user2",
 	}
 
-	_, _, err = store.FindOrCreateSyncSession(ctx, user.ID, params2)
+	_, _, _, err = store.FindOrCreateSyncSession(ctx, user.ID, params2)
 	if err != nil {
 		t.Fatalf("FindOrCreateSyncSession (2) failed: %v", err)
 	}
@@ -712,13 +782,13 @@ func TestFindOrCreateSyncSession_DifferentUsers(t *testing.T) {
 	}
 
 	// Create session for user1
-	sessionID1, _, err := store.FindOrCreateSyncSession(ctx, user1.ID, params)
+	sessionID1, _, _, err := store.FindOrCreateSyncSession(ctx, user1.ID, params)
 	if err != nil {
 		t.Fatalf("FindOrCreateSyncSession (user1) failed: %v", err)
 	}
 
 	// Create session for user2 (should be different session)
-	sessionID2, _, err := store.FindOrCreateSyncSession(ctx, user2.ID, params)
+	sessionID2, _, _, err := store.FindOrCreateSyncSession(ctx, user2.ID, params)
 	if err != nil {
 		t.Fatalf("FindOrCreateSyncSession (user2) failed: %v", err)
 	}
@@ -750,7 +820,7 @@ func TestFindOrCreateSyncSession_EmptyHostnameUsername(t *testing.T) {
 		Username:   "myuser",
 	}
 
-	sessionID, _, err := store.FindOrCreateSyncSession(ctx, user.ID, params1)
+	sessionID, _, _, err := store.FindOrCreateSyncSession(ctx, user.ID, params1)
 	if err != nil {
 		t.Fatalf("FindOrCreateSyncSession (1) failed: %v", err)
 	}
@@ -763,7 +833,7 @@ func TestFindOrCreateSyncSession_EmptyHostnameUsername(t *testing.T) {
 		Username:   "", // Empty
 	}
 
-	_, _, err = store.FindOrCreateSyncSession(ctx, user.ID, params2)
+	_, _, _, err = store.FindOrCreateSyncSession(ctx, user.ID, params2)
 	if err != nil {
 		t.Fatalf("FindOrCreateSyncSession (2) failed: %v", err)
 	}
@@ -893,7 +963,7 @@ func TestFindOrCreateSyncSession_ProviderIsolation(t *testing.T) {
 
 	commonExternalID := "shared-external-id"
 
-	ccSessionID, _, err := store.FindOrCreateSyncSession(ctx, user.ID, db.SyncSessionParams{
+	ccSessionID, _, _, err := store.FindOrCreateSyncSession(ctx, user.ID, db.SyncSessionParams{
 		ExternalID:     commonExternalID,
 		TranscriptPath: "/path/cc.jsonl",
 		Provider:       "claude-code",
@@ -902,7 +972,7 @@ func TestFindOrCreateSyncSession_ProviderIsolation(t *testing.T) {
 		t.Fatalf("FindOrCreate (claude-code) failed: %v", err)
 	}
 
-	codexSessionID, _, err := store.FindOrCreateSyncSession(ctx, user.ID, db.SyncSessionParams{
+	codexSessionID, _, _, err := store.FindOrCreateSyncSession(ctx, user.ID, db.SyncSessionParams{
 		ExternalID:     commonExternalID,
 		TranscriptPath: "/path/codex.jsonl",
 		Provider:       "codex",
@@ -947,7 +1017,7 @@ func TestFindOrCreateSyncSession_DefaultsParamsToClaudeCode(t *testing.T) {
 	user := testutil.CreateTestUser(t, env, "default-provider@test.com", "Default Provider User")
 	ctx := context.Background()
 
-	sessionID, _, err := store.FindOrCreateSyncSession(ctx, user.ID, db.SyncSessionParams{
+	sessionID, _, _, err := store.FindOrCreateSyncSession(ctx, user.ID, db.SyncSessionParams{
 		ExternalID:     "default-provider-external",
 		TranscriptPath: "/path.jsonl",
 		// Provider intentionally left empty
@@ -988,7 +1058,7 @@ func TestFindOrCreateSyncSession_FindsLegacyClaudeCodeRow(t *testing.T) {
 	preexisting := testutil.CreateTestSessionLegacyClaudeCode(t, env, user.ID, "legacy-external-id")
 
 	// New code should find that row via the IN-clause lookup.
-	found, _, err := store.FindOrCreateSyncSession(ctx, user.ID, db.SyncSessionParams{
+	found, _, _, err := store.FindOrCreateSyncSession(ctx, user.ID, db.SyncSessionParams{
 		ExternalID:     "legacy-external-id",
 		TranscriptPath: "/path.jsonl",
 		Provider:       "claude-code",
@@ -1029,7 +1099,7 @@ func TestFindOrCreateSyncSession_CodexLookupSkipsLegacyClaudeCode(t *testing.T)
 
 	legacy := testutil.CreateTestSessionLegacyClaudeCode(t, env, user.ID, "shared-external")
 
-	codexSessionID, _, err := store.FindOrCreateSyncSession(ctx, user.ID, db.SyncSessionParams{
+	codexSessionID, _, _, err := store.FindOrCreateSyncSession(ctx, user.ID, db.SyncSessionParams{
 		ExternalID:     "shared-external",
 		TranscriptPath: "/codex.jsonl",
 		Provider:       "codex",
@@ -1052,3 +1122,62 @@ func TestFindOrCreateSyncSession_CodexLookupSkipsLegacyClaudeCode(t *testing.T)
 		t.Errorf("expected 2 session rows (one per provider), got %d", count)
 	}
 }
+
+// TestFindOrCreateSyncSession_FindsReclassifiedSession asserts that once a
+// defaulted session is reclassified from its first transcript chunk, a
+// FindOrCreate without an explicit provider resumes it (reporting the
+// detected type) while an explicit claude-code lookup does not.
+func TestFindOrCreateSyncSession_FindsReclassifiedSession(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+	store := &dbsession.Store{DB: env.DB}
+
+	user := testutil.CreateTestUser(t, env, "reclassified@test.com", "Reclassified User")
+	ctx := context.Background()
+	params := db.SyncSessionParams{ExternalID: "reclassified-ext", TranscriptPath: "/path.jsonl", Provider: "claude-code"}
+
+	sessionID, sessionType, _, err := store.FindOrCreateSyncSession(ctx, user.ID, params)
+	if err != nil {
+		t.Fatalf("FindOrCreate failed: %v", err)
+	}
+	if sessionType != "claude-code" {
+		t.Fatalf("sessionType = %q, want claude-code", sessionType)
+	}
+	if can, err := store.CanReclassifySessionType(ctx, sessionID, "codex"); err != nil || !can {
+		t.Fatalf("CanReclassifySessionType = %v, %v; want true", can, err)
+	}
+	changed, err := store.ReclassifySessionType(ctx, sessionID, "codex")
+	if err != nil || !changed {
+		t.Fatalf("ReclassifySessionType = %v, %v; want true", changed, err)
+	}
+	if can, err := store.CanReclassifySessionType(ctx, sessionID, "cursor"); err != nil || can {
+		t.Errorf("CanReclassifySessionType after reclassification = %v, %v; want false", can, err)
+	}
+
+	found, sessionType, _, err := store.FindOrCreateSyncSession(ctx, user.ID, params)
+	if err != nil {
+		t.Fatalf("FindOrCreate (defaulted) failed: %v", err)
+	}
+	if found != sessionID || sessionType != "codex" {
+		t.Errorf("defaulted lookup = %s (%s), want %s (codex)", found, sessionType, sessionID)
+	}
+
+	params.ProviderExplicit = true
+	explicit, sessionType, _, err := store.FindOrCreateSyncSession(ctx, user.ID, params)
+	if err != nil {
+		t.Fatalf("FindOrCreate (explicit) failed: %v", err)
+	}
+	if explicit == sessionID || sessionType != "claude-code" {
+		t.Errorf("explicit claude-code lookup = %s (%s), want a new claude-code session", explicit, sessionType)
+	}
+	if can, err := store.CanReclassifySessionType(ctx, explicit, "cursor"); err != nil || can {
+		t.Errorf("CanReclassifySessionType on an explicit session = %v, %v; want false", can, err)
+	}
+	if changed, err := store.ReclassifySessionType(ctx, explicit, "cursor"); err != nil || changed {
+		t.Errorf("ReclassifySessionType on an explicit session = %v, %v; want false", changed, err)
+	}
+}
@@ -22,7 +22,10 @@ const SessionDetailColumns = `
 	s.id, s.external_id, s.session_type, s.custom_title,
 	s.suggested_session_title, s.summary, s.first_user_message,
 	s.first_seen, s.cwd, s.transcript_path, s.git_info,
-	s.last_sync_at, s.hostname, s.username, u.email`
+	s.last_sync_at, s.hostname, s.username, u.email,
+	s.transcript_archived_at, s.ai_generated_title,
+	s.state, s.state_changed_at, s.is_demo,
+	s.pr_url, s.detected_pr_url, s.visibility`
 
 // SessionDetailScanTargets returns the pointer arguments for scanning a
 // row matching `SessionDetailColumns` in column order. The two row
@@ -45,5 +48,8 @@ func SessionDetailScanTargets(session *SessionDetail, gitInfoBytes *[]byte) []an
 		&session.SuggestedSessionTitle, &session.Summary, &session.FirstUserMessage,
 		&session.FirstSeen, &session.CWD, &session.TranscriptPath, gitInfoBytes,
 		&session.LastSyncAt, &session.Hostname, &session.Username, &session.OwnerEmail,
+		&session.TranscriptArchivedAt, &session.AIGeneratedTitle,
+		&session.State, &session.StateChangedAt, &session.IsDemo,
+		&session.PRURL, &session.DetectedPRURL, &session.Visibility,
 	}
 }
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// sessionOwnerCacheMaxEntries bounds the cache. When it is full, expired
+// entries are swept; if none expired, the cache starts over empty.
+const sessionOwnerCacheMaxEntries = 10000
+
+// SessionOwner is what an ownership check learns about a session.
+type SessionOwner struct {
+	UserID     int64
+	ExternalID string
+	Provider   string
+}
+
+type sessionOwnerEntry struct {
+	owner   SessionOwner
+	expires time.Time
+}
+
+// SessionOwnerCache remembers session owners for a short TTL so hot sessions
+// skip the ownership query. Only confirmed ownership is cached; a miss, a
+// different user or a deleted session always goes to the database.
+//
+// The cache is per process. Writers that delete a session or change its
+// owner must invalidate it after committing; another process keeps its
+// entry until the TTL runs out. A nil *SessionOwnerCache caches nothing.
+type SessionOwnerCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]sessionOwnerEntry
+	// epoch counts invalidations. A lookup that started before one may have
+	// read a row that is now gone, so Put drops it.
+	epoch uint64
+}
+
+// NewSessionOwnerCache returns a cache holding entries for ttl, or nil (no
+// caching) when ttl is not positive.
+func NewSessionOwnerCache(ttl time.Duration) *SessionOwnerCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &SessionOwnerCache{ttl: ttl, entries: map[string]sessionOwnerEntry{}}
+}
+
+// loadSessionOwnerCache reads SESSION_OWNER_CACHE_TTL (a Go duration). Unset,
+// unparseable or non-positive values disable the cache.
+func loadSessionOwnerCache() *SessionOwnerCache {
+	ttl, err := time.ParseDuration(os.Getenv("SESSION_OWNER_CACHE_TTL"))
+	if err != nil {
+		return nil
+	}
+	return NewSessionOwnerCache(ttl)
+}
+
+// Get returns the cached owner of sessionID, if any.
+func (c *SessionOwnerCache) Get(sessionID string) (SessionOwner, bool) {
+	if c == nil {
+		return SessionOwner{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[sessionID]
+	if !ok || time.Now().After(e.expires) {
+		return SessionOwner{}, false
+	}
+	return e.owner, true
+}
+
+// Epoch returns a token to pass to Put. Take it before querying the owner.
+func (c *SessionOwnerCache) Epoch() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.epoch
+}
+
+// Put caches owner for sessionID, unless something was invalidated since
+// epoch was taken.
+func (c *SessionOwnerCache) Put(sessionID string, owner SessionOwner, epoch uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if epoch != c.epoch {
+		return
+	}
+	now := time.Now()
+	if len(c.entries) >= sessionOwnerCacheMaxEntries {
+		for id, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, id)
+			}
+		}
+		if len(c.entries) >= sessionOwnerCacheMaxEntries {
+			c.entries = map[string]sessionOwnerEntry{}
+		}
+	}
+	c.entries[sessionID] = sessionOwnerEntry{owner: owner, expires: now.Add(c.ttl)}
+}
+
+// Invalidate drops the given sessions.
+func (c *SessionOwnerCache) Invalidate(sessionIDs ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.epoch++
+	for _, id := range sessionIDs {
+		delete(c.entries, id)
+	}
+}
+
+// InvalidateUser drops every session cached as owned by userID, for writes
+// that move or delete all of a user's sessions.
+func (c *SessionOwnerCache) InvalidateUser(userID int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.epoch++
+	for id, e := range c.entries {
+		if e.owner.UserID == userID {
+			delete(c.entries, id)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSessionOwnerCache_Disabled(t *testing.T) {
+	for _, v := range []string{"", "0", "-1s", "nonsense"} {
+		t.Setenv("SESSION_OWNER_CACHE_TTL", v)
+		if c := loadSessionOwnerCache(); c != nil {
+			t.Errorf("SESSION_OWNER_CACHE_TTL=%q: expected no cache", v)
+		}
+	}
+
+	// A nil cache is a no-op.
+	var c *SessionOwnerCache
+	c.Put("s1", SessionOwner{UserID: 1}, c.Epoch())
+	c.Invalidate("s1")
+	c.InvalidateUser(1)
+	if _, ok := c.Get("s1"); ok {
+		t.Error("nil cache returned an entry")
+	}
+}
+
+func TestSessionOwnerCache_GetPutInvalidate(t *testing.T) {
+	t.Setenv("SESSION_OWNER_CACHE_TTL", "30s")
+	c := loadSessionOwnerCache()
+	if c == nil || c.ttl != 30*time.Second {
+		t.Fatalf("expected a 30s cache, got %+v", c)
+	}
+
+	owner := SessionOwner{UserID: 1, ExternalID: "ext", Provider: "claude-code"}
+	c.Put("s1", owner, c.Epoch())
+	c.Put("s2", SessionOwner{UserID: 1}, c.Epoch())
+	c.Put("s3", SessionOwner{UserID: 2}, c.Epoch())
+
+	if got, ok := c.Get("s1"); !ok || got != owner {
+		t.Errorf("Get(s1) = %+v, %v; want %+v", got, ok, owner)
+	}
+
+	c.Invalidate("s1")
+	if _, ok := c.Get("s1"); ok {
+		t.Error("s1 survived Invalidate")
+	}
+
+	c.InvalidateUser(1)
+	if _, ok := c.Get("s2"); ok {
+		t.Error("s2 survived InvalidateUser(1)")
+	}
+	if _, ok := c.Get("s3"); !ok {
+		t.Error("InvalidateUser(1) dropped user 2's session")
+	}
+}
+
+func TestSessionOwnerCache_Expiry(t *testing.T) {
+	c := NewSessionOwnerCache(time.Millisecond)
+	c.Put("s1", SessionOwner{UserID: 1}, c.Epoch())
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get("s1"); ok {
+		t.Error("expired entry returned")
+	}
+}
+
+// TestSessionOwnerCache_PutAfterInvalidate covers a lookup that read the row
+// just before a concurrent delete committed: its result must not be cached.
+func TestSessionOwnerCache_PutAfterInvalidate(t *testing.T) {
+	c := NewSessionOwnerCache(time.Minute)
+	epoch := c.Epoch()
+	c.Invalidate("s1")
+	c.Put("s1", SessionOwner{UserID: 1}, epoch)
+	if _, ok := c.Get("s1"); ok {
+		t.Error("stale lookup was cached after an invalidation")
+	}
+}
+
+func TestSessionOwnerCache_Bounded(t *testing.T) {
+	c := NewSessionOwnerCache(time.Minute)
+	for i := 0; i <= sessionOwnerCacheMaxEntries; i++ {
+		c.Put(fmt.Sprintf("s%d", i), SessionOwner{UserID: 1}, c.Epoch())
+	}
+	if n := len(c.entries); n > sessionOwnerCacheMaxEntries {
+		t.Errorf("cache holds %d entries, want at most %d", n, sessionOwnerCacheMaxEntries)
+	}
+}
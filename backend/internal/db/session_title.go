@@ -0,0 +1,30 @@
+package db
+
+import "strings"
+
+// ResolveSessionTitle picks the title a session is displayed under, from the
+// user's custom title down to the first user message:
+//
+//	custom_title > ai_generated_title > suggested_session_title > summary > first_user_message
+//
+// Blank values are skipped. custom_title is written only by the owner
+// (PATCH /sessions/{id}/title); the other two titles are machine-written and
+// never touch it, so a recap regeneration can't change a title the user chose.
+func ResolveSessionTitle(customTitle, aiGeneratedTitle, suggestedTitle, summary, firstUserMessage *string) string {
+	for _, candidate := range []*string{customTitle, aiGeneratedTitle, suggestedTitle, summary, firstUserMessage} {
+		if candidate != nil && strings.TrimSpace(*candidate) != "" {
+			return *candidate
+		}
+	}
+	return ""
+}
+
+// ResolveTitle sets Title from the session's title sources.
+func (s *SessionDetail) ResolveTitle() {
+	s.Title = ResolveSessionTitle(s.CustomTitle, s.AIGeneratedTitle, s.SuggestedSessionTitle, s.Summary, s.FirstUserMessage)
+}
+
+// ResolveTitle sets Title from the session's title sources.
+func (s *SessionListItem) ResolveTitle() {
+	s.Title = ResolveSessionTitle(s.CustomTitle, s.AIGeneratedTitle, s.SuggestedSessionTitle, s.Summary, s.FirstUserMessage)
+}
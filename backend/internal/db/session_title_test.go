@@ -0,0 +1,27 @@
+package db
+
+import "testing"
+
+func TestResolveSessionTitle(t *testing.T) {
+	s := func(v string) *string { return &v }
+	tests := []struct {
+		name                                           string
+		custom, aiGenerated, suggested, summary, first *string
+		want                                           string
+	}{
+		{"custom wins", s("Mine"), s("AI"), s("Recap"), s("Summary"), s("First"), "Mine"},
+		{"ai over suggested", nil, s("AI"), s("Recap"), s("Summary"), s("First"), "AI"},
+		{"suggested over summary", nil, nil, s("Recap"), s("Summary"), s("First"), "Recap"},
+		{"summary over first message", nil, nil, nil, s("Summary"), s("First"), "Summary"},
+		{"first message", nil, nil, nil, nil, s("First"), "First"},
+		{"blank custom falls through", s("  "), nil, s("Recap"), nil, nil, "Recap"},
+		{"nothing", nil, nil, nil, nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveSessionTitle(tt.custom, tt.aiGenerated, tt.suggested, tt.summary, tt.first); got != tt.want {
+				t.Errorf("ResolveSessionTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// DefaultSlowQueryThreshold is the duration past which a statement is logged
+// when DB_SLOW_QUERY_LOG_THRESHOLD_MS is unset.
+const DefaultSlowQueryThreshold = time.Second
+
+// maxLoggedQueryLen caps the SQL text in a slow query log line.
+const maxLoggedQueryLen = 500
+
+// LoadSlowQueryThreshold reads DB_SLOW_QUERY_LOG_THRESHOLD_MS. 0 disables
+// slow query logging; unset, unparseable or negative values keep
+// DefaultSlowQueryThreshold.
+func LoadSlowQueryThreshold() time.Duration {
+	ms, err := strconv.Atoi(os.Getenv("DB_SLOW_QUERY_LOG_THRESHOLD_MS"))
+	if err != nil || ms < 0 {
+		return DefaultSlowQueryThreshold
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+var queryDuration = newQueryDurationHistogram()
+
+func newQueryDurationHistogram() metric.Float64Histogram {
+	h, err := otel.Meter("confab/db").Float64Histogram("db.query.duration",
+		metric.WithDescription("Duration of SQL statements, by operation"),
+		metric.WithUnit("s"))
+	if err != nil {
+		logger.Warn("failed to register db query duration histogram", "error", err)
+	}
+	return h
+}
+
+// queryTracer times every statement run through a connection: it records
+// db.query.duration and logs statements slower than threshold (0 = never).
+type queryTracer struct {
+	threshold time.Duration
+	log       *slog.Logger // nil = the request's logger (logger.Ctx)
+}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql string
+	at  time.Time
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, at: time.Now()})
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	d := time.Since(start.at)
+	op := queryOperation(start.sql)
+	if queryDuration != nil {
+		queryDuration.Record(ctx, d.Seconds(), metric.WithAttributes(attribute.String("db.operation", op)))
+	}
+	if t.threshold <= 0 || d <= t.threshold {
+		return
+	}
+	log := t.log
+	if log == nil {
+		log = logger.Ctx(ctx)
+	}
+	args := []any{"duration", d, "operation", op, "query", truncateQuery(start.sql)}
+	if data.Err != nil {
+		args = append(args, "error", data.Err)
+	}
+	log.Warn("slow query", args...)
+}
+
+// queryOperation returns the statement's leading keyword (SELECT, INSERT,
+// UPDATE, DELETE, WITH, ...) in upper case, or OTHER.
+func queryOperation(query string) string {
+	query = strings.TrimSpace(query)
+	end := strings.IndexFunc(query, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		end = len(query)
+	}
+	switch word := strings.ToUpper(query[:end]); word {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH", "BEGIN", "COMMIT", "ROLLBACK", "SET":
+		return word
+	}
+	return "OTHER"
+}
+
+// truncateQuery collapses the statement's whitespace onto one line and cuts
+// it to at most maxLoggedQueryLen bytes, backing off to a rune boundary so the
+// logged text stays valid UTF-8.
+func truncateQuery(query string) string {
+	q := strings.Join(strings.Fields(query), " ")
+	if len(q) > maxLoggedQueryLen {
+		cut := maxLoggedQueryLen
+		for cut > 0 && !utf8.RuneStart(q[cut]) {
+			cut--
+		}
+		q = q[:cut] + "..."
+	}
+	return q
+}
+
+// openPool opens a database/sql pool over pgx with the query tracer attached.
+func openPool(dsn string, threshold time.Duration) (*sql.DB, error) {
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	cfg.Tracer = &queryTracer{threshold: threshold}
+	return stdlib.OpenDB(*cfg), nil
+}
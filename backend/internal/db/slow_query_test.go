@@ -0,0 +1,104 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestLoadSlowQueryThreshold(t *testing.T) {
+	for _, tt := range []struct {
+		env  string
+		want time.Duration
+	}{
+		{"", DefaultSlowQueryThreshold},
+		{"250", 250 * time.Millisecond},
+		{"0", 0},
+		{"-1", DefaultSlowQueryThreshold},
+		{"slow", DefaultSlowQueryThreshold},
+	} {
+		t.Setenv("DB_SLOW_QUERY_LOG_THRESHOLD_MS", tt.env)
+		if got := LoadSlowQueryThreshold(); got != tt.want {
+			t.Errorf("DB_SLOW_QUERY_LOG_THRESHOLD_MS=%q: got %v, want %v", tt.env, got, tt.want)
+		}
+	}
+}
+
+// traceQuery runs sql through tr as if it took d.
+func traceQuery(tr *queryTracer, sql string, d time.Duration, err error) {
+	ctx := tr.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: sql})
+	start := ctx.Value(queryStartKey{}).(queryStart)
+	start.at = start.at.Add(-d)
+	ctx = context.WithValue(ctx, queryStartKey{}, start)
+	tr.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: err})
+}
+
+func TestQueryTracer_LogsSlowQueries(t *testing.T) {
+	var buf bytes.Buffer
+	tr := &queryTracer{threshold: time.Second, log: slog.New(slog.NewTextHandler(&buf, nil))}
+
+	traceQuery(tr, "SELECT 1", 10*time.Millisecond, nil)
+	if buf.Len() != 0 {
+		t.Fatalf("fast query logged: %s", buf.String())
+	}
+
+	traceQuery(tr, "UPDATE sessions\n   SET title = $1\n WHERE id = $2", 2*time.Second, errors.New("canceled"))
+	out := buf.String()
+	for _, want := range []string{"level=WARN", `msg="slow query"`, "operation=UPDATE", `query="UPDATE sessions SET title = $1 WHERE id = $2"`, "error=canceled"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log line %q missing %s", out, want)
+		}
+	}
+
+	buf.Reset()
+	traceQuery(&queryTracer{threshold: 0, log: tr.log}, "SELECT pg_sleep(5)", 5*time.Second, nil)
+	if buf.Len() != 0 {
+		t.Errorf("threshold 0 logged: %s", buf.String())
+	}
+}
+
+func TestQueryOperation(t *testing.T) {
+	for sql, want := range map[string]string{
+		"SELECT 1":                        "SELECT",
+		"  select\n id from sessions":     "SELECT",
+		"INSERT INTO t VALUES ($1)":       "INSERT",
+		"WITH x AS (SELECT 1) SELECT *":   "WITH",
+		"delete from t":                   "DELETE",
+		"SET LOCAL statement_timeout = 1": "SET",
+		"VACUUM ANALYZE sessions":         "OTHER",
+		"":                                "OTHER",
+		"(SELECT 1) UNION (SELECT 2)":     "OTHER",
+	} {
+		if got := queryOperation(sql); got != want {
+			t.Errorf("queryOperation(%q) = %s, want %s", sql, got, want)
+		}
+	}
+}
+
+func TestTruncateQuery(t *testing.T) {
+	long := "SELECT " + strings.Repeat("x", 1000)
+	got := truncateQuery(long)
+	if len(got) != maxLoggedQueryLen+len("...") || !strings.HasSuffix(got, "...") {
+		t.Errorf("truncateQuery length = %d, want %d with an ellipsis", len(got), maxLoggedQueryLen+3)
+	}
+	if got := truncateQuery("SELECT\n\t1"); got != "SELECT 1" {
+		t.Errorf("truncateQuery = %q, want whitespace collapsed", got)
+	}
+
+	// A multi-byte rune straddling the cut is dropped whole, not split.
+	multi := "SELECT '" + strings.Repeat("x", maxLoggedQueryLen-9) + "é'"
+	got = truncateQuery(multi)
+	if !utf8.ValidString(got) {
+		t.Errorf("truncateQuery produced invalid UTF-8: %q", got[len(got)-8:])
+	}
+	if want := multi[:maxLoggedQueryLen-1] + "..."; got != want {
+		t.Errorf("truncateQuery = ...%q, want ...%q", got[len(got)-8:], want[len(want)-8:])
+	}
+}
@@ -23,8 +23,12 @@ type SessionListItem struct {
 	LastSyncTime          *time.Time `json:"last_sync_time,omitempty"`          // Last sync timestamp
 	CustomTitle           *string    `json:"custom_title,omitempty"`            // User-set title override
 	SuggestedSessionTitle *string    `json:"suggested_session_title,omitempty"` // AI-suggested title from Smart Recap
+	AIGeneratedTitle      *string    `json:"ai_generated_title,omitempty"`      // On-demand title from POST /sessions/{id}/generate-title
 	Summary               *string    `json:"summary,omitempty"`                 // First summary from transcript
 	FirstUserMessage      *string    `json:"first_user_message,omitempty"`      // First user message
+	// Title is the resolved display title (see ResolveSessionTitle). Computed
+	// after scan; not a column.
+	Title string `json:"title"`
 	// Provider is the canonical agent identifier ("claude-code" or "codex").
 	// Legacy 'Claude Code' DB values are normalized to "claude-code" at Scan
 	// time via models.NormalizeProvider so the public API never exposes the
@@ -38,10 +42,25 @@ type SessionListItem struct {
 	GitHubPRs        []string   `json:"github_prs,omitempty"`         // Linked GitHub PR URLs (e.g., ["https://github.com/org/repo/pull/123"])
 	GitHubCommits    []string   `json:"github_commits,omitempty"`     // Linked GitHub commit SHAs (latest first)
 	IsOwner          bool       `json:"is_owner"`                     // true if user owns this session
-	AccessType       string     `json:"access_type"`                  // "owner" | "private_share" | "public_share" | "system_share"
+	AccessType       string     `json:"access_type"`                  // "owner" | "private_share" | "public_share" | "system_share" | "org_share"
 	SharedByEmail    *string    `json:"shared_by_email,omitempty"`    // email of user who shared (if not owner)
 	OwnerEmail       string     `json:"owner_email"`                  // email of session owner (always populated)
 	EstimatedCostUSD *string    `json:"estimated_cost_usd,omitempty"` // Estimated API cost from analytics
+	DuplicateOf      *string    `json:"duplicate_of,omitempty"`       // Earliest owned session with the same content fingerprint (probable duplicate)
+	SearchRank       *float64   `json:"search_rank,omitempty"`        // Full-text relevance under SEARCH_WEIGHT_A/B/C (search results only)
+	InterestScore    *float64   `json:"interest_score,omitempty"`     // db.InterestScore (sort=interesting only)
+	State            string     `json:"state"`                        // Lifecycle state (see dbsession.States)
+	StateChangedAt   time.Time  `json:"state_changed_at"`             // When State last changed
+	IsDemo           bool       `json:"is_demo"`                      // Sample session seeded by POST /api/v1/me/demo
+	// HasOutOfTreeAccess is true when the code activity card recorded file
+	// reads or writes outside the session cwd.
+	HasOutOfTreeAccess bool `json:"has_out_of_tree_access"`
+	// DurationMs is the session card's duration; nil until the card is
+	// computed or when it has none.
+	DurationMs *int64 `json:"duration_ms,omitempty"`
+	// Growth is the growth history of the session's transcript file (see
+	// SyncFileDetail.Growth). Only filled with include=growth.
+	Growth []GrowthPoint `json:"growth,omitempty"`
 }
 
 // SessionListParams contains filtering and pagination parameters for listing sessions
@@ -51,12 +70,51 @@ type SessionListParams struct {
 	Owners    []string // email addresses (multi-select)
 	PRs       []string // PR number strings (multi-select)
 	Providers []string // canonical agent identifiers ("claude-code", "codex"); multi-select
-	Query     *string  // search across titles + commit SHA prefix
+	States    []string // lifecycle states ("active", "idle", ...); multi-select
+	// OutOfTreeAccess, when set, keeps only sessions whose HasOutOfTreeAccess
+	// matches.
+	OutOfTreeAccess *bool
+	Query     *string  // full-text search (ranked by relevance) + commit SHA / ID prefix
+	Sort      string   // SessionSortRecent (default) or SessionSortInteresting; ignored with Query
+	// SortKeys, when set, orders by these SessionSortKeys instead of Sort,
+	// the first key first (at most MaxSessionSortKeys); ignored with Query.
+	SortKeys []string
+	// IncludeGrowth fills SessionListItem.Growth (include=growth).
+	IncludeGrowth bool
 
 	Cursor   string // opaque cursor for keyset pagination (empty = first page)
 	PageSize int    // fixed 50
 }
 
+// Session list orderings (SessionListParams.Sort).
+const (
+	SessionSortRecent      = "recent"      // last activity, newest first
+	SessionSortInteresting = "interesting" // interest_score (see InterestScore), highest first
+)
+
+// Session list sort keys (SessionListParams.SortKeys). Sessions whose card
+// is not computed yet sort after the others for cost and duration, in either
+// direction.
+const (
+	SessionSortCreatedAtAsc  = "created_at_asc"  // first_seen, oldest first
+	SessionSortCreatedAtDesc = "created_at_desc" // first_seen, newest first
+	SessionSortCostDesc      = "cost_desc"       // tokens_v2 card estimated cost, highest first
+	SessionSortCostAsc       = "cost_asc"        // tokens_v2 card estimated cost, lowest first
+	SessionSortDurationDesc  = "duration_desc"   // session card duration, longest first
+	SessionSortDurationAsc   = "duration_asc"    // session card duration, shortest first
+	SessionSortLineCountDesc = "line_count_desc" // total synced lines, most first
+
+	MaxSessionSortKeys = 3
+)
+
+// SessionSortKeys lists the valid SessionListParams.SortKeys values.
+var SessionSortKeys = []string{
+	SessionSortCreatedAtAsc, SessionSortCreatedAtDesc,
+	SessionSortCostDesc, SessionSortCostAsc,
+	SessionSortDurationDesc, SessionSortDurationAsc,
+	SessionSortLineCountDesc,
+}
+
 // SessionListResult is the paginated response for listing sessions
 type SessionListResult struct {
 	Sessions      []SessionListItem    `json:"sessions"`
@@ -84,8 +142,12 @@ type SessionDetail struct {
 	Provider              string           `json:"provider"`
 	CustomTitle           *string          `json:"custom_title,omitempty"`            // User-set title override
 	SuggestedSessionTitle *string          `json:"suggested_session_title,omitempty"` // AI-suggested title from Smart Recap
+	AIGeneratedTitle      *string          `json:"ai_generated_title,omitempty"`      // On-demand title from POST /sessions/{id}/generate-title
 	Summary               *string          `json:"summary,omitempty"`                 // First summary from transcript
 	FirstUserMessage      *string          `json:"first_user_message,omitempty"`      // First user message
+	// Title is the resolved display title (see ResolveSessionTitle). Computed
+	// after scan; not a column.
+	Title string `json:"title"`
 	FirstSeen             time.Time        `json:"first_seen"`
 	CWD              *string          `json:"cwd,omitempty" pii:"redact"`                // Working directory
 	TranscriptPath   *string          `json:"transcript_path,omitempty" pii:"redact"`    // Original transcript path
@@ -97,6 +159,23 @@ type SessionDetail struct {
 	IsOwner          *bool            `json:"is_owner,omitempty"`           // True if viewer is session owner (shared sessions only)
 	SharedByEmail    *string          `json:"shared_by_email,omitempty"`    // Email of session owner (non-owner access only)
 	OwnerEmail       string           `json:"owner_email"`                  // Email of session owner (always populated)
+	// TranscriptArchivedAt is set once the worker's transcript retention has
+	// deleted the raw chunks. Cards and search still work; raw file reads
+	// answer 410 Gone.
+	TranscriptArchivedAt *time.Time `json:"transcript_archived_at,omitempty"`
+	// State is the lifecycle state (see dbsession.States), last changed at
+	// StateChangedAt.
+	State          string    `json:"state"`
+	StateChangedAt time.Time `json:"state_changed_at"`
+	// IsDemo marks a sample session seeded by POST /api/v1/me/demo.
+	IsDemo bool `json:"is_demo"`
+	// PRURL is the pull request the owner linked the session to;
+	// DetectedPRURL the one card computation found in gh pr create output.
+	// PRURL wins when both are set.
+	PRURL         *string `json:"pr_url,omitempty"`
+	DetectedPRURL *string `json:"detected_pr_url,omitempty"`
+	// Visibility is SessionVisibilityPrivate or SessionVisibilityOrg.
+	Visibility string `json:"visibility"`
 }
 
 // RedactForSharing strips PII fields that should not be visible to non-owners.
@@ -114,6 +193,34 @@ type SyncFileDetail struct {
 	FileType       string    `json:"file_type"`
 	LastSyncedLine int       `json:"last_synced_line"`
 	UpdatedAt      time.Time `json:"updated_at"`
+	// Generation counts the file's resets; generations below it are archived
+	// and readable via the sync file read's ?generation= parameter.
+	Generation int `json:"generation"`
+	// Growth is last_synced_line after each recent chunk upload of the live
+	// generation, oldest first and at most GrowthHistoryCap points.
+	Growth []GrowthPoint `json:"growth"`
+}
+
+// BulkDeleteFilter selects the caller's own sessions for bulk deletion.
+// Non-empty criteria are ANDed together; a filter with no criteria matches
+// nothing so an empty request body can never wipe an account.
+type BulkDeleteFilter struct {
+	SessionIDs []string   // explicit session UUIDs (non-UUID values simply match nothing)
+	OlderThan  *time.Time // COALESCE(last_message_at, first_seen) strictly before this instant
+	DemoOnly   bool       // only sample sessions seeded by POST /api/v1/me/demo
+}
+
+// IsEmpty reports whether the filter has no selection criteria.
+func (f BulkDeleteFilter) IsEmpty() bool {
+	return len(f.SessionIDs) == 0 && f.OlderThan == nil && !f.DemoOnly
+}
+
+// BulkDeleteTarget identifies one session selected for bulk deletion, with
+// the external_id and canonical provider needed to remove its S3 chunks.
+type BulkDeleteTarget struct {
+	ID         string
+	ExternalID string
+	Provider   string
 }
 
 // SessionShare represents a share link
@@ -133,6 +240,59 @@ type SessionShare struct {
 	Recipients     []string   `json:"recipients,omitempty"` // email addresses of recipients
 }
 
+// ShareAccessStats summarizes the recorded views of one share
+// (GET /api/v1/sessions/{id}/share/{shareID}/stats).
+type ShareAccessStats struct {
+	ShareID int64 `json:"share_id"`
+	// TotalViews and UniqueViewers cover the share's whole life. Viewers are
+	// approximate: one per truncated IP, so a shared network counts once.
+	TotalViews       int64            `json:"total_views"`
+	UniqueViewers    int64            `json:"unique_viewers"`
+	LastViewedAt     *time.Time       `json:"last_viewed_at,omitempty"`
+	ViewsByUserAgent map[string]int64 `json:"views_by_user_agent"`
+	// Daily has one entry per UTC day of the trailing window, oldest first,
+	// including days without views.
+	Daily []ShareAccessDay `json:"daily"`
+}
+
+// ShareAccessDay is one day of a share's views.
+type ShareAccessDay struct {
+	Date          string `json:"date"` // YYYY-MM-DD (UTC)
+	Views         int64  `json:"views"`
+	UniqueViewers int64  `json:"unique_viewers"`
+}
+
+// APIKeyUsageCounts are chunk upload counters for one API key.
+type APIKeyUsageCounts struct {
+	Requests int64 `json:"requests"`
+	Lines    int64 `json:"lines"`
+	Bytes    int64 `json:"bytes"`
+}
+
+// APIKeyUsageDelta is a batch of counts to add to one key's UTC day.
+type APIKeyUsageDelta struct {
+	APIKeyID int64
+	Day      time.Time // UTC midnight
+	APIKeyUsageCounts
+}
+
+// APIKeyUsage summarizes what one API key did (GET /api/v1/keys/{id}/usage).
+type APIKeyUsage struct {
+	APIKeyID            int64 `json:"api_key_id"`
+	CreatedSessionCount int64 `json:"created_session_count"`
+	// Totals cover every recorded day, up to the usage retention window.
+	Totals APIKeyUsageCounts `json:"totals"`
+	// Daily has one entry per UTC day of the trailing window, oldest first,
+	// including days with no uploads.
+	Daily []APIKeyUsageDay `json:"daily"`
+}
+
+// APIKeyUsageDay is one day of an API key's chunk uploads.
+type APIKeyUsageDay struct {
+	Date string `json:"date"` // YYYY-MM-DD (UTC)
+	APIKeyUsageCounts
+}
+
 // ShareWithSessionInfo includes both share and session details
 type ShareWithSessionInfo struct {
 	SessionShare
@@ -163,8 +323,54 @@ type SyncFileState struct {
 	// Do NOT use this to truncate key lists on read - always list actual S3 objects.
 	// The read path self-heals this value by comparing against actual S3 chunk count.
 	ChunkCount *int `json:"chunk_count"`
+	// Generation is the live generation; it advances on every file reset.
+	Generation int `json:"generation"`
+}
+
+// SyncFileGeneration is an archived generation of a synced file: the lines
+// it held when the client reset the file, and why.
+type SyncFileGeneration struct {
+	Generation     int       `json:"generation"`
+	FileType       string    `json:"file_type"`
+	LineCount      int       `json:"line_count"`
+	ChunkCount     *int      `json:"chunk_count"`
+	Reason         string    `json:"reason"`
+	LocalLineCount int       `json:"local_line_count"`
+	ResetAt        time.Time `json:"reset_at"`
+}
+
+// UserStorageUsage is the chunk storage a user's sessions occupy: live sync
+// files plus archived generations, excluding sessions whose transcript was
+// archived (their chunks are gone). Totals cover every session; Sessions is
+// the largest ones first, capped by the caller's limit.
+type UserStorageUsage struct {
+	TotalBytes   int64 `json:"total_bytes"`
+	ChunkCount   int64 `json:"chunk_count"`
+	SessionCount int   `json:"session_count"`
+	// PendingFileCount counts files synced before storage accounting whose
+	// size the worker has not backfilled yet; their bytes are not in the totals.
+	PendingFileCount int                   `json:"pending_file_count"`
+	Sessions         []SessionStorageUsage `json:"sessions"`
 }
 
+// SessionStorageUsage is one session's share of UserStorageUsage.
+type SessionStorageUsage struct {
+	SessionID        string `json:"session_id"`
+	ExternalID       string `json:"external_id"`
+	Provider         string `json:"provider"`
+	Title            string `json:"title"`
+	StoredBytes      int64  `json:"stored_bytes"`
+	ChunkCount       int64  `json:"chunk_count"` // estimate, like SyncFileState.ChunkCount
+	PendingFileCount int    `json:"pending_file_count"`
+}
+
+// How a session's session_type was decided (sessions.session_type_source).
+const (
+	SessionTypeSourceDefault  = "default"  // sync/init sent no type; claude-code was assumed
+	SessionTypeSourceExplicit = "explicit" // sync/init sent session_type or provider
+	SessionTypeSourceDetected = "detected" // A default type corrected from the first transcript chunk
+)
+
 // SyncSessionParams contains parameters for creating/updating a sync session
 type SyncSessionParams struct {
 	ExternalID     string
@@ -178,6 +384,13 @@ type SyncSessionParams struct {
 	// must default empty/missing to "claude-code" and validate before
 	// passing in.
 	Provider string
+	// ProviderExplicit is true when the client sent the type rather than
+	// leaving it to default. Only defaulted sessions may later be
+	// reclassified from their first transcript chunk.
+	ProviderExplicit bool
+	// APIKeyID is the API key making the call, recorded as the new session's
+	// created_by_api_key_id. Nil for callers not authenticated by API key.
+	APIKeyID *int64
 }
 
 // SessionEventParams contains parameters for inserting a session event
@@ -197,8 +410,22 @@ const (
 	SessionAccessPublic    SessionAccessType = "public"
 	SessionAccessSystem    SessionAccessType = "system"
 	SessionAccessRecipient SessionAccessType = "recipient"
+	SessionAccessOrg       SessionAccessType = "org" // viewer shares an organization with the owner of an org-visible session
 )
 
+// Session visibility values (sessions.visibility). Private sessions are seen
+// by their owner and explicit shares only; org sessions are also readable by
+// members of the owner's organizations.
+const (
+	SessionVisibilityPrivate = "private"
+	SessionVisibilityOrg     = "org"
+)
+
+// IsValidSessionVisibility reports whether v is a session visibility value.
+func IsValidSessionVisibility(v string) bool {
+	return v == SessionVisibilityPrivate || v == SessionVisibilityOrg
+}
+
 // SessionAccessInfo contains information about how a user can access a session
 type SessionAccessInfo struct {
 	AccessType  SessionAccessType
@@ -0,0 +1,248 @@
+package user
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+)
+
+// MergeResult counts what MergeUsers moved from the source to the target.
+type MergeResult struct {
+	SessionsMoved   int `json:"sessions_moved"`
+	APIKeysMoved    int `json:"api_keys_moved"`
+	IdentitiesMoved int `json:"identities_moved"`
+}
+
+// MergeConflicts returns the external IDs (at most limit) of sessions both
+// users own. Any conflict blocks MergeUsers: one user cannot hold two sessions
+// with the same external ID, and their objects would share a storage prefix.
+func (s *Store) MergeConflicts(ctx context.Context, sourceID, targetID int64, limit int) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "db.user_merge_conflicts",
+		trace.WithAttributes(
+			attribute.Int64("merge.source_user_id", sourceID),
+			attribute.Int64("merge.target_user_id", targetID),
+		))
+	defer span.End()
+
+	rows, err := s.conn().QueryContext(ctx, mergeConflictsSQL, sourceID, targetID, limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to find merge conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	conflicts := []string{}
+	for rows.Next() {
+		var externalID string
+		if err := rows.Scan(&externalID); err != nil {
+			return nil, fmt.Errorf("failed to scan merge conflict: %w", err)
+		}
+		conflicts = append(conflicts, externalID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate merge conflicts: %w", err)
+	}
+	return conflicts, nil
+}
+
+const mergeConflictsSQL = `
+	SELECT DISTINCT src.external_id
+	FROM sessions src
+	JOIN sessions dst ON dst.external_id = src.external_id AND dst.user_id = $2
+	WHERE src.user_id = $1
+	ORDER BY src.external_id
+	LIMIT $3
+`
+
+// MergeUsers moves everything the source user owns to the target and deletes
+// the source, in one transaction:
+//
+//   - sessions (with their state log and codex rollouts), data exports and
+//     share recipient grants;
+//   - API keys, renamed "<name> (merged <id>)" where the target already has
+//     the name;
+//   - provider identities, so the source's logins now reach the target;
+//   - the per-user search setting, and the smart recap quota and preferences
+//     rows when the target has none; feature-flag allowlist entries follow
+//     the user ID.
+//
+// Web sessions and pending device codes go with the source. A user_merges row
+// records the merge. Returns db.ErrUserNotFound if either user is missing and
+// db.ErrUserMergeConflict if both own a session with the same external ID.
+//
+// Stored object keys are rewritten from the source's "{id}/" prefix to the
+// target's, so the caller must copy the source's objects there first
+// (storage.CopyAllUserData) and delete the originals after the commit.
+func (s *Store) MergeUsers(ctx context.Context, sourceID, targetID, adminUserID int64) (*MergeResult, error) {
+	ctx, span := tracer.Start(ctx, "db.merge_users",
+		trace.WithAttributes(
+			attribute.Int64("merge.source_user_id", sourceID),
+			attribute.Int64("merge.target_user_id", targetID),
+		))
+	defer span.End()
+
+	result, err := s.mergeUsers(ctx, sourceID, targetID, adminUserID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(
+		attribute.Int("merge.sessions_moved", result.SessionsMoved),
+		attribute.Int("merge.api_keys_moved", result.APIKeysMoved),
+	)
+	return result, nil
+}
+
+func (s *Store) mergeUsers(ctx context.Context, sourceID, targetID, adminUserID int64) (*MergeResult, error) {
+	tx, err := s.conn().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock both rows (in id order, so concurrent merges can't deadlock) to
+	// hold off logins and other merges touching either account.
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, email FROM users WHERE id = ANY($1) ORDER BY id FOR UPDATE`,
+		pq.Array([]int64{sourceID, targetID}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock users: %w", err)
+	}
+	var sourceEmail string
+	found := 0
+	for rows.Next() {
+		var id int64
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		if id == sourceID {
+			sourceEmail = email
+		}
+		found++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lock users: %w", err)
+	}
+	if found != 2 {
+		return nil, db.ErrUserNotFound
+	}
+
+	var conflict bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (`+mergeConflictsSQL+`)`, sourceID, targetID, 1).Scan(&conflict); err != nil {
+		return nil, fmt.Errorf("failed to check merge conflicts: %w", err)
+	}
+	if conflict {
+		return nil, db.ErrUserMergeConflict
+	}
+
+	result := &MergeResult{}
+	exec := func(what, query string, count *int) error {
+		res, err := tx.ExecContext(ctx, query, sourceID, targetID)
+		if err != nil {
+			return fmt.Errorf("failed to move %s: %w", what, err)
+		}
+		if count != nil {
+			n, err := res.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to count moved %s: %w", what, err)
+			}
+			*count = int(n)
+		}
+		return nil
+	}
+
+	steps := []struct {
+		what  string
+		query string
+		count *int
+	}{
+		{"sessions", `UPDATE sessions SET user_id = $2 WHERE user_id = $1`, &result.SessionsMoved},
+		{"session state log", `UPDATE session_state_log SET user_id = $2 WHERE user_id = $1`, nil},
+		{"codex rollouts", `UPDATE codex_rollouts SET user_id = $2 WHERE user_id = $1`, nil},
+		{"share recipients", `UPDATE session_share_recipients SET user_id = $2 WHERE user_id = $1`, nil},
+		{"data exports", `
+			UPDATE user_data_exports
+			SET user_id = $2,
+				object_key = CASE
+					WHEN object_key LIKE $1::text || '/%' THEN $2::text || substr(object_key, length($1::text) + 1)
+					ELSE object_key
+				END
+			WHERE user_id = $1`, nil},
+		// May leave the target over dbwebhook.MaxEndpointsPerUser, which only
+		// limits creating endpoints.
+		{"webhook endpoints", `UPDATE webhook_endpoints SET user_id = $2 WHERE user_id = $1`, nil},
+		// Held back by the source's quiet hours; they go out on the target's.
+		{"queued notifications", `UPDATE notification_queue SET user_id = $2 WHERE user_id = $1`, nil},
+		// Sessions moved above; pending upload events still name the source's keys.
+		{"chunk upload events", `
+			UPDATE chunk_upload_events e
+			SET s3_key = $2::text || substr(e.s3_key, length($1::text) + 1)
+			FROM sessions s
+			WHERE s.id = e.session_id AND s.user_id = $2
+			  AND e.s3_key LIKE $1::text || '/%'`, nil},
+		{"api keys", `
+			UPDATE api_keys k
+			SET user_id = $2,
+				name = CASE
+					WHEN EXISTS (SELECT 1 FROM api_keys t WHERE t.user_id = $2 AND t.name = k.name)
+						THEN left(k.name, 200) || ' (merged ' || k.id || ')'
+					ELSE k.name
+				END
+			WHERE k.user_id = $1`, &result.APIKeysMoved},
+		{"identities", `UPDATE user_identities SET user_id = $2 WHERE user_id = $1`, &result.IdentitiesMoved},
+		{"settings", `
+			UPDATE users t
+			SET include_agent_files_in_search = s.include_agent_files_in_search, updated_at = NOW()
+			FROM users s
+			WHERE s.id = $1 AND t.id = $2`, nil},
+		{"smart recap quota", `
+			UPDATE smart_recap_quota SET user_id = $2
+			WHERE user_id = $1 AND NOT EXISTS (SELECT 1 FROM smart_recap_quota WHERE user_id = $2)`, nil},
+		{"preferences", `
+			UPDATE user_preferences SET user_id = $2
+			WHERE user_id = $1 AND NOT EXISTS (SELECT 1 FROM user_preferences WHERE user_id = $2)`, nil},
+		{"feature flag allowlists", `
+			UPDATE feature_flags
+			SET enabled_user_ids = ARRAY(SELECT DISTINCT unnest(array_replace(enabled_user_ids, $1::bigint, $2::bigint)))
+			WHERE $1::bigint = ANY(enabled_user_ids)`, nil},
+	}
+	for _, step := range steps {
+		if err := exec(step.what, step.query, step.count); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_merges (source_user_id, source_email, target_user_id, admin_user_id,
+			sessions_moved, api_keys_moved, identities_moved)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		sourceID, sourceEmail, targetID, adminUserID,
+		result.SessionsMoved, result.APIKeysMoved, result.IdentitiesMoved,
+	); err != nil {
+		return nil, fmt.Errorf("failed to record merge: %w", err)
+	}
+
+	// Idempotency keys stay behind and cascade with the source: their hash
+	// includes the source's ID, so no request of the target could match them.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, sourceID); err != nil {
+		return nil, fmt.Errorf("failed to delete merged user: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit merge: %w", err)
+	}
+	s.DB.SessionOwners.InvalidateUser(sourceID)
+	return result, nil
+}
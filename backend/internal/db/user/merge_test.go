@@ -0,0 +1,187 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab-web/internal/db"
+	dbuser "github.com/ConfabulousDev/confab-web/internal/db/user"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+// TestMergeUsers moves every owned row from the source to the target and
+// checks nothing is left pointing at the deleted source.
+func TestMergeUsers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+	store := &dbuser.Store{DB: env.DB}
+	ctx := context.Background()
+
+	source := testutil.CreateTestUser(t, env, "old@example.com", "Old")
+	target := testutil.CreateTestUser(t, env, "new@example.com", "New")
+	admin := testutil.CreateTestUser(t, env, "admin@example.com", "Admin")
+
+	sessionID := testutil.CreateTestSession(t, env, source.ID, "source-session")
+	testutil.CreateTestSession(t, env, target.ID, "target-session")
+	testutil.CreateTestAPIKey(t, env, source.ID, "hash-source-laptop", "laptop")
+	testutil.CreateTestAPIKey(t, env, source.ID, "hash-source-ci", "ci")
+	testutil.CreateTestAPIKey(t, env, target.ID, "hash-target-laptop", "laptop")
+
+	if _, err := env.DB.Exec(env.Ctx, `
+		INSERT INTO chunk_upload_events (session_id, file_name, file_type, first_line, last_line, s3_key, chunk_bytes)
+		VALUES ($1, 'transcript.jsonl', 'transcript', 1, 10, $2, 100)`,
+		sessionID, chunkKey(source.ID, "chunks/transcript.jsonl/chunk_00000001_00000010.jsonl"),
+	); err != nil {
+		t.Fatalf("insert chunk upload event: %v", err)
+	}
+	if _, err := env.DB.Exec(env.Ctx, `
+		INSERT INTO feature_flags (flag_name, enabled_user_ids) VALUES ('merge-test', $1)`,
+		"{"+itoa(source.ID)+","+itoa(target.ID)+"}",
+	); err != nil {
+		t.Fatalf("insert feature flag: %v", err)
+	}
+	digest := dbuser.DigestWeekly
+	if _, err := store.UpdatePreferences(ctx, source.ID, dbuser.PreferencesUpdate{DigestFrequency: &digest}); err != nil {
+		t.Fatalf("UpdatePreferences: %v", err)
+	}
+
+	result, err := store.MergeUsers(ctx, source.ID, target.ID, admin.ID)
+	if err != nil {
+		t.Fatalf("MergeUsers failed: %v", err)
+	}
+	want := dbuser.MergeResult{SessionsMoved: 1, APIKeysMoved: 2, IdentitiesMoved: 1}
+	if *result != want {
+		t.Errorf("result = %+v, want %+v", *result, want)
+	}
+
+	if _, err := store.GetUserByID(ctx, source.ID); !errors.Is(err, db.ErrUserNotFound) {
+		t.Errorf("source user still exists (err = %v)", err)
+	}
+
+	var owner int64
+	if err := env.DB.QueryRow(env.Ctx, `SELECT user_id FROM sessions WHERE id = $1`, sessionID).Scan(&owner); err != nil {
+		t.Fatalf("query session: %v", err)
+	}
+	if owner != target.ID {
+		t.Errorf("session owner = %d, want %d", owner, target.ID)
+	}
+
+	var names []string
+	rows, err := env.DB.Conn().QueryContext(env.Ctx, `SELECT name FROM api_keys WHERE user_id = $1 ORDER BY name`, target.ID)
+	if err != nil {
+		t.Fatalf("query api keys: %v", err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("scan api key: %v", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if len(names) != 3 || names[0] != "ci" || names[1] != "laptop" || !strings.HasPrefix(names[2], "laptop (merged ") {
+		t.Errorf("target api keys = %q, want ci, laptop and a renamed laptop", names)
+	}
+
+	var identities int
+	if err := env.DB.QueryRow(env.Ctx, `SELECT COUNT(*) FROM user_identities WHERE user_id = $1`, target.ID).Scan(&identities); err != nil {
+		t.Fatalf("count identities: %v", err)
+	}
+	if identities != 2 {
+		t.Errorf("target identities = %d, want 2", identities)
+	}
+
+	var s3Key string
+	if err := env.DB.QueryRow(env.Ctx, `SELECT s3_key FROM chunk_upload_events WHERE session_id = $1`, sessionID).Scan(&s3Key); err != nil {
+		t.Fatalf("query chunk upload event: %v", err)
+	}
+	if wantKey := chunkKey(target.ID, "chunks/transcript.jsonl/chunk_00000001_00000010.jsonl"); s3Key != wantKey {
+		t.Errorf("s3_key = %q, want %q", s3Key, wantKey)
+	}
+
+	var flagUsers string
+	if err := env.DB.QueryRow(env.Ctx, `SELECT enabled_user_ids::text FROM feature_flags WHERE flag_name = 'merge-test'`).Scan(&flagUsers); err != nil {
+		t.Fatalf("query feature flag: %v", err)
+	}
+	if want := "{" + itoa(target.ID) + "}"; flagUsers != want {
+		t.Errorf("enabled_user_ids = %s, want %s", flagUsers, want)
+	}
+
+	if prefs, err := store.GetPreferences(ctx, target.ID); err != nil {
+		t.Fatalf("GetPreferences: %v", err)
+	} else if prefs.DigestFrequency != dbuser.DigestWeekly {
+		t.Errorf("target digest_frequency = %q, want the source's %q", prefs.DigestFrequency, dbuser.DigestWeekly)
+	}
+
+	var logged int
+	if err := env.DB.QueryRow(env.Ctx, `
+		SELECT sessions_moved FROM user_merges
+		WHERE source_user_id = $1 AND target_user_id = $2 AND admin_user_id = $3 AND source_email = 'old@example.com'`,
+		source.ID, target.ID, admin.ID).Scan(&logged); err != nil {
+		t.Fatalf("query user_merges: %v", err)
+	}
+	if logged != 1 {
+		t.Errorf("user_merges.sessions_moved = %d, want 1", logged)
+	}
+}
+
+func TestMergeUsers_Conflict(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+	store := &dbuser.Store{DB: env.DB}
+	ctx := context.Background()
+
+	source := testutil.CreateTestUser(t, env, "old@example.com", "Old")
+	target := testutil.CreateTestUser(t, env, "new@example.com", "New")
+	testutil.CreateTestSession(t, env, source.ID, "shared-session")
+	testutil.CreateTestSession(t, env, target.ID, "shared-session")
+
+	conflicts, err := store.MergeConflicts(ctx, source.ID, target.ID, 10)
+	if err != nil {
+		t.Fatalf("MergeConflicts failed: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0] != "shared-session" {
+		t.Errorf("conflicts = %q, want [shared-session]", conflicts)
+	}
+
+	if _, err := store.MergeUsers(ctx, source.ID, target.ID, target.ID); !errors.Is(err, db.ErrUserMergeConflict) {
+		t.Fatalf("MergeUsers err = %v, want ErrUserMergeConflict", err)
+	}
+	if _, err := store.GetUserByID(ctx, source.ID); err != nil {
+		t.Errorf("source user was deleted despite the conflict: %v", err)
+	}
+}
+
+func TestMergeUsers_MissingUser(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+	store := &dbuser.Store{DB: env.DB}
+
+	target := testutil.CreateTestUser(t, env, "new@example.com", "New")
+	if _, err := store.MergeUsers(context.Background(), 999999, target.ID, target.ID); !errors.Is(err, db.ErrUserNotFound) {
+		t.Errorf("err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func chunkKey(userID int64, rest string) string {
+	return itoa(userID) + "/claude-code/source-session/" + rest
+}
+
+func itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
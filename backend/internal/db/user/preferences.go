@@ -0,0 +1,239 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Digest frequencies (Preferences.DigestFrequency).
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// Preferences are a user's per-feature choices, stored in user_preferences.
+// Features read them through GetPreferences rather than adding columns to
+// users.
+type Preferences struct {
+	SmartRecapEnabled bool   `json:"smart_recap_enabled"` // generate smart recaps for the user's sessions
+	DigestFrequency   string `json:"digest_frequency"`    // DigestOff, DigestDaily or DigestWeekly
+	NotifyOnRecap     bool   `json:"notify_on_recap"`     // notify when a smart recap is ready
+	Timezone          string `json:"timezone"`            // IANA zone name for dates shown to the user
+
+	// Notifications holds the user's channel choices by notification type.
+	// Types not listed use their defaults (notify.Types).
+	Notifications map[string]NotificationChannels `json:"notifications"`
+	QuietHours    *QuietHours                     `json:"quiet_hours"` // nil when the user has none
+}
+
+// NotificationChannels says which channels a notification type goes out on.
+type NotificationChannels struct {
+	Email   bool `json:"email"`
+	Webhook bool `json:"webhook"`
+}
+
+// QuietHours is a daily window, in the user's Timezone, during which
+// non-urgent notifications are held back and sent when it ends. Start and End
+// are "HH:MM"; a window whose End is before its Start runs past midnight.
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// quietHoursLayout is the time.Parse layout of QuietHours.Start and End.
+const quietHoursLayout = "15:04"
+
+// Minutes returns the window's start and end as minutes after midnight. It
+// expects a window that passed PreferencesUpdate.Normalize.
+func (q QuietHours) Minutes() (start, end int) {
+	return clockMinutes(q.Start), clockMinutes(q.End)
+}
+
+func clockMinutes(hhmm string) int {
+	t, err := time.Parse(quietHoursLayout, hhmm)
+	if err != nil {
+		return 0
+	}
+	return t.Hour()*60 + t.Minute()
+}
+
+// DefaultPreferences returns the preferences of a user who has set none. The
+// user_preferences column defaults (migration 093) match.
+func DefaultPreferences() Preferences {
+	return Preferences{
+		SmartRecapEnabled: true,
+		DigestFrequency:   DigestOff,
+		NotifyOnRecap:     false,
+		Timezone:          "UTC",
+		Notifications:     map[string]NotificationChannels{},
+	}
+}
+
+// PreferencesUpdate is a partial update of Preferences; nil fields are left
+// unchanged.
+type PreferencesUpdate struct {
+	SmartRecapEnabled *bool   `json:"smart_recap_enabled"`
+	DigestFrequency   *string `json:"digest_frequency"`
+	NotifyOnRecap     *bool   `json:"notify_on_recap"`
+	Timezone          *string `json:"timezone"`
+
+	// Notifications replaces the choices of the types it lists and keeps
+	// those of the others. The caller checks the type names.
+	Notifications map[string]NotificationChannels `json:"notifications"`
+	// QuietHours sets the quiet window; an empty Start and End turn it off.
+	QuietHours *QuietHours `json:"quiet_hours"`
+}
+
+// Normalize validates the update, lowercases DigestFrequency and writes quiet
+// hours as zero-padded HH:MM. The error message names the offending field and
+// is safe to show to the caller.
+func (u *PreferencesUpdate) Normalize() error {
+	if u.DigestFrequency != nil {
+		freq := strings.ToLower(strings.TrimSpace(*u.DigestFrequency))
+		switch freq {
+		case DigestOff, DigestDaily, DigestWeekly:
+			u.DigestFrequency = &freq
+		default:
+			return fmt.Errorf("digest_frequency must be one of %s, %s, %s", DigestOff, DigestDaily, DigestWeekly)
+		}
+	}
+	if u.Timezone != nil {
+		// "" and "Local" load, but name no zone of the user's.
+		if tz := *u.Timezone; tz == "" || tz == "Local" {
+			return errors.New("timezone must be an IANA time zone name")
+		} else if _, err := time.LoadLocation(tz); err != nil {
+			return fmt.Errorf("timezone %q is not a known IANA time zone", tz)
+		}
+	}
+	if q := u.QuietHours; q != nil && (q.Start != "" || q.End != "") {
+		start, err := time.Parse(quietHoursLayout, strings.TrimSpace(q.Start))
+		if err != nil {
+			return errors.New("quiet_hours.start must be a time of day as HH:MM")
+		}
+		end, err := time.Parse(quietHoursLayout, strings.TrimSpace(q.End))
+		if err != nil {
+			return errors.New("quiet_hours.end must be a time of day as HH:MM")
+		}
+		if start.Equal(end) {
+			return errors.New("quiet_hours.start and quiet_hours.end must differ")
+		}
+		u.QuietHours = &QuietHours{Start: start.Format(quietHoursLayout), End: end.Format(quietHoursLayout)}
+	}
+	return nil
+}
+
+// preferencesColumns are the user_preferences columns scanPreferences reads.
+const preferencesColumns = `smart_recap_enabled, digest_frequency, notify_on_recap, timezone,
+	notification_channels, quiet_hours_start, quiet_hours_end`
+
+// scanPreferences scans a row of preferencesColumns.
+func scanPreferences(row *sql.Row) (Preferences, error) {
+	var p Preferences
+	var channels []byte
+	var quietStart, quietEnd sql.NullString
+	if err := row.Scan(&p.SmartRecapEnabled, &p.DigestFrequency, &p.NotifyOnRecap, &p.Timezone,
+		&channels, &quietStart, &quietEnd); err != nil {
+		return Preferences{}, err
+	}
+	if err := json.Unmarshal(channels, &p.Notifications); err != nil {
+		return Preferences{}, fmt.Errorf("failed to decode notification channels: %w", err)
+	}
+	if p.Notifications == nil {
+		p.Notifications = map[string]NotificationChannels{}
+	}
+	if quietStart.Valid && quietEnd.Valid {
+		p.QuietHours = &QuietHours{Start: quietStart.String, End: quietEnd.String}
+	}
+	return p, nil
+}
+
+// GetPreferences returns the user's preferences, or DefaultPreferences when
+// the user has never changed one.
+func (s *Store) GetPreferences(ctx context.Context, userID int64) (Preferences, error) {
+	ctx, span := tracer.Start(ctx, "db.get_user_preferences",
+		trace.WithAttributes(attribute.Int64("user.id", userID)))
+	defer span.End()
+
+	p, err := scanPreferences(s.conn().QueryRowContext(ctx, `
+		SELECT `+preferencesColumns+`
+		FROM user_preferences WHERE user_id = $1`, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DefaultPreferences(), nil
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return Preferences{}, fmt.Errorf("failed to get user preferences: %w", err)
+	}
+	return p, nil
+}
+
+// UpdatePreferences applies update to the user's preferences, starting from
+// DefaultPreferences when the user has no row yet, and returns the result.
+// The update is expected to have passed Normalize. It is one upsert, so
+// concurrent updates of different fields do not overwrite each other.
+func (s *Store) UpdatePreferences(ctx context.Context, userID int64, update PreferencesUpdate) (Preferences, error) {
+	ctx, span := tracer.Start(ctx, "db.update_user_preferences",
+		trace.WithAttributes(attribute.Int64("user.id", userID)))
+	defer span.End()
+
+	// Notification choices merge into the stored ones (jsonb ||), so an
+	// update naming one type keeps the others. Quiet hours are replaced
+	// whole when $11 is set; '' turns them off.
+	var channels []byte
+	if update.Notifications != nil {
+		var err error
+		if channels, err = json.Marshal(update.Notifications); err != nil {
+			return Preferences{}, fmt.Errorf("failed to encode notification channels: %w", err)
+		}
+	}
+	var quiet QuietHours
+	if update.QuietHours != nil {
+		quiet = *update.QuietHours
+	}
+
+	defaults := DefaultPreferences()
+	query := `
+		INSERT INTO user_preferences AS p (user_id, smart_recap_enabled, digest_frequency, notify_on_recap, timezone,
+			notification_channels, quiet_hours_start, quiet_hours_end)
+		VALUES ($1,
+			COALESCE($2::boolean, $6::boolean),
+			COALESCE($3::text, $7::text),
+			COALESCE($4::boolean, $8::boolean),
+			COALESCE($5::text, $9::text),
+			COALESCE($10::jsonb, '{}'::jsonb),
+			NULLIF($12::text, ''),
+			NULLIF($13::text, ''))
+		ON CONFLICT (user_id) DO UPDATE SET
+			smart_recap_enabled = COALESCE($2::boolean, p.smart_recap_enabled),
+			digest_frequency = COALESCE($3::text, p.digest_frequency),
+			notify_on_recap = COALESCE($4::boolean, p.notify_on_recap),
+			timezone = COALESCE($5::text, p.timezone),
+			notification_channels = p.notification_channels || COALESCE($10::jsonb, '{}'::jsonb),
+			quiet_hours_start = CASE WHEN $11::boolean THEN NULLIF($12::text, '') ELSE p.quiet_hours_start END,
+			quiet_hours_end = CASE WHEN $11::boolean THEN NULLIF($13::text, '') ELSE p.quiet_hours_end END,
+			updated_at = NOW()
+		RETURNING ` + preferencesColumns
+
+	p, err := scanPreferences(s.conn().QueryRowContext(ctx, query, userID,
+		update.SmartRecapEnabled, update.DigestFrequency, update.NotifyOnRecap, update.Timezone,
+		defaults.SmartRecapEnabled, defaults.DigestFrequency, defaults.NotifyOnRecap, defaults.Timezone,
+		channels, update.QuietHours != nil, quiet.Start, quiet.End,
+	))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return Preferences{}, fmt.Errorf("failed to update user preferences: %w", err)
+	}
+	return p, nil
+}
@@ -0,0 +1,158 @@
+package user_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	dbuser "github.com/ConfabulousDev/confab-web/internal/db/user"
+	"github.com/ConfabulousDev/confab-web/internal/testutil"
+)
+
+func TestPreferencesUpdate_Normalize(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	valid := []struct {
+		name       string
+		update     dbuser.PreferencesUpdate
+		wantDigest string
+	}{
+		{"empty", dbuser.PreferencesUpdate{}, ""},
+		{"daily", dbuser.PreferencesUpdate{DigestFrequency: str("daily")}, dbuser.DigestDaily},
+		{"mixed case", dbuser.PreferencesUpdate{DigestFrequency: str(" Weekly ")}, dbuser.DigestWeekly},
+		{"timezone", dbuser.PreferencesUpdate{Timezone: str("America/New_York")}, ""},
+		{"utc", dbuser.PreferencesUpdate{Timezone: str("UTC")}, ""},
+		{"quiet hours", dbuser.PreferencesUpdate{QuietHours: &dbuser.QuietHours{Start: "22:00", End: "07:30"}}, ""},
+		{"quiet hours off", dbuser.PreferencesUpdate{QuietHours: &dbuser.QuietHours{}}, ""},
+	}
+	for _, tt := range valid {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.update.Normalize(); err != nil {
+				t.Fatalf("Normalize: %v", err)
+			}
+			if tt.wantDigest != "" && *tt.update.DigestFrequency != tt.wantDigest {
+				t.Errorf("digest_frequency = %q, want %q", *tt.update.DigestFrequency, tt.wantDigest)
+			}
+		})
+	}
+
+	invalid := []struct {
+		name   string
+		update dbuser.PreferencesUpdate
+	}{
+		{"unknown digest", dbuser.PreferencesUpdate{DigestFrequency: str("hourly")}},
+		{"empty digest", dbuser.PreferencesUpdate{DigestFrequency: str("")}},
+		{"unknown timezone", dbuser.PreferencesUpdate{Timezone: str("Mars/Olympus_Mons")}},
+		{"empty timezone", dbuser.PreferencesUpdate{Timezone: str("")}},
+		{"server timezone", dbuser.PreferencesUpdate{Timezone: str("Local")}},
+		{"quiet hours without end", dbuser.PreferencesUpdate{QuietHours: &dbuser.QuietHours{Start: "22:00"}}},
+		{"quiet hours not a time", dbuser.PreferencesUpdate{QuietHours: &dbuser.QuietHours{Start: "25:00", End: "07:00"}}},
+		{"empty quiet window", dbuser.PreferencesUpdate{QuietHours: &dbuser.QuietHours{Start: "07:00", End: "07:00"}}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.update.Normalize(); err == nil {
+				t.Error("Normalize accepted an invalid update")
+			}
+		})
+	}
+}
+
+func TestPreferences(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+	store := &dbuser.Store{DB: env.DB}
+	ctx := context.Background()
+
+	user := testutil.CreateTestUser(t, env, "prefs@example.com", "Prefs")
+	other := testutil.CreateTestUser(t, env, "other@example.com", "Other")
+
+	t.Run("defaults without a row", func(t *testing.T) {
+		got, err := store.GetPreferences(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetPreferences: %v", err)
+		}
+		if want := dbuser.DefaultPreferences(); !reflect.DeepEqual(got, want) {
+			t.Errorf("preferences = %+v, want defaults %+v", got, want)
+		}
+	})
+
+	t.Run("first update starts from the defaults", func(t *testing.T) {
+		off := false
+		got, err := store.UpdatePreferences(ctx, user.ID, dbuser.PreferencesUpdate{SmartRecapEnabled: &off})
+		if err != nil {
+			t.Fatalf("UpdatePreferences: %v", err)
+		}
+		want := dbuser.DefaultPreferences()
+		want.SmartRecapEnabled = false
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("preferences = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("partial update keeps the other fields", func(t *testing.T) {
+		digest, tz := dbuser.DigestDaily, "Europe/Berlin"
+		if _, err := store.UpdatePreferences(ctx, user.ID, dbuser.PreferencesUpdate{DigestFrequency: &digest, Timezone: &tz}); err != nil {
+			t.Fatalf("UpdatePreferences: %v", err)
+		}
+		on := true
+		if _, err := store.UpdatePreferences(ctx, user.ID, dbuser.PreferencesUpdate{NotifyOnRecap: &on}); err != nil {
+			t.Fatalf("UpdatePreferences: %v", err)
+		}
+
+		got, err := store.GetPreferences(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetPreferences: %v", err)
+		}
+		want := dbuser.Preferences{SmartRecapEnabled: false, DigestFrequency: dbuser.DigestDaily, NotifyOnRecap: true, Timezone: "Europe/Berlin",
+			Notifications: map[string]dbuser.NotificationChannels{}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("preferences = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("notification choices merge and quiet hours can be cleared", func(t *testing.T) {
+		email := map[string]dbuser.NotificationChannels{"a": {Email: true}}
+		if _, err := store.UpdatePreferences(ctx, user.ID, dbuser.PreferencesUpdate{
+			Notifications: email,
+			QuietHours:    &dbuser.QuietHours{Start: "22:00", End: "07:00"},
+		}); err != nil {
+			t.Fatalf("UpdatePreferences: %v", err)
+		}
+		got, err := store.UpdatePreferences(ctx, user.ID, dbuser.PreferencesUpdate{
+			Notifications: map[string]dbuser.NotificationChannels{"b": {Webhook: true}},
+		})
+		if err != nil {
+			t.Fatalf("UpdatePreferences: %v", err)
+		}
+		wantChannels := map[string]dbuser.NotificationChannels{"a": {Email: true}, "b": {Webhook: true}}
+		if !reflect.DeepEqual(got.Notifications, wantChannels) {
+			t.Errorf("notifications = %+v, want %+v", got.Notifications, wantChannels)
+		}
+		if got.QuietHours == nil || *got.QuietHours != (dbuser.QuietHours{Start: "22:00", End: "07:00"}) {
+			t.Errorf("quiet_hours = %+v, want 22:00-07:00 kept", got.QuietHours)
+		}
+
+		got, err = store.UpdatePreferences(ctx, user.ID, dbuser.PreferencesUpdate{QuietHours: &dbuser.QuietHours{}})
+		if err != nil {
+			t.Fatalf("UpdatePreferences: %v", err)
+		}
+		if got.QuietHours != nil {
+			t.Errorf("quiet_hours = %+v, want cleared", got.QuietHours)
+		}
+	})
+
+	t.Run("other users keep the defaults", func(t *testing.T) {
+		got, err := store.GetPreferences(ctx, other.ID)
+		if err != nil {
+			t.Fatalf("GetPreferences: %v", err)
+		}
+		if want := dbuser.DefaultPreferences(); !reflect.DeepEqual(got, want) {
+			t.Errorf("preferences = %+v, want defaults %+v", got, want)
+		}
+	})
+}
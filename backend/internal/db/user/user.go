@@ -20,7 +20,7 @@ func (s *Store) GetUserByID(ctx context.Context, userID int64) (*models.User, er
 		trace.WithAttributes(attribute.Int64("user.id", userID)))
 	defer span.End()
 
-	query := `SELECT id, email, name, avatar_url, status, read_only, is_admin, created_at, updated_at FROM users WHERE id = $1`
+	query := `SELECT id, email, name, avatar_url, status, read_only, is_admin, rate_limit_tier, created_at, updated_at FROM users WHERE id = $1`
 
 	var user models.User
 	err := s.conn().QueryRowContext(ctx, query, userID).Scan(
@@ -31,6 +31,7 @@ func (s *Store) GetUserByID(ctx context.Context, userID int64) (*models.User, er
 		&user.Status,
 		&user.ReadOnly,
 		&user.IsAdmin,
+		&user.RateLimitTier,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -179,7 +180,7 @@ func (s *Store) ListAllUsers(ctx context.Context) ([]models.AdminUserStats, erro
 
 	query := `
 		SELECT
-			u.id, u.email, u.name, u.avatar_url, u.status, u.created_at, u.updated_at, u.is_admin,
+			u.id, u.email, u.name, u.avatar_url, u.status, u.created_at, u.updated_at, u.is_admin, u.rate_limit_tier,
 			COUNT(DISTINCT s.id) AS session_count,
 			MAX(ak.last_used_at) AS last_api_key_used,
 			MAX(ws.created_at) AS last_logged_in
@@ -210,6 +211,7 @@ func (s *Store) ListAllUsers(ctx context.Context) ([]models.AdminUserStats, erro
 			&user.CreatedAt,
 			&user.UpdatedAt,
 			&user.IsAdmin,
+			&user.RateLimitTier,
 			&user.SessionCount,
 			&user.LastAPIKeyUsed,
 			&user.LastLoggedIn,
@@ -279,6 +281,7 @@ func (s *Store) DeleteUser(ctx context.Context, userID int64) error {
 		span.SetStatus(codes.Error, err.Error())
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
+	s.DB.SessionOwners.InvalidateUser(userID)
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
@@ -400,3 +403,93 @@ func (s *Store) SetUserAdmin(ctx context.Context, userID int64, isAdmin bool) er
 
 	return nil
 }
+
+// SetUserRateLimitTier sets the users.rate_limit_tier column. Returns
+// ErrUserNotFound when no row matches. Auth reads the tier on every request,
+// so the change applies from the user's next request.
+func (s *Store) SetUserRateLimitTier(ctx context.Context, userID int64, tier models.RateLimitTier) error {
+	ctx, span := tracer.Start(ctx, "db.set_user_rate_limit_tier",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.String("user.rate_limit_tier", string(tier)),
+		))
+	defer span.End()
+
+	query := `UPDATE users SET rate_limit_tier = $1, updated_at = NOW() WHERE id = $2`
+
+	result, err := s.conn().ExecContext(ctx, query, tier, userID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to set user rate limit tier: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return db.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetIncludeAgentFilesInSearch reports whether the user opted into indexing
+// subagent output for search. Returns ErrUserNotFound when no row matches.
+func (s *Store) GetIncludeAgentFilesInSearch(ctx context.Context, userID int64) (bool, error) {
+	ctx, span := tracer.Start(ctx, "db.get_include_agent_files_in_search",
+		trace.WithAttributes(attribute.Int64("user.id", userID)))
+	defer span.End()
+
+	var include bool
+	err := s.conn().QueryRowContext(ctx,
+		`SELECT include_agent_files_in_search FROM users WHERE id = $1`, userID).Scan(&include)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, db.ErrUserNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return false, fmt.Errorf("failed to get include_agent_files_in_search: %w", err)
+	}
+	return include, nil
+}
+
+// SetIncludeAgentFilesInSearch sets the user's opt-in to indexing subagent
+// output for search. Returns ErrUserNotFound when no row matches. The search
+// index metadata hash covers this setting, so flipping it marks all of the
+// user's indexes stale and the worker rebuilds them.
+func (s *Store) SetIncludeAgentFilesInSearch(ctx context.Context, userID int64, include bool) error {
+	ctx, span := tracer.Start(ctx, "db.set_include_agent_files_in_search",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.Bool("user.include_agent_files_in_search", include),
+		))
+	defer span.End()
+
+	query := `UPDATE users SET include_agent_files_in_search = $1, updated_at = NOW() WHERE id = $2`
+
+	result, err := s.conn().ExecContext(ctx, query, include, userID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to set include_agent_files_in_search: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return db.ErrUserNotFound
+	}
+
+	return nil
+}
@@ -10,11 +10,13 @@ package db
 //	visible_sessions(id uuid, user_id bigint, owner_email text,
 //	                 access_type text, shared_by_email text)
 //
-// access_type ∈ {'owner', 'private_share', 'system_share'}. shared_by_email
-// is NULL when access_type='owner' and equals the session owner's email
-// otherwise. owner_email is the session owner's email in original case;
-// callers wanting case-insensitive comparison apply LOWER() at the filter
-// site (mirrors ListUserSessionsPaginated's owner-filter semantics).
+// access_type ∈ {'owner', 'private_share', 'system_share', 'org_share'};
+// 'org_share' rows are org-visible sessions of users who share an
+// organization with $1 (default mode only). shared_by_email is NULL when
+// access_type='owner' and equals the session owner's email otherwise.
+// owner_email is the session owner's email in original case; callers wanting
+// case-insensitive comparison apply LOWER() at the filter site (mirrors
+// ListUserSessionsPaginated's owner-filter semantics).
 //
 // Callers that don't care about access_type / shared_by_email (analytics,
 // filter-options) wrap with:
@@ -28,6 +30,9 @@ package db
 // but $1 must still bind to the query's parameter list, so callers can
 // keep userID as $1 regardless of mode.
 //
+// Sessions merged away as duplicates (merged_at set) are excluded from every
+// branch, so they drop out of the list and all analytics aggregates at once.
+//
 // Mirrors the cross-cutting pattern of db.RepoRootExpr / db.RepoMatchExpr.
 func VisibleSessionsCTE(shareAllSessions bool) string {
 	if shareAllSessions {
@@ -47,6 +52,7 @@ const visibleSessionsCTEShareAll = `visible_sessions AS (
 	       CASE WHEN s.user_id = $1 THEN NULL ELSE u.email END AS shared_by_email
 	FROM sessions s
 	JOIN users u ON s.user_id = u.id
+	WHERE s.merged_at IS NULL
 	UNION ALL
 	SELECT s.id, s.user_id, u.email,
 	       'private_share' AS access_type, u.email AS shared_by_email
@@ -57,18 +63,20 @@ const visibleSessionsCTEShareAll = `visible_sessions AS (
 	WHERE ssr.user_id = $1
 	  AND (sh.expires_at IS NULL OR sh.expires_at > NOW())
 	  AND s.user_id != $1
+	  AND s.merged_at IS NULL
 )`
 
-// Default mode: UNION ALL of owned ∪ private-share ∪ system-share. Each
-// branch stamps its access_type so downstream callers can priority-dedup
-// (owner > private_share > system_share). NOT deduplicated by id —
-// analytics callers wrap with `SELECT DISTINCT id, user_id, owner_email`.
+// Default mode: UNION ALL of owned ∪ private-share ∪ system-share ∪
+// org-visible. Each branch stamps its access_type so downstream callers can
+// priority-dedup (owner > private_share > system_share > org_share). NOT
+// deduplicated by id — analytics callers wrap with `SELECT DISTINCT id, user_id, owner_email`.
 const visibleSessionsCTEDefault = `visible_sessions AS (
 	SELECT s.id, s.user_id, u.email AS owner_email,
 	       'owner' AS access_type, NULL::text AS shared_by_email
 	FROM sessions s
 	JOIN users u ON s.user_id = u.id
 	WHERE s.user_id = $1
+	  AND s.merged_at IS NULL
 	UNION ALL
 	SELECT s.id, s.user_id, u.email,
 	       'private_share' AS access_type, u.email AS shared_by_email
@@ -79,6 +87,7 @@ const visibleSessionsCTEDefault = `visible_sessions AS (
 	WHERE ssr.user_id = $1
 	  AND (sh.expires_at IS NULL OR sh.expires_at > NOW())
 	  AND s.user_id != $1
+	  AND s.merged_at IS NULL
 	UNION ALL
 	SELECT s.id, s.user_id, u.email,
 	       'system_share' AS access_type, u.email AS shared_by_email
@@ -88,4 +97,18 @@ const visibleSessionsCTEDefault = `visible_sessions AS (
 	JOIN users u ON s.user_id = u.id
 	WHERE (sh.expires_at IS NULL OR sh.expires_at > NOW())
 	  AND s.user_id != $1
+	  AND s.merged_at IS NULL
+	UNION ALL
+	SELECT s.id, s.user_id, u.email,
+	       'org_share' AS access_type, u.email AS shared_by_email
+	FROM sessions s
+	JOIN users u ON s.user_id = u.id
+	WHERE s.visibility = 'org'
+	  AND s.user_id != $1
+	  AND s.merged_at IS NULL
+	  AND s.user_id IN (
+	      SELECT owner_m.user_id FROM org_members owner_m
+	      JOIN org_members viewer_m ON viewer_m.org_id = owner_m.org_id
+	      WHERE viewer_m.user_id = $1
+	  )
 )`
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/db/dborg"
 	"github.com/ConfabulousDev/confab-web/internal/testutil"
 )
 
@@ -259,3 +260,55 @@ func TestVisibleSessionsCTE_RevokedShareNoLongerVisible(t *testing.T) {
 		t.Fatalf("bob should see 0 rows after share expires, got %d", len(rows))
 	}
 }
+
+func TestVisibleSessionsCTE_OrgVisibilityGrantsAccess(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	alice := testutil.CreateTestUser(t, env, "alice@vis.test", "Alice")
+	bob := testutil.CreateTestUser(t, env, "bob@vis.test", "Bob")
+	carol := testutil.CreateTestUser(t, env, "carol@vis.test", "Carol")
+
+	// Alice and Bob share an org; Carol is outside it.
+	orgs := &dborg.Store{DB: env.DB}
+	org, err := orgs.Create(env.Ctx, "acme")
+	if err != nil {
+		t.Fatalf("create org: %v", err)
+	}
+	for _, id := range []int64{alice.ID, bob.ID} {
+		if err := orgs.AddMember(env.Ctx, org.ID, id); err != nil {
+			t.Fatalf("add member: %v", err)
+		}
+	}
+
+	orgSession := testutil.CreateTestSessionFull(t, env, alice.ID, "alice-org", testutil.TestSessionFullOpts{Summary: "x"})
+	_ = testutil.CreateTestSessionFull(t, env, alice.ID, "alice-private", testutil.TestSessionFullOpts{Summary: "x"})
+	if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET visibility = 'org' WHERE id = $1`, orgSession); err != nil {
+		t.Fatalf("set visibility: %v", err)
+	}
+
+	rows := runVisible(t, env, bob.ID)
+	if len(rows) != 1 {
+		t.Fatalf("bob should see exactly 1 row (alice's org-visible session), got %d", len(rows))
+	}
+	r := rows[0]
+	if r.id != orgSession {
+		t.Errorf("row id = %s, want %s", r.id, orgSession)
+	}
+	if r.accessType != "org_share" {
+		t.Errorf("access_type = %q, want %q", r.accessType, "org_share")
+	}
+	if r.sharedByEmail == nil || *r.sharedByEmail != "alice@vis.test" {
+		t.Errorf("shared_by_email = %v, want alice@vis.test", r.sharedByEmail)
+	}
+
+	if rows := runVisible(t, env, carol.ID); len(rows) != 0 {
+		t.Errorf("carol is not in alice's org and should see 0 rows, got %d", len(rows))
+	}
+	if got := accessTypesFor(runVisible(t, env, alice.ID), orgSession); len(got) != 1 || got[0] != "owner" {
+		t.Errorf("alice's own org-visible session access types = %v, want [owner]", got)
+	}
+}
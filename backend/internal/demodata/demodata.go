@@ -0,0 +1,157 @@
+// Package demodata bundles the sample sessions that POST /api/v1/me/demo
+// seeds into a new account, so cards, recaps and search have something to
+// show before the user has synced anything.
+package demodata
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//go:embed fixtures/*.jsonl
+var fixtures embed.FS
+
+// ExternalIDPrefix starts the external_id of every demo session.
+const ExternalIDPrefix = "confab-demo-"
+
+// TitlePrefix labels a demo session's summary so it reads as sample data in
+// the session list.
+const TitlePrefix = "Demo: "
+
+// Session is one demo session, ready to upload through sync/chunk.
+type Session struct {
+	ExternalID     string
+	Summary        string // TitlePrefix + a short description
+	CWD            string
+	TranscriptPath string
+	Files          []File // transcript first, then agent files
+}
+
+// File is one synced file of a demo session.
+type File struct {
+	Name  string
+	Type  string // "transcript" or "agent"
+	Lines []string
+}
+
+// fixture describes a bundled transcript. endsAgo places its last message
+// relative to the seed time, spreading the sessions over the past days.
+type fixture struct {
+	slug       string
+	title      string
+	cwd        string
+	transcript string // Claude Code session UUID; the fixture is <uuid>.jsonl in the session's directory
+	file       string
+	agents     []string // agent-<id>.jsonl fixtures
+	endsAgo    time.Duration
+}
+
+var catalog = []fixture{
+	{
+		slug:       "rate-limiting",
+		title:      "Add per-client rate limiting to an API",
+		cwd:        "/home/demo/projects/bookshelf-api",
+		transcript: "d3e0c001-5e55-4a11-9d00-000000000001",
+		file:       "rate-limiting.jsonl",
+		endsAgo:    26 * time.Hour,
+	},
+	{
+		slug:       "migration-debugging",
+		title:      "Debug a failing migrations CI job",
+		cwd:        "/home/demo/projects/invoice-service",
+		transcript: "d3e0c002-5e55-4a11-9d00-000000000002",
+		file:       "migration-debugging.jsonl",
+		endsAgo:    5 * time.Hour,
+	},
+	{
+		slug:       "moment-migration",
+		title:      "Plan a moment.js to date-fns migration",
+		cwd:        "/home/demo/projects/storefront-web",
+		transcript: "d3e0c003-5e55-4a11-9d00-000000000003",
+		file:       "moment-migration.jsonl",
+		agents:     []string{"agent-5f2e9a71.jsonl"},
+		endsAgo:    40 * time.Minute,
+	},
+}
+
+// Sessions returns the demo sessions with their timestamps moved so each
+// ends a fixed time before now (between 40 minutes and a day ago). The
+// spacing between messages is kept.
+func Sessions(now time.Time) ([]Session, error) {
+	sessions := make([]Session, 0, len(catalog))
+	for _, fx := range catalog {
+		transcriptName := fx.transcript + ".jsonl"
+		files := []File{{Name: transcriptName, Type: "transcript"}}
+		fixtureNames := []string{fx.file}
+		for _, agent := range fx.agents {
+			files = append(files, File{Name: agent, Type: "agent"})
+			fixtureNames = append(fixtureNames, agent)
+		}
+		for i, name := range fixtureNames {
+			lines, err := readLines(name)
+			if err != nil {
+				return nil, err
+			}
+			files[i].Lines = lines
+		}
+		if err := shiftTimestamps(files, now.Add(-fx.endsAgo)); err != nil {
+			return nil, fmt.Errorf("demo session %s: %w", fx.slug, err)
+		}
+
+		sessions = append(sessions, Session{
+			ExternalID:     ExternalIDPrefix + fx.slug,
+			Summary:        TitlePrefix + fx.title,
+			CWD:            fx.cwd,
+			TranscriptPath: path.Join("/home/demo/.claude/projects", strings.ReplaceAll(fx.cwd, "/", "-"), transcriptName),
+			Files:          files,
+		})
+	}
+	return sessions, nil
+}
+
+func readLines(name string) ([]string, error) {
+	data, err := fixtures.ReadFile("fixtures/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read demo fixture %s: %w", name, err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), nil
+}
+
+// timestampField matches a JSON "timestamp" member. A "timestamp" inside a
+// string value is escaped (\"timestamp\"), so it doesn't match.
+var timestampField = regexp.MustCompile(`"timestamp":"([^"]+)"`)
+
+// shiftTimestamps moves every timestamp in files by the same amount, so the
+// latest one lands on endAt. Lines are rewritten in place as text, keeping
+// their key order byte for byte.
+func shiftTimestamps(files []File, endAt time.Time) error {
+	var latest time.Time
+	for _, f := range files {
+		for _, line := range f.Lines {
+			for _, m := range timestampField.FindAllStringSubmatch(line, -1) {
+				ts, err := time.Parse(time.RFC3339Nano, m[1])
+				if err != nil {
+					return fmt.Errorf("%s: bad timestamp %q: %w", f.Name, m[1], err)
+				}
+				if ts.After(latest) {
+					latest = ts
+				}
+			}
+		}
+	}
+	delta := endAt.Sub(latest)
+
+	for _, f := range files {
+		for i, line := range f.Lines {
+			f.Lines[i] = timestampField.ReplaceAllStringFunc(line, func(field string) string {
+				ts, _ := time.Parse(time.RFC3339Nano, timestampField.FindStringSubmatch(field)[1])
+				return `"timestamp":"` + ts.Add(delta).UTC().Format("2006-01-02T15:04:05.000Z") + `"`
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package demodata
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	sessions, err := Sessions(now)
+	if err != nil {
+		t.Fatalf("Sessions: %v", err)
+	}
+	if len(sessions) != len(catalog) {
+		t.Fatalf("got %d sessions, want %d", len(sessions), len(catalog))
+	}
+
+	var toolErrors, agentFiles int
+	for i, s := range sessions {
+		if !strings.HasPrefix(s.ExternalID, ExternalIDPrefix) {
+			t.Errorf("external_id %q lacks %q", s.ExternalID, ExternalIDPrefix)
+		}
+		if !strings.HasPrefix(s.Summary, TitlePrefix) {
+			t.Errorf("summary %q lacks %q", s.Summary, TitlePrefix)
+		}
+		if len(s.Files) == 0 || s.Files[0].Type != "transcript" {
+			t.Fatalf("%s: first file must be the transcript", s.ExternalID)
+		}
+
+		var latest time.Time
+		for _, f := range s.Files {
+			if f.Type == "agent" {
+				agentFiles++
+			}
+			for n, line := range f.Lines {
+				var entry struct {
+					Timestamp time.Time `json:"timestamp"`
+					Message   struct {
+						Content json.RawMessage `json:"content"`
+					} `json:"message"`
+				}
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatalf("%s line %d: invalid JSON: %v", f.Name, n+1, err)
+				}
+				if entry.Timestamp.After(latest) {
+					latest = entry.Timestamp
+				}
+				if strings.Contains(string(entry.Message.Content), `"is_error":true`) {
+					toolErrors++
+				}
+			}
+		}
+		if want := now.Add(-catalog[i].endsAgo); !latest.Equal(want) {
+			t.Errorf("%s: latest timestamp = %v, want %v", s.ExternalID, latest, want)
+		}
+	}
+	if toolErrors == 0 {
+		t.Error("no demo session has a failed tool call")
+	}
+	if agentFiles == 0 {
+		t.Error("no demo session has an agent file")
+	}
+}
+
+func TestShiftTimestamps_KeepsSpacingAndKeyOrder(t *testing.T) {
+	files := []File{{Name: "t.jsonl", Lines: []string{
+		`{"type":"user","timestamp":"2026-01-01T10:00:00.000Z","message":{"content":"the \"timestamp\":\"x\" text"}}`,
+		`{"type":"assistant","timestamp":"2026-01-01T10:05:30.250Z"}`,
+	}}}
+	end := time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC)
+
+	if err := shiftTimestamps(files, end); err != nil {
+		t.Fatalf("shiftTimestamps: %v", err)
+	}
+	want := []string{
+		`{"type":"user","timestamp":"2026-02-01T07:54:29.750Z","message":{"content":"the \"timestamp\":\"x\" text"}}`,
+		`{"type":"assistant","timestamp":"2026-02-01T08:00:00.000Z"}`,
+	}
+	for i := range want {
+		if files[0].Lines[i] != want[i] {
+			t.Errorf("line %d = %s\nwant %s", i+1, files[0].Lines[i], want[i])
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"html/template"
 	"net/http"
 	"sync"
 	"time"
@@ -32,12 +31,48 @@ type ShareInvitationParams struct {
 	// row. Optional for callers that don't yet have a share row (e.g.
 	// preview rendering in tests).
 	ShareID string
+	// Locale selects the templates; empty means DefaultLocale.
+	Locale string
+}
+
+// MagicLinkParams contains the parameters for a password-less login email
+type MagicLinkParams struct {
+	ToEmail   string
+	LoginURL  string // Signed one-click login URL
+	ExpiresAt time.Time
+	Locale    string // Template locale; empty means DefaultLocale
+}
+
+// DataExportReadyParams contains the parameters for a finished data export email
+type DataExportReadyParams struct {
+	ToEmail      string
+	DownloadURL  string // Presigned archive URL
+	ExpiresAt    time.Time
+	SessionCount int
+	SizeBytes    int64
+	Locale       string // Template locale; empty means DefaultLocale
+}
+
+// NotificationParams contains the parameters for a notification email
+// (notify.Dispatcher). Subject and Body are plain text.
+type NotificationParams struct {
+	ToEmail string
+	Subject string
+	Body    string
+	URL     string // Optional link to details; empty omits it
+	Locale  string // Template locale; empty means DefaultLocale
 }
 
 // Service defines the interface for email operations
 type Service interface {
 	// SendShareInvitation sends an invitation email for a shared session
 	SendShareInvitation(ctx context.Context, params ShareInvitationParams) error
+	// SendMagicLink sends a password-less login link
+	SendMagicLink(ctx context.Context, params MagicLinkParams) error
+	// SendDataExportReady sends the download link of a finished data export
+	SendDataExportReady(ctx context.Context, params DataExportReadyParams) error
+	// SendNotification sends a user notification
+	SendNotification(ctx context.Context, params NotificationParams) error
 }
 
 // RateLimitedService wraps a Service with rate limiting
@@ -66,6 +101,27 @@ func (s *RateLimitedService) SendShareInvitation(ctx context.Context, userID int
 	return s.service.SendShareInvitation(ctx, params)
 }
 
+// SendMagicLink sends a login link email with rate limiting. userID is the
+// account the link signs into, so repeated requests for one address share a
+// quota no matter which client asks.
+func (s *RateLimitedService) SendMagicLink(ctx context.Context, userID int64, params MagicLinkParams) error {
+	if !s.limiter.Allow(userID, s.limitPerHour) {
+		return ErrRateLimitExceeded
+	}
+	s.limiter.Record(userID)
+	return s.service.SendMagicLink(ctx, params)
+}
+
+// SendNotification sends a notification email with rate limiting, counted
+// against the same per-user hourly budget as the other emails.
+func (s *RateLimitedService) SendNotification(ctx context.Context, userID int64, params NotificationParams) error {
+	if !s.limiter.Allow(userID, s.limitPerHour) {
+		return ErrRateLimitExceeded
+	}
+	s.limiter.Record(userID)
+	return s.service.SendNotification(ctx, params)
+}
+
 // CheckRateLimit reports whether sending count emails for userID would stay
 // within the per-hour limit, WITHOUT recording the sends. It lets a caller
 // fail a whole batch up front (e.g. a multi-recipient share) before any
@@ -181,17 +237,18 @@ func humanProviderLabel(ctx context.Context, provider, shareID, toEmail string)
 	return sp.DisplayName() + " session"
 }
 
-// subjectFor formats the Subject header from a sharer name and pre-resolved
-// provider phrase.
-func subjectFor(sharerName, phrase string) string {
-	return fmt.Sprintf("%s shared a %s transcript with you", sharerName, phrase)
-}
-
-// composeSubject is the test entry point: resolves the phrase and formats
-// the subject in one call.
-func composeSubject(ctx context.Context, params ShareInvitationParams) string {
-	phrase := humanProviderLabel(ctx, params.Provider, params.ShareID, params.ToEmail)
-	return subjectFor(params.SharerName, phrase)
+// inviteData builds the template data of a share invitation from a
+// pre-resolved provider phrase.
+func inviteData(params ShareInvitationParams, phrase, frontendURL string) InviteData {
+	return InviteData{
+		SharerName:     params.SharerName,
+		SharerEmail:    params.SharerEmail,
+		SessionTitle:   params.SessionTitle,
+		ShareURL:       params.ShareURL,
+		ExpiresAt:      params.ExpiresAt,
+		ProviderPhrase: phrase,
+		UnsubscribeURL: frontendURL + "/unsubscribe",
+	}
 }
 
 // SendShareInvitation sends an invitation email via Resend.
@@ -199,18 +256,56 @@ func (s *ResendService) SendShareInvitation(ctx context.Context, params ShareInv
 	// Resolve the phrase once so the unknown-provider ERROR log (if any)
 	// fires exactly once per send, not once per template render.
 	phrase := humanProviderLabel(ctx, params.Provider, params.ShareID, params.ToEmail)
-	subject := subjectFor(params.SharerName, phrase)
+	return s.sendRendered(ctx, params.ToEmail, params.Locale, inviteData(params, phrase, s.frontendURL))
+}
 
-	htmlBody, err := renderHTMLTemplateWithPhrase(params, phrase, s.frontendURL)
-	if err != nil {
-		return fmt.Errorf("failed to render HTML template: %w", err)
+// SendMagicLink sends a password-less login link via Resend.
+func (s *ResendService) SendMagicLink(ctx context.Context, params MagicLinkParams) error {
+	return s.sendRendered(ctx, params.ToEmail, params.Locale, MagicLinkData{
+		ToEmail:        params.ToEmail,
+		LoginURL:       params.LoginURL,
+		ExpiresMinutes: magicLinkMinutes(params.ExpiresAt),
+	})
+}
+
+// SendDataExportReady sends a data export download link via Resend.
+func (s *ResendService) SendDataExportReady(ctx context.Context, params DataExportReadyParams) error {
+	return s.sendRendered(ctx, params.ToEmail, params.Locale, dataExportData(params))
+}
+
+// dataExportData builds the template data of a data export email.
+func dataExportData(params DataExportReadyParams) DataExportData {
+	return DataExportData{
+		DownloadURL:  params.DownloadURL,
+		ExpiresAt:    params.ExpiresAt,
+		SessionCount: params.SessionCount,
+		SizeBytes:    params.SizeBytes,
 	}
+}
 
-	textBody := renderTextTemplateWithPhrase(params, phrase, s.frontendURL)
+// SendNotification sends a notification email via Resend.
+func (s *ResendService) SendNotification(ctx context.Context, params NotificationParams) error {
+	return s.sendRendered(ctx, params.ToEmail, params.Locale, NotificationData{
+		Subject: params.Subject,
+		Body:    params.Body,
+		URL:     params.URL,
+	})
+}
+
+// sendRendered renders an email in locale and sends it.
+func (s *ResendService) sendRendered(ctx context.Context, toEmail, locale string, data Data) error {
+	r, err := Render(locale, data)
+	if err != nil {
+		return fmt.Errorf("failed to render email: %w", err)
+	}
+	return s.send(ctx, toEmail, r.Subject, r.HTML, r.Text)
+}
 
+// send posts one email to the Resend API.
+func (s *ResendService) send(ctx context.Context, toEmail, subject, htmlBody, textBody string) error {
 	reqBody := resendRequest{
 		From:    fmt.Sprintf("%s <%s>", s.fromName, s.fromAddress),
-		To:      []string{params.ToEmail},
+		To:      []string{toEmail},
 		Subject: subject,
 		HTML:    htmlBody,
 		Text:    textBody,
@@ -244,157 +339,7 @@ func (s *ResendService) SendShareInvitation(ctx context.Context, params ShareInv
 	return nil
 }
 
-var shareInvitationTmpl = template.Must(template.New("share_invitation").Parse(htmlTemplate))
-
-// renderHTMLTemplate is the test entry point: resolves the phrase and
-// renders the HTML template.
-func renderHTMLTemplate(params ShareInvitationParams, frontendURL string) (string, error) {
-	phrase := humanProviderLabel(context.Background(), params.Provider, params.ShareID, params.ToEmail)
-	return renderHTMLTemplateWithPhrase(params, phrase, frontendURL)
+// magicLinkMinutes rounds the remaining link lifetime up to whole minutes.
+func magicLinkMinutes(expiresAt time.Time) int {
+	return int((time.Until(expiresAt) + time.Minute - 1) / time.Minute)
 }
-
-// renderHTMLTemplateWithPhrase renders the HTML template with a pre-resolved
-// provider phrase so SendShareInvitation logs unknown-provider errors only
-// once per send.
-func renderHTMLTemplateWithPhrase(params ShareInvitationParams, phrase, frontendURL string) (string, error) {
-	data := templateData{
-		SharerName:     params.SharerName,
-		SharerEmail:    params.SharerEmail,
-		SessionTitle:   params.SessionTitle,
-		ShareURL:       params.ShareURL,
-		UnsubscribeURL: frontendURL + "/unsubscribe",
-		ProviderPhrase: phrase,
-	}
-
-	if params.ExpiresAt != nil {
-		data.ExpiresAt = params.ExpiresAt.Format("January 2, 2006")
-	}
-
-	var buf bytes.Buffer
-	if err := shareInvitationTmpl.Execute(&buf, data); err != nil {
-		return "", err
-	}
-
-	return buf.String(), nil
-}
-
-// renderTextTemplate is the test entry point: resolves the phrase and
-// renders the plain-text template.
-func renderTextTemplate(params ShareInvitationParams, frontendURL string) string {
-	phrase := humanProviderLabel(context.Background(), params.Provider, params.ShareID, params.ToEmail)
-	return renderTextTemplateWithPhrase(params, phrase, frontendURL)
-}
-
-// renderTextTemplateWithPhrase renders the plain-text template with a
-// pre-resolved provider phrase.
-func renderTextTemplateWithPhrase(params ShareInvitationParams, phrase, frontendURL string) string {
-	title := params.SessionTitle
-	if title == "" {
-		title = "Untitled Session"
-	}
-
-	text := fmt.Sprintf(`%s (%s) shared a %s transcript with you.
-
-Session: %s
-
-View it here: %s
-`, params.SharerName, params.SharerEmail, phrase, title, params.ShareURL)
-
-	if params.ExpiresAt != nil {
-		text += fmt.Sprintf("\nThis link expires on %s.\n", params.ExpiresAt.Format("January 2, 2006"))
-	}
-
-	text += fmt.Sprintf(`
----
-Unsubscribe: %s/unsubscribe
-`, frontendURL)
-
-	return text
-}
-
-type templateData struct {
-	SharerName     string
-	SharerEmail    string
-	SessionTitle   string
-	ShareURL       string
-	ExpiresAt      string
-	UnsubscribeURL string
-	// ProviderPhrase is the resolved per-provider noun phrase used in the
-	// body line "shared a {{.ProviderPhrase}} transcript with you:".
-	// Examples: "Claude Code session" / "Codex session" / "session".
-	ProviderPhrase string
-}
-
-const htmlTemplate = `<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="utf-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <meta name="x-apple-disable-message-reformatting">
-    <meta name="format-detection" content="telephone=no,address=no,email=no,date=no,url=no">
-    <!--[if mso]>
-    <noscript>
-        <xml>
-            <o:OfficeDocumentSettings>
-                <o:PixelsPerInch>96</o:PixelsPerInch>
-            </o:OfficeDocumentSettings>
-        </xml>
-    </noscript>
-    <![endif]-->
-    <style>
-        @media screen and (max-width: 600px) {
-            .email-container { width: 100% !important; }
-            .email-padding { padding: 16px !important; }
-            .content-padding { padding: 20px 16px !important; }
-        }
-    </style>
-</head>
-<body style="margin: 0; padding: 0; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif; background-color: #fafafa; -webkit-text-size-adjust: 100%; -ms-text-size-adjust: 100%;">
-    <table role="presentation" width="100%" cellspacing="0" cellpadding="0" border="0" style="background-color: #fafafa;">
-        <tr>
-            <td class="email-padding" style="padding: 24px;" align="center">
-                <table role="presentation" class="email-container" width="560" cellspacing="0" cellpadding="0" border="0" style="max-width: 560px; width: 100%; background-color: #ffffff; border: 1px solid #e5e5e5; border-radius: 6px;">
-                    <!-- Header -->
-                    <tr>
-                        <td style="padding: 16px 24px; border-bottom: 1px solid #e5e5e5;">
-                            <span style="font-family: Georgia, 'Times New Roman', serif; font-style: italic; font-size: 22px; color: #1a1a1a;">Confabulous</span>
-                        </td>
-                    </tr>
-                    <!-- Content -->
-                    <tr>
-                        <td class="content-padding" style="padding: 24px;">
-                            <p style="margin: 0 0 16px 0; font-size: 15px; line-height: 1.5; color: #1a1a1a;">
-                                <strong>{{.SharerName}}</strong> <span style="color: #666666;">({{.SharerEmail}})</span> shared a {{.ProviderPhrase}} transcript with you:
-                            </p>
-
-                            <!-- Session preview block (styled like user message) -->
-                            <div style="margin: 0 0 20px 0; padding: 12px 16px; background-color: #f0fff4; border-radius: 6px; border: 1px solid #efefef; border-left: 3px solid #22863a;">
-                                <span style="font-size: 14px; color: #1a1a1a;">{{if .SessionTitle}}{{.SessionTitle}}{{else}}Untitled Session{{end}}</span>
-                            </div>
-
-                            <table role="presentation" cellspacing="0" cellpadding="0" border="0" style="margin: 0 0 20px 0;">
-                                <tr>
-                                    <td style="border-radius: 4px; background-color: #0066cc;">
-                                        <a href="{{.ShareURL}}" target="_blank" style="display: inline-block; padding: 10px 20px; font-size: 14px; font-weight: 600; color: #ffffff; text-decoration: none;">View Session</a>
-                                    </td>
-                                </tr>
-                            </table>
-
-                            {{if .ExpiresAt}}<p style="margin: 0; font-size: 13px; color: #999999;">This link expires on {{.ExpiresAt}}.</p>{{end}}
-                        </td>
-                    </tr>
-                    <!-- Footer -->
-                    <tr>
-                        <td style="padding: 16px 24px; border-top: 1px solid #e5e5e5; background-color: #fafafa;">
-                            <p style="margin: 0; font-size: 12px; color: #999999;">
-                                <a href="{{.UnsubscribeURL}}" style="color: #999999; text-decoration: underline;">Unsubscribe</a>
-                            </p>
-                        </td>
-                    </tr>
-                </table>
-            </td>
-        </tr>
-    </table>
-</body>
-</html>`
-
@@ -15,9 +15,12 @@ import (
 
 // mockService is a mock implementation for testing
 type mockService struct {
-	SentEmails []ShareInvitationParams
-	ShouldFail bool
-	FailError  error
+	SentEmails     []ShareInvitationParams
+	SentMagicLinks []MagicLinkParams
+	SentExports    []DataExportReadyParams
+	SentNotices    []NotificationParams
+	ShouldFail     bool
+	FailError      error
 }
 
 func newMockService() *mockService {
@@ -37,6 +40,30 @@ func (m *mockService) SendShareInvitation(ctx context.Context, params ShareInvit
 	return nil
 }
 
+func (m *mockService) SendMagicLink(ctx context.Context, params MagicLinkParams) error {
+	if m.ShouldFail {
+		return fmt.Errorf("mock email service failure")
+	}
+	m.SentMagicLinks = append(m.SentMagicLinks, params)
+	return nil
+}
+
+func (m *mockService) SendDataExportReady(ctx context.Context, params DataExportReadyParams) error {
+	if m.ShouldFail {
+		return fmt.Errorf("mock email service failure")
+	}
+	m.SentExports = append(m.SentExports, params)
+	return nil
+}
+
+func (m *mockService) SendNotification(ctx context.Context, params NotificationParams) error {
+	if m.ShouldFail {
+		return fmt.Errorf("mock email service failure")
+	}
+	m.SentNotices = append(m.SentNotices, params)
+	return nil
+}
+
 func (m *mockService) reset() {
 	m.SentEmails = []ShareInvitationParams{}
 	m.ShouldFail = false
@@ -288,6 +315,36 @@ func TestMockService(t *testing.T) {
 	})
 }
 
+// composeSubject resolves the provider phrase and renders an invitation's
+// subject, the way SendShareInvitation does.
+func composeSubject(ctx context.Context, params ShareInvitationParams) string {
+	phrase := humanProviderLabel(ctx, params.Provider, params.ShareID, params.ToEmail)
+	return mustRender(Render(params.Locale, inviteData(params, phrase, ""))).Subject
+}
+
+// renderTextTemplate renders an invitation's plain-text body.
+func renderTextTemplate(params ShareInvitationParams, frontendURL string) string {
+	phrase := humanProviderLabel(context.Background(), params.Provider, params.ShareID, params.ToEmail)
+	return mustRender(Render(params.Locale, inviteData(params, phrase, frontendURL))).Text
+}
+
+// renderHTMLTemplate renders an invitation's HTML body.
+func renderHTMLTemplate(params ShareInvitationParams, frontendURL string) (string, error) {
+	phrase := humanProviderLabel(context.Background(), params.Provider, params.ShareID, params.ToEmail)
+	r, err := Render(params.Locale, inviteData(params, phrase, frontendURL))
+	if err != nil {
+		return "", err
+	}
+	return r.HTML, nil
+}
+
+func mustRender(r *Rendered, err error) *Rendered {
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
 func TestRenderTextTemplate(t *testing.T) {
 	frontendURL := "https://example.com"
 
@@ -643,3 +700,33 @@ func TestHumanProviderLabel_KnownProvidersDoNotLog(t *testing.T) {
 	}
 }
 
+
+func TestRenderDataExportReady(t *testing.T) {
+	params := DataExportReadyParams{
+		ToEmail:      "user@example.com",
+		DownloadURL:  "https://s3.example.com/bucket/1/exports/7.zip?X-Amz-Signature=abc&x=1",
+		ExpiresAt:    time.Date(2026, 3, 4, 12, 30, 0, 0, time.UTC),
+		SessionCount: 1,
+		SizeBytes:    3 << 20,
+	}
+
+	r, err := Render(params.Locale, dataExportData(params))
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	text := r.Text
+	for _, want := range []string{params.DownloadURL, "1 session, 3.0 MB", "March 4, 2026 12:30 UTC"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text body missing %q:\n%s", want, text)
+		}
+	}
+
+	html := r.HTML
+	// The query string's & is escaped in the href.
+	for _, want := range []string{"X-Amz-Signature=abc&amp;x=1", "1 session, 3.0 MB", "March 4, 2026 12:30 UTC"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML body missing %q:\n%s", want, html)
+		}
+	}
+}
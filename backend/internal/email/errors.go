@@ -4,3 +4,9 @@ import "errors"
 
 // ErrRateLimitExceeded is returned when the email rate limit is exceeded
 var ErrRateLimitExceeded = errors.New("email rate limit exceeded")
+
+// ErrEmailQueueFull is returned by PooledService when every queue slot is taken
+var ErrEmailQueueFull = errors.New("email queue full")
+
+// ErrEmailServiceClosed is returned by PooledService after Close
+var ErrEmailServiceClosed = errors.New("email service closed")
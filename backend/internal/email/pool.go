@@ -0,0 +1,177 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+var meter = otel.Meter("confab/email")
+
+// PooledService sends email on a fixed pool of workers fed by a bounded
+// queue, so a burst of sends does not hold up its callers. The Send methods
+// return once the email is queued: a failed delivery is logged, not returned.
+type PooledService struct {
+	inner Service
+	queue chan pooledEmail
+	wg    sync.WaitGroup
+
+	mu     sync.RWMutex // guards closed against concurrent sends on queue
+	closed bool
+
+	gauge metric.Registration // nil when the gauge could not be registered
+}
+
+// pooledEmail is one queued send. ctx keeps the caller's values (logger,
+// trace) but not its cancellation, since the request usually ends first.
+type pooledEmail struct {
+	ctx     context.Context
+	kind    Kind
+	toEmail string
+	send    func(ctx context.Context) error
+}
+
+// NewPooledService starts workers goroutines sending through inner, with
+// room for queueSize emails waiting. Both must be positive. Call Close to
+// drain the queue and stop the workers.
+func NewPooledService(inner Service, workers, queueSize int) *PooledService {
+	s := &PooledService{
+		inner: inner,
+		queue: make(chan pooledEmail, queueSize),
+	}
+	s.registerGauge()
+	for range workers {
+		s.wg.Add(1)
+		go s.work()
+	}
+	return s
+}
+
+// registerGauge reports the queue depth as the email.queue.depth gauge.
+func (s *PooledService) registerGauge() {
+	gauge, err := meter.Int64ObservableGauge("email.queue.depth",
+		metric.WithDescription("Emails waiting for a send worker"),
+		metric.WithUnit("{email}"))
+	if err == nil {
+		s.gauge, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+			o.ObserveInt64(gauge, int64(s.QueueDepth()))
+			return nil
+		}, gauge)
+	}
+	if err != nil {
+		logger.Warn("email: failed to register queue depth gauge", "error", err)
+	}
+}
+
+// QueueDepth returns the number of emails waiting for a worker.
+func (s *PooledService) QueueDepth() int {
+	return len(s.queue)
+}
+
+func (s *PooledService) work() {
+	defer s.wg.Done()
+	for e := range s.queue {
+		if err := e.send(e.ctx); err != nil {
+			logger.Ctx(e.ctx).Error("email: queued send failed",
+				"kind", e.kind,
+				"to_email", e.toEmail,
+				"error", err)
+		}
+	}
+}
+
+// enqueue queues e without blocking. It returns ErrEmailQueueFull when every
+// slot is taken and ErrEmailServiceClosed after Close.
+func (s *PooledService) enqueue(e pooledEmail) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return ErrEmailServiceClosed
+	}
+	select {
+	case s.queue <- e:
+		return nil
+	default:
+		return ErrEmailQueueFull
+	}
+}
+
+// SendShareInvitation queues a share invitation.
+func (s *PooledService) SendShareInvitation(ctx context.Context, params ShareInvitationParams) error {
+	return s.enqueue(pooledEmail{
+		ctx:     context.WithoutCancel(ctx),
+		kind:    KindInvite,
+		toEmail: params.ToEmail,
+		send: func(ctx context.Context) error {
+			return s.inner.SendShareInvitation(ctx, params)
+		},
+	})
+}
+
+// SendMagicLink queues a login link.
+func (s *PooledService) SendMagicLink(ctx context.Context, params MagicLinkParams) error {
+	return s.enqueue(pooledEmail{
+		ctx:     context.WithoutCancel(ctx),
+		kind:    KindMagicLink,
+		toEmail: params.ToEmail,
+		send: func(ctx context.Context) error {
+			return s.inner.SendMagicLink(ctx, params)
+		},
+	})
+}
+
+// SendDataExportReady queues a data export download link.
+func (s *PooledService) SendDataExportReady(ctx context.Context, params DataExportReadyParams) error {
+	return s.enqueue(pooledEmail{
+		ctx:     context.WithoutCancel(ctx),
+		kind:    KindDataExport,
+		toEmail: params.ToEmail,
+		send: func(ctx context.Context) error {
+			return s.inner.SendDataExportReady(ctx, params)
+		},
+	})
+}
+
+// SendNotification queues a notification email.
+func (s *PooledService) SendNotification(ctx context.Context, params NotificationParams) error {
+	return s.enqueue(pooledEmail{
+		ctx:     context.WithoutCancel(ctx),
+		kind:    KindNotification,
+		toEmail: params.ToEmail,
+		send: func(ctx context.Context) error {
+			return s.inner.SendNotification(ctx, params)
+		},
+	})
+}
+
+// Close stops accepting emails and waits for the workers to send the ones
+// already queued. If ctx ends first it returns an error; the workers keep
+// draining in the background. Close is safe to call more than once.
+func (s *PooledService) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+		if s.gauge != nil {
+			s.gauge.Unregister()
+		}
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("email queue not drained (%d left): %w", s.QueueDepth(), ctx.Err())
+	}
+}
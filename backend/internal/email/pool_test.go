@@ -0,0 +1,240 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gatedService blocks every send until release is closed, counting sends in
+// flight and done. It is safe for concurrent use, unlike mockService.
+type gatedService struct {
+	release  chan struct{}
+	started  chan string // receives each recipient as its send starts
+	inFlight atomic.Int32
+	peak     atomic.Int32
+	fail     bool
+
+	mu   sync.Mutex
+	sent []string
+}
+
+func newGatedService() *gatedService {
+	return &gatedService{release: make(chan struct{}), started: make(chan string, 100)}
+}
+
+func (g *gatedService) send(ctx context.Context, to string) error {
+	n := g.inFlight.Add(1)
+	defer g.inFlight.Add(-1)
+	for {
+		peak := g.peak.Load()
+		if n <= peak || g.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	g.started <- to
+	<-g.release
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if g.fail {
+		return errors.New("gated send failure")
+	}
+	g.mu.Lock()
+	g.sent = append(g.sent, to)
+	g.mu.Unlock()
+	return nil
+}
+
+func (g *gatedService) SendShareInvitation(ctx context.Context, params ShareInvitationParams) error {
+	return g.send(ctx, params.ToEmail)
+}
+
+func (g *gatedService) SendMagicLink(ctx context.Context, params MagicLinkParams) error {
+	return g.send(ctx, params.ToEmail)
+}
+
+func (g *gatedService) SendDataExportReady(ctx context.Context, params DataExportReadyParams) error {
+	return g.send(ctx, params.ToEmail)
+}
+
+func (g *gatedService) SendNotification(ctx context.Context, params NotificationParams) error {
+	return g.send(ctx, params.ToEmail)
+}
+
+func (g *gatedService) sentCount() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.sent)
+}
+
+// awaitStarted waits for n sends to start.
+func (g *gatedService) awaitStarted(t *testing.T, n int) {
+	t.Helper()
+	for range n {
+		select {
+		case <-g.started:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %d sends to start", n)
+		}
+	}
+}
+
+func closePool(t *testing.T, s *PooledService) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestPooledService_SendsConcurrentlyUpToWorkers(t *testing.T) {
+	inner := newGatedService()
+	s := NewPooledService(inner, 3, 10)
+
+	for _, to := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+		if err := s.SendMagicLink(context.Background(), MagicLinkParams{ToEmail: to}); err != nil {
+			t.Fatalf("SendMagicLink(%s): %v", to, err)
+		}
+	}
+	inner.awaitStarted(t, 3)
+	if got := s.QueueDepth(); got != 2 {
+		t.Errorf("QueueDepth with 3 workers busy = %d, want 2", got)
+	}
+
+	close(inner.release)
+	closePool(t, s)
+	if got := inner.sentCount(); got != 5 {
+		t.Errorf("sent %d emails, want 5", got)
+	}
+	if got := inner.peak.Load(); got != 3 {
+		t.Errorf("peak concurrent sends = %d, want 3", got)
+	}
+}
+
+func TestPooledService_QueueFull(t *testing.T) {
+	inner := newGatedService()
+	s := NewPooledService(inner, 1, 2)
+
+	send := func(to string) error {
+		return s.SendShareInvitation(context.Background(), ShareInvitationParams{ToEmail: to, Provider: "claude-code"})
+	}
+	if err := send("busy@example.com"); err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+	inner.awaitStarted(t, 1) // the only worker is now busy
+	for _, to := range []string{"q1@example.com", "q2@example.com"} {
+		if err := send(to); err != nil {
+			t.Fatalf("send %s: %v", to, err)
+		}
+	}
+	if err := send("overflow@example.com"); !errors.Is(err, ErrEmailQueueFull) {
+		t.Errorf("send past capacity = %v, want ErrEmailQueueFull", err)
+	}
+
+	close(inner.release)
+	closePool(t, s)
+	if got := inner.sentCount(); got != 3 {
+		t.Errorf("sent %d emails, want 3 (the overflow is dropped)", got)
+	}
+}
+
+func TestPooledService_CloseDrainsQueue(t *testing.T) {
+	inner := newGatedService()
+	s := NewPooledService(inner, 2, 10)
+
+	for _, to := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"} {
+		if err := s.SendDataExportReady(context.Background(), DataExportReadyParams{ToEmail: to}); err != nil {
+			t.Fatalf("SendDataExportReady(%s): %v", to, err)
+		}
+	}
+	inner.awaitStarted(t, 2)
+
+	closed := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		closed <- s.Close(ctx)
+	}()
+	select {
+	case err := <-closed:
+		t.Fatalf("Close returned %v before the queue drained", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(inner.release)
+	if err := <-closed; err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := inner.sentCount(); got != 4 {
+		t.Errorf("sent %d emails, want all 4", got)
+	}
+
+	if err := s.SendMagicLink(context.Background(), MagicLinkParams{ToEmail: "late@example.com"}); !errors.Is(err, ErrEmailServiceClosed) {
+		t.Errorf("send after Close = %v, want ErrEmailServiceClosed", err)
+	}
+	closePool(t, s) // a second Close is a no-op
+}
+
+func TestPooledService_CloseTimesOut(t *testing.T) {
+	inner := newGatedService()
+	s := NewPooledService(inner, 1, 1)
+	if err := s.SendMagicLink(context.Background(), MagicLinkParams{ToEmail: "stuck@example.com"}); err != nil {
+		t.Fatalf("SendMagicLink: %v", err)
+	}
+	inner.awaitStarted(t, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close with a stuck send = %v, want context.DeadlineExceeded", err)
+	}
+	close(inner.release)
+}
+
+func TestPooledService_OutlivesCallerContext(t *testing.T) {
+	inner := newGatedService()
+	s := NewPooledService(inner, 1, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := s.SendMagicLink(ctx, MagicLinkParams{ToEmail: "a@example.com"}); err != nil {
+		t.Fatalf("SendMagicLink: %v", err)
+	}
+	cancel() // the request ends before the email goes out
+	close(inner.release)
+	closePool(t, s)
+	if got := inner.sentCount(); got != 1 {
+		t.Errorf("sent %d emails, want 1", got)
+	}
+}
+
+func TestPooledService_LogsFailedSend(t *testing.T) {
+	inner := newGatedService()
+	inner.fail = true
+	close(inner.release)
+
+	records := captureLogs(t, func(ctx context.Context) {
+		s := NewPooledService(inner, 1, 1)
+		if err := s.SendMagicLink(ctx, MagicLinkParams{ToEmail: "a@example.com"}); err != nil {
+			t.Fatalf("SendMagicLink: %v", err)
+		}
+		closePool(t, s)
+	})
+
+	var found bool
+	for _, rec := range records {
+		if rec["msg"] == "email: queued send failed" {
+			found = true
+			if rec["level"] != "ERROR" || rec["kind"] != string(KindMagicLink) || rec["to_email"] != "a@example.com" {
+				t.Errorf("failure log = %v", rec)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("no failure log in %v", records)
+	}
+}
@@ -0,0 +1,280 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Kind names an email template. Each kind has an HTML and a plain-text
+// template per locale under templates/{locale}/, both wrapped in that
+// locale's shared layout.
+type Kind string
+
+const (
+	KindInvite       Kind = "invite"       // share invitation
+	KindMagicLink    Kind = "magic_link"   // password-less sign-in link
+	KindDataExport   Kind = "data_export"  // finished data export
+	KindNotification Kind = "notification" // notify.Dispatcher notification
+)
+
+// Kinds lists every email kind.
+var Kinds = []Kind{KindInvite, KindMagicLink, KindDataExport, KindNotification}
+
+// ParseKind returns the kind named s.
+func ParseKind(s string) (Kind, bool) {
+	for _, k := range Kinds {
+		if string(k) == s {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// DefaultLocale is the locale used when none is given or the requested one
+// has no templates. It is the only locale today.
+const DefaultLocale = "en"
+
+// Data is the typed template data of one email kind.
+type Data interface {
+	Kind() Kind
+}
+
+// InviteData is the template data of a share invitation.
+type InviteData struct {
+	SharerName   string
+	SharerEmail  string
+	SessionTitle string // empty renders "Untitled Session"
+	ShareURL     string
+	ExpiresAt    *time.Time // nil when the share never expires
+	// ProviderPhrase names the agent ("Claude Code session" / "Codex
+	// session" / "session"), resolved by humanProviderLabel.
+	ProviderPhrase string
+	UnsubscribeURL string
+}
+
+// Kind implements Data.
+func (InviteData) Kind() Kind { return KindInvite }
+
+// MagicLinkData is the template data of a sign-in link email.
+type MagicLinkData struct {
+	ToEmail        string
+	LoginURL       string
+	ExpiresMinutes int
+}
+
+// Kind implements Data.
+func (MagicLinkData) Kind() Kind { return KindMagicLink }
+
+// DataExportData is the template data of a finished data export email.
+type DataExportData struct {
+	DownloadURL  string
+	ExpiresAt    time.Time
+	SessionCount int
+	SizeBytes    int64
+}
+
+// Kind implements Data.
+func (DataExportData) Kind() Kind { return KindDataExport }
+
+// NotificationData is the template data of a notification email. The body is
+// plain text, escaped in the HTML version.
+type NotificationData struct {
+	Subject string
+	Body    string
+	URL     string // optional "View details" link
+}
+
+// Kind implements Data.
+func (NotificationData) Kind() Kind { return KindNotification }
+
+// Rendered is an email ready to send.
+type Rendered struct {
+	Locale  string // the locale whose templates were used
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// brand holds the colors the layout and content templates share.
+type brand struct {
+	Text       string
+	Muted      string
+	Accent     string
+	Background string
+	Border     string
+}
+
+var confabBrand = brand{
+	Text:       "#1a1a1a",
+	Muted:      "#999999",
+	Accent:     "#0066cc",
+	Background: "#fafafa",
+	Border:     "#e5e5e5",
+}
+
+// page is what every template executes with: content templates read their
+// kind's fields from .Data.
+type page struct {
+	Locale string
+	Brand  brand
+	Data   Data
+}
+
+// button is the argument of the layout's "button" template.
+type button struct {
+	Brand brand
+	URL   string
+	Label string
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+// kindTemplates are one locale's parsed templates of one kind.
+type kindTemplates struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// templates maps locale → kind → templates, parsed once at startup.
+var templates = mustParseTemplates()
+
+// funcs returns the template functions of a locale. Dates and sizes are
+// formatted here rather than by callers so a translation controls them.
+func funcs(locale string) map[string]any {
+	return map[string]any{
+		"date": func(t time.Time) string {
+			return t.Format("January 2, 2006")
+		},
+		"datetime": func(t time.Time) string {
+			return t.UTC().Format("January 2, 2006 15:04 UTC")
+		},
+		"megabytes": func(n int64) string {
+			return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+		},
+		"button": func(p page, url, label string) button {
+			return button{Brand: p.Brand, URL: url, Label: label}
+		},
+	}
+}
+
+// mustParseTemplates parses templates/{locale}/{layout,kind}.{html,txt} for
+// every locale directory. Every kind must exist in DefaultLocale; other
+// locales may cover a subset and fall back per kind.
+func mustParseTemplates() map[string]map[Kind]kindTemplates {
+	entries, err := fs.ReadDir(templateFS, "templates")
+	if err != nil {
+		panic(err)
+	}
+
+	out := make(map[string]map[Kind]kindTemplates)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		locale := e.Name()
+		dir := "templates/" + locale + "/"
+		out[locale] = make(map[Kind]kindTemplates)
+		for _, kind := range Kinds {
+			if _, err := fs.Stat(templateFS, dir+string(kind)+".html"); err != nil {
+				if locale == DefaultLocale {
+					panic(fmt.Sprintf("email: no %s template for default locale %s", kind, locale))
+				}
+				continue
+			}
+			out[locale][kind] = kindTemplates{
+				html: htmltemplate.Must(htmltemplate.New("layout.html").Funcs(funcs(locale)).
+					ParseFS(templateFS, dir+"layout.html", dir+string(kind)+".html")),
+				text: texttemplate.Must(texttemplate.New("layout.txt").Funcs(funcs(locale)).
+					ParseFS(templateFS, dir+"layout.txt", dir+string(kind)+".txt")),
+			}
+		}
+	}
+	return out
+}
+
+// resolveLocale picks the templates to use for a requested locale: an exact
+// match, then its language ("en-GB" → "en"), then DefaultLocale.
+func resolveLocale(locale string, kind Kind) string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	lang, _, _ := strings.Cut(locale, "-")
+	for _, l := range []string{locale, lang} {
+		if _, ok := templates[l][kind]; ok {
+			return l
+		}
+	}
+	return DefaultLocale
+}
+
+// Render renders the subject, HTML and plain-text bodies of an email in
+// locale (empty for DefaultLocale).
+func Render(locale string, data Data) (*Rendered, error) {
+	locale = resolveLocale(locale, data.Kind())
+	t := templates[locale][data.Kind()]
+	p := page{Locale: locale, Brand: confabBrand, Data: data}
+
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", p); err != nil {
+		return nil, fmt.Errorf("render %s subject: %w", data.Kind(), err)
+	}
+	if err := t.text.ExecuteTemplate(&text, "layout", p); err != nil {
+		return nil, fmt.Errorf("render %s text: %w", data.Kind(), err)
+	}
+	if err := t.html.ExecuteTemplate(&html, "layout", p); err != nil {
+		return nil, fmt.Errorf("render %s HTML: %w", data.Kind(), err)
+	}
+	return &Rendered{Locale: locale, Subject: subject.String(), HTML: html.String(), Text: text.String()}, nil
+}
+
+// Preview renders an email of the given kind with fixed sample data, for the
+// admin email preview.
+func Preview(kind Kind, locale string) (*Rendered, error) {
+	data, ok := sampleData(kind)
+	if !ok {
+		return nil, fmt.Errorf("unknown email kind %q", kind)
+	}
+	return Render(locale, data)
+}
+
+// sampleData returns the preview data of a kind.
+func sampleData(kind Kind) (Data, bool) {
+	expires := time.Date(2026, 3, 4, 12, 30, 0, 0, time.UTC)
+	switch kind {
+	case KindInvite:
+		return InviteData{
+			SharerName:     "Ada Lovelace",
+			SharerEmail:    "ada@example.com",
+			SessionTitle:   "Refactor the billing worker",
+			ShareURL:       "https://confabulous.example.com/sessions/123e4567-e89b-12d3-a456-426614174000",
+			ExpiresAt:      &expires,
+			ProviderPhrase: "Claude Code session",
+			UnsubscribeURL: "https://confabulous.example.com/unsubscribe",
+		}, true
+	case KindMagicLink:
+		return MagicLinkData{
+			ToEmail:        "ada@example.com",
+			LoginURL:       "https://confabulous.example.com/auth/email/verify?token=sample",
+			ExpiresMinutes: 15,
+		}, true
+	case KindDataExport:
+		return DataExportData{
+			DownloadURL:  "https://s3.example.com/bucket/1/exports/7.zip?X-Amz-Signature=sample&X-Amz-Expires=604800",
+			ExpiresAt:    expires,
+			SessionCount: 12,
+			SizeBytes:    3565158,
+		}, true
+	case KindNotification:
+		return NotificationData{
+			Subject: "API key \"laptop\" hit its session limit",
+			Body:    "Your API key \"laptop\" was refused 10 new sessions in the last hour because it passed its per-hour session limit. If you don't recognize this activity, revoke the key.",
+			URL:     "https://confabulous.example.com/keys",
+		}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,99 @@
+package email
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/")
+
+// TestRender_Golden pins the rendered output of every kind, with its preview
+// sample data, against testdata/{kind}.golden.{html,txt}. After an intended
+// template change, regenerate them with:
+//
+//	go test ./internal/email -run TestRender_Golden -update
+func TestRender_Golden(t *testing.T) {
+	for _, kind := range Kinds {
+		t.Run(string(kind), func(t *testing.T) {
+			r, err := Preview(kind, DefaultLocale)
+			if err != nil {
+				t.Fatalf("Preview: %v", err)
+			}
+			checkGolden(t, string(kind)+".golden.html", r.HTML)
+			checkGolden(t, string(kind)+".golden.txt", "Subject: "+r.Subject+"\n\n"+r.Text)
+		})
+	}
+}
+
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s (run with -update to create it): %v", path, err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from the rendered output (run with -update if intended):\n%s", path, got)
+	}
+}
+
+func TestRender_LocaleFallback(t *testing.T) {
+	want, err := Preview(KindInvite, "")
+	if err != nil {
+		t.Fatalf("Preview: %v", err)
+	}
+	// Only English exists: regional variants and unknown locales use it.
+	for _, locale := range []string{"en", "en-GB", "EN_us", "fr"} {
+		got, err := Preview(KindInvite, locale)
+		if err != nil {
+			t.Fatalf("Preview(%q): %v", locale, err)
+		}
+		if *got != *want {
+			t.Errorf("Preview(%q) differs from the default locale's", locale)
+		}
+	}
+}
+
+func TestRender_EscapesHTML(t *testing.T) {
+	r, err := Render("", InviteData{
+		SharerName:     "<b>Mallory</b>",
+		SessionTitle:   `<script>alert("x")</script>`,
+		ShareURL:       "javascript:alert(1)",
+		ProviderPhrase: "session",
+	})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	for _, forbid := range []string{"<b>Mallory", "<script>", `href="javascript:`} {
+		if strings.Contains(r.HTML, forbid) {
+			t.Errorf("HTML contains unescaped %q", forbid)
+		}
+	}
+	// The plain-text body and subject are not HTML and stay verbatim.
+	if !strings.Contains(r.Subject, "<b>Mallory</b>") || !strings.Contains(r.Text, `<script>alert("x")</script>`) {
+		t.Errorf("text output was escaped:\nsubject: %s\n%s", r.Subject, r.Text)
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	for _, kind := range Kinds {
+		if got, ok := ParseKind(string(kind)); !ok || got != kind {
+			t.Errorf("ParseKind(%q) = %q, %v", kind, got, ok)
+		}
+		if _, err := Preview(kind, ""); err != nil {
+			t.Errorf("Preview(%q): %v", kind, err)
+		}
+	}
+	if _, ok := ParseKind("digest"); ok {
+		t.Error(`ParseKind("digest") succeeded`)
+	}
+}
@@ -0,0 +1,111 @@
+// Package features evaluates per-user feature flags from the feature_flags
+// table, for gradual rollout of new analytics features (e.g. a new card type).
+// Flags are managed through the admin API (/api/v1/admin/feature-flags).
+package features
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Tunables are vars (not consts) so tests can shrink them.
+var cacheTTL = 5 * time.Minute
+
+// rule is the evaluable part of a feature_flags row. A nil *rule means the
+// flag does not exist, which evaluates to disabled.
+type rule struct {
+	userIDs []int64
+	pct     int
+}
+
+type cacheEntry struct {
+	rule      *rule
+	fetchedAt time.Time
+}
+
+var (
+	mu    sync.Mutex
+	cache = map[string]cacheEntry{}
+
+	// loadRule is swapped out in tests to count database reads.
+	loadRule = queryRule
+)
+
+// IsEnabled reports whether flag is on for userID: either the user is in the
+// flag's explicit allowlist, or userID % 100 < enabled_pct. Unknown flags are
+// disabled.
+//
+// Each flag is cached per process for cacheTTL, so an admin change reaches the
+// worker within five minutes. Lookup errors are returned and not cached.
+func IsEnabled(ctx context.Context, conn *sql.DB, userID int64, flag string) (bool, error) {
+	mu.Lock()
+	entry, ok := cache[flag]
+	mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < cacheTTL {
+		return entry.rule.enabledFor(userID), nil
+	}
+
+	// Load outside the lock so one slow query doesn't block other flags.
+	// Concurrent misses on the same flag may both query; accepted.
+	r, err := loadRule(ctx, conn, flag)
+	if err != nil {
+		return false, err
+	}
+
+	mu.Lock()
+	cache[flag] = cacheEntry{rule: r, fetchedAt: time.Now()}
+	mu.Unlock()
+	return r.enabledFor(userID), nil
+}
+
+// Invalidate drops the cached value for flag so the next IsEnabled call in
+// this process reads it fresh. Called by the admin API after a write; other
+// processes pick the change up when their cache entry expires.
+func Invalidate(flag string) {
+	mu.Lock()
+	delete(cache, flag)
+	mu.Unlock()
+}
+
+// EnabledSQL returns a boolean SQL expression that applies IsEnabled's rule
+// to the user ID expression userIDExpr, for the flag bound to the query
+// parameter flagParam (e.g. "$18"). Queries that must agree with IsEnabled,
+// such as the precompute worker's stale-session selection, use it. It reads
+// feature_flags uncached, so it can run up to cacheTTL ahead of IsEnabled in
+// another process.
+func EnabledSQL(userIDExpr, flagParam string) string {
+	return fmt.Sprintf(`COALESCE((SELECT %[1]s = ANY(ff.enabled_user_ids) OR %[1]s %% 100 < ff.enabled_pct
+		FROM feature_flags ff WHERE ff.flag_name = %[2]s), FALSE)`, userIDExpr, flagParam)
+}
+
+func (r *rule) enabledFor(userID int64) bool {
+	if r == nil {
+		return false
+	}
+	if slices.Contains(r.userIDs, userID) {
+		return true
+	}
+	return userID%100 < int64(r.pct)
+}
+
+func queryRule(ctx context.Context, conn *sql.DB, flag string) (*rule, error) {
+	var userIDs pq.Int64Array
+	var pct int
+	err := conn.QueryRowContext(ctx,
+		`SELECT enabled_user_ids, enabled_pct FROM feature_flags WHERE flag_name = $1`, flag,
+	).Scan(&userIDs, &pct)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule{userIDs: userIDs, pct: pct}, nil
+}
@@ -0,0 +1,166 @@
+package features
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuleEnabledFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   *rule
+		userID int64
+		want   bool
+	}{
+		{name: "unknown flag", rule: nil, userID: 1, want: false},
+		{name: "off for everyone", rule: &rule{}, userID: 1, want: false},
+		{name: "allowlisted", rule: &rule{userIDs: []int64{7, 42}}, userID: 42, want: true},
+		{name: "not allowlisted", rule: &rule{userIDs: []int64{7, 42}}, userID: 43, want: false},
+		{name: "inside rollout bucket", rule: &rule{pct: 25}, userID: 124, want: true},
+		{name: "rollout boundary is exclusive", rule: &rule{pct: 25}, userID: 125, want: false},
+		{name: "100 percent", rule: &rule{pct: 100}, userID: 99, want: true},
+		{name: "allowlist overrides 0 percent", rule: &rule{userIDs: []int64{99}, pct: 0}, userID: 99, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.enabledFor(tt.userID); got != tt.want {
+				t.Errorf("enabledFor(%d) = %v, want %v", tt.userID, got, tt.want)
+			}
+		})
+	}
+}
+
+// stubLoadRule replaces loadRule for the duration of the test and clears the
+// cache on both ends. It returns a pointer to the load counter.
+func stubLoadRule(t *testing.T, fn func(flag string) (*rule, error)) *int {
+	t.Helper()
+	loads := 0
+	orig := loadRule
+	loadRule = func(_ context.Context, _ *sql.DB, flag string) (*rule, error) {
+		loads++
+		return fn(flag)
+	}
+	clearCache := func() {
+		mu.Lock()
+		cache = map[string]cacheEntry{}
+		mu.Unlock()
+	}
+	clearCache()
+	t.Cleanup(func() {
+		loadRule = orig
+		clearCache()
+	})
+	return &loads
+}
+
+func TestIsEnabled_CachesPerFlag(t *testing.T) {
+	loads := stubLoadRule(t, func(flag string) (*rule, error) {
+		if flag == "new_card" {
+			return &rule{userIDs: []int64{1}}, nil
+		}
+		return nil, nil
+	})
+	ctx := context.Background()
+
+	for _, userID := range []int64{1, 2, 1} {
+		want := userID == 1
+		got, err := IsEnabled(ctx, nil, userID, "new_card")
+		if err != nil {
+			t.Fatalf("IsEnabled error: %v", err)
+		}
+		if got != want {
+			t.Errorf("IsEnabled(user %d) = %v, want %v", userID, got, want)
+		}
+	}
+	if *loads != 1 {
+		t.Errorf("loads = %d after repeated lookups of one flag, want 1", *loads)
+	}
+
+	// Unknown flags are cached too, separately.
+	for range 2 {
+		if got, _ := IsEnabled(ctx, nil, 1, "missing"); got {
+			t.Error("unknown flag should be disabled")
+		}
+	}
+	if *loads != 2 {
+		t.Errorf("loads = %d after adding a second flag, want 2", *loads)
+	}
+}
+
+func TestIsEnabled_ReloadsAfterTTLAndInvalidate(t *testing.T) {
+	pct := 0
+	loads := stubLoadRule(t, func(string) (*rule, error) { return &rule{pct: pct}, nil })
+	ctx := context.Background()
+
+	origTTL := cacheTTL
+	t.Cleanup(func() { cacheTTL = origTTL })
+
+	if got, _ := IsEnabled(ctx, nil, 5, "flag"); got {
+		t.Fatal("expected disabled at 0%")
+	}
+
+	// Within the TTL a change is not visible...
+	pct = 100
+	if got, _ := IsEnabled(ctx, nil, 5, "flag"); got {
+		t.Error("expected cached (disabled) value within TTL")
+	}
+
+	// ...until Invalidate drops the entry.
+	Invalidate("flag")
+	if got, _ := IsEnabled(ctx, nil, 5, "flag"); !got {
+		t.Error("expected enabled after Invalidate")
+	}
+
+	// An expired entry is reloaded.
+	pct = 0
+	cacheTTL = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if got, _ := IsEnabled(ctx, nil, 5, "flag"); got {
+		t.Error("expected disabled after TTL expiry")
+	}
+	if *loads != 3 {
+		t.Errorf("loads = %d, want 3", *loads)
+	}
+}
+
+func TestIsEnabled_ErrorsAreNotCached(t *testing.T) {
+	fail := true
+	loads := stubLoadRule(t, func(string) (*rule, error) {
+		if fail {
+			return nil, errors.New("connection refused")
+		}
+		return &rule{pct: 100}, nil
+	})
+	ctx := context.Background()
+
+	if _, err := IsEnabled(ctx, nil, 1, "flag"); err == nil {
+		t.Fatal("expected error from failing load")
+	}
+	fail = false
+	got, err := IsEnabled(ctx, nil, 1, "flag")
+	if err != nil || !got {
+		t.Errorf("IsEnabled after recovery = %v, %v; want true, nil", got, err)
+	}
+	if *loads != 2 {
+		t.Errorf("loads = %d, want 2", *loads)
+	}
+}
+
+func TestEnabledSQL(t *testing.T) {
+	got := EnabledSQL("s.user_id", "$18")
+	for _, want := range []string{
+		"s.user_id = ANY(ff.enabled_user_ids)",
+		"s.user_id % 100 < ff.enabled_pct",
+		"ff.flag_name = $18",
+		"COALESCE(",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("EnabledSQL = %q, missing %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package httputil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// JSONLimits bounds the shape of a JSON request body before it is decoded.
+// A zero MaxDepth or MaxArrayLen disables that check.
+type JSONLimits struct {
+	// MaxDepth is the deepest allowed nesting of objects and arrays. The
+	// top-level object is depth 1.
+	MaxDepth int
+	// MaxArrayLen is the most elements any single array may hold.
+	MaxArrayLen int
+	// DisallowUnknownFields rejects object keys that have no matching field
+	// in the destination struct.
+	DisallowUnknownFields bool
+}
+
+// DecodeJSON reads one JSON value from r into dst, enforcing limits. The body
+// is scanned token by token first so an oversized array or a deeply nested
+// value is rejected before anything is allocated for it. The returned error
+// is phrased for the client (it names the offending field or path) and is
+// safe to put in a 400 response.
+func DecodeJSON(r io.Reader, dst any, limits JSONLimits) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := checkJSONShape(data, limits); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if limits.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(dst); err != nil {
+		return describeDecodeError(err)
+	}
+	return nil
+}
+
+// jsonFrame is one open object or array during the shape scan.
+type jsonFrame struct {
+	path      string
+	array     bool
+	count     int    // elements seen so far (arrays only)
+	key       string // most recent key (objects only)
+	expectKey bool   // next string token is a key (objects only)
+}
+
+// childPath returns the path of the value about to be read inside f.
+func (f *jsonFrame) childPath() string {
+	if f.array {
+		return f.path + "[" + strconv.Itoa(f.count-1) + "]"
+	}
+	if f.path == "" {
+		return f.key
+	}
+	return f.path + "." + f.key
+}
+
+// checkJSONShape walks data enforcing the depth and array-length limits.
+func checkJSONShape(data []byte, limits JSONLimits) error {
+	if limits.MaxDepth <= 0 && limits.MaxArrayLen <= 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var stack []*jsonFrame
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return describeDecodeError(err)
+		}
+
+		var top *jsonFrame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		// tok starts a value (or is an object key).
+		path := ""
+		if top != nil {
+			if top.array {
+				top.count++
+				if limits.MaxArrayLen > 0 && top.count > limits.MaxArrayLen {
+					return fmt.Errorf("%s has more than %d elements", displayPath(top.path), limits.MaxArrayLen)
+				}
+			} else if top.expectKey {
+				top.key, _ = tok.(string)
+				top.expectKey = false
+				continue
+			} else {
+				top.expectKey = true
+			}
+			path = top.childPath()
+		}
+
+		if delim, ok := tok.(json.Delim); ok {
+			if limits.MaxDepth > 0 && len(stack) >= limits.MaxDepth {
+				return fmt.Errorf("%s exceeds the maximum nesting depth of %d", displayPath(path), limits.MaxDepth)
+			}
+			stack = append(stack, &jsonFrame{path: path, array: delim == '[', expectKey: delim == '{'})
+		}
+	}
+}
+
+// displayPath names a scan path for an error message.
+func displayPath(path string) string {
+	if path == "" {
+		return "request body"
+	}
+	return strconv.Quote(path)
+}
+
+// describeDecodeError rewrites encoding/json errors into client-facing
+// messages that name the field but not the Go types behind it.
+func describeDecodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return errors.New("request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("request body is truncated")
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("malformed JSON at offset %d: %s", syntaxErr.Offset, syntaxErr.Error())
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return fmt.Errorf("request body must be %s, got %s", jsonKind(typeErr.Type.Kind().String()), typeErr.Value)
+		}
+		return fmt.Errorf("field %q must be %s, got %s", typeErr.Field, jsonKind(typeErr.Type.Kind().String()), typeErr.Value)
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return fmt.Errorf("unknown field %s", field)
+	}
+	return err
+}
+
+// jsonKind maps a reflect.Kind name to the JSON type a client would send.
+func jsonKind(kind string) string {
+	switch {
+	case kind == "string":
+		return "a string"
+	case kind == "bool":
+		return "a boolean"
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "a number"
+	case kind == "slice" || kind == "array":
+		return "an array"
+	case kind == "struct" || kind == "map":
+		return "an object"
+	}
+	return "a " + kind
+}
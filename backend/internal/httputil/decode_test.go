@@ -0,0 +1,116 @@
+package httputil
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type decodeTarget struct {
+	Name  string          `json:"name"`
+	Lines []string        `json:"lines"`
+	Extra json.RawMessage `json:"extra,omitempty"`
+	Count int             `json:"count"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	limits := JSONLimits{MaxDepth: 4, MaxArrayLen: 3, DisallowUnknownFields: true}
+
+	t.Run("valid body passes", func(t *testing.T) {
+		var got decodeTarget
+		body := `{"name":"a","lines":["x","y","z"],"extra":{"b":{"c":[1]}},"count":2}`
+		if err := DecodeJSON(strings.NewReader(body), &got, limits); err != nil {
+			t.Fatalf("DecodeJSON() error = %v", err)
+		}
+		if got.Name != "a" || len(got.Lines) != 3 || got.Count != 2 {
+			t.Errorf("DecodeJSON() = %+v", got)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		body    string
+		limits  JSONLimits
+		wantErr string
+	}{
+		{
+			name:    "too deep",
+			body:    `{"extra":{"b":{"c":{"d":{}}}}}`,
+			limits:  limits,
+			wantErr: `"extra.b.c.d" exceeds the maximum nesting depth of 4`,
+		},
+		{
+			name:    "too deep inside an array",
+			body:    `{"extra":[[[[1]]]]}`,
+			limits:  limits,
+			wantErr: `"extra[0][0][0]" exceeds the maximum nesting depth of 4`,
+		},
+		{
+			name:    "array too long",
+			body:    `{"lines":["a","b","c","d"]}`,
+			limits:  limits,
+			wantErr: `"lines" has more than 3 elements`,
+		},
+		{
+			name:    "top-level array too long",
+			body:    `[1,2,3,4]`,
+			limits:  limits,
+			wantErr: `request body has more than 3 elements`,
+		},
+		{
+			name:    "unknown field",
+			body:    `{"name":"a","surprise":true}`,
+			limits:  limits,
+			wantErr: `unknown field "surprise"`,
+		},
+		{
+			name:    "wrong type names the field",
+			body:    `{"count":"two"}`,
+			limits:  limits,
+			wantErr: `field "count" must be a number, got string`,
+		},
+		{
+			name:    "malformed",
+			body:    `{"name":}`,
+			limits:  limits,
+			wantErr: "malformed JSON",
+		},
+		{
+			name:    "empty",
+			body:    ``,
+			limits:  limits,
+			wantErr: "request body is empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got decodeTarget
+			err := DecodeJSON(strings.NewReader(tt.body), &got, tt.limits)
+			if err == nil {
+				t.Fatalf("DecodeJSON() succeeded, want error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("DecodeJSON() error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("zero limits accept anything well-formed", func(t *testing.T) {
+		var got decodeTarget
+		body := `{"lines":["a","b","c","d"],"extra":{"b":{"c":{"d":{"e":{}}}}},"surprise":1}`
+		if err := DecodeJSON(strings.NewReader(body), &got, JSONLimits{}); err != nil {
+			t.Fatalf("DecodeJSON() error = %v", err)
+		}
+		if len(got.Lines) != 4 {
+			t.Errorf("lines = %v, want 4 entries", got.Lines)
+		}
+	})
+
+	t.Run("keys that look like values do not confuse the scan", func(t *testing.T) {
+		var got decodeTarget
+		body := `{"name":"lines","extra":{"name":["a","b","c"]}}`
+		if err := DecodeJSON(strings.NewReader(body), &got, limits); err != nil {
+			t.Fatalf("DecodeJSON() error = %v", err)
+		}
+	})
+}
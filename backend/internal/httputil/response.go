@@ -3,8 +3,114 @@ package httputil
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
 )
 
+// ErrorCode is a stable, machine-readable identifier carried in the "code"
+// field of every JSON error response. Clients branch on the code; the "error"
+// message is for humans and may be reworded at any time.
+type ErrorCode string
+
+// Generic codes. RespondError derives one of these from the HTTP status when
+// the handler has nothing more specific to say.
+const (
+	CodeBadRequest           ErrorCode = "bad_request"
+	CodeUnauthorized         ErrorCode = "unauthorized"
+	CodeForbidden            ErrorCode = "forbidden"
+	CodeNotFound             ErrorCode = "not_found"
+	CodeConflict             ErrorCode = "conflict"
+	CodeGone                 ErrorCode = "gone"
+	CodePayloadTooLarge      ErrorCode = "payload_too_large"
+	CodeUnsupportedMediaType ErrorCode = "unsupported_media_type"
+	CodeTooManyRequests      ErrorCode = "too_many_requests"
+	CodeInternal             ErrorCode = "internal_error"
+	CodeServiceUnavailable   ErrorCode = "service_unavailable"
+)
+
+// Specific codes emitted by the sync API.
+const (
+	CodeInvalidRequestBody  ErrorCode = "invalid_request_body"
+	CodeValidationFailed    ErrorCode = "validation_failed"
+	CodeUnsupportedFileType ErrorCode = "unsupported_file_type"
+	CodeSessionNotFound     ErrorCode = "session_not_found"
+	CodeFileNotFound        ErrorCode = "file_not_found"
+	CodeChunkOverlap        ErrorCode = "chunk_overlap"
+	CodeChunkGap            ErrorCode = "chunk_gap"
+	CodeChunkLimitExceeded  ErrorCode = "chunk_limit_exceeded"
+	// CodeChunkInProgress (429) is returned by sync/chunk while another
+	// upload to the same file holds its lock; retry after Retry-After.
+	CodeChunkInProgress ErrorCode = "chunk_in_progress"
+	// CodeSessionVelocityExceeded (429) is returned by sync/init when the API
+	// key has created too many new sessions this hour or day.
+	CodeSessionVelocityExceeded ErrorCode = "session_velocity_exceeded"
+	// CodeContentDenied (422) is returned by sync/chunk when the chunk matches
+	// a rule of the server's ingest denylist; nothing from it is stored.
+	CodeContentDenied ErrorCode = "content_denied"
+	// CodeTranscriptArchived (410) is returned for raw transcript reads and
+	// uploads after transcript retention deleted the session's chunks.
+	CodeTranscriptArchived ErrorCode = "transcript_archived"
+	// CodeInvalidFileName (400) is returned by sync/chunk, when
+	// SYNC_FILE_POLICY_STRICT is on, for a file_name that isn't a shape the
+	// session type uses for that file_type.
+	CodeInvalidFileName ErrorCode = "invalid_file_name"
+)
+
+// CodeQueryTimeout (503) is returned when a database query runs past
+// DB_QUERY_TIMEOUT_SECONDS.
+const CodeQueryTimeout ErrorCode = "query_timeout"
+
+// ErrorResponse is the JSON body of every error response.
+type ErrorResponse struct {
+	Error string    `json:"error"`
+	Code  ErrorCode `json:"code"`
+}
+
+// QueryTimeoutResponse is the 503 body for a query that timed out.
+// RetryAfter is in seconds and matches the Retry-After header.
+type QueryTimeoutResponse struct {
+	ErrorResponse
+	RetryAfter int `json:"retry_after"`
+}
+
+// FieldLimitResponse is the 400 validation_failed body for a request field
+// over its size limit. Field names the field and Limit is its limit in bytes.
+type FieldLimitResponse struct {
+	ErrorResponse
+	Field string `json:"field"`
+	Limit int    `json:"limit"`
+}
+
+// CodeForStatus returns the generic error code for an HTTP status.
+func CodeForStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusGone:
+		return CodeGone
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMediaType
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case http.StatusServiceUnavailable:
+		return CodeServiceUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
 // RespondJSON writes a JSON response with the given status code and data.
 func RespondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -13,9 +119,34 @@ func RespondJSON(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
-// RespondError writes a JSON error response with the given status code and message.
+// RespondError writes a JSON error response with the given status code and
+// message, using the generic code for the status.
 func RespondError(w http.ResponseWriter, status int, message string) {
-	RespondJSON(w, status, map[string]string{
-		"error": message,
+	RespondErrorCode(w, status, CodeForStatus(status), message)
+}
+
+// RespondErrorCode writes a JSON error response with an explicit error code.
+func RespondErrorCode(w http.ResponseWriter, status int, code ErrorCode, message string) {
+	RespondJSON(w, status, ErrorResponse{Error: message, Code: code})
+}
+
+// RespondFieldLimit writes a 400 validation_failed naming the field that is
+// over its limit and the limit.
+func RespondFieldLimit(w http.ResponseWriter, field string, limit int, message string) {
+	RespondJSON(w, http.StatusBadRequest, FieldLimitResponse{
+		ErrorResponse: ErrorResponse{Error: message, Code: CodeValidationFailed},
+		Field:         field,
+		Limit:         limit,
+	})
+}
+
+// RespondQueryTimeout writes a 503 for a query that ran past its timeout,
+// asking the client to retry after the same interval.
+func RespondQueryTimeout(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(retryAfter.Seconds())
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	RespondJSON(w, http.StatusServiceUnavailable, QueryTimeoutResponse{
+		ErrorResponse: ErrorResponse{Error: "query timeout", Code: CodeQueryTimeout},
+		RetryAfter:    seconds,
 	})
 }
@@ -72,7 +72,49 @@ func TestRespondError_ShapeAndStatus(t *testing.T) {
 	if decoded["error"] != "bad input" {
 		t.Errorf("error = %q, want %q", decoded["error"], "bad input")
 	}
-	if len(decoded) != 1 {
-		t.Errorf("body has %d keys, want 1", len(decoded))
+	if decoded["code"] != string(CodeBadRequest) {
+		t.Errorf("code = %q, want %q", decoded["code"], CodeBadRequest)
+	}
+	if len(decoded) != 2 {
+		t.Errorf("body has %d keys, want 2", len(decoded))
+	}
+}
+
+func TestRespondErrorCode_ExplicitCode(t *testing.T) {
+	rec := httptest.NewRecorder()
+	RespondErrorCode(rec, http.StatusBadRequest, CodeChunkGap, "first_line must be 5 (got 9)")
+
+	var decoded ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("body is not valid JSON: %v", err)
+	}
+	if decoded.Code != CodeChunkGap || decoded.Error != "first_line must be 5 (got 9)" {
+		t.Errorf("decoded = %+v", decoded)
+	}
+}
+
+func TestCodeForStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   ErrorCode
+	}{
+		{http.StatusBadRequest, CodeBadRequest},
+		{http.StatusUnauthorized, CodeUnauthorized},
+		{http.StatusForbidden, CodeForbidden},
+		{http.StatusNotFound, CodeNotFound},
+		{http.StatusConflict, CodeConflict},
+		{http.StatusGone, CodeGone},
+		{http.StatusRequestEntityTooLarge, CodePayloadTooLarge},
+		{http.StatusUnsupportedMediaType, CodeUnsupportedMediaType},
+		{http.StatusTooManyRequests, CodeTooManyRequests},
+		{http.StatusInternalServerError, CodeInternal},
+		{http.StatusBadGateway, CodeInternal},
+		{http.StatusServiceUnavailable, CodeServiceUnavailable},
+		{http.StatusMethodNotAllowed, CodeBadRequest},
+	}
+	for _, tt := range tests {
+		if got := CodeForStatus(tt.status); got != tt.want {
+			t.Errorf("CodeForStatus(%d) = %q, want %q", tt.status, got, tt.want)
+		}
 	}
 }
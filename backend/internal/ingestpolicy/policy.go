@@ -0,0 +1,150 @@
+// Package ingestpolicy refuses transcript content an organization never wants
+// stored. A Policy is a denylist of named regular expressions; the sync API
+// checks every chunk against it before anything is written, so a matching
+// secret is rejected rather than stored and later redacted.
+package ingestpolicy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// validRuleName keeps rule names safe to echo back in error responses.
+var validRuleName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// Rule is one denylisted pattern. Name is reported when the rule matches;
+// Pattern is RE2 syntax (https://github.com/google/re2/wiki/Syntax).
+type Rule struct {
+	Name    string
+	Pattern string
+}
+
+// Policy matches content against a set of rules. All rules are compiled into
+// one alternation, so a line is scanned once however many rules there are
+// (RE2 matching is linear in the input). A nil *Policy denies nothing.
+type Policy struct {
+	re    *regexp.Regexp
+	names []string // rule names, in rule order
+	group []int    // subexpression index of each rule's group in re
+}
+
+// New compiles rules into a Policy. Each pattern must compile on its own and
+// each name must be unique. An empty rule set returns a nil Policy.
+func New(rules []Rule) (*Policy, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(rules))
+	alts := make([]string, len(rules))
+	for i, r := range rules {
+		if !validRuleName.MatchString(r.Name) {
+			return nil, fmt.Errorf("rule %q: name must be 1-64 letters, digits, '_', '.' or '-'", r.Name)
+		}
+		if seen[r.Name] {
+			return nil, fmt.Errorf("rule %q: duplicate name", r.Name)
+		}
+		seen[r.Name] = true
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		alts[i] = fmt.Sprintf("(?P<rule%d>%s)", i, r.Pattern)
+	}
+
+	re, err := regexp.Compile(strings.Join(alts, "|"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to combine rules: %w", err)
+	}
+	p := &Policy{re: re, names: make([]string, len(rules)), group: make([]int, len(rules))}
+	for i, r := range rules {
+		p.names[i] = r.Name
+		p.group[i] = re.SubexpIndex(fmt.Sprintf("rule%d", i))
+	}
+	return p, nil
+}
+
+// Parse reads rules, one per line as "name = pattern". Blank lines and lines
+// starting with '#' are skipped. Whitespace around the name and pattern is
+// trimmed; a pattern that needs edge whitespace can use \s or \x20.
+func Parse(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, pattern, ok := strings.Cut(line, "=")
+		name, pattern = strings.TrimSpace(name), strings.TrimSpace(pattern)
+		if !ok || name == "" || pattern == "" {
+			return nil, fmt.Errorf("line %d: want \"name = pattern\"", lineNum)
+		}
+		rules = append(rules, Rule{Name: name, Pattern: pattern})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// LoadFile parses and compiles the rules in the file at path.
+func LoadFile(path string) (*Policy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rules, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	p, err := New(rules)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Len returns the number of rules.
+func (p *Policy) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.names)
+}
+
+// Match returns the name of the rule matching s, if any. When several rules
+// match, the one matching earliest in s is reported.
+func (p *Policy) Match(s string) (string, bool) {
+	if p == nil || !p.re.MatchString(s) {
+		return "", false
+	}
+	m := p.re.FindStringSubmatchIndex(s)
+	for i, g := range p.group {
+		if m[2*g] >= 0 {
+			return p.names[i], true
+		}
+	}
+	return "", false
+}
+
+// MatchLines returns the first of lines matching a rule: the rule's name and
+// the line's 0-based index.
+func (p *Policy) MatchLines(lines []string) (rule string, index int, ok bool) {
+	if p == nil {
+		return "", 0, false
+	}
+	for i, line := range lines {
+		if name, ok := p.Match(line); ok {
+			return name, i, true
+		}
+	}
+	return "", 0, false
+}
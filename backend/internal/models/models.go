@@ -13,6 +13,29 @@ const (
 	UserStatusInactive UserStatus = "inactive"
 )
 
+// RateLimitTier selects the limit set the per-user rate limiters apply to a
+// user. Every user starts on RateLimitTierStandard.
+type RateLimitTier string
+
+const (
+	RateLimitTierStandard RateLimitTier = "standard"
+	RateLimitTierTrusted  RateLimitTier = "trusted"
+	RateLimitTierInternal RateLimitTier = "internal"
+)
+
+// RateLimitTiers lists every tier, lowest limits first.
+var RateLimitTiers = []RateLimitTier{RateLimitTierStandard, RateLimitTierTrusted, RateLimitTierInternal}
+
+// Valid reports whether t is one of RateLimitTiers.
+func (t RateLimitTier) Valid() bool {
+	for _, tier := range RateLimitTiers {
+		if t == tier {
+			return true
+		}
+	}
+	return false
+}
+
 // User represents a confab user (OAuth-based)
 type User struct {
 	ID        int64      `json:"id"`
@@ -28,6 +51,9 @@ type User struct {
 	IsAdmin   bool      `json:"-"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// RateLimitTier is the users.rate_limit_tier column. Internal like IsAdmin;
+	// only the admin user list serializes it.
+	RateLimitTier RateLimitTier `json:"-"`
 }
 
 // AdminUserStats extends User with admin-visible statistics
@@ -69,6 +95,8 @@ type WebSession struct {
 	// LastActivityAt drives the sliding idle-timeout gate (60j6). Nullable in the
 	// DB (rollout-gap rows fall back to created_at via COALESCE); never serialized.
 	LastActivityAt sql.NullTime `json:"-"`
+	// RateLimitTier picks the per-user rate limits for the session's requests; never serialized.
+	RateLimitTier RateLimitTier `json:"-"`
 }
 
 // APIKey represents an API key for authentication
@@ -79,6 +107,9 @@ type APIKey struct {
 	Name       string     `json:"name"`
 	CreatedAt  time.Time  `json:"created_at"`
 	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// CreatedSessionCount is how many sessions this key's sync/init created.
+	// Filled by ListAPIKeys only.
+	CreatedSessionCount int64 `json:"created_session_count"`
 }
 
 // GitHubLinkType represents the type of GitHub artifact
@@ -1,5 +1,7 @@
 package models
 
+import "slices"
+
 // Provider identity for Confab sessions.
 //
 // Confab is OSS self-hosted, so we cannot assume any operator's database
@@ -95,3 +97,28 @@ func ExpandWithAliases(canonical []string) []string {
 	}
 	return out
 }
+
+// registeredSessionTypes holds extra session types beyond CanonicalProviders
+// that have an analytics handler registered (pluggable session types).
+// Populated at init time by analytics.RegisterProvider, so the wire
+// validator, chunk storage, and the stale-session filter all agree on
+// which types exist without this leaf package importing analytics.
+var registeredSessionTypes = map[string]struct{}{}
+
+// RegisterSessionType marks name as a known session type. Called from
+// analytics.RegisterProvider for each canonical registration; callers
+// should not need it directly. Registration happens at init time.
+func RegisterSessionType(name string) {
+	registeredSessionTypes[name] = struct{}{}
+}
+
+// IsKnownSessionType reports whether name may be stored as a new session's
+// session_type: a canonical provider, or a type with a registered analytics
+// handler. Legacy aliases are not accepted — they exist only in old rows.
+func IsKnownSessionType(name string) bool {
+	if slices.Contains(CanonicalProviders, name) {
+		return true
+	}
+	_, ok := registeredSessionTypes[name]
+	return ok
+}
@@ -140,3 +140,23 @@ func TestExpandWithAliases(t *testing.T) {
 		})
 	}
 }
+
+func TestIsKnownSessionType(t *testing.T) {
+	for _, p := range CanonicalProviders {
+		if !IsKnownSessionType(p) {
+			t.Errorf("IsKnownSessionType(%q) = false, want true for canonical provider", p)
+		}
+	}
+	if IsKnownSessionType(ProviderClaudeCodeLegacy) {
+		t.Error("legacy alias must not be a storable session type")
+	}
+	if IsKnownSessionType("other-tool") {
+		t.Error("unregistered type must be unknown")
+	}
+
+	RegisterSessionType("other-tool")
+	t.Cleanup(func() { delete(registeredSessionTypes, "other-tool") })
+	if !IsKnownSessionType("other-tool") {
+		t.Error("registered type must be known")
+	}
+}
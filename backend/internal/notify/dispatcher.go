@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/ConfabulousDev/confab-web/internal/db/dbnotify"
+	dbuser "github.com/ConfabulousDev/confab-web/internal/db/user"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+var tracer = otel.Tracer("confab/notify")
+
+const (
+	// flushLease is how long a claimed notification stays hidden from other
+	// flushes while it is sent.
+	flushLease = 5 * time.Minute
+	// maxFlushAttempts is how many sends a queued notification gets before
+	// it is dropped.
+	maxFlushAttempts = 5
+)
+
+// Notification is one message to a user. It is JSON-encoded while it waits
+// out quiet hours.
+type Notification struct {
+	UserID    int64          `json:"-"`
+	Type      string         `json:"type"`           // a registered Type name
+	Subject   string         `json:"subject"`        // email subject, plain text
+	Body      string         `json:"body"`           // email body, plain text
+	URL       string         `json:"url,omitempty"`  // optional link to details
+	Data      map[string]any `json:"data,omitempty"` // webhook payload "data"
+	CreatedAt time.Time      `json:"created_at"`     // set by Notify when zero
+}
+
+// Sender delivers a notification on one channel.
+type Sender interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// preferenceSource is where Dispatcher reads a user's channel choices and
+// quiet hours. *dbuser.Store satisfies it.
+type preferenceSource interface {
+	GetPreferences(ctx context.Context, userID int64) (dbuser.Preferences, error)
+}
+
+// queueStore holds notifications during quiet hours. *dbnotify.Store
+// satisfies it; tests pass a fake.
+type queueStore interface {
+	Enqueue(ctx context.Context, userID int64, notificationType, channel string, payload json.RawMessage, deliverAfter time.Time) error
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]dbnotify.Queued, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+// Dispatcher is where every notification producer sends its notifications.
+// It applies the user's per-channel choices and quiet hours, then sends on
+// each enabled channel or holds the notification until the window ends.
+type Dispatcher struct {
+	prefs   preferenceSource
+	queue   queueStore
+	senders map[string]Sender // by channel; a missing channel is not configured
+	now     func() time.Time
+}
+
+// NewDispatcher creates a dispatcher. senders maps ChannelEmail and
+// ChannelWebhook to their senders; leave one out when it is not configured
+// (no email service), and notifications skip it.
+func NewDispatcher(prefs preferenceSource, queue queueStore, senders map[string]Sender) *Dispatcher {
+	return &Dispatcher{prefs: prefs, queue: queue, senders: senders, now: time.Now}
+}
+
+// Notify sends n on each channel the user enabled for its type. A non-urgent
+// notification arriving in the user's quiet hours is queued per channel until
+// the window ends, for FlushDue to send. Channel failures don't stop the
+// other channels; they are returned joined.
+func (d *Dispatcher) Notify(ctx context.Context, n Notification) error {
+	ctx, span := tracer.Start(ctx, "notify.notify")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int64("user.id", n.UserID),
+		attribute.String("notification.type", n.Type),
+	)
+
+	t, ok := LookupType(n.Type)
+	if !ok {
+		return fmt.Errorf("unknown notification type %q", n.Type)
+	}
+	prefs, err := d.prefs.GetPreferences(ctx, n.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load notification preferences: %w", err)
+	}
+	now := d.now()
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = now.UTC()
+	}
+
+	var until time.Time
+	quiet := false
+	if !t.Urgent {
+		until, quiet = QuietUntil(prefs, now)
+	}
+	span.SetAttributes(attribute.Bool("notification.queued", quiet))
+
+	var payload json.RawMessage
+	if quiet {
+		if payload, err = json.Marshal(n); err != nil {
+			return fmt.Errorf("failed to encode notification: %w", err)
+		}
+	}
+
+	channels := channelsFor(prefs, t)
+	var errs []error
+	for _, channel := range channelOrder {
+		sender := d.senders[channel]
+		if sender == nil || !enabled(channels, channel) {
+			continue
+		}
+		if quiet {
+			err = d.queue.Enqueue(ctx, n.UserID, n.Type, channel, payload, until)
+		} else {
+			err = sender.Send(ctx, n)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// FlushDue sends up to limit queued notifications whose quiet window has
+// ended, in the order they fell due and then the order they were queued, and
+// returns how many it sent. A channel the user has since turned off is
+// skipped. A failed send is retried on a later flush, up to
+// maxFlushAttempts; the error is only returned when claiming fails.
+func (d *Dispatcher) FlushDue(ctx context.Context, limit int) (int, error) {
+	ctx, span := tracer.Start(ctx, "notify.flush_due")
+	defer span.End()
+
+	claimed, err := d.queue.ClaimDue(ctx, limit, flushLease)
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+
+	sent := 0
+	for _, q := range claimed {
+		log := logger.Ctx(ctx).With("notification_id", q.ID, "user_id", q.UserID,
+			"type", q.Type, "channel", q.Channel, "attempt", q.Attempts)
+		if d.flushOne(ctx, q, log) {
+			sent++
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("notification.claimed", len(claimed)),
+		attribute.Int("notification.sent", sent),
+	)
+	return sent, nil
+}
+
+// flushOne sends one claimed notification and deletes it once it is sent or
+// will never be. It reports whether it was sent.
+func (d *Dispatcher) flushOne(ctx context.Context, q dbnotify.Queued, log *slog.Logger) bool {
+	remove := func() {
+		if err := d.queue.Delete(context.WithoutCancel(ctx), q.ID); err != nil {
+			log.Error("failed to delete queued notification", "error", err)
+		}
+	}
+
+	var n Notification
+	if err := json.Unmarshal(q.Payload, &n); err != nil {
+		log.Error("dropping undecodable queued notification", "error", err)
+		remove()
+		return false
+	}
+	n.UserID = q.UserID
+
+	sender := d.senders[q.Channel]
+	t, known := LookupType(q.Type)
+	if sender == nil || !known {
+		log.Warn("dropping queued notification: channel not configured or type unknown")
+		remove()
+		return false
+	}
+	prefs, err := d.prefs.GetPreferences(ctx, q.UserID)
+	if err != nil {
+		log.Warn("failed to load notification preferences; will retry", "error", err)
+		return false
+	}
+	if !enabled(channelsFor(prefs, t), q.Channel) {
+		remove()
+		return false
+	}
+
+	if err := sender.Send(ctx, n); err != nil {
+		if q.Attempts >= maxFlushAttempts {
+			log.Error("giving up on queued notification", "error", err)
+			remove()
+		} else {
+			log.Warn("failed to send queued notification; will retry", "error", err)
+		}
+		return false
+	}
+	remove()
+	return true
+}
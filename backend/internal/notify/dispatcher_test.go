@@ -0,0 +1,333 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db/dbnotify"
+	dbuser "github.com/ConfabulousDev/confab-web/internal/db/user"
+)
+
+// digestType is a non-urgent type registered for these tests; the only
+// production type is urgent.
+const digestType = "test.digest"
+
+func registerTestTypes(t *testing.T) {
+	t.Helper()
+	saved := Types
+	Types = append(slices.Clone(Types), Type{Name: digestType, Defaults: dbuser.NotificationChannels{Email: true, Webhook: true}})
+	t.Cleanup(func() { Types = saved })
+}
+
+type fakePrefs map[int64]dbuser.Preferences
+
+func (f fakePrefs) GetPreferences(_ context.Context, userID int64) (dbuser.Preferences, error) {
+	if p, ok := f[userID]; ok {
+		return p, nil
+	}
+	return dbuser.DefaultPreferences(), nil
+}
+
+type fakeQueued struct {
+	dbnotify.Queued
+	deliverAfter time.Time
+}
+
+// fakeQueue mimics dbnotify.Store: due rows are claimed in deliver_after,
+// then id order, and leased.
+type fakeQueue struct {
+	now    func() time.Time
+	nextID int64
+	rows   []*fakeQueued
+}
+
+func (q *fakeQueue) Enqueue(_ context.Context, userID int64, notificationType, channel string, payload json.RawMessage, deliverAfter time.Time) error {
+	q.nextID++
+	q.rows = append(q.rows, &fakeQueued{
+		Queued:       dbnotify.Queued{ID: q.nextID, UserID: userID, Type: notificationType, Channel: channel, Payload: payload},
+		deliverAfter: deliverAfter,
+	})
+	return nil
+}
+
+func (q *fakeQueue) ClaimDue(_ context.Context, limit int, lease time.Duration) ([]dbnotify.Queued, error) {
+	now := q.now()
+	var due []*fakeQueued
+	for _, r := range q.rows {
+		if !r.deliverAfter.After(now) {
+			due = append(due, r)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].deliverAfter.Equal(due[j].deliverAfter) {
+			return due[i].deliverAfter.Before(due[j].deliverAfter)
+		}
+		return due[i].ID < due[j].ID
+	})
+	var claimed []dbnotify.Queued
+	for _, r := range due[:min(limit, len(due))] {
+		r.Attempts++
+		r.deliverAfter = now.Add(lease)
+		claimed = append(claimed, r.Queued)
+	}
+	return claimed, nil
+}
+
+func (q *fakeQueue) Delete(_ context.Context, id int64) error {
+	q.rows = slices.DeleteFunc(q.rows, func(r *fakeQueued) bool { return r.ID == id })
+	return nil
+}
+
+type fakeSender struct {
+	sent []Notification
+	err  error
+}
+
+func (s *fakeSender) Send(_ context.Context, n Notification) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, n)
+	return nil
+}
+
+// testDispatcher wires a dispatcher to fakes with a settable clock.
+type testDispatcher struct {
+	*Dispatcher
+	clock   time.Time
+	queue   *fakeQueue
+	email   *fakeSender
+	webhook *fakeSender
+}
+
+func newTestDispatcher(t *testing.T, prefs fakePrefs, clock time.Time) *testDispatcher {
+	t.Helper()
+	registerTestTypes(t)
+	td := &testDispatcher{clock: clock, email: &fakeSender{}, webhook: &fakeSender{}}
+	td.queue = &fakeQueue{now: func() time.Time { return td.clock }}
+	td.Dispatcher = NewDispatcher(prefs, td.queue, map[string]Sender{ChannelEmail: td.email, ChannelWebhook: td.webhook})
+	td.now = func() time.Time { return td.clock }
+	return td
+}
+
+// nightOwl has quiet hours 22:00-07:00 in Berlin.
+func nightOwl() dbuser.Preferences {
+	p := dbuser.DefaultPreferences()
+	p.Timezone = "Europe/Berlin"
+	p.QuietHours = &dbuser.QuietHours{Start: "22:00", End: "07:00"}
+	return p
+}
+
+func berlin(t *testing.T, value string) time.Time {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	at, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+	if err != nil {
+		t.Fatalf("ParseInLocation: %v", err)
+	}
+	return at
+}
+
+func TestQuietUntil(t *testing.T) {
+	daytime := dbuser.DefaultPreferences()
+	daytime.QuietHours = &dbuser.QuietHours{Start: "12:00", End: "13:30"}
+
+	tests := []struct {
+		name      string
+		prefs     dbuser.Preferences
+		now       time.Time
+		wantQuiet bool
+		wantUntil time.Time
+	}{
+		{"no quiet hours", dbuser.DefaultPreferences(), berlin(t, "2026-03-10 23:00"), false, time.Time{}},
+		{"before midnight", nightOwl(), berlin(t, "2026-03-10 23:15"), true, berlin(t, "2026-03-11 07:00")},
+		{"after midnight", nightOwl(), berlin(t, "2026-03-11 02:00"), true, berlin(t, "2026-03-11 07:00")},
+		{"at the start", nightOwl(), berlin(t, "2026-03-10 22:00"), true, berlin(t, "2026-03-11 07:00")},
+		{"at the end", nightOwl(), berlin(t, "2026-03-11 07:00"), false, time.Time{}},
+		{"daytime outside", nightOwl(), berlin(t, "2026-03-11 12:00"), false, time.Time{}},
+		{"same-day window", daytime, time.Date(2026, 3, 11, 12, 45, 0, 0, time.UTC), true, time.Date(2026, 3, 11, 13, 30, 0, 0, time.UTC)},
+		{"same-day window outside", daytime, time.Date(2026, 3, 11, 14, 0, 0, 0, time.UTC), false, time.Time{}},
+		// Europe/Berlin moves to summer time at 02:00 on 2026-03-29; 07:00
+		// local is then 05:00 UTC rather than 06:00.
+		{"across a DST change", nightOwl(), berlin(t, "2026-03-28 23:00"), true, time.Date(2026, 3, 29, 5, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, quiet := QuietUntil(tt.prefs, tt.now)
+			if quiet != tt.wantQuiet || !until.Equal(tt.wantUntil) {
+				t.Errorf("QuietUntil = %v, %v; want %v, %v", until, quiet, tt.wantUntil, tt.wantQuiet)
+			}
+		})
+	}
+}
+
+func TestNotify_QueuesDuringQuietHours(t *testing.T) {
+	d := newTestDispatcher(t, fakePrefs{1: nightOwl()}, berlin(t, "2026-03-10 23:15"))
+
+	if err := d.Notify(context.Background(), Notification{UserID: 1, Type: digestType, Subject: "Digest"}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(d.email.sent)+len(d.webhook.sent) != 0 {
+		t.Fatalf("sent during quiet hours: email %v, webhook %v", d.email.sent, d.webhook.sent)
+	}
+	if len(d.queue.rows) != 2 {
+		t.Fatalf("queued %d, want one per channel", len(d.queue.rows))
+	}
+	for _, r := range d.queue.rows {
+		if !r.deliverAfter.Equal(berlin(t, "2026-03-11 07:00")) {
+			t.Errorf("%s deliver_after = %v, want the end of the window", r.Channel, r.deliverAfter)
+		}
+	}
+
+	// Still quiet: nothing is due.
+	d.clock = berlin(t, "2026-03-11 06:59")
+	if sent, err := d.FlushDue(context.Background(), 10); err != nil || sent != 0 {
+		t.Fatalf("FlushDue before the window ends = %d, %v; want 0", sent, err)
+	}
+
+	d.clock = berlin(t, "2026-03-11 07:01")
+	if sent, err := d.FlushDue(context.Background(), 10); err != nil || sent != 2 {
+		t.Fatalf("FlushDue = %d, %v; want 2", sent, err)
+	}
+	if len(d.email.sent) != 1 || d.email.sent[0].Subject != "Digest" || d.email.sent[0].UserID != 1 {
+		t.Errorf("email sent = %+v", d.email.sent)
+	}
+	if len(d.webhook.sent) != 1 || !d.webhook.sent[0].CreatedAt.Equal(berlin(t, "2026-03-10 23:15")) {
+		t.Errorf("webhook sent = %+v, want the original created_at", d.webhook.sent)
+	}
+	if len(d.queue.rows) != 0 {
+		t.Errorf("queue holds %d after flush, want 0", len(d.queue.rows))
+	}
+}
+
+func TestNotify_UrgentBypassesQuietHours(t *testing.T) {
+	d := newTestDispatcher(t, fakePrefs{1: nightOwl()}, berlin(t, "2026-03-10 23:15"))
+
+	n := Notification{UserID: 1, Type: "api_key.velocity_exceeded", Subject: "Key tripped"}
+	if err := d.Notify(context.Background(), n); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(d.queue.rows) != 0 {
+		t.Errorf("queued %d urgent notifications, want 0", len(d.queue.rows))
+	}
+	if len(d.email.sent) != 1 || len(d.webhook.sent) != 1 {
+		t.Errorf("sent email %d, webhook %d; want 1 each", len(d.email.sent), len(d.webhook.sent))
+	}
+}
+
+func TestNotify_ChannelChoices(t *testing.T) {
+	prefs := dbuser.DefaultPreferences()
+	prefs.Notifications = map[string]dbuser.NotificationChannels{digestType: {Webhook: true}}
+	d := newTestDispatcher(t, fakePrefs{1: prefs}, time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC))
+
+	if err := d.Notify(context.Background(), Notification{UserID: 1, Type: digestType}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(d.email.sent) != 0 || len(d.webhook.sent) != 1 {
+		t.Errorf("sent email %d, webhook %d; want webhook only", len(d.email.sent), len(d.webhook.sent))
+	}
+
+	// A user without choices gets the type's defaults; an unconfigured
+	// channel is skipped.
+	delete(d.senders, ChannelEmail)
+	if err := d.Notify(context.Background(), Notification{UserID: 2, Type: digestType}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(d.webhook.sent) != 2 {
+		t.Errorf("webhook sent %d, want 2", len(d.webhook.sent))
+	}
+
+	if err := d.Notify(context.Background(), Notification{UserID: 1, Type: "no.such.type"}); err == nil {
+		t.Error("Notify accepted an unknown type")
+	}
+}
+
+func TestFlushDue_Order(t *testing.T) {
+	early := dbuser.DefaultPreferences()
+	early.QuietHours = &dbuser.QuietHours{Start: "22:00", End: "06:00"}
+	late := dbuser.DefaultPreferences()
+	late.QuietHours = &dbuser.QuietHours{Start: "22:00", End: "07:00"}
+	for _, p := range []*dbuser.Preferences{&early, &late} {
+		p.Notifications = map[string]dbuser.NotificationChannels{digestType: {Email: true}}
+	}
+	d := newTestDispatcher(t, fakePrefs{1: late, 2: early}, time.Date(2026, 3, 10, 23, 0, 0, 0, time.UTC))
+
+	// User 1's window ends last, so its notifications go out after user 2's
+	// even though user 1 was notified first; each user's go out in order.
+	for _, n := range []Notification{
+		{UserID: 1, Type: digestType, Subject: "late first"},
+		{UserID: 2, Type: digestType, Subject: "early first"},
+		{UserID: 1, Type: digestType, Subject: "late second"},
+		{UserID: 2, Type: digestType, Subject: "early second"},
+	} {
+		if err := d.Notify(context.Background(), n); err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+		d.clock = d.clock.Add(time.Minute)
+	}
+
+	d.clock = time.Date(2026, 3, 11, 8, 0, 0, 0, time.UTC)
+	if sent, err := d.FlushDue(context.Background(), 10); err != nil || sent != 4 {
+		t.Fatalf("FlushDue = %d, %v; want 4", sent, err)
+	}
+	var got []string
+	for _, n := range d.email.sent {
+		got = append(got, n.Subject)
+	}
+	want := []string{"early first", "early second", "late first", "late second"}
+	if !slices.Equal(got, want) {
+		t.Errorf("flush order = %v, want %v", got, want)
+	}
+}
+
+func TestFlushDue_RetryThenGiveUp(t *testing.T) {
+	d := newTestDispatcher(t, fakePrefs{1: nightOwl()}, berlin(t, "2026-03-10 23:15"))
+	d.senders = map[string]Sender{ChannelEmail: d.email}
+	if err := d.Notify(context.Background(), Notification{UserID: 1, Type: digestType}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	d.email.err = errors.New("mail provider down")
+	d.clock = berlin(t, "2026-03-11 07:00")
+	for attempt := 1; attempt <= maxFlushAttempts; attempt++ {
+		if sent, err := d.FlushDue(context.Background(), 10); err != nil || sent != 0 {
+			t.Fatalf("attempt %d: FlushDue = %d, %v; want 0", attempt, sent, err)
+		}
+		wantQueued := 1
+		if attempt == maxFlushAttempts {
+			wantQueued = 0
+		}
+		if len(d.queue.rows) != wantQueued {
+			t.Fatalf("attempt %d: queue holds %d, want %d", attempt, len(d.queue.rows), wantQueued)
+		}
+		d.clock = d.clock.Add(flushLease)
+	}
+}
+
+func TestFlushDue_SkipsChannelTurnedOff(t *testing.T) {
+	prefs := fakePrefs{1: nightOwl()}
+	d := newTestDispatcher(t, prefs, berlin(t, "2026-03-10 23:15"))
+	if err := d.Notify(context.Background(), Notification{UserID: 1, Type: digestType}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	off := nightOwl()
+	off.Notifications = map[string]dbuser.NotificationChannels{digestType: {Webhook: true}}
+	prefs[1] = off
+	d.clock = berlin(t, "2026-03-11 07:00")
+	if sent, err := d.FlushDue(context.Background(), 10); err != nil || sent != 1 {
+		t.Fatalf("FlushDue = %d, %v; want 1", sent, err)
+	}
+	if len(d.email.sent) != 0 || len(d.webhook.sent) != 1 || len(d.queue.rows) != 0 {
+		t.Errorf("email %d, webhook %d, queued %d; want the webhook only and an empty queue",
+			len(d.email.sent), len(d.webhook.sent), len(d.queue.rows))
+	}
+}
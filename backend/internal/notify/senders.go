@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ConfabulousDev/confab-web/internal/email"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+// webhookQueuer queues webhook deliveries. *dbwebhook.Store satisfies it.
+type webhookQueuer interface {
+	QueueEvent(ctx context.Context, userID int64, event string, payload json.RawMessage) (int, error)
+}
+
+// WebhookSender sends notifications as webhook events: one delivery per
+// endpoint of the user subscribed to the notification's type, delivered by
+// the worker's webhooks.Dispatcher.
+type WebhookSender struct {
+	store webhookQueuer
+}
+
+// NewWebhookSender creates a webhook sender over store.
+func NewWebhookSender(store webhookQueuer) *WebhookSender {
+	return &WebhookSender{store: store}
+}
+
+// Send implements Sender. The payload has the same shape as session.created:
+// event, created_at and data.
+func (s *WebhookSender) Send(ctx context.Context, n Notification) error {
+	data := n.Data
+	if data == nil {
+		data = map[string]any{}
+	}
+	payload, err := json.Marshal(map[string]any{
+		"event":      n.Type,
+		"created_at": n.CreatedAt.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"data":       data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	_, err = s.store.QueueEvent(ctx, n.UserID, n.Type, payload)
+	return err
+}
+
+// userLookup finds the user a notification email goes to. *dbuser.Store
+// satisfies it.
+type userLookup interface {
+	GetUserByID(ctx context.Context, userID int64) (*models.User, error)
+}
+
+// MailFunc sends a notification email on behalf of userID:
+// (*email.RateLimitedService).SendNotification in the server, a wrapper
+// around (*email.ResendService).SendNotification in the worker.
+type MailFunc func(ctx context.Context, userID int64, params email.NotificationParams) error
+
+// EmailSender sends notifications by email to the user's address.
+type EmailSender struct {
+	users userLookup
+	mail  MailFunc
+}
+
+// NewEmailSender creates an email sender.
+func NewEmailSender(users userLookup, mail MailFunc) *EmailSender {
+	return &EmailSender{users: users, mail: mail}
+}
+
+// Send implements Sender.
+func (s *EmailSender) Send(ctx context.Context, n Notification) error {
+	user, err := s.users.GetUserByID(ctx, n.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to look up notification recipient: %w", err)
+	}
+	return s.mail(ctx, n.UserID, email.NotificationParams{
+		ToEmail: user.Email,
+		Subject: n.Subject,
+		Body:    n.Body,
+		URL:     n.URL,
+	})
+}
@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/email"
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+type fakeWebhookQueue struct {
+	userID  int64
+	event   string
+	payload json.RawMessage
+}
+
+func (f *fakeWebhookQueue) QueueEvent(_ context.Context, userID int64, event string, payload json.RawMessage) (int, error) {
+	f.userID, f.event, f.payload = userID, event, payload
+	return 1, nil
+}
+
+func TestWebhookSender_Payload(t *testing.T) {
+	queue := &fakeWebhookQueue{}
+	err := NewWebhookSender(queue).Send(context.Background(), Notification{
+		UserID:    7,
+		Type:      "api_key.velocity_exceeded",
+		CreatedAt: time.Date(2026, 3, 1, 10, 5, 0, 123e6, time.UTC),
+		Data:      map[string]any{"api_key_id": 3},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	want := `{"created_at":"2026-03-01T10:05:00.123Z","data":{"api_key_id":3},"event":"api_key.velocity_exceeded"}`
+	if queue.userID != 7 || queue.event != "api_key.velocity_exceeded" || string(queue.payload) != want {
+		t.Errorf("queued %d %q %s, want user 7 and %s", queue.userID, queue.event, queue.payload, want)
+	}
+}
+
+type fakeUsers map[int64]*models.User
+
+func (f fakeUsers) GetUserByID(_ context.Context, userID int64) (*models.User, error) {
+	return f[userID], nil
+}
+
+func TestEmailSender_Recipient(t *testing.T) {
+	var gotUser int64
+	var got email.NotificationParams
+	sender := NewEmailSender(fakeUsers{7: {ID: 7, Email: "ada@example.com"}},
+		func(_ context.Context, userID int64, params email.NotificationParams) error {
+			gotUser, got = userID, params
+			return nil
+		})
+	if err := sender.Send(context.Background(), Notification{UserID: 7, Subject: "S", Body: "B", URL: "https://x"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	want := email.NotificationParams{ToEmail: "ada@example.com", Subject: "S", Body: "B", URL: "https://x"}
+	if gotUser != 7 || got != want {
+		t.Errorf("mailed %d %+v, want 7 %+v", gotUser, got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db/dbwebhook"
+	dbuser "github.com/ConfabulousDev/confab-web/internal/db/user"
+)
+
+// Channels a notification can go out on.
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// channelOrder is the order Notify tries the channels in.
+var channelOrder = []string{ChannelEmail, ChannelWebhook}
+
+// Type is a kind of notification. Its name is also the webhook event name,
+// so endpoints subscribe to it like any other event.
+type Type struct {
+	Name string
+	// Urgent types go out at once even during the user's quiet hours.
+	Urgent bool
+	// Defaults are the channels used until the user chooses.
+	Defaults dbuser.NotificationChannels
+}
+
+// Types lists every notification type. A var so tests can register their own.
+var Types = []Type{
+	// A security signal: someone may be using a leaked key.
+	{Name: dbwebhook.EventAPIKeyVelocityExceeded, Urgent: true, Defaults: dbuser.NotificationChannels{Email: true, Webhook: true}},
+}
+
+// LookupType returns the registered type with the given name.
+func LookupType(name string) (Type, bool) {
+	for _, t := range Types {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Type{}, false
+}
+
+// ResolveChannels returns the channels of every registered type for the
+// given preferences: the user's choice where they made one, else the type's
+// defaults. Choices for types no longer registered are dropped.
+func ResolveChannels(prefs dbuser.Preferences) map[string]dbuser.NotificationChannels {
+	out := make(map[string]dbuser.NotificationChannels, len(Types))
+	for _, t := range Types {
+		out[t.Name] = channelsFor(prefs, t)
+	}
+	return out
+}
+
+func channelsFor(prefs dbuser.Preferences, t Type) dbuser.NotificationChannels {
+	if c, ok := prefs.Notifications[t.Name]; ok {
+		return c
+	}
+	return t.Defaults
+}
+
+func enabled(c dbuser.NotificationChannels, channel string) bool {
+	switch channel {
+	case ChannelEmail:
+		return c.Email
+	case ChannelWebhook:
+		return c.Webhook
+	}
+	return false
+}
+
+// QuietUntil reports whether now falls in the user's quiet hours and, if so,
+// when the window ends. The window is read in the user's timezone (UTC if it
+// does not load), so it follows daylight saving changes; one whose end is
+// before its start runs past midnight.
+func QuietUntil(prefs dbuser.Preferences, now time.Time) (time.Time, bool) {
+	if prefs.QuietHours == nil {
+		return time.Time{}, false
+	}
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	start, end := prefs.QuietHours.Minutes()
+	endsOn := func(days int) time.Time {
+		return time.Date(local.Year(), local.Month(), local.Day()+days, end/60, end%60, 0, 0, loc)
+	}
+
+	switch {
+	case start < end && minute >= start && minute < end:
+		return endsOn(0), true
+	case start > end && minute >= start:
+		return endsOn(1), true
+	case start > end && minute < end:
+		return endsOn(0), true
+	}
+	return time.Time{}, false
+}
@@ -47,6 +47,26 @@ func MiddlewareWithKey(limiter RateLimiter, keyFunc func(*http.Request) string)
 	}
 }
 
+// TieredMiddleware is MiddlewareWithKey with per-tier limits: tierFunc picks
+// the limit set for each request (e.g. the authenticated user's rate limit
+// tier). With a TieredRateLimiter, an empty or unknown tier gets the
+// fallback limits.
+func TieredMiddleware(limiter TieredLimiter, keyFunc, tierFunc func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			if key == "" {
+				key = clientip.FromRequest(r).RateLimitKey
+			}
+			if !limiter.Allow(r.Context(), tierFunc(r), key) {
+				denyRequest(w, r, key)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // HandlerFunc wraps a single handler function with rate limiting
 // Useful for applying different limits to specific endpoints
 func HandlerFunc(limiter RateLimiter, handler http.HandlerFunc) http.HandlerFunc {
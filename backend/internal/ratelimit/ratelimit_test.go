@@ -431,3 +431,125 @@ func TestUserKeyFunc(t *testing.T) {
 		})
 	}
 }
+
+func TestLimit_Scale(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  Limit
+		factor float64
+		want   Limit
+	}{
+		{"identity", Limit{RPS: 2.78, Burst: 2000}, 1, Limit{RPS: 2.78, Burst: 2000}},
+		{"multiply", Limit{RPS: 30, Burst: 60}, 5, Limit{RPS: 150, Burst: 300}},
+		{"burst rounds", Limit{RPS: 1, Burst: 3}, 1.5, Limit{RPS: 1.5, Burst: 5}},
+		{"burst floor of one", Limit{RPS: 1, Burst: 2}, 0.1, Limit{RPS: 0.1, Burst: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limit.Scale(tt.factor); got != tt.want {
+				t.Errorf("Scale(%v) = %+v, want %+v", tt.factor, got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestTieredLimiter has effectively no refill in the test window, so each
+// tier allows exactly its burst.
+func newTestTieredLimiter() *TieredRateLimiter {
+	standard := Limit{RPS: 0.0001, Burst: 2}
+	return NewTieredRateLimiter(map[string]Limit{
+		"standard": standard,
+		"trusted":  standard.Scale(5),
+	}, "standard", 1000)
+}
+
+func TestTieredRateLimiter_HigherTierExceedsStandardLimit(t *testing.T) {
+	rl := newTestTieredLimiter()
+	defer rl.Stop()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if !rl.Allow(ctx, "standard", "user:1") {
+			t.Fatalf("standard request %d should pass (burst=2)", i+1)
+		}
+	}
+	if rl.Allow(ctx, "standard", "user:1") {
+		t.Error("standard user should be throttled after its burst")
+	}
+
+	for i := 0; i < 10; i++ {
+		if !rl.Allow(ctx, "trusted", "user:2") {
+			t.Fatalf("trusted request %d should pass (burst=10)", i+1)
+		}
+	}
+	if rl.Allow(ctx, "trusted", "user:2") {
+		t.Error("trusted user should be throttled after its own burst")
+	}
+}
+
+func TestTieredRateLimiter_UnknownTierUsesFallback(t *testing.T) {
+	rl := newTestTieredLimiter()
+	defer rl.Stop()
+	ctx := context.Background()
+
+	for _, tier := range []string{"", "platinum"} {
+		key := "user:" + tier
+		for i := 0; i < 2; i++ {
+			if !rl.Allow(ctx, tier, key) {
+				t.Fatalf("tier %q request %d should pass", tier, i+1)
+			}
+		}
+		if rl.Allow(ctx, tier, key) {
+			t.Errorf("tier %q should get the standard burst of 2", tier)
+		}
+	}
+}
+
+func TestNewTieredRateLimiter_PanicsWithoutFallbackLimits(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic when the fallback tier has no limits")
+		}
+	}()
+	NewTieredRateLimiter(map[string]Limit{"trusted": {RPS: 1, Burst: 1}}, "standard", 0)
+}
+
+func TestTieredMiddleware_AppliesTierLimits(t *testing.T) {
+	rl := newTestTieredLimiter()
+	defer rl.Stop()
+
+	keyFunc := func(r *http.Request) string { return r.Header.Get("X-User") }
+	tierFunc := func(r *http.Request) string { return r.Header.Get("X-Tier") }
+	handler := clientip.NewMiddleware(nil)(TieredMiddleware(rl, keyFunc, tierFunc)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	send := func(user, tier string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "10.0.0.42:5555"
+		req.Header.Set("X-User", user)
+		req.Header.Set("X-Tier", tier)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := send("user:1", "standard"); code != http.StatusOK {
+			t.Fatalf("standard request %d: status %d, want 200", i+1, code)
+		}
+	}
+	if code := send("user:1", "standard"); code != http.StatusTooManyRequests {
+		t.Errorf("standard request 3: status %d, want 429", code)
+	}
+
+	// From the same IP, a trusted user keeps going well past the standard burst.
+	for i := 0; i < 10; i++ {
+		if code := send("user:2", "trusted"); code != http.StatusOK {
+			t.Fatalf("trusted request %d: status %d, want 200", i+1, code)
+		}
+	}
+	if code := send("user:2", "trusted"); code != http.StatusTooManyRequests {
+		t.Errorf("trusted request 11: status %d, want 429", code)
+	}
+}
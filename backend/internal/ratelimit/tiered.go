@@ -0,0 +1,71 @@
+package ratelimit
+
+import "context"
+
+// Limit is one limit set: a steady rate in requests per second and a burst.
+type Limit struct {
+	RPS   float64
+	Burst int
+}
+
+// Scale returns l with its rate and burst multiplied by factor. The burst
+// never drops below 1.
+func (l Limit) Scale(factor float64) Limit {
+	burst := int(float64(l.Burst)*factor + 0.5)
+	if burst < 1 {
+		burst = 1
+	}
+	return Limit{RPS: l.RPS * factor, Burst: burst}
+}
+
+// TieredLimiter is RateLimiter with a tier: each tier may have its own limits
+// for the same key. Like RateLimiter it leaves room for a distributed
+// implementation.
+type TieredLimiter interface {
+	// Allow checks if a request from key is allowed under tier's limits
+	Allow(ctx context.Context, tier, key string) bool
+}
+
+// TieredRateLimiter implements TieredLimiter and applies a different limit set per tier (e.g. a user's
+// rate limit tier). Each tier has its own InMemoryRateLimiter, so a key's
+// buckets are kept per tier and moving a key to another tier starts it on a
+// fresh bucket. Unknown tiers get the fallback tier's limits.
+type TieredRateLimiter struct {
+	fallback string
+	tiers    map[string]*InMemoryRateLimiter
+}
+
+// NewTieredRateLimiter creates a limiter with one InMemoryRateLimiter per
+// entry in limits, each capped at maxBuckets keys. fallback must be a key of
+// limits; it serves requests whose tier is empty or not in limits.
+func NewTieredRateLimiter(limits map[string]Limit, fallback string, maxBuckets int) *TieredRateLimiter {
+	if _, ok := limits[fallback]; !ok {
+		panic("ratelimit: fallback tier " + fallback + " has no limits")
+	}
+	tiers := make(map[string]*InMemoryRateLimiter, len(limits))
+	for tier, limit := range limits {
+		tiers[tier] = NewInMemoryRateLimiter(limit.RPS, limit.Burst, maxBuckets)
+	}
+	return &TieredRateLimiter{fallback: fallback, tiers: tiers}
+}
+
+// Tier returns the limiter for tier, or the fallback tier's limiter when tier
+// is unknown.
+func (t *TieredRateLimiter) Tier(tier string) RateLimiter {
+	if l, ok := t.tiers[tier]; ok {
+		return l
+	}
+	return t.tiers[t.fallback]
+}
+
+// Allow checks if one request from key is allowed under tier's limits.
+func (t *TieredRateLimiter) Allow(ctx context.Context, tier, key string) bool {
+	return t.Tier(tier).Allow(ctx, key)
+}
+
+// Stop stops every tier's cleanup goroutine.
+func (t *TieredRateLimiter) Stop() {
+	for _, l := range t.tiers {
+		l.Stop()
+	}
+}
@@ -80,6 +80,21 @@ func IncrementForMonth(ctx context.Context, conn *sql.DB, userID int64, month st
 	return nil
 }
 
+// IncrementForSession is Increment for a computation about sessionID. Demo
+// sessions (sessions.is_demo) don't count against the quota, so for those it
+// does nothing.
+func IncrementForSession(ctx context.Context, conn *sql.DB, userID int64, sessionID string) error {
+	var isDemo bool
+	err := conn.QueryRowContext(ctx, `SELECT is_demo FROM sessions WHERE id = $1`, sessionID).Scan(&isDemo)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check demo session: %w", err)
+	}
+	if isDemo {
+		return nil
+	}
+	return Increment(ctx, conn, userID)
+}
+
 // GetCount returns the compute count for the current month (0 if row missing or stale).
 func GetCount(ctx context.Context, conn *sql.DB, userID int64) (int, error) {
 	return GetCountForMonth(ctx, conn, userID, CurrentMonth())
@@ -189,6 +189,39 @@ func TestIncrement_Basic(t *testing.T) {
 	}
 }
 
+func TestIncrementForSession_SkipsDemoSessions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := testutil.SetupTestEnvironment(t)
+	env.CleanDB(t)
+
+	user := testutil.CreateTestUser(t, env, "incdemo@test.com", "IncDemo User")
+	regular := testutil.CreateTestSession(t, env, user.ID, "regular-session")
+	demo := testutil.CreateTestSession(t, env, user.ID, "confab-demo-session")
+	if _, err := env.DB.Exec(env.Ctx, `UPDATE sessions SET is_demo = TRUE WHERE id = $1`, demo); err != nil {
+		t.Fatalf("failed to mark demo session: %v", err)
+	}
+	ctx := context.Background()
+	conn := env.DB.Conn()
+
+	if err := recapquota.IncrementForSession(ctx, conn, user.ID, demo); err != nil {
+		t.Fatalf("IncrementForSession (demo) failed: %v", err)
+	}
+	if err := recapquota.IncrementForSession(ctx, conn, user.ID, regular); err != nil {
+		t.Fatalf("IncrementForSession (regular) failed: %v", err)
+	}
+
+	count, err := recapquota.GetCount(ctx, conn, user.ID)
+	if err != nil {
+		t.Fatalf("GetCount failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("count = %d, want 1 (the demo recap is not counted)", count)
+	}
+}
+
 func TestIncrement_StaleMonth(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
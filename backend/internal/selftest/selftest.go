@@ -0,0 +1,220 @@
+// Package selftest runs the preflight checks behind the --selftest flag of the
+// standalone scripts under backend/scripts: database reachability, required
+// tables and columns, bucket access, and a sample read of one row and one
+// object. Each check prints PASS or FAIL so a wrong DATABASE_URL, bucket name
+// or credential shows up before the script touches any data.
+package selftest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// FlagName is the flag every script registers through Flag.
+const FlagName = "selftest"
+
+// Tunables are vars (not consts) so tests can shrink them.
+var checkTimeout = 10 * time.Second
+
+// Flag registers --selftest on the default flag set.
+func Flag() *bool {
+	return flag.Bool(FlagName, false, "Check DB, schema and S3 access (with a sample read) and exit without changing anything")
+}
+
+// Check is one named preflight check. Run returns a short detail for the PASS
+// line (may be empty) or the reason it failed.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (string, error)
+}
+
+// Run executes every check, each bounded by its own timeout, and writes one
+// PASS/FAIL line per check to w. Checks run even after an earlier failure so
+// a single run reports everything that is misconfigured. It returns an error
+// when any check failed.
+func Run(ctx context.Context, w io.Writer, checks []Check) error {
+	failed := 0
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		detail, err := c.Run(checkCtx)
+		cancel()
+		switch {
+		case err != nil:
+			failed++
+			fmt.Fprintf(w, "FAIL  %s: %v\n", c.Name, err)
+		case detail != "":
+			fmt.Fprintf(w, "PASS  %s (%s)\n", c.Name, detail)
+		default:
+			fmt.Fprintf(w, "PASS  %s\n", c.Name)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("selftest: %d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// DatabaseReachable pings conn.
+func DatabaseReachable(conn *sql.DB) Check {
+	return Check{
+		Name: "database reachable",
+		Run: func(ctx context.Context) (string, error) {
+			if err := conn.PingContext(ctx); err != nil {
+				return "", fmt.Errorf("ping failed (check DATABASE_URL): %w", err)
+			}
+			return "", nil
+		},
+	}
+}
+
+// SchemaHasColumns verifies each table exists in the current schema with at
+// least the listed columns.
+func SchemaHasColumns(conn *sql.DB, required map[string][]string) Check {
+	return Check{
+		Name: "schema has required tables and columns",
+		Run: func(ctx context.Context) (string, error) {
+			tables := make([]string, 0, len(required))
+			for table := range required {
+				tables = append(tables, table)
+			}
+			sort.Strings(tables)
+
+			var missing []string
+			for _, table := range tables {
+				have, err := tableColumns(ctx, conn, table)
+				if err != nil {
+					return "", err
+				}
+				if len(have) == 0 {
+					missing = append(missing, table)
+					continue
+				}
+				for _, col := range required[table] {
+					if !have[col] {
+						missing = append(missing, table+"."+col)
+					}
+				}
+			}
+			if len(missing) > 0 {
+				return "", fmt.Errorf("missing %s (are migrations up to date?)", strings.Join(missing, ", "))
+			}
+			return fmt.Sprintf("%d tables", len(tables)), nil
+		},
+	}
+}
+
+func tableColumns(ctx context.Context, conn *sql.DB, table string) (map[string]bool, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	cols := make(map[string]bool)
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, fmt.Errorf("failed to scan column of %s: %w", table, err)
+		}
+		cols[col] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	return cols, nil
+}
+
+// SampleRow runs query (the script's own selection, typically with LIMIT 1)
+// and reads at most one row. No rows is a pass: there is simply nothing to do.
+func SampleRow(conn *sql.DB, query string, args ...any) Check {
+	return Check{
+		Name: "sample row read",
+		Run: func(ctx context.Context) (string, error) {
+			rows, err := conn.QueryContext(ctx, query, args...)
+			if err != nil {
+				return "", fmt.Errorf("sample query failed: %w", err)
+			}
+			defer rows.Close()
+			if !rows.Next() {
+				if err := rows.Err(); err != nil {
+					return "", fmt.Errorf("sample query failed: %w", err)
+				}
+				return "no rows", nil
+			}
+			cols, err := rows.Columns()
+			if err != nil {
+				return "", fmt.Errorf("sample query failed: %w", err)
+			}
+			dest := make([]any, len(cols))
+			for i := range dest {
+				dest[i] = new(any)
+			}
+			if err := rows.Scan(dest...); err != nil {
+				return "", fmt.Errorf("failed to scan sample row: %w", err)
+			}
+			return "1 row", nil
+		},
+	}
+}
+
+// BucketExists verifies the credentials can see bucket.
+func BucketExists(client *minio.Client, bucket string) Check {
+	return Check{
+		Name: "S3 bucket exists",
+		Run: func(ctx context.Context) (string, error) {
+			exists, err := client.BucketExists(ctx, bucket)
+			if err != nil {
+				return "", fmt.Errorf("cannot reach bucket %q (check S3_ENDPOINT and credentials): %w", bucket, err)
+			}
+			if !exists {
+				return "", fmt.Errorf("bucket %q does not exist (check BUCKET_NAME)", bucket)
+			}
+			return bucket, nil
+		},
+	}
+}
+
+// SampleObject lists the first object under prefix and reads its first byte.
+// An empty prefix is a pass.
+func SampleObject(client *minio.Client, bucket, prefix string) Check {
+	return Check{
+		Name: "sample object read",
+		Run: func(ctx context.Context) (string, error) {
+			listCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			var key string
+			for obj := range client.ListObjects(listCtx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true, MaxKeys: 1}) {
+				if obj.Err != nil {
+					return "", fmt.Errorf("failed to list objects in %q: %w", bucket, obj.Err)
+				}
+				key = obj.Key
+				break
+			}
+			if key == "" {
+				return "no objects", nil
+			}
+
+			obj, err := client.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+			if err != nil {
+				return "", fmt.Errorf("failed to get %s: %w", key, err)
+			}
+			defer obj.Close()
+			if _, err := io.CopyN(io.Discard, obj, 1); err != nil && !errors.Is(err, io.EOF) {
+				return "", fmt.Errorf("failed to read %s: %w", key, err)
+			}
+			return key, nil
+		},
+	}
+}
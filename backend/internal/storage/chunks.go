@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
+	"sort"
 	"strings"
 	"time"
 
@@ -65,6 +67,38 @@ func ParseChunkKey(key string) (int, int, bool) {
 	return first, last, true
 }
 
+// CheckContiguous verifies that chunk keys (as returned by ListChunks) cover
+// lines 1..lastLine with no gap and no chunk past lastLine. Overlapping chunks
+// are fine: MergeChunks resolves them. Returns an ErrChunksNotContiguous error
+// naming the first missing or extra line.
+func CheckContiguous(keys []string, lastLine int) error {
+	type span struct{ first, last int }
+	spans := make([]span, 0, len(keys))
+	for _, key := range keys {
+		first, last, ok := ParseChunkKey(key)
+		if !ok {
+			return fmt.Errorf("%w: unparseable chunk key %s", ErrChunksNotContiguous, key)
+		}
+		spans = append(spans, span{first, last})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].first < spans[j].first })
+
+	covered := 0
+	for _, sp := range spans {
+		if sp.first > covered+1 {
+			return fmt.Errorf("%w: lines %d-%d are missing", ErrChunksNotContiguous, covered+1, sp.first-1)
+		}
+		covered = max(covered, sp.last)
+	}
+	if covered < lastLine {
+		return fmt.Errorf("%w: lines %d-%d are missing", ErrChunksNotContiguous, covered+1, lastLine)
+	}
+	if covered > lastLine {
+		return fmt.Errorf("%w: chunks run to line %d past the synced line %d", ErrChunksNotContiguous, covered, lastLine)
+	}
+	return nil
+}
+
 // DownloadAndMergeChunks downloads all chunks for a file and merges them into a single byte slice.
 // This is a convenience method that combines ListChunks, DownloadChunks, and MergeChunks.
 // Returns nil if no chunks exist (not an error).
@@ -110,6 +144,91 @@ func (s *S3Storage) DownloadAndMergeChunks(ctx context.Context, userID int64, pr
 	return merged, nil
 }
 
+// DownloadRange returns lines firstLine..lastLine (1-based, inclusive) of a
+// file, downloading only the chunks that overlap them. chunkKeys is the file's
+// listing from ListChunks, so a caller reading both ends of a transcript lists
+// once and never fetches the chunks in between. Lines are merged as in
+// MergeChunks, each followed by a newline. Returns nil if no chunk overlaps.
+func (s *S3Storage) DownloadRange(ctx context.Context, chunkKeys []string, firstLine, lastLine int) ([]byte, error) {
+	ctx, span := tracer.Start(ctx, "storage.download_range",
+		trace.WithAttributes(
+			attribute.Int("keys.count", len(chunkKeys)),
+			attribute.Int("range.first_line", firstLine),
+			attribute.Int("range.last_line", lastLine),
+		))
+	defer span.End()
+
+	keys := chunksInRange(chunkKeys, firstLine, lastLine)
+	span.SetAttributes(attribute.Int("chunks.count", len(keys)))
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	chunks, err := s.DownloadChunks(ctx, keys)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+
+	merged, err := mergeRange(chunks, firstLine, lastLine)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	return merged, nil
+}
+
+// chunksInRange returns the keys whose line span overlaps
+// firstLine..lastLine, in listing order. Unparseable keys are dropped.
+func chunksInRange(keys []string, firstLine, lastLine int) []string {
+	var inRange []string
+	for _, key := range keys {
+		first, last, ok := ParseChunkKey(key)
+		if !ok || last < firstLine || first > lastLine {
+			continue
+		}
+		inRange = append(inRange, key)
+	}
+	return inRange
+}
+
+// mergeRange is MergeChunks restricted to firstLine..lastLine: the array is
+// sized to the range (clamped to the last line any chunk holds) rather than
+// to the whole file.
+func mergeRange(chunks []ChunkInfo, firstLine, lastLine int) ([]byte, error) {
+	firstLine = max(firstLine, 1)
+	maxLine := 0
+	for _, c := range chunks {
+		maxLine = max(maxLine, c.LastLine)
+	}
+	lastLine = min(lastLine, maxLine)
+	if lastLine < firstLine {
+		return nil, nil
+	}
+	if n := lastLine - firstLine + 1; n > MaxMergeLines {
+		return nil, fmt.Errorf("range of %d lines exceeds safety limit %d", n, MaxMergeLines)
+	}
+
+	lines := make([][]byte, lastLine-firstLine+1)
+	for _, c := range chunks {
+		for i, line := range splitLines(c.Data) {
+			lineNum := c.FirstLine + i
+			if lineNum >= firstLine && lineNum <= lastLine {
+				lines[lineNum-firstLine] = line
+			}
+		}
+	}
+
+	var result []byte
+	for _, line := range lines {
+		if line != nil {
+			result = append(result, line...)
+			result = append(result, '\n')
+		}
+	}
+	return result, nil
+}
+
 // DownloadChunks downloads all chunks for the given keys in parallel and returns them as ChunkInfo slices.
 // Keys with unparseable names are skipped with a warning.
 // Downloads are limited to maxParallelDownloads concurrent operations.
@@ -224,6 +343,57 @@ func MergeChunks(chunks []ChunkInfo) ([]byte, error) {
 		return chunks[0].Data, nil
 	}
 
+	lines, err := mergeLines(chunks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build result from array
+	var result []byte
+	for _, line := range lines {
+		if line != nil {
+			result = append(result, line...)
+			result = append(result, '\n')
+		}
+	}
+
+	return result, nil
+}
+
+// WriteMergedChunks writes the same bytes MergeChunks would return to w,
+// without concatenating the lines into one buffer first.
+func WriteMergedChunks(w io.Writer, chunks []ChunkInfo) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	if len(chunks) == 1 {
+		_, err := w.Write(chunks[0].Data)
+		return err
+	}
+
+	lines, err := mergeLines(chunks)
+	if err != nil {
+		return err
+	}
+
+	newline := []byte{'\n'}
+	for _, line := range lines {
+		if line == nil {
+			continue
+		}
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+		if _, err := w.Write(newline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeLines builds the line-indexed array shared by MergeChunks and
+// WriteMergedChunks. Missing lines are left nil.
+func mergeLines(chunks []ChunkInfo) ([][]byte, error) {
 	// Find max line number
 	maxLine := 0
 	for _, c := range chunks {
@@ -268,16 +438,7 @@ func MergeChunks(chunks []ChunkInfo) ([]byte, error) {
 		}
 	}
 
-	// Build result from array
-	var result []byte
-	for _, line := range lines {
-		if line != nil {
-			result = append(result, line...)
-			result = append(result, '\n')
-		}
-	}
-
-	return result, nil
+	return lines, nil
 }
 
 // splitLines splits data into lines, preserving each line's content without the newline.
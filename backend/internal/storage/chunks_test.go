@@ -1,7 +1,15 @@
 package storage
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -202,6 +210,55 @@ func TestMergeChunks(t *testing.T) {
 	})
 }
 
+func TestWriteMergedChunks(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []ChunkInfo
+	}{
+		{"empty", nil},
+		{"single chunk without trailing newline", []ChunkInfo{
+			{Key: "chunk_00000001_00000002.jsonl", FirstLine: 1, LastLine: 2, Data: []byte("line1\nline2")},
+		}},
+		{"overlapping chunks", []ChunkInfo{
+			{Key: "chunk_00000001_00000002.jsonl", FirstLine: 1, LastLine: 2, Data: []byte("old1\nold2\n")},
+			{Key: "chunk_00000002_00000004.jsonl", FirstLine: 2, LastLine: 4, Data: []byte("new2\nnew3\nnew4\n")},
+		}},
+		{"gap between chunks", []ChunkInfo{
+			{Key: "chunk_00000001_00000001.jsonl", FirstLine: 1, LastLine: 1, Data: []byte("line1\n")},
+			{Key: "chunk_00000004_00000004.jsonl", FirstLine: 4, LastLine: 4, Data: []byte("line4\n")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := MergeChunks(tt.chunks)
+			if err != nil {
+				t.Fatalf("MergeChunks() error = %v", err)
+			}
+			var got bytes.Buffer
+			if err := WriteMergedChunks(&got, tt.chunks); err != nil {
+				t.Fatalf("WriteMergedChunks() error = %v", err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("WriteMergedChunks() wrote %q, MergeChunks() returned %q", got.Bytes(), want)
+			}
+		})
+	}
+
+	t.Run("exceeds MaxMergeLines returns error", func(t *testing.T) {
+		chunks := []ChunkInfo{
+			{Key: "chunk_00000001_00000001.jsonl", FirstLine: 1, LastLine: 1, Data: []byte("a\n")},
+			{Key: "chunk_99999990_99999999.jsonl", FirstLine: MaxMergeLines + 1, LastLine: MaxMergeLines + 10, Data: []byte("b\n")},
+		}
+		var got bytes.Buffer
+		if err := WriteMergedChunks(&got, chunks); err == nil {
+			t.Error("expected error for exceeding MaxMergeLines, got nil")
+		}
+		if got.Len() != 0 {
+			t.Errorf("expected nothing written on error, got %q", got.Bytes())
+		}
+	})
+}
+
 func TestSplitLines(t *testing.T) {
 	t.Run("normal lines with trailing newline", func(t *testing.T) {
 		data := []byte("a\nb\nc\n")
@@ -279,3 +336,139 @@ func TestParseChunkKey(t *testing.T) {
 	}
 }
 
+
+func TestCheckContiguous(t *testing.T) {
+	key := func(first, last int) string {
+		return fmt.Sprintf("1/claude-code/abc/chunks/t.jsonl/chunk_%08d_%08d.jsonl", first, last)
+	}
+	tests := []struct {
+		name     string
+		keys     []string
+		lastLine int
+		wantErr  string
+	}{
+		{"empty file", nil, 0, ""},
+		{"single chunk", []string{key(1, 10)}, 10, ""},
+		{"unsorted", []string{key(6, 10), key(1, 5)}, 10, ""},
+		{"overlap", []string{key(1, 6), key(4, 10)}, 10, ""},
+		{"gap", []string{key(1, 5), key(8, 10)}, 10, "lines 6-7 are missing"},
+		{"missing tail", []string{key(1, 5)}, 10, "lines 6-10 are missing"},
+		{"missing head", []string{key(3, 10)}, 10, "lines 1-2 are missing"},
+		{"past synced line", []string{key(1, 12)}, 10, "chunks run to line 12 past the synced line 10"},
+		{"unparseable", []string{"1/claude-code/abc/chunks/t.jsonl/notes.txt"}, 0, "unparseable chunk key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckContiguous(tt.keys, tt.lastLine)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckContiguous() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrChunksNotContiguous) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckContiguous() = %v, want ErrChunksNotContiguous containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChunksInRange(t *testing.T) {
+	keys := []string{
+		"prefix/chunk_00000001_00000100.jsonl",
+		"prefix/chunk_00000101_00000200.jsonl",
+		"prefix/chunk_00000201_00000300.jsonl",
+		"prefix/not-a-chunk.txt",
+	}
+
+	tests := []struct {
+		name        string
+		first, last int
+		want        []string
+	}{
+		{"head", 1, 50, keys[:1]},
+		{"tail", 296, 300, keys[2:3]},
+		{"spans a boundary", 100, 101, keys[:2]},
+		{"everything", 1, 300, keys[:3]},
+		{"past the end", 301, 400, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chunksInRange(keys, tt.first, tt.last)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("chunksInRange(%d, %d) = %v, want %v", tt.first, tt.last, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeRange(t *testing.T) {
+	chunks := []ChunkInfo{
+		{Key: "chunk_00000001_00000003.jsonl", FirstLine: 1, LastLine: 3, Data: []byte("line1\nline2\nline3\n")},
+		{Key: "chunk_00000003_00000005.jsonl", FirstLine: 3, LastLine: 5, Data: []byte("line3\nline4\nline5\n")},
+	}
+
+	tests := []struct {
+		name        string
+		first, last int
+		want        string
+	}{
+		{"inside one chunk", 4, 5, "line4\nline5\n"},
+		{"across the overlap", 2, 4, "line2\nline3\nline4\n"},
+		{"clamped to the chunks", 0, 1000, "line1\nline2\nline3\nline4\nline5\n"},
+		{"past the end", 6, 10, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeRange(chunks, tt.first, tt.last)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("mergeRange(%d, %d) = %q, want %q", tt.first, tt.last, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDownloadRange_SkipsMiddleChunks checks that reading both ends of a
+// file fetches only the first and last chunks.
+func TestDownloadRange_SkipsMiddleChunks(t *testing.T) {
+	bodies := map[string]string{
+		"f/chunk_00000001_00000002.jsonl": "line1\nline2\n",
+		"f/chunk_00000003_00000004.jsonl": "line3\nline4\n",
+		"f/chunk_00000005_00000006.jsonl": "line5\nline6\n",
+	}
+	keys := []string{"f/chunk_00000001_00000002.jsonl", "f/chunk_00000003_00000004.jsonl", "f/chunk_00000005_00000006.jsonl"}
+
+	var mu sync.Mutex
+	var fetched []string
+	var calls atomic.Int32
+	s := &S3Storage{
+		client: newFakeGetClient(t, func(w http.ResponseWriter, r *http.Request) {
+			key := strings.TrimPrefix(r.URL.Path, "/"+fakeS3Bucket+"/")
+			mu.Lock()
+			fetched = append(fetched, key)
+			mu.Unlock()
+			serveObject(bodies[key])(w, r)
+		}, &calls),
+		bucket: fakeS3Bucket,
+	}
+
+	head, err := s.DownloadRange(context.Background(), keys, 1, 1)
+	if err != nil {
+		t.Fatalf("DownloadRange(head): %v", err)
+	}
+	tail, err := s.DownloadRange(context.Background(), keys, 6, 6)
+	if err != nil {
+		t.Fatalf("DownloadRange(tail): %v", err)
+	}
+
+	if string(head) != "line1\n" || string(tail) != "line6\n" {
+		t.Errorf("head = %q, tail = %q", head, tail)
+	}
+	sort.Strings(fetched)
+	if want := []string{keys[0], keys[2]}; fmt.Sprint(fetched) != fmt.Sprint(want) {
+		t.Errorf("fetched %v, want only %v", fetched, want)
+	}
+}
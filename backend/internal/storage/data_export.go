@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MaxPresignExpiry is the longest validity S3 allows for a presigned URL.
+const MaxPresignExpiry = 7 * 24 * time.Hour
+
+// dataExportKey is where a full-account export archive lives. It sits under
+// the user's prefix, beside the provider subtrees, so DeleteAllUserData
+// removes it with the rest of the account.
+// Key format: {user_id}/exports/{export_id}.zip
+func dataExportKey(userID, exportID int64) string {
+	return fmt.Sprintf("%d/exports/%d.zip", userID, exportID)
+}
+
+// UploadDataExport stores a data export archive of size bytes read from r
+// and returns its key.
+func (s *S3Storage) UploadDataExport(ctx context.Context, userID, exportID int64, r io.Reader, size int64) (string, error) {
+	key := dataExportKey(userID, exportID)
+	ctx, span := tracer.Start(ctx, "storage.upload_data_export",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.Int64("export.id", exportID),
+			attribute.Int64("file.size", size),
+		))
+	defer span.End()
+
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType: "application/zip",
+	})
+	if err != nil {
+		recordSpanError(span, err)
+		return "", classifyStorageError(err, "upload data export")
+	}
+	return key, nil
+}
+
+// PresignDataExport returns a URL that downloads the archive at key as
+// filename without credentials, valid for expiry (at most MaxPresignExpiry).
+// The URL points at the configured S3 endpoint, so it only works for users
+// who can reach that endpoint.
+func (s *S3Storage) PresignDataExport(ctx context.Context, key, filename string, expiry time.Duration) (string, error) {
+	ctx, span := tracer.Start(ctx, "storage.presign_data_export",
+		trace.WithAttributes(attribute.String("object.key", key)))
+	defer span.End()
+
+	params := url.Values{}
+	params.Set("response-content-disposition", `attachment; filename="`+filename+`"`)
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, key, min(expiry, MaxPresignExpiry), params)
+	if err != nil {
+		recordSpanError(span, err)
+		return "", classifyStorageError(err, "presign data export")
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var meter = otel.Meter("confab/storage")
+
+// failoverReads counts reads served by the read failover, by operation.
+var failoverReads = newFailoverReadsCounter()
+
+func newFailoverReadsCounter() metric.Int64Counter {
+	counter, err := meter.Int64Counter("storage.failover.reads",
+		metric.WithDescription("Object reads and listings served by the read failover"),
+		metric.WithUnit("{read}"))
+	if err != nil {
+		slog.Warn("failed to register storage failover counter", "error", err)
+	}
+	return counter
+}
+
+// Circuit breaker tuning. After breakerThreshold consecutive unavailable
+// responses an endpoint is skipped for breakerCooldown; the first read after
+// the cooldown probes it again.
+const (
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
+// circuitBreaker tracks consecutive failures of one S3 endpoint. The zero
+// value is closed.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	now       func() time.Time // nil = time.Now; tests override
+}
+
+func (b *circuitBreaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+// allow reports whether the endpoint should be tried: the breaker is closed,
+// or its cooldown has passed.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.clock().Before(b.openUntil)
+}
+
+// open reports whether the breaker is currently skipping the endpoint.
+func (b *circuitBreaker) open() bool {
+	return !b.allow()
+}
+
+// record counts the outcome of a read. Only unavailability (see
+// isUnavailable) counts against the endpoint; a missing object or a denied
+// request means it answered.
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !isUnavailable(err) {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+	b.failures++
+	if b.failures >= breakerThreshold {
+		b.openUntil = b.clock().Add(breakerCooldown)
+	}
+}
+
+// isUnavailable reports whether a classified storage error means the endpoint
+// could not serve the request: a connection-level failure or a 5xx response.
+func isUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrNetworkError) {
+		return true
+	}
+	var minioErr minio.ErrorResponse
+	return errors.As(err, &minioErr) && minioErr.StatusCode >= http.StatusInternalServerError
+}
+
+type primaryOnlyKey struct{}
+
+// PrimaryOnly returns a context whose reads never use the read failover. Use
+// it when the result feeds a write (copying or moving chunks), where a
+// lagging replica's listing would silently drop the newest chunks.
+func PrimaryOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryOnlyKey{}, true)
+}
+
+type replicaReadKey struct{}
+
+// WithReplicaReadMarker returns a context that notes when a read made with it
+// was served by the read failover, and a func reporting whether any was.
+func WithReplicaReadMarker(ctx context.Context) (context.Context, func() bool) {
+	var served atomic.Bool
+	return context.WithValue(ctx, replicaReadKey{}, &served), served.Load
+}
+
+func markReplicaRead(ctx context.Context) {
+	if served, ok := ctx.Value(replicaReadKey{}).(*atomic.Bool); ok {
+		served.Store(true)
+	}
+}
+
+// readWithFailover runs read against the primary and, when the primary is
+// unavailable, once against the read failover. While the primary's breaker is
+// open the primary is skipped; while the failover's is open the primary's
+// error is returned as is. op names the read for logs and the failover metric.
+func readWithFailover[T any](ctx context.Context, s *S3Storage, op string, read func(client *minio.Client, bucket string) (T, error)) (T, error) {
+	canFailover := s.failover != nil && ctx.Value(primaryOnlyKey{}) == nil
+	span := trace.SpanFromContext(ctx)
+
+	var v T
+	var err error
+	if !canFailover || s.primaryBreaker.allow() || s.failoverBreaker.open() {
+		v, err = read(s.client, s.bucket)
+		s.primaryBreaker.record(err)
+		if err == nil || !canFailover || !isUnavailable(err) || ctx.Err() != nil || s.failoverBreaker.open() {
+			return v, err
+		}
+		slog.Warn("S3 primary read failed, retrying against read failover", "operation", op, "error", err)
+	} else {
+		span.AddEvent("storage.primary_circuit_open")
+	}
+
+	span.SetAttributes(attribute.Bool("storage.failover", true))
+	v, err = read(s.failover, s.failoverBucket)
+	s.failoverBreaker.record(err)
+	if err == nil {
+		markReplicaRead(ctx)
+		if failoverReads != nil {
+			failoverReads.Add(ctx, 1, metric.WithAttributes(attribute.String("operation", op)))
+		}
+	}
+	return v, err
+}
+
+// BackendHealth is the state of one S3 endpoint.
+type BackendHealth struct {
+	Status      string `json:"status"` // "ok" or the bucket check error
+	CircuitOpen bool   `json:"circuit_open"`
+}
+
+// Health reports each storage backend. Failover is nil when no read failover
+// is configured.
+type Health struct {
+	Primary  BackendHealth  `json:"primary"`
+	Failover *BackendHealth `json:"failover,omitempty"`
+}
+
+// Health checks that each endpoint answers for its bucket. It does not feed
+// the circuit breakers; their state is reported as seen by reads.
+func (s *S3Storage) Health(ctx context.Context) Health {
+	h := Health{Primary: backendHealth(ctx, s.client, s.bucket, &s.primaryBreaker)}
+	if s.failover != nil {
+		fh := backendHealth(ctx, s.failover, s.failoverBucket, &s.failoverBreaker)
+		h.Failover = &fh
+	}
+	return h
+}
+
+func backendHealth(ctx context.Context, client *minio.Client, bucket string, breaker *circuitBreaker) BackendHealth {
+	h := BackendHealth{Status: "ok", CircuitOpen: breaker.open()}
+	exists, err := client.BucketExists(ctx, bucket)
+	switch {
+	case err != nil:
+		h.Status = classifyStorageError(err, "bucket check").Error()
+	case !exists:
+		h.Status = "bucket " + bucket + " does not exist"
+	}
+	return h
+}
@@ -6,7 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -40,6 +43,10 @@ var (
 
 	// ErrTooManyChunks indicates a file has exceeded the maximum allowed chunks
 	ErrTooManyChunks = errors.New("file has too many chunks")
+
+	// ErrChunksNotContiguous indicates a file's chunk keys leave a gap in, or
+	// run past, its synced line range
+	ErrChunksNotContiguous = errors.New("chunks are not contiguous")
 )
 
 // MaxChunksPerFile is the maximum number of chunks allowed per file.
@@ -59,12 +66,31 @@ type S3Config struct {
 	SecretAccessKey string
 	BucketName      string
 	UseSSL          bool
+
+	// ReadFailoverEndpoint, when set, is a second S3 endpoint (typically
+	// another region) holding a replica of the bucket. Download and
+	// ListChunks fall back to it when the primary is unavailable; writes and
+	// deletes always go to the primary. Replication itself is configured
+	// outside Confab.
+	ReadFailoverEndpoint string
+	// ReadFailoverBucket names the replica bucket (default: BucketName).
+	ReadFailoverBucket string
 }
 
 // S3Storage handles object storage operations
 type S3Storage struct {
 	client *minio.Client
 	bucket string
+
+	// failover/failoverBucket serve reads when the primary fails (nil = no
+	// failover configured).
+	failover       *minio.Client
+	failoverBucket string
+
+	// primaryBreaker/failoverBreaker skip an endpoint that keeps failing
+	// (see readWithFailover).
+	primaryBreaker  circuitBreaker
+	failoverBreaker circuitBreaker
 }
 
 // NewS3Storage creates a new S3/MinIO storage client
@@ -87,35 +113,84 @@ func NewS3Storage(config S3Config) (*S3Storage, error) {
 		return nil, fmt.Errorf("bucket %q does not exist: create it before starting the server", config.BucketName)
 	}
 
-	return &S3Storage{
+	s := &S3Storage{
 		client: client,
 		bucket: config.BucketName,
-	}, nil
+	}
+
+	// The replica's bucket is not checked: a degraded secondary region must
+	// not keep the server from starting.
+	if config.ReadFailoverEndpoint != "" {
+		failover, err := minio.New(config.ReadFailoverEndpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, ""),
+			Secure: config.UseSSL,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create S3 read-failover client: %w", err)
+		}
+		s.failover = failover
+		s.failoverBucket = config.ReadFailoverBucket
+		if s.failoverBucket == "" {
+			s.failoverBucket = config.BucketName
+		}
+	}
+
+	return s, nil
 }
 
-// Download retrieves a file from S3/MinIO
+// Download retrieves a file from S3/MinIO. If the primary is unavailable and
+// a read failover is configured, the replica is tried once; a missing object
+// or a denied request does not fail over, since the primary answered.
 func (s *S3Storage) Download(ctx context.Context, key string) ([]byte, error) {
 	ctx, span := tracer.Start(ctx, "storage.download",
 		trace.WithAttributes(attribute.String("storage.key", key)))
 	defer span.End()
 
-	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	data, err := readWithFailover(ctx, s, "download", func(client *minio.Client, bucket string) ([]byte, error) {
+		return getObject(ctx, client, bucket, key)
+	})
 	if err != nil {
 		recordSpanError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("file.size", len(data)))
+	return data, nil
+}
+
+// getObject reads a whole object, returning a classified error.
+func getObject(ctx context.Context, client *minio.Client, bucket, key string) ([]byte, error) {
+	object, err := client.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+	if err != nil {
 		return nil, classifyStorageError(err, "download")
 	}
 	defer object.Close()
 
 	data, err := io.ReadAll(object)
 	if err != nil {
-		recordSpanError(span, err)
 		return nil, classifyStorageError(err, "download")
 	}
-
-	span.SetAttributes(attribute.Int("file.size", len(data)))
 	return data, nil
 }
 
+// Exists reports whether an object exists in S3/MinIO.
+func (s *S3Storage) Exists(ctx context.Context, key string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "storage.exists",
+		trace.WithAttributes(attribute.String("storage.key", key)))
+	defer span.End()
+
+	_, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		err = classifyStorageError(err, "stat")
+		if errors.Is(err, ErrObjectNotFound) {
+			return false, nil
+		}
+		recordSpanError(span, err)
+		return false, err
+	}
+	return true, nil
+}
+
 // Delete removes a file from S3/MinIO
 func (s *S3Storage) Delete(ctx context.Context, key string) error {
 	ctx, span := tracer.Start(ctx, "storage.delete",
@@ -194,9 +269,37 @@ func chunkPrefix(userID int64, provider string, externalID, fileName string) str
 	return sessionChunksPrefix(userID, provider, externalID) + fileName + "/"
 }
 
-// UploadChunk uploads a chunk file for incremental sync.
+// sessionGenerationsPrefix returns the prefix covering every archived
+// generation of every file in a session.
+//
+// Format: {userID}/{provider}/{externalID}/generations/
+func sessionGenerationsPrefix(userID int64, provider string, externalID string) string {
+	return fmt.Sprintf("%d/%s/%s/generations/", userID, provider, externalID)
+}
+
+// generationPrefix returns the prefix holding the chunks a file had before
+// its generation'th reset. Archived generations live beside the chunks/
+// subtree rather than inside it, so ListChunks on the live file never sees
+// them and a file name can never collide with a generation directory.
+//
+// Format: {userID}/{provider}/{externalID}/generations/{generation}/{fileName}/
+func generationPrefix(userID int64, provider string, externalID, fileName string, generation int) string {
+	return sessionGenerationsPrefix(userID, provider, externalID) + fmt.Sprintf("%d/%s/", generation, fileName)
+}
+
+// MultipartThreshold is the chunk size above which UploadChunk switches to the
+// S3 multipart API. It also serves as the part size: S3 requires every part but
+// the last to be at least 5 MB.
+const MultipartThreshold = 5 * 1024 * 1024
+
+// multipartAbortTimeout bounds the best-effort AbortMultipartUpload call made
+// after a failed or cancelled multipart upload.
+const multipartAbortTimeout = 30 * time.Second
+
+// ChunkKey validates the chunk coordinates and returns the object key that
+// UploadChunk writes, so callers can record it before uploading.
 // Key format: {user_id}/{provider}/{external_id}/chunks/{file_name}/chunk_{first:08d}_{last:08d}.jsonl
-func (s *S3Storage) UploadChunk(ctx context.Context, userID int64, provider string, externalID, fileName string, firstLine, lastLine int, data []byte) (string, error) {
+func ChunkKey(userID int64, provider string, externalID, fileName string, firstLine, lastLine int) (string, error) {
 	// Reject invalid provider before any S3 call so plumbing bugs fail loudly
 	// at the storage boundary instead of as missing objects.
 	if err := validation.ValidateProvider(provider); err != nil {
@@ -208,6 +311,20 @@ func (s *S3Storage) UploadChunk(ctx context.Context, userID int64, provider stri
 		return "", fmt.Errorf("invalid line range [%d, %d]: must satisfy 1 <= firstLine <= lastLine <= %d", firstLine, lastLine, MaxLineNumber)
 	}
 
+	return chunkPrefix(userID, provider, externalID, fileName) +
+		fmt.Sprintf("chunk_%08d_%08d.jsonl", firstLine, lastLine), nil
+}
+
+// UploadChunk uploads a chunk file for incremental sync. Chunks larger than
+// MultipartThreshold go through the multipart path (see UploadChunkLarge);
+// the key is the same either way.
+// Key format: {user_id}/{provider}/{external_id}/chunks/{file_name}/chunk_{first:08d}_{last:08d}.jsonl
+func (s *S3Storage) UploadChunk(ctx context.Context, userID int64, provider string, externalID, fileName string, firstLine, lastLine int, data []byte) (string, error) {
+	key, err := ChunkKey(userID, provider, externalID, fileName, firstLine, lastLine)
+	if err != nil {
+		return "", err
+	}
+
 	ctx, span := tracer.Start(ctx, "storage.upload_chunk",
 		trace.WithAttributes(
 			attribute.Int64("user.id", userID),
@@ -220,11 +337,15 @@ func (s *S3Storage) UploadChunk(ctx context.Context, userID int64, provider stri
 		))
 	defer span.End()
 
-	key := chunkPrefix(userID, provider, externalID, fileName) +
-		fmt.Sprintf("chunk_%08d_%08d.jsonl", firstLine, lastLine)
+	if len(data) > MultipartThreshold {
+		if err := s.putMultipart(ctx, span, key, data); err != nil {
+			return "", err
+		}
+		return key, nil
+	}
 
 	reader := bytes.NewReader(data)
-	_, err := s.client.PutObject(ctx, s.bucket, key, reader, int64(len(data)), minio.PutObjectOptions{
+	_, err = s.client.PutObject(ctx, s.bucket, key, reader, int64(len(data)), minio.PutObjectOptions{
 		ContentType: "application/json",
 	})
 	if err != nil {
@@ -235,9 +356,110 @@ func (s *S3Storage) UploadChunk(ctx context.Context, userID int64, provider stri
 	return key, nil
 }
 
+// CopyChunk copies an existing chunk object (srcKey, as returned by
+// ListChunks) to the chunk key for the given session, file and line range,
+// and returns the new key. The object's content is copied server-side and not
+// rewritten: only the key's line numbers change, which is how a session merge
+// re-keys the source's lines after the target's.
+func (s *S3Storage) CopyChunk(ctx context.Context, srcKey string, userID int64, provider string, externalID, fileName string, firstLine, lastLine int) (string, error) {
+	key, err := ChunkKey(userID, provider, externalID, fileName, firstLine, lastLine)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, span := tracer.Start(ctx, "storage.copy_chunk",
+		trace.WithAttributes(
+			attribute.String("storage.src_key", srcKey),
+			attribute.String("storage.key", key),
+		))
+	defer span.End()
+
+	_, err = s.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: s.bucket, Object: key},
+		minio.CopySrcOptions{Bucket: s.bucket, Object: srcKey})
+	if err != nil {
+		recordSpanError(span, err)
+		return "", classifyStorageError(err, "copy chunk")
+	}
+	return key, nil
+}
+
+// UploadChunkLarge uploads a chunk through the S3 multipart API regardless of
+// its size, writing parts of MultipartThreshold bytes straight from data. The
+// object key is identical to UploadChunk's. On any error, including context
+// cancellation, the multipart upload is aborted so no orphaned parts remain.
+func (s *S3Storage) UploadChunkLarge(ctx context.Context, userID int64, provider string, externalID, fileName string, firstLine, lastLine int, data []byte) error {
+	key, err := ChunkKey(userID, provider, externalID, fileName, firstLine, lastLine)
+	if err != nil {
+		return err
+	}
+
+	ctx, span := tracer.Start(ctx, "storage.upload_chunk_large",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.String("session.provider", provider),
+			attribute.String("session.external_id", externalID),
+			attribute.String("file.name", fileName),
+			attribute.Int("chunk.first_line", firstLine),
+			attribute.Int("chunk.last_line", lastLine),
+			attribute.Int("file.size", len(data)),
+		))
+	defer span.End()
+
+	return s.putMultipart(ctx, span, key, data)
+}
+
+// putMultipart writes data to key as a multipart upload. Each part is a
+// sub-slice of data, so nothing beyond the caller's buffer is held in memory.
+func (s *S3Storage) putMultipart(ctx context.Context, span trace.Span, key string, data []byte) error {
+	core := minio.Core{Client: s.client}
+	opts := minio.PutObjectOptions{ContentType: "application/json"}
+
+	uploadID, err := core.NewMultipartUpload(ctx, s.bucket, key, opts)
+	if err != nil {
+		recordSpanError(span, err)
+		return classifyStorageError(err, "upload chunk")
+	}
+
+	parts := make([]minio.CompletePart, 0, (len(data)+MultipartThreshold-1)/MultipartThreshold)
+	for partNumber, off := 1, 0; off < len(data) || partNumber == 1; partNumber, off = partNumber+1, off+MultipartThreshold {
+		end := min(off+MultipartThreshold, len(data))
+		part, err := core.PutObjectPart(ctx, s.bucket, key, uploadID, partNumber,
+			bytes.NewReader(data[off:end]), int64(end-off), minio.PutObjectPartOptions{})
+		if err != nil {
+			s.abortMultipart(ctx, key, uploadID)
+			recordSpanError(span, err)
+			return classifyStorageError(err, "upload chunk part")
+		}
+		parts = append(parts, minio.CompletePart{PartNumber: partNumber, ETag: part.ETag})
+	}
+
+	if _, err := core.CompleteMultipartUpload(ctx, s.bucket, key, uploadID, parts, opts); err != nil {
+		s.abortMultipart(ctx, key, uploadID)
+		recordSpanError(span, err)
+		return classifyStorageError(err, "complete chunk upload")
+	}
+
+	span.SetAttributes(attribute.Int("chunk.parts", len(parts)))
+	return nil
+}
+
+// abortMultipart discards an unfinished multipart upload. It runs detached from
+// ctx's cancellation so a cancelled request still cleans up its parts.
+func (s *S3Storage) abortMultipart(ctx context.Context, key, uploadID string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), multipartAbortTimeout)
+	defer cancel()
+
+	if err := (minio.Core{Client: s.client}).AbortMultipartUpload(ctx, s.bucket, key, uploadID); err != nil {
+		slog.Warn("failed to abort multipart chunk upload", "key", key, "upload_id", uploadID, "error", err)
+	}
+}
+
 // ListChunks lists all chunk files for a given session and file name
 // Returns keys sorted by name (which gives correct line order due to zero-padded naming)
 // Returns ErrTooManyChunks if the file exceeds MaxChunksPerFile.
+// Like Download it falls back to the read failover; callers that write based
+// on the listing pass a PrimaryOnly context.
 func (s *S3Storage) ListChunks(ctx context.Context, userID int64, provider string, externalID, fileName string) ([]string, error) {
 	if err := validation.ValidateProvider(provider); err != nil {
 		return nil, fmt.Errorf("list chunks: %w", err)
@@ -254,35 +476,200 @@ func (s *S3Storage) ListChunks(ctx context.Context, userID int64, provider strin
 
 	prefix := chunkPrefix(userID, provider, externalID, fileName)
 
+	keys, err := readWithFailover(ctx, s, "list_chunks", func(client *minio.Client, bucket string) ([]string, error) {
+		return listChunkKeys(ctx, client, bucket, prefix)
+	})
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("chunks.count", len(keys)))
+
+	// Keys are already sorted by ListObjects (lexicographic order)
+	// Due to zero-padded line numbers, this gives correct order
+	return keys, nil
+}
+
+// listChunkKeys lists the keys under prefix, returning a classified error.
+func listChunkKeys(ctx context.Context, client *minio.Client, bucket, prefix string) ([]string, error) {
 	var keys []string
-	objectCh := s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{
+	objectCh := client.ListObjects(ctx, bucket, minio.ListObjectsOptions{
 		Prefix:    prefix,
 		Recursive: true,
 	})
 
 	for obj := range objectCh {
 		if obj.Err != nil {
-			span.RecordError(obj.Err)
-			span.SetStatus(codes.Error, obj.Err.Error())
 			return nil, classifyStorageError(obj.Err, "list chunks")
 		}
 		keys = append(keys, obj.Key)
 
 		// Sanity check to prevent unbounded memory usage
 		if len(keys) > MaxChunksPerFile {
-			err := fmt.Errorf("list chunks: %w (limit: %d)", ErrTooManyChunks, MaxChunksPerFile)
+			return nil, fmt.Errorf("list chunks: %w (limit: %d)", ErrTooManyChunks, MaxChunksPerFile)
+		}
+	}
+	return keys, nil
+}
+
+// ArchiveChunkGeneration moves every chunk of a file into the archive for
+// the given generation (see generationPrefix) and returns how many chunks it
+// moved. All copies complete before any original is deleted, and a copy
+// overwrites whatever an earlier, interrupted attempt left behind, so a failed
+// archive can simply be retried with the same generation.
+func (s *S3Storage) ArchiveChunkGeneration(ctx context.Context, userID int64, provider string, externalID, fileName string, generation int) (int, error) {
+	if err := validation.ValidateProvider(provider); err != nil {
+		return 0, fmt.Errorf("archive chunk generation: %w", err)
+	}
+
+	keys, err := s.ListChunks(PrimaryOnly(ctx), userID, provider, externalID, fileName)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, span := tracer.Start(ctx, "storage.archive_chunk_generation",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.String("session.provider", provider),
+			attribute.String("session.external_id", externalID),
+			attribute.String("file.name", fileName),
+			attribute.Int("file.generation", generation),
+			attribute.Int("chunks.count", len(keys)),
+		))
+	defer span.End()
+
+	src := chunkPrefix(userID, provider, externalID, fileName)
+	dst := generationPrefix(userID, provider, externalID, fileName, generation)
+	for _, key := range keys {
+		_, err := s.client.CopyObject(ctx,
+			minio.CopyDestOptions{Bucket: s.bucket, Object: dst + strings.TrimPrefix(key, src)},
+			minio.CopySrcOptions{Bucket: s.bucket, Object: key})
+		if err != nil {
+			recordSpanError(span, err)
+			return 0, classifyStorageError(err, "archive chunk")
+		}
+	}
+	for _, key := range keys {
+		if err := s.Delete(ctx, key); err != nil {
+			recordSpanError(span, err)
+			return 0, fmt.Errorf("failed to delete archived chunk %s: %w", key, err)
+		}
+	}
+
+	return len(keys), nil
+}
+
+// ListGenerationChunks lists the chunk keys of an archived file generation,
+// sorted like ListChunks. Returns ErrTooManyChunks past MaxChunksPerFile.
+func (s *S3Storage) ListGenerationChunks(ctx context.Context, userID int64, provider string, externalID, fileName string, generation int) ([]string, error) {
+	if err := validation.ValidateProvider(provider); err != nil {
+		return nil, fmt.Errorf("list generation chunks: %w", err)
+	}
+
+	ctx, span := tracer.Start(ctx, "storage.list_generation_chunks",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.String("session.provider", provider),
+			attribute.String("session.external_id", externalID),
+			attribute.String("file.name", fileName),
+			attribute.Int("file.generation", generation),
+		))
+	defer span.End()
+
+	var keys []string
+	objectCh := s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{
+		Prefix:    generationPrefix(userID, provider, externalID, fileName, generation),
+		Recursive: true,
+	})
+	for obj := range objectCh {
+		if obj.Err != nil {
+			recordSpanError(span, obj.Err)
+			return nil, classifyStorageError(obj.Err, "list generation chunks")
+		}
+		keys = append(keys, obj.Key)
+		if len(keys) > MaxChunksPerFile {
+			err := fmt.Errorf("list generation chunks: %w (limit: %d)", ErrTooManyChunks, MaxChunksPerFile)
 			recordSpanError(span, err)
 			return nil, err
 		}
 	}
 
 	span.SetAttributes(attribute.Int("chunks.count", len(keys)))
-
-	// Keys are already sorted by ListObjects (lexicographic order)
-	// Due to zero-padded line numbers, this gives correct order
 	return keys, nil
 }
 
+// GenerationFile names one archived generation of a sync file.
+type GenerationFile struct {
+	FileName   string
+	Generation int
+}
+
+// SessionStoredBytes is the size of a session's chunk objects, keyed by live
+// file name and by archived generation. Files with no objects are absent.
+type SessionStoredBytes struct {
+	Files       map[string]int64
+	Generations map[GenerationFile]int64
+}
+
+// SessionStoredBytes lists every chunk object of a session under the given
+// provider (live files and archived generations, the same prefixes
+// DeleteAllSessionChunks clears) and totals their sizes per file.
+func (s *S3Storage) SessionStoredBytes(ctx context.Context, userID int64, provider string, externalID string) (*SessionStoredBytes, error) {
+	if err := validation.ValidateProvider(provider); err != nil {
+		return nil, fmt.Errorf("session stored bytes: %w", err)
+	}
+
+	ctx, span := tracer.Start(ctx, "storage.session_stored_bytes",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.String("session.provider", provider),
+			attribute.String("session.external_id", externalID),
+		))
+	defer span.End()
+
+	usage := &SessionStoredBytes{
+		Files:       map[string]int64{},
+		Generations: map[GenerationFile]int64{},
+	}
+
+	livePrefix := sessionChunksPrefix(userID, provider, externalID)
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: livePrefix, Recursive: true}) {
+		if obj.Err != nil {
+			recordSpanError(span, obj.Err)
+			return nil, classifyStorageError(obj.Err, "list session chunks")
+		}
+		// {file_name}/chunk_...
+		rest := strings.TrimPrefix(obj.Key, livePrefix)
+		if i := strings.LastIndexByte(rest, '/'); i > 0 {
+			usage.Files[rest[:i]] += obj.Size
+		}
+	}
+
+	genPrefix := sessionGenerationsPrefix(userID, provider, externalID)
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: genPrefix, Recursive: true}) {
+		if obj.Err != nil {
+			recordSpanError(span, obj.Err)
+			return nil, classifyStorageError(obj.Err, "list session generations")
+		}
+		// {generation}/{file_name}/chunk_...
+		rest := strings.TrimPrefix(obj.Key, genPrefix)
+		genStr, rest, ok := strings.Cut(rest, "/")
+		i := strings.LastIndexByte(rest, '/')
+		generation, err := strconv.Atoi(genStr)
+		if !ok || i <= 0 || err != nil {
+			continue
+		}
+		usage.Generations[GenerationFile{FileName: rest[:i], Generation: generation}] += obj.Size
+	}
+
+	span.SetAttributes(
+		attribute.Int("files.count", len(usage.Files)),
+		attribute.Int("generations.count", len(usage.Generations)),
+	)
+	return usage, nil
+}
+
 // DeleteAllSessionChunks deletes all chunks for all files in a session under
 // the given provider. The prefix is provider-scoped — chunks written under a
 // different provider for the same (user, externalID) pair are NOT touched.
@@ -299,13 +686,113 @@ func (s *S3Storage) DeleteAllSessionChunks(ctx context.Context, userID int64, pr
 		))
 	defer span.End()
 
-	// Session-wide prefix (no file-name segment) — deletes every chunk under
-	// this session's chunks/ subtree, scoped to the named provider.
-	prefix := sessionChunksPrefix(userID, provider, externalID)
+	// Session-wide prefixes (no file-name segment) — delete every chunk under
+	// this session's chunks/ subtree and every archived generation, scoped to
+	// the named provider.
+	prefixes := []string{
+		sessionChunksPrefix(userID, provider, externalID),
+		sessionGenerationsPrefix(userID, provider, externalID),
+	}
 
 	var deletedCount int
+	for _, prefix := range prefixes {
+		objectCh := s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{
+			Prefix:    prefix,
+			Recursive: true,
+		})
+
+		for obj := range objectCh {
+			if obj.Err != nil {
+				span.RecordError(obj.Err)
+				span.SetStatus(codes.Error, obj.Err.Error())
+				return classifyStorageError(obj.Err, "list session chunks")
+			}
+			if err := s.Delete(ctx, obj.Key); err != nil {
+				recordSpanError(span, err)
+				return fmt.Errorf("failed to delete chunk %s: %w", obj.Key, err)
+			}
+			deletedCount++
+		}
+	}
+
+	span.SetAttributes(attribute.Int("chunks.deleted", deletedCount))
+
+	return nil
+}
+
+// CopyAllSessionChunks copies every chunk and archived generation of a
+// session to the same paths under dstExternalID and returns the new keys.
+// The originals are left in place, and a copy overwrites an earlier
+// attempt's, so it can be retried. Used to re-key a session whose external
+// ID is reassigned; the caller deletes the originals once the DB points at
+// the new ID, or the returned copies if it doesn't.
+func (s *S3Storage) CopyAllSessionChunks(ctx context.Context, userID int64, provider string, srcExternalID, dstExternalID string) ([]string, error) {
+	if err := validation.ValidateProvider(provider); err != nil {
+		return nil, fmt.Errorf("copy session chunks: %w", err)
+	}
+
+	ctx, span := tracer.Start(ctx, "storage.copy_all_session_chunks",
+		trace.WithAttributes(
+			attribute.Int64("user.id", userID),
+			attribute.String("session.provider", provider),
+			attribute.String("session.external_id", srcExternalID),
+			attribute.String("session.new_external_id", dstExternalID),
+		))
+	defer span.End()
+
+	prefixes := [][2]string{
+		{sessionChunksPrefix(userID, provider, srcExternalID), sessionChunksPrefix(userID, provider, dstExternalID)},
+		{sessionGenerationsPrefix(userID, provider, srcExternalID), sessionGenerationsPrefix(userID, provider, dstExternalID)},
+	}
+
+	var copied []string
+	for _, p := range prefixes {
+		src, dst := p[0], p[1]
+		objectCh := s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{
+			Prefix:    src,
+			Recursive: true,
+		})
+
+		for obj := range objectCh {
+			if obj.Err != nil {
+				span.RecordError(obj.Err)
+				span.SetStatus(codes.Error, obj.Err.Error())
+				return copied, classifyStorageError(obj.Err, "list session chunks")
+			}
+			key := dst + strings.TrimPrefix(obj.Key, src)
+			_, err := s.client.CopyObject(ctx,
+				minio.CopyDestOptions{Bucket: s.bucket, Object: key},
+				minio.CopySrcOptions{Bucket: s.bucket, Object: obj.Key})
+			if err != nil {
+				recordSpanError(span, err)
+				return copied, classifyStorageError(err, "copy session chunk")
+			}
+			copied = append(copied, key)
+		}
+	}
+
+	span.SetAttributes(attribute.Int("chunks.copied", len(copied)))
+	return copied, nil
+}
+
+// CopyAllUserData copies every S3 object under srcUserID's prefix to the same
+// path under dstUserID's prefix and returns how many it copied. The originals
+// are left in place; a copy overwrites an earlier attempt's, so it can be
+// retried. Used by the admin user merge before ownership moves in the DB.
+func (s *S3Storage) CopyAllUserData(ctx context.Context, srcUserID, dstUserID int64) (int, error) {
+	ctx, span := tracer.Start(ctx, "storage.copy_all_user_data",
+		trace.WithAttributes(
+			attribute.Int64("user.id", srcUserID),
+			attribute.Int64("target_user.id", dstUserID),
+		))
+	defer span.End()
+
+	src := fmt.Sprintf("%d/", srcUserID)
+	dst := fmt.Sprintf("%d/", dstUserID)
+
+	var copied int
 	objectCh := s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{
-		Prefix:    prefix,
+		Prefix:    src,
 		Recursive: true,
 	})
 
@@ -313,18 +800,20 @@ func (s *S3Storage) DeleteAllSessionChunks(ctx context.Context, userID int64, pr
 		if obj.Err != nil {
 			span.RecordError(obj.Err)
 			span.SetStatus(codes.Error, obj.Err.Error())
-			return classifyStorageError(obj.Err, "list session chunks")
+			return copied, classifyStorageError(obj.Err, "list user objects")
 		}
-		if err := s.Delete(ctx, obj.Key); err != nil {
+		_, err := s.client.CopyObject(ctx,
+			minio.CopyDestOptions{Bucket: s.bucket, Object: dst + strings.TrimPrefix(obj.Key, src)},
+			minio.CopySrcOptions{Bucket: s.bucket, Object: obj.Key})
+		if err != nil {
 			recordSpanError(span, err)
-			return fmt.Errorf("failed to delete chunk %s: %w", obj.Key, err)
+			return copied, classifyStorageError(err, "copy user object")
 		}
-		deletedCount++
+		copied++
 	}
 
-	span.SetAttributes(attribute.Int("chunks.deleted", deletedCount))
-
-	return nil
+	span.SetAttributes(attribute.Int("objects.copied", copied))
+	return copied, nil
 }
 
 // DeleteAllUserData deletes all S3 objects for a user (prefix: {userID}/).
@@ -0,0 +1,326 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// newFakeGetClient returns a minio client for an endpoint answering every
+// request with handler, counting the requests made.
+func newFakeGetClient(tb testing.TB, handler http.HandlerFunc, calls *atomic.Int32) *minio.Client {
+	tb.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	}))
+	tb.Cleanup(srv.Close)
+
+	client, err := minio.New(strings.TrimPrefix(srv.URL, "http://"), &minio.Options{
+		Creds:      credentials.NewStaticV4("key", "secret", ""),
+		Region:     "us-east-1", // skip the bucket-location lookup
+		MaxRetries: 1,
+	})
+	if err != nil {
+		tb.Fatalf("minio.New: %v", err)
+	}
+	return client
+}
+
+func serveObject(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		fmt.Fprint(w, body)
+	}
+}
+
+func serveError(status int, code string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `<Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+	}
+}
+
+func TestDownload_ReadFailover(t *testing.T) {
+	t.Run("secondary serves the object when the primary fails", func(t *testing.T) {
+		var primaryCalls, failoverCalls atomic.Int32
+		s := &S3Storage{
+			client:         newFakeGetClient(t, serveError(http.StatusServiceUnavailable, "SlowDown"), &primaryCalls),
+			bucket:         fakeS3Bucket,
+			failover:       newFakeGetClient(t, serveObject("replicated chunk"), &failoverCalls),
+			failoverBucket: "chunks-replica",
+		}
+
+		data, err := s.Download(context.Background(), "chunks/key")
+		if err != nil {
+			t.Fatalf("Download: %v", err)
+		}
+		if string(data) != "replicated chunk" {
+			t.Errorf("data = %q, want the replica's copy", data)
+		}
+		if primaryCalls.Load() == 0 || failoverCalls.Load() == 0 {
+			t.Errorf("calls: primary %d, failover %d; want both tried", primaryCalls.Load(), failoverCalls.Load())
+		}
+	})
+
+	t.Run("primary success does not touch the secondary", func(t *testing.T) {
+		var primaryCalls, failoverCalls atomic.Int32
+		s := &S3Storage{
+			client:         newFakeGetClient(t, serveObject("primary chunk"), &primaryCalls),
+			bucket:         fakeS3Bucket,
+			failover:       newFakeGetClient(t, serveObject("replicated chunk"), &failoverCalls),
+			failoverBucket: fakeS3Bucket,
+		}
+
+		data, err := s.Download(context.Background(), "chunks/key")
+		if err != nil || string(data) != "primary chunk" {
+			t.Fatalf("Download = %q, %v; want the primary's copy", data, err)
+		}
+		if failoverCalls.Load() != 0 {
+			t.Errorf("failover called %d times, want 0", failoverCalls.Load())
+		}
+	})
+
+	t.Run("a missing object does not fail over", func(t *testing.T) {
+		var primaryCalls, failoverCalls atomic.Int32
+		s := &S3Storage{
+			client:         newFakeGetClient(t, serveError(http.StatusNotFound, "NoSuchKey"), &primaryCalls),
+			bucket:         fakeS3Bucket,
+			failover:       newFakeGetClient(t, serveObject("stale replica"), &failoverCalls),
+			failoverBucket: fakeS3Bucket,
+		}
+
+		_, err := s.Download(context.Background(), "chunks/key")
+		if !errors.Is(err, ErrObjectNotFound) {
+			t.Fatalf("err = %v, want ErrObjectNotFound", err)
+		}
+		if failoverCalls.Load() != 0 {
+			t.Errorf("failover called %d times, want 0", failoverCalls.Load())
+		}
+	})
+
+	t.Run("both failing returns the secondary's error", func(t *testing.T) {
+		var primaryCalls, failoverCalls atomic.Int32
+		s := &S3Storage{
+			client:         newFakeGetClient(t, serveError(http.StatusServiceUnavailable, "SlowDown"), &primaryCalls),
+			bucket:         fakeS3Bucket,
+			failover:       newFakeGetClient(t, serveError(http.StatusForbidden, "AccessDenied"), &failoverCalls),
+			failoverBucket: fakeS3Bucket,
+		}
+
+		_, err := s.Download(context.Background(), "chunks/key")
+		if !errors.Is(err, ErrAccessDenied) {
+			t.Fatalf("err = %v, want ErrAccessDenied from the failover", err)
+		}
+	})
+
+	t.Run("no failover configured", func(t *testing.T) {
+		var primaryCalls atomic.Int32
+		s := &S3Storage{
+			client: newFakeGetClient(t, serveError(http.StatusForbidden, "AccessDenied"), &primaryCalls),
+			bucket: fakeS3Bucket,
+		}
+
+		if _, err := s.Download(context.Background(), "chunks/key"); !errors.Is(err, ErrAccessDenied) {
+			t.Fatalf("err = %v, want ErrAccessDenied", err)
+		}
+	})
+}
+
+// serveList answers a ListObjectsV2 request with keys.
+func serveList(keys ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, `<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>b</Name><IsTruncated>false</IsTruncated>`)
+		for _, k := range keys {
+			fmt.Fprintf(w, `<Contents><Key>%s</Key><Size>1</Size></Contents>`, k)
+		}
+		fmt.Fprint(w, `</ListBucketResult>`)
+	}
+}
+
+func TestDownload_FailoverOnlyWhenPrimaryUnavailable(t *testing.T) {
+	var primaryCalls, failoverCalls atomic.Int32
+	s := &S3Storage{
+		client:         newFakeGetClient(t, serveError(http.StatusForbidden, "AccessDenied"), &primaryCalls),
+		bucket:         fakeS3Bucket,
+		failover:       newFakeGetClient(t, serveObject("replicated chunk"), &failoverCalls),
+		failoverBucket: fakeS3Bucket,
+	}
+
+	if _, err := s.Download(context.Background(), "chunks/key"); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("err = %v, want the primary's ErrAccessDenied", err)
+	}
+	if failoverCalls.Load() != 0 {
+		t.Errorf("failover called %d times, want 0", failoverCalls.Load())
+	}
+}
+
+func TestReadFailover_ReplicaMarker(t *testing.T) {
+	var primaryCalls, failoverCalls atomic.Int32
+	s := &S3Storage{
+		client:         newFakeGetClient(t, serveError(http.StatusInternalServerError, "InternalError"), &primaryCalls),
+		bucket:         fakeS3Bucket,
+		failover:       newFakeGetClient(t, serveObject("replicated chunk"), &failoverCalls),
+		failoverBucket: fakeS3Bucket,
+	}
+
+	ctx, served := WithReplicaReadMarker(context.Background())
+	if served() {
+		t.Fatal("served() = true before any read")
+	}
+	if _, err := s.Download(ctx, "chunks/key"); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if !served() {
+		t.Error("served() = false after a read from the failover")
+	}
+
+	// Without a marker the read still works.
+	if _, err := s.Download(context.Background(), "chunks/key"); err != nil {
+		t.Fatalf("Download without marker: %v", err)
+	}
+}
+
+func TestReadFailover_PrimaryOnly(t *testing.T) {
+	var primaryCalls, failoverCalls atomic.Int32
+	s := &S3Storage{
+		client:         newFakeGetClient(t, serveError(http.StatusServiceUnavailable, "SlowDown"), &primaryCalls),
+		bucket:         fakeS3Bucket,
+		failover:       newFakeGetClient(t, serveObject("replicated chunk"), &failoverCalls),
+		failoverBucket: fakeS3Bucket,
+	}
+
+	if _, err := s.Download(PrimaryOnly(context.Background()), "chunks/key"); err == nil {
+		t.Fatal("Download succeeded, want the primary's error")
+	}
+	if failoverCalls.Load() != 0 {
+		t.Errorf("failover called %d times, want 0", failoverCalls.Load())
+	}
+}
+
+func TestListChunks_ReadFailover(t *testing.T) {
+	keys := []string{
+		"1/claude-code/ext/chunks/transcript.jsonl/chunk_00000001_00000010.jsonl",
+		"1/claude-code/ext/chunks/transcript.jsonl/chunk_00000011_00000020.jsonl",
+	}
+	var primaryCalls, failoverCalls atomic.Int32
+	s := &S3Storage{
+		client:         newFakeGetClient(t, serveError(http.StatusServiceUnavailable, "SlowDown"), &primaryCalls),
+		bucket:         fakeS3Bucket,
+		failover:       newFakeGetClient(t, serveList(keys...), &failoverCalls),
+		failoverBucket: fakeS3Bucket,
+	}
+
+	ctx, served := WithReplicaReadMarker(context.Background())
+	got, err := s.ListChunks(ctx, 1, "claude-code", "ext", "transcript.jsonl")
+	if err != nil {
+		t.Fatalf("ListChunks: %v", err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(keys) {
+		t.Errorf("ListChunks = %v, want %v", got, keys)
+	}
+	if !served() {
+		t.Error("listing from the failover was not marked")
+	}
+
+	failoverCalls.Store(0)
+	if _, err := s.ListChunks(PrimaryOnly(context.Background()), 1, "claude-code", "ext", "transcript.jsonl"); err == nil {
+		t.Error("PrimaryOnly ListChunks succeeded, want the primary's error")
+	}
+	if failoverCalls.Load() != 0 {
+		t.Errorf("PrimaryOnly listing called the failover %d times", failoverCalls.Load())
+	}
+}
+
+func TestReadFailover_CircuitBreaker(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	var primaryCalls, failoverCalls atomic.Int32
+	s := &S3Storage{
+		client:         newFakeGetClient(t, serveError(http.StatusServiceUnavailable, "SlowDown"), &primaryCalls),
+		bucket:         fakeS3Bucket,
+		failover:       newFakeGetClient(t, serveObject("replicated chunk"), &failoverCalls),
+		failoverBucket: fakeS3Bucket,
+	}
+	s.primaryBreaker.now = clock
+	s.failoverBreaker.now = clock
+
+	for range breakerThreshold {
+		if _, err := s.Download(context.Background(), "chunks/key"); err != nil {
+			t.Fatalf("Download: %v", err)
+		}
+	}
+	if !s.primaryBreaker.open() {
+		t.Fatalf("primary breaker closed after %d failures", breakerThreshold)
+	}
+
+	// While open, reads go straight to the failover.
+	primaryCalls.Store(0)
+	if _, err := s.Download(context.Background(), "chunks/key"); err != nil {
+		t.Fatalf("Download with open breaker: %v", err)
+	}
+	if primaryCalls.Load() != 0 {
+		t.Errorf("primary called %d times with its breaker open, want 0", primaryCalls.Load())
+	}
+
+	// After the cooldown the primary is probed again.
+	now = now.Add(breakerCooldown)
+	if _, err := s.Download(context.Background(), "chunks/key"); err != nil {
+		t.Fatalf("Download after cooldown: %v", err)
+	}
+	if primaryCalls.Load() == 0 {
+		t.Error("primary not probed after the cooldown")
+	}
+	if !s.primaryBreaker.open() {
+		t.Error("breaker closed after a failed probe, want it reopened")
+	}
+}
+
+func TestCircuitBreaker_ResetsOnAnswer(t *testing.T) {
+	var b circuitBreaker
+	unavailable := fmt.Errorf("download network issue: %w", ErrNetworkError)
+	for range breakerThreshold - 1 {
+		b.record(unavailable)
+	}
+	b.record(fmt.Errorf("download: %w", ErrObjectNotFound)) // the endpoint answered
+	b.record(unavailable)
+	if b.open() {
+		t.Error("breaker open, want the missing-object answer to reset the count")
+	}
+}
+
+func TestHealth(t *testing.T) {
+	var primaryCalls, failoverCalls atomic.Int32
+	s := &S3Storage{
+		client:         newFakeGetClient(t, serveObject(""), &primaryCalls),
+		bucket:         fakeS3Bucket,
+		failover:       newFakeGetClient(t, serveError(http.StatusServiceUnavailable, "SlowDown"), &failoverCalls),
+		failoverBucket: fakeS3Bucket,
+	}
+
+	h := s.Health(context.Background())
+	if h.Primary.Status != "ok" || h.Primary.CircuitOpen {
+		t.Errorf("Primary = %+v, want ok", h.Primary)
+	}
+	if h.Failover == nil || h.Failover.Status == "ok" {
+		t.Errorf("Failover = %+v, want an error status", h.Failover)
+	}
+
+	s.failover = nil
+	if h := s.Health(context.Background()); h.Failover != nil {
+		t.Errorf("Failover = %+v, want nil without a failover", h.Failover)
+	}
+}
@@ -1,6 +1,7 @@
 package storage_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"strings"
@@ -66,6 +67,33 @@ func TestDownloadMissingKey(t *testing.T) {
 	}
 }
 
+// TestExists verifies Exists matches the key ChunkKey predicts for an upload
+// and reports a missing key as false without an error.
+func TestExists(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	ctx := context.Background()
+	externalID := freshExternalID("exists")
+	key, err := storage.ChunkKey(42, models.ProviderClaudeCode, externalID, "transcript.jsonl", 1, 1)
+	if err != nil {
+		t.Fatalf("ChunkKey: %v", err)
+	}
+
+	if exists, err := env.Storage.Exists(ctx, key); err != nil || exists {
+		t.Fatalf("before upload: Exists = %v, %v; want false, nil", exists, err)
+	}
+	if _, err := env.Storage.UploadChunk(ctx, 42, models.ProviderClaudeCode, externalID, "transcript.jsonl", 1, 1, []byte("{}\n")); err != nil {
+		t.Fatalf("UploadChunk: %v", err)
+	}
+	if exists, err := env.Storage.Exists(ctx, key); err != nil || !exists {
+		t.Fatalf("after upload: Exists = %v, %v; want true, nil", exists, err)
+	}
+}
+
 // TestListChunksReturnsSortedKeys verifies that ListChunks returns chunks in
 // line-number order (which equals lexicographic order due to %08d padding).
 func TestListChunksReturnsSortedKeys(t *testing.T) {
@@ -239,6 +267,58 @@ func TestDeleteAllSessionChunksRemovesEverythingScoped(t *testing.T) {
 	}
 }
 
+// TestArchiveChunkGeneration moves a file's chunks into a generation, leaving
+// the live prefix empty for the next generation's uploads.
+func TestArchiveChunkGeneration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	ctx := context.Background()
+	externalID := freshExternalID("session-archive-gen")
+	for _, r := range [][2]int{{1, 5}, {6, 9}} {
+		if _, err := env.Storage.UploadChunk(ctx, 7, models.ProviderClaudeCode, externalID, "transcript.jsonl", r[0], r[1], []byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	moved, err := env.Storage.ArchiveChunkGeneration(ctx, 7, models.ProviderClaudeCode, externalID, "transcript.jsonl", 0)
+	if err != nil {
+		t.Fatalf("ArchiveChunkGeneration: %v", err)
+	}
+	if moved != 2 {
+		t.Errorf("moved = %d, want 2", moved)
+	}
+
+	live, err := env.Storage.ListChunks(ctx, 7, models.ProviderClaudeCode, externalID, "transcript.jsonl")
+	if err != nil {
+		t.Fatalf("ListChunks: %v", err)
+	}
+	if len(live) != 0 {
+		t.Errorf("live chunks after archive = %v, want none", live)
+	}
+	archived, err := env.Storage.ListGenerationChunks(ctx, 7, models.ProviderClaudeCode, externalID, "transcript.jsonl", 0)
+	if err != nil {
+		t.Fatalf("ListGenerationChunks: %v", err)
+	}
+	if len(archived) != 2 {
+		t.Fatalf("archived chunks = %v, want 2", archived)
+	}
+	if first, last, ok := storage.ParseChunkKey(archived[1]); !ok || first != 6 || last != 9 {
+		t.Errorf("archived key %q parsed as (%d, %d, %v), want (6, 9, true)", archived[1], first, last, ok)
+	}
+
+	// The session-wide delete sweeps archived generations too.
+	if err := env.Storage.DeleteAllSessionChunks(ctx, 7, models.ProviderClaudeCode, externalID); err != nil {
+		t.Fatalf("DeleteAllSessionChunks: %v", err)
+	}
+	if archived, _ := env.Storage.ListGenerationChunks(ctx, 7, models.ProviderClaudeCode, externalID, "transcript.jsonl", 0); len(archived) != 0 {
+		t.Errorf("archived chunks after session delete = %v, want none", archived)
+	}
+}
+
 func TestDeleteAllUserDataRemovesEverythingForUser(t *testing.T) {
 	if testing.Short() {
 		t.Skip("integration test")
@@ -407,3 +487,79 @@ func TestNewS3StorageMissingBucket(t *testing.T) {
 		t.Errorf("error should mention missing bucket, got: %v", err)
 	}
 }
+
+// TestUploadChunkMultipartRoundTrip verifies that a chunk above
+// MultipartThreshold is written through the multipart path under the same key
+// and reads back byte-for-byte.
+func TestUploadChunkMultipartRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	ctx := context.Background()
+	externalID := freshExternalID("multipart")
+	line := []byte("{\"line\":\"" + strings.Repeat("x", 1000) + "\"}\n")
+	payload := bytes.Repeat(line, storage.MultipartThreshold/len(line)*2+1)
+
+	key, err := env.Storage.UploadChunk(ctx, 42, models.ProviderClaudeCode, externalID, "transcript.jsonl", 1, 100, payload)
+	if err != nil {
+		t.Fatalf("UploadChunk: %v", err)
+	}
+	if !strings.HasSuffix(key, "/"+externalID+"/chunks/transcript.jsonl/chunk_00000001_00000100.jsonl") {
+		t.Errorf("unexpected key: %q", key)
+	}
+
+	got, err := env.Storage.Download(ctx, key)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("round-trip mismatch: got %d bytes, want %d", len(got), len(payload))
+	}
+}
+
+// TestCopyAllUserData_CopiesUnderTargetPrefix checks that every object moves
+// to the same path under the target's prefix, originals stay, and an
+// adjacent user ID sharing the decimal prefix is not copied.
+func TestCopyAllUserData_CopiesUnderTargetPrefix(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	env := testutil.SetupTestEnvironment(t)
+	defer env.Cleanup(t)
+
+	ctx := context.Background()
+	const sourceUser int64 = 777
+	const adjacentUser int64 = 7770
+	const targetUser int64 = 778
+	externalID := freshExternalID("copy")
+
+	srcKey, err := env.Storage.UploadChunk(ctx, sourceUser, models.ProviderClaudeCode, externalID, "transcript.jsonl", 1, 5, []byte("src"))
+	if err != nil {
+		t.Fatalf("UploadChunk(source): %v", err)
+	}
+	if _, err := env.Storage.UploadChunk(ctx, adjacentUser, models.ProviderClaudeCode, freshExternalID("adjacent"), "transcript.jsonl", 1, 5, []byte("adj")); err != nil {
+		t.Fatalf("UploadChunk(adjacent): %v", err)
+	}
+
+	copied, err := env.Storage.CopyAllUserData(ctx, sourceUser, targetUser)
+	if err != nil {
+		t.Fatalf("CopyAllUserData: %v", err)
+	}
+	if copied != 1 {
+		t.Errorf("copied = %d, want 1", copied)
+	}
+
+	dstKey, err := storage.ChunkKey(targetUser, models.ProviderClaudeCode, externalID, "transcript.jsonl", 1, 5)
+	if err != nil {
+		t.Fatalf("ChunkKey: %v", err)
+	}
+	if got, err := env.Storage.Download(ctx, dstKey); err != nil || string(got) != "src" {
+		t.Errorf("Download(%s) = %q, %v; want \"src\"", dstKey, got, err)
+	}
+	if _, err := env.Storage.Download(ctx, srcKey); err != nil {
+		t.Errorf("original must remain after copy: %v", err)
+	}
+}
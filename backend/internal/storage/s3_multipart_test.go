@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/ConfabulousDev/confab-web/internal/models"
+)
+
+const fakeS3Bucket = "chunks"
+
+// fakeS3 is a minimal S3 endpoint covering PutObject and the multipart calls
+// UploadChunk makes. Bodies are discarded; only sizes and call order are kept.
+type fakeS3 struct {
+	mu        sync.Mutex
+	puts      []string // object paths written by single PutObject
+	partSizes []int64  // multipart part sizes, in part-number order
+	paths     []string // object path of every multipart request
+	completed bool
+	aborted   bool
+
+	failPart int               // part number answered with AccessDenied (0 = none)
+	onPart   func(partNum int) // called before a part is answered
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	n, _ := io.Copy(io.Discard, r.Body)
+	if decoded := r.Header.Get("X-Amz-Decoded-Content-Length"); decoded != "" {
+		n, _ = strconv.ParseInt(decoded, 10, 64)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		f.paths = append(f.paths, r.URL.Path)
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>%s</Bucket><Key>k</Key><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`, fakeS3Bucket)
+	case r.Method == http.MethodPut && q.Has("partNumber"):
+		f.paths = append(f.paths, r.URL.Path)
+		partNum, _ := strconv.Atoi(q.Get("partNumber"))
+		if f.onPart != nil {
+			f.onPart(partNum)
+		}
+		if partNum == f.failPart {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<Error><Code>AccessDenied</Code><Message>denied</Message></Error>`)
+			return
+		}
+		f.partSizes = append(f.partSizes, n)
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, partNum))
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		f.paths = append(f.paths, r.URL.Path)
+		f.completed = true
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Bucket>%s</Bucket><Key>k</Key><ETag>"done"</ETag></CompleteMultipartUploadResult>`, fakeS3Bucket)
+	case r.Method == http.MethodDelete && q.Has("uploadId"):
+		f.paths = append(f.paths, r.URL.Path)
+		f.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.puts = append(f.puts, r.URL.Path)
+		w.Header().Set("ETag", `"single"`)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// newFakeS3Storage returns an S3Storage wired to a fakeS3 server.
+func newFakeS3Storage(tb testing.TB, fake *fakeS3) *S3Storage {
+	tb.Helper()
+	srv := httptest.NewServer(fake)
+	tb.Cleanup(srv.Close)
+
+	client, err := minio.New(strings.TrimPrefix(srv.URL, "http://"), &minio.Options{
+		Creds:  credentials.NewStaticV4("key", "secret", ""),
+		Region: "us-east-1", // skip the bucket-location lookup
+	})
+	if err != nil {
+		tb.Fatalf("minio.New: %v", err)
+	}
+	return &S3Storage{client: client, bucket: fakeS3Bucket}
+}
+
+func TestUploadChunk_MultipartAboveThreshold(t *testing.T) {
+	fake := &fakeS3{}
+	s := newFakeS3Storage(t, fake)
+
+	small := bytes.Repeat([]byte("a"), MultipartThreshold)
+	smallKey, err := s.UploadChunk(t.Context(), 7, models.ProviderClaudeCode, "ext", "transcript.jsonl", 1, 10, small)
+	if err != nil {
+		t.Fatalf("UploadChunk(small): %v", err)
+	}
+	if len(fake.puts) != 1 || len(fake.paths) != 0 {
+		t.Fatalf("chunk at the threshold should use a single PutObject, got puts=%v multipart=%v", fake.puts, fake.paths)
+	}
+
+	large := bytes.Repeat([]byte("b"), 2*MultipartThreshold+10)
+	largeKey, err := s.UploadChunk(t.Context(), 7, models.ProviderClaudeCode, "ext", "transcript.jsonl", 11, 20, large)
+	if err != nil {
+		t.Fatalf("UploadChunk(large): %v", err)
+	}
+	if len(fake.puts) != 1 {
+		t.Errorf("large chunk should not use PutObject, puts=%v", fake.puts)
+	}
+	want := []int64{MultipartThreshold, MultipartThreshold, 10}
+	if fmt.Sprint(fake.partSizes) != fmt.Sprint(want) {
+		t.Errorf("part sizes = %v, want %v", fake.partSizes, want)
+	}
+	if !fake.completed || fake.aborted {
+		t.Errorf("completed=%v aborted=%v, want completed only", fake.completed, fake.aborted)
+	}
+
+	// Both paths share the key layout.
+	if smallKey != "7/claude-code/ext/chunks/transcript.jsonl/chunk_00000001_00000010.jsonl" ||
+		largeKey != "7/claude-code/ext/chunks/transcript.jsonl/chunk_00000011_00000020.jsonl" {
+		t.Errorf("keys = %q, %q", smallKey, largeKey)
+	}
+	for _, p := range fake.paths {
+		if p != "/"+fakeS3Bucket+"/"+largeKey {
+			t.Errorf("multipart request path = %q, want key %q", p, largeKey)
+		}
+	}
+}
+
+func TestUploadChunkLarge_KeyMatchesUploadChunk(t *testing.T) {
+	fake := &fakeS3{}
+	s := newFakeS3Storage(t, fake)
+
+	key, err := s.UploadChunk(t.Context(), 7, models.ProviderCodex, "ext", "agent.jsonl", 1, 2, []byte("x\ny\n"))
+	if err != nil {
+		t.Fatalf("UploadChunk: %v", err)
+	}
+	if err := s.UploadChunkLarge(t.Context(), 7, models.ProviderCodex, "ext", "agent.jsonl", 1, 2, []byte("x\ny\n")); err != nil {
+		t.Fatalf("UploadChunkLarge: %v", err)
+	}
+	if fake.puts[0] != "/"+fakeS3Bucket+"/"+key || fake.paths[0] != fake.puts[0] {
+		t.Errorf("single put path %q and multipart path %q should both be the key %q", fake.puts[0], fake.paths[0], key)
+	}
+	if len(fake.partSizes) != 1 || fake.partSizes[0] != 4 {
+		t.Errorf("part sizes = %v, want [4]", fake.partSizes)
+	}
+}
+
+func TestUploadChunkLarge_AbortsOnPartFailure(t *testing.T) {
+	fake := &fakeS3{failPart: 2}
+	s := newFakeS3Storage(t, fake)
+
+	data := bytes.Repeat([]byte("c"), 3*MultipartThreshold)
+	err := s.UploadChunkLarge(t.Context(), 1, models.ProviderClaudeCode, "ext", "transcript.jsonl", 1, 5, data)
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("err = %v, want ErrAccessDenied", err)
+	}
+	if !fake.aborted || fake.completed {
+		t.Errorf("aborted=%v completed=%v, want aborted only", fake.aborted, fake.completed)
+	}
+	if len(fake.partSizes) != 1 {
+		t.Errorf("uploaded %d parts, want 1 before the failure", len(fake.partSizes))
+	}
+}
+
+func TestUploadChunkLarge_AbortsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	fake := &fakeS3{onPart: func(int) { cancel() }}
+	s := newFakeS3Storage(t, fake)
+
+	data := bytes.Repeat([]byte("d"), 2*MultipartThreshold)
+	err := s.UploadChunkLarge(ctx, 1, models.ProviderClaudeCode, "ext", "transcript.jsonl", 1, 5, data)
+	if err == nil {
+		t.Fatal("expected an error after cancellation")
+	}
+	if !fake.aborted {
+		t.Error("cancelled upload should be aborted")
+	}
+	if fake.completed {
+		t.Error("cancelled upload should not be completed")
+	}
+}
+
+// BenchmarkUploadChunk compares allocations of the single PutObject path with
+// the multipart path against a discarding fake endpoint. Profile with:
+//
+//	go test ./internal/storage -run '^$' -bench UploadChunk -benchmem -memprofile mem.out
+func BenchmarkUploadChunk(b *testing.B) {
+	s := newFakeS3Storage(b, &fakeS3{})
+
+	for _, size := range []int{1 << 20, 10 << 20, 50 << 20} {
+		data := bytes.Repeat([]byte("x"), size)
+
+		b.Run(fmt.Sprintf("%dMB/put_object", size>>20), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := s.client.PutObject(b.Context(), s.bucket, "bench", bytes.NewReader(data), int64(size), minio.PutObjectOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("%dMB/multipart", size>>20), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := s.UploadChunkLarge(b.Context(), 1, models.ProviderClaudeCode, "bench", "transcript.jsonl", 1, 1, data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -253,6 +253,30 @@ func TestDeleteAllSessionChunks_RejectsInvalidProvider(t *testing.T) {
 	}
 }
 
+// TestArchiveChunkGeneration_RejectsInvalidProvider mirrors the UploadChunk check.
+func TestArchiveChunkGeneration_RejectsInvalidProvider(t *testing.T) {
+	s := &S3Storage{}
+	if _, err := s.ArchiveChunkGeneration(t.Context(), 1, "Claude Code", "ext-123", "transcript.jsonl", 0); err == nil {
+		t.Error("expected error for legacy provider value")
+	}
+	if _, err := s.ListGenerationChunks(t.Context(), 1, "", "ext-123", "transcript.jsonl", 0); err == nil {
+		t.Error("expected error for empty provider value")
+	}
+}
+
+// TestGenerationPrefix_OutsideChunksSubtree pins archived generations beside
+// chunks/, so no live file's ListChunks prefix can ever cover them.
+func TestGenerationPrefix_OutsideChunksSubtree(t *testing.T) {
+	got := generationPrefix(7, "claude-code", "ext-1", "subagents/agent-a.jsonl", 2)
+	want := "7/claude-code/ext-1/generations/2/subagents/agent-a.jsonl/"
+	if got != want {
+		t.Errorf("generationPrefix = %q, want %q", got, want)
+	}
+	if strings.HasPrefix(got, sessionChunksPrefix(7, "claude-code", "ext-1")) {
+		t.Errorf("generation prefix %q must not sit under the chunks/ subtree", got)
+	}
+}
+
 // TestSentinelErrors verifies sentinel errors are properly defined
 func TestSentinelErrors(t *testing.T) {
 	// Verify sentinel errors are not nil
@@ -188,6 +188,7 @@ func (e *TestEnvironment) CleanDB(t *testing.T) {
 		"api_keys",
 		"device_codes",
 		"web_sessions",
+		"orgs",
 		"users",
 	}
 
@@ -13,8 +13,10 @@ import (
 	"github.com/ConfabulousDev/confab-web/internal/analytics"
 	"github.com/ConfabulousDev/confab-web/internal/auth"
 	"github.com/ConfabulousDev/confab-web/internal/db"
+	"github.com/ConfabulousDev/confab-web/internal/features"
 	"github.com/ConfabulousDev/confab-web/internal/models"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 // AuthenticatedRequest creates an HTTP request with user authentication context
@@ -441,6 +443,30 @@ func MinimalTranscript() []byte {
 `)
 }
 
+// MinimalAgentID is the agent that MinimalTranscriptWithAgent launches; its
+// file is agent-<MinimalAgentID>.jsonl.
+const MinimalAgentID = "abc123"
+
+// MinimalTranscriptWithAgent returns a 4-line main transcript that launches
+// MinimalAgentID through the Task tool. The main assistant turns use 100
+// input and 50 output tokens.
+func MinimalTranscriptWithAgent() []byte {
+	return []byte(`{"type":"user","uuid":"m1","parentUuid":null,"isSidechain":false,"userType":"external","cwd":"/test","version":"1.0.0","sessionId":"test","timestamp":"2024-01-01T00:00:00Z","message":{"role":"user","content":"Explore the repo"}}
+{"type":"assistant","uuid":"m2","parentUuid":"m1","isSidechain":false,"userType":"external","cwd":"/test","version":"1.0.0","sessionId":"test","timestamp":"2024-01-01T00:00:01Z","message":{"id":"msg-main-1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"tool_use","id":"toolu_agent","name":"Task","input":{"subagent_type":"Explore","prompt":"List the packages"}}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":60,"output_tokens":30}}}
+{"type":"user","uuid":"m3","parentUuid":"m2","isSidechain":false,"userType":"external","cwd":"/test","version":"1.0.0","sessionId":"test","timestamp":"2024-01-01T00:00:10Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_agent","content":"Found 3 packages","is_error":false}]},"toolUseResult":{"agentId":"` + MinimalAgentID + `","status":"completed"}}
+{"type":"assistant","uuid":"m4","parentUuid":"m3","isSidechain":false,"userType":"external","cwd":"/test","version":"1.0.0","sessionId":"test","timestamp":"2024-01-01T00:00:11Z","message":{"id":"msg-main-2","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"The repo has 3 packages."}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":40,"output_tokens":20}}}
+`)
+}
+
+// MinimalAgentTranscript returns a 2-line agent transcript for
+// agent-<MinimalAgentID>.jsonl. Its assistant turn uses 200 input and 80
+// output tokens.
+func MinimalAgentTranscript() []byte {
+	return []byte(`{"type":"user","uuid":"a1","parentUuid":null,"userType":"external","cwd":"/test","version":"1.0.0","sessionId":"test","agentId":"` + MinimalAgentID + `","isSidechain":true,"timestamp":"2024-01-01T00:00:02Z","message":{"role":"user","content":"List the packages"}}
+{"type":"assistant","uuid":"a2","parentUuid":"a1","userType":"external","cwd":"/test","version":"1.0.0","sessionId":"test","agentId":"` + MinimalAgentID + `","isSidechain":true,"timestamp":"2024-01-01T00:00:09Z","message":{"id":"msg-agent-1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"Found 3 packages"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":200,"output_tokens":80}}}
+`)
+}
+
 // TestGitHubUser creates an OAuthUserInfo for a GitHub user for testing
 func TestGitHubUser(suffix string) models.OAuthUserInfo {
 	return models.OAuthUserInfo{
@@ -534,28 +560,69 @@ func CreateTestSessionFull(t *testing.T, env *TestEnvironment, userID int64, ext
 	return sessionID
 }
 
+// SetTestFeatureFlag creates or replaces a feature flag and drops this
+// process's cached value, so features.IsEnabled sees the change at once.
+// feature_flags is not cleared by CleanDB; a test relying on a flag sets it.
+func SetTestFeatureFlag(t *testing.T, env *TestEnvironment, flag string, enabledUserIDs []int64, enabledPct int) {
+	t.Helper()
+	if enabledUserIDs == nil {
+		enabledUserIDs = []int64{}
+	}
+	_, err := env.DB.Exec(env.Ctx, `
+		INSERT INTO feature_flags (flag_name, enabled_user_ids, enabled_pct)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (flag_name) DO UPDATE SET
+			enabled_user_ids = EXCLUDED.enabled_user_ids,
+			enabled_pct = EXCLUDED.enabled_pct`,
+		flag, pq.Int64Array(enabledUserIDs), enabledPct)
+	if err != nil {
+		t.Fatalf("failed to set test feature flag: %v", err)
+	}
+	features.Invalidate(flag)
+}
+
 // CreateTestSearchIndex inserts a search index row with a weighted tsvector for testing.
 // The text is indexed with weight 'A' for simplicity.
 func CreateTestSearchIndex(t *testing.T, env *TestEnvironment, sessionID string, text string, indexedUpToLine int64) {
 	t.Helper()
+	CreateTestWeightedSearchIndex(t, env, sessionID, text, "", "", indexedUpToLine)
+}
+
+// CreateTestWeightedSearchIndex inserts a search index row with separate
+// metadata (A), recap (B) and user-message (C) components, for tests of
+// search ranking.
+func CreateTestWeightedSearchIndex(t *testing.T, env *TestEnvironment, sessionID, metadata, recap, messages string, indexedUpToLine int64) {
+	t.Helper()
 
 	query := `
+		WITH v AS (
+			SELECT
+				setweight(to_tsvector('english', $2), 'A') AS m,
+				setweight(to_tsvector('english', $3), 'B') AS r,
+				setweight(to_tsvector('english', $4), 'C') AS c
+		)
 		INSERT INTO session_search_index (
-			session_id, version, content_text, search_vector,
+			session_id, version, content_text,
+			metadata_vector, recap_vector, messages_vector, search_vector,
 			indexed_up_to_line, metadata_hash, updated_at
 		) VALUES (
-			$1, 1, $2,
-			setweight(to_tsvector('english', $2), 'A'),
-			$3, '', NOW()
+			$1, 1, $6,
+			(SELECT m FROM v), (SELECT r FROM v), (SELECT c FROM v),
+			(SELECT m || r || c FROM v),
+			$5, '', NOW()
 		)
 		ON CONFLICT (session_id) DO UPDATE SET
 			content_text = EXCLUDED.content_text,
+			metadata_vector = EXCLUDED.metadata_vector,
+			recap_vector = EXCLUDED.recap_vector,
+			messages_vector = EXCLUDED.messages_vector,
 			search_vector = EXCLUDED.search_vector,
 			indexed_up_to_line = EXCLUDED.indexed_up_to_line,
 			updated_at = NOW()
 	`
 
-	_, err := env.DB.Exec(env.Ctx, query, sessionID, text, indexedUpToLine)
+	content := (&analytics.SearchIndexContent{MetadataText: metadata, RecapText: recap, UserMessagesText: messages}).CombinedText()
+	_, err := env.DB.Exec(env.Ctx, query, sessionID, metadata, recap, messages, indexedUpToLine, content)
 	if err != nil {
 		t.Fatalf("failed to create test search index: %v", err)
 	}
@@ -3,7 +3,8 @@ package validation
 import (
 	"encoding/json"
 	"fmt"
-	"slices"
+	"net/url"
+	"regexp"
 	"strings"
 	"unicode/utf8"
 
@@ -23,6 +24,8 @@ const (
 	MaxHostnameLength         = 255  // sessions.hostname
 	MaxUsernameLength         = 255  // sessions.username
 	MaxAPIKeyNameLength       = 255  // api_keys.name
+	MaxWebhookURLLength       = 2048 // webhook_endpoints.url
+	MaxPRURLLength            = 2048 // sessions.pr_url
 
 	// Filter parameter limits to prevent memory exhaustion from oversized query strings.
 	MaxFilterCount    = 50   // max number of values per filter param
@@ -73,14 +76,6 @@ func ValidateExternalID(externalID string) error {
 	return nil
 }
 
-// ValidateCWD validates a working directory path
-func ValidateCWD(cwd string) error {
-	if len(cwd) > MaxCWDLength {
-		return errMaxLength("cwd", MaxCWDLength)
-	}
-	return nil
-}
-
 // ValidateTranscriptPath validates a transcript file path
 func ValidateTranscriptPath(path string) error {
 	if len(path) > MaxTranscriptPathLength {
@@ -105,14 +100,6 @@ func ValidateSummary(summary string) error {
 	return nil
 }
 
-// ValidateFirstUserMessage validates a first user message
-func ValidateFirstUserMessage(msg string) error {
-	if len(msg) > MaxFirstUserMessageLength {
-		return errMaxLength("first_user_message", MaxFirstUserMessageLength)
-	}
-	return nil
-}
-
 // ValidateAPIKeyName validates an API key name
 func ValidateAPIKeyName(name string) error {
 	if len(name) > MaxAPIKeyNameLength {
@@ -121,6 +108,57 @@ func ValidateAPIKeyName(name string) error {
 	return nil
 }
 
+// ValidateWebhookURL validates a webhook endpoint URL: an absolute http or
+// https URL with a host and no credentials. Whether the host is reachable
+// (and public) is checked by the dispatcher at delivery time.
+func ValidateWebhookURL(raw string) error {
+	if len(raw) > MaxWebhookURLLength {
+		return errMaxLength("url", MaxWebhookURLLength)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if u.User != nil {
+		return fmt.Errorf("url must not contain credentials")
+	}
+	return nil
+}
+
+// PR URL paths: GitHub /owner/repo/pull/N and GitLab
+// /group[/subgroup...]/project/-/merge_requests/N, each optionally followed by
+// a sub-page such as /files or /diffs.
+var (
+	githubPRPathPattern = regexp.MustCompile(`^/([A-Za-z0-9-]+)/([A-Za-z0-9._-]+)/pull/([1-9][0-9]*)(?:/.*)?$`)
+	gitlabMRPathPattern = regexp.MustCompile(`^/((?:[A-Za-z0-9._-]+/)+[A-Za-z0-9._-]+)/-/merge_requests/([1-9][0-9]*)(?:/.*)?$`)
+)
+
+// NormalizePRURL validates a pull request URL and returns its canonical form:
+// a github.com pull request (https://github.com/owner/repo/pull/N) or a
+// GitLab merge request on any host (https://host/group/project/-/merge_requests/N),
+// so self-managed GitLab works. Sub-pages, query strings and fragments are
+// dropped, so links to one PR's tabs compare equal.
+func NormalizePRURL(raw string) (string, error) {
+	if len(raw) > MaxPRURLLength {
+		return "", errMaxLength("pr_url", MaxPRURLLength)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User != nil {
+		return "", fmt.Errorf("pr_url must be an absolute http or https URL")
+	}
+	host := strings.ToLower(u.Host)
+	if host == "github.com" || host == "www.github.com" {
+		if m := githubPRPathPattern.FindStringSubmatch(u.Path); m != nil {
+			return "https://github.com/" + m[1] + "/" + m[2] + "/pull/" + m[3], nil
+		}
+		return "", fmt.Errorf("pr_url must be a GitHub pull request URL (https://github.com/owner/repo/pull/N)")
+	}
+	if m := gitlabMRPathPattern.FindStringSubmatch(u.Path); m != nil {
+		return "https://" + host + "/" + m[1] + "/-/merge_requests/" + m[2], nil
+	}
+	return "", fmt.Errorf("pr_url must be a GitHub pull request or GitLab merge request URL")
+}
+
 // ValidateHostname validates a client hostname
 func ValidateHostname(hostname string) error {
 	if len(hostname) > MaxHostnameLength {
@@ -138,14 +176,15 @@ func ValidateUsername(username string) error {
 }
 
 // ValidateProvider returns an error unless p exactly equals one of the
-// canonical provider values in models.CanonicalProviders. The handler is
+// canonical provider values in models.CanonicalProviders, or a session type
+// with a registered analytics handler (models.IsKnownSessionType). The handler is
 // responsible for defaulting a missing API field to
 // models.ProviderClaudeCode before calling this — an explicit empty
 // string is not accepted here. No trimming or case folding. Legacy DB
 // values like "Claude Code" are NOT accepted on the wire; they exist
 // only at the persistence layer via models.LegacyAliases.
 func ValidateProvider(p string) error {
-	if slices.Contains(models.CanonicalProviders, p) {
+	if models.IsKnownSessionType(p) {
 		return nil
 	}
 	return fmt.Errorf("unknown provider %q: must be one of %s",
@@ -225,19 +264,10 @@ const (
 )
 
 // MaxCursorModelLength matches the cursor_session_meta.model column width in
-// migration 000055.
+// migration 000055. Enforced on the sync chunk's metadata.model (zsr6) by
+// ValidateSyncMetadata.
 const MaxCursorModelLength = 255
 
-// ValidateCursorModel enforces the length limit on the Cursor sync chunk's
-// metadata.model field (zsr6). An empty/absent model is valid — the handler
-// simply skips persistence — so only an over-length value is rejected here.
-func ValidateCursorModel(model string) error {
-	if len(model) > MaxCursorModelLength {
-		return errMaxLength("model", MaxCursorModelLength)
-	}
-	return nil
-}
-
 // ValidateCodexRolloutMetadata enforces the codex_rollout sub-block contract
 // from POST /api/v1/sync/chunk. The handler calls this only when the request
 // carries the block; the provider-mismatch check (codex sessions only) is
@@ -0,0 +1,105 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Size caps for sync metadata with no column width behind them. git_info is
+// stored as JSONB; 32 KiB holds a full remotes list with room to spare. The
+// total bounds one request's metadata as a whole, below the sum of the
+// per-field limits.
+const (
+	MaxGitInfoBytes      = 32 * 1024
+	MaxSyncMetadataBytes = 40 * 1024
+)
+
+// SyncMetadataLimits lists the metadata size limits of sync/init and
+// sync/chunk, in bytes. sync/init returns it so a client can trim before
+// sending rather than learn the limits from a 400.
+type SyncMetadataLimits struct {
+	CWD              int `json:"cwd"`
+	GitInfo          int `json:"git_info"`
+	Hostname         int `json:"hostname"`
+	Username         int `json:"username"`
+	Summary          int `json:"summary"`
+	FirstUserMessage int `json:"first_user_message"`
+	Model            int `json:"model"`
+	Total            int `json:"total"`
+}
+
+// CurrentSyncMetadataLimits returns the limits ValidateSyncMetadata enforces.
+func CurrentSyncMetadataLimits() SyncMetadataLimits {
+	return SyncMetadataLimits{
+		CWD:              MaxCWDLength,
+		GitInfo:          MaxGitInfoBytes,
+		Hostname:         MaxHostnameLength,
+		Username:         MaxUsernameLength,
+		Summary:          MaxSummaryLength,
+		FirstUserMessage: MaxFirstUserMessageLength,
+		Model:            MaxCursorModelLength,
+		Total:            MaxSyncMetadataBytes,
+	}
+}
+
+// SyncMetadata is the metadata of a sync/init or sync/chunk request, after
+// the handler has resolved the nested metadata object against the deprecated
+// top-level fields. Nil pointers are fields the request did not send.
+type SyncMetadata struct {
+	CWD              string
+	GitInfo          json.RawMessage
+	Hostname         string
+	Username         string
+	Summary          *string
+	FirstUserMessage *string
+	Model            *string
+}
+
+// FieldLimitError is a metadata field, or the metadata as a whole ("metadata"),
+// over its size limit.
+type FieldLimitError struct {
+	Field string
+	Limit int
+}
+
+func (e *FieldLimitError) Error() string {
+	if e.Field == "metadata" {
+		return fmt.Sprintf("metadata exceeds maximum total size of %d bytes", e.Limit)
+	}
+	return errMaxLength(e.Field, e.Limit).Error()
+}
+
+// ValidateSyncMetadata checks each metadata field against its limit, then the
+// fields' combined size against MaxSyncMetadataBytes. Failures are
+// *FieldLimitError naming the first field over its limit.
+func ValidateSyncMetadata(m SyncMetadata) error {
+	deref := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	total := 0
+	for _, f := range []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"cwd", m.CWD, MaxCWDLength},
+		{"git_info", string(m.GitInfo), MaxGitInfoBytes},
+		{"hostname", m.Hostname, MaxHostnameLength},
+		{"username", m.Username, MaxUsernameLength},
+		{"summary", deref(m.Summary), MaxSummaryLength},
+		{"first_user_message", deref(m.FirstUserMessage), MaxFirstUserMessageLength},
+		{"model", deref(m.Model), MaxCursorModelLength},
+	} {
+		if len(f.value) > f.max {
+			return &FieldLimitError{Field: f.name, Limit: f.max}
+		}
+		total += len(f.value)
+	}
+	if total > MaxSyncMetadataBytes {
+		return &FieldLimitError{Field: "metadata", Limit: MaxSyncMetadataBytes}
+	}
+	return nil
+}
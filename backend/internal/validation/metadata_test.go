@@ -0,0 +1,67 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateSyncMetadata(t *testing.T) {
+	str := func(n int) *string {
+		s := strings.Repeat("a", n)
+		return &s
+	}
+	// gitInfo builds a JSON object of exactly n bytes.
+	gitInfo := func(n int) json.RawMessage {
+		return json.RawMessage(`{"branch":"` + strings.Repeat("b", n-len(`{"branch":""}`)) + `"}`)
+	}
+
+	tests := []struct {
+		name      string
+		meta      SyncMetadata
+		wantField string // "" for valid
+		wantLimit int
+	}{
+		{"empty", SyncMetadata{}, "", 0},
+		{"cwd at limit", SyncMetadata{CWD: *str(MaxCWDLength)}, "", 0},
+		{"cwd over limit", SyncMetadata{CWD: *str(MaxCWDLength + 1)}, "cwd", MaxCWDLength},
+		{"git_info at limit", SyncMetadata{GitInfo: gitInfo(MaxGitInfoBytes)}, "", 0},
+		{"git_info over limit", SyncMetadata{GitInfo: gitInfo(MaxGitInfoBytes + 1)}, "git_info", MaxGitInfoBytes},
+		{"hostname over limit", SyncMetadata{Hostname: *str(MaxHostnameLength + 1)}, "hostname", MaxHostnameLength},
+		{"username over limit", SyncMetadata{Username: *str(MaxUsernameLength + 1)}, "username", MaxUsernameLength},
+		{"summary at limit", SyncMetadata{Summary: str(MaxSummaryLength)}, "", 0},
+		{"summary over limit", SyncMetadata{Summary: str(MaxSummaryLength + 1)}, "summary", MaxSummaryLength},
+		{"first_user_message over limit", SyncMetadata{FirstUserMessage: str(MaxFirstUserMessageLength + 1)}, "first_user_message", MaxFirstUserMessageLength},
+		{"model over limit", SyncMetadata{Model: str(MaxCursorModelLength + 1)}, "model", MaxCursorModelLength},
+		{
+			"total at limit",
+			SyncMetadata{GitInfo: gitInfo(MaxGitInfoBytes), CWD: *str(MaxSyncMetadataBytes - MaxGitInfoBytes)},
+			"", 0,
+		},
+		{
+			"total over limit",
+			SyncMetadata{GitInfo: gitInfo(MaxGitInfoBytes), CWD: *str(MaxSyncMetadataBytes - MaxGitInfoBytes), Hostname: "h"},
+			"metadata", MaxSyncMetadataBytes,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSyncMetadata(tt.meta)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("ValidateSyncMetadata() = %v, want nil", err)
+				}
+				return
+			}
+			var limitErr *FieldLimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("ValidateSyncMetadata() = %v, want *FieldLimitError", err)
+			}
+			if limitErr.Field != tt.wantField || limitErr.Limit != tt.wantLimit {
+				t.Errorf("error names %s (limit %d), want %s (limit %d)",
+					limitErr.Field, limitErr.Limit, tt.wantField, tt.wantLimit)
+			}
+		})
+	}
+}
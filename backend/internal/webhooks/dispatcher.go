@@ -0,0 +1,257 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/ConfabulousDev/confab-web/internal/db/dbwebhook"
+	"github.com/ConfabulousDev/confab-web/internal/logger"
+)
+
+var tracer = otel.Tracer("confab/webhooks")
+
+// Tunables are vars (not consts) so tests can shrink them.
+var (
+	baseBackoff    = 30 * time.Second
+	maxBackoff     = 6 * time.Hour
+	requestTimeout = 10 * time.Second
+)
+
+// maxErrorLen caps the error text stored on a failed attempt.
+const maxErrorLen = 500
+
+// ErrPrivateTarget is returned when an endpoint resolves to a loopback,
+// private or link-local address and Config.AllowPrivateTargets is off.
+var ErrPrivateTarget = errors.New("webhook target resolves to a private address")
+
+// Config holds dispatcher settings.
+type Config struct {
+	PollInterval        time.Duration // Wait between polls when the queue is drained (default 10s)
+	MaxAttempts         int           // Attempts before a delivery is marked failed (default 8)
+	BatchSize           int           // Deliveries claimed per query (default 20)
+	AllowPrivateTargets bool          // Permit endpoints on loopback/private networks (self-hosted, tests)
+}
+
+// deliveryStore is the narrow surface Dispatcher calls on the database.
+// *dbwebhook.Store satisfies it; tests pass a fake.
+type deliveryStore interface {
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]dbwebhook.Delivery, error)
+	MarkDelivered(ctx context.Context, id int64, statusCode int) error
+	MarkAttemptFailed(ctx context.Context, id int64, statusCode *int, reason string, retryAt *time.Time) error
+}
+
+// Dispatcher delivers queued webhook events.
+type Dispatcher struct {
+	store  deliveryStore
+	client *http.Client
+	config Config
+	now    func() time.Time
+}
+
+// NewDispatcher builds a dispatcher, filling in defaults for zero config
+// fields.
+func NewDispatcher(store deliveryStore, config Config) *Dispatcher {
+	if config.PollInterval <= 0 {
+		config.PollInterval = 10 * time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 8
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 20
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !config.AllowPrivateTargets {
+		// Checked at connect time, after DNS resolution, so a public hostname
+		// that resolves to an internal address is refused too.
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			return checkPublicAddress(address)
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Dispatcher{
+		store: store,
+		client: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: transport,
+			// Redirects would sidestep the endpoint URL the user registered.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Run delivers due events until ctx is cancelled, polling every
+// PollInterval once the queue is drained.
+func (d *Dispatcher) Run(ctx context.Context) {
+	logger.Info("webhook dispatcher started",
+		"poll_interval", d.config.PollInterval,
+		"max_attempts", d.config.MaxAttempts,
+		"allow_private_targets", d.config.AllowPrivateTargets,
+	)
+	for {
+		if _, err := d.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			logger.Error("failed to deliver webhooks", "error", err)
+		}
+
+		timer := time.NewTimer(d.config.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// DeliverDue sends every delivery that is due, a batch at a time, and
+// returns how many attempts it made.
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "webhooks.deliver_due")
+	defer span.End()
+
+	// A claimed delivery stays invisible to other dispatchers for the lease,
+	// which must outlast sending the whole batch.
+	lease := time.Duration(d.config.BatchSize+1) * requestTimeout
+
+	attempted := 0
+	for ctx.Err() == nil {
+		batch, err := d.store.ClaimDue(ctx, d.config.BatchSize, lease)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return attempted, err
+		}
+		for _, delivery := range batch {
+			d.deliver(ctx, delivery)
+		}
+		attempted += len(batch)
+		if len(batch) < d.config.BatchSize {
+			break
+		}
+	}
+	span.SetAttributes(attribute.Int("webhooks.attempted", attempted))
+	return attempted, nil
+}
+
+// deliver makes one attempt and records its outcome. Recording failures are
+// logged; the lease makes the delivery due again either way.
+func (d *Dispatcher) deliver(ctx context.Context, delivery dbwebhook.Delivery) {
+	statusCode, err := d.send(ctx, delivery)
+	if err == nil {
+		if err := d.store.MarkDelivered(ctx, delivery.ID, statusCode); err != nil {
+			logger.Error("failed to record webhook delivery", "delivery_id", delivery.ID, "error", err)
+		}
+		return
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+	var retryAt *time.Time
+	if delivery.Attempts < d.config.MaxAttempts {
+		next := d.now().Add(Backoff(delivery.Attempts))
+		retryAt = &next
+	}
+	logger.Warn("webhook delivery attempt failed",
+		"delivery_id", delivery.ID,
+		"endpoint_id", delivery.EndpointID,
+		"event", delivery.EventType,
+		"attempt", delivery.Attempts,
+		"giving_up", retryAt == nil,
+		"error", err,
+	)
+	reason := err.Error()
+	if len(reason) > maxErrorLen {
+		reason = reason[:maxErrorLen]
+	}
+	if err := d.store.MarkAttemptFailed(ctx, delivery.ID, code, reason, retryAt); err != nil {
+		logger.Error("failed to record webhook attempt", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// send POSTs the signed payload. Any 2xx response is success; the status
+// code is returned whenever a response arrived.
+func (d *Dispatcher) send(ctx context.Context, delivery dbwebhook.Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid endpoint URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "confab-webhooks")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, d.now(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Backoff is the wait after a delivery's attempt-th failed attempt: 30s,
+// doubling each time, capped at 6h.
+func Backoff(attempt int) time.Duration {
+	wait := baseBackoff
+	for i := 1; i < attempt && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxBackoff)
+}
+
+// nonPublicPrefixes are special-purpose IPv4 ranges the netip Is* checks do
+// not cover but that must never be a webhook target.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"), // CGNAT shared address space; some clouds put internal and metadata services here
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved, including broadcast
+}
+
+// checkPublicAddress rejects a dialed "ip:port" that is not publicly
+// routable.
+func checkPublicAddress(address string) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPrivateTarget, address)
+	}
+	ip := ap.Addr().Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() || ip.IsInterfaceLocalMulticast() {
+		return fmt.Errorf("%w: %s", ErrPrivateTarget, ip)
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return fmt.Errorf("%w: %s", ErrPrivateTarget, ip)
+		}
+	}
+	return nil
+}
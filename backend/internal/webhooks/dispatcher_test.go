@@ -0,0 +1,153 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab-web/internal/db/dbwebhook"
+)
+
+type fakeStore struct {
+	mu        sync.Mutex
+	due       []dbwebhook.Delivery
+	delivered map[int64]int
+	failed    map[int64]*time.Time // retryAt; nil means gave up
+	codes     map[int64]*int
+}
+
+func newFakeStore(due ...dbwebhook.Delivery) *fakeStore {
+	return &fakeStore{due: due, delivered: map[int64]int{}, failed: map[int64]*time.Time{}, codes: map[int64]*int{}}
+}
+
+func (f *fakeStore) ClaimDue(_ context.Context, limit int, _ time.Duration) ([]dbwebhook.Delivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := min(limit, len(f.due))
+	batch := f.due[:n]
+	f.due = f.due[n:]
+	return batch, nil
+}
+
+func (f *fakeStore) MarkDelivered(_ context.Context, id int64, statusCode int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delivered[id] = statusCode
+	return nil
+}
+
+func (f *fakeStore) MarkAttemptFailed(_ context.Context, id int64, statusCode *int, _ string, retryAt *time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failed[id] = retryAt
+	f.codes[id] = statusCode
+	return nil
+}
+
+func TestDispatcher_DeliversSignedPayload(t *testing.T) {
+	payload := []byte(`{"event":"session.created","data":{"external_id":"abc"}}`)
+	var got *http.Request
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	store := newFakeStore(dbwebhook.Delivery{ID: 7, EventType: dbwebhook.EventSessionCreated, Payload: payload, Attempts: 1, URL: srv.URL, Secret: "whsec_test"})
+	d := NewDispatcher(store, Config{AllowPrivateTargets: true})
+
+	n, err := d.DeliverDue(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("DeliverDue = %d, %v; want 1, nil", n, err)
+	}
+	if store.delivered[7] != http.StatusNoContent {
+		t.Errorf("delivered = %v, want 7 -> 204", store.delivered)
+	}
+	if string(gotBody) != string(payload) {
+		t.Errorf("body = %s", gotBody)
+	}
+	if got.Header.Get(HeaderEvent) != "session.created" || got.Header.Get(HeaderDelivery) != "7" ||
+		got.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v", got.Header)
+	}
+	if err := Verify("whsec_test", got.Header.Get(HeaderSignature), gotBody, time.Minute, time.Now()); err != nil {
+		t.Errorf("signature: %v", err)
+	}
+}
+
+func TestDispatcher_RetriesThenGivesUp(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	store := newFakeStore(
+		dbwebhook.Delivery{ID: 1, Payload: []byte(`{}`), Attempts: 2, URL: srv.URL},
+		dbwebhook.Delivery{ID: 2, Payload: []byte(`{}`), Attempts: 3, URL: srv.URL},
+	)
+	d := NewDispatcher(store, Config{MaxAttempts: 3, AllowPrivateTargets: true})
+	d.now = func() time.Time { return now }
+
+	if _, err := d.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if retry := store.failed[1]; retry == nil || !retry.Equal(now.Add(time.Minute)) {
+		t.Errorf("delivery 1 retryAt = %v, want %v", retry, now.Add(time.Minute))
+	}
+	if retry, ok := store.failed[2]; !ok || retry != nil {
+		t.Errorf("delivery 2 retryAt = %v (recorded %v), want nil (gave up)", retry, ok)
+	}
+	if code := store.codes[1]; code == nil || *code != http.StatusInternalServerError {
+		t.Errorf("status code = %v, want 500", code)
+	}
+}
+
+func TestDispatcher_RejectsPrivateTargets(t *testing.T) {
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hit = true }))
+	defer srv.Close()
+
+	store := newFakeStore(dbwebhook.Delivery{ID: 1, Payload: []byte(`{}`), Attempts: 1, URL: srv.URL})
+	d := NewDispatcher(store, Config{})
+	if _, err := d.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if hit {
+		t.Error("dispatcher reached a loopback endpoint")
+	}
+	if _, ok := store.failed[1]; !ok || store.codes[1] != nil {
+		t.Errorf("failed = %v, codes = %v; want a failed attempt with no status", store.failed, store.codes)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{
+		1: 30 * time.Second, 2: time.Minute, 3: 2 * time.Minute, 8: 64 * time.Minute, 20: 6 * time.Hour,
+	} {
+		if got := Backoff(attempt); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestCheckPublicAddress(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:443", "10.1.2.3:80", "192.168.0.1:443", "169.254.169.254:80", "[::1]:443", "[fe80::1]:443", "0.0.0.0:80", "[::ffff:127.0.0.1]:80",
+		"100.64.0.1:80", "100.127.255.254:443", "[::ffff:100.100.100.200]:80", "192.0.0.8:443", "198.18.0.1:80", "198.19.255.255:443", "240.0.0.1:80", "255.255.255.255:80"} {
+		if err := checkPublicAddress(addr); !errors.Is(err, ErrPrivateTarget) {
+			t.Errorf("checkPublicAddress(%s) = %v, want ErrPrivateTarget", addr, err)
+		}
+	}
+	for _, addr := range []string{"93.184.216.34:443", "[2606:4700::1]:443", "100.63.255.255:443", "100.128.0.1:443", "198.17.255.255:443", "198.20.0.1:443", "192.0.1.1:443"} {
+		if err := checkPublicAddress(addr); err != nil {
+			t.Errorf("checkPublicAddress(%s) = %v, want nil", addr, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package webhooks signs and delivers the events queued in
+// webhook_deliveries. The worker runs a Dispatcher that claims due
+// deliveries, POSTs them to their endpoints and retries failures with
+// exponential backoff.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderSignature = "Confab-Signature" // t=<unix seconds>,v1=<hex HMAC-SHA256>
+	HeaderEvent     = "Confab-Event"     // Event type, e.g. session.created
+	HeaderDelivery  = "Confab-Delivery"  // Delivery ID; the same across retries
+)
+
+// secretPrefix marks endpoint signing secrets, like cfb_ marks API keys.
+const secretPrefix = "whsec_"
+
+// ErrInvalidSignature is returned by Verify when the header is malformed,
+// too old, or does not match the body.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// NewSecret generates an endpoint signing secret.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// Sign returns the Confab-Signature header value for body sent at ts. The
+// HMAC-SHA256 covers "<unix seconds>.<body>", so a captured request can't be
+// replayed with a fresh timestamp.
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + signature(secret, t, body)
+}
+
+// Verify checks a Confab-Signature header against body. Signatures older
+// than tolerance (relative to now) are rejected; zero skips the age check.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var t, v1 string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			v1 = v
+		}
+	}
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || v1 == "" {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 && now.Sub(time.Unix(unix, 0)) > tolerance {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(v1), []byte(signature(secret, t, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func signature(secret, t string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
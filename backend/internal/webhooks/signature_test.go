@@ -0,0 +1,50 @@
+package webhooks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	ts := time.Unix(1760000000, 0)
+	body := []byte(`{"event":"session.created"}`)
+
+	header := Sign("whsec_test", ts, body)
+	if !strings.HasPrefix(header, "t=1760000000,v1=") {
+		t.Fatalf("header = %q", header)
+	}
+	if err := Verify("whsec_test", header, body, 5*time.Minute, ts.Add(time.Minute)); err != nil {
+		t.Errorf("Verify = %v, want nil", err)
+	}
+
+	cases := map[string]struct {
+		secret, header string
+		body           []byte
+		now            time.Time
+	}{
+		"wrong secret":  {"whsec_other", header, body, ts},
+		"tampered body": {"whsec_test", header, []byte(`{"event":"x"}`), ts},
+		"too old":       {"whsec_test", header, body, ts.Add(6 * time.Minute)},
+		"malformed":     {"whsec_test", "v1=abc", body, ts},
+		"replayed with new timestamp": {"whsec_test",
+			strings.Replace(header, "t=1760000000", "t=1760000100", 1), body, ts},
+	}
+	for name, tc := range cases {
+		if err := Verify(tc.secret, tc.header, tc.body, 5*time.Minute, tc.now); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: Verify = %v, want ErrInvalidSignature", name, err)
+		}
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewSecret()
+	if !strings.HasPrefix(a, "whsec_") || len(a) != len("whsec_")+64 || a == b {
+		t.Errorf("secrets %q, %q", a, b)
+	}
+}
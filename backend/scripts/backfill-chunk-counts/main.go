@@ -9,6 +9,7 @@
 // Flags:
 //   -dry-run    Print what would be updated without making changes
 //   -batch      Batch size for processing (default: 100)
+//   -selftest   Check DB, schema and S3 access (with a sample read) and exit
 
 package main
 
@@ -24,6 +25,8 @@ import (
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/ConfabulousDev/confab-web/internal/selftest"
 )
 
 type syncFile struct {
@@ -36,7 +39,7 @@ type syncFile struct {
 
 // normalizeProvider mirrors normalizeProvider() in
 // internal/db/session/provider.go. Duplicated here (rather than imported)
-// so this one-off script doesn't depend on an internal package.
+// so this one-off script doesn't depend on the db packages.
 func normalizeProvider(p string) string {
 	if p == "Claude Code" {
 		return "claude-code"
@@ -47,6 +50,7 @@ func normalizeProvider(p string) string {
 func main() {
 	dryRun := flag.Bool("dry-run", false, "Print what would be updated without making changes")
 	batchSize := flag.Int("batch", 100, "Batch size for processing")
+	selfTest := selftest.Flag()
 	flag.Parse()
 
 	dbURL := requireEnv("DATABASE_URL")
@@ -64,11 +68,6 @@ func main() {
 	}
 	defer db.Close()
 
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
-	}
-	log.Println("Connected to database")
-
 	// Connect to S3
 	s3Client, err := minio.New(s3Endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
@@ -77,7 +76,6 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create S3 client: %v", err)
 	}
-	log.Println("Connected to S3")
 
 	// Query files with NULL chunk_count
 	ctx := context.Background()
@@ -90,6 +88,29 @@ func main() {
 		LIMIT $1
 	`
 
+	if *selfTest {
+		checks := []selftest.Check{
+			selftest.DatabaseReachable(db),
+			selftest.SchemaHasColumns(db, map[string][]string{
+				"sync_files": {"session_id", "file_name", "chunk_count", "created_at", "updated_at"},
+				"sessions":   {"id", "user_id", "external_id", "session_type"},
+			}),
+			selftest.SampleRow(db, query, 1),
+			selftest.BucketExists(s3Client, bucketName),
+			selftest.SampleObject(s3Client, bucketName, ""),
+		}
+		if err := selftest.Run(ctx, os.Stdout, checks); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Selftest passed")
+		return
+	}
+
+	if err := db.Ping(); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+	log.Println("Connected to database")
+
 	totalProcessed := 0
 	totalUpdated := 0
 	totalErrors := 0
@@ -99,6 +99,29 @@ describe('SessionCard', () => {
     expect(queryByText('Avg time (auto)')).toBeNull();
   });
 
+  it('renders Out-of-order timestamps row with first line when anomalies > 0', () => {
+    const { getByText } = render(
+      <SessionCard
+        data={makeData({ timestamp_anomalies: 3, first_timestamp_anomaly_line: 42 })}
+        loading={false}
+        provider="claude-code"
+      />
+    );
+    expect(getByText('Out-of-order timestamps')).toBeInTheDocument();
+    expect(getByText('3 (from line 42)')).toBeInTheDocument();
+  });
+
+  it('omits Out-of-order timestamps row when there are no anomalies', () => {
+    const { queryByText } = render(
+      <SessionCard
+        data={makeData({ timestamp_anomalies: 0 })}
+        loading={false}
+        provider="claude-code"
+      />
+    );
+    expect(queryByText('Out-of-order timestamps')).toBeNull();
+  });
+
   it('renders loading state', () => {
     const { getByText } = render(
       <SessionCard data={null} loading={true} provider="claude-code" />
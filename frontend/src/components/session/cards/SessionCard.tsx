@@ -8,6 +8,7 @@ import {
   DurationIcon,
   RobotIcon,
   CompressIcon,
+  ClockIcon,
 } from '@/components/icons';
 import type { SessionCardData } from '@/schemas/api';
 import type { CardProps } from './types';
@@ -23,6 +24,8 @@ const TOOLTIPS = {
   compactionAuto: 'Compactions triggered automatically when context limit reached',
   compactionManual: 'Compactions triggered manually by user',
   compactionAvgTime: 'Average time for server-side summarization (auto compactions only)',
+  timestampAnomalies:
+    'Transcript lines whose timestamp jumps backwards by more than 1s (e.g. clock skew). Durations spanning them are counted as 0, so timings may read low',
   // userMessages varies by provider (CF-437) — see userMessagesTooltip below.
   userMessagesClaude: 'All user-role messages (human prompts + tool results)',
   userMessagesCodex:
@@ -200,6 +203,20 @@ export function SessionCard({ data, loading, error, provider }: SessionCardProps
           )}
         </>
       )}
+
+      {/* Out-of-order timestamps */}
+      {(data.timestamp_anomalies ?? 0) > 0 && (
+        <StatRow
+          label="Out-of-order timestamps"
+          value={
+            data.first_timestamp_anomaly_line != null
+              ? `${data.timestamp_anomalies} (from line ${data.first_timestamp_anomaly_line})`
+              : String(data.timestamp_anomalies)
+          }
+          icon={ClockIcon}
+          tooltip={TOOLTIPS.timestampAnomalies}
+        />
+      )}
     </CardWrapper>
   );
 }
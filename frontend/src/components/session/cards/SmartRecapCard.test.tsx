@@ -45,6 +45,18 @@ describe('SmartRecapCard', () => {
     expect(screen.queryByText(/this month$/)).not.toBeInTheDocument();
   });
 
+  it('subtitle flags a recap generated from a truncated transcript', () => {
+    render(<SmartRecapCard data={{ ...mockData, input_truncated: true, input_omitted_entries: 120 }} loading={false} />);
+
+    expect(screen.getByText(/partial transcript/)).toBeInTheDocument();
+  });
+
+  it('subtitle omits the truncation flag for a full transcript', () => {
+    render(<SmartRecapCard data={mockData} loading={false} />);
+
+    expect(screen.queryByText(/partial transcript/)).not.toBeInTheDocument();
+  });
+
   it('loading state shows "Loading..."', () => {
     render(<SmartRecapCard data={null} loading={true} />);
 
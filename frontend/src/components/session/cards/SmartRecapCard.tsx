@@ -116,6 +116,7 @@ export function SmartRecapCard({
   const subtitleParts = [
     formatRelativeTime(data.computed_at),
     modelShort,
+    ...(data.input_truncated ? ['partial transcript'] : []),
     ...(quota ? [`${quota.used}/${quota.limit} this month`] : []),
   ];
   const subtitle = subtitleParts.join(' · ');
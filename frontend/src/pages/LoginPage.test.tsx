@@ -1,5 +1,5 @@
 import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
-import { render, screen, waitFor } from '@testing-library/react';
+import { render, screen, waitFor, fireEvent } from '@testing-library/react';
 import { MemoryRouter } from 'react-router-dom';
 import LoginPage from './LoginPage';
 
@@ -273,6 +273,48 @@ describe('LoginPage', () => {
     expect(link).toHaveAttribute('rel', 'noopener noreferrer');
   });
 
+  it('renders an email-link form and does not auto-redirect for a sole email provider', async () => {
+    mockFetchConfig([
+      { name: 'email', display_name: 'Email link', login_url: '/api/v1/auth/email' },
+    ]);
+
+    renderWithRouter();
+
+    await waitFor(() => {
+      expect(screen.getByRole('button', { name: 'Email me a sign-in link' })).toBeInTheDocument();
+    });
+    expect(screen.queryByText('Continue with Email link')).not.toBeInTheDocument();
+  });
+
+  it('posts the email and shows a check-your-email message', async () => {
+    const fetchMock = vi.fn()
+      .mockResolvedValueOnce({
+        json: () => Promise.resolve({
+          providers: [
+            { name: 'email', display_name: 'Email link', login_url: '/api/v1/auth/email' },
+            { name: 'github', display_name: 'GitHub', login_url: '/auth/github/login' },
+          ],
+        }),
+      })
+      .mockResolvedValueOnce({ ok: true });
+    globalThis.fetch = fetchMock;
+
+    renderWithRouter();
+
+    const input = await screen.findByLabelText('Email for sign-in link');
+    fireEvent.change(input, { target: { value: 'user@example.com' } });
+    fireEvent.click(screen.getByRole('button', { name: 'Email me a sign-in link' }));
+
+    await waitFor(() => {
+      expect(screen.getByText(/a sign-in link is on its way/)).toBeInTheDocument();
+    });
+    expect(fetchMock).toHaveBeenLastCalledWith('/api/v1/auth/email', expect.objectContaining({
+      method: 'POST',
+      body: JSON.stringify({ email: 'user@example.com' }),
+    }));
+    expect(screen.getByText('Continue with GitHub')).toBeInTheDocument();
+  });
+
   it('returns null for single OAuth provider (auto-redirect)', async () => {
     mockFetchConfig([
       { name: 'github', display_name: 'GitHub', login_url: '/auth/github/login' },
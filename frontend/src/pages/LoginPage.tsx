@@ -45,6 +45,9 @@ function LockIcon() {
   );
 }
 
+// Providers rendered as in-page forms rather than OAuth redirect buttons.
+const FORM_PROVIDERS = new Set(['password', 'email']);
+
 function LoginPage() {
   useDocumentTitle('Log in');
   const { user, isAuthenticated, loading: authLoading } = useAuth();
@@ -52,6 +55,7 @@ function LoginPage() {
   const navigate = useNavigate();
   const [searchParams, setSearchParams] = useSearchParams();
   const [config, setConfig] = useState<AuthConfig | null>(null);
+  const [emailLinkState, setEmailLinkState] = useState<'idle' | 'sending' | 'sent' | 'error'>('idle');
 
   const redirectParam = searchParams.get('redirect') || '';
   const emailParam = searchParams.get('email') || '';
@@ -106,12 +110,12 @@ function LoginPage() {
     return () => { cancelled = true; };
   }, []);
 
-  // Auto-redirect: single OAuth provider (not password)
+  // Auto-redirect: single OAuth provider (not password or email link)
   // Skip redirect when there's an auth error to avoid infinite redirect loops
   useEffect(() => {
     if (!config || authError) return;
     const sole = config.providers.length === 1 ? config.providers[0] : undefined;
-    if (sole && sole.name !== 'password') {
+    if (sole && !FORM_PROVIDERS.has(sole.name)) {
       let loginURL = sole.login_url;
       const params = new URLSearchParams();
       if (redirectParam) params.set('redirect', redirectParam);
@@ -126,14 +130,34 @@ function LoginPage() {
   if (authLoading || !config) return null;
   if (isAuthenticated && !isDemoUser) return null;
 
-  // If single non-password provider and no error, we're about to redirect — show nothing
+  // If single OAuth provider and no error, we're about to redirect — show nothing
   const soleProvider = config.providers.length === 1 ? config.providers[0] : undefined;
-  if (soleProvider && soleProvider.name !== 'password' && !authError) {
+  if (soleProvider && !FORM_PROVIDERS.has(soleProvider.name) && !authError) {
     return null;
   }
 
   const hasPassword = config.providers.some((p) => p.name === 'password');
-  const oauthProviders = config.providers.filter((p) => p.name !== 'password');
+  const emailLinkProvider = config.providers.find((p) => p.name === 'email');
+  const oauthProviders = config.providers.filter((p) => !FORM_PROVIDERS.has(p.name));
+
+  // The response is the same whether or not the account exists, so "sent"
+  // only means the request was accepted.
+  async function handleEmailLinkSubmit(e: React.FormEvent<HTMLFormElement>) {
+    e.preventDefault();
+    if (!emailLinkProvider) return;
+    const email = new FormData(e.currentTarget).get('email');
+    setEmailLinkState('sending');
+    try {
+      const res = await fetch(emailLinkProvider.login_url, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ email }),
+      });
+      setEmailLinkState(res.ok ? 'sent' : 'error');
+    } catch {
+      setEmailLinkState('error');
+    }
+  }
 
   // Build query string for OAuth links
   function buildOAuthURL(loginURL: string): string {
@@ -216,7 +240,35 @@ function LoginPage() {
           </form>
         )}
 
-        {hasPassword && oauthProviders.length > 0 && (
+        {hasPassword && emailLinkProvider && (
+          <div className={styles.divider}>or</div>
+        )}
+
+        {emailLinkProvider && (emailLinkState === 'sent' ? (
+          <Alert variant="success" className={styles.errorAlert}>
+            If that email belongs to an account, a sign-in link is on its way. It expires in 15 minutes.
+          </Alert>
+        ) : (
+          <form className={styles.passwordForm} onSubmit={handleEmailLinkSubmit}>
+            {emailLinkState === 'error' && (
+              <Alert variant="error">Could not send a sign-in link. Please try again.</Alert>
+            )}
+            <input
+              type="email"
+              name="email"
+              placeholder="Email"
+              aria-label="Email for sign-in link"
+              defaultValue={emailParam}
+              required
+              className={styles.input}
+            />
+            <button type="submit" className={styles.submitBtn} disabled={emailLinkState === 'sending'}>
+              Email me a sign-in link
+            </button>
+          </form>
+        ))}
+
+        {(hasPassword || emailLinkProvider) && oauthProviders.length > 0 && (
           <div className={styles.divider}>or continue with</div>
         )}
 
@@ -157,6 +157,7 @@ function SessionsPage() {
                               </span>
                             </div>
                             <div className={styles.chipRow}>
+                              {session.is_demo && <Chip variant="green">Demo</Chip>}
                               <Chip icon={getProviderIcon(session.provider)} variant="neutral" copyValue={session.external_id}>
                                 {session.external_id.substring(0, 8)}
                               </Chip>
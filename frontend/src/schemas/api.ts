@@ -21,6 +21,7 @@ const SyncFileDetailSchema = z.object({
   file_type: z.string(),
   last_synced_line: z.number(),
   updated_at: z.string(),
+  generation: z.number().optional(),
 });
 
 // ============================================================================
@@ -35,6 +36,8 @@ const SessionSchema = z.object({
   last_sync_time: z.string().nullable().optional(),
   custom_title: z.string().max(255).nullable().optional(),
   suggested_session_title: z.string().max(100).nullable().optional(),
+  ai_generated_title: z.string().max(255).nullable().optional(),
+  title: z.string().optional(),
   summary: z.string().nullable().optional(),
   first_user_message: z.string().nullable().optional(),
   // Canonical agent identifier: 'claude-code' or 'codex'. Future providers
@@ -48,8 +51,17 @@ const SessionSchema = z.object({
   github_prs: z.array(z.string()).nullable().optional(), // Linked GitHub PR URLs (e.g., ["https://github.com/org/repo/pull/123"])
   github_commits: z.array(z.string()).nullable().optional(), // Linked GitHub commit SHAs (latest first)
   estimated_cost_usd: z.string().nullable().optional(), // Estimated API cost from analytics
+  duplicate_of: z.string().nullable().optional(), // Earliest owned session with the same content fingerprint (probable duplicate)
+  search_rank: z.number().optional(), // Full-text relevance (search results only)
+  interest_score: z.number().optional(), // Interest score (sort=interesting only)
+  // Lifecycle state: 'active' | 'idle' | 'ended' | 'archived' | 'data_missing'.
+  // Free string so new states don't fail validation; optional for older backends.
+  state: z.string().optional(),
+  state_changed_at: z.string().optional(),
+  is_demo: z.boolean().optional(), // Sample session seeded by POST /api/v1/me/demo
+  has_out_of_tree_access: z.boolean().optional(), // Read/Write/Edit touched files outside the session cwd
   is_owner: z.boolean(),
-  access_type: z.enum(['owner', 'private_share', 'public_share', 'system_share']),
+  access_type: z.enum(['owner', 'private_share', 'public_share', 'system_share', 'org_share']),
   shared_by_email: z.string().nullable().optional(),
   owner_email: z.string(),
 });
@@ -76,6 +88,8 @@ export const SessionDetailSchema = z.object({
   provider: z.string(),
   custom_title: z.string().max(255).nullable().optional(),
   suggested_session_title: z.string().max(100).nullable().optional(),
+  ai_generated_title: z.string().max(255).nullable().optional(),
+  title: z.string().optional(),
   summary: z.string().nullable().optional(),
   first_user_message: z.string().nullable().optional(),
   first_seen: z.string(),
@@ -89,6 +103,14 @@ export const SessionDetailSchema = z.object({
   is_owner: z.boolean().optional(), // True if viewer is session owner (shared sessions only)
   shared_by_email: z.string().nullable().optional(), // Email of session owner (non-owner access only)
   owner_email: z.string(), // Email of session owner (always populated)
+  // Set once transcript retention deleted the raw chunks; raw reads return 410.
+  transcript_archived_at: z.string().nullable().optional(),
+  // Lifecycle state (see SessionSchema.state) and when it last changed.
+  state: z.string().optional(),
+  state_changed_at: z.string().optional(),
+  is_demo: z.boolean().optional(), // Sample session seeded by POST /api/v1/me/demo
+  // 'private' (owner and shares) or 'org' (also readable by the owner's org members).
+  visibility: z.enum(['private', 'org']).optional(),
 });
 
 const SessionShareSchema = z.object({
@@ -115,6 +137,38 @@ export const UserSchema = z.object({
   has_own_sessions: z.boolean().optional(),
   has_api_keys: z.boolean().optional(),
   is_admin: z.boolean().optional(),
+  include_agent_files_in_search: z.boolean().optional(),
+});
+
+// GET /api/v1/me/storage: chunk storage held by the caller's sessions
+export const StorageUsageSchema = z.object({
+  total_bytes: z.number(),
+  chunk_count: z.number(),
+  session_count: z.number(),
+  pending_file_count: z.number(),
+  sessions: z.array(
+    z.object({
+      session_id: z.string(),
+      external_id: z.string(),
+      provider: z.string(),
+      title: z.string(),
+      stored_bytes: z.number(),
+      chunk_count: z.number(),
+      pending_file_count: z.number(),
+    }),
+  ),
+});
+
+// GET/POST /api/v1/me/export: the caller's latest full-account data export
+export const DataExportSchema = z.object({
+  id: z.number(),
+  status: z.enum(['pending', 'processing', 'ready', 'failed', 'expired']),
+  requested_at: z.string(),
+  completed_at: z.string().optional(),
+  expires_at: z.string().optional(),
+  size_bytes: z.number().optional(),
+  session_count: z.number().optional(),
+  download_url: z.string().optional(),
 });
 
 // ============================================================================
@@ -126,6 +180,7 @@ const APIKeySchema = z.object({
   name: z.string(),
   created_at: z.string(),
   last_used_at: z.string().nullable().optional(),
+  created_session_count: z.number().optional(),
 });
 
 export const CreateAPIKeyResponseSchema = z.object({
@@ -199,6 +254,9 @@ const TokensCardDataSchema = z.object({
   // Fast mode breakdown (only present when fast mode was used)
   fast_turns: z.number().optional(),
   fast_cost_usd: z.string().optional(),
+  // Estimated thinking split of output (null when it can't be derived)
+  thinking_tokens: z.number().nullable().optional(),
+  thinking_cost_usd: z.string().nullable().optional(),
 });
 
 // tokens_v2: hierarchical per-provider per-model breakdown. Built for all
@@ -224,6 +282,10 @@ const TokensV2CardDataSchema = z.object({
   total_input: z.number(),
   total_output: z.number(),
   by_provider: z.record(z.string(), TokensV2ProviderSchema),
+  // Estimated share of total_output spent on extended thinking (Claude Code
+  // only; null when no message could be split).
+  thinking_tokens: z.number().nullable().optional(),
+  thinking_cost_usd: z.string().nullable().optional(),
 });
 
 // Session card includes compaction info (consolidated from previous separate compaction card)
@@ -251,6 +313,9 @@ const SessionCardDataSchema = z.object({
   compaction_auto: z.number(),
   compaction_manual: z.number(),
   compaction_avg_time_ms: z.number().nullable().optional(),
+  // Transcript lines whose timestamp goes backwards by more than 1s (durations clamped to 0)
+  timestamp_anomalies: z.number().optional(),
+  first_timestamp_anomaly_line: z.number().nullable().optional(),
 });
 
 const ToolStatsSchema = z.object({
@@ -271,6 +336,15 @@ const CodeActivityCardDataSchema = z.object({
   lines_removed: z.number(),
   search_count: z.number(),
   language_breakdown: z.record(z.string(), z.number()),
+  // Files touched outside the session cwd (Claude Code only; absent from older backends)
+  out_of_tree: z
+    .object({
+      home: z.number(),
+      temp: z.number(),
+      external: z.number(),
+      samples: z.array(z.object({ path: z.string(), category: z.string() })),
+    })
+    .optional(),
 });
 
 // Conversation card: tracks timing metrics for conversational turns
@@ -284,6 +358,83 @@ const ConversationCardDataSchema = z.object({
   assistant_utilization_pct: z.number().nullable().optional(),
 });
 
+// Per-turn conversation detail (GET /sessions/{id}/cards/conversation/turns)
+const ConversationTurnSchema = z.object({
+  turn_index: z.number(),
+  role: z.enum(['user', 'assistant']),
+  line_number: z.number(),
+  duration_ms: z.number().nullable(),
+  token_count: z.number().nullable(),
+  has_tool_use: z.boolean(),
+});
+
+export const ConversationTurnsResponseSchema = z.object({
+  turns: z.array(ConversationTurnSchema),
+  has_more: z.boolean(),
+  next_cursor: z.string().optional(),
+});
+
+// Cumulative token usage across the transcript (GET /sessions/{id}/tokens/series)
+const TokenSeriesPointSchema = z.object({
+  line: z.number(),
+  input_tokens: z.number(),
+  output_tokens: z.number(),
+  cache_creation_tokens: z.number(),
+  cache_read_tokens: z.number(),
+});
+
+export const TokenSeriesResponseSchema = z.object({
+  interval_lines: z.number(),
+  total_lines: z.number(),
+  points: z.array(TokenSeriesPointSchema),
+});
+
+// Projected final cost at the current spend rate (GET /sessions/{id}/cards/cost-projection)
+const CostProjectionSchema = z.object({
+  projected_total_cost_usd: z.string(),
+  estimated_completion_lines: z.number(),
+  confidence_interval: z.object({
+    low_usd: z.string(),
+    high_usd: z.string(),
+  }),
+});
+
+export const CostProjectionResponseSchema = z.object({
+  computed_at: z.string().optional(),
+  up_to_line: z.number(),
+  history_session_count: z.number(),
+  projection: CostProjectionSchema.nullable(),
+});
+
+// Per-file code activity (GET /sessions/{id}/cards/code-activity/files)
+const CodeFileStatsSchema = z.object({
+  file_path: z.string(),
+  language: z.string(),
+  read_count: z.number(),
+  write_count: z.number(),
+  lines_added: z.number(),
+  lines_removed: z.number(),
+});
+
+export const CodeActivityFilesResponseSchema = z.object({
+  total_files: z.number(),
+  files: z.array(CodeFileStatsSchema),
+});
+
+// Sampled Edit/MultiEdit diff hunks (GET /sessions/{id}/cards/code-changes)
+const EditHunkSchema = z.object({
+  file_path: z.string(),
+  lines_before: z.number(),
+  lines_after: z.number(),
+  added_lines: z.array(z.string()),
+  removed_lines: z.array(z.string()),
+});
+
+export const CodeChangesResponseSchema = z.object({
+  total_hunks: z.number(),
+  hunks: z.array(EditHunkSchema),
+});
+
 // Agent stats: per-agent-type success/error counts (same structure as ToolStats)
 const AgentStatsSchema = z.object({
   success: z.number(),
@@ -349,6 +500,9 @@ const SmartRecapCardDataSchema = z.object({
   default_context_suggestions: z.array(AnnotatedItemSchema),
   computed_at: z.string(),
   model_used: z.string(),
+  // True when a long session was cut down to fit the recap input cap.
+  input_truncated: z.boolean().optional(),
+  input_omitted_entries: z.number().optional(),
 });
 
 // Quota information for smart recap generation
@@ -374,6 +528,13 @@ const AnalyticsCardsSchema = z.object({
   smart_recap: SmartRecapCardDataSchema.optional(),
 });
 
+const CardStalenessSchema = z.object({
+  stale: z.boolean(),
+  stored_version: z.number(),
+  current_version: z.number(),
+  version_delta: z.number(),
+});
+
 export const SessionAnalyticsSchema = z.object({
   computed_at: z.string(), // ISO timestamp when analytics were computed
   computed_lines: z.number(), // Line count when analytics were computed
@@ -392,6 +553,11 @@ export const SessionAnalyticsSchema = z.object({
   smart_recap_missing_reason: z.enum(['quota_exceeded', 'unavailable']).optional().nullable(),
   // Suggested session title from Smart Recap (if generated)
   suggested_session_title: z.string().nullable().optional(),
+  // Outdated card versions served while the worker recomputes them
+  stale: z.boolean().optional(),
+  stale_cards: z.record(z.string(), CardStalenessSchema).optional().nullable(),
+  // Raw transcript deleted by retention; cards are final and never recomputed.
+  transcript_archived: z.boolean().optional(),
 });
 
 // ============================================================================
@@ -632,6 +798,8 @@ export type Session = z.infer<typeof SessionSchema>;
 export type SessionDetail = z.infer<typeof SessionDetailSchema>;
 export type SessionShare = z.infer<typeof SessionShareSchema>;
 export type User = z.infer<typeof UserSchema>;
+export type StorageUsage = z.infer<typeof StorageUsageSchema>;
+export type DataExport = z.infer<typeof DataExportSchema>;
 export type APIKey = z.infer<typeof APIKeySchema>;
 export type CreateAPIKeyResponse = z.infer<typeof CreateAPIKeyResponseSchema>;
 export type CreateShareResponse = z.infer<typeof CreateShareResponseSchema>;
@@ -642,6 +810,8 @@ export type SessionCardData = z.infer<typeof SessionCardDataSchema>;
 export type ToolsCardData = z.infer<typeof ToolsCardDataSchema>;
 export type CodeActivityCardData = z.infer<typeof CodeActivityCardDataSchema>;
 export type ConversationCardData = z.infer<typeof ConversationCardDataSchema>;
+export type ConversationTurn = z.infer<typeof ConversationTurnSchema>;
+export type ConversationTurnsResponse = z.infer<typeof ConversationTurnsResponseSchema>;
 export type AgentsAndSkillsCardData = z.infer<typeof AgentsAndSkillsCardDataSchema>;
 export type RedactionsCardData = z.infer<typeof RedactionsCardDataSchema>;
 export type WorkflowRun = z.infer<typeof WorkflowRunSchema>;